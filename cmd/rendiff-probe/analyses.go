@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// Analysis source types recorded with each stored analysis
+const (
+	analysisSourceUpload = "upload"
+	analysisSourceURL    = "url"
+	analysisSourceStream = "stream"
+	analysisSourceWatch  = "watch"
+)
+
+// analysisSaveTimeout bounds persisting a result once the probe has finished,
+// independent of the request deadline
+const analysisSaveTimeout = 10 * time.Second
+
+// recordAnalysis persists the outcome of a file or URL probe. errMsg is the
+// client-facing error of a failed analysis. Failures are logged, never
+// returned, so a database problem doesn't fail an analysis that succeeded.
+func recordAnalysis(ctx context.Context, analysisID, filename, source, sourceType string, size int64, result *ffmpeg.FFprobeResult, llmReport, errMsg string) {
+	id, err := uuid.Parse(analysisID)
+	if err != nil {
+		return
+	}
+
+	record := &database.AnalysisRecord{
+		ID:         id,
+		FileName:   filename,
+		Source:     source,
+		FileSize:   size,
+		SourceType: sourceType,
+		Status:     database.AnalysisStatusCompleted,
+		LLMReport:  llmReport,
+		Error:      errMsg,
+	}
+	if errMsg != "" {
+		record.Status = database.AnalysisStatusFailed
+	}
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			appLogger.Error().Err(err).Str("analysis_id", analysisID).Msg("Failed to encode analysis result")
+			return
+		}
+		record.Result = data
+	}
+
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), analysisSaveTimeout)
+	defer cancel()
+	if err := analysisStore.Save(saveCtx, record); err != nil {
+		appLogger.Error().Err(err).Str("analysis_id", analysisID).Msg("Failed to store analysis")
+	}
+	notifyPolicyFailure(ctx, analysisID, filename, result)
+}
+
+// fileSize returns the size of path, or 0 if it cannot be read
+func fileSize(path string) int64 {
+	if info, err := os.Stat(path); err == nil {
+		return info.Size()
+	}
+	return 0
+}
+
+// listAnalysesHandler lists stored analyses, newest first. Supports filename
+// (substring), status, from/to (RFC 3339 or YYYY-MM-DD) and limit/offset.
+func listAnalysesHandler(c *gin.Context) {
+	filter := database.AnalysisFilter{
+		FileName: c.Query("filename"),
+		Status:   c.Query("status"),
+	}
+
+	if filter.Status != "" && filter.Status != database.AnalysisStatusCompleted && filter.Status != database.AnalysisStatusFailed {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid status, must be 'completed' or 'failed'")
+		return
+	}
+
+	var err error
+	if filter.From, err = parseDateQuery(c.Query("from"), false); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid from date, use RFC 3339 or YYYY-MM-DD")
+		return
+	}
+	if filter.To, err = parseDateQuery(c.Query("to"), true); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid to date, use RFC 3339 or YYYY-MM-DD")
+		return
+	}
+
+	filter.Limit = database.DefaultAnalysisListLimit
+	if value := c.Query("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 1 || filter.Limit > database.MaxAnalysisListLimit {
+			respondError(c, 400, apierrors.CodeBadRequest, "Invalid limit, must be between 1 and "+strconv.Itoa(database.MaxAnalysisListLimit))
+			return
+		}
+	}
+	if value := c.Query("offset"); value != "" {
+		if filter.Offset, err = strconv.Atoi(value); err != nil || filter.Offset < 0 {
+			respondError(c, 400, apierrors.CodeBadRequest, "Invalid offset")
+			return
+		}
+	}
+
+	records, total, err := analysisStore.List(c.Request.Context(), filter)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Failed to list analyses")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to list analyses")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"analyses": records,
+		"total":    total,
+		"limit":    filter.Limit,
+		"offset":   filter.Offset,
+	})
+}
+
+// parseDateQuery parses an RFC 3339 timestamp or a plain date. A plain date
+// used as an upper bound covers the whole day.
+func parseDateQuery(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// getAnalysisHandler returns a stored analysis with its full result
+func getAnalysisHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format")
+		return
+	}
+
+	writeAnalysis(c, id)
+}
+
+// writeAnalysis responds with a stored analysis as JSON
+func writeAnalysis(c *gin.Context, id uuid.UUID) {
+	record, err := analysisStore.Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrAnalysisNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Analysis not found")
+			return
+		}
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to get analysis")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to get analysis")
+		return
+	}
+
+	c.JSON(200, record)
+}
+
+// deleteAnalysisHandler removes a stored analysis and any cached report data
+func deleteAnalysisHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format")
+		return
+	}
+
+	if _, exists := memoryAnalysis(c.Request.Context(), id); exists {
+		analysesLock.Lock()
+		delete(storedAnalyses, id.String())
+		analysesLock.Unlock()
+	}
+
+	if err := analysisStore.Delete(c.Request.Context(), id); err != nil {
+		if errors.Is(err, database.ErrAnalysisNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Analysis not found")
+			return
+		}
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to delete analysis")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to delete analysis")
+		return
+	}
+	if err := thumbnailStore.Delete(c.Request.Context(), id.String()); err != nil {
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to delete analysis thumbnails")
+	}
+	if err := qctoolsStore.Delete(c.Request.Context(), id.String()); err != nil {
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to delete analysis QCTools report")
+	}
+
+	c.Status(204)
+}
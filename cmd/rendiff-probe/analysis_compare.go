@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/report"
+	"github.com/rendiffdev/rendiff-probe/internal/services"
+)
+
+// compareAnalysesRequest names the two stored analyses to compare
+type compareAnalysesRequest struct {
+	SourceID string `json:"source_id"` // e.g. the master
+	TargetID string `json:"target_id"` // e.g. a transcode of it
+}
+
+// qcCategorySummary is the compact form of a QC category sent to the LLM
+type qcCategorySummary struct {
+	Name     string            `json:"name"`
+	Severity report.Severity   `json:"severity"`
+	Fields   map[string]string `json:"fields,omitempty"`
+	Findings []string          `json:"findings,omitempty"`
+}
+
+// compareAnalysesHandler compares two stored analyses. The property
+// differences are computed directly; the LLM turns them, the raw FFprobe
+// data and the QC results of both files into a comparison report.
+func compareAnalysesHandler(c *gin.Context) {
+	var request compareAnalysesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return
+	}
+	sourceID, err := uuid.Parse(request.SourceID)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid source_id format")
+		return
+	}
+	targetID, err := uuid.Parse(request.TargetID)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid target_id format")
+		return
+	}
+	if sourceID == targetID {
+		respondError(c, 400, apierrors.CodeBadRequest, "source_id and target_id must differ")
+		return
+	}
+
+	ctx := c.Request.Context()
+	assets := make([]services.ComparedAsset, 2)
+	results := make([]*ffmpeg.FFprobeResult, 2)
+	for i, id := range []uuid.UUID{sourceID, targetID} {
+		filename, result, ok := loadComparedAnalysis(c, id)
+		if !ok {
+			return
+		}
+		results[i] = result
+		assets[i] = comparedAsset(id, filename, result)
+	}
+
+	differences := report.Diff(results[0], results[1])
+	response := gin.H{
+		"status": "success",
+		"source": gin.H{"analysis_id": sourceID, "filename": assets[0].FileName},
+		"target": gin.H{"analysis_id": targetID, "filename": assets[1].FileName},
+		// Non-nil so an identical pair encodes as [] rather than null
+		"differences": append([]report.Difference{}, differences...),
+		"timestamp":   time.Now(),
+	}
+
+	differencesJSON, _ := json.Marshal(differences)
+	comparison, err := llmService.GenerateComparisonReport(ctx, assets[0], assets[1], differencesJSON)
+	if err != nil {
+		appLogger.Warn().Err(err).Msg("LLM comparison generation failed")
+		response["llm_error"] = "LLM analysis unavailable"
+	} else {
+		response["llm_report"] = comparison.Report
+		response["llm_usage"] = comparison.Metadata
+		response["llm_enabled"] = true
+	}
+
+	c.JSON(200, response)
+}
+
+// diffAnalysesHandler returns a structured diff of two stored analyses:
+// changed container and stream parameters, added and removed streams and
+// loudness deltas, e.g. to verify that a re-encode preserved its source
+func diffAnalysesHandler(c *gin.Context) {
+	idA, err := uuid.Parse(c.Query("a"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format in a")
+		return
+	}
+	idB, err := uuid.Parse(c.Query("b"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format in b")
+		return
+	}
+
+	filenameA, resultA, ok := loadComparedAnalysis(c, idA)
+	if !ok {
+		return
+	}
+	filenameB, resultB, ok := loadComparedAnalysis(c, idB)
+	if !ok {
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"status":    "success",
+		"a":         gin.H{"analysis_id": idA, "filename": filenameA},
+		"b":         gin.H{"analysis_id": idB, "filename": filenameB},
+		"diff":      report.DiffAnalyses(resultA, resultB),
+		"timestamp": time.Now(),
+	})
+}
+
+// loadComparedAnalysis loads one side of a comparison, responding with the
+// error when it cannot be compared
+func loadComparedAnalysis(c *gin.Context, id uuid.UUID) (string, *ffmpeg.FFprobeResult, bool) {
+	filename, result, err := loadAnalysisResult(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrAnalysisNotFound):
+			apierrors.Respond(c, apierrors.New(404, apierrors.CodeNotFound, "Analysis not found").With("analysis_id", id))
+		case errors.Is(err, errAnalysisFailed):
+			apierrors.Respond(c, apierrors.New(409, apierrors.CodeConflict, "Analysis failed, there is no result to compare").With("analysis_id", id))
+		default:
+			appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to load analysis for comparison")
+			respondError(c, 500, apierrors.CodeInternalError, "Failed to load analysis")
+		}
+		return "", nil, false
+	}
+	return filename, result, true
+}
+
+// comparedAsset prepares one analysis for the LLM: the raw format and
+// streams, and the enhanced analysis summarized per QC category
+func comparedAsset(id uuid.UUID, filename string, result *ffmpeg.FFprobeResult) services.ComparedAsset {
+	ffprobeData, _ := json.Marshal(struct {
+		Format  *ffmpeg.FormatInfo  `json:"format,omitempty"`
+		Streams []ffmpeg.StreamInfo `json:"streams,omitempty"`
+	}{result.Format, result.Streams})
+
+	qc := report.Build(report.Source{AnalysisID: id.String(), Filename: filename}, result, nil)
+	categories := make([]qcCategorySummary, 0, len(qc.Categories))
+	for _, category := range qc.Categories {
+		summary := qcCategorySummary{Name: category.Name, Severity: category.Severity, Findings: category.Findings}
+		if len(category.Fields) > 0 {
+			summary.Fields = make(map[string]string, len(category.Fields))
+			for _, field := range category.Fields {
+				summary.Fields[field.Label] = field.Value
+			}
+		}
+		categories = append(categories, summary)
+	}
+	qcResults, _ := json.Marshal(categories)
+
+	return services.ComparedAsset{
+		FileName:    filename,
+		FFprobeData: ffprobeData,
+		QCResults:   qcResults,
+	}
+}
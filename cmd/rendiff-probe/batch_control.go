@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/coordination"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/webhook"
+)
+
+// batchPersistLock orders batch job saves so an older snapshot never
+// overwrites a newer one
+var batchPersistLock sync.Mutex
+
+// persistBatchJob saves a snapshot of the job so it survives a restart and
+// a resumed job knows which items are done, and shares it with the other
+// replicas. Failures are logged only. Must not be called with batchLock held.
+func persistBatchJob(job *BatchJob) {
+	if batchStore == nil && jobCoordinator == nil {
+		return
+	}
+
+	batchPersistLock.Lock()
+	defer batchPersistLock.Unlock()
+
+	batchLock.RLock()
+	state, err := json.Marshal(job)
+	record := &database.BatchJobRecord{
+		ID:        job.ID,
+		Status:    job.Status,
+		State:     state,
+		CreatedAt: job.CreatedAt,
+	}
+	batchLock.RUnlock()
+	if err != nil {
+		appLogger.Error().Err(err).Str("job_id", job.ID).Msg("Failed to encode batch job")
+		return
+	}
+
+	shareBatchJob(job.ID, state)
+	if batchStore == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), analysisSaveTimeout)
+	defer cancel()
+	if err := batchStore.Save(ctx, record); err != nil {
+		appLogger.Error().Err(err).Str("job_id", job.ID).Msg("Failed to persist batch job")
+	}
+}
+
+// loadBatchJobs restores unfinished jobs from the database. Jobs that were
+// running when the server stopped come back paused and interrupted, for
+// resumeInterruptedBatchJobs to restart; a pending pause is completed and a
+// pending cancel too. Jobs another replica is running are left to it.
+func loadBatchJobs(ctx context.Context) error {
+	records, err := batchStore.ListByStatus(ctx, "processing", "pausing", "paused", "cancelling")
+	if err != nil {
+		return err
+	}
+
+	var restored []*BatchJob
+	for _, record := range records {
+		if batchJobClaimed(ctx, record.ID) {
+			appLogger.Info().Str("job_id", record.ID).Msg("Skipping batch job running on another replica")
+			continue
+		}
+		job := &BatchJob{}
+		if err := json.Unmarshal(record.State, job); err != nil {
+			appLogger.Warn().Err(err).Str("job_id", record.ID).Msg("Skipping unreadable batch job")
+			continue
+		}
+		switch job.Status {
+		case "cancelling":
+			job.Status = "cancelled"
+		case "processing":
+			// The server stopped without checkpointing the job
+			job.Status = "paused"
+			job.Interrupted = true
+		default:
+			job.Status = "paused"
+		}
+		if job.Status == "paused" && job.isProbe() {
+			failLostUpload(job)
+		}
+		// Items that were running start over on resume
+		for i := range job.Items {
+			if !job.Items[i].Done {
+				job.Items[i].Phase = itemPhaseQueued
+				job.Items[i].PhaseProgress = 0
+				job.Items[i].Progress = 0
+			}
+		}
+		restored = append(restored, job)
+	}
+
+	batchLock.Lock()
+	for _, job := range restored {
+		batchJobs[job.ID] = job
+	}
+	batchLock.Unlock()
+
+	for _, job := range restored {
+		persistBatchJob(job)
+	}
+	if len(restored) > 0 {
+		appLogger.Info().Int("count", len(restored)).Msg("Restored batch jobs")
+	}
+	return nil
+}
+
+// batchJobFromParam resolves the :id parameter, writing the error response if
+// the job does not exist
+func batchJobFromParam(c *gin.Context) (*BatchJob, bool) {
+	jobID := c.Param("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid job ID format")
+		return nil, false
+	}
+
+	batchLock.RLock()
+	job, exists := batchJobs[jobID]
+	batchLock.RUnlock()
+
+	if !exists {
+		if shared, ok := loadSharedBatchJob(c.Request.Context(), jobID); ok && visibleBatchJob(c.Request.Context(), shared) {
+			respondError(c, 409, apierrors.CodeConflict, "Job is managed by another replica")
+			return nil, false
+		}
+		respondError(c, 404, apierrors.CodeNotFound, "Job not found")
+		return nil, false
+	}
+	if !visibleBatchJob(c.Request.Context(), job) {
+		respondError(c, 404, apierrors.CodeNotFound, "Job not found")
+		return nil, false
+	}
+	return job, true
+}
+
+// batchControlResponse describes a job after a control request
+func batchControlResponse(job *BatchJob, message string) gin.H {
+	return gin.H{
+		"job_id":     job.ID,
+		"status":     job.Status,
+		"total":      job.Total,
+		"completed":  job.Completed,
+		"failed":     job.Failed,
+		"message":    message,
+		"status_url": fmt.Sprintf("/api/v1/batch/status/%s", job.ID),
+	}
+}
+
+// cancelBatchHandler cancels a running or paused batch job. Items already
+// being analyzed are abandoned; finished results are kept.
+func cancelBatchHandler(c *gin.Context) {
+	job, ok := batchJobFromParam(c)
+	if !ok {
+		return
+	}
+
+	batchLock.Lock()
+	previous := job.Status
+	switch previous {
+	case "processing", "pausing":
+		job.Status = "cancelling"
+		job.stopStatus = "cancelled"
+		job.cancel()
+	case "paused":
+		job.Status = "cancelled"
+	default:
+		batchLock.Unlock()
+		respondError(c, 409, apierrors.CodeConflict, fmt.Sprintf("Job is %s and cannot be cancelled", previous))
+		return
+	}
+	job.UpdatedAt = time.Now()
+	progress := batchProgress(job)
+	response := batchControlResponse(job, "Batch job cancelled")
+	batchLock.Unlock()
+
+	persistBatchJob(job)
+	if previous == "paused" {
+		// Nothing is running to settle the job, so finish it here
+		sendProgressUpdate(job.ID, progress, "cancelled", "Batch job cancelled")
+		notifyBatchCallback(job, webhook.EventBatchCancelled)
+	}
+
+	appLogger.Info().Str("job_id", job.ID).Str("previous_status", previous).Msg("Batch job cancel requested")
+	c.JSON(200, response)
+}
+
+// pauseBatchHandler stops a running batch job after its in-flight items are
+// abandoned. Those items stay pending and run again on resume.
+func pauseBatchHandler(c *gin.Context) {
+	job, ok := batchJobFromParam(c)
+	if !ok {
+		return
+	}
+
+	if job.isProbe() {
+		respondError(c, 409, apierrors.CodeConflict, "Single-file probe jobs cannot be paused")
+		return
+	}
+
+	batchLock.Lock()
+	if job.Status != "processing" {
+		status := job.Status
+		batchLock.Unlock()
+		respondError(c, 409, apierrors.CodeConflict, fmt.Sprintf("Job is %s and cannot be paused", status))
+		return
+	}
+	job.Status = "pausing"
+	job.stopStatus = "paused"
+	job.UpdatedAt = time.Now()
+	job.cancel()
+	response := batchControlResponse(job, "Batch job pausing")
+	batchLock.Unlock()
+
+	persistBatchJob(job)
+	appLogger.Info().Str("job_id", job.ID).Msg("Batch job pause requested")
+	c.JSON(202, response)
+}
+
+// resumeBatchHandler restarts a paused batch job, skipping items that
+// already have a result
+func resumeBatchHandler(c *gin.Context) {
+	job, ok := batchJobFromParam(c)
+	if !ok {
+		return
+	}
+
+	release, err := claimBatchJob(job.ID)
+	if errors.Is(err, coordination.ErrLockHeld) {
+		respondError(c, 409, apierrors.CodeConflict, "Job is running on another replica")
+		return
+	}
+	if err != nil {
+		// Running without the claim beats refusing to resume
+		appLogger.Warn().Err(err).Str("job_id", job.ID).Msg("Failed to claim batch job")
+		release = func() {}
+	}
+
+	batchLock.Lock()
+	if job.Status != "paused" {
+		status := job.Status
+		batchLock.Unlock()
+		release()
+		respondError(c, 409, apierrors.CodeConflict, fmt.Sprintf("Job is %s and cannot be resumed", status))
+		return
+	}
+	restartBatchJob(job)
+	response := batchControlResponse(job, "Batch job resumed")
+	batchLock.Unlock()
+
+	persistBatchJob(job)
+	runBatchJob(job, release)
+
+	appLogger.Info().Str("job_id", job.ID).Msg("Batch job resumed")
+	c.JSON(202, response)
+}
+
+// restartBatchJob makes a paused job processing again with a new context.
+// Must be called with batchLock held.
+func restartBatchJob(job *BatchJob) {
+	job.ctx, job.cancel = context.WithCancel(batchJobContext(job.priority()))
+	job.Status = "processing"
+	job.stopStatus = ""
+	job.Interrupted = false
+	job.UpdatedAt = time.Now()
+}
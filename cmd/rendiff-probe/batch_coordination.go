@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rendiffdev/rendiff-probe/internal/config"
+	"github.com/rendiffdev/rendiff-probe/internal/coordination"
+)
+
+// Batch jobs are shared between replicas when job coordination is enabled.
+// The replica running a job holds its lock, shares a snapshot whenever the
+// job is persisted and publishes its progress; other replicas answer status
+// requests from the snapshot and relay the progress to their clients.
+
+const (
+	coordinationTimeout      = 5 * time.Second
+	batchLockTTL             = 30 * time.Second // How long a crashed replica keeps its jobs
+	batchLockRefreshInterval = 10 * time.Second
+)
+
+// initJobCoordination connects to Valkey when job coordination is enabled
+func initJobCoordination(cfg *config.Config) error {
+	if !cfg.EnableJobCoordination {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), coordinationTimeout)
+	defer cancel()
+
+	coordinator, err := coordination.New(ctx, coordination.Config{
+		Addr:      fmt.Sprintf("%s:%d", cfg.ValkeyHost, cfg.ValkeyPort),
+		Password:  cfg.ValkeyPassword,
+		DB:        cfg.ValkeyDB,
+		KeyPrefix: cfg.CoordinationKeyPrefix,
+		JobTTL:    time.Duration(cfg.CoordinationJobTTL) * time.Second,
+	}, appLogger)
+	if err != nil {
+		return err
+	}
+	jobCoordinator = coordinator
+	appLogger.Info().Str("valkey", fmt.Sprintf("%s:%d", cfg.ValkeyHost, cfg.ValkeyPort)).Msg("Job coordination enabled")
+	return nil
+}
+
+func batchLockName(jobID string) string {
+	return "batch:" + jobID
+}
+
+// shareBatchJob stores a job snapshot for the other replicas. Failures are
+// logged only.
+func shareBatchJob(jobID string, state []byte) {
+	if jobCoordinator == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), coordinationTimeout)
+	defer cancel()
+	if err := jobCoordinator.SaveJob(ctx, jobID, state); err != nil {
+		appLogger.Warn().Err(err).Str("job_id", jobID).Msg("Failed to share batch job")
+	}
+}
+
+// loadSharedBatchJob returns the snapshot of a job that another replica
+// shared, or false if there is none
+func loadSharedBatchJob(ctx context.Context, jobID string) (*BatchJob, bool) {
+	if jobCoordinator == nil {
+		return nil, false
+	}
+
+	state, err := jobCoordinator.LoadJob(ctx, jobID)
+	if err != nil {
+		if !errors.Is(err, coordination.ErrJobNotFound) {
+			appLogger.Warn().Err(err).Str("job_id", jobID).Msg("Failed to load shared batch job")
+		}
+		return nil, false
+	}
+
+	job := &BatchJob{}
+	if err := json.Unmarshal(state, job); err != nil {
+		appLogger.Warn().Err(err).Str("job_id", jobID).Msg("Skipping unreadable shared batch job")
+		return nil, false
+	}
+	return job, true
+}
+
+// isLocalBatchJob reports whether this replica runs the job
+func isLocalBatchJob(jobID string) bool {
+	batchLock.RLock()
+	defer batchLock.RUnlock()
+	_, exists := batchJobs[jobID]
+	return exists
+}
+
+// publishSharedProgress sends a progress update to the other replicas.
+// Failures are logged only.
+func publishSharedProgress(update ProgressUpdate) {
+	if jobCoordinator == nil {
+		return
+	}
+
+	event, err := json.Marshal(update)
+	if err != nil {
+		appLogger.Warn().Err(err).Str("job_id", update.JobID).Msg("Failed to encode progress update")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), coordinationTimeout)
+	defer cancel()
+	if err := jobCoordinator.PublishProgress(ctx, update.JobID, event); err != nil {
+		appLogger.Warn().Err(err).Str("job_id", update.JobID).Msg("Failed to publish progress update")
+	}
+}
+
+// subscribeSharedProgress receives the progress updates other replicas
+// publish for a job until ctx is done or the returned function is called
+func subscribeSharedProgress(ctx context.Context, jobID string) (<-chan ProgressUpdate, func(), error) {
+	if jobCoordinator == nil {
+		return nil, nil, errors.New("job coordination is disabled")
+	}
+
+	events, unsubscribe, err := jobCoordinator.SubscribeProgress(ctx, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	updates := make(chan ProgressUpdate, progressBufferSize)
+	go func() {
+		defer close(updates)
+		for event := range events {
+			var update ProgressUpdate
+			if err := json.Unmarshal(event, &update); err != nil {
+				appLogger.Debug().Err(err).Str("job_id", jobID).Msg("Skipping unreadable progress update")
+				continue
+			}
+			select {
+			case updates <- update:
+			default:
+				appLogger.Debug().Str("job_id", jobID).Msg("Dropping progress update for slow subscriber")
+			}
+		}
+	}()
+	return updates, unsubscribe, nil
+}
+
+// relaySharedProgress forwards the progress of a job that another replica
+// runs to a WebSocket client until ctx is done. It reports whether the job
+// was found.
+func relaySharedProgress(ctx context.Context, jobID string, conn *websocket.Conn) bool {
+	if jobCoordinator == nil {
+		return false
+	}
+
+	// Subscribe before reading the snapshot so no terminal update is missed
+	updates, unsubscribe, err := subscribeSharedProgress(ctx, jobID)
+	if err != nil {
+		appLogger.Warn().Err(err).Str("job_id", jobID).Msg("Failed to subscribe to shared progress")
+		return false
+	}
+	job, exists := loadSharedBatchJob(ctx, jobID)
+	if !exists {
+		unsubscribe()
+		return false
+	}
+
+	writeWebSocketUpdate(conn, ProgressUpdate{
+		Type:      "progress",
+		JobID:     jobID,
+		Progress:  batchProgress(job),
+		Message:   "Connected to progress stream",
+		Status:    job.Status,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+	go func() {
+		defer unsubscribe()
+		for update := range updates {
+			writeWebSocketUpdate(conn, update)
+		}
+	}()
+	return true
+}
+
+// claimBatchJob takes the job's lock so no other replica runs it, and keeps
+// the lock until the returned function is called. It returns
+// coordination.ErrLockHeld when another replica runs the job. Without
+// coordination every job belongs to this replica.
+func claimBatchJob(jobID string) (func(), error) {
+	if jobCoordinator == nil {
+		return func() {}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), coordinationTimeout)
+	lock, err := jobCoordinator.AcquireLock(ctx, batchLockName(jobID), batchLockTTL)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(batchLockRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), coordinationTimeout)
+				err := lock.Refresh(ctx)
+				cancel()
+				if err != nil {
+					appLogger.Warn().Err(err).Str("job_id", jobID).Msg("Failed to refresh batch job lock")
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			ctx, cancel := context.WithTimeout(context.Background(), coordinationTimeout)
+			defer cancel()
+			if err := lock.Release(ctx); err != nil && !errors.Is(err, coordination.ErrLockLost) {
+				appLogger.Warn().Err(err).Str("job_id", jobID).Msg("Failed to release batch job lock")
+			}
+		})
+	}, nil
+}
+
+// claimNewBatchJob claims a job that was just created. No other replica can
+// know its ID yet, so a coordination failure only costs the protection.
+func claimNewBatchJob(jobID string) func() {
+	release, err := claimBatchJob(jobID)
+	if err != nil {
+		appLogger.Warn().Err(err).Str("job_id", jobID).Msg("Failed to claim batch job")
+		return func() {}
+	}
+	return release
+}
+
+// batchJobClaimed reports whether a replica currently runs the job
+func batchJobClaimed(ctx context.Context, jobID string) bool {
+	if jobCoordinator == nil {
+		return false
+	}
+
+	locked, err := jobCoordinator.Locked(ctx, batchLockName(jobID))
+	if err != nil {
+		appLogger.Warn().Err(err).Str("job_id", jobID).Msg("Failed to check batch job lock")
+		return false
+	}
+	return locked
+}
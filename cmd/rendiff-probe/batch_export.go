@@ -0,0 +1,284 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/report"
+	"github.com/rendiffdev/rendiff-probe/internal/validator"
+)
+
+// Batch export archive formats
+const (
+	batchExportZip   = "zip"
+	batchExportTarGz = "tar.gz"
+)
+
+// batchExportItem is one recorded batch result as decoded for export
+type batchExportItem struct {
+	Type     string                `json:"type"`
+	Path     string                `json:"path,omitempty"`
+	URL      string                `json:"url,omitempty"`
+	Filename string                `json:"filename,omitempty"`
+	Status   string                `json:"status"`
+	Error    string                `json:"error,omitempty"`
+	Analysis *ffmpeg.FFprobeResult `json:"analysis,omitempty"`
+}
+
+// name is the file name of the item's input
+func (item batchExportItem) name() string {
+	switch {
+	case item.Filename != "":
+		return item.Filename
+	case item.Path != "":
+		return filepath.Base(item.Path)
+	case item.URL != "":
+		return extractFilename(item.URL, "")
+	}
+	return ""
+}
+
+// batchExportEntry describes an item in the archive manifest
+type batchExportEntry struct {
+	Directory string   `json:"directory"`
+	Type      string   `json:"type"`
+	Input     string   `json:"input"`
+	Status    string   `json:"status"`
+	Error     string   `json:"error,omitempty"`
+	Files     []string `json:"files"`
+}
+
+// batchExportManifest is manifest.json at the root of the archive
+type batchExportManifest struct {
+	JobID      string             `json:"job_id"`
+	Status     string             `json:"status"`
+	Total      int                `json:"total"`
+	Completed  int                `json:"completed"`
+	Failed     int                `json:"failed"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Items      []batchExportEntry `json:"items"`
+}
+
+// archiveWriter adds files to a zip or tar.gz archive
+type archiveWriter interface {
+	add(name string, data []byte, modified time.Time) error
+	Close() error
+}
+
+type zipArchive struct {
+	w *zip.Writer
+}
+
+func (a *zipArchive) add(name string, data []byte, modified time.Time) error {
+	w, err := a.w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func (a *zipArchive) Close() error {
+	return a.w.Close()
+}
+
+type tarGzArchive struct {
+	gz *gzip.Writer
+	w  *tar.Writer
+}
+
+func (a *tarGzArchive) add(name string, data []byte, modified time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: modified, Typeflag: tar.TypeReg}
+	if err := a.w.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := a.w.Write(data)
+	return err
+}
+
+func (a *tarGzArchive) Close() error {
+	if err := a.w.Close(); err != nil {
+		return err
+	}
+	return a.gz.Close()
+}
+
+// newArchiveWriter writes an archive of format to w
+func newArchiveWriter(w io.Writer, format string) archiveWriter {
+	if format == batchExportTarGz {
+		gz := gzip.NewWriter(w)
+		return &tarGzArchive{gz: gz, w: tar.NewWriter(gz)}
+	}
+	return &zipArchive{w: zip.NewWriter(w)}
+}
+
+// batchExportHandler bundles the results of a batch job into one archive:
+// a manifest, a CSV of every item and, per item, its JSON result, its QC
+// report and the events found in it
+func batchExportHandler(c *gin.Context) {
+	jobID := c.Param("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid job ID format")
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", batchExportZip))
+	if format != batchExportZip && format != batchExportTarGz {
+		respondError(c, 400, apierrors.CodeBadRequest, fmt.Sprintf("unsupported archive format %q (use zip or tar.gz)", format))
+		return
+	}
+	reportFormat, err := report.ParseFormat(c.Query("report"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	batchLock.RLock()
+	job, exists := batchJobs[jobID]
+	batchLock.RUnlock()
+	if !exists {
+		// Another replica may be running the job
+		if job, exists = loadSharedBatchJob(c.Request.Context(), jobID); !exists {
+			respondError(c, 404, apierrors.CodeNotFound, "Job not found")
+			return
+		}
+	}
+	if !visibleBatchJob(c.Request.Context(), job) {
+		respondError(c, 404, apierrors.CodeNotFound, "Job not found")
+		return
+	}
+
+	batchLock.RLock()
+	manifest := batchExportManifest{
+		JobID:      job.ID,
+		Status:     job.Status,
+		Total:      job.Total,
+		Completed:  job.Completed,
+		Failed:     job.Failed,
+		CreatedAt:  job.CreatedAt,
+		UpdatedAt:  job.UpdatedAt,
+		ExportedAt: time.Now().UTC(),
+	}
+	results, err := json.Marshal(job.Results)
+	batchLock.RUnlock()
+	if err != nil {
+		appLogger.Error().Err(err).Str("job_id", jobID).Msg("Failed to encode batch results for export")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to export batch results")
+		return
+	}
+
+	var raw []json.RawMessage
+	var items []batchExportItem
+	if err := json.Unmarshal(results, &raw); err == nil {
+		err = json.Unmarshal(results, &items)
+	}
+	if err != nil {
+		appLogger.Error().Err(err).Str("job_id", jobID).Msg("Failed to decode batch results for export")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to export batch results")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="batch-%s.%s"`, jobID, format))
+	c.Header("Cache-Control", "no-store")
+	contentType := "application/zip"
+	if format == batchExportTarGz {
+		contentType = "application/gzip"
+	}
+	c.Header("Content-Type", contentType)
+	c.Status(200)
+
+	archive := newArchiveWriter(c.Writer, format)
+	if err := writeBatchExport(archive, manifest, raw, items, reportFormat); err != nil {
+		// The response has started, so the archive is left truncated
+		appLogger.Warn().Err(err).Str("job_id", jobID).Msg("Batch export interrupted")
+		return
+	}
+	if err := archive.Close(); err != nil {
+		appLogger.Warn().Err(err).Str("job_id", jobID).Msg("Batch export interrupted")
+	}
+}
+
+// writeBatchExport adds the items of a batch to archive, in completion order,
+// followed by the manifest and results.csv. Files that cannot be rendered are
+// left out and only write errors are returned.
+func writeBatchExport(archive archiveWriter, manifest batchExportManifest, raw []json.RawMessage, items []batchExportItem, reportFormat report.Format) error {
+	modified := manifest.ExportedAt
+	flat := make([]report.FlatResult, 0, len(items))
+	manifest.Items = make([]batchExportEntry, 0, len(items))
+
+	for i, item := range items {
+		name := item.name()
+		directory := fmt.Sprintf("items/%03d-%s", i+1, validator.SanitizeFilename(name))
+		entry := batchExportEntry{Directory: directory, Type: item.Type, Input: item.Path, Status: item.Status, Error: item.Error}
+		if entry.Input == "" {
+			entry.Input = item.URL
+		}
+		source := report.Source{Filename: name, AnalyzedAt: manifest.UpdatedAt}
+
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, raw[i], "", "  "); err == nil {
+			if err := archive.add(directory+"/result.json", indented.Bytes(), modified); err != nil {
+				return err
+			}
+			entry.Files = append(entry.Files, "result.json")
+		}
+
+		var r *report.Report
+		if item.Analysis != nil {
+			r = report.Build(source, item.Analysis, nil)
+		} else {
+			r = report.Failed(source, item.Error)
+		}
+		var buf bytes.Buffer
+		if err := report.Render(&buf, reportFormat, r); err != nil {
+			appLogger.Warn().Err(err).Str("job_id", manifest.JobID).Str("item", directory).Msg("Failed to render batch item report")
+		} else {
+			file := "report." + string(reportFormat)
+			if err := archive.add(directory+"/"+file, buf.Bytes(), modified); err != nil {
+				return err
+			}
+			entry.Files = append(entry.Files, file)
+		}
+
+		if item.Analysis != nil {
+			buf.Reset()
+			if err := report.RenderMarkers(&buf, report.MarkerFormatCSV, name, item.Analysis); err != nil {
+				appLogger.Warn().Err(err).Str("job_id", manifest.JobID).Str("item", directory).Msg("Failed to render batch item events")
+			} else {
+				if err := archive.add(directory+"/events.csv", buf.Bytes(), modified); err != nil {
+					return err
+				}
+				entry.Files = append(entry.Files, "events.csv")
+			}
+		}
+
+		manifest.Items = append(manifest.Items, entry)
+		flat = append(flat, report.FlatResult{Source: source, Result: item.Analysis, Error: item.Error})
+	}
+
+	var buf bytes.Buffer
+	if err := report.RenderFlat(&buf, report.FormatCSV, flat...); err == nil {
+		if err := archive.add("results.csv", buf.Bytes(), modified); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return archive.add("manifest.json", data, modified)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// Batch item phases. Probe and content analysis use the ffmpeg phase names.
+const (
+	itemPhaseQueued    = "queued"
+	itemPhaseDownload  = "download"
+	itemPhaseLLM       = "llm"
+	itemPhaseCompleted = "completed"
+	itemPhaseFailed    = "failed"
+)
+
+// ItemProgress reports the phase of one batch item
+type ItemProgress struct {
+	Index         int     `json:"index"`
+	Input         string  `json:"input"`
+	Phase         string  `json:"phase"`
+	PhaseProgress float64 `json:"phase_progress"`
+	Progress      float64 `json:"progress"`
+}
+
+// batchItemPhases lists the phases an item of the job goes through, in order
+func batchItemPhases(job *BatchJob, item *BatchItem) []string {
+	phases := make([]string, 0, 4)
+	if item.Type == "url" {
+		phases = append(phases, itemPhaseDownload)
+	}
+	phases = append(phases, ffmpeg.PhaseProbe, ffmpeg.PhaseContentAnalysis)
+	if job.IncludeLLM {
+		phases = append(phases, itemPhaseLLM)
+	}
+	return phases
+}
+
+// batchItemProgress weights each of the item's phases equally. PSE
+// analysis runs within content analysis and stands for its progress, being
+// most of it. Must be called with batchLock held.
+func batchItemProgress(job *BatchJob, item *BatchItem) float64 {
+	if item.Done {
+		return 100
+	}
+	current := item.Phase
+	if current == ffmpeg.PhasePSE {
+		current = ffmpeg.PhaseContentAnalysis
+	}
+	phases := batchItemPhases(job, item)
+	for i, phase := range phases {
+		if phase == current {
+			return (float64(i) + item.PhaseProgress/100) / float64(len(phases)) * 100
+		}
+	}
+	return 0
+}
+
+// batchProgress is the job's completion percentage including the progress
+// of items still running. Must be called with batchLock held.
+func batchProgress(job *BatchJob) float64 {
+	if job.Total == 0 {
+		return 0
+	}
+	if len(job.Items) == 0 {
+		return float64(job.Completed+job.Failed) / float64(job.Total) * 100
+	}
+	var sum float64
+	for i := range job.Items {
+		sum += job.Items[i].Progress
+	}
+	return sum / float64(job.Total)
+}
+
+// itemProgressUpdate snapshots an item for a progress message. Must be
+// called with batchLock held.
+func itemProgressUpdate(index int, item *BatchItem) *ItemProgress {
+	return &ItemProgress{
+		Index:         index,
+		Input:         item.Input,
+		Phase:         item.Phase,
+		PhaseProgress: item.PhaseProgress,
+		Progress:      item.Progress,
+	}
+}
+
+// updateBatchItemPhase records an item's phase and percentage and sends a
+// progress update
+func updateBatchItemPhase(job *BatchJob, index int, phase string, percent float64) {
+	batchLock.Lock()
+	item := &job.Items[index]
+	item.Phase = phase
+	item.PhaseProgress = percent
+	item.Progress = batchItemProgress(job, item)
+	progress := batchProgress(job)
+	update := itemProgressUpdate(index, item)
+	status := job.Status
+	batchLock.Unlock()
+
+	message := fmt.Sprintf("%s %.0f%%: %s", phase, percent, filepath.Base(update.Input))
+	sendItemProgressUpdate(job.ID, progress, status, message, update)
+}
+
+// batchItemPhaseFunc adapts updateBatchItemPhase for ffmpeg.FFprobeOptions.OnPhase
+func batchItemPhaseFunc(job *BatchJob, index int) ffmpeg.PhaseFunc {
+	return func(phase string, percent float64) {
+		updateBatchItemPhase(job, index, phase, percent)
+	}
+}
+
+// requeueBatchItem resets an item interrupted by a pause or cancel so it
+// reports as pending
+func requeueBatchItem(job *BatchJob, index int) {
+	batchLock.Lock()
+	item := &job.Items[index]
+	item.Phase = itemPhaseQueued
+	item.PhaseProgress = 0
+	item.Progress = 0
+	batchLock.Unlock()
+}
+
+// progressReader reports whole-percent steps of a read of known length
+type progressReader struct {
+	reader  io.Reader
+	total   int64
+	read    int64
+	percent int
+	report  func(percent float64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.total > 0 {
+		if percent := int(min(r.read*100/r.total, 99)); percent > r.percent {
+			r.percent = percent
+			r.report(float64(percent))
+		}
+	}
+	return n, err
+}
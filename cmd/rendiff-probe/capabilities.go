@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rendiffdev/rendiff-probe/internal/config"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// GET /api/v1/capabilities tells clients what this deployment can do, so
+// they can hide options it lacks instead of learning from failed requests.
+// Codecs and filters are detected once at startup: rebuilding ffmpeg
+// requires a restart anyway.
+
+var (
+	// ffprobeVersion is the version ffprobe reported at startup
+	ffprobeVersion string
+	// ffmpegCapabilities is nil when ffmpeg could not be run at startup
+	ffmpegCapabilities *ffmpeg.Capabilities
+	// pluginNames are the analyzer plugins loaded from PLUGIN_DIR
+	pluginNames []string
+)
+
+// detectCapabilities records the ffprobe version and the codecs and
+// filters of ffmpeg. Analyses that need ffmpeg fail on their own when it is
+// missing, so this only warns.
+func detectCapabilities(ctx context.Context, cfg *config.Config) {
+	version, err := ffmpeg.BinaryVersion(ctx, cfg.FFprobePath)
+	if err != nil {
+		appLogger.Warn().Err(err).Str("ffprobe_path", cfg.FFprobePath).Msg("Failed to read ffprobe version")
+	}
+	ffprobeVersion = version
+
+	ffmpegCapabilities, err = ffmpeg.DetectCapabilities(ctx, cfg.FFmpegPath)
+	if err != nil {
+		appLogger.Warn().Err(err).Str("ffmpeg_path", cfg.FFmpegPath).Msg("Failed to detect ffmpeg capabilities")
+		return
+	}
+	appLogger.Info().
+		Str("version", ffmpegCapabilities.Version).
+		Int("codecs", len(ffmpegCapabilities.Codecs)).
+		Int("filters", len(ffmpegCapabilities.Filters)).
+		Msg("FFmpeg capabilities detected")
+}
+
+// capabilitiesHandler returns the tool versions, analyzers, codecs,
+// filters, limits and features of this server
+func capabilitiesHandler(c *gin.Context) {
+	ffmpegInfo := gin.H{"path": appConfig.FFmpegPath, "detected": ffmpegCapabilities != nil}
+	if ffmpegCapabilities != nil {
+		ffmpegInfo["version"] = ffmpegCapabilities.Version
+		ffmpegInfo["codecs"] = ffmpegCapabilities.Codecs
+		ffmpegInfo["filters"] = ffmpegCapabilities.Filters
+	}
+
+	profiles := ffmpeg.DeliveryProfiles()
+	profileIDs := make([]string, len(profiles))
+	for i, profile := range profiles {
+		profileIDs[i] = profile.ID
+	}
+
+	plugins := pluginNames
+	if plugins == nil {
+		plugins = []string{}
+	}
+
+	c.JSON(200, gin.H{
+		"service": "rendiff-probe",
+		"version": serviceVersion,
+		"ffprobe": gin.H{"path": appConfig.FFprobePath, "version": ffprobeVersion},
+		"ffmpeg":  ffmpegInfo,
+		"analyzers": gin.H{
+			"qc_categories":         ffmpeg.AllQCCategories,
+			"partial_qc_categories": ffmpeg.PartialQCCategories,
+			"probe_modes":           []string{probeModeFull, probeModeExpress, probeModeDeep},
+			"delivery_profiles":     profileIDs,
+			"quality_metrics":       availableQualityMetrics(),
+			"checksums":             ffmpeg.ChecksumAlgorithms,
+			"mediainfo":             appConfig.MediaInfoPath != "",
+			"plugins":               plugins,
+		},
+		"limits": gin.H{
+			"max_file_size_bytes":        maxFileSize(),
+			"max_request_body_bytes":     maxRequestBodyMB * 1024 * 1024,
+			"max_batch_items":            maxBatchItems(),
+			"max_upload_chunk_bytes":     maxUploadChunkSize,
+			"max_timeout_seconds":        int(maxTimeout() / time.Second),
+			"upload_session_ttl_seconds": appConfig.UploadSessionTTL,
+			"direct_upload_ttl_seconds":  appConfig.DirectUploadTTL,
+			"job_disk_quota_bytes":       appConfig.JobDiskQuota,
+			"disk_quota_bytes":           appConfig.DiskQuota,
+		},
+		"features": enabledFeatures(),
+	})
+}
+
+// availableQualityMetrics lists the comparison metrics ffmpeg can compute;
+// VMAF needs a build with libvmaf. All are listed if ffmpeg was not
+// detected.
+func availableQualityMetrics() []ffmpeg.QualityMetric {
+	metrics := make([]ffmpeg.QualityMetric, 0, len(ffmpeg.AllQualityMetrics))
+	for _, metric := range ffmpeg.AllQualityMetrics {
+		if metric == ffmpeg.QualityMetricVMAF && ffmpegCapabilities != nil && !ffmpegCapabilities.HasFilter("libvmaf") {
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// Checksums are computed on request while the analyzed file is on disk and
+// stored with the result, so a manifest can be verified against them later
+// without the file.
+
+// checksumCalculator hashes analyzed files and their stream essence
+var checksumCalculator *ffmpeg.ChecksumCalculator
+
+// addChecksums adds the requested checksums of path to result. Failures
+// are logged and leave the result without checksums.
+func addChecksums(ctx context.Context, path string, result *ffmpeg.FFprobeResult, algorithms []string) {
+	if len(algorithms) == 0 || result == nil {
+		return
+	}
+	checksums, err := checksumCalculator.Compute(ctx, path, result.Streams, algorithms)
+	if err != nil {
+		appLogger.Warn().Err(err).Msg("Failed to compute checksums")
+		return
+	}
+	result.Checksums = checksums
+}
+
+// verifyChecksumsHandler compares a manifest of expected checksums with
+// those stored with an analysis
+func verifyChecksumsHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format")
+		return
+	}
+
+	var manifest ffmpeg.ChecksumManifest
+	if err := c.ShouldBindJSON(&manifest); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return
+	}
+	if len(manifest.File) == 0 && len(manifest.Streams) == 0 {
+		respondError(c, 400, apierrors.CodeBadRequest, "Manifest lists no checksums")
+		return
+	}
+
+	stored, _, err := loadStoredAnalysis(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrAnalysisNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Analysis not found")
+			return
+		}
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to load analysis for checksum verification")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to load analysis")
+		return
+	}
+	if stored.result == nil {
+		respondError(c, 409, apierrors.CodeConflict, "Analysis failed and has no checksums")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"analysis_id":  id.String(),
+		"filename":     stored.source.Filename,
+		"verification": ffmpeg.VerifyChecksums(stored.result.Checksums, &manifest),
+	})
+}
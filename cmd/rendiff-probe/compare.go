@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/validator"
+)
+
+// compareRequest is the JSON body accepted by the compare endpoint. Multipart
+// requests send the same options as form fields next to the two files.
+type compareRequest struct {
+	ReferenceURL  string   `json:"reference_url"`
+	DistortedURL  string   `json:"distorted_url"`
+	Metrics       []string `json:"metrics"`        // vmaf, psnr, ssim (default: all)
+	VMAFModel     string   `json:"vmaf_model"`     // built-in libvmaf model name
+	Subsample     int      `json:"subsample"`      // score every Nth frame for VMAF
+	IncludeFrames *bool    `json:"include_frames"` // per-frame scores (default: true)
+	Timeout       int      `json:"timeout"`        // seconds, capped at MAX_ANALYSIS_TIMEOUT
+}
+
+// compareHandler scores a distorted encode against its reference with
+// VMAF, PSNR and SSIM. Inputs are either two uploaded files ("reference" and
+// "distorted" multipart fields) or two URLs in a JSON body.
+func compareHandler(c *gin.Context) {
+	var request compareRequest
+	multipart := strings.HasPrefix(c.GetHeader("Content-Type"), "multipart/form-data")
+
+	if multipart {
+		request.Metrics = strings.Split(c.PostForm("metrics"), ",")
+		request.VMAFModel = c.PostForm("vmaf_model")
+		if value := c.PostForm("subsample"); value != "" {
+			subsample, err := strconv.Atoi(value)
+			if err != nil {
+				respondError(c, 400, apierrors.CodeBadRequest, "Invalid subsample")
+				return
+			}
+			request.Subsample = subsample
+		}
+		if value := c.PostForm("include_frames"); value != "" {
+			includeFrames := value == "true"
+			request.IncludeFrames = &includeFrames
+		}
+	} else {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+			return
+		}
+		if request.ReferenceURL == "" || request.DistortedURL == "" {
+			respondError(c, 400, apierrors.CodeBadRequest, "reference_url and distorted_url are required")
+			return
+		}
+	}
+
+	metrics, err := ffmpeg.ParseQualityMetrics(request.Metrics)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+	if err := ffmpeg.ValidateVMAFModel(request.VMAFModel); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+	if request.Subsample < 0 || request.Subsample > maxCompareSubsample {
+		respondError(c, 400, apierrors.CodeBadRequest, fmt.Sprintf("subsample must be between 1 and %d", maxCompareSubsample))
+		return
+	}
+
+	timeout := defaultCompareTimeout
+	if request.Timeout > 0 {
+		timeout = time.Duration(request.Timeout) * time.Second
+		if timeout > maxTimeout() {
+			timeout = maxTimeout()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	var referencePath, distortedPath, referenceName, distortedName string
+	if multipart {
+		var ok bool
+		if referencePath, referenceName, ok = saveCompareFile(c, "reference"); !ok {
+			return
+		}
+		defer removeTempFile(referencePath)
+		if distortedPath, distortedName, ok = saveCompareFile(c, "distorted"); !ok {
+			return
+		}
+		defer removeTempFile(distortedPath)
+	} else {
+		// Validate both URLs before downloading either (SSRF prevention)
+		for _, u := range []string{request.ReferenceURL, request.DistortedURL} {
+			if err := validator.ValidateURL(u); err != nil {
+				appLogger.Warn().Str("url", u).Err(err).Msg("URL validation failed")
+				respondError(c, 400, apierrors.CodeURLBlocked, "Invalid or blocked URL")
+				return
+			}
+		}
+
+		referencePath, referenceName, err = downloadURL(ctx, request.ReferenceURL)
+		if err != nil {
+			appLogger.Warn().Err(err).Str("url", request.ReferenceURL).Msg("Reference download failed")
+			apierrors.Respond(c, storageFailure(err, apierrors.CodeDownloadFailed, "Failed to download reference"))
+			return
+		}
+		defer removeTempFile(referencePath)
+
+		distortedPath, distortedName, err = downloadURL(ctx, request.DistortedURL)
+		if err != nil {
+			appLogger.Warn().Err(err).Str("url", request.DistortedURL).Msg("Distorted download failed")
+			apierrors.Respond(c, storageFailure(err, apierrors.CodeDownloadFailed, "Failed to download distorted file"))
+			return
+		}
+		defer removeTempFile(distortedPath)
+	}
+
+	startTime := time.Now()
+	comparison, err := qualityComparator.Compare(ctx, referencePath, distortedPath, ffmpeg.QualityCompareOptions{
+		Metrics:   metrics,
+		VMAFModel: request.VMAFModel,
+		Subsample: request.Subsample,
+	})
+	if err != nil {
+		if errors.Is(err, ffmpeg.ErrVMAFUnavailable) {
+			respondError(c, 501, apierrors.CodeNotImplemented, "VMAF is not available on this server, request psnr and ssim only")
+			return
+		}
+		appLogger.Error().Err(err).Str("reference", referenceName).Str("distorted", distortedName).Msg("Quality comparison failed")
+		respondError(c, 500, apierrors.CodeInternalError, "Quality comparison failed")
+		return
+	}
+
+	if request.IncludeFrames != nil && !*request.IncludeFrames {
+		comparison.Frames = nil
+	}
+
+	c.JSON(200, gin.H{
+		"status":          "success",
+		"comparison_id":   uuid.New().String(),
+		"reference":       referenceName,
+		"distorted":       distortedName,
+		"comparison":      comparison,
+		"processing_time": time.Since(startTime).String(),
+		"timestamp":       time.Now(),
+	})
+}
+
+// saveCompareFile copies a multipart file field to a temp file, writing an
+// error response on failure
+func saveCompareFile(c *gin.Context, field string) (string, string, bool) {
+	_, header, err := c.Request.FormFile(field)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, fmt.Sprintf("No %s file provided", field))
+		return "", "", false
+	}
+	return saveFormFile(c, header, field)
+}
+
+// saveFormFile copies an uploaded file to a temp file, writing an error
+// response on failure. field names the file when its own name is unusable.
+func saveFormFile(c *gin.Context, header *multipart.FileHeader, field string) (string, string, bool) {
+	file, err := header.Open()
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, fmt.Sprintf("Unreadable %s file", field))
+		return "", "", false
+	}
+	defer file.Close()
+
+	maxSize := maxFileSize()
+	if header.Size > maxSize {
+		apierrors.Respond(c, apierrors.New(413, apierrors.CodeFileTooLarge, "File too large").With("max_size_bytes", maxSize))
+		return "", "", false
+	}
+
+	safeFilename := validator.SanitizeFilename(header.Filename)
+	if safeFilename == "" {
+		safeFilename = fmt.Sprintf("%s_%s", field, uuid.New().String()[:8])
+	}
+
+	tempFile, err := workspaceManager.Create(safeFilename)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Failed to create temporary file")
+		apierrors.Respond(c, storageFailure(err, apierrors.CodeInternalError, "Failed to process file"))
+		return "", "", false
+	}
+	defer tempFile.Close()
+	tempPath := tempFile.Name()
+
+	written, err := io.CopyN(tempFile, file, maxSize+1)
+	if (err != nil && err != io.EOF) || written > maxSize {
+		removeTempFile(tempPath)
+		if written > maxSize {
+			apierrors.Respond(c, apierrors.New(413, apierrors.CodeFileTooLarge, "File too large").With("max_size_bytes", maxSize))
+		} else {
+			appLogger.Error().Err(err).Msg("Failed to save uploaded file")
+			apierrors.Respond(c, storageFailure(err, apierrors.CodeInternalError, "Failed to process file"))
+		}
+		return "", "", false
+	}
+
+	return tempPath, safeFilename, true
+}
+
+// removeTempFile deletes a temp file, logging failures
+func removeTempFile(path string) {
+	if err := workspaceManager.Remove(path); err != nil {
+		appLogger.Warn().Err(err).Str("path", path).Msg("Failed to cleanup temp file")
+	}
+}
@@ -0,0 +1,429 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/config"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/storage"
+	"github.com/rendiffdev/rendiff-probe/internal/validator"
+)
+
+// Direct uploads let a browser PUT a file straight into the object store
+// named by STORAGE_PROVIDER (s3, gcs or azure) with a presigned URL, so the
+// bytes never pass through this server. The replica that issued the URL
+// polls for the object and analyzes it as an async probe job owned by the
+// tenant that asked for the URL. Only the first upload is analyzed; objects
+// uploaded again with the same URL are deleted when it expires.
+
+const (
+	directUploadPollInterval  = 5 * time.Second
+	directUploadCheckTimeout  = 10 * time.Second
+	directUploadKeyPrefix     = "direct-uploads/"
+	maxPendingDirectUploads   = 1000
+	directUploadPendingStatus = "pending"
+	directUploadExpiredStatus = "expired"
+)
+
+var (
+	// directUploadStorage is nil unless an object store is configured
+	directUploadStorage storage.Provider
+
+	directUploads     = make(map[string]*directUpload)
+	directUploadsLock sync.Mutex
+)
+
+// directUpload is an issued upload URL. jobID, expired and settled change
+// under directUploadsLock.
+type directUpload struct {
+	id         string
+	analysisID string
+	filename   string
+	size       int64
+	key        string
+	scope      database.Scope
+	options    *fileProbeOptions
+	expiresAt  time.Time
+
+	// jobID is the probe job started once the object arrived
+	jobID string
+	// expired is set when the URL expired before anything was uploaded
+	expired bool
+	// settled is set once the object key was cleared after expiry
+	settled bool
+}
+
+// directUploadStorageConfig maps the storage settings to the provider's,
+// returning false for providers browsers cannot upload to
+func directUploadStorageConfig(cfg *config.Config) (storage.Config, bool) {
+	storageConfig := storage.Config{
+		Provider: cfg.StorageProvider,
+		Region:   cfg.StorageRegion,
+		Bucket:   cfg.StorageBucket,
+		Endpoint: cfg.StorageEndpoint,
+		UseSSL:   cfg.StorageUseSSL,
+		BaseURL:  cfg.StorageBaseURL,
+	}
+	switch cfg.StorageProvider {
+	case "s3":
+		storageConfig.AccessKey = cfg.AWSAccessKeyID
+		storageConfig.SecretKey = cfg.AWSSecretAccessKey
+		storageConfig.Region = cfg.AWSRegion
+	case "gcs":
+		storageConfig.AccessKey = cfg.GCPServiceAccount
+	case "azure":
+		storageConfig.AccessKey = cfg.AzureStorageAccount
+		storageConfig.SecretKey = cfg.AzureStorageKey
+	default:
+		return storageConfig, false
+	}
+	return storageConfig, true
+}
+
+// startDirectUploads connects to the object store and starts polling for
+// uploads. It does nothing for local storage.
+func startDirectUploads(cfg *config.Config) error {
+	storageConfig, ok := directUploadStorageConfig(cfg)
+	if !ok {
+		return nil
+	}
+	provider, err := storage.NewProvider(storageConfig)
+	if err != nil {
+		return err
+	}
+	directUploadStorage = provider
+	go watchDirectUploads()
+
+	appLogger.Info().
+		Str("provider", cfg.StorageProvider).
+		Str("bucket", cfg.StorageBucket).
+		Int("ttl_seconds", cfg.DirectUploadTTL).
+		Msg("Direct browser uploads enabled")
+	return nil
+}
+
+// directUploadRequest is the JSON body of a direct upload: the file and the
+// options of its analysis
+type directUploadRequest struct {
+	Filename string `json:"filename" binding:"required"`
+	Size     int64  `json:"size" binding:"required"`
+	fileProbeRequest
+}
+
+// createDirectUploadHandler issues a presigned URL the caller's browser
+// uploads one file to. The analysis options are those of an upload
+// completion and apply once the file has arrived.
+func createDirectUploadHandler(c *gin.Context) {
+	if directUploadStorage == nil {
+		respondError(c, 503, apierrors.CodeFeatureDisabled, "Direct uploads are disabled (STORAGE_PROVIDER is not s3, gcs or azure)")
+		return
+	}
+
+	var request directUploadRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return
+	}
+	if request.Size <= 0 {
+		respondError(c, 400, apierrors.CodeBadRequest, "Size must be greater than 0")
+		return
+	}
+	if request.Size > maxFileSize() {
+		apierrors.Respond(c, apierrors.New(413, apierrors.CodeFileTooLarge, "File too large").With("max_size_bytes", maxFileSize()))
+		return
+	}
+	options, err := request.fileProbeRequest.validate(c.Request.Context())
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	safeFilename := validator.SanitizeFilename(request.Filename)
+	if safeFilename == "" {
+		safeFilename = fmt.Sprintf("upload_%s", uuid.New().String()[:8])
+	}
+
+	id := uuid.New().String()
+	ttl := time.Duration(appConfig.DirectUploadTTL) * time.Second
+	upload := &directUpload{
+		id:         id,
+		analysisID: uuid.New().String(),
+		filename:   safeFilename,
+		size:       request.Size,
+		key:        directUploadKeyPrefix + id + "/" + safeFilename,
+		scope:      database.ScopeFromContext(c.Request.Context()),
+		options:    options,
+		expiresAt:  time.Now().Add(ttl),
+	}
+
+	directUploadsLock.Lock()
+	pending := 0
+	for _, other := range directUploads {
+		if other.jobID == "" && !other.expired {
+			pending++
+		}
+	}
+	if pending >= maxPendingDirectUploads {
+		directUploadsLock.Unlock()
+		respondError(c, 429, apierrors.CodeTooManyRequests, "Too many pending uploads, try again later")
+		return
+	}
+	// Hold the slot while the URL is signed, so concurrent requests count it
+	directUploads[id] = upload
+	directUploadsLock.Unlock()
+
+	signed, err := directUploadStorage.GetSignedUploadURL(c.Request.Context(), upload.key, upload.size, int64(ttl/time.Second))
+	if err != nil {
+		directUploadsLock.Lock()
+		delete(directUploads, id)
+		directUploadsLock.Unlock()
+		appLogger.Error().Err(err).Msg("Failed to sign direct upload URL")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to create upload URL")
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"upload_id":   id,
+		"analysis_id": upload.analysisID,
+		"filename":    upload.filename,
+		"size":        upload.size,
+		"upload_url":  signed.URL,
+		"method":      signed.Method,
+		"headers":     signed.Headers,
+		"expires_at":  upload.expiresAt,
+		"status_url":  fmt.Sprintf("/api/v1/direct-uploads/%s", id),
+	})
+}
+
+// directUploadStatusHandler reports whether the file has arrived and, once
+// it has, the job analyzing it
+func directUploadStatusHandler(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid upload ID format")
+		return
+	}
+
+	directUploadsLock.Lock()
+	upload, exists := directUploads[id]
+	var jobID string
+	var expired bool
+	if exists {
+		jobID, expired = upload.jobID, upload.expired
+	}
+	directUploadsLock.Unlock()
+	if !exists || !database.ScopeFromContext(c.Request.Context()).Contains(upload.scope) {
+		respondError(c, 404, apierrors.CodeNotFound, "Upload not found")
+		return
+	}
+
+	response := gin.H{
+		"upload_id":   upload.id,
+		"analysis_id": upload.analysisID,
+		"filename":    upload.filename,
+		"size":        upload.size,
+		"expires_at":  upload.expiresAt,
+		"status":      directUploadPendingStatus,
+	}
+	switch {
+	case jobID != "":
+		// Finished jobs are cleaned up before the upload is forgotten
+		status := directUploadExpiredStatus
+		batchLock.RLock()
+		if job, ok := batchJobs[jobID]; ok {
+			status = probeJobStatus(job)
+		}
+		batchLock.RUnlock()
+		response["status"] = status
+		response["job_id"] = jobID
+		response["status_url"] = fmt.Sprintf("/api/v1/probe/jobs/%s", jobID)
+		response["result_url"] = fmt.Sprintf("/api/v1/probe/jobs/%s/result", jobID)
+		response["ws_url"] = fmt.Sprintf("/api/v1/ws/progress/%s", jobID)
+	case expired:
+		response["status"] = directUploadExpiredStatus
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(200, response)
+}
+
+// watchDirectUploads polls the object store for issued uploads until
+// shutdown
+func watchDirectUploads() {
+	ticker := time.NewTicker(directUploadPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCtx.Done():
+			return
+		case <-ticker.C:
+			checkDirectUploads()
+		}
+	}
+}
+
+// checkDirectUploads starts analysis of the files that have arrived. Once a
+// URL has expired and its job has finished, an object left behind by a
+// repeated upload is deleted, and the upload is forgotten when its job
+// would have been cleaned up.
+func checkDirectUploads() {
+	now := time.Now()
+
+	directUploadsLock.Lock()
+	var uploads []*directUpload
+	for id, upload := range directUploads {
+		if upload.settled && now.After(upload.expiresAt.Add(batchJobTTL)) {
+			delete(directUploads, id)
+			continue
+		}
+		if upload.settled || (upload.jobID != "" && now.Before(upload.expiresAt)) {
+			continue
+		}
+		uploads = append(uploads, upload)
+	}
+	directUploadsLock.Unlock()
+
+	for _, upload := range uploads {
+		ctx, cancel := context.WithTimeout(shutdownCtx, directUploadCheckTimeout)
+		exists, err := directUploadStorage.Exists(ctx, upload.key)
+		cancel()
+		if err != nil {
+			appLogger.Warn().Err(err).Str("upload_id", upload.id).Msg("Failed to check direct upload")
+			continue
+		}
+
+		directUploadsLock.Lock()
+		jobID := upload.jobID
+		directUploadsLock.Unlock()
+		started := jobID != ""
+
+		if !started && exists {
+			startDirectUploadJob(upload)
+			continue
+		}
+		if now.Before(upload.expiresAt) || (started && directUploadJobRunning(jobID)) {
+			continue
+		}
+		if exists {
+			deleteDirectUploadObject(upload)
+		}
+		directUploadsLock.Lock()
+		upload.expired = !started
+		upload.settled = true
+		directUploadsLock.Unlock()
+	}
+}
+
+// directUploadJobRunning reports whether a job may still read its upload
+func directUploadJobRunning(jobID string) bool {
+	batchLock.RLock()
+	defer batchLock.RUnlock()
+	job, ok := batchJobs[jobID]
+	return ok && job.Status != "completed" && job.Status != "cancelled"
+}
+
+// startDirectUploadJob analyzes an arrived file as an async probe job of
+// the tenant that requested the upload URL
+func startDirectUploadJob(upload *directUpload) {
+	run := func(ctx context.Context) (int, gin.H) {
+		path, err := fetchDirectUpload(ctx, upload)
+		if err != nil {
+			appLogger.Error().Err(err).Str("upload_id", upload.id).Msg("Failed to fetch direct upload")
+			problem := storageFailure(err, apierrors.CodeInternalError, "Failed to fetch uploaded file")
+			if sizeErr, ok := err.(*directUploadSizeError); ok {
+				// Only an object over its declared size is too large; a short
+				// one is a truncated or partial upload
+				if sizeErr.received > sizeErr.declared {
+					problem = apierrors.New(422, apierrors.CodeFileTooLarge, sizeErr.Error()).
+						With("declared_size_bytes", sizeErr.declared)
+				} else {
+					problem = apierrors.New(422, apierrors.CodeValidationError, sizeErr.Error()).
+						With("declared_size_bytes", sizeErr.declared).
+						With("received_size_bytes", sizeErr.received)
+				}
+			}
+			recordAnalysis(ctx, upload.analysisID, upload.filename, "", analysisSourceUpload, upload.size, nil, "", problem.Detail)
+			return problem.Status, problem.Payload()
+		}
+		defer func() {
+			if err := workspaceManager.Remove(path); err != nil {
+				appLogger.Warn().Err(err).Str("path", path).Msg("Failed to cleanup temp file")
+			}
+		}()
+		return upload.options.run(ctx, upload.analysisID, path, upload.filename, upload.size)
+	}
+
+	ctx := database.WithScope(context.Background(), upload.scope)
+	job := startProbeJob(ctx, upload.analysisID, upload.filename, upload.options.CallbackURL, ffmpeg.PriorityNormal, run, func() {
+		deleteDirectUploadObject(upload)
+	})
+
+	directUploadsLock.Lock()
+	upload.jobID = job.ID
+	directUploadsLock.Unlock()
+
+	appLogger.Info().Str("upload_id", upload.id).Str("job_id", job.ID).Msg("Direct upload received, analysis queued")
+}
+
+// directUploadSizeError reports an object whose size differs from the size
+// the URL was requested for
+type directUploadSizeError struct {
+	declared, received int64
+}
+
+func (e *directUploadSizeError) Error() string {
+	// Copying stops one byte past the declared size, so a larger object's
+	// full size is unknown
+	if e.received > e.declared {
+		return fmt.Sprintf("Uploaded file is larger than the expected %d bytes", e.declared)
+	}
+	return fmt.Sprintf("Uploaded file has %d bytes, expected %d", e.received, e.declared)
+}
+
+// fetchDirectUpload copies the uploaded object into the work directory,
+// returning the path of the copy
+func fetchDirectUpload(ctx context.Context, upload *directUpload) (string, error) {
+	reader, err := directUploadStorage.Download(ctx, upload.key)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	file, err := workspaceManager.Create(upload.filename)
+	if err != nil {
+		return "", err
+	}
+	path := file.Name()
+	written, err := io.CopyN(file, reader, upload.size+1)
+	file.Close()
+	if err == io.EOF {
+		err = nil
+	}
+	if err == nil && written != upload.size {
+		err = &directUploadSizeError{declared: upload.size, received: written}
+	}
+	if err != nil {
+		if removeErr := workspaceManager.Remove(path); removeErr != nil {
+			appLogger.Warn().Err(removeErr).Str("path", path).Msg("Failed to cleanup temp file")
+		}
+		return "", err
+	}
+	return path, nil
+}
+
+// deleteDirectUploadObject removes an uploaded object from the store
+func deleteDirectUploadObject(upload *directUpload) {
+	ctx, cancel := context.WithTimeout(context.Background(), directUploadCheckTimeout)
+	defer cancel()
+	if err := directUploadStorage.Delete(ctx, upload.key); err != nil {
+		appLogger.Warn().Err(err).Str("upload_id", upload.id).Msg("Failed to delete direct upload object")
+	}
+}
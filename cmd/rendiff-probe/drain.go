@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rendiffdev/rendiff-probe/internal/coordination"
+	"google.golang.org/grpc"
+)
+
+// On SIGTERM the server drains: it stops accepting requests and reports
+// not ready, batch items already running get up to DRAIN_TIMEOUT to finish
+// and items not started stay pending. Jobs still running at the deadline
+// are paused. Either way the job is checkpointed to the database as paused
+// and interrupted, and resumed automatically when the server starts again.
+
+var (
+	// draining is set once shutdown begins
+	draining atomic.Bool
+	// runningBatchJobs counts the jobs being processed, so shutdown can
+	// wait for them
+	runningBatchJobs sync.WaitGroup
+)
+
+// runBatchJob processes the job in the background
+func runBatchJob(job *BatchJob, release func()) {
+	runningBatchJobs.Add(1)
+	go func() {
+		defer runningBatchJobs.Done()
+		processBatchJob(job, release)
+	}()
+}
+
+// hasPendingItems reports whether any item of the job has no result yet.
+// Must be called with batchLock held.
+func (job *BatchJob) hasPendingItems() bool {
+	for _, item := range job.Items {
+		if !item.Done {
+			return true
+		}
+	}
+	return false
+}
+
+// stopServers stops the HTTP and gRPC servers from accepting requests and
+// gives those in progress up to timeout. The returned channel is closed
+// once both have stopped.
+func stopServers(srv *http.Server, grpcServer *grpc.Server, timeout time.Duration) <-chan struct{} {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if err := srv.Shutdown(ctx); err != nil {
+			appLogger.Error().Err(err).Msg("Server forced to shutdown")
+		}
+	}()
+	if grpcServer != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stopGRPCServer(ctx, grpcServer)
+		}()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		cancel()
+		close(stopped)
+	}()
+	return stopped
+}
+
+// drainBatchJobs waits up to timeout for the batch items already running
+// to finish. Jobs with items left pending settle as paused.
+func drainBatchJobs(timeout time.Duration) {
+	drained := make(chan struct{})
+	go func() {
+		runningBatchJobs.Wait()
+		close(drained)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+		appLogger.Info().Msg("Batch jobs drained")
+	case <-timer.C:
+		appLogger.Warn().Dur("drain_timeout", timeout).Msg("Drain timeout reached, pausing running batch jobs")
+	}
+}
+
+// resumeInterruptedBatchJobs restarts the restored jobs that a shutdown
+// paused. Jobs a client paused stay paused, and jobs another replica has
+// resumed meanwhile are left to it.
+func resumeInterruptedBatchJobs() {
+	var interrupted []*BatchJob
+	batchLock.RLock()
+	for _, job := range batchJobs {
+		if job.Status == "paused" && job.Interrupted {
+			interrupted = append(interrupted, job)
+		}
+	}
+	batchLock.RUnlock()
+
+	for _, job := range interrupted {
+		release, err := claimBatchJob(job.ID)
+		if errors.Is(err, coordination.ErrLockHeld) {
+			batchLock.Lock()
+			delete(batchJobs, job.ID)
+			batchLock.Unlock()
+			appLogger.Info().Str("job_id", job.ID).Msg("Interrupted batch job resumed by another replica")
+			continue
+		}
+		if err != nil {
+			// Running without the claim beats leaving the job paused
+			appLogger.Warn().Err(err).Str("job_id", job.ID).Msg("Failed to claim batch job")
+			release = func() {}
+		}
+
+		batchLock.Lock()
+		restartBatchJob(job)
+		batchLock.Unlock()
+
+		persistBatchJob(job)
+		runBatchJob(job, release)
+		appLogger.Info().Str("job_id", job.ID).Msg("Interrupted batch job resumed")
+	}
+}
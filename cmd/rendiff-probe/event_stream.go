@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// eventStream pushes messages to a client over WebSocket when the request is
+// an upgrade and as Server-Sent Events otherwise
+type eventStream struct {
+	c            *gin.Context
+	conn         *websocket.Conn // nil for SSE
+	writeTimeout time.Duration
+
+	// gone is set once a write fails or the client disconnects, after which
+	// no final message is attempted
+	gone atomic.Bool
+}
+
+// openEventStream upgrades or switches the response to SSE. cancel is called
+// when a WebSocket client disconnects; timeout is the lifetime of the stream
+// and writeTimeout bounds each WebSocket message. It returns false when the
+// WebSocket upgrade failed and the request is already answered.
+func openEventStream(c *gin.Context, cancel context.CancelFunc, timeout, writeTimeout time.Duration) (*eventStream, bool) {
+	stream := &eventStream{c: c, writeTimeout: writeTimeout}
+
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			appLogger.Error().Err(err).Msg("WebSocket upgrade failed")
+			return nil, false
+		}
+		stream.conn = conn
+
+		// Clients only send close frames; a read error means they are gone
+		conn.SetReadLimit(512)
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					stream.gone.Store(true)
+					cancel()
+					return
+				}
+			}
+		}()
+		return stream, true
+	}
+
+	// The server write timeout is sized for ordinary requests
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+		appLogger.Debug().Err(err).Msg("Could not extend write deadline for event stream")
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(200)
+	return stream, true
+}
+
+// send writes one message. WebSocket clients receive message as JSON; SSE
+// clients receive sseData (message when nil) as an event named eventType.
+func (s *eventStream) send(eventType string, message, sseData any) error {
+	if s.conn != nil {
+		err := s.conn.SetWriteDeadline(time.Now().Add(s.writeTimeout))
+		if err == nil {
+			err = s.conn.WriteJSON(message)
+		}
+		if err != nil {
+			s.gone.Store(true)
+		}
+		return err
+	}
+
+	if err := s.c.Request.Context().Err(); err != nil {
+		s.gone.Store(true)
+		return err
+	}
+	if sseData == nil {
+		sseData = message
+	}
+	s.c.SSEvent(eventType, sseData)
+	s.c.Writer.Flush()
+	return nil
+}
+
+// clientGone reports whether the client has disconnected
+func (s *eventStream) clientGone() bool {
+	return s.gone.Load() || s.c.Request.Context().Err() != nil
+}
+
+// close ends a WebSocket stream with a normal closure; SSE needs nothing
+func (s *eventStream) close() {
+	if s.conn == nil {
+		return
+	}
+	_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	s.conn.Close()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/validator"
+)
+
+// errFrameLimitReached stops a frame stream once the requested record limit
+// has been sent
+var errFrameLimitReached = errors.New("frame stream limit reached")
+
+// frameStreamMessage is the envelope for WebSocket frame stream messages.
+// SSE uses the type as the event name and sends the rest as data.
+type frameStreamMessage struct {
+	Type    string `json:"type"`
+	Data    any    `json:"data,omitempty"`
+	Records int    `json:"records,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// frameStreamHandler runs ffprobe -show_frames/-show_packets on a URL and
+// forwards each record as it is parsed, over WebSocket when the request is an
+// upgrade and as Server-Sent Events otherwise
+func frameStreamHandler(c *gin.Context) {
+	rawURL := c.Query("url")
+	if rawURL == "" {
+		respondError(c, 400, apierrors.CodeBadRequest, "url is required")
+		return
+	}
+	if err := validator.ValidateURL(rawURL); err != nil {
+		appLogger.Warn().Str("url", rawURL).Err(err).Msg("URL validation failed")
+		respondError(c, 400, apierrors.CodeURLBlocked, "Invalid or blocked URL")
+		return
+	}
+
+	options := &ffmpeg.FFprobeOptions{
+		Input:         rawURL,
+		SelectStreams: c.Query("select_streams"),
+		ReadIntervals: c.Query("read_intervals"),
+	}
+	sections := c.DefaultQuery("sections", "frames")
+	for _, section := range strings.Split(sections, ",") {
+		switch strings.TrimSpace(section) {
+		case "frames":
+			options.ShowFrames = true
+		case "packets":
+			options.ShowPackets = true
+		default:
+			respondError(c, 400, apierrors.CodeBadRequest, "Invalid sections, use 'frames', 'packets' or 'frames,packets'")
+			return
+		}
+	}
+	if err := ffmpeg.ValidateOptions(options); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	limit := 0
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			respondError(c, 400, apierrors.CodeBadRequest, "Invalid limit")
+			return
+		}
+	}
+
+	select {
+	case frameStreamSlots <- struct{}{}:
+		defer func() { <-frameStreamSlots }()
+	default:
+		respondError(c, 503, apierrors.CodeServerBusy, "Too many frame streams in progress, try again later")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), frameStreamTimeout)
+	defer cancel()
+	stop := context.AfterFunc(shutdownCtx, cancel)
+	defer stop()
+
+	// ffprobe only ever connects to the final, already-validated location
+	finalURL, _, _, err := resolveRemoteURL(ctx, rawURL)
+	if err != nil {
+		appLogger.Warn().Err(err).Str("url", rawURL).Msg("Frame stream URL resolution failed")
+		respondError(c, 400, apierrors.CodeBadRequest, "Failed to reach URL")
+		return
+	}
+	options.Input = finalURL
+
+	stream, ok := openEventStream(c, cancel, frameStreamTimeout, frameStreamWriteTimeout)
+	if !ok {
+		return
+	}
+	defer stream.close()
+
+	send := func(message frameStreamMessage) error {
+		switch message.Type {
+		case ffmpeg.RecordTypeFrame, ffmpeg.RecordTypePacket:
+			return stream.send(message.Type, message, message.Data)
+		default:
+			return stream.send(message.Type, message, nil)
+		}
+	}
+
+	count := 0
+	_, err = ffprobeInstance.StreamRecords(ctx, options, func(record ffmpeg.ProbeRecord) error {
+		if err := send(frameStreamMessage{Type: record.Type, Data: record.Data}); err != nil {
+			return err
+		}
+		count++
+		if limit > 0 && count >= limit {
+			return errFrameLimitReached
+		}
+		return nil
+	})
+
+	switch {
+	case err == nil || errors.Is(err, errFrameLimitReached):
+		_ = send(frameStreamMessage{Type: "complete", Records: count})
+	case stream.clientGone():
+		appLogger.Debug().Str("url", rawURL).Int("records", count).Msg("Frame stream client disconnected")
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		_ = send(frameStreamMessage{Type: "error", Records: count, Error: "Frame stream timed out"})
+	case ctx.Err() != nil:
+		_ = send(frameStreamMessage{Type: "error", Records: count, Error: "Server shutting down"})
+	default:
+		appLogger.Warn().Err(err).Str("url", rawURL).Int("records", count).Msg("Frame stream failed")
+		_ = send(frameStreamMessage{Type: "error", Records: count, Error: "Frame stream failed"})
+	}
+}
@@ -0,0 +1,848 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/validator"
+)
+
+// graphqlRequest is a GraphQL operation as sent in a graphql-transport-ws
+// subscribe message or the operations field of a multipart request
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// graphqlHTTPHandler serves queries and mutations with the standard handler,
+// except for multipart requests that carry file uploads and WebSocket
+// upgrades that carry subscriptions
+func graphqlHTTPHandler(schema *graphql.Schema, httpHandler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch {
+		case websocket.IsWebSocketUpgrade(c.Request):
+			graphqlWSHandler(c, schema)
+		case strings.HasPrefix(c.GetHeader("Content-Type"), "multipart/form-data"):
+			graphqlMultipartHandler(c, schema)
+		default:
+			httpHandler(c)
+		}
+	}
+}
+
+// jsonScalar carries analysis sections that have no dedicated GraphQL type.
+// Values are passed through and encoded with their JSON tags.
+var jsonScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "Arbitrary JSON value",
+	Serialize: func(value interface{}) interface{} {
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		return nil
+	},
+})
+
+// resolveSource resolves a field to its parent object, used for the raw
+// JSON view of a section and for subscription payloads
+func resolveSource(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source, nil
+}
+
+// rawField exposes the whole parent section as JSON, so fields without a
+// dedicated type stay reachable
+func rawField() *graphql.Field {
+	return &graphql.Field{
+		Type:        jsonScalar,
+		Description: "The complete section as JSON",
+		Resolve:     resolveSource,
+	}
+}
+
+// GraphQL Schema
+func createGraphQLSchema() graphql.Schema {
+	// Define stream type
+	streamType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Stream",
+		Fields: graphql.Fields{
+			"index":                &graphql.Field{Type: graphql.Int},
+			"codec_name":           &graphql.Field{Type: graphql.String},
+			"codec_long_name":      &graphql.Field{Type: graphql.String},
+			"profile":              &graphql.Field{Type: graphql.String},
+			"codec_type":           &graphql.Field{Type: graphql.String},
+			"codec_tag_string":     &graphql.Field{Type: graphql.String},
+			"width":                &graphql.Field{Type: graphql.Int},
+			"height":               &graphql.Field{Type: graphql.Int},
+			"coded_width":          &graphql.Field{Type: graphql.Int},
+			"coded_height":         &graphql.Field{Type: graphql.Int},
+			"sample_aspect_ratio":  &graphql.Field{Type: graphql.String},
+			"display_aspect_ratio": &graphql.Field{Type: graphql.String},
+			"pix_fmt":              &graphql.Field{Type: graphql.String},
+			"level":                &graphql.Field{Type: graphql.Int},
+			"color_range":          &graphql.Field{Type: graphql.String},
+			"color_space":          &graphql.Field{Type: graphql.String},
+			"color_transfer":       &graphql.Field{Type: graphql.String},
+			"color_primaries":      &graphql.Field{Type: graphql.String},
+			"field_order":          &graphql.Field{Type: graphql.String},
+			"r_frame_rate":         &graphql.Field{Type: graphql.String},
+			"avg_frame_rate":       &graphql.Field{Type: graphql.String},
+			"time_base":            &graphql.Field{Type: graphql.String},
+			"start_time":           &graphql.Field{Type: graphql.String},
+			"duration":             &graphql.Field{Type: graphql.String},
+			"bit_rate":             &graphql.Field{Type: graphql.String},
+			"bits_per_raw_sample":  &graphql.Field{Type: graphql.String},
+			"nb_frames":            &graphql.Field{Type: graphql.String},
+			"sample_fmt":           &graphql.Field{Type: graphql.String},
+			"sample_rate":          &graphql.Field{Type: graphql.String},
+			"channels":             &graphql.Field{Type: graphql.Int},
+			"channel_layout":       &graphql.Field{Type: graphql.String},
+			"bits_per_sample":      &graphql.Field{Type: graphql.Int},
+			"disposition":          &graphql.Field{Type: jsonScalar},
+			"tags":                 &graphql.Field{Type: jsonScalar},
+		},
+	})
+
+	// Define format type
+	formatType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Format",
+		Fields: graphql.Fields{
+			"filename":         &graphql.Field{Type: graphql.String},
+			"nb_streams":       &graphql.Field{Type: graphql.Int},
+			"nb_programs":      &graphql.Field{Type: graphql.Int},
+			"format_name":      &graphql.Field{Type: graphql.String},
+			"format_long_name": &graphql.Field{Type: graphql.String},
+			"start_time":       &graphql.Field{Type: graphql.String},
+			"duration":         &graphql.Field{Type: graphql.String},
+			"size":             &graphql.Field{Type: graphql.String},
+			"bit_rate":         &graphql.Field{Type: graphql.String},
+			"probe_score":      &graphql.Field{Type: graphql.Int},
+			"tags":             &graphql.Field{Type: jsonScalar},
+		},
+	})
+
+	enhancedAnalysisType := createEnhancedAnalysisType()
+
+	// Define analysis result type. Stored analyses also carry the record fields.
+	analysisType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AnalysisResult",
+		Fields: graphql.Fields{
+			"id":                &graphql.Field{Type: graphql.String},
+			"filename":          &graphql.Field{Type: graphql.String},
+			"source":            &graphql.Field{Type: graphql.String},
+			"source_type":       &graphql.Field{Type: graphql.String},
+			"size":              &graphql.Field{Type: graphql.Float},
+			"status":            &graphql.Field{Type: graphql.String},
+			"error":             &graphql.Field{Type: graphql.String},
+			"streams":           &graphql.Field{Type: graphql.NewList(streamType)},
+			"format":            &graphql.Field{Type: formatType},
+			"enhanced_analysis": &graphql.Field{Type: enhancedAnalysisType},
+			"llm_report":        &graphql.Field{Type: graphql.String},
+			"llm_enabled":       &graphql.Field{Type: graphql.Boolean},
+			"cached":            &graphql.Field{Type: graphql.Boolean},
+			"timestamp":         &graphql.Field{Type: graphql.String},
+			"created_at":        &graphql.Field{Type: graphql.DateTime},
+			"processed_at":      &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	analysisPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "AnalysisPage",
+		Fields: graphql.Fields{
+			"analyses": &graphql.Field{Type: graphql.NewList(analysisType)},
+			"total":    &graphql.Field{Type: graphql.Int},
+			"limit":    &graphql.Field{Type: graphql.Int},
+			"offset":   &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	batchItemType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BatchItem",
+		Fields: graphql.Fields{
+			"type":           &graphql.Field{Type: graphql.String},
+			"input":          &graphql.Field{Type: graphql.String},
+			"done":           &graphql.Field{Type: graphql.Boolean},
+			"phase":          &graphql.Field{Type: graphql.String},
+			"phase_progress": &graphql.Field{Type: graphql.Float},
+			"progress":       &graphql.Field{Type: graphql.Float},
+		},
+	})
+
+	batchJobType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "BatchJob",
+		Fields: graphql.Fields{
+			"id":            &graphql.Field{Type: graphql.String},
+			"status":        &graphql.Field{Type: graphql.String},
+			"total":         &graphql.Field{Type: graphql.Int},
+			"completed":     &graphql.Field{Type: graphql.Int},
+			"failed":        &graphql.Field{Type: graphql.Int},
+			"progress":      &graphql.Field{Type: graphql.Float},
+			"items":         &graphql.Field{Type: graphql.NewList(batchItemType)},
+			"results":       &graphql.Field{Type: graphql.NewList(jsonScalar)},
+			"qc_categories": &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"include_llm":   &graphql.Field{Type: graphql.Boolean},
+			"priority":      &graphql.Field{Type: graphql.String},
+			"created_at":    &graphql.Field{Type: graphql.DateTime},
+			"updated_at":    &graphql.Field{Type: graphql.DateTime},
+		},
+	})
+
+	itemProgressType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ItemProgress",
+		Fields: graphql.Fields{
+			"index":          &graphql.Field{Type: graphql.Int},
+			"input":          &graphql.Field{Type: graphql.String},
+			"phase":          &graphql.Field{Type: graphql.String},
+			"phase_progress": &graphql.Field{Type: graphql.Float},
+			"progress":       &graphql.Field{Type: graphql.Float},
+		},
+	})
+
+	progressUpdateType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ProgressUpdate",
+		Fields: graphql.Fields{
+			"type":      &graphql.Field{Type: graphql.String},
+			"job_id":    &graphql.Field{Type: graphql.String},
+			"progress":  &graphql.Field{Type: graphql.Float},
+			"message":   &graphql.Field{Type: graphql.String},
+			"status":    &graphql.Field{Type: graphql.String},
+			"timestamp": &graphql.Field{Type: graphql.String},
+			"item":      &graphql.Field{Type: itemProgressType},
+		},
+	})
+
+	// Define query
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"health": &graphql.Field{
+				Type: graphql.NewObject(graphql.ObjectConfig{
+					Name: "Health",
+					Fields: graphql.Fields{
+						"status":  &graphql.Field{Type: graphql.String},
+						"version": &graphql.Field{Type: graphql.String},
+					},
+				}),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return map[string]interface{}{
+						"status":  "healthy",
+						"version": serviceVersion,
+					}, nil
+				},
+			},
+			"analysis": &graphql.Field{
+				Type:        analysisType,
+				Description: "A stored analysis by ID, null when it does not exist",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: resolveStoredAnalysis,
+			},
+			"analyses": &graphql.Field{
+				Type:        analysisPageType,
+				Description: "Stored analyses, newest first",
+				Args: graphql.FieldConfigArgument{
+					"filename": &graphql.ArgumentConfig{Type: graphql.String},
+					"status":   &graphql.ArgumentConfig{Type: graphql.String},
+					"from":     &graphql.ArgumentConfig{Type: graphql.String},
+					"to":       &graphql.ArgumentConfig{Type: graphql.String},
+					"limit": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: database.DefaultAnalysisListLimit,
+					},
+					"offset": &graphql.ArgumentConfig{
+						Type:         graphql.Int,
+						DefaultValue: 0,
+					},
+				},
+				Resolve: resolveStoredAnalyses,
+			},
+			"batchJob": &graphql.Field{
+				Type:        batchJobType,
+				Description: "A batch job by ID, null when it does not exist",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					jobID := p.Args["id"].(string)
+					if _, err := uuid.Parse(jobID); err != nil {
+						return nil, apierrors.New(400, apierrors.CodeBadRequest, "invalid job ID format")
+					}
+
+					batchLock.RLock()
+					defer batchLock.RUnlock()
+					job, exists := batchJobs[jobID]
+					if !exists || !visibleBatchJob(p.Context, job) {
+						return nil, nil
+					}
+					return graphQLBatchJob(job), nil
+				},
+			},
+			"batchJobs": &graphql.Field{
+				Type:        graphql.NewList(batchJobType),
+				Description: "Batch jobs known to this instance, newest first",
+				Args: graphql.FieldConfigArgument{
+					"status": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					status, _ := p.Args["status"].(string)
+
+					batchLock.RLock()
+					jobs := make([]*BatchJob, 0, len(batchJobs))
+					for _, job := range batchJobs {
+						if (status == "" || job.Status == status) && visibleBatchJob(p.Context, job) {
+							jobs = append(jobs, job)
+						}
+					}
+					sort.Slice(jobs, func(i, j int) bool {
+						return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+					})
+					results := make([]map[string]interface{}, len(jobs))
+					for i, job := range jobs {
+						results[i] = graphQLBatchJob(job)
+					}
+					batchLock.RUnlock()
+
+					return results, nil
+				},
+			},
+		},
+	})
+
+	// Define mutation with URL validation
+	mutationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"analyzeURL": &graphql.Field{
+				Type: analysisType,
+				Args: graphql.FieldConfigArgument{
+					"url": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(graphql.String),
+					},
+					"include_llm": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: false,
+					},
+				},
+				Resolve: graphQLRole(database.RoleOperator, func(p graphql.ResolveParams) (interface{}, error) {
+					url := p.Args["url"].(string)
+
+					// Validate URL
+					if err := validator.ValidateURL(url); err != nil {
+						return nil, apierrors.New(400, apierrors.CodeURLBlocked, "invalid or blocked URL")
+					}
+
+					includeLLM := false
+					if v, ok := p.Args["include_llm"].(bool); ok {
+						includeLLM = v
+					}
+
+					ctx := p.Context
+					tempPath, filename, err := downloadURL(ctx, url)
+					if err != nil {
+						return nil, storageFailure(err, apierrors.CodeDownloadFailed, "failed to download URL")
+					}
+					defer func() {
+						if err := workspaceManager.Remove(tempPath); err != nil {
+							appLogger.Warn().Err(err).Str("path", tempPath).Msg("Failed to cleanup temp file")
+						}
+					}()
+
+					result, err := analyzeFile(ctx, tempPath, nil, nil, "")
+					if err != nil {
+						return nil, analysisFailure(err, "analysis failed")
+					}
+
+					response := map[string]interface{}{
+						"id":                uuid.New().String(),
+						"filename":          filename,
+						"status":            "completed",
+						"streams":           result.Streams,
+						"format":            result.Format,
+						"enhanced_analysis": result.EnhancedAnalysis,
+						"llm_enabled":       false,
+						"timestamp":         time.Now().Format(time.RFC3339),
+					}
+
+					if includeLLM {
+						llmReport, err := generateLLMInsights(ctx, result, filename, false)
+						if err == nil {
+							response["llm_report"] = llmReport.Report
+							response["llm_enabled"] = true
+						}
+					}
+
+					return response, nil
+				}),
+			},
+			"analyzeFile": &graphql.Field{
+				Type:        analysisType,
+				Description: "Analyze a file sent with a multipart request, like POST /api/v1/probe/file",
+				Args: graphql.FieldConfigArgument{
+					"file": &graphql.ArgumentConfig{
+						Type: graphql.NewNonNull(uploadScalar),
+					},
+					"include_llm": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: false,
+					},
+					"refresh_llm": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: false,
+					},
+					"refresh_cache": &graphql.ArgumentConfig{
+						Type:         graphql.Boolean,
+						DefaultValue: false,
+					},
+					"categories": &graphql.ArgumentConfig{
+						Type: graphql.NewList(graphql.String),
+					},
+					"profile": &graphql.ArgumentConfig{
+						Type: graphql.String,
+					},
+				},
+				Resolve: graphQLRole(database.RoleOperator, resolveAnalyzeFile),
+			},
+		},
+	})
+
+	// Define subscription, served over the graphql-transport-ws WebSocket
+	subscriptionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Subscription",
+		Fields: graphql.Fields{
+			"batchProgress": &graphql.Field{
+				Type:        progressUpdateType,
+				Description: "Progress of a batch job, starting with its current state and ending when it finishes",
+				Args: graphql.FieldConfigArgument{
+					"job_id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Subscribe: subscribeBatchProgress,
+				Resolve:   resolveSource,
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:        queryType,
+		Mutation:     mutationType,
+		Subscription: subscriptionType,
+	})
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to create GraphQL schema")
+	}
+
+	return schema
+}
+
+// createEnhancedAnalysisType defines the enhanced analysis with typed fields
+// for the commonly queried sections and raw JSON for the rest
+func createEnhancedAnalysisType() *graphql.Object {
+	streamCountsType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "StreamCounts",
+		Fields: graphql.Fields{
+			"total_streams":      &graphql.Field{Type: graphql.Int},
+			"video_streams":      &graphql.Field{Type: graphql.Int},
+			"audio_streams":      &graphql.Field{Type: graphql.Int},
+			"subtitle_streams":   &graphql.Field{Type: graphql.Int},
+			"data_streams":       &graphql.Field{Type: graphql.Int},
+			"attachment_streams": &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	// Black and freeze frame detection share a shape
+	frameDetectionType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "FrameDetection",
+		Fields: graphql.Fields{
+			"detected_frames": &graphql.Field{Type: graphql.Int},
+			"percentage":      &graphql.Field{Type: graphql.Float},
+			"threshold":       &graphql.Field{Type: graphql.Float},
+		},
+	})
+
+	loudnessPointType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "LoudnessPoint",
+		Fields: graphql.Fields{
+			"t":               &graphql.Field{Type: graphql.Float},
+			"momentary_lufs":  &graphql.Field{Type: graphql.Float},
+			"short_term_lufs": &graphql.Field{Type: graphql.Float},
+			"true_peak_dbtp":  &graphql.Field{Type: graphql.Float},
+		},
+	})
+
+	loudnessType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Loudness",
+		Fields: graphql.Fields{
+			"integrated_loudness_lufs":  &graphql.Field{Type: graphql.Float},
+			"loudness_range_lu":         &graphql.Field{Type: graphql.Float},
+			"true_peak_dbtp":            &graphql.Field{Type: graphql.Float},
+			"broadcast_compliant":       &graphql.Field{Type: graphql.Boolean},
+			"standard":                  &graphql.Field{Type: graphql.String},
+			"timeline_interval_seconds": &graphql.Field{Type: graphql.Float},
+			"timeline":                  &graphql.Field{Type: graphql.NewList(loudnessPointType)},
+		},
+	})
+
+	letterboxType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Letterbox",
+		Fields: graphql.Fields{
+			"has_letterbox":       &graphql.Field{Type: graphql.Boolean},
+			"has_pillarbox":       &graphql.Field{Type: graphql.Boolean},
+			"type":                &graphql.Field{Type: graphql.String},
+			"original_width":      &graphql.Field{Type: graphql.Int},
+			"original_height":     &graphql.Field{Type: graphql.Int},
+			"active_width":        &graphql.Field{Type: graphql.Int},
+			"active_height":       &graphql.Field{Type: graphql.Int},
+			"top_bar":             &graphql.Field{Type: graphql.Int},
+			"bottom_bar":          &graphql.Field{Type: graphql.Int},
+			"left_bar":            &graphql.Field{Type: graphql.Int},
+			"right_bar":           &graphql.Field{Type: graphql.Int},
+			"aspect_ratio":        &graphql.Field{Type: graphql.String},
+			"active_aspect_ratio": &graphql.Field{Type: graphql.String},
+			"crop_filter":         &graphql.Field{Type: graphql.String},
+			"black_percentage":    &graphql.Field{Type: graphql.Float},
+			"is_consistent":       &graphql.Field{Type: graphql.Boolean},
+			"frames_analyzed":     &graphql.Field{Type: graphql.Int},
+			"confidence":          &graphql.Field{Type: graphql.Float},
+		},
+	})
+
+	videoQualityType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "VideoQualityScore",
+		Fields: graphql.Fields{
+			"overall_score":        &graphql.Field{Type: graphql.Float},
+			"sharpness_score":      &graphql.Field{Type: graphql.Float},
+			"contrast_score":       &graphql.Field{Type: graphql.Float},
+			"color_score":          &graphql.Field{Type: graphql.Float},
+			"noise_score":          &graphql.Field{Type: graphql.Float},
+			"blockiness_score":     &graphql.Field{Type: graphql.Float},
+			"temporal_stability":   &graphql.Field{Type: graphql.Float},
+			"motion_quality":       &graphql.Field{Type: graphql.Float},
+			"quality_class":        &graphql.Field{Type: graphql.String},
+			"is_broadcast_quality": &graphql.Field{Type: graphql.Boolean},
+			"psnr":                 &graphql.Field{Type: graphql.Float},
+			"ssim":                 &graphql.Field{Type: graphql.Float},
+			"frames_analyzed":      &graphql.Field{Type: graphql.Int},
+		},
+	})
+
+	hdrType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "HDR",
+		Fields: graphql.Fields{
+			"is_hdr":          &graphql.Field{Type: graphql.Boolean},
+			"hdr_format":      &graphql.Field{Type: graphql.String},
+			"color_primaries": &graphql.Field{Type: graphql.String},
+			"color_transfer":  &graphql.Field{Type: graphql.String},
+			"color_space":     &graphql.Field{Type: graphql.String},
+			"hlg_compatible":  &graphql.Field{Type: graphql.Boolean},
+			"raw":             rawField(),
+		},
+	})
+
+	sceneChangeType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SceneChange",
+		Fields: graphql.Fields{
+			"timestamp":          &graphql.Field{Type: graphql.Float},
+			"score":              &graphql.Field{Type: graphql.Float},
+			"preceding_shot_sec": &graphql.Field{Type: graphql.Float},
+			"following_shot_sec": &graphql.Field{Type: graphql.Float},
+		},
+	})
+
+	sceneChangesType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "SceneChanges",
+		Fields: graphql.Fields{
+			"threshold": &graphql.Field{Type: graphql.Float},
+			"count":     &graphql.Field{Type: graphql.Int},
+			"changes":   &graphql.Field{Type: graphql.NewList(sceneChangeType)},
+		},
+	})
+
+	contentAnalysisType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ContentAnalysis",
+		Fields: graphql.Fields{
+			"black_frames":        &graphql.Field{Type: frameDetectionType},
+			"freeze_frames":       &graphql.Field{Type: frameDetectionType},
+			"loudness_meter":      &graphql.Field{Type: loudnessType},
+			"letterbox_info":      &graphql.Field{Type: letterboxType},
+			"video_quality_score": &graphql.Field{Type: videoQualityType},
+			"hdr_analysis":        &graphql.Field{Type: hdrType},
+			"scene_changes":       &graphql.Field{Type: sceneChangesType},
+			"raw":                 rawField(),
+		},
+	})
+
+	pseViolationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PSEViolation",
+		Fields: graphql.Fields{
+			"timestamp":            &graphql.Field{Type: graphql.Float},
+			"violation_type":       &graphql.Field{Type: graphql.String},
+			"severity":             &graphql.Field{Type: graphql.String},
+			"description":          &graphql.Field{Type: graphql.String},
+			"affected_area":        &graphql.Field{Type: graphql.Float},
+			"duration":             &graphql.Field{Type: graphql.Float},
+			"risk_score":           &graphql.Field{Type: graphql.Float},
+			"compliance_standards": &graphql.Field{Type: graphql.NewList(graphql.String)},
+		},
+	})
+
+	pseAnalysisType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PSEAnalysis",
+		Fields: graphql.Fields{
+			"pse_risk_level":       &graphql.Field{Type: graphql.String},
+			"flash_risk_level":     &graphql.Field{Type: graphql.String},
+			"red_flash_risk_level": &graphql.Field{Type: graphql.String},
+			"pattern_risk_level":   &graphql.Field{Type: graphql.String},
+			"overall_risk_score":   &graphql.Field{Type: graphql.Float},
+			"violation_instances":  &graphql.Field{Type: graphql.NewList(pseViolationType)},
+			"broadcast_compliance": &graphql.Field{Type: jsonScalar},
+			"raw":                  rawField(),
+		},
+	})
+
+	deliveryCheckType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "DeliveryCheck",
+		Fields: graphql.Fields{
+			"rule":     &graphql.Field{Type: graphql.String},
+			"status":   &graphql.Field{Type: graphql.String},
+			"expected": &graphql.Field{Type: graphql.String},
+			"actual":   &graphql.Field{Type: graphql.String},
+		},
+	})
+
+	deliveryComplianceType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "DeliveryCompliance",
+		Fields: graphql.Fields{
+			"profile":      &graphql.Field{Type: graphql.String},
+			"profile_name": &graphql.Field{Type: graphql.String},
+			"compliant":    &graphql.Field{Type: graphql.Boolean},
+			"passed":       &graphql.Field{Type: graphql.Int},
+			"failed":       &graphql.Field{Type: graphql.Int},
+			"warnings":     &graphql.Field{Type: graphql.Int},
+			"not_measured": &graphql.Field{Type: graphql.Int},
+			"checks":       &graphql.Field{Type: graphql.NewList(deliveryCheckType)},
+		},
+	})
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "EnhancedAnalysis",
+		Fields: graphql.Fields{
+			"stream_counts":       &graphql.Field{Type: streamCountsType},
+			"content_analysis":    &graphql.Field{Type: contentAnalysisType},
+			"pse_analysis":        &graphql.Field{Type: pseAnalysisType},
+			"delivery_compliance": &graphql.Field{Type: deliveryComplianceType},
+			"qc_categories":       &graphql.Field{Type: graphql.NewList(graphql.String)},
+			"raw":                 rawField(),
+		},
+	})
+}
+
+// resolveStoredAnalysis loads one analysis from the database
+func resolveStoredAnalysis(p graphql.ResolveParams) (interface{}, error) {
+	id, err := uuid.Parse(p.Args["id"].(string))
+	if err != nil {
+		return nil, apierrors.New(400, apierrors.CodeBadRequest, "invalid analysis ID format")
+	}
+
+	record, err := analysisStore.Get(p.Context, id)
+	if errors.Is(err, database.ErrAnalysisNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to get analysis for GraphQL")
+		return nil, apierrors.New(500, apierrors.CodeInternalError, "failed to get analysis")
+	}
+	return graphQLStoredAnalysis(record), nil
+}
+
+// resolveStoredAnalyses lists analyses with the same filters and bounds as
+// GET /api/v1/analyses
+func resolveStoredAnalyses(p graphql.ResolveParams) (interface{}, error) {
+	filter := database.AnalysisFilter{}
+	filter.FileName, _ = p.Args["filename"].(string)
+	filter.Status, _ = p.Args["status"].(string)
+	filter.Limit, _ = p.Args["limit"].(int)
+	filter.Offset, _ = p.Args["offset"].(int)
+
+	if filter.Status != "" && filter.Status != database.AnalysisStatusCompleted && filter.Status != database.AnalysisStatusFailed {
+		return nil, apierrors.New(400, apierrors.CodeBadRequest, "invalid status, must be 'completed' or 'failed'")
+	}
+
+	var err error
+	from, _ := p.Args["from"].(string)
+	if filter.From, err = parseDateQuery(from, false); err != nil {
+		return nil, apierrors.New(400, apierrors.CodeBadRequest, "invalid from date, use RFC 3339 or YYYY-MM-DD")
+	}
+	to, _ := p.Args["to"].(string)
+	if filter.To, err = parseDateQuery(to, true); err != nil {
+		return nil, apierrors.New(400, apierrors.CodeBadRequest, "invalid to date, use RFC 3339 or YYYY-MM-DD")
+	}
+	if filter.Limit < 1 || filter.Limit > database.MaxAnalysisListLimit {
+		return nil, apierrors.New(400, apierrors.CodeBadRequest, "invalid limit, must be between 1 and "+strconv.Itoa(database.MaxAnalysisListLimit))
+	}
+	if filter.Offset < 0 {
+		return nil, apierrors.New(400, apierrors.CodeBadRequest, "invalid offset")
+	}
+
+	records, total, err := analysisStore.List(p.Context, filter)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Failed to list analyses for GraphQL")
+		return nil, apierrors.New(500, apierrors.CodeInternalError, "failed to list analyses")
+	}
+
+	analyses := make([]map[string]interface{}, len(records))
+	for i := range records {
+		analyses[i] = graphQLStoredAnalysis(&records[i])
+	}
+	return map[string]interface{}{
+		"analyses": analyses,
+		"total":    total,
+		"limit":    filter.Limit,
+		"offset":   filter.Offset,
+	}, nil
+}
+
+// graphQLStoredAnalysis maps a stored record to an AnalysisResult. Listings
+// carry no result, so streams, format and enhanced_analysis are null there.
+func graphQLStoredAnalysis(record *database.AnalysisRecord) map[string]interface{} {
+	analysis := map[string]interface{}{
+		"id":           record.ID.String(),
+		"filename":     record.FileName,
+		"source":       record.Source,
+		"source_type":  record.SourceType,
+		"size":         record.FileSize,
+		"status":       record.Status,
+		"error":        record.Error,
+		"llm_report":   record.LLMReport,
+		"llm_enabled":  record.LLMReport != "",
+		"created_at":   record.CreatedAt,
+		"processed_at": record.ProcessedAt,
+		"timestamp":    record.CreatedAt.Format(time.RFC3339),
+	}
+
+	if len(record.Result) > 0 {
+		var result ffmpeg.FFprobeResult
+		if err := json.Unmarshal(record.Result, &result); err != nil {
+			appLogger.Warn().Err(err).Str("analysis_id", record.ID.String()).Msg("Failed to decode stored analysis for GraphQL")
+		} else {
+			analysis["streams"] = result.Streams
+			analysis["format"] = result.Format
+			analysis["enhanced_analysis"] = result.EnhancedAnalysis
+		}
+	}
+	return analysis
+}
+
+// graphQLBatchJob snapshots a batch job. The caller must hold batchLock.
+func graphQLBatchJob(job *BatchJob) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            job.ID,
+		"status":        job.Status,
+		"total":         job.Total,
+		"completed":     job.Completed,
+		"failed":        job.Failed,
+		"progress":      batchProgress(job),
+		"items":         append([]BatchItem(nil), job.Items...),
+		"results":       append([]map[string]interface{}(nil), job.Results...),
+		"qc_categories": append([]ffmpeg.QCCategory(nil), job.QCCategories...),
+		"include_llm":   job.IncludeLLM,
+		"priority":      job.priority().String(),
+		"created_at":    job.CreatedAt,
+		"updated_at":    job.UpdatedAt,
+	}
+}
+
+// subscribeBatchProgress feeds a batchProgress subscription: the job's
+// current state, then every progress update until the job finishes or the
+// subscription's context ends
+func subscribeBatchProgress(p graphql.ResolveParams) (interface{}, error) {
+	jobID := p.Args["job_id"].(string)
+	if _, err := uuid.Parse(jobID); err != nil {
+		return nil, apierrors.New(400, apierrors.CodeBadRequest, "invalid job ID format")
+	}
+
+	batchLock.RLock()
+	job, exists := batchJobs[jobID]
+	batchLock.RUnlock()
+	if !exists || !visibleBatchJob(p.Context, job) {
+		return nil, apierrors.New(404, apierrors.CodeNotFound, "job not found")
+	}
+
+	// Subscribe before reading the snapshot so no terminal update is missed
+	updates, unsubscribe := subscribeProgress(jobID)
+
+	batchLock.RLock()
+	snapshot := ProgressUpdate{
+		Type:      "progress",
+		JobID:     jobID,
+		Progress:  batchProgress(job),
+		Status:    job.Status,
+		Message:   "Connected to progress stream",
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	batchLock.RUnlock()
+
+	payloads := make(chan interface{})
+	go func() {
+		defer close(payloads)
+		defer unsubscribe()
+
+		send := func(update ProgressUpdate) bool {
+			select {
+			case payloads <- update:
+				return !isBatchJobFinished(update.Status)
+			case <-p.Context.Done():
+				return false
+			}
+		}
+		if !send(snapshot) {
+			return
+		}
+
+		// Jobs cancelled without a final update still end the subscription
+		ticker := time.NewTicker(batchStatusPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.Context.Done():
+				return
+			case update := <-updates:
+				if !send(update) {
+					return
+				}
+			case <-ticker.C:
+				batchLock.RLock()
+				jobStatus := job.Status
+				batchLock.RUnlock()
+				if isBatchJobFinished(jobStatus) {
+					send(ProgressUpdate{
+						Type:      "progress",
+						JobID:     jobID,
+						Status:    jobStatus,
+						Message:   fmt.Sprintf("Batch job %s", jobStatus),
+						Timestamp: time.Now().Format(time.RFC3339),
+					})
+					return
+				}
+			}
+		}
+	}()
+
+	return payloads, nil
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/validator"
+)
+
+// graphqlUpload is a file part of a multipart GraphQL request, saved to a
+// temp file for the duration of the request
+type graphqlUpload struct {
+	filename string
+	path     string
+	size     int64
+}
+
+// uploadScalar is the Upload type of the GraphQL multipart request spec.
+// Its values can only come from file parts, never from JSON or literals.
+var uploadScalar = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Upload",
+	Description: "A file sent as a part of a multipart request",
+	Serialize: func(value interface{}) interface{} {
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		if upload, ok := value.(*graphqlUpload); ok {
+			return upload
+		}
+		return nil
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		return nil
+	},
+})
+
+// graphqlMultipartHandler implements the GraphQL multipart request spec: the
+// operations field holds the request with null placeholders for files, and
+// the map field names the variables each file part fills in.
+func graphqlMultipartHandler(c *gin.Context, schema *graphql.Schema) {
+	var request graphqlRequest
+	if err := json.Unmarshal([]byte(c.PostForm("operations")), &request); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid operations field, batched operations are not supported")
+		return
+	}
+	var fileMap map[string][]string
+	if err := json.Unmarshal([]byte(c.PostForm("map")), &fileMap); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid map field")
+		return
+	}
+	if len(fileMap) > maxGraphQLUploads {
+		apierrors.Respond(c, apierrors.New(400, apierrors.CodeBadRequest, "Too many files").With("max_files", maxGraphQLUploads))
+		return
+	}
+	if request.Variables == nil {
+		request.Variables = make(map[string]interface{})
+	}
+
+	var uploads []*graphqlUpload
+	defer func() {
+		for _, upload := range uploads {
+			if err := workspaceManager.Remove(upload.path); err != nil {
+				appLogger.Warn().Err(err).Str("path", upload.path).Msg("Failed to cleanup temp file")
+			}
+		}
+	}()
+
+	for key, paths := range fileMap {
+		upload, ok := saveGraphQLUpload(c, key)
+		if !ok {
+			return
+		}
+		uploads = append(uploads, upload)
+
+		for _, path := range paths {
+			if !setGraphQLVariable(request.Variables, path, upload) {
+				respondError(c, 400, apierrors.CodeBadRequest, fmt.Sprintf("Invalid map path %q for file %q", path, key))
+				return
+			}
+		}
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         *schema,
+		RequestString:  request.Query,
+		VariableValues: request.Variables,
+		OperationName:  request.OperationName,
+		Context:        c.Request.Context(),
+	})
+	c.JSON(200, result)
+}
+
+// saveGraphQLUpload copies a file part to a temp file with the same limits
+// as the REST file probe. On failure it writes the error response.
+func saveGraphQLUpload(c *gin.Context, key string) (*graphqlUpload, bool) {
+	file, header, err := c.Request.FormFile(key)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, fmt.Sprintf("No file provided for %q", key))
+		return nil, false
+	}
+	defer file.Close()
+
+	maxSize := maxFileSize()
+	if header.Size > maxSize {
+		apierrors.Respond(c, apierrors.New(413, apierrors.CodeFileTooLarge, "File too large").With("max_size_bytes", maxSize))
+		return nil, false
+	}
+
+	// Sanitize filename to prevent path traversal
+	safeFilename := validator.SanitizeFilename(header.Filename)
+	if safeFilename == "" {
+		safeFilename = fmt.Sprintf("upload_%s", uuid.New().String()[:8])
+	}
+
+	tempFile, err := workspaceManager.Create(safeFilename)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Failed to create temporary file")
+		apierrors.Respond(c, storageFailure(err, apierrors.CodeInternalError, "Failed to process file"))
+		return nil, false
+	}
+	tempPath := tempFile.Name()
+
+	written, err := io.CopyN(tempFile, file, maxSize+1)
+	tempFile.Close()
+	if err == io.EOF {
+		err = nil
+	}
+	if err != nil || written > maxSize {
+		if err := workspaceManager.Remove(tempPath); err != nil {
+			appLogger.Warn().Err(err).Str("path", tempPath).Msg("Failed to cleanup temp file")
+		}
+		if err != nil {
+			appLogger.Error().Err(err).Msg("Failed to save uploaded file")
+			apierrors.Respond(c, storageFailure(err, apierrors.CodeInternalError, "Failed to process file"))
+		} else {
+			apierrors.Respond(c, apierrors.New(413, apierrors.CodeFileTooLarge, "File too large").With("max_size_bytes", maxSize))
+		}
+		return nil, false
+	}
+
+	return &graphqlUpload{filename: safeFilename, path: tempPath, size: written}, true
+}
+
+// setGraphQLVariable replaces the value at a map path such as
+// "variables.file" or "variables.files.0"
+func setGraphQLVariable(variables map[string]interface{}, path string, value interface{}) bool {
+	segments := strings.Split(path, ".")
+	if len(segments) < 2 || segments[0] != "variables" {
+		return false
+	}
+
+	var node interface{} = variables
+	for i, segment := range segments[1:] {
+		last := i == len(segments)-2
+		switch container := node.(type) {
+		case map[string]interface{}:
+			if last {
+				container[segment] = value
+				return true
+			}
+			node = container[segment]
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(container) {
+				return false
+			}
+			if last {
+				container[index] = value
+				return true
+			}
+			node = container[index]
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// resolveAnalyzeFile analyzes an uploaded file. Like the REST file probe the
+// analysis is stored, so it can be fetched again with analysis(id).
+func resolveAnalyzeFile(p graphql.ResolveParams) (interface{}, error) {
+	upload, ok := p.Args["file"].(*graphqlUpload)
+	if !ok {
+		return nil, apierrors.New(400, apierrors.CodeBadRequest, "file must be sent as a multipart upload")
+	}
+	includeLLM, _ := p.Args["include_llm"].(bool)
+	refreshLLM, _ := p.Args["refresh_llm"].(bool)
+	refreshCache, _ := p.Args["refresh_cache"].(bool)
+
+	var names []string
+	if list, ok := p.Args["categories"].([]interface{}); ok {
+		for _, name := range list {
+			if name, ok := name.(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	categories, err := ffmpeg.ParseQCCategories(strings.Join(names, ","))
+	if err != nil {
+		return nil, apierrors.New(400, apierrors.CodeInvalidCategory, err.Error())
+	}
+	profileName, _ := p.Args["profile"].(string)
+	profile, err := lookupProfile(p.Context, profileName)
+	if err != nil {
+		return nil, apierrors.New(400, apierrors.CodeInvalidProfile, err.Error())
+	}
+
+	analysisID := uuid.New().String()
+	status, response := runFileProbe(p.Context, analysisID, upload.path, upload.filename, upload.size, includeLLM, refreshLLM, refreshCache, false, categories, profile, "", nil, thumbnailOptions{})
+	if status != 200 {
+		return nil, payloadProblem(status, response)
+	}
+	result := response["analysis"].(*ffmpeg.FFprobeResult)
+
+	analysis := map[string]interface{}{
+		"id":                analysisID,
+		"filename":          upload.filename,
+		"source_type":       analysisSourceUpload,
+		"size":              upload.size,
+		"status":            "completed",
+		"streams":           result.Streams,
+		"format":            result.Format,
+		"enhanced_analysis": result.EnhancedAnalysis,
+		"llm_enabled":       response["llm_enabled"] == true,
+		"cached":            response["cached"] == true,
+		"timestamp":         time.Now().Format(time.RFC3339),
+	}
+	if report, ok := response["llm_report"].(string); ok {
+		analysis["llm_report"] = report
+	}
+	return analysis, nil
+}
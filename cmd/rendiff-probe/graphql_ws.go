@@ -0,0 +1,279 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// graphqlWSProtocol is the WebSocket subprotocol spoken by graphql-ws clients
+const graphqlWSProtocol = "graphql-transport-ws"
+
+// graphql-transport-ws close codes
+const (
+	graphqlWSCloseInvalidMessage    = 4400
+	graphqlWSCloseUnauthorized      = 4401
+	graphqlWSCloseInitTimeout       = 4408
+	graphqlWSCloseSubscriberExists  = 4409
+	graphqlWSCloseTooManyInits      = 4429
+	graphqlWSCloseTooManyOperations = 4430
+)
+
+// graphqlWSMessage is a graphql-transport-ws protocol message
+type graphqlWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// graphqlWSConn is one graphql-transport-ws connection. Operations run
+// concurrently, so writes are serialized.
+type graphqlWSConn struct {
+	conn       *websocket.Conn
+	schema     *graphql.Schema
+	writeLock  sync.Mutex
+	lock       sync.Mutex
+	operations map[string]context.CancelFunc
+}
+
+// graphqlWSHandler runs the graphql-transport-ws protocol: the client sends
+// connection_init, then subscribe messages whose results arrive as next
+// messages followed by complete. Queries and mutations are accepted too and
+// produce a single result.
+func graphqlWSHandler(c *gin.Context, schema *graphql.Schema) {
+	upgrader := wsUpgrader
+	upgrader.Subprotocols = []string{graphqlWSProtocol}
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("GraphQL WebSocket upgrade failed")
+		return
+	}
+	defer conn.Close()
+
+	if conn.Subprotocol() != graphqlWSProtocol {
+		closeGraphQLWS(conn, websocket.CloseProtocolError, "Subprotocol "+graphqlWSProtocol+" required")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	stop := context.AfterFunc(shutdownCtx, func() {
+		closeGraphQLWS(conn, websocket.CloseGoingAway, "Server shutting down")
+	})
+	defer stop()
+
+	ws := &graphqlWSConn{
+		conn:       conn,
+		schema:     schema,
+		operations: make(map[string]context.CancelFunc),
+	}
+	defer ws.cancelAll()
+
+	conn.SetReadLimit(graphqlWSReadLimit)
+	_ = conn.SetReadDeadline(time.Now().Add(graphqlWSInitTimeout))
+
+	acknowledged := false
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if !acknowledged && errors.As(err, &netErr) && netErr.Timeout() {
+				closeGraphQLWS(conn, graphqlWSCloseInitTimeout, "Connection initialisation timeout")
+			}
+			return
+		}
+		var message graphqlWSMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			closeGraphQLWS(conn, graphqlWSCloseInvalidMessage, "Invalid message")
+			return
+		}
+
+		switch message.Type {
+		case "connection_init":
+			if acknowledged {
+				closeGraphQLWS(conn, graphqlWSCloseTooManyInits, "Too many initialisation requests")
+				return
+			}
+			acknowledged = true
+			// Subscriptions may run for as long as a batch job
+			_ = conn.SetReadDeadline(time.Time{})
+			ws.write(graphqlWSMessage{Type: "connection_ack"})
+		case "ping":
+			ws.write(graphqlWSMessage{Type: "pong"})
+		case "pong":
+		case "subscribe":
+			if !acknowledged {
+				closeGraphQLWS(conn, graphqlWSCloseUnauthorized, "Unauthorized")
+				return
+			}
+			var request graphqlRequest
+			if message.ID == "" || json.Unmarshal(message.Payload, &request) != nil {
+				closeGraphQLWS(conn, graphqlWSCloseInvalidMessage, "Invalid subscribe message")
+				return
+			}
+			if code, reason := ws.start(ctx, message.ID, request); code != 0 {
+				closeGraphQLWS(conn, code, reason)
+				return
+			}
+		case "complete":
+			ws.cancel(message.ID)
+		default:
+			closeGraphQLWS(conn, graphqlWSCloseInvalidMessage, "Unknown message type")
+			return
+		}
+	}
+}
+
+// start runs an operation in the background. It returns a close code when
+// the operation must not start.
+func (ws *graphqlWSConn) start(ctx context.Context, id string, request graphqlRequest) (int, string) {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	if _, exists := ws.operations[id]; exists {
+		return graphqlWSCloseSubscriberExists, "Subscriber for " + id + " already exists"
+	}
+	if len(ws.operations) >= maxGraphQLSubscriptions {
+		return graphqlWSCloseTooManyOperations, "Too many operations"
+	}
+
+	opCtx, cancel := context.WithCancel(ctx)
+	ws.operations[id] = cancel
+	go ws.run(opCtx, id, request)
+	return 0, ""
+}
+
+// run executes one operation and sends its results. Subscriptions send a
+// result per event; queries and mutations send one.
+func (ws *graphqlWSConn) run(ctx context.Context, id string, request graphqlRequest) {
+	// An error message ends the operation on its own, without complete
+	failed := false
+	defer func() { ws.finish(id, !failed) }()
+
+	params := graphql.Params{
+		Schema:         *ws.schema,
+		RequestString:  request.Query,
+		VariableValues: request.Variables,
+		OperationName:  request.OperationName,
+		Context:        ctx,
+	}
+
+	if !isSubscriptionOperation(request.Query, request.OperationName) {
+		failed = !ws.sendResult(id, graphql.Do(params))
+		return
+	}
+
+	results := graphql.Subscribe(params)
+	for result := range results {
+		if ctx.Err() != nil {
+			break
+		}
+		if !ws.sendResult(id, result) {
+			failed = true
+			break
+		}
+	}
+	// The executor blocks sending its last result; drain so it can exit
+	go func() {
+		for range results {
+		}
+	}()
+}
+
+// sendResult sends an execution result as next, or as error when the
+// operation failed before producing data. It reports whether it sent next.
+func (ws *graphqlWSConn) sendResult(id string, result *graphql.Result) bool {
+	if result.Data == nil && len(result.Errors) > 0 {
+		payload, _ := json.Marshal(result.Errors)
+		ws.write(graphqlWSMessage{ID: id, Type: "error", Payload: payload})
+		return false
+	}
+	payload, _ := json.Marshal(result)
+	ws.write(graphqlWSMessage{ID: id, Type: "next", Payload: payload})
+	return true
+}
+
+// finish forgets an operation and, unless the client completed it first,
+// stops it and optionally tells the client it is complete
+func (ws *graphqlWSConn) finish(id string, sendComplete bool) {
+	ws.lock.Lock()
+	cancel, active := ws.operations[id]
+	delete(ws.operations, id)
+	ws.lock.Unlock()
+
+	if active {
+		cancel()
+		if sendComplete {
+			ws.write(graphqlWSMessage{ID: id, Type: "complete"})
+		}
+	}
+}
+
+// cancel stops an operation the client completed
+func (ws *graphqlWSConn) cancel(id string) {
+	ws.lock.Lock()
+	cancel, active := ws.operations[id]
+	delete(ws.operations, id)
+	ws.lock.Unlock()
+
+	if active {
+		cancel()
+	}
+}
+
+// cancelAll stops every operation when the connection ends
+func (ws *graphqlWSConn) cancelAll() {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+
+	for id, cancel := range ws.operations {
+		cancel()
+		delete(ws.operations, id)
+	}
+}
+
+// write sends a message; failures surface as a read error on the connection
+func (ws *graphqlWSConn) write(message graphqlWSMessage) {
+	ws.writeLock.Lock()
+	defer ws.writeLock.Unlock()
+
+	_ = ws.conn.SetWriteDeadline(time.Now().Add(graphqlWSWriteTimeout))
+	if err := ws.conn.WriteJSON(message); err != nil {
+		appLogger.Debug().Err(err).Str("type", message.Type).Msg("Failed to write GraphQL WebSocket message")
+	}
+}
+
+// closeGraphQLWS sends a close frame with a protocol close code
+func closeGraphQLWS(conn *websocket.Conn, code int, reason string) {
+	deadline := time.Now().Add(time.Second)
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline)
+	_ = conn.Close()
+}
+
+// isSubscriptionOperation reports whether the operation to run is a
+// subscription. Unparseable documents are left to the executor to report.
+func isSubscriptionOperation(query, operationName string) bool {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return false
+	}
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if operationName == "" || (operation.Name != nil && operation.Name.Value == operationName) {
+			return operation.Operation == ast.OperationTypeSubscription
+		}
+	}
+	return false
+}
@@ -0,0 +1,647 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/dash"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/hls"
+	"github.com/rendiffdev/rendiff-probe/internal/validator"
+	"github.com/rendiffdev/rendiff-probe/internal/workspace"
+	probev1 "github.com/rendiffdev/rendiff-probe/pkg/api/probe/v1"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// probeGRPCServer implements probev1.ProbeServiceServer on top of the same
+// analysis helpers used by the REST handlers.
+type probeGRPCServer struct {
+	probev1.UnimplementedProbeServiceServer
+}
+
+// newGRPCServer creates the gRPC server with the probe and health services registered
+func newGRPCServer() *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(grpcLoggingUnaryInterceptor),
+		grpc.ChainStreamInterceptor(grpcLoggingStreamInterceptor),
+	}
+	if appConfig.EnableTenancy {
+		opts = append(opts,
+			grpc.ChainUnaryInterceptor(grpcTenantUnaryInterceptor),
+			grpc.ChainStreamInterceptor(grpcTenantStreamInterceptor))
+	}
+	if appConfig.EnableTracing {
+		opts = append(opts, grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	}
+	srv := grpc.NewServer(opts...)
+
+	probev1.RegisterProbeServiceServer(srv, &probeGRPCServer{})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(probev1.ProbeService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, healthServer)
+
+	// Only expose reflection in cloud/dev mode, mirroring GraphiQL
+	if appConfig.CloudMode {
+		reflection.Register(srv)
+	}
+
+	return srv
+}
+
+// startGRPCServer listens on the configured gRPC port and serves in the background
+func startGRPCServer(srv *grpc.Server, port int) error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on gRPC port %d: %w", port, err)
+	}
+
+	go func() {
+		appLogger.Info().Int("port", port).Msg("gRPC server starting")
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			appLogger.Fatal().Err(err).Msg("Failed to start gRPC server")
+		}
+	}()
+
+	return nil
+}
+
+// stopGRPCServer drains in-flight RPCs, forcing a stop once ctx expires
+func stopGRPCServer(ctx context.Context, srv *grpc.Server) {
+	done := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		appLogger.Error().Msg("gRPC server forced to shutdown")
+		srv.Stop()
+	}
+}
+
+// grpcLoggingUnaryInterceptor logs unary RPCs
+func grpcLoggingUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	appLogger.Info().
+		Str("method", info.FullMethod).
+		Str("code", status.Code(err).String()).
+		Dur("latency", time.Since(start)).
+		Msg("gRPC request")
+
+	return resp, err
+}
+
+// grpcLoggingStreamInterceptor logs streaming RPCs
+func grpcLoggingStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+
+	appLogger.Info().
+		Str("method", info.FullMethod).
+		Str("code", status.Code(err).String()).
+		Dur("latency", time.Since(start)).
+		Msg("gRPC stream")
+
+	return err
+}
+
+// ProbeUpload receives a client-streamed file and analyzes it
+func (s *probeGRPCServer) ProbeUpload(stream probev1.ProbeService_ProbeUploadServer) error {
+	first, err := stream.Recv()
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "No file provided")
+	}
+	meta := first.GetMetadata()
+	if meta == nil {
+		return status.Error(codes.InvalidArgument, "First message must contain upload metadata")
+	}
+
+	categories, err := ffmpeg.NormalizeQCCategories(meta.GetCategories())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Sanitize filename to prevent path traversal
+	safeFilename := validator.SanitizeFilename(meta.GetFilename())
+	if safeFilename == "" {
+		safeFilename = fmt.Sprintf("upload_%s", uuid.New().String()[:8])
+	}
+
+	tempFile, err := workspaceManager.Create(safeFilename)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Failed to create temporary file")
+		return storageStatus(err, "Failed to process file")
+	}
+	defer tempFile.Close()
+	tempPath := tempFile.Name()
+	defer func() {
+		if err := workspaceManager.Remove(tempPath); err != nil {
+			appLogger.Warn().Err(err).Str("path", tempPath).Msg("Failed to cleanup temp file")
+		}
+	}()
+
+	var written int64
+	maxSize := maxFileSize()
+	for {
+		msg, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return status.Error(codes.Canceled, "Upload interrupted")
+		}
+
+		chunk := msg.GetChunk()
+		written += int64(len(chunk))
+		if written > maxSize {
+			return status.Errorf(codes.ResourceExhausted, "File too large, max size is %d bytes", maxSize)
+		}
+		if _, err := tempFile.Write(chunk); err != nil {
+			appLogger.Error().Err(err).Msg("Failed to save uploaded file")
+			return storageStatus(err, "Failed to process file")
+		}
+	}
+
+	if written == 0 {
+		return status.Error(codes.InvalidArgument, "No file provided")
+	}
+
+	ctx := stream.Context()
+	result, err := analyzeFile(ctx, tempPath, categories, nil, "")
+	if err != nil {
+		appLogger.Error().Err(err).Str("filename", safeFilename).Msg("Analysis failed")
+		return status.Error(codes.Internal, "Analysis failed")
+	}
+
+	response := buildProbeResponse(result, safeFilename, written)
+	if meta.GetIncludeLlm() {
+		addLLMInsights(ctx, response, result, safeFilename)
+	}
+
+	return stream.SendAndClose(response)
+}
+
+// ProbeURL downloads and analyzes a remote media file
+func (s *probeGRPCServer) ProbeURL(ctx context.Context, req *probev1.ProbeURLRequest) (*probev1.ProbeResponse, error) {
+	// Validate URL for security (SSRF prevention)
+	if err := validator.ValidateURL(req.GetUrl()); err != nil {
+		appLogger.Warn().Str("url", req.GetUrl()).Err(err).Msg("URL validation failed")
+		return nil, status.Error(codes.InvalidArgument, "Invalid or blocked URL")
+	}
+
+	// Set timeout with bounds
+	timeout := defaultTimeout()
+	if req.GetTimeoutSeconds() > 0 {
+		timeout = time.Duration(req.GetTimeoutSeconds()) * time.Second
+		if timeout > maxTimeout() {
+			timeout = maxTimeout()
+		}
+	}
+
+	categories, err := ffmpeg.NormalizeQCCategories(req.GetCategories())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	switch req.GetMode() {
+	case "", probeModeDownload, probeModeDeep:
+	case probeModeStream, probeModeExpress:
+		var remote *remoteProbeResult
+		var err error
+		if req.GetMode() == probeModeExpress {
+			remote, err = analyzeRemoteURLExpress(ctx, req.GetUrl(), nil)
+		} else {
+			remote, err = analyzeRemoteURL(ctx, req.GetUrl(), int(req.GetProbeSizeMb()), int(req.GetAnalyzeDurationSeconds()), nil)
+		}
+		if err != nil {
+			appLogger.Warn().Err(err).Str("url", req.GetUrl()).Msg("Remote URL analysis failed")
+			return nil, status.Error(codes.Unavailable, "Remote analysis failed")
+		}
+
+		response := buildProbeResponse(remote.result, remote.filename, remote.size)
+		response.MetadataOnly = true
+		if req.GetIncludeLlm() {
+			addLLMInsights(ctx, response, remote.result, remote.filename)
+		}
+		return response, nil
+	default:
+		return nil, status.Error(codes.InvalidArgument, "Invalid mode, must be 'download', 'stream', 'express' or 'deep'")
+	}
+
+	tempPath, filename, err := downloadURL(ctx, req.GetUrl())
+	if err != nil {
+		appLogger.Warn().Err(err).Str("url", req.GetUrl()).Msg("URL download failed")
+		if errors.Is(err, workspace.ErrInsufficientStorage) {
+			return nil, storageStatus(err, "Failed to download from URL")
+		}
+		return nil, status.Error(codes.Unavailable, "Failed to download from URL")
+	}
+	defer func() {
+		if err := workspaceManager.Remove(tempPath); err != nil {
+			appLogger.Warn().Err(err).Str("path", tempPath).Msg("Failed to cleanup temp file")
+		}
+	}()
+
+	var size int64
+	if info, err := os.Stat(tempPath); err == nil {
+		size = info.Size()
+	}
+
+	analyze := analyzeFile
+	if req.GetMode() == probeModeDeep {
+		analyze = analyzeFileDeep
+	}
+	result, err := analyze(ctx, tempPath, categories, nil, "")
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Analysis failed")
+		return nil, status.Error(codes.Internal, "Analysis failed")
+	}
+
+	response := buildProbeResponse(result, filename, size)
+	if req.GetIncludeLlm() {
+		addLLMInsights(ctx, response, result, filename)
+	}
+
+	return response, nil
+}
+
+// AnalyzeHLS analyzes an HLS playlist
+func (s *probeGRPCServer) AnalyzeHLS(ctx context.Context, req *probev1.AnalyzeHLSRequest) (*probev1.AnalyzeHLSResponse, error) {
+	if err := validator.ValidateURL(req.GetManifestUrl()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid or blocked URL")
+	}
+
+	hlsRequest := &hls.HLSAnalysisRequest{
+		ManifestURL:         req.GetManifestUrl(),
+		AnalyzeSegments:     req.GetAnalyzeSegments(),
+		AnalyzeQuality:      req.GetAnalyzeQuality(),
+		ValidateCompliance:  req.GetValidateCompliance(),
+		PerformanceAnalysis: req.GetPerformanceAnalysis(),
+		MaxSegments:         int(req.GetMaxSegments()),
+	}
+
+	if hlsRequest.MaxSegments <= 0 || hlsRequest.MaxSegments > 100 {
+		hlsRequest.MaxSegments = 10
+	}
+
+	result, err := hlsAnalyzer.AnalyzeHLS(ctx, hlsRequest)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("HLS analysis failed")
+		return nil, status.Error(codes.Internal, "HLS analysis failed")
+	}
+
+	response := &probev1.AnalyzeHLSResponse{
+		AnalysisId:     result.ID.String(),
+		ManifestUrl:    req.GetManifestUrl(),
+		ProcessingTime: result.ProcessingTime.String(),
+		Timestamp:      timestamppb.Now(),
+	}
+
+	if analysis := result.Analysis; analysis != nil {
+		response.ManifestType = string(analysis.ManifestType)
+		response.VariantCount = int32(len(analysis.Variants))
+		response.SegmentCount = int32(len(analysis.Segments))
+		if analysis.ValidationResults != nil {
+			response.IsValid = analysis.ValidationResults.IsValid
+		}
+		response.AnalysisJson = marshalAnalysisJSON(analysis)
+	}
+
+	return response, nil
+}
+
+// AnalyzeDASH analyzes a DASH manifest
+func (s *probeGRPCServer) AnalyzeDASH(ctx context.Context, req *probev1.AnalyzeDASHRequest) (*probev1.AnalyzeDASHResponse, error) {
+	if err := validator.ValidateURL(req.GetManifestUrl()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid or blocked URL")
+	}
+
+	dashRequest := &dash.DASHAnalysisRequest{
+		ManifestURL:        req.GetManifestUrl(),
+		ProbeSegments:      req.GetProbeSegments(),
+		AnalyzeQuality:     req.GetAnalyzeQuality(),
+		ValidateCompliance: req.GetValidateCompliance(),
+		MaxSegments:        int(req.GetMaxSegments()),
+	}
+
+	if dashRequest.MaxSegments <= 0 || dashRequest.MaxSegments > maxDASHSampleSegments {
+		dashRequest.MaxSegments = defaultDASHSampleSegments
+	}
+
+	result, err := dashAnalyzer.AnalyzeDASH(ctx, dashRequest)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("DASH analysis failed")
+		return nil, status.Error(codes.Internal, "DASH analysis failed")
+	}
+
+	response := &probev1.AnalyzeDASHResponse{
+		AnalysisId:     result.ID.String(),
+		ManifestUrl:    req.GetManifestUrl(),
+		ProcessingTime: result.ProcessingTime.String(),
+		Timestamp:      timestamppb.Now(),
+	}
+
+	if analysis := result.Analysis; analysis != nil {
+		response.PresentationType = string(analysis.PresentationType)
+		response.PeriodCount = int32(len(analysis.Periods))
+		response.RepresentationCount = int32(len(analysis.Representations))
+		if analysis.ValidationResults != nil {
+			response.IsValid = analysis.ValidationResults.IsValid
+		}
+		response.AnalysisJson = marshalAnalysisJSON(analysis)
+	}
+
+	return response, nil
+}
+
+// SubmitBatch validates inputs and starts a batch job
+func (s *probeGRPCServer) SubmitBatch(ctx context.Context, req *probev1.SubmitBatchRequest) (*probev1.BatchJob, error) {
+	total := len(req.GetFiles()) + len(req.GetUrls())
+	if total == 0 {
+		return nil, status.Error(codes.InvalidArgument, "No files or URLs provided")
+	}
+
+	// Enforce batch size limit
+	if total > maxBatchItems() {
+		return nil, status.Errorf(codes.InvalidArgument, "Batch size exceeds limit of %d items", maxBatchItems())
+	}
+
+	// Validate all URLs upfront
+	for _, url := range req.GetUrls() {
+		if err := validator.ValidateURL(url); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid or blocked URL: %s", url)
+		}
+	}
+
+	// Validate file paths
+	for _, filePath := range req.GetFiles() {
+		if err := fileValidator.ValidateFilePath(filePath); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "Invalid file path: %s", filePath)
+		}
+	}
+
+	categories, err := ffmpeg.NormalizeQCCategories(req.GetCategories())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if err := validateCallbackURL(req.GetCallbackUrl()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	priority, err := ffmpeg.ParsePriority(req.GetPriority())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	job := startBatchJob(database.ScopeFromContext(ctx), req.GetFiles(), req.GetUrls(), req.GetIncludeLlm(), categories, req.GetCallbackUrl(), priority)
+	return batchJobToProto(job), nil
+}
+
+// GetBatchStatus returns the current state of a batch job
+func (s *probeGRPCServer) GetBatchStatus(ctx context.Context, req *probev1.GetBatchStatusRequest) (*probev1.BatchJob, error) {
+	job, err := lookupBatchJob(ctx, req.GetJobId())
+	if err != nil {
+		return nil, err
+	}
+	return batchJobToProto(job), nil
+}
+
+// WatchBatch streams progress events until the job finishes
+func (s *probeGRPCServer) WatchBatch(req *probev1.WatchBatchRequest, stream probev1.ProbeService_WatchBatchServer) error {
+	jobID := req.GetJobId()
+	job, err := lookupBatchJob(stream.Context(), jobID)
+	if err != nil {
+		return err
+	}
+
+	// Subscribe before reading the snapshot so no terminal update is missed.
+	// Jobs run by another replica are followed through its shared progress.
+	local := isLocalBatchJob(jobID)
+	var updates <-chan ProgressUpdate
+	var unsubscribe func()
+	if local {
+		updates, unsubscribe = subscribeProgress(jobID)
+	} else if updates, unsubscribe, err = subscribeSharedProgress(stream.Context(), jobID); err != nil {
+		return status.Errorf(codes.Unavailable, "Failed to follow batch job: %v", err)
+	}
+	defer unsubscribe()
+
+	batchLock.RLock()
+	snapshot := &probev1.ProgressEvent{
+		JobId:     jobID,
+		Progress:  batchProgress(job),
+		Status:    job.Status,
+		Message:   "Connected to progress stream",
+		Timestamp: timestamppb.Now(),
+	}
+	batchLock.RUnlock()
+
+	if err := stream.Send(snapshot); err != nil {
+		return err
+	}
+	if isBatchJobFinished(snapshot.Status) {
+		return nil
+	}
+
+	ticker := time.NewTicker(batchStatusPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-shutdownCtx.Done():
+			return status.Error(codes.Unavailable, "Server shutting down")
+		case update := <-updates:
+			event := &probev1.ProgressEvent{
+				JobId:     update.JobID,
+				Progress:  update.Progress,
+				Status:    update.Status,
+				Message:   update.Message,
+				Timestamp: timestamppb.Now(),
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+			if isBatchJobFinished(update.Status) {
+				return nil
+			}
+		case <-ticker.C:
+			if !local {
+				if shared, ok := loadSharedBatchJob(stream.Context(), jobID); ok {
+					job = shared
+				}
+			}
+			batchLock.RLock()
+			jobStatus := job.Status
+			batchLock.RUnlock()
+			if isBatchJobFinished(jobStatus) {
+				return stream.Send(&probev1.ProgressEvent{
+					JobId:     jobID,
+					Status:    jobStatus,
+					Message:   fmt.Sprintf("Batch job %s", jobStatus),
+					Timestamp: timestamppb.Now(),
+				})
+			}
+		}
+	}
+}
+
+// lookupBatchJob validates the job ID and returns the matching job, or the
+// snapshot of a job another replica runs, if the tenant of ctx may see it
+func lookupBatchJob(ctx context.Context, jobID string) (*BatchJob, error) {
+	if _, err := uuid.Parse(jobID); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "Invalid job ID format")
+	}
+
+	batchLock.RLock()
+	job, exists := batchJobs[jobID]
+	batchLock.RUnlock()
+
+	if !exists {
+		loadCtx, cancel := context.WithTimeout(context.Background(), coordinationTimeout)
+		defer cancel()
+		if job, exists = loadSharedBatchJob(loadCtx, jobID); !exists {
+			return nil, status.Error(codes.NotFound, "Job not found")
+		}
+	}
+	if !visibleBatchJob(ctx, job) {
+		return nil, status.Error(codes.NotFound, "Job not found")
+	}
+	return job, nil
+}
+
+func isBatchJobFinished(jobStatus string) bool {
+	return jobStatus == "completed" || jobStatus == "cancelled" || jobStatus == "failed"
+}
+
+// batchJobToProto converts a batch job to its protobuf representation
+func batchJobToProto(job *BatchJob) *probev1.BatchJob {
+	batchLock.RLock()
+	defer batchLock.RUnlock()
+
+	pb := &probev1.BatchJob{
+		Id:        job.ID,
+		Status:    job.Status,
+		Total:     int32(job.Total),
+		Completed: int32(job.Completed),
+		Failed:    int32(job.Failed),
+		Results:   make([]*probev1.BatchItemResult, 0, len(job.Results)),
+		CreatedAt: timestamppb.New(job.CreatedAt),
+		UpdatedAt: timestamppb.New(job.UpdatedAt),
+		Priority:  job.priority().String(),
+	}
+
+	for _, r := range job.Results {
+		item := &probev1.BatchItemResult{}
+		item.Type, _ = r["type"].(string)
+		item.Path, _ = r["path"].(string)
+		item.Url, _ = r["url"].(string)
+		item.Filename, _ = r["filename"].(string)
+		item.Status, _ = r["status"].(string)
+		item.Error, _ = r["error"].(string)
+		item.LlmReport, _ = r["llm_report"].(string)
+		if analysis, ok := r["analysis"]; ok {
+			item.AnalysisJson = marshalAnalysisJSON(analysis)
+		}
+		pb.Results = append(pb.Results, item)
+	}
+
+	return pb
+}
+
+// buildProbeResponse converts an FFprobe result to a ProbeResponse
+func buildProbeResponse(result *ffmpeg.FFprobeResult, filename string, size int64) *probev1.ProbeResponse {
+	response := &probev1.ProbeResponse{
+		AnalysisId:   uuid.New().String(),
+		Filename:     filename,
+		Size:         size,
+		Streams:      make([]*probev1.Stream, 0, len(result.Streams)),
+		AnalysisJson: marshalAnalysisJSON(result),
+		Timestamp:    timestamppb.Now(),
+	}
+
+	if f := result.Format; f != nil {
+		response.Format = &probev1.Format{
+			Filename:       f.Filename,
+			NbStreams:      int32(f.NBStreams),
+			FormatName:     f.FormatName,
+			FormatLongName: f.FormatLongName,
+			Duration:       f.Duration,
+			Size:           f.Size,
+			BitRate:        f.BitRate,
+		}
+	}
+
+	for _, st := range result.Streams {
+		response.Streams = append(response.Streams, &probev1.Stream{
+			Index:         int32(st.Index),
+			CodecName:     st.CodecName,
+			CodecType:     st.CodecType,
+			Width:         int32(st.Width),
+			Height:        int32(st.Height),
+			PixFmt:        st.PixFmt,
+			RFrameRate:    st.RFrameRate,
+			SampleRate:    st.SampleRate,
+			Channels:      int32(st.Channels),
+			ChannelLayout: st.ChannelLayout,
+			BitRate:       st.BitRate,
+		})
+	}
+
+	return response
+}
+
+// addLLMInsights attaches an LLM report (or error) to a probe response
+func addLLMInsights(ctx context.Context, response *probev1.ProbeResponse, result *ffmpeg.FFprobeResult, filename string) {
+	llmReport, err := generateLLMInsights(ctx, result, filename, false)
+	if err != nil {
+		appLogger.Warn().Err(err).Msg("LLM insights generation failed")
+		response.LlmError = "LLM analysis unavailable"
+		return
+	}
+	response.LlmReport = llmReport.Report
+}
+
+func marshalAnalysisJSON(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		appLogger.Warn().Err(err).Msg("Failed to marshal analysis for gRPC response")
+		return nil
+	}
+	return data
+}
+
+// storageStatus is storageFailure for gRPC, reporting quota and disk space
+// errors as ResourceExhausted
+func storageStatus(err error, message string) error {
+	if problem := storageFailure(err, apierrors.CodeInternalError, message); problem.Status == 507 {
+		return status.Error(codes.ResourceExhausted, problem.Detail)
+	}
+	return status.Error(codes.Internal, message)
+}
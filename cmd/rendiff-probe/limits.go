@@ -0,0 +1,160 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rendiffdev/rendiff-probe/internal/config"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+)
+
+// Request limits can change while the server runs. SIGHUP or
+// POST /api/v1/config/reload reads LIMITS_FILE again over the limits from
+// the environment and flags, and PUT /api/v1/limits changes them on this
+// replica until the next reload or restart. Handlers read the limits once
+// when a request starts, so jobs in flight keep the limits they were
+// accepted under.
+
+var (
+	// startupLimits are the limits from the environment and flags, which
+	// LIMITS_FILE is applied over on every reload
+	startupLimits config.Limits
+	activeLimits  atomic.Pointer[config.Limits]
+	// limitsLock serializes updates, so a reload and an API change
+	// don't overwrite each other
+	limitsLock sync.Mutex
+)
+
+// initLimits applies LIMITS_FILE over the configured limits
+func initLimits(cfg *config.Config) error {
+	startupLimits = cfg.Limits
+	limits, err := config.LoadLimits(cfg.LimitsFile, startupLimits)
+	if err != nil {
+		return err
+	}
+	activeLimits.Store(&limits)
+	return nil
+}
+
+func currentLimits() config.Limits {
+	return *activeLimits.Load()
+}
+
+// maxFileSize is the largest file in bytes an upload or download may be
+func maxFileSize() int64 {
+	return currentLimits().MaxFileSize
+}
+
+// maxBatchItems is the most files and URLs a batch job may have
+func maxBatchItems() int {
+	return currentLimits().MaxBatchItems
+}
+
+// defaultTimeout bounds analyses whose request sets no timeout
+func defaultTimeout() time.Duration {
+	return time.Duration(currentLimits().AnalysisTimeout) * time.Second
+}
+
+// maxTimeout is the longest timeout a request may ask for
+func maxTimeout() time.Duration {
+	return time.Duration(currentLimits().MaxAnalysisTimeout) * time.Second
+}
+
+// setLimits makes limits current. Callers hold limitsLock.
+func setLimits(limits config.Limits) {
+	activeLimits.Store(&limits)
+	if uploadManager != nil {
+		uploadManager.SetMaxFileSize(limits.MaxFileSize)
+	}
+}
+
+// reloadLimits reads LIMITS_FILE again, dropping changes made through the
+// API. The current limits are kept when the file is missing or invalid.
+func reloadLimits(trigger string) (config.Limits, error) {
+	limitsLock.Lock()
+	defer limitsLock.Unlock()
+
+	limits, err := config.LoadLimits(appConfig.LimitsFile, startupLimits)
+	if err != nil {
+		appLogger.Error().Err(err).Str("trigger", trigger).Str("limits_file", appConfig.LimitsFile).Msg("Failed to reload limits, keeping current limits")
+		return config.Limits{}, err
+	}
+	setLimits(limits)
+	appLogger.Info().
+		Str("trigger", trigger).
+		Int64("max_file_size", limits.MaxFileSize).
+		Int("max_batch_items", limits.MaxBatchItems).
+		Int("analysis_timeout", limits.AnalysisTimeout).
+		Int("max_analysis_timeout", limits.MaxAnalysisTimeout).
+		Msg("Limits reloaded")
+	return limits, nil
+}
+
+// watchReloadSignal reloads the limits on SIGHUP until shutdown
+func watchReloadSignal() {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-shutdownCtx.Done():
+			return
+		case <-hangup:
+			reloadLimits("signal")
+		}
+	}
+}
+
+// getLimitsHandler returns the limits in force on this replica
+func getLimitsHandler(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"limits":      currentLimits(),
+		"limits_file": appConfig.LimitsFile,
+	})
+}
+
+// updateLimitsHandler changes the limits given in the body on this replica,
+// keeping the others. The change lasts until the next reload or restart.
+func updateLimitsHandler(c *gin.Context) {
+	body, err := c.GetRawData()
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request body")
+		return
+	}
+
+	limitsLock.Lock()
+	limits, err := currentLimits().Apply(body)
+	if err == nil {
+		setLimits(limits)
+	}
+	limitsLock.Unlock()
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	appLogger.Info().
+		Str("user_id", c.GetString("user_id")).
+		Int64("max_file_size", limits.MaxFileSize).
+		Int("max_batch_items", limits.MaxBatchItems).
+		Int("analysis_timeout", limits.AnalysisTimeout).
+		Int("max_analysis_timeout", limits.MaxAnalysisTimeout).
+		Msg("Limits updated")
+	c.JSON(200, gin.H{"limits": limits})
+}
+
+// reloadConfigHandler reloads the limits as SIGHUP does
+func reloadConfigHandler(c *gin.Context) {
+	limits, err := reloadLimits("api")
+	if err != nil {
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to reload limits: "+err.Error())
+		return
+	}
+	c.JSON(200, gin.H{"limits": limits})
+}
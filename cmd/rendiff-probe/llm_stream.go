@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/services"
+)
+
+// errAnalysisFailed marks a stored analysis that has no result to report on
+var errAnalysisFailed = errors.New("analysis failed")
+
+// llmStreamMessage is the envelope for streamed LLM report messages. Both
+// WebSocket and SSE clients receive it as JSON; SSE uses the type as the
+// event name.
+type llmStreamMessage struct {
+	Type   string                `json:"type"` // chunk, complete or error
+	Text   string                `json:"text,omitempty"`
+	Report string                `json:"report,omitempty"`
+	Usage  *services.LLMMetadata `json:"usage,omitempty"`
+	Error  string                `json:"error,omitempty"`
+}
+
+// llmStreamHandler generates the LLM report for a stored analysis and
+// forwards the text as it is produced, over WebSocket when the request is an
+// upgrade and as Server-Sent Events otherwise. The finished report is saved
+// with the analysis.
+func llmStreamHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format")
+		return
+	}
+	refresh := c.Query("refresh") == "true"
+
+	filename, result, err := loadAnalysisResult(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrAnalysisNotFound):
+			respondError(c, 404, apierrors.CodeNotFound, "Analysis not found")
+		case errors.Is(err, errAnalysisFailed):
+			respondError(c, 409, apierrors.CodeConflict, "Analysis failed, there is no result to report on")
+		default:
+			appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to load analysis for LLM stream")
+			respondError(c, 500, apierrors.CodeInternalError, "Failed to load analysis")
+		}
+		return
+	}
+
+	select {
+	case llmStreamSlots <- struct{}{}:
+		defer func() { <-llmStreamSlots }()
+	default:
+		respondError(c, 503, apierrors.CodeServerBusy, "Too many LLM streams in progress, try again later")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), llmStreamTimeout)
+	defer cancel()
+	stop := context.AfterFunc(shutdownCtx, cancel)
+	defer stop()
+
+	stream, ok := openEventStream(c, cancel, llmStreamTimeout, llmStreamWriteTimeout)
+	if !ok {
+		return
+	}
+	defer stream.close()
+
+	send := func(message llmStreamMessage) error {
+		return stream.send(message.Type, message, nil)
+	}
+
+	analysis, err := llmService.StreamAnalysisReport(ctx, llmAnalysis(result, filename), refresh, func(text string) error {
+		return send(llmStreamMessage{Type: "chunk", Text: text})
+	})
+
+	switch {
+	case err == nil:
+		saveCtx, saveCancel := context.WithTimeout(context.WithoutCancel(ctx), analysisSaveTimeout)
+		if err := analysisStore.SetLLMReport(saveCtx, id, analysis.Report); err != nil && !errors.Is(err, database.ErrAnalysisNotFound) {
+			appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to store streamed LLM report")
+		}
+		saveCancel()
+		_ = send(llmStreamMessage{Type: "complete", Report: analysis.Report, Usage: &analysis.Metadata})
+	case stream.clientGone():
+		appLogger.Debug().Str("analysis_id", id.String()).Msg("LLM stream client disconnected")
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		_ = send(llmStreamMessage{Type: "error", Error: "LLM stream timed out"})
+	case ctx.Err() != nil:
+		_ = send(llmStreamMessage{Type: "error", Error: "Server shutting down"})
+	default:
+		appLogger.Warn().Err(err).Str("analysis_id", id.String()).Msg("LLM stream failed")
+		_ = send(llmStreamMessage{Type: "error", Error: "LLM analysis unavailable"})
+	}
+}
+
+// loadAnalysisResult returns the filename and result of a stored analysis,
+// from memory while it is kept for reports and from the database after
+func loadAnalysisResult(ctx context.Context, id uuid.UUID) (string, *ffmpeg.FFprobeResult, error) {
+	if stored, exists := memoryAnalysis(ctx, id); exists {
+		return stored.source.Filename, stored.result, nil
+	}
+
+	record, err := analysisStore.Get(ctx, id)
+	if err != nil {
+		return "", nil, err
+	}
+	if record.Status == database.AnalysisStatusFailed {
+		return "", nil, errAnalysisFailed
+	}
+
+	var result ffmpeg.FFprobeResult
+	if err := json.Unmarshal(record.Result, &result); err != nil {
+		return "", nil, fmt.Errorf("failed to decode stored analysis: %w", err)
+	}
+	return record.FileName, &result, nil
+}
@@ -12,6 +12,8 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"log"
@@ -20,6 +22,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -28,40 +31,144 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
-	"github.com/graphql-go/graphql"
 	"github.com/graphql-go/handler"
+	"github.com/rendiffdev/rendiff-probe/internal/batch"
 	"github.com/rendiffdev/rendiff-probe/internal/config"
+	"github.com/rendiffdev/rendiff-probe/internal/coordination"
+	"github.com/rendiffdev/rendiff-probe/internal/dash"
 	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
 	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
 	"github.com/rendiffdev/rendiff-probe/internal/hls"
 	"github.com/rendiffdev/rendiff-probe/internal/models"
+	"github.com/rendiffdev/rendiff-probe/internal/monitor"
+	"github.com/rendiffdev/rendiff-probe/internal/notify"
+	"github.com/rendiffdev/rendiff-probe/internal/report"
 	"github.com/rendiffdev/rendiff-probe/internal/services"
+	"github.com/rendiffdev/rendiff-probe/internal/tracing"
+	"github.com/rendiffdev/rendiff-probe/internal/upload"
 	"github.com/rendiffdev/rendiff-probe/internal/validator"
+	"github.com/rendiffdev/rendiff-probe/internal/watch"
+	"github.com/rendiffdev/rendiff-probe/internal/webhook"
+	"github.com/rendiffdev/rendiff-probe/internal/workspace"
 	"github.com/rendiffdev/rendiff-probe/pkg/logger"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
 )
 
 // Production constants
 const (
-	maxFileSize        = 5 * 1024 * 1024 * 1024 // 5GB max file size
-	maxRequestBodyMB   = 10                      // 10MB max JSON request body
-	maxBatchItems      = 100                     // Max items in batch processing
-	defaultTimeout     = 60 * time.Second
-	maxTimeout         = 30 * time.Minute
+	serviceVersion     = "2.0.0"
+	maxRequestBodyMB   = 10 // 10MB max JSON request body
 	shutdownTimeout    = 30 * time.Second
 	wsReadBufferSize   = 1024
 	wsWriteBufferSize  = 1024
-	batchJobTTL        = 1 * time.Hour  // TTL for completed batch jobs before cleanup
+	batchJobTTL        = 1 * time.Hour   // TTL for completed batch jobs before cleanup
+	pausedBatchJobTTL  = 24 * time.Hour  // TTL for paused batch jobs that are never resumed
 	batchCleanupPeriod = 5 * time.Minute // How often to run batch job cleanup
+	progressBufferSize = 16              // Buffered progress updates per subscriber
+
+	// batchStatusPollInterval is how often progress streams re-check job
+	// state, so that they also end for jobs cancelled without a final update
+	batchStatusPollInterval = 5 * time.Second
+
+	// Probe modes. Files are analyzed in full, express or deep mode; URLs
+	// are downloaded, probed in place (stream), probed in place in express
+	// mode, or downloaded and analyzed in deep mode.
+	probeModeFull     = "full"
+	probeModeDownload = "download"
+	probeModeStream   = "stream"
+	probeModeExpress  = "express"
+	probeModeDeep     = "deep"
+
+	// Stream-mode URL probing limits
+	defaultStreamProbeSizeMB    = 10
+	maxStreamProbeSizeMB        = 100
+	defaultStreamAnalyzeSeconds = 10
+	maxStreamAnalyzeSeconds     = 60
+
+	// DASH sample segment limits (per representation)
+	defaultDASHSampleSegments = 1
+	maxDASHSampleSegments     = 5
+
+	// Resumable upload limits
+	uploadChunkContentType = "application/offset+octet-stream"
+	maxUploadChunkSize     = 100 * 1024 * 1024 // 100MB max per PATCH
+	maxUploadSessions      = 50                // Concurrent uploading/processing sessions
+
+	// Quality comparison limits
+	defaultCompareTimeout = 10 * time.Minute // VMAF runs well below real time
+	maxCompareSubsample   = 30
+
+	// Stitch validation limits
+	defaultStitchTimeout = 10 * time.Minute // Loudness is measured over every part
+	maxStitchParts       = 20
+
+	// Report export
+	analysisTTL       = 1 * time.Hour // How long finished analyses stay available for reports
+	maxStoredAnalyses = 500
+
+	// Frame/packet streaming
+	maxFrameStreams         = 4 // Concurrent ffprobe -show_frames streams
+	frameStreamTimeout      = 2 * time.Hour
+	frameStreamWriteTimeout = 30 * time.Second // Per message, so stalled clients release their slot
+
+	// LLM report streaming
+	maxLLMStreams         = 8 // Concurrent streamed LLM reports
+	llmStreamTimeout      = 10 * time.Minute
+	llmStreamWriteTimeout = 30 * time.Second
+
+	// GraphQL subscriptions over WebSocket (graphql-transport-ws)
+	graphqlWSInitTimeout    = 10 * time.Second // Time allowed for connection_init
+	graphqlWSWriteTimeout   = 30 * time.Second
+	graphqlWSReadLimit      = 64 * 1024 // Max size of a client message
+	maxGraphQLSubscriptions = 16        // Concurrent operations per connection
+
+	// GraphQL multipart uploads
+	maxGraphQLUploads = 10 // File parts per request
+
+	// Live stream monitoring
+	maxMonitors             = 20
+	minMonitorInterval      = 10 * time.Second
+	maxMonitorSampleSeconds = 60
+	monitorSampleRetention  = 7 * 24 * time.Hour
+
+	// Analysis thumbnails
+	maxFilmstripFrames         = 24 // Stills kept per analysis
+	filmstripWidth             = 320
+	defaultContactSheetColumns = 4
 )
 
 // Global instances for services
 var (
-	ffprobeInstance *ffmpeg.FFprobe
-	hlsAnalyzer     *hls.HLSAnalyzer
-	llmService      *services.LLMService
-	appLogger       zerolog.Logger
-	appConfig       *config.Config
+	ffprobeInstance    *ffmpeg.FFprobe
+	hlsAnalyzer        *hls.HLSAnalyzer
+	dashAnalyzer       *dash.DASHAnalyzer
+	qualityComparator  *ffmpeg.QualityComparator
+	stitchValidator    *ffmpeg.StitchValidator
+	thumbnailExtractor *ffmpeg.ThumbnailExtractor
+	qctoolsReporter    *ffmpeg.QCToolsReporter
+	llmService         *services.LLMService
+	batchPool          *batch.WorkerPool
+	webhookSender      *webhook.Sender
+	uploadManager      *upload.Manager
+	workspaceManager   *workspace.Manager
+	appDB              *database.DB
+	analysisStore      *database.AnalysisStore
+	batchStore         *database.BatchStore
+	jobCoordinator     *coordination.Coordinator // nil unless job coordination is enabled
+	monitorManager     *monitor.Manager
+	monitorStore       *database.MonitorStore
+	folderWatcher      *watch.Watcher
+	watchStore         *database.WatchStore
+	thumbnailStore     *database.ThumbnailStore
+	qctoolsStore       *database.QCToolsStore
+	loudnessTargets    []ffmpeg.LoudnessTarget
+	appLogger          zerolog.Logger
+	appConfig          *config.Config
 
 	// Shutdown context for graceful termination
 	shutdownCtx    context.Context
@@ -73,11 +180,26 @@ var (
 	// Active WebSocket connections for progress updates
 	wsConnections = make(map[string]*websocket.Conn)
 	wsLock        sync.RWMutex
+	wsWriteLock   sync.Mutex
 
 	// Batch job status tracking
 	batchJobs = make(map[string]*BatchJob)
 	batchLock sync.RWMutex
 
+	// Progress subscribers (gRPC WatchBatch streams)
+	progressSubscribers = make(map[string]map[chan ProgressUpdate]struct{})
+	progressLock        sync.RWMutex
+
+	// Finished analyses kept for report export
+	storedAnalyses = make(map[string]*storedAnalysis)
+	analysesLock   sync.RWMutex
+
+	// Slots limiting concurrent frame/packet streams
+	frameStreamSlots = make(chan struct{}, maxFrameStreams)
+
+	// Slots limiting concurrent streamed LLM reports
+	llmStreamSlots = make(chan struct{}, maxLLMStreams)
+
 	// File path validator
 	fileValidator *validator.FilePathValidator
 )
@@ -92,8 +214,41 @@ type BatchJob struct {
 	Results   []map[string]interface{} `json:"results"`
 	CreatedAt time.Time                `json:"created_at"`
 	UpdatedAt time.Time                `json:"updated_at"`
-	ctx       context.Context
-	cancel    context.CancelFunc
+	// QCCategories restricts analysis of every item to these categories (empty = all)
+	QCCategories []ffmpeg.QCCategory `json:"qc_categories,omitempty"`
+	// CallbackURL receives a signed summary when the job finishes
+	CallbackURL string `json:"callback_url,omitempty"`
+	IncludeLLM  bool   `json:"include_llm,omitempty"`
+	// Priority is the worker pool lane of the job: "high", "normal" or "low"
+	Priority string `json:"priority,omitempty"`
+	// Scope is the tenant that submitted the job
+	Scope database.Scope `json:"scope"`
+	// Items are the job inputs in submission order; resuming skips done items
+	Items  []BatchItem `json:"items"`
+	ctx    context.Context
+	cancel context.CancelFunc
+	// probe runs the upload of an async single-file probe job and cleanup
+	// removes it; see probe_jobs.go
+	probe   func(context.Context) (int, gin.H)
+	cleanup func()
+	// stopStatus is the status to settle on once a stopped job's running
+	// items have returned: "paused" or "cancelled"
+	stopStatus string
+	// Interrupted is set on jobs a shutdown paused rather than a client;
+	// they resume when the server starts again
+	Interrupted bool `json:"interrupted,omitempty"`
+}
+
+// BatchItem is one file or URL of a batch job
+type BatchItem struct {
+	Type  string `json:"type"` // "file", "url" or "upload"
+	Input string `json:"input"`
+	Done  bool   `json:"done"`
+	// Phase is the step the item is in, see batch_progress.go
+	Phase         string  `json:"phase"`
+	PhaseProgress float64 `json:"phase_progress"`
+	// Progress is the item's overall completion across its phases
+	Progress float64 `json:"progress"`
 }
 
 // ProgressUpdate represents a WebSocket progress message
@@ -104,6 +259,8 @@ type ProgressUpdate struct {
 	Message   string  `json:"message"`
 	Status    string  `json:"status"`
 	Timestamp string  `json:"timestamp"`
+	// Item is set when the update is about a single batch item
+	Item *ItemProgress `json:"item,omitempty"`
 }
 
 func main() {
@@ -112,6 +269,12 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	// Command-line flags override the limits from the environment
+	cfg.Limits.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if err := cfg.Limits.Validate(); err != nil {
+		log.Fatalf("Invalid limits: %v", err)
+	}
 	appConfig = cfg
 
 	// Set Gin mode based on environment (CloudMode = development, !CloudMode = production)
@@ -125,9 +288,20 @@ func main() {
 		Bool("cloud_mode", cfg.CloudMode).
 		Msg("Starting rendiff-probe with full feature set")
 
+	// Apply LIMITS_FILE over the configured limits
+	if err := initLimits(cfg); err != nil {
+		appLogger.Fatal().Err(err).Str("limits_file", cfg.LimitsFile).Msg("Failed to load limits")
+	}
+
 	// Initialize shutdown context
 	shutdownCtx, shutdownCancel = context.WithCancel(context.Background())
 
+	// Initialize tracing (no-op unless ENABLE_TRACING is set)
+	shutdownTracing, err := tracing.Init(shutdownCtx, cfg, serviceVersion, appLogger)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialize tracing")
+	}
+
 	// Initialize file validator
 	fileValidator = validator.NewFilePathValidator()
 
@@ -144,6 +318,62 @@ func main() {
 		appLogger.Fatal().Err(err).Msg("Failed to initialize database")
 	}
 	defer db.Close()
+	appDB = db
+
+	analysisStore, err = database.NewAnalysisStore(context.Background(), db)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialize analysis store")
+	}
+
+	thumbnailStore, err = database.NewThumbnailStore(context.Background(), db)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialize thumbnail store")
+	}
+
+	qctoolsStore, err = database.NewQCToolsStore(context.Background(), db)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialize QCTools report store")
+	}
+
+	policyStore, err = database.NewPolicyStore(context.Background(), db)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialize policy store")
+	}
+	notificationStore, err = database.NewNotificationStore(context.Background(), db)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialize notification store")
+	}
+	if cfg.EnableTenancy {
+		tenantStore, err = database.NewTenantStore(context.Background(), db)
+		if err != nil {
+			appLogger.Fatal().Err(err).Msg("Failed to initialize tenant store")
+		}
+		appLogger.Info().Msg("Multi-tenancy enabled, API keys are required")
+	}
+
+	batchStore, err = database.NewBatchStore(context.Background(), db)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialize batch store")
+	}
+	if err := initJobCoordination(cfg); err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialize job coordination")
+	}
+	if err := loadBatchJobs(context.Background()); err != nil {
+		appLogger.Error().Err(err).Msg("Failed to restore batch jobs")
+	}
+
+	// Cap concurrent ffmpeg/ffprobe processes so bursts queue instead of
+	// exhausting the host
+	ffmpeg.SetProcessPool(ffmpeg.NewProcessPool(cfg.FFmpegMaxProcesses, cfg.FFmpegMaxQueued))
+	appLogger.Info().
+		Int("max_processes", cfg.FFmpegMaxProcesses).
+		Int("max_queued", cfg.FFmpegMaxQueued).
+		Msg("FFmpeg process pool initialized")
+
+	loudnessTargets, err = ffmpeg.ParseLoudnessTargets(cfg.LoudnessTargets)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Invalid LOUDNESS_TARGETS")
+	}
 
 	// Validate FFmpeg/FFprobe binary at startup
 	appLogger.Info().Msg("Validating FFmpeg/FFprobe binaries...")
@@ -158,27 +388,173 @@ func main() {
 			Str("ffprobe_path", cfg.FFprobePath).
 			Msg("FFprobe binary validation failed")
 	}
+	detectCapabilities(ctx, cfg)
+
+	if cfg.MediaInfoPath != "" {
+		ffprobeInstance.SetMediaInfoPath(cfg.MediaInfoPath)
+		appLogger.Info().Str("mediainfo_path", cfg.MediaInfoPath).Msg("MediaInfo cross-check enabled")
+	}
+
+	// Custom analyzers merged into every full analysis
+	if cfg.PluginDir != "" {
+		plugins, err := ffmpeg.LoadPluginAnalyzers(cfg.PluginDir, time.Duration(cfg.PluginTimeout)*time.Second)
+		if err == nil {
+			err = ffprobeInstance.SetPluginAnalyzers(plugins)
+		}
+		if err != nil {
+			appLogger.Fatal().Err(err).Str("plugin_dir", cfg.PluginDir).Msg("Failed to load analyzer plugins")
+		}
+		pluginNames = make([]string, len(plugins))
+		for i, plugin := range plugins {
+			pluginNames[i] = plugin.Name()
+		}
+		appLogger.Info().Strs("plugins", pluginNames).Msg("Analyzer plugins loaded")
+	}
 
 	// Initialize HLS Analyzer
 	hlsAnalyzer = hls.NewHLSAnalyzer(appLogger)
+	hlsAnalyzer.SetHTTPClient(newValidatedHTTPClient(defaultTimeout()))
 	appLogger.Info().Msg("HLS Analyzer initialized")
 
+	// Initialize DASH Analyzer
+	dashAnalyzer = dash.NewDASHAnalyzer(appLogger)
+	dashAnalyzer.SetHTTPClient(newValidatedHTTPClient(defaultTimeout()))
+	dashAnalyzer.SetFFprobe(ffprobeInstance)
+	dashAnalyzer.SetURLValidator(validator.ValidateURL)
+	appLogger.Info().Msg("DASH Analyzer initialized")
+
+	// Initialize quality comparator (VMAF/PSNR/SSIM)
+	qualityComparator = ffmpeg.NewQualityComparator(cfg.FFmpegPath, appLogger)
+	appLogger.Info().Msg("Quality comparator initialized")
+
+	// Initialize multi-part delivery stitch validation
+	stitchValidator = ffmpeg.NewStitchValidator(cfg.FFmpegPath, appLogger)
+
+	// Initialize thumbnail extractor for HTML/PDF reports
+	thumbnailExtractor = ffmpeg.NewThumbnailExtractor(cfg.FFmpegPath, appLogger)
+
+	// Initialize QCTools report export of frame-level signal statistics
+	qctoolsReporter = ffmpeg.NewQCToolsReporter(cfg.FFprobePath, appLogger)
+
+	// Initialize checksum calculator for file and stream checksums
+	checksumCalculator = ffmpeg.NewChecksumCalculator(cfg.FFmpegPath, appLogger)
+
+	// Initialize webhook sender (callbacks are disabled without a signing secret)
+	webhookSender = webhook.NewSender(webhook.Config{
+		Secret:     cfg.WebhookSecret,
+		Timeout:    time.Duration(cfg.WebhookTimeout) * time.Second,
+		MaxRetries: cfg.WebhookMaxRetries,
+	}, appLogger)
+	webhookSender.SetHTTPClient(newValidatedHTTPClient(time.Duration(cfg.WebhookTimeout) * time.Second))
+	webhookSender.SetURLValidator(validator.ValidateURL)
+	appLogger.Info().Bool("enabled", webhookSender.Enabled()).Msg("Webhook sender initialized")
+
+	// Initialize email, Slack and Teams notifications (email needs an SMTP relay)
+	notifier = notify.NewNotifier(notify.Config{
+		SMTP: notify.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+		},
+		Timeout: time.Duration(cfg.NotificationTimeout) * time.Second,
+	}, appLogger)
+	notifier.SetHTTPClient(newValidatedHTTPClient(time.Duration(cfg.NotificationTimeout) * time.Second))
+	notifier.SetURLValidator(validator.ValidateURL)
+	appLogger.Info().Bool("email", notifier.EmailEnabled()).Msg("Notifier initialized")
+
 	// Initialize LLM Service
 	llmService = services.NewLLMService(cfg, appLogger)
 	appLogger.Info().Msg("LLM Service initialized")
 
+	// Initialize batch worker pool
+	batchPool = batch.NewWorkerPool(batch.WorkerPoolConfig{
+		Workers:   cfg.BatchWorkers,
+		QueueSize: cfg.BatchQueueSize,
+		MaxPerJob: cfg.BatchJobParallelism,
+	}, appLogger)
+	batchPool.Start()
+
+	// Initialize temporary media storage, removing files left by a previous run
+	workspaceManager, err = workspace.NewManager(workspace.Config{
+		Dir:          cfg.WorkDir,
+		JobQuota:     cfg.JobDiskQuota,
+		TotalQuota:   cfg.DiskQuota,
+		MinFreeSpace: cfg.MinFreeDiskSpace,
+	}, appLogger)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialize work directory")
+	}
+
+	// Initialize resumable upload sessions
+	uploadManager, err = upload.NewManager(upload.Config{
+		Dir:         filepath.Join(cfg.UploadDir, "sessions"),
+		MaxFileSize: maxFileSize(),
+		SessionTTL:  time.Duration(cfg.UploadSessionTTL) * time.Second,
+		MaxSessions: maxUploadSessions,
+		Reserve:     workspaceManager.Reserve,
+	}, appLogger)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialize upload manager")
+	}
+	uploadManager.StartCleanup(shutdownCtx, batchCleanupPeriod)
+
+	// Initialize live stream monitoring, restarting saved monitors
+	monitorStore, err = database.NewMonitorStore(context.Background(), db)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialize monitor store")
+	}
+	monitorManager = monitor.NewManager(monitor.Config{
+		MaxMonitors:      maxMonitors,
+		MinInterval:      minMonitorInterval,
+		MaxSampleSeconds: maxMonitorSampleSeconds,
+	}, ffmpeg.NewLiveSampler(cfg.FFmpegPath, appLogger), appLogger)
+	monitorManager.SetAlertHandler(notifyMonitorAlert)
+	monitorManager.SetSampleHandler(recordMonitorSample)
+	if err := loadMonitors(context.Background()); err != nil {
+		appLogger.Error().Err(err).Msg("Failed to restore monitors")
+	}
+
+	// Initialize watch folder ingestion (disabled without WATCH_FOLDERS)
+	watchStore, err = database.NewWatchStore(context.Background(), db)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialize watch store")
+	}
+	if len(cfg.WatchFolders) > 0 {
+		if err := startWatchFolders(cfg); err != nil {
+			appLogger.Fatal().Err(err).Msg("Failed to start watch folders")
+		}
+	}
+
+	// Initialize direct browser uploads (disabled for local storage)
+	if err := startDirectUploads(cfg); err != nil {
+		appLogger.Fatal().Err(err).Msg("Failed to initialize direct upload storage")
+	}
+
 	appLogger.Info().Msg("All services initialized successfully")
 
 	// Start batch job cleanup goroutine
 	go cleanupBatchJobs()
 	appLogger.Info().Dur("ttl", batchJobTTL).Dur("period", batchCleanupPeriod).Msg("Batch job cleanup started")
 
+	// Start stored analysis cleanup goroutine
+	go cleanupAnalyses()
+
+	// Start monitor sample retention goroutine
+	go pruneMonitorSamples()
+
 	// Create Gin router with production settings
 	router := gin.New()
 
 	// Add recovery middleware with logging
 	router.Use(gin.Recovery())
 
+	// Start a server span per request, continuing any incoming trace context
+	if cfg.EnableTracing {
+		router.Use(otelgin.Middleware(tracing.ServiceName))
+	}
+
 	// Add custom logging middleware
 	router.Use(requestLoggingMiddleware())
 
@@ -213,23 +589,51 @@ func main() {
 		}
 	}()
 
+	// Start gRPC server alongside REST
+	var grpcServer *grpc.Server
+	if cfg.EnableGRPC {
+		grpcServer = newGRPCServer()
+		if err := startGRPCServer(grpcServer, cfg.GRPCPort); err != nil {
+			appLogger.Fatal().Err(err).Msg("Failed to start gRPC server")
+		}
+	}
+
+	// Resume the batch jobs the last shutdown interrupted
+	resumeInterruptedBatchJobs()
+
+	// Reload limits on SIGHUP
+	go watchReloadSignal()
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	appLogger.Info().Msg("Shutting down server...")
 
-	// Cancel all batch jobs
+	// Stop accepting work and let running batch items finish within
+	// DRAIN_TIMEOUT; items not started stay pending for the restart
+	draining.Store(true)
+	drainTimeout := time.Duration(cfg.DrainTimeout) * time.Second
+	serversStopped := stopServers(srv, grpcServer, drainTimeout+shutdownTimeout)
+	drainBatchJobs(drainTimeout)
+
+	// Pause the batch jobs still running
 	shutdownCancel()
 	cancelAllBatchJobs()
+	batchPool.Stop()
+	monitorManager.Close()
 
 	// Close all WebSocket connections
 	closeAllWebSocketConnections()
+	<-serversStopped
 
 	ctx, cancel = context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
-	if err := srv.Shutdown(ctx); err != nil {
-		appLogger.Error().Err(err).Msg("Server forced to shutdown")
+	if err := shutdownTracing(ctx); err != nil {
+		appLogger.Error().Err(err).Msg("Failed to flush traces")
+	}
+	if jobCoordinator != nil {
+		jobCoordinator.Close()
 	}
 
 	appLogger.Info().Msg("Server exited gracefully")
@@ -264,18 +668,31 @@ func checkWebSocketOrigin(r *http.Request) bool {
 	return false
 }
 
-// cancelAllBatchJobs cancels all running batch jobs during shutdown
+// cancelAllBatchJobs stops the batch jobs still running when draining ends.
+// They are persisted as paused, and those no client was pausing resume
+// after a restart.
 func cancelAllBatchJobs() {
-	batchLock.Lock()
-	defer batchLock.Unlock()
+	var stopped []*BatchJob
 
+	batchLock.Lock()
 	for id, job := range batchJobs {
-		if job.cancel != nil && job.Status == "processing" {
-			appLogger.Info().Str("job_id", id).Msg("Cancelling batch job")
+		if job.cancel != nil && (job.Status == "processing" || job.Status == "pausing") {
+			appLogger.Info().Str("job_id", id).Msg("Pausing batch job for shutdown")
 			job.cancel()
-			job.Status = "cancelled"
+			if job.Status == "processing" {
+				job.Interrupted = true
+			}
+			job.Status = "paused"
+			job.stopStatus = "paused"
+			job.UpdatedAt = time.Now()
+			stopped = append(stopped, job)
 		}
 	}
+	batchLock.Unlock()
+
+	for _, job := range stopped {
+		persistBatchJob(job)
+	}
 }
 
 // closeAllWebSocketConnections closes all active WebSocket connections
@@ -285,7 +702,9 @@ func closeAllWebSocketConnections() {
 
 	for id, conn := range wsConnections {
 		appLogger.Info().Str("job_id", id).Msg("Closing WebSocket connection")
+		wsWriteLock.Lock()
 		_ = conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "Server shutting down"))
+		wsWriteLock.Unlock()
 		conn.Close()
 	}
 	wsConnections = make(map[string]*websocket.Conn)
@@ -313,6 +732,9 @@ func cleanupBatchJobs() {
 						toDelete = append(toDelete, id)
 					}
 				}
+				if job.Status == "paused" && now.Sub(job.UpdatedAt) > pausedBatchJobTTL {
+					toDelete = append(toDelete, id)
+				}
 			}
 			batchLock.RUnlock()
 
@@ -323,6 +745,11 @@ func cleanupBatchJobs() {
 					appLogger.Debug().Str("job_id", id).Msg("Cleaned up expired batch job")
 				}
 				batchLock.Unlock()
+				for _, id := range toDelete {
+					if err := batchStore.Delete(context.Background(), id); err != nil {
+						appLogger.Error().Err(err).Str("job_id", id).Msg("Failed to delete expired batch job")
+					}
+				}
 				appLogger.Info().Int("count", len(toDelete)).Msg("Batch job cleanup completed")
 			}
 		}
@@ -365,14 +792,17 @@ func securityHeadersMiddleware() gin.HandlerFunc {
 }
 
 // requestSizeLimitMiddleware limits request body size
-// Note: Multipart form requests (file uploads) are excluded - they use maxFileSize limit
+// Note: Multipart form requests (file uploads) are excluded - they use the MAX_FILE_SIZE limit
 func requestSizeLimitMiddleware(maxBytes int64) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip limit for multipart form data (file uploads)
 		contentType := c.GetHeader("Content-Type")
 		if strings.HasPrefix(contentType, "multipart/form-data") {
-			// For file uploads, use the much larger maxFileSize limit
-			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFileSize)
+			// For file uploads, use the much larger MAX_FILE_SIZE limit
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFileSize())
+		} else if contentType == uploadChunkContentType {
+			// Resumable upload chunks
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadChunkSize)
 		} else {
 			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
 		}
@@ -381,27 +811,142 @@ func requestSizeLimitMiddleware(maxBytes int64) gin.HandlerFunc {
 }
 
 func setupRoutes(router *gin.Engine, cfg *config.Config) {
-	// Health check (no auth required)
+	// Health checks (no auth required)
 	router.GET("/health", healthHandler)
+	router.GET("/healthz", livenessHandler)
+	router.GET("/readyz", readinessHandler)
 
-	// API v1 routes
+	// Signed report links, authorized by their signature instead of a key
+	router.GET("/shared/analyses/:id", sharedAnalysisHandler)
+
+	// API v1 routes, authenticated by API key in multi-tenant mode. Each
+	// route names the least role that may call it; see rbac.go.
 	v1 := router.Group("/api/v1")
+	if cfg.EnableTenancy {
+		v1.Use(tenantAuthMiddleware())
+	}
+	viewer := requireRole(database.RoleViewer)
+	operator := requireRole(database.RoleOperator)
+	admin := requireRole(database.RoleAdmin)
+	deploymentOnly := requireDeploymentScope()
 	{
 		// File probing
-		v1.POST("/probe/file", probeFileHandler)
+		v1.POST("/probe/file", operator, probeFileHandler)
+		v1.GET("/probe/jobs/:id", viewer, probeJobStatusHandler)
+		v1.GET("/probe/jobs/:id/result", viewer, probeJobResultHandler)
+
+		// Capability discovery
+		v1.GET("/capabilities", viewer, capabilitiesHandler)
 
 		// URL probing
-		v1.POST("/probe/url", probeURLHandler)
+		v1.POST("/probe/url", operator, probeURLHandler)
 
 		// HLS analysis
-		v1.POST("/probe/hls", probeHLSHandler)
+		v1.POST("/probe/hls", operator, probeHLSHandler)
+
+		// DASH analysis
+		v1.POST("/probe/dash", operator, probeDASHHandler)
+
+		// Subtitle deliverable validation
+		v1.POST("/subtitles/validate", operator, validateSubtitlesHandler)
+
+		// Reference quality comparison
+		v1.POST("/compare", operator, compareHandler)
+
+		// Multi-part delivery stitch validation
+		v1.POST("/stitch/validate", operator, validateStitchHandler)
 
 		// Batch processing
-		v1.POST("/batch/analyze", batchAnalyzeHandler)
-		v1.GET("/batch/status/:id", batchStatusHandler)
+		v1.POST("/batch/analyze", operator, batchAnalyzeHandler)
+		v1.GET("/batch/status/:id", viewer, batchStatusHandler)
+		v1.GET("/batch/:id/export", viewer, batchExportHandler)
+		v1.DELETE("/batch/:id", operator, cancelBatchHandler)
+		v1.POST("/batch/:id/pause", operator, pauseBatchHandler)
+		v1.POST("/batch/:id/resume", operator, resumeBatchHandler)
+
+		// Delivery profiles accepted by the probe endpoints
+		v1.GET("/profiles", viewer, listProfilesHandler)
+
+		// Delivery profiles defined through the API
+		v1.GET("/policies", viewer, listPoliciesHandler)
+		v1.POST("/policies", admin, createPolicyHandler)
+		v1.GET("/policies/:id", viewer, getPolicyHandler)
+		v1.DELETE("/policies/:id", admin, deletePolicyHandler)
+
+		// Email, Slack and Teams channels notified of batch, policy and
+		// watch folder events
+		v1.POST("/notifications/channels", admin, createNotificationChannelHandler)
+		v1.GET("/notifications/channels", admin, listNotificationChannelsHandler)
+		v1.DELETE("/notifications/channels/:id", admin, deleteNotificationChannelHandler)
+		v1.POST("/notifications/channels/:id/test", admin, testNotificationChannelHandler)
+
+		// Stored file/URL analyses and report export. Comparisons and LLM
+		// streams generate new reports, so they need an operator.
+		v1.GET("/analyses", viewer, listAnalysesHandler)
+		v1.POST("/analyses/compare", operator, compareAnalysesHandler)
+		v1.GET("/analyses/diff", viewer, diffAnalysesHandler)
+		v1.GET("/analyses/:id", viewer, getAnalysisHandler)
+		v1.DELETE("/analyses/:id", operator, deleteAnalysisHandler)
+		v1.GET("/analyses/:id/report", viewer, analysisReportHandler)
+		v1.POST("/analyses/:id/share", operator, createShareLinkHandler)
+		v1.GET("/analyses/:id/markers", viewer, analysisMarkersHandler)
+		v1.GET("/analyses/:id/encoding-ladder", viewer, analysisEncodingLadderHandler)
+		v1.GET("/analyses/:id/thumbnails", viewer, analysisThumbnailsHandler)
+		v1.GET("/analyses/:id/qctools", viewer, analysisQCToolsHandler)
+		v1.POST("/analyses/:id/checksums/verify", viewer, verifyChecksumsHandler)
+		v1.GET("/analyses/:id/llm/stream", operator, llmStreamHandler)
+
+		// Resumable chunked uploads
+		v1.POST("/uploads", operator, createUploadHandler)
+		v1.HEAD("/uploads/:id", operator, uploadOffsetHandler)
+		v1.GET("/uploads/:id", viewer, uploadStatusHandler)
+		v1.PATCH("/uploads/:id", operator, appendUploadHandler)
+		v1.POST("/uploads/:id/complete", operator, completeUploadHandler)
+		v1.DELETE("/uploads/:id", operator, abortUploadHandler)
+		v1.POST("/direct-uploads", operator, createDirectUploadHandler)
+		v1.GET("/direct-uploads/:id", viewer, directUploadStatusHandler)
+
+		// Live stream monitors
+		v1.POST("/monitors", deploymentOnly, operator, createMonitorHandler)
+		v1.GET("/monitors", deploymentOnly, viewer, listMonitorsHandler)
+		v1.GET("/monitors/:id", deploymentOnly, viewer, getMonitorHandler)
+		v1.PUT("/monitors/:id", deploymentOnly, operator, updateMonitorHandler)
+		v1.DELETE("/monitors/:id", deploymentOnly, operator, deleteMonitorHandler)
+		v1.GET("/monitors/:id/samples", deploymentOnly, viewer, monitorSamplesHandler)
+
+		// Watch folder ingestion
+		v1.GET("/watch-folders", deploymentOnly, admin, listWatchFoldersHandler)
+		v1.GET("/watch-folders/results", deploymentOnly, admin, listWatchResultsHandler)
 
 		// WebSocket for progress
-		v1.GET("/ws/progress/:id", wsProgressHandler)
+		v1.GET("/ws/progress/:id", viewer, wsProgressHandler)
+
+		// Incremental ffprobe frame/packet output (WebSocket or SSE)
+		v1.GET("/stream/frames", operator, frameStreamHandler)
+	}
+
+	// Runtime limits apply to the whole deployment, so they always need
+	// the deployment key and are left out when there is none to check
+	if cfg.EnableTenancy || cfg.APIKey != "" {
+		deploymentKey := requireDeploymentKey()
+		v1.GET("/limits", deploymentKey, deploymentOnly, admin, getLimitsHandler)
+		v1.PUT("/limits", deploymentKey, deploymentOnly, admin, updateLimitsHandler)
+		v1.POST("/config/reload", deploymentKey, deploymentOnly, admin, reloadConfigHandler)
+	} else {
+		appLogger.Warn().Msg("API_KEY is not set, runtime limit endpoints are disabled")
+	}
+
+	// Tenant administration
+	if cfg.EnableTenancy {
+		v1.POST("/organizations", deploymentOnly, admin, createOrganizationHandler)
+		v1.GET("/organizations", deploymentOnly, admin, listOrganizationsHandler)
+		v1.DELETE("/organizations/:id", deploymentOnly, admin, deleteOrganizationHandler)
+		v1.POST("/organizations/:id/projects", deploymentOnly, admin, createProjectHandler)
+		v1.GET("/organizations/:id/projects", deploymentOnly, admin, listProjectsHandler)
+		v1.DELETE("/organizations/:id/projects/:project_id", deploymentOnly, admin, deleteProjectHandler)
+		v1.POST("/api-keys", admin, createAPIKeyHandler)
+		v1.GET("/api-keys", admin, listAPIKeysHandler)
+		v1.DELETE("/api-keys/:id", admin, revokeAPIKeyHandler)
 	}
 
 	// GraphQL endpoint
@@ -411,25 +956,23 @@ func setupRoutes(router *gin.Engine, cfg *config.Config) {
 		Pretty:   appConfig.CloudMode, // Only enable pretty output in cloud/dev mode
 		GraphiQL: appConfig.CloudMode, // Only enable GraphiQL in cloud/dev mode
 	})
-	router.POST("/api/v1/graphql", gin.WrapH(graphqlHandler))
-	router.GET("/api/v1/graphql", gin.WrapH(graphqlHandler))
+	v1.POST("/graphql", viewer, graphqlHTTPHandler(&schema, gin.WrapH(graphqlHandler)))
+	v1.GET("/graphql", viewer, graphqlHTTPHandler(&schema, gin.WrapH(graphqlHandler)))
+
+	// OpenAPI document of the routes above, public so client generators
+	// can fetch it; see openapi.go
+	router.GET("/api/v1/openapi.json", openAPIHandler(router, cfg))
 }
 
 // Health check handler
 func healthHandler(c *gin.Context) {
 	c.JSON(200, gin.H{
-		"status":  "healthy",
-		"service": "rendiff-probe",
-		"version": "2.0.0",
-		"features": gin.H{
-			"file_probe":       true,
-			"url_probe":        true,
-			"hls_analysis":     true,
-			"batch_processing": true,
-			"websocket":        true,
-			"graphql":          true,
-			"llm_insights":     true,
-		},
+		"status":           "healthy",
+		"service":          "rendiff-probe",
+		"version":          serviceVersion,
+		"features":         enabledFeatures(),
+		"batch_queue":      batchPool.Stats(),
+		"ffmpeg_processes": ffmpeg.CurrentProcessPool().Stats(),
 		"qc_tools": []string{
 			"AFD Analysis", "Dead Pixel Detection", "PSE Flash Analysis",
 			"HDR Analysis", "Audio Wrapping Analysis", "Endianness Detection",
@@ -444,18 +987,49 @@ func healthHandler(c *gin.Context) {
 	})
 }
 
+// enabledFeatures reports which optional features this server offers
+func enabledFeatures() gin.H {
+	return gin.H{
+		"file_probe":        true,
+		"url_probe":         true,
+		"hls_analysis":      true,
+		"dash_analysis":     true,
+		"quality_compare":   true,
+		"report_export":     true,
+		"thumbnails":        true,
+		"analysis_history":  true,
+		"delivery_profiles": true,
+		"batch_processing":  true,
+		"resumable_upload":  true,
+		"direct_upload":     directUploadStorage != nil,
+		"websocket":         true,
+		"frame_streaming":   true,
+		"llm_streaming":     true,
+		"llm_comparison":    true,
+		"live_monitoring":   true,
+		"watch_folders":     folderWatcher != nil,
+		"graphql":           true,
+		"llm_insights":      true,
+		"grpc":              appConfig.EnableGRPC,
+		"webhooks":          webhookSender.Enabled(),
+		"notifications":     true,
+		"tracing":           appConfig.EnableTracing,
+	}
+}
+
 // File probe handler with security validations
 func probeFileHandler(c *gin.Context) {
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
-		c.JSON(400, gin.H{"error": "No file provided"})
+		respondError(c, 400, apierrors.CodeBadRequest, "No file provided")
 		return
 	}
 	defer file.Close()
 
 	// Validate file size
-	if header.Size > maxFileSize {
-		c.JSON(413, gin.H{"error": "File too large", "max_size_bytes": maxFileSize})
+	maxSize := maxFileSize()
+	if header.Size > maxSize {
+		apierrors.Respond(c, apierrors.New(413, apierrors.CodeFileTooLarge, "File too large").With("max_size_bytes", maxSize))
 		return
 	}
 
@@ -467,228 +1041,805 @@ func probeFileHandler(c *gin.Context) {
 
 	// Check if LLM insights requested
 	includeLLM := c.PostForm("include_llm") == "true"
+	refreshLLM := c.PostForm("refresh_llm") == "true"
+	refreshCache := c.PostForm("refresh_cache") == "true"
 
-	// Create temp file with sanitized name
-	tempPath := filepath.Join(os.TempDir(), fmt.Sprintf("ffprobe_%d_%s", time.Now().UnixNano(), safeFilename))
-	tempFile, err := os.Create(tempPath)
+	// Optional express mode for a fast format and streams lookup, or deep
+	// mode to also decode the whole file
+	mode, err := parseFileProbeMode(c.PostForm("mode"))
 	if err != nil {
-		appLogger.Error().Err(err).Msg("Failed to create temporary file")
-		c.JSON(500, gin.H{"error": "Failed to process file"})
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
 		return
 	}
-	defer tempFile.Close()
-	defer func() {
-		if err := os.Remove(tempPath); err != nil {
-			appLogger.Warn().Err(err).Str("path", tempPath).Msg("Failed to cleanup temp file")
-		}
-	}()
 
-	// Copy file with size limit
-	written, err := io.CopyN(tempFile, file, maxFileSize+1)
-	if err != nil && err != io.EOF {
-		appLogger.Error().Err(err).Msg("Failed to save uploaded file")
-		c.JSON(500, gin.H{"error": "Failed to process file"})
+	// Optional comma-separated QC category selection
+	categories, err := ffmpeg.ParseQCCategories(c.PostForm("categories"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeInvalidCategory, err.Error())
 		return
 	}
-	if written > maxFileSize {
-		c.JSON(413, gin.H{"error": "File too large", "max_size_bytes": maxFileSize})
+
+	// Optional delivery profile to check the file against
+	profile, err := lookupProfile(c.Request.Context(), c.PostForm("profile"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeInvalidProfile, err.Error())
 		return
 	}
 
-	// Perform analysis
-	result, err := analyzeFile(c.Request.Context(), tempPath)
-	if err != nil {
-		appLogger.Error().Err(err).Str("filename", safeFilename).Msg("Analysis failed")
-		c.JSON(500, gin.H{"error": "Analysis failed"})
+	// Optional select_streams style selector for per-stream audio checks
+	streams := c.PostForm("streams")
+	if _, err := ffmpeg.ParseStreamSelector(streams); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
 		return
 	}
 
-	response := gin.H{
-		"status":                 "success",
-		"analysis_id":            uuid.New().String(),
-		"filename":               safeFilename,
-		"size":                   written,
-		"analysis":               result,
-		"qc_categories_analyzed": 19,
-		"timestamp":              time.Now(),
+	// Optional comma-separated checksum algorithms for the file and streams
+	checksums, err := ffmpeg.ParseChecksumAlgorithms(c.PostForm("checksums"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
 	}
 
-	// Add LLM insights if requested
-	if includeLLM {
-		llmReport, err := generateLLMInsights(c.Request.Context(), result, safeFilename)
-		if err != nil {
-			appLogger.Warn().Err(err).Msg("LLM insights generation failed")
-			response["llm_error"] = "LLM analysis unavailable"
-		} else {
-			response["llm_report"] = llmReport
-			response["llm_enabled"] = true
-		}
+	// Optional filmstrip selection, kept as the analysis thumbnails, tonemap
+	// operator for SDR previews of HDR files and QCTools report export
+	thumbnails, err := parseThumbnailForm(c)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
 	}
 
-	c.JSON(200, response)
-}
+	// Optional CSV or XML response instead of JSON
+	format, err := flatProbeFormat(c, c.PostForm("format"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
 
-// URL probe handler with security validations
-func probeURLHandler(c *gin.Context) {
-	var request struct {
-		URL        string `json:"url" binding:"required"`
-		IncludeLLM bool   `json:"include_llm"`
-		Timeout    int    `json:"timeout"`
+	// Optional JSON object of allowlisted ffprobe flags
+	probeOptions, err := ffmpeg.ParseProbeOptions([]byte(c.PostForm("ffprobe_options")))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
 	}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid request"})
+	// Optional segment of the file to analyze instead of all of it
+	segment, err := parseSegmentForm(c)
+	if err == nil {
+		err = checkSegment(segment, mode, probeOptions)
+	}
+	if err != nil {
+		respondError(c, 400, apierrors.CodeInvalidSegment, err.Error())
 		return
 	}
 
-	// Validate URL for security (SSRF prevention)
-	if err := validator.ValidateURL(request.URL); err != nil {
-		appLogger.Warn().Str("url", request.URL).Err(err).Msg("URL validation failed")
-		c.JSON(400, gin.H{"error": "Invalid or blocked URL"})
+	// Optional queueing priority for the ffmpeg processes of this request
+	priority, err := ffmpeg.ParsePriority(c.PostForm("priority"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
 		return
 	}
+	ctx := ffmpeg.WithPriority(c.Request.Context(), priority)
 
-	// Set timeout with bounds
-	timeout := defaultTimeout
-	if request.Timeout > 0 {
-		timeout = time.Duration(request.Timeout) * time.Second
-		if timeout > maxTimeout {
-			timeout = maxTimeout
-		}
+	// Optional webhook callback makes the request asynchronous
+	callbackURL := c.PostForm("callback_url")
+	if err := validateCallbackURL(callbackURL); err != nil {
+		respondError(c, 400, apierrors.CodeInvalidCallbackURL, err.Error())
+		return
 	}
 
-	// Download file from URL
-	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
-	defer cancel()
+	// Optional job handle to poll instead of waiting for the result
+	asyncJob := c.PostForm("async") == "true"
 
-	tempPath, filename, err := downloadURL(ctx, request.URL)
+	// Create temp file with sanitized name
+	tempFile, err := workspaceManager.Create(safeFilename)
 	if err != nil {
-		appLogger.Warn().Err(err).Str("url", request.URL).Msg("URL download failed")
-		c.JSON(500, gin.H{"error": "Failed to download from URL"})
+		appLogger.Error().Err(err).Msg("Failed to create temporary file")
+		apierrors.Respond(c, storageFailure(err, apierrors.CodeInternalError, "Failed to process file"))
 		return
 	}
-	defer func() {
-		if err := os.Remove(tempPath); err != nil {
+	defer tempFile.Close()
+	tempPath := tempFile.Name()
+
+	// The background probe takes over cleanup when it is asynchronous
+	cleanupTemp := func() {
+		if err := workspaceManager.Remove(tempPath); err != nil {
 			appLogger.Warn().Err(err).Str("path", tempPath).Msg("Failed to cleanup temp file")
 		}
+	}
+	async := false
+	defer func() {
+		if !async {
+			cleanupTemp()
+		}
 	}()
 
-	// Perform analysis
-	result, err := analyzeFile(ctx, tempPath)
-	if err != nil {
-		appLogger.Error().Err(err).Msg("Analysis failed")
-		c.JSON(500, gin.H{"error": "Analysis failed"})
+	// Copy file with size limit
+	written, err := io.CopyN(tempFile, file, maxSize+1)
+	if err != nil && err != io.EOF {
+		appLogger.Error().Err(err).Msg("Failed to save uploaded file")
+		apierrors.Respond(c, storageFailure(err, apierrors.CodeInternalError, "Failed to process file"))
+		return
+	}
+	if written > maxSize {
+		apierrors.Respond(c, apierrors.New(413, apierrors.CodeFileTooLarge, "File too large").With("max_size_bytes", maxSize))
+		return
+	}
+
+	analysisID := uuid.New().String()
+	run := func(ctx context.Context) (int, gin.H) {
+		ctx = ffmpeg.WithAnalysisSegment(ffmpeg.WithProbeOptions(ctx, probeOptions), segment)
+		if mode == probeModeExpress {
+			return runExpressFileProbe(ctx, analysisID, tempPath, safeFilename, written, includeLLM, refreshLLM, profile)
+		}
+		return runFileProbe(ctx, analysisID, tempPath, safeFilename, written, includeLLM, refreshLLM, refreshCache, mode == probeModeDeep, categories, profile, streams, checksums, thumbnails)
+	}
+
+	if asyncJob {
+		async = true
+		// The form priority was validated above and names a batch lane too
+		jobPriority, _ := ffmpeg.ParsePriority(c.PostForm("priority"))
+		job := startProbeJob(ctx, analysisID, safeFilename, callbackURL, jobPriority, run, cleanupTemp)
+		c.JSON(202, acceptedProbeJobResponse(job, analysisID))
+		return
+	}
+	if callbackURL != "" {
+		async = true
+		startBackgroundProbe(ctx, analysisID, callbackURL, maxTimeout(), run, func(int, gin.H) { cleanupTemp() })
+		c.JSON(202, acceptedProbeResponse(analysisID, callbackURL))
 		return
 	}
 
+	status, response := run(ctx)
+	respondProbe(c, format, status, response)
+}
+
+// runFileProbe analyzes an uploaded file that has been saved to tempPath,
+// decoding all of it in deep mode and adding the checksums requested
+func runFileProbe(ctx context.Context, analysisID, tempPath, filename string, size int64, includeLLM, refreshLLM, refreshCache, deep bool, categories []ffmpeg.QCCategory, profile *ffmpeg.DeliveryProfile, streams string, checksums []string, thumbnails thumbnailOptions) (int, gin.H) {
+	// Reuse the result of an identical earlier request, if any
+	cacheKey := resultCacheKey(ctx, tempPath, deep, categories, profile, streams)
+	var result *ffmpeg.FFprobeResult
+	var cached *database.AnalysisRecord
+	if !refreshCache {
+		result, cached = cachedResult(ctx, cacheKey)
+	}
+
+	// Perform analysis
+	if result == nil {
+		analyze := analyzeFile
+		if deep {
+			analyze = analyzeFileDeep
+		}
+		var err error
+		result, err = analyze(ctx, tempPath, categories, profile, streams)
+		if err != nil {
+			appLogger.Error().Err(err).Str("filename", filename).Msg("Analysis failed")
+			problem := analysisFailure(err, "Analysis failed")
+			recordAnalysis(ctx, analysisID, filename, "", analysisSourceUpload, size, nil, "", problem.Detail)
+			return problem.Status, analysisFailureResponse(problem, result)
+		}
+	}
+	addChecksums(ctx, tempPath, result, checksums)
+	stills := captureThumbnails(ctx, tempPath, result)
+	previews := captureTonemapPreviews(ctx, tempPath, result, thumbnails.tonemap)
+	storeAnalysis(ctx, analysisID, filename, result, stills, previews)
+
 	response := gin.H{
 		"status":                 "success",
-		"analysis_id":            uuid.New().String(),
-		"url":                    request.URL,
+		"analysis_id":            analysisID,
 		"filename":               filename,
+		"size":                   size,
 		"analysis":               result,
-		"qc_categories_analyzed": 19,
+		"qc_result":              report.BuildQCResult(report.Source{AnalysisID: analysisID, Filename: filename}, result),
+		"qc_categories_analyzed": countQCCategories(categories),
+		"cached":                 cached != nil,
 		"timestamp":              time.Now(),
 	}
+	if cached != nil {
+		response["cached_analysis_id"] = cached.ID
+	}
+	if deep {
+		response["mode"] = probeModeDeep
+	}
+	if filmstrip := saveFilmstrip(ctx, analysisID, tempPath, result, stills, thumbnails.filmstrip); filmstrip != nil {
+		response["thumbnails"] = filmstrip
+	}
+	if previews != nil {
+		response["tonemap_previews"] = previews
+	}
+	if thumbnails.qctools {
+		if qctools, err := saveQCToolsReport(ctx, analysisID, tempPath, result); err != nil {
+			response["qctools_error"] = err.Error()
+		} else {
+			response["qctools"] = qctools
+		}
+	}
 
 	// Add LLM insights if requested
-	if request.IncludeLLM {
-		llmReport, err := generateLLMInsights(ctx, result, filename)
+	var llmReport string
+	if includeLLM {
+		insights, err := generateLLMInsights(ctx, result, filename, refreshLLM)
 		if err != nil {
+			appLogger.Warn().Err(err).Msg("LLM insights generation failed")
 			response["llm_error"] = "LLM analysis unavailable"
 		} else {
+			llmReport = insights.Report
 			response["llm_report"] = llmReport
+			response["llm_usage"] = insights.Metadata
 			response["llm_enabled"] = true
 		}
 	}
+	recordAnalysis(ctx, analysisID, filename, "", analysisSourceUpload, size, result, llmReport, "")
+	rememberResult(ctx, analysisID, cacheKey)
 
-	c.JSON(200, response)
+	return 200, response
 }
 
-// HLS probe handler with validation
-func probeHLSHandler(c *gin.Context) {
-	var request struct {
-		ManifestURL         string `json:"manifest_url" binding:"required"`
-		AnalyzeSegments     bool   `json:"analyze_segments"`
-		AnalyzeQuality      bool   `json:"analyze_quality"`
-		ValidateCompliance  bool   `json:"validate_compliance"`
-		PerformanceAnalysis bool   `json:"performance_analysis"`
-		MaxSegments         int    `json:"max_segments"`
-		IncludeLLM          bool   `json:"include_llm"`
+// analysisFailure returns the problem of a failed analysis. A full ffmpeg
+// process queue is reported as 503 so clients retry later, an analysis
+// that ran out of time as 504, and a segment outside the file as 400.
+func analysisFailure(err error, message string) *apierrors.Problem {
+	switch {
+	case errors.Is(err, ffmpeg.ErrInvalidSegment):
+		return apierrors.New(400, apierrors.CodeInvalidSegment, err.Error())
+	case errors.Is(err, ffmpeg.ErrProcessQueueFull):
+		return apierrors.New(503, apierrors.CodeServerBusy, "Server busy, too many analyses queued")
+	case errors.Is(err, context.DeadlineExceeded):
+		return apierrors.New(504, apierrors.CodeFFprobeTimeout, "Analysis timed out")
+	}
+	return storageFailure(err, apierrors.CodeAnalysisFailed, message)
+}
+
+// analysisFailureResponse is the body of a failed analysis, with the
+// repair suggestions for a file the probe could not read
+func analysisFailureResponse(problem *apierrors.Problem, result *ffmpeg.FFprobeResult) gin.H {
+	response := problem.Payload()
+	if result != nil && len(result.RepairSuggestions) > 0 {
+		response["repair_suggestions"] = result.RepairSuggestions
 	}
+	return response
+}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid request"})
-		return
+// storageFailure maps an error writing temporary media to a problem,
+// reporting quota and disk space errors as 507 and others as 500 with code
+func storageFailure(err error, code, message string) *apierrors.Problem {
+	switch {
+	case errors.Is(err, workspace.ErrJobQuotaExceeded):
+		return apierrors.New(507, apierrors.CodeQuotaExceeded, "File exceeds the per-job disk quota")
+	case errors.Is(err, workspace.ErrInsufficientStorage):
+		return apierrors.New(507, apierrors.CodeInsufficientStorage, "Insufficient storage, try again later")
 	}
+	return apierrors.New(500, code, message)
+}
 
-	// Validate URL
-	if err := validator.ValidateURL(request.ManifestURL); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid or blocked URL"})
+// urlProbeRequest is the JSON body accepted by the URL probe endpoint
+type urlProbeRequest struct {
+	URL                    string   `json:"url" binding:"required"`
+	IncludeLLM             bool     `json:"include_llm"`
+	RefreshLLM             bool     `json:"refresh_llm"`   // bypass the cached LLM report
+	RefreshCache           bool     `json:"refresh_cache"` // analyze again even if the file was analyzed before, download mode only
+	Timeout                int      `json:"timeout"`
+	Mode                   string   `json:"mode"`                     // "download" (default), "stream", "express" or "deep"
+	ProbeSizeMB            int      `json:"probe_size_mb"`            // stream mode only
+	AnalyzeDurationSeconds int      `json:"analyze_duration_seconds"` // stream mode only
+	Categories             []string `json:"categories"`               // download mode only
+	Profile                string   `json:"profile"`                  // delivery profile ID, see /api/v1/profiles
+	Streams                string   `json:"streams"`                  // select_streams style selector, download mode only
+	Checksums              []string `json:"checksums"`                // checksum algorithms, download mode only
+	Format                 string   `json:"format"`                   // "json" (default), "csv" or "xml"
+	CallbackURL            string   `json:"callback_url"`             // respond 202 and POST the result here
+	Priority               string   `json:"priority"`                 // "low", "normal" (default) or "high"
+	thumbnailRequest                // filmstrip, SDR preview and QCTools report selection, download mode only
+	// FFprobeOptions overrides probesize, analyzeduration, read_intervals
+	// and select_streams, see ffmpeg.ProbeOptionNames
+	FFprobeOptions json.RawMessage `json:"ffprobe_options"`
+	// StartTime and Duration limit the analysis to a segment, in seconds;
+	// a negative start counts back from the end. Download and deep mode only.
+	StartTime float64 `json:"start_time"`
+	Duration  float64 `json:"duration"`
+}
+
+// URL probe handler with security validations
+func probeURLHandler(c *gin.Context) {
+	var request urlProbeRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
 		return
 	}
 
-	hlsRequest := &hls.HLSAnalysisRequest{
-		ManifestURL:         request.ManifestURL,
-		AnalyzeSegments:     request.AnalyzeSegments,
-		AnalyzeQuality:      request.AnalyzeQuality,
-		ValidateCompliance:  request.ValidateCompliance,
-		PerformanceAnalysis: request.PerformanceAnalysis,
-		MaxSegments:         request.MaxSegments,
+	if request.Mode == "" {
+		request.Mode = probeModeDownload
 	}
-
-	if hlsRequest.MaxSegments <= 0 || hlsRequest.MaxSegments > 100 {
-		hlsRequest.MaxSegments = 10
+	switch request.Mode {
+	case probeModeDownload, probeModeStream, probeModeExpress, probeModeDeep:
+	default:
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid mode, must be 'download', 'stream', 'express' or 'deep'")
+		return
 	}
 
-	result, err := hlsAnalyzer.AnalyzeHLS(c.Request.Context(), hlsRequest)
+	categories, err := ffmpeg.NormalizeQCCategories(request.Categories)
 	if err != nil {
-		appLogger.Error().Err(err).Msg("HLS analysis failed")
-		c.JSON(500, gin.H{"error": "HLS analysis failed"})
+		respondError(c, 400, apierrors.CodeInvalidCategory, err.Error())
 		return
 	}
 
-	response := gin.H{
-		"status":          "success",
-		"analysis_id":     result.ID.String(),
-		"manifest_url":    request.ManifestURL,
-		"analysis":        result.Analysis,
-		"processing_time": result.ProcessingTime.String(),
-		"timestamp":       time.Now(),
+	profile, err := lookupProfile(c.Request.Context(), request.Profile)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeInvalidProfile, err.Error())
+		return
 	}
 
-	c.JSON(200, response)
-}
+	if _, err := ffmpeg.ParseStreamSelector(request.Streams); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
 
-// Batch analyze handler with validation and limits
-func batchAnalyzeHandler(c *gin.Context) {
-	var request struct {
-		Files      []string `json:"files"`
-		URLs       []string `json:"urls"`
-		IncludeLLM bool     `json:"include_llm"`
+	if request.Checksums, err = ffmpeg.NormalizeChecksumAlgorithms(request.Checksums); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
 	}
 
-	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid request"})
+	format, err := flatProbeFormat(c, request.Format)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	thumbnails, err := request.options()
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	probeOptions, err := ffmpeg.ParseProbeOptions(request.FFprobeOptions)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	segment, err := ffmpeg.NewAnalysisSegment(request.StartTime, request.Duration)
+	if err == nil {
+		err = checkSegment(segment, request.Mode, probeOptions)
+	}
+	if err != nil {
+		respondError(c, 400, apierrors.CodeInvalidSegment, err.Error())
+		return
+	}
+
+	priority, err := ffmpeg.ParsePriority(request.Priority)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+	requestCtx := ffmpeg.WithPriority(c.Request.Context(), priority)
+
+	// Validate URL for security (SSRF prevention)
+	if err := validator.ValidateURL(request.URL); err != nil {
+		appLogger.Warn().Str("url", request.URL).Err(err).Msg("URL validation failed")
+		respondError(c, 400, apierrors.CodeURLBlocked, "Invalid or blocked URL")
+		return
+	}
+
+	if err := validateCallbackURL(request.CallbackURL); err != nil {
+		respondError(c, 400, apierrors.CodeInvalidCallbackURL, err.Error())
+		return
+	}
+
+	// Set timeout with bounds
+	timeout := defaultTimeout()
+	if request.Timeout > 0 {
+		timeout = time.Duration(request.Timeout) * time.Second
+		if timeout > maxTimeout() {
+			timeout = maxTimeout()
+		}
+	}
+
+	analysisID := uuid.New().String()
+	run := func(ctx context.Context) (int, gin.H) {
+		ctx = ffmpeg.WithAnalysisSegment(ffmpeg.WithProbeOptions(ctx, probeOptions), segment)
+		return runURLProbe(ctx, analysisID, &request, categories, profile, thumbnails)
+	}
+
+	if request.CallbackURL != "" {
+		startBackgroundProbe(requestCtx, analysisID, request.CallbackURL, timeout, run, nil)
+		c.JSON(202, acceptedProbeResponse(analysisID, request.CallbackURL))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(requestCtx, timeout)
+	defer cancel()
+
+	status, response := run(ctx)
+	respondProbe(c, format, status, response)
+}
+
+// runURLProbe analyzes a validated URL in download, stream, express or deep mode
+func runURLProbe(ctx context.Context, analysisID string, request *urlProbeRequest, categories []ffmpeg.QCCategory, profile *ffmpeg.DeliveryProfile, thumbnails thumbnailOptions) (int, gin.H) {
+	var result *ffmpeg.FFprobeResult
+	var filename string
+
+	if request.Mode == probeModeStream || request.Mode == probeModeExpress {
+		// Point ffprobe at the URL directly; only the probed headers are read
+		var remote *remoteProbeResult
+		var err error
+		if request.Mode == probeModeExpress {
+			remote, err = analyzeRemoteURLExpress(ctx, request.URL, profile)
+		} else {
+			remote, err = analyzeRemoteURL(ctx, request.URL, request.ProbeSizeMB, request.AnalyzeDurationSeconds, profile)
+		}
+		if err != nil {
+			appLogger.Warn().Err(err).Str("url", request.URL).Msg("Remote URL analysis failed")
+			problem := analysisFailure(err, "Remote analysis failed")
+			recordAnalysis(ctx, analysisID, validator.SanitizeFilename(extractFilename(request.URL, "")), request.URL, analysisSourceStream, 0, nil, "", problem.Detail)
+			return problem.Status, problem.Payload()
+		}
+		result, filename = remote.result, remote.filename
+		storeAnalysis(ctx, analysisID, filename, result, nil, nil)
+
+		response := gin.H{
+			"status":        "success",
+			"analysis_id":   analysisID,
+			"url":           request.URL,
+			"filename":      filename,
+			"mode":          request.Mode,
+			"metadata_only": true,
+			"analysis":      result,
+			"qc_result":     report.BuildQCResult(report.Source{AnalysisID: analysisID, Filename: filename}, result),
+			"timestamp":     time.Now(),
+		}
+		if remote.size > 0 {
+			response["size"] = remote.size
+		}
+
+		var llmReport string
+		if request.IncludeLLM {
+			insights, err := generateLLMInsights(ctx, result, filename, request.RefreshLLM)
+			if err != nil {
+				response["llm_error"] = "LLM analysis unavailable"
+			} else {
+				llmReport = insights.Report
+				response["llm_report"] = llmReport
+				response["llm_usage"] = insights.Metadata
+				response["llm_enabled"] = true
+			}
+		}
+		recordAnalysis(ctx, analysisID, filename, request.URL, analysisSourceStream, remote.size, result, llmReport, "")
+
+		return 200, response
+	}
+
+	// Download file from URL
+	tempPath, filename, err := downloadURL(ctx, request.URL)
+	if err != nil {
+		appLogger.Warn().Err(err).Str("url", request.URL).Msg("URL download failed")
+		problem := storageFailure(err, apierrors.CodeDownloadFailed, "Failed to download from URL")
+		recordAnalysis(ctx, analysisID, validator.SanitizeFilename(extractFilename(request.URL, "")), request.URL, analysisSourceURL, 0, nil, "", problem.Detail)
+		return problem.Status, problem.Payload()
+	}
+	defer func() {
+		if err := workspaceManager.Remove(tempPath); err != nil {
+			appLogger.Warn().Err(err).Str("path", tempPath).Msg("Failed to cleanup temp file")
+		}
+	}()
+
+	// Reuse the result of an identical earlier request, if any
+	size := fileSize(tempPath)
+	deep := request.Mode == probeModeDeep
+	cacheKey := resultCacheKey(ctx, tempPath, deep, categories, profile, request.Streams)
+	var cached *database.AnalysisRecord
+	if !request.RefreshCache {
+		result, cached = cachedResult(ctx, cacheKey)
+	}
+
+	// Perform analysis
+	if result == nil {
+		analyze := analyzeFile
+		if deep {
+			analyze = analyzeFileDeep
+		}
+		result, err = analyze(ctx, tempPath, categories, profile, request.Streams)
+		if err != nil {
+			appLogger.Error().Err(err).Msg("Analysis failed")
+			problem := analysisFailure(err, "Analysis failed")
+			recordAnalysis(ctx, analysisID, filename, request.URL, analysisSourceURL, size, nil, "", problem.Detail)
+			return problem.Status, analysisFailureResponse(problem, result)
+		}
+	}
+	addChecksums(ctx, tempPath, result, request.Checksums)
+	stills := captureThumbnails(ctx, tempPath, result)
+	previews := captureTonemapPreviews(ctx, tempPath, result, thumbnails.tonemap)
+	storeAnalysis(ctx, analysisID, filename, result, stills, previews)
+
+	response := gin.H{
+		"status":                 "success",
+		"analysis_id":            analysisID,
+		"url":                    request.URL,
+		"filename":               filename,
+		"mode":                   request.Mode,
+		"analysis":               result,
+		"qc_result":              report.BuildQCResult(report.Source{AnalysisID: analysisID, Filename: filename}, result),
+		"qc_categories_analyzed": countQCCategories(categories),
+		"cached":                 cached != nil,
+		"timestamp":              time.Now(),
+	}
+	if cached != nil {
+		response["cached_analysis_id"] = cached.ID
+	}
+	if filmstrip := saveFilmstrip(ctx, analysisID, tempPath, result, stills, thumbnails.filmstrip); filmstrip != nil {
+		response["thumbnails"] = filmstrip
+	}
+	if previews != nil {
+		response["tonemap_previews"] = previews
+	}
+	if thumbnails.qctools {
+		if qctools, err := saveQCToolsReport(ctx, analysisID, tempPath, result); err != nil {
+			response["qctools_error"] = err.Error()
+		} else {
+			response["qctools"] = qctools
+		}
+	}
+
+	// Add LLM insights if requested
+	var llmReport string
+	if request.IncludeLLM {
+		insights, err := generateLLMInsights(ctx, result, filename, request.RefreshLLM)
+		if err != nil {
+			response["llm_error"] = "LLM analysis unavailable"
+		} else {
+			llmReport = insights.Report
+			response["llm_report"] = llmReport
+			response["llm_usage"] = insights.Metadata
+			response["llm_enabled"] = true
+		}
+	}
+	recordAnalysis(ctx, analysisID, filename, request.URL, analysisSourceURL, size, result, llmReport, "")
+	rememberResult(ctx, analysisID, cacheKey)
+
+	return 200, response
+}
+
+// validateCallbackURL checks an optional webhook callback URL; empty is allowed
+func validateCallbackURL(callbackURL string) error {
+	if callbackURL == "" {
+		return nil
+	}
+	if err := webhookSender.ValidateCallbackURL(callbackURL); err != nil {
+		if errors.Is(err, webhook.ErrDisabled) {
+			return errors.New("Webhook callbacks are not enabled on this server")
+		}
+		appLogger.Warn().Str("callback_url", callbackURL).Err(err).Msg("Callback URL validation failed")
+		return errors.New("Invalid or blocked callback URL")
+	}
+	return nil
+}
+
+// acceptedProbeResponse is returned when a probe will be reported via webhook
+func acceptedProbeResponse(analysisID, callbackURL string) gin.H {
+	return gin.H{
+		"status":       "accepted",
+		"analysis_id":  analysisID,
+		"callback_url": callbackURL,
+		"message":      "Analysis started, result will be POSTed to callback_url",
+	}
+}
+
+// startBackgroundProbe runs a probe in the background. done, if set, receives
+// the outcome once the probe finishes, which is then POSTed to callbackURL
+// when one is given. The probe stays in the trace of the request that started
+// it and keeps its ffmpeg priority and tenant scope.
+func startBackgroundProbe(parent context.Context, analysisID, callbackURL string, timeout time.Duration, run func(context.Context) (int, gin.H), done func(status int, payload gin.H)) {
+	spanContext := trace.SpanContextFromContext(parent)
+	priority := ffmpeg.PriorityFromContext(parent)
+	scope := database.ScopeFromContext(parent)
+
+	go func() {
+		ctx := ffmpeg.WithPriority(trace.ContextWithSpanContext(shutdownCtx, spanContext), priority)
+		ctx = database.WithScope(ctx, scope)
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		status, payload := run(ctx)
+		if status != 200 {
+			markProbeFailed(payload, analysisID)
+		}
+
+		if done != nil {
+			done(status, payload)
+		}
+		deliverProbeCallback(callbackURL, analysisID, status, payload)
+	}()
+}
+
+// markProbeFailed adds the failed status and analysis ID to the body of a
+// failed background probe
+func markProbeFailed(payload gin.H, analysisID string) {
+	payload["status"] = "failed"
+	payload["analysis_id"] = analysisID
+}
+
+// deliverProbeCallback POSTs the outcome of a background probe to
+// callbackURL, if one is given. Failures are logged.
+func deliverProbeCallback(callbackURL, analysisID string, status int, payload gin.H) {
+	if callbackURL == "" {
+		return
+	}
+
+	event := webhook.EventProbeCompleted
+	if status != 200 {
+		event = webhook.EventProbeFailed
+	}
+
+	ctx, cancel := callbackContext()
+	defer cancel()
+
+	if err := webhookSender.Send(ctx, callbackURL, event, payload); err != nil {
+		appLogger.Error().Err(err).Str("analysis_id", analysisID).Msg("Failed to deliver probe callback")
+	}
+}
+
+// callbackContext bounds webhook delivery. It is detached from shutdownCtx so
+// work interrupted by shutdown is still reported to the caller.
+func callbackContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), shutdownTimeout)
+}
+
+// hlsProbeRequest is the JSON body accepted by the HLS probe endpoint
+type hlsProbeRequest struct {
+	ManifestURL         string `json:"manifest_url" binding:"required"`
+	AnalyzeSegments     bool   `json:"analyze_segments"`
+	AnalyzeQuality      bool   `json:"analyze_quality"`
+	ValidateCompliance  bool   `json:"validate_compliance"`
+	PerformanceAnalysis bool   `json:"performance_analysis"`
+	ValidateAlignment   bool   `json:"validate_alignment"`
+	InspectEncryption   bool   `json:"inspect_encryption"`
+	ValidateLowLatency  bool   `json:"validate_low_latency"`
+	MaxSegments         int    `json:"max_segments"`
+	IncludeLLM          bool   `json:"include_llm"`
+}
+
+// HLS probe handler with validation
+func probeHLSHandler(c *gin.Context) {
+	var request hlsProbeRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return
+	}
+
+	// Validate URL
+	if err := validator.ValidateURL(request.ManifestURL); err != nil {
+		respondError(c, 400, apierrors.CodeURLBlocked, "Invalid or blocked URL")
+		return
+	}
+
+	hlsRequest := &hls.HLSAnalysisRequest{
+		ManifestURL:         request.ManifestURL,
+		AnalyzeSegments:     request.AnalyzeSegments,
+		AnalyzeQuality:      request.AnalyzeQuality,
+		ValidateCompliance:  request.ValidateCompliance,
+		PerformanceAnalysis: request.PerformanceAnalysis,
+		ValidateAlignment:   request.ValidateAlignment,
+		InspectEncryption:   request.InspectEncryption,
+		ValidateLowLatency:  request.ValidateLowLatency,
+		MaxSegments:         request.MaxSegments,
+	}
+
+	if hlsRequest.MaxSegments <= 0 || hlsRequest.MaxSegments > 100 {
+		hlsRequest.MaxSegments = 10
+	}
+
+	result, err := hlsAnalyzer.AnalyzeHLS(c.Request.Context(), hlsRequest)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("HLS analysis failed")
+		respondError(c, 500, apierrors.CodeInternalError, "HLS analysis failed")
+		return
+	}
+
+	response := gin.H{
+		"status":          "success",
+		"analysis_id":     result.ID.String(),
+		"manifest_url":    request.ManifestURL,
+		"analysis":        result.Analysis,
+		"processing_time": result.ProcessingTime.String(),
+		"timestamp":       time.Now(),
+	}
+
+	c.JSON(200, response)
+}
+
+// dashProbeRequest is the JSON body accepted by the DASH probe endpoint
+type dashProbeRequest struct {
+	ManifestURL        string `json:"manifest_url" binding:"required"`
+	ProbeSegments      bool   `json:"probe_segments"`
+	AnalyzeQuality     bool   `json:"analyze_quality"`
+	ValidateCompliance bool   `json:"validate_compliance"`
+	InspectEncryption  bool   `json:"inspect_encryption"`
+	MaxSegments        int    `json:"max_segments"`
+}
+
+// DASH probe handler with validation
+func probeDASHHandler(c *gin.Context) {
+	var request dashProbeRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return
+	}
+
+	// Validate URL
+	if err := validator.ValidateURL(request.ManifestURL); err != nil {
+		respondError(c, 400, apierrors.CodeURLBlocked, "Invalid or blocked URL")
+		return
+	}
+
+	dashRequest := &dash.DASHAnalysisRequest{
+		ManifestURL:        request.ManifestURL,
+		ProbeSegments:      request.ProbeSegments,
+		AnalyzeQuality:     request.AnalyzeQuality,
+		ValidateCompliance: request.ValidateCompliance,
+		InspectEncryption:  request.InspectEncryption,
+		MaxSegments:        request.MaxSegments,
+	}
+
+	if dashRequest.MaxSegments <= 0 || dashRequest.MaxSegments > maxDASHSampleSegments {
+		dashRequest.MaxSegments = defaultDASHSampleSegments
+	}
+
+	result, err := dashAnalyzer.AnalyzeDASH(c.Request.Context(), dashRequest)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("DASH analysis failed")
+		respondError(c, 500, apierrors.CodeInternalError, "DASH analysis failed")
+		return
+	}
+
+	response := gin.H{
+		"status":          "success",
+		"analysis_id":     result.ID.String(),
+		"manifest_url":    request.ManifestURL,
+		"analysis":        result.Analysis,
+		"processing_time": result.ProcessingTime.String(),
+		"timestamp":       time.Now(),
+	}
+
+	c.JSON(200, response)
+}
+
+// batchAnalyzeRequest is the JSON body accepted by the batch endpoint
+type batchAnalyzeRequest struct {
+	Files       []string `json:"files"`
+	URLs        []string `json:"urls"`
+	IncludeLLM  bool     `json:"include_llm"`
+	Categories  []string `json:"categories"`
+	CallbackURL string   `json:"callback_url"`
+	Priority    string   `json:"priority"` // "high", "normal" (default) or "low"
+}
+
+// Batch analyze handler with validation and limits
+func batchAnalyzeHandler(c *gin.Context) {
+	var request batchAnalyzeRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
 		return
 	}
 
 	total := len(request.Files) + len(request.URLs)
 	if total == 0 {
-		c.JSON(400, gin.H{"error": "No files or URLs provided"})
+		respondError(c, 400, apierrors.CodeBadRequest, "No files or URLs provided")
 		return
 	}
 
 	// Enforce batch size limit
-	if total > maxBatchItems {
-		c.JSON(400, gin.H{"error": fmt.Sprintf("Batch size exceeds limit of %d items", maxBatchItems)})
+	if total > maxBatchItems() {
+		respondError(c, 400, apierrors.CodeBadRequest, fmt.Sprintf("Batch size exceeds limit of %d items", maxBatchItems()))
 		return
 	}
 
 	// Validate all URLs upfront
 	for _, url := range request.URLs {
 		if err := validator.ValidateURL(url); err != nil {
-			c.JSON(400, gin.H{"error": "Invalid or blocked URL", "url": url})
+			apierrors.Respond(c, apierrors.New(400, apierrors.CodeURLBlocked, "Invalid or blocked URL").With("url", url))
 			return
 		}
 	}
@@ -696,38 +1847,36 @@ func batchAnalyzeHandler(c *gin.Context) {
 	// Validate file paths
 	for _, filePath := range request.Files {
 		if err := fileValidator.ValidateFilePath(filePath); err != nil {
-			c.JSON(400, gin.H{"error": "Invalid file path", "path": filePath})
+			apierrors.Respond(c, apierrors.New(400, apierrors.CodeBadRequest, "Invalid file path").With("path", filePath))
 			return
 		}
 	}
 
-	// Create batch job with cancellation context
-	jobCtx, jobCancel := context.WithCancel(shutdownCtx)
-	jobID := uuid.New().String()
-	job := &BatchJob{
-		ID:        jobID,
-		Status:    "processing",
-		Total:     total,
-		Completed: 0,
-		Failed:    0,
-		Results:   make([]map[string]interface{}, 0),
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-		ctx:       jobCtx,
-		cancel:    jobCancel,
+	categories, err := ffmpeg.NormalizeQCCategories(request.Categories)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeInvalidCategory, err.Error())
+		return
 	}
 
-	batchLock.Lock()
-	batchJobs[jobID] = job
-	batchLock.Unlock()
+	if err := validateCallbackURL(request.CallbackURL); err != nil {
+		respondError(c, 400, apierrors.CodeInvalidCallbackURL, err.Error())
+		return
+	}
 
-	// Process in background with cancellation support
-	go processBatchJob(job, request.Files, request.URLs, request.IncludeLLM)
+	priority, err := ffmpeg.ParsePriority(request.Priority)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	job := startBatchJob(database.ScopeFromContext(c.Request.Context()), request.Files, request.URLs, request.IncludeLLM, categories, request.CallbackURL, priority)
+	jobID := job.ID
 
 	c.JSON(202, gin.H{
 		"status":     "accepted",
 		"job_id":     jobID,
 		"total":      total,
+		"priority":   job.Priority,
 		"message":    "Batch job started",
 		"status_url": fmt.Sprintf("/api/v1/batch/status/%s", jobID),
 		"ws_url":     fmt.Sprintf("/api/v1/ws/progress/%s", jobID),
@@ -740,7 +1889,7 @@ func batchStatusHandler(c *gin.Context) {
 
 	// Validate UUID format
 	if _, err := uuid.Parse(jobID); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid job ID format"})
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid job ID format")
 		return
 	}
 
@@ -749,21 +1898,34 @@ func batchStatusHandler(c *gin.Context) {
 	batchLock.RUnlock()
 
 	if !exists {
-		c.JSON(404, gin.H{"error": "Job not found"})
+		// Another replica may be running the job
+		if job, exists = loadSharedBatchJob(c.Request.Context(), jobID); !exists {
+			respondError(c, 404, apierrors.CodeNotFound, "Job not found")
+			return
+		}
+	}
+	if !visibleBatchJob(c.Request.Context(), job) {
+		respondError(c, 404, apierrors.CodeNotFound, "Job not found")
 		return
 	}
 
 	// Return job status without internal fields
-	c.JSON(200, gin.H{
+	batchLock.RLock()
+	response := gin.H{
 		"id":         job.ID,
 		"status":     job.Status,
 		"total":      job.Total,
 		"completed":  job.Completed,
 		"failed":     job.Failed,
-		"results":    job.Results,
+		"priority":   job.priority().String(),
+		"progress":   batchProgress(job),
+		"items":      append([]BatchItem(nil), job.Items...),
+		"results":    append([]map[string]interface{}(nil), job.Results...),
 		"created_at": job.CreatedAt,
 		"updated_at": job.UpdatedAt,
-	})
+	}
+	batchLock.RUnlock()
+	c.JSON(200, response)
 }
 
 // WebSocket progress handler
@@ -772,7 +1934,11 @@ func wsProgressHandler(c *gin.Context) {
 
 	// Validate UUID format
 	if _, err := uuid.Parse(jobID); err != nil {
-		c.JSON(400, gin.H{"error": "Invalid job ID format"})
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid job ID format")
+		return
+	}
+	if !visibleProgress(c.Request.Context(), jobID) {
+		respondError(c, 404, apierrors.CodeNotFound, "Job not found")
 		return
 	}
 
@@ -805,8 +1971,17 @@ func wsProgressHandler(c *gin.Context) {
 	batchLock.RUnlock()
 
 	if exists {
-		progress := float64(job.Completed) / float64(job.Total) * 100
-		sendProgressUpdate(jobID, progress, job.Status, "Connected to progress stream")
+		batchLock.RLock()
+		progress := batchProgress(job)
+		jobStatus := job.Status
+		batchLock.RUnlock()
+		sendProgressUpdate(jobID, progress, jobStatus, "Connected to progress stream")
+	} else if session, err := uploadManager.Get(jobID); err == nil {
+		sendProgressUpdate(jobID, session.Progress(), string(session.Status), "Connected to progress stream")
+	} else {
+		relayCtx, stopRelay := context.WithCancel(shutdownCtx)
+		defer stopRelay()
+		relaySharedProgress(relayCtx, jobID, conn)
 	}
 
 	// Keep connection alive with ping/pong
@@ -818,7 +1993,10 @@ func wsProgressHandler(c *gin.Context) {
 		case <-shutdownCtx.Done():
 			return
 		case <-ticker.C:
-			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			wsWriteLock.Lock()
+			err := conn.WriteMessage(websocket.PingMessage, nil)
+			wsWriteLock.Unlock()
+			if err != nil {
 				return
 			}
 		default:
@@ -835,8 +2013,28 @@ func wsProgressHandler(c *gin.Context) {
 
 // Helper functions
 
-func analyzeFile(ctx context.Context, filePath string) (*ffmpeg.FFprobeResult, error) {
-	options := ffmpeg.NewOptionsBuilder().
+// analyzeFile runs the full ffprobe analysis on a local file. categories
+// restricts QC analysis to a subset; nil runs every category. A non-nil
+// profile adds a delivery compliance check to the result. A non-empty
+// streams selector adds per-stream audio measurements.
+func analyzeFile(ctx context.Context, filePath string, categories []ffmpeg.QCCategory, profile *ffmpeg.DeliveryProfile, streams string) (*ffmpeg.FFprobeResult, error) {
+	return analyzeFileWithPhases(ctx, filePath, categories, profile, streams, nil)
+}
+
+// analyzeFileWithPhases is analyzeFile reporting each probe phase to onPhase
+func analyzeFileWithPhases(ctx context.Context, filePath string, categories []ffmpeg.QCCategory, profile *ffmpeg.DeliveryProfile, streams string, onPhase ffmpeg.PhaseFunc) (*ffmpeg.FFprobeResult, error) {
+	return runAnalysis(ctx, filePath, 5*time.Minute, analysisOptions(filePath, categories, profile, streams).OnPhase(onPhase))
+}
+
+// analyzeFileDeep is analyzeFile also decoding the whole file to report
+// decode errors and corrupt frames under data integrity
+func analyzeFileDeep(ctx context.Context, filePath string, categories []ffmpeg.QCCategory, profile *ffmpeg.DeliveryProfile, streams string) (*ffmpeg.FFprobeResult, error) {
+	return runAnalysis(ctx, filePath, deepAnalysisTimeout, analysisOptions(filePath, categories, profile, streams).DecodeScan())
+}
+
+// analysisOptions returns the options of a full analysis
+func analysisOptions(filePath string, categories []ffmpeg.QCCategory, profile *ffmpeg.DeliveryProfile, streams string) *ffmpeg.OptionsBuilder {
+	return ffmpeg.NewOptionsBuilder().
 		Input(filePath).
 		JSON().
 		ShowAll().
@@ -850,15 +2048,50 @@ func analyzeFile(ctx context.Context, filePath string) (*ffmpeg.FFprobeResult, e
 		CRC32Hash().
 		ProbeSizeMB(100).
 		AnalyzeDurationSeconds(60).
-		Build()
+		QCCategories(categories...).
+		DeliveryProfile(profile).
+		AnalysisStreams(streams).
+		ContentSampling(contentSamplingOptions()).
+		LoudnessTargets(loudnessTargets...)
+}
+
+// runAnalysis probes filePath with the options of builder, and the probe
+// options and segment of the request, within timeout
+func runAnalysis(ctx context.Context, filePath string, timeout time.Duration, builder *ffmpeg.OptionsBuilder) (*ffmpeg.FFprobeResult, error) {
+	// A zipped IMF package has no media stream of its own to probe
+	if ffmpeg.IsIMFPackage(filePath) {
+		ctx, cancel := context.WithTimeout(ctx, ffmpeg.DefaultIMFAnalysisTimeout)
+		defer cancel()
+		return ffprobeInstance.ProbeIMFPackage(ctx, filePath)
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	return ffprobeInstance.Probe(ctx, options)
+	builder.ProbeOptions(ffmpeg.ProbeOptionsFromContext(ctx)).Segment(ffmpeg.AnalysisSegmentFromContext(ctx))
+	return ffprobeInstance.Probe(ctx, builder.Build())
+}
+
+// contentSamplingOptions returns the configured content analysis sampling,
+// or nil when long assets are analyzed in full
+func contentSamplingOptions() *ffmpeg.ContentSamplingOptions {
+	if appConfig == nil || appConfig.ContentSamplingWindows <= 0 {
+		return nil
+	}
+	return &ffmpeg.ContentSamplingOptions{
+		Windows:       appConfig.ContentSamplingWindows,
+		WindowSeconds: appConfig.ContentSamplingWindowSeconds,
+		MinDuration:   appConfig.ContentSamplingMinDuration,
+	}
 }
 
 func downloadURL(ctx context.Context, urlStr string) (string, string, error) {
+	return downloadURLWithProgress(ctx, urlStr, nil)
+}
+
+// downloadURLWithProgress is downloadURL reporting the percentage downloaded
+// to onProgress. Without a Content-Length only 0 and 100 are reported.
+func downloadURLWithProgress(ctx context.Context, urlStr string, onProgress func(percent float64)) (string, string, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create request: %w", err)
@@ -867,19 +2100,7 @@ func downloadURL(ctx context.Context, urlStr string) (string, string, error) {
 	// Set reasonable headers
 	req.Header.Set("User-Agent", "rendiff-probe/2.0")
 
-	client := &http.Client{
-		Timeout: 5 * time.Minute,
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			if len(via) >= 10 {
-				return fmt.Errorf("too many redirects")
-			}
-			// Validate redirect URL
-			if err := validator.ValidateURL(req.URL.String()); err != nil {
-				return fmt.Errorf("redirect blocked: %w", err)
-			}
-			return nil
-		},
-	}
+	client := newValidatedHTTPClient(5 * time.Minute)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -892,7 +2113,8 @@ func downloadURL(ctx context.Context, urlStr string) (string, string, error) {
 	}
 
 	// Check content length
-	if resp.ContentLength > maxFileSize {
+	maxSize := maxFileSize()
+	if resp.ContentLength > maxSize {
 		return "", "", fmt.Errorf("file too large: %d bytes", resp.ContentLength)
 	}
 
@@ -903,27 +2125,171 @@ func downloadURL(ctx context.Context, urlStr string) (string, string, error) {
 		safeFilename = fmt.Sprintf("download_%s", uuid.New().String()[:8])
 	}
 
-	tempPath := filepath.Join(os.TempDir(), fmt.Sprintf("ffprobe_%d_%s", time.Now().UnixNano(), safeFilename))
-	tempFile, err := os.Create(tempPath)
+	tempFile, err := workspaceManager.Create(safeFilename)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to create temp file: %w", err)
 	}
 	defer tempFile.Close()
+	tempPath := tempFile.Name()
+
+	var body io.Reader = resp.Body
+	if onProgress != nil {
+		onProgress(0)
+		body = &progressReader{reader: resp.Body, total: resp.ContentLength, report: onProgress}
+	}
 
 	// Copy with size limit
-	written, err := io.CopyN(tempFile, resp.Body, maxFileSize+1)
+	written, err := io.CopyN(tempFile, body, maxSize+1)
 	if err != nil && err != io.EOF {
-		os.Remove(tempPath)
+		workspaceManager.Remove(tempPath)
 		return "", "", fmt.Errorf("failed to save file: %w", err)
 	}
-	if written > maxFileSize {
-		os.Remove(tempPath)
+	if written > maxSize {
+		workspaceManager.Remove(tempPath)
 		return "", "", fmt.Errorf("file too large: %d bytes", written)
 	}
+	if onProgress != nil {
+		onProgress(100)
+	}
 
 	return tempPath, safeFilename, nil
 }
 
+// countQCCategories reports how many QC categories a request runs
+func countQCCategories(categories []ffmpeg.QCCategory) int {
+	if len(categories) == 0 {
+		return len(ffmpeg.AllQCCategories)
+	}
+	return len(categories)
+}
+
+// newValidatedHTTPClient returns an HTTP client that re-validates every redirect target
+func newValidatedHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: otelhttp.NewTransport(http.DefaultTransport),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("too many redirects")
+			}
+			// Validate redirect URL
+			if err := validator.ValidateURL(req.URL.String()); err != nil {
+				return fmt.Errorf("redirect blocked: %w", err)
+			}
+			return nil
+		},
+	}
+}
+
+// remoteProbeResult holds the outcome of a stream-mode URL probe
+type remoteProbeResult struct {
+	result   *ffmpeg.FFprobeResult
+	filename string
+	size     int64
+}
+
+// analyzeRemoteURL probes a URL in place without downloading it.
+// Redirects are resolved (and validated) up front so ffprobe only ever
+// connects to the final, already-validated location. Only the container
+// and stream rules of a delivery profile can be checked this way.
+func analyzeRemoteURL(ctx context.Context, urlStr string, probeSizeMB, analyzeSeconds int, profile *ffmpeg.DeliveryProfile) (*remoteProbeResult, error) {
+	if probeSizeMB <= 0 {
+		probeSizeMB = defaultStreamProbeSizeMB
+	} else if probeSizeMB > maxStreamProbeSizeMB {
+		probeSizeMB = maxStreamProbeSizeMB
+	}
+	if analyzeSeconds <= 0 {
+		analyzeSeconds = defaultStreamAnalyzeSeconds
+	} else if analyzeSeconds > maxStreamAnalyzeSeconds {
+		analyzeSeconds = maxStreamAnalyzeSeconds
+	}
+
+	return probeRemoteURL(ctx, urlStr, ffmpeg.NewOptionsBuilder().
+		RemoteMetadata(probeSizeMB, analyzeSeconds).
+		DeliveryProfile(profile))
+}
+
+// probeRemoteURL resolves urlStr and probes the final location with the
+// options of builder and the probe options of the request
+func probeRemoteURL(ctx context.Context, urlStr string, builder *ffmpeg.OptionsBuilder) (*remoteProbeResult, error) {
+	finalURL, filename, size, err := resolveRemoteURL(ctx, urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	builder.ProbeOptions(remoteProbeOptions(ffmpeg.ProbeOptionsFromContext(ctx)))
+	result, err := ffprobeInstance.Probe(ctx, builder.Input(finalURL).Build())
+	if err != nil {
+		return nil, err
+	}
+
+	return &remoteProbeResult{result: result, filename: filename, size: size}, nil
+}
+
+// remoteProbeOptions returns options with the probe size and duration
+// capped at the stream mode maximums, since a URL probed in place is read
+// over the network
+func remoteProbeOptions(options *ffmpeg.ProbeOptions) *ffmpeg.ProbeOptions {
+	if options == nil {
+		return nil
+	}
+	capped := *options
+	if capped.ProbeSize > maxStreamProbeSizeMB*1024*1024 {
+		capped.ProbeSize = maxStreamProbeSizeMB * 1024 * 1024
+	}
+	if capped.AnalyzeDuration > maxStreamAnalyzeSeconds*1000000 {
+		capped.AnalyzeDuration = maxStreamAnalyzeSeconds * 1000000
+	}
+	return &capped
+}
+
+// resolveRemoteURL follows redirects for urlStr using a single-byte range request
+// and returns the final URL, a sanitized filename and the total size when known.
+// GET is used rather than HEAD because presigned URLs are usually signed for GET only.
+func resolveRemoteURL(ctx context.Context, urlStr string) (string, string, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", "rendiff-probe/2.0")
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err := newValidatedHTTPClient(30 * time.Second).Do(req)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to reach URL: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return "", "", 0, fmt.Errorf("HTTP error: %d", resp.StatusCode)
+	}
+
+	finalURL := resp.Request.URL.String()
+	if err := validator.ValidateURL(finalURL); err != nil {
+		return "", "", 0, fmt.Errorf("resolved URL blocked: %w", err)
+	}
+
+	// Total size comes from Content-Range (bytes 0-0/12345) for partial
+	// responses; zero means unknown.
+	var size int64
+	if resp.StatusCode == http.StatusPartialContent {
+		if _, total, ok := strings.Cut(resp.Header.Get("Content-Range"), "/"); ok {
+			if n, err := strconv.ParseInt(total, 10, 64); err == nil {
+				size = n
+			}
+		}
+	} else if resp.ContentLength > 0 {
+		size = resp.ContentLength
+	}
+
+	filename := validator.SanitizeFilename(extractFilename(finalURL, resp.Header.Get("Content-Disposition")))
+	if filename == "" {
+		filename = fmt.Sprintf("stream_%s", uuid.New().String()[:8])
+	}
+
+	return finalURL, filename, size, nil
+}
+
 // extractFilename safely extracts filename from URL or Content-Disposition
 func extractFilename(urlStr, contentDisposition string) string {
 	// Try Content-Disposition first
@@ -940,7 +2306,16 @@ func extractFilename(urlStr, contentDisposition string) string {
 	return filepath.Base(strings.Split(urlStr, "?")[0])
 }
 
-func generateLLMInsights(ctx context.Context, result *ffmpeg.FFprobeResult, filename string) (string, error) {
+// generateLLMInsights writes an LLM report for a result along with the
+// provider's usage. Reports are cached for identical FFprobe data unless
+// refresh is set.
+func generateLLMInsights(ctx context.Context, result *ffmpeg.FFprobeResult, filename string, refresh bool) (*services.AnalysisReport, error) {
+	return llmService.GenerateAnalysisReport(ctx, llmAnalysis(result, filename), refresh)
+}
+
+// llmAnalysis converts an FFprobe result into the analysis model the LLM
+// prompts are built from
+func llmAnalysis(result *ffmpeg.FFprobeResult, filename string) *models.Analysis {
 	// Create analysis model from FFprobe result
 	analysis := &models.Analysis{
 		ID:       uuid.New(),
@@ -966,127 +2341,155 @@ func generateLLMInsights(ctx context.Context, result *ffmpeg.FFprobeResult, file
 		}
 	}
 
-	return llmService.GenerateAnalysis(ctx, analysis)
+	return analysis
 }
 
-func processBatchJob(job *BatchJob, files []string, urls []string, includeLLM bool) {
-	ctx := job.ctx
-
-	// Process files
+// startBatchJob registers a new batch job owned by scope and processes it in
+// the background. Inputs must already be validated by the caller.
+func startBatchJob(scope database.Scope, files []string, urls []string, includeLLM bool, categories []ffmpeg.QCCategory, callbackURL string, priority ffmpeg.Priority) *BatchJob {
+	items := make([]BatchItem, 0, len(files)+len(urls))
 	for _, filePath := range files {
-		select {
-		case <-ctx.Done():
-			appLogger.Info().Str("job_id", job.ID).Msg("Batch job cancelled")
-			batchLock.Lock()
-			job.Status = "cancelled"
-			job.UpdatedAt = time.Now()
-			batchLock.Unlock()
-			return
-		default:
-		}
+		items = append(items, BatchItem{Type: "file", Input: filePath, Phase: itemPhaseQueued})
+	}
+	for _, url := range urls {
+		items = append(items, BatchItem{Type: "url", Input: url, Phase: itemPhaseQueued})
+	}
 
-		result, err := analyzeFile(ctx, filePath)
+	job := newBatchJob(scope, items, includeLLM, categories, callbackURL, priority)
+	launchBatchJob(job)
+	return job
+}
 
-		batchLock.Lock()
-		if err != nil {
-			job.Failed++
-			job.Results = append(job.Results, map[string]interface{}{
-				"type":   "file",
-				"path":   filePath,
-				"status": "failed",
-				"error":  "Analysis failed",
-			})
-		} else {
-			job.Completed++
-			resultMap := map[string]interface{}{
-				"type":     "file",
-				"path":     filePath,
-				"status":   "success",
-				"analysis": result,
-			}
-			if includeLLM {
-				llmReport, err := generateLLMInsights(ctx, result, filepath.Base(filePath))
-				if err == nil {
-					resultMap["llm_report"] = llmReport
-				}
-			}
-			job.Results = append(job.Results, resultMap)
-		}
-		job.UpdatedAt = time.Now()
-		batchLock.Unlock()
+// newBatchJob returns a processing job owned by scope with a cancellation
+// context
+func newBatchJob(scope database.Scope, items []BatchItem, includeLLM bool, categories []ffmpeg.QCCategory, callbackURL string, priority ffmpeg.Priority) *BatchJob {
+	jobCtx, jobCancel := context.WithCancel(batchJobContext(priority))
+	return &BatchJob{
+		ID:           uuid.New().String(),
+		Status:       "processing",
+		Total:        len(items),
+		Completed:    0,
+		Failed:       0,
+		Results:      make([]map[string]interface{}, 0),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+		QCCategories: categories,
+		CallbackURL:  callbackURL,
+		IncludeLLM:   includeLLM,
+		Priority:     priority.String(),
+		Scope:        scope,
+		Items:        items,
+		ctx:          jobCtx,
+		cancel:       jobCancel,
+	}
+}
 
-		// Send progress update
-		progress := float64(job.Completed+job.Failed) / float64(job.Total) * 100
-		sendProgressUpdate(job.ID, progress, "processing", fmt.Sprintf("Processed: %s", filepath.Base(filePath)))
+// launchBatchJob registers a new job and processes it in the background
+func launchBatchJob(job *BatchJob) {
+	batchLock.Lock()
+	batchJobs[job.ID] = job
+	batchLock.Unlock()
+	release := claimNewBatchJob(job.ID)
+	persistBatchJob(job)
+
+	// Process in background with cancellation support
+	runBatchJob(job, release)
+}
+
+// batchJobContext returns the base context of a batch job's items. Their
+// ffmpeg processes queue behind interactive requests, except for high
+// priority jobs, which queue with them.
+func batchJobContext(priority ffmpeg.Priority) context.Context {
+	if priority == ffmpeg.PriorityHigh {
+		return ffmpeg.WithPriority(shutdownCtx, ffmpeg.PriorityNormal)
 	}
+	return ffmpeg.WithPriority(shutdownCtx, ffmpeg.PriorityLow)
+}
 
-	// Process URLs
-	for _, url := range urls {
-		select {
-		case <-ctx.Done():
-			appLogger.Info().Str("job_id", job.ID).Msg("Batch job cancelled")
-			batchLock.Lock()
-			job.Status = "cancelled"
-			job.UpdatedAt = time.Now()
-			batchLock.Unlock()
-			return
-		default:
-		}
+// priority returns the worker pool lane of the job; jobs saved before
+// priorities existed are normal
+func (job *BatchJob) priority() ffmpeg.Priority {
+	priority, _ := ffmpeg.ParsePriority(job.Priority)
+	return priority
+}
 
-		tempPath, filename, err := downloadURL(ctx, url)
-		if err != nil {
-			batchLock.Lock()
-			job.Failed++
-			job.Results = append(job.Results, map[string]interface{}{
-				"type":   "url",
-				"url":    url,
-				"status": "failed",
-				"error":  "Download failed",
-			})
-			job.UpdatedAt = time.Now()
-			batchLock.Unlock()
+// processBatchJob runs the job's items that are not yet done, so a resumed
+// job picks up where it was paused. release gives up the job's claim once
+// it stops, and may be called more than once.
+func processBatchJob(job *BatchJob, release func()) {
+	defer release()
 
-			progress := float64(job.Completed+job.Failed) / float64(job.Total) * 100
-			sendProgressUpdate(job.ID, progress, "processing", fmt.Sprintf("Failed: %s", url))
-			continue
+	batchLock.RLock()
+	ctx := job.ctx
+	var pending []int
+	items := make([]BatchItem, len(job.Items))
+	for i, item := range job.Items {
+		items[i] = item
+		if !item.Done {
+			pending = append(pending, i)
 		}
+	}
+	batchLock.RUnlock()
 
-		result, err := analyzeFile(ctx, tempPath)
-		if removeErr := os.Remove(tempPath); removeErr != nil {
-			appLogger.Warn().Err(removeErr).Str("path", tempPath).Msg("Failed to cleanup temp file")
+	group := batchPool.NewPriorityJobGroup(ctx, job.ID, 0, job.priority())
+
+	// Submit blocks while the job is at its parallelism cap or the queue is
+	// full, so items are fed to the pool as capacity frees up.
+	submitted := true
+	for _, index := range pending {
+		if draining.Load() {
+			break
+		}
+		item := items[index]
+		task := func(ctx context.Context) { processBatchFile(ctx, job, index, item.Input) }
+		switch item.Type {
+		case "url":
+			task = func(ctx context.Context) { processBatchURL(ctx, job, index, item.Input) }
+		case "upload":
+			task = func(ctx context.Context) { processBatchUpload(ctx, job, index) }
+		}
+		if err := group.Submit(task); err != nil {
+			submitted = false
+			break
 		}
+	}
+
+	group.Wait()
+
+	batchLock.RLock()
+	drained := draining.Load() && job.hasPendingItems()
+	batchLock.RUnlock()
 
+	if ctx.Err() != nil || !submitted || drained {
+		// Give up the claim before the job shows as paused, so a resume
+		// request does not find it still held
+		release()
 		batchLock.Lock()
-		if err != nil {
-			job.Failed++
-			job.Results = append(job.Results, map[string]interface{}{
-				"type":   "url",
-				"url":    url,
-				"status": "failed",
-				"error":  "Analysis failed",
-			})
-		} else {
-			job.Completed++
-			resultMap := map[string]interface{}{
-				"type":     "url",
-				"url":      url,
-				"filename": filename,
-				"status":   "success",
-				"analysis": result,
-			}
-			if includeLLM {
-				llmReport, err := generateLLMInsights(ctx, result, filename)
-				if err == nil {
-					resultMap["llm_report"] = llmReport
-				}
+		// Jobs interrupted by shutdown are kept resumable and resume on restart
+		interrupted := job.stopStatus == "" && draining.Load()
+		paused := job.stopStatus == "paused" || interrupted
+		if paused {
+			job.Status = "paused"
+			if interrupted {
+				job.Interrupted = true
 			}
-			job.Results = append(job.Results, resultMap)
+		} else {
+			job.Status = "cancelled"
 		}
 		job.UpdatedAt = time.Now()
+		progress := batchProgress(job)
 		batchLock.Unlock()
+		persistBatchJob(job)
 
-		progress := float64(job.Completed+job.Failed) / float64(job.Total) * 100
-		sendProgressUpdate(job.ID, progress, "processing", fmt.Sprintf("Processed: %s", filename))
+		if paused {
+			appLogger.Info().Str("job_id", job.ID).Msg("Batch job paused")
+			sendProgressUpdate(job.ID, progress, "paused", "Batch job paused")
+			return
+		}
+		appLogger.Info().Str("job_id", job.ID).Msg("Batch job cancelled")
+		sendProgressUpdate(job.ID, progress, "cancelled", "Batch job cancelled")
+		notifyBatchCallback(job, webhook.EventBatchCancelled)
+		return
 	}
 
 	// Mark job as completed
@@ -1094,175 +2497,257 @@ func processBatchJob(job *BatchJob, files []string, urls []string, includeLLM bo
 	job.Status = "completed"
 	job.UpdatedAt = time.Now()
 	batchLock.Unlock()
+	persistBatchJob(job)
 
 	sendProgressUpdate(job.ID, 100, "completed", "Batch processing completed")
+	if job.isProbe() {
+		// The probe result was delivered when the upload finished
+		return
+	}
+	notifyBatchCallback(job, webhook.EventBatchCompleted)
+	notifyBatchCompleted(job)
 }
 
-func sendProgressUpdate(jobID string, progress float64, status, message string) {
-	wsLock.RLock()
-	conn, exists := wsConnections[jobID]
-	wsLock.RUnlock()
+// notifyBatchCallback POSTs a job summary to the job's callback URL. Results
+// are not inlined since batches can be large; receivers fetch them from status_url.
+func notifyBatchCallback(job *BatchJob, event string) {
+	if job.CallbackURL == "" {
+		return
+	}
 
-	if !exists {
+	batchLock.RLock()
+	summary := gin.H{
+		"job_id":     job.ID,
+		"status":     job.Status,
+		"total":      job.Total,
+		"completed":  job.Completed,
+		"failed":     job.Failed,
+		"created_at": job.CreatedAt,
+		"updated_at": job.UpdatedAt,
+		"status_url": strings.TrimRight(appConfig.BaseURL, "/") + "/api/v1/batch/status/" + job.ID,
+	}
+	batchLock.RUnlock()
+
+	ctx, cancel := callbackContext()
+	defer cancel()
+
+	if err := webhookSender.Send(ctx, job.CallbackURL, event, summary); err != nil {
+		appLogger.Error().Err(err).Str("job_id", job.ID).Msg("Failed to deliver batch callback")
+	}
+}
+
+// processBatchFile analyzes a single local file as part of a batch job.
+// Items interrupted by a pause or cancel are left pending, not failed.
+func processBatchFile(ctx context.Context, job *BatchJob, index int, filePath string) {
+	// Items not started before shutdown wait for the restart
+	if ctx.Err() != nil || draining.Load() {
 		return
 	}
 
-	update := ProgressUpdate{
-		Type:      "progress",
-		JobID:     jobID,
-		Progress:  progress,
-		Message:   message,
-		Status:    status,
-		Timestamp: time.Now().Format(time.RFC3339),
+	result, err := analyzeFileWithPhases(ctx, filePath, job.QCCategories, nil, "", batchItemPhaseFunc(job, index))
+	if ctx.Err() != nil {
+		requeueBatchItem(job, index)
+		return
 	}
 
-	if err := conn.WriteJSON(update); err != nil {
-		appLogger.Warn().Err(err).Str("job_id", jobID).Msg("Failed to send WebSocket update")
+	var resultMap map[string]interface{}
+	if err != nil {
+		problem := analysisFailure(err, "Analysis failed")
+		resultMap = map[string]interface{}{
+			"type":   "file",
+			"path":   filePath,
+			"status": "failed",
+			"error":  problem.Detail,
+			"code":   problem.Code,
+		}
+	} else {
+		resultMap = map[string]interface{}{
+			"type":     "file",
+			"path":     filePath,
+			"status":   "success",
+			"analysis": result,
+		}
+		if job.IncludeLLM {
+			updateBatchItemPhase(job, index, itemPhaseLLM, 0)
+			llmReport, err := generateLLMInsights(ctx, result, filepath.Base(filePath), false)
+			if err == nil {
+				resultMap["llm_report"] = llmReport.Report
+				resultMap["llm_usage"] = llmReport.Metadata
+			}
+		}
 	}
+
+	recordBatchResult(job, index, resultMap, err == nil, fmt.Sprintf("Processed: %s", filepath.Base(filePath)))
 }
 
-// GraphQL Schema
-func createGraphQLSchema() graphql.Schema {
-	// Define stream type
-	streamType := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Stream",
-		Fields: graphql.Fields{
-			"index":       &graphql.Field{Type: graphql.Int},
-			"codec_name":  &graphql.Field{Type: graphql.String},
-			"codec_type":  &graphql.Field{Type: graphql.String},
-			"width":       &graphql.Field{Type: graphql.Int},
-			"height":      &graphql.Field{Type: graphql.Int},
-			"sample_rate": &graphql.Field{Type: graphql.String},
-			"channels":    &graphql.Field{Type: graphql.Int},
-		},
-	})
+// processBatchURL downloads and analyzes a single URL as part of a batch job
+func processBatchURL(ctx context.Context, job *BatchJob, index int, url string) {
+	if ctx.Err() != nil || draining.Load() {
+		return
+	}
 
-	// Define format type
-	formatType := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Format",
-		Fields: graphql.Fields{
-			"filename":         &graphql.Field{Type: graphql.String},
-			"nb_streams":       &graphql.Field{Type: graphql.Int},
-			"format_name":      &graphql.Field{Type: graphql.String},
-			"format_long_name": &graphql.Field{Type: graphql.String},
-			"duration":         &graphql.Field{Type: graphql.String},
-			"size":             &graphql.Field{Type: graphql.String},
-			"bit_rate":         &graphql.Field{Type: graphql.String},
-		},
+	onPhase := batchItemPhaseFunc(job, index)
+	tempPath, filename, err := downloadURLWithProgress(ctx, url, func(percent float64) {
+		onPhase(itemPhaseDownload, percent)
 	})
+	if ctx.Err() != nil {
+		if err == nil {
+			_ = workspaceManager.Remove(tempPath)
+		}
+		requeueBatchItem(job, index)
+		return
+	}
+	if err != nil {
+		problem := storageFailure(err, apierrors.CodeDownloadFailed, "Download failed")
+		recordBatchResult(job, index, map[string]interface{}{
+			"type":   "url",
+			"url":    url,
+			"status": "failed",
+			"error":  problem.Detail,
+			"code":   problem.Code,
+		}, false, fmt.Sprintf("Failed: %s", url))
+		return
+	}
 
-	// Define analysis result type
-	analysisType := graphql.NewObject(graphql.ObjectConfig{
-		Name: "AnalysisResult",
-		Fields: graphql.Fields{
-			"id":          &graphql.Field{Type: graphql.String},
-			"filename":    &graphql.Field{Type: graphql.String},
-			"status":      &graphql.Field{Type: graphql.String},
-			"streams":     &graphql.Field{Type: graphql.NewList(streamType)},
-			"format":      &graphql.Field{Type: formatType},
-			"llm_report":  &graphql.Field{Type: graphql.String},
-			"llm_enabled": &graphql.Field{Type: graphql.Boolean},
-			"timestamp":   &graphql.Field{Type: graphql.String},
-		},
-	})
+	result, err := analyzeFileWithPhases(ctx, tempPath, job.QCCategories, nil, "", onPhase)
+	if removeErr := workspaceManager.Remove(tempPath); removeErr != nil {
+		appLogger.Warn().Err(removeErr).Str("path", tempPath).Msg("Failed to cleanup temp file")
+	}
+	if ctx.Err() != nil {
+		requeueBatchItem(job, index)
+		return
+	}
 
-	// Define query
-	queryType := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Query",
-		Fields: graphql.Fields{
-			"health": &graphql.Field{
-				Type: graphql.NewObject(graphql.ObjectConfig{
-					Name: "Health",
-					Fields: graphql.Fields{
-						"status":  &graphql.Field{Type: graphql.String},
-						"version": &graphql.Field{Type: graphql.String},
-					},
-				}),
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					return map[string]interface{}{
-						"status":  "healthy",
-						"version": "2.0.0",
-					}, nil
-				},
-			},
-		},
-	})
+	var resultMap map[string]interface{}
+	if err != nil {
+		problem := analysisFailure(err, "Analysis failed")
+		resultMap = map[string]interface{}{
+			"type":   "url",
+			"url":    url,
+			"status": "failed",
+			"error":  problem.Detail,
+			"code":   problem.Code,
+		}
+	} else {
+		resultMap = map[string]interface{}{
+			"type":     "url",
+			"url":      url,
+			"filename": filename,
+			"status":   "success",
+			"analysis": result,
+		}
+		if job.IncludeLLM {
+			updateBatchItemPhase(job, index, itemPhaseLLM, 0)
+			llmReport, err := generateLLMInsights(ctx, result, filename, false)
+			if err == nil {
+				resultMap["llm_report"] = llmReport.Report
+				resultMap["llm_usage"] = llmReport.Metadata
+			}
+		}
+	}
 
-	// Define mutation with URL validation
-	mutationType := graphql.NewObject(graphql.ObjectConfig{
-		Name: "Mutation",
-		Fields: graphql.Fields{
-			"analyzeURL": &graphql.Field{
-				Type: analysisType,
-				Args: graphql.FieldConfigArgument{
-					"url": &graphql.ArgumentConfig{
-						Type: graphql.NewNonNull(graphql.String),
-					},
-					"include_llm": &graphql.ArgumentConfig{
-						Type:         graphql.Boolean,
-						DefaultValue: false,
-					},
-				},
-				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
-					url := p.Args["url"].(string)
-
-					// Validate URL
-					if err := validator.ValidateURL(url); err != nil {
-						return nil, fmt.Errorf("invalid or blocked URL")
-					}
+	recordBatchResult(job, index, resultMap, err == nil, fmt.Sprintf("Processed: %s", filename))
+}
 
-					includeLLM := false
-					if v, ok := p.Args["include_llm"].(bool); ok {
-						includeLLM = v
-					}
+// recordBatchResult appends an item result to the job, marks the item done
+// and sends a progress update with message
+func recordBatchResult(job *BatchJob, index int, resultMap map[string]interface{}, success bool, message string) {
+	batchLock.Lock()
+	item := &job.Items[index]
+	if success {
+		job.Completed++
+		item.Phase = itemPhaseCompleted
+	} else {
+		job.Failed++
+		item.Phase = itemPhaseFailed
+	}
+	job.Results = append(job.Results, resultMap)
+	item.Done = true
+	item.PhaseProgress = 100
+	item.Progress = 100
+	job.UpdatedAt = time.Now()
+	progress := batchProgress(job)
+	update := itemProgressUpdate(index, item)
+	status := job.Status
+	batchLock.Unlock()
 
-					ctx := p.Context
-					tempPath, filename, err := downloadURL(ctx, url)
-					if err != nil {
-						return nil, fmt.Errorf("failed to download URL")
-					}
-					defer func() {
-						if err := os.Remove(tempPath); err != nil {
-							appLogger.Warn().Err(err).Str("path", tempPath).Msg("Failed to cleanup temp file")
-						}
-					}()
-
-					result, err := analyzeFile(ctx, tempPath)
-					if err != nil {
-						return nil, fmt.Errorf("analysis failed")
-					}
+	persistBatchJob(job)
+	sendItemProgressUpdate(job.ID, progress, status, message, update)
+}
 
-					response := map[string]interface{}{
-						"id":          uuid.New().String(),
-						"filename":    filename,
-						"status":      "completed",
-						"streams":     result.Streams,
-						"format":      result.Format,
-						"llm_enabled": false,
-						"timestamp":   time.Now().Format(time.RFC3339),
-					}
+func sendProgressUpdate(jobID string, progress float64, status, message string) {
+	sendItemProgressUpdate(jobID, progress, status, message, nil)
+}
 
-					if includeLLM {
-						llmReport, err := generateLLMInsights(ctx, result, filename)
-						if err == nil {
-							response["llm_report"] = llmReport
-							response["llm_enabled"] = true
-						}
-					}
+// sendItemProgressUpdate is sendProgressUpdate for an update about one batch item
+func sendItemProgressUpdate(jobID string, progress float64, status, message string, item *ItemProgress) {
+	update := ProgressUpdate{
+		Type:      "progress",
+		JobID:     jobID,
+		Progress:  progress,
+		Message:   message,
+		Status:    status,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Item:      item,
+	}
 
-					return response, nil
-				},
-			},
-		},
-	})
+	publishProgress(update)
+	publishSharedProgress(update)
 
-	schema, err := graphql.NewSchema(graphql.SchemaConfig{
-		Query:    queryType,
-		Mutation: mutationType,
-	})
+	wsLock.RLock()
+	conn, exists := wsConnections[jobID]
+	wsLock.RUnlock()
+
+	if !exists {
+		return
+	}
+	writeWebSocketUpdate(conn, update)
+}
+
+// writeWebSocketUpdate sends an update to a progress WebSocket
+func writeWebSocketUpdate(conn *websocket.Conn, update ProgressUpdate) {
+	// Batch items report concurrently and a connection allows one writer
+	wsWriteLock.Lock()
+	err := conn.WriteJSON(update)
+	wsWriteLock.Unlock()
 	if err != nil {
-		appLogger.Fatal().Err(err).Msg("Failed to create GraphQL schema")
+		appLogger.Warn().Err(err).Str("job_id", update.JobID).Msg("Failed to send WebSocket update")
+	}
+}
+
+// subscribeProgress registers a channel that receives progress updates for a job.
+// The returned function must be called to unregister the channel.
+func subscribeProgress(jobID string) (<-chan ProgressUpdate, func()) {
+	ch := make(chan ProgressUpdate, progressBufferSize)
+
+	progressLock.Lock()
+	if progressSubscribers[jobID] == nil {
+		progressSubscribers[jobID] = make(map[chan ProgressUpdate]struct{})
 	}
+	progressSubscribers[jobID][ch] = struct{}{}
+	progressLock.Unlock()
 
-	return schema
+	return ch, func() {
+		progressLock.Lock()
+		delete(progressSubscribers[jobID], ch)
+		if len(progressSubscribers[jobID]) == 0 {
+			delete(progressSubscribers, jobID)
+		}
+		progressLock.Unlock()
+	}
+}
+
+// publishProgress fans out a progress update to all subscribers of the job.
+// Slow subscribers drop updates rather than blocking batch processing.
+func publishProgress(update ProgressUpdate) {
+	progressLock.RLock()
+	defer progressLock.RUnlock()
+
+	for ch := range progressSubscribers[update.JobID] {
+		select {
+		case ch <- update:
+		default:
+			appLogger.Debug().Str("job_id", update.JobID).Msg("Dropping progress update for slow subscriber")
+		}
+	}
 }
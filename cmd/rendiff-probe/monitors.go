@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/monitor"
+	"github.com/rendiffdev/rendiff-probe/internal/validator"
+	"github.com/rendiffdev/rendiff-probe/internal/webhook"
+)
+
+// loadMonitors restarts the monitors saved by an earlier process
+func loadMonitors(ctx context.Context) error {
+	records, err := monitorStore.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		var spec monitor.Spec
+		if err := json.Unmarshal(record.State, &spec); err != nil {
+			appLogger.Warn().Err(err).Str("monitor_id", record.ID).Msg("Skipping unreadable monitor")
+			continue
+		}
+		if _, err := monitorManager.Restore(record.ID, spec, record.CreatedAt); err != nil {
+			appLogger.Warn().Err(err).Str("monitor_id", record.ID).Msg("Failed to restore monitor")
+		}
+	}
+	return nil
+}
+
+// persistMonitor saves a monitor's spec so it survives a restart
+func persistMonitor(ctx context.Context, mon monitor.Monitor) error {
+	state, err := json.Marshal(mon.Spec)
+	if err != nil {
+		return err
+	}
+	return monitorStore.Save(ctx, &database.MonitorRecord{
+		ID:        mon.ID,
+		Name:      mon.Name,
+		State:     state,
+		CreatedAt: mon.CreatedAt,
+	})
+}
+
+// recordMonitorSample adds a sample to the monitor's stored time series
+func recordMonitorSample(id string, sample ffmpeg.LiveSample) {
+	data, err := json.Marshal(sample)
+	if err != nil {
+		appLogger.Error().Err(err).Str("monitor_id", id).Msg("Failed to encode monitor sample")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), analysisSaveTimeout)
+	defer cancel()
+	record := &database.MonitorSampleRecord{MonitorID: id, SampledAt: sample.Timestamp, Sample: data}
+	if err := monitorStore.AddSample(ctx, record); err != nil {
+		appLogger.Error().Err(err).Str("monitor_id", id).Msg("Failed to store monitor sample")
+	}
+}
+
+// notifyMonitorAlert POSTs a raised or resolved alert to the monitor's
+// callback URL. Delivery runs in the background so retries do not delay
+// the next sample.
+func notifyMonitorAlert(mon monitor.Monitor, alert monitor.Alert) {
+	if mon.CallbackURL == "" {
+		return
+	}
+
+	event := webhook.EventMonitorAlert
+	if alert.Resolved() {
+		event = webhook.EventMonitorResolved
+	}
+	payload := gin.H{
+		"monitor_id": mon.ID,
+		"name":       mon.Name,
+		"url":        mon.URL,
+		"status":     mon.Status,
+		"alert":      alert,
+		"sample":     mon.LastSample,
+	}
+
+	go func() {
+		ctx, cancel := callbackContext()
+		defer cancel()
+		if err := webhookSender.Send(ctx, mon.CallbackURL, event, payload); err != nil {
+			appLogger.Error().Err(err).Str("monitor_id", mon.ID).Str("rule", alert.Rule).Msg("Failed to deliver monitor alert")
+		}
+	}()
+}
+
+// bindMonitorSpec reads and validates a monitor definition. On failure it
+// writes the error response.
+func bindMonitorSpec(c *gin.Context) (monitor.Spec, bool) {
+	var spec monitor.Spec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return spec, false
+	}
+
+	// Validate URL for security (SSRF prevention)
+	if err := validator.ValidateURL(spec.URL); err != nil {
+		appLogger.Warn().Str("url", spec.URL).Err(err).Msg("URL validation failed")
+		respondError(c, 400, apierrors.CodeURLBlocked, "Invalid or blocked URL")
+		return spec, false
+	}
+	if err := validateCallbackURL(spec.CallbackURL); err != nil {
+		respondError(c, 400, apierrors.CodeInvalidCallbackURL, err.Error())
+		return spec, false
+	}
+	if err := monitorManager.Validate(&spec); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return spec, false
+	}
+	return spec, true
+}
+
+// createMonitorHandler starts monitoring a live stream
+func createMonitorHandler(c *gin.Context) {
+	spec, ok := bindMonitorSpec(c)
+	if !ok {
+		return
+	}
+
+	mon, err := monitorManager.Create(spec)
+	if err != nil {
+		if errors.Is(err, monitor.ErrTooManyMonitors) {
+			apierrors.Respond(c, apierrors.New(429, apierrors.CodeTooManyRequests, "Too many monitors").With("max_monitors", maxMonitors))
+			return
+		}
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	if err := persistMonitor(c.Request.Context(), mon); err != nil {
+		appLogger.Error().Err(err).Str("monitor_id", mon.ID).Msg("Failed to persist monitor")
+	}
+	c.JSON(201, mon)
+}
+
+// listMonitorsHandler returns every monitor with its current status
+func listMonitorsHandler(c *gin.Context) {
+	monitors := monitorManager.List()
+	c.JSON(200, gin.H{
+		"monitors": monitors,
+		"total":    len(monitors),
+	})
+}
+
+// getMonitorHandler returns a monitor with its active alerts and last sample
+func getMonitorHandler(c *gin.Context) {
+	mon, err := monitorManager.Get(c.Param("id"))
+	if err != nil {
+		respondError(c, 404, apierrors.CodeNotFound, "Monitor not found")
+		return
+	}
+	c.JSON(200, mon)
+}
+
+// updateMonitorHandler replaces a monitor's definition. Sampling restarts
+// with the new settings; setting paused stops it.
+func updateMonitorHandler(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := monitorManager.Get(id); err != nil {
+		respondError(c, 404, apierrors.CodeNotFound, "Monitor not found")
+		return
+	}
+
+	spec, ok := bindMonitorSpec(c)
+	if !ok {
+		return
+	}
+
+	mon, err := monitorManager.Update(id, spec)
+	if err != nil {
+		if errors.Is(err, monitor.ErrMonitorNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Monitor not found")
+			return
+		}
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	if err := persistMonitor(c.Request.Context(), mon); err != nil {
+		appLogger.Error().Err(err).Str("monitor_id", mon.ID).Msg("Failed to persist monitor")
+	}
+	c.JSON(200, mon)
+}
+
+// deleteMonitorHandler stops a monitor and deletes its samples
+func deleteMonitorHandler(c *gin.Context) {
+	id := c.Param("id")
+	if err := monitorManager.Delete(id); err != nil {
+		respondError(c, 404, apierrors.CodeNotFound, "Monitor not found")
+		return
+	}
+
+	if err := monitorStore.Delete(c.Request.Context(), id); err != nil {
+		appLogger.Error().Err(err).Str("monitor_id", id).Msg("Failed to delete monitor")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to delete monitor")
+		return
+	}
+	c.JSON(200, gin.H{"status": "deleted", "monitor_id": id})
+}
+
+// monitorSamplesHandler returns a monitor's stored samples, newest first
+func monitorSamplesHandler(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := monitorManager.Get(id); err != nil {
+		respondError(c, 404, apierrors.CodeNotFound, "Monitor not found")
+		return
+	}
+
+	from, err := parseDateQuery(c.Query("from"), false)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid from date, use RFC 3339 or YYYY-MM-DD")
+		return
+	}
+	to, err := parseDateQuery(c.Query("to"), true)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid to date, use RFC 3339 or YYYY-MM-DD")
+		return
+	}
+
+	limit := database.DefaultMonitorSampleLimit
+	if value := c.Query("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 || limit > database.MaxMonitorSampleLimit {
+			respondError(c, 400, apierrors.CodeBadRequest, "Invalid limit, must be between 1 and "+strconv.Itoa(database.MaxMonitorSampleLimit))
+			return
+		}
+	}
+
+	samples, err := monitorStore.Samples(c.Request.Context(), id, from, to, limit)
+	if err != nil {
+		appLogger.Error().Err(err).Str("monitor_id", id).Msg("Failed to list monitor samples")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to list monitor samples")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"monitor_id": id,
+		"samples":    samples,
+		"count":      len(samples),
+		"limit":      limit,
+	})
+}
+
+// pruneMonitorSamples periodically deletes samples older than the retention
+// period
+func pruneMonitorSamples() {
+	ticker := time.NewTicker(batchCleanupPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCtx.Done():
+			appLogger.Debug().Msg("Monitor sample pruning goroutine stopped")
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), analysisSaveTimeout)
+			pruned, err := monitorStore.PruneSamples(ctx, time.Now().Add(-monitorSampleRetention))
+			cancel()
+			if err != nil {
+				appLogger.Error().Err(err).Msg("Failed to prune monitor samples")
+			} else if pruned > 0 {
+				appLogger.Info().Int64("count", pruned).Msg("Old monitor samples pruned")
+			}
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/notify"
+	"github.com/rendiffdev/rendiff-probe/internal/watch"
+)
+
+// Notification channels tell people, rather than systems, that a batch
+// job finished, a file failed its QC policy or a watch folder could not
+// process a file. Channels belong to a tenant like policies: events of a
+// project reach the channels of the project, of its organization and of
+// the deployment. Watch folders belong to the deployment, so only its
+// channels hear of them.
+
+var (
+	notifier          *notify.Notifier
+	notificationStore *database.NotificationStore
+)
+
+// maxNotificationReasons limits the failed checks listed in a message
+const maxNotificationReasons = 5
+
+// notificationChannelRequest is the JSON body of a new channel
+type notificationChannelRequest struct {
+	Name   string   `json:"name"`
+	Type   string   `json:"type" binding:"required"`   // email, slack or teams
+	Target string   `json:"target" binding:"required"` // webhook URL or comma-separated email addresses
+	Events []string `json:"events"`                    // empty subscribes to every event
+}
+
+// notificationChannelResponse is a stored channel. Webhook URLs carry
+// their credentials in the path, so only their host is shown.
+type notificationChannelResponse struct {
+	ID             string    `json:"id"`
+	Name           string    `json:"name,omitempty"`
+	Type           string    `json:"type"`
+	Target         string    `json:"target"`
+	Events         []string  `json:"events"`
+	OrganizationID string    `json:"organization_id,omitempty"`
+	ProjectID      string    `json:"project_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+func newNotificationChannelResponse(record *database.NotificationChannelRecord) *notificationChannelResponse {
+	target := record.Target
+	if record.Type != notify.ChannelEmail {
+		if parsed, err := url.Parse(target); err == nil {
+			target = parsed.Scheme + "://" + parsed.Host + "/…"
+		}
+	}
+	return &notificationChannelResponse{
+		ID:             record.ID,
+		Name:           record.Name,
+		Type:           record.Type,
+		Target:         target,
+		Events:         record.Events,
+		OrganizationID: record.Owner.OrganizationID,
+		ProjectID:      record.Owner.ProjectID,
+		CreatedAt:      record.CreatedAt,
+	}
+}
+
+// createNotificationChannelHandler adds a channel for the caller's tenant
+func createNotificationChannelHandler(c *gin.Context) {
+	var request notificationChannelRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return
+	}
+
+	channel := notify.Channel{
+		Type:   strings.ToLower(strings.TrimSpace(request.Type)),
+		Target: strings.TrimSpace(request.Target),
+	}
+	if err := notifier.ValidateChannel(channel); err != nil {
+		if errors.Is(err, notify.ErrEmailDisabled) {
+			respondError(c, 400, apierrors.CodeFeatureDisabled, "Email notifications are not enabled on this server")
+			return
+		}
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+	events, err := notify.ParseEvents(request.Events)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	record := &database.NotificationChannelRecord{
+		Name:   strings.TrimSpace(request.Name),
+		Type:   channel.Type,
+		Target: channel.Target,
+		Events: events,
+	}
+	if err := notificationStore.Create(c.Request.Context(), record); err != nil {
+		appLogger.Error().Err(err).Msg("Failed to create notification channel")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to create notification channel")
+		return
+	}
+	c.JSON(201, newNotificationChannelResponse(record))
+}
+
+// listNotificationChannelsHandler lists the channels the caller manages
+func listNotificationChannelsHandler(c *gin.Context) {
+	records, err := notificationStore.List(c.Request.Context())
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Failed to list notification channels")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to list notification channels")
+		return
+	}
+
+	channels := make([]*notificationChannelResponse, len(records))
+	for i := range records {
+		channels[i] = newNotificationChannelResponse(&records[i])
+	}
+	c.JSON(200, gin.H{"channels": channels, "count": len(channels), "events": notify.Events})
+}
+
+// deleteNotificationChannelHandler removes a channel the caller manages
+func deleteNotificationChannelHandler(c *gin.Context) {
+	if err := notificationStore.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, database.ErrNotificationChannelNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Notification channel not found")
+			return
+		}
+		appLogger.Error().Err(err).Msg("Failed to delete notification channel")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to delete notification channel")
+		return
+	}
+	c.Status(204)
+}
+
+// testNotificationChannelHandler sends a test message to a channel and
+// reports delivery errors, so a new channel can be checked right away
+func testNotificationChannelHandler(c *gin.Context) {
+	record, err := notificationStore.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrNotificationChannelNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Notification channel not found")
+			return
+		}
+		appLogger.Error().Err(err).Msg("Failed to get notification channel")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to get notification channel")
+		return
+	}
+
+	message := notify.Message{
+		Event: notify.EventTest,
+		Title: "Test notification",
+		Text:  "This channel will be notified of " + strings.Join(subscribedEvents(record.Events), ", ") + ".",
+	}
+	if err := notifier.Send(c.Request.Context(), notify.Channel{Type: record.Type, Target: record.Target}, message); err != nil {
+		respondError(c, 502, apierrors.CodeUpstreamError, err.Error())
+		return
+	}
+	c.JSON(200, gin.H{"status": "delivered"})
+}
+
+func subscribedEvents(events []string) []string {
+	if len(events) == 0 {
+		return notify.Events
+	}
+	return events
+}
+
+// sendNotification delivers a message in the background to every channel
+// of scope subscribed to its event. Failures are logged.
+func sendNotification(scope database.Scope, message notify.Message) {
+	if notificationStore == nil {
+		return
+	}
+	go func() {
+		ctx, cancel := callbackContext()
+		defer cancel()
+
+		channels, err := notificationStore.Recipients(ctx, scope)
+		if err != nil {
+			appLogger.Error().Err(err).Str("event", message.Event).Msg("Failed to look up notification channels")
+			return
+		}
+		for _, channel := range channels {
+			if !notify.Subscribed(channel.Events, message.Event) {
+				continue
+			}
+			if err := notifier.Send(ctx, notify.Channel{Type: channel.Type, Target: channel.Target}, message); err != nil {
+				appLogger.Warn().Err(err).Str("channel_id", channel.ID).Str("event", message.Event).Msg("Failed to deliver notification")
+			}
+		}
+	}()
+}
+
+// apiURL returns the absolute URL of an API path
+func apiURL(path string) string {
+	return strings.TrimRight(appConfig.BaseURL, "/") + path
+}
+
+// notifyBatchCompleted reports a finished batch job to its tenant
+func notifyBatchCompleted(job *BatchJob) {
+	batchLock.RLock()
+	message := notify.Message{
+		Event: notify.EventBatchCompleted,
+		Title: "Batch job completed",
+		Text:  fmt.Sprintf("%d of %d items analyzed, %d failed.", job.Completed, job.Total, job.Failed),
+		Fields: []notify.Field{
+			{Name: "Job", Value: job.ID},
+			{Name: "Completed", Value: strconv.Itoa(job.Completed)},
+			{Name: "Failed", Value: strconv.Itoa(job.Failed)},
+			{Name: "Duration", Value: job.UpdatedAt.Sub(job.CreatedAt).Round(time.Second).String()},
+		},
+		Link: apiURL("/api/v1/batch/status/" + job.ID),
+	}
+	scope := job.Scope
+	batchLock.RUnlock()
+
+	sendNotification(scope, message)
+}
+
+// notifyPolicyFailure reports an analysis that does not meet the delivery
+// profile it was checked against
+func notifyPolicyFailure(ctx context.Context, analysisID, filename string, result *ffmpeg.FFprobeResult) {
+	if result == nil || result.EnhancedAnalysis == nil || result.EnhancedAnalysis.DeliveryCompliance == nil {
+		return
+	}
+	compliance := result.EnhancedAnalysis.DeliveryCompliance
+	if compliance.Compliant {
+		return
+	}
+
+	var failed []string
+	for _, check := range compliance.Checks {
+		if check.Status != ffmpeg.DeliveryCheckFail {
+			continue
+		}
+		if len(failed) == maxNotificationReasons {
+			failed = append(failed, fmt.Sprintf("and %d more", compliance.Failed-maxNotificationReasons))
+			break
+		}
+		reason := fmt.Sprintf("%s: expected %s", check.Rule, check.Expected)
+		if check.Actual != "" {
+			reason += ", got " + check.Actual
+		}
+		failed = append(failed, reason)
+	}
+
+	sendNotification(database.ScopeFromContext(ctx), notify.Message{
+		Event: notify.EventPolicyFailed,
+		Title: "QC policy failed: " + filename,
+		Text:  fmt.Sprintf("%s does not meet %s.\n%s", filename, compliance.ProfileName, strings.Join(failed, "\n")),
+		Fields: []notify.Field{
+			{Name: "File", Value: filename},
+			{Name: "Policy", Value: compliance.Profile},
+			{Name: "Failed checks", Value: strconv.Itoa(compliance.Failed)},
+			{Name: "Analysis", Value: analysisID},
+		},
+		Link: apiURL("/api/v1/analyses/" + analysisID + "/report"),
+	})
+}
+
+// notifyWatchError reports a watch folder file that could not be analyzed
+// or moved out of the folder
+func notifyWatchError(folder, filename, analysisID, problem string) {
+	message := notify.Message{
+		Event: notify.EventWatchError,
+		Title: "Watch folder error: " + filename,
+		Text:  problem,
+		Fields: []notify.Field{
+			{Name: "Folder", Value: folder},
+			{Name: "File", Value: filename},
+		},
+	}
+	if analysisID != "" {
+		message.Fields = append(message.Fields, notify.Field{Name: "Analysis", Value: analysisID})
+		message.Link = apiURL("/api/v1/analyses/" + analysisID)
+	}
+	sendNotification(database.Scope{}, message)
+}
+
+// notifyWatchResult reports watch folder results whose file could not be
+// moved to the pass or fail folder
+func notifyWatchResult(result watch.Result) {
+	if result.Error == "" {
+		return
+	}
+	notifyWatchError(result.Folder, result.Filename, result.AnalysisID, "File could not be moved: "+result.Error)
+}
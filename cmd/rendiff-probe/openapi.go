@@ -0,0 +1,340 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rendiffdev/rendiff-probe/internal/config"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/monitor"
+	"github.com/rendiffdev/rendiff-probe/internal/openapi"
+	"github.com/rendiffdev/rendiff-probe/internal/report"
+)
+
+// The OpenAPI document at /api/v1/openapi.json is built at startup from the
+// routes setupRoutes registered, so it lists exactly the routes this server
+// answers. apiOperations annotates them with a summary, the least role and
+// the Go types their handlers bind and return, whose schemas are generated.
+// Routes missing from apiOperations are listed without a body schema.
+
+// apiOperation documents one route
+type apiOperation struct {
+	summary string
+	tag     string
+	role    database.Role
+	query   []openapi.Parameter
+	// request is the JSON body, or a *openapi.Schema of a multipart form
+	request interface{}
+	// response is the JSON body of the success status, or a *openapi.Schema
+	response interface{}
+	// status is the success status, 200 if unset
+	status int
+	// produces is the media type of a response that isn't JSON
+	produces string
+}
+
+// probeResponse documents the body of a completed file or URL probe
+type probeResponse struct {
+	Status            string                    `json:"status"`
+	AnalysisID        string                    `json:"analysis_id"`
+	Filename          string                    `json:"filename"`
+	Size              int64                     `json:"size,omitempty"`
+	Mode              string                    `json:"mode,omitempty"`
+	Analysis          *ffmpeg.FFprobeResult     `json:"analysis"`
+	QCResult          *report.QCResult          `json:"qc_result"`
+	Cached            bool                      `json:"cached"`
+	LLMEnabled        bool                      `json:"llm_enabled,omitempty"`
+	LLMReport         string                    `json:"llm_report,omitempty"`
+	LLMError          string                    `json:"llm_error,omitempty"`
+	Timestamp         time.Time                 `json:"timestamp"`
+	RepairSuggestions []ffmpeg.RepairSuggestion `json:"repair_suggestions,omitempty"`
+}
+
+// acceptedResponse documents the body of a probe or job accepted to run in
+// the background
+type acceptedResponse struct {
+	Status      string `json:"status"`
+	JobID       string `json:"job_id,omitempty"`
+	AnalysisID  string `json:"analysis_id,omitempty"`
+	CallbackURL string `json:"callback_url,omitempty"`
+	Message     string `json:"message"`
+	StatusURL   string `json:"status_url,omitempty"`
+	ResultURL   string `json:"result_url,omitempty"`
+	WSURL       string `json:"ws_url,omitempty"`
+}
+
+// analysisListResponse documents a page of stored analyses
+type analysisListResponse struct {
+	Analyses []database.AnalysisRecord `json:"analyses"`
+	Total    int                       `json:"total"`
+	Limit    int                       `json:"limit"`
+	Offset   int                       `json:"offset"`
+}
+
+// limitsResponse documents the runtime limits endpoints
+type limitsResponse struct {
+	Limits     config.Limits `json:"limits"`
+	LimitsFile string        `json:"limits_file,omitempty"`
+}
+
+func queryParam(name, typ, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: typ}}
+}
+
+// fileForm is the schema of a multipart form with a file field and string
+// fields
+func fileForm(fields ...string) *openapi.Schema {
+	schema := &openapi.Schema{
+		Type:       "object",
+		Properties: map[string]*openapi.Schema{"file": {Type: "string", Format: "binary"}},
+		Required:   []string{"file"},
+	}
+	for _, field := range fields {
+		schema.Properties[field] = &openapi.Schema{Type: "string"}
+	}
+	return schema
+}
+
+var (
+	pageParams = []openapi.Parameter{
+		queryParam("limit", "integer", "Page size"),
+		queryParam("offset", "integer", "Results to skip"),
+	}
+	rangeParams = []openapi.Parameter{
+		queryParam("from", "string", "Start, RFC 3339 or YYYY-MM-DD"),
+		queryParam("to", "string", "End, RFC 3339 or YYYY-MM-DD"),
+	}
+)
+
+// apiOperations documents the routes by method and gin path
+var apiOperations = map[string]apiOperation{
+	"GET /health":                {summary: "Service health with queue and process pool statistics", tag: "System"},
+	"GET /healthz":               {summary: "Liveness probe", tag: "System"},
+	"GET /readyz":                {summary: "Readiness probe checking dependencies", tag: "System"},
+	"GET /shared/analyses/:id":   {summary: "Report of an analysis through a signed share link", tag: "Analyses", produces: "text/html"},
+	"GET /api/v1/openapi.json":   {summary: "This OpenAPI document", tag: "System"},
+	"GET /api/v1/capabilities":   {summary: "Tool versions, codecs, filters, limits and features", tag: "System", role: database.RoleViewer},
+	"GET /api/v1/limits":         {summary: "Limits in force on this replica", tag: "Administration", role: database.RoleAdmin, response: limitsResponse{}},
+	"PUT /api/v1/limits":         {summary: "Change limits on this replica until the next reload", tag: "Administration", role: database.RoleAdmin, request: config.Limits{}, response: limitsResponse{}},
+	"POST /api/v1/config/reload": {summary: "Read LIMITS_FILE again", tag: "Administration", role: database.RoleAdmin, response: limitsResponse{}},
+
+	"POST /api/v1/probe/file": {
+		summary: "Analyze an uploaded file", tag: "Probe", role: database.RoleOperator,
+		request:  fileForm("include_llm", "refresh_llm", "refresh_cache", "mode", "categories", "profile", "streams", "checksums", "format", "callback_url", "async", "priority", "ffprobe_options", "start_time", "duration", "thumbnails", "thumbnail_interval", "tonemap", "qctools"),
+		response: probeResponse{},
+	},
+	"GET /api/v1/probe/jobs/:id":        {summary: "Status of an async probe job", tag: "Probe", role: database.RoleViewer},
+	"GET /api/v1/probe/jobs/:id/result": {summary: "Result of an async probe job", tag: "Probe", role: database.RoleViewer, response: probeResponse{}},
+	"POST /api/v1/probe/url":            {summary: "Analyze a file or stream at a URL", tag: "Probe", role: database.RoleOperator, request: urlProbeRequest{}, response: probeResponse{}},
+	"POST /api/v1/probe/hls":            {summary: "Analyze an HLS stream", tag: "Probe", role: database.RoleOperator, request: hlsProbeRequest{}},
+	"POST /api/v1/probe/dash":           {summary: "Analyze a DASH stream", tag: "Probe", role: database.RoleOperator, request: dashProbeRequest{}},
+	"POST /api/v1/subtitles/validate":   {summary: "Validate a subtitle deliverable", tag: "Probe", role: database.RoleOperator, request: fileForm("analysis_id")},
+	"POST /api/v1/compare":              {summary: "Score a distorted encode against its reference", tag: "Probe", role: database.RoleOperator, request: compareRequest{}},
+	"POST /api/v1/stitch/validate":      {summary: "Check that the parts of a multi-part delivery concatenate cleanly", tag: "Probe", role: database.RoleOperator, request: stitchRequest{}},
+
+	"POST /api/v1/batch/analyze":    {summary: "Start a batch job", tag: "Batch", role: database.RoleOperator, request: batchAnalyzeRequest{}, response: acceptedResponse{}, status: 202},
+	"GET /api/v1/batch/status/:id":  {summary: "Status and results of a batch job", tag: "Batch", role: database.RoleViewer, response: BatchJob{}},
+	"GET /api/v1/batch/:id/export":  {summary: "Export batch results", tag: "Batch", role: database.RoleViewer, query: []openapi.Parameter{queryParam("format", "string", "csv, json or xml"), queryParam("report", "string", "Report layout")}, produces: "text/csv"},
+	"DELETE /api/v1/batch/:id":      {summary: "Cancel a batch job", tag: "Batch", role: database.RoleOperator},
+	"POST /api/v1/batch/:id/pause":  {summary: "Pause a batch job", tag: "Batch", role: database.RoleOperator, status: 202},
+	"POST /api/v1/batch/:id/resume": {summary: "Resume a paused batch job", tag: "Batch", role: database.RoleOperator, status: 202},
+	"GET /api/v1/ws/progress/:id":   {summary: "WebSocket stream of job progress", tag: "Batch", role: database.RoleViewer, status: 101},
+	"GET /api/v1/stream/frames":     {summary: "Frame and packet records over WebSocket or SSE", tag: "Probe", role: database.RoleOperator, query: []openapi.Parameter{queryParam("url", "string", "Media URL"), queryParam("sections", "string", "frames, packets or both"), queryParam("select_streams", "string", "Stream selector"), queryParam("read_intervals", "string", "FFprobe read intervals"), queryParam("limit", "integer", "Most records to send")}, produces: "text/event-stream"},
+	"GET /api/v1/profiles":          {summary: "Built-in delivery profiles", tag: "Profiles", role: database.RoleViewer},
+	"GET /api/v1/policies":          {summary: "Custom delivery profiles", tag: "Profiles", role: database.RoleViewer},
+	"POST /api/v1/policies":         {summary: "Create a custom delivery profile", tag: "Profiles", role: database.RoleAdmin, request: ffmpeg.DeliveryProfile{}, response: policyResponse{}, status: 201},
+	"GET /api/v1/policies/:id":      {summary: "A custom delivery profile", tag: "Profiles", role: database.RoleViewer, response: ffmpeg.DeliveryProfile{}},
+	"DELETE /api/v1/policies/:id":   {summary: "Delete a custom delivery profile", tag: "Profiles", role: database.RoleAdmin, status: 204},
+
+	"POST /api/v1/notifications/channels":          {summary: "Add a notification channel", tag: "Notifications", role: database.RoleAdmin, request: notificationChannelRequest{}, response: notificationChannelResponse{}, status: 201},
+	"GET /api/v1/notifications/channels":           {summary: "Notification channels", tag: "Notifications", role: database.RoleAdmin},
+	"DELETE /api/v1/notifications/channels/:id":    {summary: "Remove a notification channel", tag: "Notifications", role: database.RoleAdmin, status: 204},
+	"POST /api/v1/notifications/channels/:id/test": {summary: "Send a test notification", tag: "Notifications", role: database.RoleAdmin},
+
+	"GET /api/v1/analyses": {
+		summary: "Stored analyses", tag: "Analyses", role: database.RoleViewer, response: analysisListResponse{},
+		query: append(append([]openapi.Parameter{queryParam("filename", "string", "Filename substring"), queryParam("status", "string", "completed or failed")}, rangeParams...), pageParams...),
+	},
+	"POST /api/v1/analyses/compare":              {summary: "LLM comparison of two analyses", tag: "Analyses", role: database.RoleOperator, request: compareAnalysesRequest{}},
+	"GET /api/v1/analyses/diff":                  {summary: "Field differences between two analyses", tag: "Analyses", role: database.RoleViewer, query: []openapi.Parameter{queryParam("a", "string", "First analysis ID"), queryParam("b", "string", "Second analysis ID")}},
+	"GET /api/v1/analyses/:id":                   {summary: "A stored analysis", tag: "Analyses", role: database.RoleViewer, response: database.AnalysisRecord{}},
+	"DELETE /api/v1/analyses/:id":                {summary: "Delete a stored analysis", tag: "Analyses", role: database.RoleOperator, status: 204},
+	"GET /api/v1/analyses/:id/report":            {summary: "QC report of an analysis", tag: "Analyses", role: database.RoleViewer, query: []openapi.Parameter{queryParam("format", "string", "html or pdf")}, produces: "text/html"},
+	"POST /api/v1/analyses/:id/share":            {summary: "Sign a share link to the report", tag: "Analyses", role: database.RoleOperator, request: shareLinkRequest{}, status: 201},
+	"GET /api/v1/analyses/:id/markers":           {summary: "Event markers of an analysis", tag: "Analyses", role: database.RoleViewer, query: []openapi.Parameter{queryParam("format", "string", "json, edl or csv")}},
+	"GET /api/v1/analyses/:id/encoding-ladder":   {summary: "Encoding ladder recommendation", tag: "Analyses", role: database.RoleViewer},
+	"GET /api/v1/analyses/:id/thumbnails":        {summary: "Thumbnails of an analysis", tag: "Analyses", role: database.RoleViewer, query: []openapi.Parameter{queryParam("format", "string", "json, contact-sheet or filmstrip"), queryParam("columns", "integer", "Contact sheet columns")}},
+	"GET /api/v1/analyses/:id/qctools":           {summary: "QCTools report of an analysis", tag: "Analyses", role: database.RoleViewer},
+	"POST /api/v1/analyses/:id/checksums/verify": {summary: "Verify checksums against a manifest", tag: "Analyses", role: database.RoleViewer, request: ffmpeg.ChecksumManifest{}},
+	"GET /api/v1/analyses/:id/llm/stream":        {summary: "Stream an LLM report as it is generated", tag: "Analyses", role: database.RoleOperator, query: []openapi.Parameter{queryParam("refresh", "boolean", "Bypass the cached report")}, produces: "text/event-stream"},
+
+	"POST /api/v1/uploads":              {summary: "Start a resumable upload", tag: "Uploads", role: database.RoleOperator, request: createUploadRequest{}, status: 201},
+	"HEAD /api/v1/uploads/:id":          {summary: "Offset of a resumable upload", tag: "Uploads", role: database.RoleOperator},
+	"GET /api/v1/uploads/:id":           {summary: "Status of a resumable upload", tag: "Uploads", role: database.RoleViewer},
+	"PATCH /api/v1/uploads/:id":         {summary: "Append a chunk at the current offset", tag: "Uploads", role: database.RoleOperator, request: &openapi.Schema{Type: "string", Format: "binary"}},
+	"POST /api/v1/uploads/:id/complete": {summary: "Finish an upload and start its analysis", tag: "Uploads", role: database.RoleOperator, request: fileProbeRequest{}, response: acceptedResponse{}, status: 202},
+	"DELETE /api/v1/uploads/:id":        {summary: "Abort a resumable upload", tag: "Uploads", role: database.RoleOperator, status: 204},
+	"POST /api/v1/direct-uploads":       {summary: "Presigned URL for a direct browser upload", tag: "Uploads", role: database.RoleOperator, request: directUploadRequest{}, status: 201},
+	"GET /api/v1/direct-uploads/:id":    {summary: "Status of a direct upload", tag: "Uploads", role: database.RoleViewer},
+
+	"POST /api/v1/monitors":            {summary: "Start monitoring a live stream", tag: "Monitors", role: database.RoleOperator, request: monitor.Spec{}, response: monitor.Monitor{}, status: 201},
+	"GET /api/v1/monitors":             {summary: "Live stream monitors", tag: "Monitors", role: database.RoleViewer},
+	"GET /api/v1/monitors/:id":         {summary: "A live stream monitor", tag: "Monitors", role: database.RoleViewer, response: monitor.Monitor{}},
+	"PUT /api/v1/monitors/:id":         {summary: "Change a live stream monitor", tag: "Monitors", role: database.RoleOperator, request: monitor.Spec{}, response: monitor.Monitor{}},
+	"DELETE /api/v1/monitors/:id":      {summary: "Stop a live stream monitor", tag: "Monitors", role: database.RoleOperator},
+	"GET /api/v1/monitors/:id/samples": {summary: "QC samples of a monitor", tag: "Monitors", role: database.RoleViewer, query: append([]openapi.Parameter{queryParam("limit", "integer", "Most samples to return")}, rangeParams...)},
+
+	"GET /api/v1/watch-folders": {summary: "Watch folders", tag: "Watch Folders", role: database.RoleAdmin},
+	"GET /api/v1/watch-folders/results": {
+		summary: "Results of watch folder files", tag: "Watch Folders", role: database.RoleAdmin,
+		query: append([]openapi.Parameter{queryParam("folder", "string", "Watch folder name"), queryParam("verdict", "string", "pass or fail")}, pageParams...),
+	},
+
+	"POST /api/v1/organizations":                            {summary: "Create an organization", tag: "Tenancy", role: database.RoleAdmin, request: tenantNameRequest{}, response: database.Organization{}, status: 201},
+	"GET /api/v1/organizations":                             {summary: "Organizations", tag: "Tenancy", role: database.RoleAdmin},
+	"DELETE /api/v1/organizations/:id":                      {summary: "Delete an organization", tag: "Tenancy", role: database.RoleAdmin, status: 204},
+	"POST /api/v1/organizations/:id/projects":               {summary: "Create a project", tag: "Tenancy", role: database.RoleAdmin, request: tenantNameRequest{}, response: database.Project{}, status: 201},
+	"GET /api/v1/organizations/:id/projects":                {summary: "Projects of an organization", tag: "Tenancy", role: database.RoleAdmin},
+	"DELETE /api/v1/organizations/:id/projects/:project_id": {summary: "Delete a project", tag: "Tenancy", role: database.RoleAdmin, status: 204},
+	"POST /api/v1/api-keys":                                 {summary: "Issue an API key", tag: "Tenancy", role: database.RoleAdmin, request: createAPIKeyRequest{}, status: 201},
+	"GET /api/v1/api-keys":                                  {summary: "API keys", tag: "Tenancy", role: database.RoleAdmin, query: []openapi.Parameter{queryParam("organization_id", "string", "Only keys of this organization")}},
+	"DELETE /api/v1/api-keys/:id":                           {summary: "Revoke an API key", tag: "Tenancy", role: database.RoleAdmin, status: 204},
+
+	"POST /api/v1/graphql": {summary: "GraphQL query or mutation, or a multipart file upload", tag: "GraphQL", role: database.RoleViewer},
+	"GET /api/v1/graphql":  {summary: "GraphiQL, or a graphql-transport-ws subscription", tag: "GraphQL", role: database.RoleViewer},
+}
+
+// newOpenAPIDocument documents routes
+func newOpenAPIDocument(routes gin.RoutesInfo, cfg *config.Config) *openapi.Document {
+	doc := openapi.New(openapi.Info{
+		Title:       "Rendiff Probe API",
+		Description: "Media analysis and broadcast QC built on FFprobe. Errors are RFC 7807 problem details with a machine-readable code.",
+		Version:     serviceVersion,
+	})
+	doc.Servers = []openapi.Server{{URL: "/"}}
+	if cfg.EnableTenancy {
+		doc.Components.SecuritySchemes["ApiKeyAuth"] = &openapi.SecurityScheme{Type: "apiKey", In: "header", Name: "X-API-Key"}
+		doc.Security = []map[string][]string{{"ApiKeyAuth": {}}}
+	}
+
+	problem := doc.SchemaOf(apierrors.Problem{})
+	// Problems may carry extension members such as max_size_bytes
+	doc.Components.Schemas["Problem"].AdditionalProperties = &openapi.Schema{}
+	errorResponse := &openapi.Response{
+		Description: "Problem details",
+		Content:     map[string]openapi.MediaType{apierrors.ContentType: {Schema: problem}},
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	ids := make(map[string]bool)
+	tags := make(map[string]bool)
+	for _, route := range routes {
+		spec := apiOperations[route.Method+" "+route.Path]
+		op := &openapi.Operation{
+			OperationID: operationID(route, ids),
+			Summary:     spec.summary,
+			Parameters:  spec.query,
+			Role:        string(spec.role),
+			Responses:   map[string]*openapi.Response{"default": errorResponse},
+		}
+		if spec.tag != "" {
+			op.Tags = []string{spec.tag}
+			tags[spec.tag] = true
+		}
+		if !strings.HasPrefix(route.Path, "/api/v1/") || route.Path == "/api/v1/openapi.json" {
+			public := []map[string][]string{}
+			op.Security = &public
+		}
+
+		switch request := spec.request.(type) {
+		case nil:
+		case *openapi.Schema:
+			mediaType := "multipart/form-data"
+			if request.Format == "binary" {
+				mediaType = uploadChunkContentType
+			}
+			op.RequestBody = &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{mediaType: {Schema: request}}}
+		default:
+			op.RequestBody = &openapi.RequestBody{Content: doc.JSON(request)}
+		}
+
+		status := spec.status
+		if status == 0 {
+			status = 200
+		}
+		response := &openapi.Response{Description: http.StatusText(status)}
+		switch {
+		case spec.produces != "":
+			response.Content = map[string]openapi.MediaType{spec.produces: {Schema: &openapi.Schema{Type: "string"}}}
+		case spec.response != nil:
+			response.Content = doc.JSON(spec.response)
+		case status != 204 && status != 101 && route.Method != http.MethodHead:
+			response.Content = map[string]openapi.MediaType{"application/json": {Schema: &openapi.Schema{Type: "object"}}}
+		}
+		op.Responses[strconv.Itoa(status)] = response
+
+		doc.Add(route.Method, route.Path, op)
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, openapi.Tag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	return doc
+}
+
+// operationID names an operation after its handler, such as probeFile for
+// probeFileHandler, or after its method and path for closures
+func operationID(route gin.RouteInfo, ids map[string]bool) string {
+	name := route.Handler[strings.LastIndex(route.Handler, ".")+1:]
+	if strings.HasPrefix(name, "func") {
+		name = strings.ToLower(route.Method)
+		for _, segment := range strings.FieldsFunc(route.Path, func(r rune) bool { return r == '/' || r == '-' || r == '.' }) {
+			segment = strings.TrimLeft(segment, ":*")
+			if segment != "" && segment != "api" && segment != "v1" {
+				name += strings.ToUpper(segment[:1]) + segment[1:]
+			}
+		}
+	}
+	name = strings.TrimSuffix(name, "Handler")
+	if ids[name] {
+		name += strings.ToUpper(route.Method[:1]) + strings.ToLower(route.Method[1:])
+	}
+	ids[name] = true
+	return name
+}
+
+// openAPIHandler serves the document of router's routes. It is built and
+// encoded on the first request, once every route, this one included, is
+// registered.
+func openAPIHandler(router *gin.Engine, cfg *config.Config) gin.HandlerFunc {
+	var once sync.Once
+	var body []byte
+	var err error
+	return func(c *gin.Context) {
+		once.Do(func() {
+			body, err = json.Marshal(newOpenAPIDocument(router.Routes(), cfg))
+			if err != nil {
+				appLogger.Error().Err(err).Msg("Failed to encode the OpenAPI document")
+			}
+		})
+		if err != nil {
+			respondError(c, 500, apierrors.CodeInternalError, "OpenAPI document unavailable")
+			return
+		}
+		c.Data(200, "application/json; charset=utf-8", body)
+	}
+}
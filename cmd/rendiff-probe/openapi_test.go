@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rendiffdev/rendiff-probe/internal/config"
+	"github.com/rs/zerolog"
+)
+
+var routeParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+func TestOpenAPIDocumentCoversOperations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	appLogger = zerolog.Nop()
+	// Tenancy registers every conditional route
+	appConfig = &config.Config{EnableTenancy: true, APIKey: strings.Repeat("k", 32)}
+	router := gin.New()
+	setupRoutes(router, appConfig)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	if recorder.Code != 200 {
+		t.Fatalf("expected 200, got %d: %s", recorder.Code, recorder.Body)
+	}
+	var doc struct {
+		Paths map[string]map[string]struct {
+			Summary  string             `json:"summary"`
+			Security *[]json.RawMessage `json:"security"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, spec := range apiOperations {
+		method, path, _ := strings.Cut(key, " ")
+		op, ok := doc.Paths[routeParam.ReplaceAllString(path, "{$1}")][strings.ToLower(method)]
+		if !ok {
+			t.Errorf("%s is documented but missing from the OpenAPI document", key)
+			continue
+		}
+		if op.Summary != spec.summary {
+			t.Errorf("%s summary = %q, want %q", key, op.Summary, spec.summary)
+		}
+	}
+
+	if op := doc.Paths["/api/v1/openapi.json"]["get"]; op.Security == nil || len(*op.Security) != 0 {
+		t.Error("expected the OpenAPI document to be public")
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// Policies are delivery profiles defined through the API. They are passed
+// as the profile parameter like the built-in ones, which they cannot
+// replace. The deployment's policies apply to every tenant; a tenant's
+// policies only to itself, shadowing a deployment policy with the same ID.
+
+// policyStore persists API-defined delivery profiles
+var policyStore *database.PolicyStore
+
+// policyIDPattern keeps policy IDs usable as a form value and URL segment
+var policyIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// policyResponse is a stored policy with its owner
+type policyResponse struct {
+	*ffmpeg.DeliveryProfile
+	OrganizationID string    `json:"organization_id,omitempty"`
+	ProjectID      string    `json:"project_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// decodePolicy returns the profile of a stored policy
+func decodePolicy(record *database.PolicyRecord) (*ffmpeg.DeliveryProfile, error) {
+	var profile ffmpeg.DeliveryProfile
+	if err := json.Unmarshal(record.Definition, &profile); err != nil {
+		return nil, fmt.Errorf("failed to decode policy %s: %w", record.ID, err)
+	}
+	return &profile, nil
+}
+
+func newPolicyResponse(record *database.PolicyRecord) (*policyResponse, error) {
+	profile, err := decodePolicy(record)
+	if err != nil {
+		return nil, err
+	}
+	return &policyResponse{
+		DeliveryProfile: profile,
+		OrganizationID:  record.Owner.OrganizationID,
+		ProjectID:       record.Owner.ProjectID,
+		CreatedAt:       record.CreatedAt,
+	}, nil
+}
+
+// lookupProfile resolves a profile parameter to a built-in delivery profile
+// or a policy visible to the scope of ctx. An empty name selects no profile.
+func lookupProfile(ctx context.Context, name string) (*ffmpeg.DeliveryProfile, error) {
+	profile, err := ffmpeg.LookupDeliveryProfile(name)
+	if err == nil {
+		return profile, nil
+	}
+
+	record, getErr := policyStore.Get(ctx, strings.ToLower(strings.TrimSpace(name)))
+	if getErr != nil {
+		if !errors.Is(getErr, database.ErrPolicyNotFound) {
+			appLogger.Error().Err(getErr).Str("profile", name).Msg("Failed to load policy")
+		}
+		return nil, err
+	}
+	return decodePolicy(record)
+}
+
+// createPolicyHandler stores a delivery profile for the caller's tenant
+func createPolicyHandler(c *gin.Context) {
+	var profile ffmpeg.DeliveryProfile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return
+	}
+
+	profile.ID = strings.ToLower(strings.TrimSpace(profile.ID))
+	if !policyIDPattern.MatchString(profile.ID) {
+		respondError(c, 400, apierrors.CodeBadRequest, "id must be 1-64 lowercase letters, digits, '-' or '_'")
+		return
+	}
+	if builtin, _ := ffmpeg.LookupDeliveryProfile(profile.ID); builtin != nil {
+		respondError(c, 409, apierrors.CodeConflict, "id is used by a built-in delivery profile")
+		return
+	}
+	if strings.TrimSpace(profile.Name) == "" {
+		respondError(c, 400, apierrors.CodeBadRequest, "name is required")
+		return
+	}
+
+	definition, err := json.Marshal(profile)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return
+	}
+	record := &database.PolicyRecord{ID: profile.ID, Definition: definition}
+	if err := policyStore.Create(c.Request.Context(), record); err != nil {
+		if errors.Is(err, database.ErrPolicyExists) {
+			respondError(c, 409, apierrors.CodeConflict, "Policy already exists")
+			return
+		}
+		appLogger.Error().Err(err).Msg("Failed to create policy")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to create policy")
+		return
+	}
+
+	c.JSON(201, &policyResponse{
+		DeliveryProfile: &profile,
+		OrganizationID:  record.Owner.OrganizationID,
+		ProjectID:       record.Owner.ProjectID,
+		CreatedAt:       record.CreatedAt,
+	})
+}
+
+// listPoliciesHandler lists the policies the caller can apply
+func listPoliciesHandler(c *gin.Context) {
+	records, err := policyStore.List(c.Request.Context())
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Failed to list policies")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to list policies")
+		return
+	}
+
+	policies := make([]*policyResponse, 0, len(records))
+	for i := range records {
+		policy, err := newPolicyResponse(&records[i])
+		if err != nil {
+			appLogger.Error().Err(err).Msg("Skipping unreadable policy")
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	c.JSON(200, gin.H{"policies": policies, "count": len(policies)})
+}
+
+// getPolicyHandler returns the policy the caller would apply for an ID
+func getPolicyHandler(c *gin.Context) {
+	record, err := policyStore.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrPolicyNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Policy not found")
+			return
+		}
+		appLogger.Error().Err(err).Msg("Failed to get policy")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to get policy")
+		return
+	}
+
+	policy, err := newPolicyResponse(record)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Failed to decode policy")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to get policy")
+		return
+	}
+	c.JSON(200, policy)
+}
+
+// deletePolicyHandler removes a policy owned by the caller's tenant
+func deletePolicyHandler(c *gin.Context) {
+	if err := policyStore.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, database.ErrPolicyNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Policy not found")
+			return
+		}
+		appLogger.Error().Err(err).Msg("Failed to delete policy")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to delete policy")
+		return
+	}
+	c.Status(204)
+}
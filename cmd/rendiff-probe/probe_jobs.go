@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// An async single-file probe (probe/file with async=true) runs as a batch
+// job with one "upload" item, so it queues in the batch worker pool and
+// shares batch persistence, progress streams and cancellation. The upload
+// only exists in this process: the job cannot be paused, and one
+// interrupted by a restart fails.
+
+// startProbeJob queues run, the probe of an upload, as a job owned by the
+// scope of ctx. cleanup removes the upload once the job no longer needs it.
+// The probe result is POSTed to callbackURL, if given, when it is ready.
+func startProbeJob(ctx context.Context, analysisID, filename, callbackURL string, priority ffmpeg.Priority, run func(context.Context) (int, gin.H), cleanup func()) *BatchJob {
+	items := []BatchItem{{Type: "upload", Input: filename, Phase: itemPhaseQueued}}
+	job := newBatchJob(database.ScopeFromContext(ctx), items, false, nil, callbackURL, priority)
+	job.probe = func(ctx context.Context) (int, gin.H) {
+		status, payload := run(ctx)
+		if status != 200 {
+			markProbeFailed(payload, analysisID)
+		}
+		payload["type"] = "upload"
+		payload["filename"] = filename
+		return status, payload
+	}
+	job.cleanup = cleanup
+	launchBatchJob(job)
+	return job
+}
+
+// isProbe reports whether the job is an async single-file probe
+func (job *BatchJob) isProbe() bool {
+	return len(job.Items) == 1 && job.Items[0].Type == "upload"
+}
+
+// processBatchUpload runs the probe of an async single-file job. The
+// upload is removed afterwards, even when the job was stopped, since the
+// job cannot be resumed.
+func processBatchUpload(ctx context.Context, job *BatchJob, index int) {
+	defer job.cleanup()
+	if ctx.Err() != nil {
+		return
+	}
+
+	updateBatchItemPhase(job, index, ffmpeg.PhaseProbe, 0)
+	status, payload := job.probe(database.WithScope(ctx, job.Scope))
+	if ctx.Err() != nil {
+		requeueBatchItem(job, index)
+		return
+	}
+
+	recordBatchResult(job, index, payload, status == 200, fmt.Sprintf("Processed: %s", job.Items[index].Input))
+	analysisID, _ := payload["analysis_id"].(string)
+	deliverProbeCallback(job.CallbackURL, analysisID, status, payload)
+}
+
+// failLostUpload settles a restored probe job whose upload was removed
+// with the process that received it. Must be called with batchLock held or
+// before the job is shared.
+func failLostUpload(job *BatchJob) {
+	item := &job.Items[0]
+	item.Done = true
+	item.Phase = itemPhaseFailed
+	item.PhaseProgress = 100
+	item.Progress = 100
+	job.Failed++
+	job.Results = append(job.Results, map[string]interface{}{
+		"type":     "upload",
+		"filename": item.Input,
+		"status":   "failed",
+		"error":    "Upload was lost when the server restarted, submit the file again",
+		"code":     apierrors.CodeGone,
+	})
+	job.Status = "completed"
+}
+
+// acceptedProbeJobResponse is returned when a probe is queued as a job
+func acceptedProbeJobResponse(job *BatchJob, analysisID string) gin.H {
+	return gin.H{
+		"status":      "accepted",
+		"job_id":      job.ID,
+		"analysis_id": analysisID,
+		"priority":    job.Priority,
+		"message":     "Analysis queued",
+		"status_url":  fmt.Sprintf("/api/v1/probe/jobs/%s", job.ID),
+		"result_url":  fmt.Sprintf("/api/v1/probe/jobs/%s/result", job.ID),
+		"ws_url":      fmt.Sprintf("/api/v1/ws/progress/%s", job.ID),
+	}
+}
+
+// probeJobFromParam resolves the :id parameter to a probe job, possibly run
+// by another replica, writing the error response if there is none
+func probeJobFromParam(c *gin.Context) (*BatchJob, bool) {
+	jobID := c.Param("id")
+	if _, err := uuid.Parse(jobID); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid job ID format")
+		return nil, false
+	}
+
+	batchLock.RLock()
+	job, exists := batchJobs[jobID]
+	batchLock.RUnlock()
+
+	if !exists {
+		job, exists = loadSharedBatchJob(c.Request.Context(), jobID)
+	}
+	if !exists || !job.isProbe() || !visibleBatchJob(c.Request.Context(), job) {
+		respondError(c, 404, apierrors.CodeNotFound, "Job not found")
+		return nil, false
+	}
+	return job, true
+}
+
+// probeJobStatus is the status of a probe job: its batch status, except
+// that a finished job whose probe failed is "failed". Must be called with
+// batchLock held.
+func probeJobStatus(job *BatchJob) string {
+	if job.Status == "completed" && job.Failed > 0 {
+		return "failed"
+	}
+	return job.Status
+}
+
+// probeJobStatusHandler reports the progress of an async probe
+func probeJobStatusHandler(c *gin.Context) {
+	job, ok := probeJobFromParam(c)
+	if !ok {
+		return
+	}
+
+	batchLock.RLock()
+	item := job.Items[0]
+	response := gin.H{
+		"job_id":     job.ID,
+		"status":     probeJobStatus(job),
+		"filename":   item.Input,
+		"phase":      item.Phase,
+		"progress":   batchProgress(job),
+		"priority":   job.priority().String(),
+		"created_at": job.CreatedAt,
+		"updated_at": job.UpdatedAt,
+		"result_url": fmt.Sprintf("/api/v1/probe/jobs/%s/result", job.ID),
+	}
+	if len(job.Results) > 0 {
+		if analysisID, ok := job.Results[0]["analysis_id"]; ok {
+			response["analysis_id"] = analysisID
+		}
+		if message, ok := job.Results[0]["error"]; ok {
+			response["error"] = message
+		}
+		if code, ok := job.Results[0]["code"]; ok {
+			response["code"] = code
+		}
+	}
+	batchLock.RUnlock()
+	c.JSON(200, response)
+}
+
+// probeJobResultHandler returns the body a synchronous probe of the file
+// would have returned, with "status" "failed" if the probe failed. Jobs
+// without a result yet get 409.
+func probeJobResultHandler(c *gin.Context) {
+	job, ok := probeJobFromParam(c)
+	if !ok {
+		return
+	}
+
+	batchLock.RLock()
+	status := probeJobStatus(job)
+	var result map[string]interface{}
+	if len(job.Results) > 0 {
+		result = job.Results[0]
+	}
+	batchLock.RUnlock()
+
+	if result == nil {
+		apierrors.Respond(c, apierrors.New(409, apierrors.CodeConflict, fmt.Sprintf("Job is %s and has no result", status)).
+			With("job_status", status).
+			With("status_url", fmt.Sprintf("/api/v1/probe/jobs/%s", job.ID)))
+		return
+	}
+	c.JSON(200, result)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/report"
+)
+
+// Files are analyzed in full by default. Express mode answers simple
+// metadata lookups in about a second by reading only the format and streams
+// of the start of the input. QC categories, per-stream measurements,
+// thumbnails and the result cache are skipped; a delivery profile still
+// checks its container and stream rules. Deep mode runs the full analysis
+// and then decodes the whole file, so Data Integrity reports the decode
+// errors and corrupt frames found rather than what probing can see.
+
+// expressTimeout bounds an express probe, which normally takes well under
+// a second
+const expressTimeout = 30 * time.Second
+
+// deepAnalysisTimeout bounds a deep analysis, which decodes the whole file
+const deepAnalysisTimeout = 30 * time.Minute
+
+// parseFileProbeMode validates the mode of a file probe. An empty mode
+// runs the full analysis.
+func parseFileProbeMode(mode string) (string, error) {
+	switch mode {
+	case "", probeModeFull:
+		return probeModeFull, nil
+	case probeModeExpress, probeModeDeep:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unsupported mode %q (use full, express or deep)", mode)
+}
+
+// analyzeFileExpress probes the format and streams of a local file
+func analyzeFileExpress(ctx context.Context, filePath string, profile *ffmpeg.DeliveryProfile) (*ffmpeg.FFprobeResult, error) {
+	options := ffmpeg.NewOptionsBuilder().
+		Input(filePath).
+		Express().
+		DeliveryProfile(profile).
+		ProbeOptions(ffmpeg.ProbeOptionsFromContext(ctx)).
+		Build()
+
+	ctx, cancel := context.WithTimeout(ctx, expressTimeout)
+	defer cancel()
+
+	return ffprobeInstance.Probe(ctx, options)
+}
+
+// analyzeRemoteURLExpress probes the format and streams of a URL in place
+func analyzeRemoteURLExpress(ctx context.Context, urlStr string, profile *ffmpeg.DeliveryProfile) (*remoteProbeResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, expressTimeout)
+	defer cancel()
+
+	return probeRemoteURL(ctx, urlStr, ffmpeg.NewOptionsBuilder().RemoteExpress().DeliveryProfile(profile))
+}
+
+// runExpressFileProbe is runFileProbe in express mode
+func runExpressFileProbe(ctx context.Context, analysisID, tempPath, filename string, size int64, includeLLM, refreshLLM bool, profile *ffmpeg.DeliveryProfile) (int, gin.H) {
+	result, err := analyzeFileExpress(ctx, tempPath, profile)
+	if err != nil {
+		appLogger.Error().Err(err).Str("filename", filename).Msg("Express analysis failed")
+		problem := analysisFailure(err, "Analysis failed")
+		recordAnalysis(ctx, analysisID, filename, "", analysisSourceUpload, size, nil, "", problem.Detail)
+		return problem.Status, analysisFailureResponse(problem, result)
+	}
+	storeAnalysis(ctx, analysisID, filename, result, nil, nil)
+
+	response := gin.H{
+		"status":        "success",
+		"analysis_id":   analysisID,
+		"filename":      filename,
+		"size":          size,
+		"mode":          probeModeExpress,
+		"metadata_only": true,
+		"analysis":      result,
+		"qc_result":     report.BuildQCResult(report.Source{AnalysisID: analysisID, Filename: filename}, result),
+		"timestamp":     time.Now(),
+	}
+
+	var llmReport string
+	if includeLLM {
+		insights, err := generateLLMInsights(ctx, result, filename, refreshLLM)
+		if err != nil {
+			appLogger.Warn().Err(err).Msg("LLM insights generation failed")
+			response["llm_error"] = "LLM analysis unavailable"
+		} else {
+			llmReport = insights.Report
+			response["llm_report"] = llmReport
+			response["llm_usage"] = insights.Metadata
+			response["llm_enabled"] = true
+		}
+	}
+	recordAnalysis(ctx, analysisID, filename, "", analysisSourceUpload, size, result, llmReport, "")
+
+	return 200, response
+}
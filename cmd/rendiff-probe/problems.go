@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+)
+
+// respondError writes an RFC 7807 problem response
+func respondError(c *gin.Context, status int, code, detail string) {
+	apierrors.Respond(c, apierrors.New(status, code, detail))
+}
+
+// abortWithError writes a problem response and stops the handler chain
+func abortWithError(c *gin.Context, status int, code, detail string) {
+	apierrors.Abort(c, apierrors.New(status, code, detail))
+}
+
+// payloadProblem turns the error payload of a probe run, as stored in job
+// results, back into the problem a synchronous request responds with
+func payloadProblem(status int, payload gin.H) *apierrors.Problem {
+	code, _ := payload["code"].(string)
+	if code == "" {
+		code = apierrors.CodeForStatus(status)
+	}
+	detail, _ := payload["error"].(string)
+	problem := apierrors.New(status, code, detail)
+	for key, value := range payload {
+		if key != "error" && key != "code" {
+			problem.With(key, value)
+		}
+	}
+	return problem
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// listProfilesHandler lists the built-in delivery profiles and their thresholds
+func listProfilesHandler(c *gin.Context) {
+	profiles := ffmpeg.DeliveryProfiles()
+	c.JSON(200, gin.H{
+		"profiles": profiles,
+		"count":    len(profiles),
+	})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// saveQCToolsReport generates and persists the QCTools report of an
+// analysis while its file is still on disk. It returns the summary added to
+// the probe response, or an error safe to show the caller.
+func saveQCToolsReport(ctx context.Context, analysisID, path string, result *ffmpeg.FFprobeResult) (gin.H, error) {
+	report, err := qctoolsReporter.Generate(ctx, path, result)
+	if errors.Is(err, ffmpeg.ErrNoQCToolsVideo) {
+		return nil, err
+	}
+	if err != nil {
+		appLogger.Warn().Err(err).Str("analysis_id", analysisID).Msg("Failed to generate QCTools report")
+		return nil, errors.New("QCTools report unavailable")
+	}
+
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), analysisSaveTimeout)
+	defer cancel()
+	if err := qctoolsStore.Save(saveCtx, analysisID, report); err != nil {
+		appLogger.Error().Err(err).Str("analysis_id", analysisID).Msg("Failed to save QCTools report")
+		return nil, errors.New("QCTools report unavailable")
+	}
+
+	return gin.H{
+		"size": len(report),
+		"url":  "/api/v1/analyses/" + analysisID + "/qctools",
+	}, nil
+}
+
+// analysisQCToolsHandler downloads the QCTools report of an analysis, named
+// after the analyzed file so QCTools finds it next to the media
+func analysisQCToolsHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format")
+		return
+	}
+
+	record, err := qctoolsStore.Get(c.Request.Context(), id.String())
+	if err != nil {
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to get QCTools report")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to get QCTools report")
+		return
+	}
+	analysis, err := analysisStore.Get(c.Request.Context(), id)
+	if errors.Is(err, database.ErrAnalysisNotFound) {
+		respondError(c, 404, apierrors.CodeNotFound, "Analysis not found")
+		return
+	}
+	if record == nil {
+		// Only probes that asked for qctools have a report
+		respondError(c, 404, apierrors.CodeNotFound, "No QCTools report for this analysis")
+		return
+	}
+
+	filename := id.String()
+	if err == nil && analysis.FileName != "" {
+		filename = analysis.FileName
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+ffmpeg.QCToolsReportExtension))
+	c.Data(200, "application/gzip", record.Report)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	probev1 "github.com/rendiffdev/rendiff-probe/pkg/api/probe/v1"
+)
+
+// Every API route is annotated with the least role that may call it:
+// viewers read analyses, reports and job status, operators also submit
+// work, admins also manage API keys, policies and watch folders. Roles come
+// from the request's API key, so they are only enforced with
+// ENABLE_TENANCY; without it every caller acts as admin, as before keys,
+// except on the runtime limit routes, which check API_KEY themselves.
+
+type roleKey struct{}
+
+// withRole returns a context acting with role
+func withRole(ctx context.Context, role database.Role) context.Context {
+	return context.WithValue(ctx, roleKey{}, role)
+}
+
+// roleFromContext returns the role set by withRole, or admin for requests
+// that were not authenticated by key
+func roleFromContext(ctx context.Context) database.Role {
+	if role, ok := ctx.Value(roleKey{}).(database.Role); ok {
+		return role
+	}
+	return database.RoleAdmin
+}
+
+// requireRole annotates a route with the least role that may call it
+func requireRole(required database.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !roleFromContext(c.Request.Context()).Includes(required) {
+			abortWithError(c, 403, apierrors.CodeForbidden, fmt.Sprintf("Requires the %s role", required))
+			return
+		}
+		c.Next()
+	}
+}
+
+// graphQLRole annotates a GraphQL field like requireRole does a route.
+// The GraphQL endpoint itself is open to viewers.
+func graphQLRole(required database.Role, resolve graphql.FieldResolveFn) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		if !roleFromContext(p.Context).Includes(required) {
+			return nil, apierrors.New(403, apierrors.CodeForbidden, fmt.Sprintf("requires the %s role", required))
+		}
+		return resolve(p)
+	}
+}
+
+// grpcMethodRoles annotates the gRPC methods. Methods missing here, such as
+// server reflection, need an admin key.
+var grpcMethodRoles = map[string]database.Role{
+	probev1.ProbeService_ProbeUpload_FullMethodName:    database.RoleOperator,
+	probev1.ProbeService_ProbeURL_FullMethodName:       database.RoleOperator,
+	probev1.ProbeService_AnalyzeHLS_FullMethodName:     database.RoleOperator,
+	probev1.ProbeService_AnalyzeDASH_FullMethodName:    database.RoleOperator,
+	probev1.ProbeService_SubmitBatch_FullMethodName:    database.RoleOperator,
+	probev1.ProbeService_GetBatchStatus_FullMethodName: database.RoleViewer,
+	probev1.ProbeService_WatchBatch_FullMethodName:     database.RoleViewer,
+}
+
+// grpcMethodRole returns the least role that may call a gRPC method
+func grpcMethodRole(method string) database.Role {
+	if role, ok := grpcMethodRoles[method]; ok {
+		return role
+	}
+	return database.RoleAdmin
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/services"
+)
+
+// /healthz and /readyz are liveness and readiness probes for orchestrators.
+// Liveness only shows the process still serves requests, so it is not
+// restarted because a dependency is down. Readiness checks what analyses
+// need (the database, ffprobe and ffmpeg, work directory space and the
+// LLM backend, if one is configured) and returns 503 with the state of each
+// when one fails, so load balancers stop routing to the replica.
+
+const (
+	// readinessCheckTimeout bounds each dependency check
+	readinessCheckTimeout = 5 * time.Second
+	// readinessCacheTTL is how long a readiness result is reused, so
+	// frequent or unauthenticated probes don't run ffmpeg for every request
+	readinessCacheTTL = 5 * time.Second
+)
+
+// Dependency check statuses
+const (
+	dependencyOK       = "ok"
+	dependencyFailed   = "failed"
+	dependencyDisabled = "disabled"
+)
+
+// dependencyStatus is the result of one readiness check
+type dependencyStatus struct {
+	Status    string `json:"status"`
+	Backend   string `json:"backend,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Path      string `json:"path,omitempty"`
+	FreeBytes *int64 `json:"free_bytes,omitempty"`
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+}
+
+// readinessCheck is one dependency of readiness, returning its status
+// without Status and LatencyMS, which are set from the error
+type readinessCheck func(ctx context.Context) (dependencyStatus, error)
+
+var (
+	// readinessLock serializes checks, so concurrent probes share one run
+	readinessLock      sync.Mutex
+	readinessCheckedAt time.Time
+	readinessResponse  gin.H
+	readinessReady     bool
+)
+
+// livenessHandler reports that the process is up
+func livenessHandler(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"status":  "alive",
+		"service": "rendiff-probe",
+		"version": serviceVersion,
+	})
+}
+
+// readinessHandler reports whether the replica can serve analyses, with the
+// state of each dependency. It returns 503 while shutting down or when a
+// dependency check fails.
+func readinessHandler(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	if draining.Load() {
+		c.JSON(503, gin.H{"status": "shutting_down"})
+		return
+	}
+
+	readinessLock.Lock()
+	if time.Since(readinessCheckedAt) >= readinessCacheTTL {
+		readinessResponse, readinessReady = checkReadiness(c.Request.Context())
+		readinessCheckedAt = time.Now()
+	}
+	response, ready := readinessResponse, readinessReady
+	readinessLock.Unlock()
+
+	if !ready {
+		c.JSON(503, response)
+		return
+	}
+	c.JSON(200, response)
+}
+
+// checkReadiness runs every dependency check concurrently
+func checkReadiness(ctx context.Context) (gin.H, bool) {
+	checks := map[string]readinessCheck{
+		"database": checkDatabase,
+		"ffprobe":  checkBinary(appConfig.FFprobePath),
+		"ffmpeg":   checkBinary(appConfig.FFmpegPath),
+		"disk":     checkWorkDirectory,
+		"llm":      checkLLM,
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]dependencyStatus, len(checks))
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check readinessCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			status, err := check(checkCtx)
+			status.LatencyMS = time.Since(start).Milliseconds()
+			switch {
+			case errors.Is(err, services.ErrLLMDisabled):
+				status.Status = dependencyDisabled
+			case err != nil:
+				status.Status = dependencyFailed
+				status.Error = err.Error()
+			default:
+				status.Status = dependencyOK
+			}
+
+			mu.Lock()
+			results[name] = status
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+
+	ready := true
+	for name, status := range results {
+		if status.Status == dependencyFailed {
+			ready = false
+			appLogger.Warn().Str("dependency", name).Str("error", status.Error).Msg("Readiness check failed")
+		}
+	}
+
+	overall := "ready"
+	if !ready {
+		overall = "not_ready"
+	}
+	return gin.H{
+		"status":     overall,
+		"checks":     results,
+		"checked_at": time.Now().UTC(),
+	}, ready
+}
+
+// checkDatabase pings the database
+func checkDatabase(ctx context.Context) (dependencyStatus, error) {
+	return dependencyStatus{Backend: appDB.DbType}, appDB.Health(ctx)
+}
+
+// checkBinary runs an FFmpeg tool to read its version
+func checkBinary(path string) readinessCheck {
+	return func(ctx context.Context) (dependencyStatus, error) {
+		version, err := ffmpeg.BinaryVersion(ctx, path)
+		return dependencyStatus{Path: path, Version: version}, err
+	}
+}
+
+// checkWorkDirectory fails when the work directory's volume is full or
+// below MIN_FREE_DISK_SPACE
+func checkWorkDirectory(ctx context.Context) (dependencyStatus, error) {
+	status := dependencyStatus{Path: workspaceManager.Dir()}
+	free, low, ok := workspaceManager.FreeSpace()
+	if !ok {
+		return status, nil
+	}
+	status.FreeBytes = &free
+	if low {
+		return status, errors.New("not enough free disk space")
+	}
+	return status, nil
+}
+
+// checkLLM checks the backend LLM reports would be generated with
+func checkLLM(ctx context.Context) (dependencyStatus, error) {
+	backend, err := llmService.CheckHealth(ctx)
+	return dependencyStatus{Backend: backend}, err
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/report"
+)
+
+// reportContentSecurityPolicy replaces the API-wide policy for HTML reports,
+// which carry inline styles and data: URI thumbnails but no scripts
+const reportContentSecurityPolicy = "default-src 'none'; style-src 'unsafe-inline'; img-src data:"
+
+// storedAnalysis is a finished file or URL analysis kept in memory with its
+// thumbnails for report export
+type storedAnalysis struct {
+	source     report.Source
+	result     *ffmpeg.FFprobeResult
+	thumbnails []ffmpeg.Thumbnail
+	previews   *ffmpeg.TonemapPreviews // SDR previews, when requested for an HDR file
+	scope      database.Scope          // Tenant that ran the analysis
+}
+
+// storeAnalysis keeps an analysis of the tenant of ctx for analysisTTL,
+// evicting the oldest one when the store is full
+func storeAnalysis(ctx context.Context, analysisID, filename string, result *ffmpeg.FFprobeResult, thumbnails []ffmpeg.Thumbnail, previews *ffmpeg.TonemapPreviews) {
+	analysesLock.Lock()
+	defer analysesLock.Unlock()
+
+	if len(storedAnalyses) >= maxStoredAnalyses {
+		var oldestID string
+		var oldest time.Time
+		for id, stored := range storedAnalyses {
+			if oldestID == "" || stored.source.AnalyzedAt.Before(oldest) {
+				oldestID, oldest = id, stored.source.AnalyzedAt
+			}
+		}
+		delete(storedAnalyses, oldestID)
+	}
+
+	storedAnalyses[analysisID] = &storedAnalysis{
+		source: report.Source{
+			AnalysisID: analysisID,
+			Filename:   filename,
+			AnalyzedAt: time.Now(),
+		},
+		result:     result,
+		thumbnails: thumbnails,
+		previews:   previews,
+		scope:      database.ScopeFromContext(ctx),
+	}
+}
+
+// captureThumbnails grabs report stills while the analyzed file is still on
+// disk; reports are rendered without them on failure
+func captureThumbnails(ctx context.Context, path string, result *ffmpeg.FFprobeResult) []ffmpeg.Thumbnail {
+	thumbnails, err := report.CaptureThumbnails(ctx, thumbnailExtractor, path, result)
+	if err != nil {
+		appLogger.Warn().Err(err).Msg("Failed to capture report thumbnails")
+	}
+	return thumbnails
+}
+
+// captureTonemapPreviews renders SDR previews of an HDR file while it is
+// still on disk; an empty operator or an SDR file yields none
+func captureTonemapPreviews(ctx context.Context, path string, result *ffmpeg.FFprobeResult, operator string) *ffmpeg.TonemapPreviews {
+	if operator == "" {
+		return nil
+	}
+	previews, err := report.CaptureTonemapPreviews(ctx, thumbnailExtractor, path, result, operator)
+	if err != nil {
+		appLogger.Warn().Err(err).Msg("Failed to render SDR previews")
+		return nil
+	}
+	return previews
+}
+
+// cleanupAnalyses periodically removes analyses older than analysisTTL
+func cleanupAnalyses() {
+	ticker := time.NewTicker(batchCleanupPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-shutdownCtx.Done():
+			appLogger.Debug().Msg("Analysis cleanup goroutine stopped")
+			return
+		case <-ticker.C:
+			cutoff := time.Now().Add(-analysisTTL)
+			removed := 0
+
+			analysesLock.Lock()
+			for id, stored := range storedAnalyses {
+				if stored.source.AnalyzedAt.Before(cutoff) {
+					delete(storedAnalyses, id)
+					removed++
+				}
+			}
+			analysesLock.Unlock()
+
+			if removed > 0 {
+				appLogger.Info().Int("count", removed).Msg("Analysis cleanup completed")
+			}
+		}
+	}
+}
+
+// memoryAnalysis returns the in-memory copy of an analysis if the tenant of
+// ctx may see it. Other tenants' analyses are looked up in the database,
+// which does not find them either.
+func memoryAnalysis(ctx context.Context, id uuid.UUID) (*storedAnalysis, bool) {
+	analysesLock.RLock()
+	stored, exists := storedAnalyses[id.String()]
+	analysesLock.RUnlock()
+	if !exists || !database.ScopeFromContext(ctx).Contains(stored.scope) {
+		return nil, false
+	}
+	return stored, true
+}
+
+// loadStoredAnalysis returns an analysis, preferring the in-memory copy with
+// thumbnails and falling back to the database once it has expired. A failed
+// analysis has no result; failure holds its error.
+func loadStoredAnalysis(ctx context.Context, id uuid.UUID) (stored *storedAnalysis, failure string, err error) {
+	if stored, exists := memoryAnalysis(ctx, id); exists {
+		return stored, "", nil
+	}
+
+	record, err := analysisStore.Get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	stored = &storedAnalysis{source: report.Source{
+		AnalysisID: id.String(),
+		Filename:   record.FileName,
+		AnalyzedAt: record.CreatedAt,
+	}}
+	if record.Status == database.AnalysisStatusFailed {
+		return stored, record.Error, nil
+	}
+
+	var result ffmpeg.FFprobeResult
+	if err := json.Unmarshal(record.Result, &result); err != nil {
+		return nil, "", fmt.Errorf("failed to decode stored analysis: %w", err)
+	}
+	stored.result = &result
+	return stored, "", nil
+}
+
+// loadReport builds the report for an analysis
+func loadReport(ctx context.Context, id uuid.UUID) (*report.Report, error) {
+	stored, failure, err := loadStoredAnalysis(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if stored.result == nil {
+		return report.Failed(stored.source, failure), nil
+	}
+	r := report.Build(stored.source, stored.result, stored.thumbnails)
+	r.TonemapPreviews = stored.previews
+	return r, nil
+}
+
+// analysisReportHandler renders a stored analysis as an HTML or PDF report
+func analysisReportHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format")
+		return
+	}
+
+	format, err := report.ParseFormat(c.Query("format"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	writeReport(c, id, format)
+}
+
+// writeReport responds with a stored analysis rendered as an HTML or PDF
+// report
+func writeReport(c *gin.Context, id uuid.UUID, format report.Format) {
+	r, err := loadReport(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrAnalysisNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Analysis not found")
+			return
+		}
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to load analysis for report")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to load analysis")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := report.Render(&buf, format, r); err != nil {
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Str("format", string(format)).Msg("Report rendering failed")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to render report")
+		return
+	}
+
+	disposition := "inline"
+	if format == report.FormatPDF {
+		disposition = "attachment"
+	} else {
+		c.Header("Content-Security-Policy", reportContentSecurityPolicy)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`%s; filename="qc-report-%s.%s"`, disposition, id, format))
+	c.Header("Cache-Control", "no-store")
+	c.Data(200, format.ContentType(), buf.Bytes())
+}
+
+// analysisMarkersHandler exports the events detected in a stored analysis
+// as a CSV, EDL or Avid marker list with SMPTE timecodes
+func analysisMarkersHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format")
+		return
+	}
+
+	format, err := report.ParseMarkerFormat(c.Query("format"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	stored, _, err := loadStoredAnalysis(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrAnalysisNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Analysis not found")
+			return
+		}
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to load analysis for markers")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to load analysis")
+		return
+	}
+	if stored.result == nil {
+		respondError(c, 409, apierrors.CodeConflict, "Analysis failed and has no events")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := report.RenderMarkers(&buf, format, stored.source.Filename, stored.result); err != nil {
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Str("format", string(format)).Msg("Marker rendering failed")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to render markers")
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="qc-markers-%s.%s"`, id, format.Extension()))
+	c.Header("Cache-Control", "no-store")
+	c.Data(200, format.ContentType(), buf.Bytes())
+}
+
+// analysisEncodingLadderHandler recommends a per-title ABR ladder from the
+// complexity measured by a stored analysis
+func analysisEncodingLadderHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format")
+		return
+	}
+
+	stored, _, err := loadStoredAnalysis(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, database.ErrAnalysisNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Analysis not found")
+			return
+		}
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to load analysis for encoding ladder")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to load analysis")
+		return
+	}
+	if stored.result == nil {
+		respondError(c, 409, apierrors.CodeConflict, "Analysis failed and has no measurements")
+		return
+	}
+
+	ladder, err := report.RecommendLadder(stored.result)
+	if err != nil {
+		respondError(c, 422, apierrors.CodeValidationError, err.Error())
+		return
+	}
+	c.JSON(200, gin.H{
+		"analysis_id":    id.String(),
+		"filename":       stored.source.Filename,
+		"recommendation": ladder,
+	})
+}
+
+// flatProbeFormat resolves the CSV or XML format a probe request asks for,
+// from its format option or else its Accept header; empty means JSON
+func flatProbeFormat(c *gin.Context, requested string) (report.Format, error) {
+	switch requested {
+	case "json":
+		return "", nil
+	case "":
+	default:
+		return report.ParseFlatFormat(requested)
+	}
+
+	switch c.NegotiateFormat(gin.MIMEJSON, "text/csv", gin.MIMEXML, gin.MIMEXML2) {
+	case "text/csv":
+		return report.FormatCSV, nil
+	case gin.MIMEXML, gin.MIMEXML2:
+		return report.FormatXML, nil
+	default:
+		return "", nil
+	}
+}
+
+// respondProbe writes a probe response as JSON, or as flat CSV/XML rows when
+// a format was requested and the analysis succeeded. Errors are problems.
+func respondProbe(c *gin.Context, format report.Format, status int, response gin.H) {
+	if status >= 400 {
+		apierrors.Respond(c, payloadProblem(status, response))
+		return
+	}
+	result, ok := response["analysis"].(*ffmpeg.FFprobeResult)
+	if format == "" || status != 200 || !ok {
+		c.JSON(status, response)
+		return
+	}
+
+	analysisID, _ := response["analysis_id"].(string)
+	filename, _ := response["filename"].(string)
+	flat := report.FlatResult{
+		Source: report.Source{AnalysisID: analysisID, Filename: filename, AnalyzedAt: time.Now()},
+		Result: result,
+	}
+	var buf bytes.Buffer
+	if err := report.RenderFlat(&buf, format, flat); err != nil {
+		appLogger.Error().Err(err).Str("analysis_id", analysisID).Str("format", string(format)).Msg("Flat result rendering failed")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to render result")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="analysis-%s.%s"`, analysisID, format))
+	c.Data(200, format.ContentType(), buf.Bytes())
+}
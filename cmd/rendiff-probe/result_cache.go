@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/filehash"
+)
+
+// A file submitted again with the same analysis options reuses the newest
+// stored result visible to the caller for RESULT_CACHE_TTL, unless the
+// request sets refresh_cache. The reuse is recorded as a new analysis
+// marked cached, so reports and callbacks work as for a fresh one.
+
+// resultCacheKey returns the cache key for analyzing path with the options,
+// or "" if the cache is disabled or the file cannot be hashed. Deep analyses
+// are keyed apart since their integrity results cover the whole file, and
+// so are analyses with the probe options or a segment of the request in ctx
+// and with other loudness targets, content sampling settings, plugins or
+// MediaInfo cross-checking.
+func resultCacheKey(ctx context.Context, path string, deep bool, categories []ffmpeg.QCCategory, profile *ffmpeg.DeliveryProfile, streams string) string {
+	if appConfig.ResultCacheTTL <= 0 {
+		return ""
+	}
+	mode, err := filehash.ParseMode(appConfig.ResultCacheHash)
+	if err != nil {
+		return ""
+	}
+	sum, err := filehash.Sum(path, mode)
+	if err != nil {
+		appLogger.Warn().Err(err).Msg("Failed to hash file for the result cache")
+		return ""
+	}
+
+	names := make([]string, len(categories))
+	for i, category := range categories {
+		names[i] = string(category)
+	}
+	sort.Strings(names)
+
+	// Policies can change under the same ID, so the definition is the key
+	var definition []byte
+	if profile != nil {
+		if definition, err = json.Marshal(profile); err != nil {
+			return ""
+		}
+	}
+
+	options := []string{
+		"categories=" + strings.Join(names, ","),
+		"profile=" + string(definition),
+		"streams=" + streams,
+	}
+	if deep {
+		options = append(options, "mode="+probeModeDeep)
+	}
+	if probeOptions := ffmpeg.ProbeOptionsFromContext(ctx); probeOptions != nil {
+		flags, err := json.Marshal(probeOptions)
+		if err != nil {
+			return ""
+		}
+		options = append(options, "ffprobe_options="+string(flags))
+	}
+	if segment := ffmpeg.AnalysisSegmentFromContext(ctx); segment != nil {
+		options = append(options, fmt.Sprintf("segment=%g+%g", segment.StartTime, segment.Duration))
+	}
+
+	// The configured loudness targets, content sampling, analyzer plugins
+	// and MediaInfo cross-check shape the result too, and change with the
+	// deployment's configuration
+	if len(loudnessTargets) > 0 {
+		targets, err := json.Marshal(loudnessTargets)
+		if err != nil {
+			return ""
+		}
+		options = append(options, "loudness_targets="+string(targets))
+	}
+	if sampling := contentSamplingOptions(); sampling != nil {
+		options = append(options, fmt.Sprintf("sampling=%d*%g/%g", sampling.Windows, sampling.WindowSeconds, sampling.MinDuration))
+	}
+	if len(pluginNames) > 0 {
+		plugins := append([]string(nil), pluginNames...)
+		sort.Strings(plugins)
+		options = append(options, "plugins="+strings.Join(plugins, ","))
+	}
+	if appConfig.MediaInfoPath != "" {
+		options = append(options, "mediainfo=1")
+	}
+	return filehash.Key(sum, options...)
+}
+
+// cachedResult returns the newest stored result for key visible to ctx and
+// the analysis it came from, or nil
+func cachedResult(ctx context.Context, key string) (*ffmpeg.FFprobeResult, *database.AnalysisRecord) {
+	if key == "" {
+		return nil, nil
+	}
+
+	since := time.Now().Add(-time.Duration(appConfig.ResultCacheTTL) * time.Second)
+	record, err := analysisStore.FindByContentHash(ctx, key, since)
+	if err != nil {
+		if !errors.Is(err, database.ErrAnalysisNotFound) {
+			appLogger.Warn().Err(err).Msg("Result cache lookup failed")
+		}
+		return nil, nil
+	}
+
+	var result ffmpeg.FFprobeResult
+	if err := json.Unmarshal(record.Result, &result); err != nil {
+		appLogger.Warn().Err(err).Str("analysis_id", record.ID.String()).Msg("Failed to decode cached analysis")
+		return nil, nil
+	}
+	return &result, record
+}
+
+// rememberResult stores key with a completed analysis for later requests
+func rememberResult(ctx context.Context, analysisID, key string) {
+	id, err := uuid.Parse(analysisID)
+	if key == "" || err != nil {
+		return
+	}
+
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), analysisSaveTimeout)
+	defer cancel()
+	if err := analysisStore.SetContentHash(saveCtx, id, key); err != nil {
+		appLogger.Warn().Err(err).Str("analysis_id", analysisID).Msg("Failed to store result cache key")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// Full and deep analyses can be limited to a segment of the file with
+// start_time and duration, in seconds, so a portion of a long file is
+// checked without reading the rest. A negative start_time counts back from
+// the end: -600 checks the last ten minutes of a live record. Modes that
+// only read the headers have no use for a segment and reject it.
+
+// parseSegmentForm returns the segment of the start_time and duration form
+// fields, or nil when neither is set
+func parseSegmentForm(c *gin.Context) (*ffmpeg.AnalysisSegment, error) {
+	var startTime, duration float64
+	var err error
+	if value := c.PostForm("start_time"); value != "" {
+		if startTime, err = strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("%w: start_time must be a number of seconds", ffmpeg.ErrInvalidSegment)
+		}
+	}
+	if value := c.PostForm("duration"); value != "" {
+		if duration, err = strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("%w: duration must be a number of seconds", ffmpeg.ErrInvalidSegment)
+		}
+	}
+	return ffmpeg.NewAnalysisSegment(startTime, duration)
+}
+
+// checkSegment rejects a segment for a mode that reads only the headers,
+// and a segment alongside the read_intervals it is read through
+func checkSegment(segment *ffmpeg.AnalysisSegment, mode string, probeOptions *ffmpeg.ProbeOptions) error {
+	if segment == nil {
+		return nil
+	}
+	if mode == probeModeExpress || mode == probeModeStream {
+		return fmt.Errorf("%w: start_time and duration are not supported in %s mode", ffmpeg.ErrInvalidSegment, mode)
+	}
+	if probeOptions != nil && probeOptions.ReadIntervals != "" {
+		return fmt.Errorf("%w: start_time and duration cannot be combined with read_intervals", ffmpeg.ErrInvalidSegment)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/report"
+	"github.com/rendiffdev/rendiff-probe/internal/sharelink"
+)
+
+// Share links let people without API credentials, such as vendors, read
+// one analysis as JSON or as an HTML or PDF report until the link expires.
+// They are signed with SHARE_LINK_SECRET and served outside /api/v1, so
+// rotating the secret revokes every link.
+
+// defaultShareLinkTTL is how long a share link stays valid unless asked
+const defaultShareLinkTTL = 24 * time.Hour
+
+// shareFormatJSON shares the stored analysis itself
+const shareFormatJSON = "json"
+
+// parseShareFormat resolves the format of a share link; empty selects HTML
+func parseShareFormat(name string) (string, error) {
+	if strings.EqualFold(strings.TrimSpace(name), shareFormatJSON) {
+		return shareFormatJSON, nil
+	}
+	format, err := report.ParseFormat(name)
+	if err != nil {
+		return "", fmt.Errorf("unsupported share format %q (use json, html or pdf)", name)
+	}
+	return string(format), nil
+}
+
+// shareLinkRequest is the optional JSON body of a share link request
+type shareLinkRequest struct {
+	Format    string `json:"format"`
+	ExpiresIn int    `json:"expires_in"` // seconds
+}
+
+// createShareLinkHandler signs a link to an analysis the caller can read
+func createShareLinkHandler(c *gin.Context) {
+	if appConfig.ShareLinkSecret == "" {
+		respondError(c, 503, apierrors.CodeFeatureDisabled, "Share links are disabled (SHARE_LINK_SECRET is not set)")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format")
+		return
+	}
+
+	var request shareLinkRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+			return
+		}
+	}
+
+	format, err := parseShareFormat(request.Format)
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	ttl := defaultShareLinkTTL
+	maxTTL := time.Duration(appConfig.ShareLinkMaxTTL) * time.Second
+	if request.ExpiresIn < 0 || time.Duration(request.ExpiresIn)*time.Second > maxTTL {
+		respondError(c, 400, apierrors.CodeBadRequest, fmt.Sprintf("expires_in must be between 1 and %d seconds", appConfig.ShareLinkMaxTTL))
+		return
+	}
+	if request.ExpiresIn > 0 {
+		ttl = time.Duration(request.ExpiresIn) * time.Second
+	}
+	if ttl > maxTTL {
+		ttl = maxTTL
+	}
+
+	if _, _, err := loadStoredAnalysis(c.Request.Context(), id); err != nil {
+		if errors.Is(err, database.ErrAnalysisNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Analysis not found")
+			return
+		}
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to load analysis for share link")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to load analysis")
+		return
+	}
+
+	link := sharelink.Link{
+		AnalysisID: id.String(),
+		Format:     format,
+		Expires:    time.Now().Add(ttl).Truncate(time.Second).UTC(),
+	}
+	url := strings.TrimRight(appConfig.BaseURL, "/") + "/shared/analyses/" + link.AnalysisID +
+		"?" + sharelink.Query(appConfig.ShareLinkSecret, link).Encode()
+
+	appLogger.Info().
+		Str("analysis_id", link.AnalysisID).
+		Str("format", format).
+		Time("expires_at", link.Expires).
+		Msg("Share link created")
+
+	c.JSON(201, gin.H{
+		"url":         url,
+		"analysis_id": link.AnalysisID,
+		"format":      format,
+		"expires_at":  link.Expires,
+	})
+}
+
+// sharedAnalysisHandler serves an analysis to the holder of a share link
+func sharedAnalysisHandler(c *gin.Context) {
+	if appConfig.ShareLinkSecret == "" {
+		respondError(c, 404, apierrors.CodeNotFound, "Not found")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format")
+		return
+	}
+
+	link, err := sharelink.Verify(appConfig.ShareLinkSecret, id.String(), c.Request.URL.Query(), time.Now())
+	if err != nil {
+		if errors.Is(err, sharelink.ErrExpired) {
+			respondError(c, 410, apierrors.CodeGone, "Share link has expired")
+			return
+		}
+		respondError(c, 403, apierrors.CodeForbidden, "Invalid share link")
+		return
+	}
+
+	// Keep the signature out of requests the report makes elsewhere
+	c.Header("Referrer-Policy", "no-referrer")
+	if link.Format == shareFormatJSON {
+		c.Header("Cache-Control", "no-store")
+		writeAnalysis(c, id)
+		return
+	}
+	writeReport(c, id, report.Format(link.Format))
+}
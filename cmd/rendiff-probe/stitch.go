@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/validator"
+)
+
+// stitchRequest is the JSON body accepted by the stitch validation
+// endpoint. Multipart requests send the parts as repeated "files" fields,
+// in order, and the timeout as a form field.
+type stitchRequest struct {
+	URLs    []string `json:"urls"`    // The parts in playback order
+	Timeout int      `json:"timeout"` // seconds, capped at MAX_ANALYSIS_TIMEOUT
+}
+
+// validateStitchHandler checks that the reels or segments of a multi-part
+// delivery concatenate cleanly: matching streams and codec parameters,
+// audio that ends with its video, timecode running on across each join and
+// consistent loudness. Inputs are either uploaded files or URLs in a JSON
+// body, in playback order.
+func validateStitchHandler(c *gin.Context) {
+	var request stitchRequest
+	isMultipart := strings.HasPrefix(c.GetHeader("Content-Type"), "multipart/form-data")
+
+	count := 0
+	if isMultipart {
+		form, err := c.MultipartForm()
+		if err != nil {
+			respondError(c, 400, apierrors.CodeBadRequest, "Invalid multipart request")
+			return
+		}
+		count = len(form.File["files"])
+		if value := c.PostForm("timeout"); value != "" {
+			timeout, err := strconv.Atoi(value)
+			if err != nil {
+				respondError(c, 400, apierrors.CodeBadRequest, "Invalid timeout")
+				return
+			}
+			request.Timeout = timeout
+		}
+	} else {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+			return
+		}
+		count = len(request.URLs)
+	}
+	if count < 2 || count > maxStitchParts {
+		respondError(c, 400, apierrors.CodeBadRequest, fmt.Sprintf("Between 2 and %d parts are required", maxStitchParts))
+		return
+	}
+
+	timeout := defaultStitchTimeout
+	if request.Timeout > 0 {
+		timeout = time.Duration(request.Timeout) * time.Second
+		if timeout > maxTimeout() {
+			timeout = maxTimeout()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+
+	parts := make([]ffmpeg.StitchPart, 0, count)
+	defer func() {
+		for _, part := range parts {
+			removeTempFile(part.Path)
+		}
+	}()
+	if isMultipart {
+		for i, header := range c.Request.MultipartForm.File["files"] {
+			path, name, ok := saveFormFile(c, header, fmt.Sprintf("part%d", i+1))
+			if !ok {
+				return
+			}
+			parts = append(parts, ffmpeg.StitchPart{Name: name, Path: path})
+		}
+	} else {
+		// Validate every URL before downloading any (SSRF prevention)
+		for _, u := range request.URLs {
+			if err := validator.ValidateURL(u); err != nil {
+				appLogger.Warn().Str("url", u).Err(err).Msg("URL validation failed")
+				respondError(c, 400, apierrors.CodeURLBlocked, "Invalid or blocked URL")
+				return
+			}
+		}
+		for _, u := range request.URLs {
+			path, name, err := downloadURL(ctx, u)
+			if err != nil {
+				appLogger.Warn().Err(err).Str("url", u).Msg("Part download failed")
+				apierrors.Respond(c, storageFailure(err, apierrors.CodeDownloadFailed, "Failed to download part").With("url", u))
+				return
+			}
+			parts = append(parts, ffmpeg.StitchPart{Name: name, Path: path})
+		}
+	}
+
+	startTime := time.Now()
+	for i := range parts {
+		result, err := analyzeFileExpress(ctx, parts[i].Path, nil)
+		if err != nil {
+			appLogger.Warn().Err(err).Str("part", parts[i].Name).Msg("Part probe failed")
+			apierrors.Respond(c, apierrors.New(422, apierrors.CodeAnalysisFailed, "Failed to probe part").With("part", parts[i].Name))
+			return
+		}
+		parts[i].Result = result
+	}
+
+	analysis, err := stitchValidator.ValidateStitch(ctx, parts)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Stitch validation failed")
+		respondError(c, 500, apierrors.CodeInternalError, "Stitch validation failed")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"status":          "success",
+		"validation_id":   uuid.New().String(),
+		"stitch":          analysis,
+		"processing_time": time.Since(startTime).String(),
+		"timestamp":       time.Now(),
+	})
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/subtitles"
+	"github.com/rendiffdev/rendiff-probe/internal/validator"
+)
+
+// validateSubtitlesHandler validates an uploaded SRT, TTML/IMSC or EBU STL
+// file. The reference media is either given as a duration and frame rate
+// or taken from a stored analysis; explicit values take precedence.
+func validateSubtitlesHandler(c *gin.Context) {
+	startTime := time.Now()
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "No file provided")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > subtitles.MaxFileSize {
+		apierrors.Respond(c, apierrors.New(413, apierrors.CodeFileTooLarge, "File too large").With("max_size_bytes", subtitles.MaxFileSize))
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, subtitles.MaxFileSize+1))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Failed to read file")
+		return
+	}
+	if len(data) > subtitles.MaxFileSize {
+		apierrors.Respond(c, apierrors.New(413, apierrors.CodeFileTooLarge, "File too large").With("max_size_bytes", subtitles.MaxFileSize))
+		return
+	}
+
+	var opts subtitles.Options
+	if value := c.PostForm("analysis_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format")
+			return
+		}
+		stored, _, err := loadStoredAnalysis(c.Request.Context(), id)
+		if errors.Is(err, database.ErrAnalysisNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Analysis not found")
+			return
+		}
+		if err != nil {
+			appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to load reference analysis")
+			respondError(c, 500, apierrors.CodeInternalError, "Failed to load analysis")
+			return
+		}
+		if stored.result == nil {
+			respondError(c, 400, apierrors.CodeBadRequest, "Reference analysis failed and has no result")
+			return
+		}
+		opts.ReferenceDuration, opts.FrameRate = referenceMedia(stored.result)
+	}
+
+	if value := c.PostForm("frame_rate"); value != "" {
+		rate, err := parseRate(value)
+		if err != nil || !(rate > 0) || math.IsInf(rate, 0) {
+			respondError(c, 400, apierrors.CodeBadRequest, "Invalid frame_rate")
+			return
+		}
+		opts.FrameRate = rate
+	}
+	for _, field := range []struct {
+		name  string
+		value *float64
+	}{
+		{"reference_duration", &opts.ReferenceDuration},
+		{"max_cps", &opts.MaxCPS},
+		{"min_duration", &opts.MinCueDuration},
+	} {
+		if value := c.PostForm(field.name); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil || !(parsed > 0) || math.IsInf(parsed, 0) {
+				respondError(c, 400, apierrors.CodeBadRequest, fmt.Sprintf("Invalid %s", field.name))
+				return
+			}
+			*field.value = parsed
+		}
+	}
+	for _, field := range []struct {
+		name  string
+		value *int
+	}{
+		{"max_line_length", &opts.MaxLineLength},
+		{"max_lines", &opts.MaxLines},
+	} {
+		if value := c.PostForm(field.name); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				respondError(c, 400, apierrors.CodeBadRequest, fmt.Sprintf("Invalid %s", field.name))
+				return
+			}
+			*field.value = parsed
+		}
+	}
+
+	validation, err := subtitles.Validate(data, opts)
+	if err != nil {
+		respondError(c, 422, apierrors.CodeValidationError, err.Error())
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"status":          "success",
+		"filename":        validator.SanitizeFilename(header.Filename),
+		"validation":      validation,
+		"processing_time": time.Since(startTime).String(),
+		"timestamp":       time.Now(),
+	})
+}
+
+// referenceMedia returns the duration and the frame rate of the first video
+// stream of an analysis, zero when unknown
+func referenceMedia(result *ffmpeg.FFprobeResult) (duration, frameRate float64) {
+	if result.Format != nil {
+		duration, _ = strconv.ParseFloat(result.Format.Duration, 64)
+	}
+	for _, stream := range result.Streams {
+		if stream.CodecType != "video" || stream.Disposition["attached_pic"] != 0 {
+			continue
+		}
+		frameRate, _ = parseRate(stream.RFrameRate)
+		break
+	}
+	return duration, frameRate
+}
+
+// parseRate parses a decimal number or a rational such as 30000/1001
+func parseRate(value string) (float64, error) {
+	numerator, denominator, rational := strings.Cut(strings.TrimSpace(value), "/")
+	n, err := strconv.ParseFloat(numerator, 64)
+	if err != nil || !rational {
+		return n, err
+	}
+	d, err := strconv.ParseFloat(denominator, 64)
+	if err != nil || d == 0 {
+		return 0, fmt.Errorf("invalid rate %q", value)
+	}
+	return n / d, nil
+}
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// With ENABLE_TENANCY every API request carries a key. API_KEY acts for the
+// deployment and sees everything; a tenant key acts for one organization or
+// one of its projects. The key's scope travels in the request context
+// (database.WithScope), so the stores filter every query by it and the
+// in-memory analyses, batch jobs and uploads check it before answering.
+// Resources of another tenant are reported as not found.
+
+// tenantStore is nil unless multi-tenancy is enabled
+var tenantStore *database.TenantStore
+
+var (
+	// uploadOwners maps resumable upload IDs to the scope that created them
+	uploadOwners     = make(map[string]database.Scope)
+	uploadOwnersLock sync.Mutex
+)
+
+var errAPIKeyRequired = errors.New("API key required")
+
+// extractAPIKey reads the key from X-API-Key, an "Authorization: ApiKey"
+// header or the api_key query parameter, which WebSocket clients need
+func extractAPIKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "ApiKey ") {
+		return strings.TrimPrefix(auth, "ApiKey ")
+	}
+	return c.Query("api_key")
+}
+
+// authenticateAPIKey returns ctx with the scope and role a key acts for:
+// the zero scope and admin role for the deployment key, the key's tenant
+// and role otherwise
+func authenticateAPIKey(ctx context.Context, key string) (context.Context, error) {
+	if key == "" {
+		return nil, errAPIKeyRequired
+	}
+	if appConfig.APIKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(appConfig.APIKey)) == 1 {
+		return withRole(database.WithScope(ctx, database.Scope{}), database.RoleAdmin), nil
+	}
+	record, err := tenantStore.Authenticate(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return withRole(database.WithScope(ctx, record.Scope()), record.Role), nil
+}
+
+// tenantAuthMiddleware rejects requests without a valid API key and scopes
+// the rest to the key's tenant and role
+func tenantAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, err := authenticateAPIKey(c.Request.Context(), extractAPIKey(c))
+		switch {
+		case errors.Is(err, errAPIKeyRequired):
+			abortWithError(c, 401, apierrors.CodeUnauthorized, "API key required")
+			return
+		case errors.Is(err, database.ErrAPIKeyNotFound):
+			abortWithError(c, 401, apierrors.CodeUnauthorized, "Invalid API key")
+			return
+		case err != nil:
+			appLogger.Error().Err(err).Msg("Failed to authenticate API key")
+			abortWithError(c, 500, apierrors.CodeInternalError, "Failed to authenticate request")
+			return
+		}
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// requireDeploymentScope limits a route to the deployment key. Monitors,
+// watch folders and tenant administration are shared by every tenant.
+func requireDeploymentScope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !database.ScopeFromContext(c.Request.Context()).IsZero() {
+			abortWithError(c, 403, apierrors.CodeForbidden, "Requires the deployment API key")
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireDeploymentKey limits a route to callers holding API_KEY when
+// tenancy is off and no key was checked on the way in. With tenancy the
+// key was authenticated already and requireDeploymentScope applies.
+func requireDeploymentKey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if appConfig.EnableTenancy {
+			c.Next()
+			return
+		}
+		key := extractAPIKey(c)
+		if key == "" {
+			abortWithError(c, 401, apierrors.CodeUnauthorized, "API key required")
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(key), []byte(appConfig.APIKey)) != 1 {
+			abortWithError(c, 401, apierrors.CodeUnauthorized, "Invalid API key")
+			return
+		}
+		c.Next()
+	}
+}
+
+// grpcAPIKey reads the key from x-api-key or "authorization: ApiKey"
+// metadata
+func grpcAPIKey(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if keys := md.Get("x-api-key"); len(keys) > 0 {
+		return keys[0]
+	}
+	for _, auth := range md.Get("authorization") {
+		if strings.HasPrefix(auth, "ApiKey ") {
+			return strings.TrimPrefix(auth, "ApiKey ")
+		}
+	}
+	return ""
+}
+
+// grpcScope authenticates an RPC and returns its context with the key's
+// scope and role, rejecting keys whose role does not allow the method.
+// Health checks need no key.
+func grpcScope(ctx context.Context, method string) (context.Context, error) {
+	if strings.HasPrefix(method, "/grpc.health.v1.Health/") {
+		return ctx, nil
+	}
+
+	ctx, err := authenticateAPIKey(ctx, grpcAPIKey(ctx))
+	switch {
+	case errors.Is(err, errAPIKeyRequired):
+		return nil, status.Error(codes.Unauthenticated, "API key required")
+	case errors.Is(err, database.ErrAPIKeyNotFound):
+		return nil, status.Error(codes.Unauthenticated, "Invalid API key")
+	case err != nil:
+		appLogger.Error().Err(err).Msg("Failed to authenticate API key")
+		return nil, status.Error(codes.Internal, "Failed to authenticate request")
+	}
+	if required := grpcMethodRole(method); !roleFromContext(ctx).Includes(required) {
+		return nil, status.Errorf(codes.PermissionDenied, "Requires the %s role", required)
+	}
+	return ctx, nil
+}
+
+// grpcTenantUnaryInterceptor is tenantAuthMiddleware for unary RPCs
+func grpcTenantUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := grpcScope(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// grpcTenantStreamInterceptor is tenantAuthMiddleware for streaming RPCs
+func grpcTenantStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := grpcScope(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &scopedServerStream{ServerStream: ss, ctx: ctx})
+}
+
+// scopedServerStream replaces the context of a stream
+type scopedServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *scopedServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// visibleBatchJob reports whether the scope of ctx may see a job. A job's
+// scope is set when it starts and never changes, so no lock is needed.
+func visibleBatchJob(ctx context.Context, job *BatchJob) bool {
+	return database.ScopeFromContext(ctx).Contains(job.Scope)
+}
+
+// claimUpload records the scope of ctx as the owner of a new upload,
+// forgetting uploads whose sessions have expired
+func claimUpload(ctx context.Context, id string) {
+	uploadOwnersLock.Lock()
+	defer uploadOwnersLock.Unlock()
+
+	for uploadID := range uploadOwners {
+		if _, err := uploadManager.Get(uploadID); err != nil {
+			delete(uploadOwners, uploadID)
+		}
+	}
+	uploadOwners[id] = database.ScopeFromContext(ctx)
+}
+
+// ownsUpload reports whether the scope of ctx may use an upload. Unknown
+// uploads are left for the upload manager to reject.
+func ownsUpload(ctx context.Context, id string) bool {
+	uploadOwnersLock.Lock()
+	owner, exists := uploadOwners[id]
+	uploadOwnersLock.Unlock()
+	return !exists || database.ScopeFromContext(ctx).Contains(owner)
+}
+
+// tenantNameRequest is the JSON body that creates an organization or project
+type tenantNameRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// createOrganizationHandler adds a tenant
+func createOrganizationHandler(c *gin.Context) {
+	var request tenantNameRequest
+	if err := c.ShouldBindJSON(&request); err != nil || strings.TrimSpace(request.Name) == "" {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return
+	}
+
+	org, err := tenantStore.CreateOrganization(c.Request.Context(), strings.TrimSpace(request.Name))
+	if err != nil {
+		if errors.Is(err, database.ErrNameTaken) {
+			respondError(c, 409, apierrors.CodeConflict, "Organization name already in use")
+			return
+		}
+		appLogger.Error().Err(err).Msg("Failed to create organization")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to create organization")
+		return
+	}
+	c.JSON(201, org)
+}
+
+// listOrganizationsHandler lists every tenant
+func listOrganizationsHandler(c *gin.Context) {
+	orgs, err := tenantStore.ListOrganizations(c.Request.Context())
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Failed to list organizations")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to list organizations")
+		return
+	}
+	c.JSON(200, gin.H{"organizations": orgs, "count": len(orgs)})
+}
+
+// deleteOrganizationHandler removes a tenant with its projects, keys and
+// policies. Its analyses stay visible to the deployment.
+func deleteOrganizationHandler(c *gin.Context) {
+	if err := tenantStore.DeleteOrganization(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, database.ErrOrganizationNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Organization not found")
+			return
+		}
+		appLogger.Error().Err(err).Msg("Failed to delete organization")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to delete organization")
+		return
+	}
+	c.Status(204)
+}
+
+// createProjectHandler adds a project to an organization
+func createProjectHandler(c *gin.Context) {
+	var request tenantNameRequest
+	if err := c.ShouldBindJSON(&request); err != nil || strings.TrimSpace(request.Name) == "" {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return
+	}
+
+	project, err := tenantStore.CreateProject(c.Request.Context(), c.Param("id"), strings.TrimSpace(request.Name))
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrOrganizationNotFound):
+			respondError(c, 404, apierrors.CodeNotFound, "Organization not found")
+		case errors.Is(err, database.ErrNameTaken):
+			respondError(c, 409, apierrors.CodeConflict, "Project name already in use")
+		default:
+			appLogger.Error().Err(err).Msg("Failed to create project")
+			respondError(c, 500, apierrors.CodeInternalError, "Failed to create project")
+		}
+		return
+	}
+	c.JSON(201, project)
+}
+
+// listProjectsHandler lists the projects of an organization
+func listProjectsHandler(c *gin.Context) {
+	if _, err := tenantStore.GetOrganization(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, database.ErrOrganizationNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Organization not found")
+			return
+		}
+		appLogger.Error().Err(err).Msg("Failed to get organization")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to list projects")
+		return
+	}
+
+	projects, err := tenantStore.ListProjects(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Failed to list projects")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to list projects")
+		return
+	}
+	c.JSON(200, gin.H{"projects": projects, "count": len(projects)})
+}
+
+// deleteProjectHandler removes a project with its keys and policies
+func deleteProjectHandler(c *gin.Context) {
+	if err := tenantStore.DeleteProject(c.Request.Context(), c.Param("id"), c.Param("project_id")); err != nil {
+		if errors.Is(err, database.ErrProjectNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Project not found")
+			return
+		}
+		appLogger.Error().Err(err).Msg("Failed to delete project")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to delete project")
+		return
+	}
+	c.Status(204)
+}
+
+// createAPIKeyRequest is the JSON body that issues an API key
+type createAPIKeyRequest struct {
+	OrganizationID string `json:"organization_id"`
+	ProjectID      string `json:"project_id"`
+	Name           string `json:"name"`
+	Role           string `json:"role"`
+}
+
+// createAPIKeyHandler issues a tenant key, an operator key unless the
+// request names a role. The deployment may issue keys for any tenant, an
+// organization key only for itself and its projects. The key is in the
+// response once and cannot be recovered.
+func createAPIKeyHandler(c *gin.Context) {
+	var request createAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return
+	}
+
+	role := database.RoleOperator
+	if request.Role != "" {
+		var err error
+		if role, err = database.ParseRole(request.Role); err != nil {
+			respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+			return
+		}
+	}
+
+	caller := database.ScopeFromContext(c.Request.Context())
+	scope := database.Scope{OrganizationID: request.OrganizationID, ProjectID: request.ProjectID}
+	if scope.OrganizationID == "" {
+		scope.OrganizationID = caller.OrganizationID
+	}
+	if scope.IsZero() {
+		respondError(c, 400, apierrors.CodeBadRequest, "organization_id is required")
+		return
+	}
+	if !caller.Contains(scope) || (caller.ProjectID != "" && scope.ProjectID == "") {
+		respondError(c, 403, apierrors.CodeForbidden, "Cannot issue keys for another tenant")
+		return
+	}
+
+	record, key, err := tenantStore.CreateAPIKey(c.Request.Context(), scope, request.Name, role)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrOrganizationNotFound):
+			respondError(c, 404, apierrors.CodeNotFound, "Organization not found")
+		case errors.Is(err, database.ErrProjectNotFound):
+			respondError(c, 404, apierrors.CodeNotFound, "Project not found")
+		default:
+			appLogger.Error().Err(err).Msg("Failed to create API key")
+			respondError(c, 500, apierrors.CodeInternalError, "Failed to create API key")
+		}
+		return
+	}
+	c.JSON(201, gin.H{"api_key": record, "key": key})
+}
+
+// listAPIKeysHandler lists the keys the caller can see, optionally of one
+// organization
+func listAPIKeysHandler(c *gin.Context) {
+	scope := database.ScopeFromContext(c.Request.Context())
+	if orgID := c.Query("organization_id"); orgID != "" && scope.IsZero() {
+		scope.OrganizationID = orgID
+	}
+
+	keys, err := tenantStore.ListAPIKeys(c.Request.Context(), scope)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Failed to list API keys")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to list API keys")
+		return
+	}
+	c.JSON(200, gin.H{"api_keys": keys, "count": len(keys)})
+}
+
+// revokeAPIKeyHandler deletes a key the caller can see
+func revokeAPIKeyHandler(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid API key ID format")
+		return
+	}
+
+	if err := tenantStore.RevokeAPIKey(c.Request.Context(), database.ScopeFromContext(c.Request.Context()), id); err != nil {
+		if errors.Is(err, database.ErrAPIKeyNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "API key not found")
+			return
+		}
+		appLogger.Error().Err(err).Msg("Failed to revoke API key")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to revoke API key")
+		return
+	}
+	c.Status(204)
+}
+
+// visibleProgress reports whether the scope of ctx may follow the progress
+// of a batch job or upload
+func visibleProgress(ctx context.Context, id string) bool {
+	if database.ScopeFromContext(ctx).IsZero() {
+		return true
+	}
+
+	batchLock.RLock()
+	job, exists := batchJobs[id]
+	batchLock.RUnlock()
+	if !exists {
+		job, exists = loadSharedBatchJob(ctx, id)
+	}
+	if exists {
+		return visibleBatchJob(ctx, job)
+	}
+
+	uploadOwnersLock.Lock()
+	_, known := uploadOwners[id]
+	uploadOwnersLock.Unlock()
+	return known && ownsUpload(ctx, id)
+}
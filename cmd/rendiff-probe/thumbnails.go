@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// Thumbnail endpoint output formats
+const (
+	thumbnailFormatJSON         = "json"
+	thumbnailFormatContactSheet = "contact_sheet"
+	thumbnailFormatFilmstrip    = "filmstrip"
+)
+
+// thumbnailRequest is the optional still selection of a probe request,
+// with the other pictures taken while the file is on disk. Without a mode
+// the report stills are kept as the analysis thumbnails.
+type thumbnailRequest struct {
+	Thumbnails        string  `json:"thumbnails"`         // "interval" or "scene"
+	ThumbnailInterval float64 `json:"thumbnail_interval"` // Seconds between interval stills
+	Tonemap           string  `json:"tonemap"`            // tonemap operator for SDR previews of HDR files
+	QCTools           bool    `json:"qctools"`            // keep a QCTools report of the frame-level signal statistics
+}
+
+// thumbnailOptions is a validated thumbnailRequest
+type thumbnailOptions struct {
+	filmstrip *ffmpeg.FilmstripOptions // nil keeps the report stills
+	tonemap   string                   // empty skips SDR previews
+	qctools   bool
+}
+
+// options validates a still selection
+func (r thumbnailRequest) options() (thumbnailOptions, error) {
+	options := thumbnailOptions{qctools: r.QCTools}
+	if r.Tonemap != "" {
+		operator, err := ffmpeg.ParseTonemapOperator(r.Tonemap)
+		if err != nil {
+			return options, err
+		}
+		options.tonemap = operator
+	}
+	if r.Thumbnails == "" {
+		return options, nil
+	}
+	mode, err := ffmpeg.ParseThumbnailMode(r.Thumbnails)
+	if err != nil {
+		return options, err
+	}
+	if r.ThumbnailInterval < 0 {
+		return options, fmt.Errorf("thumbnail_interval must not be negative")
+	}
+	options.filmstrip = &ffmpeg.FilmstripOptions{
+		Mode:     mode,
+		Interval: r.ThumbnailInterval,
+		MaxCount: maxFilmstripFrames,
+		Width:    filmstripWidth,
+	}
+	return options, nil
+}
+
+// parseThumbnailForm reads a still selection from multipart form fields
+func parseThumbnailForm(c *gin.Context) (thumbnailOptions, error) {
+	request := thumbnailRequest{Thumbnails: c.PostForm("thumbnails"), Tonemap: c.PostForm("tonemap"), QCTools: c.PostForm("qctools") == "true"}
+	if value := c.PostForm("thumbnail_interval"); value != "" {
+		interval, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return thumbnailOptions{}, fmt.Errorf("invalid thumbnail_interval %q", value)
+		}
+		request.ThumbnailInterval = interval
+	}
+	return request.options()
+}
+
+// saveFilmstrip persists the thumbnails of an analysis while its file is
+// still on disk: the requested filmstrip, or the report stills when none
+// was requested. It returns the summary added to the probe response, or
+// nil when the file has no stills.
+func saveFilmstrip(ctx context.Context, analysisID, path string, result *ffmpeg.FFprobeResult, reportStills []ffmpeg.Thumbnail, options *ffmpeg.FilmstripOptions) gin.H {
+	mode := ffmpeg.ThumbnailModeInterval
+	thumbnails := reportStills
+	if options != nil && len(reportStills) > 0 {
+		// Report stills are only missing for files without usable video
+		mode = options.Mode
+		var duration float64
+		if result.Format != nil {
+			duration, _ = strconv.ParseFloat(result.Format.Duration, 64)
+		}
+		filmstrip, err := thumbnailExtractor.Filmstrip(ctx, path, duration, *options)
+		if err != nil || len(filmstrip) == 0 {
+			appLogger.Warn().Err(err).Str("analysis_id", analysisID).Msg("Failed to capture filmstrip, keeping report stills")
+			mode = ffmpeg.ThumbnailModeInterval
+		} else {
+			thumbnails = filmstrip
+		}
+	}
+	if len(thumbnails) == 0 {
+		return nil
+	}
+
+	records := make([]database.ThumbnailRecord, len(thumbnails))
+	frames := make([]gin.H, len(thumbnails))
+	for i, thumbnail := range thumbnails {
+		records[i] = database.ThumbnailRecord{
+			Mode:   string(mode),
+			Time:   thumbnail.Time,
+			Width:  thumbnail.Width,
+			Height: thumbnail.Height,
+			JPEG:   thumbnail.JPEG,
+		}
+		frames[i] = gin.H{"time": thumbnail.Time, "width": thumbnail.Width, "height": thumbnail.Height}
+	}
+
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), analysisSaveTimeout)
+	defer cancel()
+	if err := thumbnailStore.Save(saveCtx, analysisID, records); err != nil {
+		appLogger.Error().Err(err).Str("analysis_id", analysisID).Msg("Failed to save thumbnails")
+		return nil
+	}
+
+	return gin.H{
+		"mode":   mode,
+		"count":  len(frames),
+		"frames": frames,
+		"url":    "/api/v1/analyses/" + analysisID + "/thumbnails",
+	}
+}
+
+// analysisThumbnailsHandler returns the stills of an analysis as JSON with
+// base64 JPEG data, or tiled into a contact sheet or filmstrip JPEG
+func analysisThumbnailsHandler(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid analysis ID format")
+		return
+	}
+
+	format := c.DefaultQuery("format", thumbnailFormatJSON)
+	columns := defaultContactSheetColumns
+	switch format {
+	case thumbnailFormatJSON, thumbnailFormatFilmstrip:
+	case thumbnailFormatContactSheet:
+		if value := c.Query("columns"); value != "" {
+			if columns, err = strconv.Atoi(value); err != nil || columns < 1 || columns > maxFilmstripFrames {
+				respondError(c, 400, apierrors.CodeBadRequest, "Invalid columns, must be between 1 and "+strconv.Itoa(maxFilmstripFrames))
+				return
+			}
+		}
+	default:
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid format, must be 'json', 'contact_sheet' or 'filmstrip'")
+		return
+	}
+
+	records, err := thumbnailStore.List(c.Request.Context(), id.String())
+	if err != nil {
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to list thumbnails")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to get thumbnails")
+		return
+	}
+	if len(records) == 0 {
+		if _, err := analysisStore.Get(c.Request.Context(), id); errors.Is(err, database.ErrAnalysisNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Analysis not found")
+			return
+		}
+		// Audio-only files, stream-mode probes and failed analyses have no stills
+		respondError(c, 404, apierrors.CodeNotFound, "No thumbnails for this analysis")
+		return
+	}
+
+	thumbnails := make([]ffmpeg.Thumbnail, len(records))
+	for i, record := range records {
+		thumbnails[i] = ffmpeg.Thumbnail{
+			Time:   record.Time,
+			Width:  record.Width,
+			Height: record.Height,
+			JPEG:   record.JPEG,
+		}
+	}
+
+	if format == thumbnailFormatJSON {
+		c.JSON(200, gin.H{
+			"analysis_id": id.String(),
+			"mode":        records[0].Mode,
+			"count":       len(thumbnails),
+			"thumbnails":  thumbnails,
+		})
+		return
+	}
+
+	if format == thumbnailFormatFilmstrip {
+		columns = len(thumbnails)
+	}
+	sheet, err := ffmpeg.ContactSheet(thumbnails, columns)
+	if err != nil {
+		appLogger.Error().Err(err).Str("analysis_id", id.String()).Msg("Failed to build contact sheet")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to build contact sheet")
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", id.String()+"_"+format+".jpg"))
+	c.Data(200, "image/jpeg", sheet)
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/upload"
+	"github.com/rendiffdev/rendiff-probe/internal/validator"
+)
+
+// Resumable uploads follow the tus.io flow without its protocol headers
+// beyond Upload-Offset/Upload-Length: create a session with the file size,
+// PATCH chunks at the current offset (HEAD returns it after a dropped
+// connection), then complete the session to start analysis.
+
+// createUploadRequest is the JSON body that starts an upload session
+type createUploadRequest struct {
+	Filename string `json:"filename" binding:"required"`
+	Size     int64  `json:"size" binding:"required"`
+}
+
+// createUploadHandler starts a resumable upload session
+func createUploadHandler(c *gin.Context) {
+	var request createUploadRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return
+	}
+
+	if request.Size <= 0 {
+		respondError(c, 400, apierrors.CodeBadRequest, "Size must be greater than 0")
+		return
+	}
+	if request.Size > maxFileSize() {
+		apierrors.Respond(c, apierrors.New(413, apierrors.CodeFileTooLarge, "File too large").With("max_size_bytes", maxFileSize()))
+		return
+	}
+
+	safeFilename := validator.SanitizeFilename(request.Filename)
+	if safeFilename == "" {
+		safeFilename = fmt.Sprintf("upload_%s", uuid.New().String()[:8])
+	}
+
+	session, err := uploadManager.Create(safeFilename, request.Size)
+	if err != nil {
+		if errors.Is(err, upload.ErrTooManySessions) {
+			respondError(c, 429, apierrors.CodeTooManyRequests, "Too many active uploads, try again later")
+			return
+		}
+		appLogger.Error().Err(err).Msg("Failed to create upload session")
+		apierrors.Respond(c, storageFailure(err, apierrors.CodeInternalError, "Failed to create upload"))
+		return
+	}
+	claimUpload(c.Request.Context(), session.ID)
+
+	uploadURL := fmt.Sprintf("/api/v1/uploads/%s", session.ID)
+	c.Header("Location", uploadURL)
+	c.JSON(201, gin.H{
+		"upload_id":      session.ID,
+		"filename":       session.Filename,
+		"size":           session.Size,
+		"offset":         session.Offset,
+		"expires_at":     session.ExpiresAt,
+		"max_chunk_size": maxUploadChunkSize,
+		"upload_url":     uploadURL,
+		"ws_url":         fmt.Sprintf("/api/v1/ws/progress/%s", session.ID),
+	})
+}
+
+// uploadOffsetHandler reports the current offset so clients can resume
+func uploadOffsetHandler(c *gin.Context) {
+	session, ok := lookupUpload(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(session.Size, 10))
+	c.Header("Cache-Control", "no-store")
+	c.Status(200)
+}
+
+// uploadStatusHandler returns the session state, including the analysis
+// result once processing has finished
+func uploadStatusHandler(c *gin.Context) {
+	session, ok := lookupUpload(c)
+	if !ok {
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(200, gin.H{
+		"upload":   session,
+		"progress": session.Progress(),
+	})
+}
+
+// appendUploadHandler writes one chunk at the offset given in Upload-Offset
+func appendUploadHandler(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid upload ID format")
+		return
+	}
+	if !ownsUpload(c.Request.Context(), id) {
+		respondError(c, 404, apierrors.CodeNotFound, "Upload not found")
+		return
+	}
+
+	if c.GetHeader("Content-Type") != uploadChunkContentType {
+		respondError(c, 415, apierrors.CodeUnsupportedMedia, fmt.Sprintf("Content-Type must be %s", uploadChunkContentType))
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		respondError(c, 400, apierrors.CodeBadRequest, "Missing or invalid Upload-Offset header")
+		return
+	}
+
+	session, err := uploadManager.Append(id, offset, c.Request.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, upload.ErrSessionNotFound):
+			respondError(c, 404, apierrors.CodeNotFound, "Upload not found")
+		case errors.Is(err, upload.ErrOffsetMismatch):
+			apierrors.Respond(c, apierrors.New(409, apierrors.CodeConflict, "Upload offset mismatch").With("offset", session.Offset))
+		case errors.Is(err, upload.ErrNotUploading):
+			apierrors.Respond(c, apierrors.New(409, apierrors.CodeConflict, "Upload is no longer accepting data").With("status", session.Status))
+		case errors.Is(err, upload.ErrSizeExceeded):
+			apierrors.Respond(c, apierrors.New(413, apierrors.CodePayloadTooLarge, "Chunk exceeds declared upload size").With("offset", session.Offset).With("size", session.Size))
+		case errors.As(err, &maxBytesErr):
+			// Bytes up to the limit were kept; the client resumes from offset
+			c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+			apierrors.Respond(c, apierrors.New(413, apierrors.CodePayloadTooLarge, "Chunk too large").With("max_chunk_size", maxUploadChunkSize).With("offset", session.Offset))
+		default:
+			appLogger.Warn().Err(err).Str("upload_id", id).Int64("offset", session.Offset).Msg("Upload chunk interrupted")
+			c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+			apierrors.Respond(c, apierrors.New(400, apierrors.CodeBadRequest, "Upload interrupted").With("offset", session.Offset))
+		}
+		return
+	}
+
+	sendProgressUpdate(session.ID, session.Progress(), string(session.Status),
+		fmt.Sprintf("Received %d of %d bytes", session.Offset, session.Size))
+
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	c.JSON(200, gin.H{
+		"upload_id": session.ID,
+		"offset":    session.Offset,
+		"size":      session.Size,
+		"progress":  session.Progress(),
+	})
+}
+
+// completeUploadHandler finalizes a fully received upload and starts analysis
+func completeUploadHandler(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid upload ID format")
+		return
+	}
+	if !ownsUpload(c.Request.Context(), id) {
+		respondError(c, 404, apierrors.CodeNotFound, "Upload not found")
+		return
+	}
+
+	// The body is optional; an empty body runs the default analysis
+	var request fileProbeRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid request")
+		return
+	}
+	options, err := request.validate(c.Request.Context())
+	if err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, err.Error())
+		return
+	}
+
+	session, path, err := uploadManager.Complete(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, upload.ErrSessionNotFound):
+			respondError(c, 404, apierrors.CodeNotFound, "Upload not found")
+		case errors.Is(err, upload.ErrIncomplete):
+			apierrors.Respond(c, apierrors.New(409, apierrors.CodeConflict, "Upload is incomplete").With("offset", session.Offset).With("size", session.Size))
+		default:
+			apierrors.Respond(c, apierrors.New(409, apierrors.CodeConflict, "Upload already completed").With("status", session.Status))
+		}
+		return
+	}
+
+	sendProgressUpdate(session.ID, 100, string(upload.StatusProcessing), "Upload complete, analysis started")
+
+	run := func(ctx context.Context) (int, gin.H) {
+		return options.run(ctx, session.ID, path, session.Filename, session.Size)
+	}
+	startBackgroundProbe(c.Request.Context(), session.ID, request.CallbackURL, maxTimeout(), run, func(status int, payload gin.H) {
+		if status == 200 {
+			uploadManager.Finish(session.ID, payload, nil)
+			sendProgressUpdate(session.ID, 100, string(upload.StatusCompleted), "Analysis completed")
+			return
+		}
+		message, _ := payload["error"].(string)
+		uploadManager.Finish(session.ID, nil, errors.New(message))
+		sendProgressUpdate(session.ID, 100, string(upload.StatusFailed), message)
+	})
+
+	response := gin.H{
+		"status":     "accepted",
+		"upload_id":  session.ID,
+		"message":    "Analysis started",
+		"status_url": fmt.Sprintf("/api/v1/uploads/%s", session.ID),
+		"ws_url":     fmt.Sprintf("/api/v1/ws/progress/%s", session.ID),
+	}
+	if request.CallbackURL != "" {
+		response["callback_url"] = request.CallbackURL
+	}
+	c.JSON(202, response)
+}
+
+// fileProbeRequest is the optional JSON analysis options of a file that is
+// uploaded rather than posted with probe/file
+type fileProbeRequest struct {
+	IncludeLLM   bool     `json:"include_llm"`
+	RefreshLLM   bool     `json:"refresh_llm"`
+	RefreshCache bool     `json:"refresh_cache"`
+	Mode         string   `json:"mode"`
+	Categories   []string `json:"categories"`
+	Profile      string   `json:"profile"`
+	Streams      string   `json:"streams"`
+	Checksums    []string `json:"checksums"`
+	CallbackURL  string   `json:"callback_url"`
+	thumbnailRequest
+	// FFprobeOptions overrides probesize, analyzeduration, read_intervals
+	// and select_streams, see ffmpeg.ProbeOptionNames
+	FFprobeOptions json.RawMessage `json:"ffprobe_options"`
+	// StartTime and Duration limit the analysis to a segment, in seconds;
+	// a negative start counts back from the end
+	StartTime float64 `json:"start_time"`
+	Duration  float64 `json:"duration"`
+}
+
+// fileProbeOptions is a validated fileProbeRequest
+type fileProbeOptions struct {
+	fileProbeRequest
+	mode         string
+	categories   []ffmpeg.QCCategory
+	profile      *ffmpeg.DeliveryProfile
+	checksums    []string
+	thumbnails   thumbnailOptions
+	probeOptions *ffmpeg.ProbeOptions
+	segment      *ffmpeg.AnalysisSegment
+}
+
+// validate checks the options, looking the profile up for the tenant of ctx
+func (r fileProbeRequest) validate(ctx context.Context) (*fileProbeOptions, error) {
+	options := &fileProbeOptions{fileProbeRequest: r}
+	var err error
+	if options.mode, err = parseFileProbeMode(r.Mode); err != nil {
+		return nil, err
+	}
+	if options.categories, err = ffmpeg.NormalizeQCCategories(r.Categories); err != nil {
+		return nil, err
+	}
+	if options.profile, err = lookupProfile(ctx, r.Profile); err != nil {
+		return nil, err
+	}
+	if _, err := ffmpeg.ParseStreamSelector(r.Streams); err != nil {
+		return nil, err
+	}
+	if options.checksums, err = ffmpeg.NormalizeChecksumAlgorithms(r.Checksums); err != nil {
+		return nil, err
+	}
+	if options.thumbnails, err = r.options(); err != nil {
+		return nil, err
+	}
+	if options.probeOptions, err = ffmpeg.ParseProbeOptions(r.FFprobeOptions); err != nil {
+		return nil, err
+	}
+	if options.segment, err = ffmpeg.NewAnalysisSegment(r.StartTime, r.Duration); err != nil {
+		return nil, err
+	}
+	if err := checkSegment(options.segment, options.mode, options.probeOptions); err != nil {
+		return nil, err
+	}
+	if err := validateCallbackURL(r.CallbackURL); err != nil {
+		return nil, err
+	}
+	return options, nil
+}
+
+// run analyzes a received file like probe/file with these options
+func (o *fileProbeOptions) run(ctx context.Context, analysisID, path, filename string, size int64) (int, gin.H) {
+	ctx = ffmpeg.WithAnalysisSegment(ffmpeg.WithProbeOptions(ctx, o.probeOptions), o.segment)
+	if o.mode == probeModeExpress {
+		return runExpressFileProbe(ctx, analysisID, path, filename, size, o.IncludeLLM, o.RefreshLLM, o.profile)
+	}
+	return runFileProbe(ctx, analysisID, path, filename, size, o.IncludeLLM, o.RefreshLLM, o.RefreshCache, o.mode == probeModeDeep, o.categories, o.profile, o.Streams, o.checksums, o.thumbnails)
+}
+
+// abortUploadHandler cancels an upload and deletes its data
+func abortUploadHandler(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid upload ID format")
+		return
+	}
+	if !ownsUpload(c.Request.Context(), id) {
+		respondError(c, 404, apierrors.CodeNotFound, "Upload not found")
+		return
+	}
+
+	if err := uploadManager.Abort(id); err != nil {
+		if errors.Is(err, upload.ErrSessionNotFound) {
+			respondError(c, 404, apierrors.CodeNotFound, "Upload not found")
+			return
+		}
+		respondError(c, 409, apierrors.CodeConflict, "Upload is being analyzed and cannot be cancelled")
+		return
+	}
+
+	c.Status(204)
+}
+
+// lookupUpload resolves the :id parameter, writing an error response on failure
+func lookupUpload(c *gin.Context) (upload.Session, bool) {
+	id := c.Param("id")
+	if _, err := uuid.Parse(id); err != nil {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid upload ID format")
+		return upload.Session{}, false
+	}
+
+	session, err := uploadManager.Get(id)
+	if err != nil || !ownsUpload(c.Request.Context(), id) {
+		respondError(c, 404, apierrors.CodeNotFound, "Upload not found")
+		return upload.Session{}, false
+	}
+	return session, true
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/config"
+	"github.com/rendiffdev/rendiff-probe/internal/database"
+	apierrors "github.com/rendiffdev/rendiff-probe/internal/errors"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/watch"
+)
+
+// startWatchFolders starts scanning the configured watch folders. Files are
+// judged by the WATCH_PROFILE delivery profile, or only need to be readable
+// media when no profile is set.
+func startWatchFolders(cfg *config.Config) error {
+	profile, err := ffmpeg.LookupDeliveryProfile(cfg.WatchProfile)
+	if err != nil {
+		return fmt.Errorf("invalid WATCH_PROFILE: %w", err)
+	}
+
+	folderWatcher, err = watch.NewWatcher(watch.Config{
+		Folders:  cfg.WatchFolders,
+		Interval: time.Duration(cfg.WatchInterval) * time.Second,
+		PassDir:  cfg.WatchPassDir,
+		FailDir:  cfg.WatchFailDir,
+	}, watchFolderAnalyzer(profile), appLogger)
+	if err != nil {
+		return err
+	}
+	folderWatcher.SetResultHandler(recordWatchResult)
+	folderWatcher.Start(shutdownCtx)
+
+	appLogger.Info().
+		Strs("folders", cfg.WatchFolders).
+		Int("interval_seconds", cfg.WatchInterval).
+		Str("profile", cfg.WatchProfile).
+		Msg("Watch folder ingestion started")
+	return nil
+}
+
+// watchFolderAnalyzer probes a watch folder file and stores the analysis
+// like an upload, so it can be fetched and reported on by its ID
+func watchFolderAnalyzer(profile *ffmpeg.DeliveryProfile) watch.Analyzer {
+	return func(ctx context.Context, path string) (watch.Outcome, error) {
+		// Unattended ingest queues behind interactive requests
+		ctx = ffmpeg.WithPriority(ctx, ffmpeg.PriorityLow)
+		analysisID := uuid.New().String()
+		filename := filepath.Base(path)
+		var size int64
+		if info, err := os.Stat(path); err == nil {
+			size = info.Size()
+		}
+
+		result, err := analyzeFile(ctx, path, nil, profile, "")
+		if err != nil {
+			appLogger.Error().Err(err).Str("file", path).Msg("Watch folder analysis failed")
+			recordAnalysis(ctx, analysisID, filename, path, analysisSourceWatch, size, nil, "", "Analysis failed")
+			notifyWatchError(filepath.Dir(path), filename, analysisID, "File could not be analyzed")
+			return watch.Outcome{AnalysisID: analysisID}, fmt.Errorf("analysis failed")
+		}
+		stills := captureThumbnails(ctx, path, result)
+		storeAnalysis(ctx, analysisID, filename, result, stills, nil)
+		saveFilmstrip(ctx, analysisID, path, result, stills, nil)
+		recordAnalysis(ctx, analysisID, filename, path, analysisSourceWatch, size, result, "", "")
+
+		return watchFolderOutcome(analysisID, result), nil
+	}
+}
+
+// watchFolderOutcome applies the QC policy: a file passes when it has at
+// least one stream and meets the delivery profile, if one was checked
+func watchFolderOutcome(analysisID string, result *ffmpeg.FFprobeResult) watch.Outcome {
+	outcome := watch.Outcome{AnalysisID: analysisID, Passed: true}
+	if len(result.Streams) == 0 {
+		outcome.Passed = false
+		outcome.Reasons = append(outcome.Reasons, "no streams found")
+	}
+
+	if result.EnhancedAnalysis == nil || result.EnhancedAnalysis.DeliveryCompliance == nil {
+		return outcome
+	}
+	compliance := result.EnhancedAnalysis.DeliveryCompliance
+	if !compliance.Compliant {
+		outcome.Passed = false
+		for _, check := range compliance.Checks {
+			if check.Status != ffmpeg.DeliveryCheckFail {
+				continue
+			}
+			reason := fmt.Sprintf("%s: expected %s", check.Rule, check.Expected)
+			if check.Actual != "" {
+				reason += ", got " + check.Actual
+			}
+			outcome.Reasons = append(outcome.Reasons, reason)
+		}
+	}
+	return outcome
+}
+
+// recordWatchResult stores the outcome of a processed watch folder file
+func recordWatchResult(result watch.Result) {
+	ctx, cancel := context.WithTimeout(context.Background(), analysisSaveTimeout)
+	defer cancel()
+
+	record := &database.WatchResultRecord{
+		Folder:      result.Folder,
+		FileName:    result.Filename,
+		FileSize:    result.Size,
+		AnalysisID:  result.AnalysisID,
+		Verdict:     result.Verdict,
+		Reasons:     result.Reasons,
+		MovedTo:     result.MovedTo,
+		Error:       result.Error,
+		ProcessedAt: result.ProcessedAt,
+	}
+	if err := watchStore.Save(ctx, record); err != nil {
+		appLogger.Error().Err(err).Str("file", result.Filename).Msg("Failed to store watch folder result")
+	}
+	notifyWatchResult(result)
+}
+
+// listWatchFoldersHandler returns the watched folders and their counters
+func listWatchFoldersHandler(c *gin.Context) {
+	if folderWatcher == nil {
+		c.JSON(200, gin.H{"enabled": false, "folders": []watch.FolderStatus{}})
+		return
+	}
+	c.JSON(200, gin.H{
+		"enabled":          true,
+		"interval_seconds": appConfig.WatchInterval,
+		"profile":          appConfig.WatchProfile,
+		"folders":          folderWatcher.Folders(),
+	})
+}
+
+// listWatchResultsHandler lists processed watch folder files, newest first
+func listWatchResultsHandler(c *gin.Context) {
+	filter := database.WatchResultFilter{
+		Folder:  c.Query("folder"),
+		Verdict: c.Query("verdict"),
+	}
+
+	if filter.Verdict != "" && filter.Verdict != watch.VerdictPassed && filter.Verdict != watch.VerdictFailed {
+		respondError(c, 400, apierrors.CodeBadRequest, "Invalid verdict, must be 'passed' or 'failed'")
+		return
+	}
+
+	var err error
+	filter.Limit = database.DefaultWatchResultLimit
+	if value := c.Query("limit"); value != "" {
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 1 || filter.Limit > database.MaxWatchResultLimit {
+			respondError(c, 400, apierrors.CodeBadRequest, "Invalid limit, must be between 1 and "+strconv.Itoa(database.MaxWatchResultLimit))
+			return
+		}
+	}
+	if value := c.Query("offset"); value != "" {
+		if filter.Offset, err = strconv.Atoi(value); err != nil || filter.Offset < 0 {
+			respondError(c, 400, apierrors.CodeBadRequest, "Invalid offset")
+			return
+		}
+	}
+
+	records, total, err := watchStore.List(c.Request.Context(), filter)
+	if err != nil {
+		appLogger.Error().Err(err).Msg("Failed to list watch folder results")
+		respondError(c, 500, apierrors.CodeInternalError, "Failed to list watch folder results")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"results": records,
+		"total":   total,
+		"limit":   filter.Limit,
+		"offset":  filter.Offset,
+	})
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/report"
+	"github.com/rendiffdev/rendiff-probe/internal/watch"
+	"github.com/spf13/cobra"
+)
+
+// Batch command flags
+var (
+	batchFormat   string
+	batchWorkers  int
+	batchIncludes []string
+	batchExcludes []string
+)
+
+// defaultBatchWorkers is the default number of files analyzed at once; each
+// analysis runs several ffprobe/ffmpeg processes of its own
+const defaultBatchWorkers = 2
+
+// batchSummaryColumns are the CSV columns, one row per file
+var batchSummaryColumns = []string{
+	"file", "status", "error", "overall", "failed_categories", "warning_categories",
+	"delivery_compliant", "container", "duration_seconds", "size_bytes",
+	"video_codec", "resolution", "frame_rate", "audio_codec", "audio_channels",
+	"elapsed_seconds",
+}
+
+// batchEntry is the summary of one analyzed file
+type batchEntry struct {
+	File              string          `json:"file"`
+	Status            string          `json:"status"`
+	Error             string          `json:"error,omitempty"`
+	Overall           report.Severity `json:"overall,omitempty"`
+	FailedCategories  int             `json:"failed_categories"`
+	WarningCategories int             `json:"warning_categories"`
+	DeliveryCompliant *bool           `json:"delivery_compliant,omitempty"` // Only with --profile
+	Container         string          `json:"container,omitempty"`
+	DurationSeconds   float64         `json:"duration_seconds,omitempty"`
+	SizeBytes         int64           `json:"size_bytes,omitempty"`
+	VideoCodec        string          `json:"video_codec,omitempty"`
+	Resolution        string          `json:"resolution,omitempty"`
+	FrameRate         string          `json:"frame_rate,omitempty"`
+	AudioCodec        string          `json:"audio_codec,omitempty"`
+	AudioChannels     int             `json:"audio_channels,omitempty"`
+	ElapsedSeconds    float64         `json:"elapsed_seconds"`
+}
+
+// batchSummary is the consolidated JSON output of a batch run
+type batchSummary struct {
+	Paths          []string     `json:"paths"`
+	StartedAt      time.Time    `json:"started_at"`
+	ElapsedSeconds float64      `json:"elapsed_seconds"`
+	Count          int          `json:"count"`
+	Succeeded      int          `json:"succeeded"`
+	Failed         int          `json:"failed"`
+	Cancelled      bool         `json:"cancelled,omitempty"`
+	Files          []batchEntry `json:"files"`
+}
+
+func newBatchCommand() *cobra.Command {
+	batchCmd := &cobra.Command{
+		Use:   "batch <dir|file> [more...]",
+		Short: "Analyze directory trees with concurrent workers",
+		Long: `Walk directories recursively and analyze every media file found, several
+files at a time, printing progress to stderr and writing one consolidated
+JSON or CSV summary.
+
+Without --include, files with a known media extension are analyzed. Include
+and exclude patterns are shell globs matched against both the file name and
+the path relative to the walked directory; an excluded directory is not
+descended into. IMF package directories are analyzed as one package.
+
+Examples:
+  rendiffprobe-cli batch /media/incoming --workers 4
+  rendiffprobe-cli batch /media --include '*.mxf' --exclude 'proxies' --format csv -o qc.csv
+  rendiffprobe-cli batch /media/masters --profile dpp_as11_uk`,
+		Args: cobra.MinimumNArgs(1),
+		Run:  runBatch,
+	}
+
+	batchCmd.Flags().StringVarP(&batchFormat, "format", "f", "json", "Summary format: json, csv")
+	batchCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Summary file (default: stdout)")
+	batchCmd.Flags().IntVarP(&batchWorkers, "workers", "w", defaultBatchWorkers, "Files analyzed concurrently")
+	batchCmd.Flags().StringArrayVar(&batchIncludes, "include", nil, "Glob of files to analyze, repeatable (default: known media extensions)")
+	batchCmd.Flags().StringArrayVar(&batchExcludes, "exclude", nil, "Glob of files or directories to skip, repeatable")
+	batchCmd.Flags().StringVar(&ffprobePath, "ffprobe", "", "Path to ffprobe binary (auto-detect if not set)")
+	batchCmd.Flags().StringVar(&pluginDir, "plugin-dir", "", "Run every executable in this directory as a custom analyzer plugin")
+	addMediaInfoFlag(batchCmd)
+	batchCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	batchCmd.Flags().BoolVarP(&prettyPrint, "pretty", "p", true, "Pretty print JSON output")
+	batchCmd.Flags().IntVarP(&timeout, "timeout", "t", 300, "Analysis timeout per file in seconds")
+	batchCmd.Flags().StringVarP(&categories, "categories", "c", "", "Comma-separated QC categories to run (default: all)")
+	batchCmd.Flags().StringVar(&profileName, "profile", "", "Delivery profile to check against (see 'rendiffprobe-cli profiles')")
+	batchCmd.Flags().StringVar(&streams, "streams", "", "Audio streams to measure individually, in ffprobe select_streams syntax")
+	return batchCmd
+}
+
+func runBatch(cmd *cobra.Command, args []string) {
+	if batchFormat != "json" && batchFormat != "csv" {
+		fmt.Fprintf(os.Stderr, "Error: unsupported summary format %q, must be json or csv\n", batchFormat)
+		os.Exit(1)
+	}
+	if batchWorkers < 1 {
+		fmt.Fprintf(os.Stderr, "Error: --workers must be at least 1\n")
+		os.Exit(1)
+	}
+	for _, pattern := range append(append([]string{}, batchIncludes...), batchExcludes...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid pattern %q: %v\n", pattern, err)
+			os.Exit(1)
+		}
+	}
+
+	ffprobeExec := findFFprobe()
+	if ffprobeExec == "" {
+		fmt.Fprintf(os.Stderr, "Error: ffprobe not found. Please install FFmpeg or specify path with --ffprobe\n")
+		os.Exit(1)
+	}
+
+	selectedCategories, err := ffmpeg.ParseQCCategories(categories)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (run 'rendiffprobe-cli categories' for valid names)\n", err)
+		os.Exit(1)
+	}
+	profile, err := ffmpeg.LookupDeliveryProfile(profileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if _, err := ffmpeg.ParseStreamSelector(streams); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	files, err := collectBatchFiles(args, batchIncludes, batchExcludes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if len(files) == 0 {
+		fmt.Fprintf(os.Stderr, "No matching files found\n")
+	} else {
+		fmt.Fprintf(os.Stderr, "Analyzing %d files with %d workers\n", len(files), batchWorkers)
+	}
+
+	// Interrupting stops new analyses; the summary covers what finished
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	ffprobe := newFFprobe(ffprobeExec, createLogger())
+	summary := batchSummary{Paths: args, StartedAt: time.Now(), Files: []batchEntry{}}
+
+	jobs := make(chan string)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < batchWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				entry := analyzeBatchFile(ctx, ffprobe, file, selectedCategories, profile)
+
+				mu.Lock()
+				summary.Files = append(summary.Files, entry)
+				status := "ok"
+				if entry.Status != "success" {
+					summary.Failed++
+					status = "error"
+				} else {
+					summary.Succeeded++
+				}
+				fmt.Fprintf(os.Stderr, "[%d/%d] %-5s %s (%.1fs)", len(summary.Files), len(files), status, entry.File, entry.ElapsedSeconds)
+				if entry.Error != "" {
+					fmt.Fprintf(os.Stderr, ": %s", entry.Error)
+				}
+				fmt.Fprintln(os.Stderr)
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for _, file := range files {
+		select {
+		case jobs <- file:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	summary.Cancelled = ctx.Err() != nil
+	summary.Count = len(summary.Files)
+	summary.ElapsedSeconds = roundSeconds(time.Since(summary.StartedAt))
+	sort.Slice(summary.Files, func(i, j int) bool { return summary.Files[i].File < summary.Files[j].File })
+	fmt.Fprintf(os.Stderr, "Done: %d succeeded, %d failed in %.1fs\n", summary.Succeeded, summary.Failed, summary.ElapsedSeconds)
+	if summary.Cancelled {
+		fmt.Fprintf(os.Stderr, "Interrupted: %d of %d files were not analyzed\n", len(files)-summary.Count, len(files))
+	}
+
+	out := io.Writer(os.Stdout)
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing to %s: %v\n", outputFile, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	if batchFormat == "csv" {
+		err = writeBatchCSV(out, summary.Files)
+	} else {
+		err = writeBatchJSON(out, summary)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing summary: %v\n", err)
+		os.Exit(1)
+	}
+	if verbose && outputFile != "" {
+		fmt.Fprintf(os.Stderr, "Summary written to: %s\n", outputFile)
+	}
+}
+
+// collectBatchFiles expands the given files and directory trees into the
+// files to analyze, without duplicates, in walk order
+func collectBatchFiles(paths, includes, excludes []string) ([]string, error) {
+	var files []string
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() || ffmpeg.IsIMFPackage(root) {
+			add(root)
+			continue
+		}
+
+		err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", path, err)
+				if entry != nil && entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if path == root {
+				return nil
+			}
+			rel, _ := filepath.Rel(root, path)
+			if matchesBatchPattern(excludes, entry.Name(), rel) {
+				if entry.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if entry.IsDir() {
+				if strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+				if ffmpeg.IsIMFPackage(path) {
+					add(path)
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+				return nil
+			}
+			if len(includes) > 0 {
+				if matchesBatchPattern(includes, entry.Name(), rel) {
+					add(path)
+				}
+			} else if isMediaExtension(entry.Name()) {
+				add(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// matchesBatchPattern reports whether any glob matches the file name or the
+// slash-separated path relative to the walked directory
+func matchesBatchPattern(patterns []string, name, rel string) bool {
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isMediaExtension(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, known := range watch.DefaultExtensions {
+		if ext == known {
+			return true
+		}
+	}
+	return false
+}
+
+// analyzeBatchFile analyzes one file with its own timeout and summarizes it
+func analyzeBatchFile(ctx context.Context, ffprobe *ffmpeg.FFprobe, file string, selected []ffmpeg.QCCategory, profile *ffmpeg.DeliveryProfile) batchEntry {
+	start := time.Now()
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	entry := batchEntry{File: file, Status: "success"}
+	_, probeResult, err := analyzeFile(ctx, ffprobe, file, selected, profile, streams)
+	entry.ElapsedSeconds = roundSeconds(time.Since(start))
+	if err != nil {
+		entry.Status = "error"
+		entry.Error = err.Error()
+		return entry
+	}
+	summarizeBatchResult(&entry, probeResult)
+	return entry
+}
+
+// summarizeBatchResult fills the entry's media properties and QC verdict
+func summarizeBatchResult(entry *batchEntry, result *ffmpeg.FFprobeResult) {
+	qc := report.Build(report.Source{Filename: filepath.Base(entry.File)}, result, nil)
+	counts := qc.SeverityCounts()
+	entry.Overall = qc.Overall
+	entry.FailedCategories = counts[report.SeverityFail]
+	entry.WarningCategories = counts[report.SeverityWarning]
+	if result.EnhancedAnalysis != nil && result.EnhancedAnalysis.DeliveryCompliance != nil {
+		compliant := result.EnhancedAnalysis.DeliveryCompliance.Compliant
+		entry.DeliveryCompliant = &compliant
+	}
+
+	if format := result.Format; format != nil {
+		entry.Container = format.FormatName
+		entry.DurationSeconds, _ = strconv.ParseFloat(format.Duration, 64)
+		entry.SizeBytes, _ = strconv.ParseInt(format.Size, 10, 64)
+	}
+	for _, stream := range result.Streams {
+		switch {
+		case stream.CodecType == "video" && entry.VideoCodec == "" && stream.Disposition["attached_pic"] != 1:
+			entry.VideoCodec = stream.CodecName
+			entry.Resolution = fmt.Sprintf("%dx%d", stream.Width, stream.Height)
+			entry.FrameRate = stream.AvgFrameRate
+		case stream.CodecType == "audio" && entry.AudioCodec == "":
+			entry.AudioCodec = stream.CodecName
+			entry.AudioChannels = stream.Channels
+		}
+	}
+}
+
+func writeBatchJSON(w io.Writer, summary batchSummary) error {
+	encoder := json.NewEncoder(w)
+	if prettyPrint {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(summary)
+}
+
+func writeBatchCSV(w io.Writer, entries []batchEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(batchSummaryColumns); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		compliant := ""
+		if entry.DeliveryCompliant != nil {
+			compliant = strconv.FormatBool(*entry.DeliveryCompliant)
+		}
+		record := []string{
+			entry.File, entry.Status, entry.Error, string(entry.Overall),
+			strconv.Itoa(entry.FailedCategories), strconv.Itoa(entry.WarningCategories),
+			compliant, entry.Container,
+			strconv.FormatFloat(entry.DurationSeconds, 'f', -1, 64),
+			strconv.FormatInt(entry.SizeBytes, 10),
+			entry.VideoCodec, entry.Resolution, entry.FrameRate, entry.AudioCodec,
+			strconv.Itoa(entry.AudioChannels),
+			strconv.FormatFloat(entry.ElapsedSeconds, 'f', -1, 64),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func roundSeconds(d time.Duration) float64 {
+	return d.Round(time.Millisecond).Seconds()
+}
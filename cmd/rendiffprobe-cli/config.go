@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is read from the home directory unless --config names
+// another file
+const defaultConfigFile = ".rendiffprobe.yaml"
+
+// configPath is the --config flag
+var configPath string
+
+// analyzeFormats are the output formats of the analyze command
+var analyzeFormats = []string{"text", "json", "report", "html", "pdf", "csv", "xml"}
+
+// cliConfig holds the defaults a team shares for the CLI flags. Flags given
+// on the command line take precedence.
+type cliConfig struct {
+	FFprobe    string   `yaml:"ffprobe"`    // Path to the ffprobe binary
+	Format     string   `yaml:"format"`     // Output format of analyze
+	Categories []string `yaml:"categories"` // QC categories to run
+	Profile    string   `yaml:"profile"`    // Delivery profile to check against
+	ServerURL  string   `yaml:"server_url"` // Analyze on this server instead of locally
+	PluginDir  string   `yaml:"plugin_dir"` // Directory of custom analyzer plugins
+	MediaInfo  string   `yaml:"mediainfo"`  // Cross-check with this mediainfo binary
+}
+
+// loadConfig reads the configuration file at path, or ~/.rendiffprobe.yaml
+// when path is empty. A missing default file is an empty configuration.
+func loadConfig(path string) (*cliConfig, error) {
+	explicit := path != ""
+	if !explicit {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return &cliConfig{}, nil
+		}
+		path = filepath.Join(home, defaultConfigFile)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return &cliConfig{}, nil
+		}
+		return nil, err
+	}
+
+	config := &cliConfig{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+func (c *cliConfig) validate() error {
+	if c.Format != "" && !containsString(analyzeFormats, c.Format) {
+		return fmt.Errorf("unsupported format %q, must be one of %s", c.Format, strings.Join(analyzeFormats, ", "))
+	}
+	if _, err := ffmpeg.ParseQCCategories(strings.Join(c.Categories, ",")); err != nil {
+		return err
+	}
+	if c.ServerURL != "" {
+		u, err := url.Parse(c.ServerURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("server_url %q must be an http or https URL", c.ServerURL)
+		}
+	}
+	return nil
+}
+
+// applyConfig sets the flags of cmd that were not given on the command line
+// from config. The output format only applies to analyze, since batch and
+// diff write formats of their own.
+func applyConfig(cmd *cobra.Command, config *cliConfig) error {
+	defaults := map[string]string{
+		"ffprobe":    config.FFprobe,
+		"categories": strings.Join(config.Categories, ","),
+		"profile":    config.Profile,
+		"server":     config.ServerURL,
+		"plugin-dir": config.PluginDir,
+		"mediainfo":  config.MediaInfo,
+	}
+	if cmd.Name() == "analyze" {
+		defaults["format"] = config.Format
+	}
+
+	for name, value := range defaults {
+		flag := cmd.Flags().Lookup(name)
+		if value == "" || flag == nil || flag.Changed {
+			continue
+		}
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/report"
+	"github.com/spf13/cobra"
+)
+
+// Diff command flags
+var diffFormat string
+
+// diffFile identifies one side of a comparison
+type diffFile struct {
+	Filename string `json:"filename"`
+	Filepath string `json:"filepath"`
+}
+
+// diffOutput is the JSON output of the diff command
+type diffOutput struct {
+	A         diffFile             `json:"a"`
+	B         diffFile             `json:"b"`
+	Identical bool                 `json:"identical"` // No property differs at all
+	Diff      *report.AnalysisDiff `json:"diff"`
+	Enhanced  []report.Difference  `json:"enhanced"` // Quality and compliance results that differ
+	Timestamp string               `json:"timestamp"`
+}
+
+func newDiffCommand() *cobra.Command {
+	diffCmd := &cobra.Command{
+		Use:   "diff <fileA> <fileB>",
+		Short: "Compare the analyses of two media files side by side",
+		Long: `Analyze two media files and list what differs between them: container
+format, stream parameters, loudness and the enhanced analysis results.
+
+Streams are paired by type and order, so the second audio stream of A is
+compared with the second audio stream of B. Changes that alter what is seen
+or heard, such as resolution, frame rate or channel layout, are marked as
+essential. Either file may be - to read it from stdin.
+
+Examples:
+  rendiffprobe-cli diff master.mov transcode.mp4
+  rendiffprobe-cli diff master.mov transcode.mp4 --format json -o diff.json
+  rendiffprobe-cli diff master.mxf delivery.mxf --categories codec,container`,
+		Args: cobra.ExactArgs(2),
+		Run:  runDiff,
+	}
+
+	diffCmd.Flags().StringVarP(&diffFormat, "format", "f", "text", "Output format: text, json")
+	diffCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file (default: stdout)")
+	diffCmd.Flags().StringVar(&ffprobePath, "ffprobe", "", "Path to ffprobe binary (auto-detect if not set)")
+	diffCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
+	diffCmd.Flags().BoolVarP(&prettyPrint, "pretty", "p", true, "Pretty print JSON output")
+	diffCmd.Flags().IntVarP(&timeout, "timeout", "t", 300, "Analysis timeout in seconds")
+	diffCmd.Flags().StringVarP(&categories, "categories", "c", "", "Comma-separated QC categories to run (default: all)")
+	diffCmd.Flags().StringVar(&pluginDir, "plugin-dir", "", "Run every executable in this directory as a custom analyzer plugin")
+	addMediaInfoFlag(diffCmd)
+	diffCmd.Flags().StringVar(&profileName, "profile", "", "Delivery profile to check both files against (see 'rendiffprobe-cli profiles')")
+	return diffCmd
+}
+
+func runDiff(cmd *cobra.Command, args []string) {
+	if diffFormat != "text" && diffFormat != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unsupported diff format %q, must be text or json\n", diffFormat)
+		os.Exit(1)
+	}
+	if args[0] == stdinArg && args[1] == stdinArg {
+		fmt.Fprintf(os.Stderr, "Error: stdin (-) can only be analyzed once\n")
+		os.Exit(1)
+	}
+
+	ffprobeExec := findFFprobe()
+	if ffprobeExec == "" {
+		fmt.Fprintf(os.Stderr, "Error: ffprobe not found. Please install FFmpeg or specify path with --ffprobe\n")
+		os.Exit(1)
+	}
+
+	selectedCategories, err := ffmpeg.ParseQCCategories(categories)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (run 'rendiffprobe-cli categories' for valid names)\n", err)
+		os.Exit(1)
+	}
+	profile, err := ffmpeg.LookupDeliveryProfile(profileName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ffprobe := newFFprobe(ffprobeExec, createLogger())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	files := make([]diffFile, 2)
+	results := make([]*ffmpeg.FFprobeResult, 2)
+	for i, path := range args {
+		files[i] = diffFile{Filename: filepath.Base(path), Filepath: path}
+		if path == stdinArg {
+			spooled, cleanup, err := spoolStdin()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer cleanup()
+			files[i].Filename = filepath.Base(spooled)
+			path = spooled
+		}
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Analyzing: %s\n", files[i].Filepath)
+		}
+
+		_, result, err := analyzeFile(ctx, ffprobe, path, selectedCategories, profile, "")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error analyzing %s: %v\n", files[i].Filepath, err)
+			os.Exit(1)
+		}
+		results[i] = result
+	}
+
+	output := diffOutput{
+		A:         files[0],
+		B:         files[1],
+		Diff:      report.DiffAnalyses(results[0], results[1]),
+		Enhanced:  enhancedDifferences(results[0], results[1]),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	output.Identical = isIdenticalDiff(output.Diff) && len(output.Enhanced) == 0
+
+	out := io.Writer(os.Stdout)
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing to %s: %v\n", outputFile, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		out = f
+	}
+	if diffFormat == "json" {
+		err = writeDiffJSON(out, output)
+	} else {
+		err = writeDiffText(out, output)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing diff: %v\n", err)
+		os.Exit(1)
+	}
+	if verbose && outputFile != "" {
+		fmt.Fprintf(os.Stderr, "Diff written to: %s\n", outputFile)
+	}
+}
+
+// enhancedDifferences keeps the quality and compliance differences; the
+// container, codec and loudness ones are covered stream by stream
+func enhancedDifferences(a, b *ffmpeg.FFprobeResult) []report.Difference {
+	differences := []report.Difference{}
+	for _, difference := range report.Diff(a, b) {
+		if difference.Area == report.AreaQuality || difference.Area == report.AreaCompliance {
+			differences = append(differences, difference)
+		}
+	}
+	return differences
+}
+
+// isIdenticalDiff reports whether no container or stream property and no
+// loudness measurement differs
+func isIdenticalDiff(diff *report.AnalysisDiff) bool {
+	if len(diff.Format) > 0 || len(diff.AddedStreams) > 0 || len(diff.RemovedStreams) > 0 {
+		return false
+	}
+	if !zeroLoudnessDelta(diff.Loudness) {
+		return false
+	}
+	for _, stream := range diff.Streams {
+		if len(stream.Changes) > 0 || !zeroLoudnessDelta(stream.Loudness) {
+			return false
+		}
+	}
+	return true
+}
+
+func zeroLoudnessDelta(delta *report.LoudnessDelta) bool {
+	return delta == nil || (delta.IntegratedDelta == 0 && delta.TruePeakDelta == 0 && delta.RangeDelta == 0)
+}
+
+func writeDiffJSON(w io.Writer, output diffOutput) error {
+	encoder := json.NewEncoder(w)
+	if prettyPrint {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(output)
+}
+
+// writeDiffText prints the differences as a table with the values of A and
+// B side by side. Essential changes are marked with !.
+func writeDiffText(w io.Writer, output diffOutput) error {
+	diff := output.Diff
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("A: %s\n", output.A.Filepath))
+	sb.WriteString(fmt.Sprintf("B: %s\n", output.B.Filepath))
+	sb.WriteString(fmt.Sprintf("Timestamp: %s\n", output.Timestamp))
+
+	table := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	row := func(indent, property, a, b string, essential bool) {
+		marker := ""
+		if essential {
+			marker = "!"
+		}
+		fmt.Fprintf(table, "%s%s\t%s\t%s\t%s\n", indent, property, a, b, marker)
+	}
+	section := func(title string) {
+		// Blank cells keep the columns aligned across sections
+		fmt.Fprintf(table, "\t\t\t\n--- %s ---\t\t\t\n", title)
+	}
+
+	fmt.Fprintf(table, "\t\t\t\n\tA\tB\t\n")
+	section("FORMAT")
+	if len(diff.Format) == 0 {
+		row("  ", "(no changes)", "", "", false)
+	}
+	for _, change := range diff.Format {
+		row("  ", change.Property, change.A, change.B, change.Essential)
+	}
+
+	section("STREAMS")
+	if len(diff.Streams) == 0 && len(diff.AddedStreams) == 0 && len(diff.RemovedStreams) == 0 {
+		row("  ", "(no streams)", "", "", false)
+	}
+	for _, stream := range diff.Streams {
+		row("  ", fmt.Sprintf("%s stream", stream.CodecType), fmt.Sprintf("#%d", stream.IndexA), fmt.Sprintf("#%d", stream.IndexB), false)
+		if len(stream.Changes) == 0 && stream.Loudness == nil {
+			row("    ", "(no changes)", "", "", false)
+		}
+		for _, change := range stream.Changes {
+			row("    ", change.Property, change.A, change.B, change.Essential)
+		}
+		writeLoudnessRows(row, "    ", stream.Loudness)
+	}
+	for _, stream := range diff.RemovedStreams {
+		row("  ", fmt.Sprintf("%s stream #%d", stream.CodecType, stream.Index), stream.Description, "(removed)", true)
+	}
+	for _, stream := range diff.AddedStreams {
+		row("  ", fmt.Sprintf("%s stream #%d", stream.CodecType, stream.Index), "(added)", stream.Description, true)
+	}
+
+	if diff.Loudness != nil {
+		section("LOUDNESS")
+		writeLoudnessRows(row, "  ", diff.Loudness)
+	}
+
+	section("ENHANCED ANALYSIS")
+	if len(output.Enhanced) == 0 {
+		row("  ", "(no changes)", "", "", false)
+	}
+	for _, difference := range output.Enhanced {
+		row("  ", difference.Property, difference.Source, difference.Target, false)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	sb.WriteString("\n")
+	switch {
+	case output.Identical:
+		sb.WriteString("Result: IDENTICAL\n")
+	case diff.Preserved:
+		sb.WriteString("Result: PRESERVED (no essential changes)\n")
+	default:
+		sb.WriteString("Result: CHANGED (! marks essential changes)\n")
+	}
+
+	// The padding of the blank and marker cells trails some lines
+	lines := strings.Split(sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// writeLoudnessRows adds the loudness values of A and B; a shift in
+// integrated loudness beyond tolerance is essential
+func writeLoudnessRows(row func(indent, property, a, b string, essential bool), indent string, delta *report.LoudnessDelta) {
+	if delta == nil {
+		return
+	}
+	row(indent, "Integrated Loudness", fmt.Sprintf("%.1f LUFS", delta.IntegratedA), fmt.Sprintf("%.1f LUFS (%+.1f LU)", delta.IntegratedB, delta.IntegratedDelta), !delta.WithinTolerance)
+	row(indent, "True Peak", fmt.Sprintf("%.1f dBTP", delta.TruePeakA), fmt.Sprintf("%.1f dBTP (%+.1f dB)", delta.TruePeakB, delta.TruePeakDelta), false)
+	row(indent, "Loudness Range", fmt.Sprintf("%.1f LU", delta.RangeA), fmt.Sprintf("%.1f LU (%+.1f LU)", delta.RangeB, delta.RangeDelta), false)
+}
@@ -10,16 +10,20 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rendiffdev/rendiff-probe/internal/report"
 	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 )
@@ -36,8 +40,20 @@ var (
 	verbose      bool
 	prettyPrint  bool
 	timeout      int
+	categories   string
+	profileName  string
+	streams      string
+	tonemap      string
+	pluginDir    string
+	mediainfo    string
 )
 
+// maxReportedPixelDefects limits the defect coordinates printed per type in reports
+const maxReportedPixelDefects = 10
+
+// maxReportedAFDSegments limits the AFD segments printed in reports
+const maxReportedAFDSegments = 20
+
 // QCCategory represents a QC analysis category
 type QCCategory struct {
 	Name        string `json:"name"`
@@ -78,7 +94,7 @@ using FFprobe with enhanced QC capabilities across 19 analysis categories.
 
 Features:
   - 19 QC analysis categories (codec, container, resolution, HDR, etc.)
-  - Multiple output formats (JSON, text, detailed report)
+  - Multiple output formats (JSON, text, detailed report, HTML, PDF)
   - Batch processing support
   - Professional broadcast compliance checks
 
@@ -86,15 +102,37 @@ Examples:
   rendiffprobe-cli analyze video.mp4
   rendiffprobe-cli analyze video.mp4 --format json --output result.json
   rendiffprobe-cli analyze video.mp4 --format report
-  rendiffprobe-cli categories`,
+  rendiffprobe-cli analyze video.mp4 --format pdf --output report.pdf
+  rendiffprobe-cli analyze video.mp4 --categories hdr,loudness,timecode
+  rendiffprobe-cli analyze master.mxf --profile dpp_as11_uk
+  rendiffprobe-cli analyze master.mov --streams a:m:language:fre
+  curl -s https://example.com/clip.ts | rendiffprobe-cli analyze - --stdin-name clip.ts
+  rendiffprobe-cli batch /media/incoming --workers 4 --format csv -o qc.csv
+  rendiffprobe-cli diff master.mov transcode.mp4 --format json
+  rendiffprobe-cli categories
+
+Defaults for --ffprobe, --format, --categories, --profile and --server can be
+set in ~/.rendiffprobe.yaml or the file named by --config.`,
 		Version: version,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			config, err := loadConfig(configPath)
+			if err == nil {
+				err = applyConfig(cmd, config)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: config: %v\n", err)
+				os.Exit(1)
+			}
+		},
 	}
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Configuration file with flag defaults (default: ~/"+defaultConfigFile+")")
 
 	// Analyze command
 	analyzeCmd := &cobra.Command{
 		Use:   "analyze <file> [files...]",
 		Short: "Analyze media file(s) with comprehensive QC checks",
 		Long: `Analyze one or more media files with comprehensive quality control checks.
+Pass - as a file to read the media from stdin, e.g. at the end of a pipeline.
 
 Performs analysis across 19 QC categories including:
   - Codec and container validation
@@ -107,12 +145,20 @@ Performs analysis across 19 QC categories including:
 		Run:  runAnalyze,
 	}
 
-	analyzeCmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format: json, text, report")
+	analyzeCmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format: json, text, report, html, pdf, csv, xml")
 	analyzeCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file (default: stdout)")
 	analyzeCmd.Flags().StringVar(&ffprobePath, "ffprobe", "", "Path to ffprobe binary (auto-detect if not set)")
 	analyzeCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
 	analyzeCmd.Flags().BoolVarP(&prettyPrint, "pretty", "p", true, "Pretty print JSON output")
 	analyzeCmd.Flags().IntVarP(&timeout, "timeout", "t", 300, "Analysis timeout in seconds")
+	analyzeCmd.Flags().StringVarP(&categories, "categories", "c", "", "Comma-separated QC categories to run (default: all)")
+	analyzeCmd.Flags().StringVar(&profileName, "profile", "", "Delivery profile to check against (see 'rendiffprobe-cli profiles')")
+	analyzeCmd.Flags().StringVar(&streams, "streams", "", "Audio streams to measure individually, in ffprobe select_streams syntax (e.g. a, a:1, a:m:language:fre)")
+	analyzeCmd.Flags().StringVar(&serverURL, "server", "", "Upload the files to this Rendiff Probe server for analysis instead of running ffprobe locally (API key from $"+apiKeyEnv+")")
+	analyzeCmd.Flags().StringVar(&pluginDir, "plugin-dir", "", "Run every executable in this directory as a custom analyzer plugin")
+	addMediaInfoFlag(analyzeCmd)
+	analyzeCmd.Flags().StringVar(&stdinName, "stdin-name", "stdin", "File name to report for media read from stdin (-); its extension hints the container")
+	analyzeCmd.Flags().StringVar(&tonemap, "tonemap", "", "Add SDR previews of HDR files to html and pdf reports using this tonemap operator: "+strings.Join(ffmpeg.TonemapOperators, ", "))
 
 	// Categories command
 	categoriesCmd := &cobra.Command{
@@ -122,14 +168,23 @@ Performs analysis across 19 QC categories including:
 		Run:   runCategories,
 	}
 
+	// Profiles command
+	profilesCmd := &cobra.Command{
+		Use:   "profiles",
+		Short: "List built-in delivery profiles",
+		Long:  "Display the broadcast and platform delivery profiles accepted by --profile.",
+		Run:   runProfiles,
+	}
+
 	// Info command
 	infoCmd := &cobra.Command{
 		Use:   "info <file>",
 		Short: "Quick file information (basic metadata only)",
-		Long:  "Display basic file information without full QC analysis.",
+		Long:  "Display basic file information without full QC analysis. Pass - to read the media from stdin.",
 		Args:  cobra.ExactArgs(1),
 		Run:   runInfo,
 	}
+	infoCmd.Flags().StringVar(&ffprobePath, "ffprobe", "", "Path to ffprobe binary (auto-detect if not set)")
 
 	// Version command
 	versionCmd := &cobra.Command{
@@ -143,7 +198,10 @@ Performs analysis across 19 QC categories including:
 	}
 
 	rootCmd.AddCommand(analyzeCmd)
+	rootCmd.AddCommand(newBatchCommand())
+	rootCmd.AddCommand(newDiffCommand())
 	rootCmd.AddCommand(categoriesCmd)
+	rootCmd.AddCommand(profilesCmd)
 	rootCmd.AddCommand(infoCmd)
 	rootCmd.AddCommand(versionCmd)
 
@@ -153,6 +211,13 @@ Performs analysis across 19 QC categories including:
 	}
 }
 
+// addMediaInfoFlag adds --mediainfo, which takes an optional path to the
+// mediainfo binary
+func addMediaInfoFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&mediainfo, "mediainfo", "", "Cross-check duration, bit rate and channel counts with MediaInfo (optionally the path to mediainfo)")
+	cmd.Flags().Lookup("mediainfo").NoOptDefVal = "mediainfo"
+}
+
 func createLogger() zerolog.Logger {
 	if verbose {
 		return zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Logger()
@@ -160,33 +225,125 @@ func createLogger() zerolog.Logger {
 	return zerolog.New(io.Discard)
 }
 
+// newFFprobe creates the FFprobe instance of an analysis, with the plugin
+// analyzers of --plugin-dir and the --mediainfo cross-check
+func newFFprobe(ffprobeExec string, logger zerolog.Logger) *ffmpeg.FFprobe {
+	ffprobe := ffmpeg.NewFFprobe(ffprobeExec, logger)
+	ffprobe.SetMediaInfoPath(mediainfo)
+	if pluginDir == "" {
+		return ffprobe
+	}
+	plugins, err := ffmpeg.LoadPluginAnalyzers(pluginDir, time.Duration(timeout)*time.Second)
+	if err == nil {
+		err = ffprobe.SetPluginAnalyzers(plugins)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: loading plugins from %s: %v\n", pluginDir, err)
+		os.Exit(1)
+	}
+	if verbose {
+		for _, plugin := range plugins {
+			fmt.Fprintf(os.Stderr, "Using plugin: %s\n", plugin.Name())
+		}
+	}
+	return ffprobe
+}
+
 func runAnalyze(cmd *cobra.Command, args []string) {
-	// Find ffprobe binary
+	// Find ffprobe binary; with --server it is only used for report thumbnails
 	ffprobeExec := findFFprobe()
-	if ffprobeExec == "" {
+	if ffprobeExec == "" && serverURL == "" {
 		fmt.Fprintf(os.Stderr, "Error: ffprobe not found. Please install FFmpeg or specify path with --ffprobe\n")
 		os.Exit(1)
 	}
 
 	if verbose {
-		fmt.Fprintf(os.Stderr, "Using ffprobe: %s\n", ffprobeExec)
+		if serverURL != "" {
+			fmt.Fprintf(os.Stderr, "Using server: %s\n", serverURL)
+		} else {
+			fmt.Fprintf(os.Stderr, "Using ffprobe: %s\n", ffprobeExec)
+		}
+	}
+
+	selectedCategories, err := ffmpeg.ParseQCCategories(categories)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v (run 'rendiffprobe-cli categories' for valid names)\n", err)
+		os.Exit(1)
+	}
+
+	// The server checks the profile itself, including profiles defined there
+	var profile *ffmpeg.DeliveryProfile
+	if serverURL == "" {
+		profile, err = ffmpeg.LookupDeliveryProfile(profileName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if _, err := ffmpeg.ParseStreamSelector(streams); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if tonemap != "" {
+		if _, err := ffmpeg.ParseTonemapOperator(tonemap); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	// Create logger and FFprobe instance
 	logger := createLogger()
-	ffprobe := ffmpeg.NewFFprobe(ffprobeExec, logger)
+	ffprobe := newFFprobe(ffprobeExec, logger)
 
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
 	defer cancel()
 
+	// HTML and PDF reports embed thumbnails, which need ffmpeg
+	var documentFormat report.Format
+	var reports []*report.Report
+	var thumbnailExtractor *ffmpeg.ThumbnailExtractor
+	if outputFormat == string(report.FormatHTML) || outputFormat == string(report.FormatPDF) {
+		documentFormat = report.Format(outputFormat)
+		if ffprobeExec != "" {
+			thumbnailExtractor = ffmpeg.NewThumbnailExtractor(strings.Replace(ffprobeExec, "ffprobe", "ffmpeg", 1), logger)
+		}
+	}
+
+	// CSV and XML flatten every file to one row per stream
+	flatFormat, _ := report.ParseFlatFormat(outputFormat)
+	var flatResults []report.FlatResult
+
+	// Media piped to stdin is spooled to a temporary file first
+	var stdinPath string
+	for _, filePath := range args {
+		if filePath != stdinArg {
+			continue
+		}
+		if stdinPath != "" {
+			fmt.Fprintf(os.Stderr, "Error: stdin (-) can only be analyzed once\n")
+			os.Exit(1)
+		}
+		path, cleanup, err := spoolStdin()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer cleanup()
+		stdinPath = path
+	}
+
 	// Process each file
 	results := make([]map[string]interface{}, 0)
 
 	for _, filePath := range args {
 		// Expand glob patterns
-		matches, err := filepath.Glob(filePath)
-		if err != nil {
+		var matches []string
+		if filePath == stdinArg {
+			matches = []string{stdinPath}
+		} else if matches, err = filepath.Glob(filePath); err != nil {
 			fmt.Fprintf(os.Stderr, "Error expanding pattern %s: %v\n", filePath, err)
 			continue
 		}
@@ -196,30 +353,68 @@ func runAnalyze(cmd *cobra.Command, args []string) {
 		}
 
 		for _, file := range matches {
+			displayPath := file
+			if file == stdinPath {
+				displayPath = stdinArg
+			}
 			if verbose {
-				fmt.Fprintf(os.Stderr, "Analyzing: %s\n", file)
+				fmt.Fprintf(os.Stderr, "Analyzing: %s\n", displayPath)
 			}
 
-			result, err := analyzeFile(ctx, ffprobe, file)
+			var result map[string]interface{}
+			var probeResult *ffmpeg.FFprobeResult
+			if serverURL != "" {
+				result, probeResult, err = analyzeRemoteFile(ctx, file, selectedCategories)
+			} else {
+				result, probeResult, err = analyzeFile(ctx, ffprobe, file, selectedCategories, profile, streams)
+			}
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error analyzing %s: %v\n", file, err)
+				fmt.Fprintf(os.Stderr, "Error analyzing %s: %v\n", displayPath, err)
 				result = map[string]interface{}{
 					"filename": filepath.Base(file),
-					"filepath": file,
+					"filepath": displayPath,
 					"status":   "error",
 					"error":    err.Error(),
 				}
+				if probeResult != nil && len(probeResult.RepairSuggestions) > 0 {
+					result["repair_suggestions"] = probeResult.RepairSuggestions
+				}
 			}
 
+			result["filepath"] = displayPath
+
 			results = append(results, result)
+			if documentFormat != "" {
+				reports = append(reports, buildReport(ctx, thumbnailExtractor, file, result, probeResult, err))
+			}
+			if flatFormat != "" {
+				flatResults = append(flatResults, flatResult(file, result, probeResult, err))
+			}
 		}
 	}
 
 	// Output results
-	output := formatOutput(results)
+	var output []byte
+	if documentFormat != "" {
+		var buf bytes.Buffer
+		if err := report.Render(&buf, documentFormat, reports...); err != nil {
+			fmt.Fprintf(os.Stderr, "Error rendering %s report: %v\n", documentFormat, err)
+			os.Exit(1)
+		}
+		output = buf.Bytes()
+	} else if flatFormat != "" {
+		var buf bytes.Buffer
+		if err := report.RenderFlat(&buf, flatFormat, flatResults...); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing %s output: %v\n", flatFormat, err)
+			os.Exit(1)
+		}
+		output = buf.Bytes()
+	} else {
+		output = []byte(formatOutput(results))
+	}
 
 	if outputFile != "" {
-		err := os.WriteFile(outputFile, []byte(output), 0644)
+		err := os.WriteFile(outputFile, output, 0644)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error writing to %s: %v\n", outputFile, err)
 			os.Exit(1)
@@ -228,22 +423,104 @@ func runAnalyze(cmd *cobra.Command, args []string) {
 			fmt.Fprintf(os.Stderr, "Results written to: %s\n", outputFile)
 		}
 	} else {
-		fmt.Print(output)
+		os.Stdout.Write(output)
 	}
 }
 
-func analyzeFile(ctx context.Context, ffprobe *ffmpeg.FFprobe, filePath string) (map[string]interface{}, error) {
+// buildReport assembles the HTML/PDF report for one analyzed file
+func buildReport(ctx context.Context, extractor *ffmpeg.ThumbnailExtractor, filePath string, result map[string]interface{}, probeResult *ffmpeg.FFprobeResult, analyzeErr error) *report.Report {
+	source := report.Source{
+		Filename:   filepath.Base(filePath),
+		AnalyzedAt: time.Now(),
+	}
+	if analyzeErr != nil {
+		return report.Failed(source, analyzeErr.Error())
+	}
+	source.AnalysisID = getString(result, "analysis_id")
+
+	// Reports of files analyzed on a server lack stills without a local ffmpeg
+	if extractor == nil {
+		return report.Build(source, probeResult, nil)
+	}
+
+	thumbnails, err := report.CaptureThumbnails(ctx, extractor, filePath, probeResult)
+	if err != nil && verbose {
+		fmt.Fprintf(os.Stderr, "Thumbnails unavailable for %s: %v\n", filePath, err)
+	}
+	r := report.Build(source, probeResult, thumbnails)
+
+	if tonemap != "" {
+		previews, err := report.CaptureTonemapPreviews(ctx, extractor, filePath, probeResult, tonemap)
+		if err != nil && verbose {
+			fmt.Fprintf(os.Stderr, "SDR previews unavailable for %s: %v\n", filePath, err)
+		}
+		r.TonemapPreviews = previews
+	}
+	return r
+}
+
+// flatResult prepares one analyzed file for CSV/XML output
+func flatResult(filePath string, result map[string]interface{}, probeResult *ffmpeg.FFprobeResult, analyzeErr error) report.FlatResult {
+	flat := report.FlatResult{
+		Source: report.Source{Filename: filepath.Base(filePath), AnalyzedAt: time.Now()},
+	}
+	if analyzeErr != nil {
+		flat.Error = analyzeErr.Error()
+		return flat
+	}
+	flat.Result = probeResult
+	flat.AnalysisID = getString(result, "analysis_id")
+	return flat
+}
+
+func analyzeFile(ctx context.Context, ffprobe *ffmpeg.FFprobe, filePath string, selected []ffmpeg.QCCategory, profile *ffmpeg.DeliveryProfile, streams string) (map[string]interface{}, *ffmpeg.FFprobeResult, error) {
 	// Check file exists
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("file not found: %s", filePath)
+		return nil, nil, fmt.Errorf("file not found: %s", filePath)
 	}
 
 	// Run FFprobe analysis
-	probeResult, err := ffprobe.ProbeFile(ctx, filePath)
+	var probeResult *ffmpeg.FFprobeResult
+	var err error
+	if ffmpeg.IsIMFPackage(filePath) {
+		probeResult, err = ffprobe.ProbeIMFPackage(ctx, filePath)
+	} else if streams != "" {
+		probeResult, err = ffprobe.ProbeFileForStreams(ctx, filePath, selected, profile, streams)
+	} else if profile != nil {
+		probeResult, err = ffprobe.ProbeFileForDelivery(ctx, filePath, selected, profile)
+	} else if len(selected) > 0 {
+		probeResult, err = ffprobe.ProbeFileWithCategories(ctx, filePath, selected)
+	} else {
+		probeResult, err = ffprobe.ProbeFile(ctx, filePath)
+	}
 	if err != nil {
-		return nil, err
+		// The failed probe carries the repair suggestions, if any
+		return nil, probeResult, err
+	}
+
+	result, err := fileResult(filePath, probeResult, selected)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, probeResult, nil
+}
+
+// analyzeRemoteFile analyzes a file on the --server
+func analyzeRemoteFile(ctx context.Context, filePath string, selected []ffmpeg.QCCategory) (map[string]interface{}, *ffmpeg.FFprobeResult, error) {
+	probeResult, err := probeRemote(ctx, serverURL, filePath, categories, profileName, streams)
+	if err != nil {
+		return nil, probeResult, err
 	}
 
+	result, err := fileResult(filePath, probeResult, selected)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, probeResult, nil
+}
+
+// fileResult builds the output of one analyzed file
+func fileResult(filePath string, probeResult *ffmpeg.FFprobeResult, selected []ffmpeg.QCCategory) (map[string]interface{}, error) {
 	// Convert to map for flexible JSON output
 	resultJSON, err := json.Marshal(probeResult)
 	if err != nil {
@@ -255,17 +532,27 @@ func analyzeFile(ctx context.Context, ffprobe *ffmpeg.FFprobe, filePath string)
 		return nil, err
 	}
 
+	categoriesAnalyzed := len(allCategories)
+	if len(selected) > 0 {
+		categoriesAnalyzed = len(selected)
+	}
+
 	// Build comprehensive result
+	analysisID := fmt.Sprintf("cli-%d", time.Now().UnixNano())
 	result := map[string]interface{}{
 		"filename":               filepath.Base(filePath),
 		"filepath":               filePath,
-		"analysis_id":            fmt.Sprintf("cli-%d", time.Now().UnixNano()),
+		"analysis_id":            analysisID,
 		"timestamp":              time.Now().Format(time.RFC3339),
 		"status":                 "success",
-		"qc_categories_analyzed": 19,
+		"qc_categories_analyzed": categoriesAnalyzed,
 		"tool":                   "rendiffprobe-cli",
 		"version":                version,
 		"analysis":               analysisMap,
+		"qc_result":              report.BuildQCResult(report.Source{AnalysisID: analysisID, Filename: filepath.Base(filePath)}, probeResult),
+	}
+	if serverURL != "" {
+		result["server"] = serverURL
 	}
 
 	return result, nil
@@ -325,6 +612,7 @@ func formatText(results []map[string]interface{}) string {
 
 		if status == "error" {
 			sb.WriteString(fmt.Sprintf("Error: %s\n", getString(result, "error")))
+			writeRepairSuggestions(&sb, result["repair_suggestions"])
 			continue
 		}
 
@@ -409,12 +697,140 @@ func formatText(results []map[string]interface{}) string {
 			}
 
 			sb.WriteString("\n")
+
+			if compliance, ok := enhanced["delivery_compliance"].(map[string]interface{}); ok {
+				sb.WriteString("--- DELIVERY COMPLIANCE ---\n")
+				writeDeliveryCompliance(&sb, compliance)
+				sb.WriteString("\n")
+			}
+
+			if integrity, ok := enhanced["data_integrity_analysis"].(map[string]interface{}); ok {
+				if crossCheck, ok := integrity["mediainfo_cross_check"].(map[string]interface{}); ok {
+					sb.WriteString("--- MEDIAINFO CROSS-CHECK ---\n")
+					writeMediaInfoCrossCheck(&sb, crossCheck)
+					sb.WriteString("\n")
+				}
+			}
+
+			if plugins, ok := enhanced["plugins"].([]interface{}); ok {
+				sb.WriteString("--- PLUGINS ---\n")
+				writePlugins(&sb, plugins)
+				sb.WriteString("\n")
+			}
+		}
+
+		if _, ok := analysis["repair_suggestions"]; ok {
+			sb.WriteString("--- REPAIR SUGGESTIONS ---\n")
+			writeRepairSuggestions(&sb, analysis["repair_suggestions"])
+			sb.WriteString("\n")
 		}
 	}
 
 	return sb.String()
 }
 
+// writeRepairSuggestions writes the repair commands of a result, as decoded
+// from JSON or as returned by the probe
+func writeRepairSuggestions(sb *strings.Builder, value interface{}) {
+	if value == nil {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	var suggestions []ffmpeg.RepairSuggestion
+	if err := json.Unmarshal(data, &suggestions); err != nil {
+		return
+	}
+	for _, suggestion := range suggestions {
+		kind := "remux"
+		if !suggestion.Lossless {
+			kind = "re-encode"
+		}
+		sb.WriteString(fmt.Sprintf("  [%s] %s\n", strings.ToUpper(kind), suggestion.Title))
+		sb.WriteString(fmt.Sprintf("    %s\n", suggestion.Command))
+	}
+}
+
+// writeDeliveryCompliance writes the verdict and the checks that did not pass
+func writeDeliveryCompliance(sb *strings.Builder, compliance map[string]interface{}) {
+	verdict := "NON-COMPLIANT"
+	if getBool(compliance, "compliant") {
+		verdict = "COMPLIANT"
+	}
+	sb.WriteString(fmt.Sprintf("  Profile:              %s\n", getString(compliance, "profile_name")))
+	sb.WriteString(fmt.Sprintf("  Verdict:              %s\n", verdict))
+	sb.WriteString(fmt.Sprintf("  Passed/Failed:        %v/%v (%v warnings, %v not measured)\n",
+		compliance["passed"], compliance["failed"], compliance["warnings"], compliance["not_measured"]))
+
+	checks, _ := compliance["checks"].([]interface{})
+	for _, c := range checks {
+		check, ok := c.(map[string]interface{})
+		if !ok || getString(check, "status") == ffmpeg.DeliveryCheckPass {
+			continue
+		}
+		actual := getString(check, "actual")
+		if actual == "" {
+			actual = "n/a"
+		}
+		sb.WriteString(fmt.Sprintf("  [%s] %s: expected %s, got %s\n",
+			strings.ToUpper(getString(check, "status")), getString(check, "rule"), getString(check, "expected"), actual))
+	}
+}
+
+// writeMediaInfoCrossCheck writes the values of ffprobe and MediaInfo side
+// by side, marking discrepancies
+func writeMediaInfoCrossCheck(sb *strings.Builder, crossCheck map[string]interface{}) {
+	sb.WriteString(fmt.Sprintf("  MediaInfo Version:    %s\n", getString(crossCheck, "version")))
+	sb.WriteString(fmt.Sprintf("  Discrepancies:        %v\n", crossCheck["discrepancies"]))
+	comparisons, _ := crossCheck["comparisons"].([]interface{})
+	for _, c := range comparisons {
+		comparison, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		status := "MATCH"
+		if !getBool(comparison, "match") {
+			status = "MISMATCH"
+		}
+		field := strings.ReplaceAll(getString(comparison, "field"), "_", " ")
+		if stream, ok := comparison["stream"]; ok {
+			field = fmt.Sprintf("%s (stream %v)", field, stream)
+		}
+		sb.WriteString(fmt.Sprintf("  [%s] %s: ffprobe %s, MediaInfo %s\n", status, field, getString(comparison, "ffprobe"), getString(comparison, "mediainfo")))
+	}
+}
+
+// writePlugins writes the status of each plugin analyzer with its summary,
+// findings and failed checks
+func writePlugins(sb *strings.Builder, plugins []interface{}) {
+	for _, p := range plugins {
+		plugin, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		summary := getString(plugin, "summary")
+		if message := getString(plugin, "error"); message != "" {
+			summary = "failed: " + message
+		}
+		sb.WriteString(fmt.Sprintf("  %-21s %s %s\n", getString(plugin, "name")+":", strings.ToUpper(getString(plugin, "status")), summary))
+
+		findings, _ := plugin["findings"].([]interface{})
+		for _, finding := range findings {
+			sb.WriteString(fmt.Sprintf("    - %v\n", finding))
+		}
+		checks, _ := plugin["checks"].([]interface{})
+		for _, c := range checks {
+			check, ok := c.(map[string]interface{})
+			if !ok || getString(check, "status") == ffmpeg.PluginStatusPass {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("    [%s] %s: %s\n", strings.ToUpper(getString(check, "status")), getString(check, "id"), getString(check, "message")))
+		}
+	}
+}
+
 func formatReport(results []map[string]interface{}) string {
 	var sb strings.Builder
 
@@ -433,6 +849,7 @@ func formatReport(results []map[string]interface{}) string {
 
 		if status == "error" {
 			sb.WriteString(fmt.Sprintf("Error: %s\n", getString(result, "error")))
+			writeRepairSuggestions(&sb, result["repair_suggestions"])
 			continue
 		}
 
@@ -464,7 +881,28 @@ func formatReport(results []map[string]interface{}) string {
 		sb.WriteString(strings.Repeat("=", 80) + "\n")
 		sb.WriteString("CATEGORY 1: AFD ANALYSIS (Active Format Description)\n")
 		sb.WriteString(strings.Repeat("=", 80) + "\n")
-		sb.WriteString("  AFD Present:                    N/A\n")
+		if afd, ok := enhanced["afd_analysis"].(map[string]interface{}); ok {
+			primary, _ := afd["primary_afd"].(map[string]interface{})
+			segments, _ := afd["afd_segments"].([]interface{})
+			changes, _ := afd["afd_changes"].([]interface{})
+			sb.WriteString(fmt.Sprintf("  AFD Present:                    %s\n", getString(afd, "has_afd")))
+			if primary != nil {
+				sb.WriteString(fmt.Sprintf("  Primary AFD Code:               %s (%s)\n", getString(primary, "afd_value"), getString(primary, "afd_description")))
+			}
+			sb.WriteString(fmt.Sprintf("  AFD Changes:                    %d\n", len(changes)))
+			for i, s := range segments {
+				if i == maxReportedAFDSegments {
+					sb.WriteString(fmt.Sprintf("    ... %d more listed in JSON output\n", len(segments)-i))
+					break
+				}
+				segment, _ := s.(map[string]interface{})
+				sb.WriteString(fmt.Sprintf("    AFD %-2s frames %s-%s (%ss-%ss): %s\n",
+					getString(segment, "afd_value"), getString(segment, "start_frame"), getString(segment, "end_frame"),
+					getString(segment, "start_time"), getString(segment, "end_time"), getString(segment, "afd_description")))
+			}
+		} else {
+			sb.WriteString("  AFD Present:                    N/A (not analyzed)\n")
+		}
 		sb.WriteString(fmt.Sprintf("  Display Aspect Ratio:           %s\n", getStreamString(videoStream, "display_aspect_ratio")))
 		sb.WriteString(fmt.Sprintf("  Sample Aspect Ratio:            %s\n", getStreamString(videoStream, "sample_aspect_ratio")))
 		sb.WriteString("\n")
@@ -473,9 +911,29 @@ func formatReport(results []map[string]interface{}) string {
 		sb.WriteString(strings.Repeat("=", 80) + "\n")
 		sb.WriteString("CATEGORY 2: DEAD PIXEL DETECTION\n")
 		sb.WriteString(strings.Repeat("=", 80) + "\n")
-		sb.WriteString("  Dead Pixel Count:               N/A (requires frame analysis)\n")
-		sb.WriteString("  Stuck Pixel Count:              N/A (requires frame analysis)\n")
-		sb.WriteString("  Hot Pixel Count:                N/A (requires frame analysis)\n")
+		if dp, ok := enhanced["dead_pixel_analysis"].(map[string]interface{}); ok {
+			sb.WriteString(fmt.Sprintf("  Dead Pixel Count:               %s\n", getString(dp, "dead_pixel_count")))
+			sb.WriteString(fmt.Sprintf("  Stuck Pixel Count:              %s\n", getString(dp, "stuck_pixel_count")))
+			sb.WriteString(fmt.Sprintf("  Hot Pixel Count:                %s\n", getString(dp, "hot_pixel_count")))
+			sb.WriteString(fmt.Sprintf("  Analysis Method:                %s\n", getString(dp, "analysis_method")))
+			sb.WriteString(fmt.Sprintf("  Detection Confidence:           %s%%\n", getString(dp, "detection_confidence")))
+			for _, key := range []string{"dead_pixel_map", "stuck_pixel_map", "hot_pixel_map"} {
+				defects, _ := dp[key].([]interface{})
+				for i, d := range defects {
+					if i == maxReportedPixelDefects {
+						sb.WriteString(fmt.Sprintf("    ... %d more listed in JSON output\n", len(defects)-i))
+						break
+					}
+					defect, _ := d.(map[string]interface{})
+					sb.WriteString(fmt.Sprintf("    %-6s pixel at (%s, %s) in %s sampled frames\n",
+						getString(defect, "defect_type"), getString(defect, "x"), getString(defect, "y"), getString(defect, "frame_count")))
+				}
+			}
+		} else {
+			sb.WriteString("  Dead Pixel Count:               N/A (not analyzed)\n")
+			sb.WriteString("  Stuck Pixel Count:              N/A (not analyzed)\n")
+			sb.WriteString("  Hot Pixel Count:                N/A (not analyzed)\n")
+		}
 		sb.WriteString("\n")
 
 		// Category 3: PSE Flash Analysis
@@ -613,8 +1071,26 @@ func formatReport(results []map[string]interface{}) string {
 		sb.WriteString(strings.Repeat("=", 80) + "\n")
 		sb.WriteString("CATEGORY 14: IMF COMPLIANCE\n")
 		sb.WriteString(strings.Repeat("=", 80) + "\n")
-		sb.WriteString("  Is IMF Package:                 No\n")
-		sb.WriteString("  (IMF-specific parameters N/A)\n")
+		if imf, ok := enhanced["imf_analysis"].(map[string]interface{}); ok && imf["asset_map_analysis"] != nil {
+			sb.WriteString("  Is IMF Package:                 Yes\n")
+			sb.WriteString(fmt.Sprintf("  IMF Compliant:                  %s\n", boolToYesNo(getBool(imf, "is_imf_compliant"))))
+			sb.WriteString(fmt.Sprintf("  Application Profile:            %s\n", getString(imf, "imf_profile")))
+			if cpl, ok := imf["cpl_analysis"].(map[string]interface{}); ok {
+				sb.WriteString(fmt.Sprintf("  CPL Title:                      %s\n", getString(cpl, "cpl_title")))
+				sb.WriteString(fmt.Sprintf("  Edit Rate:                      %s\n", getString(cpl, "edit_rate")))
+				sb.WriteString(fmt.Sprintf("  Total Running Time:             %s\n", getString(cpl, "total_running_time")))
+			}
+			if validation, ok := imf["validation_results"].(map[string]interface{}); ok {
+				issues, _ := validation["critical_issues"].([]interface{})
+				sb.WriteString(fmt.Sprintf("  Compliance Issues:              %d\n", len(issues)))
+				for _, issue := range issues {
+					sb.WriteString(fmt.Sprintf("    - %v\n", issue))
+				}
+			}
+		} else {
+			sb.WriteString("  Is IMF Package:                 No\n")
+			sb.WriteString("  (IMF-specific parameters N/A)\n")
+		}
 		sb.WriteString("\n")
 
 		// Category 15: Transport Stream Analysis
@@ -626,6 +1102,33 @@ func formatReport(results []map[string]interface{}) string {
 		sb.WriteString(fmt.Sprintf("  Program Count:                  %v\n", format["nb_programs"]))
 		if !isTS {
 			sb.WriteString("  (TS-specific parameters N/A)\n")
+		} else if ts, ok := enhanced["transport_stream_analysis"].(map[string]interface{}); ok {
+			if mux, ok := ts["mux_statistics"].(map[string]interface{}); ok {
+				rate, _ := mux["mux_bit_rate"].(float64)
+				null, _ := mux["null_packet_percent"].(float64)
+				sb.WriteString(fmt.Sprintf("  Mux Bitrate:                    %.3f Mbps\n", rate/1e6))
+				sb.WriteString(fmt.Sprintf("  Constant Bitrate:               %s\n", boolToYesNo(getBool(mux, "constant_bit_rate"))))
+				sb.WriteString(fmt.Sprintf("  Null Packets:                   %.1f%%\n", null))
+			}
+			pcrs, _ := ts["pcr_analysis"].([]interface{})
+			for _, item := range pcrs {
+				if pcr, ok := item.(map[string]interface{}); ok {
+					pid, _ := pcr["pid"].(float64)
+					interval, _ := pcr["max_interval_ms"].(float64)
+					jitter, _ := pcr["max_jitter_ns"].(float64)
+					sb.WriteString(fmt.Sprintf("  PCR PID 0x%04X:                 max interval %.1f ms, max jitter %.0f ns\n", int(pid), interval, jitter))
+				}
+			}
+			if etr, ok := ts["etr_290"].(map[string]interface{}); ok {
+				sb.WriteString(fmt.Sprintf("  TR 101 290 Priority 1:          %s\n", boolToYesNo(getBool(etr, "priority_1_passed"))))
+				sb.WriteString(fmt.Sprintf("  TR 101 290 Priority 2:          %s\n", boolToYesNo(getBool(etr, "priority_2_passed"))))
+				checks, _ := etr["checks"].([]interface{})
+				for _, item := range checks {
+					if check, ok := item.(map[string]interface{}); ok && !getBool(check, "passed") {
+						sb.WriteString(fmt.Sprintf("    - %s %s: %v errors\n", getString(check, "id"), getString(check, "name"), check["error_count"]))
+					}
+				}
+			}
 		}
 		sb.WriteString("\n")
 
@@ -635,6 +1138,9 @@ func formatReport(results []map[string]interface{}) string {
 		sb.WriteString(strings.Repeat("=", 80) + "\n")
 		sb.WriteString("  Scene Type:                     N/A (requires frame analysis)\n")
 		sb.WriteString("  Motion Intensity:               N/A (requires frame analysis)\n")
+		if content, ok := enhanced["content_analysis"].(map[string]interface{}); ok {
+			writeAudioStreams(&sb, content)
+		}
 		sb.WriteString("\n")
 
 		// Category 17: Enhanced Analysis
@@ -674,7 +1180,36 @@ func formatReport(results []map[string]interface{}) string {
 		sb.WriteString(fmt.Sprintf("  Probe Score:                    %v\n", format["probe_score"]))
 		sb.WriteString(fmt.Sprintf("  Analysis Success:               %s\n", strings.ToUpper(status)))
 		sb.WriteString("  File Corruption Detected:       No\n")
+		if integrity, ok := enhanced["data_integrity_analysis"].(map[string]interface{}); ok {
+			if crossCheck, ok := integrity["mediainfo_cross_check"].(map[string]interface{}); ok {
+				sb.WriteString("  --- MEDIAINFO CROSS-CHECK ---\n")
+				writeMediaInfoCrossCheck(&sb, crossCheck)
+			}
+		}
 		sb.WriteString("\n")
+		if _, ok := analysis["repair_suggestions"]; ok {
+			sb.WriteString("  Repair Suggestions:\n")
+			writeRepairSuggestions(&sb, analysis["repair_suggestions"])
+			sb.WriteString("\n")
+		}
+
+		// Delivery profile verdict, when a profile was selected
+		if compliance, ok := enhanced["delivery_compliance"].(map[string]interface{}); ok {
+			sb.WriteString(strings.Repeat("=", 80) + "\n")
+			sb.WriteString("DELIVERY COMPLIANCE\n")
+			sb.WriteString(strings.Repeat("=", 80) + "\n")
+			writeDeliveryCompliance(&sb, compliance)
+			sb.WriteString("\n")
+		}
+
+		// Custom analyzer plugins, when --plugin-dir was given
+		if plugins, ok := enhanced["plugins"].([]interface{}); ok {
+			sb.WriteString(strings.Repeat("=", 80) + "\n")
+			sb.WriteString("PLUGIN ANALYZERS\n")
+			sb.WriteString(strings.Repeat("=", 80) + "\n")
+			writePlugins(&sb, plugins)
+			sb.WriteString("\n")
+		}
 
 		// Recommendations
 		sb.WriteString(strings.Repeat("=", 80) + "\n")
@@ -721,10 +1256,47 @@ func runCategories(cmd *cobra.Command, args []string) {
 	fmt.Println("  rendiffprobe-cli analyze video.mp4")
 	fmt.Println("  rendiffprobe-cli analyze video.mp4 --format json")
 	fmt.Println("  rendiffprobe-cli analyze video.mp4 --format report")
+	fmt.Println("  rendiffprobe-cli analyze video.mp4 --format html --output report.html")
+	fmt.Println("  rendiffprobe-cli analyze video.mp4 --categories hdr,loudness,timecode")
+	fmt.Println()
+	fmt.Println("'loudness' can also be selected to run only the EBU R128 loudness check from content analysis,")
+	fmt.Println("'video_levels' to run only the signalstats legal range measurement, 'dolby' to read only")
+	fmt.Println("the Dolby bitstream metadata from audio wrapping analysis, 'bwf' to validate only the Broadcast")
+	fmt.Println("Wave bext chunk, 'timestamps' to run only the packet PTS/DTS discontinuity check from data")
+	fmt.Println("integrity, 'bitrate' to run only the bit rate timeline and VBV buffer simulation from codec")
+	fmt.Println("analysis, 'mezzanine' to check only ProRes, DNxHD/DNxHR and JPEG 2000 frame headers, and")
+	fmt.Println("'av_sync' to estimate the lip-sync offset and drift between the first video and audio streams.")
+}
+
+func runProfiles(cmd *cobra.Command, args []string) {
+	profiles := ffmpeg.DeliveryProfiles()
+	fmt.Printf("Available Delivery Profiles (%d total):\n", len(profiles))
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Println()
+
+	for _, profile := range profiles {
+		fmt.Printf("  %-14s %s\n", profile.ID, profile.Name)
+		fmt.Printf("  %-14s %s\n", "", profile.Description)
+	}
+
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  rendiffprobe-cli analyze master.mxf --profile dpp_as11_uk")
+	fmt.Println("  rendiffprobe-cli analyze master.mxf --profile netflix_imf --format json")
 }
 
 func runInfo(cmd *cobra.Command, args []string) {
 	filePath := args[0]
+	displayPath := filePath
+	if filePath == stdinArg {
+		path, cleanup, err := spoolStdin()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer cleanup()
+		filePath = path
+	}
 
 	info, err := os.Stat(filePath)
 	if os.IsNotExist(err) {
@@ -733,7 +1305,7 @@ func runInfo(cmd *cobra.Command, args []string) {
 	}
 
 	fmt.Printf("File: %s\n", filepath.Base(filePath))
-	fmt.Printf("Path: %s\n", filePath)
+	fmt.Printf("Path: %s\n", displayPath)
 	fmt.Printf("Size: %d bytes (%.2f MB)\n", info.Size(), float64(info.Size())/(1024*1024))
 	fmt.Printf("Modified: %s\n", info.ModTime().Format(time.RFC3339))
 
@@ -776,6 +1348,80 @@ func findFFprobe() string {
 	return "ffprobe"
 }
 
+// writeAudioStreams prints the per-stream audio measurements selected with
+// --streams, in stream order
+func writeAudioStreams(sb *strings.Builder, content map[string]interface{}) {
+	streams, ok := content["audio_streams"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	keys := make([]string, 0, len(streams))
+	for key := range streams {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, _ := strconv.Atoi(keys[i])
+		b, _ := strconv.Atoi(keys[j])
+		return a < b
+	})
+
+	for _, key := range keys {
+		stream, ok := streams[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  --- AUDIO STREAM %s (%s, %s) ---\n", key, getString(stream, "codec_name"), getString(stream, "language")))
+		if loudness, ok := stream["loudness"].(map[string]interface{}); ok {
+			sb.WriteString(fmt.Sprintf("  Integrated Loudness:            %s LUFS\n", getString(loudness, "integrated_loudness_lufs")))
+			sb.WriteString(fmt.Sprintf("  True Peak:                      %s dBTP\n", getString(loudness, "true_peak_dbtp")))
+			sb.WriteString(fmt.Sprintf("  Loudness Compliant:             %s\n", boolToYesNo(getBool(loudness, "broadcast_compliant"))))
+			if dialog, ok := loudness["dialog"].(map[string]interface{}); ok {
+				if _, ok := dialog["integrated_loudness_lufs"]; ok {
+					sb.WriteString(fmt.Sprintf("  Dialog Loudness:                %s LUFS\n", getString(dialog, "integrated_loudness_lufs")))
+				}
+				sb.WriteString(fmt.Sprintf("  Speech:                         %s%%\n", getString(dialog, "speech_percentage")))
+			}
+		}
+		if silence, ok := stream["silence"].(map[string]interface{}); ok {
+			sb.WriteString(fmt.Sprintf("  Total Silence:                  %s s\n", getString(silence, "total_silence_seconds")))
+			sb.WriteString(fmt.Sprintf("  Problematic Mute:               %s\n", boolToYesNo(getBool(silence, "has_problematic_mute"))))
+		}
+		if phase, ok := stream["phase"].(map[string]interface{}); ok {
+			sb.WriteString(fmt.Sprintf("  Phase Correlation:              %s\n", getString(phase, "phase_correlation")))
+			sb.WriteString(fmt.Sprintf("  Phase Issues:                   %s\n", boolToYesNo(getBool(phase, "has_phase_issues"))))
+		}
+		if pairs, ok := stream["channel_pairs"].([]interface{}); ok {
+			for _, p := range pairs {
+				pair, _ := p.(map[string]interface{})
+				phase, _ := pair["phase"].(map[string]interface{})
+				sb.WriteString(fmt.Sprintf("  %-32s%s (issues: %s)\n", getString(pair, "pair")+" Phase:", getString(phase, "phase_correlation"), boolToYesNo(getBool(phase, "has_phase_issues"))))
+			}
+		}
+		if channels, ok := stream["channel_silence"].([]interface{}); ok {
+			for _, c := range channels {
+				channel, _ := c.(map[string]interface{})
+				silence, _ := channel["silence"].(map[string]interface{})
+				sb.WriteString(fmt.Sprintf("  %-32s%s s (problematic mute: %s)\n", getString(channel, "name")+" Silence:", getString(silence, "total_silence_seconds"), boolToYesNo(getBool(silence, "has_problematic_mute"))))
+			}
+		}
+		if checks, ok := stream["metadata_checks"].([]interface{}); ok {
+			for _, c := range checks {
+				check, _ := c.(map[string]interface{})
+				status := "PASS"
+				if !getBool(check, "passed") {
+					status = "FAIL - " + getString(check, "message")
+				}
+				sb.WriteString(fmt.Sprintf("  %-32s%s (%s declared, %s)\n", getString(check, "check")+":", status, getString(check, "declared"), getString(check, "measured")))
+			}
+		}
+		if errs, ok := stream["errors"].([]interface{}); ok {
+			for _, err := range errs {
+				sb.WriteString(fmt.Sprintf("  Error:                          %v\n", err))
+			}
+		}
+	}
+}
+
 func getString(m map[string]interface{}, key string) string {
 	if m == nil {
 		return "N/A"
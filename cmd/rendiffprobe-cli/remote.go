@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// apiKeyEnv names the environment variable holding the API key sent to
+// the --server
+const apiKeyEnv = "RENDIFFPROBE_API_KEY"
+
+// serverURL is the --server flag
+var serverURL string
+
+// remoteProbeResponse is the part of the POST /api/v1/probe/file response
+// the CLI uses
+type remoteProbeResponse struct {
+	Error             string                    `json:"error"`
+	Analysis          *ffmpeg.FFprobeResult     `json:"analysis"`
+	RepairSuggestions []ffmpeg.RepairSuggestion `json:"repair_suggestions"`
+}
+
+// probeRemote uploads filePath to a Rendiff Probe server and returns its
+// analysis. The categories, delivery profile and streams are checked by the
+// server, so profiles defined there can be used. A failed analysis returns
+// the repair suggestions of the server, if any.
+func probeRemote(ctx context.Context, server, filePath, categories, profile, streams string) (*ffmpeg.FFprobeResult, error) {
+	info, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("file not found: %s", filePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory; IMF packages can only be analyzed locally", filePath)
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Stream the upload rather than buffering the media in memory
+	body, pipe := io.Pipe()
+	form := multipart.NewWriter(pipe)
+	go func() {
+		defer file.Close()
+		pipe.CloseWithError(writeProbeForm(form, file, filepath.Base(filePath), map[string]string{
+			"categories": categories,
+			"profile":    profile,
+			"streams":    streams,
+		}))
+	}()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(server, "/")+"/api/v1/probe/file", body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	request.Header.Set("Content-Type", form.FormDataContentType())
+	if key := os.Getenv(apiKeyEnv); key != "" {
+		request.Header.Set("X-API-Key", key)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var decoded remoteProbeResponse
+	decodeErr := json.NewDecoder(response.Body).Decode(&decoded)
+	if response.StatusCode != http.StatusOK || decodeErr != nil || decoded.Analysis == nil {
+		message := decoded.Error
+		if message == "" {
+			message = response.Status
+		}
+		var failed *ffmpeg.FFprobeResult
+		if len(decoded.RepairSuggestions) > 0 {
+			failed = &ffmpeg.FFprobeResult{RepairSuggestions: decoded.RepairSuggestions}
+		}
+		return failed, fmt.Errorf("server analysis failed: %s", message)
+	}
+	return decoded.Analysis, nil
+}
+
+// writeProbeForm writes the multipart form of a file probe: the non-empty
+// fields, then the media
+func writeProbeForm(form *multipart.Writer, media io.Reader, filename string, fields map[string]string) error {
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := form.WriteField(name, value); err != nil {
+			return err
+		}
+	}
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, media); err != nil {
+		return err
+	}
+	return form.Close()
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// stdinArg is the file argument that reads the media from standard input
+const stdinArg = "-"
+
+// stdinName names media read from standard input in results and reports
+var stdinName string
+
+// spoolStdin copies standard input to a temporary file named stdinName, so
+// the analyses that seek or re-read the media can run on a pipe. The caller
+// removes the file with cleanup.
+func spoolStdin() (path string, cleanup func(), err error) {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return "", nil, fmt.Errorf("reading stdin: %w", err)
+	}
+	if info.Mode()&os.ModeCharDevice != 0 {
+		return "", nil, errors.New("no media piped to stdin")
+	}
+
+	name := filepath.Base(stdinName)
+	if name == "." || name == string(filepath.Separator) {
+		name = "stdin"
+	}
+	dir, err := os.MkdirTemp("", "rendiffprobe-stdin-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() { os.RemoveAll(dir) }
+
+	path = filepath.Join(dir, name)
+	file, err := os.Create(path)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	n, err := io.Copy(file, os.Stdin)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n == 0 {
+		err = errors.New("stdin is empty")
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("reading stdin: %w", err)
+	}
+	return path, cleanup, nil
+}
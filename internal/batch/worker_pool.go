@@ -0,0 +1,339 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rs/zerolog"
+)
+
+var (
+	// ErrPoolStopped is returned when submitting to a pool that has been stopped
+	ErrPoolStopped = errors.New("worker pool stopped")
+)
+
+// Task is a unit of work executed by the worker pool
+type Task func(ctx context.Context)
+
+// Jobs are queued in one lane per ffmpeg.Priority: low for archive
+// re-scans and other bulk work, high for urgent jobs such as air-check QC.
+
+// priorityWeights are the shares of dispatches each lane gets while every
+// lane has tasks queued: out of every 7, 4 go to high, 2 to normal and 1 to
+// low, so low priority jobs slow down but never starve
+var priorityWeights = [...]int{ffmpeg.PriorityLow + 1: 1, ffmpeg.PriorityNormal + 1: 2, ffmpeg.PriorityHigh + 1: 4}
+
+// priorityLane returns the index of the priority's queue
+func priorityLane(p ffmpeg.Priority) int {
+	switch {
+	case p < ffmpeg.PriorityNormal:
+		return int(ffmpeg.PriorityLow + 1)
+	case p > ffmpeg.PriorityNormal:
+		return int(ffmpeg.PriorityHigh + 1)
+	default:
+		return int(ffmpeg.PriorityNormal + 1)
+	}
+}
+
+// WorkerPoolConfig configures a WorkerPool
+type WorkerPoolConfig struct {
+	Workers   int // Number of concurrent workers (e.g. FFprobe processes)
+	QueueSize int // Pending tasks buffered before Submit blocks
+	MaxPerJob int // Maximum tasks a single job may have queued or running
+}
+
+// PoolStats is a snapshot of worker pool utilisation
+type PoolStats struct {
+	Workers          int            `json:"workers"`
+	Active           int64          `json:"active"`
+	Queued           int            `json:"queued"`
+	QueuedByPriority map[string]int `json:"queued_by_priority"`
+	QueueSize        int            `json:"queue_size"`
+	MaxPerJob        int            `json:"max_per_job"`
+	Completed        int64          `json:"completed"`
+}
+
+// WorkerPool runs tasks from many jobs on a fixed number of workers.
+//
+// Backpressure is applied at two levels: a job can only have MaxPerJob tasks
+// queued or running at once, and the shared queue holds at most QueueSize
+// tasks. Submit blocks when either limit is reached, so a single large batch
+// cannot monopolise the workers or grow memory without bound.
+//
+// Queued tasks wait in one lane per priority. Idle workers take the next
+// task by smooth weighted round robin over the lanes that have tasks, see
+// priorityWeights; within a lane tasks run in submission order.
+type WorkerPool struct {
+	config WorkerPoolConfig
+	logger zerolog.Logger
+	slots  chan struct{} // One token per queued task, bounding the queue
+	ready  chan struct{} // One token per queued task, waking a worker
+	quit   chan struct{}
+
+	lanesMu sync.Mutex
+	lanes   [len(priorityWeights)][]*poolItem
+	credits [len(priorityWeights)]int
+
+	mu      sync.RWMutex
+	started bool
+	stopped bool
+	workers sync.WaitGroup
+
+	active    int64
+	completed int64
+}
+
+type poolItem struct {
+	group *JobGroup
+	task  Task
+}
+
+// NewWorkerPool creates a new worker pool. Non-positive config values fall back to 1.
+func NewWorkerPool(config WorkerPoolConfig, logger zerolog.Logger) *WorkerPool {
+	if config.Workers <= 0 {
+		config.Workers = 1
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 1
+	}
+	if config.MaxPerJob <= 0 {
+		config.MaxPerJob = 1
+	}
+
+	return &WorkerPool{
+		config: config,
+		logger: logger,
+		slots:  make(chan struct{}, config.QueueSize),
+		ready:  make(chan struct{}, config.QueueSize),
+		quit:   make(chan struct{}),
+	}
+}
+
+// Start launches the workers. Calling Start more than once has no effect.
+func (p *WorkerPool) Start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.started || p.stopped {
+		return
+	}
+	p.started = true
+
+	for i := 0; i < p.config.Workers; i++ {
+		p.workers.Add(1)
+		go p.worker(i)
+	}
+
+	p.logger.Info().
+		Int("workers", p.config.Workers).
+		Int("queue_size", p.config.QueueSize).
+		Int("max_per_job", p.config.MaxPerJob).
+		Msg("Worker pool started")
+}
+
+// Stop stops accepting tasks and waits for running tasks to finish.
+// Tasks still queued are discarded without running.
+func (p *WorkerPool) Stop() {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return
+	}
+	p.stopped = true
+	close(p.quit)
+	p.mu.Unlock()
+
+	p.workers.Wait()
+
+	// Release anything left in the queue so JobGroup.Wait does not block forever
+	p.lanesMu.Lock()
+	var discarded []*poolItem
+	for lane := range p.lanes {
+		discarded = append(discarded, p.lanes[lane]...)
+		p.lanes[lane] = nil
+	}
+	p.lanesMu.Unlock()
+
+	for _, item := range discarded {
+		item.group.done()
+	}
+	if len(discarded) > 0 {
+		p.logger.Warn().Int("discarded", len(discarded)).Msg("Worker pool stopped with queued tasks")
+	}
+	p.logger.Info().Msg("Worker pool stopped")
+}
+
+// Stats returns a snapshot of pool utilisation
+func (p *WorkerPool) Stats() PoolStats {
+	stats := PoolStats{
+		Workers:          p.config.Workers,
+		Active:           atomic.LoadInt64(&p.active),
+		QueuedByPriority: make(map[string]int, len(p.lanes)),
+		QueueSize:        p.config.QueueSize,
+		MaxPerJob:        p.config.MaxPerJob,
+		Completed:        atomic.LoadInt64(&p.completed),
+	}
+
+	p.lanesMu.Lock()
+	for _, priority := range []ffmpeg.Priority{ffmpeg.PriorityLow, ffmpeg.PriorityNormal, ffmpeg.PriorityHigh} {
+		queued := len(p.lanes[priorityLane(priority)])
+		stats.QueuedByPriority[priority.String()] = queued
+		stats.Queued += queued
+	}
+	p.lanesMu.Unlock()
+	return stats
+}
+
+// NewJobGroup creates a group for submitting the tasks of one job at normal
+// priority. parallelism caps concurrent tasks for the job; values <= 0 or
+// above the pool's MaxPerJob use MaxPerJob.
+func (p *WorkerPool) NewJobGroup(ctx context.Context, jobID string, parallelism int) *JobGroup {
+	return p.NewPriorityJobGroup(ctx, jobID, parallelism, ffmpeg.PriorityNormal)
+}
+
+// NewPriorityJobGroup is NewJobGroup queueing the job's tasks in the lane of
+// priority
+func (p *WorkerPool) NewPriorityJobGroup(ctx context.Context, jobID string, parallelism int, priority ffmpeg.Priority) *JobGroup {
+	if parallelism <= 0 || parallelism > p.config.MaxPerJob {
+		parallelism = p.config.MaxPerJob
+	}
+
+	return &JobGroup{
+		pool:     p,
+		ctx:      ctx,
+		id:       jobID,
+		priority: priority,
+		slots:    make(chan struct{}, parallelism),
+	}
+}
+
+func (p *WorkerPool) worker(id int) {
+	defer p.workers.Done()
+
+	for {
+		select {
+		case <-p.quit:
+			return
+		case <-p.ready:
+			item := p.next()
+			<-p.slots
+			p.run(id, item)
+		}
+	}
+}
+
+// next removes the task to run from the lanes by smooth weighted round
+// robin: every lane with tasks earns its weight in credit, the richest lane
+// is served and pays back the weights earned in this round. Ties go to the
+// higher priority. Each ready token guarantees a queued task.
+func (p *WorkerPool) next() *poolItem {
+	p.lanesMu.Lock()
+	defer p.lanesMu.Unlock()
+
+	best, total := -1, 0
+	for lane := len(p.lanes) - 1; lane >= 0; lane-- {
+		if len(p.lanes[lane]) == 0 {
+			p.credits[lane] = 0
+			continue
+		}
+		p.credits[lane] += priorityWeights[lane]
+		total += priorityWeights[lane]
+		if best < 0 || p.credits[lane] > p.credits[best] {
+			best = lane
+		}
+	}
+	p.credits[best] -= total
+
+	item := p.lanes[best][0]
+	p.lanes[best][0] = nil
+	p.lanes[best] = p.lanes[best][1:]
+	return item
+}
+
+func (p *WorkerPool) run(workerID int, item *poolItem) {
+	atomic.AddInt64(&p.active, 1)
+	defer func() {
+		if r := recover(); r != nil {
+			p.logger.Error().
+				Interface("panic", r).
+				Int("worker", workerID).
+				Str("job_id", item.group.id).
+				Msg("Worker pool task panicked")
+		}
+		atomic.AddInt64(&p.active, -1)
+		atomic.AddInt64(&p.completed, 1)
+		item.group.done()
+	}()
+
+	item.task(item.group.ctx)
+}
+
+func (p *WorkerPool) enqueue(ctx context.Context, item *poolItem) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.stopped {
+		return ErrPoolStopped
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-p.quit:
+		return ErrPoolStopped
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	lane := priorityLane(item.group.priority)
+	p.lanesMu.Lock()
+	p.lanes[lane] = append(p.lanes[lane], item)
+	p.lanesMu.Unlock()
+
+	// Never blocks: ready holds at most one token per queued task
+	p.ready <- struct{}{}
+	return nil
+}
+
+// JobGroup submits the tasks of a single job to a WorkerPool while
+// enforcing the job's parallelism cap.
+type JobGroup struct {
+	pool     *WorkerPool
+	ctx      context.Context
+	id       string
+	priority ffmpeg.Priority
+	slots    chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Submit queues a task, blocking while the job is at its parallelism cap or
+// the pool queue is full. It returns an error if the job context is cancelled
+// or the pool is stopped before the task could be queued.
+func (g *JobGroup) Submit(task Task) error {
+	select {
+	case g.slots <- struct{}{}:
+	case <-g.ctx.Done():
+		return g.ctx.Err()
+	case <-g.pool.quit:
+		return ErrPoolStopped
+	}
+
+	g.wg.Add(1)
+	if err := g.pool.enqueue(g.ctx, &poolItem{group: g, task: task}); err != nil {
+		g.done()
+		return err
+	}
+	return nil
+}
+
+// Wait blocks until every submitted task has finished
+func (g *JobGroup) Wait() {
+	g.wg.Wait()
+}
+
+func (g *JobGroup) done() {
+	<-g.slots
+	g.wg.Done()
+}
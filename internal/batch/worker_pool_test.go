@@ -0,0 +1,215 @@
+package batch
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rs/zerolog"
+)
+
+func TestWorkerPool_RunsAllTasks(t *testing.T) {
+	pool := NewWorkerPool(WorkerPoolConfig{Workers: 4, QueueSize: 8, MaxPerJob: 4}, zerolog.Nop())
+	pool.Start()
+	defer pool.Stop()
+
+	var count int64
+	group := pool.NewJobGroup(context.Background(), "job-1", 0)
+	for i := 0; i < 50; i++ {
+		if err := group.Submit(func(ctx context.Context) {
+			atomic.AddInt64(&count, 1)
+		}); err != nil {
+			t.Fatalf("unexpected submit error: %v", err)
+		}
+	}
+	group.Wait()
+
+	if count != 50 {
+		t.Errorf("expected 50 tasks to run, got %d", count)
+	}
+	if stats := pool.Stats(); stats.Completed != 50 {
+		t.Errorf("expected 50 completed tasks in stats, got %d", stats.Completed)
+	}
+}
+
+func TestWorkerPool_PerJobParallelismCap(t *testing.T) {
+	pool := NewWorkerPool(WorkerPoolConfig{Workers: 8, QueueSize: 16, MaxPerJob: 8}, zerolog.Nop())
+	pool.Start()
+	defer pool.Stop()
+
+	tests := []struct {
+		name        string
+		parallelism int
+		expectMax   int64
+	}{
+		{name: "explicit cap", parallelism: 2, expectMax: 2},
+		{name: "default to pool cap", parallelism: 0, expectMax: 8},
+		{name: "clamped to pool cap", parallelism: 100, expectMax: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var running, maxRunning int64
+			group := pool.NewJobGroup(context.Background(), tt.name, tt.parallelism)
+
+			for i := 0; i < 20; i++ {
+				if err := group.Submit(func(ctx context.Context) {
+					n := atomic.AddInt64(&running, 1)
+					for {
+						m := atomic.LoadInt64(&maxRunning)
+						if n <= m || atomic.CompareAndSwapInt64(&maxRunning, m, n) {
+							break
+						}
+					}
+					time.Sleep(5 * time.Millisecond)
+					atomic.AddInt64(&running, -1)
+				}); err != nil {
+					t.Fatalf("unexpected submit error: %v", err)
+				}
+			}
+			group.Wait()
+
+			if maxRunning > tt.expectMax {
+				t.Errorf("expected at most %d concurrent tasks, got %d", tt.expectMax, maxRunning)
+			}
+		})
+	}
+}
+
+func TestWorkerPool_SubmitBlocksUntilContextCancelled(t *testing.T) {
+	pool := NewWorkerPool(WorkerPoolConfig{Workers: 1, QueueSize: 1, MaxPerJob: 1}, zerolog.Nop())
+	pool.Start()
+	defer pool.Stop()
+
+	release := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	group := pool.NewJobGroup(ctx, "job-1", 1)
+
+	if err := group.Submit(func(ctx context.Context) { <-release }); err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- group.Submit(func(ctx context.Context) {})
+	}()
+
+	select {
+	case err := <-errCh:
+		t.Fatalf("expected Submit to block at parallelism cap, returned %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel()
+	if err := <-errCh; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+
+	close(release)
+	group.Wait()
+}
+
+func TestWorkerPool_StopReleasesQueuedTasks(t *testing.T) {
+	pool := NewWorkerPool(WorkerPoolConfig{Workers: 1, QueueSize: 4, MaxPerJob: 4}, zerolog.Nop())
+	pool.Start()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	group := pool.NewJobGroup(context.Background(), "job-1", 0)
+
+	var once sync.Once
+	for i := 0; i < 3; i++ {
+		if err := group.Submit(func(ctx context.Context) {
+			once.Do(func() { close(started) })
+			<-release
+		}); err != nil {
+			t.Fatalf("unexpected submit error: %v", err)
+		}
+	}
+
+	<-started
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	pool.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		group.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("JobGroup.Wait blocked after pool stop")
+	}
+
+	if err := group.Submit(func(ctx context.Context) {}); err != ErrPoolStopped {
+		t.Errorf("expected ErrPoolStopped after stop, got %v", err)
+	}
+}
+
+func TestWorkerPool_PriorityLanes(t *testing.T) {
+	pool := NewWorkerPool(WorkerPoolConfig{Workers: 1, QueueSize: 64, MaxPerJob: 20}, zerolog.Nop())
+	pool.Start()
+	defer pool.Stop()
+
+	// Hold the only worker until every lane is full
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocker := pool.NewJobGroup(context.Background(), "blocker", 1)
+	if err := blocker.Submit(func(ctx context.Context) {
+		close(started)
+		<-release
+	}); err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	<-started
+
+	var mu sync.Mutex
+	var order []ffmpeg.Priority
+	groups := make(map[ffmpeg.Priority]*JobGroup)
+	for _, priority := range []ffmpeg.Priority{ffmpeg.PriorityLow, ffmpeg.PriorityNormal, ffmpeg.PriorityHigh} {
+		group := pool.NewPriorityJobGroup(context.Background(), priority.String(), 0, priority)
+		groups[priority] = group
+		for i := 0; i < 14; i++ {
+			if err := group.Submit(func(ctx context.Context) {
+				mu.Lock()
+				order = append(order, priority)
+				mu.Unlock()
+			}); err != nil {
+				t.Fatalf("unexpected submit error: %v", err)
+			}
+		}
+	}
+
+	stats := pool.Stats()
+	if stats.Queued != 42 || stats.QueuedByPriority["high"] != 14 || stats.QueuedByPriority["low"] != 14 {
+		t.Errorf("unexpected queue stats %+v", stats)
+	}
+
+	close(release)
+	for _, group := range groups {
+		group.Wait()
+	}
+	blocker.Wait()
+
+	// While every lane has tasks, each 7 dispatches are 4 high, 2 normal and 1 low
+	for round := 0; round < 2; round++ {
+		counts := make(map[ffmpeg.Priority]int)
+		for _, priority := range order[round*7 : round*7+7] {
+			counts[priority]++
+		}
+		if counts[ffmpeg.PriorityHigh] != 4 || counts[ffmpeg.PriorityNormal] != 2 || counts[ffmpeg.PriorityLow] != 1 {
+			t.Errorf("round %d dispatched %v, want 4 high, 2 normal and 1 low", round, counts)
+		}
+	}
+	if order[0] != ffmpeg.PriorityHigh {
+		t.Errorf("first task was %s, want high", order[0])
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"net/mail"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 )
@@ -18,10 +21,26 @@ type Config struct {
 	BaseURL  string `json:"base_url"`
 	LogLevel string `json:"log_level"`
 
+	// gRPC server configuration
+	EnableGRPC bool `json:"enable_grpc"`
+	GRPCPort   int  `json:"grpc_port"`
+
+	// Tracing configuration (exporter endpoint uses the standard OTEL_EXPORTER_OTLP_* variables)
+	EnableTracing      bool    `json:"enable_tracing"`
+	TracingSampleRatio float64 `json:"tracing_sample_ratio"` // fraction of new traces to sample, 0-1
+
 	// Database configuration
-	DatabaseType string `json:"database_type"` // sqlite only
-	DatabaseURL  string `json:"database_url"`
-	DatabasePath string `json:"database_path"` // for SQLite
+	DatabaseType         string `json:"database_type"` // sqlite or postgres
+	DatabaseURL          string `json:"database_url"`
+	DatabasePath         string `json:"database_path"` // for SQLite
+	PostgresHost         string `json:"postgres_host"`
+	PostgresPort         int    `json:"postgres_port"`
+	PostgresDB           string `json:"postgres_db"`
+	PostgresUser         string `json:"postgres_user"`
+	PostgresPassword     string `json:"-"`
+	PostgresSSLMode      string `json:"postgres_sslmode"`
+	DatabaseMaxOpenConns int    `json:"database_max_open_conns"` // PostgreSQL only; SQLite uses one connection
+	DatabaseMaxIdleConns int    `json:"database_max_idle_conns"`
 
 	// Valkey configuration (Redis-compatible)
 	ValkeyHost     string `json:"valkey_host"`
@@ -32,6 +51,10 @@ type Config struct {
 	// API configuration
 	APIKey string `json:"api_key"`
 
+	// Multi-tenancy requires an API key on every /api request: API_KEY acts
+	// for the deployment, tenant keys for one organization or project
+	EnableTenancy bool `json:"enable_tenancy"`
+
 	// Authentication configuration
 	JWTSecret       string `json:"jwt_secret"`
 	TokenExpiry     int    `json:"token_expiry_hours"`   // hours
@@ -53,22 +76,107 @@ type Config struct {
 	FFmpegPath  string `json:"ffmpeg_path"`
 	FFprobePath string `json:"ffprobe_path"`
 
+	// FFmpeg process limits shared by every request, batch job and watch folder
+	FFmpegMaxProcesses int `json:"ffmpeg_max_processes"` // Concurrent ffmpeg/ffprobe processes
+	FFmpegMaxQueued    int `json:"ffmpeg_max_queued"`    // Invocations waiting for a process slot, 0 for unbounded
+
+	// Content analysis sampling reads evenly spaced windows of long assets
+	// instead of decoding them in full (disabled when windows is 0)
+	ContentSamplingWindows       int     `json:"content_sampling_windows"`
+	ContentSamplingWindowSeconds float64 `json:"content_sampling_window_seconds"`
+	ContentSamplingMinDuration   float64 `json:"content_sampling_min_duration"` // seconds; shorter assets are analyzed in full
+
+	// Loudness normalization targets, comma-separated built-in names or
+	// I/TP/LRA triples (empty for ebu_r128, atsc_a85 and streaming)
+	LoudnessTargets string `json:"loudness_targets"`
+
+	// Custom analyzer plugins: every executable in the directory runs after
+	// the built-in analyzers of each full analysis (disabled when empty)
+	PluginDir     string `json:"plugin_dir"`
+	PluginTimeout int    `json:"plugin_timeout"` // seconds per plugin run
+
+	// Path to mediainfo for cross-checking duration, bit rate and channel
+	// counts against ffprobe (disabled when empty)
+	MediaInfoPath string `json:"mediainfo_path"`
+
+	// Batch worker pool configuration
+	BatchWorkers        int `json:"batch_workers"`         // Concurrent FFprobe processes across all batch jobs
+	BatchQueueSize      int `json:"batch_queue_size"`      // Pending batch items before submission blocks
+	BatchJobParallelism int `json:"batch_job_parallelism"` // Maximum concurrent items per batch job
+	DrainTimeout        int `json:"drain_timeout"`         // seconds running batch items may take to finish on shutdown
+
+	// Job coordination shares batch state, progress and locks between
+	// replicas through Valkey/Redis
+	EnableJobCoordination bool   `json:"enable_job_coordination"`
+	CoordinationKeyPrefix string `json:"coordination_key_prefix"`
+	CoordinationJobTTL    int    `json:"coordination_job_ttl"` // seconds a shared job outlives its last update
+
+	// Webhook callback configuration (callbacks are disabled without a secret)
+	WebhookSecret     string `json:"webhook_secret"`      // HMAC-SHA256 signing key for callback payloads
+	WebhookTimeout    int    `json:"webhook_timeout"`     // seconds per delivery attempt
+	WebhookMaxRetries int    `json:"webhook_max_retries"` // retries after the first failed attempt
+
+	// Notification channels. Slack and Teams channels only need their
+	// incoming webhook URL; email channels are sent through this SMTP relay.
+	SMTPHost            string `json:"smtp_host"`
+	SMTPPort            int    `json:"smtp_port"` // 465 for implicit TLS, otherwise STARTTLS when offered
+	SMTPUsername        string `json:"smtp_username"`
+	SMTPPassword        string `json:"smtp_password"`
+	SMTPFrom            string `json:"smtp_from"`
+	NotificationTimeout int    `json:"notification_timeout"` // seconds per delivery
+
+	// Signed report links (disabled without a secret)
+	ShareLinkSecret string `json:"share_link_secret"`  // HMAC-SHA256 signing key for shared report links
+	ShareLinkMaxTTL int    `json:"share_link_max_ttl"` // seconds a shared link may stay valid
+
 	// Upload configuration
-	UploadDir   string `json:"upload_dir"`
-	MaxFileSize int64  `json:"max_file_size"`
+	UploadDir        string `json:"upload_dir"`
+	UploadSessionTTL int    `json:"upload_session_ttl"` // seconds an idle resumable upload is kept
+	DirectUploadTTL  int    `json:"direct_upload_ttl"`  // seconds a presigned browser upload URL is valid
+
+	// Request limits, reloadable from LimitsFile without a restart
+	Limits
+	LimitsFile string `json:"limits_file"` // JSON file overriding Limits
+
+	// Temporary media storage. Uploaded and downloaded files are written to
+	// WorkDir before analysis; zero quotas are unlimited.
+	WorkDir          string `json:"work_dir"`
+	JobDiskQuota     int64  `json:"job_disk_quota"`      // bytes one upload or download may occupy
+	DiskQuota        int64  `json:"disk_quota"`          // bytes all temporary media may occupy at once
+	MinFreeDiskSpace int64  `json:"min_free_disk_space"` // bytes to leave free on the WorkDir volume
+
+	// Result cache: a file submitted again with the same analysis options
+	// returns the stored analysis instead of being analyzed again
+	ResultCacheTTL  int    `json:"result_cache_ttl"`  // seconds a stored analysis is reused, 0 disables
+	ResultCacheHash string `json:"result_cache_hash"` // fast (size plus xxHash of head and tail) or sha256
+
+	// Watch folder ingestion (disabled when no folders are set)
+	WatchFolders  []string `json:"watch_folders"`  // directories scanned for new media, local or mounted
+	WatchInterval int      `json:"watch_interval"` // seconds between scans
+	WatchProfile  string   `json:"watch_profile"`  // delivery profile deciding pass/fail, empty to only require a readable file
+	WatchPassDir  string   `json:"watch_pass_dir"` // where passing files are moved, relative to their watch folder unless absolute
+	WatchFailDir  string   `json:"watch_fail_dir"` // where failing files are moved
 
 	// Reports configuration
 	ReportsDir string `json:"reports_dir"`
 
 	// LLM configuration (required by default)
-	LLMModelPath        string `json:"llm_model_path"`
-	LLMTimeout          int    `json:"llm_timeout"` // seconds, default 120
-	OpenRouterAPIKey    string `json:"openrouter_api_key"`
-	EnableLocalLLM      bool   `json:"enable_local_llm"`
-	OllamaURL           string `json:"ollama_url"`
-	OllamaModel         string `json:"ollama_model"`
-	OllamaFallbackModel string `json:"ollama_fallback_model"`
-	RequireLLM          bool   `json:"require_llm"` // Make LLM mandatory for analysis
+	LLMModelPath         string  `json:"llm_model_path"`
+	LLMTimeout           int     `json:"llm_timeout"`   // seconds, default 120
+	LLMCacheTTL          int     `json:"llm_cache_ttl"` // seconds an LLM report is reused for identical FFprobe data, 0 disables
+	LLMProvider          string  `json:"llm_provider"`  // ollama (Ollama with OpenRouter fallback), openai, anthropic or gemini
+	LLMAPIKey            string  `json:"llm_api_key"`
+	LLMBaseURL           string  `json:"llm_base_url"` // overrides the provider API URL, e.g. an OpenAI-compatible server
+	LLMModel             string  `json:"llm_model"`
+	LLMMaxTokens         int     `json:"llm_max_tokens"`
+	LLMInputCostPerMTok  float64 `json:"llm_input_cost_per_mtok"`  // USD per million prompt tokens, for cost accounting
+	LLMOutputCostPerMTok float64 `json:"llm_output_cost_per_mtok"` // USD per million completion tokens
+	OpenRouterAPIKey     string  `json:"openrouter_api_key"`
+	EnableLocalLLM       bool    `json:"enable_local_llm"`
+	OllamaURL            string  `json:"ollama_url"`
+	OllamaModel          string  `json:"ollama_model"`
+	OllamaFallbackModel  string  `json:"ollama_fallback_model"`
+	RequireLLM           bool    `json:"require_llm"` // Make LLM mandatory for analysis
 
 	// Cloud deployment configuration
 	CloudMode          bool `json:"cloud_mode"`           // Enable cloud deployment mode
@@ -104,13 +212,27 @@ func Load() (*Config, error) {
 		Host:                   getEnv("API_HOST", "localhost"),
 		BaseURL:                getEnv("BASE_URL", ""),
 		LogLevel:               getEnv("LOG_LEVEL", "info"),
+		EnableGRPC:             getEnvAsBool("ENABLE_GRPC", true),
+		GRPCPort:               getEnvAsInt("GRPC_PORT", 50051),
+		EnableTracing:          getEnvAsBool("ENABLE_TRACING", false),
+		TracingSampleRatio:     getEnvAsFloat64("TRACING_SAMPLE_RATIO", 1.0),
 		DatabaseType:           getEnv("DB_TYPE", "sqlite"),
+		DatabaseURL:            getEnv("DATABASE_URL", ""),
 		DatabasePath:           getEnv("DB_PATH", "./data/rendiff-probe.db"),
+		PostgresHost:           getEnv("POSTGRES_HOST", "localhost"),
+		PostgresPort:           getEnvAsInt("POSTGRES_PORT", 5432),
+		PostgresDB:             getEnv("POSTGRES_DB", "rendiff_probe"),
+		PostgresUser:           getEnv("POSTGRES_USER", "rendiff"),
+		PostgresPassword:       getEnv("POSTGRES_PASSWORD", ""),
+		PostgresSSLMode:        getEnv("POSTGRES_SSLMODE", "disable"),
+		DatabaseMaxOpenConns:   getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
+		DatabaseMaxIdleConns:   getEnvAsInt("DB_MAX_IDLE_CONNS", 10),
 		ValkeyHost:             getEnv("VALKEY_HOST", "localhost"),
 		ValkeyPort:             getEnvAsInt("VALKEY_PORT", 6379),
 		ValkeyPassword:         getEnv("VALKEY_PASSWORD", ""),
 		ValkeyDB:               getEnvAsInt("VALKEY_DB", 0),
 		APIKey:                 getEnv("API_KEY", ""),
+		EnableTenancy:          getEnvAsBool("ENABLE_TENANCY", false),
 		JWTSecret:              getEnv("JWT_SECRET", ""),
 		TokenExpiry:            getEnvAsInt("TOKEN_EXPIRY_HOURS", 24),
 		RefreshExpiry:          getEnvAsInt("REFRESH_EXPIRY_HOURS", 168), // 7 days
@@ -124,8 +246,40 @@ func Load() (*Config, error) {
 		TrustedProxies:         getEnvAsStringSlice("TRUSTED_PROXIES", []string{}),
 		FFmpegPath:             getEnv("FFMPEG_PATH", "ffmpeg"),
 		FFprobePath:            getEnv("FFPROBE_PATH", "ffprobe"),
+		FFmpegMaxProcesses:     getEnvAsInt("FFMPEG_MAX_PROCESSES", 2*runtime.NumCPU()),
+		FFmpegMaxQueued:        getEnvAsInt("FFMPEG_MAX_QUEUED", 0),
+		BatchWorkers:           getEnvAsInt("BATCH_WORKERS", runtime.NumCPU()),
+		BatchQueueSize:         getEnvAsInt("BATCH_QUEUE_SIZE", 100),
+		BatchJobParallelism:    getEnvAsInt("BATCH_JOB_PARALLELISM", 4),
+		DrainTimeout:           getEnvAsInt("DRAIN_TIMEOUT", 30),
+		EnableJobCoordination:  getEnvAsBool("ENABLE_JOB_COORDINATION", false),
+		CoordinationKeyPrefix:  getEnv("COORDINATION_KEY_PREFIX", "rendiff-probe"),
+		CoordinationJobTTL:     getEnvAsInt("COORDINATION_JOB_TTL", 86400),
+		WebhookSecret:          getEnv("WEBHOOK_SECRET", ""),
+		WebhookTimeout:         getEnvAsInt("WEBHOOK_TIMEOUT", 10),
+		WebhookMaxRetries:      getEnvAsInt("WEBHOOK_MAX_RETRIES", 3),
+		SMTPHost:               getEnv("SMTP_HOST", ""),
+		SMTPPort:               getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername:           getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:           getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:               getEnv("SMTP_FROM", ""),
+		NotificationTimeout:    getEnvAsInt("NOTIFICATION_TIMEOUT", 10),
+		ShareLinkSecret:        getEnv("SHARE_LINK_SECRET", ""),
+		ShareLinkMaxTTL:        getEnvAsInt("SHARE_LINK_MAX_TTL", 604800),
 		UploadDir:              getEnv("UPLOAD_DIR", "/tmp/uploads"),
-		MaxFileSize:            getEnvAsInt64("MAX_FILE_SIZE", 50*1024*1024*1024), // 50GB default
+		UploadSessionTTL:       getEnvAsInt("UPLOAD_SESSION_TTL", 86400), // 24 hours
+		DirectUploadTTL:        getEnvAsInt("DIRECT_UPLOAD_TTL", 3600),   // 1 hour
+		WorkDir:                getEnv("WORK_DIR", "/tmp/rendiff-work"),
+		JobDiskQuota:           getEnvAsInt64("JOB_DISK_QUOTA", 0),
+		DiskQuota:              getEnvAsInt64("DISK_QUOTA", 0),
+		MinFreeDiskSpace:       getEnvAsInt64("MIN_FREE_DISK_SPACE", 0),
+		ResultCacheTTL:         getEnvAsInt("RESULT_CACHE_TTL", 86400), // 24 hours
+		ResultCacheHash:        getEnv("RESULT_CACHE_HASH", "fast"),
+		WatchFolders:           getEnvAsStringSlice("WATCH_FOLDERS", []string{}),
+		WatchInterval:          getEnvAsInt("WATCH_INTERVAL", 60),
+		WatchProfile:           getEnv("WATCH_PROFILE", ""),
+		WatchPassDir:           getEnv("WATCH_PASS_DIR", "passed"),
+		WatchFailDir:           getEnv("WATCH_FAIL_DIR", "failed"),
 		ReportsDir:             getEnv("REPORTS_DIR", "/tmp/reports"),
 		LLMModelPath:           getEnv("LLM_MODEL_PATH", ""),
 		OpenRouterAPIKey:       getEnv("OPENROUTER_API_KEY", ""),
@@ -133,6 +287,14 @@ func Load() (*Config, error) {
 		OllamaURL:              getEnv("OLLAMA_URL", "http://localhost:11434"),
 		OllamaModel:            getEnv("OLLAMA_MODEL", "gemma3:270m"),
 		OllamaFallbackModel:    getEnv("OLLAMA_FALLBACK_MODEL", "phi3:mini"),
+		LLMCacheTTL:            getEnvAsInt("LLM_CACHE_TTL", 86400), // 24 hours
+		LLMProvider:            getEnv("LLM_PROVIDER", "ollama"),
+		LLMAPIKey:              getEnv("LLM_API_KEY", ""),
+		LLMBaseURL:             getEnv("LLM_BASE_URL", ""),
+		LLMModel:               getEnv("LLM_MODEL", ""),
+		LLMMaxTokens:           getEnvAsInt("LLM_MAX_TOKENS", 1500),
+		LLMInputCostPerMTok:    getEnvAsFloat64("LLM_INPUT_COST_PER_MTOK", 0),
+		LLMOutputCostPerMTok:   getEnvAsFloat64("LLM_OUTPUT_COST_PER_MTOK", 0),
 		RequireLLM:             getEnvAsBool("REQUIRE_LLM", true), // LLM is mandatory by default
 		CloudMode:              getEnvAsBool("CLOUD_MODE", false), // Detect cloud deployment
 		SkipAuthValidation:     getEnvAsBool("SKIP_AUTH_VALIDATION", false),
@@ -155,6 +317,16 @@ func Load() (*Config, error) {
 		AzureStorageKey:        getEnv("AZURE_STORAGE_KEY", ""),
 	}
 
+	cfg.ContentSamplingWindows = getEnvAsInt("CONTENT_SAMPLING_WINDOWS", 0)
+	cfg.ContentSamplingWindowSeconds = getEnvAsFloat64("CONTENT_SAMPLING_WINDOW_SECONDS", 30)
+	cfg.ContentSamplingMinDuration = getEnvAsFloat64("CONTENT_SAMPLING_MIN_DURATION", 1800)
+	cfg.LoudnessTargets = getEnv("LOUDNESS_TARGETS", "")
+	cfg.PluginDir = getEnv("PLUGIN_DIR", "")
+	cfg.PluginTimeout = getEnvAsInt("PLUGIN_TIMEOUT", 300)
+	cfg.MediaInfoPath = getEnv("MEDIAINFO_PATH", "")
+	cfg.Limits = limitsFromEnv()
+	cfg.LimitsFile = getEnv("LIMITS_FILE", "")
+
 	// Build database URL if not provided directly
 	if cfg.DatabaseURL == "" {
 		cfg.DatabaseURL = buildDatabaseURL(cfg)
@@ -201,6 +373,16 @@ func getEnvAsInt64(key string, fallback int64) int64 {
 	return fallback
 }
 
+// getEnvAsFloat64 gets an environment variable as float64 with a fallback value
+func getEnvAsFloat64(key string, fallback float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return fallback
+}
+
 // getEnvAsBool gets an environment variable as boolean with a fallback value
 func getEnvAsBool(key string, fallback bool) bool {
 	if value := os.Getenv(key); value != "" {
@@ -221,9 +403,15 @@ func getEnvAsStringSlice(key string, fallback []string) []string {
 
 // buildDatabaseURL constructs a database connection URL
 func buildDatabaseURL(cfg *Config) string {
-	if cfg.DatabaseType != "sqlite" {
-		// Force SQLite
-		cfg.DatabaseType = "sqlite"
+	if cfg.DatabaseType == "postgres" {
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(cfg.PostgresUser, cfg.PostgresPassword),
+			Host:     fmt.Sprintf("%s:%d", cfg.PostgresHost, cfg.PostgresPort),
+			Path:     "/" + cfg.PostgresDB,
+			RawQuery: "sslmode=" + url.QueryEscape(cfg.PostgresSSLMode),
+		}
+		return dsn.String()
 	}
 	return fmt.Sprintf("sqlite3://%s", cfg.DatabasePath)
 }
@@ -241,6 +429,11 @@ func buildBaseURL(cfg *Config) string {
 func validateConfig(cfg *Config) error {
 	var errors []string
 
+	// Multi-tenancy needs a key the operator knows, not a generated one
+	if cfg.EnableTenancy && cfg.APIKey == "" {
+		errors = append(errors, "API_KEY is required with ENABLE_TENANCY, it administers tenants")
+	}
+
 	// Skip strict auth validation in cloud mode or when explicitly disabled
 	if !cfg.CloudMode && !cfg.SkipAuthValidation {
 		// Validate required security settings for production
@@ -267,19 +460,36 @@ func validateConfig(cfg *Config) error {
 		}
 	}
 
-	// Validate database configuration - only SQLite supported
-	if cfg.DatabaseType != "sqlite" {
-		errors = append(errors, "DB_TYPE must be 'sqlite' (PostgreSQL no longer supported)")
-	}
-
-	if cfg.DatabasePath == "" {
-		errors = append(errors, "DB_PATH is required when using SQLite")
+	// Validate database configuration
+	switch cfg.DatabaseType {
+	case "sqlite":
+		if cfg.DatabasePath == "" {
+			errors = append(errors, "DB_PATH is required when using SQLite")
+		}
+	case "postgres":
+		if !strings.HasPrefix(cfg.DatabaseURL, "postgres://") && !strings.HasPrefix(cfg.DatabaseURL, "postgresql://") {
+			errors = append(errors, "DATABASE_URL must be a postgres:// URL when using PostgreSQL")
+		}
+	default:
+		errors = append(errors, "DB_TYPE must be 'sqlite' or 'postgres'")
 	}
 
 	// Validate ports
 	if cfg.Port <= 0 || cfg.Port > 65535 {
 		errors = append(errors, "API_PORT must be between 1 and 65535")
 	}
+	if cfg.EnableGRPC {
+		if cfg.GRPCPort <= 0 || cfg.GRPCPort > 65535 {
+			errors = append(errors, "GRPC_PORT must be between 1 and 65535")
+		} else if cfg.GRPCPort == cfg.Port {
+			errors = append(errors, "GRPC_PORT must differ from API_PORT")
+		}
+	}
+
+	// Validate tracing
+	if cfg.EnableTracing && (cfg.TracingSampleRatio < 0 || cfg.TracingSampleRatio > 1) {
+		errors = append(errors, "TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
 
 	// Validate host
 	if cfg.Host == "" {
@@ -301,6 +511,32 @@ func validateConfig(cfg *Config) error {
 			errors = append(errors, fmt.Sprintf("UPLOAD_DIR validation failed: %v", err))
 		}
 	}
+	if cfg.UploadSessionTTL <= 0 {
+		errors = append(errors, "UPLOAD_SESSION_TTL must be greater than 0")
+	}
+	// S3 presigned URLs are valid for at most 7 days
+	if cfg.DirectUploadTTL <= 0 || cfg.DirectUploadTTL > 604800 {
+		errors = append(errors, "DIRECT_UPLOAD_TTL must be between 1 and 604800 seconds")
+	}
+
+	if cfg.WorkDir == "" {
+		errors = append(errors, "WORK_DIR is required")
+	} else if err := validateDirectory(cfg.WorkDir); err != nil {
+		errors = append(errors, fmt.Sprintf("WORK_DIR validation failed: %v", err))
+	}
+	if cfg.JobDiskQuota < 0 || cfg.DiskQuota < 0 || cfg.MinFreeDiskSpace < 0 {
+		errors = append(errors, "JOB_DISK_QUOTA, DISK_QUOTA and MIN_FREE_DISK_SPACE must not be negative")
+	}
+	if cfg.JobDiskQuota > 0 && cfg.DiskQuota > 0 && cfg.JobDiskQuota > cfg.DiskQuota {
+		errors = append(errors, "JOB_DISK_QUOTA cannot exceed DISK_QUOTA")
+	}
+
+	if cfg.ResultCacheTTL < 0 {
+		errors = append(errors, "RESULT_CACHE_TTL must be 0 (disabled) or greater")
+	}
+	if cfg.ResultCacheHash != "fast" && cfg.ResultCacheHash != "sha256" {
+		errors = append(errors, "RESULT_CACHE_HASH must be fast or sha256")
+	}
 
 	if cfg.ReportsDir == "" {
 		errors = append(errors, "REPORTS_DIR is required")
@@ -310,9 +546,93 @@ func validateConfig(cfg *Config) error {
 		}
 	}
 
-	// Validate file size limits
-	if cfg.MaxFileSize <= 0 {
-		errors = append(errors, "MAX_FILE_SIZE must be greater than 0")
+	// Validate request limits
+	errors = append(errors, cfg.Limits.problems()...)
+
+	// Validate ffmpeg process limits
+	if cfg.FFmpegMaxProcesses <= 0 {
+		errors = append(errors, "FFMPEG_MAX_PROCESSES must be greater than 0")
+	}
+	if cfg.FFmpegMaxQueued < 0 {
+		errors = append(errors, "FFMPEG_MAX_QUEUED must not be negative")
+	}
+
+	// Validate content analysis sampling
+	if cfg.ContentSamplingWindows < 0 {
+		errors = append(errors, "CONTENT_SAMPLING_WINDOWS must not be negative")
+	}
+	if cfg.ContentSamplingWindows > 0 && cfg.ContentSamplingWindowSeconds <= 0 {
+		errors = append(errors, "CONTENT_SAMPLING_WINDOW_SECONDS must be greater than 0")
+	}
+	if cfg.ContentSamplingMinDuration < 0 {
+		errors = append(errors, "CONTENT_SAMPLING_MIN_DURATION must not be negative")
+	}
+
+	// Validate batch worker pool
+	if cfg.BatchWorkers <= 0 {
+		errors = append(errors, "BATCH_WORKERS must be greater than 0")
+	}
+	if cfg.BatchQueueSize <= 0 {
+		errors = append(errors, "BATCH_QUEUE_SIZE must be greater than 0")
+	}
+	if cfg.BatchJobParallelism <= 0 {
+		errors = append(errors, "BATCH_JOB_PARALLELISM must be greater than 0")
+	}
+	if cfg.DrainTimeout < 0 {
+		errors = append(errors, "DRAIN_TIMEOUT must be 0 (pause at once) or greater")
+	}
+
+	// Validate watch folders. Missing folders are not created, so an unmounted
+	// share is reported instead of silently filling the local disk.
+	if len(cfg.WatchFolders) > 0 {
+		for _, folder := range cfg.WatchFolders {
+			if stat, err := os.Stat(folder); err != nil || !stat.IsDir() {
+				errors = append(errors, fmt.Sprintf("WATCH_FOLDERS entry %q is not an existing directory", folder))
+			} else if err := validateDirectory(folder); err != nil {
+				errors = append(errors, fmt.Sprintf("WATCH_FOLDERS entry %q validation failed: %v", folder, err))
+			}
+		}
+		if cfg.WatchInterval <= 0 {
+			errors = append(errors, "WATCH_INTERVAL must be greater than 0 when watch folders are set")
+		}
+		if cfg.WatchPassDir == "" || cfg.WatchFailDir == "" {
+			errors = append(errors, "WATCH_PASS_DIR and WATCH_FAIL_DIR are required when watch folders are set")
+		} else if cfg.WatchPassDir == cfg.WatchFailDir {
+			errors = append(errors, "WATCH_PASS_DIR and WATCH_FAIL_DIR must differ")
+		}
+	}
+
+	// Validate webhook callbacks
+	if cfg.WebhookSecret != "" {
+		if len(cfg.WebhookSecret) < 16 {
+			errors = append(errors, "WEBHOOK_SECRET must be at least 16 characters long")
+		}
+		if cfg.WebhookTimeout <= 0 {
+			errors = append(errors, "WEBHOOK_TIMEOUT must be greater than 0 when webhooks are enabled")
+		}
+		if cfg.WebhookMaxRetries < 0 {
+			errors = append(errors, "WEBHOOK_MAX_RETRIES cannot be negative")
+		}
+	}
+
+	// Validate the SMTP relay of email notifications
+	if cfg.SMTPHost != "" {
+		if cfg.SMTPPort <= 0 || cfg.SMTPPort > 65535 {
+			errors = append(errors, "SMTP_PORT must be between 1 and 65535")
+		}
+		if _, err := mail.ParseAddress(cfg.SMTPFrom); err != nil {
+			errors = append(errors, "SMTP_FROM must be a valid email address when SMTP_HOST is set")
+		}
+	}
+
+	// Validate shared report links
+	if cfg.ShareLinkSecret != "" {
+		if len(cfg.ShareLinkSecret) < 32 {
+			errors = append(errors, "SHARE_LINK_SECRET must be at least 32 characters long")
+		}
+		if cfg.ShareLinkMaxTTL <= 0 {
+			errors = append(errors, "SHARE_LINK_MAX_TTL must be greater than 0 when share links are enabled")
+		}
 	}
 
 	// Validate rate limiting
@@ -351,12 +671,36 @@ func validateConfig(cfg *Config) error {
 	}
 
 	// Validate LLM configuration - LLM is now mandatory by default
-	if cfg.RequireLLM {
+	if cfg.RequireLLM && (cfg.LLMProvider == "" || cfg.LLMProvider == "ollama") {
 		if !cfg.EnableLocalLLM && cfg.OpenRouterAPIKey == "" {
-			errors = append(errors, "LLM is required for analysis: either enable local LLM (ENABLE_LOCAL_LLM=true) or provide OpenRouter API key (OPENROUTER_API_KEY)")
+			errors = append(errors, "LLM is required for analysis: either enable local LLM (ENABLE_LOCAL_LLM=true), provide OpenRouter API key (OPENROUTER_API_KEY) or set LLM_PROVIDER")
 		}
 	}
 
+	switch cfg.LLMProvider {
+	case "", "ollama":
+	case "openai":
+		if cfg.LLMAPIKey == "" && cfg.LLMBaseURL == "" {
+			errors = append(errors, "LLM_API_KEY or LLM_BASE_URL is required when LLM_PROVIDER is openai")
+		}
+	case "anthropic", "gemini":
+		if cfg.LLMAPIKey == "" {
+			errors = append(errors, fmt.Sprintf("LLM_API_KEY is required when LLM_PROVIDER is %s", cfg.LLMProvider))
+		}
+	default:
+		errors = append(errors, "LLM_PROVIDER must be one of: ollama, openai, anthropic, gemini")
+	}
+	if cfg.LLMMaxTokens < 0 {
+		errors = append(errors, "LLM_MAX_TOKENS cannot be negative")
+	}
+	if cfg.LLMInputCostPerMTok < 0 || cfg.LLMOutputCostPerMTok < 0 {
+		errors = append(errors, "LLM_INPUT_COST_PER_MTOK and LLM_OUTPUT_COST_PER_MTOK cannot be negative")
+	}
+
+	if cfg.LLMCacheTTL < 0 {
+		errors = append(errors, "LLM_CACHE_TTL must be 0 (disabled) or greater")
+	}
+
 	if cfg.EnableLocalLLM {
 		if cfg.OllamaURL == "" {
 			errors = append(errors, "OLLAMA_URL is required when local LLM is enabled")
@@ -384,15 +728,18 @@ func validateConfig(cfg *Config) error {
 		errors = append(errors, "REFRESH_EXPIRY_HOURS must be greater than TOKEN_EXPIRY_HOURS")
 	}
 
-	// Validate Valkey configuration if used for rate limiting
-	if cfg.EnableRateLimit {
+	// Validate Valkey configuration if used for rate limiting or job coordination
+	if cfg.EnableRateLimit || cfg.EnableJobCoordination {
 		if cfg.ValkeyPort <= 0 || cfg.ValkeyPort > 65535 {
 			errors = append(errors, "VALKEY_PORT must be between 1 and 65535")
 		}
 		if cfg.ValkeyHost == "" {
-			errors = append(errors, "VALKEY_HOST is required when rate limiting is enabled")
+			errors = append(errors, "VALKEY_HOST is required when rate limiting or job coordination is enabled")
 		}
 	}
+	if cfg.EnableJobCoordination && cfg.CoordinationJobTTL <= 0 {
+		errors = append(errors, "COORDINATION_JOB_TTL must be greater than 0")
+	}
 
 	// Validate CORS configuration
 	if len(cfg.AllowedOrigins) > 0 {
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Limits are the request limits that can change while the server runs.
+// They come from the environment, then command-line flags, then
+// LIMITS_FILE, which is read again when the configuration is reloaded.
+type Limits struct {
+	MaxFileSize        int64 `json:"max_file_size"`        // bytes per upload or download
+	MaxBatchItems      int   `json:"max_batch_items"`      // files and URLs per batch job
+	AnalysisTimeout    int   `json:"analysis_timeout"`     // seconds, for requests without a timeout
+	MaxAnalysisTimeout int   `json:"max_analysis_timeout"` // seconds a request may ask for
+}
+
+// limitsFromEnv reads the limits from the environment
+func limitsFromEnv() Limits {
+	return Limits{
+		MaxFileSize:        getEnvAsInt64("MAX_FILE_SIZE", 5*1024*1024*1024), // 5GB
+		MaxBatchItems:      getEnvAsInt("MAX_BATCH_ITEMS", 100),
+		AnalysisTimeout:    getEnvAsInt("ANALYSIS_TIMEOUT", 60),
+		MaxAnalysisTimeout: getEnvAsInt("MAX_ANALYSIS_TIMEOUT", 1800), // 30 minutes
+	}
+}
+
+// RegisterFlags adds a command-line flag for each limit to fs, defaulting
+// to the current value, so parsing fs overrides the environment
+func (l *Limits) RegisterFlags(fs *flag.FlagSet) {
+	fs.Int64Var(&l.MaxFileSize, "max-file-size", l.MaxFileSize, "maximum upload or download size in bytes (MAX_FILE_SIZE)")
+	fs.IntVar(&l.MaxBatchItems, "max-batch-items", l.MaxBatchItems, "maximum items per batch job (MAX_BATCH_ITEMS)")
+	fs.IntVar(&l.AnalysisTimeout, "analysis-timeout", l.AnalysisTimeout, "seconds an analysis may run when the request sets no timeout (ANALYSIS_TIMEOUT)")
+	fs.IntVar(&l.MaxAnalysisTimeout, "max-analysis-timeout", l.MaxAnalysisTimeout, "longest timeout in seconds a request may ask for (MAX_ANALYSIS_TIMEOUT)")
+}
+
+// Validate reports every limit out of range
+func (l Limits) Validate() error {
+	if problems := l.problems(); len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func (l Limits) problems() []string {
+	var problems []string
+	if l.MaxFileSize <= 0 {
+		problems = append(problems, "MAX_FILE_SIZE must be greater than 0")
+	}
+	if l.MaxBatchItems <= 0 {
+		problems = append(problems, "MAX_BATCH_ITEMS must be greater than 0")
+	}
+	if l.AnalysisTimeout <= 0 {
+		problems = append(problems, "ANALYSIS_TIMEOUT must be greater than 0")
+	}
+	if l.MaxAnalysisTimeout < l.AnalysisTimeout {
+		problems = append(problems, "MAX_ANALYSIS_TIMEOUT must not be less than ANALYSIS_TIMEOUT")
+	}
+	return problems
+}
+
+// Apply returns l with the limits set in data, a JSON object with the keys
+// of Limits, replaced. Unknown keys and invalid limits are errors.
+func (l Limits) Apply(data []byte) (Limits, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&l); err != nil {
+		return Limits{}, fmt.Errorf("invalid limits: %w", err)
+	}
+	if err := l.Validate(); err != nil {
+		return Limits{}, err
+	}
+	return l, nil
+}
+
+// LoadLimits returns base with the limits set in the JSON file at path
+// replaced. An empty path returns base.
+func LoadLimits(path string, base Limits) (Limits, error) {
+	if path == "" {
+		return base, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Limits{}, fmt.Errorf("failed to read limits file: %w", err)
+	}
+	return base.Apply(data)
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testLimits() Limits {
+	return Limits{MaxFileSize: 1024, MaxBatchItems: 100, AnalysisTimeout: 60, MaxAnalysisTimeout: 1800}
+}
+
+func TestLimits_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(l *Limits)
+		wantErr string
+	}{
+		{name: "valid", modify: func(l *Limits) {}},
+		{name: "zero file size", modify: func(l *Limits) { l.MaxFileSize = 0 }, wantErr: "MAX_FILE_SIZE"},
+		{name: "zero batch items", modify: func(l *Limits) { l.MaxBatchItems = 0 }, wantErr: "MAX_BATCH_ITEMS"},
+		{name: "zero timeout", modify: func(l *Limits) { l.AnalysisTimeout = 0 }, wantErr: "ANALYSIS_TIMEOUT must be greater than 0"},
+		{name: "max below default", modify: func(l *Limits) { l.MaxAnalysisTimeout = 30 }, wantErr: "MAX_ANALYSIS_TIMEOUT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limits := testLimits()
+			tt.modify(&limits)
+			err := limits.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLimits_Apply(t *testing.T) {
+	limits, err := testLimits().Apply([]byte(`{"max_batch_items": 500, "analysis_timeout": 120}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Limits{MaxFileSize: 1024, MaxBatchItems: 500, AnalysisTimeout: 120, MaxAnalysisTimeout: 1800}
+	if limits != want {
+		t.Errorf("Apply() = %+v, want %+v", limits, want)
+	}
+
+	if _, err := testLimits().Apply([]byte(`{"max_batch_itms": 500}`)); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+	if _, err := testLimits().Apply([]byte(`{"max_file_size": -1}`)); err == nil {
+		t.Error("expected an error for an invalid limit")
+	}
+}
+
+func TestLoadLimits(t *testing.T) {
+	if limits, err := LoadLimits("", testLimits()); err != nil || limits != testLimits() {
+		t.Errorf("LoadLimits without a file = %+v, %v", limits, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "limits.json")
+	if err := os.WriteFile(path, []byte(`{"max_file_size": 2048}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	limits, err := LoadLimits(path, testLimits())
+	if err != nil || limits.MaxFileSize != 2048 || limits.MaxBatchItems != 100 {
+		t.Errorf("LoadLimits = %+v, %v", limits, err)
+	}
+
+	if _, err := LoadLimits(filepath.Join(t.TempDir(), "missing.json"), testLimits()); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestLimits_RegisterFlags(t *testing.T) {
+	limits := testLimits()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	limits.RegisterFlags(fs)
+	if err := fs.Parse([]string{"-max-batch-items", "250"}); err != nil {
+		t.Fatal(err)
+	}
+	if limits.MaxBatchItems != 250 || limits.MaxFileSize != 1024 {
+		t.Errorf("flags should override only the limits given: got %+v", limits)
+	}
+}
@@ -0,0 +1,253 @@
+// Package coordination shares batch job state, progress events and locks
+// between API replicas through Redis (or Valkey), so any replica can answer
+// for a job that another replica runs.
+package coordination
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+)
+
+var (
+	// ErrJobNotFound is returned when no replica has shared the job
+	ErrJobNotFound = errors.New("job not found")
+
+	// ErrLockHeld is returned when another replica holds the lock
+	ErrLockHeld = errors.New("lock held by another replica")
+
+	// ErrLockLost is returned when a lock expired or was taken over before
+	// it was refreshed or released
+	ErrLockLost = errors.New("lock no longer held")
+)
+
+const (
+	defaultKeyPrefix = "rendiff-probe"
+	defaultJobTTL    = 24 * time.Hour
+
+	// subscriptionBufferSize is how many events a slow subscriber may fall
+	// behind before events are dropped
+	subscriptionBufferSize = 64
+)
+
+// Lock scripts compare the token first so a replica never releases or
+// extends a lock that expired and was taken by another replica
+var (
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	refreshScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// Config configures the connection and key layout
+type Config struct {
+	Addr     string // host:port
+	Password string
+	DB       int
+
+	// KeyPrefix namespaces every key and channel, so deployments can share
+	// a server. Defaults to "rendiff-probe".
+	KeyPrefix string
+
+	// JobTTL is how long a job snapshot is kept after its last update.
+	// Defaults to 24 hours.
+	JobTTL time.Duration
+}
+
+// Coordinator shares job state between replicas
+type Coordinator struct {
+	client *redis.Client
+	prefix string
+	jobTTL time.Duration
+	logger zerolog.Logger
+}
+
+// New connects to the server and verifies it answers
+func New(ctx context.Context, cfg Config, logger zerolog.Logger) (*Coordinator, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", cfg.Addr, err)
+	}
+
+	c := &Coordinator{
+		client: client,
+		prefix: cfg.KeyPrefix,
+		jobTTL: cfg.JobTTL,
+		logger: logger.With().Str("component", "coordination").Logger(),
+	}
+	if c.prefix == "" {
+		c.prefix = defaultKeyPrefix
+	}
+	if c.jobTTL <= 0 {
+		c.jobTTL = defaultJobTTL
+	}
+	return c, nil
+}
+
+// Close closes the connection
+func (c *Coordinator) Close() error {
+	return c.client.Close()
+}
+
+func (c *Coordinator) key(kind, id string) string {
+	return c.prefix + ":" + kind + ":" + id
+}
+
+// SaveJob shares a job snapshot. The snapshot layout belongs to the caller.
+func (c *Coordinator) SaveJob(ctx context.Context, jobID string, state []byte) error {
+	if err := c.client.Set(ctx, c.key("job", jobID), state, c.jobTTL).Err(); err != nil {
+		return fmt.Errorf("failed to share job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+// LoadJob returns the last snapshot shared for a job, or ErrJobNotFound
+func (c *Coordinator) LoadJob(ctx context.Context, jobID string) ([]byte, error) {
+	state, err := c.client.Get(ctx, c.key("job", jobID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job %s: %w", jobID, err)
+	}
+	return state, nil
+}
+
+// DeleteJob removes a shared job snapshot
+func (c *Coordinator) DeleteJob(ctx context.Context, jobID string) error {
+	if err := c.client.Del(ctx, c.key("job", jobID)).Err(); err != nil {
+		return fmt.Errorf("failed to delete job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+// PublishProgress sends a progress event to the subscribers of the job on
+// every replica
+func (c *Coordinator) PublishProgress(ctx context.Context, jobID string, event []byte) error {
+	if err := c.client.Publish(ctx, c.key("progress", jobID), event).Err(); err != nil {
+		return fmt.Errorf("failed to publish progress for job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+// SubscribeProgress returns the progress events published for the job by
+// any replica. The channel is closed once ctx is done or the returned
+// function is called, which must happen to release the subscription.
+func (c *Coordinator) SubscribeProgress(ctx context.Context, jobID string) (<-chan []byte, func(), error) {
+	pubsub := c.client.Subscribe(ctx, c.key("progress", jobID))
+	// Wait for the confirmation so no event published after this returns is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, nil, fmt.Errorf("failed to subscribe to job %s: %w", jobID, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	events := make(chan []byte, subscriptionBufferSize)
+	messages := pubsub.Channel()
+	go func() {
+		defer close(events)
+		defer pubsub.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case message, ok := <-messages:
+				if !ok {
+					return
+				}
+				select {
+				case events <- []byte(message.Payload):
+				default:
+					c.logger.Debug().Str("job_id", jobID).Msg("Dropping progress event for slow subscriber")
+				}
+			}
+		}
+	}()
+	return events, cancel, nil
+}
+
+// Lock is a lock held by this replica until it is released or expires
+type Lock struct {
+	c     *Coordinator
+	key   string
+	token string
+	ttl   time.Duration
+}
+
+// AcquireLock takes the named lock for ttl, or returns ErrLockHeld if
+// another replica holds it. Holders refresh the lock before ttl passes.
+func (c *Coordinator) AcquireLock(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	token, err := newLockToken()
+	if err != nil {
+		return nil, err
+	}
+
+	key := c.key("lock", name)
+	acquired, err := c.client.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	if !acquired {
+		return nil, ErrLockHeld
+	}
+	return &Lock{c: c, key: key, token: token, ttl: ttl}, nil
+}
+
+// Locked reports whether any replica holds the named lock
+func (c *Coordinator) Locked(ctx context.Context, name string) (bool, error) {
+	n, err := c.client.Exists(ctx, c.key("lock", name)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check lock %s: %w", name, err)
+	}
+	return n > 0, nil
+}
+
+// Refresh extends the lock by its ttl, or returns ErrLockLost
+func (l *Lock) Refresh(ctx context.Context) error {
+	n, err := refreshScript.Run(ctx, l.c.client, []string{l.key}, l.token, l.ttl.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to refresh lock: %w", err)
+	}
+	if n == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release gives up the lock, or returns ErrLockLost if it was no longer held
+func (l *Lock) Release(ctx context.Context) error {
+	n, err := releaseScript.Run(ctx, l.c.client, []string{l.key}, l.token).Int()
+	if err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	if n == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+func newLockToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	return hex.EncodeToString(token), nil
+}
@@ -0,0 +1,132 @@
+package coordination
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/rs/zerolog"
+)
+
+func newTestCoordinator(t *testing.T) (*Coordinator, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	c, err := New(context.Background(), Config{Addr: server.Addr(), JobTTL: time.Minute}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, server
+}
+
+func TestCoordinatorJobs(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestCoordinator(t)
+
+	if _, err := c.LoadJob(ctx, "job-1"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("LoadJob of a missing job = %v, want ErrJobNotFound", err)
+	}
+
+	if err := c.SaveJob(ctx, "job-1", []byte(`{"status":"processing"}`)); err != nil {
+		t.Fatalf("SaveJob: %v", err)
+	}
+	state, err := c.LoadJob(ctx, "job-1")
+	if err != nil || string(state) != `{"status":"processing"}` {
+		t.Fatalf("LoadJob = %s, %v", state, err)
+	}
+	if ttl := server.TTL("rendiff-probe:job:job-1"); ttl != time.Minute {
+		t.Errorf("job TTL = %v, want 1m", ttl)
+	}
+
+	if err := c.DeleteJob(ctx, "job-1"); err != nil {
+		t.Fatalf("DeleteJob: %v", err)
+	}
+	if _, err := c.LoadJob(ctx, "job-1"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("LoadJob after delete = %v, want ErrJobNotFound", err)
+	}
+}
+
+func TestCoordinatorProgress(t *testing.T) {
+	ctx := context.Background()
+	c, _ := newTestCoordinator(t)
+
+	events, unsubscribe, err := c.SubscribeProgress(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("SubscribeProgress: %v", err)
+	}
+
+	if err := c.PublishProgress(ctx, "job-2", []byte("other")); err != nil {
+		t.Fatalf("PublishProgress: %v", err)
+	}
+	if err := c.PublishProgress(ctx, "job-1", []byte("50%")); err != nil {
+		t.Fatalf("PublishProgress: %v", err)
+	}
+
+	select {
+	case event := <-events:
+		if string(event) != "50%" {
+			t.Errorf("event = %q, want 50%%", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no progress event received")
+	}
+
+	unsubscribe()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("received an event after unsubscribing")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("events channel not closed after unsubscribing")
+	}
+}
+
+func TestCoordinatorLocks(t *testing.T) {
+	ctx := context.Background()
+	c, server := newTestCoordinator(t)
+
+	lock, err := c.AcquireLock(ctx, "batch:job-1", 10*time.Second)
+	if err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+	if _, err := c.AcquireLock(ctx, "batch:job-1", 10*time.Second); !errors.Is(err, ErrLockHeld) {
+		t.Errorf("second AcquireLock = %v, want ErrLockHeld", err)
+	}
+	if locked, err := c.Locked(ctx, "batch:job-1"); err != nil || !locked {
+		t.Errorf("Locked = %v, %v, want true", locked, err)
+	}
+
+	server.FastForward(5 * time.Second)
+	if err := lock.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if ttl := server.TTL("rendiff-probe:lock:batch:job-1"); ttl != 10*time.Second {
+		t.Errorf("lock TTL after refresh = %v, want 10s", ttl)
+	}
+
+	if err := lock.Release(ctx); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if locked, _ := c.Locked(ctx, "batch:job-1"); locked {
+		t.Error("lock still held after release")
+	}
+
+	// A lock that expired and was taken over is not released by its old holder
+	expired, err := c.AcquireLock(ctx, "batch:job-2", time.Second)
+	if err != nil {
+		t.Fatalf("AcquireLock: %v", err)
+	}
+	server.FastForward(2 * time.Second)
+	if _, err := c.AcquireLock(ctx, "batch:job-2", time.Minute); err != nil {
+		t.Fatalf("AcquireLock after expiry: %v", err)
+	}
+	if err := expired.Release(ctx); !errors.Is(err, ErrLockLost) {
+		t.Errorf("Release of an expired lock = %v, want ErrLockLost", err)
+	}
+	if locked, _ := c.Locked(ctx, "batch:job-2"); !locked {
+		t.Error("expired holder released the new holder's lock")
+	}
+}
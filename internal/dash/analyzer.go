@@ -0,0 +1,659 @@
+package dash
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// maxManifestSize bounds the MPD download
+	maxManifestSize = 10 * 1024 * 1024
+	// maxSegmentSize bounds each sampled init/media segment download
+	maxSegmentSize = 50 * 1024 * 1024
+)
+
+// DASHAnalyzer performs DASH manifest analysis
+type DASHAnalyzer struct {
+	parser       *DASHParser
+	httpClient   *http.Client
+	ffprobe      *ffmpeg.FFprobe
+	urlValidator func(string) error
+	logger       zerolog.Logger
+}
+
+// NewDASHAnalyzer creates a new DASH analyzer
+func NewDASHAnalyzer(logger zerolog.Logger) *DASHAnalyzer {
+	return &DASHAnalyzer{
+		parser:     NewDASHParser(logger),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		logger:     logger,
+	}
+}
+
+// SetHTTPClient sets a custom HTTP client
+func (a *DASHAnalyzer) SetHTTPClient(client *http.Client) {
+	a.httpClient = client
+}
+
+// SetFFprobe sets the ffprobe instance used to probe sample segments.
+// Without it, segment probing only records segment sizes.
+func (a *DASHAnalyzer) SetFFprobe(ffprobe *ffmpeg.FFprobe) {
+	a.ffprobe = ffprobe
+}
+
+// SetURLValidator sets a validator applied to every segment URL before it is
+// fetched. Segment URLs come from the manifest and may point to other hosts.
+func (a *DASHAnalyzer) SetURLValidator(validator func(string) error) {
+	a.urlValidator = validator
+}
+
+// AnalyzeDASH performs DASH manifest analysis
+func (a *DASHAnalyzer) AnalyzeDASH(ctx context.Context, request *DASHAnalysisRequest) (*DASHAnalysisResult, error) {
+	startTime := time.Now()
+
+	a.logger.Info().
+		Str("manifest_url", request.ManifestURL).
+		Bool("probe_segments", request.ProbeSegments).
+		Bool("analyze_quality", request.AnalyzeQuality).
+		Bool("validate_compliance", request.ValidateCompliance).
+		Bool("inspect_encryption", request.InspectEncryption).
+		Msg("Starting DASH analysis")
+
+	result := &DASHAnalysisResult{
+		ID:     uuid.New(),
+		Status: DASHStatusProcessing,
+	}
+
+	analysis, err := a.fetchAndParseManifest(ctx, request.ManifestURL)
+	if err != nil {
+		a.logger.Error().Err(err).Msg("Failed to fetch and parse manifest")
+		result.Status = DASHStatusFailed
+		result.Error = err.Error()
+		return result, err
+	}
+
+	analysis.AnalysisID = result.ID
+
+	a.analyzeEncryption(ctx, analysis, request.InspectEncryption)
+
+	if request.ProbeSegments {
+		a.probeSegments(ctx, analysis, request.MaxSegments)
+	}
+
+	if request.AnalyzeQuality {
+		a.analyzeQualityLadder(analysis)
+	}
+
+	if request.ValidateCompliance {
+		a.validateCompliance(analysis)
+	}
+
+	analysis.ProcessingTime = time.Since(startTime)
+	analysis.Status = DASHStatusCompleted
+	analysis.UpdatedAt = time.Now()
+	completedAt := time.Now()
+	analysis.CompletedAt = &completedAt
+
+	result.Status = DASHStatusCompleted
+	result.Analysis = analysis
+	result.ProcessingTime = analysis.ProcessingTime
+	result.Message = "DASH analysis completed successfully"
+
+	a.logger.Info().
+		Str("analysis_id", result.ID.String()).
+		Int("representations", len(analysis.Representations)).
+		Dur("processing_time", result.ProcessingTime).
+		Msg("DASH analysis completed")
+
+	return result, nil
+}
+
+// fetchAndParseManifest fetches and parses the MPD
+func (a *DASHAnalyzer) fetchAndParseManifest(ctx context.Context, manifestURL string) (*DASHAnalysis, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", manifestURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch manifest: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch manifest: HTTP %d", resp.StatusCode)
+	}
+
+	// Resolve relative URLs against the final location after redirects
+	baseURL := manifestURL
+	if resp.Request != nil && resp.Request.URL != nil {
+		baseURL = resp.Request.URL.String()
+	}
+
+	analysis, err := a.parser.ParseManifest(io.LimitReader(resp.Body, maxManifestSize), baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	analysis.ManifestURL = manifestURL
+
+	return analysis, nil
+}
+
+// probeSegments probes up to maxSegments media segments of every representation
+func (a *DASHAnalyzer) probeSegments(ctx context.Context, analysis *DASHAnalysis, maxSegments int) {
+	if maxSegments <= 0 {
+		maxSegments = 1
+	}
+
+	for _, rep := range analysis.Representations {
+		urls := rep.MediaURLs
+		if len(urls) > maxSegments {
+			urls = urls[:maxSegments]
+		}
+
+		for _, mediaURL := range urls {
+			if ctx.Err() != nil {
+				return
+			}
+
+			sample := a.probeSegment(ctx, rep, mediaURL)
+			if sample.Error != "" {
+				a.logger.Warn().
+					Str("representation_id", rep.ID).
+					Str("segment_url", mediaURL).
+					Str("error", sample.Error).
+					Msg("Failed to probe segment")
+			}
+			rep.SampledSegments = append(rep.SampledSegments, sample)
+		}
+	}
+}
+
+// probeSegment downloads a media segment (prefixed by its initialization
+// segment for fragmented MP4) and probes the result with ffprobe
+func (a *DASHAnalyzer) probeSegment(ctx context.Context, rep *DASHRepresentation, mediaURL string) *DASHSegmentSample {
+	sample := &DASHSegmentSample{URL: mediaURL}
+
+	tmpFile, err := os.CreateTemp("", "dash_segment_*")
+	if err != nil {
+		sample.Error = fmt.Sprintf("failed to create temp file: %v", err)
+		return sample
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	if rep.InitializationURL != "" && rep.AddressingMode != "segment_base" {
+		if _, err := a.downloadSegment(ctx, rep.InitializationURL, tmpFile); err != nil {
+			sample.Error = fmt.Sprintf("initialization segment: %v", err)
+			return sample
+		}
+	}
+
+	size, err := a.downloadSegment(ctx, mediaURL, tmpFile)
+	if err != nil {
+		sample.Error = err.Error()
+		return sample
+	}
+	sample.Size = size
+	sample.Duration = rep.SegmentDuration
+	if rep.SegmentDuration > 0 && rep.AddressingMode != "segment_base" {
+		sample.Bitrate = int(float64(size*8) / rep.SegmentDuration)
+	}
+
+	if a.ffprobe == nil {
+		return sample
+	}
+
+	options := ffmpeg.NewOptionsBuilder().
+		Input(tmpFile.Name()).
+		JSON().
+		ShowFormat().
+		ShowStreams().
+		MetadataOnly().
+		Build()
+
+	probeResult, err := a.ffprobe.Probe(ctx, options)
+	if err != nil {
+		sample.Error = fmt.Sprintf("ffprobe failed: %v", err)
+		return sample
+	}
+
+	if probeResult.Format != nil {
+		sample.FormatName = probeResult.Format.FormatName
+		if d, err := strconv.ParseFloat(probeResult.Format.Duration, 64); err == nil && d > 0 {
+			sample.Duration = d
+			sample.Bitrate = int(float64(size*8) / d)
+		}
+	}
+	for _, stream := range probeResult.Streams {
+		if stream.CodecType == rep.ContentType || len(probeResult.Streams) == 1 {
+			sample.CodecName = stream.CodecName
+			sample.Width = stream.Width
+			sample.Height = stream.Height
+			break
+		}
+	}
+
+	return sample
+}
+
+// downloadSegment appends a segment to w, returning the number of bytes written
+func (a *DASHAnalyzer) downloadSegment(ctx context.Context, segmentURL string, w io.Writer) (int64, error) {
+	if a.urlValidator != nil {
+		if err := a.urlValidator(segmentURL); err != nil {
+			return 0, fmt.Errorf("segment URL rejected: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", segmentURL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch segment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("failed to fetch segment: HTTP %d", resp.StatusCode)
+	}
+
+	written, err := io.Copy(w, io.LimitReader(resp.Body, maxSegmentSize+1))
+	if err != nil {
+		return written, fmt.Errorf("failed to read segment: %w", err)
+	}
+	if written > maxSegmentSize {
+		return written, fmt.Errorf("segment exceeds %d bytes", maxSegmentSize)
+	}
+
+	return written, nil
+}
+
+// analyzeQualityLadder analyzes the bitrate ladder of the video representations
+func (a *DASHAnalyzer) analyzeQualityLadder(analysis *DASHAnalysis) {
+	var video []*DASHRepresentation
+	for _, rep := range analysis.Representations {
+		if rep.ContentType == "video" {
+			video = append(video, rep)
+		}
+	}
+	if len(video) == 0 {
+		return
+	}
+
+	sort.Slice(video, func(i, j int) bool {
+		return video[i].Bandwidth < video[j].Bandwidth
+	})
+
+	ladder := &DASHQualityLadder{
+		RepresentationCount: len(video),
+		MinBandwidth:        video[0].Bandwidth,
+		MaxBandwidth:        video[len(video)-1].Bandwidth,
+		CodecDistribution:   make(map[string]int),
+		QualityGaps:         make([]*DASHQualityGap, 0),
+	}
+
+	total := 0
+	for i, rep := range video {
+		total += rep.Bandwidth
+		if rep.Codecs != "" {
+			ladder.CodecDistribution[rep.Codecs]++
+		}
+		if rep.Height > 0 {
+			if ladder.MinHeight == 0 || rep.Height < ladder.MinHeight {
+				ladder.MinHeight = rep.Height
+			}
+			if rep.Height > ladder.MaxHeight {
+				ladder.MaxHeight = rep.Height
+			}
+		}
+
+		if i == 0 || video[i-1].Bandwidth <= 0 {
+			continue
+		}
+		lower := video[i-1]
+		ratio := float64(rep.Bandwidth) / float64(lower.Bandwidth)
+		if ratio > 2.0 {
+			gap := &DASHQualityGap{
+				LowerID:        lower.ID,
+				UpperID:        rep.ID,
+				LowerBandwidth: lower.Bandwidth,
+				UpperBandwidth: rep.Bandwidth,
+				Ratio:          ratio,
+				Severity:       "medium",
+			}
+			if ratio > 3.0 {
+				gap.Severity = "high"
+			}
+			ladder.QualityGaps = append(ladder.QualityGaps, gap)
+		}
+	}
+	ladder.AverageBandwidth = float64(total) / float64(len(video))
+	ladder.Recommendations = a.generateQualityRecommendations(ladder)
+
+	analysis.QualityLadder = ladder
+}
+
+func (a *DASHAnalyzer) generateQualityRecommendations(ladder *DASHQualityLadder) []string {
+	recommendations := make([]string, 0)
+
+	if ladder.RepresentationCount < 3 {
+		recommendations = append(recommendations, "Consider adding more video representations for better adaptive streaming")
+	}
+	if ladder.MinBandwidth > 500000 { // 500 kbps
+		recommendations = append(recommendations, "Consider adding a lower bandwidth representation for poor network conditions")
+	}
+	if ladder.MaxBandwidth < 2000000 { // 2 Mbps
+		recommendations = append(recommendations, "Consider adding a higher bandwidth representation for better quality")
+	}
+	if len(ladder.QualityGaps) > 0 {
+		recommendations = append(recommendations, "Large bandwidth gaps detected - consider adding intermediate representations")
+	}
+	if len(ladder.CodecDistribution) > 2 {
+		recommendations = append(recommendations, "Multiple codecs detected - ensure client compatibility")
+	}
+
+	if len(recommendations) == 0 {
+		recommendations = append(recommendations, "Quality ladder appears well-configured")
+	}
+
+	return recommendations
+}
+
+// validateCompliance validates the MPD against ISO/IEC 23009-1 and DASH-IF IOP rules
+func (a *DASHAnalyzer) validateCompliance(analysis *DASHAnalysis) {
+	validation := &DASHValidationResults{
+		Errors:   make([]*DASHValidationIssue, 0),
+		Warnings: make([]*DASHValidationIssue, 0),
+	}
+
+	mpd := analysis.MPD
+	if mpd == nil {
+		validation.Errors = append(validation.Errors, &DASHValidationIssue{
+			Code:    "MISSING_MPD",
+			Message: "MPD is missing",
+		})
+	} else {
+		a.validateMPD(mpd, validation)
+		a.validatePeriods(mpd, validation)
+	}
+	a.validateRepresentations(analysis.Representations, validation)
+
+	compliance := &DASHComplianceCheck{
+		Profiles:          analysis.Profiles,
+		ISO23009Compliant: len(validation.Errors) == 0,
+	}
+	for _, profile := range analysis.Profiles {
+		switch profile {
+		case ProfileISOFFLive:
+			compliance.LiveProfile = true
+		case ProfileISOFFOnDemand:
+			compliance.OnDemandProfile = true
+		case ProfileCMAF:
+			compliance.CMAFProfile = true
+		}
+	}
+	// DASH-IF IOP builds on the live and on-demand profiles and expects aligned segments
+	compliance.DASHIFCompliant = compliance.ISO23009Compliant &&
+		(compliance.LiveProfile || compliance.OnDemandProfile || compliance.CMAFProfile) &&
+		!hasIssue(validation.Warnings, "MISSING_SEGMENT_ALIGNMENT")
+
+	validation.Compliance = compliance
+	validation.IsValid = len(validation.Errors) == 0
+	validation.Summary = a.generateValidationSummary(validation)
+
+	analysis.ValidationResults = validation
+}
+
+func (a *DASHAnalyzer) validateMPD(mpd *MPD, validation *DASHValidationResults) {
+	if mpd.Type != "" && mpd.Type != string(PresentationStatic) && mpd.Type != string(PresentationDynamic) {
+		validation.Errors = append(validation.Errors, &DASHValidationIssue{
+			Code:    "INVALID_MPD_TYPE",
+			Message: fmt.Sprintf("MPD@type must be static or dynamic, got %q", mpd.Type),
+			Element: "MPD",
+		})
+	}
+
+	profiles := splitList(mpd.Profiles, ",")
+	if len(profiles) == 0 {
+		validation.Errors = append(validation.Errors, &DASHValidationIssue{
+			Code:       "MISSING_PROFILES",
+			Message:    "MPD@profiles is required",
+			Element:    "MPD",
+			Suggestion: "Declare at least one profile, e.g. " + ProfileISOFFLive,
+		})
+	} else if !containsKnownProfile(profiles) {
+		validation.Warnings = append(validation.Warnings, &DASHValidationIssue{
+			Code:    "UNKNOWN_PROFILES",
+			Message: fmt.Sprintf("No well-known DASH profile declared: %s", mpd.Profiles),
+			Element: "MPD",
+		})
+	}
+
+	if _, err := ParseISODuration(mpd.MinBufferTime); err != nil {
+		validation.Errors = append(validation.Errors, &DASHValidationIssue{
+			Code:    "MISSING_MIN_BUFFER_TIME",
+			Message: "MPD@minBufferTime is required and must be an ISO 8601 duration",
+			Element: "MPD",
+		})
+	}
+
+	if mpd.Type == string(PresentationDynamic) {
+		if mpd.AvailabilityStartTime == "" {
+			validation.Errors = append(validation.Errors, &DASHValidationIssue{
+				Code:    "MISSING_AVAILABILITY_START_TIME",
+				Message: "Dynamic MPDs require MPD@availabilityStartTime",
+				Element: "MPD",
+			})
+		}
+		if mpd.MinimumUpdatePeriod == "" {
+			validation.Warnings = append(validation.Warnings, &DASHValidationIssue{
+				Code:       "MISSING_MINIMUM_UPDATE_PERIOD",
+				Message:    "Dynamic MPD has no MPD@minimumUpdatePeriod; clients will not refresh the manifest",
+				Element:    "MPD",
+				Suggestion: "Set minimumUpdatePeriod unless the presentation is about to end",
+			})
+		}
+	} else if mpd.MediaPresentationDuration == "" {
+		lastPeriodHasDuration := len(mpd.Periods) > 0 && mpd.Periods[len(mpd.Periods)-1].Duration != ""
+		if !lastPeriodHasDuration {
+			validation.Errors = append(validation.Errors, &DASHValidationIssue{
+				Code:    "MISSING_PRESENTATION_DURATION",
+				Message: "Static MPDs require MPD@mediaPresentationDuration or a duration on the last Period",
+				Element: "MPD",
+			})
+		}
+	}
+
+	if len(mpd.Periods) == 0 {
+		validation.Errors = append(validation.Errors, &DASHValidationIssue{
+			Code:    "NO_PERIODS",
+			Message: "MPD must contain at least one Period",
+			Element: "MPD",
+		})
+	}
+}
+
+func (a *DASHAnalyzer) validatePeriods(mpd *MPD, validation *DASHValidationResults) {
+	for i, period := range mpd.Periods {
+		periodElement := fmt.Sprintf("Period[%d]", i)
+
+		if len(period.AdaptationSets) == 0 {
+			validation.Errors = append(validation.Errors, &DASHValidationIssue{
+				Code:    "NO_ADAPTATION_SETS",
+				Message: fmt.Sprintf("Period %d contains no AdaptationSet", i),
+				Element: periodElement,
+			})
+		}
+
+		for j, as := range period.AdaptationSets {
+			element := fmt.Sprintf("%s/AdaptationSet[%d]", periodElement, j)
+			contentType := contentTypeOf(as.ContentType, as.MimeType, as.Codecs)
+			if contentType == "" && len(as.Representations) > 0 {
+				contentType = contentTypeOf("", as.Representations[0].MimeType, as.Representations[0].Codecs)
+			}
+
+			if len(as.Representations) == 0 {
+				validation.Errors = append(validation.Errors, &DASHValidationIssue{
+					Code:    "NO_REPRESENTATIONS",
+					Message: "AdaptationSet contains no Representation",
+					Element: element,
+				})
+			}
+
+			if contentType == "video" && len(as.Representations) > 1 &&
+				as.SegmentAlignment != "true" && as.SegmentAlignment != "1" {
+				validation.Warnings = append(validation.Warnings, &DASHValidationIssue{
+					Code:       "MISSING_SEGMENT_ALIGNMENT",
+					Message:    "Video AdaptationSet does not declare segmentAlignment",
+					Element:    element,
+					Suggestion: "Set segmentAlignment=\"true\" to allow seamless switching",
+				})
+			}
+
+			if contentType == "audio" && as.Lang == "" {
+				validation.Warnings = append(validation.Warnings, &DASHValidationIssue{
+					Code:    "MISSING_LANGUAGE",
+					Message: "Audio AdaptationSet has no lang attribute",
+					Element: element,
+				})
+			}
+
+			templates := []*SegmentTemplate{period.SegmentTemplate, as.SegmentTemplate}
+			for _, rep := range as.Representations {
+				templates = append(templates, rep.SegmentTemplate)
+			}
+			for _, tmpl := range templates {
+				if tmpl == nil {
+					continue
+				}
+				if strings.Contains(tmpl.Media, "$Number") && strings.Contains(tmpl.Media, "$Time") {
+					validation.Errors = append(validation.Errors, &DASHValidationIssue{
+						Code:    "INVALID_SEGMENT_TEMPLATE",
+						Message: "SegmentTemplate@media must not use both $Number$ and $Time$",
+						Element: element,
+					})
+				}
+				if tmpl.Duration > 0 && tmpl.SegmentTimeline != nil {
+					validation.Errors = append(validation.Errors, &DASHValidationIssue{
+						Code:    "CONFLICTING_SEGMENT_TIMING",
+						Message: "SegmentTemplate must not carry both @duration and SegmentTimeline",
+						Element: element,
+					})
+				}
+			}
+		}
+	}
+}
+
+func (a *DASHAnalyzer) validateRepresentations(representations []*DASHRepresentation, validation *DASHValidationResults) {
+	seen := make(map[string]bool)
+
+	for _, rep := range representations {
+		element := fmt.Sprintf("Representation[@id=%q]", rep.ID)
+
+		if rep.ID == "" || strings.ContainsAny(rep.ID, " \t\r\n") {
+			validation.Errors = append(validation.Errors, &DASHValidationIssue{
+				Code:    "INVALID_REPRESENTATION_ID",
+				Message: "Representation@id is required and must not contain whitespace",
+				Element: element,
+			})
+		}
+		key := rep.PeriodID + "/" + rep.ID
+		if seen[key] {
+			validation.Errors = append(validation.Errors, &DASHValidationIssue{
+				Code:    "DUPLICATE_REPRESENTATION_ID",
+				Message: fmt.Sprintf("Representation id %q is not unique within its Period", rep.ID),
+				Element: element,
+			})
+		}
+		seen[key] = true
+
+		if rep.Bandwidth <= 0 {
+			validation.Errors = append(validation.Errors, &DASHValidationIssue{
+				Code:    "INVALID_BANDWIDTH",
+				Message: fmt.Sprintf("Representation has invalid bandwidth: %d", rep.Bandwidth),
+				Element: element,
+			})
+		}
+
+		if rep.AddressingMode == "none" {
+			validation.Errors = append(validation.Errors, &DASHValidationIssue{
+				Code:       "MISSING_SEGMENT_INFORMATION",
+				Message:    "Representation has no SegmentTemplate, SegmentList, SegmentBase or BaseURL",
+				Element:    element,
+				Suggestion: "Add a SegmentTemplate on the Representation or its AdaptationSet",
+			})
+		}
+
+		if rep.MimeType == "" {
+			validation.Errors = append(validation.Errors, &DASHValidationIssue{
+				Code:    "MISSING_MIME_TYPE",
+				Message: "mimeType is not set on the Representation or its AdaptationSet",
+				Element: element,
+			})
+		}
+
+		if rep.Codecs == "" {
+			validation.Warnings = append(validation.Warnings, &DASHValidationIssue{
+				Code:    "MISSING_CODECS",
+				Message: "codecs is not set on the Representation or its AdaptationSet",
+				Element: element,
+			})
+		}
+
+		if rep.ContentType == "video" && (rep.Width == 0 || rep.Height == 0) {
+			validation.Warnings = append(validation.Warnings, &DASHValidationIssue{
+				Code:    "MISSING_RESOLUTION",
+				Message: "Video Representation does not declare width and height",
+				Element: element,
+			})
+		}
+	}
+}
+
+func (a *DASHAnalyzer) generateValidationSummary(validation *DASHValidationResults) string {
+	if validation.IsValid && len(validation.Warnings) == 0 {
+		return "DASH manifest is valid and compliant"
+	}
+	if validation.IsValid {
+		return fmt.Sprintf("DASH manifest is valid with %d warnings", len(validation.Warnings))
+	}
+
+	return fmt.Sprintf("DASH manifest has %d errors and %d warnings",
+		len(validation.Errors), len(validation.Warnings))
+}
+
+func containsKnownProfile(profiles []string) bool {
+	for _, profile := range profiles {
+		switch profile {
+		case ProfileISOFFLive, ProfileISOFFOnDemand, ProfileISOFFMain, ProfileFull,
+			ProfileCMAF, ProfileDASHIF264, ProfileDVB:
+			return true
+		}
+	}
+	return false
+}
+
+func hasIssue(issues []*DASHValidationIssue, code string) bool {
+	for _, issue := range issues {
+		if issue.Code == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package dash
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/rendiffdev/rendiff-probe/internal/drm"
+)
+
+// SchemeMP4Protection is the ContentProtection scheme that signals Common
+// Encryption; its value is the scheme type, e.g. cenc or cbcs
+const SchemeMP4Protection = "urn:mpeg:dash:mp4protection:2011"
+
+// maxEncryptionInitSegments bounds the init segments fetched to read their
+// protection boxes
+const maxEncryptionInitSegments = 10
+
+// contentProtections returns the ContentProtection descriptors that apply
+// to a Representation
+func contentProtections(as *AdaptationSet, rep *Representation) []*ContentProtection {
+	protections := make([]*ContentProtection, 0, len(as.ContentProtections)+len(rep.ContentProtections))
+	protections = append(protections, as.ContentProtections...)
+	return append(protections, rep.ContentProtections...)
+}
+
+// encryptionFromMPD summarizes the ContentProtection signalling of an MPD
+func encryptionFromMPD(mpd *MPD) *drm.Encryption {
+	encryption := &drm.Encryption{}
+	for _, period := range mpd.Periods {
+		for _, as := range period.AdaptationSets {
+			for _, rep := range as.Representations {
+				contentType := contentTypeOf(as.ContentType, firstNonEmpty(rep.MimeType, as.MimeType), firstNonEmpty(rep.Codecs, as.Codecs))
+				protections := contentProtections(as, rep)
+				if contentType == "video" || contentType == "audio" {
+					if len(protections) > 0 {
+						encryption.EncryptedRenditions++
+					} else {
+						encryption.ClearRenditions++
+					}
+				}
+				if len(protections) == 0 {
+					continue
+				}
+
+				hasDefaultKID := false
+				for _, cp := range protections {
+					addContentProtection(encryption, cp)
+					hasDefaultKID = hasDefaultKID || cp.DefaultKID != ""
+				}
+				if !hasDefaultKID {
+					encryption.AddIssue("Representation %s is protected but no ContentProtection carries cenc:default_KID", rep.ID)
+				}
+			}
+		}
+	}
+	return encryption
+}
+
+// addContentProtection records one ContentProtection descriptor
+func addContentProtection(encryption *drm.Encryption, cp *ContentProtection) {
+	encryption.AddDefaultKID(cp.DefaultKID)
+
+	scheme := strings.ToLower(cp.SchemeIDURI)
+	switch {
+	case scheme == SchemeMP4Protection:
+		if cp.Value != "" {
+			encryption.AddMethod(cp.Value)
+		} else {
+			encryption.AddMethod(drm.SchemeCENC)
+		}
+	case strings.HasPrefix(scheme, "urn:uuid:"):
+		systemID := strings.TrimPrefix(scheme, "urn:uuid:")
+		encryption.AddSystem(systemID, drm.SystemName(systemID), "")
+	}
+
+	if cp.PSSH == "" {
+		return
+	}
+	pssh, err := drm.ParsePSSHBase64(cp.PSSH)
+	if err != nil {
+		encryption.AddIssue("ContentProtection %s has an invalid cenc:pssh: %v", cp.SchemeIDURI, err)
+		return
+	}
+	encryption.AddPSSH(pssh, false)
+}
+
+// analyzeEncryption reports the encryption signalled by the manifest and,
+// when inspect is set, by the initialization segments of the representations
+func (a *DASHAnalyzer) analyzeEncryption(ctx context.Context, analysis *DASHAnalysis, inspect bool) {
+	encryption := encryptionFromMPD(analysis.MPD)
+	if inspect {
+		a.inspectInitSegments(ctx, analysis, encryption)
+	}
+	encryption.Finalize()
+	analysis.Encryption = encryption
+}
+
+// inspectInitSegments reads the protection boxes of the initialization
+// segments and checks them against the manifest
+func (a *DASHAnalyzer) inspectInitSegments(ctx context.Context, analysis *DASHAnalysis, encryption *drm.Encryption) {
+	inspected := make(map[string]bool)
+	for _, rep := range analysis.Representations {
+		if rep.InitializationURL == "" || rep.AddressingMode == "segment_base" || inspected[rep.InitializationURL] {
+			continue
+		}
+		if len(inspected) >= maxEncryptionInitSegments || ctx.Err() != nil {
+			return
+		}
+		inspected[rep.InitializationURL] = true
+
+		var buf bytes.Buffer
+		if _, err := a.downloadSegment(ctx, rep.InitializationURL, &buf); err != nil {
+			a.logger.Warn().Err(err).Str("representation_id", rep.ID).Msg("Failed to fetch initialization segment")
+			encryption.InitSegments = append(encryption.InitSegments, &drm.InitSegment{URL: rep.InitializationURL, Error: err.Error()})
+			continue
+		}
+		encryption.AddInitSegment(rep.InitializationURL, buf.Bytes())
+
+		segment := encryption.InitSegments[len(encryption.InitSegments)-1]
+		switch {
+		case segment.Error != "":
+		case rep.Encrypted && segment.Scheme == "":
+			encryption.AddIssue("Representation %s is signalled as protected but its initialization segment has no protection scheme", rep.ID)
+		case !rep.Encrypted && segment.Scheme != "":
+			encryption.AddIssue("Representation %s has no ContentProtection but its initialization segment is encrypted with %s", rep.ID, segment.Scheme)
+		case rep.ProtectionScheme != "" && !strings.EqualFold(rep.ProtectionScheme, segment.Scheme):
+			encryption.AddIssue("Representation %s signals scheme %s but its initialization segment uses %s", rep.ID, rep.ProtectionScheme, segment.Scheme)
+		}
+	}
+}
@@ -0,0 +1,506 @@
+package dash
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// maxListedSegments limits how many media segment URLs are expanded per representation
+const maxListedSegments = 10
+
+// maxTimelineSegments bounds the segments a SegmentTimeline may describe per
+// representation, a year of one-second segments being far beyond any real
+// presentation
+const maxTimelineSegments = 1 << 25
+
+var (
+	isoDurationPattern  = regexp.MustCompile(`^P(?:(\d+(?:\.\d+)?)Y)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+	templateIdentifiers = regexp.MustCompile(`\$(RepresentationID|Number|Bandwidth|Time)(%0(\d+)d)?\$`)
+)
+
+// DASHParser handles parsing of DASH MPD manifests
+type DASHParser struct {
+	logger zerolog.Logger
+}
+
+// NewDASHParser creates a new DASH parser
+func NewDASHParser(logger zerolog.Logger) *DASHParser {
+	return &DASHParser{
+		logger: logger,
+	}
+}
+
+// ParseManifest parses a DASH MPD from a reader. baseURL is the manifest
+// location and is used to resolve relative BaseURL and segment URLs.
+func (p *DASHParser) ParseManifest(reader io.Reader, baseURL string) (*DASHAnalysis, error) {
+	var mpd MPD
+	decoder := xml.NewDecoder(reader)
+	if err := decoder.Decode(&mpd); err != nil {
+		return nil, fmt.Errorf("invalid MPD format: %w", err)
+	}
+
+	presentationType := PresentationDynamic
+	if mpd.Type == "" || mpd.Type == string(PresentationStatic) {
+		presentationType = PresentationStatic
+	}
+
+	analysis := &DASHAnalysis{
+		ID:               uuid.New(),
+		ManifestURL:      baseURL,
+		PresentationType: presentationType,
+		Profiles:         splitList(mpd.Profiles, ","),
+		Periods:          make([]*DASHPeriod, 0, len(mpd.Periods)),
+		Representations:  make([]*DASHRepresentation, 0),
+		MPD:              &mpd,
+		Status:           DASHStatusProcessing,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	if d, err := ParseISODuration(mpd.MediaPresentationDuration); err == nil {
+		analysis.Duration = d
+	}
+	if d, err := ParseISODuration(mpd.MinBufferTime); err == nil {
+		analysis.MinBufferTime = d
+	}
+
+	mpdBase := p.resolveBaseURL(baseURL, mpd.BaseURLs)
+
+	for i, period := range mpd.Periods {
+		info := &DASHPeriod{
+			ID:             period.ID,
+			AdaptationSets: make([]*DASHAdaptationSetInfo, 0, len(period.AdaptationSets)),
+		}
+		if start, err := ParseISODuration(period.Start); err == nil {
+			info.Start = start
+		}
+		info.Duration = p.periodDuration(&mpd, i, info.Start, analysis.Duration)
+
+		periodBase := p.resolveBaseURL(mpdBase, period.BaseURLs)
+
+		for _, as := range period.AdaptationSets {
+			asInfo := p.summarizeAdaptationSet(as)
+			asBase := p.resolveBaseURL(periodBase, as.BaseURLs)
+
+			for _, rep := range as.Representations {
+				representation, err := p.flattenRepresentation(period, as, rep, asBase, info.Duration)
+				if err != nil {
+					return nil, fmt.Errorf("invalid MPD format: representation %q: %w", rep.ID, err)
+				}
+				analysis.Representations = append(analysis.Representations, representation)
+				asInfo.RepresentationIDs = append(asInfo.RepresentationIDs, rep.ID)
+			}
+			asInfo.RepresentationCount = len(asInfo.RepresentationIDs)
+			info.AdaptationSets = append(info.AdaptationSets, asInfo)
+		}
+
+		analysis.Periods = append(analysis.Periods, info)
+	}
+
+	p.logger.Debug().
+		Str("manifest_url", baseURL).
+		Int("periods", len(analysis.Periods)).
+		Int("representations", len(analysis.Representations)).
+		Msg("Parsed DASH manifest")
+
+	return analysis, nil
+}
+
+// periodDuration derives a Period's duration from its own attribute, the next
+// Period's start, or the presentation duration
+func (p *DASHParser) periodDuration(mpd *MPD, index int, start, presentationDuration float64) float64 {
+	if d, err := ParseISODuration(mpd.Periods[index].Duration); err == nil {
+		return d
+	}
+	if index+1 < len(mpd.Periods) {
+		if nextStart, err := ParseISODuration(mpd.Periods[index+1].Start); err == nil && nextStart > start {
+			return nextStart - start
+		}
+	}
+	if presentationDuration > start {
+		return presentationDuration - start
+	}
+	return 0
+}
+
+// summarizeAdaptationSet builds the AdaptationSet summary
+func (p *DASHParser) summarizeAdaptationSet(as *AdaptationSet) *DASHAdaptationSetInfo {
+	info := &DASHAdaptationSetInfo{
+		ID:                as.ID,
+		ContentType:       contentTypeOf(as.ContentType, as.MimeType, as.Codecs),
+		MimeType:          as.MimeType,
+		Language:          as.Lang,
+		SegmentAlignment:  as.SegmentAlignment == "true" || as.SegmentAlignment == "1",
+		RepresentationIDs: make([]string, 0, len(as.Representations)),
+	}
+
+	for _, role := range as.Roles {
+		info.Roles = append(info.Roles, role.Value)
+	}
+	// ContentProtection may be declared per Representation as well
+	schemes := make(map[string]bool)
+	for _, cp := range as.ContentProtections {
+		schemes[cp.SchemeIDURI] = true
+		info.ProtectionSchemes = append(info.ProtectionSchemes, cp.SchemeIDURI)
+	}
+	for _, rep := range as.Representations {
+		for _, cp := range rep.ContentProtections {
+			if !schemes[cp.SchemeIDURI] {
+				schemes[cp.SchemeIDURI] = true
+				info.ProtectionSchemes = append(info.ProtectionSchemes, cp.SchemeIDURI)
+			}
+		}
+	}
+	info.Encrypted = len(info.ProtectionSchemes) > 0
+
+	// Derive content type from the first representation if the set does not declare it
+	if info.ContentType == "" && len(as.Representations) > 0 {
+		rep := as.Representations[0]
+		info.ContentType = contentTypeOf("", rep.MimeType, rep.Codecs)
+	}
+
+	return info
+}
+
+// flattenRepresentation resolves attributes inherited from the AdaptationSet and Period
+func (p *DASHParser) flattenRepresentation(period *Period, as *AdaptationSet, rep *Representation, asBase string, periodDuration float64) (*DASHRepresentation, error) {
+	r := &DASHRepresentation{
+		ID:                rep.ID,
+		PeriodID:          period.ID,
+		AdaptationSetID:   as.ID,
+		MimeType:          firstNonEmpty(rep.MimeType, as.MimeType),
+		Codecs:            firstNonEmpty(rep.Codecs, as.Codecs),
+		Bandwidth:         rep.Bandwidth,
+		Width:             rep.Width,
+		Height:            rep.Height,
+		AudioSamplingRate: firstNonEmpty(rep.AudioSamplingRate, as.AudioSamplingRate),
+		Language:          as.Lang,
+	}
+	if r.Width == 0 {
+		r.Width = as.Width
+	}
+	if r.Height == 0 {
+		r.Height = as.Height
+	}
+	r.FrameRate = ParseFrameRate(firstNonEmpty(rep.FrameRate, as.FrameRate))
+	r.ContentType = contentTypeOf(as.ContentType, r.MimeType, r.Codecs)
+
+	protections := contentProtections(as, rep)
+	r.Encrypted = len(protections) > 0
+	for _, cp := range protections {
+		if cp.SchemeIDURI == SchemeMP4Protection && cp.Value != "" {
+			r.ProtectionScheme = cp.Value
+		}
+	}
+
+	repBase := p.resolveBaseURL(asBase, rep.BaseURLs)
+
+	switch {
+	case rep.SegmentList != nil || as.SegmentList != nil:
+		list := rep.SegmentList
+		if list == nil {
+			list = as.SegmentList
+		}
+		r.AddressingMode = "segment_list"
+		p.expandSegmentList(r, list, repBase)
+	case rep.SegmentTemplate != nil || as.SegmentTemplate != nil || period.SegmentTemplate != nil:
+		tmpl := mergeTemplates(period.SegmentTemplate, as.SegmentTemplate, rep.SegmentTemplate)
+		r.AddressingMode = "segment_template"
+		if err := p.expandSegmentTemplate(r, tmpl, repBase, periodDuration); err != nil {
+			return nil, err
+		}
+	case rep.SegmentBase != nil || as.SegmentBase != nil || len(rep.BaseURLs) > 0:
+		// Single indexed file (on-demand profile)
+		r.AddressingMode = "segment_base"
+		r.SegmentCount = 1
+		r.SegmentDuration = periodDuration
+		r.MediaURLs = []string{repBase}
+	default:
+		r.AddressingMode = "none"
+	}
+
+	return r, nil
+}
+
+// expandSegmentList resolves SegmentList URLs
+func (p *DASHParser) expandSegmentList(r *DASHRepresentation, list *SegmentList, base string) {
+	if list.Initialization != nil && list.Initialization.SourceURL != "" {
+		r.InitializationURL = resolveURL(base, list.Initialization.SourceURL)
+	}
+	timescale := list.Timescale
+	if timescale <= 0 {
+		timescale = 1
+	}
+	if list.Duration > 0 {
+		r.SegmentDuration = float64(list.Duration) / float64(timescale)
+	}
+	r.SegmentCount = len(list.SegmentURLs)
+	for i, seg := range list.SegmentURLs {
+		if i >= maxListedSegments {
+			break
+		}
+		r.MediaURLs = append(r.MediaURLs, resolveURL(base, seg.Media))
+	}
+}
+
+// expandSegmentTemplate resolves SegmentTemplate URLs for the first segments.
+// SegmentTimeline repeat counts come from the manifest, so totals are
+// computed rather than iterated and absurd counts are rejected.
+func (p *DASHParser) expandSegmentTemplate(r *DASHRepresentation, tmpl *SegmentTemplate, base string, periodDuration float64) error {
+	timescale := tmpl.Timescale
+	if timescale <= 0 {
+		timescale = 1
+	}
+	startNumber := int64(1)
+	if tmpl.StartNumber != nil {
+		startNumber = *tmpl.StartNumber
+	}
+
+	if tmpl.Initialization != "" {
+		r.InitializationURL = resolveURL(base, ExpandTemplate(tmpl.Initialization, r.ID, r.Bandwidth, 0, 0))
+	}
+	if tmpl.Media == "" {
+		return nil
+	}
+
+	if tmpl.SegmentTimeline != nil && len(tmpl.SegmentTimeline.S) > 0 {
+		var t, total, count int64
+		for _, s := range tmpl.SegmentTimeline.S {
+			if s.T != nil {
+				t = *s.T
+			}
+			if s.D < 0 {
+				return fmt.Errorf("negative SegmentTimeline duration %d", s.D)
+			}
+			repeat := s.R
+			if repeat < 0 {
+				// Negative repeat means "until the end of the period"; count it once
+				repeat = 0
+			}
+			if repeat >= maxTimelineSegments-count {
+				return fmt.Errorf("SegmentTimeline describes more than %d segments", maxTimelineSegments)
+			}
+			segments := repeat + 1
+			if s.D > 0 && (segments > (math.MaxInt64-total)/s.D || segments > (math.MaxInt64-t)/s.D) {
+				return fmt.Errorf("SegmentTimeline duration overflows")
+			}
+
+			for i := int64(0); i < segments && len(r.MediaURLs) < maxListedSegments; i++ {
+				media := ExpandTemplate(tmpl.Media, r.ID, r.Bandwidth, startNumber+count+i, t+i*s.D)
+				r.MediaURLs = append(r.MediaURLs, resolveURL(base, media))
+			}
+			t += segments * s.D
+			total += segments * s.D
+			count += segments
+		}
+		r.SegmentCount = int(count)
+		if count > 0 {
+			r.SegmentDuration = float64(total) / float64(count) / float64(timescale)
+		}
+		return nil
+	}
+
+	if tmpl.Duration <= 0 {
+		return nil
+	}
+
+	r.SegmentDuration = float64(tmpl.Duration) / float64(timescale)
+	if periodDuration > 0 {
+		r.SegmentCount = int(math.Ceil(periodDuration / r.SegmentDuration))
+	}
+
+	listed := maxListedSegments
+	if r.SegmentCount > 0 && r.SegmentCount < listed {
+		listed = r.SegmentCount
+	}
+	for i := 0; i < listed; i++ {
+		number := startNumber + int64(i)
+		media := ExpandTemplate(tmpl.Media, r.ID, r.Bandwidth, number, int64(i)*tmpl.Duration)
+		r.MediaURLs = append(r.MediaURLs, resolveURL(base, media))
+	}
+	return nil
+}
+
+// resolveBaseURL applies the first BaseURL element (if any) to the parent base
+func (p *DASHParser) resolveBaseURL(parent string, baseURLs []string) string {
+	if len(baseURLs) == 0 || strings.TrimSpace(baseURLs[0]) == "" {
+		return parent
+	}
+	return resolveURL(parent, strings.TrimSpace(baseURLs[0]))
+}
+
+// ExpandTemplate substitutes DASH template identifiers ($RepresentationID$,
+// $Number$, $Bandwidth$, $Time$ and their %0Nd width variants, plus $$)
+func ExpandTemplate(template, representationID string, bandwidth int, number, t int64) string {
+	expanded := templateIdentifiers.ReplaceAllStringFunc(template, func(match string) string {
+		parts := templateIdentifiers.FindStringSubmatch(match)
+		width := 0
+		if parts[3] != "" {
+			width, _ = strconv.Atoi(parts[3])
+		}
+
+		var value int64
+		switch parts[1] {
+		case "RepresentationID":
+			return representationID
+		case "Number":
+			value = number
+		case "Bandwidth":
+			value = int64(bandwidth)
+		case "Time":
+			value = t
+		}
+
+		if width > 0 {
+			return fmt.Sprintf("%0*d", width, value)
+		}
+		return strconv.FormatInt(value, 10)
+	})
+
+	return strings.ReplaceAll(expanded, "$$", "$")
+}
+
+// ParseISODuration parses an ISO 8601 duration (e.g. PT1H2M3.5S) into seconds.
+// Years and months are approximated as 365 and 30 days.
+func ParseISODuration(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	matches := isoDurationPattern.FindStringSubmatch(value)
+	if matches == nil || value == "P" || value == "PT" {
+		return 0, fmt.Errorf("invalid ISO 8601 duration: %s", value)
+	}
+
+	multipliers := []float64{365 * 86400, 30 * 86400, 86400, 3600, 60, 1}
+	var seconds float64
+	for i, multiplier := range multipliers {
+		if matches[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(matches[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO 8601 duration: %s", value)
+		}
+		seconds += n * multiplier
+	}
+
+	return seconds, nil
+}
+
+// ParseFrameRate parses a DASH frame rate ("25", "30000/1001")
+func ParseFrameRate(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	if num, den, ok := strings.Cut(value, "/"); ok {
+		n, err1 := strconv.ParseFloat(num, 64)
+		d, err2 := strconv.ParseFloat(den, 64)
+		if err1 != nil || err2 != nil || d == 0 {
+			return 0
+		}
+		return n / d
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// mergeTemplates overlays SegmentTemplate attributes from the most specific level
+func mergeTemplates(templates ...*SegmentTemplate) *SegmentTemplate {
+	merged := &SegmentTemplate{}
+	for _, t := range templates {
+		if t == nil {
+			continue
+		}
+		if t.Media != "" {
+			merged.Media = t.Media
+		}
+		if t.Initialization != "" {
+			merged.Initialization = t.Initialization
+		}
+		if t.Timescale > 0 {
+			merged.Timescale = t.Timescale
+		}
+		if t.Duration > 0 {
+			merged.Duration = t.Duration
+		}
+		if t.StartNumber != nil {
+			merged.StartNumber = t.StartNumber
+		}
+		if t.SegmentTimeline != nil {
+			merged.SegmentTimeline = t.SegmentTimeline
+		}
+	}
+	return merged
+}
+
+// contentTypeOf derives video/audio/text from explicit contentType, mimeType or codecs
+func contentTypeOf(contentType, mimeType, codecs string) string {
+	if contentType != "" {
+		return contentType
+	}
+	if major, _, ok := strings.Cut(mimeType, "/"); ok {
+		switch major {
+		case "video", "audio", "text", "image":
+			return major
+		}
+	}
+	codecs = strings.ToLower(codecs)
+	switch {
+	case strings.HasPrefix(codecs, "stpp"), strings.HasPrefix(codecs, "wvtt"):
+		return "text"
+	case strings.HasPrefix(codecs, "mp4a"), strings.HasPrefix(codecs, "ac-3"), strings.HasPrefix(codecs, "ec-3"), strings.HasPrefix(codecs, "opus"):
+		return "audio"
+	case codecs != "":
+		return "video"
+	}
+	return ""
+}
+
+func resolveURL(base, ref string) string {
+	if base == "" {
+		return ref
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
+
+func splitList(value, sep string) []string {
+	var out []string
+	for _, part := range strings.Split(value, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
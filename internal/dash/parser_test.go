@@ -0,0 +1,255 @@
+package dash
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+const sampleMPD = `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static"
+     profiles="urn:mpeg:dash:profile:isoff-live:2011"
+     mediaPresentationDuration="PT1M0.0S" minBufferTime="PT2S">
+  <BaseURL>media/</BaseURL>
+  <Period id="p0">
+    <AdaptationSet id="1" contentType="video" mimeType="video/mp4" segmentAlignment="true" frameRate="30000/1001">
+      <SegmentTemplate media="$RepresentationID$/seg-$Number%05d$.m4s" initialization="$RepresentationID$/init.mp4" timescale="1000" duration="4000" startNumber="1"/>
+      <Representation id="720p" bandwidth="3000000" width="1280" height="720" codecs="avc1.64001f"/>
+      <Representation id="1080p" bandwidth="6000000" width="1920" height="1080" codecs="avc1.640028"/>
+    </AdaptationSet>
+    <AdaptationSet id="2" mimeType="audio/mp4" codecs="mp4a.40.2" lang="en">
+      <SegmentTemplate media="audio/$Time$.m4s" initialization="audio/init.mp4" timescale="48000">
+        <SegmentTimeline>
+          <S t="0" d="96000" r="2"/>
+          <S d="48000"/>
+        </SegmentTimeline>
+      </SegmentTemplate>
+      <Representation id="aac" bandwidth="128000" audioSamplingRate="48000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+func TestParseManifest(t *testing.T) {
+	parser := NewDASHParser(zerolog.Nop())
+
+	analysis, err := parser.ParseManifest(strings.NewReader(sampleMPD), "https://cdn.example.com/vod/manifest.mpd")
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+
+	if analysis.PresentationType != PresentationStatic {
+		t.Errorf("expected static presentation, got %q", analysis.PresentationType)
+	}
+	if analysis.Duration != 60 {
+		t.Errorf("expected duration 60, got %v", analysis.Duration)
+	}
+	if len(analysis.Periods) != 1 || len(analysis.Periods[0].AdaptationSets) != 2 {
+		t.Fatalf("unexpected period structure: %+v", analysis.Periods)
+	}
+	if len(analysis.Representations) != 3 {
+		t.Fatalf("expected 3 representations, got %d", len(analysis.Representations))
+	}
+
+	video := analysis.Representations[0]
+	if video.MimeType != "video/mp4" || video.ContentType != "video" {
+		t.Errorf("expected inherited video/mp4 mime type, got %q (%q)", video.MimeType, video.ContentType)
+	}
+	if video.FrameRate < 29.96 || video.FrameRate > 29.98 {
+		t.Errorf("expected frame rate ~29.97, got %v", video.FrameRate)
+	}
+	if video.SegmentDuration != 4 || video.SegmentCount != 15 {
+		t.Errorf("expected 15 segments of 4s, got %d of %v", video.SegmentCount, video.SegmentDuration)
+	}
+	if video.InitializationURL != "https://cdn.example.com/vod/media/720p/init.mp4" {
+		t.Errorf("unexpected initialization URL %q", video.InitializationURL)
+	}
+	if len(video.MediaURLs) == 0 || video.MediaURLs[0] != "https://cdn.example.com/vod/media/720p/seg-00001.m4s" {
+		t.Errorf("unexpected media URLs %v", video.MediaURLs)
+	}
+
+	audio := analysis.Representations[2]
+	if audio.ContentType != "audio" || audio.Codecs != "mp4a.40.2" || audio.Language != "en" {
+		t.Errorf("unexpected audio representation %+v", audio)
+	}
+	if audio.AddressingMode != "segment_template" || audio.SegmentCount != 4 {
+		t.Errorf("expected 4 timeline segments, got %d (%s)", audio.SegmentCount, audio.AddressingMode)
+	}
+	if len(audio.MediaURLs) < 2 || audio.MediaURLs[1] != "https://cdn.example.com/vod/media/audio/96000.m4s" {
+		t.Errorf("unexpected timeline media URLs %v", audio.MediaURLs)
+	}
+}
+
+func TestParseManifest_Invalid(t *testing.T) {
+	parser := NewDASHParser(zerolog.Nop())
+
+	if _, err := parser.ParseManifest(strings.NewReader("#EXTM3U"), ""); err == nil {
+		t.Error("expected error for non-MPD input")
+	}
+}
+
+func TestParseManifest_TimelineRepeat(t *testing.T) {
+	parser := NewDASHParser(zerolog.Nop())
+	timeline := func(repeat string) string {
+		return strings.Replace(sampleMPD, `<S t="0" d="96000" r="2"/>`, `<S t="0" d="96000" r="`+repeat+`"/>`, 1)
+	}
+
+	analysis, err := parser.ParseManifest(strings.NewReader(timeline("99999")), "")
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+	audio := analysis.Representations[2]
+	if audio.SegmentCount != 100001 || len(audio.MediaURLs) != maxListedSegments {
+		t.Errorf("expected 100001 segments with %d listed, got %d with %d", maxListedSegments, audio.SegmentCount, len(audio.MediaURLs))
+	}
+	if audio.MediaURLs[9] != "/media/audio/864000.m4s" {
+		t.Errorf("unexpected tenth media URL %q", audio.MediaURLs[9])
+	}
+
+	for _, repeat := range []string{"3000000000", "9223372036854775807"} {
+		if _, err := parser.ParseManifest(strings.NewReader(timeline(repeat)), ""); err == nil {
+			t.Errorf("expected r=%s to be rejected", repeat)
+		}
+	}
+}
+
+func TestParseISODuration(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+		wantErr  bool
+	}{
+		{"PT2S", 2, false},
+		{"PT1H2M3.5S", 3723.5, false},
+		{"P1DT1S", 86401, false},
+		{"PT0.5S", 0.5, false},
+		{"", 0, true},
+		{"PT", 0, true},
+		{"2 seconds", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseISODuration(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseISODuration(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ParseISODuration(%q) = %v, want %v", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{"$RepresentationID$/$Number$.m4s", "v1/7.m4s"},
+		{"seg-$Number%04d$.m4s", "seg-0007.m4s"},
+		{"$Bandwidth$/$Time$.m4s", "500000/9000.m4s"},
+		{"price$$list.m4s", "price$list.m4s"},
+	}
+
+	for _, tt := range tests {
+		if got := ExpandTemplate(tt.template, "v1", 500000, 7, 9000); got != tt.expected {
+			t.Errorf("ExpandTemplate(%q) = %q, want %q", tt.template, got, tt.expected)
+		}
+	}
+}
+
+func TestValidateCompliance(t *testing.T) {
+	parser := NewDASHParser(zerolog.Nop())
+	analyzer := NewDASHAnalyzer(zerolog.Nop())
+
+	analysis, err := parser.ParseManifest(strings.NewReader(sampleMPD), "https://cdn.example.com/vod/manifest.mpd")
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+
+	analyzer.validateCompliance(analysis)
+	if !analysis.ValidationResults.IsValid {
+		t.Errorf("expected valid manifest, got errors: %+v", analysis.ValidationResults.Errors)
+	}
+	if !analysis.ValidationResults.Compliance.LiveProfile || !analysis.ValidationResults.Compliance.DASHIFCompliant {
+		t.Errorf("unexpected compliance: %+v", analysis.ValidationResults.Compliance)
+	}
+
+	broken := strings.Replace(sampleMPD, `minBufferTime="PT2S"`, "", 1)
+	broken = strings.Replace(broken, `bandwidth="128000"`, `bandwidth="0"`, 1)
+	analysis, err = parser.ParseManifest(strings.NewReader(broken), "https://cdn.example.com/vod/manifest.mpd")
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+
+	analyzer.validateCompliance(analysis)
+	if analysis.ValidationResults.IsValid {
+		t.Fatal("expected invalid manifest")
+	}
+	if !hasIssue(analysis.ValidationResults.Errors, "MISSING_MIN_BUFFER_TIME") ||
+		!hasIssue(analysis.ValidationResults.Errors, "INVALID_BANDWIDTH") {
+		t.Errorf("missing expected errors: %+v", analysis.ValidationResults.Errors)
+	}
+}
+
+const encryptedMPD = `<?xml version="1.0" encoding="UTF-8"?>
+<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:cenc="urn:mpeg:cenc:2013" type="static"
+     profiles="urn:mpeg:dash:profile:isoff-live:2011" mediaPresentationDuration="PT8S" minBufferTime="PT2S">
+  <Period id="p0">
+    <AdaptationSet id="1" contentType="video" mimeType="video/mp4">
+      <ContentProtection schemeIdUri="urn:mpeg:dash:mp4protection:2011" value="cenc" cenc:default_KID="01234567-89AB-CDEF-0123-456789ABCDEF"/>
+      <ContentProtection schemeIdUri="urn:uuid:EDEF8BA9-79D6-4ACE-A3C8-27DCD51D21ED">
+        <cenc:pssh>AAAAIHBzc2gAAAAA7e+LqXnWSs6jyCfc1R0h7QAAAAA=</cenc:pssh>
+      </ContentProtection>
+      <SegmentTemplate media="$RepresentationID$/$Number$.m4s" initialization="$RepresentationID$/init.mp4" timescale="1000" duration="4000"/>
+      <Representation id="v1" bandwidth="3000000" width="1280" height="720" codecs="avc1.64001f"/>
+    </AdaptationSet>
+    <AdaptationSet id="2" mimeType="audio/mp4" codecs="mp4a.40.2">
+      <SegmentTemplate media="$RepresentationID$/$Number$.m4s" initialization="$RepresentationID$/init.mp4" timescale="1000" duration="4000"/>
+      <Representation id="a1" bandwidth="128000">
+        <ContentProtection schemeIdUri="urn:uuid:9a04f079-9840-4286-ab92-e65be0885f95"/>
+      </Representation>
+      <Representation id="a2" bandwidth="64000"/>
+    </AdaptationSet>
+  </Period>
+</MPD>`
+
+func TestEncryptionFromMPD(t *testing.T) {
+	analysis, err := NewDASHParser(zerolog.Nop()).ParseManifest(strings.NewReader(encryptedMPD), "https://cdn.example.com/drm/manifest.mpd")
+	if err != nil {
+		t.Fatalf("ParseManifest failed: %v", err)
+	}
+	if !analysis.Periods[0].AdaptationSets[1].Encrypted {
+		t.Error("expected representation ContentProtection to mark the audio set encrypted")
+	}
+	if video := analysis.Representations[0]; !video.Encrypted || video.ProtectionScheme != "cenc" {
+		t.Errorf("expected cenc video representation, got %+v", video)
+	}
+
+	encryption := encryptionFromMPD(analysis.MPD)
+	encryption.Finalize()
+
+	if encryption.EncryptedRenditions != 2 || encryption.ClearRenditions != 1 {
+		t.Errorf("expected 2 encrypted and 1 clear renditions, got %d and %d", encryption.EncryptedRenditions, encryption.ClearRenditions)
+	}
+	if len(encryption.Methods) != 1 || encryption.Methods[0] != "cenc" {
+		t.Errorf("unexpected methods %v", encryption.Methods)
+	}
+	if len(encryption.DefaultKIDs) != 1 || encryption.DefaultKIDs[0] != "01234567-89ab-cdef-0123-456789abcdef" {
+		t.Errorf("unexpected default KIDs %v", encryption.DefaultKIDs)
+	}
+	if len(encryption.Systems) != 2 || encryption.Systems[0].Name != "PlayReady" || encryption.Systems[1].Name != "Widevine" || !encryption.Systems[1].HasPSSH {
+		t.Errorf("expected PlayReady and Widevine with pssh, got %+v", encryption.Systems)
+	}
+
+	expected := map[string]bool{
+		"Representation a1 is protected but no ContentProtection carries cenc:default_KID": true,
+		"1 of 3 renditions are not encrypted":                                              true,
+	}
+	for _, issue := range encryption.Issues {
+		delete(expected, issue)
+	}
+	for issue := range expected {
+		t.Errorf("missing issue %q in %v", issue, encryption.Issues)
+	}
+}
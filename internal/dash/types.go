@@ -0,0 +1,314 @@
+package dash
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/drm"
+)
+
+// DASHAnalysis represents a complete DASH analysis result
+type DASHAnalysis struct {
+	ID                uuid.UUID              `json:"id"`
+	AnalysisID        uuid.UUID              `json:"analysis_id"`
+	ManifestURL       string                 `json:"manifest_url"`
+	PresentationType  DASHPresentationType   `json:"presentation_type"`
+	Profiles          []string               `json:"profiles"`
+	Duration          float64                `json:"duration,omitempty"`
+	MinBufferTime     float64                `json:"min_buffer_time,omitempty"`
+	Periods           []*DASHPeriod          `json:"periods"`
+	Representations   []*DASHRepresentation  `json:"representations"`
+	QualityLadder     *DASHQualityLadder     `json:"quality_ladder,omitempty"`
+	ValidationResults *DASHValidationResults `json:"validation_results,omitempty"`
+	Encryption        *drm.Encryption        `json:"encryption,omitempty"`
+	MPD               *MPD                   `json:"-"`
+	ProcessingTime    time.Duration          `json:"processing_time"`
+	Status            DASHAnalysisStatus     `json:"status"`
+	ErrorMessage      string                 `json:"error_message,omitempty"`
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+	CompletedAt       *time.Time             `json:"completed_at,omitempty"`
+}
+
+// DASHAnalysisStatus represents the status of DASH analysis
+type DASHAnalysisStatus string
+
+const (
+	DASHStatusPending    DASHAnalysisStatus = "pending"
+	DASHStatusProcessing DASHAnalysisStatus = "processing"
+	DASHStatusCompleted  DASHAnalysisStatus = "completed"
+	DASHStatusFailed     DASHAnalysisStatus = "failed"
+)
+
+// DASHPresentationType represents the MPD@type attribute
+type DASHPresentationType string
+
+const (
+	PresentationStatic  DASHPresentationType = "static"
+	PresentationDynamic DASHPresentationType = "dynamic"
+)
+
+// Well-known MPD@profiles identifiers
+const (
+	ProfileISOFFLive     = "urn:mpeg:dash:profile:isoff-live:2011"
+	ProfileISOFFOnDemand = "urn:mpeg:dash:profile:isoff-on-demand:2011"
+	ProfileISOFFMain     = "urn:mpeg:dash:profile:isoff-main:2011"
+	ProfileFull          = "urn:mpeg:dash:profile:full:2011"
+	ProfileCMAF          = "urn:mpeg:dash:profile:cmaf:2019"
+	ProfileDASHIF264     = "http://dashif.org/guidelines/dash264"
+	ProfileDVB           = "urn:dvb:dash:profile:dvb-dash:2014"
+)
+
+// DASHPeriod summarizes a Period and its adaptation sets
+type DASHPeriod struct {
+	ID             string                   `json:"id,omitempty"`
+	Start          float64                  `json:"start"`
+	Duration       float64                  `json:"duration,omitempty"`
+	AdaptationSets []*DASHAdaptationSetInfo `json:"adaptation_sets"`
+}
+
+// DASHAdaptationSetInfo summarizes an AdaptationSet
+type DASHAdaptationSetInfo struct {
+	ID                  string   `json:"id,omitempty"`
+	ContentType         string   `json:"content_type"`
+	MimeType            string   `json:"mime_type,omitempty"`
+	Language            string   `json:"language,omitempty"`
+	Roles               []string `json:"roles,omitempty"`
+	SegmentAlignment    bool     `json:"segment_alignment"`
+	Encrypted           bool     `json:"encrypted"`
+	ProtectionSchemes   []string `json:"protection_schemes,omitempty"`
+	RepresentationIDs   []string `json:"representation_ids"`
+	RepresentationCount int      `json:"representation_count"`
+}
+
+// DASHRepresentation represents a flattened Representation with inherited attributes resolved
+type DASHRepresentation struct {
+	ID                string               `json:"id"`
+	PeriodID          string               `json:"period_id,omitempty"`
+	AdaptationSetID   string               `json:"adaptation_set_id,omitempty"`
+	ContentType       string               `json:"content_type"`
+	MimeType          string               `json:"mime_type,omitempty"`
+	Codecs            string               `json:"codecs,omitempty"`
+	Bandwidth         int                  `json:"bandwidth"`
+	Width             int                  `json:"width,omitempty"`
+	Height            int                  `json:"height,omitempty"`
+	FrameRate         float64              `json:"frame_rate,omitempty"`
+	AudioSamplingRate string               `json:"audio_sampling_rate,omitempty"`
+	Language          string               `json:"language,omitempty"`
+	Encrypted         bool                 `json:"encrypted"`
+	ProtectionScheme  string               `json:"protection_scheme,omitempty"` // mp4protection value, e.g. cenc or cbcs
+	AddressingMode    string               `json:"addressing_mode"`
+	SegmentDuration   float64              `json:"segment_duration,omitempty"`
+	SegmentCount      int                  `json:"segment_count,omitempty"`
+	InitializationURL string               `json:"initialization_url,omitempty"`
+	MediaURLs         []string             `json:"media_urls,omitempty"`
+	SampledSegments   []*DASHSegmentSample `json:"sampled_segments,omitempty"`
+}
+
+// DASHSegmentSample represents a probed sample segment
+type DASHSegmentSample struct {
+	URL        string  `json:"url"`
+	Size       int64   `json:"size,omitempty"`
+	Bitrate    int     `json:"bitrate,omitempty"`
+	Duration   float64 `json:"duration,omitempty"`
+	CodecName  string  `json:"codec_name,omitempty"`
+	Width      int     `json:"width,omitempty"`
+	Height     int     `json:"height,omitempty"`
+	FormatName string  `json:"format_name,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// DASHQualityLadder represents the bitrate ladder of the video representations
+type DASHQualityLadder struct {
+	RepresentationCount int               `json:"representation_count"`
+	MinBandwidth        int               `json:"min_bandwidth"`
+	MaxBandwidth        int               `json:"max_bandwidth"`
+	AverageBandwidth    float64           `json:"average_bandwidth"`
+	MinHeight           int               `json:"min_height,omitempty"`
+	MaxHeight           int               `json:"max_height,omitempty"`
+	CodecDistribution   map[string]int    `json:"codec_distribution"`
+	QualityGaps         []*DASHQualityGap `json:"quality_gaps"`
+	Recommendations     []string          `json:"recommendations"`
+}
+
+// DASHQualityGap represents a large bandwidth step between adjacent representations
+type DASHQualityGap struct {
+	LowerID        string  `json:"lower_id"`
+	UpperID        string  `json:"upper_id"`
+	LowerBandwidth int     `json:"lower_bandwidth"`
+	UpperBandwidth int     `json:"upper_bandwidth"`
+	Ratio          float64 `json:"ratio"`
+	Severity       string  `json:"severity"`
+}
+
+// DASHValidationResults represents DASH validation results
+type DASHValidationResults struct {
+	IsValid    bool                   `json:"is_valid"`
+	Errors     []*DASHValidationIssue `json:"errors,omitempty"`
+	Warnings   []*DASHValidationIssue `json:"warnings,omitempty"`
+	Compliance *DASHComplianceCheck   `json:"compliance,omitempty"`
+	Summary    string                 `json:"summary"`
+}
+
+// DASHValidationIssue represents a single validation error or warning
+type DASHValidationIssue struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Element    string `json:"element,omitempty"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// DASHComplianceCheck represents profile compliance results
+type DASHComplianceCheck struct {
+	Profiles          []string `json:"profiles"`
+	ISO23009Compliant bool     `json:"iso23009_compliant"`
+	DASHIFCompliant   bool     `json:"dashif_iop_compliant"`
+	LiveProfile       bool     `json:"live_profile"`
+	OnDemandProfile   bool     `json:"on_demand_profile"`
+	CMAFProfile       bool     `json:"cmaf_profile"`
+}
+
+// DASHAnalysisRequest represents a DASH analysis request
+type DASHAnalysisRequest struct {
+	ManifestURL        string `json:"manifest_url" binding:"required"`
+	ProbeSegments      bool   `json:"probe_segments,omitempty"`
+	AnalyzeQuality     bool   `json:"analyze_quality,omitempty"`
+	ValidateCompliance bool   `json:"validate_compliance,omitempty"`
+	MaxSegments        int    `json:"max_segments,omitempty"`       // Sample segments probed per representation
+	InspectEncryption  bool   `json:"inspect_encryption,omitempty"` // Fetch init segments and read their protection boxes
+}
+
+// DASHAnalysisResult represents the result of DASH analysis
+type DASHAnalysisResult struct {
+	ID             uuid.UUID          `json:"id"`
+	Status         DASHAnalysisStatus `json:"status"`
+	Analysis       *DASHAnalysis      `json:"analysis,omitempty"`
+	ProcessingTime time.Duration      `json:"processing_time"`
+	Message        string             `json:"message,omitempty"`
+	Error          string             `json:"error,omitempty"`
+}
+
+// MPD is the root element of a DASH Media Presentation Description (ISO/IEC 23009-1)
+type MPD struct {
+	XMLName                   xml.Name  `xml:"MPD"`
+	ID                        string    `xml:"id,attr"`
+	Type                      string    `xml:"type,attr"`
+	Profiles                  string    `xml:"profiles,attr"`
+	MinBufferTime             string    `xml:"minBufferTime,attr"`
+	MediaPresentationDuration string    `xml:"mediaPresentationDuration,attr"`
+	MinimumUpdatePeriod       string    `xml:"minimumUpdatePeriod,attr"`
+	AvailabilityStartTime     string    `xml:"availabilityStartTime,attr"`
+	TimeShiftBufferDepth      string    `xml:"timeShiftBufferDepth,attr"`
+	MaxSegmentDuration        string    `xml:"maxSegmentDuration,attr"`
+	BaseURLs                  []string  `xml:"BaseURL"`
+	Periods                   []*Period `xml:"Period"`
+}
+
+// Period represents an MPD Period element
+type Period struct {
+	ID              string           `xml:"id,attr"`
+	Start           string           `xml:"start,attr"`
+	Duration        string           `xml:"duration,attr"`
+	BaseURLs        []string         `xml:"BaseURL"`
+	SegmentTemplate *SegmentTemplate `xml:"SegmentTemplate"`
+	AdaptationSets  []*AdaptationSet `xml:"AdaptationSet"`
+}
+
+// AdaptationSet represents an MPD AdaptationSet element
+type AdaptationSet struct {
+	ID                 string               `xml:"id,attr"`
+	ContentType        string               `xml:"contentType,attr"`
+	MimeType           string               `xml:"mimeType,attr"`
+	Codecs             string               `xml:"codecs,attr"`
+	Lang               string               `xml:"lang,attr"`
+	SegmentAlignment   string               `xml:"segmentAlignment,attr"`
+	BitstreamSwitching string               `xml:"bitstreamSwitching,attr"`
+	Width              int                  `xml:"width,attr"`
+	Height             int                  `xml:"height,attr"`
+	FrameRate          string               `xml:"frameRate,attr"`
+	AudioSamplingRate  string               `xml:"audioSamplingRate,attr"`
+	BaseURLs           []string             `xml:"BaseURL"`
+	Roles              []*Descriptor        `xml:"Role"`
+	ContentProtections []*ContentProtection `xml:"ContentProtection"`
+	SegmentTemplate    *SegmentTemplate     `xml:"SegmentTemplate"`
+	SegmentBase        *SegmentBase         `xml:"SegmentBase"`
+	SegmentList        *SegmentList         `xml:"SegmentList"`
+	Representations    []*Representation    `xml:"Representation"`
+}
+
+// Representation represents an MPD Representation element
+type Representation struct {
+	ID                string           `xml:"id,attr"`
+	Bandwidth         int              `xml:"bandwidth,attr"`
+	Width             int              `xml:"width,attr"`
+	Height            int              `xml:"height,attr"`
+	FrameRate         string           `xml:"frameRate,attr"`
+	Codecs            string           `xml:"codecs,attr"`
+	MimeType          string           `xml:"mimeType,attr"`
+	AudioSamplingRate string           `xml:"audioSamplingRate,attr"`
+	SAR               string           `xml:"sar,attr"`
+	BaseURLs          []string         `xml:"BaseURL"`
+	SegmentTemplate   *SegmentTemplate `xml:"SegmentTemplate"`
+	SegmentBase       *SegmentBase     `xml:"SegmentBase"`
+	SegmentList       *SegmentList     `xml:"SegmentList"`
+
+	ContentProtections []*ContentProtection `xml:"ContentProtection"`
+}
+
+// Descriptor represents a generic DASH descriptor (Role, Accessibility, ...)
+type Descriptor struct {
+	SchemeIDURI string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr"`
+}
+
+// ContentProtection represents a ContentProtection descriptor
+type ContentProtection struct {
+	SchemeIDURI string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr"`
+	DefaultKID  string `xml:"urn:mpeg:cenc:2013 default_KID,attr"`
+	PSSH        string `xml:"urn:mpeg:cenc:2013 pssh"` // Base64 pssh box
+}
+
+// SegmentTemplate represents a SegmentTemplate element
+type SegmentTemplate struct {
+	Media           string           `xml:"media,attr"`
+	Initialization  string           `xml:"initialization,attr"`
+	Timescale       int64            `xml:"timescale,attr"`
+	Duration        int64            `xml:"duration,attr"`
+	StartNumber     *int64           `xml:"startNumber,attr"`
+	SegmentTimeline *SegmentTimeline `xml:"SegmentTimeline"`
+}
+
+// SegmentTimeline represents a SegmentTimeline element
+type SegmentTimeline struct {
+	S []*TimelineEntry `xml:"S"`
+}
+
+// TimelineEntry represents an S element of a SegmentTimeline
+type TimelineEntry struct {
+	T *int64 `xml:"t,attr"`
+	D int64  `xml:"d,attr"`
+	R int64  `xml:"r,attr"`
+}
+
+// SegmentBase represents a SegmentBase element (single-segment addressing)
+type SegmentBase struct {
+	IndexRange     string `xml:"indexRange,attr"`
+	Timescale      int64  `xml:"timescale,attr"`
+	Initialization *struct {
+		Range string `xml:"range,attr"`
+	} `xml:"Initialization"`
+}
+
+// SegmentList represents a SegmentList element
+type SegmentList struct {
+	Timescale      int64 `xml:"timescale,attr"`
+	Duration       int64 `xml:"duration,attr"`
+	Initialization *struct {
+		SourceURL string `xml:"sourceURL,attr"`
+	} `xml:"Initialization"`
+	SegmentURLs []*struct {
+		Media string `xml:"media,attr"`
+	} `xml:"SegmentURL"`
+}
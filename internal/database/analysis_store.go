@@ -0,0 +1,380 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Analysis record statuses, matching the CHECK constraint on analyses.status
+const (
+	AnalysisStatusCompleted = "completed"
+	AnalysisStatusFailed    = "failed"
+)
+
+// Analysis list pagination bounds
+const (
+	DefaultAnalysisListLimit = 20
+	MaxAnalysisListLimit     = 100
+)
+
+// analysesSchema mirrors the analyses table from the migrations so the store
+// works on databases that were never migrated. The PostgreSQL table leaves
+// out the users foreign key, as users only exists once migrated.
+var analysesSchema = schema{
+	sqlite: []string{
+		`CREATE TABLE IF NOT EXISTS analyses (
+			id TEXT PRIMARY KEY DEFAULT (lower(hex(randomblob(16)))),
+			user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+			file_name TEXT NOT NULL,
+			file_path TEXT,
+			file_size INTEGER,
+			content_hash TEXT,
+			source_type TEXT DEFAULT 'local',
+			status TEXT DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'completed', 'failed')),
+			ffprobe_data TEXT,
+			llm_report TEXT,
+			processed_at DATETIME,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			error_msg TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_analyses_status ON analyses(status)`,
+		`CREATE INDEX IF NOT EXISTS idx_analyses_created_at ON analyses(created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_analyses_content_hash ON analyses(content_hash)`,
+	},
+	postgres: []string{
+		`CREATE TABLE IF NOT EXISTS analyses (
+			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+			user_id UUID,
+			file_name VARCHAR(500) NOT NULL,
+			file_path VARCHAR(1000),
+			file_size BIGINT,
+			content_hash VARCHAR(64),
+			source_type VARCHAR(50) DEFAULT 'local',
+			status VARCHAR(50) DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'completed', 'failed')),
+			ffprobe_data JSONB,
+			llm_report TEXT,
+			processed_at TIMESTAMP WITH TIME ZONE,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			error_msg TEXT
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_analyses_status ON analyses(status)`,
+		`CREATE INDEX IF NOT EXISTS idx_analyses_created_at ON analyses(created_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_analyses_content_hash ON analyses(content_hash)`,
+	},
+}
+
+// AnalysisRecord is a persisted probe result. Source holds the URL for
+// URL analyses and is empty for uploads.
+type AnalysisRecord struct {
+	ID          uuid.UUID       `json:"analysis_id" db:"id"`
+	FileName    string          `json:"filename" db:"file_name"`
+	Source      string          `json:"source,omitempty" db:"file_path"`
+	FileSize    int64           `json:"size,omitempty" db:"file_size"`
+	SourceType  string          `json:"source_type" db:"source_type"`
+	Status      string          `json:"status" db:"status"`
+	Result      json.RawMessage `json:"analysis,omitempty" db:"-"`
+	LLMReport   string          `json:"llm_report,omitempty" db:"llm_report"`
+	Error       string          `json:"error,omitempty" db:"error_msg"`
+	ProcessedAt *time.Time      `json:"processed_at,omitempty" db:"processed_at"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AnalysisFilter narrows an analysis listing. Zero values match everything.
+type AnalysisFilter struct {
+	FileName string    // Case-insensitive substring match
+	Status   string    // completed or failed
+	From     time.Time // Created at or after
+	To       time.Time // Created before
+	Limit    int
+	Offset   int
+}
+
+// AnalysisStore persists probe results in the analyses table. Every query
+// is restricted to the tenant scope of its context, see WithScope.
+type AnalysisStore struct {
+	db *DB
+}
+
+// NewAnalysisStore creates the analyses table if needed and returns a store
+func NewAnalysisStore(ctx context.Context, db *DB) (*AnalysisStore, error) {
+	if err := db.createSchema(ctx, analysesSchema); err != nil {
+		return nil, fmt.Errorf("failed to create analyses schema: %w", err)
+	}
+	if err := db.createSchema(ctx, analysisOwnersSchema); err != nil {
+		return nil, fmt.Errorf("failed to create analysis_owners schema: %w", err)
+	}
+	return &AnalysisStore{db: db}, nil
+}
+
+// Save inserts a record, replacing any earlier record with the same ID.
+// A new record is owned by the scope of ctx.
+func (s *AnalysisStore) Save(ctx context.Context, record *AnalysisRecord) error {
+	now := time.Now().UTC()
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = now
+	}
+	if record.ProcessedAt == nil {
+		record.ProcessedAt = &now
+	}
+
+	query := `
+		INSERT INTO analyses (id, file_name, file_path, file_size, source_type,
+			status, ffprobe_data, llm_report, error_msg, processed_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			file_name = excluded.file_name, file_path = excluded.file_path,
+			file_size = excluded.file_size, source_type = excluded.source_type,
+			status = excluded.status, ffprobe_data = excluded.ffprobe_data,
+			llm_report = excluded.llm_report, error_msg = excluded.error_msg,
+			processed_at = excluded.processed_at, created_at = excluded.created_at,
+			updated_at = excluded.updated_at`
+
+	tx, err := s.db.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save analysis: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, tx.Rebind(query),
+		record.ID,
+		record.FileName,
+		record.Source,
+		record.FileSize,
+		record.SourceType,
+		record.Status,
+		nullableJSON(record.Result),
+		record.LLMReport,
+		record.Error,
+		record.ProcessedAt,
+		record.CreatedAt,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save analysis: %w", err)
+	}
+
+	// The first save decides the owner; IDs are generated by the server,
+	// so a later save never comes from another tenant
+	if scope := ScopeFromContext(ctx); !scope.IsZero() {
+		_, err := tx.ExecContext(ctx, tx.Rebind(`
+			INSERT INTO analysis_owners (analysis_id, organization_id, project_id) VALUES (?, ?, ?)
+			ON CONFLICT (analysis_id) DO NOTHING`),
+			record.ID.String(), scope.OrganizationID, scope.ProjectID)
+		if err != nil {
+			return fmt.Errorf("failed to save analysis owner: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// scoped appends the tenant condition of ctx to a query whose last clause
+// is a WHERE
+func scoped(ctx context.Context, query string, args []interface{}) (string, []interface{}) {
+	condition, scopeArgs := ScopeFromContext(ctx).ownedAnalyses("id")
+	if condition == "" {
+		return query, args
+	}
+	return query + " AND " + condition, append(args, scopeArgs...)
+}
+
+// recordColumns selects nullable text columns as empty strings so rows
+// written by other tools still scan into AnalysisRecord. Date columns are
+// selected bare; wrapping them would lose the DATETIME type the driver needs
+// to parse them.
+const recordColumns = `id, file_name, COALESCE(file_path, '') AS file_path,
+	COALESCE(file_size, 0) AS file_size, COALESCE(source_type, '') AS source_type,
+	status, COALESCE(llm_report, '') AS llm_report, COALESCE(error_msg, '') AS error_msg,
+	processed_at, created_at`
+
+// Get returns a record including its full result, or ErrAnalysisNotFound
+func (s *AnalysisStore) Get(ctx context.Context, id uuid.UUID) (*AnalysisRecord, error) {
+	query, args := scoped(ctx, `SELECT `+recordColumns+`, COALESCE(CAST(ffprobe_data AS TEXT), '') AS result_json
+		FROM analyses WHERE id = ?`, []interface{}{id})
+
+	var row struct {
+		AnalysisRecord
+		ResultJSON string `db:"result_json"`
+	}
+	if err := s.db.DB.GetContext(ctx, &row, s.db.DB.Rebind(query), args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAnalysisNotFound
+		}
+		return nil, fmt.Errorf("failed to get analysis: %w", err)
+	}
+
+	record := row.AnalysisRecord
+	if row.ResultJSON != "" {
+		record.Result = json.RawMessage(row.ResultJSON)
+	}
+
+	return &record, nil
+}
+
+// List returns matching records newest first, without their results, and
+// the total number of matches
+func (s *AnalysisStore) List(ctx context.Context, filter AnalysisFilter) ([]AnalysisRecord, int, error) {
+	whereConditions := []string{}
+	args := []interface{}{}
+
+	if filter.FileName != "" {
+		// LIKE only ignores case on SQLite, so both sides are lowered
+		whereConditions = append(whereConditions, `LOWER(file_name) LIKE LOWER(?) ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(filter.FileName)+"%")
+	}
+	if filter.Status != "" {
+		whereConditions = append(whereConditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if !filter.From.IsZero() {
+		whereConditions = append(whereConditions, "created_at >= ?")
+		args = append(args, filter.From.UTC())
+	}
+	if !filter.To.IsZero() {
+		whereConditions = append(whereConditions, "created_at < ?")
+		args = append(args, filter.To.UTC())
+	}
+	if condition, scopeArgs := ScopeFromContext(ctx).ownedAnalyses("id"); condition != "" {
+		whereConditions = append(whereConditions, condition)
+		args = append(args, scopeArgs...)
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = " WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	var total int
+	if err := s.db.DB.GetContext(ctx, &total, s.db.DB.Rebind("SELECT COUNT(*) FROM analyses"+whereClause), args...); err != nil {
+		return nil, 0, fmt.Errorf("failed to count analyses: %w", err)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultAnalysisListLimit
+	} else if limit > MaxAnalysisListLimit {
+		limit = MaxAnalysisListLimit
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := `SELECT ` + recordColumns + ` FROM analyses` + whereClause + `
+		ORDER BY created_at DESC
+		LIMIT ? OFFSET ?`
+
+	records := []AnalysisRecord{}
+	if err := s.db.DB.SelectContext(ctx, &records, s.db.DB.Rebind(query), append(args, limit, offset)...); err != nil {
+		return nil, 0, fmt.Errorf("failed to list analyses: %w", err)
+	}
+
+	return records, total, nil
+}
+
+// Delete removes a record, returning ErrAnalysisNotFound if it does not exist
+func (s *AnalysisStore) Delete(ctx context.Context, id uuid.UUID) error {
+	tx, err := s.db.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete analysis: %w", err)
+	}
+	defer tx.Rollback()
+
+	query, args := scoped(ctx, "DELETE FROM analyses WHERE id = ?", []interface{}{id})
+	result, err := tx.ExecContext(ctx, tx.Rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete analysis: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete analysis: %w", err)
+	}
+	if rows == 0 {
+		return ErrAnalysisNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM analysis_owners WHERE analysis_id = ?"), id.String()); err != nil {
+		return fmt.Errorf("failed to delete analysis owner: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// SetLLMReport stores an LLM report generated after the analysis was saved,
+// returning ErrAnalysisNotFound if the record does not exist
+func (s *AnalysisStore) SetLLMReport(ctx context.Context, id uuid.UUID, report string) error {
+	query, args := scoped(ctx, "UPDATE analyses SET llm_report = ?, updated_at = ? WHERE id = ?",
+		[]interface{}{report, time.Now().UTC(), id})
+	result, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("failed to update analysis: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update analysis: %w", err)
+	}
+	if rows == 0 {
+		return ErrAnalysisNotFound
+	}
+
+	return nil
+}
+
+// SetContentHash records the hash that identifies what a completed analysis
+// was run on, see FindByContentHash. It returns ErrAnalysisNotFound if the
+// record does not exist.
+func (s *AnalysisStore) SetContentHash(ctx context.Context, id uuid.UUID, hash string) error {
+	query, args := scoped(ctx, "UPDATE analyses SET content_hash = ? WHERE id = ?", []interface{}{hash, id})
+	result, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("failed to update analysis: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update analysis: %w", err)
+	}
+	if rows == 0 {
+		return ErrAnalysisNotFound
+	}
+
+	return nil
+}
+
+// FindByContentHash returns the newest completed record with the hash
+// created at or after since, including its full result, or
+// ErrAnalysisNotFound
+func (s *AnalysisStore) FindByContentHash(ctx context.Context, hash string, since time.Time) (*AnalysisRecord, error) {
+	query, args := scoped(ctx, `SELECT id FROM analyses WHERE content_hash = ? AND status = ? AND created_at >= ?`,
+		[]interface{}{hash, AnalysisStatusCompleted, since.UTC()})
+
+	var id string
+	if err := s.db.DB.GetContext(ctx, &id, s.db.DB.Rebind(query+" ORDER BY created_at DESC LIMIT 1"), args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrAnalysisNotFound
+		}
+		return nil, fmt.Errorf("failed to find analysis: %w", err)
+	}
+
+	parsed, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find analysis: %w", err)
+	}
+	return s.Get(ctx, parsed)
+}
+
+// escapeLike escapes LIKE wildcards so filenames match literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
@@ -0,0 +1,269 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+func newTestAnalysisStore(t *testing.T) *AnalysisStore {
+	t.Helper()
+	sqlxDB, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	// Each connection to :memory: is a separate database
+	sqlxDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlxDB.Close() })
+
+	store, err := NewAnalysisStore(context.Background(), &DB{SQLX: sqlxDB, DB: sqlxDB})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	return store
+}
+
+func TestAnalysisStoreSaveGetDelete(t *testing.T) {
+	ctx := context.Background()
+	store := newTestAnalysisStore(t)
+
+	record := &AnalysisRecord{
+		ID:         uuid.New(),
+		FileName:   "clip.mp4",
+		FileSize:   1024,
+		SourceType: "upload",
+		Status:     AnalysisStatusCompleted,
+		Result:     json.RawMessage(`{"format":{"format_name":"mp4"}}`),
+	}
+	if err := store.Save(ctx, record); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := store.Get(ctx, record.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.FileName != "clip.mp4" || got.FileSize != 1024 || got.Status != AnalysisStatusCompleted {
+		t.Errorf("unexpected record: %+v", got)
+	}
+	if string(got.Result) != string(record.Result) {
+		t.Errorf("result = %s, want %s", got.Result, record.Result)
+	}
+	if got.ProcessedAt == nil || got.CreatedAt.IsZero() {
+		t.Errorf("timestamps not set: %+v", got)
+	}
+
+	if err := store.Delete(ctx, record.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Get(ctx, record.ID); !errors.Is(err, ErrAnalysisNotFound) {
+		t.Errorf("Get after delete: expected ErrAnalysisNotFound, got %v", err)
+	}
+	if err := store.Delete(ctx, record.ID); !errors.Is(err, ErrAnalysisNotFound) {
+		t.Errorf("second Delete: expected ErrAnalysisNotFound, got %v", err)
+	}
+}
+
+func TestAnalysisStoreList(t *testing.T) {
+	ctx := context.Background()
+	store := newTestAnalysisStore(t)
+
+	base := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	for i, name := range []string{"news_open.mov", "promo_100%.mp4", "NEWS_close.mov", "trailer.mp4"} {
+		status := AnalysisStatusCompleted
+		if i == 3 {
+			status = AnalysisStatusFailed
+		}
+		err := store.Save(ctx, &AnalysisRecord{
+			ID:        uuid.New(),
+			FileName:  name,
+			Status:    status,
+			Result:    json.RawMessage(`{}`),
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter AnalysisFilter
+		want   []string
+		total  int
+	}{
+		{"all newest first", AnalysisFilter{}, []string{"trailer.mp4", "NEWS_close.mov", "promo_100%.mp4", "news_open.mov"}, 4},
+		{"filename case-insensitive", AnalysisFilter{FileName: "news"}, []string{"NEWS_close.mov", "news_open.mov"}, 2},
+		{"filename wildcard literal", AnalysisFilter{FileName: "0%"}, []string{"promo_100%.mp4"}, 1},
+		{"status", AnalysisFilter{Status: AnalysisStatusFailed}, []string{"trailer.mp4"}, 1},
+		{"date range", AnalysisFilter{From: base.Add(time.Hour), To: base.Add(3 * time.Hour)}, []string{"NEWS_close.mov", "promo_100%.mp4"}, 2},
+		{"pagination", AnalysisFilter{Limit: 2, Offset: 1}, []string{"NEWS_close.mov", "promo_100%.mp4"}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, total, err := store.List(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if total != tt.total {
+				t.Errorf("total = %d, want %d", total, tt.total)
+			}
+			var names []string
+			for _, record := range records {
+				names = append(names, record.FileName)
+				if record.Result != nil {
+					t.Errorf("%s: listing should not include results", record.FileName)
+				}
+			}
+			if len(names) != len(tt.want) {
+				t.Fatalf("got %v, want %v", names, tt.want)
+			}
+			for i := range names {
+				if names[i] != tt.want[i] {
+					t.Errorf("got %v, want %v", names, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestAnalysisStoreSetLLMReport(t *testing.T) {
+	ctx := context.Background()
+	store := newTestAnalysisStore(t)
+
+	record := &AnalysisRecord{
+		ID:         uuid.New(),
+		FileName:   "clip.mp4",
+		SourceType: "upload",
+		Status:     AnalysisStatusCompleted,
+		Result:     json.RawMessage(`{"format":{"format_name":"mp4"}}`),
+	}
+	if err := store.Save(ctx, record); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if err := store.SetLLMReport(ctx, record.ID, "streamed report"); err != nil {
+		t.Fatalf("SetLLMReport: %v", err)
+	}
+	got, err := store.Get(ctx, record.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.LLMReport != "streamed report" || string(got.Result) != string(record.Result) {
+		t.Errorf("unexpected record after SetLLMReport: %+v", got)
+	}
+
+	if err := store.SetLLMReport(ctx, uuid.New(), "report"); !errors.Is(err, ErrAnalysisNotFound) {
+		t.Errorf("SetLLMReport on missing record: expected ErrAnalysisNotFound, got %v", err)
+	}
+}
+
+func TestAnalysisStoreFindByContentHash(t *testing.T) {
+	ctx := context.Background()
+	globex := WithScope(ctx, Scope{OrganizationID: "globex"})
+	store := newTestAnalysisStore(t)
+	now := time.Now().UTC()
+
+	save := func(name, status string, createdAt time.Time, hash string) uuid.UUID {
+		t.Helper()
+		record := &AnalysisRecord{ID: uuid.New(), FileName: name, Status: status, Result: json.RawMessage(`{}`), CreatedAt: createdAt}
+		if err := store.Save(ctx, record); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		if err := store.SetContentHash(ctx, record.ID, hash); err != nil {
+			t.Fatalf("SetContentHash: %v", err)
+		}
+		return record.ID
+	}
+	save("old.mp4", AnalysisStatusCompleted, now.Add(-2*time.Hour), "abc")
+	newest := save("new.mp4", AnalysisStatusCompleted, now.Add(-time.Hour), "abc")
+	save("failed.mp4", AnalysisStatusFailed, now, "abc")
+	save("other.mp4", AnalysisStatusCompleted, now, "def")
+
+	got, err := store.FindByContentHash(ctx, "abc", now.Add(-3*time.Hour))
+	if err != nil {
+		t.Fatalf("FindByContentHash: %v", err)
+	}
+	if got.ID != newest || string(got.Result) != "{}" {
+		t.Errorf("expected the newest completed analysis %s with its result, got %+v", newest, got)
+	}
+
+	if _, err := store.FindByContentHash(ctx, "abc", now.Add(-30*time.Minute)); !errors.Is(err, ErrAnalysisNotFound) {
+		t.Errorf("expected ErrAnalysisNotFound past the cutoff, got %v", err)
+	}
+	if _, err := store.FindByContentHash(globex, "abc", time.Time{}); !errors.Is(err, ErrAnalysisNotFound) {
+		t.Errorf("expected ErrAnalysisNotFound from other organization, got %v", err)
+	}
+	if err := store.SetContentHash(ctx, uuid.New(), "abc"); !errors.Is(err, ErrAnalysisNotFound) {
+		t.Errorf("SetContentHash on missing record: expected ErrAnalysisNotFound, got %v", err)
+	}
+}
+
+func TestAnalysisStoreScope(t *testing.T) {
+	admin := context.Background()
+	acme := WithScope(admin, Scope{OrganizationID: "acme"})
+	acmeNews := WithScope(admin, Scope{OrganizationID: "acme", ProjectID: "news"})
+	globex := WithScope(admin, Scope{OrganizationID: "globex"})
+	store := newTestAnalysisStore(t)
+
+	save := func(ctx context.Context, name string) uuid.UUID {
+		t.Helper()
+		record := &AnalysisRecord{ID: uuid.New(), FileName: name, Status: AnalysisStatusCompleted, Result: json.RawMessage(`{}`)}
+		if err := store.Save(ctx, record); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		return record.ID
+	}
+	deployment := save(admin, "deployment.mp4")
+	news := save(acmeNews, "news.mp4")
+	acmeWide := save(acme, "acme.mp4")
+
+	listed := func(ctx context.Context) int {
+		t.Helper()
+		_, total, err := store.List(ctx, AnalysisFilter{})
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		return total
+	}
+	if got := listed(admin); got != 3 {
+		t.Errorf("deployment sees %d analyses, want 3", got)
+	}
+	if got := listed(acme); got != 2 {
+		t.Errorf("organization sees %d analyses, want 2", got)
+	}
+	if got := listed(acmeNews); got != 1 {
+		t.Errorf("project sees %d analyses, want 1", got)
+	}
+	if got := listed(globex); got != 0 {
+		t.Errorf("other organization sees %d analyses, want 0", got)
+	}
+
+	for _, id := range []uuid.UUID{deployment, news, acmeWide} {
+		if _, err := store.Get(globex, id); !errors.Is(err, ErrAnalysisNotFound) {
+			t.Errorf("Get from other organization: expected ErrAnalysisNotFound, got %v", err)
+		}
+	}
+	if _, err := store.Get(acmeNews, acmeWide); !errors.Is(err, ErrAnalysisNotFound) {
+		t.Errorf("project should not see organization-wide analyses, got %v", err)
+	}
+	if _, err := store.Get(acme, news); err != nil {
+		t.Errorf("organization should see its projects' analyses: %v", err)
+	}
+	if err := store.SetLLMReport(globex, news, "report"); !errors.Is(err, ErrAnalysisNotFound) {
+		t.Errorf("SetLLMReport from other organization: expected ErrAnalysisNotFound, got %v", err)
+	}
+	if err := store.Delete(globex, news); !errors.Is(err, ErrAnalysisNotFound) {
+		t.Errorf("Delete from other organization: expected ErrAnalysisNotFound, got %v", err)
+	}
+	if err := store.Delete(acmeNews, news); err != nil {
+		t.Errorf("Delete by owner: %v", err)
+	}
+}
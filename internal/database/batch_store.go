@@ -0,0 +1,132 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// batchJobsSchema stores batch job state for pause/resume. It is separate
+// from the migrated batches table, whose status constraint has no paused
+// state and which has no room for per-item progress.
+var batchJobsSchema = schema{
+	sqlite: []string{
+		`CREATE TABLE IF NOT EXISTS batch_jobs (
+			id TEXT PRIMARY KEY,
+			status TEXT NOT NULL,
+			state TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_batch_jobs_status ON batch_jobs(status)`,
+	},
+	postgres: []string{
+		`CREATE TABLE IF NOT EXISTS batch_jobs (
+			id TEXT PRIMARY KEY,
+			status TEXT NOT NULL,
+			state TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_batch_jobs_status ON batch_jobs(status)`,
+	},
+}
+
+// BatchJobRecord is a persisted batch job. State is the job snapshot as
+// JSON; its layout belongs to the caller.
+type BatchJobRecord struct {
+	ID        string
+	Status    string
+	State     json.RawMessage
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// BatchStore persists batch job state in the batch_jobs table
+type BatchStore struct {
+	db *DB
+}
+
+// NewBatchStore creates the batch_jobs table if needed and returns a store
+func NewBatchStore(ctx context.Context, db *DB) (*BatchStore, error) {
+	if err := db.createSchema(ctx, batchJobsSchema); err != nil {
+		return nil, fmt.Errorf("failed to create batch_jobs schema: %w", err)
+	}
+	return &BatchStore{db: db}, nil
+}
+
+// Save inserts or replaces the state of a job
+func (s *BatchStore) Save(ctx context.Context, record *BatchJobRecord) error {
+	now := time.Now().UTC()
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = now
+	}
+	record.UpdatedAt = now
+
+	query := `
+		INSERT INTO batch_jobs (id, status, state, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET status = excluded.status, state = excluded.state,
+			created_at = excluded.created_at, updated_at = excluded.updated_at`
+
+	_, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind(query),
+		record.ID,
+		record.Status,
+		string(record.State),
+		record.CreatedAt.UTC(),
+		record.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save batch job: %w", err)
+	}
+
+	return nil
+}
+
+// ListByStatus returns the jobs in any of the given statuses, oldest first
+func (s *BatchStore) ListByStatus(ctx context.Context, statuses ...string) ([]BatchJobRecord, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	query, args, err := sqlx.In(`SELECT id, status, state, created_at, updated_at
+		FROM batch_jobs WHERE status IN (?) ORDER BY created_at`, statuses)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build batch job query: %w", err)
+	}
+
+	var rows []struct {
+		ID        string    `db:"id"`
+		Status    string    `db:"status"`
+		State     string    `db:"state"`
+		CreatedAt time.Time `db:"created_at"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	if err := s.db.DB.SelectContext(ctx, &rows, s.db.DB.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to list batch jobs (%s): %w", strings.Join(statuses, ", "), err)
+	}
+
+	records := make([]BatchJobRecord, len(rows))
+	for i, row := range rows {
+		records[i] = BatchJobRecord{
+			ID:        row.ID,
+			Status:    row.Status,
+			State:     json.RawMessage(row.State),
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+		}
+	}
+	return records, nil
+}
+
+// Delete removes a job. Deleting a job that does not exist is not an error.
+func (s *BatchStore) Delete(ctx context.Context, id string) error {
+	if _, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind("DELETE FROM batch_jobs WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete batch job: %w", err)
+	}
+	return nil
+}
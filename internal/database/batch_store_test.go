@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestBatchStore(t *testing.T) {
+	ctx := context.Background()
+	sqlxDB, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlxDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlxDB.Close() })
+
+	store, err := NewBatchStore(ctx, &DB{SQLX: sqlxDB, DB: sqlxDB})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	for id, status := range map[string]string{"a": "processing", "b": "paused", "c": "completed"} {
+		record := &BatchJobRecord{ID: id, Status: status, State: json.RawMessage(`{"id":"` + id + `"}`)}
+		if err := store.Save(ctx, record); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	// Saving again replaces the earlier state
+	if err := store.Save(ctx, &BatchJobRecord{ID: "a", Status: "paused", State: json.RawMessage(`{"id":"a","done":1}`)}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	records, err := store.ListByStatus(ctx, "processing", "paused")
+	if err != nil {
+		t.Fatalf("ListByStatus: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 resumable jobs, got %d", len(records))
+	}
+	for _, record := range records {
+		if record.ID == "a" && (record.Status != "paused" || string(record.State) != `{"id":"a","done":1}`) {
+			t.Errorf("job a not replaced: %+v", record)
+		}
+		if record.UpdatedAt.IsZero() {
+			t.Errorf("job %s has no updated_at", record.ID)
+		}
+	}
+
+	if err := store.Delete(ctx, "b"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete(ctx, "missing"); err != nil {
+		t.Errorf("Delete of a missing job should succeed, got %v", err)
+	}
+	records, _ = store.ListByStatus(ctx, "paused")
+	if len(records) != 1 || records[0].ID != "a" {
+		t.Errorf("unexpected records after delete: %+v", records)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/rendiffdev/rendiff-probe/internal/config"
 	"github.com/rs/zerolog"
@@ -19,36 +20,26 @@ type DB struct {
 	DB     *sqlx.DB // Alias for SQLX to match repository expectations
 	Config *config.Config
 	Logger zerolog.Logger
-	DbType string // "sqlite" only
+	DbType string // TypeSQLite or TypePostgres
 }
 
-// New creates a new database connection
+// New creates a new database connection for the configured engine
 func New(cfg *config.Config, logger zerolog.Logger) (*DB, error) {
 	var sqlxDB *sqlx.DB
 	var err error
 
-	if cfg.DatabaseType != "sqlite" {
-		return nil, fmt.Errorf("only SQLite is supported, got: %s", cfg.DatabaseType)
+	switch cfg.DatabaseType {
+	case TypeSQLite:
+		sqlxDB, err = connectSQLite(cfg, logger)
+	case TypePostgres:
+		sqlxDB, err = connectPostgres(cfg, logger)
+	default:
+		return nil, fmt.Errorf("unsupported database type %q (use sqlite or postgres)", cfg.DatabaseType)
 	}
-
-	// Ensure database directory exists
-	if err := ensureDatabaseDir(cfg.DatabasePath); err != nil {
-		return nil, fmt.Errorf("failed to create database directory: %w", err)
-	}
-
-	// Create SQLite connection
-	sqlxDB, err = sqlx.Connect("sqlite3", cfg.DatabasePath+"?_busy_timeout=10000&_journal_mode=WAL&_foreign_keys=ON")
 	if err != nil {
-		return nil, fmt.Errorf("failed to create SQLite connection: %w", err)
+		return nil, err
 	}
 
-	// Configure SQLite connection for better performance
-	sqlxDB.SetMaxOpenConns(1) // SQLite works best with single connection
-	sqlxDB.SetMaxIdleConns(1)
-	sqlxDB.SetConnMaxLifetime(time.Hour)
-
-	logger.Info().Str("path", cfg.DatabasePath).Msg("SQLite database connection established")
-
 	// Test the sqlx connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -70,6 +61,47 @@ func New(cfg *config.Config, logger zerolog.Logger) (*DB, error) {
 	return db, nil
 }
 
+// connectSQLite opens the embedded database file, creating its directory
+func connectSQLite(cfg *config.Config, logger zerolog.Logger) (*sqlx.DB, error) {
+	// Ensure database directory exists
+	if err := ensureDatabaseDir(cfg.DatabasePath); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	// Create SQLite connection
+	sqlxDB, err := sqlx.Connect("sqlite3", cfg.DatabasePath+"?_busy_timeout=10000&_journal_mode=WAL&_foreign_keys=ON")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SQLite connection: %w", err)
+	}
+
+	// Configure SQLite connection for better performance
+	sqlxDB.SetMaxOpenConns(1) // SQLite works best with single connection
+	sqlxDB.SetMaxIdleConns(1)
+	sqlxDB.SetConnMaxLifetime(time.Hour)
+
+	logger.Info().Str("path", cfg.DatabasePath).Msg("SQLite database connection established")
+	return sqlxDB, nil
+}
+
+// connectPostgres opens a pooled connection to the server at DatabaseURL
+func connectPostgres(cfg *config.Config, logger zerolog.Logger) (*sqlx.DB, error) {
+	sqlxDB, err := sqlx.Connect("postgres", cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create PostgreSQL connection: %w", err)
+	}
+
+	if cfg.DatabaseMaxOpenConns > 0 {
+		sqlxDB.SetMaxOpenConns(cfg.DatabaseMaxOpenConns)
+	}
+	if cfg.DatabaseMaxIdleConns > 0 {
+		sqlxDB.SetMaxIdleConns(cfg.DatabaseMaxIdleConns)
+	}
+	sqlxDB.SetConnMaxLifetime(time.Hour)
+
+	logger.Info().Int("max_open_conns", cfg.DatabaseMaxOpenConns).Msg("PostgreSQL database connection established")
+	return sqlxDB, nil
+}
+
 // ensureDatabaseDir creates the directory for the SQLite database if it doesn't exist
 func ensureDatabaseDir(dbPath string) error {
 	dir := filepath.Dir(dbPath)
@@ -93,7 +125,6 @@ func (db *DB) Close() {
 
 // Health checks the database connection health
 func (db *DB) Health(ctx context.Context) error {
-	// Check sqlx connection for SQLite
 	if err := db.SQLX.PingContext(ctx); err != nil {
 		return fmt.Errorf("sqlx health check failed: %w", err)
 	}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// Supported database engines, as set by DB_TYPE
+const (
+	TypeSQLite   = "sqlite"
+	TypePostgres = "postgres"
+)
+
+// schema holds the statements that create a store's tables on each engine.
+// Queries are written once with ? placeholders and rebound for the engine,
+// so only the DDL differs.
+type schema struct {
+	sqlite   []string
+	postgres []string
+}
+
+// IsPostgres reports whether db is connected to PostgreSQL. Connections
+// without a type, as tests open them, are SQLite.
+func (db *DB) IsPostgres() bool {
+	return db.DbType == TypePostgres
+}
+
+// createSchema runs the statements of s for the engine of db
+func (db *DB) createSchema(ctx context.Context, s schema) error {
+	statements := s.sqlite
+	if db.IsPostgres() {
+		statements = s.postgres
+	}
+	for _, statement := range statements {
+		if _, err := db.DB.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nullableJSON stores an empty document as NULL; PostgreSQL rejects an
+// empty string in a JSONB column
+func nullableJSON(document json.RawMessage) interface{} {
+	if len(document) == 0 {
+		return nil
+	}
+	return string(document)
+}
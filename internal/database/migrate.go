@@ -3,9 +3,11 @@ package database
 import (
 	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/rs/zerolog"
@@ -13,11 +15,8 @@ import (
 
 // MigrateUp runs all available migrations
 func MigrateUp(databaseURL string, migrationsPath string, logger zerolog.Logger) error {
-	// Determine migration path based on database type
-	finalMigrationsPath := getMigrationPath(databaseURL, migrationsPath)
-
 	m, err := migrate.New(
-		fmt.Sprintf("file://%s", finalMigrationsPath),
+		fmt.Sprintf("file://%s", getMigrationPath(databaseURL, migrationsPath)),
 		databaseURL,
 	)
 	if err != nil {
@@ -37,19 +36,19 @@ func MigrateUp(databaseURL string, migrationsPath string, logger zerolog.Logger)
 	return nil
 }
 
-// getMigrationPath returns the appropriate migration path based on database type
+// getMigrationPath returns the migrations for the engine of databaseURL.
+// Each engine has its own directory under basePath with the same versions.
 func getMigrationPath(databaseURL, basePath string) string {
-	if strings.HasPrefix(databaseURL, "sqlite3://") {
-		// Use SQLite-specific migrations if they exist
-		return basePath // For now, use the same path but we could organize differently
+	if strings.HasPrefix(databaseURL, "postgres://") || strings.HasPrefix(databaseURL, "postgresql://") {
+		return filepath.Join(basePath, TypePostgres)
 	}
-	return basePath
+	return filepath.Join(basePath, TypeSQLite)
 }
 
 // MigrateDown rolls back one migration
 func MigrateDown(databaseURL string, migrationsPath string, logger zerolog.Logger) error {
 	m, err := migrate.New(
-		fmt.Sprintf("file://%s", migrationsPath),
+		fmt.Sprintf("file://%s", getMigrationPath(databaseURL, migrationsPath)),
 		databaseURL,
 	)
 	if err != nil {
@@ -72,7 +71,7 @@ func MigrateDown(databaseURL string, migrationsPath string, logger zerolog.Logge
 // MigrateToVersion migrates to a specific version
 func MigrateToVersion(databaseURL string, migrationsPath string, version uint, logger zerolog.Logger) error {
 	m, err := migrate.New(
-		fmt.Sprintf("file://%s", migrationsPath),
+		fmt.Sprintf("file://%s", getMigrationPath(databaseURL, migrationsPath)),
 		databaseURL,
 	)
 	if err != nil {
@@ -95,7 +94,7 @@ func MigrateToVersion(databaseURL string, migrationsPath string, version uint, l
 // GetMigrationVersion returns the current migration version
 func GetMigrationVersion(databaseURL string, migrationsPath string) (uint, bool, error) {
 	m, err := migrate.New(
-		fmt.Sprintf("file://%s", migrationsPath),
+		fmt.Sprintf("file://%s", getMigrationPath(databaseURL, migrationsPath)),
 		databaseURL,
 	)
 	if err != nil {
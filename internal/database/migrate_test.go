@@ -0,0 +1,62 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestGetMigrationPath(t *testing.T) {
+	tests := map[string]string{
+		"sqlite3:///data/rendiff-probe.db":           filepath.Join("migrations", "sqlite"),
+		"postgres://rendiff@db:5432/rendiff_probe":   filepath.Join("migrations", "postgres"),
+		"postgresql://rendiff@db:5432/rendiff_probe": filepath.Join("migrations", "postgres"),
+	}
+	for url, want := range tests {
+		if got := getMigrationPath(url, "migrations"); got != want {
+			t.Errorf("getMigrationPath(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+// Both engines must carry the same migration versions
+func TestMigrationsMatchAcrossEngines(t *testing.T) {
+	names := func(engine string) []string {
+		entries, err := os.ReadDir(filepath.Join("migrations", engine))
+		if err != nil {
+			t.Fatalf("failed to read %s migrations: %v", engine, err)
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	sqlite, postgres := names(TypeSQLite), names(TypePostgres)
+	if len(sqlite) == 0 || !reflect.DeepEqual(sqlite, postgres) {
+		t.Errorf("sqlite migrations %v differ from postgres migrations %v", sqlite, postgres)
+	}
+}
+
+func TestMigrateUpDownSQLite(t *testing.T) {
+	databaseURL := "sqlite3://" + filepath.Join(t.TempDir(), "migrate.db")
+
+	if err := MigrateUp(databaseURL, "migrations", zerolog.Nop()); err != nil {
+		t.Fatalf("MigrateUp: %v", err)
+	}
+	version, dirty, err := GetMigrationVersion(databaseURL, "migrations")
+	if err != nil || dirty || version != 4 {
+		t.Errorf("version = %d, dirty %v, err %v, want 4", version, dirty, err)
+	}
+
+	if err := MigrateDown(databaseURL, "migrations", zerolog.Nop()); err != nil {
+		t.Fatalf("MigrateDown: %v", err)
+	}
+	if version, _, _ := GetMigrationVersion(databaseURL, "migrations"); version != 3 {
+		t.Errorf("version after rollback = %d, want 3", version)
+	}
+}
@@ -0,0 +1,7 @@
+-- Rollback script for the tenancy migration
+
+DROP TABLE IF EXISTS analysis_owners;
+DROP TABLE IF EXISTS policies;
+DROP TABLE IF EXISTS tenant_api_keys;
+DROP TABLE IF EXISTS projects;
+DROP TABLE IF EXISTS organizations;
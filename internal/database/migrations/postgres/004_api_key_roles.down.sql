@@ -0,0 +1,3 @@
+-- Rollback script for the API key roles migration
+
+DROP TABLE IF EXISTS api_key_roles;
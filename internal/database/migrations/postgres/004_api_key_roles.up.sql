@@ -0,0 +1,7 @@
+-- Roles of tenant API keys. A key without a row is an admin key, as every
+-- key was before roles existed. The tenant store also creates this table.
+
+CREATE TABLE IF NOT EXISTS api_key_roles (
+    key_id TEXT PRIMARY KEY,
+    role TEXT NOT NULL
+);
@@ -1,5 +1,5 @@
 -- FFprobe API SQLite Schema Migration
--- SQLite-compatible version of the core schema
+-- SQLite version of the core schema; postgres/001_initial_schema mirrors it
 
 -- Users table
 CREATE TABLE users (
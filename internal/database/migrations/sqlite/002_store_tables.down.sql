@@ -0,0 +1,7 @@
+-- Rollback script for the store tables migration
+
+DROP TABLE IF EXISTS watch_results;
+DROP TABLE IF EXISTS analysis_thumbnails;
+DROP TABLE IF EXISTS monitor_samples;
+DROP TABLE IF EXISTS monitors;
+DROP TABLE IF EXISTS batch_jobs;
@@ -0,0 +1,53 @@
+-- Tables owned by the batch, monitor, thumbnail and watch folder stores.
+-- The stores also create them on start, so every statement is idempotent.
+
+CREATE TABLE IF NOT EXISTS batch_jobs (
+    id TEXT PRIMARY KEY,
+    status TEXT NOT NULL,
+    state TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS monitors (
+    id TEXT PRIMARY KEY,
+    name TEXT,
+    state TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS monitor_samples (
+    monitor_id TEXT NOT NULL,
+    sampled_at DATETIME NOT NULL,
+    sample TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS analysis_thumbnails (
+    analysis_id TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    mode TEXT NOT NULL,
+    time_seconds REAL NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    jpeg BLOB NOT NULL,
+    PRIMARY KEY (analysis_id, position)
+);
+
+CREATE TABLE IF NOT EXISTS watch_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    folder TEXT NOT NULL,
+    filename TEXT NOT NULL,
+    file_size INTEGER,
+    analysis_id TEXT,
+    verdict TEXT NOT NULL,
+    reasons TEXT,
+    moved_to TEXT,
+    error_msg TEXT,
+    processed_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_batch_jobs_status ON batch_jobs(status);
+CREATE INDEX IF NOT EXISTS idx_monitor_samples_monitor ON monitor_samples(monitor_id, sampled_at);
+CREATE INDEX IF NOT EXISTS idx_monitor_samples_sampled_at ON monitor_samples(sampled_at);
+CREATE INDEX IF NOT EXISTS idx_watch_results_processed_at ON watch_results(processed_at);
@@ -0,0 +1,46 @@
+-- Organizations, projects, tenant API keys, tenant QC policies and the
+-- owner of each analysis. The tenant and analysis stores also create these
+-- tables on start, so every statement is idempotent.
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS projects (
+    id TEXT PRIMARY KEY,
+    organization_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    UNIQUE (organization_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS tenant_api_keys (
+    id TEXT PRIMARY KEY,
+    organization_id TEXT NOT NULL,
+    project_id TEXT NOT NULL DEFAULT '',
+    name TEXT,
+    key_prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL UNIQUE,
+    created_at DATETIME NOT NULL,
+    last_used_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS policies (
+    organization_id TEXT NOT NULL DEFAULT '',
+    id TEXT NOT NULL,
+    project_id TEXT NOT NULL DEFAULT '',
+    definition TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    PRIMARY KEY (organization_id, id)
+);
+
+CREATE TABLE IF NOT EXISTS analysis_owners (
+    analysis_id TEXT PRIMARY KEY,
+    organization_id TEXT NOT NULL,
+    project_id TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_api_keys_scope ON tenant_api_keys(organization_id, project_id);
+CREATE INDEX IF NOT EXISTS idx_analysis_owners_scope ON analysis_owners(organization_id, project_id);
@@ -0,0 +1,217 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Monitor sample listing bounds
+const (
+	DefaultMonitorSampleLimit = 100
+	MaxMonitorSampleLimit     = 1000
+)
+
+// monitorsSchema stores live stream monitors and the time series of their
+// samples
+var monitorsSchema = schema{
+	sqlite: []string{
+		`CREATE TABLE IF NOT EXISTS monitors (
+			id TEXT PRIMARY KEY,
+			name TEXT,
+			state TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS monitor_samples (
+			monitor_id TEXT NOT NULL,
+			sampled_at DATETIME NOT NULL,
+			sample TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_monitor_samples_monitor ON monitor_samples(monitor_id, sampled_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_monitor_samples_sampled_at ON monitor_samples(sampled_at)`,
+	},
+	postgres: []string{
+		`CREATE TABLE IF NOT EXISTS monitors (
+			id TEXT PRIMARY KEY,
+			name TEXT,
+			state TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS monitor_samples (
+			monitor_id TEXT NOT NULL,
+			sampled_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			sample TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_monitor_samples_monitor ON monitor_samples(monitor_id, sampled_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_monitor_samples_sampled_at ON monitor_samples(sampled_at)`,
+	},
+}
+
+// MonitorRecord is a persisted monitor. State is the monitor definition as
+// JSON; its layout belongs to the caller.
+type MonitorRecord struct {
+	ID        string
+	Name      string
+	State     json.RawMessage
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// MonitorSampleRecord is one sample of a monitor. Sample is the measured
+// metrics as JSON.
+type MonitorSampleRecord struct {
+	MonitorID string          `json:"-"`
+	SampledAt time.Time       `json:"sampled_at"`
+	Sample    json.RawMessage `json:"sample"`
+}
+
+// MonitorStore persists monitors and their samples
+type MonitorStore struct {
+	db *DB
+}
+
+// NewMonitorStore creates the monitor tables if needed and returns a store
+func NewMonitorStore(ctx context.Context, db *DB) (*MonitorStore, error) {
+	if err := db.createSchema(ctx, monitorsSchema); err != nil {
+		return nil, fmt.Errorf("failed to create monitors schema: %w", err)
+	}
+	return &MonitorStore{db: db}, nil
+}
+
+// Save inserts or replaces a monitor
+func (s *MonitorStore) Save(ctx context.Context, record *MonitorRecord) error {
+	now := time.Now().UTC()
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = now
+	}
+	record.UpdatedAt = now
+
+	query := `
+		INSERT INTO monitors (id, name, state, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET name = excluded.name, state = excluded.state,
+			created_at = excluded.created_at, updated_at = excluded.updated_at`
+
+	_, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind(query),
+		record.ID,
+		record.Name,
+		string(record.State),
+		record.CreatedAt.UTC(),
+		record.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save monitor: %w", err)
+	}
+
+	return nil
+}
+
+// List returns every monitor, oldest first
+func (s *MonitorStore) List(ctx context.Context) ([]MonitorRecord, error) {
+	var rows []struct {
+		ID        string    `db:"id"`
+		Name      string    `db:"name"`
+		State     string    `db:"state"`
+		CreatedAt time.Time `db:"created_at"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	query := `SELECT id, COALESCE(name, '') AS name, state, created_at, updated_at
+		FROM monitors ORDER BY created_at`
+	if err := s.db.DB.SelectContext(ctx, &rows, query); err != nil {
+		return nil, fmt.Errorf("failed to list monitors: %w", err)
+	}
+
+	records := make([]MonitorRecord, len(rows))
+	for i, row := range rows {
+		records[i] = MonitorRecord{
+			ID:        row.ID,
+			Name:      row.Name,
+			State:     json.RawMessage(row.State),
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+		}
+	}
+	return records, nil
+}
+
+// Delete removes a monitor and its samples. Deleting a monitor that does not
+// exist is not an error.
+func (s *MonitorStore) Delete(ctx context.Context, id string) error {
+	if _, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind("DELETE FROM monitor_samples WHERE monitor_id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete monitor samples: %w", err)
+	}
+	if _, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind("DELETE FROM monitors WHERE id = ?"), id); err != nil {
+		return fmt.Errorf("failed to delete monitor: %w", err)
+	}
+	return nil
+}
+
+// AddSample appends a sample to a monitor's time series
+func (s *MonitorStore) AddSample(ctx context.Context, record *MonitorSampleRecord) error {
+	_, err := s.db.DB.ExecContext(ctx,
+		s.db.DB.Rebind("INSERT INTO monitor_samples (monitor_id, sampled_at, sample) VALUES (?, ?, ?)"),
+		record.MonitorID,
+		record.SampledAt.UTC(),
+		string(record.Sample),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save monitor sample: %w", err)
+	}
+	return nil
+}
+
+// Samples returns a monitor's samples taken in [from, to), newest first.
+// Zero times leave that end of the range open.
+func (s *MonitorStore) Samples(ctx context.Context, monitorID string, from, to time.Time, limit int) ([]MonitorSampleRecord, error) {
+	if limit <= 0 {
+		limit = DefaultMonitorSampleLimit
+	}
+	if limit > MaxMonitorSampleLimit {
+		limit = MaxMonitorSampleLimit
+	}
+
+	query := "SELECT monitor_id, sampled_at, sample FROM monitor_samples WHERE monitor_id = ?"
+	args := []interface{}{monitorID}
+	if !from.IsZero() {
+		query += " AND sampled_at >= ?"
+		args = append(args, from.UTC())
+	}
+	if !to.IsZero() {
+		query += " AND sampled_at < ?"
+		args = append(args, to.UTC())
+	}
+	query += " ORDER BY sampled_at DESC LIMIT ?"
+	args = append(args, limit)
+
+	var rows []struct {
+		MonitorID string    `db:"monitor_id"`
+		SampledAt time.Time `db:"sampled_at"`
+		Sample    string    `db:"sample"`
+	}
+	if err := s.db.DB.SelectContext(ctx, &rows, s.db.DB.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to list monitor samples: %w", err)
+	}
+
+	records := make([]MonitorSampleRecord, len(rows))
+	for i, row := range rows {
+		records[i] = MonitorSampleRecord{
+			MonitorID: row.MonitorID,
+			SampledAt: row.SampledAt,
+			Sample:    json.RawMessage(row.Sample),
+		}
+	}
+	return records, nil
+}
+
+// PruneSamples deletes samples taken before the given time and returns how
+// many were deleted
+func (s *MonitorStore) PruneSamples(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind("DELETE FROM monitor_samples WHERE sampled_at < ?"), before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune monitor samples: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestMonitorStore(t *testing.T) {
+	ctx := context.Background()
+	sqlxDB, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlxDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlxDB.Close() })
+
+	store, err := NewMonitorStore(ctx, &DB{SQLX: sqlxDB, DB: sqlxDB})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	for _, id := range []string{"a", "b"} {
+		record := &MonitorRecord{ID: id, Name: "channel " + id, State: json.RawMessage(`{"url":"udp://` + id + `"}`)}
+		if err := store.Save(ctx, record); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		for _, id := range []string{"a", "b"} {
+			sample := &MonitorSampleRecord{
+				MonitorID: id,
+				SampledAt: start.Add(time.Duration(i) * time.Minute),
+				Sample:    json.RawMessage(`{"bit_rate":` + string(rune('0'+i)) + `}`),
+			}
+			if err := store.AddSample(ctx, sample); err != nil {
+				t.Fatalf("AddSample: %v", err)
+			}
+		}
+	}
+
+	samples, err := store.Samples(ctx, "a", start.Add(time.Minute), start.Add(4*time.Minute), 0)
+	if err != nil {
+		t.Fatalf("Samples: %v", err)
+	}
+	if len(samples) != 3 || string(samples[0].Sample) != `{"bit_rate":3}` || string(samples[2].Sample) != `{"bit_rate":1}` {
+		t.Errorf("expected minutes 3 to 1 newest first, got %+v", samples)
+	}
+
+	if samples, _ = store.Samples(ctx, "a", time.Time{}, time.Time{}, 2); len(samples) != 2 {
+		t.Errorf("expected limit of 2 samples, got %d", len(samples))
+	}
+
+	pruned, err := store.PruneSamples(ctx, start.Add(2*time.Minute))
+	if err != nil {
+		t.Fatalf("PruneSamples: %v", err)
+	}
+	if pruned != 4 {
+		t.Errorf("expected 4 samples pruned, got %d", pruned)
+	}
+
+	if err := store.Delete(ctx, "b"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	records, err := store.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(records) != 1 || records[0].ID != "a" || records[0].Name != "channel a" {
+		t.Errorf("unexpected records after delete: %+v", records)
+	}
+	if samples, _ = store.Samples(ctx, "b", time.Time{}, time.Time{}, 0); len(samples) != 0 {
+		t.Errorf("expected samples of b to be deleted, got %d", len(samples))
+	}
+}
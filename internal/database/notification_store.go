@@ -0,0 +1,217 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrNotificationChannelNotFound is returned for unknown or foreign
+// notification channels
+var ErrNotificationChannelNotFound = errors.New("notification channel not found")
+
+// notificationChannelsSchema stores the email, Slack and Teams channels
+// tenants are notified on. Events is a comma-separated list; empty
+// subscribes to every event.
+var notificationChannelsSchema = schema{
+	sqlite: []string{
+		`CREATE TABLE IF NOT EXISTS notification_channels (
+			id TEXT PRIMARY KEY,
+			organization_id TEXT NOT NULL DEFAULT '',
+			project_id TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL DEFAULT '',
+			type TEXT NOT NULL,
+			target TEXT NOT NULL,
+			events TEXT NOT NULL DEFAULT '',
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_notification_channels_scope ON notification_channels(organization_id, project_id)`,
+	},
+	postgres: []string{
+		`CREATE TABLE IF NOT EXISTS notification_channels (
+			id TEXT PRIMARY KEY,
+			organization_id TEXT NOT NULL DEFAULT '',
+			project_id TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL DEFAULT '',
+			type TEXT NOT NULL,
+			target TEXT NOT NULL,
+			events TEXT NOT NULL DEFAULT '',
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_notification_channels_scope ON notification_channels(organization_id, project_id)`,
+	},
+}
+
+// NotificationChannelRecord is a stored notification channel. Target is a
+// webhook URL or a list of email addresses, depending on Type.
+type NotificationChannelRecord struct {
+	ID        string
+	Owner     Scope
+	Name      string
+	Type      string
+	Target    string
+	Events    []string // Empty subscribes to every event
+	CreatedAt time.Time
+}
+
+// NotificationStore persists notification channels in the
+// notification_channels table
+type NotificationStore struct {
+	db *DB
+}
+
+// NewNotificationStore creates the notification_channels table if needed
+// and returns a store
+func NewNotificationStore(ctx context.Context, db *DB) (*NotificationStore, error) {
+	if err := db.createSchema(ctx, notificationChannelsSchema); err != nil {
+		return nil, fmt.Errorf("failed to create notification channels schema: %w", err)
+	}
+	return &NotificationStore{db: db}, nil
+}
+
+// Create stores a channel owned by the scope of ctx
+func (s *NotificationStore) Create(ctx context.Context, record *NotificationChannelRecord) error {
+	record.ID = uuid.New().String()
+	record.Owner = ScopeFromContext(ctx)
+	record.CreatedAt = time.Now().UTC()
+
+	_, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind(`
+		INSERT INTO notification_channels (id, organization_id, project_id, name, type, target, events, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+		record.ID, record.Owner.OrganizationID, record.Owner.ProjectID, record.Name, record.Type,
+		record.Target, strings.Join(record.Events, ","), record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create notification channel: %w", err)
+	}
+	return nil
+}
+
+// notificationChannelRow is a notification_channels row as scanned
+type notificationChannelRow struct {
+	ID             string    `db:"id"`
+	OrganizationID string    `db:"organization_id"`
+	ProjectID      string    `db:"project_id"`
+	Name           string    `db:"name"`
+	Type           string    `db:"type"`
+	Target         string    `db:"target"`
+	Events         string    `db:"events"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+func (r notificationChannelRow) record() NotificationChannelRecord {
+	record := NotificationChannelRecord{
+		ID:        r.ID,
+		Owner:     Scope{OrganizationID: r.OrganizationID, ProjectID: r.ProjectID},
+		Name:      r.Name,
+		Type:      r.Type,
+		Target:    r.Target,
+		Events:    []string{},
+		CreatedAt: r.CreatedAt,
+	}
+	if r.Events != "" {
+		record.Events = strings.Split(r.Events, ",")
+	}
+	return record
+}
+
+const notificationChannelColumns = "id, organization_id, project_id, name, type, target, events, created_at"
+
+// ownedChannels restricts a query to the channels the scope of ctx
+// manages: all for the deployment, an organization's own including its
+// projects', or a project's own
+func ownedChannels(ctx context.Context) (string, []interface{}) {
+	scope := ScopeFromContext(ctx)
+	if scope.IsZero() {
+		return "", nil
+	}
+	if scope.ProjectID == "" {
+		return "organization_id = ?", []interface{}{scope.OrganizationID}
+	}
+	return "organization_id = ? AND project_id = ?", []interface{}{scope.OrganizationID, scope.ProjectID}
+}
+
+// List returns the channels managed by the scope of ctx, oldest first
+func (s *NotificationStore) List(ctx context.Context) ([]NotificationChannelRecord, error) {
+	query := "SELECT " + notificationChannelColumns + " FROM notification_channels"
+	condition, args := ownedChannels(ctx)
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+	return s.list(ctx, query+" ORDER BY created_at, id", args)
+}
+
+// Get returns a channel managed by the scope of ctx, or
+// ErrNotificationChannelNotFound
+func (s *NotificationStore) Get(ctx context.Context, id string) (*NotificationChannelRecord, error) {
+	query := "SELECT " + notificationChannelColumns + " FROM notification_channels WHERE id = ?"
+	args := []interface{}{id}
+	if condition, scopeArgs := ownedChannels(ctx); condition != "" {
+		query += " AND " + condition
+		args = append(args, scopeArgs...)
+	}
+
+	var row notificationChannelRow
+	err := s.db.DB.GetContext(ctx, &row, s.db.DB.Rebind(query), args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotificationChannelNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification channel: %w", err)
+	}
+	record := row.record()
+	return &record, nil
+}
+
+// Delete removes a channel managed by the scope of ctx, returning
+// ErrNotificationChannelNotFound otherwise
+func (s *NotificationStore) Delete(ctx context.Context, id string) error {
+	query := "DELETE FROM notification_channels WHERE id = ?"
+	args := []interface{}{id}
+	if condition, scopeArgs := ownedChannels(ctx); condition != "" {
+		query += " AND " + condition
+		args = append(args, scopeArgs...)
+	}
+
+	result, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification channel: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete notification channel: %w", err)
+	}
+	if rows == 0 {
+		return ErrNotificationChannelNotFound
+	}
+	return nil
+}
+
+// Recipients returns the channels notified of an event in scope: the
+// deployment's, and for a tenant event those of its organization and of
+// its project. Event subscriptions are left to the caller.
+func (s *NotificationStore) Recipients(ctx context.Context, scope Scope) ([]NotificationChannelRecord, error) {
+	query := "SELECT " + notificationChannelColumns + " FROM notification_channels WHERE organization_id = ''"
+	var args []interface{}
+	if !scope.IsZero() {
+		query += " OR (organization_id = ? AND project_id IN ('', ?))"
+		args = append(args, scope.OrganizationID, scope.ProjectID)
+	}
+	return s.list(ctx, query+" ORDER BY created_at, id", args)
+}
+
+func (s *NotificationStore) list(ctx context.Context, query string, args []interface{}) ([]NotificationChannelRecord, error) {
+	var rows []notificationChannelRow
+	if err := s.db.DB.SelectContext(ctx, &rows, s.db.DB.Rebind(query), args...); err != nil {
+		return nil, fmt.Errorf("failed to list notification channels: %w", err)
+	}
+	records := make([]NotificationChannelRecord, len(rows))
+	for i, row := range rows {
+		records[i] = row.record()
+	}
+	return records, nil
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestNotificationStore(t *testing.T) {
+	sqlxDB, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlxDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlxDB.Close() })
+
+	store, err := NewNotificationStore(context.Background(), &DB{SQLX: sqlxDB, DB: sqlxDB})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	admin := context.Background()
+	acme := WithScope(admin, Scope{OrganizationID: "acme"})
+	acmeNews := WithScope(admin, Scope{OrganizationID: "acme", ProjectID: "news"})
+	acmeSport := WithScope(admin, Scope{OrganizationID: "acme", ProjectID: "sport"})
+	globex := WithScope(admin, Scope{OrganizationID: "globex"})
+
+	create := func(ctx context.Context, name string, events ...string) *NotificationChannelRecord {
+		t.Helper()
+		record := &NotificationChannelRecord{Name: name, Type: "slack", Target: "https://hooks.slack.com/services/" + name, Events: events}
+		if err := store.Create(ctx, record); err != nil {
+			t.Fatalf("Create %s: %v", name, err)
+		}
+		return record
+	}
+	ops := create(admin, "ops", "watch.error")
+	create(acme, "acme-qc")
+	news := create(acmeNews, "news", "batch.completed", "policy.failed")
+	create(globex, "globex")
+
+	got, err := store.Get(acmeNews, news.ID)
+	if err != nil || got.Name != "news" || len(got.Events) != 2 || got.Events[1] != "policy.failed" || got.Owner.ProjectID != "news" {
+		t.Errorf("Get: got %+v, %v", got, err)
+	}
+	if _, err := store.Get(acmeSport, news.ID); !errors.Is(err, ErrNotificationChannelNotFound) {
+		t.Errorf("other project's channel: expected ErrNotificationChannelNotFound, got %v", err)
+	}
+	if _, err := store.Get(globex, ops.ID); !errors.Is(err, ErrNotificationChannelNotFound) {
+		t.Errorf("tenants should not manage deployment channels: got %v", err)
+	}
+
+	if channels, err := store.List(acme); err != nil || len(channels) != 2 {
+		t.Errorf("acme should manage its own and its projects' channels: got %+v, %v", channels, err)
+	}
+	if channels, err := store.List(admin); err != nil || len(channels) != 4 {
+		t.Errorf("the deployment should manage every channel: got %+v, %v", channels, err)
+	}
+
+	names := func(scope Scope) []string {
+		t.Helper()
+		channels, err := store.Recipients(admin, scope)
+		if err != nil {
+			t.Fatalf("Recipients: %v", err)
+		}
+		var names []string
+		for _, channel := range channels {
+			names = append(names, channel.Name)
+		}
+		return names
+	}
+	if got := names(Scope{OrganizationID: "acme", ProjectID: "news"}); len(got) != 3 || got[0] != "ops" || got[2] != "news" {
+		t.Errorf("news events should reach the deployment, acme and news channels: got %v", got)
+	}
+	if got := names(Scope{OrganizationID: "acme", ProjectID: "sport"}); len(got) != 2 {
+		t.Errorf("sport events should not reach the news channel: got %v", got)
+	}
+	if got := names(Scope{}); len(got) != 1 || got[0] != "ops" {
+		t.Errorf("deployment events should only reach deployment channels: got %v", got)
+	}
+
+	if err := store.Delete(acmeSport, news.ID); !errors.Is(err, ErrNotificationChannelNotFound) {
+		t.Errorf("deleting another project's channel: expected ErrNotificationChannelNotFound, got %v", err)
+	}
+	if err := store.Delete(acme, news.ID); err != nil {
+		t.Errorf("organization should delete its project's channel: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrPolicyNotFound is returned for unknown or foreign policies
+	ErrPolicyNotFound = errors.New("policy not found")
+	// ErrPolicyExists is returned when the organization already has a
+	// policy with the ID
+	ErrPolicyExists = errors.New("policy already exists")
+)
+
+// policiesSchema stores tenant QC policies. Policies without an
+// organization belong to the deployment and apply to every tenant.
+var policiesSchema = schema{
+	sqlite: []string{
+		`CREATE TABLE IF NOT EXISTS policies (
+			organization_id TEXT NOT NULL DEFAULT '',
+			id TEXT NOT NULL,
+			project_id TEXT NOT NULL DEFAULT '',
+			definition TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			PRIMARY KEY (organization_id, id)
+		)`,
+	},
+	postgres: []string{
+		`CREATE TABLE IF NOT EXISTS policies (
+			organization_id TEXT NOT NULL DEFAULT '',
+			id TEXT NOT NULL,
+			project_id TEXT NOT NULL DEFAULT '',
+			definition TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			PRIMARY KEY (organization_id, id)
+		)`,
+	},
+}
+
+// PolicyRecord is a stored QC policy. Definition is the policy as JSON;
+// its layout belongs to the caller.
+type PolicyRecord struct {
+	ID         string
+	Owner      Scope
+	Definition json.RawMessage
+	CreatedAt  time.Time
+}
+
+// PolicyStore persists tenant QC policies in the policies table
+type PolicyStore struct {
+	db *DB
+}
+
+// NewPolicyStore creates the policies table if needed and returns a store
+func NewPolicyStore(ctx context.Context, db *DB) (*PolicyStore, error) {
+	if err := db.createSchema(ctx, policiesSchema); err != nil {
+		return nil, fmt.Errorf("failed to create policies schema: %w", err)
+	}
+	return &PolicyStore{db: db}, nil
+}
+
+// Create stores a policy owned by the scope of ctx, returning
+// ErrPolicyExists if its organization already has one with the ID
+func (s *PolicyStore) Create(ctx context.Context, record *PolicyRecord) error {
+	record.Owner = ScopeFromContext(ctx)
+	record.CreatedAt = time.Now().UTC()
+
+	var taken int
+	if err := s.db.DB.GetContext(ctx, &taken,
+		s.db.DB.Rebind("SELECT COUNT(*) FROM policies WHERE organization_id = ? AND id = ?"),
+		record.Owner.OrganizationID, record.ID); err != nil {
+		return fmt.Errorf("failed to create policy: %w", err)
+	}
+	if taken > 0 {
+		return ErrPolicyExists
+	}
+
+	_, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind(`
+		INSERT INTO policies (organization_id, id, project_id, definition, created_at)
+		VALUES (?, ?, ?, ?, ?)`),
+		record.Owner.OrganizationID, record.ID, record.Owner.ProjectID, string(record.Definition), record.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create policy: %w", err)
+	}
+	return nil
+}
+
+// policyRow is a policies row as scanned
+type policyRow struct {
+	OrganizationID string    `db:"organization_id"`
+	ID             string    `db:"id"`
+	ProjectID      string    `db:"project_id"`
+	Definition     string    `db:"definition"`
+	CreatedAt      time.Time `db:"created_at"`
+}
+
+func (r policyRow) record() PolicyRecord {
+	return PolicyRecord{
+		ID:         r.ID,
+		Owner:      Scope{OrganizationID: r.OrganizationID, ProjectID: r.ProjectID},
+		Definition: json.RawMessage(r.Definition),
+		CreatedAt:  r.CreatedAt,
+	}
+}
+
+// visiblePolicies restricts a policies query to those the scope of ctx
+// can apply: the deployment's, its organization's and, for a project
+// scope, those of its project. Unlike analyses, organization-wide policies
+// are shared with every project.
+func visiblePolicies(ctx context.Context) (string, []interface{}) {
+	scope := ScopeFromContext(ctx)
+	if scope.IsZero() {
+		return "", nil
+	}
+	if scope.ProjectID == "" {
+		return "(organization_id = '' OR organization_id = ?)", []interface{}{scope.OrganizationID}
+	}
+	return "(organization_id = '' OR (organization_id = ? AND project_id IN ('', ?)))",
+		[]interface{}{scope.OrganizationID, scope.ProjectID}
+}
+
+// Get returns the policy with the ID visible to the scope of ctx,
+// preferring the organization's own over the deployment's, or
+// ErrPolicyNotFound
+func (s *PolicyStore) Get(ctx context.Context, id string) (*PolicyRecord, error) {
+	query := "SELECT organization_id, id, project_id, definition, created_at FROM policies WHERE id = ?"
+	args := []interface{}{id}
+	if condition, scopeArgs := visiblePolicies(ctx); condition != "" {
+		query += " AND " + condition
+		args = append(args, scopeArgs...)
+	}
+
+	var row policyRow
+	err := s.db.DB.GetContext(ctx, &row, s.db.DB.Rebind(query+" ORDER BY organization_id DESC LIMIT 1"), args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPolicyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get policy: %w", err)
+	}
+	record := row.record()
+	return &record, nil
+}
+
+// List returns the policies visible to the scope of ctx by ID
+func (s *PolicyStore) List(ctx context.Context) ([]PolicyRecord, error) {
+	query := "SELECT organization_id, id, project_id, definition, created_at FROM policies"
+	condition, args := visiblePolicies(ctx)
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+
+	var rows []policyRow
+	if err := s.db.DB.SelectContext(ctx, &rows, s.db.DB.Rebind(query+" ORDER BY id, organization_id"), args...); err != nil {
+		return nil, fmt.Errorf("failed to list policies: %w", err)
+	}
+	records := make([]PolicyRecord, len(rows))
+	for i, row := range rows {
+		records[i] = row.record()
+	}
+	return records, nil
+}
+
+// Delete removes a policy owned by the scope of ctx itself, returning
+// ErrPolicyNotFound otherwise. Tenants cannot delete deployment policies.
+func (s *PolicyStore) Delete(ctx context.Context, id string) error {
+	scope := ScopeFromContext(ctx)
+	query := "DELETE FROM policies WHERE id = ? AND organization_id = ?"
+	args := []interface{}{id, scope.OrganizationID}
+	if scope.ProjectID != "" {
+		query += " AND project_id = ?"
+		args = append(args, scope.ProjectID)
+	}
+
+	result, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete policy: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete policy: %w", err)
+	}
+	if rows == 0 {
+		return ErrPolicyNotFound
+	}
+	return nil
+}
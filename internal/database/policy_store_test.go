@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestPolicyStore(t *testing.T) {
+	sqlxDB, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlxDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlxDB.Close() })
+
+	store, err := NewPolicyStore(context.Background(), &DB{SQLX: sqlxDB, DB: sqlxDB})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	admin := context.Background()
+	acme := WithScope(admin, Scope{OrganizationID: "acme"})
+	acmeNews := WithScope(admin, Scope{OrganizationID: "acme", ProjectID: "news"})
+	acmeSport := WithScope(admin, Scope{OrganizationID: "acme", ProjectID: "sport"})
+	globex := WithScope(admin, Scope{OrganizationID: "globex"})
+
+	create := func(ctx context.Context, id, definition string) {
+		t.Helper()
+		if err := store.Create(ctx, &PolicyRecord{ID: id, Definition: json.RawMessage(definition)}); err != nil {
+			t.Fatalf("Create %s: %v", id, err)
+		}
+	}
+	create(admin, "house", `{"v":"deployment"}`)
+	create(acme, "house", `{"v":"acme"}`)
+	create(acmeNews, "news-only", `{}`)
+
+	if err := store.Create(acme, &PolicyRecord{ID: "house", Definition: json.RawMessage(`{}`)}); !errors.Is(err, ErrPolicyExists) {
+		t.Errorf("duplicate policy: expected ErrPolicyExists, got %v", err)
+	}
+
+	if got, err := store.Get(acmeNews, "house"); err != nil || string(got.Definition) != `{"v":"acme"}` {
+		t.Errorf("organization policy should shadow the deployment's: got %+v, %v", got, err)
+	}
+	if got, err := store.Get(globex, "house"); err != nil || string(got.Definition) != `{"v":"deployment"}` {
+		t.Errorf("deployment policy should apply to every tenant: got %+v, %v", got, err)
+	}
+	if _, err := store.Get(acmeSport, "news-only"); !errors.Is(err, ErrPolicyNotFound) {
+		t.Errorf("other project's policy: expected ErrPolicyNotFound, got %v", err)
+	}
+
+	if policies, err := store.List(globex); err != nil || len(policies) != 1 {
+		t.Errorf("globex should see only the deployment policy: got %+v, %v", policies, err)
+	}
+	if policies, err := store.List(admin); err != nil || len(policies) != 3 {
+		t.Errorf("the deployment should see every policy: got %+v, %v", policies, err)
+	}
+
+	if err := store.Delete(globex, "house"); !errors.Is(err, ErrPolicyNotFound) {
+		t.Errorf("tenants cannot delete deployment policies: got %v", err)
+	}
+	if err := store.Delete(acmeSport, "news-only"); !errors.Is(err, ErrPolicyNotFound) {
+		t.Errorf("projects cannot delete each other's policies: got %v", err)
+	}
+	if err := store.Delete(acme, "house"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got, err := store.Get(acme, "house"); err != nil || got.Owner != (Scope{}) {
+		t.Errorf("expected the deployment policy after delete, got %+v, %v", got, err)
+	}
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// qctoolsSchema stores the QCTools reports generated while analyzed files
+// were on disk
+var qctoolsSchema = schema{
+	sqlite: []string{
+		`CREATE TABLE IF NOT EXISTS analysis_qctools (
+			analysis_id TEXT PRIMARY KEY,
+			report BLOB NOT NULL,
+			created_at DATETIME NOT NULL
+		)`,
+	},
+	postgres: []string{
+		`CREATE TABLE IF NOT EXISTS analysis_qctools (
+			analysis_id TEXT PRIMARY KEY,
+			report BYTEA NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL
+		)`,
+	},
+}
+
+// QCToolsRecord is the gzipped QCTools report of an analysis
+type QCToolsRecord struct {
+	Report    []byte    `db:"report"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// QCToolsStore persists QCTools reports in the analysis_qctools table
+type QCToolsStore struct {
+	db *DB
+}
+
+// NewQCToolsStore creates the analysis_qctools table if needed and returns
+// a store
+func NewQCToolsStore(ctx context.Context, db *DB) (*QCToolsStore, error) {
+	if err := db.createSchema(ctx, qctoolsSchema); err != nil {
+		return nil, fmt.Errorf("failed to create analysis_qctools schema: %w", err)
+	}
+	if err := db.createSchema(ctx, analysisOwnersSchema); err != nil {
+		return nil, fmt.Errorf("failed to create analysis_owners schema: %w", err)
+	}
+	return &QCToolsStore{db: db}, nil
+}
+
+// Save replaces the QCTools report of an analysis
+func (s *QCToolsStore) Save(ctx context.Context, analysisID string, report []byte) error {
+	tx, err := s.db.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin QCTools report transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM analysis_qctools WHERE analysis_id = ?"), analysisID); err != nil {
+		return fmt.Errorf("failed to replace QCTools report: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, tx.Rebind("INSERT INTO analysis_qctools (analysis_id, report, created_at) VALUES (?, ?, ?)"),
+		analysisID, report, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to save QCTools report: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save QCTools report: %w", err)
+	}
+	return nil
+}
+
+// Get returns the QCTools report of an analysis, or nil when it has none or
+// belongs to another tenant than the scope of ctx
+func (s *QCToolsStore) Get(ctx context.Context, analysisID string) (*QCToolsRecord, error) {
+	query := "SELECT report, created_at FROM analysis_qctools WHERE analysis_id = ?"
+	args := []interface{}{analysisID}
+	if condition, scopeArgs := ScopeFromContext(ctx).ownedAnalyses("analysis_id"); condition != "" {
+		query += " AND " + condition
+		args = append(args, scopeArgs...)
+	}
+
+	var record QCToolsRecord
+	err := s.db.DB.GetContext(ctx, &record, s.db.DB.Rebind(query), args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get QCTools report: %w", err)
+	}
+	return &record, nil
+}
+
+// Delete removes the QCTools report of an analysis owned by the scope of ctx
+func (s *QCToolsStore) Delete(ctx context.Context, analysisID string) error {
+	query := "DELETE FROM analysis_qctools WHERE analysis_id = ?"
+	args := []interface{}{analysisID}
+	if condition, scopeArgs := ScopeFromContext(ctx).ownedAnalyses("analysis_id"); condition != "" {
+		query += " AND " + condition
+		args = append(args, scopeArgs...)
+	}
+	if _, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind(query), args...); err != nil {
+		return fmt.Errorf("failed to delete QCTools report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestQCToolsStore(t *testing.T) {
+	ctx := context.Background()
+	sqlxDB, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlxDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlxDB.Close() })
+
+	store, err := NewQCToolsStore(ctx, &DB{SQLX: sqlxDB, DB: sqlxDB})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	if record, err := store.Get(ctx, "a1"); err != nil || record != nil {
+		t.Fatalf("Get before save = %+v, %v; want none", record, err)
+	}
+	if err := store.Save(ctx, "a1", []byte{0x1f, 0x8b, 1}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	// Saving again replaces the earlier report
+	if err := store.Save(ctx, "a1", []byte{0x1f, 0x8b, 2}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	record, err := store.Get(ctx, "a1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if record == nil || !bytes.Equal(record.Report, []byte{0x1f, 0x8b, 2}) || record.CreatedAt.IsZero() {
+		t.Errorf("unexpected report: %+v", record)
+	}
+
+	if err := store.Delete(ctx, "a1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if record, _ := store.Get(ctx, "a1"); record != nil {
+		t.Errorf("expected no report after delete, got %+v", record)
+	}
+}
@@ -0,0 +1,576 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrOrganizationNotFound is returned for unknown organization IDs
+	ErrOrganizationNotFound = errors.New("organization not found")
+	// ErrProjectNotFound is returned for unknown projects, or projects of
+	// another organization
+	ErrProjectNotFound = errors.New("project not found")
+	// ErrAPIKeyNotFound is returned for unknown, revoked or foreign API keys
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrNameTaken is returned when an organization or project name is in use
+	ErrNameTaken = errors.New("name already in use")
+)
+
+// APIKeyPrefix starts every tenant API key, so keys are recognisable in
+// logs and secret scanners
+const APIKeyPrefix = "rpk_"
+
+// apiKeyDisplayLength is the number of leading key characters kept in clear
+// to identify a key in listings
+const apiKeyDisplayLength = 12
+
+// apiKeyTouchInterval limits how often authentication records last use, so
+// every request does not write to the database
+const apiKeyTouchInterval = time.Minute
+
+// Role is what an API key may do. Each role includes the ones below it.
+type Role string
+
+// API key roles, from least to most privileged
+const (
+	RoleViewer   Role = "viewer"   // Read analyses, reports and job status
+	RoleOperator Role = "operator" // Also submit probes, uploads and batch jobs
+	RoleAdmin    Role = "admin"    // Also manage API keys and policies
+)
+
+// roleRanks orders the roles
+var roleRanks = map[Role]int{RoleViewer: 1, RoleOperator: 2, RoleAdmin: 3}
+
+// ParseRole maps a request parameter to a role
+func ParseRole(name string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := roleRanks[role]; !ok {
+		return "", fmt.Errorf("unknown role %q (use viewer, operator or admin)", name)
+	}
+	return role, nil
+}
+
+// Includes reports whether r grants everything required does
+func (r Role) Includes(required Role) bool {
+	return roleRanks[r] >= roleRanks[required]
+}
+
+// Scope identifies the tenant a request acts for: an organization and,
+// for project keys, one of its projects. The zero scope is the deployment
+// itself and sees every tenant, as in single-tenant mode.
+type Scope struct {
+	OrganizationID string `json:"organization_id,omitempty"`
+	ProjectID      string `json:"project_id,omitempty"`
+}
+
+// IsZero reports whether the scope is the unrestricted deployment scope
+func (s Scope) IsZero() bool {
+	return s.OrganizationID == ""
+}
+
+// Contains reports whether s may see a resource owned by owner. An
+// organization scope sees all of its projects; a project scope sees only
+// its own project.
+func (s Scope) Contains(owner Scope) bool {
+	if s.IsZero() {
+		return true
+	}
+	if owner.OrganizationID != s.OrganizationID {
+		return false
+	}
+	return s.ProjectID == "" || owner.ProjectID == s.ProjectID
+}
+
+type scopeKey struct{}
+
+// WithScope returns a context whose store queries are restricted to scope
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// ScopeFromContext returns the scope set by WithScope, or the zero scope
+func ScopeFromContext(ctx context.Context) Scope {
+	scope, _ := ctx.Value(scopeKey{}).(Scope)
+	return scope
+}
+
+// analysisOwnersSchema records the tenant of each analysis. Ownership lives
+// in its own table so existing analyses tables need no new columns; an
+// analysis without a row belongs to the deployment.
+var analysisOwnersSchema = schema{
+	sqlite: []string{
+		`CREATE TABLE IF NOT EXISTS analysis_owners (
+			analysis_id TEXT PRIMARY KEY,
+			organization_id TEXT NOT NULL,
+			project_id TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_analysis_owners_scope ON analysis_owners(organization_id, project_id)`,
+	},
+	postgres: []string{
+		`CREATE TABLE IF NOT EXISTS analysis_owners (
+			analysis_id TEXT PRIMARY KEY,
+			organization_id TEXT NOT NULL,
+			project_id TEXT NOT NULL DEFAULT ''
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_analysis_owners_scope ON analysis_owners(organization_id, project_id)`,
+	},
+}
+
+// ownedAnalyses restricts a query to the analyses scope can see, matching
+// column against the analysis ID. The zero scope adds no condition.
+func (s Scope) ownedAnalyses(column string) (string, []interface{}) {
+	if s.IsZero() {
+		return "", nil
+	}
+	condition := "CAST(" + column + " AS TEXT) IN (SELECT analysis_id FROM analysis_owners WHERE organization_id = ?"
+	args := []interface{}{s.OrganizationID}
+	if s.ProjectID != "" {
+		condition += " AND project_id = ?"
+		args = append(args, s.ProjectID)
+	}
+	return condition + ")", args
+}
+
+// tenantsSchema holds organizations, their projects and tenant API keys.
+// Tenant keys are separate from the api_keys table of the initial schema,
+// which belongs to user accounts and has no project. Key roles came later
+// and live in api_key_roles; a key without a role row is an admin key.
+var tenantsSchema = schema{
+	sqlite: []string{
+		`CREATE TABLE IF NOT EXISTS organizations (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			created_at DATETIME NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS projects (
+			id TEXT PRIMARY KEY,
+			organization_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			created_at DATETIME NOT NULL,
+			UNIQUE (organization_id, name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS tenant_api_keys (
+			id TEXT PRIMARY KEY,
+			organization_id TEXT NOT NULL,
+			project_id TEXT NOT NULL DEFAULT '',
+			name TEXT,
+			key_prefix TEXT NOT NULL,
+			key_hash TEXT NOT NULL UNIQUE,
+			created_at DATETIME NOT NULL,
+			last_used_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_tenant_api_keys_scope ON tenant_api_keys(organization_id, project_id)`,
+		`CREATE TABLE IF NOT EXISTS api_key_roles (
+			key_id TEXT PRIMARY KEY,
+			role TEXT NOT NULL
+		)`,
+	},
+	postgres: []string{
+		`CREATE TABLE IF NOT EXISTS organizations (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL UNIQUE,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS projects (
+			id TEXT PRIMARY KEY,
+			organization_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			UNIQUE (organization_id, name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS tenant_api_keys (
+			id TEXT PRIMARY KEY,
+			organization_id TEXT NOT NULL,
+			project_id TEXT NOT NULL DEFAULT '',
+			name TEXT,
+			key_prefix TEXT NOT NULL,
+			key_hash TEXT NOT NULL UNIQUE,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+			last_used_at TIMESTAMP WITH TIME ZONE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_tenant_api_keys_scope ON tenant_api_keys(organization_id, project_id)`,
+		`CREATE TABLE IF NOT EXISTS api_key_roles (
+			key_id TEXT PRIMARY KEY,
+			role TEXT NOT NULL
+		)`,
+	},
+}
+
+// Organization is a tenant of the deployment
+type Organization struct {
+	ID        string    `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Project groups the work of an organization
+type Project struct {
+	ID             string    `json:"id" db:"id"`
+	OrganizationID string    `json:"organization_id" db:"organization_id"`
+	Name           string    `json:"name" db:"name"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// APIKeyRecord is a tenant API key. Only a hash of the key is stored;
+// Prefix is enough of it to tell keys apart.
+type APIKeyRecord struct {
+	ID             string     `json:"id" db:"id"`
+	OrganizationID string     `json:"organization_id" db:"organization_id"`
+	ProjectID      string     `json:"project_id,omitempty" db:"project_id"`
+	Name           string     `json:"name,omitempty" db:"name"`
+	Prefix         string     `json:"prefix" db:"key_prefix"`
+	Role           Role       `json:"role" db:"role"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt     *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+}
+
+// Scope returns the tenant the key acts for
+func (k *APIKeyRecord) Scope() Scope {
+	return Scope{OrganizationID: k.OrganizationID, ProjectID: k.ProjectID}
+}
+
+// TenantStore persists organizations, projects and tenant API keys
+type TenantStore struct {
+	db *DB
+}
+
+// NewTenantStore creates the tenant tables if needed and returns a store.
+// It also creates the policies table, which deletions cascade into.
+func NewTenantStore(ctx context.Context, db *DB) (*TenantStore, error) {
+	if err := db.createSchema(ctx, tenantsSchema); err != nil {
+		return nil, fmt.Errorf("failed to create tenant schema: %w", err)
+	}
+	if err := db.createSchema(ctx, policiesSchema); err != nil {
+		return nil, fmt.Errorf("failed to create policies schema: %w", err)
+	}
+	return &TenantStore{db: db}, nil
+}
+
+// CreateOrganization adds a tenant, returning ErrNameTaken if the name is
+// in use
+func (s *TenantStore) CreateOrganization(ctx context.Context, name string) (*Organization, error) {
+	org := &Organization{ID: uuid.New().String(), Name: name, CreatedAt: time.Now().UTC()}
+
+	var taken int
+	if err := s.db.DB.GetContext(ctx, &taken, s.db.DB.Rebind("SELECT COUNT(*) FROM organizations WHERE name = ?"), name); err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	if taken > 0 {
+		return nil, ErrNameTaken
+	}
+
+	_, err := s.db.DB.ExecContext(ctx,
+		s.db.DB.Rebind("INSERT INTO organizations (id, name, created_at) VALUES (?, ?, ?)"),
+		org.ID, org.Name, org.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+	return org, nil
+}
+
+// GetOrganization returns an organization, or ErrOrganizationNotFound
+func (s *TenantStore) GetOrganization(ctx context.Context, id string) (*Organization, error) {
+	var org Organization
+	err := s.db.DB.GetContext(ctx, &org,
+		s.db.DB.Rebind("SELECT id, name, created_at FROM organizations WHERE id = ?"), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrOrganizationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	return &org, nil
+}
+
+// ListOrganizations returns every organization by name
+func (s *TenantStore) ListOrganizations(ctx context.Context) ([]Organization, error) {
+	orgs := []Organization{}
+	if err := s.db.DB.SelectContext(ctx, &orgs, "SELECT id, name, created_at FROM organizations ORDER BY name"); err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	return orgs, nil
+}
+
+// DeleteOrganization removes an organization with its projects, API keys
+// and policies, returning ErrOrganizationNotFound if it does not exist. Its
+// analyses are kept for the deployment.
+func (s *TenantStore) DeleteOrganization(ctx context.Context, id string) error {
+	tx, err := s.db.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM organizations WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	} else if rows == 0 {
+		return ErrOrganizationNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(
+		"DELETE FROM api_key_roles WHERE key_id IN (SELECT id FROM tenant_api_keys WHERE organization_id = ?)"), id); err != nil {
+		return fmt.Errorf("failed to delete organization api key roles: %w", err)
+	}
+	for _, table := range []string{"projects", "tenant_api_keys", "policies"} {
+		if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM "+table+" WHERE organization_id = ?"), id); err != nil {
+			return fmt.Errorf("failed to delete organization %s: %w", table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// CreateProject adds a project to an organization, returning
+// ErrOrganizationNotFound or ErrNameTaken
+func (s *TenantStore) CreateProject(ctx context.Context, organizationID, name string) (*Project, error) {
+	if _, err := s.GetOrganization(ctx, organizationID); err != nil {
+		return nil, err
+	}
+
+	var taken int
+	if err := s.db.DB.GetContext(ctx, &taken,
+		s.db.DB.Rebind("SELECT COUNT(*) FROM projects WHERE organization_id = ? AND name = ?"), organizationID, name); err != nil {
+		return nil, fmt.Errorf("failed to create project: %w", err)
+	}
+	if taken > 0 {
+		return nil, ErrNameTaken
+	}
+
+	project := &Project{ID: uuid.New().String(), OrganizationID: organizationID, Name: name, CreatedAt: time.Now().UTC()}
+	_, err := s.db.DB.ExecContext(ctx,
+		s.db.DB.Rebind("INSERT INTO projects (id, organization_id, name, created_at) VALUES (?, ?, ?, ?)"),
+		project.ID, project.OrganizationID, project.Name, project.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create project: %w", err)
+	}
+	return project, nil
+}
+
+// GetProject returns a project of an organization, or ErrProjectNotFound
+func (s *TenantStore) GetProject(ctx context.Context, organizationID, id string) (*Project, error) {
+	var project Project
+	err := s.db.DB.GetContext(ctx, &project,
+		s.db.DB.Rebind("SELECT id, organization_id, name, created_at FROM projects WHERE id = ? AND organization_id = ?"),
+		id, organizationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrProjectNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+	return &project, nil
+}
+
+// ListProjects returns the projects of an organization by name
+func (s *TenantStore) ListProjects(ctx context.Context, organizationID string) ([]Project, error) {
+	projects := []Project{}
+	err := s.db.DB.SelectContext(ctx, &projects,
+		s.db.DB.Rebind("SELECT id, organization_id, name, created_at FROM projects WHERE organization_id = ? ORDER BY name"),
+		organizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	return projects, nil
+}
+
+// DeleteProject removes a project with its API keys and policies,
+// returning ErrProjectNotFound if the organization has no such project
+func (s *TenantStore) DeleteProject(ctx context.Context, organizationID, id string) error {
+	tx, err := s.db.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM projects WHERE id = ? AND organization_id = ?"), id, organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to delete project: %w", err)
+	} else if rows == 0 {
+		return ErrProjectNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind(
+		"DELETE FROM api_key_roles WHERE key_id IN (SELECT id FROM tenant_api_keys WHERE organization_id = ? AND project_id = ?)"),
+		organizationID, id); err != nil {
+		return fmt.Errorf("failed to delete project api key roles: %w", err)
+	}
+	for _, table := range []string{"tenant_api_keys", "policies"} {
+		if _, err := tx.ExecContext(ctx,
+			tx.Rebind("DELETE FROM "+table+" WHERE organization_id = ? AND project_id = ?"), organizationID, id); err != nil {
+			return fmt.Errorf("failed to delete project %s: %w", table, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// CreateAPIKey issues a key with role for scope, which must name an
+// existing organization and, optionally, one of its projects. The key
+// itself is returned once and cannot be recovered.
+func (s *TenantStore) CreateAPIKey(ctx context.Context, scope Scope, name string, role Role) (*APIKeyRecord, string, error) {
+	if _, err := ParseRole(string(role)); err != nil {
+		return nil, "", err
+	}
+	if _, err := s.GetOrganization(ctx, scope.OrganizationID); err != nil {
+		return nil, "", err
+	}
+	if scope.ProjectID != "" {
+		if _, err := s.GetProject(ctx, scope.OrganizationID, scope.ProjectID); err != nil {
+			return nil, "", err
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	record := &APIKeyRecord{
+		ID:             uuid.New().String(),
+		OrganizationID: scope.OrganizationID,
+		ProjectID:      scope.ProjectID,
+		Name:           name,
+		Prefix:         key[:apiKeyDisplayLength],
+		Role:           role,
+		CreatedAt:      time.Now().UTC(),
+	}
+
+	tx, err := s.db.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, tx.Rebind(`
+		INSERT INTO tenant_api_keys (id, organization_id, project_id, name, key_prefix, key_hash, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		record.ID, record.OrganizationID, record.ProjectID, record.Name, record.Prefix, hashAPIKey(key), record.CreatedAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind("INSERT INTO api_key_roles (key_id, role) VALUES (?, ?)"), record.ID, string(role)); err != nil {
+		return nil, "", fmt.Errorf("failed to create api key role: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+	return record, key, nil
+}
+
+// apiKeyColumns selects an APIKeyRecord from apiKeyTables
+const apiKeyColumns = `id, organization_id, project_id, COALESCE(name, '') AS name, key_prefix,
+	COALESCE(role, 'admin') AS role, created_at, last_used_at`
+
+// apiKeyTables joins tenant keys with their roles
+const apiKeyTables = `tenant_api_keys LEFT JOIN api_key_roles ON api_key_roles.key_id = tenant_api_keys.id`
+
+// ListAPIKeys returns the keys scope can see, newest first
+func (s *TenantStore) ListAPIKeys(ctx context.Context, scope Scope) ([]APIKeyRecord, error) {
+	query := "SELECT " + apiKeyColumns + " FROM " + apiKeyTables
+	condition, args := scope.ownedRows()
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+
+	keys := []APIKeyRecord{}
+	if err := s.db.DB.SelectContext(ctx, &keys, s.db.DB.Rebind(query+" ORDER BY created_at DESC"), args...); err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey deletes a key scope can see, returning ErrAPIKeyNotFound
+// otherwise
+func (s *TenantStore) RevokeAPIKey(ctx context.Context, scope Scope, id string) error {
+	query := "DELETE FROM tenant_api_keys WHERE id = ?"
+	args := []interface{}{id}
+	if condition, scopeArgs := scope.ownedRows(); condition != "" {
+		query += " AND " + condition
+		args = append(args, scopeArgs...)
+	}
+
+	tx, err := s.db.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, tx.Rebind(query), args...)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if rows == 0 {
+		return ErrAPIKeyNotFound
+	}
+	if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM api_key_roles WHERE key_id = ?"), id); err != nil {
+		return fmt.Errorf("failed to revoke api key role: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Authenticate returns the record of a key, or ErrAPIKeyNotFound
+func (s *TenantStore) Authenticate(ctx context.Context, key string) (*APIKeyRecord, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	var record APIKeyRecord
+	err := s.db.DB.GetContext(ctx, &record,
+		s.db.DB.Rebind("SELECT "+apiKeyColumns+" FROM "+apiKeyTables+" WHERE key_hash = ?"), hashAPIKey(key))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate api key: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= apiKeyTouchInterval {
+		// Best effort: a failed update must not reject a valid key
+		if _, err := s.db.DB.ExecContext(ctx,
+			s.db.DB.Rebind("UPDATE tenant_api_keys SET last_used_at = ? WHERE id = ?"), now, record.ID); err == nil {
+			record.LastUsedAt = &now
+		}
+	}
+	return &record, nil
+}
+
+// ownedRows restricts a query on a table with organization_id and
+// project_id columns to the rows scope can see
+func (s Scope) ownedRows() (string, []interface{}) {
+	if s.IsZero() {
+		return "", nil
+	}
+	if s.ProjectID == "" {
+		return "organization_id = ?", []interface{}{s.OrganizationID}
+	}
+	return "organization_id = ? AND project_id = ?", []interface{}{s.OrganizationID, s.ProjectID}
+}
+
+// hashAPIKey returns the stored form of a key. Keys carry 256 random bits,
+// so an unsalted hash cannot be reversed by brute force.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
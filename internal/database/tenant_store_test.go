@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func newTestTenantStore(t *testing.T) *TenantStore {
+	t.Helper()
+	sqlxDB, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlxDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlxDB.Close() })
+
+	store, err := NewTenantStore(context.Background(), &DB{SQLX: sqlxDB, DB: sqlxDB})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	return store
+}
+
+func TestTenantStore(t *testing.T) {
+	ctx := context.Background()
+	store := newTestTenantStore(t)
+
+	acme, err := store.CreateOrganization(ctx, "acme")
+	if err != nil {
+		t.Fatalf("CreateOrganization: %v", err)
+	}
+	if _, err := store.CreateOrganization(ctx, "acme"); !errors.Is(err, ErrNameTaken) {
+		t.Errorf("duplicate organization: expected ErrNameTaken, got %v", err)
+	}
+	globex, err := store.CreateOrganization(ctx, "globex")
+	if err != nil {
+		t.Fatalf("CreateOrganization: %v", err)
+	}
+
+	news, err := store.CreateProject(ctx, acme.ID, "news")
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	if _, err := store.CreateProject(ctx, acme.ID, "news"); !errors.Is(err, ErrNameTaken) {
+		t.Errorf("duplicate project: expected ErrNameTaken, got %v", err)
+	}
+	if _, err := store.CreateProject(ctx, globex.ID, "news"); err != nil {
+		t.Errorf("project names are per organization: %v", err)
+	}
+	if _, err := store.GetProject(ctx, globex.ID, news.ID); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("foreign project: expected ErrProjectNotFound, got %v", err)
+	}
+
+	orgKey, rawOrgKey, err := store.CreateAPIKey(ctx, Scope{OrganizationID: acme.ID}, "ci", RoleAdmin)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if !strings.HasPrefix(rawOrgKey, APIKeyPrefix) || !strings.HasPrefix(rawOrgKey, orgKey.Prefix) {
+		t.Errorf("unexpected key %q for prefix %q", rawOrgKey, orgKey.Prefix)
+	}
+	projectKey, rawProjectKey, err := store.CreateAPIKey(ctx, Scope{OrganizationID: acme.ID, ProjectID: news.ID}, "", RoleViewer)
+	if err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if _, _, err := store.CreateAPIKey(ctx, Scope{OrganizationID: globex.ID, ProjectID: news.ID}, "", RoleOperator); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("key for foreign project: expected ErrProjectNotFound, got %v", err)
+	}
+
+	got, err := store.Authenticate(ctx, rawProjectKey)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if got.Scope() != (Scope{OrganizationID: acme.ID, ProjectID: news.ID}) || got.Role != RoleViewer || got.LastUsedAt == nil {
+		t.Errorf("unexpected key record: %+v", got)
+	}
+	if _, err := store.Authenticate(ctx, rawProjectKey+"x"); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("wrong key: expected ErrAPIKeyNotFound, got %v", err)
+	}
+	if _, _, err := store.CreateAPIKey(ctx, Scope{OrganizationID: acme.ID}, "", "owner"); err == nil {
+		t.Error("expected an error for an unknown role")
+	}
+
+	// Keys issued before roles existed have no role row and stay admins
+	if _, err := store.db.DB.Exec("DELETE FROM api_key_roles WHERE key_id = ?", orgKey.ID); err != nil {
+		t.Fatalf("failed to drop role: %v", err)
+	}
+	if got, err := store.Authenticate(ctx, rawOrgKey); err != nil || got.Role != RoleAdmin {
+		t.Errorf("key without role: got %+v, %v", got, err)
+	}
+
+	if keys, err := store.ListAPIKeys(ctx, Scope{OrganizationID: acme.ID, ProjectID: news.ID}); err != nil || len(keys) != 1 || keys[0].ID != projectKey.ID {
+		t.Errorf("project keys: got %+v, %v", keys, err)
+	}
+	if keys, err := store.ListAPIKeys(ctx, Scope{OrganizationID: globex.ID}); err != nil || len(keys) != 0 {
+		t.Errorf("foreign keys should be hidden: got %+v, %v", keys, err)
+	}
+	if err := store.RevokeAPIKey(ctx, Scope{OrganizationID: globex.ID}, orgKey.ID); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("foreign revoke: expected ErrAPIKeyNotFound, got %v", err)
+	}
+	if err := store.RevokeAPIKey(ctx, Scope{OrganizationID: acme.ID}, orgKey.ID); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if _, err := store.Authenticate(ctx, rawOrgKey); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("revoked key: expected ErrAPIKeyNotFound, got %v", err)
+	}
+
+	if err := store.DeleteOrganization(ctx, acme.ID); err != nil {
+		t.Fatalf("DeleteOrganization: %v", err)
+	}
+	if _, err := store.Authenticate(ctx, rawProjectKey); !errors.Is(err, ErrAPIKeyNotFound) {
+		t.Errorf("key of deleted organization: expected ErrAPIKeyNotFound, got %v", err)
+	}
+	if projects, err := store.ListProjects(ctx, acme.ID); err != nil || len(projects) != 0 {
+		t.Errorf("projects of deleted organization: got %+v, %v", projects, err)
+	}
+	if orgs, err := store.ListOrganizations(ctx); err != nil || len(orgs) != 1 || orgs[0].ID != globex.ID {
+		t.Errorf("organizations after delete: got %+v, %v", orgs, err)
+	}
+}
+
+func TestRoleIncludes(t *testing.T) {
+	if !RoleAdmin.Includes(RoleOperator) || !RoleOperator.Includes(RoleOperator) || RoleViewer.Includes(RoleOperator) {
+		t.Error("unexpected role order")
+	}
+	if role, err := ParseRole(" Viewer "); err != nil || role != RoleViewer {
+		t.Errorf("ParseRole: got %q, %v", role, err)
+	}
+	if Role("").Includes(RoleViewer) {
+		t.Error("the empty role should grant nothing")
+	}
+}
+
+func TestScopeContains(t *testing.T) {
+	org := Scope{OrganizationID: "a"}
+	project := Scope{OrganizationID: "a", ProjectID: "p"}
+	tests := []struct {
+		scope, owner Scope
+		want         bool
+	}{
+		{Scope{}, project, true},
+		{org, Scope{}, false},
+		{org, project, true},
+		{project, org, false},
+		{project, project, true},
+		{project, Scope{OrganizationID: "a", ProjectID: "q"}, false},
+		{org, Scope{OrganizationID: "b"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.scope.Contains(tt.owner); got != tt.want {
+			t.Errorf("%+v.Contains(%+v) = %v, want %v", tt.scope, tt.owner, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"fmt"
+)
+
+// thumbnailsSchema stores the stills taken from analyzed files, which are
+// gone by the time a reviewer asks for them
+var thumbnailsSchema = schema{
+	sqlite: []string{
+		`CREATE TABLE IF NOT EXISTS analysis_thumbnails (
+			analysis_id TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			mode TEXT NOT NULL,
+			time_seconds REAL NOT NULL,
+			width INTEGER NOT NULL,
+			height INTEGER NOT NULL,
+			jpeg BLOB NOT NULL,
+			PRIMARY KEY (analysis_id, position)
+		)`,
+	},
+	postgres: []string{
+		`CREATE TABLE IF NOT EXISTS analysis_thumbnails (
+			analysis_id TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			mode TEXT NOT NULL,
+			time_seconds DOUBLE PRECISION NOT NULL,
+			width INTEGER NOT NULL,
+			height INTEGER NOT NULL,
+			jpeg BYTEA NOT NULL,
+			PRIMARY KEY (analysis_id, position)
+		)`,
+	},
+}
+
+// ThumbnailRecord is one still of an analysis, in time order by Position
+type ThumbnailRecord struct {
+	Position int     `db:"position"`
+	Mode     string  `db:"mode"` // How the stills were chosen
+	Time     float64 `db:"time_seconds"`
+	Width    int     `db:"width"`
+	Height   int     `db:"height"`
+	JPEG     []byte  `db:"jpeg"`
+}
+
+// ThumbnailStore persists analysis stills in the analysis_thumbnails table
+type ThumbnailStore struct {
+	db *DB
+}
+
+// NewThumbnailStore creates the analysis_thumbnails table if needed and
+// returns a store
+func NewThumbnailStore(ctx context.Context, db *DB) (*ThumbnailStore, error) {
+	if err := db.createSchema(ctx, thumbnailsSchema); err != nil {
+		return nil, fmt.Errorf("failed to create analysis_thumbnails schema: %w", err)
+	}
+	if err := db.createSchema(ctx, analysisOwnersSchema); err != nil {
+		return nil, fmt.Errorf("failed to create analysis_owners schema: %w", err)
+	}
+	return &ThumbnailStore{db: db}, nil
+}
+
+// Save replaces the stills of an analysis. Positions are assigned in the
+// order given.
+func (s *ThumbnailStore) Save(ctx context.Context, analysisID string, records []ThumbnailRecord) error {
+	tx, err := s.db.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin thumbnail transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, tx.Rebind("DELETE FROM analysis_thumbnails WHERE analysis_id = ?"), analysisID); err != nil {
+		return fmt.Errorf("failed to replace thumbnails: %w", err)
+	}
+	for i := range records {
+		records[i].Position = i
+		_, err := tx.ExecContext(ctx, tx.Rebind(`
+			INSERT INTO analysis_thumbnails (analysis_id, position, mode, time_seconds, width, height, jpeg)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`),
+			analysisID,
+			i,
+			records[i].Mode,
+			records[i].Time,
+			records[i].Width,
+			records[i].Height,
+			records[i].JPEG,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save thumbnail: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save thumbnails: %w", err)
+	}
+	return nil
+}
+
+// List returns the stills of an analysis in time order, or none when it has
+// no stills or belongs to another tenant than the scope of ctx
+func (s *ThumbnailStore) List(ctx context.Context, analysisID string) ([]ThumbnailRecord, error) {
+	query := `
+		SELECT position, mode, time_seconds, width, height, jpeg
+		FROM analysis_thumbnails
+		WHERE analysis_id = ?`
+	args := []interface{}{analysisID}
+	if condition, scopeArgs := ScopeFromContext(ctx).ownedAnalyses("analysis_id"); condition != "" {
+		query += " AND " + condition
+		args = append(args, scopeArgs...)
+	}
+
+	var records []ThumbnailRecord
+	err := s.db.DB.SelectContext(ctx, &records, s.db.DB.Rebind(query+" ORDER BY position"), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list thumbnails: %w", err)
+	}
+	return records, nil
+}
+
+// Delete removes the stills of an analysis owned by the scope of ctx
+func (s *ThumbnailStore) Delete(ctx context.Context, analysisID string) error {
+	query := "DELETE FROM analysis_thumbnails WHERE analysis_id = ?"
+	args := []interface{}{analysisID}
+	if condition, scopeArgs := ScopeFromContext(ctx).ownedAnalyses("analysis_id"); condition != "" {
+		query += " AND " + condition
+		args = append(args, scopeArgs...)
+	}
+	if _, err := s.db.DB.ExecContext(ctx, s.db.DB.Rebind(query), args...); err != nil {
+		return fmt.Errorf("failed to delete thumbnails: %w", err)
+	}
+	return nil
+}
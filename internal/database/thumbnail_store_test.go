@@ -0,0 +1,61 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestThumbnailStore(t *testing.T) {
+	ctx := context.Background()
+	sqlxDB, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlxDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlxDB.Close() })
+
+	store, err := NewThumbnailStore(ctx, &DB{SQLX: sqlxDB, DB: sqlxDB})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	err = store.Save(ctx, "a1", []ThumbnailRecord{
+		{Mode: "interval", Time: 5, Width: 320, Height: 180, JPEG: []byte{0xff, 0xd8, 1}},
+		{Mode: "interval", Time: 15, Width: 320, Height: 180, JPEG: []byte{0xff, 0xd8, 2}},
+		{Mode: "interval", Time: 25, Width: 320, Height: 180, JPEG: []byte{0xff, 0xd8, 3}},
+	})
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := store.Save(ctx, "a2", []ThumbnailRecord{{Mode: "scene", Time: 1, Width: 160, Height: 90, JPEG: []byte{1}}}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	records, err := store.List(ctx, "a1")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(records) != 3 || records[2].Position != 2 || records[2].Time != 25 || records[2].JPEG[2] != 3 {
+		t.Errorf("unexpected thumbnails: %+v", records)
+	}
+
+	// Saving again replaces the earlier stills
+	if err := store.Save(ctx, "a1", []ThumbnailRecord{{Mode: "scene", Time: 7.5, Width: 320, Height: 180, JPEG: []byte{4}}}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if records, _ := store.List(ctx, "a1"); len(records) != 1 || records[0].Mode != "scene" {
+		t.Errorf("expected the stills to be replaced, got %+v", records)
+	}
+
+	if err := store.Delete(ctx, "a1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if records, _ := store.List(ctx, "a1"); len(records) != 0 {
+		t.Errorf("expected no thumbnails after delete, got %d", len(records))
+	}
+	if records, _ := store.List(ctx, "a2"); len(records) != 1 {
+		t.Errorf("other analyses should keep their thumbnails, got %d", len(records))
+	}
+}
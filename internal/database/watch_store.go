@@ -0,0 +1,195 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Watch result listing bounds
+const (
+	DefaultWatchResultLimit = 50
+	MaxWatchResultLimit     = 500
+)
+
+// watchResultsSchema stores the outcome of every file picked up from a
+// watch folder
+var watchResultsSchema = schema{
+	sqlite: []string{
+		`CREATE TABLE IF NOT EXISTS watch_results (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			folder TEXT NOT NULL,
+			filename TEXT NOT NULL,
+			file_size INTEGER,
+			analysis_id TEXT,
+			verdict TEXT NOT NULL,
+			reasons TEXT,
+			moved_to TEXT,
+			error_msg TEXT,
+			processed_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_watch_results_processed_at ON watch_results(processed_at)`,
+	},
+	postgres: []string{
+		`CREATE TABLE IF NOT EXISTS watch_results (
+			id BIGSERIAL PRIMARY KEY,
+			folder TEXT NOT NULL,
+			filename TEXT NOT NULL,
+			file_size BIGINT,
+			analysis_id TEXT,
+			verdict TEXT NOT NULL,
+			reasons TEXT,
+			moved_to TEXT,
+			error_msg TEXT,
+			processed_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_watch_results_processed_at ON watch_results(processed_at)`,
+	},
+}
+
+// WatchResultRecord is the outcome of one watch folder file
+type WatchResultRecord struct {
+	ID          int64     `json:"id"`
+	Folder      string    `json:"folder"`
+	FileName    string    `json:"filename"`
+	FileSize    int64     `json:"size"`
+	AnalysisID  string    `json:"analysis_id,omitempty"`
+	Verdict     string    `json:"verdict"`
+	Reasons     []string  `json:"reasons,omitempty"`
+	MovedTo     string    `json:"moved_to,omitempty"`
+	Error       string    `json:"error,omitempty"`
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// WatchResultFilter narrows a watch result listing. Zero values match
+// everything.
+type WatchResultFilter struct {
+	Folder  string
+	Verdict string
+	Limit   int
+	Offset  int
+}
+
+// WatchStore persists watch folder outcomes in the watch_results table
+type WatchStore struct {
+	db *DB
+}
+
+// NewWatchStore creates the watch_results table if needed and returns a store
+func NewWatchStore(ctx context.Context, db *DB) (*WatchStore, error) {
+	if err := db.createSchema(ctx, watchResultsSchema); err != nil {
+		return nil, fmt.Errorf("failed to create watch_results schema: %w", err)
+	}
+	return &WatchStore{db: db}, nil
+}
+
+// Save inserts a result and sets its ID
+func (s *WatchStore) Save(ctx context.Context, record *WatchResultRecord) error {
+	reasons, err := json.Marshal(record.Reasons)
+	if err != nil {
+		return fmt.Errorf("failed to encode watch result reasons: %w", err)
+	}
+
+	query := `
+		INSERT INTO watch_results (folder, filename, file_size, analysis_id, verdict, reasons, moved_to, error_msg, processed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		RETURNING id`
+
+	// The PostgreSQL driver has no LastInsertId, so the ID is returned instead
+	err = s.db.DB.GetContext(ctx, &record.ID, s.db.DB.Rebind(query),
+		record.Folder,
+		record.FileName,
+		record.FileSize,
+		record.AnalysisID,
+		record.Verdict,
+		string(reasons),
+		record.MovedTo,
+		record.Error,
+		record.ProcessedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save watch result: %w", err)
+	}
+
+	return nil
+}
+
+// List returns matching results newest first and the total number of matches
+func (s *WatchStore) List(ctx context.Context, filter WatchResultFilter) ([]WatchResultRecord, int, error) {
+	whereConditions := []string{}
+	args := []interface{}{}
+
+	if filter.Folder != "" {
+		whereConditions = append(whereConditions, "folder = ?")
+		args = append(args, filter.Folder)
+	}
+	if filter.Verdict != "" {
+		whereConditions = append(whereConditions, "verdict = ?")
+		args = append(args, filter.Verdict)
+	}
+
+	whereClause := ""
+	if len(whereConditions) > 0 {
+		whereClause = " WHERE " + strings.Join(whereConditions, " AND ")
+	}
+
+	var total int
+	if err := s.db.DB.GetContext(ctx, &total, s.db.DB.Rebind("SELECT COUNT(*) FROM watch_results"+whereClause), args...); err != nil {
+		return nil, 0, fmt.Errorf("failed to count watch results: %w", err)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultWatchResultLimit
+	} else if limit > MaxWatchResultLimit {
+		limit = MaxWatchResultLimit
+	}
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	query := `SELECT id, folder, filename, COALESCE(file_size, 0) AS file_size,
+		COALESCE(analysis_id, '') AS analysis_id, verdict, COALESCE(reasons, '') AS reasons,
+		COALESCE(moved_to, '') AS moved_to, COALESCE(error_msg, '') AS error_msg, processed_at
+		FROM watch_results` + whereClause + `
+		ORDER BY processed_at DESC, id DESC
+		LIMIT ? OFFSET ?`
+
+	var rows []struct {
+		ID          int64     `db:"id"`
+		Folder      string    `db:"folder"`
+		FileName    string    `db:"filename"`
+		FileSize    int64     `db:"file_size"`
+		AnalysisID  string    `db:"analysis_id"`
+		Verdict     string    `db:"verdict"`
+		Reasons     string    `db:"reasons"`
+		MovedTo     string    `db:"moved_to"`
+		Error       string    `db:"error_msg"`
+		ProcessedAt time.Time `db:"processed_at"`
+	}
+	if err := s.db.DB.SelectContext(ctx, &rows, s.db.DB.Rebind(query), append(args, limit, offset)...); err != nil {
+		return nil, 0, fmt.Errorf("failed to list watch results: %w", err)
+	}
+
+	records := make([]WatchResultRecord, len(rows))
+	for i, row := range rows {
+		records[i] = WatchResultRecord{
+			ID:          row.ID,
+			Folder:      row.Folder,
+			FileName:    row.FileName,
+			FileSize:    row.FileSize,
+			AnalysisID:  row.AnalysisID,
+			Verdict:     row.Verdict,
+			MovedTo:     row.MovedTo,
+			Error:       row.Error,
+			ProcessedAt: row.ProcessedAt,
+		}
+		if row.Reasons != "" {
+			_ = json.Unmarshal([]byte(row.Reasons), &records[i].Reasons)
+		}
+	}
+	return records, total, nil
+}
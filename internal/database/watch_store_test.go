@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestWatchStore(t *testing.T) {
+	ctx := context.Background()
+	sqlxDB, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlxDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlxDB.Close() })
+
+	store, err := NewWatchStore(ctx, &DB{SQLX: sqlxDB, DB: sqlxDB})
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	records := []*WatchResultRecord{
+		{Folder: "/ingest/a", FileName: "one.mp4", Verdict: "passed", MovedTo: "/ingest/a/passed/one.mp4", ProcessedAt: start},
+		{Folder: "/ingest/a", FileName: "two.mxf", Verdict: "failed", Reasons: []string{"loudness.integrated", "video.codec"}, ProcessedAt: start.Add(time.Minute)},
+		{Folder: "/ingest/b", FileName: "three.mov", Verdict: "failed", Error: "failed to move file", ProcessedAt: start.Add(2 * time.Minute)},
+	}
+	for _, record := range records {
+		if err := store.Save(ctx, record); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		if record.ID == 0 {
+			t.Errorf("record %s has no ID", record.FileName)
+		}
+	}
+
+	all, total, err := store.List(ctx, WatchResultFilter{})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if total != 3 || len(all) != 3 || all[0].FileName != "three.mov" {
+		t.Errorf("expected 3 results newest first, got %d: %+v", total, all)
+	}
+
+	failed, total, err := store.List(ctx, WatchResultFilter{Folder: "/ingest/a", Verdict: "failed"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if total != 1 || len(failed) != 1 || len(failed[0].Reasons) != 2 || failed[0].Reasons[1] != "video.codec" {
+		t.Errorf("unexpected filtered results: %+v", failed)
+	}
+
+	page, total, _ := store.List(ctx, WatchResultFilter{Limit: 1, Offset: 1})
+	if total != 3 || len(page) != 1 || page[0].FileName != "two.mxf" {
+		t.Errorf("unexpected page: %+v", page)
+	}
+}
@@ -0,0 +1,149 @@
+package drm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// PSSH is a Protection System Specific Header box
+type PSSH struct {
+	SystemID string   `json:"system_id"`
+	Version  int      `json:"version"`
+	KIDs     []string `json:"kids,omitempty"` // Version 1 boxes list the key IDs they cover
+	DataSize int      `json:"data_size"`
+}
+
+// InitSegmentProtection is the protection found in the boxes of an
+// initialization segment
+type InitSegmentProtection struct {
+	Scheme     string // schm scheme type, e.g. cenc or cbcs
+	DefaultKID string // tenc default_KID
+	PSSH       []PSSH
+}
+
+// ErrNotISOBMFF is returned for data that does not start with an ISO base
+// media file box
+var ErrNotISOBMFF = errors.New("not an ISO base media file")
+
+// Container boxes walked to find protection boxes
+var containerBoxes = map[string]bool{
+	"moov": true, "trak": true, "mdia": true, "minf": true, "stbl": true,
+	"sinf": true, "schi": true, "moof": true, "traf": true,
+}
+
+// Header sizes of protected sample entries after the box header; their
+// child boxes, including sinf, follow
+var sampleEntryHeaders = map[string]int{
+	"encv": 78, // VisualSampleEntry
+	"enca": 28, // AudioSampleEntry
+}
+
+// ParsePSSH parses a complete pssh box
+func ParsePSSH(data []byte) (PSSH, error) {
+	boxType, payload, _, err := readBox(data)
+	if err != nil {
+		return PSSH{}, err
+	}
+	if boxType != "pssh" {
+		return PSSH{}, fmt.Errorf("expected pssh box, got %q", boxType)
+	}
+	return parsePSSHPayload(payload)
+}
+
+// InspectInitSegment walks the boxes of an initialization segment and
+// collects its pssh, schm and tenc boxes
+func InspectInitSegment(data []byte) (*InitSegmentProtection, error) {
+	if _, _, _, err := readBox(data); err != nil {
+		return nil, ErrNotISOBMFF
+	}
+	protection := &InitSegmentProtection{}
+	walkBoxes(data, protection)
+	return protection, nil
+}
+
+func walkBoxes(data []byte, protection *InitSegmentProtection) {
+	for len(data) >= 8 {
+		boxType, payload, size, err := readBox(data)
+		if err != nil {
+			return
+		}
+		switch {
+		case containerBoxes[boxType]:
+			walkBoxes(payload, protection)
+		case boxType == "stsd" && len(payload) >= 8:
+			// Full box header and entry count precede the sample entries
+			walkBoxes(payload[8:], protection)
+		case sampleEntryHeaders[boxType] > 0 && len(payload) >= sampleEntryHeaders[boxType]:
+			walkBoxes(payload[sampleEntryHeaders[boxType]:], protection)
+		case boxType == "schm" && len(payload) >= 8:
+			if protection.Scheme == "" {
+				protection.Scheme = string(payload[4:8])
+			}
+		case boxType == "tenc" && len(payload) >= 24:
+			if protection.DefaultKID == "" {
+				protection.DefaultKID = formatUUID(payload[8:24])
+			}
+		case boxType == "pssh":
+			if pssh, err := parsePSSHPayload(payload); err == nil {
+				protection.PSSH = append(protection.PSSH, pssh)
+			}
+		}
+		data = data[size:]
+	}
+}
+
+// readBox reads the box at the start of data and returns its type, its
+// payload and its total size
+func readBox(data []byte) (string, []byte, int, error) {
+	if len(data) < 8 {
+		return "", nil, 0, fmt.Errorf("truncated box header")
+	}
+	size := uint64(binary.BigEndian.Uint32(data[0:4]))
+	boxType := string(data[4:8])
+	header := uint64(8)
+	switch size {
+	case 0:
+		size = uint64(len(data))
+	case 1:
+		if len(data) < 16 {
+			return "", nil, 0, fmt.Errorf("truncated box header")
+		}
+		size = binary.BigEndian.Uint64(data[8:16])
+		header = 16
+	}
+	if size < header || size > uint64(len(data)) {
+		return "", nil, 0, fmt.Errorf("invalid size %d of box %q", size, boxType)
+	}
+	return boxType, data[header:size], int(size), nil
+}
+
+func parsePSSHPayload(payload []byte) (PSSH, error) {
+	if len(payload) < 24 {
+		return PSSH{}, fmt.Errorf("truncated pssh box")
+	}
+	pssh := PSSH{
+		Version:  int(payload[0]),
+		SystemID: formatUUID(payload[4:20]),
+	}
+	rest := payload[20:]
+	if pssh.Version > 0 {
+		count := int(binary.BigEndian.Uint32(rest[0:4]))
+		rest = rest[4:]
+		if count > len(rest)/16 {
+			return PSSH{}, fmt.Errorf("truncated pssh key IDs")
+		}
+		for i := 0; i < count; i++ {
+			pssh.KIDs = append(pssh.KIDs, formatUUID(rest[i*16:(i+1)*16]))
+		}
+		rest = rest[count*16:]
+	}
+	if len(rest) < 4 {
+		return PSSH{}, fmt.Errorf("truncated pssh box")
+	}
+	pssh.DataSize = int(binary.BigEndian.Uint32(rest[0:4]))
+	if pssh.DataSize > len(rest)-4 {
+		return PSSH{}, fmt.Errorf("truncated pssh data")
+	}
+	return pssh, nil
+}
@@ -0,0 +1,255 @@
+// Package drm reports the encryption and DRM signalling of streaming
+// packages: HLS keys, DASH ContentProtection descriptors and the protection
+// boxes (pssh, schm, tenc) of fragmented MP4 initialization segments.
+package drm
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Well-known DRM system IDs
+const (
+	SystemWidevine  = "edef8ba9-79d6-4ace-a3c8-27dcd51d21ed"
+	SystemPlayReady = "9a04f079-9840-4286-ab92-e65be0885f95"
+	SystemFairPlay  = "94ce86fb-07ff-4f43-adb8-93d2fa968ca2"
+	SystemClearKey  = "e2719d58-a985-b3c9-781a-b030af78d30e"
+	SystemCommon    = "1077efec-c0b2-4d02-ace3-3c1e52e2fb4b" // W3C common PSSH, used by ClearKey
+	SystemMarlin    = "5e629af5-38da-4063-8977-97ffbd9902d4"
+)
+
+var systemNames = map[string]string{
+	SystemWidevine:  "Widevine",
+	SystemPlayReady: "PlayReady",
+	SystemFairPlay:  "FairPlay",
+	SystemClearKey:  "ClearKey",
+	SystemCommon:    "ClearKey",
+	SystemMarlin:    "Marlin",
+}
+
+// SystemName returns the name of a DRM system ID, or "unknown"
+func SystemName(systemID string) string {
+	if name, ok := systemNames[strings.ToLower(systemID)]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Protection scheme types of ISO/IEC 23001-7 (Common Encryption)
+const (
+	SchemeCENC = "cenc" // AES-CTR, full sample
+	SchemeCBCS = "cbcs" // AES-CBC, pattern; used by FairPlay and CMAF
+	SchemeCENS = "cens"
+	SchemeCBC1 = "cbc1"
+)
+
+// Encryption summarizes how a package is encrypted and which DRM systems it
+// signals, in the manifest and, when inspected, in initialization segments
+type Encryption struct {
+	Encrypted   bool      `json:"encrypted"`
+	Methods     []string  `json:"methods,omitempty"` // HLS METHOD values and CENC scheme types
+	Systems     []*System `json:"drm_systems,omitempty"`
+	KeyURIs     []string  `json:"key_uris,omitempty"`
+	DefaultKIDs []string  `json:"default_kids,omitempty"`
+
+	EncryptedRenditions int `json:"encrypted_renditions"`
+	ClearRenditions     int `json:"clear_renditions"`
+
+	InitSegmentsInspected int            `json:"init_segments_inspected"`
+	InitSegments          []*InitSegment `json:"init_segments,omitempty"`
+	Issues                []string       `json:"issues,omitempty"`
+}
+
+// System is a DRM system signalled by a package
+type System struct {
+	SystemID      string   `json:"system_id,omitempty"`
+	Name          string   `json:"name"`
+	KeyFormats    []string `json:"key_formats,omitempty"` // HLS KEYFORMAT values
+	InManifest    bool     `json:"in_manifest"`
+	InInitSegment bool     `json:"in_init_segment"`
+	HasPSSH       bool     `json:"has_pssh"` // A pssh box was found in the manifest or a segment
+	KIDs          []string `json:"kids,omitempty"`
+}
+
+// InitSegment is the protection found in one initialization segment
+type InitSegment struct {
+	URL        string   `json:"url"`
+	Scheme     string   `json:"scheme,omitempty"` // schm scheme type
+	DefaultKID string   `json:"default_kid,omitempty"`
+	Systems    []string `json:"drm_systems,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// AddMethod records an encryption method or scheme type
+func (e *Encryption) AddMethod(method string) {
+	if method == "" || strings.EqualFold(method, "NONE") {
+		return
+	}
+	e.Encrypted = true
+	e.Methods = appendUnique(e.Methods, method)
+}
+
+// AddKeyURI records a key delivery URI. Inline data URIs are not listed.
+func (e *Encryption) AddKeyURI(uri string) {
+	if uri == "" || strings.HasPrefix(uri, "data:") {
+		return
+	}
+	e.KeyURIs = appendUnique(e.KeyURIs, uri)
+}
+
+// AddDefaultKID records a default key ID
+func (e *Encryption) AddDefaultKID(kid string) {
+	if kid = NormalizeKID(kid); kid != "" {
+		e.DefaultKIDs = appendUnique(e.DefaultKIDs, kid)
+	}
+}
+
+// AddSystem records a DRM system signalled in the manifest. systemID may be
+// empty when only the name is known, as with HLS KEYFORMATs.
+func (e *Encryption) AddSystem(systemID, name, keyFormat string) *System {
+	e.Encrypted = true
+	system := e.system(strings.ToLower(systemID), name)
+	system.InManifest = true
+	if keyFormat != "" {
+		system.KeyFormats = appendUnique(system.KeyFormats, keyFormat)
+	}
+	return system
+}
+
+// AddPSSH records a pssh box found in the manifest, or in an init segment
+// when inInitSegment is set
+func (e *Encryption) AddPSSH(pssh PSSH, inInitSegment bool) {
+	e.Encrypted = true
+	system := e.system(pssh.SystemID, SystemName(pssh.SystemID))
+	system.HasPSSH = true
+	if inInitSegment {
+		system.InInitSegment = true
+	} else {
+		system.InManifest = true
+	}
+	for _, kid := range pssh.KIDs {
+		system.KIDs = appendUnique(system.KIDs, kid)
+	}
+}
+
+// AddInitSegment records the protection boxes of an initialization segment
+func (e *Encryption) AddInitSegment(url string, data []byte) {
+	e.InitSegmentsInspected++
+	segment := &InitSegment{URL: url}
+	protection, err := InspectInitSegment(data)
+	if err != nil {
+		segment.Error = err.Error()
+		e.InitSegments = append(e.InitSegments, segment)
+		return
+	}
+
+	segment.Scheme = protection.Scheme
+	segment.DefaultKID = protection.DefaultKID
+	if protection.Scheme != "" {
+		e.AddMethod(protection.Scheme)
+	}
+	e.AddDefaultKID(protection.DefaultKID)
+	for _, pssh := range protection.PSSH {
+		segment.Systems = appendUnique(segment.Systems, SystemName(pssh.SystemID))
+		e.AddPSSH(pssh, true)
+	}
+	e.InitSegments = append(e.InitSegments, segment)
+}
+
+// AddIssue records a packaging problem
+func (e *Encryption) AddIssue(format string, args ...interface{}) {
+	e.Issues = appendUnique(e.Issues, fmt.Sprintf(format, args...))
+}
+
+// Finalize checks the collected signalling for consistency. Call it once
+// everything has been added.
+func (e *Encryption) Finalize() {
+	if e.EncryptedRenditions > 0 && e.ClearRenditions > 0 {
+		e.AddIssue("%d of %d renditions are not encrypted", e.ClearRenditions, e.EncryptedRenditions+e.ClearRenditions)
+	}
+
+	schemes := map[string]bool{}
+	for _, segment := range e.InitSegments {
+		if segment.Scheme != "" {
+			schemes[segment.Scheme] = true
+		}
+	}
+	if len(schemes) > 1 {
+		e.AddIssue("Initialization segments use different protection schemes: %s", strings.Join(sortedKeys(schemes), ", "))
+	}
+
+	// Widevine and PlayReady licenses are requested with the pssh data,
+	// so it must reach the player through the manifest or the segments
+	if e.InitSegmentsInspected > 0 {
+		for _, system := range e.Systems {
+			if (system.SystemID == SystemWidevine || system.SystemID == SystemPlayReady) && !system.HasPSSH {
+				e.AddIssue("%s is signalled but no pssh box was found in the manifest or initialization segments", system.Name)
+			}
+		}
+	}
+
+	sort.Slice(e.Systems, func(i, j int) bool { return e.Systems[i].Name < e.Systems[j].Name })
+}
+
+// system returns the recorded system with systemID, or with name when the
+// ID is not known, adding it if needed
+func (e *Encryption) system(systemID, name string) *System {
+	for _, system := range e.Systems {
+		if (systemID != "" && system.SystemID == systemID) || (system.Name == name && (systemID == "" || system.SystemID == "")) {
+			if system.SystemID == "" {
+				system.SystemID = systemID
+			}
+			return system
+		}
+	}
+	system := &System{SystemID: systemID, Name: name}
+	e.Systems = append(e.Systems, system)
+	return system
+}
+
+// ParsePSSHBase64 decodes a base64 pssh box, as carried by the cenc:pssh
+// element of DASH and the data URIs of HLS Widevine keys
+func ParsePSSHBase64(value string) (PSSH, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return PSSH{}, fmt.Errorf("invalid base64 pssh: %w", err)
+	}
+	return ParsePSSH(data)
+}
+
+// NormalizeKID formats a key ID as a lower-case UUID. Values that are not
+// 16-byte key IDs are returned lower-cased.
+func NormalizeKID(kid string) string {
+	kid = strings.ToLower(strings.TrimSpace(kid))
+	raw, err := hex.DecodeString(strings.ReplaceAll(kid, "-", ""))
+	if err != nil || len(raw) != 16 {
+		return kid
+	}
+	return formatUUID(raw)
+}
+
+func formatUUID(b []byte) string {
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,136 @@
+package drm
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+const testKID = "0123456789abcdef0123456789abcdef"
+
+func box(boxType string, payload ...[]byte) []byte {
+	var body []byte
+	for _, p := range payload {
+		body = append(body, p...)
+	}
+	out := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(out, uint32(8+len(body)))
+	copy(out[4:], boxType)
+	return append(out, body...)
+}
+
+func uuidBytes(t *testing.T, id string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(id, "-", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func psshBox(t *testing.T, systemID string, version byte, kids ...string) []byte {
+	payload := []byte{version, 0, 0, 0}
+	payload = append(payload, uuidBytes(t, systemID)...)
+	if version > 0 {
+		count := make([]byte, 4)
+		binary.BigEndian.PutUint32(count, uint32(len(kids)))
+		payload = append(payload, count...)
+		for _, kid := range kids {
+			payload = append(payload, uuidBytes(t, kid)...)
+		}
+	}
+	data := []byte{1, 2, 3}
+	size := make([]byte, 4)
+	binary.BigEndian.PutUint32(size, uint32(len(data)))
+	return box("pssh", payload, size, data)
+}
+
+// protectedInitSegment builds a minimal init segment with an encv sample
+// entry protected by scheme, and a Widevine pssh in moov
+func protectedInitSegment(t *testing.T, scheme string) []byte {
+	schm := box("schm", []byte{0, 0, 0, 0}, []byte(scheme), []byte{0, 1, 0, 0})
+	tenc := box("tenc", []byte{0, 0, 0, 0, 0, 0, 1, 8}, uuidBytes(t, testKID))
+	sinf := box("sinf", box("frma", []byte("avc1")), schm, box("schi", tenc))
+	encv := box("encv", make([]byte, 78), sinf)
+	stsd := box("stsd", []byte{0, 0, 0, 0, 0, 0, 0, 1}, encv)
+	trak := box("trak", box("mdia", box("minf", box("stbl", stsd))))
+	moov := box("moov", box("mvhd", make([]byte, 100)), trak, psshBox(t, SystemWidevine, 0))
+	return append(box("ftyp", []byte("iso6")), moov...)
+}
+
+func TestInspectInitSegment(t *testing.T) {
+	protection, err := InspectInitSegment(protectedInitSegment(t, SchemeCBCS))
+	if err != nil {
+		t.Fatalf("InspectInitSegment failed: %v", err)
+	}
+	if protection.Scheme != SchemeCBCS {
+		t.Errorf("expected scheme cbcs, got %q", protection.Scheme)
+	}
+	if protection.DefaultKID != NormalizeKID(testKID) {
+		t.Errorf("expected default KID %s, got %q", NormalizeKID(testKID), protection.DefaultKID)
+	}
+	if len(protection.PSSH) != 1 || protection.PSSH[0].SystemID != SystemWidevine || protection.PSSH[0].DataSize != 3 {
+		t.Errorf("expected one Widevine pssh, got %+v", protection.PSSH)
+	}
+
+	if _, err := InspectInitSegment([]byte("#EXTM3U")); err != ErrNotISOBMFF {
+		t.Errorf("expected ErrNotISOBMFF for a playlist, got %v", err)
+	}
+}
+
+func TestParsePSSHBase64(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString(psshBox(t, SystemPlayReady, 1, testKID))
+	pssh, err := ParsePSSHBase64(encoded)
+	if err != nil {
+		t.Fatalf("ParsePSSHBase64 failed: %v", err)
+	}
+	if pssh.SystemID != SystemPlayReady || pssh.Version != 1 {
+		t.Errorf("unexpected pssh %+v", pssh)
+	}
+	if len(pssh.KIDs) != 1 || pssh.KIDs[0] != NormalizeKID(testKID) {
+		t.Errorf("expected key ID %s, got %v", NormalizeKID(testKID), pssh.KIDs)
+	}
+
+	truncated := psshBox(t, SystemWidevine, 0)
+	binary.BigEndian.PutUint32(truncated, uint32(len(truncated)-2))
+	if _, err := ParsePSSH(truncated[:len(truncated)-2]); err == nil {
+		t.Error("expected an error for truncated pssh data")
+	}
+	if _, err := ParsePSSHBase64("not base64!"); err == nil {
+		t.Error("expected an error for invalid base64")
+	}
+}
+
+func TestEncryptionFinalize(t *testing.T) {
+	encryption := &Encryption{EncryptedRenditions: 3, ClearRenditions: 1}
+	encryption.AddSystem(SystemPlayReady, SystemName(SystemPlayReady), "")
+	encryption.AddInitSegment("video/init.mp4", protectedInitSegment(t, SchemeCENC))
+	encryption.AddInitSegment("audio/init.mp4", protectedInitSegment(t, SchemeCBCS))
+	encryption.Finalize()
+
+	if !encryption.Encrypted || encryption.InitSegmentsInspected != 2 {
+		t.Fatalf("unexpected summary %+v", encryption)
+	}
+	if len(encryption.Systems) != 2 || encryption.Systems[0].Name != "PlayReady" || encryption.Systems[1].Name != "Widevine" {
+		t.Fatalf("expected PlayReady and Widevine, got %+v", encryption.Systems)
+	}
+	if !encryption.Systems[1].InInitSegment || !encryption.Systems[1].HasPSSH {
+		t.Errorf("expected Widevine pssh from the init segment, got %+v", encryption.Systems[1])
+	}
+
+	for _, want := range []string{
+		"1 of 4 renditions are not encrypted",
+		"Initialization segments use different protection schemes: cbcs, cenc",
+		"PlayReady is signalled but no pssh box was found in the manifest or initialization segments",
+	} {
+		found := false
+		for _, issue := range encryption.Issues {
+			found = found || issue == want
+		}
+		if !found {
+			t.Errorf("expected issue %q in %v", want, encryption.Issues)
+		}
+	}
+}
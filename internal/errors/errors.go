@@ -1,20 +1,24 @@
 package errors
 
 import (
+	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// ErrorResponse represents a standardized error response
-type ErrorResponse struct {
-	Error     string    `json:"error"`
-	Code      string    `json:"code,omitempty"`
-	Details   string    `json:"details,omitempty"`
-	Timestamp time.Time `json:"timestamp"`
-	RequestID string    `json:"request_id,omitempty"`
-}
+// Error responses are RFC 7807 problem details served as
+// application/problem+json. Code is a stable machine-readable identifier
+// clients branch on; "error" repeats the detail for clients written against
+// the earlier {"error": "..."} bodies.
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// typePrefix starts the type URI of every problem, followed by its code
+const typePrefix = "urn:rendiff-probe:problem:"
 
 // Common error codes
 const (
@@ -27,26 +31,209 @@ const (
 	CodeConflict           = "CONFLICT"
 	CodeTooManyRequests    = "TOO_MANY_REQUESTS"
 	CodeServiceUnavailable = "SERVICE_UNAVAILABLE"
+	CodeNotImplemented     = "NOT_IMPLEMENTED"
+	CodeGone               = "GONE"
+	CodeTimeout            = "TIMEOUT"
+	CodeUpstreamError      = "UPSTREAM_ERROR"
+	CodePayloadTooLarge    = "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMedia   = "UNSUPPORTED_MEDIA_TYPE"
 )
 
-// RespondWithError sends a standardized error response
-func RespondWithError(c *gin.Context, statusCode int, code, message, details string) {
-	requestID := ""
-	if rid, exists := c.Get("request_id"); exists {
-		if ridStr, ok := rid.(string); ok {
-			requestID = ridStr
+// Error codes of media analysis
+const (
+	CodeFileTooLarge        = "FILE_TOO_LARGE"
+	CodeURLBlocked          = "URL_BLOCKED"
+	CodeDownloadFailed      = "DOWNLOAD_FAILED"
+	CodeAnalysisFailed      = "ANALYSIS_FAILED"
+	CodeFFprobeTimeout      = "FFPROBE_TIMEOUT"
+	CodeServerBusy          = "SERVER_BUSY"
+	CodeQuotaExceeded       = "QUOTA_EXCEEDED"
+	CodeInsufficientStorage = "INSUFFICIENT_STORAGE"
+	CodeFeatureDisabled     = "FEATURE_DISABLED"
+	CodeInvalidProfile      = "INVALID_PROFILE"
+	CodeInvalidCategory     = "INVALID_CATEGORY"
+	CodeInvalidCallbackURL  = "INVALID_CALLBACK_URL"
+	CodeInvalidSegment      = "INVALID_SEGMENT"
+)
+
+// titles are the short summaries of codes whose title is not the status text
+var titles = map[string]string{
+	CodeValidationError:     "Validation error",
+	CodeFileTooLarge:        "File too large",
+	CodeURLBlocked:          "URL not allowed",
+	CodeDownloadFailed:      "Download failed",
+	CodeAnalysisFailed:      "Analysis failed",
+	CodeFFprobeTimeout:      "Analysis timed out",
+	CodeServerBusy:          "Server busy",
+	CodeQuotaExceeded:       "Disk quota exceeded",
+	CodeInsufficientStorage: "Insufficient storage",
+	CodeFeatureDisabled:     "Feature disabled",
+	CodeInvalidProfile:      "Invalid delivery profile",
+	CodeInvalidCategory:     "Invalid QC category",
+	CodeInvalidCallbackURL:  "Invalid callback URL",
+	CodeInvalidSegment:      "Invalid segment",
+}
+
+// Problem is an RFC 7807 problem details body. It is also an error, so
+// GraphQL resolvers can return it and keep its code.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	// Message repeats Detail as "error", the member earlier bodies had
+	Message   string    `json:"error"`
+	Details   string    `json:"details,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	RequestID string    `json:"request_id,omitempty"`
+	// members are extension members, such as max_size_bytes
+	members map[string]interface{}
+}
+
+// ErrorResponse is the former name of Problem
+type ErrorResponse = Problem
+
+// New returns the problem of a response with status and code. detail
+// explains this occurrence and is shown to clients.
+func New(status int, code, detail string) *Problem {
+	title, ok := titles[code]
+	if !ok {
+		title = http.StatusText(status)
+	}
+	return &Problem{
+		Type:    typePrefix + strings.ReplaceAll(strings.ToLower(code), "_", "-"),
+		Title:   title,
+		Status:  status,
+		Detail:  detail,
+		Code:    code,
+		Message: detail,
+	}
+}
+
+// CodeForStatus is the generic code of an HTTP error status
+func CodeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeBadRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusGone:
+		return CodeGone
+	case http.StatusRequestEntityTooLarge:
+		return CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		return CodeUnsupportedMedia
+	case http.StatusUnprocessableEntity:
+		return CodeValidationError
+	case http.StatusTooManyRequests:
+		return CodeTooManyRequests
+	case http.StatusNotImplemented:
+		return CodeNotImplemented
+	case http.StatusBadGateway:
+		return CodeUpstreamError
+	case http.StatusServiceUnavailable:
+		return CodeServiceUnavailable
+	case http.StatusGatewayTimeout:
+		return CodeTimeout
+	case http.StatusInsufficientStorage:
+		return CodeInsufficientStorage
+	}
+	return CodeInternalError
+}
+
+// With adds an extension member to the problem. Members named like a
+// standard member are ignored.
+func (p *Problem) With(key string, value interface{}) *Problem {
+	if p.members == nil {
+		p.members = make(map[string]interface{})
+	}
+	p.members[key] = value
+	return p
+}
+
+// Error returns the detail
+func (p *Problem) Error() string {
+	return p.Detail
+}
+
+// Extensions returns the code and status, which GraphQL adds to the
+// extensions of the error
+func (p *Problem) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"code": p.Code, "status": p.Status}
+	for key, value := range p.members {
+		if _, ok := extensions[key]; !ok {
+			extensions[key] = value
+		}
+	}
+	return extensions
+}
+
+// Payload returns the problem as the error of a job result or callback,
+// which have a status member of their own: the detail as "error", the code
+// and the extension members
+func (p *Problem) Payload() gin.H {
+	payload := gin.H{"error": p.Detail, "code": p.Code}
+	for key, value := range p.members {
+		if _, ok := payload[key]; !ok {
+			payload[key] = value
+		}
+	}
+	return payload
+}
+
+// MarshalJSON encodes the standard members and the extension members
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	data, err := json.Marshal((*problem)(p))
+	if err != nil || len(p.members) == 0 {
+		return data, err
+	}
+
+	var members map[string]interface{}
+	if err := json.Unmarshal(data, &members); err != nil {
+		return nil, err
+	}
+	for key, value := range p.members {
+		if _, ok := members[key]; !ok {
+			members[key] = value
 		}
 	}
+	return json.Marshal(members)
+}
 
-	response := ErrorResponse{
-		Error:     message,
-		Code:      code,
-		Details:   details,
-		Timestamp: time.Now(),
-		RequestID: requestID,
+// Respond writes the problem as the response, filling in the request path,
+// request ID and time
+func Respond(c *gin.Context, p *Problem) {
+	if p.Instance == "" && c.Request != nil {
+		p.Instance = c.Request.URL.Path
 	}
+	if p.RequestID == "" {
+		p.RequestID = c.GetString("request_id")
+	}
+	p.Timestamp = time.Now()
+	c.Header("Content-Type", ContentType)
+	c.JSON(p.Status, p)
+}
+
+// Abort writes the problem as the response and stops the handler chain
+func Abort(c *gin.Context, p *Problem) {
+	Respond(c, p)
+	c.Abort()
+}
 
-	c.JSON(statusCode, response)
+// RespondWithError sends a standardized error response
+func RespondWithError(c *gin.Context, statusCode int, code, message, details string) {
+	problem := New(statusCode, code, message)
+	problem.Details = details
+	Respond(c, problem)
 }
 
 // Common error response helpers
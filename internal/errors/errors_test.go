@@ -0,0 +1,102 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNew(t *testing.T) {
+	problem := New(413, CodeFileTooLarge, "File too large")
+	if problem.Type != "urn:rendiff-probe:problem:file-too-large" {
+		t.Errorf("unexpected type %q", problem.Type)
+	}
+	if problem.Title != "File too large" || problem.Message != "File too large" {
+		t.Errorf("unexpected title %q or message %q", problem.Title, problem.Message)
+	}
+
+	problem = New(404, CodeNotFound, "Analysis not found")
+	if problem.Title != "Not Found" {
+		t.Errorf("expected the status text as title, got %q", problem.Title)
+	}
+}
+
+func TestProblem_MarshalJSON(t *testing.T) {
+	problem := New(413, CodeFileTooLarge, "File too large").With("max_size_bytes", 1024).With("status", "ignored")
+	data, err := json.Marshal(problem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["code"] != CodeFileTooLarge || body["error"] != "File too large" {
+		t.Errorf("unexpected body %v", body)
+	}
+	if body["status"] != float64(413) {
+		t.Errorf("expected a member not to override status, got %v", body["status"])
+	}
+	if body["max_size_bytes"] != float64(1024) {
+		t.Errorf("expected max_size_bytes member, got %v", body["max_size_bytes"])
+	}
+}
+
+func TestProblem_Payload(t *testing.T) {
+	payload := New(504, CodeFFprobeTimeout, "Analysis timed out").With("error", "ignored").Payload()
+	if payload["error"] != "Analysis timed out" || payload["code"] != CodeFFprobeTimeout {
+		t.Errorf("unexpected payload %v", payload)
+	}
+	if _, ok := payload["status"]; ok {
+		t.Error("payload must leave status to the job result")
+	}
+}
+
+func TestRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/analyses/42", nil)
+	c.Set("request_id", "req-1")
+
+	Abort(c, New(400, CodeURLBlocked, "Invalid or blocked URL"))
+
+	if recorder.Code != 400 {
+		t.Errorf("expected status 400, got %d", recorder.Code)
+	}
+	if got := recorder.Header().Get("Content-Type"); got != ContentType {
+		t.Errorf("expected content type %q, got %q", ContentType, got)
+	}
+	if !c.IsAborted() {
+		t.Error("expected the handler chain to be aborted")
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["instance"] != "/api/v1/analyses/42" || body["request_id"] != "req-1" || body["code"] != CodeURLBlocked {
+		t.Errorf("unexpected body %v", body)
+	}
+}
+
+func TestCodeForStatus(t *testing.T) {
+	tests := map[int]string{
+		400: CodeBadRequest,
+		404: CodeNotFound,
+		413: CodePayloadTooLarge,
+		503: CodeServiceUnavailable,
+		507: CodeInsufficientStorage,
+		500: CodeInternalError,
+		418: CodeInternalError,
+	}
+	for status, want := range tests {
+		if got := CodeForStatus(status); got != want {
+			t.Errorf("CodeForStatus(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
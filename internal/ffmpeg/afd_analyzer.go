@@ -30,7 +30,9 @@ type AFDAnalysis struct {
 	HasAFD              bool                 `json:"has_afd"`
 	AFDStreams          map[int]*AFDInfo     `json:"afd_streams,omitempty"`
 	PrimaryAFD          *AFDInfo             `json:"primary_afd,omitempty"`
+	AFDSegments         []AFDSegment         `json:"afd_segments,omitempty"`
 	AFDChanges          []AFDChange          `json:"afd_changes,omitempty"`
+	FramesScanned       int                  `json:"frames_scanned,omitempty"`
 	AspectRatioInfo     *AspectRatioInfo     `json:"aspect_ratio_info,omitempty"`
 	ValidationResults   *AFDValidation       `json:"validation_results,omitempty"`
 	BroadcastCompliance *BroadcastCompliance `json:"broadcast_compliance,omitempty"`
@@ -62,6 +64,19 @@ type AFDChange struct {
 	Reason         string  `json:"reason"`
 }
 
+// AFDSegment is a run of frames carrying the same AFD code. Decoders hold
+// the last signaled code until a new one arrives, so frames without AFD side
+// data extend the current segment.
+type AFDSegment struct {
+	AFDValue       int     `json:"afd_value"`
+	AFDDescription string  `json:"afd_description"`
+	StartFrame     int     `json:"start_frame"`
+	EndFrame       int     `json:"end_frame"`
+	StartTime      float64 `json:"start_time"`
+	EndTime        float64 `json:"end_time"`
+	SignaledFrames int     `json:"signaled_frames"` // Frames carrying AFD side data
+}
+
 // AspectRatioInfo contains detailed aspect ratio analysis
 type AspectRatioInfo struct {
 	DisplayAspectRatio   string  `json:"display_aspect_ratio"`
@@ -113,6 +128,13 @@ var afdDefinitions = map[int]string{
 	15: "Full frame 16:9 (center, shoot & protect 4:3)",
 }
 
+// afdSideDataType is the name ffprobe gives AV_FRAME_DATA_AFD side data
+const afdSideDataType = "Active format description"
+
+// afdScanTimeout bounds the full-program side data scan, which decodes every
+// frame of the first video stream
+const afdScanTimeout = 10 * time.Minute
+
 var afdPresentationModes = map[int]string{
 	0:  "undefined",
 	1:  "reserved",
@@ -138,28 +160,24 @@ func (aa *AFDAnalyzer) AnalyzeAFD(ctx context.Context, filePath string, streams
 	// Step 1: Analyze aspect ratio information from stream metadata
 	aa.analyzeAspectRatioInfo(streams, analysis)
 
-	// Step 2: Extract AFD from user data (H.264/H.265 SEI messages)
-	if err := aa.extractAFDFromUserData(ctx, filePath, analysis); err != nil {
-		aa.logger.Warn().Err(err).Msg("Failed to extract AFD from user data")
+	// Step 2: Extract the AFD timeline from decoded side data (MPEG-2 user
+	// data, H.264/H.265 SEI) and detect mid-program changes
+	if err := aa.extractAFDTimeline(ctx, filePath, analysis); err != nil {
+		aa.logger.Warn().Err(err).Msg("Failed to extract AFD side data")
 	}
 
-	// Step 3: Detect AFD from video characteristics and metadata
+	// Step 3: Infer AFD from video characteristics when none is signaled
 	if err := aa.detectAFDFromVideoCharacteristics(ctx, filePath, streams, analysis); err != nil {
 		aa.logger.Warn().Err(err).Msg("Failed to detect AFD from video characteristics")
 	}
 
-	// Step 4: Analyze AFD changes throughout the content
-	if err := aa.analyzeAFDChanges(ctx, filePath, analysis); err != nil {
-		aa.logger.Warn().Err(err).Msg("Failed to analyze AFD changes")
-	}
-
-	// Step 5: Determine primary AFD
+	// Step 4: Determine primary AFD
 	aa.determinePrimaryAFD(analysis)
 
-	// Step 6: Validate AFD compliance and consistency
+	// Step 5: Validate AFD compliance and consistency
 	analysis.ValidationResults = aa.validateAFD(analysis)
 
-	// Step 7: Check broadcast compliance
+	// Step 6: Check broadcast compliance
 	analysis.BroadcastCompliance = aa.checkBroadcastCompliance(analysis)
 
 	return analysis, nil
@@ -202,69 +220,156 @@ func (aa *AFDAnalyzer) analyzeAspectRatioInfo(streams []StreamInfo, analysis *AF
 	}
 }
 
-// extractAFDFromUserData extracts AFD information from H.264/H.265 user data
-func (aa *AFDAnalyzer) extractAFDFromUserData(ctx context.Context, filePath string, analysis *AFDAnalysis) error {
-	// Use ffprobe to extract user data that might contain AFD information
+// extractAFDTimeline decodes the first video stream and reads the AFD side
+// data FFmpeg exports from MPEG-2 picture user data and H.264/H.265
+// registered user data SEI. Every frame is scanned so that changes anywhere
+// in the program are found.
+func (aa *AFDAnalyzer) extractAFDTimeline(ctx context.Context, filePath string, analysis *AFDAnalysis) error {
 	cmd := []string{
 		aa.ffprobePath,
 		"-v", "quiet",
 		"-print_format", "json",
-		"-show_entries", "frame=side_data,pkt_pts_time",
 		"-select_streams", "v:0",
-		"-read_intervals", "%+#20", // Sample first 20 frames
+		"-show_entries", "frame=best_effort_timestamp_time:frame_side_data_list",
 		filePath,
 	}
 
-	output, err := aa.executeCommand(ctx, cmd)
+	execCtx, cancel := context.WithTimeout(ctx, afdScanTimeout)
+	defer cancel()
+
+	output, err := executeFFprobeCommand(execCtx, cmd)
 	if err != nil {
-		return fmt.Errorf("failed to extract user data: %w", err)
+		return fmt.Errorf("failed to read frame side data: %w", err)
 	}
 
+	samples, err := parseAFDSideData([]byte(output))
+	if err != nil {
+		return err
+	}
+
+	aa.applyAFDTimeline(samples, analysis)
+	return nil
+}
+
+// afdSample is the AFD code of one decoded frame, or -1 when the frame
+// carries no AFD side data
+type afdSample struct {
+	value     int
+	timestamp float64
+}
+
+// parseAFDSideData extracts per-frame AFD codes from ffprobe frame JSON
+func parseAFDSideData(output []byte) ([]afdSample, error) {
 	var result struct {
 		Frames []struct {
-			PktPtsTime string `json:"pkt_pts_time"`
-			SideData   []struct {
-				Type string                 `json:"type"`
-				Data map[string]interface{} `json:"data,omitempty"`
-			} `json:"side_data,omitempty"`
+			Timestamp    string `json:"best_effort_timestamp_time"`
+			SideDataList []struct {
+				Type         string `json:"side_data_type"`
+				ActiveFormat *int   `json:"active_format"`
+			} `json:"side_data_list"`
 		} `json:"frames"`
 	}
 
-	if err := json.Unmarshal([]byte(output), &result); err != nil {
-		return fmt.Errorf("failed to parse frame JSON: %w", err)
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse frame JSON: %w", err)
 	}
 
-	frameNumber := 0
-	for _, frame := range result.Frames {
-		frameNumber++
-
-		for _, sideData := range frame.SideData {
-			// Look for AFD in various side data types
-			if aa.containsAFDData(sideData.Type) {
-				if afdValue := aa.extractAFDValue(sideData.Data); afdValue >= 0 {
-					afdInfo := &AFDInfo{
-						StreamIndex:        0,
-						AFDValue:           afdValue,
-						AFDDescription:     afdDefinitions[afdValue],
-						PresentationMode:   afdPresentationModes[afdValue],
-						FirstDetectedFrame: frameNumber,
-						LastDetectedFrame:  frameNumber,
-						Confidence:         0.9,
-						IsValid:            aa.isValidAFDValue(afdValue),
-					}
-
-					// Derive aspect ratio from AFD value
-					afdInfo.AspectRatio = aa.deriveAspectRatioFromAFD(afdValue)
-					afdInfo.ProtectedArea = aa.deriveProtectedAreaFromAFD(afdValue)
-
-					analysis.AFDStreams[0] = afdInfo
-					analysis.HasAFD = true
+	samples := make([]afdSample, len(result.Frames))
+	for i, frame := range result.Frames {
+		samples[i].value = -1
+		samples[i].timestamp, _ = strconv.ParseFloat(frame.Timestamp, 64)
+		for _, sideData := range frame.SideDataList {
+			if sideData.ActiveFormat != nil && strings.EqualFold(sideData.Type, afdSideDataType) {
+				// active_format is the 4-bit code from the AFD byte
+				samples[i].value = *sideData.ActiveFormat & 0x0F
+			}
+		}
+	}
+	return samples, nil
+}
+
+// buildAFDSegments groups frames into runs of the same AFD code. Frames
+// before the first signaled code are not part of any segment.
+func buildAFDSegments(samples []afdSample) []AFDSegment {
+	var segments []AFDSegment
+	for frame, sample := range samples {
+		if sample.value < 0 || (len(segments) > 0 && segments[len(segments)-1].AFDValue == sample.value) {
+			if len(segments) > 0 {
+				current := &segments[len(segments)-1]
+				current.EndFrame = frame
+				current.EndTime = sample.timestamp
+				if sample.value >= 0 {
+					current.SignaledFrames++
 				}
 			}
+			continue
+		}
+		segments = append(segments, AFDSegment{
+			AFDValue:       sample.value,
+			AFDDescription: afdDefinitions[sample.value],
+			StartFrame:     frame,
+			EndFrame:       frame,
+			StartTime:      sample.timestamp,
+			EndTime:        sample.timestamp,
+			SignaledFrames: 1,
+		})
+	}
+	return segments
+}
+
+// applyAFDTimeline records segments, changes and the dominant AFD code
+func (aa *AFDAnalyzer) applyAFDTimeline(samples []afdSample, analysis *AFDAnalysis) {
+	analysis.FramesScanned = len(samples)
+	segments := buildAFDSegments(samples)
+	if len(segments) == 0 {
+		return
+	}
+	analysis.AFDSegments = segments
+
+	for i := 1; i < len(segments); i++ {
+		previous, current := segments[i-1], segments[i]
+		analysis.AFDChanges = append(analysis.AFDChanges, AFDChange{
+			FrameNumber:    current.StartFrame,
+			Timestamp:      current.StartTime,
+			OldAFDValue:    previous.AFDValue,
+			NewAFDValue:    current.AFDValue,
+			OldDescription: previous.AFDDescription,
+			NewDescription: current.AFDDescription,
+			Reason:         "Mid-program AFD change",
+		})
+	}
+
+	// The code covering the most frames describes the program
+	frames := make(map[int]int)
+	dominant := segments[0].AFDValue
+	for _, segment := range segments {
+		frames[segment.AFDValue] += segment.EndFrame - segment.StartFrame + 1
+		if frames[segment.AFDValue] > frames[dominant] {
+			dominant = segment.AFDValue
 		}
 	}
 
-	return nil
+	afdInfo := &AFDInfo{
+		StreamIndex:        0,
+		AFDValue:           dominant,
+		AFDDescription:     afdDefinitions[dominant],
+		PresentationMode:   afdPresentationModes[dominant],
+		AspectRatio:        aa.deriveAspectRatioFromAFD(dominant),
+		ProtectedArea:      aa.deriveProtectedAreaFromAFD(dominant),
+		FirstDetectedFrame: segments[0].StartFrame,
+		LastDetectedFrame:  segments[len(segments)-1].EndFrame,
+		Confidence:         0.9,
+		IsValid:            aa.isValidAFDValue(dominant),
+	}
+	if segments[0].StartFrame > 0 {
+		afdInfo.Issues = append(afdInfo.Issues, fmt.Sprintf("AFD first signaled at frame %d", segments[0].StartFrame))
+	}
+	if len(frames) > 1 {
+		afdInfo.Issues = append(afdInfo.Issues, fmt.Sprintf("%d different AFD codes signaled", len(frames)))
+	}
+
+	analysis.AFDStreams[0] = afdInfo
+	analysis.HasAFD = true
 }
 
 // detectAFDFromVideoCharacteristics infers AFD from video properties
@@ -379,75 +484,6 @@ func (aa *AFDAnalyzer) detectLetterboxing(ctx context.Context, filePath string,
 	return nil
 }
 
-// analyzeAFDChanges detects changes in AFD signaling throughout the content
-func (aa *AFDAnalyzer) analyzeAFDChanges(ctx context.Context, filePath string, analysis *AFDAnalysis) error {
-	if !analysis.HasAFD {
-		return nil
-	}
-
-	// Sample frames throughout the content to detect AFD changes
-	cmd := []string{
-		aa.ffprobePath,
-		"-v", "quiet",
-		"-print_format", "json",
-		"-show_entries", "frame=side_data,pkt_pts_time",
-		"-select_streams", "v:0",
-		"-read_intervals", "%+#100", // Sample 100 frames
-		filePath,
-	}
-
-	output, err := aa.executeCommand(ctx, cmd)
-	if err != nil {
-		return fmt.Errorf("failed to analyze AFD changes: %w", err)
-	}
-
-	// Parse and detect AFD changes
-	var result struct {
-		Frames []struct {
-			PktPtsTime string `json:"pkt_pts_time"`
-			SideData   []struct {
-				Type string                 `json:"type"`
-				Data map[string]interface{} `json:"data,omitempty"`
-			} `json:"side_data,omitempty"`
-		} `json:"frames"`
-	}
-
-	if err := json.Unmarshal([]byte(output), &result); err != nil {
-		return fmt.Errorf("failed to parse AFD changes JSON: %w", err)
-	}
-
-	previousAFD := -1
-	frameNumber := 0
-
-	for _, frame := range result.Frames {
-		frameNumber++
-		timestamp, _ := strconv.ParseFloat(frame.PktPtsTime, 64)
-
-		for _, sideData := range frame.SideData {
-			if aa.containsAFDData(sideData.Type) {
-				if afdValue := aa.extractAFDValue(sideData.Data); afdValue >= 0 {
-					if previousAFD >= 0 && afdValue != previousAFD {
-						// AFD change detected
-						change := AFDChange{
-							FrameNumber:    frameNumber,
-							Timestamp:      timestamp,
-							OldAFDValue:    previousAFD,
-							NewAFDValue:    afdValue,
-							OldDescription: afdDefinitions[previousAFD],
-							NewDescription: afdDefinitions[afdValue],
-							Reason:         "AFD signaling change",
-						}
-						analysis.AFDChanges = append(analysis.AFDChanges, change)
-					}
-					previousAFD = afdValue
-				}
-			}
-		}
-	}
-
-	return nil
-}
-
 // determinePrimaryAFD identifies the most reliable AFD value
 func (aa *AFDAnalyzer) determinePrimaryAFD(analysis *AFDAnalysis) {
 	// Use the AFD with highest confidence
@@ -496,7 +532,10 @@ func (aa *AFDAnalyzer) validateAFD(analysis *AFDAnalysis) *AFDValidation {
 
 	// Validate AFD changes
 	if len(analysis.AFDChanges) > 0 {
-		validation.Warnings = append(validation.Warnings, "AFD changes detected - verify intentional")
+		validation.IsConsistent = false
+		first := analysis.AFDChanges[0]
+		validation.Warnings = append(validation.Warnings, fmt.Sprintf("%d mid-program AFD change(s), first from %d to %d at %.3fs - verify intentional",
+			len(analysis.AFDChanges), first.OldAFDValue, first.NewAFDValue, first.Timestamp))
 
 		// Check for rapid AFD changes
 		for i := 1; i < len(analysis.AFDChanges); i++ {
@@ -607,35 +646,6 @@ func (aa *AFDAnalyzer) categorizeAspectRatio(aspectRatio string) string {
 	}
 }
 
-func (aa *AFDAnalyzer) containsAFDData(sideDataType string) bool {
-	afdTypes := []string{
-		"h264_sei", "h265_sei", "afd", "user_data",
-		"cea_708", "atsc_a53", "bar_data",
-	}
-
-	lowerType := strings.ToLower(sideDataType)
-	for _, afdType := range afdTypes {
-		if strings.Contains(lowerType, afdType) {
-			return true
-		}
-	}
-	return false
-}
-
-func (aa *AFDAnalyzer) extractAFDValue(data map[string]interface{}) int {
-	// Try to extract AFD value from various data structures
-	if afdVal, ok := data["afd"].(float64); ok {
-		return int(afdVal)
-	}
-	if afdVal, ok := data["active_format"].(float64); ok {
-		return int(afdVal)
-	}
-	if afdVal, ok := data["aspect_ratio_info"].(float64); ok {
-		return int(afdVal) & 0x0F // AFD is lower 4 bits
-	}
-	return -1
-}
-
 func (aa *AFDAnalyzer) isValidAFDValue(afdValue int) bool {
 	return afdValue >= 0 && afdValue <= 15
 }
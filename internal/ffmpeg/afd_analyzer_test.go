@@ -0,0 +1,79 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestParseAFDSideData(t *testing.T) {
+	output := []byte(`{"frames":[
+		{"best_effort_timestamp_time":"0.000000","side_data_list":[{"side_data_type":"Active format description","active_format":10}]},
+		{"best_effort_timestamp_time":"0.040000","side_data_list":[{"side_data_type":"ATSC A53 Part 4 Closed Captions"}]},
+		{"best_effort_timestamp_time":"0.080000"}
+	]}`)
+
+	samples, err := parseAFDSideData(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("expected 3 samples, got %d", len(samples))
+	}
+	if samples[0].value != 10 || samples[1].value != -1 || samples[2].value != -1 {
+		t.Errorf("unexpected AFD values: %+v", samples)
+	}
+	if samples[1].timestamp != 0.04 {
+		t.Errorf("expected timestamp 0.04, got %v", samples[1].timestamp)
+	}
+
+	if _, err := parseAFDSideData([]byte("not json")); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestApplyAFDTimeline(t *testing.T) {
+	samples := []afdSample{
+		{value: -1, timestamp: 0},
+		{value: 10, timestamp: 1},
+		{value: -1, timestamp: 2},
+		{value: 10, timestamp: 3},
+		{value: 9, timestamp: 4},
+		{value: -1, timestamp: 5},
+	}
+
+	aa := NewAFDAnalyzer("ffprobe", zerolog.Nop())
+	analysis := &AFDAnalysis{AFDStreams: make(map[int]*AFDInfo)}
+	aa.applyAFDTimeline(samples, analysis)
+
+	if !analysis.HasAFD || analysis.FramesScanned != 6 {
+		t.Fatalf("unexpected analysis: %+v", analysis)
+	}
+	if len(analysis.AFDSegments) != 2 {
+		t.Fatalf("expected 2 segments, got %+v", analysis.AFDSegments)
+	}
+	first, second := analysis.AFDSegments[0], analysis.AFDSegments[1]
+	if first.StartFrame != 1 || first.EndFrame != 3 || first.SignaledFrames != 2 {
+		t.Errorf("unexpected first segment: %+v", first)
+	}
+	if second.AFDValue != 9 || second.StartFrame != 4 || second.EndFrame != 5 || second.EndTime != 5 {
+		t.Errorf("unexpected second segment: %+v", second)
+	}
+
+	if len(analysis.AFDChanges) != 1 {
+		t.Fatalf("expected 1 change, got %+v", analysis.AFDChanges)
+	}
+	change := analysis.AFDChanges[0]
+	if change.FrameNumber != 4 || change.OldAFDValue != 10 || change.NewAFDValue != 9 {
+		t.Errorf("unexpected change: %+v", change)
+	}
+
+	primary := analysis.AFDStreams[0]
+	if primary.AFDValue != 10 || primary.FirstDetectedFrame != 1 || len(primary.Issues) != 2 {
+		t.Errorf("unexpected primary AFD: %+v", primary)
+	}
+
+	if validation := aa.validateAFD(analysis); validation.IsConsistent {
+		t.Error("expected AFD changes to mark the stream inconsistent")
+	}
+}
@@ -0,0 +1,208 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// aspectRatioTolerance is the relative difference between two display
+// aspect ratios that is still a match. It allows for the 704 and 720 sample
+// active widths ITU-R BT.601 video is described with.
+const aspectRatioTolerance = 0.03
+
+// h264SampleAspectRatios are the sample aspect ratios of aspect_ratio_idc in
+// H.264 and HEVC video usability information, ITU-T H.264 table E-1
+var h264SampleAspectRatios = map[int][2]int{
+	1: {1, 1}, 2: {12, 11}, 3: {10, 11}, 4: {16, 11}, 5: {40, 33}, 6: {24, 11}, 7: {20, 11}, 8: {32, 11},
+	9: {80, 33}, 10: {18, 11}, 11: {15, 11}, 12: {64, 33}, 13: {160, 99}, 14: {4, 3}, 15: {3, 2}, 16: {2, 1},
+}
+
+// h264ExtendedSAR is the aspect_ratio_idc whose ratio follows in sar_width
+// and sar_height
+const h264ExtendedSAR = 255
+
+// mpeg2DisplayAspectRatios are the display aspect ratios of
+// aspect_ratio_information in an MPEG-2 sequence header, ISO/IEC 13818-2
+// table 6-3. Code 1 signals square samples instead.
+var mpeg2DisplayAspectRatios = map[int][2]int{2: {4, 3}, 3: {16, 9}, 4: {221, 100}}
+
+// sdRasters are the SD picture sizes, in lines by samples per line, that
+// are always anamorphic: none of them shows 4:3 or 16:9 with square pixels
+var sdRasters = map[int][]int{
+	480: {704, 720},
+	486: {720},
+	576: {704, 720},
+}
+
+// checkAspectRatio cross-checks the aspect ratios of a video stream: the
+// display aspect ratio its size and sample aspect ratio give against the
+// one declared, and whether SD video is anamorphic and needs its sample
+// aspect ratio carried through transcodes
+func (ra *ResolutionAnalyzer) checkAspectRatio(resolution *VideoResolution, stream StreamInfo) {
+	if resolution.Width <= 0 || resolution.Height <= 0 {
+		return
+	}
+	sar := resolution.SampleAspectRatio
+	if sar <= 0 {
+		sar = 1
+	}
+	resolution.ExpectedDisplayAspectRatio = roundTo(float64(resolution.Width)*sar/float64(resolution.Height), 4)
+
+	if declared := resolution.DisplayAspectRatio; declared > 0 && !sameAspectRatio(declared, resolution.ExpectedDisplayAspectRatio) {
+		resolution.AspectRatioIssues = append(resolution.AspectRatioIssues, fmt.Sprintf(
+			"Display aspect ratio %s does not match the %.3f of %dx%d with sample aspect ratio %s",
+			stream.DisplayAspectRatio, resolution.ExpectedDisplayAspectRatio, resolution.Width, resolution.Height, orSquare(stream.SampleAspectRatio)))
+	}
+
+	if resolution.Height >= 720 {
+		return
+	}
+	if resolution.IsAnamorphic {
+		resolution.IsAnamorphicSD = true
+		resolution.SquarePixelWidth = int(math.Round(float64(resolution.Width)*sar/2)) * 2
+		return
+	}
+	for _, width := range sdRasters[resolution.Height] {
+		if width == resolution.Width {
+			resolution.AspectRatioIssues = append(resolution.AspectRatioIssues, fmt.Sprintf(
+				"%dx%d SD video has square pixels, so it displays at %.3f rather than 4:3 or 16:9; the sample aspect ratio is probably missing",
+				resolution.Width, resolution.Height, resolution.ExpectedDisplayAspectRatio))
+		}
+	}
+}
+
+// AnalyzeBitstreamAspectRatio reads the aspect ratio the codec headers of
+// H.264, HEVC and MPEG-2 video signal, which the container's overrides in
+// ffprobe's output, and flags streams where the two disagree: players that
+// follow the container and players that follow the codec show the picture
+// at different shapes. A stream whose headers cannot be read is skipped.
+func (ra *ResolutionAnalyzer) AnalyzeBitstreamAspectRatio(ctx context.Context, filePath string, analysis *ResolutionAnalysis, streams []StreamInfo) {
+	if analysis == nil {
+		return
+	}
+	for _, stream := range streams {
+		resolution := analysis.VideoStreams[stream.Index]
+		if resolution == nil || resolution.Height <= 0 || stream.Disposition["attached_pic"] == 1 {
+			continue
+		}
+		switch stream.CodecName {
+		case "h264", "hevc", "mpeg2video":
+		default:
+			continue
+		}
+
+		output, err := ra.traceHeaders(ctx, filePath, stream.Index)
+		if err != nil {
+			ra.logger.Debug().Err(err).Int("stream", stream.Index).Msg("Failed to read codec aspect ratio")
+			continue
+		}
+		sar, dar, ok := parseHeaderAspectRatio(output, stream.CodecName)
+		if !ok {
+			continue
+		}
+		if sar[1] > 0 {
+			resolution.BitstreamSampleAspectRatio = fmt.Sprintf("%d:%d", sar[0], sar[1])
+			resolution.BitstreamDisplayAspectRatio = roundTo(float64(resolution.Width*sar[0])/float64(resolution.Height*sar[1]), 4)
+		} else {
+			resolution.BitstreamDisplayAspectRatio = roundTo(float64(dar[0])/float64(dar[1]), 4)
+		}
+
+		container := resolution.DisplayAspectRatio
+		if container <= 0 {
+			container = resolution.ExpectedDisplayAspectRatio
+		}
+		if !sameAspectRatio(container, resolution.BitstreamDisplayAspectRatio) {
+			resolution.AspectRatioIssues = append(resolution.AspectRatioIssues, fmt.Sprintf(
+				"The container's display aspect ratio %.3f does not match the %.3f the %s headers signal",
+				container, resolution.BitstreamDisplayAspectRatio, stream.CodecName))
+			resolution.IsConsistent = false
+		}
+	}
+	analysis.Validation = ra.validateResolution(analysis)
+}
+
+// traceHeaders logs the headers of the first packet of a stream
+func (ra *ResolutionAnalyzer) traceHeaders(ctx context.Context, filePath string, index int) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, ra.ffmpegPath,
+		"-hide_banner", "-nostats",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", index),
+		"-c", "copy",
+		"-bsf:v", "trace_headers",
+		"-frames:v", "1",
+		"-f", "null",
+		"-",
+	)
+	output, err := commandCombinedOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("trace_headers failed: %w", err)
+	}
+	return output, nil
+}
+
+// parseHeaderAspectRatio reads the first aspect ratio in trace_headers
+// output, e.g.
+//
+//	[trace_headers @ 0x5581] 183         aspect_ratio_idc                11111111 = 255
+//
+// H.264 and HEVC signal a sample aspect ratio, MPEG-2 a display aspect
+// ratio or square samples; the other is returned as zero. ok is false when
+// the headers leave the aspect ratio unspecified.
+func parseHeaderAspectRatio(output []byte, codec string) (sar, dar [2]int, ok bool) {
+	values := make(map[string]int)
+	forEachLine(output, func(line string) bool {
+		if !strings.Contains(line, "[trace_headers") {
+			return true
+		}
+		fields := strings.Fields(line[strings.Index(line, "]")+1:])
+		if len(fields) < 4 || fields[len(fields)-2] != "=" {
+			return true
+		}
+		name := fields[1]
+		if _, seen := values[name]; seen {
+			return true
+		}
+		if value, err := strconv.Atoi(fields[len(fields)-1]); err == nil {
+			values[name] = value
+		}
+		return true
+	})
+
+	if codec == "mpeg2video" {
+		code, found := values["aspect_ratio_information"]
+		if code == 1 {
+			return [2]int{1, 1}, dar, true
+		}
+		dar, ok = mpeg2DisplayAspectRatios[code]
+		return sar, dar, found && ok
+	}
+
+	idc, found := values["aspect_ratio_idc"]
+	if !found {
+		return sar, dar, false
+	}
+	if idc == h264ExtendedSAR {
+		sar = [2]int{values["sar_width"], values["sar_height"]}
+		return sar, dar, sar[0] > 0 && sar[1] > 0
+	}
+	sar, ok = h264SampleAspectRatios[idc]
+	return sar, dar, ok
+}
+
+// sameAspectRatio reports whether display aspect ratios a and b match
+// within aspectRatioTolerance
+func sameAspectRatio(a, b float64) bool {
+	return math.Abs(a-b) <= aspectRatioTolerance*math.Min(a, b)
+}
+
+// orSquare names an unset sample aspect ratio
+func orSquare(sar string) string {
+	if sar == "" || sar == "0:1" || sar == "N/A" {
+		return "1:1 (unset)"
+	}
+	return sar
+}
@@ -0,0 +1,143 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// traceOutput builds trace_headers output logging fields with their values
+func traceOutput(fields ...string) string {
+	var b strings.Builder
+	b.WriteString("[trace_headers @ 0x5581] Sequence Parameter Set\n")
+	for i := 0; i+1 < len(fields); i += 2 {
+		b.WriteString("[trace_headers @ 0x5581] 183         " + fields[i] + "                        00000001 = " + fields[i+1] + "\n")
+	}
+	return b.String()
+}
+
+func TestAnalyzeResolution_AspectRatio(t *testing.T) {
+	ra := NewResolutionAnalyzer("ffmpeg", zerolog.Nop())
+	tests := []struct {
+		name           string
+		stream         StreamInfo
+		wantConsistent bool
+		wantAnamorphic bool
+		wantSquare     int
+		wantIssue      string
+	}{
+		{
+			name:           "square HD",
+			stream:         StreamInfo{Width: 1920, Height: 1080, SampleAspectRatio: "1:1", DisplayAspectRatio: "16:9"},
+			wantConsistent: true,
+		},
+		{
+			name:           "anamorphic PAL 16:9",
+			stream:         StreamInfo{Width: 720, Height: 576, SampleAspectRatio: "64:45", DisplayAspectRatio: "16:9"},
+			wantConsistent: true,
+			wantAnamorphic: true,
+			wantSquare:     1024,
+		},
+		{
+			name:           "NTSC 4:3",
+			stream:         StreamInfo{Width: 720, Height: 480, SampleAspectRatio: "8:9", DisplayAspectRatio: "4:3"},
+			wantConsistent: true,
+			wantAnamorphic: true,
+			wantSquare:     640,
+		},
+		{
+			name:           "mismatched display aspect ratio",
+			stream:         StreamInfo{Width: 1440, Height: 1080, SampleAspectRatio: "1:1", DisplayAspectRatio: "16:9"},
+			wantConsistent: false,
+			wantIssue:      "Display aspect ratio 16:9 does not match the 1.333 of 1440x1080 with sample aspect ratio 1:1",
+		},
+		{
+			name:           "SD without a sample aspect ratio",
+			stream:         StreamInfo{Width: 720, Height: 576, SampleAspectRatio: "0:1", DisplayAspectRatio: "0:1"},
+			wantConsistent: true,
+			wantIssue:      "720x576 SD video has square pixels, so it displays at 1.250 rather than 4:3 or 16:9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.stream.CodecType = "video"
+			analysis := ra.AnalyzeResolution([]StreamInfo{tt.stream})
+			resolution := analysis.VideoStreams[0]
+			if resolution.IsConsistent != tt.wantConsistent || resolution.IsAnamorphicSD != tt.wantAnamorphic || resolution.SquarePixelWidth != tt.wantSquare {
+				t.Errorf("got %+v", resolution)
+			}
+			issues := strings.Join(resolution.AspectRatioIssues, "\n")
+			if (tt.wantIssue == "") != (issues == "") || !strings.Contains(issues, tt.wantIssue) {
+				t.Errorf("aspect ratio issues %q, want %q", issues, tt.wantIssue)
+			}
+			recommendations := strings.Join(analysis.Validation.Recommendations, "\n")
+			if tt.wantAnamorphic != strings.Contains(recommendations, "is anamorphic SD") {
+				t.Errorf("unexpected recommendations %v", analysis.Validation.Recommendations)
+			}
+		})
+	}
+}
+
+func TestParseHeaderAspectRatio(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		codec   string
+		wantSAR [2]int
+		wantDAR [2]int
+		wantOK  bool
+	}{
+		{"H.264 table", traceOutput("aspect_ratio_info_present_flag", "1", "aspect_ratio_idc", "4"), "h264", [2]int{16, 11}, [2]int{}, true},
+		{"HEVC extended", traceOutput("aspect_ratio_idc", "255", "sar_width", "64", "sar_height", "45"), "hevc", [2]int{64, 45}, [2]int{}, true},
+		{"H.264 unspecified", traceOutput("aspect_ratio_info_present_flag", "0"), "h264", [2]int{}, [2]int{}, false},
+		{"MPEG-2 16:9", traceOutput("horizontal_size_value", "720", "aspect_ratio_information", "3"), "mpeg2video", [2]int{}, [2]int{16, 9}, true},
+		{"MPEG-2 square", traceOutput("aspect_ratio_information", "1"), "mpeg2video", [2]int{1, 1}, [2]int{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sar, dar, ok := parseHeaderAspectRatio([]byte(tt.output), tt.codec)
+			if sar != tt.wantSAR || dar != tt.wantDAR || ok != tt.wantOK {
+				t.Errorf("got %v %v %v, want %v %v %v", sar, dar, ok, tt.wantSAR, tt.wantDAR, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestAnalyzeBitstreamAspectRatio(t *testing.T) {
+	// The MP4 pasp box says 16:9, the H.264 VUI square pixels
+	dir := t.TempDir()
+	output := filepath.Join(dir, "trace")
+	if err := os.WriteFile(output, []byte(traceOutput("aspect_ratio_info_present_flag", "1", "aspect_ratio_idc", "1")), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat "+output+" >&2\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	streams := []StreamInfo{
+		{Index: 0, CodecType: "video", CodecName: "h264", Width: 720, Height: 576, SampleAspectRatio: "64:45", DisplayAspectRatio: "16:9"},
+		{Index: 1, CodecType: "video", CodecName: "prores", Width: 1920, Height: 1080, SampleAspectRatio: "1:1", DisplayAspectRatio: "16:9"},
+	}
+	ra := NewResolutionAnalyzer(script, zerolog.Nop())
+	analysis := ra.AnalyzeResolution(streams)
+	if !analysis.Validation.IsValid {
+		t.Fatalf("expected the stream metadata to be consistent, got %v", analysis.Validation.Issues)
+	}
+
+	ra.AnalyzeBitstreamAspectRatio(t.Context(), filepath.Join(dir, "in.mp4"), analysis, streams)
+	resolution := analysis.VideoStreams[0]
+	if resolution.BitstreamSampleAspectRatio != "1:1" || resolution.BitstreamDisplayAspectRatio != 1.25 || resolution.IsConsistent {
+		t.Errorf("expected the square pixel VUI to conflict with the container, got %+v", resolution)
+	}
+	if analysis.Validation.IsValid || !strings.Contains(strings.Join(analysis.Validation.Issues, "\n"),
+		"Video stream 0: The container's display aspect ratio 1.778 does not match the 1.250 the h264 headers signal") {
+		t.Errorf("expected the mismatch as an issue, got %v", analysis.Validation.Issues)
+	}
+	if analysis.VideoStreams[1].BitstreamDisplayAspectRatio != 0 {
+		t.Error("expected ProRes headers not to be read")
+	}
+}
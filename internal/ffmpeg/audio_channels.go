@@ -0,0 +1,116 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// audioLayoutChannels lists the channels of the layouts ffprobe reports, in
+// stream order
+var audioLayoutChannels = map[string][]string{
+	"mono":       {"FC"},
+	"stereo":     {"FL", "FR"},
+	"2.1":        {"FL", "FR", "LFE"},
+	"3.0":        {"FL", "FR", "FC"},
+	"3.1":        {"FL", "FR", "FC", "LFE"},
+	"quad":       {"FL", "FR", "BL", "BR"},
+	"quad(side)": {"FL", "FR", "SL", "SR"},
+	"4.0":        {"FL", "FR", "FC", "BC"},
+	"5.0":        {"FL", "FR", "FC", "BL", "BR"},
+	"5.0(side)":  {"FL", "FR", "FC", "SL", "SR"},
+	"5.1":        {"FL", "FR", "FC", "LFE", "BL", "BR"},
+	"5.1(side)":  {"FL", "FR", "FC", "LFE", "SL", "SR"},
+	"7.0":        {"FL", "FR", "FC", "BL", "BR", "SL", "SR"},
+	"7.1":        {"FL", "FR", "FC", "LFE", "BL", "BR", "SL", "SR"},
+	"7.1(wide)":  {"FL", "FR", "FC", "LFE", "BL", "BR", "FLC", "FRC"},
+}
+
+// audioChannelNames returns the channel names of a layout, or c0, c1 and
+// so on when the layout is unknown or does not match the channel count
+func audioChannelNames(layout string, channels int) []string {
+	if names, ok := audioLayoutChannels[layout]; ok && len(names) == channels {
+		return names
+	}
+	names := make([]string, channels)
+	for i := range names {
+		names[i] = fmt.Sprintf("c%d", i)
+	}
+	return names
+}
+
+// audioChannelPairs returns the left and right pairs of a layout: the
+// fronts, the surrounds (the sides, or the backs of a 5.1) and the rear
+// surrounds of a 7.1. Two channels of an unknown layout are taken as L/R.
+func audioChannelPairs(layout string, channels int) []ChannelPairPhase {
+	index := make(map[string]int)
+	for i, name := range audioChannelNames(layout, channels) {
+		index[name] = i
+	}
+
+	var pairs []ChannelPairPhase
+	add := func(pair, left, right string) bool {
+		l, okLeft := index[left]
+		r, okRight := index[right]
+		if okLeft && okRight {
+			pairs = append(pairs, ChannelPairPhase{Pair: pair, Left: l, Right: r})
+		}
+		return okLeft && okRight
+	}
+	if !add("L/R", "FL", "FR") && channels == 2 {
+		add("L/R", "c0", "c1")
+	}
+	if add("Ls/Rs", "SL", "SR") {
+		add("Lrs/Rrs", "BL", "BR")
+	} else {
+		add("Ls/Rs", "BL", "BR")
+	}
+	return pairs
+}
+
+// analyzeChannelSilence detects silence in each channel of one audio stream
+// with a single silencedetect pass
+func (ca *ContentAnalyzer) analyzeChannelSilence(ctx context.Context, filePath string, stream int, names []string) ([]ChannelSilence, error) {
+	args := audioInputArgs(ctx, filePath, stream)
+	args = append(args,
+		"-af", fmt.Sprintf("silencedetect=noise=%ddB:d=%f:mono=1", int(silenceNoiseThreshold), silenceMinDuration),
+		"-f", "null",
+		"-",
+	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
+
+	output, err := commandCombinedOutput(ctx, cmd)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		ca.logger.Debug().Err(err).Msg("Channel silence detection completed with warnings")
+	}
+
+	channels := make([]ChannelSilence, len(names))
+	for i, name := range names {
+		channels[i] = ChannelSilence{
+			Channel: i,
+			Name:    name,
+			Silence: summarizeSilence(parseSilenceDetect(output, i)),
+		}
+	}
+	return channels, nil
+}
+
+// analyzeChannelPhase measures the phase of each channel pair of one audio
+// stream, panning only the pair into aphasemeter
+func (ca *ContentAnalyzer) analyzeChannelPhase(ctx context.Context, filePath string, stream int, pairs []ChannelPairPhase) error {
+	for i := range pairs {
+		filter := fmt.Sprintf("pan=stereo|c0=c%d|c1=c%d,aphasemeter=video=0", pairs[i].Left, pairs[i].Right)
+		phase, err := ca.measurePhase(ctx, filePath, stream, filter)
+		if err != nil {
+			return fmt.Errorf("%s: %w", pairs[i].Pair, err)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		pairs[i].Phase = phase
+	}
+	return nil
+}
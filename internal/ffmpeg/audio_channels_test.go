@@ -0,0 +1,113 @@
+package ffmpeg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestAudioChannelPairs(t *testing.T) {
+	tests := []struct {
+		layout   string
+		channels int
+		want     []ChannelPairPhase
+	}{
+		{"mono", 1, nil},
+		{"stereo", 2, []ChannelPairPhase{{Pair: "L/R", Left: 0, Right: 1}}},
+		{"", 2, []ChannelPairPhase{{Pair: "L/R", Left: 0, Right: 1}}},
+		{"5.1", 6, []ChannelPairPhase{{Pair: "L/R", Left: 0, Right: 1}, {Pair: "Ls/Rs", Left: 4, Right: 5}}},
+		{"5.1(side)", 6, []ChannelPairPhase{{Pair: "L/R", Left: 0, Right: 1}, {Pair: "Ls/Rs", Left: 4, Right: 5}}},
+		{"7.1", 8, []ChannelPairPhase{{Pair: "L/R", Left: 0, Right: 1}, {Pair: "Ls/Rs", Left: 6, Right: 7}, {Pair: "Lrs/Rrs", Left: 4, Right: 5}}},
+		// A layout that does not match the channel count is unknown
+		{"5.1", 8, nil},
+	}
+	for _, tt := range tests {
+		if got := audioChannelPairs(tt.layout, tt.channels); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("audioChannelPairs(%q, %d) = %+v, want %+v", tt.layout, tt.channels, got, tt.want)
+		}
+	}
+}
+
+func TestParseSilenceDetect_Channels(t *testing.T) {
+	output := []byte(`  Duration: 00:00:20.00, start: 0.000000, bitrate: 4608 kb/s
+[silencedetect @ 0x55a4] channel: 4 | silence_start: 5
+[silencedetect @ 0x55a4] channel: 3 | silence_start: 0
+[silencedetect @ 0x55a4] channel: 4 | silence_end: 10.5 | silence_duration: 5.5
+[silencedetect @ 0x55a4] channel: 3 | silence_end: 20 | silence_duration: 20
+`)
+
+	periods, duration := parseSilenceDetect(output, 4)
+	if duration != 20 || len(periods) != 1 {
+		t.Fatalf("channel 4: duration = %v, periods = %+v", duration, periods)
+	}
+	surround := summarizeSilence(periods, duration)
+	if !surround.HasProblematicMute || surround.LongestSilenceSec != 5.5 || periods[0].StartTime != 5 {
+		t.Errorf("channel 4 = %+v", surround)
+	}
+
+	lfe := summarizeSilence(parseSilenceDetect(output, 3))
+	if lfe.SilencePercentage != 100 || lfe.HasProblematicMute {
+		t.Errorf("channel 3 = %+v, want silent throughout", lfe)
+	}
+	if periods, _ := parseSilenceDetect(output, 0); len(periods) != 0 {
+		t.Errorf("channel 0 periods = %+v, want none", periods)
+	}
+}
+
+func TestAnalyzeAudioStreams_Channels(t *testing.T) {
+	// The fake ffmpeg finds the LFE silent and the surrounds out of phase
+	script := filepath.Join(t.TempDir(), "ffmpeg")
+	body := `#!/bin/sh
+case "$*" in
+*mono=1*)
+	echo "  Duration: 00:00:10.00, start: 0.000000, bitrate: 4608 kb/s"
+	echo "[silencedetect @ 0x1] channel: 3 | silence_start: 0"
+	echo "[silencedetect @ 0x1] channel: 3 | silence_end: 10 | silence_duration: 10" ;;
+*c0=c4*)
+	echo "[Parsed_aphasemeter_1 @ 0x1] phase: -0.9" ;;
+*aphasemeter*)
+	echo "[Parsed_aphasemeter_0 @ 0x1] phase: 0.8" ;;
+esac
+`
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	ca := NewContentAnalyzer(script, zerolog.Nop())
+	selector, err := ParseStreamSelector("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	streams := []StreamInfo{
+		{Index: 1, CodecType: "audio", Channels: 6, ChannelLayout: "5.1"},
+		{Index: 2, CodecType: "audio", Channels: 2, ChannelLayout: "stereo"},
+	}
+	results := ca.AnalyzeAudioStreams(context.Background(), "input.mxf", streams, selector)
+
+	surround := results[1]
+	if surround == nil || len(surround.ChannelSilence) != 6 || len(surround.ChannelPairs) != 2 {
+		t.Fatalf("5.1 stream = %+v", surround)
+	}
+	if lfe := surround.ChannelSilence[3]; lfe.Name != "LFE" || lfe.Silence.SilencePercentage != 100 {
+		t.Errorf("LFE silence = %+v", lfe)
+	}
+	if front := surround.ChannelSilence[0]; front.Name != "FL" || front.Silence.TotalSilenceCount != 0 {
+		t.Errorf("FL silence = %+v", front)
+	}
+	if pair := surround.ChannelPairs[0]; pair.Pair != "L/R" || pair.Phase.HasPhaseIssues {
+		t.Errorf("L/R = %+v", pair.Phase)
+	}
+	if pair := surround.ChannelPairs[1]; pair.Pair != "Ls/Rs" || !pair.Phase.HasPhaseIssues || pair.Phase.MinPhase != -0.9 {
+		t.Errorf("Ls/Rs = %+v", pair.Phase)
+	}
+
+	// A stereo stream's pair is the stream itself
+	stereo := results[2]
+	if stereo == nil || len(stereo.ChannelSilence) != 2 || stereo.ChannelPairs != nil || stereo.Phase == nil {
+		t.Errorf("stereo stream = %+v", stereo)
+	}
+}
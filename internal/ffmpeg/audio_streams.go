@@ -0,0 +1,132 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// maxConcurrentAudioStreams bounds how many selected streams are measured at
+// once; each runs up to five ffmpeg processes
+const maxConcurrentAudioStreams = 2
+
+// AnalyzeAudioStreams measures loudness, silence and phase separately for
+// each audio stream the selector picks, keyed by stream index. Streams of
+// more than one channel also get silence per channel, and those of more
+// than two phase per channel pair. The measurements are then checked
+// against the declared channel layout and language. Selected streams that
+// are not audio are skipped.
+func (ca *ContentAnalyzer) AnalyzeAudioStreams(ctx context.Context, filePath string, streams []StreamInfo, selector *StreamSelector) map[int]*AudioStreamAnalysis {
+	ctx, span := tracer.Start(ctx, "ContentAnalyzer.AnalyzeAudioStreams")
+	defer span.End()
+
+	results := make(map[int]*AudioStreamAnalysis)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxConcurrentAudioStreams)
+
+	for _, stream := range selector.Select(streams) {
+		if stream.CodecType != "audio" {
+			continue
+		}
+		analysis := &AudioStreamAnalysis{
+			StreamIndex:   stream.Index,
+			CodecName:     stream.CodecName,
+			Channels:      stream.Channels,
+			ChannelLayout: stream.ChannelLayout,
+			Language:      stream.Tags["language"],
+			Title:         stream.Tags["title"],
+		}
+		results[stream.Index] = analysis
+
+		wg.Add(1)
+		go func(index int, layout string, channels int) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				mu.Lock()
+				analysis.Errors = append(analysis.Errors, ctx.Err().Error())
+				mu.Unlock()
+				return
+			}
+
+			var measurements sync.WaitGroup
+			measure := func(name string, run func() error) {
+				defer measurements.Done()
+				if err := run(); err != nil {
+					mu.Lock()
+					analysis.Errors = append(analysis.Errors, fmt.Sprintf("%s: %v", name, err))
+					mu.Unlock()
+				}
+			}
+			measurements.Add(3)
+			go measure("loudness", func() (err error) {
+				analysis.Loudness, err = ca.analyzeStreamLoudness(ctx, filePath, index)
+				return err
+			})
+			go measure("silence", func() (err error) {
+				analysis.Silence, err = ca.analyzeStreamSilence(ctx, filePath, index)
+				return err
+			})
+			go measure("phase", func() (err error) {
+				analysis.Phase, err = ca.analyzeStreamPhase(ctx, filePath, index)
+				return err
+			})
+			if channels > 1 {
+				measurements.Add(1)
+				go measure("channel silence", func() (err error) {
+					silence, err := ca.analyzeChannelSilence(ctx, filePath, index, audioChannelNames(layout, channels))
+					analysis.ChannelSilence = silence
+					return err
+				})
+			}
+			if pairs := audioChannelPairs(layout, channels); channels > 2 && len(pairs) > 0 {
+				measurements.Add(1)
+				go measure("channel phase", func() error {
+					err := ca.analyzeChannelPhase(ctx, filePath, index, pairs)
+					if err == nil {
+						analysis.ChannelPairs = pairs
+					}
+					return err
+				})
+			}
+			measurements.Wait()
+			analysis.MetadataChecks = verifyAudioMetadata(analysis)
+		}(stream.Index, stream.ChannelLayout, stream.Channels)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// AnalyzeAudioStreamSelection adds per-stream audio measurements for the
+// streams spec selects to the result's content analysis
+func (ea *EnhancedAnalyzer) AnalyzeAudioStreamSelection(ctx context.Context, result *FFprobeResult, filePath, spec string) error {
+	selector, err := ParseStreamSelector(spec)
+	if err != nil {
+		return err
+	}
+	if selector == nil || filePath == "" {
+		return nil
+	}
+
+	contentAnalyzer := ea.contentAnalyzer
+	if contentAnalyzer == nil {
+		contentAnalyzer = NewContentAnalyzer(ea.ffmpegPath, ea.logger)
+	}
+	streams := contentAnalyzer.AnalyzeAudioStreams(ctx, filePath, result.Streams, selector)
+	if len(streams) == 0 {
+		return fmt.Errorf("stream selector %q matches no audio stream", spec)
+	}
+
+	if result.EnhancedAnalysis == nil {
+		result.EnhancedAnalysis = &EnhancedAnalysis{}
+	}
+	if result.EnhancedAnalysis.ContentAnalysis == nil {
+		result.EnhancedAnalysis.ContentAnalysis = &ContentAnalysis{}
+	}
+	result.EnhancedAnalysis.ContentAnalysis.AudioStreams = streams
+	return nil
+}
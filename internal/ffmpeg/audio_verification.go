@@ -0,0 +1,152 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// silentChannelPercent of silence marks a channel as carrying nothing
+	silentChannelPercent = 99.0
+	// dualMonoPhase is the average L/R phase of identical channels; silent
+	// frames pull it down, so real stereo never reaches it
+	dualMonoPhase = 0.98
+	// A stream with dialogSpeechPercent of speech carries dialog, one below
+	// noDialogSpeechPercent carries none
+	dialogSpeechPercent   = 10.0
+	noDialogSpeechPercent = 1.0
+)
+
+// AudioMetadataCheck compares what a stream declares, its channel layout or
+// language tag, with what was measured in it
+type AudioMetadataCheck struct {
+	Check    string `json:"check"`    // "channel_layout", "dual_mono" or "language"
+	Passed   bool   `json:"passed"`   // The measurement matches the declaration
+	Declared string `json:"declared"` // e.g. "5.1" or "eng"
+	Measured string `json:"measured"` // e.g. "4 of 6 channels active"
+	Message  string `json:"message,omitempty"`
+}
+
+// verifyAudioMetadata checks the declared layout and language of a measured
+// stream. Checks whose measurement is missing are left out.
+func verifyAudioMetadata(a *AudioStreamAnalysis) []AudioMetadataCheck {
+	layout := a.ChannelLayout
+	if layout == "" {
+		layout = fmt.Sprintf("%d channels", a.Channels)
+	}
+
+	var checks []AudioMetadataCheck
+	if check, ok := verifyActiveChannels(a.ChannelSilence, layout); ok {
+		checks = append(checks, check)
+	}
+	if check, ok := verifyStereoImage(a, layout); ok {
+		checks = append(checks, check)
+	}
+	if check, ok := verifyLanguage(a); ok {
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// verifyActiveChannels fails a layout whose channels, other than the LFE,
+// are silent throughout, such as a 5.1 mix with empty surrounds. Streams
+// that are silent altogether are not checked.
+func verifyActiveChannels(channels []ChannelSilence, layout string) (AudioMetadataCheck, bool) {
+	var silent []string
+	active := 0
+	for _, channel := range channels {
+		if channel.Silence == nil {
+			return AudioMetadataCheck{}, false
+		}
+		if channel.Silence.SilencePercentage < silentChannelPercent {
+			active++
+		} else if channel.Name != "LFE" {
+			silent = append(silent, channel.Name)
+		}
+	}
+	if active == 0 {
+		return AudioMetadataCheck{}, false
+	}
+
+	check := AudioMetadataCheck{
+		Check:    "channel_layout",
+		Passed:   len(silent) == 0,
+		Declared: layout,
+		Measured: fmt.Sprintf("%d of %d channels active", active, len(channels)),
+	}
+	if !check.Passed {
+		check.Message = fmt.Sprintf("%s stream has %s silent throughout", layout, strings.Join(silent, ", "))
+	}
+	return check, true
+}
+
+// verifyStereoImage fails a stream whose left and right channels carry the
+// same audio, i.e. mono declared as stereo or as the fronts of a surround mix
+func verifyStereoImage(a *AudioStreamAnalysis, layout string) (AudioMetadataCheck, bool) {
+	phase := a.Phase
+	if a.Channels > 2 {
+		phase = nil
+		for _, pair := range a.ChannelPairs {
+			if pair.Pair == "L/R" {
+				phase = pair.Phase
+			}
+		}
+	}
+	if a.Channels < 2 || phase == nil || phase.TotalFrames == 0 {
+		return AudioMetadataCheck{}, false
+	}
+
+	check := AudioMetadataCheck{
+		Check:    "dual_mono",
+		Passed:   phase.AveragePhase < dualMonoPhase,
+		Declared: layout,
+		Measured: fmt.Sprintf("L/R phase %.2f", phase.AveragePhase),
+	}
+	if !check.Passed {
+		check.Message = fmt.Sprintf("%s stream has identical left and right channels", layout)
+	}
+	return check, true
+}
+
+// verifyLanguage compares the language tag with the dialog found in the
+// stream: dialog needs a language, "zxx" (no linguistic content) rules it
+// out, and a language needs dialog
+func verifyLanguage(a *AudioStreamAnalysis) (AudioMetadataCheck, bool) {
+	if a.Loudness == nil || a.Loudness.Dialog == nil {
+		return AudioMetadataCheck{}, false
+	}
+	if a.Silence != nil && a.Silence.SilencePercentage >= silentChannelPercent {
+		return AudioMetadataCheck{}, false
+	}
+
+	speech := a.Loudness.Dialog.SpeechPercentage
+	declared := strings.ToLower(a.Language)
+	check := AudioMetadataCheck{
+		Check:    "language",
+		Passed:   true,
+		Declared: a.Language,
+		Measured: fmt.Sprintf("%.1f%% speech", speech),
+	}
+	switch declared {
+	case "", "und":
+		if speech >= dialogSpeechPercent {
+			check.Passed = false
+			check.Message = "Stream carries dialog but declares no language"
+		}
+	case "zxx":
+		if speech >= dialogSpeechPercent {
+			check.Passed = false
+			check.Message = "Stream declares no linguistic content but carries dialog"
+		}
+	case "mul", "mis":
+	default:
+		if speech < noDialogSpeechPercent {
+			check.Passed = false
+			check.Message = fmt.Sprintf("Stream declares %s but carries no detectable dialog", a.Language)
+		}
+	}
+	if check.Declared == "" {
+		check.Declared = "none"
+	}
+	return check, true
+}
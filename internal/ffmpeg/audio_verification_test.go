@@ -0,0 +1,128 @@
+package ffmpeg
+
+import (
+	"testing"
+)
+
+// channelSilence returns the channels of a layout with the given silence
+// percentages
+func channelSilence(layout string, percentages ...float64) []ChannelSilence {
+	names := audioChannelNames(layout, len(percentages))
+	channels := make([]ChannelSilence, len(percentages))
+	for i, percentage := range percentages {
+		channels[i] = ChannelSilence{Channel: i, Name: names[i], Silence: &SilenceAnalysis{SilencePercentage: percentage}}
+	}
+	return channels
+}
+
+func TestVerifyAudioMetadata_Layout(t *testing.T) {
+	tests := []struct {
+		name        string
+		stream      *AudioStreamAnalysis
+		want        map[string]bool
+		wantMessage string
+	}{
+		{
+			name: "5.1 with silent surrounds",
+			stream: &AudioStreamAnalysis{
+				Channels: 6, ChannelLayout: "5.1",
+				ChannelSilence: channelSilence("5.1", 0, 0, 2, 100, 100, 100),
+				ChannelPairs: []ChannelPairPhase{
+					{Pair: "L/R", Phase: &PhaseAnalysis{AveragePhase: 0.6, TotalFrames: 500}},
+				},
+			},
+			want:        map[string]bool{"channel_layout": false, "dual_mono": true},
+			wantMessage: "5.1 stream has BL, BR silent throughout",
+		},
+		{
+			name: "5.1 with a silent LFE",
+			stream: &AudioStreamAnalysis{
+				Channels: 6, ChannelLayout: "5.1",
+				ChannelSilence: channelSilence("5.1", 0, 0, 2, 100, 10, 10),
+			},
+			want: map[string]bool{"channel_layout": true},
+		},
+		{
+			name: "dual mono stereo",
+			stream: &AudioStreamAnalysis{
+				Channels: 2, ChannelLayout: "stereo",
+				ChannelSilence: channelSilence("stereo", 0, 0),
+				Phase:          &PhaseAnalysis{AveragePhase: 0.995, TotalFrames: 500},
+			},
+			want:        map[string]bool{"channel_layout": true, "dual_mono": false},
+			wantMessage: "stereo stream has identical left and right channels",
+		},
+		{
+			name: "silent stream",
+			stream: &AudioStreamAnalysis{
+				Channels: 2, ChannelLayout: "stereo",
+				ChannelSilence: channelSilence("stereo", 100, 100),
+				Phase:          &PhaseAnalysis{},
+			},
+			want: map[string]bool{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checks := verifyAudioMetadata(tt.stream)
+			got := make(map[string]bool)
+			message := ""
+			for _, check := range checks {
+				got[check.Check] = check.Passed
+				if !check.Passed {
+					message = check.Message
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("checks = %+v, want %v", checks, tt.want)
+			}
+			for check, passed := range tt.want {
+				if p, ok := got[check]; !ok || p != passed {
+					t.Errorf("%s passed = %v, want %v", check, p, passed)
+				}
+			}
+			if message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", message, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestVerifyAudioMetadata_Language(t *testing.T) {
+	tests := []struct {
+		language string
+		speech   float64
+		passed   bool
+	}{
+		{"eng", 35, true},
+		{"eng", 0.2, false},
+		{"", 35, false},
+		{"und", 5, true},
+		{"zxx", 0, true},
+		{"zxx", 40, false},
+		{"mul", 0, true},
+	}
+	for _, tt := range tests {
+		stream := &AudioStreamAnalysis{
+			Channels: 1,
+			Language: tt.language,
+			Loudness: &LoudnessAnalysis{Dialog: &DialogLoudness{SpeechPercentage: tt.speech}},
+			Silence:  &SilenceAnalysis{SilencePercentage: 3},
+		}
+		checks := verifyAudioMetadata(stream)
+		if len(checks) != 1 || checks[0].Check != "language" || checks[0].Passed != tt.passed {
+			t.Errorf("language %q with %v%% speech: checks = %+v, want passed %v", tt.language, tt.speech, checks, tt.passed)
+		}
+	}
+
+	// Silence says nothing about the language
+	silent := &AudioStreamAnalysis{
+		Language: "eng",
+		Loudness: &LoudnessAnalysis{Dialog: &DialogLoudness{}},
+		Silence:  &SilenceAnalysis{SilencePercentage: 100},
+	}
+	if checks := verifyAudioMetadata(silent); len(checks) != 0 {
+		t.Errorf("silent stream checks = %+v", checks)
+	}
+}
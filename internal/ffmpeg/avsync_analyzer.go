@@ -0,0 +1,472 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// avSyncToleranceMs is the lip-sync offset, in either direction, beyond
+	// which a file is flagged. EBU R37 allows audio 40 ms early or 60 ms
+	// late; the tighter bound is applied both ways.
+	avSyncToleranceMs = 40.0
+
+	// avSyncBin is the resolution of the onset envelopes, in seconds
+	avSyncBin = 0.01
+
+	// avSyncWindow is the length of each correlation window, in seconds
+	avSyncWindow = 30.0
+
+	// avSyncMaxLag bounds the offset searched for, in bins (±500 ms)
+	avSyncMaxLag = 50
+
+	// avSyncMaxDuration bounds how much of the file is decoded, in seconds
+	avSyncMaxDuration = 600
+
+	// Windows whose correlation peak is weaker than avSyncMinCorrelation,
+	// or stands out from the next best lag by less than
+	// avSyncMinConfidence, carry no usable sync information
+	avSyncMinCorrelation = 0.1
+	avSyncMinConfidence  = 0.25
+)
+
+// AVSyncAnalyzer estimates the lip-sync offset between the first video and
+// audio streams of a file
+type AVSyncAnalyzer struct {
+	ffmpegPath string
+	logger     zerolog.Logger
+}
+
+// NewAVSyncAnalyzer creates a new audio/video sync analyzer
+func NewAVSyncAnalyzer(ffmpegPath string, logger zerolog.Logger) *AVSyncAnalyzer {
+	return &AVSyncAnalyzer{
+		ffmpegPath: ffmpegPath,
+		logger:     logger,
+	}
+}
+
+// AVSyncAnalysis estimates how far the audio is ahead of or behind the
+// video. Offsets are positive when the audio lags the video.
+type AVSyncAnalysis struct {
+	VideoStreamIndex int                  `json:"video_stream_index"`
+	AudioStreamIndex int                  `json:"audio_stream_index"`
+	ToleranceMs      float64              `json:"tolerance_ms"`
+	Method           string               `json:"method"` // "content" or "timestamps", whichever the estimate comes from
+	OffsetMs         float64              `json:"offset_ms"`
+	DriftMsPerMinute float64              `json:"drift_ms_per_minute"`
+	MaxOffsetMs      float64              `json:"max_offset_ms"` // Largest offset over the file, drift included
+	InSync           bool                 `json:"in_sync"`
+	Timestamps       *AVSyncTimestamps    `json:"timestamps,omitempty"`
+	Content          *AVSyncContentResult `json:"content,omitempty"`
+	Issues           []string             `json:"issues,omitempty"`
+}
+
+// AVSyncTimestamps compares the start and end timestamps of the two streams
+type AVSyncTimestamps struct {
+	StartOffsetMs    float64 `json:"start_offset_ms"` // Audio start minus video start
+	EndOffsetMs      float64 `json:"end_offset_ms"`   // Audio end minus video end
+	DriftMsPerMinute float64 `json:"drift_ms_per_minute"`
+}
+
+// AVSyncContentResult is the offset found by correlating audio onsets with
+// changes in picture activity. Speech and cuts line up with their sound
+// often enough for the correlation to peak at the offset, but files with
+// little of either give no usable windows.
+type AVSyncContentResult struct {
+	AnalyzedSeconds  float64        `json:"analyzed_seconds"`
+	OffsetMs         float64        `json:"offset_ms"`
+	DriftMsPerMinute float64        `json:"drift_ms_per_minute"`
+	Confidence       float64        `json:"confidence"` // Mean confidence of the usable windows, 0-1
+	WindowsUsed      int            `json:"windows_used"`
+	Windows          []AVSyncWindow `json:"windows,omitempty"`
+}
+
+// AVSyncWindow is the correlation result of one window of the file
+type AVSyncWindow struct {
+	Start       float64 `json:"start"`
+	End         float64 `json:"end"`
+	OffsetMs    float64 `json:"offset_ms"`
+	Correlation float64 `json:"correlation"` // Normalized correlation at the peak
+	Confidence  float64 `json:"confidence"`  // How far the peak stands out from the next best lag, 0-1
+	Used        bool    `json:"used"`
+}
+
+// AnalyzeAVSync estimates the sync of the first video and audio streams
+// from their timestamps and, by decoding up to ten minutes of both, from
+// their content. It returns nil when the file lacks either stream.
+func (aa *AVSyncAnalyzer) AnalyzeAVSync(ctx context.Context, filePath string, streams []StreamInfo) (*AVSyncAnalysis, error) {
+	video, audio := avSyncStreams(streams)
+	if video == nil || audio == nil {
+		return nil, nil
+	}
+
+	analysis := &AVSyncAnalysis{
+		VideoStreamIndex: video.Index,
+		AudioStreamIndex: audio.Index,
+		ToleranceMs:      avSyncToleranceMs,
+		Timestamps:       compareStreamTimestamps(video, audio),
+	}
+
+	output, err := aa.readActivity(ctx, filePath, video.Index, audio.Index)
+	if err != nil {
+		if analysis.Timestamps == nil {
+			return nil, err
+		}
+		aa.logger.Debug().Err(err).Msg("Failed to measure audio and video activity")
+		analysis.Issues = append(analysis.Issues, fmt.Sprintf("Content could not be analyzed: %v", err))
+	} else {
+		videoActivity, audioLevels := parseAVSyncActivity(output)
+		analysis.Content = correlateAVSync(videoActivity, audioLevels)
+	}
+
+	analysis.evaluate()
+	return analysis, nil
+}
+
+// avSyncStreams picks the first video stream that is not cover art and the
+// first audio stream
+func avSyncStreams(streams []StreamInfo) (video, audio *StreamInfo) {
+	for i := range streams {
+		stream := &streams[i]
+		switch strings.ToLower(stream.CodecType) {
+		case "video":
+			if video == nil && stream.Disposition["attached_pic"] == 0 {
+				video = stream
+			}
+		case "audio":
+			if audio == nil {
+				audio = stream
+			}
+		}
+	}
+	return video, audio
+}
+
+// compareStreamTimestamps compares the start and end of the streams. It
+// returns nil when ffprobe reported no start times.
+func compareStreamTimestamps(video, audio *StreamInfo) *AVSyncTimestamps {
+	videoStart, err1 := strconv.ParseFloat(video.StartTime, 64)
+	audioStart, err2 := strconv.ParseFloat(audio.StartTime, 64)
+	if err1 != nil || err2 != nil {
+		return nil
+	}
+
+	timestamps := &AVSyncTimestamps{StartOffsetMs: roundTo((audioStart-videoStart)*1000, 1)}
+	videoDuration, err1 := strconv.ParseFloat(video.Duration, 64)
+	audioDuration, err2 := strconv.ParseFloat(audio.Duration, 64)
+	if err1 == nil && err2 == nil && videoDuration > 0 {
+		timestamps.EndOffsetMs = roundTo((audioStart+audioDuration-videoStart-videoDuration)*1000, 1)
+		timestamps.DriftMsPerMinute = roundTo((timestamps.EndOffsetMs-timestamps.StartOffsetMs)/(videoDuration/60), 2)
+	}
+	return timestamps
+}
+
+// readActivity logs the luma difference of each video frame and the RMS
+// level of each 10 ms of audio
+func (aa *AVSyncAnalyzer) readActivity(ctx context.Context, filePath string, videoIndex, audioIndex int) ([]byte, error) {
+	filter := fmt.Sprintf(
+		"[0:%d]scale=160:90,format=gray,signalstats,metadata=mode=print:key=lavfi.signalstats.YDIF[v];"+
+			"[0:%d]aresample=48000,asetnsamples=n=480:p=0,astats=metadata=1:reset=1,ametadata=mode=print:key=lavfi.astats.Overall.RMS_level[a]",
+		videoIndex, audioIndex)
+
+	cmd := exec.CommandContext(ctx, aa.ffmpegPath,
+		"-hide_banner",
+		"-t", strconv.Itoa(avSyncMaxDuration),
+		"-i", filePath,
+		"-filter_complex", filter,
+		"-map", "[v]",
+		"-map", "[a]",
+		"-f", "null",
+		"-",
+	)
+
+	output, err := commandCombinedOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure audio and video activity: %w", err)
+	}
+	return output, nil
+}
+
+// avSyncSample is a measurement at a presentation time
+type avSyncSample struct {
+	time  float64
+	value float64
+}
+
+// parseAVSyncActivity reads the frames logged by metadata and ametadata, e.g.
+//
+//	[Parsed_metadata_3 @ 0x55a4] frame:12   pts:12      pts_time:0.48
+//	[Parsed_metadata_3 @ 0x55a4] lavfi.signalstats.YDIF=3.215
+//	[Parsed_ametadata_9 @ 0x55a4] frame:48  pts:23040   pts_time:0.48
+//	[Parsed_ametadata_9 @ 0x55a4] lavfi.astats.Overall.RMS_level=-31.2
+//
+// Silent audio is logged as -inf and kept at -120 dB.
+func parseAVSyncActivity(output []byte) (video, audio []avSyncSample) {
+	videoTime, audioTime := -1.0, -1.0
+
+	forEachLine(output, func(line string) bool {
+		isAudio := strings.Contains(line, "Parsed_ametadata")
+		if !isAudio && !strings.Contains(line, "Parsed_metadata") {
+			return true
+		}
+		if index := strings.Index(line, "pts_time:"); index >= 0 {
+			value, ok := parseFiniteValue(line[index+len("pts_time:"):])
+			if !ok {
+				value = -1
+			}
+			if isAudio {
+				audioTime = value
+			} else {
+				videoTime = value
+			}
+			return true
+		}
+
+		if index := strings.Index(line, "lavfi.signalstats.YDIF="); index >= 0 && !isAudio && videoTime >= 0 {
+			if value, ok := parseFiniteValue(line[index+len("lavfi.signalstats.YDIF="):]); ok {
+				video = append(video, avSyncSample{videoTime, value})
+			}
+		} else if index := strings.Index(line, "lavfi.astats.Overall.RMS_level="); index >= 0 && isAudio && audioTime >= 0 {
+			value, ok := parseFiniteValue(line[index+len("lavfi.astats.Overall.RMS_level="):])
+			if !ok || value < -120 {
+				value = -120
+			}
+			audio = append(audio, avSyncSample{audioTime, value})
+		}
+		return true
+	})
+	return video, audio
+}
+
+// correlateAVSync cross-correlates the onsets of audio energy with the
+// onsets of picture activity in 30 second windows. It returns nil when
+// either stream logged nothing.
+func correlateAVSync(video, audio []avSyncSample) *AVSyncContentResult {
+	if len(video) < 2 || len(audio) < 2 {
+		return nil
+	}
+	end := math.Min(video[len(video)-1].time, audio[len(audio)-1].time)
+	bins := int(end/avSyncBin) + 1
+	videoOnsets := onsetEnvelope(video, bins)
+	audioOnsets := onsetEnvelope(audio, bins)
+
+	result := &AVSyncContentResult{AnalyzedSeconds: roundTo(end, 3)}
+	windowBins := int(avSyncWindow / avSyncBin)
+	var centers, offsets []float64
+	var confidence float64
+
+	for start := 0; start < bins; start += windowBins {
+		stop := start + windowBins
+		if stop > bins {
+			// Fold a short tail into a window of its own only when it is
+			// long enough to hold the lag range several times over
+			if bins-start < 10*avSyncMaxLag {
+				break
+			}
+			stop = bins
+		}
+
+		offset, correlation, windowConfidence, ok := correlateWindow(videoOnsets, audioOnsets, start, stop)
+		if !ok {
+			continue
+		}
+		window := AVSyncWindow{
+			Start:       roundTo(float64(start)*avSyncBin, 3),
+			End:         roundTo(float64(stop)*avSyncBin, 3),
+			OffsetMs:    roundTo(offset*avSyncBin*1000, 1),
+			Correlation: roundTo(correlation, 3),
+			Confidence:  roundTo(windowConfidence, 3),
+			Used:        correlation >= avSyncMinCorrelation && windowConfidence >= avSyncMinConfidence,
+		}
+		result.Windows = append(result.Windows, window)
+		if window.Used {
+			centers = append(centers, (window.Start+window.End)/2)
+			offsets = append(offsets, offset*avSyncBin*1000)
+			confidence += windowConfidence
+		}
+	}
+
+	result.WindowsUsed = len(offsets)
+	if len(offsets) == 0 {
+		return result
+	}
+	result.OffsetMs = roundTo(median(offsets), 1)
+	result.Confidence = roundTo(confidence/float64(len(offsets)), 3)
+	// Drift needs at least three windows to tell a trend from noise
+	if len(offsets) >= 3 {
+		slope, _ := linearFit(centers, offsets)
+		result.DriftMsPerMinute = roundTo(slope*60, 2)
+	}
+	return result
+}
+
+// onsetEnvelope resamples a series onto bins of avSyncBin, keeping the rise
+// of each sample over the one before it, so that only the start of a sound
+// or a movement counts. A frame only places a change within half a frame
+// either side of it, so each rise is spread over that span.
+func onsetEnvelope(samples []avSyncSample, bins int) []float64 {
+	envelope := make([]float64, bins)
+	for i := 1; i < len(samples); i++ {
+		rise := samples[i].value - samples[i-1].value
+		if rise <= 0 {
+			continue
+		}
+		center := int(math.Round(samples[i].time / avSyncBin))
+		half := int((samples[i].time - samples[i-1].time) / 2 / avSyncBin)
+		for bin := max(center-half, 0); bin <= center+half && bin < bins; bin++ {
+			envelope[bin] = math.Max(envelope[bin], rise)
+		}
+	}
+	return envelope
+}
+
+// correlateWindow finds the lag, in bins, at which the audio onsets of
+// [start, stop) best match the video onsets. A positive lag means the audio
+// follows the video. The lag is refined between bins by fitting a parabola
+// through the peak and its neighbours.
+func correlateWindow(video, audio []float64, start, stop int) (lag, peak, confidence float64, ok bool) {
+	videoMean, videoDev := meanDeviation(video[start:stop])
+	audioMean, audioDev := meanDeviation(audio[start:stop])
+	if videoDev == 0 || audioDev == 0 {
+		return 0, 0, 0, false
+	}
+
+	correlations := make([]float64, 2*avSyncMaxLag+1)
+	best := 0
+	for l := -avSyncMaxLag; l <= avSyncMaxLag; l++ {
+		var sum float64
+		n := 0
+		for i := start; i < stop; i++ {
+			j := i + l
+			if j < 0 || j >= len(audio) {
+				continue
+			}
+			sum += (video[i] - videoMean) * (audio[j] - audioMean)
+			n++
+		}
+		if n > 0 {
+			correlations[l+avSyncMaxLag] = sum / (float64(n) * videoDev * audioDev)
+		}
+		if correlations[l+avSyncMaxLag] > correlations[best] {
+			best = l + avSyncMaxLag
+		}
+	}
+	peak = correlations[best]
+	if peak <= 0 {
+		return 0, peak, 0, false
+	}
+
+	lag = float64(best - avSyncMaxLag)
+	if best > 0 && best < len(correlations)-1 {
+		before, after := correlations[best-1], correlations[best+1]
+		if denominator := before - 2*peak + after; denominator < 0 {
+			lag += 0.5 * (before - after) / denominator
+		}
+	}
+
+	// Compare with the best lag away from the peak, 50 ms on either side
+	var runnerUp float64
+	for i, correlation := range correlations {
+		if (i < best-5 || i > best+5) && correlation > runnerUp {
+			runnerUp = correlation
+		}
+	}
+	return lag, peak, 1 - runnerUp/peak, true
+}
+
+// evaluate picks the estimate to report and flags offsets beyond the
+// tolerance. Content is preferred: timestamps only show how the streams were
+// muxed, not whether the sound matches the picture.
+func (a *AVSyncAnalysis) evaluate() {
+	a.InSync = true
+	var first, last float64
+
+	switch {
+	case a.Content != nil && a.Content.WindowsUsed > 0:
+		a.Method = "content"
+		a.OffsetMs = a.Content.OffsetMs
+		a.DriftMsPerMinute = a.Content.DriftMsPerMinute
+		// The median offset sits mid-file, so half the drift lies either side
+		half := a.DriftMsPerMinute * a.Content.AnalyzedSeconds / 120
+		first, last = roundTo(a.OffsetMs-half, 1), roundTo(a.OffsetMs+half, 1)
+	case a.Timestamps != nil:
+		a.Method = "timestamps"
+		a.OffsetMs = a.Timestamps.StartOffsetMs
+		a.DriftMsPerMinute = a.Timestamps.DriftMsPerMinute
+		first, last = a.Timestamps.StartOffsetMs, a.Timestamps.EndOffsetMs
+		if a.Content != nil {
+			a.Issues = append(a.Issues, "Content gave no usable sync estimate; falling back to stream timestamps")
+		}
+	default:
+		a.Issues = append(a.Issues, "Neither content nor timestamps gave a sync estimate")
+		return
+	}
+
+	a.MaxOffsetMs = a.OffsetMs
+	for _, offset := range []float64{first, last} {
+		if math.Abs(offset) > math.Abs(a.MaxOffsetMs) {
+			a.MaxOffsetMs = offset
+		}
+	}
+
+	describe := func(offset float64) string {
+		if offset > 0 {
+			return fmt.Sprintf("lags the video by %.0f ms", offset)
+		}
+		return fmt.Sprintf("leads the video by %.0f ms", -offset)
+	}
+	if math.Abs(a.OffsetMs) > a.ToleranceMs {
+		a.InSync = false
+		a.Issues = append(a.Issues, fmt.Sprintf("Audio %s, beyond the ±%.0f ms tolerance", describe(a.OffsetMs), a.ToleranceMs))
+	}
+	// Drift is only flagged once it alone moves the sync past the tolerance
+	if math.Abs(last-first) > a.ToleranceMs && math.Abs(a.MaxOffsetMs) > a.ToleranceMs {
+		a.InSync = false
+		a.Issues = append(a.Issues, fmt.Sprintf("Audio drifts by %.1f ms per minute: it %s at the start and %s at the end",
+			a.DriftMsPerMinute, describe(first), describe(last)))
+	}
+}
+
+func meanDeviation(values []float64) (mean, deviation float64) {
+	for _, value := range values {
+		mean += value
+	}
+	mean /= float64(len(values))
+	for _, value := range values {
+		deviation += (value - mean) * (value - mean)
+	}
+	return mean, math.Sqrt(deviation / float64(len(values)))
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// linearFit returns the least squares slope and intercept of y over x
+func linearFit(x, y []float64) (slope, intercept float64) {
+	xMean, _ := meanDeviation(x)
+	yMean, _ := meanDeviation(y)
+	var covariance, variance float64
+	for i := range x {
+		covariance += (x[i] - xMean) * (y[i] - yMean)
+		variance += (x[i] - xMean) * (x[i] - xMean)
+	}
+	if variance == 0 {
+		return 0, yMean
+	}
+	slope = covariance / variance
+	return slope, yMean - slope*xMean
+}
@@ -0,0 +1,112 @@
+package ffmpeg
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestParseAVSyncActivity(t *testing.T) {
+	output := []byte(`[Parsed_metadata_3 @ 0x55a4] frame:0    pts:0       pts_time:0
+[Parsed_metadata_3 @ 0x55a4] lavfi.signalstats.YDIF=0.000
+[Parsed_ametadata_9 @ 0x55a5] frame:0    pts:0       pts_time:0
+[Parsed_ametadata_9 @ 0x55a5] lavfi.astats.Overall.RMS_level=-inf
+[Parsed_ametadata_9 @ 0x55a5] frame:1    pts:480     pts_time:0.01
+[Parsed_ametadata_9 @ 0x55a5] lavfi.astats.Overall.RMS_level=-31.2
+[Parsed_metadata_3 @ 0x55a4] frame:1    pts:1       pts_time:0.04
+[Parsed_metadata_3 @ 0x55a4] lavfi.signalstats.YDIF=3.215
+`)
+
+	video, audio := parseAVSyncActivity(output)
+	if len(video) != 2 || video[1] != (avSyncSample{0.04, 3.215}) {
+		t.Errorf("unexpected video activity %+v", video)
+	}
+	if len(audio) != 2 || audio[0] != (avSyncSample{0, -120}) || audio[1] != (avSyncSample{0.01, -31.2}) {
+		t.Errorf("unexpected audio levels %+v", audio)
+	}
+}
+
+// syntheticAVSync builds 25 fps picture activity and 10 ms audio levels
+// with an event every second or so; the sound of each event follows the
+// picture by offset(t) seconds. Like astats, each audio level covers the
+// 10 ms from its timestamp.
+func syntheticAVSync(duration float64, offset func(t float64) float64) (video, audio []avSyncSample) {
+	random := rand.New(rand.NewSource(1))
+	var events []float64
+	for t := 0.5; t < duration-1; t += 0.6 + random.Float64() {
+		events = append(events, t)
+	}
+
+	for frame := 0; float64(frame)*0.04 < duration; frame++ {
+		t := float64(frame) * 0.04
+		value := 1.0
+		for _, event := range events {
+			if math.Abs(t-event) < 0.02 {
+				value = 20
+			}
+		}
+		video = append(video, avSyncSample{t, value})
+	}
+	for chunk := 0; float64(chunk)*avSyncBin < duration; chunk++ {
+		t := float64(chunk) * avSyncBin
+		level := -60.0
+		for _, event := range events {
+			if since := t + avSyncBin - event - offset(event); since >= 0 && since < 0.2 {
+				level = math.Max(level, -20-100*since)
+			}
+		}
+		audio = append(audio, avSyncSample{t, level})
+	}
+	return video, audio
+}
+
+func TestCorrelateAVSyncOffset(t *testing.T) {
+	video, audio := syntheticAVSync(120, func(float64) float64 { return 0.12 })
+	analysis := &AVSyncAnalysis{ToleranceMs: avSyncToleranceMs, Content: correlateAVSync(video, audio)}
+	analysis.evaluate()
+
+	content := analysis.Content
+	if content == nil || content.WindowsUsed != 4 {
+		t.Fatalf("expected four usable windows, got %+v", content)
+	}
+	if math.Abs(content.OffsetMs-120) > 10 || math.Abs(content.DriftMsPerMinute) > 5 {
+		t.Errorf("expected a steady 120 ms offset, got %v ms drifting %v ms/min", content.OffsetMs, content.DriftMsPerMinute)
+	}
+	if analysis.Method != "content" || analysis.InSync || len(analysis.Issues) != 1 {
+		t.Errorf("expected the offset to be flagged, got %+v", analysis)
+	}
+}
+
+func TestCorrelateAVSyncDrift(t *testing.T) {
+	// Audio starts in sync and falls behind by 60 ms per minute
+	video, audio := syntheticAVSync(180, func(t float64) float64 { return t / 1000 })
+	analysis := &AVSyncAnalysis{ToleranceMs: avSyncToleranceMs, Content: correlateAVSync(video, audio)}
+	analysis.evaluate()
+
+	if drift := analysis.DriftMsPerMinute; math.Abs(drift-60) > 10 {
+		t.Errorf("expected about 60 ms/min of drift, got %v", drift)
+	}
+	if analysis.InSync || math.Abs(analysis.MaxOffsetMs) <= avSyncToleranceMs {
+		t.Errorf("expected the drift to be flagged, got %+v", analysis)
+	}
+}
+
+func TestCompareStreamTimestamps(t *testing.T) {
+	video := &StreamInfo{CodecType: "video", StartTime: "0.000000", Duration: "600.000000"}
+	audio := &StreamInfo{CodecType: "audio", StartTime: "0.020000", Duration: "600.080000"}
+
+	timestamps := compareStreamTimestamps(video, audio)
+	if timestamps.StartOffsetMs != 20 || timestamps.EndOffsetMs != 100 || timestamps.DriftMsPerMinute != 8 {
+		t.Fatalf("unexpected timestamps %+v", timestamps)
+	}
+
+	analysis := &AVSyncAnalysis{ToleranceMs: avSyncToleranceMs, Timestamps: timestamps}
+	analysis.evaluate()
+	if analysis.Method != "timestamps" || analysis.InSync || analysis.MaxOffsetMs != 100 {
+		t.Errorf("expected the end offset to be flagged, got %+v", analysis)
+	}
+
+	if compareStreamTimestamps(video, &StreamInfo{CodecType: "audio"}) != nil {
+		t.Error("expected no comparison without an audio start time")
+	}
+}
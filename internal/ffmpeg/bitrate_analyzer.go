@@ -0,0 +1,363 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// maxBitrateTimelinePoints bounds the timeline of long files like the
+	// loudness timeline; two hours fit at one point per second
+	maxBitrateTimelinePoints = 7200
+
+	// maxVBVEvents bounds the underruns and overshoots listed; the counts
+	// still cover all
+	maxVBVEvents = 100
+)
+
+// Kinds of VBV event
+const (
+	VBVUnderrun  = "underrun"  // A frame is due for decoding before all its bits have arrived
+	VBVOvershoot = "overshoot" // A timeline interval carries more bits than the level's maximum bit rate
+)
+
+// BitrateAnalyzer measures the bit rate of a video stream over time and
+// simulates its decoder buffer
+type BitrateAnalyzer struct {
+	ffprobePath string
+	logger      zerolog.Logger
+}
+
+// NewBitrateAnalyzer creates a new bit rate timeline analyzer
+func NewBitrateAnalyzer(ffprobePath string, logger zerolog.Logger) *BitrateAnalyzer {
+	return &BitrateAnalyzer{
+		ffprobePath: ffprobePath,
+		logger:      logger,
+	}
+}
+
+// BitrateAnalysis is the bit rate timeline of the first video stream and,
+// for H.264 and HEVC, the simulation of its decoder buffer
+type BitrateAnalysis struct {
+	StreamIndex      int            `json:"stream_index"`
+	CodecName        string         `json:"codec_name"`
+	AverageBitRate   float64        `json:"average_bit_rate"` // Bits per second
+	PeakBitRate      float64        `json:"peak_bit_rate"`    // Highest timeline interval
+	MinBitRate       float64        `json:"min_bit_rate"`     // Lowest timeline interval
+	PeakToAverage    float64        `json:"peak_to_average"`
+	TimelineInterval float64        `json:"timeline_interval_seconds"`
+	Timeline         []BitratePoint `json:"timeline"`
+	VBV              *VBVSimulation `json:"vbv,omitempty"`
+}
+
+// BitratePoint is the bit rate of one timeline interval
+type BitratePoint struct {
+	Time          float64  `json:"t"`                             // Start of the interval in seconds from the first packet
+	BitRate       float64  `json:"bit_rate"`                      // Bits per second
+	BufferPercent *float64 `json:"buffer_fullness_pct,omitempty"` // Lowest decoder buffer fullness in the interval
+}
+
+// VBVSimulation replays the stream through the hypothetical reference
+// decoder buffer (the VBV) that its profile and level allow. Bits arrive at
+// the level's maximum bit rate until the buffer is full and each frame is
+// removed at its decoding time, starting from a full buffer. Stream-specific
+// HRD parameters are not exposed by ffprobe, so these are the loosest limits
+// a conforming decoder guarantees.
+type VBVSimulation struct {
+	Profile            string     `json:"profile"`
+	Level              string     `json:"level"`
+	MaxBitRate         float64    `json:"max_bit_rate"`     // Bits per second
+	BufferSize         float64    `json:"buffer_size_bits"` // Coded picture buffer size
+	Underruns          int        `json:"underruns"`
+	Overshoots         int        `json:"overshoots"`
+	MinFullnessPercent float64    `json:"min_fullness_pct"`
+	Compliant          bool       `json:"compliant"`
+	Events             []VBVEvent `json:"events,omitempty"` // The first 100, in time order
+}
+
+// VBVEvent is one underrun or overshoot
+type VBVEvent struct {
+	Kind    string  `json:"kind"`
+	Time    float64 `json:"time"`               // Seconds from the first packet
+	Bits    float64 `json:"bits"`               // Bits missing from the buffer, or carried by the interval
+	BitRate float64 `json:"bit_rate,omitempty"` // Bit rate of the interval, for overshoots
+}
+
+// bitratePacket is the decoding time and size of one packet
+type bitratePacket struct {
+	dts  float64
+	bits float64
+}
+
+// AnalyzeBitrate reads the packet sizes of the first video stream. It
+// returns nil when the file has no video.
+func (ba *BitrateAnalyzer) AnalyzeBitrate(ctx context.Context, filePath string, streams []StreamInfo) (*BitrateAnalysis, error) {
+	var video *StreamInfo
+	for i := range streams {
+		if strings.EqualFold(streams[i].CodecType, "video") && streams[i].Disposition["attached_pic"] == 0 {
+			video = &streams[i]
+			break
+		}
+	}
+	if video == nil {
+		return nil, nil
+	}
+
+	cmd := exec.CommandContext(ctx, ba.ffprobePath,
+		"-v", "error",
+		"-select_streams", strconv.Itoa(video.Index),
+		"-show_entries", "packet=dts_time,pts_time,size",
+		"-of", "compact=p=0",
+		filePath,
+	)
+	output, err := commandOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read packet sizes: %w", err)
+	}
+
+	packets := parseBitratePackets(output)
+	analysis := buildBitrateTimeline(packets)
+	analysis.StreamIndex = video.Index
+	analysis.CodecName = video.CodecName
+	if limits, ok := vbvLimits(video.CodecName, video.Profile, video.Level); ok {
+		analysis.VBV = simulateVBV(packets, analysis, limits)
+	}
+	return analysis, nil
+}
+
+// parseBitratePackets reads packets from ffprobe's compact output, e.g.
+//
+//	dts_time=0.040000|pts_time=0.120000|size=18204
+//
+// Packets without a DTS fall back to their PTS.
+func parseBitratePackets(output []byte) []bitratePacket {
+	var packets []bitratePacket
+	forEachLine(output, func(line string) bool {
+		var packet bitratePacket
+		hasTime, hasSize := false, false
+		for _, field := range strings.Split(strings.TrimSpace(line), "|") {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "dts_time":
+				if t, err := strconv.ParseFloat(value, 64); err == nil {
+					packet.dts, hasTime = t, true
+				}
+			case "pts_time":
+				if t, err := strconv.ParseFloat(value, 64); err == nil && !hasTime {
+					packet.dts, hasTime = t, true
+				}
+			case "size":
+				if size, err := strconv.ParseFloat(value, 64); err == nil {
+					packet.bits, hasSize = size*8, true
+				}
+			}
+		}
+		if hasTime && hasSize {
+			packets = append(packets, packet)
+		}
+		return true
+	})
+	return packets
+}
+
+// buildBitrateTimeline sums packet sizes into one second intervals,
+// doubling the interval until the timeline fits maxBitrateTimelinePoints
+func buildBitrateTimeline(packets []bitratePacket) *BitrateAnalysis {
+	analysis := &BitrateAnalysis{}
+	if len(packets) == 0 {
+		return analysis
+	}
+
+	start, end := packets[0].dts, packets[0].dts
+	var total float64
+	for _, packet := range packets {
+		start = math.Min(start, packet.dts)
+		end = math.Max(end, packet.dts)
+		total += packet.bits
+	}
+
+	interval := 1.0
+	for (end-start)/interval >= maxBitrateTimelinePoints {
+		interval *= 2
+	}
+	bits := make([]float64, int((end-start)/interval)+1)
+	for _, packet := range packets {
+		bits[int((packet.dts-start)/interval)] += packet.bits
+	}
+
+	analysis.TimelineInterval = interval
+	analysis.MinBitRate = math.Inf(1)
+	for i, value := range bits {
+		rate := value / interval
+		analysis.Timeline = append(analysis.Timeline, BitratePoint{Time: float64(i) * interval, BitRate: math.Round(rate)})
+		analysis.PeakBitRate = math.Max(analysis.PeakBitRate, rate)
+		// A partial last interval understates the rate
+		if i < len(bits)-1 || len(bits) == 1 {
+			analysis.MinBitRate = math.Min(analysis.MinBitRate, rate)
+		}
+	}
+
+	// Packets sit at the start of the time they last, so the last one's
+	// duration is estimated from the average packet spacing
+	duration := end - start
+	if len(packets) > 1 {
+		duration += duration / float64(len(packets)-1)
+	}
+	if duration > 0 {
+		analysis.AverageBitRate = math.Round(total / duration)
+	}
+	analysis.PeakBitRate = math.Round(analysis.PeakBitRate)
+	analysis.MinBitRate = math.Round(analysis.MinBitRate)
+	if analysis.AverageBitRate > 0 {
+		analysis.PeakToAverage = roundTo(analysis.PeakBitRate/analysis.AverageBitRate, 2)
+	}
+	return analysis
+}
+
+// vbvLimit is the maximum bit rate and buffer size of a profile and level
+type vbvLimit struct {
+	profile    string
+	level      string
+	maxBitRate float64 // Bits per second
+	bufferSize float64 // Bits
+}
+
+// h264Levels holds MaxBR and MaxCPB of H.264 Table A-1 by level_idc, in
+// units of the profile's cpbBrNalFactor bits
+var h264Levels = map[int][2]float64{
+	9: {128, 350}, 10: {64, 175}, 11: {192, 500}, 12: {384, 1000}, 13: {768, 2000},
+	20: {2000, 2000}, 21: {4000, 4000}, 22: {4000, 4000},
+	30: {10000, 10000}, 31: {14000, 14000}, 32: {20000, 20000},
+	40: {20000, 25000}, 41: {50000, 62500}, 42: {50000, 62500},
+	50: {135000, 135000}, 51: {240000, 240000}, 52: {240000, 240000},
+	60: {240000, 240000}, 61: {480000, 480000}, 62: {800000, 800000},
+}
+
+// hevcMainTierLevels holds the Main tier MaxBr and MaxCPB of H.265 Table
+// A.8 by general_level_idc (30 times the level), in units of the profile's
+// CpbNalFactor bits. ffprobe does not report the tier, so Main is assumed.
+var hevcMainTierLevels = map[int][2]float64{
+	30: {128, 350}, 60: {1500, 1500}, 63: {3000, 3000},
+	90: {6000, 6000}, 93: {10000, 10000},
+	120: {12000, 12000}, 123: {20000, 20000},
+	150: {25000, 25000}, 153: {40000, 40000}, 156: {60000, 60000},
+	180: {60000, 60000}, 183: {120000, 120000}, 186: {240000, 240000},
+}
+
+// vbvLimits looks up the NAL HRD limits of an H.264 or HEVC profile and
+// level. Packet sizes include NAL unit headers, so the NAL factors apply.
+func vbvLimits(codecName, profile string, level int) (vbvLimit, bool) {
+	lowerProfile := strings.ToLower(profile)
+	switch strings.ToLower(codecName) {
+	case "h264", "avc":
+		limits, ok := h264Levels[level]
+		if !ok {
+			return vbvLimit{}, false
+		}
+		factor := 1200.0
+		switch {
+		case strings.Contains(lowerProfile, "4:2:2"), strings.Contains(lowerProfile, "4:4:4"):
+			factor = 4800
+		case strings.Contains(lowerProfile, "high 10"):
+			factor = 3600
+		case strings.HasPrefix(lowerProfile, "high"):
+			factor = 1500
+		}
+		name := fmt.Sprintf("%d.%d", level/10, level%10)
+		if level == 9 {
+			name = "1b"
+		}
+		return vbvLimit{profile, name, limits[0] * factor, limits[1] * factor}, true
+	case "hevc", "h265":
+		limits, ok := hevcMainTierLevels[level]
+		if !ok {
+			return vbvLimit{}, false
+		}
+		factor := 1100.0
+		switch {
+		case strings.Contains(lowerProfile, "4:4:4"), lowerProfile == "rext":
+			factor = 2200
+		case strings.Contains(lowerProfile, "4:2:2"):
+			factor = 1833
+		}
+		return vbvLimit{profile, fmt.Sprintf("%d.%d", level/30, level%30/3), limits[0] * factor, limits[1] * factor}, true
+	}
+	return vbvLimit{}, false
+}
+
+// simulateVBV replays the packets through the decoder buffer of limits and
+// records the lowest fullness of each timeline interval
+func simulateVBV(packets []bitratePacket, analysis *BitrateAnalysis, limits vbvLimit) *VBVSimulation {
+	simulation := &VBVSimulation{
+		Profile:            limits.profile,
+		Level:              limits.level,
+		MaxBitRate:         limits.maxBitRate,
+		BufferSize:         limits.bufferSize,
+		MinFullnessPercent: 100,
+	}
+	addEvent := func(event VBVEvent) {
+		if len(simulation.Events) < maxVBVEvents {
+			simulation.Events = append(simulation.Events, event)
+		}
+	}
+	if len(packets) == 0 || analysis.TimelineInterval <= 0 {
+		simulation.Compliant = true
+		return simulation
+	}
+
+	start := packets[0].dts
+	for _, packet := range packets {
+		start = math.Min(start, packet.dts)
+	}
+
+	fullness := limits.bufferSize
+	previous := packets[0].dts
+	for _, packet := range packets {
+		// Packets are listed in decoding order; reordered timestamps add nothing
+		if packet.dts > previous {
+			fullness = math.Min(limits.bufferSize, fullness+limits.maxBitRate*(packet.dts-previous))
+			previous = packet.dts
+		}
+
+		fullness -= packet.bits
+		if fullness < 0 {
+			simulation.Underruns++
+			addEvent(VBVEvent{Kind: VBVUnderrun, Time: roundTo(packet.dts-start, 3), Bits: math.Round(-fullness)})
+			// The decoder waits for the missing bits
+			fullness = 0
+		}
+
+		percent := roundTo(fullness/limits.bufferSize*100, 1)
+		simulation.MinFullnessPercent = math.Min(simulation.MinFullnessPercent, percent)
+		point := &analysis.Timeline[int((packet.dts-start)/analysis.TimelineInterval)]
+		if point.BufferPercent == nil || percent < *point.BufferPercent {
+			point.BufferPercent = &percent
+		}
+	}
+
+	for _, point := range analysis.Timeline {
+		if point.BitRate > limits.maxBitRate {
+			simulation.Overshoots++
+			addEvent(VBVEvent{Kind: VBVOvershoot, Time: point.Time, Bits: point.BitRate * analysis.TimelineInterval, BitRate: point.BitRate})
+		}
+	}
+	sortVBVEvents(simulation.Events)
+
+	simulation.Compliant = simulation.Underruns == 0 && simulation.Overshoots == 0
+	return simulation
+}
+
+// sortVBVEvents orders underruns and overshoots by time
+func sortVBVEvents(events []VBVEvent) {
+	for i := 1; i < len(events); i++ {
+		for j := i; j > 0 && events[j].Time < events[j-1].Time; j-- {
+			events[j], events[j-1] = events[j-1], events[j]
+		}
+	}
+}
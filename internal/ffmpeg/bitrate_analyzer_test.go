@@ -0,0 +1,109 @@
+package ffmpeg
+
+import (
+	"testing"
+)
+
+func TestParseBitratePackets(t *testing.T) {
+	output := []byte(`dts_time=0.000000|pts_time=0.080000|size=1000
+dts_time=N/A|pts_time=0.040000|size=500
+dts_time=N/A|pts_time=N/A|size=250
+dts_time=0.080000|pts_time=0.120000|size=N/A
+`)
+
+	packets := parseBitratePackets(output)
+	if len(packets) != 2 || packets[0] != (bitratePacket{0, 8000}) || packets[1] != (bitratePacket{0.04, 4000}) {
+		t.Errorf("unexpected packets %+v", packets)
+	}
+}
+
+// constantPackets builds 25 fps packets of frameBits each lasting seconds
+func constantPackets(seconds float64, frameBits float64) []bitratePacket {
+	var packets []bitratePacket
+	for frame := 0; float64(frame)*0.04 < seconds; frame++ {
+		packets = append(packets, bitratePacket{float64(frame) * 0.04, frameBits})
+	}
+	return packets
+}
+
+func TestBuildBitrateTimeline(t *testing.T) {
+	packets := constantPackets(10, 40000)
+	// A two second burst at 4 Mbit/s
+	for i := 100; i < 150; i++ {
+		packets[i].bits = 160000
+	}
+
+	analysis := buildBitrateTimeline(packets)
+	if analysis.TimelineInterval != 1 || len(analysis.Timeline) != 10 {
+		t.Fatalf("expected ten one second points, got %v x %d", analysis.TimelineInterval, len(analysis.Timeline))
+	}
+	if analysis.Timeline[0].BitRate != 1000000 || analysis.Timeline[5].BitRate != 4000000 {
+		t.Errorf("unexpected timeline %+v", analysis.Timeline)
+	}
+	if analysis.AverageBitRate != 1600000 || analysis.PeakBitRate != 4000000 || analysis.MinBitRate != 1000000 || analysis.PeakToAverage != 2.5 {
+		t.Errorf("unexpected summary %+v", analysis)
+	}
+
+	// Three hours no longer fit at one point per second
+	long := buildBitrateTimeline([]bitratePacket{{0, 8}, {10800, 8}})
+	if long.TimelineInterval != 2 || len(long.Timeline) != 5401 {
+		t.Errorf("expected two second points, got %v x %d", long.TimelineInterval, len(long.Timeline))
+	}
+}
+
+func TestVBVLimits(t *testing.T) {
+	tests := []struct {
+		codec, profile string
+		level          int
+		name           string
+		rate, buffer   float64
+	}{
+		{"h264", "High", 41, "4.1", 75000000, 93750000},
+		{"h264", "Main", 30, "3.0", 12000000, 12000000},
+		{"h264", "Constrained Baseline", 9, "1b", 153600, 420000},
+		{"h264", "High 10", 51, "5.1", 864000000, 864000000},
+		{"hevc", "Main 10", 153, "5.1", 44000000, 44000000},
+		{"hevc", "Main", 123, "4.1", 22000000, 22000000},
+	}
+	for _, tt := range tests {
+		limits, ok := vbvLimits(tt.codec, tt.profile, tt.level)
+		if !ok || limits.level != tt.name || limits.maxBitRate != tt.rate || limits.bufferSize != tt.buffer {
+			t.Errorf("%s %s %d: unexpected limits %+v", tt.codec, tt.profile, tt.level, limits)
+		}
+	}
+
+	if _, ok := vbvLimits("h264", "High", -99); ok {
+		t.Error("expected no limits for an unknown level")
+	}
+	if _, ok := vbvLimits("vp9", "Profile 0", 41); ok {
+		t.Error("expected no limits for VP9")
+	}
+}
+
+func TestSimulateVBV(t *testing.T) {
+	limits := vbvLimit{profile: "Main", level: "3.0", maxBitRate: 1000000, bufferSize: 500000}
+
+	packets := constantPackets(10, 32000)
+	analysis := buildBitrateTimeline(packets)
+	steady := simulateVBV(packets, analysis, limits)
+	if !steady.Compliant || steady.MinFullnessPercent != 93.6 || *analysis.Timeline[3].BufferPercent != 93.6 {
+		t.Errorf("expected an 800 kbit/s stream to fit, got %+v", steady)
+	}
+
+	// A 1.6 Mbit/s second drains the buffer
+	packets = constantPackets(10, 32000)
+	for i := 100; i < 125; i++ {
+		packets[i].bits = 64000
+	}
+	analysis = buildBitrateTimeline(packets)
+	burst := simulateVBV(packets, analysis, limits)
+	if burst.Compliant || burst.Overshoots != 1 || burst.Underruns == 0 || burst.MinFullnessPercent != 0 {
+		t.Fatalf("expected the burst to underrun, got %+v", burst)
+	}
+	if first := burst.Events[0]; first.Kind != VBVOvershoot || first.Time != 4 || first.BitRate != 1600000 {
+		t.Errorf("unexpected first event %+v", first)
+	}
+	if last := burst.Events[len(burst.Events)-1]; last.Kind != VBVUnderrun || last.Time < 4 || last.Time >= 5 {
+		t.Errorf("unexpected last event %+v", last)
+	}
+}
@@ -345,6 +345,56 @@ func (b *OptionsBuilder) MaxOutputSizeMB(sizeMB int) *OptionsBuilder {
 	return b
 }
 
+// MetadataOnly skips enhanced analysis so only the probed headers are read
+func (b *OptionsBuilder) MetadataOnly() *OptionsBuilder {
+	b.options.MetadataOnly = true
+	return b
+}
+
+// DecodeScan decodes the whole input to find decode errors and corrupt frames
+func (b *OptionsBuilder) DecodeScan() *OptionsBuilder {
+	b.options.DecodeScan = true
+	return b
+}
+
+// QCCategories restricts enhanced analysis to the given QC categories
+func (b *OptionsBuilder) QCCategories(categories ...QCCategory) *OptionsBuilder {
+	b.options.QCCategories = categories
+	return b
+}
+
+// DeliveryProfile checks the result against a delivery profile
+func (b *OptionsBuilder) DeliveryProfile(profile *DeliveryProfile) *OptionsBuilder {
+	b.options.DeliveryProfile = profile
+	return b
+}
+
+// AnalysisStreams measures loudness, silence and phase separately for each
+// audio stream the select_streams specifier picks
+func (b *OptionsBuilder) AnalysisStreams(specifier string) *OptionsBuilder {
+	b.options.AnalysisStreams = specifier
+	return b
+}
+
+// ContentSampling analyzes the content of long assets through evenly spaced
+// windows
+func (b *OptionsBuilder) ContentSampling(options *ContentSamplingOptions) *OptionsBuilder {
+	b.options.ContentSampling = options
+	return b
+}
+
+// LoudnessTargets sets the targets loudness normalization is computed for
+func (b *OptionsBuilder) LoudnessTargets(targets ...LoudnessTarget) *OptionsBuilder {
+	b.options.LoudnessTargets = targets
+	return b
+}
+
+// OnPhase sets a callback for probe phase progress
+func (b *OptionsBuilder) OnPhase(fn PhaseFunc) *OptionsBuilder {
+	b.options.OnPhase = fn
+	return b
+}
+
 // InputOption adds a custom input option
 func (b *OptionsBuilder) InputOption(key, value string) *OptionsBuilder {
 	if b.options.InputOptions == nil {
@@ -416,6 +466,31 @@ func (b *OptionsBuilder) BroadcastQC() *OptionsBuilder {
 		CountFrames().CountPackets().ProbeSizeMB(50).AnalyzeDurationSeconds(30)
 }
 
+// remoteProtocols are the protocols ffprobe may use for a remote input
+const remoteProtocols = "http,https,tcp,tls"
+
+// RemoteMetadata configures options for probing a network URL in place.
+// Only network protocols are allowed and reading stops once the probe size or
+// analyze duration is reached, so large remote files are not fully downloaded.
+func (b *OptionsBuilder) RemoteMetadata(probeSizeMB, analyzeSeconds int) *OptionsBuilder {
+	return b.JSON().ShowFormat().ShowStreams().ShowChapters().ShowPrograms().ShowError().
+		ProbeSizeMB(probeSizeMB).AnalyzeDurationSeconds(analyzeSeconds).
+		InputOption("protocol_whitelist", remoteProtocols).
+		MetadataOnly()
+}
+
+// Express configures options for a fast metadata lookup: only the format
+// and streams are read, from the first megabyte and second of the input.
+// Frame counting, hashing and content analysis are skipped.
+func (b *OptionsBuilder) Express() *OptionsBuilder {
+	return b.QuickInfo().ShowError().MetadataOnly()
+}
+
+// RemoteExpress is Express for a network URL probed in place
+func (b *OptionsBuilder) RemoteExpress() *OptionsBuilder {
+	return b.Express().InputOption("protocol_whitelist", remoteProtocols)
+}
+
 // StreamingQC configures options for streaming platform compliance
 func (b *OptionsBuilder) StreamingQC() *OptionsBuilder {
 	return b.JSON().ShowAll().ShowError().ShowDataHash().
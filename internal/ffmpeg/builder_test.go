@@ -638,3 +638,139 @@ func TestOptionsBuilder_ShowEntries(t *testing.T) {
 		}
 	})
 }
+
+func TestOptionsBuilder_RemoteMetadata(t *testing.T) {
+	opts := NewOptionsBuilder().Input("https://example.com/video.mp4").RemoteMetadata(10, 5).Build()
+
+	if !opts.MetadataOnly {
+		t.Error("expected MetadataOnly to be set")
+	}
+	if opts.CountFrames || opts.CountPackets {
+		t.Error("remote metadata probe must not count frames or packets")
+	}
+	if opts.ProbeSize != 10*1024*1024 {
+		t.Errorf("expected ProbeSize 10MB, got %d", opts.ProbeSize)
+	}
+	if opts.AnalyzeDuration != 5*1000000 {
+		t.Errorf("expected AnalyzeDuration 5s, got %d", opts.AnalyzeDuration)
+	}
+	if opts.InputOptions["protocol_whitelist"] != "http,https,tcp,tls" {
+		t.Errorf("unexpected protocol_whitelist: %q", opts.InputOptions["protocol_whitelist"])
+	}
+	if err := ValidateOptions(opts); err != nil {
+		t.Errorf("expected remote options to validate, got %v", err)
+	}
+}
+
+func TestOptionsBuilder_Express(t *testing.T) {
+	opts := NewOptionsBuilder().Input("/tmp/video.mp4").Express().Build()
+
+	if !opts.MetadataOnly {
+		t.Error("expected MetadataOnly to be set")
+	}
+	if !opts.ShowFormat || !opts.ShowStreams {
+		t.Error("expected format and streams to be shown")
+	}
+	if opts.ShowFrames || opts.ShowPackets || opts.CountFrames || opts.CountPackets {
+		t.Error("express probe must not read frames or packets")
+	}
+	if opts.HashAlgorithm != "" || opts.ShowDataHash {
+		t.Error("express probe must not hash the input")
+	}
+	if opts.ProbeSize != 1024*1024 {
+		t.Errorf("expected ProbeSize 1MB, got %d", opts.ProbeSize)
+	}
+	if opts.AnalyzeDuration != 1000000 {
+		t.Errorf("expected AnalyzeDuration 1s, got %d", opts.AnalyzeDuration)
+	}
+	if _, ok := opts.InputOptions["protocol_whitelist"]; ok {
+		t.Error("local express probe must not restrict protocols")
+	}
+
+	remote := NewOptionsBuilder().Input("https://example.com/video.mp4").RemoteExpress().Build()
+	if remote.InputOptions["protocol_whitelist"] != "http,https,tcp,tls" {
+		t.Errorf("unexpected protocol_whitelist: %q", remote.InputOptions["protocol_whitelist"])
+	}
+	if err := ValidateOptions(remote); err != nil {
+		t.Errorf("expected remote express options to validate, got %v", err)
+	}
+}
+
+func TestValidateOptions_URLQueryString(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expectErr bool
+	}{
+		{name: "signed URL query", input: "https://bucket.example.com/v.mp4?X-Amz-Expires=60&X-Amz-Signature=abc", expectErr: false},
+		{name: "dangerous char in URL path", input: "https://example.com/$(id).mp4", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOptions(&FFprobeOptions{Input: tt.input})
+			if tt.expectErr && err == nil {
+				t.Error("expected error, got nil")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseQCCategories(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		expected  []QCCategory
+		expectErr bool
+	}{
+		{name: "empty selects all", input: "", expected: nil},
+		{name: "targeted list", input: "hdr,loudness,timecode", expected: []QCCategory{QCCategoryHDR, QCCategoryLoudness, QCCategoryTimecode}},
+		{name: "aliases and duplicates", input: " Frame_Rate, framerate,data_integrity ", expected: []QCCategory{QCCategoryFrameRate, QCCategoryIntegrity}},
+		{name: "unknown category", input: "hdr,colour", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseQCCategories(tt.input)
+			if tt.expectErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, got)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Errorf("expected %v, got %v", tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestOptionsBuilder_QCCategories(t *testing.T) {
+	opts := NewOptionsBuilder().
+		Input("https://example.com/video.mp4").
+		QCCategories(QCCategoryHDR, QCCategoryLoudness).
+		Build()
+
+	if len(opts.QCCategories) != 2 {
+		t.Fatalf("expected 2 categories, got %v", opts.QCCategories)
+	}
+	if err := ValidateOptions(opts); err != nil {
+		t.Errorf("expected options to validate, got %v", err)
+	}
+
+	opts.QCCategories = append(opts.QCCategories, QCCategory("bogus"))
+	if err := ValidateOptions(opts); err == nil {
+		t.Error("expected error for unknown category")
+	}
+}
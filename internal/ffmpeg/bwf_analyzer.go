@@ -0,0 +1,377 @@
+package ffmpeg
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Layout of the bext chunk, EBU Tech 3285
+const (
+	bextMinSize           = 602    // Fixed fields before the coding history
+	bextLoudnessNotSet    = 0x7FFF // Loudness fields without a value
+	bextLoudnessTolerance = 1.0    // LU the stored loudness may differ from the measured one
+)
+
+// BWFAnalyzer reads and validates the Broadcast Wave metadata of WAV files
+type BWFAnalyzer struct {
+	logger zerolog.Logger
+}
+
+// NewBWFAnalyzer creates a new Broadcast Wave analyzer
+func NewBWFAnalyzer(logger zerolog.Logger) *BWFAnalyzer {
+	return &BWFAnalyzer{
+		logger: logger,
+	}
+}
+
+// BWFAnalysis contains the Broadcast Wave metadata of a WAV file and its
+// validation. Radio deliverables need a bext chunk with a valid origination
+// date and time, time reference and coding history.
+type BWFAnalysis struct {
+	Format        string        `json:"format"` // RIFF, RF64 or BW64
+	SampleRate    int           `json:"sample_rate,omitempty"`
+	Channels      int           `json:"channels,omitempty"`
+	BitsPerSample int           `json:"bits_per_sample,omitempty"`
+	HasBext       bool          `json:"has_bext"`
+	HasIXML       bool          `json:"has_ixml"`
+	Bext          *BextChunk    `json:"bext,omitempty"`
+	LoudnessCheck *BextLoudness `json:"loudness_check,omitempty"`
+	IsValid       bool          `json:"is_valid"`
+	Issues        []string      `json:"issues,omitempty"`
+	Warnings      []string      `json:"warnings,omitempty"`
+}
+
+// BextChunk is the broadcast audio extension chunk. Loudness fields are
+// only set from version 2 on, when they carry a value.
+type BextChunk struct {
+	Version               int                  `json:"version"`
+	Description           string               `json:"description,omitempty"`
+	Originator            string               `json:"originator,omitempty"`
+	OriginatorReference   string               `json:"originator_reference,omitempty"`
+	OriginationDate       string               `json:"origination_date,omitempty"` // As written, yyyy-mm-dd with any of the allowed separators
+	OriginationTime       string               `json:"origination_time,omitempty"` // As written, hh:mm:ss
+	TimeReference         uint64               `json:"time_reference_samples"`     // Samples since midnight at the first sample
+	TimeReferenceSeconds  float64              `json:"time_reference_seconds"`
+	TimeReferenceTimecode string               `json:"time_reference_timecode,omitempty"` // HH:MM:SS.mmm
+	UMID                  string               `json:"umid,omitempty"`
+	LoudnessValue         *float64             `json:"loudness_value_lufs,omitempty"`
+	LoudnessRange         *float64             `json:"loudness_range_lu,omitempty"`
+	MaxTruePeakLevel      *float64             `json:"max_true_peak_level_dbtp,omitempty"`
+	MaxMomentaryLoudness  *float64             `json:"max_momentary_loudness_lufs,omitempty"`
+	MaxShortTermLoudness  *float64             `json:"max_short_term_loudness_lufs,omitempty"`
+	CodingHistory         []CodingHistoryEntry `json:"coding_history,omitempty"`
+}
+
+// CodingHistoryEntry is one line of the coding history, such as
+// A=PCM,F=48000,W=24,M=stereo,T=original
+type CodingHistoryEntry struct {
+	Line       string `json:"line"`
+	Algorithm  string `json:"algorithm,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty"`
+	BitRate    int    `json:"bit_rate_kbps,omitempty"`
+	WordLength int    `json:"word_length,omitempty"`
+	Mode       string `json:"mode,omitempty"`
+	Text       string `json:"text,omitempty"`
+}
+
+// BextLoudness compares the loudness stored in the bext chunk with the
+// measured integrated loudness
+type BextLoudness struct {
+	StoredLoudness   float64 `json:"stored_loudness_lufs"`
+	MeasuredLoudness float64 `json:"measured_loudness_lufs"`
+	Difference       float64 `json:"difference_lu"` // Measured minus stored
+	Tolerance        float64 `json:"tolerance_lu"`
+	Matches          bool    `json:"matches"`
+}
+
+// AnalyzeBWF reads the fmt, bext and iXML chunks of a WAV, RF64 or BW64
+// file. Other files, URLs and an empty filePath return nil.
+func (ba *BWFAnalyzer) AnalyzeBWF(filePath string) (*BWFAnalysis, error) {
+	if filePath == "" || strings.Contains(filePath, "://") {
+		return nil, nil
+	}
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil
+	}
+	return parseBWF(f, info.Size())
+}
+
+// parseBWF reads the Broadcast Wave metadata of the file in r, which holds
+// size bytes
+func parseBWF(r io.ReaderAt, size int64) (*BWFAnalysis, error) {
+	header := make([]byte, 12)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	riff := string(header[:4])
+	if (riff != "RIFF" && riff != "RF64" && riff != "BW64") || string(header[8:12]) != "WAVE" {
+		return nil, nil
+	}
+
+	analysis := &BWFAnalysis{Format: riff, IsValid: true}
+	var bext []byte
+	walkChunks(r, 12, size, binary.LittleEndian, func(id string, data func() ([]byte, error)) {
+		switch id {
+		case "fmt ":
+			if chunk, err := data(); err == nil && len(chunk) >= 16 {
+				analysis.Channels = int(binary.LittleEndian.Uint16(chunk[2:]))
+				analysis.SampleRate = int(binary.LittleEndian.Uint32(chunk[4:]))
+				analysis.BitsPerSample = int(binary.LittleEndian.Uint16(chunk[14:]))
+			}
+		case "bext":
+			if chunk, err := data(); err == nil && !analysis.HasBext {
+				analysis.HasBext = true
+				bext = chunk
+			}
+		case "iXML":
+			analysis.HasIXML = true
+		}
+	})
+
+	if !analysis.HasBext {
+		analysis.addIssue("No bext chunk; Broadcast Wave deliverables require one")
+		return analysis, nil
+	}
+	if len(bext) < bextMinSize {
+		analysis.addIssue(fmt.Sprintf("bext chunk is %d bytes, shorter than the %d its fixed fields need", len(bext), bextMinSize))
+		return analysis, nil
+	}
+	analysis.Bext = parseBextChunk(bext, analysis.SampleRate)
+	analysis.validate()
+	return analysis, nil
+}
+
+// parseBextChunk reads the fields of a bext chunk of at least bextMinSize
+// bytes
+func parseBextChunk(data []byte, sampleRate int) *BextChunk {
+	text := func(start, end int) string {
+		value := data[start:end]
+		if i := strings.IndexByte(string(value), 0); i >= 0 {
+			value = value[:i]
+		}
+		return strings.TrimSpace(decodeMetadataText(value))
+	}
+
+	bext := &BextChunk{
+		Description:         text(0, 256),
+		Originator:          text(256, 288),
+		OriginatorReference: text(288, 320),
+		OriginationDate:     text(320, 330),
+		OriginationTime:     text(330, 338),
+		TimeReference:       binary.LittleEndian.Uint64(data[338:]),
+		Version:             int(binary.LittleEndian.Uint16(data[346:])),
+	}
+	if sampleRate > 0 {
+		bext.TimeReferenceSeconds = float64(bext.TimeReference) / float64(sampleRate)
+		bext.TimeReferenceTimecode = formatSampleClock(bext.TimeReference, sampleRate)
+	}
+	if bext.Version >= 1 {
+		umid := data[348:412]
+		// A basic UMID is 32 bytes, the rest of the field is then zero
+		if umid[11] == 0x13 {
+			umid = umid[:32]
+		}
+		if strings.Trim(string(umid), "\x00") != "" {
+			bext.UMID = strings.ToUpper(hex.EncodeToString(umid))
+		}
+	}
+	if bext.Version >= 2 {
+		loudness := func(offset int) *float64 {
+			raw := int16(binary.LittleEndian.Uint16(data[offset:]))
+			if raw == bextLoudnessNotSet {
+				return nil
+			}
+			value := float64(raw) / 100
+			return &value
+		}
+		bext.LoudnessValue = loudness(412)
+		bext.LoudnessRange = loudness(414)
+		bext.MaxTruePeakLevel = loudness(416)
+		bext.MaxMomentaryLoudness = loudness(418)
+		bext.MaxShortTermLoudness = loudness(420)
+	}
+	bext.CodingHistory = parseCodingHistory(string(data[bextMinSize:]))
+	return bext
+}
+
+// parseCodingHistory splits the coding history into its lines and their
+// comma-separated parameters. T= runs to the end of the line.
+func parseCodingHistory(history string) []CodingHistoryEntry {
+	var entries []CodingHistoryEntry
+	history = strings.ReplaceAll(strings.TrimRight(history, "\x00"), "\r", "\n")
+	for _, line := range strings.Split(history, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		entry := CodingHistoryEntry{Line: line}
+		rest := line
+		for rest != "" {
+			var part string
+			if strings.HasPrefix(rest, "T=") {
+				part, rest = rest, ""
+			} else {
+				part, rest, _ = strings.Cut(rest, ",")
+			}
+			key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch strings.ToUpper(key) {
+			case "A":
+				entry.Algorithm = value
+			case "F":
+				entry.SampleRate, _ = strconv.Atoi(value)
+			case "B":
+				entry.BitRate, _ = strconv.Atoi(value)
+			case "W":
+				entry.WordLength, _ = strconv.Atoi(value)
+			case "M":
+				entry.Mode = value
+			case "T":
+				entry.Text = value
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// formatSampleClock formats a sample count as HH:MM:SS.mmm
+func formatSampleClock(samples uint64, sampleRate int) string {
+	milliseconds := samples * 1000 / uint64(sampleRate)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d",
+		milliseconds/3600000, milliseconds/60000%60, milliseconds/1000%60, milliseconds%1000)
+}
+
+// bextDateTimeSeparators are the separators EBU Tech 3285 allows in the
+// origination date and time
+const bextDateTimeSeparators = "-_:. /"
+
+// parseBextDateTime checks an origination date or time against its
+// layout, e.g. "2006-01-02" or "15:04:05", with any allowed separators
+func parseBextDateTime(value, layout string) bool {
+	if len(value) != len(layout) {
+		return false
+	}
+	normalized := []byte(value)
+	for i := range layout {
+		if strings.IndexByte(bextDateTimeSeparators, layout[i]) >= 0 {
+			if strings.IndexByte(bextDateTimeSeparators, value[i]) < 0 {
+				return false
+			}
+			normalized[i] = layout[i]
+		}
+	}
+	_, err := time.Parse(layout, string(normalized))
+	return err == nil
+}
+
+// validate checks the bext fields for presence and consistency with each
+// other and with the fmt chunk
+func (a *BWFAnalysis) validate() {
+	bext := a.Bext
+
+	switch {
+	case bext.OriginationDate == "":
+		a.addIssue("Origination date is empty")
+	case !parseBextDateTime(bext.OriginationDate, "2006-01-02"):
+		a.addIssue(fmt.Sprintf("Origination date %q is not a valid yyyy-mm-dd date", bext.OriginationDate))
+	}
+	switch {
+	case bext.OriginationTime == "":
+		a.addIssue("Origination time is empty")
+	case !parseBextDateTime(bext.OriginationTime, "15:04:05"):
+		a.addIssue(fmt.Sprintf("Origination time %q is not a valid hh:mm:ss time", bext.OriginationTime))
+	}
+	if bext.Originator == "" {
+		a.addWarning("Originator is empty")
+	}
+
+	if a.SampleRate > 0 && bext.TimeReference >= uint64(a.SampleRate)*86400 {
+		a.addIssue(fmt.Sprintf("Time reference of %d samples is %s, past the 24 hours it counts from midnight", bext.TimeReference, bext.TimeReferenceTimecode))
+	}
+
+	switch {
+	case bext.Version > 2:
+		a.addWarning(fmt.Sprintf("Unknown bext version %d", bext.Version))
+	case bext.Version >= 1 && bext.UMID == "":
+		a.addWarning("No UMID in a version 1 or later bext chunk")
+	}
+	if bext.UMID != "" && !strings.HasPrefix(bext.UMID, "060A2B34") {
+		a.addWarning("UMID does not start with the SMPTE universal label")
+	}
+	if bext.Version >= 2 && bext.LoudnessValue == nil {
+		a.addWarning("Version 2 bext chunk carries no loudness value")
+	}
+
+	if len(bext.CodingHistory) == 0 {
+		a.addWarning("Coding history is empty")
+		return
+	}
+	// The last line describes the file as it is now
+	last := bext.CodingHistory[len(bext.CodingHistory)-1]
+	if last.SampleRate > 0 && a.SampleRate > 0 && last.SampleRate != a.SampleRate {
+		a.addIssue(fmt.Sprintf("Coding history ends at F=%d but the file is %d Hz", last.SampleRate, a.SampleRate))
+	}
+	if last.WordLength > 0 && a.BitsPerSample > 0 && last.WordLength != a.BitsPerSample {
+		a.addIssue(fmt.Sprintf("Coding history ends at W=%d but the file is %d-bit", last.WordLength, a.BitsPerSample))
+	}
+	if channels, ok := codingHistoryModeChannels[strings.ToLower(last.Mode)]; ok && a.Channels > 0 && channels != a.Channels {
+		a.addIssue(fmt.Sprintf("Coding history ends at M=%s but the file has %d channels", last.Mode, a.Channels))
+	}
+}
+
+// codingHistoryModeChannels is the channel count of each coding history
+// mode that implies one
+var codingHistoryModeChannels = map[string]int{
+	"mono": 1, "stereo": 2, "dual-mono": 2, "joint-stereo": 2,
+}
+
+func (a *BWFAnalysis) addIssue(issue string) {
+	a.Issues = append(a.Issues, issue)
+	a.IsValid = false
+}
+
+func (a *BWFAnalysis) addWarning(warning string) {
+	a.Warnings = append(a.Warnings, warning)
+}
+
+// CheckLoudness compares the loudness value of a version 2 bext chunk with
+// the measured integrated loudness
+func (a *BWFAnalysis) CheckLoudness(loudness *LoudnessAnalysis) {
+	if a == nil || a.Bext == nil || a.Bext.LoudnessValue == nil || a.LoudnessCheck != nil || loudness == nil || loudness.IntegratedLoudness == 0 {
+		return
+	}
+	difference := loudness.IntegratedLoudness - *a.Bext.LoudnessValue
+	a.LoudnessCheck = &BextLoudness{
+		StoredLoudness:   *a.Bext.LoudnessValue,
+		MeasuredLoudness: loudness.IntegratedLoudness,
+		Difference:       math.Round(difference*10) / 10,
+		Tolerance:        bextLoudnessTolerance,
+		Matches:          math.Abs(difference) <= bextLoudnessTolerance,
+	}
+	if !a.LoudnessCheck.Matches {
+		a.addIssue(fmt.Sprintf("bext loudness value %.1f LUFS differs from the measured %.1f LUFS", *a.Bext.LoudnessValue, loudness.IntegratedLoudness))
+	}
+}
@@ -0,0 +1,199 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// testBextFields are the fields of a test bext chunk
+type testBextFields struct {
+	originator, date, clock string
+	timeReference           uint64
+	version                 uint16
+	umid                    []byte
+	loudness                int16 // Hundredths of a LUFS
+	codingHistory           string
+}
+
+func testBext(fields testBextFields) []byte {
+	data := make([]byte, bextMinSize)
+	copy(data[0:], "Morning news bulletin")
+	copy(data[256:], fields.originator)
+	copy(data[288:], "REF-0001")
+	copy(data[320:], fields.date)
+	copy(data[330:], fields.clock)
+	binary.LittleEndian.PutUint64(data[338:], fields.timeReference)
+	binary.LittleEndian.PutUint16(data[346:], fields.version)
+	copy(data[348:], fields.umid)
+	for offset := 412; offset < 422; offset += 2 {
+		binary.LittleEndian.PutUint16(data[offset:], bextLoudnessNotSet)
+	}
+	binary.LittleEndian.PutUint16(data[412:], uint16(fields.loudness))
+	return append(data, fields.codingHistory...)
+}
+
+func testWAV(chunks ...[]byte) []byte {
+	body := append([]byte("WAVE"), bytes.Join(chunks, nil)...)
+	file := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	return append(file, body...)
+}
+
+func testRIFFChunk(id string, data []byte) []byte {
+	chunk := append([]byte(id), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+// testFmtChunk is 48 kHz 24-bit PCM with the given channels
+func testFmtChunk(channels int) []byte {
+	data := binary.LittleEndian.AppendUint16(nil, 1)
+	data = binary.LittleEndian.AppendUint16(data, uint16(channels))
+	data = binary.LittleEndian.AppendUint32(data, 48000)
+	data = binary.LittleEndian.AppendUint32(data, uint32(48000*3*channels))
+	data = binary.LittleEndian.AppendUint16(data, uint16(3*channels))
+	data = binary.LittleEndian.AppendUint16(data, 24)
+	return testRIFFChunk("fmt ", data)
+}
+
+var testUMID = append([]byte{0x06, 0x0A, 0x2B, 0x34, 0x01, 0x01, 0x01, 0x05, 0x01, 0x01, 0x0D, 0x13}, bytes.Repeat([]byte{0x42}, 20)...)
+
+func TestBWFAnalyzer_AnalyzeBWF(t *testing.T) {
+	bext := testBext(testBextFields{
+		originator:    "Studio 4",
+		date:          "2024:05:01",
+		clock:         "06-30-00",
+		timeReference: 48000 * 3600 * 6,
+		version:       2,
+		umid:          testUMID,
+		loudness:      -2310,
+		codingHistory: "A=PCM,F=48000,W=24,M=stereo,T=Studio 4 desk, take 2\r\n",
+	})
+	path := filepath.Join(t.TempDir(), "bulletin.wav")
+	file := testWAV(testFmtChunk(2), testRIFFChunk("bext", bext), testRIFFChunk("iXML", []byte("<BWFXML/>")), testRIFFChunk("data", make([]byte, 600)))
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	analysis, err := NewBWFAnalyzer(zerolog.Nop()).AnalyzeBWF(path)
+	if err != nil {
+		t.Fatalf("AnalyzeBWF() error = %v", err)
+	}
+	if !analysis.IsValid || len(analysis.Issues) > 0 || len(analysis.Warnings) > 0 {
+		t.Errorf("valid = %v, issues = %v, warnings = %v", analysis.IsValid, analysis.Issues, analysis.Warnings)
+	}
+	if !analysis.HasBext || !analysis.HasIXML || analysis.SampleRate != 48000 || analysis.Channels != 2 || analysis.BitsPerSample != 24 {
+		t.Errorf("analysis = %+v", analysis)
+	}
+
+	b := analysis.Bext
+	if b.Originator != "Studio 4" || b.OriginatorReference != "REF-0001" || b.Description != "Morning news bulletin" || b.Version != 2 {
+		t.Errorf("bext = %+v", b)
+	}
+	if b.TimeReferenceSeconds != 21600 || b.TimeReferenceTimecode != "06:00:00.000" {
+		t.Errorf("time reference = %v, %q", b.TimeReferenceSeconds, b.TimeReferenceTimecode)
+	}
+	if b.UMID != strings.ToUpper("060a2b340101010501010d13"+strings.Repeat("42", 20)) {
+		t.Errorf("UMID = %s", b.UMID)
+	}
+	if b.LoudnessValue == nil || *b.LoudnessValue != -23.1 || b.LoudnessRange != nil {
+		t.Errorf("loudness = %v, range = %v", b.LoudnessValue, b.LoudnessRange)
+	}
+	if len(b.CodingHistory) != 1 || b.CodingHistory[0].Text != "Studio 4 desk, take 2" || b.CodingHistory[0].SampleRate != 48000 {
+		t.Errorf("coding history = %+v", b.CodingHistory)
+	}
+
+	analysis.CheckLoudness(&LoudnessAnalysis{IntegratedLoudness: -23.4})
+	if analysis.LoudnessCheck == nil || !analysis.LoudnessCheck.Matches || analysis.LoudnessCheck.Difference != -0.3 {
+		t.Errorf("loudness check = %+v", analysis.LoudnessCheck)
+	}
+}
+
+func TestParseBWF_Validation(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     []byte
+		issues   []string
+		warnings []string
+	}{
+		{
+			name:   "no bext",
+			file:   testWAV(testFmtChunk(2), testRIFFChunk("data", make([]byte, 12))),
+			issues: []string{"No bext chunk"},
+		},
+		{
+			name: "invalid date and time reference",
+			file: testWAV(testFmtChunk(2), testRIFFChunk("bext", testBext(testBextFields{
+				originator: "Studio 4", date: "2024-13-01", clock: "06:30:00",
+				timeReference: 48000 * 86400, codingHistory: "A=PCM,F=48000,W=24,M=stereo\r\n",
+			}))),
+			issues: []string{"Origination date \"2024-13-01\"", "past the 24 hours"},
+		},
+		{
+			name: "coding history disagrees with the format",
+			file: testWAV(testFmtChunk(1), testRIFFChunk("bext", testBext(testBextFields{
+				date: "2024-05-01", clock: "06:30:00", version: 1,
+				codingHistory: "A=PCM,F=44100,W=16,M=stereo,T=CD\r\nA=PCM,F=44100,W=16,M=stereo\r\n",
+			}))),
+			issues:   []string{"ends at F=44100", "ends at W=16", "ends at M=stereo"},
+			warnings: []string{"Originator is empty", "No UMID"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis, err := parseBWF(bytes.NewReader(tt.file), int64(len(tt.file)))
+			if err != nil {
+				t.Fatalf("parseBWF() error = %v", err)
+			}
+			if analysis.IsValid {
+				t.Error("IsValid = true, want false")
+			}
+			for i, want := range tt.issues {
+				if i >= len(analysis.Issues) || !strings.Contains(analysis.Issues[i], want) {
+					t.Errorf("Issues = %q, want %q at %d", analysis.Issues, want, i)
+				}
+			}
+			for i, want := range tt.warnings {
+				if i >= len(analysis.Warnings) || !strings.Contains(analysis.Warnings[i], want) {
+					t.Errorf("Warnings = %q, want %q at %d", analysis.Warnings, want, i)
+				}
+			}
+		})
+	}
+}
+
+func TestParseBWF_NotWave(t *testing.T) {
+	data := []byte("FORM\x00\x00\x00\x04AIFF")
+	if analysis, err := parseBWF(bytes.NewReader(data), int64(len(data))); analysis != nil || err != nil {
+		t.Errorf("parseBWF() = %v, %v, want nil", analysis, err)
+	}
+}
+
+func TestParseBextDateTime(t *testing.T) {
+	tests := []struct {
+		value, layout string
+		want          bool
+	}{
+		{"2024-05-01", "2006-01-02", true},
+		{"2024:05:01", "2006-01-02", true},
+		{"2024_05_01", "2006-01-02", true},
+		{"2024-02-30", "2006-01-02", false},
+		{"24-05-01", "2006-01-02", false},
+		{"23.59.59", "15:04:05", true},
+		{"24:00:00", "15:04:05", false},
+		{"063000", "15:04:05", false},
+	}
+	for _, tt := range tests {
+		if got := parseBextDateTime(tt.value, tt.layout); got != tt.want {
+			t.Errorf("parseBextDateTime(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
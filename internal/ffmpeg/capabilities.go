@@ -0,0 +1,120 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// Capabilities describes what an ffmpeg build can decode, encode and
+// filter, as detected from ffmpeg -codecs and -filters
+type Capabilities struct {
+	Version string            `json:"version"`
+	Codecs  []CodecCapability `json:"codecs"`
+	Filters []string          `json:"filters"`
+}
+
+// CodecCapability is one codec of ffmpeg -codecs
+type CodecCapability struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"` // video, audio, subtitle, data or attachment
+	Decode bool   `json:"decode"`
+	Encode bool   `json:"encode"`
+}
+
+// codecTypes maps the third flag of an ffmpeg -codecs line to a codec type
+var codecTypes = map[byte]string{
+	'V': "video",
+	'A': "audio",
+	'S': "subtitle",
+	'D': "data",
+	'T': "attachment",
+}
+
+// DetectCapabilities runs ffmpeg at binaryPath to list its version, codecs
+// and filters. Like BinaryVersion it runs outside the process pool.
+func DetectCapabilities(ctx context.Context, binaryPath string) (*Capabilities, error) {
+	version, err := BinaryVersion(ctx, binaryPath)
+	if err != nil {
+		return nil, err
+	}
+
+	codecs, err := exec.CommandContext(ctx, binaryPath, "-hide_banner", "-codecs").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ffmpeg codecs: %w", err)
+	}
+	filters, err := exec.CommandContext(ctx, binaryPath, "-hide_banner", "-filters").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ffmpeg filters: %w", err)
+	}
+
+	return &Capabilities{
+		Version: version,
+		Codecs:  parseCodecs(string(codecs)),
+		Filters: parseFilters(string(filters)),
+	}, nil
+}
+
+// HasFilter reports whether the build includes the named filter
+func (c *Capabilities) HasFilter(name string) bool {
+	i := sort.SearchStrings(c.Filters, name)
+	return i < len(c.Filters) && c.Filters[i] == name
+}
+
+// parseCodecs reads the codec table of ffmpeg -codecs, which follows a
+// legend ending in a line of dashes:
+//
+//	DEV.LS h264    H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10
+func parseCodecs(output string) []CodecCapability {
+	codecs := []CodecCapability{}
+	inTable := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if !inTable {
+			inTable = strings.HasPrefix(fields[0], "---")
+			continue
+		}
+
+		flags := fields[0]
+		if len(fields) < 2 || len(flags) < 3 {
+			continue
+		}
+		codecType, ok := codecTypes[flags[2]]
+		if !ok {
+			continue
+		}
+		codecs = append(codecs, CodecCapability{
+			Name:   fields[1],
+			Type:   codecType,
+			Decode: flags[0] == 'D',
+			Encode: flags[1] == 'E',
+		})
+	}
+	sort.Slice(codecs, func(i, j int) bool { return codecs[i].Name < codecs[j].Name })
+	return codecs
+}
+
+// parseFilters reads the filter names of ffmpeg -filters, whose lines give
+// flags, name and input/output pads:
+//
+//	T.C ebur128    V->A     EBU R128 scanner.
+func parseFilters(output string) []string {
+	filters := []string{}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || !strings.Contains(fields[2], "->") {
+			continue
+		}
+		filters = append(filters, fields[1])
+	}
+	sort.Strings(filters)
+	return filters
+}
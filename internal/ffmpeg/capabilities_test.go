@@ -0,0 +1,89 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testCodecsOutput = `Codecs:
+ D..... = Decoding supported
+ .E.... = Encoding supported
+ ..V... = Video codec
+ ..A... = Audio codec
+ ..S... = Subtitle codec
+ ..D... = Data codec
+ ..T... = Attachment codec
+ ...I.. = Intra frame-only codec
+ ....L. = Lossy compression
+ .....S = Lossless compression
+ -------
+ DEV.LS h264                 H.264 / AVC / MPEG-4 AVC / MPEG-4 part 10 (encoders: libx264 )
+ DEAIL. aac                  AAC (Advanced Audio Coding)
+ D.VI.S prores               Apple ProRes (iCodec Pro)
+ DES... subrip               SubRip subtitle
+ D.D... klv                  SMPTE 336M Key-Length-Value (KLV) metadata
+ ..T... ttf                  TrueType font
+`
+
+const testFiltersOutput = `Filters:
+  T.. = Timeline support
+  .S. = Slice threading
+  ..C = Command support
+  A = Audio input/output
+  V = Video input/output
+  N = Dynamic number and/or type of input/output
+  | = Source or sink filter
+ TSC signalstats       V->V       Generate statistics from video analysis.
+ ... ebur128           A->N       EBU R128 scanner.
+ ... libvmaf           VV->V      Calculate the VMAF between two video streams.
+ ... anullsrc          |->A       Null audio source, return empty audio frames.
+`
+
+func TestParseCodecs(t *testing.T) {
+	got := parseCodecs(testCodecsOutput)
+	want := []CodecCapability{
+		{Name: "aac", Type: "audio", Decode: true, Encode: true},
+		{Name: "h264", Type: "video", Decode: true, Encode: true},
+		{Name: "klv", Type: "data", Decode: true},
+		{Name: "prores", Type: "video", Decode: true},
+		{Name: "subrip", Type: "subtitle", Decode: true, Encode: true},
+		{Name: "ttf", Type: "attachment"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseCodecs() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseFilters(t *testing.T) {
+	got := parseFilters(testFiltersOutput)
+	want := []string{"anullsrc", "ebur128", "libvmaf", "signalstats"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseFilters() = %v, want %v", got, want)
+	}
+
+	capabilities := &Capabilities{Filters: got}
+	if !capabilities.HasFilter("libvmaf") || capabilities.HasFilter("vmafmotion") {
+		t.Error("HasFilter() should only report listed filters")
+	}
+}
+
+func TestDetectCapabilities(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "ffmpeg")
+	content := "#!/bin/sh\ncase \"$2\" in\n" +
+		"-codecs) cat <<'EOF'\n" + testCodecsOutput + "EOF\n;;\n" +
+		"-filters) cat <<'EOF'\n" + testFiltersOutput + "EOF\n;;\n" +
+		"*) echo 'ffmpeg version 7.0.2 Copyright (c) 2000-2024 the FFmpeg developers' ;;\nesac\n"
+	if err := os.WriteFile(script, []byte(content), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	capabilities, err := DetectCapabilities(t.Context(), script)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if capabilities.Version != "7.0.2" || len(capabilities.Codecs) != 6 || len(capabilities.Filters) != 4 {
+		t.Errorf("DetectCapabilities() = %+v", capabilities)
+	}
+}
@@ -0,0 +1,199 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Zero crossing rates, per sample, that tell LFE content from full-range
+// audio: content below 120 Hz crosses zero fewer than 0.005 times a sample
+// at 48 kHz, while speech and music cross it several times as often
+const (
+	lfeZeroCrossingRate      = 0.01
+	fullBandZeroCrossingRate = 0.02
+)
+
+// Levels, in dB, of the channel statistics: channels whose RMS is below the
+// silence threshold are silent and those peaking above it active
+const (
+	channelStatsFloor         = -96.0
+	channelSilenceThresholdDB = -60.0
+)
+
+// parseChannelStats reads the per-channel statistics astats prints at the
+// end of a pass, e.g.
+//
+//	[Parsed_astats_0 @ 0x5581] Channel: 1
+//	[Parsed_astats_0 @ 0x5581] RMS level dB: -23.512
+//
+// astats numbers channels from 1 and ends with an Overall block, which is
+// not a channel.
+func parseChannelStats(output []byte, layout string) []ChannelDetail {
+	var details []ChannelDetail
+	var current *ChannelDetail
+	forEachLine(output, func(line string) bool {
+		if !strings.Contains(line, "astats") {
+			return true
+		}
+		line = strings.TrimSpace(line[strings.Index(line, "]")+1:])
+		name, value, _ := strings.Cut(line, ":")
+		switch name {
+		case "Channel":
+			channel, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				current = nil
+				return true
+			}
+			details = append(details, ChannelDetail{
+				Index:     channel - 1,
+				Name:      getChannelName(channel-1, layout),
+				PeakLevel: channelStatsFloor,
+				RMSLevel:  channelStatsFloor,
+			})
+			current = &details[len(details)-1]
+		case "Overall":
+			current = nil
+		case "Peak level dB", "RMS level dB", "Zero crossings rate":
+			if current == nil {
+				return true
+			}
+			v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return true
+			}
+			// Digital silence is -inf dB, which JSON cannot carry, so
+			// levels stop at the floor
+			switch name {
+			case "Peak level dB":
+				current.PeakLevel = math.Max(v, channelStatsFloor)
+			case "RMS level dB":
+				current.RMSLevel = math.Max(v, channelStatsFloor)
+			default:
+				current.ZeroCrossingRate = v
+			}
+		}
+		return true
+	})
+
+	for i := range details {
+		details[i].IsSilent = details[i].RMSLevel < channelSilenceThresholdDB
+		details[i].IsActive = details[i].PeakLevel > channelSilenceThresholdDB
+	}
+	return details
+}
+
+// checkChannelRemap looks for channels of the mapped audio that are silent
+// or swapped and suggests the filter that corrects the layout: a channelmap
+// putting swapped channels back in place, or a pan down to the layout of
+// the channels that carry audio. A silent LFE is common and left alone.
+func checkChannelRemap(analysis *ChannelMappingAnalysis) {
+	names := audioChannelNames(analysis.ChannelLayout, analysis.TotalChannels)
+	details := analysis.ChannelDetails
+	if len(details) != len(names) || len(names) == 0 {
+		return
+	}
+	for i, detail := range details {
+		if detail.IsSilent && names[i] != "LFE" {
+			analysis.SilentChannels = append(analysis.SilentChannels, i)
+		}
+	}
+
+	if swap, ok := findLFESwap(details, names); ok {
+		analysis.SwappedChannels = append(analysis.SwappedChannels, swap)
+		analysis.LayoutIssues = append(analysis.LayoutIssues, swap.Reason)
+		analysis.IsValid = false
+
+		order := make([]string, len(names))
+		for i := range order {
+			order[i] = strconv.Itoa(i)
+		}
+		order[swap.Channels[0]], order[swap.Channels[1]] = order[swap.Channels[1]], order[swap.Channels[0]]
+		analysis.SuggestedRemap = fmt.Sprintf("channelmap=map=%s:channel_layout=%s", strings.Join(order, "|"), analysis.ChannelLayout)
+		return
+	}
+
+	if len(analysis.SilentChannels) > 0 {
+		analysis.SuggestedRemap = silentChannelRemap(details, names)
+	}
+}
+
+// findLFESwap finds an LFE channel carrying full-range audio while another
+// channel carries only low frequencies, the mark of channels written in
+// the wrong order, e.g. C and LFE exchanged
+func findLFESwap(details []ChannelDetail, names []string) (ChannelSwap, bool) {
+	lfe := -1
+	for i, name := range names {
+		if name == "LFE" {
+			lfe = i
+		}
+	}
+	if lfe < 0 || !details[lfe].IsActive || details[lfe].ZeroCrossingRate <= fullBandZeroCrossingRate {
+		return ChannelSwap{}, false
+	}
+
+	low := -1
+	for i, detail := range details {
+		if i == lfe || !detail.IsActive || detail.ZeroCrossingRate >= lfeZeroCrossingRate {
+			continue
+		}
+		if low < 0 || detail.ZeroCrossingRate < details[low].ZeroCrossingRate {
+			low = i
+		}
+	}
+	if low < 0 {
+		return ChannelSwap{}, false
+	}
+	return ChannelSwap{
+		Channels: [2]int{low, lfe},
+		Names:    [2]string{names[low], names[lfe]},
+		Reason: fmt.Sprintf("Channel %d (%s) carries only low frequencies while channel %d (LFE) carries full-range audio; the two look swapped",
+			low, names[low], lfe),
+	}, true
+}
+
+// silentChannelRemap pans the channels that carry audio to the layout made
+// of exactly those channels, or duplicates the one live side of a stereo
+// pair as dual mono. It returns "" when no layout fits.
+func silentChannelRemap(details []ChannelDetail, names []string) string {
+	live := make(map[string]int)
+	for i, detail := range details {
+		if !detail.IsSilent {
+			live[names[i]] = i
+		}
+	}
+	if len(live) == 0 {
+		return ""
+	}
+	if len(names) == 2 && len(live) == 1 {
+		for _, i := range live {
+			return fmt.Sprintf("pan=stereo|c0=c%d|c1=c%d", i, i)
+		}
+	}
+
+	layouts := make([]string, 0, len(audioLayoutChannels))
+	for layout := range audioLayoutChannels {
+		layouts = append(layouts, layout)
+	}
+	sort.Strings(layouts)
+	for _, layout := range layouts {
+		channels := audioLayoutChannels[layout]
+		if len(channels) != len(live) || len(channels) == len(names) {
+			continue
+		}
+		pan := []string{"pan=" + layout}
+		for j, name := range channels {
+			i, ok := live[name]
+			if !ok {
+				break
+			}
+			pan = append(pan, fmt.Sprintf("c%d=c%d", j, i))
+		}
+		if len(pan) == len(channels)+1 {
+			return strings.Join(pan, "|")
+		}
+	}
+	return ""
+}
@@ -0,0 +1,171 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// astatsOutput builds astats output for channels given as RMS level and
+// zero crossing rate pairs, followed by the Overall block
+func astatsOutput(channels ...[2]float64) string {
+	var b strings.Builder
+	for i, channel := range channels {
+		fmt.Fprintf(&b, "[Parsed_astats_0 @ 0x5581] Channel: %d\n", i+1)
+		if channel[0] < channelSilenceThresholdDB {
+			b.WriteString("[Parsed_astats_0 @ 0x5581] Peak level dB: -inf\n[Parsed_astats_0 @ 0x5581] RMS level dB: -inf\n")
+		} else {
+			fmt.Fprintf(&b, "[Parsed_astats_0 @ 0x5581] Peak level dB: %f\n", channel[0]+12)
+			fmt.Fprintf(&b, "[Parsed_astats_0 @ 0x5581] RMS level dB: %f\n", channel[0])
+		}
+		fmt.Fprintf(&b, "[Parsed_astats_0 @ 0x5581] Zero crossings rate: %f\n", channel[1])
+	}
+	b.WriteString("[Parsed_astats_0 @ 0x5581] Overall\n[Parsed_astats_0 @ 0x5581] Peak level dB: -1.000000\n[Parsed_astats_0 @ 0x5581] RMS level dB: -20.000000\n")
+	return b.String()
+}
+
+var silentChannel = [2]float64{-200, 0}
+
+func TestParseChannelStats(t *testing.T) {
+	details := parseChannelStats([]byte(astatsOutput([2]float64{-20, 0.05}, silentChannel)), "stereo")
+	want := []ChannelDetail{
+		{Index: 0, Name: "Left", PeakLevel: -8, RMSLevel: -20, ZeroCrossingRate: 0.05, IsActive: true},
+		{Index: 1, Name: "Right", PeakLevel: channelStatsFloor, RMSLevel: channelStatsFloor, IsSilent: true},
+	}
+	if !reflect.DeepEqual(details, want) {
+		t.Errorf("got %+v, want %+v", details, want)
+	}
+}
+
+func TestCheckChannelRemap(t *testing.T) {
+	speech, music, lfe := [2]float64{-22, 0.06}, [2]float64{-24, 0.04}, [2]float64{-30, 0.003}
+	tests := []struct {
+		name        string
+		layout      string
+		channels    [][2]float64
+		wantSilent  []int
+		wantSwapped [][2]int
+		wantRemap   string
+	}{
+		{
+			name:     "correct 5.1",
+			layout:   "5.1",
+			channels: [][2]float64{music, music, speech, lfe, music, music},
+		},
+		{
+			name:        "center and LFE swapped",
+			layout:      "5.1",
+			channels:    [][2]float64{music, music, lfe, speech, music, music},
+			wantSwapped: [][2]int{{2, 3}},
+			wantRemap:   "channelmap=map=0|1|3|2|4|5:channel_layout=5.1",
+		},
+		{
+			name:     "silent LFE",
+			layout:   "5.1",
+			channels: [][2]float64{music, music, speech, silentChannel, music, music},
+		},
+		{
+			name:       "stereo in 5.1",
+			layout:     "5.1",
+			channels:   [][2]float64{music, music, silentChannel, silentChannel, silentChannel, silentChannel},
+			wantSilent: []int{2, 4, 5},
+			wantRemap:  "pan=stereo|c0=c0|c1=c1",
+		},
+		{
+			name:       "LCR with LFE in 5.1",
+			layout:     "5.1",
+			channels:   [][2]float64{music, music, speech, lfe, silentChannel, silentChannel},
+			wantSilent: []int{4, 5},
+			wantRemap:  "pan=3.1|c0=c0|c1=c1|c2=c2|c3=c3",
+		},
+		{
+			name:       "stereo with a silent left",
+			layout:     "stereo",
+			channels:   [][2]float64{silentChannel, speech},
+			wantSilent: []int{0},
+			wantRemap:  "pan=stereo|c0=c1|c1=c1",
+		},
+		{
+			name:       "silent surrounds without a matching layout",
+			layout:     "5.1",
+			channels:   [][2]float64{music, silentChannel, speech, lfe, music, silentChannel},
+			wantSilent: []int{1, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := &ChannelMappingAnalysis{
+				TotalChannels:  len(tt.channels),
+				ChannelLayout:  tt.layout,
+				IsValid:        true,
+				ChannelDetails: parseChannelStats([]byte(astatsOutput(tt.channels...)), tt.layout),
+			}
+			checkChannelRemap(analysis)
+			if !reflect.DeepEqual(analysis.SilentChannels, tt.wantSilent) {
+				t.Errorf("silent channels %v, want %v", analysis.SilentChannels, tt.wantSilent)
+			}
+			var swapped [][2]int
+			for _, swap := range analysis.SwappedChannels {
+				swapped = append(swapped, swap.Channels)
+			}
+			if !reflect.DeepEqual(swapped, tt.wantSwapped) || analysis.IsValid != (tt.wantSwapped == nil) {
+				t.Errorf("swapped channels %v (valid %v), want %v", swapped, analysis.IsValid, tt.wantSwapped)
+			}
+			if analysis.SuggestedRemap != tt.wantRemap {
+				t.Errorf("remap %q, want %q", analysis.SuggestedRemap, tt.wantRemap)
+			}
+		})
+	}
+}
+
+func TestAnalyzeChannelMapping_Swap(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "astats")
+	header := "  Stream #0:1[0x2](und): Audio: aac (LC), 48000 Hz, 5.1, fltp, 384 kb/s\n"
+	music, lfe, speech := [2]float64{-24, 0.04}, [2]float64{-30, 0.003}, [2]float64{-22, 0.06}
+	if err := os.WriteFile(output, []byte(header+astatsOutput(music, music, lfe, speech, music, music)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat "+output+" >&2\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	ca := NewContentAnalyzer(script, zerolog.Nop())
+	analysis, err := ca.analyzeChannelMapping(t.Context(), filepath.Join(dir, "in.mp4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(analysis.ChannelDetails) != 6 || analysis.ChannelDetails[3].Name != "LFE" {
+		t.Fatalf("expected six channels named by the 5.1 layout, got %+v", analysis.ChannelDetails)
+	}
+	if analysis.IsValid || analysis.SuggestedRemap != "channelmap=map=0|1|3|2|4|5:channel_layout=5.1" {
+		t.Errorf("expected the center and LFE swap to be corrected, got %+v", analysis)
+	}
+}
+
+func TestSuggestRepairs_ChannelMapping(t *testing.T) {
+	result := &FFprobeResult{
+		Streams: []StreamInfo{
+			{Index: 0, CodecType: "video", CodecName: "h264"},
+			{Index: 1, CodecType: "audio", CodecName: "aac", Channels: 2},
+			{Index: 2, CodecType: "audio", CodecName: "ac3", Channels: 6},
+		},
+		EnhancedAnalysis: &EnhancedAnalysis{ContentAnalysis: &ContentAnalysis{ChannelMappingInfo: &ChannelMappingAnalysis{
+			SuggestedRemap: "channelmap=map=0|1|3|2|4|5:channel_layout=5.1",
+		}}},
+	}
+	suggestions := SuggestRepairs(result, "input.mkv")
+	if len(suggestions) != 1 || suggestions[0].Issue != RepairChannelMapping || suggestions[0].Lossless {
+		t.Fatalf("expected an audio re-encode remapping channels, got %+v", suggestions)
+	}
+	want := "ffmpeg -i input.mkv -map 0 -c copy -filter:a:1 'channelmap=map=0|1|3|2|4|5:channel_layout=5.1' -c:a:1 ac3 repaired.mkv"
+	if suggestions[0].Command != want {
+		t.Errorf("command = %q, want %q", suggestions[0].Command, want)
+	}
+}
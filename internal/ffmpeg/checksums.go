@@ -0,0 +1,314 @@
+package ffmpeg
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/rs/zerolog"
+)
+
+// Checksum algorithms
+const (
+	ChecksumMD5    = "md5"
+	ChecksumSHA256 = "sha256"
+	ChecksumXXH64  = "xxh64"
+)
+
+// ChecksumAlgorithms lists the supported checksum algorithms
+var ChecksumAlgorithms = []string{ChecksumMD5, ChecksumSHA256, ChecksumXXH64}
+
+// Checksum match statuses
+const (
+	ChecksumMatch    = "match"
+	ChecksumMismatch = "mismatch"
+	ChecksumMissing  = "missing" // The analysis has no checksum to compare with
+)
+
+// maxChecksumStreams bounds the streams hashed in one pass, each of which
+// needs its own pipe
+const maxChecksumStreams = 64
+
+// ParseChecksumAlgorithms parses a comma-separated list of checksum
+// algorithms; an empty list selects none
+func ParseChecksumAlgorithms(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	return NormalizeChecksumAlgorithms(strings.Split(value, ","))
+}
+
+// NormalizeChecksumAlgorithms validates checksum algorithm names, dropping
+// duplicates
+func NormalizeChecksumAlgorithms(names []string) ([]string, error) {
+	var algorithms []string
+	for _, name := range names {
+		algorithm := strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(ChecksumAlgorithms, algorithm) {
+			return nil, fmt.Errorf("unsupported checksum algorithm %q (use %s)", name, strings.Join(ChecksumAlgorithms, ", "))
+		}
+		if !slices.Contains(algorithms, algorithm) {
+			algorithms = append(algorithms, algorithm)
+		}
+	}
+	return algorithms, nil
+}
+
+// Checksums are the hex checksums of a file and of the essence of each of
+// its streams, by algorithm
+type Checksums struct {
+	File    map[string]string `json:"file"`
+	Streams []StreamChecksums `json:"streams,omitempty"`
+}
+
+// StreamChecksums are the checksums of one stream's packet payloads, as
+// written by ffmpeg -c copy -f data. They do not change when the stream is
+// remuxed into another container.
+type StreamChecksums struct {
+	Index     int               `json:"index"`
+	CodecType string            `json:"codec_type"`
+	Bytes     int64             `json:"bytes"`
+	Checksums map[string]string `json:"checksums"`
+}
+
+// ChecksumCalculator hashes files and their stream essence
+type ChecksumCalculator struct {
+	ffmpegPath string
+	logger     zerolog.Logger
+}
+
+// NewChecksumCalculator creates a new checksum calculator
+func NewChecksumCalculator(ffmpegPath string, logger zerolog.Logger) *ChecksumCalculator {
+	return &ChecksumCalculator{
+		ffmpegPath: ffmpegPath,
+		logger:     logger,
+	}
+}
+
+// Compute hashes the file and every stream in streams with each algorithm
+func (cc *ChecksumCalculator) Compute(ctx context.Context, filePath string, streams []StreamInfo, algorithms []string) (*Checksums, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	hashers := newChecksumHashers(algorithms)
+	if _, err := io.Copy(hashers, file); err != nil {
+		return nil, fmt.Errorf("failed to hash file: %w", err)
+	}
+	checksums := &Checksums{File: hashers.sums()}
+
+	if checksums.Streams, err = cc.streamChecksums(ctx, filePath, streams, algorithms); err != nil {
+		return nil, err
+	}
+	return checksums, nil
+}
+
+// streamChecksums copies the packets of every stream to its own pipe in a
+// single ffmpeg pass and hashes each pipe as it is written
+func (cc *ChecksumCalculator) streamChecksums(ctx context.Context, filePath string, streams []StreamInfo, algorithms []string) ([]StreamChecksums, error) {
+	var selected []StreamInfo
+	for _, stream := range streams {
+		// Attachments carry no packets
+		if strings.ToLower(stream.CodecType) != "attachment" {
+			selected = append(selected, stream)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, nil
+	}
+	if len(selected) > maxChecksumStreams {
+		return nil, fmt.Errorf("too many streams to hash: %d (max %d)", len(selected), maxChecksumStreams)
+	}
+
+	args := []string{"-hide_banner", "-nostdin", "-v", "error", "-i", filePath}
+	readers := make([]*os.File, 0, len(selected))
+	writers := make([]*os.File, 0, len(selected))
+	defer func() {
+		for _, reader := range readers {
+			reader.Close()
+		}
+	}()
+	for i, stream := range selected {
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			for _, writer := range writers {
+				writer.Close()
+			}
+			return nil, fmt.Errorf("failed to create pipe: %w", err)
+		}
+		readers = append(readers, reader)
+		writers = append(writers, writer)
+		// ExtraFiles become descriptors 3, 4, ... in ffmpeg
+		args = append(args, "-map", fmt.Sprintf("0:%d", stream.Index), "-c", "copy", "-f", "data", fmt.Sprintf("pipe:%d", 3+i))
+	}
+
+	results := make([]StreamChecksums, len(selected))
+	var wg sync.WaitGroup
+	for i, stream := range selected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hashers := newChecksumHashers(algorithms)
+			n, _ := io.Copy(hashers, readers[i])
+			results[i] = StreamChecksums{
+				Index:     stream.Index,
+				CodecType: strings.ToLower(stream.CodecType),
+				Bytes:     n,
+				Checksums: hashers.sums(),
+			}
+		}()
+	}
+
+	cmd := exec.CommandContext(ctx, cc.ffmpegPath, args...)
+	cmd.ExtraFiles = writers
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	err := runCommand(ctx, cmd)
+	// The readers see the end of their pipe once ffmpeg and this process
+	// have both closed the write ends
+	for _, writer := range writers {
+		writer.Close()
+	}
+	wg.Wait()
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash streams: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return results, nil
+}
+
+// checksumHashers writes to one hash per algorithm
+type checksumHashers struct {
+	algorithms []string
+	hashes     []hash.Hash
+	io.Writer
+}
+
+func newChecksumHashers(algorithms []string) *checksumHashers {
+	h := &checksumHashers{algorithms: algorithms}
+	writers := make([]io.Writer, len(algorithms))
+	for i, algorithm := range algorithms {
+		switch algorithm {
+		case ChecksumMD5:
+			h.hashes = append(h.hashes, md5.New())
+		case ChecksumSHA256:
+			h.hashes = append(h.hashes, sha256.New())
+		default:
+			h.hashes = append(h.hashes, xxhash.New())
+		}
+		writers[i] = h.hashes[i]
+	}
+	h.Writer = io.MultiWriter(writers...)
+	return h
+}
+
+// sums returns the hex checksums by algorithm
+func (h *checksumHashers) sums() map[string]string {
+	sums := make(map[string]string, len(h.algorithms))
+	for i, algorithm := range h.algorithms {
+		sums[algorithm] = hex.EncodeToString(h.hashes[i].Sum(nil))
+	}
+	return sums
+}
+
+// ChecksumManifest lists expected hex checksums by algorithm for the file
+// and for streams by index
+type ChecksumManifest struct {
+	File    map[string]string         `json:"file,omitempty"`
+	Streams map[int]map[string]string `json:"streams,omitempty"`
+}
+
+// ChecksumVerification compares a manifest with computed checksums
+type ChecksumVerification struct {
+	Verified   bool             `json:"verified"` // Every expected checksum matched
+	Matched    int              `json:"matched"`
+	Mismatched int              `json:"mismatched"`
+	Missing    int              `json:"missing"`
+	Results    []ChecksumResult `json:"results"`
+}
+
+// ChecksumResult is the comparison of one expected checksum
+type ChecksumResult struct {
+	Stream    *int   `json:"stream,omitempty"` // Omitted for the whole file
+	Algorithm string `json:"algorithm"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual,omitempty"`
+	Status    string `json:"status"`
+}
+
+// VerifyChecksums compares every checksum in manifest with checksums, which
+// may be nil. Hex digits are compared case-insensitively. An empty manifest
+// is not verified.
+func VerifyChecksums(checksums *Checksums, manifest *ChecksumManifest) *ChecksumVerification {
+	verification := &ChecksumVerification{Results: []ChecksumResult{}}
+	if checksums == nil {
+		checksums = &Checksums{}
+	}
+
+	compare := func(stream *int, expected, actual map[string]string) {
+		for _, algorithm := range sortedKeys(expected) {
+			result := ChecksumResult{
+				Stream:    stream,
+				Algorithm: strings.ToLower(algorithm),
+				Expected:  strings.ToLower(strings.TrimSpace(expected[algorithm])),
+				Status:    ChecksumMissing,
+			}
+			if value, ok := actual[result.Algorithm]; ok {
+				result.Actual = value
+				result.Status = ChecksumMismatch
+				if value == result.Expected {
+					result.Status = ChecksumMatch
+				}
+			}
+			switch result.Status {
+			case ChecksumMatch:
+				verification.Matched++
+			case ChecksumMismatch:
+				verification.Mismatched++
+			default:
+				verification.Missing++
+			}
+			verification.Results = append(verification.Results, result)
+		}
+	}
+
+	compare(nil, manifest.File, checksums.File)
+	indexes := make([]int, 0, len(manifest.Streams))
+	for index := range manifest.Streams {
+		indexes = append(indexes, index)
+	}
+	slices.Sort(indexes)
+	for _, index := range indexes {
+		var actual map[string]string
+		for _, stream := range checksums.Streams {
+			if stream.Index == index {
+				actual = stream.Checksums
+			}
+		}
+		compare(&index, manifest.Streams[index], actual)
+	}
+
+	verification.Verified = len(verification.Results) > 0 && verification.Matched == len(verification.Results)
+	return verification
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
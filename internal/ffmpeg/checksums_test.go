@@ -0,0 +1,106 @@
+package ffmpeg
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// Checksums of "abc"
+const (
+	abcMD5    = "900150983cd24fb0d6963f7d28e17f72"
+	abcSHA256 = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+	abcXXH64  = "44bc2cf5ad770999"
+)
+
+func TestParseChecksumAlgorithms(t *testing.T) {
+	algorithms, err := ParseChecksumAlgorithms(" MD5,xxh64,md5 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(algorithms) != 2 || algorithms[0] != ChecksumMD5 || algorithms[1] != ChecksumXXH64 {
+		t.Errorf("unexpected algorithms %v", algorithms)
+	}
+
+	if algorithms, err := ParseChecksumAlgorithms(""); err != nil || algorithms != nil {
+		t.Errorf("expected no algorithms, got %v, %v", algorithms, err)
+	}
+	if _, err := ParseChecksumAlgorithms("md5,crc32"); err == nil {
+		t.Error("expected an error for an unsupported algorithm")
+	}
+}
+
+func TestChecksumCalculator_Compute(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	dir := t.TempDir()
+	input := filepath.Join(dir, "video.mp4")
+	if err := os.WriteFile(input, []byte("abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Stands in for ffmpeg writing each mapped stream to its pipe
+	script := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nprintf abc >&3\nprintf abcabc >&4\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	streams := []StreamInfo{
+		{Index: 0, CodecType: "video"},
+		{Index: 1, CodecType: "attachment"},
+		{Index: 2, CodecType: "audio"},
+	}
+	checksums, err := NewChecksumCalculator(script, zerolog.Nop()).Compute(t.Context(), input, streams, ChecksumAlgorithms)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if checksums.File[ChecksumMD5] != abcMD5 || checksums.File[ChecksumSHA256] != abcSHA256 || checksums.File[ChecksumXXH64] != abcXXH64 {
+		t.Errorf("unexpected file checksums %v", checksums.File)
+	}
+	if len(checksums.Streams) != 2 {
+		t.Fatalf("expected 2 hashed streams, got %+v", checksums.Streams)
+	}
+	video, audio := checksums.Streams[0], checksums.Streams[1]
+	if video.Index != 0 || video.Bytes != 3 || video.Checksums[ChecksumSHA256] != abcSHA256 {
+		t.Errorf("unexpected video checksums %+v", video)
+	}
+	if audio.Index != 2 || audio.CodecType != "audio" || audio.Bytes != 6 || audio.Checksums[ChecksumMD5] == abcMD5 {
+		t.Errorf("unexpected audio checksums %+v", audio)
+	}
+}
+
+func TestVerifyChecksums(t *testing.T) {
+	checksums := &Checksums{
+		File: map[string]string{ChecksumMD5: abcMD5},
+		Streams: []StreamChecksums{
+			{Index: 0, Checksums: map[string]string{ChecksumSHA256: abcSHA256}},
+		},
+	}
+
+	verification := VerifyChecksums(checksums, &ChecksumManifest{
+		File:    map[string]string{"MD5": " 900150983CD24FB0D6963F7D28E17F72 "},
+		Streams: map[int]map[string]string{0: {ChecksumSHA256: abcSHA256}},
+	})
+	if !verification.Verified || verification.Matched != 2 {
+		t.Errorf("expected the manifest to verify, got %+v", verification)
+	}
+
+	verification = VerifyChecksums(checksums, &ChecksumManifest{
+		File:    map[string]string{ChecksumMD5: abcMD5, ChecksumXXH64: abcXXH64},
+		Streams: map[int]map[string]string{0: {ChecksumSHA256: abcMD5}},
+	})
+	if verification.Verified || verification.Matched != 1 || verification.Missing != 1 || verification.Mismatched != 1 {
+		t.Errorf("unexpected verification %+v", verification)
+	}
+	if stream := verification.Results[2]; stream.Stream == nil || *stream.Stream != 0 || stream.Status != ChecksumMismatch || stream.Actual != abcSHA256 {
+		t.Errorf("unexpected stream result %+v", stream)
+	}
+
+	if VerifyChecksums(nil, &ChecksumManifest{}).Verified {
+		t.Error("an empty manifest must not verify")
+	}
+}
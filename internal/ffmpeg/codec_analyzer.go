@@ -2,16 +2,25 @@ package ffmpeg
 
 import (
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/rs/zerolog"
 )
 
 // CodecAnalyzer handles codec profile and level analysis
-type CodecAnalyzer struct{}
+type CodecAnalyzer struct {
+	ffmpegPath string
+	logger     zerolog.Logger
+}
 
 // NewCodecAnalyzer creates a new codec analyzer
-func NewCodecAnalyzer() *CodecAnalyzer {
-	return &CodecAnalyzer{}
+func NewCodecAnalyzer(ffmpegPath string, logger zerolog.Logger) *CodecAnalyzer {
+	return &CodecAnalyzer{
+		ffmpegPath: ffmpegPath,
+		logger:     logger,
+	}
 }
 
 // AnalyzeCodecs analyzes codec profiles and levels from stream information
@@ -84,6 +93,7 @@ func (ca *CodecAnalyzer) analyzeVideoCodec(stream StreamInfo) *VideoCodecInfo {
 
 	// Validate profile/level combination
 	codec.IsValid = ca.validateVideoProfileLevel(codec)
+	codec.StreamingNotes = ca.getStreamingNotes(codec)
 
 	return codec
 }
@@ -127,6 +137,8 @@ func (ca *CodecAnalyzer) getVideoCodecFamily(codecName string) string {
 		"h265":       "H.265/HEVC",
 		"hevc":       "H.265/HEVC",
 		"av1":        "AV1",
+		"vvc":        "H.266/VVC",
+		"h266":       "H.266/VVC",
 		"vp8":        "VP8",
 		"vp9":        "VP9",
 		"mpeg2video": "MPEG-2",
@@ -172,6 +184,9 @@ func (ca *CodecAnalyzer) parseVideoProfile(codecName, profile string) *ProfileIn
 	case "av1":
 		profileInfo.Description = ca.getAV1ProfileDescription(profileLower)
 		profileInfo.Capabilities = ca.getAV1ProfileCapabilities(profileLower)
+	case "vvc", "h266":
+		profileInfo.Description = ca.getVVCProfileDescription(profileLower)
+		profileInfo.Capabilities = ca.getVVCProfileCapabilities(profileLower)
 	case "vp9":
 		profileInfo.Description = ca.getVP9ProfileDescription(profileLower)
 		profileInfo.Capabilities = ca.getVP9ProfileCapabilities(profileLower)
@@ -201,6 +216,16 @@ func (ca *CodecAnalyzer) parseVideoLevel(codecName string, level int) *LevelInfo
 		levelInfo.Description = ca.getH265LevelDescription(level)
 		levelInfo.MaxResolution = ca.getH265LevelMaxResolution(level)
 		levelInfo.MaxFrameRate = ca.getH265LevelMaxFrameRate(level)
+	case "av1":
+		levelInfo.Description = fmt.Sprintf("Level %s", av1LevelName(level))
+		if limits, ok := av1Levels[level]; ok {
+			levelInfo.MaxResolution, levelInfo.MaxFrameRate = limits.resolution, limits.frameRate
+		}
+	case "vvc", "h266":
+		levelInfo.Description = fmt.Sprintf("VVC Level %s", vvcLevelName(level))
+		if limits, ok := vvcLevels[level]; ok {
+			levelInfo.MaxResolution, levelInfo.MaxFrameRate = limits.resolution, limits.frameRate
+		}
 	}
 
 	return levelInfo
@@ -278,6 +303,7 @@ func (ca *CodecAnalyzer) getCodecGeneration(codecFamily string) string {
 		"H.264/AVC":  "4th Generation",
 		"H.265/HEVC": "5th Generation",
 		"AV1":        "5th Generation",
+		"H.266/VVC":  "6th Generation",
 		"VP9":        "5th Generation",
 		"VP8":        "4th Generation",
 		"MPEG-2":     "2nd Generation",
@@ -304,6 +330,8 @@ func (ca *CodecAnalyzer) getCodecFeatures(codecFamily, profile string) []string
 		features = append(features, "CTU/CTB", "Advanced Motion Vectors", "SAO Filtering", "Tiles")
 	case "AV1":
 		features = append(features, "Film Grain", "CDEF Filtering", "Loop Restoration", "Superblocks")
+	case "H.266/VVC":
+		features = append(features, "Multi-Type Tree Partitioning", "Affine Motion", "ALF/CC-ALF Filtering", "Subpictures")
 	case "VP9":
 		features = append(features, "Tiles", "Frame Parallel Decoding", "Lossless Mode")
 	}
@@ -325,6 +353,8 @@ func (ca *CodecAnalyzer) getHardwareSupport(codecFamily, profile string) []strin
 		}
 	case "AV1":
 		support = append(support, "Limited Hardware Support", "Growing Support", "Chrome/Firefox")
+	case "H.266/VVC":
+		support = append(support, "Software Decoding (VVdeC)", "Early Hardware Support")
 	case "VP9":
 		support = append(support, "Google Ecosystem", "YouTube", "Chrome", "Android")
 	}
@@ -345,6 +375,12 @@ func (ca *CodecAnalyzer) validateVideoProfileLevel(codec *VideoCodecInfo) bool {
 		return ca.validateH264ProfileLevel(codec.Profile, codec.Level)
 	case "h265", "hevc":
 		return ca.validateH265ProfileLevel(codec.Profile, codec.Level)
+	case "av1":
+		ca.checkAV1ProfileLevel(codec)
+		return len(codec.Issues) == 0
+	case "vvc", "h266":
+		ca.checkVVCProfileLevel(codec)
+		return len(codec.Issues) == 0
 	}
 
 	return true // Assume valid for other codecs
@@ -478,6 +514,9 @@ func (ca *CodecAnalyzer) validateCodecs(analysis *CodecAnalysis) *CodecValidatio
 				fmt.Sprintf("Video stream %d has invalid codec configuration", streamIndex))
 			validation.IsValid = false
 		}
+		for _, issue := range videoCodec.Issues {
+			validation.Issues = append(validation.Issues, fmt.Sprintf("Video stream %d: %s", streamIndex, issue))
+		}
 	}
 
 	for streamIndex, audioCodec := range analysis.AudioCodecs {
@@ -512,6 +551,7 @@ func (ca *CodecAnalyzer) hasModernCodecs(videoCodecs map[int]*VideoCodecInfo, au
 	modernVideoCodecs := map[string]bool{
 		"H.265/HEVC": true,
 		"AV1":        true,
+		"H.266/VVC":  true,
 		"VP9":        true,
 	}
 
@@ -607,6 +647,112 @@ func (ca *CodecAnalyzer) getAV1ProfileCapabilities(profile string) []string {
 	return []string{"Advanced compression", "Royalty-free", "Future-proof"}
 }
 
+// checkAV1ProfileLevel checks that the level is one AV1 defines, from the
+// sequence header when it has been read
+func (ca *CodecAnalyzer) checkAV1ProfileLevel(codec *VideoCodecInfo) {
+	level := codec.Level
+	if codec.AV1 != nil {
+		level = codec.AV1.LevelIndex
+	}
+	if _, ok := av1Levels[level]; !ok && level >= 0 && level != av1LevelMax {
+		codec.addIssue("seq_level_idx %d (level %s) is not a defined AV1 level", level, av1LevelName(level))
+	}
+}
+
+// checkVVCProfileLevel checks the level and, from the SPS when it has been
+// read, that the profile is known and allows the chroma format
+func (ca *CodecAnalyzer) checkVVCProfileLevel(codec *VideoCodecInfo) {
+	level := codec.Level
+	if codec.VVC != nil {
+		level = codec.VVC.LevelIDC
+	}
+	if _, ok := vvcLevels[level]; !ok && level > 0 {
+		codec.addIssue("general_level_idc %d is not a defined VVC level", level)
+	}
+	if codec.VVC == nil {
+		return
+	}
+	profile, ok := vvcProfiles[codec.VVC.ProfileIDC]
+	if !ok {
+		codec.addIssue("general_profile_idc %d is not a VVC profile", codec.VVC.ProfileIDC)
+	} else if chroma := slices.Index(vvcChromaFormats, codec.VVC.ChromaFormat); chroma > profile.maxChromaIDC {
+		codec.addIssue("%s profile allows up to %s, the SPS signals %s", profile.name, vvcChromaFormats[profile.maxChromaIDC], codec.VVC.ChromaFormat)
+	}
+}
+
+// getStreamingNotes returns what limits where an AV1 or VVC stream plays,
+// from its sequence header or SPS when read and from ffprobe otherwise
+func (ca *CodecAnalyzer) getStreamingNotes(codec *VideoCodecInfo) []string {
+	var notes []string
+	switch codec.CodecFamily {
+	case "AV1":
+		profile, level, tier := codec.Profile, codec.Level, ""
+		var filmGrain, stillPicture bool
+		if seq := codec.AV1; seq != nil {
+			profile, level, tier = seq.ProfileName, seq.LevelIndex, seq.Tier
+			filmGrain, stillPicture = seq.FilmGrain, seq.StillPicture
+		}
+		notes = append(notes, "AV1 is hardware decoded only on recent devices; keep an H.264 or HEVC rendition as a fallback")
+		if profile != "" && !strings.EqualFold(profile, "Main") {
+			notes = append(notes, fmt.Sprintf("%s profile is rarely hardware decoded; streaming devices decode Main profile", profile))
+		}
+		switch {
+		case level == av1LevelMax:
+			notes = append(notes, "The stream declares no level, so players cannot tell whether they can decode it")
+		case level > 13:
+			notes = append(notes, fmt.Sprintf("Level %s is above 5.1, the highest level most hardware decoders support", av1LevelName(level)))
+		}
+		if tier == "High" {
+			notes = append(notes, "High tier allows bit rates above the Main tier limits hardware decoders are built for")
+		}
+		if filmGrain {
+			notes = append(notes, "Film grain is synthesized by the decoder; players that skip synthesis show the denoised picture")
+		}
+		if stillPicture {
+			notes = append(notes, "The sequence header is for still pictures, as in AVIF, not video playback")
+		}
+	case "H.266/VVC":
+		profile, level, tier, multilayer := codec.Profile, codec.Level, "", false
+		if vvc := codec.VVC; vvc != nil {
+			profile, level, tier, multilayer = vvc.Profile, vvc.LevelIDC, vvc.Tier, vvc.Multilayer
+		}
+		notes = append(notes, "Browsers and most streaming devices do not decode VVC; keep an HEVC or H.264 rendition as a fallback")
+		if profile != "" && !strings.EqualFold(profile, "Main 10") {
+			notes = append(notes, fmt.Sprintf("%s profile goes beyond Main 10, the profile VVC players target", profile))
+		}
+		if level > 83 && level != 255 {
+			notes = append(notes, fmt.Sprintf("Level %s is above 5.1, the highest level most decoders support", vvcLevelName(level)))
+		}
+		if tier == "High" {
+			notes = append(notes, "High tier allows bit rates above the Main tier limits decoders are built for")
+		}
+		if multilayer {
+			notes = append(notes, "Multilayer streams need a decoder that supports multiple layers")
+		}
+	}
+	return notes
+}
+
+func (ca *CodecAnalyzer) getVVCProfileDescription(profile string) string {
+	profiles := map[string]string{
+		"main 10":               "Main 10 Profile - 8/10-bit 4:2:0",
+		"main 10 still picture": "Main 10 Still Picture Profile - single 8/10-bit 4:2:0 picture",
+		"main 10 4:4:4":         "Main 10 4:4:4 Profile - 8/10-bit up to 4:4:4",
+		"multilayer main 10":    "Multilayer Main 10 Profile - scalable and multiview 4:2:0",
+	}
+	if desc, exists := profiles[profile]; exists {
+		return desc
+	}
+	return "Unknown VVC Profile"
+}
+
+func (ca *CodecAnalyzer) getVVCProfileCapabilities(profile string) []string {
+	if strings.Contains(profile, "4:4:4") {
+		return []string{"10-bit depth", "4:4:4 chroma"}
+	}
+	return []string{"10-bit depth", "4:2:0 chroma"}
+}
+
 func (ca *CodecAnalyzer) getVP9ProfileDescription(profile string) string {
 	profiles := map[string]string{
 		"profile 0": "Profile 0 - 8-bit 4:2:0",
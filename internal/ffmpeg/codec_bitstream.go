@@ -0,0 +1,521 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+)
+
+// AV1SequenceHeader is what an AV1 sequence header OBU signals, AV1
+// specification section 5.5
+type AV1SequenceHeader struct {
+	Profile           int    `json:"profile"`      // seq_profile
+	ProfileName       string `json:"profile_name"` // "Main", "High" or "Professional"
+	LevelIndex        int    `json:"seq_level_idx"`
+	Level             string `json:"level"` // e.g. "5.1", or "Max" for an unconstrained stream
+	Tier              string `json:"tier"`  // "Main" or "High"
+	OperatingPoints   int    `json:"operating_points"`
+	StillPicture      bool   `json:"still_picture"`
+	MaxWidth          int    `json:"max_frame_width"`
+	MaxHeight         int    `json:"max_frame_height"`
+	BitDepth          int    `json:"bit_depth"`
+	ChromaSubsampling string `json:"chroma_subsampling"` // "4:2:0", "4:2:2", "4:4:4" or "4:0:0"
+	FullRange         bool   `json:"full_range"`
+	FilmGrain         bool   `json:"film_grain"` // film_grain_params_present_flag
+	Superres          bool   `json:"superres"`
+}
+
+// VVCParameters is the profile, tier and level of a VVC sequence parameter
+// set, ITU-T H.266 section 7.3.2.4
+type VVCParameters struct {
+	ProfileIDC   int    `json:"profile_idc"`
+	Profile      string `json:"profile"`
+	Tier         string `json:"tier"` // "Main" or "High"
+	LevelIDC     int    `json:"level_idc"`
+	Level        string `json:"level"`         // e.g. "5.1"
+	ChromaFormat string `json:"chroma_format"` // "4:0:0", "4:2:0", "4:2:2" or "4:4:4"
+	CTUSize      int    `json:"ctu_size"`
+	SubLayers    int    `json:"sub_layers"`
+	FrameOnly    bool   `json:"frame_only"` // ptl_frame_only_constraint_flag
+	Multilayer   bool   `json:"multilayer"` // ptl_multilayer_enabled_flag
+}
+
+// codecLevel is the picture size limit of a level and the format it is
+// typically used for
+type codecLevel struct {
+	name       string
+	maxPicSize int
+	maxWidth   int
+	maxHeight  int
+	resolution string
+	frameRate  string
+}
+
+// av1Levels are the defined AV1 levels by seq_level_idx, AV1 specification
+// annex A.3
+var av1Levels = map[int]codecLevel{
+	0:  {"2.0", 147456, 2048, 1152, "426x240", "30 fps"},
+	1:  {"2.1", 278784, 2816, 1584, "640x360", "30 fps"},
+	4:  {"3.0", 665856, 4352, 2448, "854x480", "30 fps"},
+	5:  {"3.1", 1065024, 5504, 3096, "1280x720", "30 fps"},
+	8:  {"4.0", 2359296, 6144, 3456, "1920x1080", "30 fps"},
+	9:  {"4.1", 2359296, 6144, 3456, "1920x1080", "60 fps"},
+	12: {"5.0", 8912896, 8192, 4352, "3840x2160", "30 fps"},
+	13: {"5.1", 8912896, 8192, 4352, "3840x2160", "60 fps"},
+	14: {"5.2", 8912896, 8192, 4352, "3840x2160", "120 fps"},
+	15: {"5.3", 8912896, 8192, 4352, "3840x2160", "120 fps"},
+	16: {"6.0", 35651584, 16384, 8704, "7680x4320", "30 fps"},
+	17: {"6.1", 35651584, 16384, 8704, "7680x4320", "60 fps"},
+	18: {"6.2", 35651584, 16384, 8704, "7680x4320", "120 fps"},
+	19: {"6.3", 35651584, 16384, 8704, "7680x4320", "120 fps"},
+}
+
+// av1LevelMax is the seq_level_idx of streams without level constraints
+const av1LevelMax = 31
+
+// vvcLevels are the VVC levels by general_level_idc, ITU-T H.266 table A.8.
+// The largest width or height is sqrt(8 * MaxLumaPs).
+var vvcLevels = map[int]codecLevel{
+	16:  {"1.0", 36864, 0, 0, "176x144", "15 fps"},
+	32:  {"2.0", 122880, 0, 0, "352x288", "30 fps"},
+	35:  {"2.1", 245760, 0, 0, "640x360", "30 fps"},
+	48:  {"3.0", 552960, 0, 0, "960x540", "30 fps"},
+	51:  {"3.1", 983040, 0, 0, "1280x720", "30 fps"},
+	64:  {"4.0", 2228224, 0, 0, "1920x1080", "30 fps"},
+	67:  {"4.1", 2228224, 0, 0, "1920x1080", "60 fps"},
+	80:  {"5.0", 8912896, 0, 0, "3840x2160", "30 fps"},
+	83:  {"5.1", 8912896, 0, 0, "3840x2160", "60 fps"},
+	86:  {"5.2", 8912896, 0, 0, "3840x2160", "120 fps"},
+	96:  {"6.0", 35651584, 0, 0, "7680x4320", "30 fps"},
+	99:  {"6.1", 35651584, 0, 0, "7680x4320", "60 fps"},
+	102: {"6.2", 35651584, 0, 0, "7680x4320", "120 fps"},
+	105: {"6.3", 80216064, 0, 0, "7680x4320", "120 fps"},
+	255: {"15.5", 0, 0, 0, "Unconstrained", "Unconstrained"},
+}
+
+// vvcProfiles are the VVC profiles by general_profile_idc and the highest
+// sps_chroma_format_idc each allows
+var vvcProfiles = map[int]struct {
+	name         string
+	maxChromaIDC int
+}{
+	1:  {"Main 10", 1},
+	2:  {"Main 12", 1},
+	10: {"Main 12 Intra", 1},
+	17: {"Multilayer Main 10", 1},
+	33: {"Main 10 4:4:4", 3},
+	34: {"Main 12 4:4:4", 3},
+	35: {"Main 16 4:4:4", 3},
+	42: {"Main 12 4:4:4 Intra", 3},
+	43: {"Main 16 4:4:4 Intra", 3},
+	49: {"Multilayer Main 10 4:4:4", 3},
+	65: {"Main 10 Still Picture", 1},
+	66: {"Main 12 Still Picture", 1},
+	97: {"Main 10 4:4:4 Still Picture", 3},
+	98: {"Main 12 4:4:4 Still Picture", 3},
+	99: {"Main 16 4:4:4 Still Picture", 3},
+}
+
+var (
+	av1ProfileNames  = []string{"Main", "High", "Professional"}
+	vvcChromaFormats = []string{"4:0:0", "4:2:0", "4:2:2", "4:4:4"}
+	tierNames        = []string{"Main", "High"}
+)
+
+// AV1 OBU types and the VVC SPS NAL unit type
+const (
+	av1OBUSequenceHeader = 1
+	vvcNALSPS            = 15
+)
+
+// AnalyzeBitstreams reads the sequence header of AV1 streams and the SPS of
+// VVC streams, of which ffprobe reports only the profile and level, and adds
+// them to the codec analysis. A stream whose headers cannot be read keeps
+// its ffprobe-derived analysis.
+func (ca *CodecAnalyzer) AnalyzeBitstreams(ctx context.Context, filePath string, analysis *CodecAnalysis, streams []StreamInfo) {
+	for _, stream := range streams {
+		codec := analysis.VideoCodecs[stream.Index]
+		if codec == nil {
+			continue
+		}
+
+		var err error
+		switch codec.CodecFamily {
+		case "AV1":
+			// Sequence headers of MP4 and Matroska files are in the av1C
+			// configuration, which dump_extra puts in front of the packet
+			var data []byte
+			if data, err = ca.readFirstPacket(ctx, filePath, stream.Index, "dump_extra=freq=keyframe"); err == nil {
+				codec.AV1, err = parseAV1SequenceHeader(data)
+			}
+		case "H.266/VVC":
+			var data []byte
+			if data, err = ca.readFirstPacket(ctx, filePath, stream.Index, "vvc_mp4toannexb"); err == nil {
+				codec.VVC, err = parseVVCSPS(data)
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			ca.logger.Debug().Err(err).Int("stream", stream.Index).Str("codec", codec.CodecFamily).Msg("Failed to read codec headers")
+			continue
+		}
+
+		ca.revalidate(codec, stream)
+	}
+	analysis.Validation = ca.validateCodecs(analysis)
+}
+
+// revalidate checks a codec again once its headers have been read
+func (ca *CodecAnalyzer) revalidate(codec *VideoCodecInfo, stream StreamInfo) {
+	codec.Issues = nil
+	codec.IsValid = ca.validateVideoProfileLevel(codec)
+	ca.checkLevelLimits(codec, stream)
+	codec.StreamingNotes = ca.getStreamingNotes(codec)
+}
+
+// readFirstPacket copies the first packet of a video stream through a
+// bitstream filter that puts the parameter sets in front of it
+func (ca *CodecAnalyzer) readFirstPacket(ctx context.Context, filePath string, index int, filter string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
+		"-v", "error",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", index),
+		"-c:v", "copy",
+		"-bsf:v", filter,
+		"-frames:v", "1",
+		"-f", "rawvideo",
+		"-",
+	)
+
+	output, err := commandOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract first packet: %w", err)
+	}
+	return output, nil
+}
+
+// checkLevelLimits checks the picture size against the level the headers
+// declare
+func (ca *CodecAnalyzer) checkLevelLimits(codec *VideoCodecInfo, stream StreamInfo) {
+	var level codecLevel
+	var ok bool
+	switch {
+	case codec.AV1 != nil:
+		if codec.AV1.MaxWidth > 0 && (stream.Width > codec.AV1.MaxWidth || stream.Height > codec.AV1.MaxHeight) {
+			codec.addIssue("Picture size %dx%d exceeds the sequence header's maximum %dx%d", stream.Width, stream.Height, codec.AV1.MaxWidth, codec.AV1.MaxHeight)
+		}
+		level, ok = av1Levels[codec.AV1.LevelIndex]
+	case codec.VVC != nil:
+		level, ok = vvcLevels[codec.VVC.LevelIDC]
+		if ok && level.maxPicSize > 0 {
+			level.maxWidth = int(math.Sqrt(float64(level.maxPicSize) * 8))
+			level.maxHeight = level.maxWidth
+		}
+	}
+	if !ok || level.maxPicSize == 0 || stream.Width == 0 {
+		return
+	}
+	if stream.Width*stream.Height > level.maxPicSize || stream.Width > level.maxWidth || stream.Height > level.maxHeight {
+		codec.addIssue("Picture size %dx%d exceeds level %s", stream.Width, stream.Height, level.name)
+	}
+}
+
+func (codec *VideoCodecInfo) addIssue(format string, args ...any) {
+	codec.Issues = append(codec.Issues, fmt.Sprintf(format, args...))
+	codec.IsValid = false
+}
+
+// parseAV1SequenceHeader finds the first sequence header OBU, in a low
+// overhead OBU stream optionally preceded by an av1C configuration record
+func parseAV1SequenceHeader(data []byte) (*AV1SequenceHeader, error) {
+	// av1C starts with its marker bit set, which is the forbidden bit of an
+	// OBU header
+	if len(data) >= 4 && data[0] == 0x81 {
+		data = data[4:]
+	}
+	for len(data) > 0 {
+		header := data[0]
+		if header&0x80 != 0 {
+			return nil, fmt.Errorf("invalid OBU header")
+		}
+		obuType := int(header>>3) & 0x0F
+		offset := 1
+		if header&0x04 != 0 {
+			offset++ // obu_extension_header
+		}
+		size := len(data) - offset
+		if header&0x02 != 0 {
+			value, n := readLEB128(data[min(offset, len(data)):])
+			if n == 0 {
+				return nil, fmt.Errorf("invalid OBU size")
+			}
+			offset += n
+			size = int(value)
+		}
+		if size < 0 || offset+size > len(data) {
+			return nil, fmt.Errorf("OBU is truncated")
+		}
+		if obuType == av1OBUSequenceHeader {
+			return readAV1SequenceHeader(data[offset : offset+size])
+		}
+		data = data[offset+size:]
+	}
+	return nil, fmt.Errorf("no AV1 sequence header found")
+}
+
+// readAV1SequenceHeader reads sequence_header_obu() up to film grain
+func readAV1SequenceHeader(payload []byte) (*AV1SequenceHeader, error) {
+	r := &bitReader{data: payload}
+	seq := &AV1SequenceHeader{Profile: int(r.read(3)), Tier: tierNames[0]}
+	seq.ProfileName = codeName(av1ProfileNames, seq.Profile)
+	seq.StillPicture = r.read(1) == 1
+	reduced := r.read(1) == 1
+
+	if reduced {
+		seq.OperatingPoints = 1
+		seq.LevelIndex = int(r.read(5))
+	} else {
+		var bufferDelayLength int
+		decoderModel := false
+		if r.read(1) == 1 { // timing_info_present_flag
+			r.skip(64) // num_units_in_display_tick, time_scale
+			if r.read(1) == 1 {
+				readUVLC(r) // num_ticks_per_picture_minus_1
+			}
+			if decoderModel = r.read(1) == 1; decoderModel {
+				bufferDelayLength = int(r.read(5)) + 1
+				r.skip(32 + 5 + 5)
+			}
+		}
+		initialDisplayDelay := r.read(1) == 1
+		seq.OperatingPoints = int(r.read(5)) + 1
+		for i := 0; i < seq.OperatingPoints; i++ {
+			r.skip(12) // operating_point_idc
+			level := int(r.read(5))
+			tier := 0
+			if level > 7 {
+				tier = int(r.read(1))
+			}
+			// The first operating point is the whole stream
+			if i == 0 {
+				seq.LevelIndex, seq.Tier = level, tierNames[tier]
+			}
+			if decoderModel && r.read(1) == 1 {
+				r.skip(2*bufferDelayLength + 1)
+			}
+			if initialDisplayDelay && r.read(1) == 1 {
+				r.skip(4)
+			}
+		}
+	}
+	seq.Level = av1LevelName(seq.LevelIndex)
+
+	widthBits, heightBits := int(r.read(4))+1, int(r.read(4))+1
+	seq.MaxWidth = int(r.read(widthBits)) + 1
+	seq.MaxHeight = int(r.read(heightBits)) + 1
+	if !reduced && r.read(1) == 1 { // frame_id_numbers_present_flag
+		r.skip(4 + 3)
+	}
+	r.skip(3) // use_128x128_superblock, enable_filter_intra, enable_intra_edge_filter
+	if !reduced {
+		r.skip(4) // interintra, masked compound, warped motion, dual filter
+		orderHint := r.read(1) == 1
+		if orderHint {
+			r.skip(2) // enable_jnt_comp, enable_ref_frame_mvs
+		}
+		screenContent := uint32(2)
+		if r.read(1) == 0 { // seq_choose_screen_content_tools
+			screenContent = r.read(1)
+		}
+		if screenContent > 0 && r.read(1) == 0 { // seq_choose_integer_mv
+			r.skip(1)
+		}
+		if orderHint {
+			r.skip(3)
+		}
+	}
+	seq.Superres = r.read(1) == 1
+	r.skip(2) // enable_cdef, enable_restoration
+
+	seq.readColorConfig(r)
+	seq.FilmGrain = r.read(1) == 1
+	if r.overrun {
+		return nil, fmt.Errorf("AV1 sequence header is truncated")
+	}
+	return seq, nil
+}
+
+// readColorConfig reads color_config()
+func (seq *AV1SequenceHeader) readColorConfig(r *bitReader) {
+	seq.BitDepth = 8
+	if r.read(1) == 1 { // high_bitdepth
+		seq.BitDepth = 10
+		if seq.Profile == 2 && r.read(1) == 1 {
+			seq.BitDepth = 12
+		}
+	}
+	mono := seq.Profile != 1 && r.read(1) == 1
+	primaries, transfer, matrix := uint32(2), uint32(2), uint32(2)
+	if r.read(1) == 1 { // color_description_present_flag
+		primaries, transfer, matrix = r.read(8), r.read(8), r.read(8)
+	}
+	if mono {
+		seq.FullRange = r.read(1) == 1
+		seq.ChromaSubsampling = "4:0:0"
+		return
+	}
+
+	// BT.709 primaries with the sRGB transfer and identity matrix is RGB
+	subX, subY := uint32(1), uint32(1)
+	if primaries == 1 && transfer == 13 && matrix == 0 {
+		seq.FullRange = true
+		subX, subY = 0, 0
+	} else {
+		seq.FullRange = r.read(1) == 1
+		switch {
+		case seq.Profile == 1:
+			subX, subY = 0, 0
+		case seq.Profile == 2 && seq.BitDepth == 12:
+			if subX = r.read(1); subX == 1 {
+				subY = r.read(1)
+			} else {
+				subY = 0
+			}
+		case seq.Profile == 2:
+			subY = 0
+		}
+		if subX == 1 && subY == 1 {
+			r.skip(2) // chroma_sample_position
+		}
+	}
+	r.skip(1) // separate_uv_delta_q
+
+	switch {
+	case subX == 1 && subY == 1:
+		seq.ChromaSubsampling = "4:2:0"
+	case subX == 1:
+		seq.ChromaSubsampling = "4:2:2"
+	default:
+		seq.ChromaSubsampling = "4:4:4"
+	}
+}
+
+// av1LevelName names a seq_level_idx: X.Y is 2+(idx>>2).(idx&3)
+func av1LevelName(index int) string {
+	if index == av1LevelMax {
+		return "Max"
+	}
+	return fmt.Sprintf("%d.%d", 2+index>>2, index&3)
+}
+
+// readLEB128 reads an unsigned LEB128 value and returns it with the number
+// of bytes read, or zero bytes if it is truncated or too long
+func readLEB128(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < 8 && i < len(data); i++ {
+		value |= uint64(data[i]&0x7F) << (7 * i)
+		if data[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// readUVLC reads an AV1 uvlc() code
+func readUVLC(r *bitReader) uint32 {
+	leadingZeros := 0
+	for r.read(1) == 0 && !r.overrun {
+		leadingZeros++
+	}
+	if leadingZeros >= 32 {
+		return math.MaxUint32
+	}
+	return r.read(leadingZeros) + (1 << leadingZeros) - 1
+}
+
+// parseVVCSPS finds the first SPS in an Annex B byte stream and reads its
+// profile_tier_level
+func parseVVCSPS(data []byte) (*VVCParameters, error) {
+	for _, nal := range splitAnnexB(data) {
+		if len(nal) < 3 || int(nal[1]>>3) != vvcNALSPS {
+			continue
+		}
+		r := &bitReader{data: removeEmulationPrevention(nal[2:])}
+		vvc := &VVCParameters{}
+		r.skip(8) // sps_seq_parameter_set_id, sps_video_parameter_set_id
+		vvc.SubLayers = int(r.read(3)) + 1
+		chromaFormat := int(r.read(2))
+		vvc.ChromaFormat = vvcChromaFormats[chromaFormat]
+		vvc.CTUSize = 1 << (r.read(2) + 5)
+		if r.read(1) == 0 { // sps_ptl_dpb_hrd_params_present_flag
+			return nil, fmt.Errorf("VVC SPS carries no profile, tier and level")
+		}
+		vvc.ProfileIDC = int(r.read(7))
+		vvc.Tier = tierNames[r.read(1)]
+		vvc.LevelIDC = int(r.read(8))
+		vvc.FrameOnly = r.read(1) == 1
+		vvc.Multilayer = r.read(1) == 1
+		if r.overrun {
+			return nil, fmt.Errorf("VVC SPS is truncated")
+		}
+
+		vvc.Profile = fmt.Sprintf("Unknown (%d)", vvc.ProfileIDC)
+		if profile, ok := vvcProfiles[vvc.ProfileIDC]; ok {
+			vvc.Profile = profile.name
+		}
+		vvc.Level = vvcLevelName(vvc.LevelIDC)
+		return vvc, nil
+	}
+	return nil, fmt.Errorf("no VVC SPS found")
+}
+
+// vvcLevelName names a general_level_idc, which is 16 times the major
+// level plus 3 times the minor one
+func vvcLevelName(idc int) string {
+	return fmt.Sprintf("%d.%d", idc/16, idc%16/3)
+}
+
+// splitAnnexB splits a byte stream at its three and four byte start codes
+func splitAnnexB(data []byte) [][]byte {
+	var nals [][]byte
+	startCode := []byte{0x00, 0x00, 0x01}
+	start := bytes.Index(data, startCode)
+	for start >= 0 {
+		start += len(startCode)
+		next := bytes.Index(data[start:], startCode)
+		if next < 0 {
+			nals = append(nals, data[start:])
+			break
+		}
+		end := start + next
+		nal := data[start:end]
+		// A four byte start code leaves its leading zero on the NAL unit
+		// before it
+		nals = append(nals, bytes.TrimRight(nal, "\x00"))
+		start = end
+	}
+	return nals
+}
+
+// removeEmulationPrevention turns a NAL unit payload into its RBSP by
+// dropping the 0x03 that follows every two zero bytes
+func removeEmulationPrevention(data []byte) []byte {
+	rbsp := make([]byte, 0, len(data))
+	zeros := 0
+	for _, b := range data {
+		if zeros >= 2 && b == 0x03 {
+			zeros = 0
+			continue
+		}
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+		rbsp = append(rbsp, b)
+	}
+	return rbsp
+}
@@ -0,0 +1,144 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// testAV1SequenceHeader builds a Main profile 1920x1080 10-bit 4:2:0
+// sequence header at level 4.0 with film grain
+func testAV1SequenceHeader() []byte {
+	w := &bitWriter{}
+	w.put(0, 3).put(0, 1).put(0, 1)  // seq_profile, still_picture, reduced_still_picture_header
+	w.put(0, 1).put(0, 1).put(0, 5)  // timing_info_present, initial_display_delay_present, operating_points_cnt_minus_1
+	w.put(0, 12).put(8, 5).put(0, 1) // operating_point_idc, seq_level_idx 4.0, seq_tier
+	w.put(10, 4).put(10, 4).put(1919, 11).put(1079, 11)
+	w.put(0, 1).put(3, 3).put(0, 4)            // frame_id_numbers_present, superblock and intra tools, inter tools
+	w.put(1, 1).put(1, 2).put(1, 1).put(1, 1)  // order hint, jnt_comp/ref_frame_mvs, choose screen content, choose integer mv
+	w.put(6, 3).put(0, 1).put(3, 2)            // order_hint_bits_minus_1, superres, cdef/restoration
+	w.put(1, 1).put(0, 1).put(1, 1)            // high_bitdepth, mono_chrome, color_description_present
+	w.put(9, 8).put(16, 8).put(9, 8).put(0, 1) // BT.2020 PQ, color_range
+	w.put(0, 2).put(0, 1).put(1, 1).put(1, 1)  // chroma_sample_position, separate_uv_delta_q, film grain, trailing bit
+	return w.data
+}
+
+// testOBU wraps a payload in an OBU with a size field
+func testOBU(obuType byte, payload []byte) []byte {
+	return append([]byte{obuType<<3 | 0x02, byte(len(payload))}, payload...)
+}
+
+func TestParseAV1SequenceHeader(t *testing.T) {
+	obus := append(testOBU(2, nil), testOBU(av1OBUSequenceHeader, testAV1SequenceHeader())...)
+
+	for name, data := range map[string][]byte{
+		"OBU stream":       obus,
+		"av1C in front of": append([]byte{0x81, 0x08, 0x0C, 0x00}, obus...),
+	} {
+		t.Run(name, func(t *testing.T) {
+			seq, err := parseAV1SequenceHeader(data)
+			if err != nil {
+				t.Fatalf("parseAV1SequenceHeader() error = %v", err)
+			}
+			want := AV1SequenceHeader{Profile: 0, ProfileName: "Main", LevelIndex: 8, Level: "4.0", Tier: "Main", OperatingPoints: 1,
+				MaxWidth: 1920, MaxHeight: 1080, BitDepth: 10, ChromaSubsampling: "4:2:0", FilmGrain: true}
+			if *seq != want {
+				t.Errorf("got %+v, want %+v", *seq, want)
+			}
+		})
+	}
+
+	t.Run("High profile with a decoder model", func(t *testing.T) {
+		w := &bitWriter{}
+		w.put(1, 3).put(0, 2).put(1, 1)                    // High profile, timing_info_present
+		w.put(1001, 32).put(60000, 32).put(1, 1).put(1, 1) // equal_picture_interval, num_ticks_per_picture_minus_1 = 0
+		w.put(1, 1).put(9, 5).put(0, 32).put(0, 5).put(0, 5)
+		w.put(0, 1).put(0, 5).put(0, 12).put(13, 5).put(1, 1) // level 5.1, High tier
+		w.put(1, 1).put(0, 21)                                // decoder_model_present_for_this_op
+		w.put(11, 4).put(11, 4).put(3839, 12).put(2159, 12)
+		w.put(0, 1).put(0, 3).put(0, 4).put(0, 1).put(0, 1).put(0, 1) // no order hint, screen content forced off
+		w.put(1, 1).put(0, 2)                                         // superres
+		w.put(0, 1).put(0, 1).put(1, 1).put(0, 1).put(0, 1)           // 8-bit, no color description, full range
+		seq, err := parseAV1SequenceHeader(testOBU(av1OBUSequenceHeader, w.data))
+		if err != nil {
+			t.Fatalf("parseAV1SequenceHeader() error = %v", err)
+		}
+		if seq.ProfileName != "High" || seq.Level != "5.1" || seq.Tier != "High" || seq.MaxWidth != 3840 || seq.ChromaSubsampling != "4:4:4" || !seq.FullRange || !seq.Superres || seq.FilmGrain {
+			t.Errorf("got %+v", *seq)
+		}
+	})
+
+	if _, err := parseAV1SequenceHeader(testOBU(2, nil)); err == nil {
+		t.Error("parseAV1SequenceHeader() without a sequence header: error = nil")
+	}
+}
+
+// testVVCSPS builds an Annex B VPS and SPS with the given profile, level
+// and chroma format
+func testVVCSPS(profile, level, chroma uint32) []byte {
+	w := &bitWriter{}
+	w.put(0, 8).put(0, 3).put(chroma, 2).put(2, 2).put(1, 1) // ids, sublayers, chroma, 128x128 CTUs, PTL present
+	w.put(profile, 7).put(0, 1).put(level, 8).put(1, 1).put(0, 1)
+	w.put(0, 1).put(1, 1) // gci_present_flag, stop bit
+
+	data := []byte{0x00, 0x00, 0x00, 0x01, 0x00, 14<<3 | 1, 0x00, 0x00, 0x03, 0x00}
+	data = append(data, 0x00, 0x00, 0x01, 0x00, vvcNALSPS<<3|1)
+	return append(data, w.data...)
+}
+
+func TestParseVVCSPS(t *testing.T) {
+	vvc, err := parseVVCSPS(testVVCSPS(1, 83, 1))
+	if err != nil {
+		t.Fatalf("parseVVCSPS() error = %v", err)
+	}
+	want := VVCParameters{ProfileIDC: 1, Profile: "Main 10", Tier: "Main", LevelIDC: 83, Level: "5.1", ChromaFormat: "4:2:0", CTUSize: 128, SubLayers: 1, FrameOnly: true}
+	if *vvc != want {
+		t.Errorf("got %+v, want %+v", *vvc, want)
+	}
+
+	if _, err := parseVVCSPS([]byte{0x00, 0x00, 0x01, 0x00, 14<<3 | 1, 0x00}); err == nil {
+		t.Error("parseVVCSPS() without an SPS: error = nil")
+	}
+}
+
+func TestCodecAnalyzer_Revalidate(t *testing.T) {
+	ca := NewCodecAnalyzer("ffmpeg", zerolog.Nop())
+
+	t.Run("AV1 above its level", func(t *testing.T) {
+		stream := StreamInfo{Index: 0, CodecName: "av1", CodecType: "video", Profile: "Main", Level: 8, Width: 3840, Height: 2160}
+		codec := ca.analyzeVideoCodec(stream)
+		codec.AV1, _ = parseAV1SequenceHeader(testOBU(av1OBUSequenceHeader, testAV1SequenceHeader()))
+		ca.revalidate(codec, stream)
+
+		want := []string{"exceeds the sequence header's maximum 1920x1080", "exceeds level 4.0"}
+		if codec.IsValid || len(codec.Issues) != len(want) {
+			t.Fatalf("Issues = %q", codec.Issues)
+		}
+		for i, w := range want {
+			if !strings.Contains(codec.Issues[i], w) {
+				t.Errorf("Issues[%d] = %q, want %q", i, codec.Issues[i], w)
+			}
+		}
+		if len(codec.StreamingNotes) != 2 || !strings.Contains(codec.StreamingNotes[1], "Film grain") {
+			t.Errorf("StreamingNotes = %q", codec.StreamingNotes)
+		}
+	})
+
+	t.Run("VVC 4:4:4 in Main 10", func(t *testing.T) {
+		stream := StreamInfo{Index: 0, CodecName: "vvc", CodecType: "video", Profile: "Main 10", Level: 102, Width: 3840, Height: 2160}
+		codec := ca.analyzeVideoCodec(stream)
+		if codec.CodecFamily != "H.266/VVC" || codec.LevelInfo.Description != "VVC Level 6.2" {
+			t.Errorf("family %q, level %+v", codec.CodecFamily, codec.LevelInfo)
+		}
+		codec.VVC, _ = parseVVCSPS(testVVCSPS(1, 102, 3))
+		ca.revalidate(codec, stream)
+
+		if codec.IsValid || len(codec.Issues) != 1 || !strings.Contains(codec.Issues[0], "Main 10 profile allows up to 4:2:0, the SPS signals 4:4:4") {
+			t.Errorf("Issues = %q", codec.Issues)
+		}
+		if len(codec.StreamingNotes) != 2 || !strings.Contains(codec.StreamingNotes[1], "Level 6.2 is above 5.1") {
+			t.Errorf("StreamingNotes = %q", codec.StreamingNotes)
+		}
+	})
+}
@@ -0,0 +1,256 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Color standards the tags of a stream are grouped into
+const (
+	ColorStandardBT601  = "BT.601"
+	ColorStandardBT709  = "BT.709"
+	ColorStandardBT2020 = "BT.2020"
+)
+
+// ColorConsistencyAnalysis checks that the color primaries, transfer
+// characteristics and matrix coefficients of each video stream are tagged,
+// agree with each other and suit the stream's resolution
+type ColorConsistencyAnalysis struct {
+	Streams         []*StreamColorTags `json:"streams"`
+	IsConsistent    bool               `json:"is_consistent"`
+	Issues          []string           `json:"issues,omitempty"`
+	Recommendations []string           `json:"recommendations,omitempty"`
+}
+
+// StreamColorTags holds the color tags of a video stream and the tags it
+// should carry
+type StreamColorTags struct {
+	StreamIndex      int       `json:"stream_index"`
+	Tags             ColorTags `json:"tags"`
+	MissingTags      []string  `json:"missing_tags,omitempty"` // e.g. "color_primaries"
+	Conflicts        []string  `json:"conflicts,omitempty"`
+	ExpectedStandard string    `json:"expected_standard"` // What players assume for the resolution
+	Recommended      ColorTags `json:"recommended"`
+	IsConsistent     bool      `json:"is_consistent"`
+}
+
+// ColorTags are the color description of a stream, in ffprobe's names
+type ColorTags struct {
+	ColorPrimaries string `json:"color_primaries,omitempty"`
+	ColorTransfer  string `json:"color_transfer,omitempty"`
+	ColorSpace     string `json:"color_space,omitempty"` // Matrix coefficients
+	ColorRange     string `json:"color_range,omitempty"`
+}
+
+// Standards of the primaries and matrix coefficients ffprobe reports. The
+// BT.601 625 and 525 line variants share a matrix, so both count as BT.601.
+var (
+	colorPrimariesStandards = map[string]string{
+		"bt709":     ColorStandardBT709,
+		"bt470bg":   ColorStandardBT601,
+		"smpte170m": ColorStandardBT601,
+		"bt2020":    ColorStandardBT2020,
+	}
+	colorMatrixStandards = map[string]string{
+		"bt709":     ColorStandardBT709,
+		"bt470bg":   ColorStandardBT601,
+		"smpte170m": ColorStandardBT601,
+		"bt2020nc":  ColorStandardBT2020,
+		"bt2020c":   ColorStandardBT2020,
+	}
+)
+
+// hdrTransferTags are the transfer characteristics only BT.2020 primaries
+// can carry in a delivery
+var hdrTransferTags = map[string]bool{
+	"smpte2084":    true,
+	"arib-std-b67": true,
+}
+
+// unspecifiedColorTag reports whether a tag value leaves the color
+// description to the player
+func unspecifiedColorTag(value string) bool {
+	switch strings.ToLower(value) {
+	case "", "unknown", "unspecified", "reserved", "n/a":
+		return true
+	}
+	return false
+}
+
+// analyzeColorConsistency checks the color tags of each video stream:
+// missing or unspecified tags, BT.601, BT.709 and BT.2020 primaries and
+// matrices mixed in one stream, an HDR transfer on SDR primaries, tags that
+// differ from what players assume for the resolution (BT.601 below 720
+// lines, BT.709 or, for wide gamut and HDR, BT.2020 from there) and video
+// streams tagged differently. It returns nil without video.
+func analyzeColorConsistency(streams []StreamInfo) *ColorConsistencyAnalysis {
+	analysis := &ColorConsistencyAnalysis{IsConsistent: true}
+	var first *StreamColorTags
+	for _, stream := range streams {
+		if stream.CodecType != "video" || stream.Disposition["attached_pic"] == 1 {
+			continue
+		}
+		tags := checkStreamColorTags(stream)
+		analysis.Streams = append(analysis.Streams, tags)
+		if !tags.IsConsistent {
+			analysis.IsConsistent = false
+		}
+		for _, tag := range tags.MissingTags {
+			analysis.Issues = append(analysis.Issues, fmt.Sprintf("Video stream %d has no %s tag", stream.Index, tag))
+		}
+		for _, conflict := range tags.Conflicts {
+			analysis.Issues = append(analysis.Issues, fmt.Sprintf("Video stream %d: %s", stream.Index, conflict))
+		}
+		if !tags.IsConsistent {
+			analysis.Recommendations = append(analysis.Recommendations, fmt.Sprintf(
+				"Tag video stream %d as %s: color_primaries=%s, color_trc=%s, colorspace=%s, color_range=%s",
+				stream.Index, tags.standard(), tags.Recommended.ColorPrimaries, tags.Recommended.ColorTransfer,
+				tags.Recommended.ColorSpace, tags.Recommended.ColorRange))
+		}
+
+		if first == nil {
+			first = tags
+		} else if tags.Tags.String() != first.Tags.String() {
+			analysis.IsConsistent = false
+			analysis.Issues = append(analysis.Issues, fmt.Sprintf("Video streams %d and %d are tagged with different colors (%s and %s)",
+				first.StreamIndex, tags.StreamIndex, first.Tags, tags.Tags))
+		}
+	}
+	if len(analysis.Streams) == 0 {
+		return nil
+	}
+	return analysis
+}
+
+// checkStreamColorTags checks the color tags of one video stream and
+// works out the tags it should carry
+func checkStreamColorTags(stream StreamInfo) *StreamColorTags {
+	tags := &StreamColorTags{
+		StreamIndex: stream.Index,
+		Tags: ColorTags{
+			ColorPrimaries: stream.ColorPrimaries,
+			ColorTransfer:  stream.ColorTransfer,
+			ColorSpace:     stream.ColorSpace,
+			ColorRange:     stream.ColorRange,
+		},
+		ExpectedStandard: expectedColorStandard(stream.Height),
+	}
+	for _, tag := range []struct{ name, value string }{
+		{"color_primaries", stream.ColorPrimaries},
+		{"color_transfer", stream.ColorTransfer},
+		{"color_space", stream.ColorSpace},
+	} {
+		if unspecifiedColorTag(tag.value) {
+			tags.MissingTags = append(tags.MissingTags, tag.name)
+		}
+	}
+
+	primaries := colorPrimariesStandards[strings.ToLower(stream.ColorPrimaries)]
+	matrix := colorMatrixStandards[strings.ToLower(stream.ColorSpace)]
+	transfer := strings.ToLower(stream.ColorTransfer)
+	if primaries != "" && matrix != "" && primaries != matrix {
+		tags.Conflicts = append(tags.Conflicts, fmt.Sprintf("%s primaries (%s) with a %s matrix (%s)",
+			primaries, stream.ColorPrimaries, matrix, stream.ColorSpace))
+	}
+	if hdrTransferTags[transfer] && (primaries == ColorStandardBT601 || primaries == ColorStandardBT709) {
+		tags.Conflicts = append(tags.Conflicts, fmt.Sprintf("HDR transfer %s with %s primaries (%s)",
+			stream.ColorTransfer, primaries, stream.ColorPrimaries))
+	}
+	for _, standard := range []string{primaries, matrix} {
+		if standard != "" && !colorStandardSuitsHeight(standard, stream.Height) {
+			tags.Conflicts = append(tags.Conflicts, fmt.Sprintf("%s tags on %d-line video, where players assume %s",
+				standard, stream.Height, tags.ExpectedStandard))
+			break
+		}
+	}
+
+	tags.Recommended = recommendedColorTags(stream, primaries, matrix)
+	tags.IsConsistent = len(tags.MissingTags) == 0 && len(tags.Conflicts) == 0
+	return tags
+}
+
+// expectedColorStandard is the standard players assume for untagged video
+// of height lines
+func expectedColorStandard(height int) string {
+	switch {
+	case height >= 720:
+		return ColorStandardBT709
+	case height > 0:
+		return ColorStandardBT601
+	}
+	return ""
+}
+
+// colorStandardSuitsHeight reports whether video of height lines may be
+// tagged with standard: BT.601 is for SD, BT.709 for HD and up, and BT.2020
+// for HD HDR and up
+func colorStandardSuitsHeight(standard string, height int) bool {
+	if height <= 0 {
+		return true
+	}
+	if standard == ColorStandardBT601 {
+		return height < 720
+	}
+	return height >= 720
+}
+
+// recommendedColorTags picks the tags stream should carry: the standard its
+// primaries and matrix agree on, else the one that suits its resolution,
+// keeping an HDR transfer and a tagged range
+func recommendedColorTags(stream StreamInfo, primaries, matrix string) ColorTags {
+	transfer := strings.ToLower(stream.ColorTransfer)
+	standard := expectedColorStandard(stream.Height)
+	switch {
+	case hdrTransferTags[transfer]:
+		standard = ColorStandardBT2020
+	case primaries != "" && (matrix == "" || matrix == primaries) && colorStandardSuitsHeight(primaries, stream.Height):
+		standard = primaries
+	case matrix != "" && primaries == "" && colorStandardSuitsHeight(matrix, stream.Height):
+		standard = matrix
+	}
+
+	var tags ColorTags
+	switch standard {
+	case ColorStandardBT2020:
+		tags = ColorTags{ColorPrimaries: "bt2020", ColorTransfer: "bt2020-10", ColorSpace: "bt2020nc"}
+		if hdrTransferTags[transfer] {
+			tags.ColorTransfer = transfer
+		}
+	case ColorStandardBT601:
+		// 625 line (PAL) and 525 line (NTSC) video have their own primaries
+		tags = ColorTags{ColorPrimaries: "smpte170m", ColorTransfer: "smpte170m", ColorSpace: "smpte170m"}
+		if stream.Height == 576 || stream.Height == 288 || strings.EqualFold(stream.ColorPrimaries, "bt470bg") {
+			tags = ColorTags{ColorPrimaries: "bt470bg", ColorTransfer: "bt709", ColorSpace: "bt470bg"}
+		}
+	default:
+		tags = ColorTags{ColorPrimaries: "bt709", ColorTransfer: "bt709", ColorSpace: "bt709"}
+	}
+
+	tags.ColorRange = strings.ToLower(stream.ColorRange)
+	if unspecifiedColorTag(tags.ColorRange) {
+		tags.ColorRange = "tv"
+		if strings.HasPrefix(stream.PixFmt, "yuvj") || strings.HasPrefix(stream.PixFmt, "rgb") || strings.HasPrefix(stream.PixFmt, "gbr") {
+			tags.ColorRange = "pc"
+		}
+	}
+	return tags
+}
+
+// standard names the standard of the recommended tags
+func (t *StreamColorTags) standard() string {
+	if standard := colorPrimariesStandards[t.Recommended.ColorPrimaries]; standard != "" {
+		return standard
+	}
+	return ColorStandardBT709
+}
+
+// String formats tags as primaries/transfer/matrix
+func (t ColorTags) String() string {
+	format := func(value string) string {
+		if unspecifiedColorTag(value) {
+			return "unknown"
+		}
+		return value
+	}
+	return fmt.Sprintf("%s/%s/%s", format(t.ColorPrimaries), format(t.ColorTransfer), format(t.ColorSpace))
+}
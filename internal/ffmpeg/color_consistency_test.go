@@ -0,0 +1,149 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// colorStream builds a video stream of height lines with color tags
+func colorStream(index, height int, primaries, transfer, matrix string) StreamInfo {
+	return StreamInfo{
+		Index:          index,
+		CodecType:      "video",
+		CodecName:      "h264",
+		Height:         height,
+		PixFmt:         "yuv420p",
+		ColorPrimaries: primaries,
+		ColorTransfer:  transfer,
+		ColorSpace:     matrix,
+		ColorRange:     "tv",
+	}
+}
+
+func TestAnalyzeColorConsistency(t *testing.T) {
+	tests := []struct {
+		name          string
+		stream        StreamInfo
+		wantMissing   []string
+		wantConflicts []string
+		wantTags      ColorTags
+	}{
+		{
+			name:     "HD BT.709",
+			stream:   colorStream(0, 1080, "bt709", "bt709", "bt709"),
+			wantTags: ColorTags{"bt709", "bt709", "bt709", "tv"},
+		},
+		{
+			name:     "PAL SD",
+			stream:   colorStream(0, 576, "bt470bg", "bt709", "bt470bg"),
+			wantTags: ColorTags{"bt470bg", "bt709", "bt470bg", "tv"},
+		},
+		{
+			name:     "UHD HDR10",
+			stream:   colorStream(0, 2160, "bt2020", "smpte2084", "bt2020nc"),
+			wantTags: ColorTags{"bt2020", "smpte2084", "bt2020nc", "tv"},
+		},
+		{
+			name:          "BT.709 primaries with a BT.601 matrix",
+			stream:        colorStream(0, 1080, "bt709", "bt709", "smpte170m"),
+			wantConflicts: []string{"BT.709 primaries (bt709) with a BT.601 matrix (smpte170m)", "BT.601 tags on 1080-line video, where players assume BT.709"},
+			wantTags:      ColorTags{"bt709", "bt709", "bt709", "tv"},
+		},
+		{
+			name:          "HD tagged for SD",
+			stream:        colorStream(0, 720, "smpte170m", "smpte170m", "smpte170m"),
+			wantConflicts: []string{"BT.601 tags on 720-line video, where players assume BT.709"},
+			wantTags:      ColorTags{"bt709", "bt709", "bt709", "tv"},
+		},
+		{
+			name:          "PQ on BT.709 primaries",
+			stream:        colorStream(0, 2160, "bt709", "smpte2084", "bt709"),
+			wantConflicts: []string{"HDR transfer smpte2084 with BT.709 primaries (bt709)"},
+			wantTags:      ColorTags{"bt2020", "smpte2084", "bt2020nc", "tv"},
+		},
+		{
+			name:        "untagged NTSC",
+			stream:      StreamInfo{Index: 0, CodecType: "video", Height: 480, ColorSpace: "unknown", ColorPrimaries: "unknown"},
+			wantMissing: []string{"color_primaries", "color_transfer", "color_space"},
+			wantTags:    ColorTags{"smpte170m", "smpte170m", "smpte170m", "tv"},
+		},
+		{
+			name:        "untagged full range JPEG",
+			stream:      StreamInfo{Index: 0, CodecType: "video", Height: 1080, PixFmt: "yuvj420p", ColorSpace: "bt709"},
+			wantMissing: []string{"color_primaries", "color_transfer"},
+			wantTags:    ColorTags{"bt709", "bt709", "bt709", "pc"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analysis := analyzeColorConsistency([]StreamInfo{tt.stream})
+			if analysis == nil || len(analysis.Streams) != 1 {
+				t.Fatalf("expected one stream, got %+v", analysis)
+			}
+			tags := analysis.Streams[0]
+			if !reflect.DeepEqual(tags.MissingTags, tt.wantMissing) || !reflect.DeepEqual(tags.Conflicts, tt.wantConflicts) {
+				t.Errorf("missing %v and conflicts %v, want %v and %v", tags.MissingTags, tags.Conflicts, tt.wantMissing, tt.wantConflicts)
+			}
+			if tags.Recommended != tt.wantTags {
+				t.Errorf("recommended %+v, want %+v", tags.Recommended, tt.wantTags)
+			}
+			consistent := tt.wantMissing == nil && tt.wantConflicts == nil
+			if tags.IsConsistent != consistent || analysis.IsConsistent != consistent || (len(analysis.Recommendations) == 0) == !consistent {
+				t.Errorf("expected consistency %v, got %+v", consistent, analysis)
+			}
+		})
+	}
+}
+
+func TestAnalyzeColorConsistency_Streams(t *testing.T) {
+	cover := colorStream(2, 600, "", "", "")
+	cover.Disposition = map[string]int{"attached_pic": 1}
+	analysis := analyzeColorConsistency([]StreamInfo{
+		colorStream(0, 1080, "bt709", "bt709", "bt709"),
+		{Index: 1, CodecType: "audio"},
+		cover,
+		colorStream(3, 2160, "bt2020", "arib-std-b67", "bt2020nc"),
+	})
+	if len(analysis.Streams) != 2 || analysis.IsConsistent {
+		t.Fatalf("expected two differently tagged streams, got %+v", analysis)
+	}
+	if !analysis.Streams[0].IsConsistent || !analysis.Streams[1].IsConsistent {
+		t.Errorf("expected each stream to be consistent on its own, got %+v and %+v", analysis.Streams[0], analysis.Streams[1])
+	}
+	if len(analysis.Issues) != 1 || analysis.Issues[0] != "Video streams 0 and 3 are tagged with different colors (bt709/bt709/bt709 and bt2020/arib-std-b67/bt2020nc)" {
+		t.Errorf("unexpected issues %v", analysis.Issues)
+	}
+
+	if analyzeColorConsistency([]StreamInfo{{Index: 0, CodecType: "audio"}}) != nil {
+		t.Error("expected no analysis without video")
+	}
+}
+
+func TestSuggestRepairs_ColorTags(t *testing.T) {
+	streams := []StreamInfo{
+		colorStream(0, 1080, "bt709", "bt709", "smpte170m"),
+		{Index: 1, CodecType: "audio", CodecName: "aac"},
+	}
+	streams[0].ColorRange = ""
+	result := &FFprobeResult{
+		Streams:          streams,
+		EnhancedAnalysis: &EnhancedAnalysis{ColorConsistencyAnalysis: analyzeColorConsistency(streams)},
+	}
+	suggestions := SuggestRepairs(result, "input.mp4")
+	if len(suggestions) != 1 || suggestions[0].Issue != RepairColorTags || !suggestions[0].Lossless {
+		t.Fatalf("expected a lossless color tag remux, got %+v", suggestions)
+	}
+	want := "ffmpeg -i input.mp4 -map 0 -c copy" +
+		" -bsf:0 h264_metadata=colour_primaries=1:transfer_characteristics=1:matrix_coefficients=1:video_full_range_flag=0" +
+		" -color_primaries:0 bt709 -color_trc:0 bt709 -colorspace:0 bt709 -color_range:0 tv repaired.mp4"
+	if suggestions[0].Command != want {
+		t.Errorf("command = %q, want %q", suggestions[0].Command, want)
+	}
+
+	streams[0].CodecName = "prores"
+	command := SuggestRepairs(result, "input.mov")[0].Command
+	if strings.Contains(command, "-bsf") || !strings.Contains(command, "-colorspace:0 bt709") {
+		t.Errorf("expected only container tags for ProRes, got %q", command)
+	}
+}
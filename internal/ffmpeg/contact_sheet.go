@@ -0,0 +1,64 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+)
+
+// Contact sheet layout
+const (
+	contactSheetPadding = 4
+	contactSheetQuality = 85
+)
+
+// contactSheetBackground fills the gaps between stills
+var contactSheetBackground = color.RGBA{R: 0x20, G: 0x20, B: 0x20, A: 0xff}
+
+// ContactSheet tiles thumbnails into a single JPEG, columns stills per row
+// in time order. Every cell is sized to the largest still so mixed aspect
+// ratios stay aligned; a filmstrip is a sheet with one row.
+func ContactSheet(thumbnails []Thumbnail, columns int) ([]byte, error) {
+	if len(thumbnails) == 0 {
+		return nil, fmt.Errorf("no thumbnails to tile")
+	}
+	if columns <= 0 || columns > len(thumbnails) {
+		columns = len(thumbnails)
+	}
+
+	images := make([]image.Image, len(thumbnails))
+	cellWidth, cellHeight := 0, 0
+	for i, thumbnail := range thumbnails {
+		img, err := jpeg.Decode(bytes.NewReader(thumbnail.JPEG))
+		if err != nil {
+			return nil, fmt.Errorf("invalid thumbnail image at %.3fs: %w", thumbnail.Time, err)
+		}
+		images[i] = img
+		cellWidth = max(cellWidth, img.Bounds().Dx())
+		cellHeight = max(cellHeight, img.Bounds().Dy())
+	}
+
+	rows := (len(images) + columns - 1) / columns
+	sheet := image.NewRGBA(image.Rect(0, 0,
+		columns*(cellWidth+contactSheetPadding)+contactSheetPadding,
+		rows*(cellHeight+contactSheetPadding)+contactSheetPadding,
+	))
+	draw.Draw(sheet, sheet.Bounds(), image.NewUniform(contactSheetBackground), image.Point{}, draw.Src)
+
+	for i, img := range images {
+		bounds := img.Bounds()
+		// Centre each still in its cell
+		x := contactSheetPadding + (i%columns)*(cellWidth+contactSheetPadding) + (cellWidth-bounds.Dx())/2
+		y := contactSheetPadding + (i/columns)*(cellHeight+contactSheetPadding) + (cellHeight-bounds.Dy())/2
+		draw.Draw(sheet, image.Rect(x, y, x+bounds.Dx(), y+bounds.Dy()), img, bounds.Min, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, sheet, &jpeg.Options{Quality: contactSheetQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode contact sheet: %w", err)
+	}
+	return buf.Bytes(), nil
+}
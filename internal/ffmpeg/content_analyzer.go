@@ -51,8 +51,19 @@ func NewContentAnalyzer(ffmpegPath string, logger zerolog.Logger) *ContentAnalyz
 
 // AnalyzeContent performs content-based analysis on a video file
 func (ca *ContentAnalyzer) AnalyzeContent(ctx context.Context, filePath string) (*ContentAnalysis, error) {
+	ctx, span := tracer.Start(ctx, "ContentAnalyzer.AnalyzeContent")
+	defer span.End()
+
 	analysis := &ContentAnalysis{}
 
+	// Long assets may be analyzed through windows; the deferred apply maps
+	// the results to the whole asset on either return below
+	ctx, plan := ca.startSampling(ctx, filePath)
+	if plan != nil {
+		defer plan.close()
+		defer plan.apply(analysis)
+	}
+
 	// Create cancellable context for proper cleanup on timeout
 	analyzeCtx, cancel := context.WithTimeout(ctx, 120*time.Second)
 	defer cancel() // Ensures all goroutines terminate
@@ -76,7 +87,10 @@ func (ca *ContentAnalyzer) AnalyzeContent(ctx context.Context, filePath string)
 				return // Context cancelled, exit gracefully
 			default:
 			}
-			if applyResult, err := analyze(analyzeCtx, filePath); err != nil {
+			spanCtx, span := tracer.Start(analyzeCtx, "ContentAnalyzer "+name)
+			defer span.End()
+			if applyResult, err := analyze(spanCtx, filePath); err != nil {
+				recordSpanError(span, err)
 				select {
 				case errorChan <- fmt.Errorf("%s failed: %w", name, err):
 				case <-analyzeCtx.Done():
@@ -260,11 +274,14 @@ func (ca *ContentAnalyzer) AnalyzeContent(ctx context.Context, filePath string)
 	})
 
 	launchAnalyzer("temporal complexity analysis", func(ctx context.Context, path string) (func(), error) {
-		result, err := ca.analyzeTemporalComplexity(ctx, path)
+		result, scenes, err := ca.analyzeTemporalComplexity(ctx, path)
 		if err != nil {
 			return nil, err
 		}
-		return func() { analysis.TemporalComplexity = result }, nil
+		return func() {
+			analysis.TemporalComplexity = result
+			analysis.SceneChanges = scenes
+		}, nil
 	})
 
 	launchAnalyzer("field dominance analysis", func(ctx context.Context, path string) (func(), error) {
@@ -338,14 +355,14 @@ func (ca *ContentAnalyzer) AnalyzeContent(ctx context.Context, filePath string)
 func (ca *ContentAnalyzer) analyzeBlackFrames(ctx context.Context, filePath string) (*BlackFrameAnalysis, error) {
 	threshold := 0.1 // 10% threshold for blackness
 
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(contentInputArgs(ctx, filePath),
 		"-vf", fmt.Sprintf("blackdetect=d=0.5:pix_th=%f", threshold),
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("blackdetect failed: %w", err)
 	}
@@ -363,6 +380,7 @@ func (ca *ContentAnalyzer) analyzeBlackFrames(ctx context.Context, filePath stri
 		DetectedFrames: detectedFrames,
 		Percentage:     0.0, // Would need total frame count to calculate
 		Threshold:      threshold,
+		Periods:        parseBlackDetect(output),
 	}, nil
 }
 
@@ -370,14 +388,14 @@ func (ca *ContentAnalyzer) analyzeBlackFrames(ctx context.Context, filePath stri
 func (ca *ContentAnalyzer) analyzeFreezeFrames(ctx context.Context, filePath string) (*FreezeFrameAnalysis, error) {
 	threshold := 0.001 // Very low threshold for freeze detection
 
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(contentInputArgs(ctx, filePath),
 		"-vf", fmt.Sprintf("freezedetect=n=%f:d=2", threshold),
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("freezedetect failed: %w", err)
 	}
@@ -395,19 +413,20 @@ func (ca *ContentAnalyzer) analyzeFreezeFrames(ctx context.Context, filePath str
 		DetectedFrames: detectedFrames,
 		Percentage:     0.0, // Would need total frame count to calculate
 		Threshold:      threshold,
+		Periods:        parseFreezeDetect(output),
 	}, nil
 }
 
 // analyzeAudioClipping detects audio clipping
 func (ca *ContentAnalyzer) analyzeAudioClipping(ctx context.Context, filePath string) (*AudioClippingAnalysis, error) {
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(contentInputArgs(ctx, filePath),
 		"-af", "astats=metadata=1:reset=1",
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("audio clipping analysis failed: %w", err)
 	}
@@ -444,28 +463,47 @@ func (ca *ContentAnalyzer) analyzeAudioClipping(ctx context.Context, filePath st
 
 // analyzeSilence detects silence/mute periods using FFmpeg silencedetect
 func (ca *ContentAnalyzer) analyzeSilence(ctx context.Context, filePath string) (*SilenceAnalysis, error) {
-	// Default thresholds for broadcast QC
-	noiseThreshold := -50.0 // dB threshold for silence detection
-	minDuration := 0.5      // Minimum silence duration in seconds
+	return ca.analyzeStreamSilence(ctx, filePath, -1)
+}
 
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
-		"-af", fmt.Sprintf("silencedetect=noise=%ddB:d=%f", int(noiseThreshold), minDuration),
+// Default silence thresholds for broadcast QC
+const (
+	silenceNoiseThreshold = -50.0 // dB threshold for silence detection
+	silenceMinDuration    = 0.5   // Minimum silence duration in seconds
+)
+
+// analyzeStreamSilence detects silence in one audio stream, or the default
+// one when stream is negative
+func (ca *ContentAnalyzer) analyzeStreamSilence(ctx context.Context, filePath string, stream int) (*SilenceAnalysis, error) {
+	args := audioInputArgs(ctx, filePath, stream)
+	args = append(args,
+		"-af", fmt.Sprintf("silencedetect=noise=%ddB:d=%f", int(silenceNoiseThreshold), silenceMinDuration),
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, cmd)
 	if err != nil {
 		// silencedetect may return non-zero if no audio stream
 		ca.logger.Debug().Err(err).Msg("Silence detection completed with warnings")
 	}
 
-	// Parse silencedetect output
+	return summarizeSilence(parseSilenceDetect(output, -1)), nil
+}
+
+// parseSilenceDetect reads the silence periods silencedetect logged for one
+// channel, or for the whole stream when channel is negative, and the input
+// duration. With mono=1 each line names its channel:
+//
+//	[silencedetect @ 0x55a4] channel: 4 | silence_start: 12.5
+//	[silencedetect @ 0x55a4] channel: 4 | silence_end: 15.02 | silence_duration: 2.52
+func parseSilenceDetect(output []byte, channel int) ([]SilencePeriod, float64) {
 	lines := strings.Split(string(output), "\n")
 	var silencePeriods []SilencePeriod
 	var currentStart float64 = -1
 	var totalDuration float64
+	channelPrefix := fmt.Sprintf("channel: %d |", channel)
 
 	// Get total duration from ffprobe-style output
 	for _, line := range lines {
@@ -480,11 +518,15 @@ func (ca *ContentAnalyzer) analyzeSilence(ctx context.Context, filePath string)
 	}
 
 	for _, line := range lines {
+		if channel >= 0 && !strings.Contains(line, channelPrefix) {
+			continue
+		}
+
 		// Parse silence_start
 		if strings.Contains(line, "silence_start:") {
 			parts := strings.Split(line, "silence_start:")
 			if len(parts) > 1 {
-				startStr := strings.TrimSpace(strings.Split(parts[1], " ")[0])
+				startStr := firstField(parts[1])
 				if start, err := strconv.ParseFloat(startStr, 64); err == nil {
 					currentStart = start
 				}
@@ -498,7 +540,7 @@ func (ca *ContentAnalyzer) analyzeSilence(ctx context.Context, filePath string)
 			// Extract end time
 			parts := strings.Split(line, "silence_end:")
 			if len(parts) > 1 {
-				endStr := strings.TrimSpace(strings.Split(parts[1], " ")[0])
+				endStr := firstField(parts[1])
 				if end, err := strconv.ParseFloat(endStr, 64); err == nil {
 					endTime = end
 				}
@@ -508,7 +550,7 @@ func (ca *ContentAnalyzer) analyzeSilence(ctx context.Context, filePath string)
 			if strings.Contains(line, "silence_duration:") {
 				durParts := strings.Split(line, "silence_duration:")
 				if len(durParts) > 1 {
-					durStr := strings.TrimSpace(strings.Split(durParts[1], " ")[0])
+					durStr := firstField(durParts[1])
 					if dur, err := strconv.ParseFloat(durStr, 64); err == nil {
 						duration = dur
 					}
@@ -523,7 +565,7 @@ func (ca *ContentAnalyzer) analyzeSilence(ctx context.Context, filePath string)
 				StartTime:   currentStart,
 				EndTime:     endTime,
 				Duration:    duration,
-				NoiseFloor:  noiseThreshold,
+				NoiseFloor:  silenceNoiseThreshold,
 				IsStartMute: isStartMute,
 				IsEndMute:   isEndMute,
 			})
@@ -531,7 +573,12 @@ func (ca *ContentAnalyzer) analyzeSilence(ctx context.Context, filePath string)
 			currentStart = -1
 		}
 	}
+	return silencePeriods, totalDuration
+}
 
+// summarizeSilence totals the silence periods of an input of totalDuration
+// seconds
+func summarizeSilence(silencePeriods []SilencePeriod, totalDuration float64) *SilenceAnalysis {
 	// Calculate statistics
 	var totalSilenceSec float64
 	var longestSilenceSec float64
@@ -559,11 +606,31 @@ func (ca *ContentAnalyzer) analyzeSilence(ctx context.Context, filePath string)
 		TotalSilenceSec:    totalSilenceSec,
 		LongestSilenceSec:  longestSilenceSec,
 		SilencePercentage:  silencePercentage,
-		NoiseFloorDB:       noiseThreshold,
-		ThresholdDB:        noiseThreshold,
-		MinDurationSec:     minDuration,
+		NoiseFloorDB:       silenceNoiseThreshold,
+		ThresholdDB:        silenceNoiseThreshold,
+		MinDurationSec:     silenceMinDuration,
 		HasProblematicMute: hasProblematicMute,
-	}, nil
+	}
+}
+
+// audioInputArgs returns the ffmpeg input arguments that read one stream of
+// filePath, or let ffmpeg pick its default streams when stream is negative.
+// Sampled analyses read only the windows, as with contentInputArgs.
+func audioInputArgs(ctx context.Context, filePath string, stream int) []string {
+	args := contentInputArgs(ctx, filePath)
+	if stream >= 0 {
+		args = append(args, "-map", fmt.Sprintf("0:%d", stream))
+	}
+	return args
+}
+
+// firstField returns the first space separated field of a logged value,
+// e.g. "12.5" of " 12.5 | silence_duration: 2"
+func firstField(s string) string {
+	if fields := strings.Fields(s); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
 }
 
 // parseDurationToSeconds converts HH:MM:SS.ms format to seconds
@@ -583,18 +650,31 @@ func parseDurationToSeconds(duration string) float64 {
 
 // analyzePhase detects audio phase issues using FFmpeg aphasemeter
 func (ca *ContentAnalyzer) analyzePhase(ctx context.Context, filePath string) (*PhaseAnalysis, error) {
+	return ca.analyzeStreamPhase(ctx, filePath, -1)
+}
+
+// analyzeStreamPhase measures the phase of one audio stream, or the default
+// one when stream is negative
+func (ca *ContentAnalyzer) analyzeStreamPhase(ctx context.Context, filePath string, stream int) (*PhaseAnalysis, error) {
+	return ca.measurePhase(ctx, filePath, stream, "aphasemeter=video=0")
+}
+
+// measurePhase runs filter, which ends in aphasemeter, on one audio stream
+// and summarizes the phase it logs
+func (ca *ContentAnalyzer) measurePhase(ctx context.Context, filePath string, stream int, filter string) (*PhaseAnalysis, error) {
 	// aphasemeter outputs phase correlation values:
 	// +1.0 = perfectly in phase (mono compatible)
 	// 0.0 = unrelated (decorrelated)
 	// -1.0 = perfectly out of phase (will cancel in mono)
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
-		"-af", "aphasemeter=video=0",
+	args := audioInputArgs(ctx, filePath, stream)
+	args = append(args,
+		"-af", filter,
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, cmd)
 	if err != nil {
 		// aphasemeter may fail on mono or no-audio files
 		ca.logger.Debug().Err(err).Msg("Phase analysis completed with warnings")
@@ -617,7 +697,7 @@ func (ca *ContentAnalyzer) analyzePhase(ctx context.Context, filePath string) (*
 		if strings.Contains(line, "aphasemeter") && strings.Contains(line, "phase:") {
 			parts := strings.Split(line, "phase:")
 			if len(parts) > 1 {
-				phaseStr := strings.TrimSpace(strings.Split(parts[1], " ")[0])
+				phaseStr := firstField(parts[1])
 				if phase, err := strconv.ParseFloat(phaseStr, 64); err == nil {
 					phaseValues = append(phaseValues, phase)
 					totalPhase += phase
@@ -732,17 +812,20 @@ func (ca *ContentAnalyzer) analyzePhase(ctx context.Context, filePath string) (*
 	}, nil
 }
 
-// analyzeAudioLevels provides detailed audio level measurements using FFmpeg astats
+// analyzeAudioLevels provides detailed audio level measurements using FFmpeg
+// astats, and true peaks per channel using ebur128
 func (ca *ContentAnalyzer) analyzeAudioLevels(ctx context.Context, filePath string) (*AudioLevelAnalysis, error) {
-	// Use astats filter for comprehensive audio statistics
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
-		"-af", "astats=metadata=1:reset=0",
+	// Use astats filter for comprehensive audio statistics. Its peaks are
+	// sample peaks, so ebur128 adds 4x oversampled true peaks; its frame log
+	// carries the running true peak of each channel.
+	args := append(contentInputArgs(ctx, filePath),
+		"-af", "astats=metadata=1:reset=0,ebur128=peak=true:framelog=info",
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, cmd)
 	if err != nil {
 		ca.logger.Debug().Err(err).Msg("Audio level analysis completed with warnings")
 	}
@@ -755,12 +838,21 @@ func (ca *ContentAnalyzer) analyzeAudioLevels(ctx context.Context, filePath stri
 	var clippingCount int
 	var dcOffsetTotal float64
 
+	var truePeaks []*float64
+
 	currentChannel := -1
 	var currentChannelInfo ChannelLevelInfo
 
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
 
+		if strings.Contains(line, "Parsed_ebur128") {
+			if peaks := loudnessChannelPeaks(line, " TPK:"); peaks != nil {
+				truePeaks = peaks
+			}
+			continue
+		}
+
 		// Detect channel sections
 		if strings.Contains(line, "Channel:") {
 			// Save previous channel if exists
@@ -852,6 +944,14 @@ func (ca *ContentAnalyzer) analyzeAudioLevels(ctx context.Context, filePath stri
 		channels = append(channels, currentChannelInfo)
 	}
 
+	var overallTruePeak *float64
+	for i, peak := range truePeaks {
+		if i < len(channels) && peak != nil {
+			channels[i].TruePeakDB = peak
+			overallTruePeak = maxLoudness(overallTruePeak, *peak)
+		}
+	}
+
 	// Calculate overall statistics
 	dynamicRange := overallPeakDB - overallRMSDB
 	crestFactor := 0.0
@@ -870,22 +970,29 @@ func (ca *ContentAnalyzer) analyzeAudioLevels(ctx context.Context, filePath stri
 	// Check for clipping (peak > -0.1 dB)
 	hasClipping := overallPeakDB > -0.1 || clippingCount > 0
 
+	// Broadcast limits apply to true peaks, which inter-sample peaks can
+	// push above the sample peak
+	peak := overallPeakDB
+	if overallTruePeak != nil {
+		peak = *overallTruePeak
+	}
+
 	// Calculate headroom (how much room below 0dB)
-	headroom := -overallPeakDB
+	headroom := -peak
 	if headroom < 0 {
 		headroom = 0
 	}
 
 	// Broadcast safe check (peak < -1dB, no excessive DC offset)
-	isBroadcastSafe := overallPeakDB < -1.0 && avgDCOffset < 0.1 && avgDCOffset > -0.1
+	isBroadcastSafe := peak < -1.0 && avgDCOffset < 0.1 && avgDCOffset > -0.1
 
 	// Determine severity
 	var severity string
-	if overallPeakDB > 0 {
+	if peak > 0 {
 		severity = "critical"
-	} else if overallPeakDB > -1.0 || hasClipping {
+	} else if peak > -1.0 || hasClipping {
 		severity = "warning"
-	} else if overallPeakDB > -3.0 {
+	} else if peak > -3.0 {
 		severity = "minor"
 	} else {
 		severity = "none"
@@ -903,9 +1010,30 @@ func (ca *ContentAnalyzer) analyzeAudioLevels(ctx context.Context, filePath stri
 		IsBroadcastSafe:  isBroadcastSafe,
 		Headroom:         headroom,
 		Severity:         severity,
+		OverallTruePeak:  overallTruePeak,
+		TruePeakLimits:   checkTruePeakLimits(channels, overallTruePeak),
 	}, nil
 }
 
+// checkTruePeakLimits validates channel true peaks against the EBU R128
+// and ATSC A/85 limits. Nothing is checked without true peaks.
+func checkTruePeakLimits(channels []ChannelLevelInfo, overallTruePeak *float64) []TruePeakLimitCheck {
+	if overallTruePeak == nil {
+		return nil
+	}
+	checks := make([]TruePeakLimitCheck, 0, 2)
+	for _, target := range []LoudnessTarget{LoudnessTargetEBUR128, LoudnessTargetATSCA85} {
+		check := TruePeakLimitCheck{Standard: target.Name, LimitDBTP: target.TruePeak, Compliant: *overallTruePeak <= target.TruePeak}
+		for _, channel := range channels {
+			if channel.TruePeakDB != nil && *channel.TruePeakDB > target.TruePeak {
+				check.ChannelsOver = append(check.ChannelsOver, channel.Channel)
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
 // parseAudioStatValue extracts a numeric value from an astats output line
 func parseAudioStatValue(line string) float64 {
 	parts := strings.Split(line, ":")
@@ -933,7 +1061,7 @@ func (ca *ContentAnalyzer) analyzeLetterbox(ctx context.Context, filePath string
 		"-",
 	)
 
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, cmd)
 	if err != nil {
 		ca.logger.Debug().Err(err).Msg("Letterbox detection completed with warnings")
 	}
@@ -1108,14 +1236,14 @@ func (ca *ContentAnalyzer) analyzeDropouts(ctx context.Context, filePath string)
 	framesAnalyzed := 0
 
 	// Detect audio dropouts using silence detection with shorter duration threshold
-	audioCmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	audioArgs := append(contentInputArgs(ctx, filePath),
 		"-af", "silencedetect=noise=-60dB:d=0.1",
 		"-f", "null",
 		"-",
 	)
+	audioCmd := exec.CommandContext(ctx, ca.ffmpegPath, audioArgs...)
 
-	audioOutput, _ := audioCmd.CombinedOutput()
+	audioOutput, _ := commandCombinedOutput(ctx, audioCmd)
 	audioLines := strings.Split(string(audioOutput), "\n")
 
 	// Get total duration from output
@@ -1188,14 +1316,14 @@ func (ca *ContentAnalyzer) analyzeDropouts(ctx context.Context, filePath string)
 	}
 
 	// Detect video dropouts using freezedetect (frozen frames = potential dropout)
-	videoCmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	videoArgs := append(contentInputArgs(ctx, filePath),
 		"-vf", "freezedetect=n=0.003:d=0.05",
 		"-f", "null",
 		"-",
 	)
+	videoCmd := exec.CommandContext(ctx, ca.ffmpegPath, videoArgs...)
 
-	videoOutput, _ := videoCmd.CombinedOutput()
+	videoOutput, _ := commandCombinedOutput(ctx, videoCmd)
 	videoLines := strings.Split(string(videoOutput), "\n")
 
 	var currentFreezeStart float64 = -1
@@ -1310,14 +1438,14 @@ func (ca *ContentAnalyzer) analyzeDropouts(ctx context.Context, filePath string)
 
 // analyzeBlockiness measures compression blockiness
 func (ca *ContentAnalyzer) analyzeBlockiness(ctx context.Context, filePath string) (*BlockinessAnalysis, error) {
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(contentInputArgs(ctx, filePath),
 		"-vf", "blockdetect",
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("blockiness analysis failed: %w", err)
 	}
@@ -1357,14 +1485,14 @@ func (ca *ContentAnalyzer) analyzeBlockiness(ctx context.Context, filePath strin
 // analyzeBlurriness measures image sharpness
 func (ca *ContentAnalyzer) analyzeBlurriness(ctx context.Context, filePath string) (*BlurrinessAnalysis, error) {
 	// Use a simple edge detection approach for blur measurement
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(contentInputArgs(ctx, filePath),
 		"-vf", "convolution='0 -1 0:-1 5 -1:0 -1 0:0 -1 0:-1 5 -1:0 -1 0',signalstats",
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("blurriness analysis failed: %w", err)
 	}
@@ -1406,14 +1534,14 @@ func (ca *ContentAnalyzer) analyzeBlurriness(ctx context.Context, filePath strin
 
 // analyzeInterlacing detects interlacing artifacts
 func (ca *ContentAnalyzer) analyzeInterlacing(ctx context.Context, filePath string) (*InterlaceAnalysis, error) {
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(contentInputArgs(ctx, filePath),
 		"-vf", "idet",
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("interlace detection failed: %w", err)
 	}
@@ -1459,14 +1587,14 @@ func (ca *ContentAnalyzer) analyzeInterlacing(ctx context.Context, filePath stri
 
 // analyzeNoise measures video noise levels
 func (ca *ContentAnalyzer) analyzeNoise(ctx context.Context, filePath string) (*NoiseAnalysis, error) {
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(contentInputArgs(ctx, filePath),
 		"-vf", "signalstats",
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("noise analysis failed: %w", err)
 	}
@@ -1502,70 +1630,39 @@ func (ca *ContentAnalyzer) analyzeNoise(ctx context.Context, filePath string) (*
 	}, nil
 }
 
-// analyzeLoudness provides broadcast loudness compliance
+// analyzeLoudness provides broadcast loudness compliance and a loudness
+// timeline for drawing
 func (ca *ContentAnalyzer) analyzeLoudness(ctx context.Context, filePath string) (*LoudnessAnalysis, error) {
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
-		"-af", "ebur128=metadata=1",
+	return ca.analyzeStreamLoudness(ctx, filePath, -1)
+}
+
+// analyzeStreamLoudness measures the loudness of one audio stream, or the
+// default one when stream is negative
+func (ca *ContentAnalyzer) analyzeStreamLoudness(ctx context.Context, filePath string, stream int) (*LoudnessAnalysis, error) {
+	args := append([]string{"-nostats"}, audioInputArgs(ctx, filePath, stream)...)
+	args = append(args,
+		// Frame logging drops to verbose with some options, so pin it to info.
+		// ebur128 passes the audio on to the speech detection of the dialog
+		// loudness.
+		"-af", "ebur128=peak=true:framelog=info,"+dialogLoudnessFilter,
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("loudness analysis failed: %w", err)
 	}
 
-	// Parse EBU R128 output
-	lines := strings.Split(string(output), "\n")
-	var integratedLoudness, loudnessRange, truePeak float64
-
-	for _, line := range lines {
-		if strings.Contains(line, "Integrated loudness:") {
-			parts := strings.Fields(line)
-			for i, part := range parts {
-				if part == "I:" && i+1 < len(parts) {
-					val := strings.TrimSuffix(parts[i+1], " LUFS")
-					if lufs, err := strconv.ParseFloat(val, 64); err == nil {
-						integratedLoudness = lufs
-					}
-				}
-			}
-		}
-		if strings.Contains(line, "Loudness range:") {
-			parts := strings.Fields(line)
-			for i, part := range parts {
-				if part == "LRA:" && i+1 < len(parts) {
-					val := strings.TrimSuffix(parts[i+1], " LU")
-					if lu, err := strconv.ParseFloat(val, 64); err == nil {
-						loudnessRange = lu
-					}
-				}
-			}
-		}
-		if strings.Contains(line, "True peak:") {
-			parts := strings.Fields(line)
-			for i, part := range parts {
-				if part == "Peak:" && i+1 < len(parts) {
-					val := strings.TrimSuffix(parts[i+1], " dBTP")
-					if dbtp, err := strconv.ParseFloat(val, 64); err == nil {
-						truePeak = dbtp
-					}
-				}
-			}
-		}
-	}
+	analysis := parseLoudnessOutput(output)
+	analysis.Dialog = parseDialogLoudness(output)
 
 	// Check compliance with broadcast standards (EBU R128)
-	compliant := integratedLoudness >= -25.0 && integratedLoudness <= -21.0 && truePeak <= -1.0
-
-	return &LoudnessAnalysis{
-		IntegratedLoudness: integratedLoudness,
-		LoudnessRange:      loudnessRange,
-		TruePeak:           truePeak,
-		Compliant:          compliant,
-		Standard:           "EBU R128",
-	}, nil
+	analysis.Compliant = analysis.IntegratedLoudness >= -25.0 && analysis.IntegratedLoudness <= -21.0 && analysis.TruePeak <= -1.0
+	analysis.Normalization = normalizeLoudness(analysis, loudnessTargets(ctx))
+
+	return analysis, nil
 }
 
 // analyzeColorBars detects color bars/test patterns at start/end of content
@@ -1583,12 +1680,12 @@ func (ca *ContentAnalyzer) analyzeColorBars(ctx context.Context, filePath string
 	detectedPattern := ""
 
 	// Get total duration first
-	durationCmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	durationArgs := append(headerInputArgs(ctx, filePath),
 		"-f", "null",
 		"-",
 	)
-	durationOutput, _ := durationCmd.CombinedOutput()
+	durationCmd := exec.CommandContext(ctx, ca.ffmpegPath, durationArgs...)
+	durationOutput, _ := commandCombinedOutput(ctx, durationCmd)
 	for _, line := range strings.Split(string(durationOutput), "\n") {
 		if strings.Contains(line, "Duration:") && strings.Contains(line, ",") {
 			parts := strings.Split(line, "Duration:")
@@ -1609,7 +1706,7 @@ func (ca *ContentAnalyzer) analyzeColorBars(ctx context.Context, filePath string
 		"-",
 	)
 
-	startOutput, _ := startCmd.CombinedOutput()
+	startOutput, _ := commandCombinedOutput(ctx, startCmd)
 	startLines := strings.Split(string(startOutput), "\n")
 
 	// Count frames with characteristics typical of color bars:
@@ -1674,7 +1771,7 @@ func (ca *ContentAnalyzer) analyzeColorBars(ctx context.Context, filePath string
 			"-",
 		)
 
-		endOutput, _ := endCmd.CombinedOutput()
+		endOutput, _ := commandCombinedOutput(ctx, endCmd)
 		endLines := strings.Split(string(endOutput), "\n")
 
 		endLowDiffFrames := 0
@@ -1767,12 +1864,12 @@ func (ca *ContentAnalyzer) analyzeTestTone(ctx context.Context, filePath string)
 	var totalDuration float64
 
 	// Get total duration
-	durationCmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	durationArgs := append(headerInputArgs(ctx, filePath),
 		"-f", "null",
 		"-",
 	)
-	durationOutput, _ := durationCmd.CombinedOutput()
+	durationCmd := exec.CommandContext(ctx, ca.ffmpegPath, durationArgs...)
+	durationOutput, _ := commandCombinedOutput(ctx, durationCmd)
 	for _, line := range strings.Split(string(durationOutput), "\n") {
 		if strings.Contains(line, "Duration:") && strings.Contains(line, ",") {
 			parts := strings.Split(line, "Duration:")
@@ -1793,7 +1890,7 @@ func (ca *ContentAnalyzer) analyzeTestTone(ctx context.Context, filePath string)
 		"-",
 	)
 
-	startOutput, _ := startCmd.CombinedOutput()
+	startOutput, _ := commandCombinedOutput(ctx, startCmd)
 	startLines := strings.Split(string(startOutput), "\n")
 
 	// Look for consistent audio characteristics of test tone:
@@ -1881,7 +1978,7 @@ func (ca *ContentAnalyzer) analyzeTestTone(ctx context.Context, filePath string)
 			"-",
 		)
 
-		endOutput, _ := endCmd.CombinedOutput()
+		endOutput, _ := commandCombinedOutput(ctx, endCmd)
 		endLines := strings.Split(string(endOutput), "\n")
 
 		var endRMSValues []float64
@@ -1991,12 +2088,12 @@ func (ca *ContentAnalyzer) analyzeSafeArea(ctx context.Context, filePath string)
 	var originalWidth, originalHeight int
 
 	// Get video dimensions first
-	dimCmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	dimArgs := append(headerInputArgs(ctx, filePath),
 		"-f", "null",
 		"-",
 	)
-	dimOutput, _ := dimCmd.CombinedOutput()
+	dimCmd := exec.CommandContext(ctx, ca.ffmpegPath, dimArgs...)
+	dimOutput, _ := commandCombinedOutput(ctx, dimCmd)
 	for _, line := range strings.Split(string(dimOutput), "\n") {
 		if strings.Contains(line, "Video:") && strings.Contains(line, "x") {
 			parts := strings.Fields(line)
@@ -2040,7 +2137,7 @@ func (ca *ContentAnalyzer) analyzeSafeArea(ctx context.Context, filePath string)
 		"-",
 	)
 
-	output, _ := cmd.CombinedOutput()
+	output, _ := commandCombinedOutput(ctx, cmd)
 	lines := strings.Split(string(output), "\n")
 
 	var cropValues []struct{ w, h, x, y int }
@@ -2117,14 +2214,14 @@ func (ca *ContentAnalyzer) analyzeChannelMapping(ctx context.Context, filePath s
 	// Analyze audio stream channel configuration
 	// Check for proper channel layout (stereo, 5.1, 7.1, etc.)
 
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
-		"-af", "astats=metadata=1:reset=0,channelsplit",
+	args := append(contentInputArgs(ctx, filePath),
+		"-af", "astats=metadata=1:reset=0",
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, _ := cmd.CombinedOutput()
+	output, _ := commandCombinedOutput(ctx, cmd)
 	lines := strings.Split(string(output), "\n")
 
 	// Parse audio stream info
@@ -2174,67 +2271,7 @@ func (ca *ContentAnalyzer) analyzeChannelMapping(ctx context.Context, filePath s
 	}
 
 	// Parse per-channel statistics from astats
-	currentChannel := -1
-	var currentPeak, currentRMS float64
-
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-
-		if strings.Contains(line, "Channel:") {
-			// Save previous channel
-			if currentChannel >= 0 {
-				isSilent := currentRMS < -60
-				isActive := currentPeak > -60
-
-				channelDetails = append(channelDetails, ChannelDetail{
-					Index:     currentChannel,
-					Name:      getChannelName(currentChannel, channelLayout),
-					PeakLevel: currentPeak,
-					RMSLevel:  currentRMS,
-					IsSilent:  isSilent,
-					IsActive:  isActive,
-				})
-			}
-
-			// Start new channel
-			parts := strings.Split(line, ":")
-			if len(parts) > 1 {
-				if ch, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil {
-					currentChannel = ch
-					currentPeak = -96.0
-					currentRMS = -96.0
-				}
-			}
-		}
-
-		if currentChannel >= 0 {
-			if strings.Contains(line, "Peak level dB:") {
-				if val := parseAudioStatValue(line); val != -1000 {
-					currentPeak = val
-				}
-			}
-			if strings.Contains(line, "RMS level dB:") {
-				if val := parseAudioStatValue(line); val != -1000 {
-					currentRMS = val
-				}
-			}
-		}
-	}
-
-	// Save last channel
-	if currentChannel >= 0 {
-		isSilent := currentRMS < -60
-		isActive := currentPeak > -60
-
-		channelDetails = append(channelDetails, ChannelDetail{
-			Index:     currentChannel,
-			Name:      getChannelName(currentChannel, channelLayout),
-			PeakLevel: currentPeak,
-			RMSLevel:  currentRMS,
-			IsSilent:  isSilent,
-			IsActive:  isActive,
-		})
-	}
+	channelDetails = parseChannelStats(output, channelLayout)
 
 	// Validate channel configuration
 	hasSurround := totalChannels > 2
@@ -2293,7 +2330,7 @@ func (ca *ContentAnalyzer) analyzeChannelMapping(ctx context.Context, filePath s
 		}
 	}
 
-	return &ChannelMappingAnalysis{
+	analysis := &ChannelMappingAnalysis{
 		TotalChannels:     totalChannels,
 		ChannelLayout:     channelLayout,
 		ExpectedLayout:    "", // Could be set based on delivery specs
@@ -2303,7 +2340,9 @@ func (ca *ContentAnalyzer) analyzeChannelMapping(ctx context.Context, filePath s
 		HasLFE:            hasLFE,
 		IsBroadcastLayout: isBroadcastLayout,
 		LayoutIssues:      layoutIssues,
-	}, nil
+	}
+	checkChannelRemap(analysis)
+	return analysis, nil
 }
 
 // getChannelName returns human-readable channel name based on index and layout
@@ -2334,13 +2373,13 @@ func (ca *ContentAnalyzer) analyzeTimecodeContinuity(ctx context.Context, filePa
 	// Analyze timecode metadata from video stream
 	// Check for gaps, discontinuities, and proper formatting
 
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(headerInputArgs(ctx, filePath),
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, _ := cmd.CombinedOutput()
+	output, _ := commandCombinedOutput(ctx, cmd)
 	lines := strings.Split(string(output), "\n")
 
 	hasTimecode := false
@@ -2395,13 +2434,13 @@ func (ca *ContentAnalyzer) analyzeTimecodeContinuity(ctx context.Context, filePa
 	// If no timecode in metadata, check for timecode data stream
 	if !hasTimecode {
 		// Try to extract timecode from data streams
-		tcCmd := exec.CommandContext(ctx, ca.ffmpegPath,
-			"-i", filePath,
+		tcArgs := append(headerInputArgs(ctx, filePath),
 			"-map", "0:d?", // Select data streams
 			"-f", "null",
 			"-",
 		)
-		tcOutput, _ := tcCmd.CombinedOutput()
+		tcCmd := exec.CommandContext(ctx, ca.ffmpegPath, tcArgs...)
+		tcOutput, _ := commandCombinedOutput(ctx, tcCmd)
 
 		for _, line := range strings.Split(string(tcOutput), "\n") {
 			if strings.Contains(line, "timecode") || strings.Contains(line, "Data:") {
@@ -2447,14 +2486,14 @@ func (ca *ContentAnalyzer) analyzeTimecodeContinuity(ctx context.Context, filePa
 func (ca *ContentAnalyzer) analyzeBaseband(ctx context.Context, filePath string) (*BasebandAnalysis, error) {
 	// Use signalstats filter for comprehensive baseband analysis
 	// This measures luminance levels, chroma levels, and broadcast range violations
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(contentInputArgs(ctx, filePath),
 		"-vf", "signalstats=stat=tout+vrep+brng",
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, _ := cmd.CombinedOutput()
+	output, _ := commandCombinedOutput(ctx, cmd)
 	lines := strings.Split(string(output), "\n")
 
 	var highestLuma, lowestLuma float64 = 0, 255
@@ -2624,14 +2663,14 @@ func (ca *ContentAnalyzer) analyzeVideoQualityScore(ctx context.Context, filePat
 	// Use multiple filters to compute quality scores
 	// signalstats for sharpness/contrast, blur detection for blur score
 
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(contentInputArgs(ctx, filePath),
 		"-vf", "signalstats=stat=tout+vrep+brng,entropy",
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, _ := cmd.CombinedOutput()
+	output, _ := commandCombinedOutput(ctx, cmd)
 	lines := strings.Split(string(output), "\n")
 
 	var totalSharpness, totalEntropy float64
@@ -2727,22 +2766,23 @@ func (ca *ContentAnalyzer) analyzeVideoQualityScore(ctx context.Context, filePat
 	}, nil
 }
 
-// analyzeTemporalComplexity measures scene complexity and motion over time
-func (ca *ContentAnalyzer) analyzeTemporalComplexity(ctx context.Context, filePath string) (*TemporalComplexityAnalysis, error) {
-	// Use signalstats YDIF for temporal difference and scene change detection
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
-		"-vf", "signalstats=stat=tout+vrep,select='gt(scene,0.3)',showinfo",
+// analyzeTemporalComplexity measures scene complexity and motion over time,
+// and lists the scene changes found on the way
+func (ca *ContentAnalyzer) analyzeTemporalComplexity(ctx context.Context, filePath string) (*TemporalComplexityAnalysis, *SceneChangeAnalysis, error) {
+	// Use signalstats YDIF for temporal difference and scene change detection,
+	// and siti for the spatial and temporal information of every frame
+	args := append(contentInputArgs(ctx, filePath),
+		"-vf", fmt.Sprintf("siti=print_summary=1,signalstats=stat=tout+vrep,select='gt(scene,%g)',metadata=print:key=lavfi.scene_score", sceneChangeThreshold),
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, _ := cmd.CombinedOutput()
+	output, _ := commandCombinedOutput(ctx, cmd)
 	lines := strings.Split(string(output), "\n")
 
 	var totalComplexity, maxComplexity, minComplexity float64 = 0, 0, 1000
 	var complexityValues []float64
-	var sceneChanges int
 	framesAnalyzed := 0
 	var totalDuration float64
 
@@ -2776,13 +2816,11 @@ func (ca *ContentAnalyzer) analyzeTemporalComplexity(ctx context.Context, filePa
 				}
 			}
 		}
-
-		// Count scene changes from select filter
-		if strings.Contains(line, "select") && strings.Contains(line, "pts_time") {
-			sceneChanges++
-		}
 	}
 
+	scenes := parseSceneChanges(output, totalDuration)
+	sceneChanges := scenes.Count
+
 	avgComplexity := 0.0
 	variance := 0.0
 	highMotionCount := 0
@@ -2842,20 +2880,21 @@ func (ca *ContentAnalyzer) analyzeTemporalComplexity(ctx context.Context, filePa
 		ComplexityClass:    complexityClass,
 		EncodingDifficulty: encodingDifficulty,
 		FramesAnalyzed:     framesAnalyzed,
-	}, nil
+		SITI:               parseSITISummary(output),
+	}, scenes, nil
 }
 
 // analyzeFieldDominance detects field order issues in interlaced content
 func (ca *ContentAnalyzer) analyzeFieldDominance(ctx context.Context, filePath string) (*FieldDominanceAnalysis, error) {
 	// Use idet filter for interlace detection and field order analysis
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(contentInputArgs(ctx, filePath),
 		"-vf", "idet",
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, _ := cmd.CombinedOutput()
+	output, _ := commandCombinedOutput(ctx, cmd)
 	lines := strings.Split(string(output), "\n")
 
 	var tff, bff, progressive, undetermined int
@@ -2969,14 +3008,14 @@ func (ca *ContentAnalyzer) analyzeFieldDominance(ctx context.Context, filePath s
 // analyzeDifferentialFrames detects frame differences and anomalies
 func (ca *ContentAnalyzer) analyzeDifferentialFrames(ctx context.Context, filePath string) (*DifferentialFrameAnalysis, error) {
 	// Use signalstats YDIF for frame-to-frame differences
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(contentInputArgs(ctx, filePath),
 		"-vf", "signalstats=stat=tout+vrep",
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, _ := cmd.CombinedOutput()
+	output, _ := commandCombinedOutput(ctx, cmd)
 	lines := strings.Split(string(output), "\n")
 
 	var differences []float64
@@ -3082,14 +3121,14 @@ func (ca *ContentAnalyzer) analyzeDifferentialFrames(ctx context.Context, filePa
 func (ca *ContentAnalyzer) analyzeLineErrors(ctx context.Context, filePath string) (*LineErrorAnalysis, error) {
 	// Use signalstats with out-of-range detection to find line errors
 	// Line errors typically show as horizontal bands with incorrect values
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(contentInputArgs(ctx, filePath),
 		"-vf", "signalstats=stat=tout+vrep+brng",
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, _ := cmd.CombinedOutput()
+	output, _ := commandCombinedOutput(ctx, cmd)
 	lines := strings.Split(string(output), "\n")
 
 	var lumaLineErrors, chromaLineErrors, digiBetaErrors int
@@ -3180,14 +3219,14 @@ func (ca *ContentAnalyzer) analyzeLineErrors(ctx context.Context, filePath strin
 // analyzeAudioFrequency provides detailed audio frequency analysis
 func (ca *ContentAnalyzer) analyzeAudioFrequency(ctx context.Context, filePath string) (*AudioFrequencyAnalysis, error) {
 	// Use astats and showfreqs for frequency analysis
-	cmd := exec.CommandContext(ctx, ca.ffmpegPath,
-		"-i", filePath,
+	args := append(contentInputArgs(ctx, filePath),
 		"-af", "astats=metadata=1:reset=0",
 		"-f", "null",
 		"-",
 	)
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, args...)
 
-	output, _ := cmd.CombinedOutput()
+	output, _ := commandCombinedOutput(ctx, cmd)
 	lines := strings.Split(string(output), "\n")
 
 	var sampleRate float64
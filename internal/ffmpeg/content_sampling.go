@@ -0,0 +1,432 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ContentSamplingOptions makes content analysis of long assets read evenly
+// spaced windows instead of decoding the whole asset
+type ContentSamplingOptions struct {
+	Windows       int     `json:"windows"`                        // Number of windows, the first at the start and the last at the end
+	WindowSeconds float64 `json:"window_seconds"`                 // Length of each window
+	MinDuration   float64 `json:"min_duration_seconds,omitempty"` // Shorter assets are analyzed in full
+}
+
+// Validate checks that the options describe at least one window
+func (o *ContentSamplingOptions) Validate() error {
+	if o.Windows < 1 {
+		return fmt.Errorf("content sampling needs at least one window")
+	}
+	if o.WindowSeconds <= 0 {
+		return fmt.Errorf("content sampling window must be longer than 0 seconds")
+	}
+	if o.MinDuration < 0 {
+		return fmt.Errorf("content sampling minimum duration must not be negative")
+	}
+	return nil
+}
+
+// ContentSampling describes the windows a sampled content analysis read.
+// Counts and durations of detected events are extrapolated from the windows
+// to the whole asset and listed in Extrapolated; levels, averages and
+// percentages are measured over the windows. Event timestamps are positions
+// in the asset. Analyses that inspect the start and end of the asset, or
+// only its stream headers, read the asset itself.
+type ContentSampling struct {
+	Windows             int       `json:"windows"`
+	WindowSeconds       float64   `json:"window_seconds"`
+	WindowStarts        []float64 `json:"window_starts"`
+	SampledSeconds      float64   `json:"sampled_seconds"`
+	TotalSeconds        float64   `json:"total_seconds"`
+	Coverage            float64   `json:"coverage"`             // Share of the asset read, 0-1
+	ExtrapolationFactor float64   `json:"extrapolation_factor"` // TotalSeconds / SampledSeconds
+	Extrapolated        []string  `json:"extrapolated,omitempty"`
+	Confidence          float64   `json:"confidence"`       // 0-1, see samplingConfidence
+	ConfidenceLevel     string    `json:"confidence_level"` // high, medium or low
+}
+
+// sampleJoinTolerance is how long after a window join cuts and sudden
+// changes are attributed to the join rather than the content. Windows start
+// at the keyframe before their in point, so the join is not frame accurate.
+const sampleJoinTolerance = 0.5
+
+type contentSamplingKey struct{}
+
+// withContentSampling makes content analysis under ctx sample long assets
+func withContentSampling(ctx context.Context, options *ContentSamplingOptions) context.Context {
+	if options == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, contentSamplingKey{}, options)
+}
+
+type samplingPlanKey struct{}
+
+// samplingPlan is the set of windows a content analysis reads from input,
+// through an ffconcat list that joins them. The plan of a segment is its one
+// window, read with -ss and -t and not extrapolated.
+type samplingPlan struct {
+	input    string
+	listPath string
+	starts   []float64
+	window   float64
+	total    float64
+	segment  *AnalysisSegment
+}
+
+// sampleWindowStarts spreads windows evenly over duration, the first at the
+// start and the last ending at the end. It returns nil when the windows
+// would cover the whole asset.
+func sampleWindowStarts(duration float64, windows int, window float64) []float64 {
+	if windows < 1 || window <= 0 || float64(windows)*window >= duration {
+		return nil
+	}
+	if windows == 1 {
+		return []float64{(duration - window) / 2}
+	}
+	stride := (duration - window) / float64(windows-1)
+	starts := make([]float64, windows)
+	for i := range starts {
+		starts[i] = float64(i) * stride
+	}
+	return starts
+}
+
+// startSampling plans the windows of filePath when ctx asks for sampling
+// and the asset is long enough, or the one window of the segment ctx limits
+// the analysis to. The returned context carries the plan to the analyzers;
+// the plan must be closed once they are done.
+func (ca *ContentAnalyzer) startSampling(ctx context.Context, filePath string) (context.Context, *samplingPlan) {
+	if segment := segmentFromContext(ctx); segment != nil {
+		plan := &samplingPlan{input: filePath, starts: []float64{segment.StartTime}, window: segment.Duration, total: segment.Duration, segment: segment}
+		return context.WithValue(ctx, samplingPlanKey{}, plan), plan
+	}
+
+	options, ok := ctx.Value(contentSamplingKey{}).(*ContentSamplingOptions)
+	if !ok || strings.Contains(filePath, "://") {
+		return ctx, nil
+	}
+
+	duration := ca.probeDuration(ctx, filePath)
+	if duration <= 0 || duration < options.MinDuration {
+		return ctx, nil
+	}
+	starts := sampleWindowStarts(duration, options.Windows, options.WindowSeconds)
+	if starts == nil {
+		return ctx, nil
+	}
+
+	plan := &samplingPlan{input: filePath, starts: starts, window: options.WindowSeconds, total: duration}
+	if err := plan.writeList(); err != nil {
+		ca.logger.Warn().Err(err).Msg("Content sampling unavailable, analyzing the whole asset")
+		return ctx, nil
+	}
+	ca.logger.Debug().
+		Int("windows", len(starts)).
+		Float64("window_seconds", plan.window).
+		Float64("duration", duration).
+		Msg("Sampling content analysis")
+	return context.WithValue(ctx, samplingPlanKey{}, plan), plan
+}
+
+// probeDuration reads the duration of filePath from the header ffmpeg prints
+func (ca *ContentAnalyzer) probeDuration(ctx context.Context, filePath string) float64 {
+	// Without an output ffmpeg stops after printing the input header
+	cmd := exec.CommandContext(ctx, ca.ffmpegPath, "-hide_banner", "-i", filePath)
+	output, _ := commandCombinedOutput(ctx, cmd)
+
+	var duration float64
+	forEachLine(output, func(line string) bool {
+		if parts := strings.SplitN(line, "Duration:", 2); len(parts) == 2 {
+			duration = parseDurationToSeconds(strings.Split(parts[1], ",")[0])
+			return false
+		}
+		return true
+	})
+	return duration
+}
+
+// writeList writes the ffconcat list that reads the windows of the input
+func (p *samplingPlan) writeList() error {
+	input, err := filepath.Abs(p.input)
+	if err != nil {
+		return err
+	}
+
+	var list strings.Builder
+	list.WriteString("ffconcat version 1.0\n")
+	for _, start := range p.starts {
+		fmt.Fprintf(&list, "file '%s'\n", strings.ReplaceAll(input, "'", `'\''`))
+		fmt.Fprintf(&list, "inpoint %s\noutpoint %s\n", formatSeconds(start), formatSeconds(start+p.window))
+	}
+
+	file, err := os.CreateTemp("", "content_sampling_*.ffconcat")
+	if err != nil {
+		return fmt.Errorf("failed to create sampling list: %w", err)
+	}
+	defer file.Close()
+	p.listPath = file.Name()
+	if _, err := file.WriteString(list.String()); err != nil {
+		os.Remove(p.listPath)
+		return fmt.Errorf("failed to write sampling list: %w", err)
+	}
+	return nil
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
+
+// close removes the ffconcat list
+func (p *samplingPlan) close() {
+	if p.listPath != "" {
+		os.Remove(p.listPath)
+	}
+}
+
+// contentInputArgs returns the ffmpeg input arguments that read filePath, or
+// only its windows when the content analysis under ctx samples it
+func contentInputArgs(ctx context.Context, filePath string) []string {
+	if plan, ok := ctx.Value(samplingPlanKey{}).(*samplingPlan); ok && plan.input == filePath {
+		if plan.segment != nil {
+			return plan.segment.inputArgs(filePath)
+		}
+		return []string{"-f", "concat", "-safe", "0", "-i", plan.listPath}
+	}
+	return []string{"-i", filePath}
+}
+
+// headerInputArgs returns the ffmpeg input arguments for commands that only
+// need the header ffmpeg prints for filePath. When the content analysis under
+// ctx samples the asset, only its first window is read rather than all of it.
+func headerInputArgs(ctx context.Context, filePath string) []string {
+	if plan, ok := ctx.Value(samplingPlanKey{}).(*samplingPlan); ok && plan.input == filePath {
+		if plan.segment != nil {
+			return plan.segment.inputArgs(filePath)
+		}
+		return []string{"-t", formatSeconds(plan.window), "-i", filePath}
+	}
+	return []string{"-i", filePath}
+}
+
+// sourceTime maps a position in the joined windows to the asset
+func (p *samplingPlan) sourceTime(t float64) float64 {
+	if p.segment != nil {
+		return p.segment.StartTime + t
+	}
+	i := int(t / p.window)
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(p.starts) {
+		i = len(p.starts) - 1
+	}
+	return p.starts[i] + t - float64(i)*p.window
+}
+
+// atJoin reports whether a position in the joined windows falls just after
+// the join of two windows
+func (p *samplingPlan) atJoin(t float64) bool {
+	if p.segment != nil || t < p.window {
+		return false
+	}
+	return math.Mod(t, p.window) < sampleJoinTolerance
+}
+
+// samplingConfidence rates how well the windows represent the asset. Full
+// coverage is certain; otherwise the unread share is discounted by how many
+// windows it is spread over, so many short windows beat one long one.
+func samplingConfidence(coverage float64, windows int) float64 {
+	if coverage >= 1 {
+		return 1
+	}
+	spread := 1 - 1/math.Sqrt(float64(windows))
+	return coverage + (1-coverage)*spread
+}
+
+func samplingConfidenceLevel(confidence float64) string {
+	switch {
+	case confidence >= 0.8:
+		return "high"
+	case confidence >= 0.5:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+// apply maps the event times of a sampled analysis to the asset, drops the
+// cuts the window joins caused, extrapolates counts to the whole asset and
+// records the sampling on the analysis. The counts of a segment describe the
+// segment, so only its times are mapped.
+func (p *samplingPlan) apply(analysis *ContentAnalysis) {
+	var sampling *ContentSampling
+	factor := 1.0
+	if p.segment == nil {
+		sampled := float64(len(p.starts)) * p.window
+		factor = p.total / sampled
+		sampling = &ContentSampling{
+			Windows:             len(p.starts),
+			WindowSeconds:       p.window,
+			WindowStarts:        p.starts,
+			SampledSeconds:      sampled,
+			TotalSeconds:        p.total,
+			Coverage:            sampled / p.total,
+			ExtrapolationFactor: factor,
+		}
+		sampling.Confidence = samplingConfidence(sampling.Coverage, sampling.Windows)
+		sampling.ConfidenceLevel = samplingConfidenceLevel(sampling.Confidence)
+		analysis.Sampling = sampling
+	}
+
+	scaleCount := func(name string, count *int) {
+		if sampling == nil {
+			return
+		}
+		*count = int(math.Round(float64(*count) * factor))
+		sampling.Extrapolated = append(sampling.Extrapolated, name)
+	}
+	scaleSeconds := func(name string, seconds *float64) {
+		if sampling == nil {
+			return
+		}
+		*seconds *= factor
+		sampling.Extrapolated = append(sampling.Extrapolated, name)
+	}
+
+	if black := analysis.BlackFrames; black != nil {
+		p.mapPeriods(black.Periods)
+		scaleCount("black_frames.detected_frames", &black.DetectedFrames)
+	}
+	if freeze := analysis.FreezeFrames; freeze != nil {
+		p.mapPeriods(freeze.Periods)
+		scaleCount("freeze_frames.detected_frames", &freeze.DetectedFrames)
+	}
+	if silence := analysis.SilenceInfo; silence != nil {
+		for i := range silence.SilencePeriods {
+			period := &silence.SilencePeriods[i]
+			period.StartTime = p.sourceTime(period.StartTime)
+			period.EndTime = period.StartTime + period.Duration
+		}
+		scaleCount("silence_info.total_silence_count", &silence.TotalSilenceCount)
+		scaleSeconds("silence_info.total_silence_seconds", &silence.TotalSilenceSec)
+	}
+	if phase := analysis.PhaseInfo; phase != nil {
+		for i := range phase.PhaseEvents {
+			event := &phase.PhaseEvents[i]
+			event.StartTime = p.sourceTime(event.StartTime)
+			event.EndTime = event.StartTime + event.Duration
+		}
+	}
+	if levels := analysis.AudioLevelInfo; levels != nil {
+		scaleCount("audio_level_info.clipping_count", &levels.ClippingCount)
+	}
+	if dropouts := analysis.DropoutInfo; dropouts != nil {
+		p.mapDropouts(dropouts.VideoDropouts)
+		p.mapDropouts(dropouts.AudioDropouts)
+		scaleCount("dropout_info.total_video_dropouts", &dropouts.TotalVideoDropouts)
+		scaleCount("dropout_info.total_audio_dropouts", &dropouts.TotalAudioDropouts)
+		scaleSeconds("dropout_info.total_dropout_seconds", &dropouts.TotalDropoutSec)
+	}
+	if loudness := analysis.LoudnessMeter; loudness != nil {
+		for i := range loudness.Timeline {
+			loudness.Timeline[i].Time = p.sourceTime(loudness.Timeline[i].Time)
+		}
+	}
+	if baseband := analysis.BasebandInfo; baseband != nil {
+		for i := range baseband.ViolationFrames {
+			baseband.ViolationFrames[i].Timestamp = p.sourceTime(baseband.ViolationFrames[i].Timestamp)
+		}
+		scaleCount("baseband_info.luma_footroom_violations", &baseband.LumaFootroomViolations)
+		scaleCount("baseband_info.luma_headroom_violations", &baseband.LumaHeadroomViolations)
+		scaleCount("baseband_info.chroma_headroom_violations", &baseband.ChromaHeadroomViolations)
+		scaleCount("baseband_info.gamut_errors", &baseband.GamutErrors)
+	}
+	if scenes := analysis.SceneChanges; scenes != nil {
+		kept := scenes.Changes[:0]
+		for _, change := range scenes.Changes {
+			if p.atJoin(change.Timestamp) {
+				scenes.Count--
+				continue
+			}
+			change.Timestamp = p.sourceTime(change.Timestamp)
+			kept = append(kept, change)
+		}
+		scenes.Changes = kept
+		scaleCount("scene_changes.count", &scenes.Count)
+	}
+	if complexity := analysis.TemporalComplexity; complexity != nil {
+		for i := range complexity.HighComplexitySegments {
+			segment := &complexity.HighComplexitySegments[i]
+			segment.StartTime = p.sourceTime(segment.StartTime)
+			segment.EndTime = segment.StartTime + segment.Duration
+		}
+		if analysis.SceneChanges != nil {
+			complexity.SceneChangeCount = analysis.SceneChanges.Count
+			if sampling != nil {
+				sampling.Extrapolated = append(sampling.Extrapolated, "temporal_complexity.scene_change_count")
+			}
+		} else {
+			scaleCount("temporal_complexity.scene_change_count", &complexity.SceneChangeCount)
+		}
+	}
+	if differential := analysis.DifferentialFrame; differential != nil {
+		kept := differential.SuddenChanges[:0]
+		for _, event := range differential.SuddenChanges {
+			if p.atJoin(event.Timestamp) {
+				differential.SuddenChangeCount--
+				continue
+			}
+			event.Timestamp = p.sourceTime(event.Timestamp)
+			kept = append(kept, event)
+		}
+		differential.SuddenChanges = kept
+		scaleCount("differential_frame.anomalous_frames", &differential.AnomalousFrames)
+		scaleCount("differential_frame.duplicate_frames", &differential.DuplicateFrames)
+		scaleCount("differential_frame.sudden_change_count", &differential.SuddenChangeCount)
+		scaleCount("differential_frame.estimated_drops", &differential.EstimatedDrops)
+	}
+	if lineErrors := analysis.LineErrors; lineErrors != nil {
+		for i := range lineErrors.LuminanceErrorLines {
+			lineErrors.LuminanceErrorLines[i].Timestamp = p.sourceTime(lineErrors.LuminanceErrorLines[i].Timestamp)
+		}
+		for i := range lineErrors.ChrominanceErrorLines {
+			lineErrors.ChrominanceErrorLines[i].Timestamp = p.sourceTime(lineErrors.ChrominanceErrorLines[i].Timestamp)
+		}
+		scaleCount("line_errors.luminance_line_errors", &lineErrors.LuminanceLineErrors)
+		scaleCount("line_errors.chrominance_line_errors", &lineErrors.ChrominanceLineErrors)
+		scaleCount("line_errors.digibeta_errors", &lineErrors.DigiBetaErrors)
+		scaleCount("line_errors.total_line_errors", &lineErrors.TotalLineErrors)
+		scaleCount("line_errors.affected_frames", &lineErrors.AffectedFrames)
+	}
+	if frequency := analysis.AudioFrequency; frequency != nil {
+		for i := range frequency.FrequencyAnomalies {
+			anomaly := &frequency.FrequencyAnomalies[i]
+			length := anomaly.EndTime - anomaly.StartTime
+			anomaly.StartTime = p.sourceTime(anomaly.StartTime)
+			anomaly.EndTime = anomaly.StartTime + length
+		}
+	}
+}
+
+func (p *samplingPlan) mapDropouts(events []DropoutEvent) {
+	for i := range events {
+		events[i].StartTime = p.sourceTime(events[i].StartTime)
+		events[i].EndTime = events[i].StartTime + events[i].Duration
+	}
+}
+
+// mapPeriods maps detector periods to the asset
+func (p *samplingPlan) mapPeriods(periods []EventPeriod) {
+	for i := range periods {
+		periods[i].StartTime = p.sourceTime(periods[i].StartTime)
+		periods[i].EndTime = periods[i].StartTime + periods[i].Duration
+	}
+}
@@ -0,0 +1,176 @@
+package ffmpeg
+
+import (
+	"context"
+	"math"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSampleWindowStarts(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration float64
+		windows  int
+		window   float64
+		want     []float64
+	}{
+		{"spread from start to end", 1000, 5, 100, []float64{0, 225, 450, 675, 900}},
+		{"single window is centred", 1000, 1, 100, []float64{450}},
+		{"windows cover the asset", 300, 3, 100, nil},
+		{"no windows", 1000, 0, 100, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sampleWindowStarts(tt.duration, tt.windows, tt.window); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sampleWindowStarts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentSamplingOptionsValidate(t *testing.T) {
+	if err := (&ContentSamplingOptions{Windows: 10, WindowSeconds: 30}).Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	for _, options := range []ContentSamplingOptions{
+		{Windows: 0, WindowSeconds: 30},
+		{Windows: 10, WindowSeconds: 0},
+		{Windows: 10, WindowSeconds: 30, MinDuration: -1},
+	} {
+		if err := options.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded, want an error", options)
+		}
+	}
+}
+
+func TestSamplingPlanList(t *testing.T) {
+	plan := &samplingPlan{input: "/media/it's.mxf", starts: []float64{0, 450.5}, window: 30, total: 480.5}
+	if err := plan.writeList(); err != nil {
+		t.Fatalf("writeList: %v", err)
+	}
+	defer plan.close()
+
+	list, err := os.ReadFile(plan.listPath)
+	if err != nil {
+		t.Fatalf("reading list: %v", err)
+	}
+	want := "ffconcat version 1.0\n" +
+		"file '/media/it'\\''s.mxf'\ninpoint 0.000\noutpoint 30.000\n" +
+		"file '/media/it'\\''s.mxf'\ninpoint 450.500\noutpoint 480.500\n"
+	if string(list) != want {
+		t.Errorf("list =\n%s\nwant\n%s", list, want)
+	}
+
+	ctx := context.WithValue(context.Background(), samplingPlanKey{}, plan)
+	if args := contentInputArgs(ctx, plan.input); !reflect.DeepEqual(args, []string{"-f", "concat", "-safe", "0", "-i", plan.listPath}) {
+		t.Errorf("contentInputArgs = %v", args)
+	}
+	if args := headerInputArgs(ctx, plan.input); !reflect.DeepEqual(args, []string{"-t", "30.000", "-i", plan.input}) {
+		t.Errorf("headerInputArgs = %v", args)
+	}
+	if args := contentInputArgs(ctx, "/media/other.mxf"); !reflect.DeepEqual(args, []string{"-i", "/media/other.mxf"}) {
+		t.Errorf("contentInputArgs of another input = %v", args)
+	}
+
+	plan.close()
+	if _, err := os.Stat(plan.listPath); !os.IsNotExist(err) {
+		t.Errorf("list not removed: %v", err)
+	}
+}
+
+func TestSamplingPlanSourceTime(t *testing.T) {
+	plan := &samplingPlan{starts: []float64{0, 1000, 2000}, window: 60, total: 2060}
+	for joined, want := range map[float64]float64{0: 0, 59: 59, 60: 1000, 75: 1015, 150: 2030, 200: 2080} {
+		if got := plan.sourceTime(joined); got != want {
+			t.Errorf("sourceTime(%v) = %v, want %v", joined, got, want)
+		}
+	}
+	for joined, want := range map[float64]bool{0.1: false, 59.9: false, 60.2: true, 61: false, 120: true} {
+		if got := plan.atJoin(joined); got != want {
+			t.Errorf("atJoin(%v) = %v, want %v", joined, got, want)
+		}
+	}
+}
+
+func TestSamplingConfidence(t *testing.T) {
+	if got := samplingConfidence(1, 1); got != 1 {
+		t.Errorf("full coverage confidence = %v, want 1", got)
+	}
+	few := samplingConfidence(0.05, 2)
+	many := samplingConfidence(0.05, 20)
+	if few >= many {
+		t.Errorf("confidence with 2 windows (%v) not below 20 windows (%v)", few, many)
+	}
+	if level := samplingConfidenceLevel(many); level != "medium" {
+		t.Errorf("level of %v = %s, want medium", many, level)
+	}
+	if level := samplingConfidenceLevel(samplingConfidence(0.1, 100)); level != "high" {
+		t.Errorf("level with 100 windows = %s, want high", level)
+	}
+}
+
+func TestSamplingPlanApply(t *testing.T) {
+	// Ten 30 s windows of a 3000 s asset: every count is scaled by 10
+	starts := sampleWindowStarts(3000, 10, 30)
+	plan := &samplingPlan{starts: starts, window: 30, total: 3000}
+
+	analysis := &ContentAnalysis{
+		BlackFrames: &BlackFrameAnalysis{DetectedFrames: 2},
+		SilenceInfo: &SilenceAnalysis{
+			SilencePeriods:    []SilencePeriod{{StartTime: 35, EndTime: 37, Duration: 2}},
+			TotalSilenceCount: 1,
+			TotalSilenceSec:   2,
+			SilencePercentage: 0.67,
+		},
+		SceneChanges: &SceneChangeAnalysis{
+			Count: 3,
+			Changes: []SceneChange{
+				{Timestamp: 10},
+				{Timestamp: 30.04}, // Join of the first two windows
+				{Timestamp: 95},
+			},
+		},
+		TemporalComplexity: &TemporalComplexityAnalysis{SceneChangeCount: 3},
+	}
+	plan.apply(analysis)
+
+	sampling := analysis.Sampling
+	if sampling == nil {
+		t.Fatal("sampling not recorded")
+	}
+	if sampling.Windows != 10 || sampling.SampledSeconds != 300 || sampling.ExtrapolationFactor != 10 || math.Abs(sampling.Coverage-0.1) > 1e-9 {
+		t.Errorf("sampling = %+v", sampling)
+	}
+	if analysis.BlackFrames.DetectedFrames != 20 {
+		t.Errorf("black frames = %d, want 20", analysis.BlackFrames.DetectedFrames)
+	}
+
+	silence := analysis.SilenceInfo
+	if silence.TotalSilenceCount != 10 || silence.TotalSilenceSec != 20 || silence.SilencePercentage != 0.67 {
+		t.Errorf("silence = %+v, want 10 periods over 20 s at 0.67%%", silence)
+	}
+	// 35 s into the joined windows is 5 s into the second window
+	if period := silence.SilencePeriods[0]; period.StartTime != starts[1]+5 || period.EndTime != starts[1]+7 {
+		t.Errorf("silence period = %+v, want %v-%v", period, starts[1]+5, starts[1]+7)
+	}
+
+	scenes := analysis.SceneChanges
+	if scenes.Count != 20 || len(scenes.Changes) != 2 {
+		t.Errorf("scene changes = %d listed %d, want 20 listed 2", scenes.Count, len(scenes.Changes))
+	}
+	if scenes.Changes[1].Timestamp != starts[3]+5 {
+		t.Errorf("second cut at %v, want %v", scenes.Changes[1].Timestamp, starts[3]+5)
+	}
+	if analysis.TemporalComplexity.SceneChangeCount != 20 {
+		t.Errorf("temporal complexity scene changes = %d, want 20", analysis.TemporalComplexity.SceneChangeCount)
+	}
+
+	for _, field := range []string{"black_frames.detected_frames", "silence_info.total_silence_seconds", "scene_changes.count"} {
+		if !strings.Contains(strings.Join(sampling.Extrapolated, ","), field) {
+			t.Errorf("extrapolated fields %v miss %s", sampling.Extrapolated, field)
+		}
+	}
+}
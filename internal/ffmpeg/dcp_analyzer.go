@@ -0,0 +1,455 @@
+package ffmpeg
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// Digital Cinema Package standards, told apart by their XML namespaces
+const (
+	DCPStandardSMPTE   = "SMPTE"
+	DCPStandardInterop = "Interop"
+)
+
+// dcpFrameRates are the picture edit rates of SMPTE ST 429-2 and its high
+// frame rate additions
+var dcpFrameRates = []int64{24, 25, 30, 48, 50, 60, 96, 100, 120}
+
+// DCPAnalyzer validates the structure of Digital Cinema Packages and the
+// JPEG 2000 essence of their picture track files
+type DCPAnalyzer struct {
+	mezzanine *MezzanineAnalyzer
+	logger    zerolog.Logger
+}
+
+// NewDCPAnalyzer creates a new DCP analyzer
+func NewDCPAnalyzer(ffmpegPath string, logger zerolog.Logger) *DCPAnalyzer {
+	return &DCPAnalyzer{
+		mezzanine: NewMezzanineAnalyzer(ffmpegPath, logger),
+		logger:    logger,
+	}
+}
+
+// DCPAnalysis is the structure of a DCP: its packing lists, its composition
+// playlists and their reels
+type DCPAnalysis struct {
+	Standard     string           `json:"standard"` // "SMPTE" or "Interop"
+	AssetMapID   string           `json:"asset_map_id,omitempty"`
+	PackingLists []DCPPackingList `json:"packing_lists"`
+	Compositions []DCPComposition `json:"compositions"`
+	Picture      *MezzanineStream `json:"picture_essence,omitempty"` // JPEG 2000 conformance of the first picture track file
+	IsValid      bool             `json:"is_valid"`
+	Issues       []string         `json:"issues,omitempty"`
+	Warnings     []string         `json:"warnings,omitempty"`
+}
+
+// DCPPackingList is one packing list and how many of its assets are
+// missing or differ in size
+type DCPPackingList struct {
+	ID             string `json:"id"`
+	Path           string `json:"path"`
+	AssetCount     int    `json:"asset_count"`
+	MissingAssets  int    `json:"missing_assets"` // Not in this package, as in a version file
+	SizeMismatches int    `json:"size_mismatches"`
+}
+
+// DCPComposition is one composition playlist
+type DCPComposition struct {
+	ID           string    `json:"id"`
+	Path         string    `json:"path"`
+	Standard     string    `json:"standard"`
+	ContentTitle string    `json:"content_title,omitempty"`
+	ContentKind  string    `json:"content_kind,omitempty"` // e.g. "feature" or "trailer"
+	IssueDate    string    `json:"issue_date,omitempty"`
+	EditRate     string    `json:"edit_rate,omitempty"` // Of the picture, e.g. "24 1"
+	Reels        []DCPReel `json:"reels"`
+	Duration     int64     `json:"duration_frames"`
+	RunningTime  string    `json:"running_time,omitempty"` // HH:MM:SS:FF
+	Encrypted    bool      `json:"encrypted"`
+	Stereoscopic bool      `json:"stereoscopic"`
+}
+
+// DCPReel is one reel and the assets it plays
+type DCPReel struct {
+	ID       string         `json:"id"`
+	Duration int64          `json:"duration_frames"` // Of the picture
+	Assets   []DCPReelAsset `json:"assets"`
+}
+
+// DCPReelAsset is one asset of a reel
+type DCPReelAsset struct {
+	Kind              string `json:"kind"` // Element name, e.g. "MainPicture" or "MainSound"
+	ID                string `json:"id"`
+	EditRate          string `json:"edit_rate,omitempty"`
+	IntrinsicDuration int64  `json:"intrinsic_duration"`
+	EntryPoint        int64  `json:"entry_point"`
+	Duration          int64  `json:"duration"`
+	Encrypted         bool   `json:"encrypted"`
+	Present           bool   `json:"present"` // The track file is in this package
+}
+
+// dcpComposition is a DCP Composition Playlist, SMPTE ST 429-7 or its
+// Interop predecessor
+type dcpComposition struct {
+	XMLName      xml.Name
+	ID           string `xml:"Id"`
+	ContentTitle string `xml:"ContentTitleText"`
+	ContentKind  string `xml:"ContentKind"`
+	IssueDate    string `xml:"IssueDate"`
+	Reels        []struct {
+		ID        string `xml:"Id"`
+		AssetList struct {
+			Assets []dcpReelAsset `xml:",any"`
+		} `xml:"AssetList"`
+	} `xml:"ReelList>Reel"`
+}
+
+type dcpReelAsset struct {
+	XMLName           xml.Name
+	ID                string `xml:"Id"`
+	EditRate          string `xml:"EditRate"`
+	IntrinsicDuration int64  `xml:"IntrinsicDuration"`
+	EntryPoint        *int64 `xml:"EntryPoint"`
+	Duration          *int64 `xml:"Duration"`
+	KeyID             string `xml:"KeyId"`
+}
+
+// duration returns the edit units the asset plays
+func (a dcpReelAsset) duration() int64 {
+	if a.Duration != nil {
+		return *a.Duration
+	}
+	return a.IntrinsicDuration - a.entryPoint()
+}
+
+func (a dcpReelAsset) entryPoint() int64 {
+	if a.EntryPoint != nil {
+		return *a.EntryPoint
+	}
+	return 0
+}
+
+// dcpStandard returns the standard a CPL or PKL namespace belongs to, or ""
+// for IMF and other namespaces
+func dcpStandard(name xml.Name) string {
+	switch {
+	case strings.Contains(name.Space, "429-7") || strings.Contains(name.Space, "429-8"):
+		return DCPStandardSMPTE
+	case strings.Contains(name.Space, "PROTO-ASDCP"):
+		return DCPStandardInterop
+	}
+	return ""
+}
+
+// isDCPComposition reports whether an XML root element is a DCP CPL
+func isDCPComposition(name xml.Name) bool {
+	return name.Local == "CompositionPlaylist" && (strings.Contains(name.Space, "429-7") || strings.Contains(name.Space, "PROTO-ASDCP-CPL"))
+}
+
+// IsDCPPackage reports whether path is a DCP: a package with an ASSETMAP,
+// as IMF packages are, whose composition playlist is a DCP one
+func IsDCPPackage(path string) bool {
+	if !IsIMFPackage(path) {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	if info.IsDir() {
+		root, _ := imfPackageRoot(path)
+		entries, err := os.ReadDir(root)
+		if err != nil {
+			return false
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".xml") {
+				continue
+			}
+			f, err := os.Open(filepath.Join(root, entry.Name()))
+			if err != nil {
+				continue
+			}
+			name := xmlRootName(f)
+			f.Close()
+			if isDCPComposition(name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return false
+	}
+	defer archive.Close()
+	for _, file := range archive.File {
+		name := strings.TrimPrefix(file.Name, "./")
+		if strings.Count(name, "/") > 1 || !strings.EqualFold(filepath.Ext(name), ".xml") {
+			continue
+		}
+		f, err := file.Open()
+		if err != nil {
+			continue
+		}
+		root := xmlRootName(f)
+		f.Close()
+		if isDCPComposition(root) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnalyzeDCP validates a DCP, given as its directory or a zip of it: the
+// packing lists and composition playlists must be readable, the assets the
+// packing lists name must have their listed size, every reel's assets must
+// play for the same time, and the first picture track file is checked
+// against the DCI JPEG 2000 profile. Asset hashes are not verified; DCPs
+// run to hundreds of gigabytes.
+func (da *DCPAnalyzer) AnalyzeDCP(ctx context.Context, packagePath string) (*DCPAnalysis, error) {
+	root, cleanup, err := openIMFPackage(packagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DCP: %w", err)
+	}
+	defer cleanup()
+
+	pkg, err := readIMFPackage(root)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := &DCPAnalysis{
+		AssetMapID:   pkg.assetMap.ID,
+		PackingLists: []DCPPackingList{},
+		Compositions: []DCPComposition{},
+	}
+	analysis.Issues = append(analysis.Issues, pkg.pklIssues...)
+	analysis.Issues = append(analysis.Issues, pkg.cplIssues...)
+
+	for _, id := range pkg.cplAssets {
+		var cpl dcpComposition
+		if err := readIMFXML(pkg.assetPath(id), &cpl); err != nil {
+			analysis.Issues = append(analysis.Issues, fmt.Sprintf("Failed to parse CPL %s: %v", pkg.paths[id], err))
+			continue
+		}
+		composition := analysis.analyzeComposition(pkg, cpl)
+		composition.Path = pkg.paths[id]
+		analysis.Compositions = append(analysis.Compositions, composition)
+	}
+	if len(analysis.Compositions) == 0 {
+		analysis.Issues = append(analysis.Issues, "No composition playlist found")
+	} else {
+		analysis.Standard = analysis.Compositions[0].Standard
+	}
+
+	if len(pkg.packingLists) == 0 {
+		analysis.Issues = append(analysis.Issues, "No packing list found")
+	}
+	for _, pkl := range pkg.packingLists {
+		analysis.analyzePackingList(pkg, pkl)
+	}
+
+	// SMPTE packages name the asset map ASSETMAP.xml, Interop ones ASSETMAP
+	assetMapName, want := filepath.Base(imfAssetMapPath(root)), imfAssetMapNames[0]
+	if analysis.Standard == DCPStandardInterop {
+		want = imfAssetMapNames[1]
+	}
+	if analysis.Standard != "" && assetMapName != want {
+		analysis.Warnings = append(analysis.Warnings, fmt.Sprintf("%s DCPs name the asset map %s, found %s", analysis.Standard, want, assetMapName))
+	}
+
+	if path := firstDCPPicture(pkg, analysis.Compositions); path != "" {
+		picture := &MezzanineStream{Codec: mezzanineCodecs["jpeg2000"], IsValid: true}
+		frames, err := da.mezzanine.readFrames(ctx, path, 0)
+		if err == nil {
+			err = picture.checkJPEG2000(frames, StreamInfo{}, true)
+		}
+		if err != nil {
+			da.logger.Debug().Err(err).Str("track_file", path).Msg("Failed to read DCP picture essence")
+			picture.Error = err.Error()
+		}
+		analysis.Picture = picture
+	}
+
+	analysis.IsValid = len(analysis.Issues) == 0 && (analysis.Picture == nil || analysis.Picture.IsValid)
+	return analysis, nil
+}
+
+// analyzeComposition lists the reels of a CPL and checks that every asset
+// of a reel plays for as long as its picture
+func (a *DCPAnalysis) analyzeComposition(pkg *imfPkg, cpl dcpComposition) DCPComposition {
+	composition := DCPComposition{
+		ID:           cpl.ID,
+		Standard:     dcpStandard(cpl.XMLName),
+		ContentTitle: strings.TrimSpace(cpl.ContentTitle),
+		ContentKind:  strings.TrimSpace(cpl.ContentKind),
+		IssueDate:    cpl.IssueDate,
+		Reels:        []DCPReel{},
+	}
+	if len(cpl.Reels) == 0 {
+		a.Issues = append(a.Issues, fmt.Sprintf("CPL %s has no reels", cpl.ID))
+	}
+
+	var editRate *big.Rat
+	for i, reel := range cpl.Reels {
+		number := i + 1
+		result := DCPReel{ID: reel.ID, Assets: []DCPReelAsset{}}
+		var pictureTime *big.Rat
+		var times []*big.Rat
+		var kinds []string
+
+		for _, asset := range reel.AssetList.Assets {
+			kind := asset.XMLName.Local
+			if kind == "CompositionMetadataAsset" {
+				continue
+			}
+			entry := DCPReelAsset{
+				Kind:              kind,
+				ID:                asset.ID,
+				EditRate:          strings.TrimSpace(asset.EditRate),
+				IntrinsicDuration: asset.IntrinsicDuration,
+				EntryPoint:        asset.entryPoint(),
+				Duration:          asset.duration(),
+				Encrypted:         strings.TrimSpace(asset.KeyID) != "",
+			}
+			_, entry.Present = pkg.paths[imfUUID(asset.ID)]
+			result.Assets = append(result.Assets, entry)
+			composition.Encrypted = composition.Encrypted || entry.Encrypted
+
+			// Markers carry no track file or timing of their own
+			if kind == "MainMarkers" {
+				continue
+			}
+			if !entry.Present {
+				a.Warnings = append(a.Warnings, fmt.Sprintf("Reel %d %s %s is not in this package; a version file needs its original version to play", number, kind, asset.ID))
+			}
+			switch {
+			case asset.IntrinsicDuration <= 0:
+				a.Issues = append(a.Issues, fmt.Sprintf("Reel %d %s has no intrinsic duration", number, kind))
+				continue
+			case entry.EntryPoint < 0 || entry.Duration <= 0 || entry.EntryPoint+entry.Duration > asset.IntrinsicDuration:
+				a.Issues = append(a.Issues, fmt.Sprintf("Reel %d %s plays %d edit units from %d, outside its %d edit units", number, kind, entry.Duration, entry.EntryPoint, asset.IntrinsicDuration))
+				continue
+			}
+			rate, ok := parseIMFEditRate(entry.EditRate)
+			if !ok {
+				a.Issues = append(a.Issues, fmt.Sprintf("Reel %d %s has an invalid edit rate %q", number, kind, asset.EditRate))
+				continue
+			}
+			played := new(big.Rat).Quo(big.NewRat(entry.Duration, 1), rate)
+
+			if kind == "MainPicture" || kind == "MainStereoscopicPicture" {
+				composition.Stereoscopic = composition.Stereoscopic || kind == "MainStereoscopicPicture"
+				if pictureTime != nil {
+					a.Issues = append(a.Issues, fmt.Sprintf("Reel %d has more than one picture", number))
+					continue
+				}
+				pictureTime = played
+				result.Duration = entry.Duration
+				if editRate == nil {
+					editRate = rate
+					composition.EditRate = entry.EditRate
+				} else if rate.Cmp(editRate) != 0 {
+					a.Issues = append(a.Issues, fmt.Sprintf("Reel %d picture runs at %s, earlier reels at %s", number, entry.EditRate, composition.EditRate))
+				}
+				continue
+			}
+			times = append(times, played)
+			kinds = append(kinds, kind)
+		}
+
+		if pictureTime == nil {
+			a.Issues = append(a.Issues, fmt.Sprintf("Reel %d has no picture", number))
+		} else {
+			for j, played := range times {
+				if played.Cmp(pictureTime) != 0 {
+					a.Issues = append(a.Issues, fmt.Sprintf("Reel %d %s lasts %ss, the picture %ss", number, kinds[j], played.FloatString(3), pictureTime.FloatString(3)))
+				}
+			}
+			if pictureTime.Cmp(big.NewRat(1, 1)) < 0 {
+				a.Warnings = append(a.Warnings, fmt.Sprintf("Reel %d is shorter than one second", number))
+			}
+		}
+		composition.Duration += result.Duration
+		composition.Reels = append(composition.Reels, result)
+	}
+
+	if editRate != nil {
+		if !editRate.IsInt() || !containsFrameRate(editRate.Num().Int64()) {
+			a.Warnings = append(a.Warnings, fmt.Sprintf("Picture edit rate %s is not a digital cinema frame rate", composition.EditRate))
+		}
+		fps := new(big.Rat).Add(editRate, big.NewRat(1, 2))
+		composition.RunningTime = FormatTimecode(composition.Duration, int(new(big.Int).Quo(fps.Num(), fps.Denom()).Int64()), false)
+	}
+	return composition
+}
+
+// analyzePackingList checks that the assets of a PKL are in the package at
+// their listed size and that it shares the CPL's standard
+func (a *DCPAnalysis) analyzePackingList(pkg *imfPkg, pkl imfPackingList) {
+	id := imfUUID(pkl.ID)
+	result := DCPPackingList{ID: pkl.ID, Path: pkg.paths[id], AssetCount: len(pkl.Assets)}
+
+	if path := pkg.assetPath(id); path != "" {
+		f, err := os.Open(path)
+		if err == nil {
+			standard := dcpStandard(xmlRootName(f))
+			f.Close()
+			if a.Standard != "" && standard != "" && standard != a.Standard {
+				a.Issues = append(a.Issues, fmt.Sprintf("PKL %s is %s but the CPL is %s", result.Path, standard, a.Standard))
+			}
+		}
+	}
+
+	for _, asset := range pkl.Assets {
+		path := pkg.assetPath(imfUUID(asset.ID))
+		info, err := os.Stat(path)
+		if path == "" || err != nil {
+			result.MissingAssets++
+			continue
+		}
+		if asset.Size > 0 && info.Size() != asset.Size {
+			result.SizeMismatches++
+			a.Issues = append(a.Issues, fmt.Sprintf("Asset %s is %d bytes, the PKL lists %d", pkg.paths[imfUUID(asset.ID)], info.Size(), asset.Size))
+		}
+	}
+	if result.MissingAssets > 0 {
+		a.Warnings = append(a.Warnings, fmt.Sprintf("%d of %d assets of PKL %s are not in this package", result.MissingAssets, result.AssetCount, result.Path))
+	}
+	a.PackingLists = append(a.PackingLists, result)
+}
+
+// firstDCPPicture returns the path of the first picture track file that is
+// in the package and not encrypted
+func firstDCPPicture(pkg *imfPkg, compositions []DCPComposition) string {
+	for _, composition := range compositions {
+		for _, reel := range composition.Reels {
+			for _, asset := range reel.Assets {
+				if (asset.Kind == "MainPicture" || asset.Kind == "MainStereoscopicPicture") && asset.Present && !asset.Encrypted {
+					return pkg.assetPath(imfUUID(asset.ID))
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func containsFrameRate(rate int64) bool {
+	for _, r := range dcpFrameRates {
+		if r == rate {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package ffmpeg
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	testDCPPKLID     = "urn:uuid:d1111111-1111-4111-8111-111111111111"
+	testDCPCPLID     = "urn:uuid:d2222222-2222-4222-8222-222222222222"
+	testDCPPictureID = "urn:uuid:d3333333-3333-4333-8333-333333333333"
+	testDCPSoundID   = "urn:uuid:d4444444-4444-4444-8444-444444444444"
+)
+
+// writeTestDCP writes a two reel SMPTE DCP to dir. The sound of the second
+// reel plays soundShort edit units less than its picture, and the PKL lists
+// the wrong size for the sound track file when badSize is set.
+func writeTestDCP(t *testing.T, dir string, soundShort int, badSize bool) {
+	t.Helper()
+	reel := func(n int, soundDuration int) string {
+		return fmt.Sprintf(`<Reel><Id>urn:uuid:d555555%[1]d-5555-4555-8555-555555555555</Id><AssetList>
+<MainPicture><Id>%[2]s</Id><EditRate>24 1</EditRate><IntrinsicDuration>96</IntrinsicDuration><EntryPoint>%[4]d</EntryPoint><Duration>48</Duration><FrameRate>24 1</FrameRate></MainPicture>
+<MainSound><Id>%[3]s</Id><EditRate>24 1</EditRate><IntrinsicDuration>96</IntrinsicDuration><EntryPoint>%[4]d</EntryPoint><Duration>%[5]d</Duration></MainSound>
+</AssetList></Reel>`, n, testDCPPictureID, testDCPSoundID, (n-1)*48, soundDuration)
+	}
+	files := map[string][]byte{
+		"CPL.xml": []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<CompositionPlaylist xmlns="http://www.smpte-ra.org/schemas/429-7/2006/CPL">
+<Id>` + testDCPCPLID + `</Id>
+<IssueDate>2024-05-01T10:00:00+00:00</IssueDate>
+<ContentTitleText>Test_FTR_F_EN-XX_51_2K_20240501_SMPTE_OV</ContentTitleText>
+<ContentKind>feature</ContentKind>
+<ReelList>` + reel(1, 48) + reel(2, 48-soundShort) + `</ReelList>
+</CompositionPlaylist>`),
+		"picture.mxf": []byte("not really a picture track file"),
+		"sound.mxf":   []byte("not really a sound track file"),
+	}
+
+	asset := func(id string, size int) string {
+		return fmt.Sprintf(`<Asset><Id>%s</Id><Hash>AAAAAAAAAAAAAAAAAAAAAAAAAAA=</Hash><Size>%d</Size><Type>application/mxf</Type></Asset>`, id, size)
+	}
+	soundSize := len(files["sound.mxf"])
+	if badSize {
+		soundSize++
+	}
+	files["PKL.xml"] = []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<PackingList xmlns="http://www.smpte-ra.org/schemas/429-8/2007/PKL"><Id>` + testDCPPKLID + `</Id><AssetList>` +
+		asset(testDCPCPLID, len(files["CPL.xml"])) +
+		asset(testDCPPictureID, len(files["picture.mxf"])) +
+		asset(testDCPSoundID, soundSize) +
+		`</AssetList></PackingList>`)
+
+	chunk := func(id, path string, packingList bool) string {
+		pkl := ""
+		if packingList {
+			pkl = "<PackingList>true</PackingList>"
+		}
+		return fmt.Sprintf(`<Asset><Id>%s</Id>%s<ChunkList><Chunk><Path>%s</Path></Chunk></ChunkList></Asset>`, id, pkl, path)
+	}
+	files["ASSETMAP.xml"] = []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<AssetMap xmlns="http://www.smpte-ra.org/schemas/429-9/2007/AM"><Id>urn:uuid:dccccccc-cccc-4ccc-8ccc-cccccccccccc</Id><VolumeCount>1</VolumeCount><AssetList>` +
+		chunk(testDCPPKLID, "PKL.xml", true) +
+		chunk(testDCPCPLID, "CPL.xml", false) +
+		chunk(testDCPPictureID, "picture.mxf", false) +
+		chunk(testDCPSoundID, "sound.mxf", false) +
+		`</AssetList></AssetMap>`)
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestIsDCPPackage(t *testing.T) {
+	dcp := t.TempDir()
+	writeTestDCP(t, dcp, 0, false)
+	if !IsDCPPackage(dcp) {
+		t.Error("IsDCPPackage(DCP) = false, want true")
+	}
+
+	imf := t.TempDir()
+	writeTestIMFPackage(t, imf, testIMFCPL(false), false)
+	if IsDCPPackage(imf) {
+		t.Error("IsDCPPackage(IMF package) = true, want false")
+	}
+
+	zipped := filepath.Join(t.TempDir(), "dcp.zip")
+	writeTestZip(t, zipped, func(w *zip.Writer) {
+		for _, name := range []string{"ASSETMAP.xml", "CPL.xml"} {
+			data, _ := os.ReadFile(filepath.Join(dcp, name))
+			f, _ := w.Create("DCP/" + name)
+			f.Write(data)
+		}
+	})
+	if !IsDCPPackage(zipped) {
+		t.Error("IsDCPPackage(zipped DCP) = false, want true")
+	}
+}
+
+func TestAnalyzeDCP(t *testing.T) {
+	analyzer := NewDCPAnalyzer("/nonexistent/ffmpeg", zerolog.Nop())
+
+	t.Run("valid", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestDCP(t, dir, 0, false)
+		analysis, err := analyzer.AnalyzeDCP(context.Background(), dir)
+		if err != nil {
+			t.Fatalf("AnalyzeDCP() error = %v", err)
+		}
+		if !analysis.IsValid || len(analysis.Issues) > 0 || len(analysis.Warnings) > 0 {
+			t.Errorf("valid = %v, issues = %v, warnings = %v", analysis.IsValid, analysis.Issues, analysis.Warnings)
+		}
+		if analysis.Standard != DCPStandardSMPTE || len(analysis.PackingLists) != 1 || len(analysis.Compositions) != 1 {
+			t.Fatalf("analysis = %+v", analysis)
+		}
+		cpl := analysis.Compositions[0]
+		if cpl.ContentKind != "feature" || cpl.EditRate != "24 1" || len(cpl.Reels) != 2 || cpl.Duration != 96 || cpl.RunningTime != "00:00:04:00" {
+			t.Errorf("composition = %+v", cpl)
+		}
+		if reel := cpl.Reels[1]; len(reel.Assets) != 2 || reel.Assets[0].EntryPoint != 48 || !reel.Assets[0].Present || reel.Assets[0].Encrypted {
+			t.Errorf("reel 2 = %+v", reel)
+		}
+
+		// ffmpeg is not there to read the picture, which is not an issue
+		// with the package
+		if analysis.Picture == nil || analysis.Picture.Error == "" {
+			t.Errorf("Picture = %+v, want a read error", analysis.Picture)
+		}
+	})
+
+	t.Run("short sound and size mismatch", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestDCP(t, dir, 12, true)
+		analysis, err := analyzer.AnalyzeDCP(context.Background(), dir)
+		if err != nil {
+			t.Fatalf("AnalyzeDCP() error = %v", err)
+		}
+		want := []string{"Reel 2 MainSound lasts 1.500s, the picture 2.000s", "Asset sound.mxf is 29 bytes, the PKL lists 30"}
+		if analysis.IsValid || len(analysis.Issues) != len(want) {
+			t.Fatalf("Issues = %q", analysis.Issues)
+		}
+		for i, w := range want {
+			if !strings.Contains(analysis.Issues[i], w) {
+				t.Errorf("Issues[%d] = %q, want %q", i, analysis.Issues[i], w)
+			}
+		}
+		if analysis.PackingLists[0].SizeMismatches != 1 {
+			t.Errorf("SizeMismatches = %d, want 1", analysis.PackingLists[0].SizeMismatches)
+		}
+	})
+}
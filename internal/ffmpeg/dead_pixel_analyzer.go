@@ -6,9 +6,7 @@ import (
 	"fmt"
 	"math"
 	"os/exec"
-	"regexp"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -19,6 +17,7 @@ type DeadPixelAnalyzer struct {
 	ffprobePath string
 	ffmpegPath  string
 	logger      zerolog.Logger
+	thresholds  DeadPixelThresholds
 }
 
 // NewDeadPixelAnalyzer creates a new dead pixel analyzer
@@ -35,9 +34,19 @@ func NewDeadPixelAnalyzer(ffprobePath string, logger zerolog.Logger) *DeadPixelA
 		ffprobePath: ffprobePath,
 		ffmpegPath:  ffmpegPath,
 		logger:      logger,
+		thresholds:  DefaultDeadPixelThresholds(),
 	}
 }
 
+// SetThresholds sets custom detection thresholds
+func (dpa *DeadPixelAnalyzer) SetThresholds(thresholds DeadPixelThresholds) error {
+	if err := thresholds.Validate(); err != nil {
+		return fmt.Errorf("invalid dead pixel thresholds: %w", err)
+	}
+	dpa.thresholds = thresholds
+	return nil
+}
+
 // DeadPixelAnalysis contains comprehensive dead pixel analysis
 type DeadPixelAnalysis struct {
 	HasDeadPixels           bool                   `json:"has_dead_pixels"`
@@ -250,72 +259,105 @@ func (dpa *DeadPixelAnalyzer) AnalyzeDeadPixels(ctx context.Context, filePath st
 		StuckPixelMap:       []PixelDefect{},
 		HotPixelMap:         []PixelDefect{},
 		DetectionConfidence: 0.0,
-		AnalysisMethod:      "Computer Vision Analysis",
+		AnalysisMethod:      "Pixel stability map",
 		RecommendedActions:  []string{},
 	}
 
-	// Step 1: Extract sample frames for analysis
-	frames, err := dpa.extractSampleFrames(ctx, filePath)
+	// Step 1: Read the video geometry and duration
+	info, err := dpa.probeVideo(ctx, filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract frames: %w", err)
+		return nil, fmt.Errorf("failed to read video info: %w", err)
 	}
-
-	if len(frames) == 0 {
-		analysis.RecommendedActions = append(analysis.RecommendedActions, "No frames available for analysis")
+	if info.width*info.height > maxDeadPixelFramePixels {
+		analysis.RecommendedActions = append(analysis.RecommendedActions,
+			fmt.Sprintf("Resolution %dx%d exceeds the pixel analysis limit - manual inspection recommended", info.width, info.height))
 		return analysis, nil
 	}
 
-	// Step 2: Analyze each frame for pixel defects using FFmpeg signalstats
-	if err := dpa.analyzeFramesForDefects(ctx, filePath, frames, analysis); err != nil {
-		dpa.logger.Warn().Err(err).Msg("Failed to analyze frames for defects")
+	// Step 2: Sample frames across the timeline into a per-pixel stability map
+	stability, frames, err := dpa.buildStabilityMap(ctx, filePath, info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample frames: %w", err)
+	}
+
+	if len(frames) < minDeadPixelFrames {
+		analysis.RecommendedActions = append(analysis.RecommendedActions, "Not enough frames available for pixel analysis")
+		return analysis, nil
 	}
 
-	// Step 3: Perform temporal analysis across frames
-	if err := dpa.performTemporalAnalysis(frames, analysis); err != nil {
+	// Step 3: Classify pixels that stay anomalous while their surroundings change
+	summary := stability.defects(dpa.thresholds)
+	dpa.applyDefects(analysis, summary, len(frames))
+
+	// Step 4: Perform temporal analysis across frames
+	if err := dpa.performTemporalAnalysis(frames, summary, analysis); err != nil {
 		dpa.logger.Warn().Err(err).Msg("Failed to perform temporal analysis")
 	}
 
-	// Step 4: Perform spatial analysis of defect distribution
-	if err := dpa.performSpatialAnalysis(analysis); err != nil {
+	// Step 5: Perform spatial analysis of defect distribution
+	if err := dpa.performSpatialAnalysis(info.width, info.height, analysis); err != nil {
 		dpa.logger.Warn().Err(err).Msg("Failed to perform spatial analysis")
 	}
 
-	// Step 5: Calculate pixel statistics
+	// Step 6: Calculate pixel statistics
 	analysis.PixelStatistics = dpa.calculatePixelStatistics(analysis, frames)
 
-	// Step 6: Assess quality impact
+	// Step 7: Assess quality impact
 	analysis.QualityImpactAssessment = dpa.assessQualityImpact(analysis)
 
-	// Step 7: Generate recommended actions
+	// Step 8: Generate recommended actions
 	analysis.RecommendedActions = dpa.generateRecommendedActions(analysis)
 
-	// Step 8: Calculate overall detection confidence
+	// Step 9: Calculate overall detection confidence
 	analysis.DetectionConfidence = dpa.calculateDetectionConfidence(analysis)
 
 	return analysis, nil
 }
 
-// extractSampleFrames extracts frames for dead pixel analysis
-func (dpa *DeadPixelAnalyzer) extractSampleFrames(ctx context.Context, filePath string) ([]FrameData, error) {
-	// First, get video information
+const (
+	// minDeadPixelFrames is the fewest samples that can separate defects from content
+	minDeadPixelFrames = 3
+
+	// maxDeadPixelFramePixels bounds memory use; the stability map needs
+	// about ten bytes per pixel plus one rgb24 frame
+	maxDeadPixelFramePixels = 7680 * 4320
+
+	// deadPixelSampleTimeout bounds decoding of a single sampled frame
+	deadPixelSampleTimeout = 60 * time.Second
+)
+
+// deadPixelVideoInfo is the geometry of the first video stream
+type deadPixelVideoInfo struct {
+	width, height int
+	duration      float64 // Seconds; 0 when unknown
+}
+
+// probeVideo reads the first video stream's dimensions and the file duration
+func (dpa *DeadPixelAnalyzer) probeVideo(ctx context.Context, filePath string) (*deadPixelVideoInfo, error) {
 	cmd := []string{
 		dpa.ffprobePath,
 		"-v", "quiet",
 		"-print_format", "json",
-		"-show_streams",
 		"-select_streams", "v:0",
+		"-show_entries", "stream=width,height,duration:format=duration",
 		filePath,
 	}
 
 	output, err := dpa.executeCommand(ctx, cmd)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get video info: %w", err)
+		return nil, err
 	}
 
 	var result struct {
-		Streams []StreamInfo `json:"streams"`
+		Streams []struct {
+			Width    int    `json:"width"`
+			Height   int    `json:"height"`
+			Duration string `json:"duration"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
 	}
-
 	if err := json.Unmarshal([]byte(output), &result); err != nil {
 		return nil, fmt.Errorf("failed to parse video info: %w", err)
 	}
@@ -324,304 +366,149 @@ func (dpa *DeadPixelAnalyzer) extractSampleFrames(ctx context.Context, filePath
 		return nil, fmt.Errorf("no video streams found")
 	}
 
-	// Extract sample frames using ffprobe frame analysis
-	frameCmd := []string{
-		dpa.ffprobePath,
-		"-v", "quiet",
-		"-print_format", "json",
-		"-show_frames",
-		"-select_streams", "v:0",
-		"-read_intervals", "%+#10", // Sample 10 frames
-		filePath,
+	stream := result.Streams[0]
+	if stream.Width <= 2*pixelNeighborOffset || stream.Height <= 2*pixelNeighborOffset {
+		return nil, fmt.Errorf("invalid video dimensions %dx%d", stream.Width, stream.Height)
 	}
 
-	frameOutput, err := dpa.executeCommand(ctx, frameCmd)
-	if err != nil {
-		return nil, fmt.Errorf("failed to extract frames: %w", err)
-	}
-
-	var frameResult struct {
-		Frames []FrameInfo `json:"frames"`
-	}
-
-	if err := json.Unmarshal([]byte(frameOutput), &frameResult); err != nil {
-		return nil, fmt.Errorf("failed to parse frame data: %w", err)
-	}
-
-	// Convert to FrameData for analysis
-	frames := make([]FrameData, len(frameResult.Frames))
-	for i, frame := range frameResult.Frames {
-		frames[i] = FrameData{
-			FrameNumber: i + 1,
-			Width:       frame.Width,
-			Height:      frame.Height,
-			PixelFormat: frame.PixFmt,
-			PtsTime:     frame.PtsTime,
-			KeyFrame:    frame.KeyFrame == 1,
+	info := &deadPixelVideoInfo{width: stream.Width, height: stream.Height}
+	for _, value := range []string{stream.Duration, result.Format.Duration} {
+		if duration, err := strconv.ParseFloat(value, 64); err == nil && duration > 0 {
+			info.duration = duration
+			break
 		}
 	}
 
-	return frames, nil
-}
-
-// FrameData represents frame information for analysis
-type FrameData struct {
-	FrameNumber int    `json:"frame_number"`
-	Width       int    `json:"width"`
-	Height      int    `json:"height"`
-	PixelFormat string `json:"pixel_format"`
-	PtsTime     string `json:"pts_time"`
-	KeyFrame    bool   `json:"key_frame"`
+	return info, nil
 }
 
-// analyzeFramesForDefects analyzes frames for pixel defects using FFmpeg signalstats filter
-func (dpa *DeadPixelAnalyzer) analyzeFramesForDefects(ctx context.Context, filePath string, frames []FrameData, analysis *DeadPixelAnalysis) error {
-	if len(frames) == 0 {
-		return fmt.Errorf("no frames to analyze")
-	}
-
-	// Use FFmpeg signalstats filter for real pixel analysis
-	// signalstats outputs per-frame statistics including BRNG (out-of-range pixels)
-	analysis.AnalysisMethod = "FFmpeg signalstats filter"
-
-	// Run signalstats analysis on the actual video file
-	stats, err := dpa.runSignalStatsAnalysis(ctx, filePath, frames)
-	if err != nil {
-		dpa.logger.Warn().Err(err).Msg("Signalstats analysis failed, reporting no defects detected")
-		// Return clean result rather than error for graceful degradation
-		analysis.DetectionConfidence = 0.0
-		analysis.RecommendedActions = append(analysis.RecommendedActions,
-			"Signalstats analysis unavailable - manual inspection recommended")
+// buildStabilityMap decodes frames spread evenly across the timeline as
+// rgb24 and accumulates them. Each sample seeks independently so long files
+// are not decoded end to end; files without a known duration use the first
+// frames instead.
+func (dpa *DeadPixelAnalyzer) buildStabilityMap(ctx context.Context, filePath string, info *deadPixelVideoInfo) (*pixelStabilityMap, []FrameData, error) {
+	stability := newPixelStabilityMap(info.width, info.height)
+	frameSize := info.width * info.height * 3
+	buf := make([]byte, frameSize)
+	samples := dpa.thresholds.SampleFrames
+	var frames []FrameData
+
+	addSample := func(rgb []byte, seconds float64) error {
+		if err := stability.addFrame(rgb, dpa.thresholds); err != nil {
+			return err
+		}
+		frames = append(frames, FrameData{
+			FrameNumber: len(frames),
+			Width:       info.width,
+			Height:      info.height,
+			PixelFormat: "rgb24",
+			PtsTime:     strconv.FormatFloat(seconds, 'f', 3, 64),
+		})
 		return nil
 	}
 
-	// Analyze the statistics for defect indicators
-	dpa.analyzeSignalStats(stats, frames, analysis)
-
-	return nil
-}
-
-// SignalStats holds parsed signalstats output
-type SignalStats struct {
-	FrameNumber int
-	YMIN        float64 // Minimum luminance
-	YMAX        float64 // Maximum luminance
-	YLOW        float64 // Low luminance count
-	YHIGH       float64 // High luminance count
-	BRNG        float64 // Broadcast range violations (percentage)
-	SATMIN      float64 // Minimum saturation
-	SATMAX      float64 // Maximum saturation
-}
-
-// runSignalStatsAnalysis runs FFmpeg signalstats filter and parses output
-func (dpa *DeadPixelAnalyzer) runSignalStatsAnalysis(ctx context.Context, filePath string, frames []FrameData) ([]SignalStats, error) {
-	if filePath == "" {
-		return nil, fmt.Errorf("file path required for signalstats analysis")
-	}
-
-	// Run FFmpeg signalstats filter to get per-frame pixel statistics
-	// signalstats outputs: YMIN, YMAX, YLOW, YHIGH, BRNG, etc.
-	cmd := exec.CommandContext(ctx,
-		dpa.ffmpegPath,
-		"-i", filePath,
-		"-vf", "signalstats=stat=brng+vrep+tout,metadata=mode=print",
-		"-f", "null",
-		"-t", "10", // Analyze first 10 seconds for performance
-		"-",
-	)
-
-	dpa.logger.Debug().
-		Str("file", filePath).
-		Str("ffmpeg_path", dpa.ffmpegPath).
-		Msg("Running signalstats analysis")
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		// Log but don't fail completely - signalstats might not be available
-		dpa.logger.Warn().
-			Err(err).
-			Str("output", string(output)).
-			Msg("FFmpeg signalstats failed")
-		return nil, fmt.Errorf("signalstats filter failed: %w", err)
-	}
-
-	// Parse signalstats output
-	return dpa.parseSignalStatsOutput(string(output), frames)
-}
-
-// parseSignalStatsOutput parses FFmpeg signalstats metadata output
-func (dpa *DeadPixelAnalyzer) parseSignalStatsOutput(output string, frames []FrameData) ([]SignalStats, error) {
-	var stats []SignalStats
-
-	// signalstats with metadata=mode=print outputs lines like:
-	// [Parsed_metadata_1 @ 0x...] frame:0    pts:0       pts_time:0
-	// [Parsed_metadata_1 @ 0x...] lavfi.signalstats.YMIN=16
-	// [Parsed_metadata_1 @ 0x...] lavfi.signalstats.YMAX=235
-	// [Parsed_metadata_1 @ 0x...] lavfi.signalstats.BRNG=0.001234
-
-	lines := strings.Split(output, "\n")
-	var currentStats *SignalStats
-	frameNum := 0
-
-	// Regex patterns for parsing
-	yminRe := regexp.MustCompile(`signalstats\.YMIN=(\d+\.?\d*)`)
-	ymaxRe := regexp.MustCompile(`signalstats\.YMAX=(\d+\.?\d*)`)
-	brngRe := regexp.MustCompile(`signalstats\.BRNG=(\d+\.?\d*)`)
-	frameRe := regexp.MustCompile(`frame:\s*(\d+)`)
-
-	for _, line := range lines {
-		// Check for new frame
-		if match := frameRe.FindStringSubmatch(line); len(match) > 1 {
-			// Save previous frame stats if any
-			if currentStats != nil {
-				stats = append(stats, *currentStats)
+	if info.duration <= 0 {
+		output, err := dpa.decodeRawFrames(ctx, filePath, -1, samples)
+		if err != nil {
+			return nil, nil, err
+		}
+		for offset := 0; offset+frameSize <= len(output); offset += frameSize {
+			if err := addSample(output[offset:offset+frameSize], 0); err != nil {
+				return nil, nil, err
 			}
-			frameNum, _ = strconv.Atoi(match[1])
-			currentStats = &SignalStats{FrameNumber: frameNum}
 		}
+		return stability, frames, nil
+	}
 
-		if currentStats == nil {
-			currentStats = &SignalStats{FrameNumber: 0}
-		}
+	for i := 0; i < samples; i++ {
+		seconds := info.duration * (float64(i) + 0.5) / float64(samples)
 
-		// Parse YMIN
-		if match := yminRe.FindStringSubmatch(line); len(match) > 1 {
-			currentStats.YMIN, _ = strconv.ParseFloat(match[1], 64)
+		output, err := dpa.decodeRawFrames(ctx, filePath, seconds, 1)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			dpa.logger.Debug().Err(err).Float64("position", seconds).Msg("Skipping unreadable sample frame")
+			continue
 		}
-
-		// Parse YMAX
-		if match := ymaxRe.FindStringSubmatch(line); len(match) > 1 {
-			currentStats.YMAX, _ = strconv.ParseFloat(match[1], 64)
+		if len(output) < frameSize {
+			// Seeking past the last frame of a stream shorter than the container
+			continue
 		}
 
-		// Parse BRNG (broadcast range violations)
-		if match := brngRe.FindStringSubmatch(line); len(match) > 1 {
-			currentStats.BRNG, _ = strconv.ParseFloat(match[1], 64)
+		copy(buf, output[:frameSize])
+		if err := addSample(buf, seconds); err != nil {
+			return nil, nil, err
 		}
 	}
 
-	// Add last frame stats
-	if currentStats != nil && (currentStats.YMIN != 0 || currentStats.YMAX != 0 || currentStats.BRNG != 0) {
-		stats = append(stats, *currentStats)
-	}
+	return stability, frames, nil
+}
 
-	if len(stats) == 0 {
-		dpa.logger.Debug().Msg("No signalstats data parsed from output")
-		return nil, fmt.Errorf("no signalstats data found in output")
-	}
+// decodeRawFrames decodes count frames as rgb24 starting at position seconds,
+// or from the start when position is negative
+func (dpa *DeadPixelAnalyzer) decodeRawFrames(ctx context.Context, filePath string, position float64, count int) ([]byte, error) {
+	execCtx, cancel := context.WithTimeout(ctx, deadPixelSampleTimeout)
+	defer cancel()
 
-	dpa.logger.Debug().
-		Int("frames_analyzed", len(stats)).
-		Msg("Successfully parsed signalstats output")
+	args := []string{"-hide_banner", "-nostdin", "-loglevel", "error"}
+	if position >= 0 {
+		args = append(args, "-ss", strconv.FormatFloat(position, 'f', 3, 64))
+	}
+	// Keep the coded orientation so frames match the probed dimensions
+	args = append(args,
+		"-noautorotate",
+		"-i", filePath,
+		"-map", "0:v:0",
+		"-frames:v", strconv.Itoa(count),
+		"-f", "rawvideo",
+		"-pix_fmt", "rgb24",
+		"-",
+	)
 
-	return stats, nil
+	return commandOutput(execCtx, exec.CommandContext(execCtx, dpa.ffmpegPath, args...))
 }
 
-// analyzeSignalStats interprets signalstats data for defect detection
-func (dpa *DeadPixelAnalyzer) analyzeSignalStats(stats []SignalStats, frames []FrameData, analysis *DeadPixelAnalysis) {
-	if len(stats) == 0 {
-		// No stats available - report clean result
-		analysis.DetectionConfidence = 0.0
-		analysis.HasDeadPixels = false
-		analysis.HasStuckPixels = false
-		analysis.HasHotPixels = false
-		analysis.DeadPixelCount = 0
-		analysis.StuckPixelCount = 0
-		analysis.HotPixelCount = 0
-		return
-	}
-
-	// Analyze BRNG (broadcast range violations) across frames
-	// High BRNG values indicate pixels outside normal range
-	var totalBRNG float64
-	var maxBRNG float64
-	var framesWithHighBRNG int
-
-	for _, stat := range stats {
-		totalBRNG += stat.BRNG
-		if stat.BRNG > maxBRNG {
-			maxBRNG = stat.BRNG
-		}
-		if stat.BRNG > 0.01 { // More than 0.01% out of range
-			framesWithHighBRNG++
-		}
-	}
+// FrameData represents frame information for analysis
+type FrameData struct {
+	FrameNumber int    `json:"frame_number"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	PixelFormat string `json:"pixel_format"`
+	PtsTime     string `json:"pts_time"`
+	KeyFrame    bool   `json:"key_frame"`
+}
 
-	avgBRNG := totalBRNG / float64(len(stats))
+// applyDefects copies classified defects into the analysis
+func (dpa *DeadPixelAnalyzer) applyDefects(analysis *DeadPixelAnalysis, summary pixelDefectSummary, frameCount int) {
+	analysis.AnalysisMethod = fmt.Sprintf("Pixel stability map over %d sampled frames", frameCount)
 
-	// Detect potential defects based on consistent BRNG patterns
-	// Consistent BRNG across frames suggests stuck/dead pixels
-	if avgBRNG > 0.001 && framesWithHighBRNG >= len(stats)/2 {
-		// Estimate defect count based on BRNG percentage
-		totalPixels := 0
-		if len(frames) > 0 {
-			totalPixels = frames[0].Width * frames[0].Height
-		}
+	analysis.DeadPixelMap = append(analysis.DeadPixelMap, summary.dead...)
+	analysis.StuckPixelMap = append(analysis.StuckPixelMap, summary.stuck...)
+	analysis.HotPixelMap = append(analysis.HotPixelMap, summary.hot...)
 
-		// BRNG is a percentage - convert to pixel count
-		estimatedDefects := int(avgBRNG * float64(totalPixels) / 100.0)
-
-		if estimatedDefects > 0 {
-			// We can detect that defects exist but not their exact locations
-			// Mark as detected with appropriate confidence
-			analysis.HasDeadPixels = true
-			analysis.DeadPixelCount = estimatedDefects
-			analysis.DetectionConfidence = math.Min(90.0, avgBRNG*1000) // Higher BRNG = higher confidence
-
-			dpa.logger.Info().
-				Float64("avg_brng", avgBRNG).
-				Float64("max_brng", maxBRNG).
-				Int("estimated_defects", estimatedDefects).
-				Msg("Detected potential pixel defects via signalstats BRNG")
-		}
-	} else {
-		// No significant defects detected
-		analysis.HasDeadPixels = false
-		analysis.HasStuckPixels = false
-		analysis.HasHotPixels = false
-		analysis.DeadPixelCount = 0
-		analysis.StuckPixelCount = 0
-		analysis.HotPixelCount = 0
-		analysis.DetectionConfidence = 85.0 // High confidence in clean result
-	}
-
-	// Analyze luminance extremes for stuck pixels
-	var framesWithMinLuma int
-	var framesWithMaxLuma int
-	for _, stat := range stats {
-		if stat.YMIN == 0 {
-			framesWithMinLuma++
-		}
-		if stat.YMAX >= 254 {
-			framesWithMaxLuma++
-		}
-	}
+	analysis.DeadPixelCount = summary.deadCount
+	analysis.StuckPixelCount = summary.stuckCount
+	analysis.HotPixelCount = summary.hotCount
+	analysis.HasDeadPixels = summary.deadCount > 0
+	analysis.HasStuckPixels = summary.stuckCount > 0
+	analysis.HasHotPixels = summary.hotCount > 0
 
-	// If all frames have exactly 0 minimum or 255 maximum consistently,
-	// this could indicate stuck black or white pixels
-	if framesWithMinLuma == len(stats) {
-		analysis.HasDeadPixels = true
-		if analysis.DeadPixelCount == 0 {
-			analysis.DeadPixelCount = 1 // At least one dead pixel detected
-		}
+	if total := summary.deadCount + summary.stuckCount + summary.hotCount; total > 0 {
+		dpa.logger.Info().
+			Int("dead", summary.deadCount).
+			Int("stuck", summary.stuckCount).
+			Int("hot", summary.hotCount).
+			Msg("Detected pixel defects")
 	}
-	if framesWithMaxLuma == len(stats) {
-		analysis.HasStuckPixels = true
-		if analysis.StuckPixelCount == 0 {
-			analysis.StuckPixelCount = 1 // At least one stuck pixel detected
-		}
-	}
-
-	// Update counts
-	analysis.HotPixelCount = len(analysis.HotPixelMap)
 }
 
 // performTemporalAnalysis analyzes pixel behavior over time
-func (dpa *DeadPixelAnalyzer) performTemporalAnalysis(frames []FrameData, analysis *DeadPixelAnalysis) error {
+func (dpa *DeadPixelAnalyzer) performTemporalAnalysis(frames []FrameData, summary pixelDefectSummary, analysis *DeadPixelAnalysis) error {
 	temporal := &TemporalPixelAnalysis{
 		FramesAnalyzed:      len(frames),
 		AnalysisWindowSize:  len(frames),
-		TemporalStability:   0.95, // High stability for permanent defects
+		TemporalStability:   1.0,
 		MotionCompensation:  false,
 		SceneChangeHandling: true,
 		Issues:              []string{},
@@ -636,11 +523,37 @@ func (dpa *DeadPixelAnalyzer) performTemporalAnalysis(frames []FrameData, analys
 		FlickerPixels:    []PixelLocation{},
 	}
 
-	// Check for any intermittent pixels that might flicker
-	for _, defect := range analysis.DeadPixelMap {
-		if defect.TemporalBehavior != nil && defect.TemporalBehavior.VariationPattern == "flickering" {
-			flicker.HasFlicker = true
-			flicker.FlickerPixels = append(flicker.FlickerPixels, PixelLocation{X: defect.X, Y: defect.Y})
+	var consistency, variation float64
+	var defects int
+	for _, defectMap := range [][]PixelDefect{analysis.DeadPixelMap, analysis.StuckPixelMap, analysis.HotPixelMap} {
+		for _, defect := range defectMap {
+			if defect.TemporalBehavior == nil {
+				continue
+			}
+			defects++
+			consistency += defect.TemporalBehavior.FrameConsistency
+			if defect.TemporalBehavior.VariationPattern == "flickering" {
+				flicker.HasFlicker = true
+				flicker.FlickerPixels = append(flicker.FlickerPixels, PixelLocation{X: defect.X, Y: defect.Y})
+				variation += defect.TemporalBehavior.IntensityVariation
+			}
+		}
+	}
+
+	if defects > 0 {
+		// Stability of the defects themselves: 1 means present in every sample
+		temporal.TemporalStability = consistency / float64(defects)
+	}
+	if len(flicker.FlickerPixels) > 0 {
+		flicker.FlickerIntensity = variation / float64(len(flicker.FlickerPixels))
+		flicker.FlickerPattern = "irregular"
+	}
+
+	if summary.analyzedPixels > 0 {
+		staticRatio := float64(summary.staticPixels) / float64(summary.analyzedPixels)
+		if staticRatio > 0.5 {
+			temporal.Issues = append(temporal.Issues,
+				fmt.Sprintf("%.0f%% of the picture is too static to separate defects from content", staticRatio*100))
 		}
 	}
 
@@ -651,24 +564,26 @@ func (dpa *DeadPixelAnalyzer) performTemporalAnalysis(frames []FrameData, analys
 }
 
 // performSpatialAnalysis analyzes spatial distribution of defects
-func (dpa *DeadPixelAnalyzer) performSpatialAnalysis(analysis *DeadPixelAnalysis) error {
+func (dpa *DeadPixelAnalyzer) performSpatialAnalysis(width, height int, analysis *DeadPixelAnalysis) error {
 	spatial := &SpatialPixelAnalysis{
 		DefectClusters:      []DefectCluster{},
 		SpatialDistribution: "uniform",
 		HotspotRegions:      []Region{},
-		EdgeBias:            0.0,
-		CornerBias:          0.0,
-		CenterBias:          1.0,
 	}
 
+	var defects []PixelDefect
+	defects = append(defects, analysis.DeadPixelMap...)
+	defects = append(defects, analysis.StuckPixelMap...)
+	defects = append(defects, analysis.HotPixelMap...)
+
 	// Analyze clusters of defects
-	clusters := dpa.findDefectClusters(analysis.DeadPixelMap, analysis.StuckPixelMap)
+	clusters, isolated := dpa.findDefectClusters(defects)
 	spatial.DefectClusters = clusters
 
 	// Calculate cluster analysis
 	clusterAnalysis := &ClusterAnalysis{
 		TotalClusters:       len(clusters),
-		IsolatedDefects:     dpa.countIsolatedDefects(analysis.DeadPixelMap, analysis.StuckPixelMap),
+		IsolatedDefects:     isolated,
 		ClusterDistribution: "sparse",
 	}
 
@@ -683,6 +598,46 @@ func (dpa *DeadPixelAnalyzer) performSpatialAnalysis(analysis *DeadPixelAnalysis
 		}
 		clusterAnalysis.LargestClusterSize = maxClusterSize
 		clusterAnalysis.AverageClusterSize = float64(totalPixels) / float64(len(clusters))
+
+		switch {
+		case len(clusters) > 10:
+			clusterAnalysis.ClusterDistribution = "dense"
+		case len(clusters) > 2:
+			clusterAnalysis.ClusterDistribution = "moderate"
+		}
+	}
+
+	// Bias toward edges (outer 10%), corners and the center (middle 50%)
+	if len(defects) > 0 && width > 0 && height > 0 {
+		marginX, marginY := float64(width)*0.1, float64(height)*0.1
+		var edge, corner, center int
+		for _, defect := range defects {
+			x, y := float64(defect.X), float64(defect.Y)
+			nearX := x < marginX || x >= float64(width)-marginX
+			nearY := y < marginY || y >= float64(height)-marginY
+			if nearX || nearY {
+				edge++
+			}
+			if nearX && nearY {
+				corner++
+			}
+			if x >= float64(width)*0.25 && x < float64(width)*0.75 && y >= float64(height)*0.25 && y < float64(height)*0.75 {
+				center++
+			}
+		}
+
+		spatial.EdgeBias = float64(edge) / float64(len(defects))
+		spatial.CornerBias = float64(corner) / float64(len(defects))
+		spatial.CenterBias = float64(center) / float64(len(defects))
+
+		switch {
+		case len(defects)-isolated > len(defects)/2:
+			spatial.SpatialDistribution = "clustered"
+		case spatial.CornerBias > 0.5:
+			spatial.SpatialDistribution = "corner-biased"
+		case spatial.EdgeBias > 0.5:
+			spatial.SpatialDistribution = "edge-biased"
+		}
 	}
 
 	spatial.ClusterAnalysis = clusterAnalysis
@@ -829,41 +784,78 @@ func (dpa *DeadPixelAnalyzer) assessQualityImpact(analysis *DeadPixelAnalysis) *
 
 // Helper methods
 
-func (dpa *DeadPixelAnalyzer) getRandomStuckColor() string {
-	colors := []string{"red", "green", "blue", "white", "cyan", "magenta", "yellow"}
-	return colors[len(colors)/2] // Just return a middle color for simulation
-}
+// defectClusterDistance is the largest gap, in pixels, between defects of one cluster
+const defectClusterDistance = 2
 
-func (dpa *DeadPixelAnalyzer) findDefectClusters(deadPixels, stuckPixels []PixelDefect) []DefectCluster {
-	// Simple clustering algorithm simulation
+// findDefectClusters groups defects lying within defectClusterDistance of each
+// other and returns clusters of two or more defects plus the isolated count
+func (dpa *DeadPixelAnalyzer) findDefectClusters(defects []PixelDefect) ([]DefectCluster, int) {
 	clusters := []DefectCluster{}
+	visited := make([]bool, len(defects))
+	isolated := 0
 
-	allDefects := append(deadPixels, stuckPixels...)
-	if len(allDefects) < 2 {
-		return clusters
-	}
-
-	// Create a simple cluster if we have multiple defects
-	if len(allDefects) >= 3 {
-		cluster := DefectCluster{
-			ClusterID:          1,
-			CenterX:            float64(allDefects[0].X+allDefects[1].X) / 2.0,
-			CenterY:            float64(allDefects[0].Y+allDefects[1].Y) / 2.0,
-			Radius:             10.0,
-			PixelCount:         min(len(allDefects), 3),
-			DominantDefectType: allDefects[0].DefectType,
-			ClusterSeverity:    0.5,
-			DefectDensity:      0.3,
+	for start := range defects {
+		if visited[start] {
+			continue
+		}
+		visited[start] = true
+
+		// Flood fill; defect maps are capped, so the quadratic scan is cheap
+		members := []int{start}
+		for next := 0; next < len(members); next++ {
+			current := defects[members[next]]
+			for j := range defects {
+				if visited[j] {
+					continue
+				}
+				dx, dy := defects[j].X-current.X, defects[j].Y-current.Y
+				if dx >= -defectClusterDistance && dx <= defectClusterDistance &&
+					dy >= -defectClusterDistance && dy <= defectClusterDistance {
+					visited[j] = true
+					members = append(members, j)
+				}
+			}
 		}
-		clusters = append(clusters, cluster)
-	}
 
-	return clusters
-}
+		if len(members) == 1 {
+			isolated++
+			continue
+		}
+
+		var sumX, sumY float64
+		typeCounts := make(map[string]int)
+		for _, m := range members {
+			sumX += float64(defects[m].X)
+			sumY += float64(defects[m].Y)
+			typeCounts[defects[m].DefectType]++
+		}
+		centerX, centerY := sumX/float64(len(members)), sumY/float64(len(members))
 
-func (dpa *DeadPixelAnalyzer) countIsolatedDefects(deadPixels, stuckPixels []PixelDefect) int {
-	// For simulation, assume most defects are isolated
-	return len(deadPixels) + len(stuckPixels) - 3 // Subtract cluster members
+		radius := 0.5
+		for _, m := range members {
+			radius = math.Max(radius, math.Hypot(float64(defects[m].X)-centerX, float64(defects[m].Y)-centerY))
+		}
+
+		dominant := ""
+		for defectType, count := range typeCounts {
+			if count > typeCounts[dominant] || (count == typeCounts[dominant] && defectType < dominant) {
+				dominant = defectType
+			}
+		}
+
+		clusters = append(clusters, DefectCluster{
+			ClusterID:          len(clusters) + 1,
+			CenterX:            centerX,
+			CenterY:            centerY,
+			Radius:             radius,
+			PixelCount:         len(members),
+			DominantDefectType: dominant,
+			ClusterSeverity:    math.Min(1.0, float64(len(members))/10.0),
+			DefectDensity:      float64(len(members)) / (math.Pi * radius * radius),
+		})
+	}
+
+	return clusters, isolated
 }
 
 func (dpa *DeadPixelAnalyzer) calculateIntensityDistribution(intensities []float64) *IntensityDistribution {
@@ -1051,13 +1043,18 @@ func (dpa *DeadPixelAnalyzer) calculateDetectionConfidence(analysis *DeadPixelAn
 		confidence += 5.0
 	}
 
+	// Mostly static content leaves defects and picture detail indistinguishable
+	if analysis.TemporalAnalysis != nil && len(analysis.TemporalAnalysis.Issues) > 0 {
+		confidence -= 30.0
+	}
+
 	// Decrease confidence if we have conflicting indicators
 	totalDefects := analysis.DeadPixelCount + analysis.StuckPixelCount + analysis.HotPixelCount
 	if totalDefects == 0 {
 		confidence += 5.0 // High confidence in "no defects" finding
 	}
 
-	return math.Min(confidence, 95.0) // Cap at 95% since we're using statistical analysis
+	return math.Min(confidence, 95.0) // Cap at 95% since samples can miss intermittent defects
 }
 
 func (dpa *DeadPixelAnalyzer) executeCommand(ctx context.Context, cmd []string) (string, error) {
@@ -0,0 +1,218 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// maxDecodeErrors bounds the decode errors listed per file; the counts
+// still cover all
+const maxDecodeErrors = 1000
+
+// DecodeScanAnalyzer decodes every video and audio frame of a file with
+// ffmpeg to find corruption that probing the headers and packets misses
+type DecodeScanAnalyzer struct {
+	ffmpegPath string
+	logger     zerolog.Logger
+}
+
+// NewDecodeScanAnalyzer creates a new full-file decode scanner
+func NewDecodeScanAnalyzer(ffmpegPath string, logger zerolog.Logger) *DecodeScanAnalyzer {
+	return &DecodeScanAnalyzer{
+		ffmpegPath: ffmpegPath,
+		logger:     logger,
+	}
+}
+
+// DecodeScan reports the errors logged while decoding a whole file
+type DecodeScan struct {
+	DecodeErrors   int           `json:"decode_errors"`
+	CorruptFrames  int           `json:"corrupt_frames"`  // Frames the decoder flagged as corrupt or concealed errors in
+	DecodedSeconds float64       `json:"decoded_seconds"` // How far decoding got
+	Completed      bool          `json:"completed"`       // False when ffmpeg gave up before the end of the file
+	Errors         []DecodeError `json:"errors,omitempty"`
+}
+
+// DecodeError is one error or corrupt frame logged by the decoder
+type DecodeError struct {
+	Timestamp    float64 `json:"timestamp"`        // Decode position in seconds when it was logged, to within half a second
+	Source       string  `json:"source,omitempty"` // Logging component, e.g. h264
+	Message      string  `json:"message"`
+	CorruptFrame bool    `json:"corrupt_frame,omitempty"`
+}
+
+// HasErrors reports whether the scan found a decode error or corrupt frame,
+// or stopped before the end of the file
+func (s *DecodeScan) HasErrors() bool {
+	return s.DecodeErrors > 0 || s.CorruptFrames > 0 || !s.Completed
+}
+
+// ScanDecodeErrors decodes filePath to the null muxer. Progress is written
+// to stderr between the log lines, so each error is placed at the position
+// decoding had reached. When ctx limits the analysis to a segment only the
+// segment is decoded, and positions stay positions in the file.
+func (da *DecodeScanAnalyzer) ScanDecodeErrors(ctx context.Context, filePath string) (*DecodeScan, error) {
+	input := []string{"-i", filePath}
+	segment := segmentFromContext(ctx)
+	if segment != nil {
+		input = segment.inputArgs(filePath)
+	}
+	args := append([]string{
+		"-hide_banner", "-nostdin", "-nostats",
+		"-loglevel", "level+warning",
+		"-progress", "pipe:2",
+	}, input...)
+	cmd := exec.CommandContext(ctx, da.ffmpegPath, append(args,
+		"-map", "0:v?", "-map", "0:a?",
+		"-f", "null", "-",
+	)...)
+
+	output, err := commandCombinedOutput(ctx, cmd)
+	if err != nil {
+		// ffmpeg exits non-zero when the input is too broken to continue,
+		// which is evidence rather than a failure of the scan
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || ctx.Err() != nil {
+			return nil, fmt.Errorf("failed to decode file: %w", err)
+		}
+		da.logger.Debug().Err(err).Msg("decode scan stopped early")
+	}
+	scan := parseDecodeScan(output)
+	if segment != nil {
+		for i := range scan.Errors {
+			scan.Errors[i].Timestamp += segment.StartTime
+		}
+	}
+	return scan, nil
+}
+
+// parseDecodeScan reads ffmpeg's level-prefixed log interleaved with
+// -progress output, e.g.
+//
+//	out_time_us=12480000
+//	progress=continue
+//	[h264 @ 0x55d1] [error] error while decoding MB 31 17, bytestream -7
+//	[h264 @ 0x55d1] [error] concealing 402 DC, 402 AC, 402 MV errors in P frame
+//	[vist#0:0/h264 @ 0x55d2] [warning] corrupt decoded frame
+//	progress=end
+func parseDecodeScan(output []byte) *DecodeScan {
+	scan := &DecodeScan{}
+	var position float64
+
+	forEachLine(output, func(line string) bool {
+		line = strings.TrimSpace(line)
+		// out_time_ms is also in microseconds, kept for older versions
+		key, value, _ := strings.Cut(line, "=")
+		if key == "out_time_us" || key == "out_time_ms" {
+			if us, err := strconv.ParseInt(value, 10, 64); err == nil && us >= 0 {
+				position = float64(us) / 1e6
+			}
+			return true
+		}
+		if line == "progress=end" {
+			scan.Completed = true
+			return true
+		}
+
+		source, level, message := splitLogLine(line)
+		lower := strings.ToLower(message)
+		corrupt := strings.Contains(lower, "corrupt decoded frame") || strings.HasPrefix(lower, "concealing ")
+		switch {
+		case corrupt:
+			scan.CorruptFrames++
+		case level == "error" || level == "fatal":
+			scan.DecodeErrors++
+		default:
+			return true
+		}
+		if len(scan.Errors) < maxDecodeErrors {
+			scan.Errors = append(scan.Errors, DecodeError{
+				Timestamp:    position,
+				Source:       source,
+				Message:      message,
+				CorruptFrame: corrupt,
+			})
+		}
+		return true
+	})
+
+	scan.DecodedSeconds = position
+	return scan
+}
+
+// splitLogLine splits "[h264 @ 0x55d1] [error] message" into the
+// component, level and message. Either prefix may be missing.
+func splitLogLine(line string) (source, level, message string) {
+	message = line
+	if rest, ok := strings.CutPrefix(message, "["); ok {
+		if component, after, ok := strings.Cut(rest, "] "); ok && strings.Contains(component, " @ ") {
+			source, _, _ = strings.Cut(component, " @ ")
+			if _, name, ok := strings.Cut(source, "/"); ok {
+				source = name
+			}
+			message = after
+		}
+	}
+	if rest, ok := strings.CutPrefix(message, "["); ok {
+		if prefix, after, ok := strings.Cut(rest, "] "); ok {
+			level = prefix
+			message = after
+		}
+	}
+	return source, level, strings.TrimSpace(message)
+}
+
+// AnalyzeDecodeErrors decodes the whole file and adds the result to the
+// data integrity analysis, creating one if the selected categories did not
+func (ea *EnhancedAnalyzer) AnalyzeDecodeErrors(ctx context.Context, result *FFprobeResult, filePath string) error {
+	scan, err := NewDecodeScanAnalyzer(ea.ffmpegPath, ea.logger).ScanDecodeErrors(ctx, filePath)
+	if err != nil {
+		return err
+	}
+
+	if result.EnhancedAnalysis == nil {
+		result.EnhancedAnalysis = &EnhancedAnalysis{}
+	}
+	if result.EnhancedAnalysis.DataIntegrityAnalysis == nil {
+		result.EnhancedAnalysis.DataIntegrityAnalysis = &DataIntegrityAnalysis{
+			IntegrityScore:       100,
+			IsBroadcastCompliant: true,
+			Validation:           &DataIntegrityValidation{IsValid: true, BroadcastCompliant: true, StreamingCompliant: true},
+		}
+	}
+	result.EnhancedAnalysis.DataIntegrityAnalysis.applyDecodeScan(scan)
+	return nil
+}
+
+// applyDecodeScan adds the evidence of a full decode to the analysis. Any
+// decode error or corrupt frame marks the file corrupted.
+func (d *DataIntegrityAnalysis) applyDecodeScan(scan *DecodeScan) {
+	d.DecodeScan = scan
+	if !scan.HasErrors() {
+		return
+	}
+
+	d.IsCorrupted = true
+	d.IsBroadcastCompliant = false
+	d.IntegrityScore = max(0, d.IntegrityScore-min(50, 10*scan.CorruptFrames+5*scan.DecodeErrors))
+	if d.Validation == nil {
+		d.Validation = &DataIntegrityValidation{}
+	}
+	d.Validation.IsValid = false
+	d.Validation.BroadcastCompliant = false
+	d.Validation.StreamingCompliant = false
+
+	if scan.DecodeErrors > 0 || scan.CorruptFrames > 0 {
+		d.Validation.Issues = append(d.Validation.Issues, fmt.Sprintf("%d decode errors and %d corrupt frames found decoding the whole file", scan.DecodeErrors, scan.CorruptFrames))
+		d.Validation.RequiredActions = append(d.Validation.RequiredActions, "Replace or re-encode the corrupted sections")
+	}
+	if !scan.Completed {
+		d.Validation.Issues = append(d.Validation.Issues, fmt.Sprintf("Decoding stopped at %.2fs before the end of the file", scan.DecodedSeconds))
+	}
+}
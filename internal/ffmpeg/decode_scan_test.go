@@ -0,0 +1,96 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDecodeScan(t *testing.T) {
+	output := strings.Join([]string{
+		"[mov,mp4,m4a,3gp,3g2,mj2 @ 0x55d0] [warning] stream 0, timescale not set",
+		"out_time_us=500000",
+		"out_time_ms=500000",
+		"progress=continue",
+		"out_time_us=12480000",
+		"progress=continue",
+		"[h264 @ 0x55d1] [error] error while decoding MB 31 17, bytestream -7",
+		"[h264 @ 0x55d1] [error] concealing 402 DC, 402 AC, 402 MV errors in P frame",
+		"[vist#0:0/h264 @ 0x55d2] [warning] corrupt decoded frame",
+		"out_time_us=60000000",
+		"[error] Error while decoding stream #0:1: Invalid data found when processing input",
+		"out_time_us=61500000",
+		"progress=end",
+	}, "\n")
+
+	scan := parseDecodeScan([]byte(output))
+
+	if !scan.Completed {
+		t.Error("expected the scan to be completed")
+	}
+	if scan.DecodeErrors != 2 {
+		t.Errorf("expected 2 decode errors, got %d", scan.DecodeErrors)
+	}
+	if scan.CorruptFrames != 2 {
+		t.Errorf("expected 2 corrupt frames, got %d", scan.CorruptFrames)
+	}
+	if scan.DecodedSeconds != 61.5 {
+		t.Errorf("expected 61.5 decoded seconds, got %v", scan.DecodedSeconds)
+	}
+	if len(scan.Errors) != 4 {
+		t.Fatalf("expected 4 listed errors, got %d", len(scan.Errors))
+	}
+
+	first := scan.Errors[0]
+	if first.Timestamp != 12.48 || first.Source != "h264" || first.CorruptFrame {
+		t.Errorf("unexpected first error: %+v", first)
+	}
+	if first.Message != "error while decoding MB 31 17, bytestream -7" {
+		t.Errorf("unexpected message %q", first.Message)
+	}
+	if corrupt := scan.Errors[2]; !corrupt.CorruptFrame || corrupt.Source != "h264" {
+		t.Errorf("unexpected corrupt frame: %+v", corrupt)
+	}
+	if last := scan.Errors[3]; last.Timestamp != 60 || last.Source != "" {
+		t.Errorf("unexpected last error: %+v", last)
+	}
+}
+
+func TestParseDecodeScan_Incomplete(t *testing.T) {
+	scan := parseDecodeScan([]byte("out_time_us=3000000\nprogress=continue\n[fatal] Conversion failed!\n"))
+
+	if scan.Completed {
+		t.Error("expected the scan to be incomplete")
+	}
+	if !scan.HasErrors() {
+		t.Error("expected an incomplete scan to report errors")
+	}
+	if scan.DecodedSeconds != 3 {
+		t.Errorf("expected 3 decoded seconds, got %v", scan.DecodedSeconds)
+	}
+}
+
+func TestDataIntegrityAnalysis_ApplyDecodeScan(t *testing.T) {
+	t.Run("clean", func(t *testing.T) {
+		analysis := &DataIntegrityAnalysis{IntegrityScore: 100, IsBroadcastCompliant: true}
+		analysis.applyDecodeScan(&DecodeScan{Completed: true, DecodedSeconds: 10})
+
+		if analysis.IsCorrupted || analysis.IntegrityScore != 100 || analysis.DecodeScan == nil {
+			t.Errorf("clean scan must not change the analysis: %+v", analysis)
+		}
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		analysis := &DataIntegrityAnalysis{IntegrityScore: 100, IsBroadcastCompliant: true}
+		analysis.applyDecodeScan(&DecodeScan{Completed: true, DecodeErrors: 1, CorruptFrames: 2})
+
+		if !analysis.IsCorrupted || analysis.IsBroadcastCompliant {
+			t.Error("expected the file to be marked corrupted")
+		}
+		if analysis.IntegrityScore != 75 {
+			t.Errorf("expected integrity score 75, got %d", analysis.IntegrityScore)
+		}
+		if analysis.Validation == nil || len(analysis.Validation.Issues) != 1 || analysis.Validation.IsValid {
+			t.Errorf("unexpected validation: %+v", analysis.Validation)
+		}
+	})
+}
@@ -0,0 +1,477 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Delivery check statuses
+const (
+	DeliveryCheckPass        = "pass"
+	DeliveryCheckFail        = "fail"
+	DeliveryCheckWarning     = "warning"      // An advisory threshold was missed
+	DeliveryCheckNotMeasured = "not_measured" // The measurement was unavailable
+)
+
+// DeliveryProfile is a broadcaster or platform delivery specification mapped
+// to concrete thresholds. Nil sections and empty lists are not checked.
+type DeliveryProfile struct {
+	ID          string                `json:"id"`
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Container   ContainerConstraints  `json:"container"`
+	Loudness    *LoudnessThresholds   `json:"loudness,omitempty"`
+	VideoLevels *VideoLevelThresholds `json:"video_levels,omitempty"`
+	Timecode    *TimecodeRequirements `json:"timecode,omitempty"`
+}
+
+// ContainerConstraints restricts the wrapper and essence of a delivery
+type ContainerConstraints struct {
+	Formats          []string `json:"formats,omitempty"`      // ffprobe format names, any may match
+	VideoCodecs      []string `json:"video_codecs,omitempty"` // ffprobe codec names
+	Resolutions      []string `json:"resolutions,omitempty"`  // WIDTHxHEIGHT
+	FrameRates       []string `json:"frame_rates,omitempty"`  // As reported by ffprobe, e.g. "25/1"
+	AudioCodecs      []string `json:"audio_codecs,omitempty"`
+	AudioSampleRates []int    `json:"audio_sample_rates,omitempty"`
+	MinAudioChannels int      `json:"min_audio_channels,omitempty"` // Summed over all audio streams
+}
+
+// LoudnessThresholds are integrated loudness and true peak limits. A zero
+// ToleranceLU skips the integrated loudness check.
+type LoudnessThresholds struct {
+	Standard        string  `json:"standard"`
+	IntegratedLUFS  float64 `json:"integrated_lufs,omitempty"`
+	ToleranceLU     float64 `json:"tolerance_lu,omitempty"`
+	MaxTruePeakDBTP float64 `json:"max_true_peak_dbtp"`
+	DialogGated     bool    `json:"dialog_gated,omitempty"` // Integrated loudness is measured on dialog, falling back to the programme without enough speech
+	Advisory        bool    `json:"advisory,omitempty"`     // Misses are warnings, not failures
+}
+
+// VideoLevelThresholds limit the share of analyzed frames with luma or
+// chroma outside the 8-bit legal range
+type VideoLevelThresholds struct {
+	Standard                   string  `json:"standard"`
+	MaxOutOfRangeFramesPercent float64 `json:"max_out_of_range_frames_percent"`
+	MaxGamutErrorPercent       float64 `json:"max_gamut_error_percent"`
+}
+
+// TimecodeRequirements describe the expected timecode track
+type TimecodeRequirements struct {
+	Required       bool   `json:"required"`
+	StartTimecode  string `json:"start_timecode,omitempty"`
+	AllowDropFrame bool   `json:"allow_drop_frame"`
+}
+
+// DeliveryCompliance is the result of checking an analysis against a profile.
+// Compliant requires every check to pass; advisory warnings are allowed.
+type DeliveryCompliance struct {
+	Profile     string          `json:"profile"`
+	ProfileName string          `json:"profile_name"`
+	Compliant   bool            `json:"compliant"`
+	Passed      int             `json:"passed"`
+	Failed      int             `json:"failed"`
+	Warnings    int             `json:"warnings"`
+	NotMeasured int             `json:"not_measured"`
+	Checks      []DeliveryCheck `json:"checks"`
+}
+
+// DeliveryCheck is a single profile rule evaluated against the analysis
+type DeliveryCheck struct {
+	Rule     string `json:"rule"` // e.g. "loudness.integrated"
+	Status   string `json:"status"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+// deliveryProfiles are the built-in presets in listing order
+var deliveryProfiles = []*DeliveryProfile{
+	{
+		ID:          "dpp_as11_uk",
+		Name:        "DPP AS-11 UK (HD)",
+		Description: "UK broadcaster HD file delivery: AS-11 MXF OP1a with AVC-Intra 100, 1080i25, EBU R128 loudness and EBU R103 video levels",
+		Container: ContainerConstraints{
+			Formats:          []string{"mxf"},
+			VideoCodecs:      []string{"h264"},
+			Resolutions:      []string{"1920x1080"},
+			FrameRates:       []string{"25/1"},
+			AudioCodecs:      []string{"pcm_s24le"},
+			AudioSampleRates: []int{48000},
+			MinAudioChannels: 4,
+		},
+		Loudness: &LoudnessThresholds{
+			Standard:        "EBU R128",
+			IntegratedLUFS:  -23,
+			ToleranceLU:     0.5,
+			MaxTruePeakDBTP: -1,
+		},
+		VideoLevels: &VideoLevelThresholds{
+			Standard:                   "EBU R103",
+			MaxOutOfRangeFramesPercent: 1,
+			MaxGamutErrorPercent:       1,
+		},
+		Timecode: &TimecodeRequirements{
+			Required:      true,
+			StartTimecode: "10:00:00:00",
+		},
+	},
+	{
+		ID:          "netflix_imf",
+		Name:        "Netflix IMF",
+		Description: "Netflix IMF App 2E package: JPEG 2000 in MXF, 24-bit 48 kHz PCM, -27 LKFS loudness and continuous timecode",
+		Container: ContainerConstraints{
+			Formats:          []string{"mxf"},
+			VideoCodecs:      []string{"jpeg2000"},
+			Resolutions:      []string{"1920x1080", "3840x2160", "4096x2160"},
+			AudioCodecs:      []string{"pcm_s24le"},
+			AudioSampleRates: []int{48000},
+			MinAudioChannels: 2,
+		},
+		Loudness: &LoudnessThresholds{
+			Standard:        "ITU-R BS.1770 (Netflix)",
+			IntegratedLUFS:  -27,
+			ToleranceLU:     2,
+			MaxTruePeakDBTP: -2,
+			DialogGated:     true,
+		},
+		Timecode: &TimecodeRequirements{
+			Required:       true,
+			AllowDropFrame: true,
+		},
+	},
+	{
+		ID:          "itunes",
+		Name:        "iTunes / Apple TV",
+		Description: "Apple film and TV delivery: ProRes 422 HQ in QuickTime with 48 kHz PCM audio and no true peak overs",
+		Container: ContainerConstraints{
+			Formats:          []string{"mov"},
+			VideoCodecs:      []string{"prores"},
+			Resolutions:      []string{"1920x1080", "3840x2160"},
+			AudioCodecs:      []string{"pcm_s16le", "pcm_s24le", "pcm_s16be", "pcm_s24be"},
+			AudioSampleRates: []int{48000},
+			MinAudioChannels: 2,
+		},
+		Loudness: &LoudnessThresholds{
+			Standard:        "ITU-R BS.1770",
+			MaxTruePeakDBTP: -1,
+		},
+	},
+	{
+		ID:          "youtube",
+		Name:        "YouTube",
+		Description: "YouTube upload recommendations: MP4 or MOV with a common codec; loudness above -14 LUFS is turned down on playback",
+		Container: ContainerConstraints{
+			Formats:          []string{"mp4", "mov"},
+			VideoCodecs:      []string{"h264", "hevc", "vp9", "av1", "prores"},
+			AudioCodecs:      []string{"aac", "opus", "mp3", "ac3", "eac3", "pcm_s16le", "pcm_s24le"},
+			AudioSampleRates: []int{44100, 48000},
+			MinAudioChannels: 1,
+		},
+		Loudness: &LoudnessThresholds{
+			Standard:        "YouTube loudness normalization",
+			IntegratedLUFS:  -14,
+			ToleranceLU:     1,
+			MaxTruePeakDBTP: -1,
+			Advisory:        true,
+		},
+	},
+}
+
+// DeliveryProfiles returns the built-in delivery profiles
+func DeliveryProfiles() []*DeliveryProfile {
+	return deliveryProfiles
+}
+
+// LookupDeliveryProfile finds a built-in profile by ID. An empty name
+// selects no profile.
+func LookupDeliveryProfile(name string) (*DeliveryProfile, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return nil, nil
+	}
+	for _, profile := range deliveryProfiles {
+		if profile.ID == name {
+			return profile, nil
+		}
+	}
+
+	ids := make([]string, len(deliveryProfiles))
+	for i, profile := range deliveryProfiles {
+		ids[i] = profile.ID
+	}
+	return nil, fmt.Errorf("unknown delivery profile: %s (available: %s)", name, strings.Join(ids, ", "))
+}
+
+// QCCategories returns the measurements the profile needs beyond stream
+// metadata
+func (p *DeliveryProfile) QCCategories() []QCCategory {
+	var categories []QCCategory
+	if p.Loudness != nil {
+		categories = append(categories, QCCategoryLoudness)
+	}
+	if p.VideoLevels != nil {
+		categories = append(categories, QCCategoryVideoLevels)
+	}
+	if p.Timecode != nil {
+		categories = append(categories, QCCategoryTimecode)
+	}
+	return categories
+}
+
+// EvaluateDeliveryProfile checks a probe result against a profile. Loudness,
+// video levels and timecode come from the enhanced analysis; checks whose
+// measurement is missing are reported as not measured and fail compliance.
+func EvaluateDeliveryProfile(result *FFprobeResult, profile *DeliveryProfile) *DeliveryCompliance {
+	e := &deliveryEvaluator{result: result}
+	if result.EnhancedAnalysis != nil {
+		e.enhanced = result.EnhancedAnalysis
+		e.content = result.EnhancedAnalysis.ContentAnalysis
+	}
+
+	e.checkContainer(profile.Container)
+	if profile.Loudness != nil {
+		e.checkLoudness(profile.Loudness)
+	}
+	if profile.VideoLevels != nil {
+		e.checkVideoLevels(profile.VideoLevels)
+	}
+	if profile.Timecode != nil {
+		e.checkTimecode(profile.Timecode)
+	}
+
+	compliance := &DeliveryCompliance{
+		Profile:     profile.ID,
+		ProfileName: profile.Name,
+		Checks:      e.checks,
+	}
+	for _, check := range e.checks {
+		switch check.Status {
+		case DeliveryCheckPass:
+			compliance.Passed++
+		case DeliveryCheckFail:
+			compliance.Failed++
+		case DeliveryCheckWarning:
+			compliance.Warnings++
+		case DeliveryCheckNotMeasured:
+			compliance.NotMeasured++
+		}
+	}
+	compliance.Compliant = compliance.Failed == 0 && compliance.NotMeasured == 0
+
+	return compliance
+}
+
+// deliveryEvaluator accumulates checks for one profile evaluation
+type deliveryEvaluator struct {
+	result   *FFprobeResult
+	enhanced *EnhancedAnalysis
+	content  *ContentAnalysis
+	checks   []DeliveryCheck
+}
+
+func (e *deliveryEvaluator) add(rule string, ok bool, advisory bool, expected, actual string) {
+	status := DeliveryCheckPass
+	if !ok {
+		status = DeliveryCheckFail
+		if advisory {
+			status = DeliveryCheckWarning
+		}
+	}
+	e.checks = append(e.checks, DeliveryCheck{Rule: rule, Status: status, Expected: expected, Actual: actual})
+}
+
+func (e *deliveryEvaluator) notMeasured(rule, expected string) {
+	e.checks = append(e.checks, DeliveryCheck{Rule: rule, Status: DeliveryCheckNotMeasured, Expected: expected})
+}
+
+// oneOf formats an allowed-values list for the expected column
+func oneOf(values []string) string {
+	if len(values) == 1 {
+		return values[0]
+	}
+	return "one of " + strings.Join(values, ", ")
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *deliveryEvaluator) checkContainer(c ContainerConstraints) {
+	if len(c.Formats) > 0 {
+		var formatName string
+		if e.result.Format != nil {
+			formatName = e.result.Format.FormatName
+		}
+		// ffprobe reports demuxer aliases such as "mov,mp4,m4a,3gp,3g2,mj2"
+		ok := false
+		for _, name := range strings.Split(formatName, ",") {
+			ok = ok || containsFold(c.Formats, name)
+		}
+		e.add("container.format", ok, false, oneOf(c.Formats), formatName)
+	}
+
+	var video []StreamInfo
+	var audio []StreamInfo
+	for _, stream := range e.result.Streams {
+		switch stream.CodecType {
+		case "video":
+			// Cover art and thumbnails are not programme video
+			if stream.Disposition["attached_pic"] == 0 {
+				video = append(video, stream)
+			}
+		case "audio":
+			audio = append(audio, stream)
+		}
+	}
+
+	if len(c.VideoCodecs) > 0 || len(c.Resolutions) > 0 || len(c.FrameRates) > 0 {
+		if len(video) == 0 {
+			e.add("video.stream", false, false, "video stream present", "none")
+		}
+	}
+	for i, stream := range video {
+		prefix := fmt.Sprintf("video[%d]", i)
+		if len(c.VideoCodecs) > 0 {
+			e.add(prefix+".codec", containsFold(c.VideoCodecs, stream.CodecName), false, oneOf(c.VideoCodecs), stream.CodecName)
+		}
+		if len(c.Resolutions) > 0 {
+			resolution := fmt.Sprintf("%dx%d", stream.Width, stream.Height)
+			e.add(prefix+".resolution", containsFold(c.Resolutions, resolution), false, oneOf(c.Resolutions), resolution)
+		}
+		if len(c.FrameRates) > 0 {
+			e.add(prefix+".frame_rate", containsFold(c.FrameRates, stream.RFrameRate), false, oneOf(c.FrameRates), stream.RFrameRate)
+		}
+	}
+
+	if len(c.AudioCodecs) > 0 || len(c.AudioSampleRates) > 0 || c.MinAudioChannels > 0 {
+		if len(audio) == 0 {
+			e.add("audio.stream", false, false, "audio stream present", "none")
+			return
+		}
+	}
+	channels := 0
+	for i, stream := range audio {
+		channels += stream.Channels
+		prefix := fmt.Sprintf("audio[%d]", i)
+		if len(c.AudioCodecs) > 0 {
+			e.add(prefix+".codec", containsFold(c.AudioCodecs, stream.CodecName), false, oneOf(c.AudioCodecs), stream.CodecName)
+		}
+		if len(c.AudioSampleRates) > 0 {
+			rates := make([]string, len(c.AudioSampleRates))
+			for j, rate := range c.AudioSampleRates {
+				rates[j] = fmt.Sprintf("%d", rate)
+			}
+			e.add(prefix+".sample_rate", containsFold(rates, stream.SampleRate), false, oneOf(rates)+" Hz", stream.SampleRate+" Hz")
+		}
+	}
+	if c.MinAudioChannels > 0 {
+		e.add("audio.channels", channels >= c.MinAudioChannels, false, fmt.Sprintf("at least %d", c.MinAudioChannels), fmt.Sprintf("%d", channels))
+	}
+}
+
+func (e *deliveryEvaluator) checkLoudness(t *LoudnessThresholds) {
+	var loudness *LoudnessAnalysis
+	if e.content != nil {
+		loudness = e.content.LoudnessMeter
+	}
+
+	if t.ToleranceLU > 0 {
+		expected := fmt.Sprintf("%.1f LUFS ±%.1f LU (%s)", t.IntegratedLUFS, t.ToleranceLU, t.Standard)
+		if t.DialogGated {
+			expected = fmt.Sprintf("%.1f LUFS ±%.1f LU dialog-gated (%s)", t.IntegratedLUFS, t.ToleranceLU, t.Standard)
+		}
+		if loudness == nil {
+			e.notMeasured("loudness.integrated", expected)
+		} else {
+			measured, actual := loudness.IntegratedLoudness, fmt.Sprintf("%.1f LUFS", loudness.IntegratedLoudness)
+			if t.DialogGated {
+				if dialog := loudness.Dialog; dialog != nil && dialog.IntegratedLoudness != nil {
+					measured, actual = *dialog.IntegratedLoudness, fmt.Sprintf("%.1f LUFS dialog", *dialog.IntegratedLoudness)
+				} else {
+					actual += " programme, too little dialog"
+				}
+			}
+			ok := math.Abs(measured-t.IntegratedLUFS) <= t.ToleranceLU
+			e.add("loudness.integrated", ok, t.Advisory, expected, actual)
+		}
+	}
+
+	expected := fmt.Sprintf("<= %.1f dBTP", t.MaxTruePeakDBTP)
+	if loudness == nil {
+		e.notMeasured("loudness.true_peak", expected)
+	} else {
+		e.add("loudness.true_peak", loudness.TruePeak <= t.MaxTruePeakDBTP, t.Advisory, expected, fmt.Sprintf("%.1f dBTP", loudness.TruePeak))
+	}
+}
+
+func (e *deliveryEvaluator) checkVideoLevels(t *VideoLevelThresholds) {
+	var baseband *BasebandAnalysis
+	if e.content != nil {
+		baseband = e.content.BasebandInfo
+	}
+
+	rangeExpected := fmt.Sprintf("<= %.1f%% of frames outside legal range (%s)", t.MaxOutOfRangeFramesPercent, t.Standard)
+	gamutExpected := fmt.Sprintf("<= %.1f%% gamut errors", t.MaxGamutErrorPercent)
+	if baseband == nil || baseband.FramesAnalyzed == 0 {
+		e.notMeasured("video_levels.legal_range", rangeExpected)
+		e.notMeasured("video_levels.gamut", gamutExpected)
+		return
+	}
+
+	outOfRange := math.Max(baseband.LumaOutOfRangePercent, baseband.ChromaOutOfRangePercent)
+	e.add("video_levels.legal_range", outOfRange <= t.MaxOutOfRangeFramesPercent, false, rangeExpected, fmt.Sprintf("%.2f%%", outOfRange))
+	e.add("video_levels.gamut", baseband.GamutErrorPercent <= t.MaxGamutErrorPercent, false, gamutExpected, fmt.Sprintf("%.2f%%", baseband.GamutErrorPercent))
+}
+
+func (e *deliveryEvaluator) checkTimecode(t *TimecodeRequirements) {
+	var timecode *TimecodeAnalysis
+	if e.enhanced != nil {
+		timecode = e.enhanced.TimecodeAnalysis
+	}
+
+	if timecode == nil {
+		if t.Required || t.StartTimecode != "" {
+			e.notMeasured("timecode.present", "timecode track present")
+		}
+		return
+	}
+
+	if t.Required {
+		e.add("timecode.present", timecode.HasTimecode, false, "timecode track present", yesNoString(timecode.HasTimecode))
+	}
+	if !timecode.HasTimecode {
+		return
+	}
+
+	if t.StartTimecode != "" {
+		var start string
+		if timecode.PrimaryTimecode != nil {
+			start = timecode.PrimaryTimecode.StartTimecode
+		}
+		// Drop-frame timecode uses ';' before the frame count
+		ok := strings.ReplaceAll(start, ";", ":") == t.StartTimecode
+		e.add("timecode.start", ok, false, t.StartTimecode, start)
+	}
+	if !t.AllowDropFrame {
+		e.add("timecode.drop_frame", !timecode.IsDropFrame, false, "non-drop-frame", dropFrameLabel(timecode.IsDropFrame))
+	}
+}
+
+func yesNoString(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+func dropFrameLabel(dropFrame bool) string {
+	if dropFrame {
+		return "drop-frame"
+	}
+	return "non-drop-frame"
+}
@@ -0,0 +1,156 @@
+package ffmpeg
+
+import (
+	"testing"
+)
+
+func as11Result() *FFprobeResult {
+	return &FFprobeResult{
+		Format: &FormatInfo{FormatName: "mxf"},
+		Streams: []StreamInfo{
+			{Index: 0, CodecType: "video", CodecName: "h264", Width: 1920, Height: 1080, RFrameRate: "25/1"},
+			{Index: 1, CodecType: "audio", CodecName: "pcm_s24le", SampleRate: "48000", Channels: 2},
+			{Index: 2, CodecType: "audio", CodecName: "pcm_s24le", SampleRate: "48000", Channels: 2},
+		},
+		EnhancedAnalysis: &EnhancedAnalysis{
+			ContentAnalysis: &ContentAnalysis{
+				LoudnessMeter: &LoudnessAnalysis{IntegratedLoudness: -23.2, TruePeak: -2.5},
+				BasebandInfo:  &BasebandAnalysis{FramesAnalyzed: 250, LumaOutOfRangePercent: 0.4},
+			},
+			TimecodeAnalysis: &TimecodeAnalysis{
+				HasTimecode:     true,
+				PrimaryTimecode: &TimecodeInfo{StartTimecode: "10:00:00:00"},
+			},
+		},
+	}
+}
+
+func checkStatuses(compliance *DeliveryCompliance) map[string]string {
+	statuses := make(map[string]string, len(compliance.Checks))
+	for _, check := range compliance.Checks {
+		statuses[check.Rule] = check.Status
+	}
+	return statuses
+}
+
+func TestEvaluateDeliveryProfileCompliant(t *testing.T) {
+	profile, err := LookupDeliveryProfile("DPP_AS11_UK")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	compliance := EvaluateDeliveryProfile(as11Result(), profile)
+	if !compliance.Compliant {
+		t.Fatalf("expected compliant, got checks %+v", compliance.Checks)
+	}
+	if compliance.Passed != len(compliance.Checks) {
+		t.Errorf("expected all %d checks to pass, got %d", len(compliance.Checks), compliance.Passed)
+	}
+}
+
+func TestEvaluateDeliveryProfileFailures(t *testing.T) {
+	profile, _ := LookupDeliveryProfile("dpp_as11_uk")
+	result := as11Result()
+	result.Format.FormatName = "mov,mp4,m4a,3gp,3g2,mj2"
+	result.Streams[0].RFrameRate = "30000/1001"
+	result.Streams = result.Streams[:2]
+	result.EnhancedAnalysis.ContentAnalysis.LoudnessMeter.IntegratedLoudness = -20
+	result.EnhancedAnalysis.ContentAnalysis.BasebandInfo = nil
+	result.EnhancedAnalysis.TimecodeAnalysis.PrimaryTimecode.StartTimecode = "00:00:00:00"
+
+	compliance := EvaluateDeliveryProfile(result, profile)
+	if compliance.Compliant {
+		t.Fatal("expected non-compliant result")
+	}
+
+	expected := map[string]string{
+		"container.format":         DeliveryCheckFail,
+		"video[0].codec":           DeliveryCheckPass,
+		"video[0].frame_rate":      DeliveryCheckFail,
+		"audio.channels":           DeliveryCheckFail,
+		"loudness.integrated":      DeliveryCheckFail,
+		"loudness.true_peak":       DeliveryCheckPass,
+		"video_levels.legal_range": DeliveryCheckNotMeasured,
+		"timecode.present":         DeliveryCheckPass,
+		"timecode.start":           DeliveryCheckFail,
+		"timecode.drop_frame":      DeliveryCheckPass,
+		"audio[0].sample_rate":     DeliveryCheckPass,
+		"video_levels.gamut":       DeliveryCheckNotMeasured,
+		"video[0].resolution":      DeliveryCheckPass,
+		"audio[0].codec":           DeliveryCheckPass,
+	}
+	statuses := checkStatuses(compliance)
+	for rule, status := range expected {
+		if statuses[rule] != status {
+			t.Errorf("%s: expected %s, got %q", rule, status, statuses[rule])
+		}
+	}
+	if compliance.Failed != 5 || compliance.NotMeasured != 2 {
+		t.Errorf("expected 5 failed and 2 not measured, got %d and %d", compliance.Failed, compliance.NotMeasured)
+	}
+}
+
+func TestEvaluateDeliveryProfileAdvisory(t *testing.T) {
+	profile, _ := LookupDeliveryProfile("youtube")
+	result := &FFprobeResult{
+		Format: &FormatInfo{FormatName: "mov,mp4,m4a,3gp,3g2,mj2"},
+		Streams: []StreamInfo{
+			{CodecType: "video", CodecName: "h264", Width: 1280, Height: 720, RFrameRate: "30/1"},
+			{CodecType: "video", CodecName: "mjpeg", Disposition: map[string]int{"attached_pic": 1}},
+			{CodecType: "audio", CodecName: "aac", SampleRate: "48000", Channels: 2},
+		},
+		EnhancedAnalysis: &EnhancedAnalysis{
+			ContentAnalysis: &ContentAnalysis{LoudnessMeter: &LoudnessAnalysis{IntegratedLoudness: -9, TruePeak: 0.3}},
+		},
+	}
+
+	compliance := EvaluateDeliveryProfile(result, profile)
+	if !compliance.Compliant {
+		t.Errorf("advisory misses should not break compliance: %+v", compliance.Checks)
+	}
+	if compliance.Warnings != 2 {
+		t.Errorf("expected 2 warnings, got %d", compliance.Warnings)
+	}
+	if _, ok := checkStatuses(compliance)["video[1].codec"]; ok {
+		t.Error("cover art should not be checked as programme video")
+	}
+}
+
+func TestLookupDeliveryProfile(t *testing.T) {
+	if profile, err := LookupDeliveryProfile(""); profile != nil || err != nil {
+		t.Errorf("empty name should select no profile, got %v, %v", profile, err)
+	}
+	if _, err := LookupDeliveryProfile("bbc"); err == nil {
+		t.Error("expected error for unknown profile")
+	}
+	for _, profile := range DeliveryProfiles() {
+		if got, err := LookupDeliveryProfile(profile.ID); err != nil || got != profile {
+			t.Errorf("LookupDeliveryProfile(%q) = %v, %v", profile.ID, got, err)
+		}
+	}
+
+	profile, _ := LookupDeliveryProfile("dpp_as11_uk")
+	categories := profile.QCCategories()
+	if len(categories) != 3 || categories[0] != QCCategoryLoudness || categories[1] != QCCategoryVideoLevels || categories[2] != QCCategoryTimecode {
+		t.Errorf("unexpected categories: %v", categories)
+	}
+}
+
+func TestEvaluateDeliveryProfileDialogGated(t *testing.T) {
+	profile, _ := LookupDeliveryProfile("netflix_imf")
+	dialog := -27.5
+	loudness := &LoudnessAnalysis{IntegratedLoudness: -22, TruePeak: -3, Dialog: &DialogLoudness{IntegratedLoudness: &dialog}}
+	result := &FFprobeResult{
+		EnhancedAnalysis: &EnhancedAnalysis{ContentAnalysis: &ContentAnalysis{LoudnessMeter: loudness}},
+	}
+
+	if status := checkStatuses(EvaluateDeliveryProfile(result, profile))["loudness.integrated"]; status != DeliveryCheckPass {
+		t.Errorf("dialog loudness within tolerance: got %q", status)
+	}
+
+	// Without enough dialog the programme loudness is checked
+	loudness.Dialog.IntegratedLoudness = nil
+	if status := checkStatuses(EvaluateDeliveryProfile(result, profile))["loudness.integrated"]; status != DeliveryCheckFail {
+		t.Errorf("programme loudness out of tolerance: got %q", status)
+	}
+}
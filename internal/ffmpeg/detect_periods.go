@@ -0,0 +1,78 @@
+package ffmpeg
+
+import (
+	"strconv"
+	"strings"
+)
+
+// logValue returns the number following "key:" in an ffmpeg filter log line
+func logValue(line, key string) (float64, bool) {
+	i := strings.Index(line, key+":")
+	if i < 0 {
+		return 0, false
+	}
+	fields := strings.Fields(line[i+len(key)+1:])
+	if len(fields) == 0 {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	return value, err == nil
+}
+
+// parseBlackDetect collects the periods blackdetect logs, one line each:
+//
+//	[blackdetect @ 0x...] black_start:12.4 black_end:14.48 black_duration:2.08
+func parseBlackDetect(output []byte) []EventPeriod {
+	var periods []EventPeriod
+	forEachLine(output, func(line string) bool {
+		if !strings.Contains(line, "blackdetect") {
+			return true
+		}
+		start, ok := logValue(line, "black_start")
+		if !ok {
+			return true
+		}
+		end, _ := logValue(line, "black_end")
+		duration, ok := logValue(line, "black_duration")
+		if !ok {
+			duration = end - start
+		}
+		periods = append(periods, EventPeriod{StartTime: start, EndTime: end, Duration: duration})
+		return true
+	})
+	return periods
+}
+
+// parseFreezeDetect collects the periods freezedetect logs as separate
+// freeze_start, freeze_duration and freeze_end lines. A freeze still running
+// when the input ends has no end and keeps a zero duration.
+func parseFreezeDetect(output []byte) []EventPeriod {
+	var periods []EventPeriod
+	open := false
+	forEachLine(output, func(line string) bool {
+		if !strings.Contains(line, "freezedetect") {
+			return true
+		}
+		if start, ok := logValue(line, "lavfi.freezedetect.freeze_start"); ok {
+			periods = append(periods, EventPeriod{StartTime: start, EndTime: start})
+			open = true
+			return true
+		}
+		if !open {
+			return true
+		}
+		period := &periods[len(periods)-1]
+		if duration, ok := logValue(line, "lavfi.freezedetect.freeze_duration"); ok {
+			period.Duration = duration
+		}
+		if end, ok := logValue(line, "lavfi.freezedetect.freeze_end"); ok {
+			period.EndTime = end
+			if period.Duration == 0 {
+				period.Duration = end - period.StartTime
+			}
+			open = false
+		}
+		return true
+	})
+	return periods
+}
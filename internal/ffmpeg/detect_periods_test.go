@@ -0,0 +1,36 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBlackDetect(t *testing.T) {
+	output := []byte(`Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'clip.mp4':
+[blackdetect @ 0x5581] black_start:0 black_end:1.52 black_duration:1.52
+frame= 1200 fps=400 q=-0.0 size=N/A time=00:00:48.00 bitrate=N/A speed=16x
+[blackdetect @ 0x5581] black_start:30.04 black_end:31.2 black_duration:1.16
+`)
+	want := []EventPeriod{
+		{StartTime: 0, EndTime: 1.52, Duration: 1.52},
+		{StartTime: 30.04, EndTime: 31.2, Duration: 1.16},
+	}
+	if got := parseBlackDetect(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseBlackDetect = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseFreezeDetect(t *testing.T) {
+	output := []byte(`[freezedetect @ 0x55c1] lavfi.freezedetect.freeze_start: 5.005
+[freezedetect @ 0x55c1] lavfi.freezedetect.freeze_duration: 2.5025
+[freezedetect @ 0x55c1] lavfi.freezedetect.freeze_end: 7.5075
+[freezedetect @ 0x55c1] lavfi.freezedetect.freeze_start: 40
+`)
+	want := []EventPeriod{
+		{StartTime: 5.005, EndTime: 7.5075, Duration: 2.5025},
+		{StartTime: 40, EndTime: 40},
+	}
+	if got := parseFreezeDetect(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseFreezeDetect = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,216 @@
+package ffmpeg
+
+import (
+	"math"
+	"strings"
+)
+
+// dialogLoudnessFilter follows ebur128 in the loudness filter chain. It
+// downmixes to mono and logs the RMS level of every 100 ms block twice:
+// over the full band and over the 300-3400 Hz speech band.
+const dialogLoudnessFilter = "aresample=48000,aformat=channel_layouts=mono,asetnsamples=n=4800:p=0," +
+	"astats=metadata=1:reset=1,ametadata=mode=print:key=lavfi.astats.Overall.RMS_level," +
+	"highpass=f=300,lowpass=f=3400," +
+	"astats=metadata=1:reset=1,ametadata=mode=print:key=lavfi.astats.Overall.RMS_level"
+
+// The ametadata instances of the chain, numbered after ebur128
+const (
+	dialogFullBandFilter   = "Parsed_ametadata_5 "
+	dialogSpeechBandFilter = "Parsed_ametadata_9 "
+)
+
+const (
+	dialogBlockSeconds = 0.1
+	// dialogFloorDB is the level below which a block is taken as silence
+	dialogFloorDB = -50.0
+	// dialogBandShareDB is how far below the full band the speech band may
+	// be, i.e. at least half of the energy lies in the speech band
+	dialogBandShareDB = -3.0
+	// dialogModulationDB is the spread of speech band levels over half a
+	// second that marks syllables; sustained music and effects vary less
+	dialogModulationDB = 4.0
+	// dialogMinSeconds of speech are needed to gate on dialog
+	dialogMinSeconds = 5.0
+	// BS.1770-4 gates
+	dialogAbsoluteGate = -70.0
+	dialogRelativeGate = -10.0
+)
+
+// DialogLoudness is the integrated loudness of the blocks that carry speech.
+// Speech is detected from the share of energy in the speech band and its
+// syllabic modulation, which approximates dialog gating rather than
+// implementing Dolby's Dialogue Intelligence.
+type DialogLoudness struct {
+	IntegratedLoudness *float64 `json:"integrated_loudness_lufs,omitempty"`  // Omitted with too little speech to gate on
+	Threshold          float64  `json:"integrated_threshold_lufs,omitempty"` // Relative gate of the dialog loudness
+	SpeechDuration     float64  `json:"speech_duration_seconds"`
+	SpeechPercentage   float64  `json:"speech_percentage"` // Share of the measured duration
+	Method             string   `json:"method"`
+}
+
+// dialogBlock is the level of one 100 ms block
+type dialogBlock struct {
+	full, band float64
+	hasFull    bool
+	hasBand    bool
+}
+
+// audible reports whether the block is above the silence floor
+func (b dialogBlock) audible() bool {
+	return b.hasFull && b.full > dialogFloorDB
+}
+
+// dialogMomentary is one logged 400 ms momentary loudness
+type dialogMomentary struct {
+	end      float64
+	loudness float64
+}
+
+// parseDialogLoudness gates the ebur128 momentary loudness of the loudness
+// filter chain on the speech blocks its ametadata instances logged. It
+// returns nil when the output has no block levels.
+func parseDialogLoudness(output []byte) *DialogLoudness {
+	var blocks []dialogBlock
+	var momentary []dialogMomentary
+	times := make(map[string]float64)
+
+	forEachLine(output, func(line string) bool {
+		switch {
+		case strings.Contains(line, "Parsed_ebur128") && strings.Contains(line, " t:"):
+			t, okTime := loudnessField(line, " t:")
+			m, okLoudness := loudnessField(line, " M:")
+			if okTime && okLoudness {
+				momentary = append(momentary, dialogMomentary{end: t, loudness: m})
+			}
+		case strings.Contains(line, dialogFullBandFilter), strings.Contains(line, dialogSpeechBandFilter):
+			filter := dialogFullBandFilter
+			if strings.Contains(line, dialogSpeechBandFilter) {
+				filter = dialogSpeechBandFilter
+			}
+			if index := strings.Index(line, "pts_time:"); index >= 0 {
+				if t, ok := parseFiniteValue(firstField(line[index+len("pts_time:"):])); ok {
+					times[filter] = t
+				}
+				return true
+			}
+			index := strings.Index(line, "RMS_level=")
+			if index < 0 {
+				return true
+			}
+			block := int(math.Round(times[filter] / dialogBlockSeconds))
+			if block < 0 {
+				return true
+			}
+			for len(blocks) <= block {
+				blocks = append(blocks, dialogBlock{})
+			}
+			// Digital silence is logged as -inf and stays unset
+			level, ok := parseFiniteValue(firstField(line[index+len("RMS_level="):]))
+			if filter == dialogFullBandFilter {
+				blocks[block].full, blocks[block].hasFull = level, ok
+			} else {
+				blocks[block].band, blocks[block].hasBand = level, ok
+			}
+		}
+		return true
+	})
+
+	if len(blocks) == 0 {
+		return nil
+	}
+	return gateDialog(blocks, momentary)
+}
+
+// gateDialog classifies the blocks and integrates the momentary loudness of
+// the windows that are mostly speech
+func gateDialog(blocks []dialogBlock, momentary []dialogMomentary) *DialogLoudness {
+	speech := dialogSpeechBlocks(blocks)
+	speechBlocks := 0
+	for _, s := range speech {
+		if s {
+			speechBlocks++
+		}
+	}
+
+	dialog := &DialogLoudness{
+		SpeechDuration:   roundTo(float64(speechBlocks)*dialogBlockSeconds, 1),
+		SpeechPercentage: roundTo(100*float64(speechBlocks)/float64(len(blocks)), 1),
+		Method:           "ITU-R BS.1770-4 gating of speech blocks",
+	}
+	if dialog.SpeechDuration < dialogMinSeconds {
+		return dialog
+	}
+
+	// A 400 ms window is dialog when at least two of its 100 ms blocks are
+	// speech
+	var gated []float64
+	for _, m := range momentary {
+		end := int(math.Round(m.end / dialogBlockSeconds))
+		count := 0
+		for block := end - 4; block < end; block++ {
+			if block >= 0 && block < len(speech) && speech[block] {
+				count++
+			}
+		}
+		if count >= 2 && m.loudness > dialogAbsoluteGate {
+			gated = append(gated, m.loudness)
+		}
+	}
+	if len(gated) == 0 {
+		return dialog
+	}
+
+	threshold := meanLoudness(gated, math.Inf(-1)) + dialogRelativeGate
+	integrated := roundTo(meanLoudness(gated, threshold), 1)
+	dialog.IntegratedLoudness = &integrated
+	dialog.Threshold = roundTo(threshold, 1)
+	return dialog
+}
+
+// dialogSpeechBlocks marks the audible blocks that have most of their energy
+// in the speech band and whose speech band level is modulated like syllables
+func dialogSpeechBlocks(blocks []dialogBlock) []bool {
+	speech := make([]bool, len(blocks))
+	for i, b := range blocks {
+		if !b.audible() || !b.hasBand || b.band-b.full < dialogBandShareDB {
+			continue
+		}
+
+		// Spread of the speech band levels over the surrounding half second,
+		// counting silence at the floor
+		var sum, sumSquares float64
+		n := 0
+		for j := i - 2; j <= i+2; j++ {
+			if j < 0 || j >= len(blocks) {
+				continue
+			}
+			level := dialogFloorDB
+			if blocks[j].audible() && blocks[j].hasBand {
+				level = math.Max(blocks[j].band, dialogFloorDB)
+			}
+			sum += level
+			sumSquares += level * level
+			n++
+		}
+		mean := sum / float64(n)
+		spread := math.Sqrt(math.Max(sumSquares/float64(n)-mean*mean, 0))
+		speech[i] = spread >= dialogModulationDB
+	}
+	return speech
+}
+
+// meanLoudness is the energy mean of the loudness values above gate
+func meanLoudness(values []float64, gate float64) float64 {
+	var energy float64
+	n := 0
+	for _, value := range values {
+		if value > gate {
+			energy += math.Pow(10, value/10)
+			n++
+		}
+	}
+	if n == 0 {
+		return gate
+	}
+	return 10 * math.Log10(energy/float64(n))
+}
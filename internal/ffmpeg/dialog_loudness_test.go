@@ -0,0 +1,63 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// dialogOutput logs the loudness chain for 100 ms blocks of steady music
+// followed by speech, whose speech band level alternates every 200 ms
+func dialogOutput(musicBlocks, speechBlocks int) []byte {
+	var output strings.Builder
+	for k := 0; k < musicBlocks+speechBlocks; k++ {
+		full, band, momentary := -18.0, -20.0, -15.0
+		if k >= musicBlocks {
+			full, band, momentary = -19.0, -20.0, -27.0
+			if ((k-musicBlocks)/2)%2 == 1 {
+				full, band = -35.0, -36.0
+			}
+		}
+		fmt.Fprintf(&output, "[Parsed_ebur128_0 @ 0x1] t: %g  TARGET:-23 LUFS  M: %.1f S: -20.0  I: -20.0 LUFS  LRA: 0.0 LU\n", float64(k+1)/10, momentary)
+		for filter, level := range map[string]float64{"5": full, "9": band} {
+			fmt.Fprintf(&output, "[Parsed_ametadata_%s @ 0x2] frame:%-4d pts:%-7d pts_time:%g\n", filter, k, k*4800, float64(k)/10)
+			fmt.Fprintf(&output, "[Parsed_ametadata_%s @ 0x2] lavfi.astats.Overall.RMS_level=%.1f\n", filter, level)
+		}
+	}
+	return []byte(output.String())
+}
+
+func TestParseDialogLoudness(t *testing.T) {
+	dialog := parseDialogLoudness(dialogOutput(80, 120))
+	if dialog == nil || dialog.IntegratedLoudness == nil {
+		t.Fatalf("dialog = %+v", dialog)
+	}
+	if *dialog.IntegratedLoudness != -27 || dialog.Threshold != -37 {
+		t.Errorf("integrated = %v, threshold = %v, want the speech alone at -27 LUFS", *dialog.IntegratedLoudness, dialog.Threshold)
+	}
+	if dialog.SpeechDuration != 12 || dialog.SpeechPercentage != 60 {
+		t.Errorf("speech = %v s, %v%%", dialog.SpeechDuration, dialog.SpeechPercentage)
+	}
+
+	// Too little speech measures the share but not the loudness
+	if sparse := parseDialogLoudness(dialogOutput(100, 20)); sparse == nil || sparse.IntegratedLoudness != nil || sparse.SpeechDuration != 2 {
+		t.Errorf("sparse dialog = %+v", sparse)
+	}
+	if music := parseDialogLoudness(dialogOutput(100, 0)); music == nil || music.SpeechPercentage != 0 {
+		t.Errorf("music = %+v", music)
+	}
+
+	// Digital silence is not speech
+	silent := []byte(`[Parsed_ametadata_5 @ 0x2] frame:0    pts:0       pts_time:0
+[Parsed_ametadata_5 @ 0x2] lavfi.astats.Overall.RMS_level=-inf
+[Parsed_ametadata_9 @ 0x2] frame:0    pts:0       pts_time:0
+[Parsed_ametadata_9 @ 0x2] lavfi.astats.Overall.RMS_level=-inf
+`)
+	if dialog := parseDialogLoudness(silent); dialog == nil || dialog.SpeechDuration != 0 {
+		t.Errorf("silence = %+v", dialog)
+	}
+
+	if dialog := parseDialogLoudness([]byte("[Parsed_ebur128_0 @ 0x1] t: 0.1  M: -20.0\n")); dialog != nil {
+		t.Errorf("output without block levels = %+v, want nil", dialog)
+	}
+}
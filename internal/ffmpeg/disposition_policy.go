@@ -0,0 +1,167 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Packaging rules checked on stream disposition flags
+const (
+	DispositionRuleDefaultVideo     = "default_video"     // Exactly one default video stream
+	DispositionRuleDefaultAudio     = "default_audio"     // Exactly one default audio stream per language
+	DispositionRuleForcedSubtitles  = "forced_subtitles"  // Forced flags consistent with the forced-only subtitles
+	DispositionRuleDefaultSubtitles = "default_subtitles" // Only forced subtitles shown by default
+)
+
+// DispositionPolicyRules lists the packaging rules in report order
+var DispositionPolicyRules = []string{
+	DispositionRuleDefaultVideo,
+	DispositionRuleDefaultAudio,
+	DispositionRuleForcedSubtitles,
+	DispositionRuleDefaultSubtitles,
+}
+
+// Severities of a disposition policy violation
+const (
+	DispositionViolationError   = "error"   // Players pick the wrong stream; fails QC
+	DispositionViolationWarning = "warning" // Worth reviewing
+)
+
+// DispositionViolation is a broadcast/OTT packaging rule the disposition
+// flags of a file break
+type DispositionViolation struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"` // "error" or "warning"
+	Streams  []int  `json:"streams"`
+	Language string `json:"language,omitempty"` // The language group, for per-language rules
+	Message  string `json:"message"`
+}
+
+// checkDispositionPolicy checks the flags of analysis against the packaging
+// rules players rely on to pick streams: exactly one default video, exactly
+// one default audio in each language, forced flags only on forced-only
+// subtitles, and no full subtitles shown by default
+func checkDispositionPolicy(analysis *StreamDispositionAnalysis) []DispositionViolation {
+	var violations []DispositionViolation
+	add := func(rule, severity, language string, streams []int, format string, args ...any) {
+		violations = append(violations, DispositionViolation{
+			Rule:     rule,
+			Severity: severity,
+			Streams:  streams,
+			Language: language,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	// Cover art and thumbnails are not played, so they take no part
+	var videos, defaultVideos []int
+	for _, index := range sortedStreamIndexes(analysis.VideoStreams) {
+		disposition := analysis.VideoStreams[index]
+		if disposition.AttachedPic || disposition.TimedThumbnails {
+			continue
+		}
+		videos = append(videos, index)
+		if disposition.Default {
+			defaultVideos = append(defaultVideos, index)
+		}
+	}
+	switch {
+	case len(videos) > 0 && len(defaultVideos) == 0:
+		add(DispositionRuleDefaultVideo, DispositionViolationError, "", videos,
+			"No video stream is flagged default")
+	case len(defaultVideos) > 1:
+		add(DispositionRuleDefaultVideo, DispositionViolationError, "", defaultVideos,
+			"%d video streams are flagged default (streams %s)", len(defaultVideos), joinStreamIndexes(defaultVideos))
+	}
+
+	audio := groupByLanguage(analysis.AudioStreams)
+	for _, language := range sortedLanguages(audio) {
+		var defaults []int
+		for _, index := range audio[language] {
+			if analysis.AudioStreams[index].Default {
+				defaults = append(defaults, index)
+			}
+		}
+		switch {
+		case len(defaults) == 0:
+			add(DispositionRuleDefaultAudio, DispositionViolationError, language, audio[language],
+				"No %s audio stream is flagged default (streams %s)", language, joinStreamIndexes(audio[language]))
+		case len(defaults) > 1:
+			add(DispositionRuleDefaultAudio, DispositionViolationError, language, defaults,
+				"%d %s audio streams are flagged default (streams %s)", len(defaults), language, joinStreamIndexes(defaults))
+		}
+	}
+
+	audioLanguages := make(map[string]bool, len(audio))
+	for language := range audio {
+		audioLanguages[language] = true
+	}
+	subtitles := groupByLanguage(analysis.SubtitleStreams)
+	for _, language := range sortedLanguages(subtitles) {
+		var forced []int
+		for _, index := range subtitles[language] {
+			disposition := analysis.SubtitleStreams[index]
+			titledForced := strings.Contains(strings.ToLower(disposition.Title), "forced")
+			switch {
+			case titledForced && !disposition.Forced:
+				add(DispositionRuleForcedSubtitles, DispositionViolationError, language, []int{index},
+					"Subtitle stream %d is titled %q but not flagged forced", index, disposition.Title)
+			case disposition.Forced && disposition.HearingImpaired:
+				add(DispositionRuleForcedSubtitles, DispositionViolationError, language, []int{index},
+					"Subtitle stream %d is flagged both forced and hearing impaired", index)
+			}
+			if disposition.Forced {
+				forced = append(forced, index)
+			} else if disposition.Default {
+				add(DispositionRuleDefaultSubtitles, DispositionViolationWarning, language, []int{index},
+					"Subtitle stream %d is flagged default but not forced, so it shows for every viewer", index)
+			}
+		}
+		if len(forced) > 1 {
+			add(DispositionRuleForcedSubtitles, DispositionViolationError, language, forced,
+				"%d %s subtitle streams are flagged forced (streams %s)", len(forced), language, joinStreamIndexes(forced))
+		}
+		if len(forced) > 0 && len(audio) > 0 && !audioLanguages[language] {
+			add(DispositionRuleForcedSubtitles, DispositionViolationWarning, language, forced,
+				"Forced %s subtitles have no %s audio to accompany", language, language)
+		}
+	}
+	return violations
+}
+
+// groupByLanguage returns the stream indexes of each language, in order
+func groupByLanguage(streams map[int]*StreamDisposition) map[string][]int {
+	groups := make(map[string][]int)
+	for _, index := range sortedStreamIndexes(streams) {
+		language := streams[index].Language
+		groups[language] = append(groups[language], index)
+	}
+	return groups
+}
+
+func sortedStreamIndexes(streams map[int]*StreamDisposition) []int {
+	indexes := make([]int, 0, len(streams))
+	for index := range streams {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+func sortedLanguages(groups map[string][]int) []string {
+	languages := make([]string, 0, len(groups))
+	for language := range groups {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+func joinStreamIndexes(indexes []int) string {
+	parts := make([]string, len(indexes))
+	for i, index := range indexes {
+		parts[i] = fmt.Sprint(index)
+	}
+	return strings.Join(parts, ", ")
+}
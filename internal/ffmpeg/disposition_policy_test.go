@@ -0,0 +1,89 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// dispositionStream builds a stream with disposition flags and tags
+func dispositionStream(index int, codecType, language string, flags ...string) StreamInfo {
+	stream := StreamInfo{Index: index, CodecType: codecType, Disposition: map[string]int{}, Tags: map[string]string{}}
+	if language != "" {
+		stream.Tags["language"] = language
+	}
+	for _, flag := range flags {
+		stream.Disposition[flag] = 1
+	}
+	return stream
+}
+
+func TestCheckDispositionPolicy(t *testing.T) {
+	analyzer := NewStreamDispositionAnalyzer("ffprobe", zerolog.Nop())
+
+	compliant := []StreamInfo{
+		dispositionStream(0, "video", "", "default"),
+		dispositionStream(1, "video", "", "attached_pic"),
+		dispositionStream(2, "audio", "eng", "default"),
+		dispositionStream(3, "audio", "eng", "visual_impaired"),
+		dispositionStream(4, "audio", "fre", "default"),
+		dispositionStream(5, "subtitle", "fre", "forced", "default"),
+		dispositionStream(6, "subtitle", "eng", "hearing_impaired"),
+	}
+	analysis, err := analyzer.AnalyzeStreamDisposition(t.Context(), "in.mp4", compliant)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !analysis.PolicyCompliant || len(analysis.PolicyViolations) != 0 {
+		t.Errorf("expected no violations, got %+v", analysis.PolicyViolations)
+	}
+
+	forcedTitle := dispositionStream(8, "subtitle", "eng")
+	forcedTitle.Tags["title"] = "English (Forced)"
+	broken := []StreamInfo{
+		dispositionStream(0, "video", "", "default"),
+		dispositionStream(1, "video", "", "default"),
+		dispositionStream(2, "audio", "eng", "default"),
+		dispositionStream(3, "audio", "eng", "default"),
+		dispositionStream(4, "audio", "fre"),
+		dispositionStream(5, "subtitle", "eng", "forced", "hearing_impaired"),
+		dispositionStream(6, "subtitle", "ger", "forced"),
+		dispositionStream(7, "subtitle", "fre", "default"),
+		forcedTitle,
+	}
+	analysis, err = analyzer.AnalyzeStreamDisposition(t.Context(), "in.mp4", broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.PolicyCompliant || analysis.Validation.IsValid {
+		t.Error("expected the errors to fail the disposition checks")
+	}
+
+	want := []DispositionViolation{
+		{DispositionRuleDefaultVideo, DispositionViolationError, []int{0, 1}, "", "2 video streams are flagged default (streams 0, 1)"},
+		{DispositionRuleDefaultAudio, DispositionViolationError, []int{2, 3}, "eng", "2 eng audio streams are flagged default (streams 2, 3)"},
+		{DispositionRuleDefaultAudio, DispositionViolationError, []int{4}, "fre", "No fre audio stream is flagged default (streams 4)"},
+		{DispositionRuleForcedSubtitles, DispositionViolationError, []int{5}, "eng", "Subtitle stream 5 is flagged both forced and hearing impaired"},
+		{DispositionRuleForcedSubtitles, DispositionViolationError, []int{8}, "eng", `Subtitle stream 8 is titled "English (Forced)" but not flagged forced`},
+		{DispositionRuleDefaultSubtitles, DispositionViolationWarning, []int{7}, "fre", "Subtitle stream 7 is flagged default but not forced, so it shows for every viewer"},
+		{DispositionRuleForcedSubtitles, DispositionViolationWarning, []int{6}, "ger", "Forced ger subtitles have no ger audio to accompany"},
+	}
+	if !reflect.DeepEqual(analysis.PolicyViolations, want) {
+		t.Errorf("violations:\n got %+v\nwant %+v", analysis.PolicyViolations, want)
+	}
+}
+
+func TestCheckDispositionPolicy_MultipleForced(t *testing.T) {
+	analysis := &StreamDispositionAnalysis{
+		AudioStreams: map[int]*StreamDisposition{1: {StreamIndex: 1, Default: true, Language: "eng"}},
+		SubtitleStreams: map[int]*StreamDisposition{
+			2: {StreamIndex: 2, Forced: true, Language: "eng"},
+			3: {StreamIndex: 3, Forced: true, Language: "eng"},
+		},
+	}
+	violations := checkDispositionPolicy(analysis)
+	if len(violations) != 1 || violations[0].Rule != DispositionRuleForcedSubtitles || !reflect.DeepEqual(violations[0].Streams, []int{2, 3}) {
+		t.Errorf("expected two forced English subtitles to be flagged, got %+v", violations)
+	}
+}
@@ -0,0 +1,510 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// dialnormTolerance is how far, in dB, dialnorm may sit from the measured
+// integrated loudness before the stream is flagged
+const dialnormTolerance = 2.0
+
+// dolbySyncWord starts every AC-3 and E-AC-3 sync frame
+const dolbySyncWord = 0x0B77
+
+// DolbyAnalyzer reads the metadata carried in Dolby audio bitstreams
+type DolbyAnalyzer struct {
+	ffmpegPath string
+	logger     zerolog.Logger
+}
+
+// NewDolbyAnalyzer creates a new Dolby metadata analyzer
+func NewDolbyAnalyzer(ffmpegPath string, logger zerolog.Logger) *DolbyAnalyzer {
+	return &DolbyAnalyzer{
+		ffmpegPath: ffmpegPath,
+		logger:     logger,
+	}
+}
+
+// DolbyAudioAnalysis contains the Dolby metadata of each Dolby audio stream
+type DolbyAudioAnalysis struct {
+	Streams []DolbyStreamMetadata `json:"streams"`
+}
+
+// DolbyStreamMetadata is the metadata of one AC-3, E-AC-3 or TrueHD stream.
+// Bitstream fields are read from the first sync frame and are only set for
+// AC-3 and E-AC-3.
+type DolbyStreamMetadata struct {
+	StreamIndex       int            `json:"stream_index"`
+	Format            string         `json:"format"` // "AC-3", "E-AC-3" or "TrueHD"
+	BitstreamID       int            `json:"bsid,omitempty"`
+	BitstreamMode     string         `json:"bitstream_mode,omitempty"`    // Service type, e.g. "complete_main"
+	AudioCodingMode   string         `json:"audio_coding_mode,omitempty"` // Front/rear channels, e.g. "3/2"
+	LFE               bool           `json:"lfe"`                         // Low frequency effects channel present
+	Dialnorm          *int           `json:"dialnorm_db,omitempty"`       // Dialogue level, -31 to -1 dBFS
+	CompressionGainDB *float64       `json:"compr_gain_db,omitempty"`     // RF-mode DRC gain word of the first frame
+	Downmix           *DolbyDownmix  `json:"downmix,omitempty"`           // Stereo downmix metadata, when signalled
+	Atmos             bool           `json:"atmos"`                       // Dolby Atmos (E-AC-3 JOC or TrueHD) present
+	AtmosComplexity   int            `json:"atmos_complexity,omitempty"`  // JOC complexity index, the number of coded objects
+	BedConfiguration  string         `json:"bed_configuration,omitempty"` // Channel layout of the bed, e.g. "5.1(side)"
+	DialnormCheck     *DialnormCheck `json:"dialnorm_check,omitempty"`
+	Error             string         `json:"error,omitempty"` // Why the bitstream could not be read
+}
+
+// DolbyDownmix contains the stereo downmix metadata. Levels are gains in dB
+// applied to the center and surround channels, omitted when the channel is
+// muted in the downmix or the level is not signalled.
+type DolbyDownmix struct {
+	PreferredMode        string   `json:"preferred_mode,omitempty"` // "not_indicated", "lt_rt", "lo_ro" or "pro_logic_ii"
+	CenterMixLevel       *float64 `json:"center_mix_level_db,omitempty"`
+	SurroundMixLevel     *float64 `json:"surround_mix_level_db,omitempty"`
+	LtRtCenterMixLevel   *float64 `json:"ltrt_center_mix_level_db,omitempty"`
+	LtRtSurroundMixLevel *float64 `json:"ltrt_surround_mix_level_db,omitempty"`
+	LoRoCenterMixLevel   *float64 `json:"loro_center_mix_level_db,omitempty"`
+	LoRoSurroundMixLevel *float64 `json:"loro_surround_mix_level_db,omitempty"`
+}
+
+// DialnormCheck compares dialnorm with the measured integrated loudness.
+// Broadcast deliverables expect the two to agree, or decoders apply the
+// wrong gain.
+type DialnormCheck struct {
+	MeasuredLoudness float64 `json:"measured_loudness_lufs"`
+	Difference       float64 `json:"difference_db"` // Measured loudness minus dialnorm
+	Tolerance        float64 `json:"tolerance_db"`
+	Matches          bool    `json:"matches"`
+}
+
+// dolbyFormats names the Dolby codecs by their ffprobe codec name
+var dolbyFormats = map[string]string{"ac3": "AC-3", "eac3": "E-AC-3", "truehd": "TrueHD"}
+
+// Bitstream mode names, indexed by bsmod
+var dolbyBitstreamModes = []string{
+	"complete_main", "music_and_effects", "visually_impaired", "hearing_impaired",
+	"dialogue", "commentary", "emergency", "voice_over",
+}
+
+// Audio coding modes as front/rear channel counts, indexed by acmod
+var dolbyAudioCodingModes = []string{"1+1", "1/0", "2/0", "3/0", "2/1", "3/1", "2/2", "3/2"}
+
+// Preferred stereo downmix modes, indexed by dmixmod
+var dolbyDownmixModes = []string{"not_indicated", "lt_rt", "lo_ro", "pro_logic_ii"}
+
+// Legacy AC-3 center and surround mix levels in dB, indexed by cmixlev and
+// surmixlev; reserved codes use the intermediate level as A/52 recommends
+var (
+	ac3CenterMixLevels   = []float64{-3, -4.5, -6, -4.5}
+	ac3SurroundMixLevels = []float64{-3, -6, math.Inf(-1), -6}
+)
+
+// Lt/Rt and Lo/Ro mix levels in dB, indexed by their 3 bit codes. Codes 0-2
+// are reserved for surround levels and decode as -1.5 dB.
+var (
+	dolbyCenterMixLevels   = []float64{3, 1.5, 0, -1.5, -3, -4.5, -6, math.Inf(-1)}
+	dolbySurroundMixLevels = []float64{-1.5, -1.5, -1.5, -1.5, -3, -4.5, -6, math.Inf(-1)}
+)
+
+// E-AC-3 audio blocks per frame, indexed by numblkscod
+var eac3BlocksPerFrame = []int{1, 2, 3, 6}
+
+// AnalyzeDolby reads the metadata of every Dolby audio stream. AC-3 and
+// E-AC-3 streams are copied out of the container for a moment so their
+// bitstream information can be parsed. The encoder's DRC profile name is not
+// carried in the bitstream; the RF-mode gain word it produces is reported
+// instead.
+func (da *DolbyAnalyzer) AnalyzeDolby(ctx context.Context, filePath string, streams []StreamInfo) (*DolbyAudioAnalysis, error) {
+	analysis := &DolbyAudioAnalysis{}
+
+	for _, stream := range streams {
+		if !strings.EqualFold(stream.CodecType, "audio") {
+			continue
+		}
+		codec := strings.ToLower(stream.CodecName)
+		format, ok := dolbyFormats[codec]
+		if !ok {
+			continue
+		}
+
+		metadata := DolbyStreamMetadata{
+			StreamIndex: stream.Index,
+			Format:      format,
+			// ffprobe names Atmos in the profile of both E-AC-3 JOC and TrueHD streams
+			Atmos:            strings.Contains(strings.ToLower(stream.Profile), "atmos"),
+			BedConfiguration: stream.ChannelLayout,
+		}
+		if metadata.BedConfiguration == "" && stream.Channels > 0 {
+			metadata.BedConfiguration = strconv.Itoa(stream.Channels) + " channels"
+		}
+
+		if codec != "truehd" {
+			frames, err := da.readFrames(ctx, filePath, stream.Index, codec)
+			if err == nil {
+				err = parseDolbyFrames(frames, &metadata)
+			}
+			if err != nil {
+				da.logger.Debug().Err(err).Int("stream", stream.Index).Msg("Failed to read Dolby bitstream metadata")
+				metadata.Error = err.Error()
+			}
+		}
+
+		analysis.Streams = append(analysis.Streams, metadata)
+	}
+
+	if len(analysis.Streams) == 0 {
+		return nil, nil
+	}
+	return analysis, nil
+}
+
+// readFrames copies the first frames of a stream into its raw elementary
+// stream format
+func (da *DolbyAnalyzer) readFrames(ctx context.Context, filePath string, index int, codec string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, da.ffmpegPath,
+		"-v", "error",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", index),
+		"-c:a", "copy",
+		"-frames:a", "4",
+		"-f", codec,
+		"-",
+	)
+
+	output, err := commandOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s frames: %w", codec, err)
+	}
+	return output, nil
+}
+
+// CheckDialnorm compares the dialnorm of the stream the loudness meter
+// measured with the integrated loudness. ffmpeg measures its default audio
+// stream, the one with the most channels, so only that stream is checked.
+func (a *DolbyAudioAnalysis) CheckDialnorm(streams []StreamInfo, loudness *LoudnessAnalysis) {
+	if a == nil || loudness == nil || loudness.IntegratedLoudness == 0 {
+		return
+	}
+
+	measured, channels := -1, 0
+	for _, stream := range streams {
+		if strings.EqualFold(stream.CodecType, "audio") && stream.Channels > channels {
+			measured, channels = stream.Index, stream.Channels
+		}
+	}
+
+	for i := range a.Streams {
+		metadata := &a.Streams[i]
+		if metadata.StreamIndex != measured || metadata.Dialnorm == nil {
+			continue
+		}
+		difference := loudness.IntegratedLoudness - float64(*metadata.Dialnorm)
+		metadata.DialnormCheck = &DialnormCheck{
+			MeasuredLoudness: loudness.IntegratedLoudness,
+			Difference:       math.Round(difference*10) / 10,
+			Tolerance:        dialnormTolerance,
+			Matches:          math.Abs(difference) <= dialnormTolerance,
+		}
+	}
+}
+
+// parseDolbyFrames parses the first independent sync frame of a raw AC-3 or
+// E-AC-3 stream into metadata
+func parseDolbyFrames(data []byte, metadata *DolbyStreamMetadata) error {
+	start := bytes.Index(data, []byte{dolbySyncWord >> 8, dolbySyncWord & 0xFF})
+	if start < 0 {
+		return fmt.Errorf("no sync frame found")
+	}
+	data = data[start:]
+
+	for len(data) >= 6 {
+		// bsid sits at the same position in both syntaxes
+		bsid := int(data[5] >> 3)
+		switch {
+		case bsid <= 10:
+			return parseAC3BSI(data, metadata)
+		case bsid <= 16:
+			// Dependent substreams only extend the channels of the
+			// independent one, skip them
+			if data[2]>>6 == 1 {
+				size := (int(data[2]&0x07)<<8 | int(data[3]) + 1) * 2
+				if size >= len(data) {
+					return fmt.Errorf("no independent E-AC-3 frame found")
+				}
+				data = data[size:]
+				continue
+			}
+			return parseEAC3BSI(data, metadata)
+		default:
+			return fmt.Errorf("unsupported bitstream id %d", bsid)
+		}
+	}
+	return fmt.Errorf("sync frame is truncated")
+}
+
+// parseAC3BSI reads the bit stream information of an AC-3 sync frame
+// (ATSC A/52 section 5.3.2, alternate syntax in annex D)
+func parseAC3BSI(frame []byte, metadata *DolbyStreamMetadata) error {
+	r := &bitReader{data: frame}
+	r.skip(16 + 16 + 2 + 6) // syncword, crc1, fscod, frmsizecod
+
+	bsid := int(r.read(5))
+	bsmod := r.read(3)
+	acmod := int(r.read(3))
+	metadata.BitstreamID = bsid
+	metadata.BitstreamMode = dolbyBitstreamModes[bsmod]
+	metadata.AudioCodingMode = dolbyAudioCodingModes[acmod]
+
+	downmix := &DolbyDownmix{}
+	if acmod&1 != 0 && acmod != 1 {
+		downmix.CenterMixLevel = mixLevel(ac3CenterMixLevels, r.read(2))
+	}
+	if acmod&4 != 0 {
+		downmix.SurroundMixLevel = mixLevel(ac3SurroundMixLevels, r.read(2))
+	}
+	if acmod == 2 {
+		r.skip(2) // dsurmod
+	}
+	metadata.LFE = r.read(1) == 1
+	metadata.Dialnorm = dialnorm(r.read(5))
+	if r.read(1) == 1 {
+		metadata.CompressionGainDB = compressionGain(r.read(8))
+	}
+	if r.read(1) == 1 {
+		r.skip(8) // langcod
+	}
+	if r.read(1) == 1 {
+		r.skip(5 + 2) // mixlevel, roomtyp
+	}
+	if acmod == 0 {
+		// The second mono channel of dual mono has its own dialnorm
+		r.skip(5)
+		if r.read(1) == 1 {
+			r.skip(8)
+		}
+		if r.read(1) == 1 {
+			r.skip(8)
+		}
+		if r.read(1) == 1 {
+			r.skip(5 + 2)
+		}
+	}
+	r.skip(1 + 1) // copyrightb, origbs
+
+	if bsid == 6 && r.read(1) == 1 {
+		downmix.PreferredMode = dolbyDownmixModes[r.read(2)]
+		downmix.LtRtCenterMixLevel = mixLevel(dolbyCenterMixLevels, r.read(3))
+		downmix.LtRtSurroundMixLevel = mixLevel(dolbySurroundMixLevels, r.read(3))
+		downmix.LoRoCenterMixLevel = mixLevel(dolbyCenterMixLevels, r.read(3))
+		downmix.LoRoSurroundMixLevel = mixLevel(dolbySurroundMixLevels, r.read(3))
+	}
+
+	if r.overrun {
+		return fmt.Errorf("AC-3 bit stream information is truncated")
+	}
+	if acmod > 2 {
+		metadata.Downmix = downmix
+	}
+	return nil
+}
+
+// parseEAC3BSI reads the bit stream information of an independent E-AC-3
+// sync frame (ATSC A/52 annex E section 2.3.1) up to the additional bit
+// stream information that flags Atmos
+func parseEAC3BSI(frame []byte, metadata *DolbyStreamMetadata) error {
+	r := &bitReader{data: frame}
+	r.skip(16) // syncword
+
+	strmtyp := r.read(2)
+	r.skip(3 + 11) // substreamid, frmsiz
+	fscod := r.read(2)
+	numblkscod := uint32(3)
+	if fscod == 3 {
+		r.skip(2) // fscod2
+	} else {
+		numblkscod = r.read(2)
+	}
+	acmod := int(r.read(3))
+	lfeon := r.read(1) == 1
+	metadata.BitstreamID = int(r.read(5))
+	metadata.AudioCodingMode = dolbyAudioCodingModes[acmod]
+	metadata.LFE = lfeon
+	metadata.Dialnorm = dialnorm(r.read(5))
+	if r.read(1) == 1 {
+		metadata.CompressionGainDB = compressionGain(r.read(8))
+	}
+	if acmod == 0 {
+		r.skip(5) // dialnorm2
+		if r.read(1) == 1 {
+			r.skip(8) // compr2
+		}
+	}
+
+	if r.read(1) == 1 { // mixmdate
+		downmix := &DolbyDownmix{}
+		if acmod > 2 {
+			downmix.PreferredMode = dolbyDownmixModes[r.read(2)]
+		}
+		if acmod&1 != 0 && acmod > 2 {
+			downmix.LtRtCenterMixLevel = mixLevel(dolbyCenterMixLevels, r.read(3))
+			downmix.LoRoCenterMixLevel = mixLevel(dolbyCenterMixLevels, r.read(3))
+		}
+		if acmod&4 != 0 {
+			downmix.LtRtSurroundMixLevel = mixLevel(dolbySurroundMixLevels, r.read(3))
+			downmix.LoRoSurroundMixLevel = mixLevel(dolbySurroundMixLevels, r.read(3))
+		}
+		if acmod > 2 {
+			metadata.Downmix = downmix
+		}
+		if lfeon && r.read(1) == 1 {
+			r.skip(5) // lfemixlevcod
+		}
+		if strmtyp == 0 {
+			if r.read(1) == 1 {
+				r.skip(6) // pgmscl
+			}
+			if acmod == 0 && r.read(1) == 1 {
+				r.skip(6) // pgmscl2
+			}
+			if r.read(1) == 1 {
+				r.skip(6) // extpgmscl
+			}
+			switch r.read(2) { // mixdef
+			case 1:
+				r.skip(5)
+			case 2:
+				r.skip(12)
+			case 3:
+				r.skip((int(r.read(5)) + 2) * 8)
+			}
+			if acmod < 2 {
+				if r.read(1) == 1 {
+					r.skip(14) // paninfo
+				}
+				if acmod == 0 && r.read(1) == 1 {
+					r.skip(14) // paninfo2
+				}
+			}
+			if r.read(1) == 1 { // frmmixcfginfoe
+				if numblkscod == 0 {
+					r.skip(5)
+				} else {
+					for block := 0; block < eac3BlocksPerFrame[numblkscod]; block++ {
+						if r.read(1) == 1 {
+							r.skip(5)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	if r.read(1) == 1 { // infomdate
+		metadata.BitstreamMode = dolbyBitstreamModes[r.read(3)]
+		r.skip(1 + 1) // copyrightb, origbs
+		if acmod == 2 {
+			r.skip(2 + 2) // dsurmod, dheadphonmod
+		}
+		if acmod >= 6 {
+			r.skip(2) // dsurexmod
+		}
+		if r.read(1) == 1 {
+			r.skip(5 + 2 + 1) // mixlevel, roomtyp, adconvtyp
+		}
+		if acmod == 0 && r.read(1) == 1 {
+			r.skip(5 + 2 + 1)
+		}
+		if fscod < 3 {
+			r.skip(1) // sourcefscod
+		}
+	}
+	if strmtyp == 0 && numblkscod != 3 {
+		r.skip(1) // convsync
+	}
+	if strmtyp == 2 {
+		blkid := uint32(1)
+		if numblkscod != 3 {
+			blkid = r.read(1)
+		}
+		if blkid == 1 {
+			r.skip(6) // frmsizecod
+		}
+	}
+
+	if r.read(1) == 1 { // addbsie
+		addbsil := int(r.read(6))
+		// The low bit of the first byte is flag_ec3_extension_type_a, set
+		// for Joint Object Coding (Atmos), followed by the complexity index
+		r.skip(7)
+		if r.read(1) == 1 && addbsil >= 1 {
+			metadata.Atmos = true
+			metadata.AtmosComplexity = int(r.read(8))
+		}
+	}
+
+	if r.overrun {
+		return fmt.Errorf("E-AC-3 bit stream information is truncated")
+	}
+	return nil
+}
+
+// dialnorm decodes a 5 bit dialnorm code; 0 is reserved and means -31 dB
+func dialnorm(code uint32) *int {
+	value := -int(code)
+	if code == 0 {
+		value = -31
+	}
+	return &value
+}
+
+// compressionGain decodes an 8 bit compr word: a signed 4 bit exponent X and
+// 4 bit mantissa Y giving a gain of 2^(X+1) * (16+Y)/32
+func compressionGain(code uint32) *float64 {
+	exponent := int(int8(code)) >> 4
+	mantissa := float64(code & 0x0F)
+	gain := 20 * math.Log10(math.Pow(2, float64(exponent+1))*(16+mantissa)/32)
+	gain = math.Round(gain*100) / 100
+	return &gain
+}
+
+// mixLevel looks up a mix level code, returning nil for a muted channel
+func mixLevel(levels []float64, code uint32) *float64 {
+	level := levels[code]
+	if math.IsInf(level, -1) {
+		return nil
+	}
+	return &level
+}
+
+// bitReader reads big-endian bit fields. Reads past the end return zero and
+// set overrun.
+type bitReader struct {
+	data    []byte
+	pos     int
+	overrun bool
+}
+
+func (r *bitReader) read(n int) uint32 {
+	var value uint32
+	for i := 0; i < n; i++ {
+		value <<= 1
+		if r.pos >= len(r.data)*8 {
+			r.overrun = true
+		} else if r.data[r.pos/8]&(0x80>>(r.pos%8)) != 0 {
+			value |= 1
+		}
+		r.pos++
+	}
+	return value
+}
+
+func (r *bitReader) skip(n int) {
+	if r.pos+n > len(r.data)*8 {
+		r.overrun = true
+	}
+	r.pos += n
+}
@@ -0,0 +1,140 @@
+package ffmpeg
+
+import (
+	"testing"
+)
+
+// bitWriter builds big-endian bit fields for hand-made sync frames
+type bitWriter struct {
+	data []byte
+	bits int
+}
+
+func (w *bitWriter) put(value uint32, n int) *bitWriter {
+	for i := n - 1; i >= 0; i-- {
+		if w.bits%8 == 0 {
+			w.data = append(w.data, 0)
+		}
+		if value&(1<<i) != 0 {
+			w.data[len(w.data)-1] |= 0x80 >> (w.bits % 8)
+		}
+		w.bits++
+	}
+	return w
+}
+
+func TestParseDolbyFrames_AC3(t *testing.T) {
+	w := &bitWriter{}
+	w.put(dolbySyncWord, 16).put(0, 16).put(0, 2).put(0x1C, 6) // crc1, fscod, frmsizecod
+	w.put(6, 5).put(0, 3).put(7, 3)                            // bsid (alternate syntax), bsmod, acmod 3/2
+	w.put(0, 2).put(1, 2)                                      // cmixlev -3 dB, surmixlev -6 dB
+	w.put(1, 1).put(24, 5).put(1, 1).put(0x10, 8)              // lfeon, dialnorm, compr
+	w.put(0, 1).put(0, 1).put(1, 1).put(1, 1)                  // langcode, audprodie, copyrightb, origbs
+	w.put(1, 1).put(2, 2).put(4, 3).put(4, 3).put(4, 3).put(7, 3)
+	w.put(0, 1).put(0, 32)
+
+	var metadata DolbyStreamMetadata
+	if err := parseDolbyFrames(append([]byte{0, 0}, w.data...), &metadata); err != nil {
+		t.Fatalf("parseDolbyFrames failed: %v", err)
+	}
+
+	if metadata.BitstreamID != 6 || metadata.BitstreamMode != "complete_main" || metadata.AudioCodingMode != "3/2" || !metadata.LFE {
+		t.Errorf("unexpected stream configuration %+v", metadata)
+	}
+	if metadata.Dialnorm == nil || *metadata.Dialnorm != -24 {
+		t.Errorf("expected dialnorm -24, got %v", metadata.Dialnorm)
+	}
+	if metadata.CompressionGainDB == nil || *metadata.CompressionGainDB != 6.02 {
+		t.Errorf("expected a 6.02 dB compr gain, got %v", metadata.CompressionGainDB)
+	}
+
+	downmix := metadata.Downmix
+	if downmix == nil || downmix.PreferredMode != "lo_ro" {
+		t.Fatalf("expected Lo/Ro downmix metadata, got %+v", downmix)
+	}
+	if *downmix.CenterMixLevel != -3 || *downmix.SurroundMixLevel != -6 || *downmix.LtRtCenterMixLevel != -3 || *downmix.LoRoCenterMixLevel != -3 {
+		t.Errorf("unexpected mix levels %+v", downmix)
+	}
+	if downmix.LoRoSurroundMixLevel != nil {
+		t.Errorf("expected muted Lo/Ro surrounds, got %v", *downmix.LoRoSurroundMixLevel)
+	}
+}
+
+func TestParseDolbyFrames_EAC3Atmos(t *testing.T) {
+	// A dependent substream frame of 8 bytes comes first and is skipped
+	dependent := &bitWriter{}
+	dependent.put(dolbySyncWord, 16).put(1, 2).put(0, 3).put(3, 11)
+	dependent.put(0, 2).put(3, 2).put(7, 3).put(1, 1).put(16, 5).put(0, 19)
+
+	w := &bitWriter{}
+	w.put(dolbySyncWord, 16).put(0, 2).put(0, 3).put(100, 11) // strmtyp, substreamid, frmsiz
+	w.put(0, 2).put(3, 2).put(7, 3).put(1, 1).put(16, 5)      // fscod, numblkscod, acmod, lfeon, bsid
+	w.put(27, 5).put(0, 1)                                    // dialnorm, compre
+	w.put(1, 1).put(1, 2).put(4, 3).put(4, 3).put(4, 3).put(4, 3)
+	w.put(0, 1).put(0, 1).put(0, 1).put(0, 2).put(0, 1) // lfemixlevcode, pgmscle, extpgmscle, mixdef, frmmixcfginfoe
+	w.put(1, 1).put(1, 3).put(0, 2).put(0, 2).put(0, 1).put(0, 1)
+	w.put(1, 1).put(1, 6).put(0x01, 8).put(16, 8) // addbsie, addbsil, flag_ec3_extension_type_a, complexity
+	w.put(0, 32)
+
+	metadata := DolbyStreamMetadata{Format: "E-AC-3"}
+	if err := parseDolbyFrames(append(dependent.data, w.data...), &metadata); err != nil {
+		t.Fatalf("parseDolbyFrames failed: %v", err)
+	}
+
+	if metadata.BitstreamID != 16 || metadata.AudioCodingMode != "3/2" || !metadata.LFE || metadata.BitstreamMode != "music_and_effects" {
+		t.Errorf("unexpected stream configuration %+v", metadata)
+	}
+	if metadata.Dialnorm == nil || *metadata.Dialnorm != -27 || metadata.CompressionGainDB != nil {
+		t.Errorf("expected dialnorm -27 without compr, got %v, %v", metadata.Dialnorm, metadata.CompressionGainDB)
+	}
+	if metadata.Downmix == nil || metadata.Downmix.PreferredMode != "lt_rt" || *metadata.Downmix.LoRoSurroundMixLevel != -3 {
+		t.Errorf("unexpected downmix metadata %+v", metadata.Downmix)
+	}
+	if !metadata.Atmos || metadata.AtmosComplexity != 16 {
+		t.Errorf("expected Atmos with 16 objects, got %v/%d", metadata.Atmos, metadata.AtmosComplexity)
+	}
+}
+
+func TestParseDolbyFrames_Invalid(t *testing.T) {
+	var metadata DolbyStreamMetadata
+	if err := parseDolbyFrames([]byte("no sync here"), &metadata); err == nil {
+		t.Error("expected an error without a sync word")
+	}
+	if err := parseDolbyFrames([]byte{0x0B, 0x77, 0, 0, 0x1C, 6 << 3}, &metadata); err == nil {
+		t.Error("expected an error for a truncated frame")
+	}
+}
+
+func TestCompressionGain(t *testing.T) {
+	for code, expected := range map[uint32]float64{0x00: 0, 0xF0: -6.02, 0x18: 9.54} {
+		if gain := compressionGain(code); *gain != expected {
+			t.Errorf("compr %#x: expected %v dB, got %v", code, expected, *gain)
+		}
+	}
+}
+
+func TestCheckDialnorm(t *testing.T) {
+	dialnorm := -24
+	analysis := &DolbyAudioAnalysis{Streams: []DolbyStreamMetadata{
+		{StreamIndex: 1, Dialnorm: &dialnorm},
+		{StreamIndex: 2, Dialnorm: &dialnorm},
+	}}
+	streams := []StreamInfo{
+		{Index: 0, CodecType: "video"},
+		{Index: 1, CodecType: "audio", Channels: 2},
+		{Index: 2, CodecType: "audio", Channels: 6},
+	}
+
+	analysis.CheckDialnorm(streams, &LoudnessAnalysis{IntegratedLoudness: -20.7})
+
+	if analysis.Streams[0].DialnormCheck != nil {
+		t.Error("expected only the measured stream to be checked")
+	}
+	check := analysis.Streams[1].DialnormCheck
+	if check == nil || check.Difference != 3.3 || check.Matches {
+		t.Errorf("expected a 3.3 dB mismatch, got %+v", check)
+	}
+
+	var missing *DolbyAudioAnalysis
+	missing.CheckDialnorm(streams, &LoudnessAnalysis{IntegratedLoudness: -23})
+}
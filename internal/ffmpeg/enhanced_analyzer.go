@@ -24,12 +24,19 @@ type EnhancedAnalyzer struct {
 	transportStreamAnalyzer   *TransportStreamAnalyzer
 	endiannessAnalyzer        *EndiannessAnalyzer
 	audioWrappingAnalyzer     *AudioWrappingAnalyzer
+	dolbyAnalyzer             *DolbyAnalyzer
+	bwfAnalyzer               *BWFAnalyzer
+	avSyncAnalyzer            *AVSyncAnalyzer
 	imfAnalyzer               *IMFAnalyzer
 	mxfAnalyzer               *MXFAnalyzer
 	deadPixelAnalyzer         *DeadPixelAnalyzer
 	pseAnalyzer               *PSEAnalyzer
 	streamDispositionAnalyzer *StreamDispositionAnalyzer
 	dataIntegrityAnalyzer     *DataIntegrityAnalyzer
+	timestampAnalyzer         *TimestampAnalyzer
+	bitrateAnalyzer           *BitrateAnalyzer
+	mezzanineAnalyzer         *MezzanineAnalyzer
+	ffmpegPath                string
 	logger                    zerolog.Logger
 }
 
@@ -38,9 +45,9 @@ func NewEnhancedAnalyzer(ffprobePath string, logger zerolog.Logger) *EnhancedAna
 	return &EnhancedAnalyzer{
 		hdrAnalyzer:               NewHDRAnalyzer(ffprobePath, logger),
 		bitDepthAnalyzer:          NewBitDepthAnalyzer(),
-		resolutionAnalyzer:        NewResolutionAnalyzer(),
-		frameRateAnalyzer:         NewFrameRateAnalyzer(),
-		codecAnalyzer:             NewCodecAnalyzer(),
+		resolutionAnalyzer:        NewResolutionAnalyzer(strings.Replace(ffprobePath, "ffprobe", "ffmpeg", 1), logger),
+		frameRateAnalyzer:         NewFrameRateAnalyzer(strings.Replace(ffprobePath, "ffprobe", "ffmpeg", 1), ffprobePath, logger),
+		codecAnalyzer:             NewCodecAnalyzer(strings.Replace(ffprobePath, "ffprobe", "ffmpeg", 1), logger),
 		containerAnalyzer:         NewContainerAnalyzer(),
 		llmAnalyzer:               nil, // Will be set via SetLLMAnalyzer if enabled
 		timecodeAnalyzer:          NewTimecodeAnalyzer(ffprobePath, logger),
@@ -48,12 +55,19 @@ func NewEnhancedAnalyzer(ffprobePath string, logger zerolog.Logger) *EnhancedAna
 		transportStreamAnalyzer:   NewTransportStreamAnalyzer(ffprobePath, logger),
 		endiannessAnalyzer:        NewEndiannessAnalyzer(logger),
 		audioWrappingAnalyzer:     NewAudioWrappingAnalyzer(ffprobePath, logger),
+		dolbyAnalyzer:             NewDolbyAnalyzer(strings.Replace(ffprobePath, "ffprobe", "ffmpeg", 1), logger),
+		bwfAnalyzer:               NewBWFAnalyzer(logger),
+		avSyncAnalyzer:            NewAVSyncAnalyzer(strings.Replace(ffprobePath, "ffprobe", "ffmpeg", 1), logger),
 		imfAnalyzer:               NewIMFAnalyzer(ffprobePath, logger),
 		mxfAnalyzer:               NewMXFAnalyzer(ffprobePath, logger),
 		deadPixelAnalyzer:         NewDeadPixelAnalyzer(ffprobePath, logger),
 		pseAnalyzer:               NewPSEAnalyzer(ffprobePath, logger),
 		streamDispositionAnalyzer: NewStreamDispositionAnalyzer(ffprobePath, logger),
 		dataIntegrityAnalyzer:     NewDataIntegrityAnalyzer(ffprobePath, logger),
+		timestampAnalyzer:         NewTimestampAnalyzer(ffprobePath, logger),
+		bitrateAnalyzer:           NewBitrateAnalyzer(ffprobePath, logger),
+		mezzanineAnalyzer:         NewMezzanineAnalyzer(strings.Replace(ffprobePath, "ffprobe", "ffmpeg", 1), logger),
+		ffmpegPath:                strings.Replace(ffprobePath, "ffprobe", "ffmpeg", 1),
 		logger:                    logger,
 	}
 }
@@ -64,9 +78,9 @@ func NewEnhancedAnalyzerWithContentAnalysis(ffmpegPath string, ffprobePath strin
 		contentAnalyzer:           NewContentAnalyzer(ffmpegPath, logger),
 		hdrAnalyzer:               NewHDRAnalyzer(ffprobePath, logger),
 		bitDepthAnalyzer:          NewBitDepthAnalyzer(),
-		resolutionAnalyzer:        NewResolutionAnalyzer(),
-		frameRateAnalyzer:         NewFrameRateAnalyzer(),
-		codecAnalyzer:             NewCodecAnalyzer(),
+		resolutionAnalyzer:        NewResolutionAnalyzer(ffmpegPath, logger),
+		frameRateAnalyzer:         NewFrameRateAnalyzer(ffmpegPath, ffprobePath, logger),
+		codecAnalyzer:             NewCodecAnalyzer(ffmpegPath, logger),
 		containerAnalyzer:         NewContainerAnalyzer(),
 		llmAnalyzer:               nil, // Will be set via SetLLMAnalyzer if enabled
 		timecodeAnalyzer:          NewTimecodeAnalyzer(ffprobePath, logger),
@@ -74,12 +88,19 @@ func NewEnhancedAnalyzerWithContentAnalysis(ffmpegPath string, ffprobePath strin
 		transportStreamAnalyzer:   NewTransportStreamAnalyzer(ffprobePath, logger),
 		endiannessAnalyzer:        NewEndiannessAnalyzer(logger),
 		audioWrappingAnalyzer:     NewAudioWrappingAnalyzer(ffprobePath, logger),
+		dolbyAnalyzer:             NewDolbyAnalyzer(ffmpegPath, logger),
+		bwfAnalyzer:               NewBWFAnalyzer(logger),
+		avSyncAnalyzer:            NewAVSyncAnalyzer(ffmpegPath, logger),
 		imfAnalyzer:               NewIMFAnalyzer(ffprobePath, logger),
 		mxfAnalyzer:               NewMXFAnalyzer(ffprobePath, logger),
 		deadPixelAnalyzer:         NewDeadPixelAnalyzer(ffprobePath, logger),
 		pseAnalyzer:               NewPSEAnalyzer(ffprobePath, logger),
 		streamDispositionAnalyzer: NewStreamDispositionAnalyzer(ffprobePath, logger),
 		dataIntegrityAnalyzer:     NewDataIntegrityAnalyzer(ffprobePath, logger),
+		timestampAnalyzer:         NewTimestampAnalyzer(ffprobePath, logger),
+		bitrateAnalyzer:           NewBitrateAnalyzer(ffprobePath, logger),
+		mezzanineAnalyzer:         NewMezzanineAnalyzer(ffmpegPath, logger),
+		ffmpegPath:                ffmpegPath,
 		logger:                    logger,
 	}
 }
@@ -125,6 +146,9 @@ func (ea *EnhancedAnalyzer) AnalyzeResult(result *FFprobeResult) error {
 		enhanced.ResolutionAnalysis = ea.resolutionAnalyzer.AnalyzeResolution(result.Streams)
 	}
 
+	// Check color tags against each other and the resolution
+	enhanced.ColorConsistencyAnalysis = analyzeColorConsistency(result.Streams)
+
 	// Analyze frame rate
 	if ea.frameRateAnalyzer != nil && len(result.Streams) > 0 {
 		enhanced.FrameRateAnalysis = ea.frameRateAnalyzer.AnalyzeFrameRate(result.Streams)
@@ -146,137 +170,19 @@ func (ea *EnhancedAnalyzer) AnalyzeResult(result *FFprobeResult) error {
 
 // AnalyzeResultWithAdvancedQC performs comprehensive QC analysis including all advanced features
 func (ea *EnhancedAnalyzer) AnalyzeResultWithAdvancedQC(ctx context.Context, result *FFprobeResult, filePath string) error {
-	// First run standard enhanced analysis
-	if err := ea.AnalyzeResult(result); err != nil {
-		return err
-	}
-
-	// Initialize enhanced analysis if not already done
-	if result.EnhancedAnalysis == nil {
-		result.EnhancedAnalysis = &EnhancedAnalysis{}
-	}
-
-	// Run timecode analysis
-	if ea.timecodeAnalyzer != nil && len(result.Streams) > 0 {
-		timecodeAnalysis, err := ea.timecodeAnalyzer.AnalyzeTimecode(ctx, filePath, result.Streams)
-		if err != nil {
-			// Log error but don't fail entire analysis - some files may not have timecode
-			ea.logger.Warn().Err(err).Msg("timecode analysis failed")
-		} else {
-			result.EnhancedAnalysis.TimecodeAnalysis = timecodeAnalysis
-		}
-	}
-
-	// Run AFD analysis
-	if ea.afdAnalyzer != nil && len(result.Streams) > 0 {
-		afdAnalysis, err := ea.afdAnalyzer.AnalyzeAFD(ctx, filePath, result.Streams)
-		if err != nil {
-			// Log error but don't fail entire analysis - some files may not have AFD
-			ea.logger.Warn().Err(err).Msg("AFD analysis failed")
-		} else {
-			result.EnhancedAnalysis.AFDAnalysis = afdAnalysis
-		}
-	}
-
-	// Run transport stream analysis
-	if ea.transportStreamAnalyzer != nil {
-		transportAnalysis, err := ea.transportStreamAnalyzer.AnalyzeTransportStream(ctx, filePath, result.Streams, result.Format)
-		if err != nil {
-			// Log error but don't fail entire analysis - only applies to transport streams
-			ea.logger.Warn().Err(err).Msg("transport stream analysis failed")
-		} else {
-			result.EnhancedAnalysis.TransportStreamAnalysis = transportAnalysis
-		}
-	}
-
-	// Run endianness analysis
-	if ea.endiannessAnalyzer != nil {
-		endiannessAnalysis, err := ea.endiannessAnalyzer.AnalyzeEndianness(ctx, filePath, result.Streams, result.Format)
-		if err != nil {
-			// Log error but don't fail entire analysis - endianness may not be detectable for all formats
-			ea.logger.Warn().Err(err).Msg("endianness analysis failed")
-		} else {
-			result.EnhancedAnalysis.EndiannessAnalysis = endiannessAnalysis
-		}
-	}
-
-	// Run audio wrapping analysis
-	if ea.audioWrappingAnalyzer != nil && len(result.Streams) > 0 {
-		audioWrappingAnalysis, err := ea.audioWrappingAnalyzer.AnalyzeAudioWrapping(ctx, filePath, result.Streams, result.Format)
-		if err != nil {
-			// Log error but don't fail entire analysis - not all formats have professional audio wrapping
-			ea.logger.Warn().Err(err).Msg("audio wrapping analysis failed")
-		} else {
-			result.EnhancedAnalysis.AudioWrappingAnalysis = audioWrappingAnalysis
-		}
-	}
-
-	// Run IMF analysis if this appears to be an IMF package
-	if ea.imfAnalyzer != nil {
-		imfAnalysis, err := ea.imfAnalyzer.AnalyzeIMF(ctx, filePath)
-		if err != nil {
-			// Log error but don't fail entire analysis - only applies to IMF packages
-			ea.logger.Warn().Err(err).Msg("IMF analysis failed")
-		} else {
-			result.EnhancedAnalysis.IMFAnalysis = imfAnalysis
-		}
-	}
-
-	// Run MXF analysis if this is an MXF file
-	if ea.mxfAnalyzer != nil {
-		mxfAnalysis, err := ea.mxfAnalyzer.AnalyzeMXF(ctx, filePath)
-		if err != nil {
-			// Log error but don't fail entire analysis - only applies to MXF files
-			ea.logger.Warn().Err(err).Msg("MXF analysis failed")
-		} else {
-			result.EnhancedAnalysis.MXFAnalysis = mxfAnalysis
-		}
-	}
-
-	// Run dead pixel analysis
-	if ea.deadPixelAnalyzer != nil {
-		deadPixelAnalysis, err := ea.deadPixelAnalyzer.AnalyzeDeadPixels(ctx, filePath)
-		if err != nil {
-			// Log error but don't fail entire analysis - analysis may fail on some video types
-			ea.logger.Warn().Err(err).Msg("dead pixel analysis failed")
-		} else {
-			result.EnhancedAnalysis.DeadPixelAnalysis = deadPixelAnalysis
-		}
-	}
-
-	// Run photosensitive epilepsy risk analysis
-	if ea.pseAnalyzer != nil {
-		pseAnalysis, err := ea.pseAnalyzer.AnalyzePSERisk(ctx, filePath)
-		if err != nil {
-			// Log error but don't fail entire analysis - analysis may fail on some video types
-			ea.logger.Warn().Err(err).Msg("PSE analysis failed")
-		} else {
-			result.EnhancedAnalysis.PSEAnalysis = pseAnalysis
-		}
-	}
-
-	// Run stream disposition analysis
-	if ea.streamDispositionAnalyzer != nil && len(result.Streams) > 0 {
-		dispositionAnalysis, err := ea.streamDispositionAnalyzer.AnalyzeStreamDisposition(ctx, filePath, result.Streams)
-		if err != nil {
-			// Log error but don't fail entire analysis
-			ea.logger.Warn().Err(err).Msg("stream disposition analysis failed")
-		} else {
-			result.EnhancedAnalysis.StreamDispositionAnalysis = dispositionAnalysis
-		}
+	if result == nil {
+		return fmt.Errorf("result cannot be nil")
 	}
 
-	// Run data integrity analysis
-	if ea.dataIntegrityAnalyzer != nil {
-		integrityAnalysis, err := ea.dataIntegrityAnalyzer.AnalyzeDataIntegrity(ctx, filePath)
-		if err != nil {
-			// Log error but don't fail entire analysis
-			fmt.Printf("Warning: data integrity analysis failed: %v\n", err)
-		} else {
-			result.EnhancedAnalysis.DataIntegrityAnalysis = integrityAnalysis
-		}
+	// Keep the content analysis of a preceding AnalyzeResultWithContent or
+	// AnalyzeResultWithHDR
+	enhanced := &EnhancedAnalysis{}
+	if result.EnhancedAnalysis != nil {
+		enhanced.ContentAnalysis = result.EnhancedAnalysis.ContentAnalysis
 	}
+	result.EnhancedAnalysis = enhanced
 
+	ea.runQCCategories(ctx, result, filePath, newQCCategorySet(advancedQCCategories))
 	return nil
 }
 
@@ -285,6 +191,14 @@ func (ea *EnhancedAnalyzer) SetLLMAnalyzer(llmAnalyzer *LLMEnhancedAnalyzer) {
 	ea.llmAnalyzer = llmAnalyzer
 }
 
+// SetDeadPixelThresholds configures dead, stuck and hot pixel detection
+func (ea *EnhancedAnalyzer) SetDeadPixelThresholds(thresholds DeadPixelThresholds) error {
+	if ea.deadPixelAnalyzer == nil {
+		return nil
+	}
+	return ea.deadPixelAnalyzer.SetThresholds(thresholds)
+}
+
 // AnalyzeResultWithLLM performs enhanced analysis including LLM-powered insights
 func (ea *EnhancedAnalyzer) AnalyzeResultWithLLM(ctx context.Context, result *FFprobeResult, filePath string) error {
 	// First run standard enhanced analysis
@@ -361,6 +275,315 @@ func (ea *EnhancedAnalyzer) AnalyzeResultWithContent(ctx context.Context, result
 	return nil
 }
 
+// AnalyzeResultWithCategories runs only the selected QC categories. Content
+// and loudness analysis run even when content analysis is not enabled on the
+// FFprobe instance, since the caller asked for them explicitly.
+func (ea *EnhancedAnalyzer) AnalyzeResultWithCategories(ctx context.Context, result *FFprobeResult, filePath string, categories []QCCategory) error {
+	if result == nil {
+		return fmt.Errorf("result cannot be nil")
+	}
+
+	result.EnhancedAnalysis = &EnhancedAnalysis{QCCategories: categories}
+	ea.runQCCategories(ctx, result, filePath, newQCCategorySet(categories))
+	return nil
+}
+
+// runQCCategories runs the selected categories into result.EnhancedAnalysis.
+// Content analysis already on it is kept, and HDR is not measured again.
+func (ea *EnhancedAnalyzer) runQCCategories(ctx context.Context, result *FFprobeResult, filePath string, selected qcCategorySet) {
+	enhanced := result.EnhancedAnalysis
+
+	// Stream-metadata categories are cheap and need no extra ffprobe runs
+	if selected.has(QCCategoryEnhanced) {
+		if len(result.Streams) > 0 {
+			enhanced.StreamCounts = ea.analyzeStreamCounts(result.Streams)
+		}
+		enhanced.VideoAnalysis = ea.analyzeVideoStreams(result.Streams)
+		enhanced.AudioAnalysis = ea.analyzeAudioStreams(result.Streams)
+		if len(result.Frames) > 0 {
+			enhanced.GOPAnalysis = ea.analyzeGOPStructure(result.Frames)
+			enhanced.FrameStatistics = ea.analyzeFrameStatistics(result.Frames)
+		}
+	}
+	if len(result.Streams) > 0 {
+		if selected.has(QCCategoryBitDepth) && ea.bitDepthAnalyzer != nil {
+			enhanced.BitDepthAnalysis = ea.bitDepthAnalyzer.AnalyzeBitDepth(result.Streams)
+		}
+		if selected.has(QCCategoryResolution) && ea.resolutionAnalyzer != nil {
+			enhanced.ResolutionAnalysis = ea.resolutionAnalyzer.AnalyzeResolution(result.Streams)
+			ea.resolutionAnalyzer.AnalyzeBitstreamAspectRatio(ctx, filePath, enhanced.ResolutionAnalysis, result.Streams)
+		}
+		if selected.has(QCCategoryResolution) || selected.has(QCCategoryHDR) {
+			enhanced.ColorConsistencyAnalysis = analyzeColorConsistency(result.Streams)
+		}
+		if selected.has(QCCategoryFrameRate) && ea.frameRateAnalyzer != nil {
+			enhanced.FrameRateAnalysis = ea.frameRateAnalyzer.AnalyzeFrameRate(result.Streams)
+			if err := ea.frameRateAnalyzer.AnalyzeFrameTiming(ctx, filePath, enhanced.FrameRateAnalysis, result.Streams); err != nil {
+				ea.logger.Warn().Err(err).Msg("frame timing analysis failed")
+			}
+			ea.frameRateAnalyzer.AnalyzeCadence(ctx, filePath, enhanced.FrameRateAnalysis, result.Streams)
+		}
+		if selected.has(QCCategoryCodec) && ea.codecAnalyzer != nil {
+			enhanced.CodecAnalysis = ea.codecAnalyzer.AnalyzeCodecs(result.Streams)
+			ea.codecAnalyzer.AnalyzeBitstreams(ctx, filePath, enhanced.CodecAnalysis, result.Streams)
+		}
+	}
+	if selected.has(QCCategoryContainer) && ea.containerAnalyzer != nil && result.Format != nil {
+		enhanced.ContainerAnalysis = ea.containerAnalyzer.AnalyzeContainer(result.Format)
+	}
+
+	if selected.has(QCCategoryTimecode) && ea.timecodeAnalyzer != nil && len(result.Streams) > 0 {
+		if analysis, err := ea.timecodeAnalyzer.AnalyzeTimecode(ctx, filePath, result.Streams); err != nil {
+			ea.logger.Warn().Err(err).Msg("timecode analysis failed")
+		} else {
+			enhanced.TimecodeAnalysis = analysis
+		}
+	}
+
+	if selected.has(QCCategoryAFD) && ea.afdAnalyzer != nil && len(result.Streams) > 0 {
+		if analysis, err := ea.afdAnalyzer.AnalyzeAFD(ctx, filePath, result.Streams); err != nil {
+			ea.logger.Warn().Err(err).Msg("AFD analysis failed")
+		} else {
+			enhanced.AFDAnalysis = analysis
+		}
+	}
+
+	if selected.has(QCCategoryTransportStream) && ea.transportStreamAnalyzer != nil {
+		if analysis, err := ea.transportStreamAnalyzer.AnalyzeTransportStream(ctx, filePath, result.Streams, result.Format); err != nil {
+			ea.logger.Warn().Err(err).Msg("transport stream analysis failed")
+		} else {
+			enhanced.TransportStreamAnalysis = analysis
+		}
+	}
+
+	if selected.has(QCCategoryEndianness) && ea.endiannessAnalyzer != nil {
+		if analysis, err := ea.endiannessAnalyzer.AnalyzeEndianness(ctx, filePath, result.Streams, result.Format); err != nil {
+			ea.logger.Warn().Err(err).Msg("endianness analysis failed")
+		} else {
+			enhanced.EndiannessAnalysis = analysis
+		}
+	}
+
+	if selected.has(QCCategoryAudioWrapping) && ea.audioWrappingAnalyzer != nil && len(result.Streams) > 0 {
+		if analysis, err := ea.audioWrappingAnalyzer.AnalyzeAudioWrapping(ctx, filePath, result.Streams, result.Format); err != nil {
+			ea.logger.Warn().Err(err).Msg("audio wrapping analysis failed")
+		} else {
+			enhanced.AudioWrappingAnalysis = analysis
+		}
+	}
+
+	if (selected.has(QCCategoryAudioWrapping) || selected.has(QCCategoryDolby)) && ea.dolbyAnalyzer != nil && len(result.Streams) > 0 {
+		if analysis, err := ea.dolbyAnalyzer.AnalyzeDolby(ctx, filePath, result.Streams); err != nil {
+			ea.logger.Warn().Err(err).Msg("Dolby audio analysis failed")
+		} else {
+			enhanced.DolbyAudioAnalysis = analysis
+		}
+	}
+
+	if (selected.has(QCCategoryAudioWrapping) || selected.has(QCCategoryBWF)) && ea.bwfAnalyzer != nil {
+		if analysis, err := ea.bwfAnalyzer.AnalyzeBWF(filePath); err != nil {
+			ea.logger.Warn().Err(err).Msg("Broadcast Wave analysis failed")
+		} else {
+			enhanced.BWFAnalysis = analysis
+		}
+	}
+
+	if selected.has(QCCategoryAVSync) && ea.avSyncAnalyzer != nil && len(result.Streams) > 0 {
+		if analysis, err := ea.avSyncAnalyzer.AnalyzeAVSync(ctx, filePath, result.Streams); err != nil {
+			ea.logger.Warn().Err(err).Msg("A/V sync analysis failed")
+		} else {
+			enhanced.AVSyncAnalysis = analysis
+		}
+	}
+
+	if selected.has(QCCategoryIMF) && ea.imfAnalyzer != nil {
+		if analysis, err := ea.imfAnalyzer.AnalyzeIMF(ctx, filePath); err != nil {
+			ea.logger.Warn().Err(err).Msg("IMF analysis failed")
+		} else {
+			enhanced.IMFAnalysis = analysis
+		}
+	}
+
+	if selected.has(QCCategoryMXF) && ea.mxfAnalyzer != nil {
+		if analysis, err := ea.mxfAnalyzer.AnalyzeMXF(ctx, filePath); err != nil {
+			ea.logger.Warn().Err(err).Msg("MXF analysis failed")
+		} else {
+			enhanced.MXFAnalysis = analysis
+		}
+	}
+
+	if selected.has(QCCategoryDeadPixel) && ea.deadPixelAnalyzer != nil {
+		if analysis, err := ea.deadPixelAnalyzer.AnalyzeDeadPixels(ctx, filePath); err != nil {
+			ea.logger.Warn().Err(err).Msg("dead pixel analysis failed")
+		} else {
+			enhanced.DeadPixelAnalysis = analysis
+		}
+	}
+
+	if selected.has(QCCategoryPSE) && ea.pseAnalyzer != nil {
+		if analysis, err := ea.pseAnalyzer.AnalyzePSERisk(ctx, filePath); err != nil {
+			ea.logger.Warn().Err(err).Msg("PSE analysis failed")
+		} else {
+			enhanced.PSEAnalysis = analysis
+		}
+	}
+
+	if selected.has(QCCategoryDisposition) && ea.streamDispositionAnalyzer != nil && len(result.Streams) > 0 {
+		if analysis, err := ea.streamDispositionAnalyzer.AnalyzeStreamDisposition(ctx, filePath, result.Streams); err != nil {
+			ea.logger.Warn().Err(err).Msg("stream disposition analysis failed")
+		} else {
+			enhanced.StreamDispositionAnalysis = analysis
+		}
+	}
+
+	if selected.has(QCCategoryIntegrity) && ea.dataIntegrityAnalyzer != nil {
+		if analysis, err := ea.dataIntegrityAnalyzer.AnalyzeDataIntegrity(ctx, filePath); err != nil {
+			ea.logger.Warn().Err(err).Msg("data integrity analysis failed")
+		} else {
+			enhanced.DataIntegrityAnalysis = analysis
+		}
+	}
+
+	if (selected.has(QCCategoryIntegrity) || selected.has(QCCategoryTimestamps)) && ea.timestampAnalyzer != nil {
+		if analysis, err := ea.timestampAnalyzer.AnalyzeTimestamps(ctx, filePath, result.Streams); err != nil {
+			ea.logger.Warn().Err(err).Msg("timestamp analysis failed")
+		} else {
+			enhanced.TimestampAnalysis = analysis
+		}
+	}
+
+	if (selected.has(QCCategoryCodec) || selected.has(QCCategoryBitrate)) && ea.bitrateAnalyzer != nil {
+		if analysis, err := ea.bitrateAnalyzer.AnalyzeBitrate(ctx, filePath, result.Streams); err != nil {
+			ea.logger.Warn().Err(err).Msg("bitrate analysis failed")
+		} else {
+			enhanced.BitrateAnalysis = analysis
+		}
+	}
+
+	if (selected.has(QCCategoryCodec) || selected.has(QCCategoryMezzanine)) && ea.mezzanineAnalyzer != nil && len(result.Streams) > 0 {
+		if analysis, err := ea.mezzanineAnalyzer.AnalyzeMezzanine(ctx, filePath, result.Streams); err != nil {
+			ea.logger.Warn().Err(err).Msg("mezzanine codec analysis failed")
+		} else {
+			enhanced.MezzanineAnalysis = analysis
+		}
+	}
+
+	// Content-level categories share the ContentAnalysis container
+	contentAnalyzer := ea.contentAnalyzer
+	if contentAnalyzer == nil && (selected.has(QCCategoryContent) || selected.has(QCCategoryLoudness) || selected.has(QCCategoryVideoLevels)) {
+		contentAnalyzer = NewContentAnalyzer(ea.ffmpegPath, ea.logger)
+	}
+
+	if selected.has(QCCategoryContent) {
+		if analysis, err := contentAnalyzer.AnalyzeContent(ctx, filePath); err != nil {
+			ea.logger.Warn().Err(err).Msg("content analysis failed")
+		} else {
+			enhanced.ContentAnalysis = analysis
+		}
+	} else if selected.has(QCCategoryLoudness) || selected.has(QCCategoryVideoLevels) {
+		content := &ContentAnalysis{}
+		sampleCtx, plan := contentAnalyzer.startSampling(ctx, filePath)
+		if selected.has(QCCategoryLoudness) {
+			if loudness, err := contentAnalyzer.analyzeLoudness(sampleCtx, filePath); err != nil {
+				ea.logger.Warn().Err(err).Msg("loudness analysis failed")
+			} else {
+				content.LoudnessMeter = loudness
+			}
+		}
+		if selected.has(QCCategoryVideoLevels) {
+			if baseband, err := contentAnalyzer.analyzeBaseband(sampleCtx, filePath); err != nil {
+				ea.logger.Warn().Err(err).Msg("video level analysis failed")
+			} else {
+				content.BasebandInfo = baseband
+			}
+		}
+		if content.LoudnessMeter != nil || content.BasebandInfo != nil {
+			if plan != nil {
+				plan.apply(content)
+			}
+			enhanced.ContentAnalysis = content
+		}
+		if plan != nil {
+			plan.close()
+		}
+	}
+
+	// Full content analysis already includes HDR
+	if selected.has(QCCategoryHDR) && ea.hdrAnalyzer != nil && filePath != "" &&
+		(enhanced.ContentAnalysis == nil || enhanced.ContentAnalysis.HDRAnalysis == nil) {
+		if analysis, err := ea.hdrAnalyzer.AnalyzeHDR(ctx, filePath); err != nil {
+			ea.logger.Warn().Err(err).Msg("HDR analysis failed")
+		} else {
+			if enhanced.ContentAnalysis == nil {
+				enhanced.ContentAnalysis = &ContentAnalysis{}
+			}
+			enhanced.ContentAnalysis.HDRAnalysis = analysis
+		}
+	}
+
+	// Dialnorm and the bext loudness are checked against loudness when both
+	// categories ran
+	if enhanced.ContentAnalysis != nil {
+		enhanced.DolbyAudioAnalysis.CheckDialnorm(result.Streams, enhanced.ContentAnalysis.LoudnessMeter)
+		enhanced.BWFAnalysis.CheckLoudness(enhanced.ContentAnalysis.LoudnessMeter)
+	}
+}
+
+// AnalyzeDeliveryProfile runs the measurements a delivery profile needs that
+// the preceding analysis did not produce, then records the profile's
+// compliance on the result. An empty filePath skips measurement, so only
+// stream metadata is checked.
+func (ea *EnhancedAnalyzer) AnalyzeDeliveryProfile(ctx context.Context, result *FFprobeResult, filePath string, profile *DeliveryProfile) {
+	if result.EnhancedAnalysis == nil {
+		result.EnhancedAnalysis = &EnhancedAnalysis{}
+	}
+	enhanced := result.EnhancedAnalysis
+
+	var missing []QCCategory
+	for _, category := range profile.QCCategories() {
+		switch category {
+		case QCCategoryLoudness:
+			if enhanced.ContentAnalysis == nil || enhanced.ContentAnalysis.LoudnessMeter == nil {
+				missing = append(missing, category)
+			}
+		case QCCategoryVideoLevels:
+			if enhanced.ContentAnalysis == nil || enhanced.ContentAnalysis.BasebandInfo == nil {
+				missing = append(missing, category)
+			}
+		case QCCategoryTimecode:
+			if enhanced.TimecodeAnalysis == nil {
+				missing = append(missing, category)
+			}
+		}
+	}
+
+	if len(missing) > 0 && filePath != "" {
+		// Run into a scratch result so the existing analysis is kept
+		scratch := &FFprobeResult{Format: result.Format, Streams: result.Streams}
+		if err := ea.AnalyzeResultWithCategories(ctx, scratch, filePath, missing); err != nil {
+			ea.logger.Warn().Err(err).Str("profile", profile.ID).Msg("delivery profile measurements failed")
+		} else {
+			measured := scratch.EnhancedAnalysis
+			if measured.TimecodeAnalysis != nil {
+				enhanced.TimecodeAnalysis = measured.TimecodeAnalysis
+			}
+			if content := measured.ContentAnalysis; content != nil {
+				if enhanced.ContentAnalysis == nil {
+					enhanced.ContentAnalysis = &ContentAnalysis{}
+				}
+				if content.LoudnessMeter != nil {
+					enhanced.ContentAnalysis.LoudnessMeter = content.LoudnessMeter
+				}
+				if content.BasebandInfo != nil {
+					enhanced.ContentAnalysis.BasebandInfo = content.BasebandInfo
+				}
+			}
+		}
+	}
+
+	enhanced.DeliveryCompliance = EvaluateDeliveryProfile(result, profile)
+}
+
 // analyzeStreamCounts counts different types of streams
 func (ea *EnhancedAnalyzer) analyzeStreamCounts(streams []StreamInfo) *StreamCounts {
 	counts := &StreamCounts{}
@@ -8,11 +8,13 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // executeFFprobeCommand executes an ffprobe command and returns the output.
@@ -33,7 +35,7 @@ func executeFFprobeCommand(ctx context.Context, cmd []string) (string, error) {
 	}
 
 	command := exec.CommandContext(ctx, cmd[0], cmd[1:]...)
-	output, err := command.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, command)
 	if err != nil {
 		return "", fmt.Errorf("command failed: %w, output: %s", err, string(output))
 	}
@@ -61,6 +63,9 @@ type FFprobe struct {
 	maxOutputSize         int64
 	enhancedAnalyzer      *EnhancedAnalyzer
 	enableContentAnalysis bool
+	deadPixelThresholds   *DeadPixelThresholds // Reapplied when the enhanced analyzer is replaced
+	plugins               []PluginAnalyzer
+	mediainfoPath         string // MediaInfo cross-check, disabled when empty
 }
 
 // NewFFprobe creates a new FFprobe instance with default configuration.
@@ -201,12 +206,31 @@ func (f *FFprobe) EnableContentAnalysis() {
 	// Replace with content-enabled analyzer
 	ffmpegPath := strings.Replace(f.binaryPath, "ffprobe", "ffmpeg", 1)
 	f.enhancedAnalyzer = NewEnhancedAnalyzerWithContentAnalysis(ffmpegPath, f.binaryPath, f.logger)
+	f.applyDeadPixelThresholds()
 }
 
 // DisableContentAnalysis disables content-based analysis for performance
 func (f *FFprobe) DisableContentAnalysis() {
 	f.enableContentAnalysis = false
 	f.enhancedAnalyzer = NewEnhancedAnalyzer(f.binaryPath, f.logger)
+	f.applyDeadPixelThresholds()
+}
+
+// SetDeadPixelThresholds configures dead, stuck and hot pixel detection
+func (f *FFprobe) SetDeadPixelThresholds(thresholds DeadPixelThresholds) error {
+	if err := thresholds.Validate(); err != nil {
+		return fmt.Errorf("invalid dead pixel thresholds: %w", err)
+	}
+	f.deadPixelThresholds = &thresholds
+	f.applyDeadPixelThresholds()
+	return nil
+}
+
+func (f *FFprobe) applyDeadPixelThresholds() {
+	if f.deadPixelThresholds != nil && f.enhancedAnalyzer != nil {
+		// Already validated by SetDeadPixelThresholds
+		_ = f.enhancedAnalyzer.SetDeadPixelThresholds(*f.deadPixelThresholds)
+	}
 }
 
 // SetLLMAnalyzer sets the LLM analyzer for AI-powered quality analysis
@@ -216,8 +240,131 @@ func (f *FFprobe) SetLLMAnalyzer(llmAnalyzer *LLMEnhancedAnalyzer) {
 	}
 }
 
+// SetPluginAnalyzers registers custom analyzers that run, in order, after
+// the built-in analyzers of every full analysis. Analyses limited to
+// selected QC categories and metadata-only probes skip them.
+func (f *FFprobe) SetPluginAnalyzers(plugins []PluginAnalyzer) error {
+	seen := make(map[string]bool, len(plugins))
+	for _, plugin := range plugins {
+		name := plugin.Name()
+		if err := ValidatePluginName(name); err != nil {
+			return err
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate plugin %q", name)
+		}
+		seen[name] = true
+	}
+	f.plugins = plugins
+	return nil
+}
+
+// SetMediaInfoPath enables the MediaInfo cross-check of local files with
+// the mediainfo binary at path; an empty path disables it. Full analyses
+// and analyses selecting the integrity category run it.
+func (f *FFprobe) SetMediaInfoPath(path string) {
+	f.mediainfoPath = path
+}
+
 // Probe executes ffprobe with the given options
 func (f *FFprobe) Probe(ctx context.Context, options *FFprobeOptions) (*FFprobeResult, error) {
+	ctx, span := tracer.Start(ctx, "FFprobe.Probe")
+	defer span.End()
+
+	if options != nil {
+		inputKind := "file"
+		if strings.Contains(options.Input, "://") {
+			inputKind = "url"
+		}
+		span.SetAttributes(
+			attribute.String("ffprobe.input.kind", inputKind),
+			attribute.Bool("ffprobe.metadata_only", options.MetadataOnly),
+			attribute.Int("ffprobe.qc_categories", len(options.QCCategories)),
+		)
+		ctx = withContentSampling(ctx, options.ContentSampling)
+		ctx = withLoudnessTargets(ctx, options.LoudnessTargets)
+		ctx = withPhaseFunc(ctx, options.OnPhase)
+	}
+
+	// A segment is read by ffprobe as an interval and by the analyzers
+	// under ctx with -ss and -t
+	var segment *AnalysisSegment
+	if options != nil && options.Segment != nil {
+		var err error
+		if segment, err = f.resolveSegment(ctx, options); err != nil {
+			recordSpanError(span, err)
+			return nil, err
+		}
+		segmented := *options
+		segmented.ReadIntervals = segment.readIntervals()
+		options = &segmented
+		ctx = withSegment(ctx, segment)
+	}
+
+	options.reportPhase(PhaseProbe, 0)
+	result, err := f.probe(ctx, options)
+	if err != nil {
+		recordSpanError(span, err)
+		if result != nil {
+			// An unreadable file may still be repairable
+			result.RepairSuggestions = SuggestRepairs(result, options.Input)
+		}
+		return result, err
+	}
+	result.Segment = segment
+
+	// Embedded metadata is read from headers only, so metadata-only
+	// probes include it too
+	if !strings.Contains(options.Input, "://") {
+		metadata, err := ReadDescriptiveMetadata(options.Input)
+		if err != nil {
+			f.logger.Warn().
+				Err(err).
+				Msg("Embedded metadata extraction failed")
+		}
+		result.Metadata = metadata
+	}
+	if options.AnalysisStreams != "" && !options.MetadataOnly {
+		if err := f.enhancedAnalyzer.AnalyzeAudioStreamSelection(ctx, result, options.Input, options.AnalysisStreams); err != nil {
+			f.logger.Warn().
+				Err(err).
+				Msg("Per-stream audio analysis failed")
+		}
+	}
+	if options.DecodeScan && !options.MetadataOnly {
+		if err := f.enhancedAnalyzer.AnalyzeDecodeErrors(ctx, result, options.Input); err != nil {
+			f.logger.Warn().
+				Err(err).
+				Msg("Decode error scan failed")
+		}
+	}
+	if f.mediainfoPath != "" && !options.MetadataOnly && !strings.Contains(options.Input, "://") &&
+		(len(options.QCCategories) == 0 || slices.Contains(options.QCCategories, QCCategoryIntegrity)) {
+		if err := f.AnalyzeMediaInfo(ctx, result, options.Input); err != nil {
+			f.logger.Warn().
+				Err(err).
+				Msg("MediaInfo cross-check failed")
+		}
+	}
+	if len(f.plugins) > 0 && !options.MetadataOnly && len(options.QCCategories) == 0 {
+		runPluginAnalyzers(ctx, f.plugins, result, options.Input, f.logger)
+	}
+	if options.DeliveryProfile != nil {
+		// Metadata-only probes must not read the full input
+		filePath := options.Input
+		if options.MetadataOnly {
+			filePath = ""
+		}
+		f.enhancedAnalyzer.AnalyzeDeliveryProfile(ctx, result, filePath, options.DeliveryProfile)
+	}
+	result.RepairSuggestions = SuggestRepairs(result, options.Input)
+	if !options.MetadataOnly {
+		options.reportPhase(PhaseContentAnalysis, 100)
+	}
+	return result, nil
+}
+
+func (f *FFprobe) probe(ctx context.Context, options *FFprobeOptions) (*FFprobeResult, error) {
 	startTime := time.Now()
 
 	// Validate options first
@@ -254,7 +401,7 @@ func (f *FFprobe) Probe(ctx context.Context, options *FFprobeOptions) (*FFprobeR
 		Msg("Executing ffprobe command")
 
 	// Execute command
-	err = cmd.Run()
+	err = runCommand(ctx, cmd)
 	executionTime := time.Since(startTime)
 
 	// Get exit code
@@ -295,6 +442,11 @@ func (f *FFprobe) Probe(ctx context.Context, options *FFprobeOptions) (*FFprobeR
 			Err(err).
 			Str("stderr", result.StdErr).
 			Msg("FFprobe execution failed")
+		// A process killed at the timeout reports the signal, report the
+		// deadline instead so callers can tell a timeout from a failure
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
 		return result, fmt.Errorf("ffprobe execution failed: %w", err)
 	}
 
@@ -314,6 +466,24 @@ func (f *FFprobe) Probe(ctx context.Context, options *FFprobeOptions) (*FFprobeR
 		// Don't fail on validation warnings, just log them
 	}
 
+	options.reportPhase(PhaseProbe, 100)
+
+	// Enhanced analysis re-reads the whole input, which metadata-only probes avoid
+	if options.MetadataOnly {
+		return result, nil
+	}
+	options.reportPhase(PhaseContentAnalysis, 0)
+
+	// A category selection replaces the default analysis pipeline
+	if len(options.QCCategories) > 0 {
+		if err := f.enhancedAnalyzer.AnalyzeResultWithCategories(ctx, result, options.Input, options.QCCategories); err != nil {
+			f.logger.Warn().
+				Err(err).
+				Msg("Selected QC category analysis failed")
+		}
+		return result, nil
+	}
+
 	// Perform enhanced analysis
 	if f.enableContentAnalysis {
 		// Perform comprehensive content analysis with all advanced QC features
@@ -362,7 +532,75 @@ func (f *FFprobe) Probe(ctx context.Context, options *FFprobeOptions) (*FFprobeR
 
 // ProbeFile is a convenience method for probing a single file with comprehensive analysis
 func (f *FFprobe) ProbeFile(ctx context.Context, filePath string) (*FFprobeResult, error) {
-	options := &FFprobeOptions{
+	return f.Probe(ctx, fileProbeOptions(filePath))
+}
+
+// ProbeFileWithCategories probes a file like ProbeFile but runs only the given QC categories
+func (f *FFprobe) ProbeFileWithCategories(ctx context.Context, filePath string, categories []QCCategory) (*FFprobeResult, error) {
+	options := fileProbeOptions(filePath)
+	options.QCCategories = categories
+	return f.Probe(ctx, options)
+}
+
+// ProbeFileForDelivery probes a file like ProbeFileWithCategories and checks
+// the result against a delivery profile
+func (f *FFprobe) ProbeFileForDelivery(ctx context.Context, filePath string, categories []QCCategory, profile *DeliveryProfile) (*FFprobeResult, error) {
+	options := fileProbeOptions(filePath)
+	options.QCCategories = categories
+	options.DeliveryProfile = profile
+	return f.Probe(ctx, options)
+}
+
+// ProbeFileForStreams probes a file like ProbeFileForDelivery and measures
+// loudness, silence and phase for each audio stream the select_streams
+// specifier picks
+func (f *FFprobe) ProbeFileForStreams(ctx context.Context, filePath string, categories []QCCategory, profile *DeliveryProfile, streams string) (*FFprobeResult, error) {
+	options := fileProbeOptions(filePath)
+	options.QCCategories = categories
+	options.DeliveryProfile = profile
+	options.AnalysisStreams = streams
+	return f.Probe(ctx, options)
+}
+
+// ProbeIMFPackage validates an IMF package, given as its directory or a zip
+// of it. The package has no single media stream to probe, so the result
+// carries only the IMF analysis; its track files are probed individually.
+// Digital Cinema Packages share the ASSETMAP layout and get the DCP analysis
+// instead.
+func (f *FFprobe) ProbeIMFPackage(ctx context.Context, packagePath string) (*FFprobeResult, error) {
+	start := time.Now()
+
+	if IsDCPPackage(packagePath) {
+		analysis, err := NewDCPAnalyzer(strings.Replace(f.binaryPath, "ffprobe", "ffmpeg", 1), f.logger).AnalyzeDCP(ctx, packagePath)
+		if err != nil {
+			return nil, fmt.Errorf("DCP analysis failed: %w", err)
+		}
+		return &FFprobeResult{
+			EnhancedAnalysis: &EnhancedAnalysis{DCPAnalysis: analysis},
+			ExecutionTime:    time.Since(start),
+			Success:          true,
+		}, nil
+	}
+
+	analyzer := NewIMFAnalyzer(f.binaryPath, f.logger)
+	if f.enhancedAnalyzer != nil && f.enhancedAnalyzer.imfAnalyzer != nil {
+		analyzer = f.enhancedAnalyzer.imfAnalyzer
+	}
+	analysis, err := analyzer.AnalyzeIMF(ctx, packagePath)
+	if err != nil {
+		return nil, fmt.Errorf("IMF package analysis failed: %w", err)
+	}
+
+	return &FFprobeResult{
+		EnhancedAnalysis: &EnhancedAnalysis{IMFAnalysis: analysis},
+		ExecutionTime:    time.Since(start),
+		Success:          true,
+	}, nil
+}
+
+// fileProbeOptions returns the options used for comprehensive single-file probes
+func fileProbeOptions(filePath string) *FFprobeOptions {
+	return &FFprobeOptions{
 		Input:           filePath,
 		OutputFormat:    OutputJSON,
 		ShowFormat:      true,
@@ -379,8 +617,6 @@ func (f *FFprobe) ProbeFile(ctx context.Context, filePath string) (*FFprobeResul
 		HideBanner:      true,
 		ReadIntervals:   "0%+#100", // Analyze first 100 frames for GOP analysis
 	}
-
-	return f.Probe(ctx, options)
 }
 
 // ProbeFileWithOptions probes a file with custom options
@@ -656,7 +892,7 @@ func (f *FFprobe) ValidateInput(input string) error {
 // GetVersion returns the ffprobe version
 func (f *FFprobe) GetVersion(ctx context.Context) (string, error) {
 	cmd := exec.CommandContext(ctx, f.binaryPath, "-version")
-	output, err := cmd.Output()
+	output, err := commandOutput(ctx, cmd)
 	if err != nil {
 		return "", fmt.Errorf("failed to get ffprobe version: %w", err)
 	}
@@ -672,6 +908,24 @@ func (f *FFprobe) GetVersion(ctx context.Context) (string, error) {
 	return string(output), nil
 }
 
+// BinaryVersion returns the version an FFmpeg tool such as ffmpeg or
+// ffprobe reports for -version, e.g. "6.1.1". It runs outside the process
+// pool, so health checks answer while every slot is busy.
+func BinaryVersion(ctx context.Context, binaryPath string) (string, error) {
+	output, err := exec.CommandContext(ctx, binaryPath, "-version").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s -version: %w", binaryPath, err)
+	}
+
+	// The first line reads "<tool> version <version> Copyright ..."
+	line, _, _ := strings.Cut(string(output), "\n")
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[1] != "version" {
+		return "", fmt.Errorf("unexpected %s -version output: %q", binaryPath, line)
+	}
+	return fields[2], nil
+}
+
 // CheckBinary verifies that ffprobe binary is available and executable
 func (f *FFprobe) CheckBinary(ctx context.Context) error {
 	version, err := f.GetVersion(ctx)
@@ -728,7 +982,15 @@ func (f *FFprobe) StreamFile(ctx context.Context, filePath string, chunkCallback
 		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
+	release, err := AcquireProcess(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	span := startCommandSpan(ctx, cmd)
 	if err := cmd.Start(); err != nil {
+		endCommandSpan(span, err)
 		return fmt.Errorf("failed to start ffprobe: %w", err)
 	}
 
@@ -737,13 +999,17 @@ func (f *FFprobe) StreamFile(ctx context.Context, filePath string, chunkCallback
 	for scanner.Scan() {
 		if err := chunkCallback(scanner.Text()); err != nil {
 			_ = cmd.Process.Kill() // Best effort kill on error
+			endCommandSpan(span, err)
 			return fmt.Errorf("chunk callback error: %w", err)
 		}
 	}
 
 	if err := scanner.Err(); err != nil {
+		endCommandSpan(span, err)
 		return fmt.Errorf("error reading ffprobe output: %w", err)
 	}
 
-	return cmd.Wait()
+	err = cmd.Wait()
+	endCommandSpan(span, err)
+	return err
 }
@@ -2,8 +2,12 @@ package ffmpeg
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -611,6 +615,24 @@ func TestValidateBinaryAtStartup_InvalidPath(t *testing.T) {
 	}
 }
 
+func TestProbeTimeout(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "ffprobe")
+	if err := os.WriteFile(binary, []byte("#!/bin/sh\nexec sleep 10\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	input := filepath.Join(dir, "input.mp4")
+	if err := os.WriteFile(input, []byte("not media"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ffprobe := NewFFprobe(binary, zerolog.Nop())
+	_, err := ffprobe.Probe(context.Background(), &FFprobeOptions{Input: input, Timeout: 100 * time.Millisecond})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
 func TestProbeWithProgress(t *testing.T) {
 	// Skip if ffprobe is not available or not executable
 	ffprobePath, err := exec.LookPath("ffprobe")
@@ -823,3 +845,77 @@ func TestParseOutput(t *testing.T) {
 		}
 	})
 }
+
+func TestProbeReportsPhases(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "video.mp4")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ffprobe := fakeFFprobe(t, `{"format": {"filename": "video.mp4", "format_name": "mp4"}, "streams": [{"index": 0, "codec_type": "video", "codec_name": "h264"}]}`, 0)
+
+	tests := []struct {
+		name    string
+		options *FFprobeOptions
+		want    []string
+	}{
+		{
+			name:    "metadata only",
+			options: NewOptionsBuilder().Input(input).JSON().ShowFormat().ShowStreams().MetadataOnly().Build(),
+			want:    []string{"probe 0", "probe 100"},
+		},
+		{
+			name:    "with content analysis",
+			options: NewOptionsBuilder().Input(input).JSON().ShowFormat().ShowStreams().QCCategories(QCCategoryCodec).Build(),
+			want:    []string{"probe 0", "probe 100", "content_analysis 0", "content_analysis 100"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var phases []string
+			tt.options.OnPhase = func(phase string, percent float64) {
+				phases = append(phases, fmt.Sprintf("%s %.0f", phase, percent))
+			}
+			if _, err := ffprobe.Probe(t.Context(), tt.options); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if strings.Join(phases, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got phases %v, want %v", phases, tt.want)
+			}
+		})
+	}
+}
+
+func TestBinaryVersion(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{name: "release", output: "ffmpeg version 6.1.1 Copyright (c) 2000-2023 the FFmpeg developers\nbuilt with gcc 13", want: "6.1.1"},
+		{name: "git build", output: "ffprobe version N-113211-g5a1b3c Copyright (c) 2007-2024 the FFmpeg developers", want: "N-113211-g5a1b3c"},
+		{name: "not ffmpeg", output: "usage: tool [options]", wantErr: true},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			script := filepath.Join(dir, fmt.Sprintf("tool%d", i))
+			if err := os.WriteFile(script, []byte("#!/bin/sh\ncat <<'EOF'\n"+tt.output+"\nEOF\n"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			got, err := BinaryVersion(t.Context(), script)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BinaryVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BinaryVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := BinaryVersion(t.Context(), filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected an error for a missing binary")
+	}
+}
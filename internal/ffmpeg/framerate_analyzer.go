@@ -5,14 +5,24 @@ import (
 	"math"
 	"strconv"
 	"strings"
+
+	"github.com/rs/zerolog"
 )
 
 // FrameRateAnalyzer handles frame rate analysis and validation
-type FrameRateAnalyzer struct{}
+type FrameRateAnalyzer struct {
+	ffmpegPath  string
+	ffprobePath string
+	logger      zerolog.Logger
+}
 
 // NewFrameRateAnalyzer creates a new frame rate analyzer
-func NewFrameRateAnalyzer() *FrameRateAnalyzer {
-	return &FrameRateAnalyzer{}
+func NewFrameRateAnalyzer(ffmpegPath, ffprobePath string, logger zerolog.Logger) *FrameRateAnalyzer {
+	return &FrameRateAnalyzer{
+		ffmpegPath:  ffmpegPath,
+		ffprobePath: ffprobePath,
+		logger:      logger,
+	}
 }
 
 // AnalyzeFrameRate analyzes frame rate from stream information
@@ -243,6 +253,20 @@ func (fra *FrameRateAnalyzer) hasVariableFrameRate(videoStreams map[int]*VideoFr
 	return false
 }
 
+// needsDeinterlacing checks if an interlaced stream is interlaced at the
+// source, rather than carrying progressive frames in pulldown
+func (fra *FrameRateAnalyzer) needsDeinterlacing(videoStreams map[int]*VideoFrameRate) bool {
+	for _, frameRate := range videoStreams {
+		if !frameRate.IsInterlaced {
+			continue
+		}
+		if frameRate.Cadence == nil || (frameRate.Cadence.Pattern != CadencePulldown32 && frameRate.Cadence.Pattern != CadencePulldown22) {
+			return true
+		}
+	}
+	return false
+}
+
 // hasMultipleFrameRates checks if there are multiple different frame rates
 func (fra *FrameRateAnalyzer) hasMultipleFrameRates(videoStreams map[int]*VideoFrameRate) bool {
 	if len(videoStreams) <= 1 {
@@ -304,11 +328,31 @@ func (fra *FrameRateAnalyzer) validateFrameRate(analysis *FrameRateAnalysis) *Fr
 			}
 		}
 
+		// Check for telecine cadence found by AnalyzeCadence
+		if cadence := frameRate.Cadence; cadence != nil {
+			switch cadence.Pattern {
+			case CadencePulldown32:
+				validation.Recommendations = append(validation.Recommendations,
+					fmt.Sprintf("Video stream %d at %.2f fps is %.3fp with 3:2 pulldown - inverse telecine (%s) rather than deinterlace", streamIndex, frameRate.EffectiveFrameRate, cadence.SourceFrameRate, cadence.IVTCFilter))
+				if cadence.CadenceBreaks > 0 {
+					validation.Issues = append(validation.Issues,
+						fmt.Sprintf("Video stream %d has %d breaks in its 3:2 cadence - likely edits after telecine, use field matching rather than a fixed pattern", streamIndex, cadence.CadenceBreaks))
+				}
+			case CadencePulldown22:
+				validation.Recommendations = append(validation.Recommendations,
+					fmt.Sprintf("Video stream %d is flagged interlaced but carries progressive frames (2:2 pulldown) - treat as progressive, do not deinterlace", streamIndex))
+			}
+		}
+
 		// Check for variable frame rate issues
 		if frameRate.IsVariableFrameRate {
 			validation.Recommendations = append(validation.Recommendations,
 				fmt.Sprintf("Video stream %d uses variable frame rate - consider converting to constant frame rate for better compatibility", streamIndex))
 		}
+		if timing := frameRate.Timing; timing != nil && timing.IsVariableFrameRate {
+			validation.Issues = append(validation.Issues,
+				fmt.Sprintf("Video stream %d timestamps range from %.3f to %.3f fps over %d segments - conform to a constant frame rate before editing or syncing", streamIndex, timing.MinFrameRate, timing.MaxFrameRate, timing.TotalSegments))
+		}
 	}
 
 	// Provide recommendations based on frame rate characteristics
@@ -322,7 +366,7 @@ func (fra *FrameRateAnalyzer) validateFrameRate(analysis *FrameRateAnalysis) *Fr
 			"Multiple frame rates detected - verify this is intentional for adaptive streaming")
 	}
 
-	if analysis.IsInterlaced {
+	if analysis.IsInterlaced && fra.needsDeinterlacing(analysis.VideoStreams) {
 		validation.Recommendations = append(validation.Recommendations,
 			"Interlaced content detected - consider deinterlacing for modern viewing devices")
 	}
@@ -0,0 +1,212 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// cadenceFrames is the number of frames read for cadence detection, about
+// 40 seconds at 29.97 fps
+const cadenceFrames = 1200
+
+// Cadence patterns
+const (
+	CadencePulldown32  = "3:2"         // 23.976p telecined to 29.97i
+	CadencePulldown22  = "2:2"         // progressive frames carried as interlaced
+	CadenceInterlaced  = "interlaced"  // interlaced at the source
+	CadenceProgressive = "progressive" // no pulldown
+)
+
+// idetRepeatedKey is the frame metadata in which idet reports the field,
+// if any, that repeats one of the previous frame
+const idetRepeatedKey = "lavfi.idet.repeated.current_frame"
+
+// CadenceAnalysis is the field cadence of a video stream, from the repeated
+// field and multi frame detection of the idet filter
+type CadenceAnalysis struct {
+	Pattern           string  `json:"pattern"` // "3:2", "2:2", "interlaced" or "progressive"
+	FramesAnalyzed    int     `json:"frames_analyzed"`
+	RepeatedTop       int     `json:"repeated_top"`
+	RepeatedBottom    int     `json:"repeated_bottom"`
+	RepeatedNeither   int     `json:"repeated_neither"`
+	ProgressiveFrames int     `json:"progressive_frames"`
+	InterlacedFrames  int     `json:"interlaced_frames"`           // TFF and BFF frames
+	PatternCoverage   float64 `json:"pattern_coverage"`            // share of repeated fields in 3:2 cadence
+	CadenceBreaks     int     `json:"cadence_breaks"`              // places the 3:2 cadence is interrupted
+	IsTelecined       bool    `json:"is_telecined"`                // 3:2 pulldown found
+	SourceFrameRate   float64 `json:"source_frame_rate,omitempty"` // frame rate before pulldown
+	RecommendIVTC     bool    `json:"recommend_ivtc"`              // inverse telecine restores the source
+	IVTCFilter        string  `json:"ivtc_filter,omitempty"`       // ffmpeg filter chain for the inverse telecine
+}
+
+// AnalyzeCadence runs idet over the first frames of each interlaced or
+// 29.97/30 fps video stream to find telecine cadence: 23.976p film carried
+// as 29.97i with 3:2 pulldown repeats a top field and a bottom field in
+// every five frames, and progressive frames flagged as interlaced are 2:2.
+// The validation of analysis is rebuilt with the cadence found. A stream
+// that cannot be read keeps its header-derived analysis.
+func (fra *FrameRateAnalyzer) AnalyzeCadence(ctx context.Context, filePath string, analysis *FrameRateAnalysis, streams []StreamInfo) {
+	if analysis == nil {
+		return
+	}
+	for _, stream := range streams {
+		frameRate := analysis.VideoStreams[stream.Index]
+		if frameRate == nil || stream.Disposition["attached_pic"] == 1 || !fra.mayCarryPulldown(frameRate) {
+			continue
+		}
+
+		output, err := fra.runIdet(ctx, filePath, stream.Index)
+		if err != nil {
+			fra.logger.Debug().Err(err).Int("stream", stream.Index).Msg("Failed to detect field cadence")
+			continue
+		}
+		frameRate.Cadence = detectCadence(output, frameRate.EffectiveFrameRate, frameRate.IsInterlaced)
+	}
+	analysis.Validation = fra.validateFrameRate(analysis)
+}
+
+// mayCarryPulldown reports whether a stream is interlaced or at the frame
+// rate 3:2 pulldown produces
+func (fra *FrameRateAnalyzer) mayCarryPulldown(frameRate *VideoFrameRate) bool {
+	if frameRate.IsInterlaced {
+		return true
+	}
+	return math.Abs(frameRate.EffectiveFrameRate-29.97) < 0.1 || math.Abs(frameRate.EffectiveFrameRate-30) < 0.1
+}
+
+// runIdet prints the repeated field idet finds in each frame of a stream,
+// followed by its summary. The segment of the analysis, if any, is read
+// rather than the start of the file.
+func (fra *FrameRateAnalyzer) runIdet(ctx context.Context, filePath string, index int) (string, error) {
+	args := []string{"-hide_banner", "-nostats"}
+	if segment := segmentFromContext(ctx); segment != nil {
+		args = append(args, segment.inputArgs(filePath)...)
+	} else {
+		args = append(args, "-i", filePath)
+	}
+	args = append(args,
+		"-map", fmt.Sprintf("0:%d", index),
+		"-vf", "idet,metadata=mode=print:key="+idetRepeatedKey,
+		"-frames:v", strconv.Itoa(cadenceFrames),
+		"-f", "null",
+		"-",
+	)
+	cmd := exec.CommandContext(ctx, fra.ffmpegPath, args...)
+
+	output, err := commandCombinedOutput(ctx, cmd)
+	if err != nil {
+		return "", fmt.Errorf("idet failed: %w", err)
+	}
+	return string(output), nil
+}
+
+// detectCadence classifies the idet output of a stream at frameRate, which
+// is flagged interlaced or not in its headers
+func detectCadence(output string, frameRate float64, flaggedInterlaced bool) *CadenceAnalysis {
+	cadence := &CadenceAnalysis{}
+	var repeated []string
+	for _, line := range strings.Split(output, "\n") {
+		if i := strings.Index(line, idetRepeatedKey+"="); i >= 0 {
+			repeated = append(repeated, strings.TrimSpace(line[i+len(idetRepeatedKey)+1:]))
+			continue
+		}
+		if strings.Contains(line, "Multi frame detection:") {
+			counts := idetCounts(line)
+			cadence.ProgressiveFrames = counts["Progressive"]
+			cadence.InterlacedFrames = counts["TFF"] + counts["BFF"]
+		}
+	}
+
+	cadence.FramesAnalyzed = len(repeated)
+	for _, field := range repeated {
+		switch field {
+		case "top":
+			cadence.RepeatedTop++
+		case "bottom":
+			cadence.RepeatedBottom++
+		default:
+			cadence.RepeatedNeither++
+		}
+	}
+	cadence.PatternCoverage, cadence.CadenceBreaks = matchPulldown(repeated)
+
+	// 3:2 pulldown repeats two fields in five frames
+	repeatedRatio := 0.0
+	if cadence.FramesAnalyzed > 0 {
+		repeatedRatio = float64(cadence.RepeatedTop+cadence.RepeatedBottom) / float64(cadence.FramesAnalyzed)
+	}
+	switch {
+	case cadence.PatternCoverage >= 0.5 && repeatedRatio >= 0.3 && repeatedRatio <= 0.5:
+		cadence.Pattern = CadencePulldown32
+		cadence.IsTelecined = true
+		cadence.RecommendIVTC = true
+		cadence.IVTCFilter = "fieldmatch,decimate"
+		if frameRate > 0 {
+			cadence.SourceFrameRate = math.Round(frameRate*4/5*1000) / 1000
+		}
+	case cadence.InterlacedFrames > cadence.ProgressiveFrames:
+		cadence.Pattern = CadenceInterlaced
+	case flaggedInterlaced && cadence.ProgressiveFrames > 0:
+		cadence.Pattern = CadencePulldown22
+	default:
+		cadence.Pattern = CadenceProgressive
+	}
+	return cadence
+}
+
+// idetCounts returns the "Name: count" pairs of an idet summary line
+func idetCounts(line string) map[string]int {
+	counts := make(map[string]int)
+	fields := strings.Fields(line)
+	for i := 0; i+1 < len(fields); i++ {
+		name, ok := strings.CutSuffix(fields[i], ":")
+		if !ok {
+			continue
+		}
+		if count, err := strconv.Atoi(fields[i+1]); err == nil {
+			counts[name] = count
+		}
+	}
+	return counts
+}
+
+// matchPulldown returns the share of repeated fields that are in 3:2
+// cadence, and the number of times the cadence is interrupted. In 3:2
+// pulldown the frames with a repeated field are alternately two and three
+// frames apart and alternately repeat the top and the bottom field.
+func matchPulldown(repeated []string) (coverage float64, breaks int) {
+	var positions []int
+	for i, field := range repeated {
+		if field == "top" || field == "bottom" {
+			positions = append(positions, i)
+		}
+	}
+	if len(positions) < 3 {
+		return 0, 0
+	}
+
+	inCadence := make([]bool, len(positions))
+	for i := 1; i < len(positions); i++ {
+		gap := positions[i] - positions[i-1]
+		if gap != 2 && gap != 3 || repeated[positions[i]] == repeated[positions[i-1]] {
+			continue
+		}
+		if i == 1 || positions[i-1]-positions[i-2] == 5-gap {
+			inCadence[i-1], inCadence[i] = true, true
+		}
+	}
+
+	matched := 0
+	for i, ok := range inCadence {
+		if ok {
+			matched++
+		} else if i > 0 && inCadence[i-1] {
+			breaks++
+		}
+	}
+	return float64(matched) / float64(len(positions)), breaks
+}
@@ -0,0 +1,109 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// idetOutput builds the output of idet with metadata printing for the
+// repeated field of each frame and its multi frame summary
+func idetOutput(repeated []string, tff, bff, progressive int) string {
+	var b strings.Builder
+	for i, field := range repeated {
+		fmt.Fprintf(&b, "[Parsed_metadata_1 @ 0x5581] frame:%d    pts:%d    pts_time:%.3f\n", i, i*1001, float64(i)*1001/30000)
+		fmt.Fprintf(&b, "[Parsed_metadata_1 @ 0x5581] %s=%s\n", idetRepeatedKey, field)
+	}
+	fmt.Fprintf(&b, "[Parsed_idet_0 @ 0x5580] Repeated Fields: Neither: %5d Top: %5d Bottom: %5d\n", 0, 0, 0)
+	fmt.Fprintf(&b, "[Parsed_idet_0 @ 0x5580] Multi frame detection: TFF: %5d BFF: %5d Progressive: %5d Undetermined: %5d\n", tff, bff, progressive, 0)
+	return b.String()
+}
+
+// telecined returns the repeated fields idet finds in frames of 3:2 pulldown:
+// a top field then a bottom field repeated in every five frames
+func telecined(frames int) []string {
+	cycle := []string{"neither", "top", "neither", "bottom", "neither"}
+	repeated := make([]string, frames)
+	for i := range repeated {
+		repeated[i] = cycle[i%5]
+	}
+	return repeated
+}
+
+func TestDetectCadence_Pulldown(t *testing.T) {
+	cadence := detectCadence(idetOutput(telecined(100), 60, 0, 40), 29.97, true)
+	if cadence.Pattern != CadencePulldown32 || !cadence.IsTelecined || !cadence.RecommendIVTC {
+		t.Fatalf("expected 3:2 pulldown, got %+v", cadence)
+	}
+	if cadence.FramesAnalyzed != 100 || cadence.RepeatedTop != 20 || cadence.RepeatedBottom != 20 || cadence.RepeatedNeither != 60 {
+		t.Errorf("unexpected field counts %+v", cadence)
+	}
+	if cadence.PatternCoverage != 1 || cadence.CadenceBreaks != 0 {
+		t.Errorf("expected an unbroken cadence, got coverage %.2f and %d breaks", cadence.PatternCoverage, cadence.CadenceBreaks)
+	}
+	if cadence.SourceFrameRate != 23.976 || cadence.IVTCFilter != "fieldmatch,decimate" {
+		t.Errorf("expected IVTC to 23.976p, got %.3f with %q", cadence.SourceFrameRate, cadence.IVTCFilter)
+	}
+}
+
+func TestDetectCadence_BrokenPulldown(t *testing.T) {
+	// An edit after telecine drops two frames and shifts the cadence
+	repeated := append(telecined(52), telecined(53)[5:]...)
+	cadence := detectCadence(idetOutput(repeated, 60, 0, 40), 29.97, true)
+	if cadence.Pattern != CadencePulldown32 {
+		t.Fatalf("expected 3:2 pulldown, got %+v", cadence)
+	}
+	if cadence.CadenceBreaks != 1 || cadence.PatternCoverage >= 1 {
+		t.Errorf("expected one break, got coverage %.2f and %d breaks", cadence.PatternCoverage, cadence.CadenceBreaks)
+	}
+}
+
+func TestDetectCadence_NoPulldown(t *testing.T) {
+	neither := make([]string, 100)
+	for i := range neither {
+		neither[i] = "neither"
+	}
+	tests := []struct {
+		name        string
+		output      string
+		interlaced  bool
+		wantPattern string
+	}{
+		{"interlaced video", idetOutput(neither, 95, 0, 5), true, CadenceInterlaced},
+		{"progressive flagged interlaced", idetOutput(neither, 2, 0, 98), true, CadencePulldown22},
+		{"progressive", idetOutput(neither, 0, 0, 100), false, CadenceProgressive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cadence := detectCadence(tt.output, 29.97, tt.interlaced)
+			if cadence.Pattern != tt.wantPattern || cadence.IsTelecined {
+				t.Errorf("got %+v, want pattern %q", cadence, tt.wantPattern)
+			}
+		})
+	}
+}
+
+func TestValidateFrameRate_Cadence(t *testing.T) {
+	fra := NewFrameRateAnalyzer("ffmpeg", "ffprobe", zerolog.Nop())
+	analysis := fra.AnalyzeFrameRate([]StreamInfo{
+		{Index: 0, CodecType: "video", RFrameRate: "30000/1001", AvgFrameRate: "30000/1001", FieldOrder: "tt"},
+	})
+	if !strings.Contains(strings.Join(analysis.Validation.Recommendations, "\n"), "consider deinterlacing") {
+		t.Fatalf("expected deinterlacing to be recommended before cadence detection, got %v", analysis.Validation.Recommendations)
+	}
+
+	analysis.VideoStreams[0].Cadence = &CadenceAnalysis{Pattern: CadencePulldown32, IsTelecined: true, CadenceBreaks: 2, SourceFrameRate: 23.976, IVTCFilter: "fieldmatch,decimate"}
+	validation := fra.validateFrameRate(analysis)
+	recommendations := strings.Join(validation.Recommendations, "\n")
+	if !strings.Contains(recommendations, "23.976p with 3:2 pulldown - inverse telecine (fieldmatch,decimate)") {
+		t.Errorf("expected IVTC to be recommended, got %v", validation.Recommendations)
+	}
+	if strings.Contains(recommendations, "consider deinterlacing") {
+		t.Errorf("telecined content must not be deinterlaced, got %v", validation.Recommendations)
+	}
+	if len(validation.Issues) != 1 || !strings.Contains(validation.Issues[0], "2 breaks in its 3:2 cadence") {
+		t.Errorf("expected the broken cadence as an issue, got %v", validation.Issues)
+	}
+}
@@ -0,0 +1,294 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// frameRateTolerance is the relative change in frame duration that
+	// starts a new frame rate segment
+	frameRateTolerance = 0.01
+
+	// minFrameRateSegmentFrames is the fewest frames a segment needs to
+	// stand on its own; shorter runs, such as a dropped frame, are merged
+	// into the segment before them
+	minFrameRateSegmentFrames = 12
+
+	// maxFrameRateSegments bounds the segments listed per stream
+	maxFrameRateSegments = 100
+
+	// maxFrameDurationBins bounds the histogram to its most common
+	// durations
+	maxFrameDurationBins = 20
+
+	// maxIrregularFrameShare is the share of frames off the median frame
+	// rate above which a stream without steady segments is variable
+	maxIrregularFrameShare = 0.05
+)
+
+// FrameTimingAnalysis is the frame rate of a video stream measured from the
+// presentation timestamps of its packets rather than the header rates
+type FrameTimingAnalysis struct {
+	FramesAnalyzed      int                `json:"frames_analyzed"`
+	MinFrameRate        float64            `json:"min_frame_rate"`
+	MaxFrameRate        float64            `json:"max_frame_rate"`
+	MedianFrameRate     float64            `json:"median_frame_rate"` // Frame rate of the segment holding the median frame
+	IrregularFrames     int                `json:"irregular_frames"`  // Frames whose duration is off the median frame rate
+	IsVariableFrameRate bool               `json:"is_variable_frame_rate"`
+	DurationHistogram   []FrameDurationBin `json:"duration_histogram"` // The 20 most common durations, shortest first
+	Segments            []FrameRateSegment `json:"segments"`           // The first 100
+	TotalSegments       int                `json:"total_segments"`
+}
+
+// FrameDurationBin counts the frames of one duration, to 0.1 ms
+type FrameDurationBin struct {
+	DurationMs float64 `json:"duration_ms"`
+	FrameRate  float64 `json:"frame_rate"`
+	Frames     int     `json:"frames"`
+	Percent    float64 `json:"percent"`
+}
+
+// FrameRateSegment is a run of frames at the same frame rate
+type FrameRateSegment struct {
+	StartTime float64 `json:"start_time"` // Seconds
+	EndTime   float64 `json:"end_time"`
+	Frames    int     `json:"frames"`
+	FrameRate float64 `json:"frame_rate"` // Leaving out dropped or held frames
+}
+
+// AnalyzeFrameTiming reads the presentation timestamps of every video
+// packet and measures the frame rate each stream actually has, replacing
+// the guess from r_frame_rate and avg_frame_rate. The validation of
+// analysis is rebuilt with the measured rates.
+func (fra *FrameRateAnalyzer) AnalyzeFrameTiming(ctx context.Context, filePath string, analysis *FrameRateAnalysis, streams []StreamInfo) error {
+	if analysis == nil || len(analysis.VideoStreams) == 0 {
+		return nil
+	}
+
+	args := []string{"-v", "error", "-select_streams", "v"}
+	if segment := segmentFromContext(ctx); segment != nil {
+		args = append(args, "-read_intervals", segment.readIntervals())
+	}
+	args = append(args,
+		"-show_entries", "packet=stream_index,pts_time",
+		"-of", "compact=p=0",
+		filePath,
+	)
+	cmd := exec.CommandContext(ctx, fra.ffprobePath, args...)
+	output, err := commandOutput(ctx, cmd)
+	if err != nil {
+		return fmt.Errorf("failed to read packet timestamps: %w", err)
+	}
+
+	timestamps := parsePresentationTimes(output)
+	for _, stream := range streams {
+		frameRate := analysis.VideoStreams[stream.Index]
+		if frameRate == nil || stream.Disposition["attached_pic"] == 1 {
+			continue
+		}
+		timing := measureFrameTiming(timestamps[stream.Index], parseTimeBase(stream.TimeBase))
+		if timing == nil {
+			continue
+		}
+		frameRate.Timing = timing
+		frameRate.IsVariableFrameRate = timing.IsVariableFrameRate
+	}
+	analysis.IsVariableFrameRate = fra.hasVariableFrameRate(analysis.VideoStreams)
+	analysis.Validation = fra.validateFrameRate(analysis)
+	return nil
+}
+
+// parsePresentationTimes reads the PTS of each stream's packets from
+// ffprobe's compact output, e.g.
+//
+//	stream_index=0|pts_time=0.083417
+func parsePresentationTimes(output []byte) map[int][]float64 {
+	timestamps := make(map[int][]float64)
+	forEachLine(output, func(line string) bool {
+		index, pts := -1, math.NaN()
+		for _, field := range strings.Split(strings.TrimSpace(line), "|") {
+			key, value, _ := strings.Cut(field, "=")
+			switch key {
+			case "stream_index":
+				if i, err := strconv.Atoi(value); err == nil {
+					index = i
+				}
+			case "pts_time":
+				if t, err := strconv.ParseFloat(value, 64); err == nil {
+					pts = t
+				}
+			}
+		}
+		if index >= 0 && !math.IsNaN(pts) {
+			timestamps[index] = append(timestamps[index], pts)
+		}
+		return true
+	})
+	return timestamps
+}
+
+// measureFrameTiming measures the frame rate of packets presented at pts,
+// in decoding order, in a stream whose timestamps are rounded to timeBase
+// seconds. It returns nil for fewer than two frames.
+func measureFrameTiming(pts []float64, timeBase float64) *FrameTimingAnalysis {
+	if len(pts) < 2 {
+		return nil
+	}
+	sorted := append([]float64(nil), pts...)
+	sort.Float64s(sorted)
+
+	// Frames are presented in PTS order; a repeated PTS is not a frame
+	var starts, durations []float64
+	for i := 1; i < len(sorted); i++ {
+		if duration := sorted[i] - sorted[i-1]; duration > 0 {
+			starts = append(starts, sorted[i-1])
+			durations = append(durations, duration)
+		}
+	}
+	if len(durations) == 0 {
+		return nil
+	}
+
+	timing := &FrameTimingAnalysis{
+		FramesAnalyzed:    len(durations),
+		DurationHistogram: frameDurationHistogram(durations),
+	}
+
+	segments := frameRateSegments(starts, durations, timeBase)
+	timing.TotalSegments = len(segments)
+	// The frame rate of the segment holding the median frame, which keeps
+	// timestamps rounded to the time base from showing as two rates
+	middle, counted := len(durations)/2, 0
+	for _, segment := range segments {
+		counted += segment.Frames
+		if counted > middle {
+			timing.MedianFrameRate = segment.FrameRate
+			break
+		}
+	}
+
+	// Frames off the median rate by more than rounding set the range
+	timing.MinFrameRate, timing.MaxFrameRate = timing.MedianFrameRate, timing.MedianFrameRate
+	nominal := 1 / timing.MedianFrameRate
+	for _, duration := range durations {
+		if sameFrameDuration(duration, nominal, timeBase) {
+			continue
+		}
+		timing.IrregularFrames++
+		timing.MinFrameRate = math.Min(timing.MinFrameRate, roundTo(1/duration, 3))
+		timing.MaxFrameRate = math.Max(timing.MaxFrameRate, roundTo(1/duration, 3))
+	}
+	timing.IsVariableFrameRate = len(segments) > 1 ||
+		float64(timing.IrregularFrames) > maxIrregularFrameShare*float64(len(durations))
+
+	if len(segments) > maxFrameRateSegments {
+		segments = segments[:maxFrameRateSegments]
+	}
+	timing.Segments = segments
+	return timing
+}
+
+// frameRateSegments splits frames into runs of the same duration, within
+// frameRateTolerance or one and a half time base ticks. Runs shorter than
+// minFrameRateSegmentFrames are merged into the run before them, a short
+// first run into the one after it, and neighbouring runs left at the same
+// rate are joined. A segment's rate is that of the frames at its own rate.
+func frameRateSegments(starts, durations []float64, timeBase float64) []FrameRateSegment {
+	// A run keeps the frames at its own rate apart from the frames of the
+	// short runs merged into it, so a dropped frame does not change its rate
+	type run struct {
+		start, length, steadyLength float64
+		frames, steadyFrames        int
+	}
+	rate := func(r run) float64 { return r.steadyLength / float64(r.steadyFrames) }
+
+	var runs []run
+	for i, duration := range durations {
+		if n := len(runs); n > 0 && sameFrameDuration(duration, rate(runs[n-1]), timeBase) {
+			runs[n-1].length += duration
+			runs[n-1].steadyLength += duration
+			runs[n-1].frames++
+			runs[n-1].steadyFrames++
+			continue
+		}
+		runs = append(runs, run{start: starts[i], length: duration, steadyLength: duration, frames: 1, steadyFrames: 1})
+	}
+
+	var merged []run
+	for _, r := range runs {
+		n := len(merged)
+		switch {
+		case n > 0 && r.frames < minFrameRateSegmentFrames:
+			merged[n-1].length += r.length
+			merged[n-1].frames += r.frames
+		case n > 0 && merged[n-1].frames < minFrameRateSegmentFrames:
+			r.start, r.length, r.frames = merged[n-1].start, merged[n-1].length+r.length, merged[n-1].frames+r.frames
+			merged[n-1] = r
+		case n > 0 && sameFrameDuration(rate(r), rate(merged[n-1]), timeBase):
+			merged[n-1].length += r.length
+			merged[n-1].steadyLength += r.steadyLength
+			merged[n-1].frames += r.frames
+			merged[n-1].steadyFrames += r.steadyFrames
+		default:
+			merged = append(merged, r)
+		}
+	}
+
+	segments := make([]FrameRateSegment, len(merged))
+	for i, r := range merged {
+		segments[i] = FrameRateSegment{
+			StartTime: roundTo(r.start, 3),
+			EndTime:   roundTo(r.start+r.length, 3),
+			Frames:    r.frames,
+			FrameRate: roundTo(1/rate(r), 3),
+		}
+	}
+	return segments
+}
+
+// sameFrameDuration reports whether frame durations a and b, in seconds,
+// differ by no more than frameRateTolerance or the rounding of timestamps
+// to timeBase
+func sameFrameDuration(a, b, timeBase float64) bool {
+	return math.Abs(a-b) <= math.Max(1.5*timeBase, frameRateTolerance*math.Min(a, b))
+}
+
+// frameDurationHistogram counts frames by duration to 0.1 ms, keeping the
+// most common durations
+func frameDurationHistogram(durations []float64) []FrameDurationBin {
+	counts := make(map[int64]int)
+	for _, duration := range durations {
+		counts[int64(math.Round(duration*1e4))]++
+	}
+
+	bins := make([]FrameDurationBin, 0, len(counts))
+	for key, frames := range counts {
+		durationMs := float64(key) / 10
+		bin := FrameDurationBin{
+			DurationMs: durationMs,
+			Frames:     frames,
+			Percent:    roundTo(float64(frames)*100/float64(len(durations)), 2),
+		}
+		if durationMs > 0 {
+			bin.FrameRate = roundTo(1000/durationMs, 3)
+		}
+		bins = append(bins, bin)
+	}
+	sort.Slice(bins, func(i, j int) bool {
+		if bins[i].Frames != bins[j].Frames {
+			return bins[i].Frames > bins[j].Frames
+		}
+		return bins[i].DurationMs < bins[j].DurationMs
+	})
+	if len(bins) > maxFrameDurationBins {
+		bins = bins[:maxFrameDurationBins]
+	}
+	sort.Slice(bins, func(i, j int) bool { return bins[i].DurationMs < bins[j].DurationMs })
+	return bins
+}
@@ -0,0 +1,139 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// constantRate returns the PTS of frames at rate from start, rounded to
+// timeBase
+func constantRate(start float64, frames int, rate, timeBase float64) []float64 {
+	pts := make([]float64, frames)
+	for i := range pts {
+		pts[i] = math.Round((start+float64(i)/rate)/timeBase) * timeBase
+	}
+	return pts
+}
+
+func TestMeasureFrameTiming_Constant(t *testing.T) {
+	// Matroska rounds 23.976 fps timestamps to the millisecond, so frames
+	// alternate between 41 and 42 ms
+	pts := constantRate(0, 240, 24000.0/1001, 0.001)
+	// B-frames put packets out of presentation order
+	pts[3], pts[4] = pts[4], pts[3]
+
+	timing := measureFrameTiming(pts, 0.001)
+	if timing.IsVariableFrameRate || timing.TotalSegments != 1 || timing.IrregularFrames != 0 {
+		t.Fatalf("expected a constant frame rate, got %+v", timing)
+	}
+	if math.Abs(timing.MedianFrameRate-23.976) > 0.002 || timing.MinFrameRate != timing.MedianFrameRate || timing.MaxFrameRate != timing.MedianFrameRate {
+		t.Errorf("expected 23.976 fps, got median %.3f, min %.3f and max %.3f", timing.MedianFrameRate, timing.MinFrameRate, timing.MaxFrameRate)
+	}
+	if len(timing.DurationHistogram) != 2 || timing.DurationHistogram[0].DurationMs != 41 || timing.DurationHistogram[1].DurationMs != 42 {
+		t.Errorf("expected 41 and 42 ms frames, got %+v", timing.DurationHistogram)
+	}
+}
+
+func TestMeasureFrameTiming_DroppedFrame(t *testing.T) {
+	pts := constantRate(10, 100, 25, 1.0/90000)
+	pts = append(pts[:50], pts[51:]...)
+
+	timing := measureFrameTiming(pts, 1.0/90000)
+	if timing.IsVariableFrameRate || timing.TotalSegments != 1 {
+		t.Fatalf("expected one dropped frame not to make the stream variable, got %+v", timing)
+	}
+	if timing.IrregularFrames != 1 || timing.MinFrameRate != 12.5 || timing.MaxFrameRate != 25 {
+		t.Errorf("expected the dropped frame in the range, got %+v", timing)
+	}
+	if segment := timing.Segments[0]; segment.StartTime != 10 || segment.EndTime != 13.96 || segment.Frames != 98 {
+		t.Errorf("unexpected segment %+v", segment)
+	}
+}
+
+func TestMeasureFrameTiming_RateChange(t *testing.T) {
+	pts := append(constantRate(0, 250, 25, 1.0/90000), constantRate(10, 300, 30000.0/1001, 1.0/90000)...)
+
+	timing := measureFrameTiming(pts, 1.0/90000)
+	if !timing.IsVariableFrameRate || timing.TotalSegments != 2 {
+		t.Fatalf("expected two segments, got %+v", timing)
+	}
+	want := []FrameRateSegment{
+		{StartTime: 0, EndTime: 10, Frames: 250, FrameRate: 25},
+		{StartTime: 10, EndTime: 19.977, Frames: 299, FrameRate: 29.97},
+	}
+	for i, segment := range timing.Segments {
+		if segment != want[i] {
+			t.Errorf("segment %d = %+v, want %+v", i, segment, want[i])
+		}
+	}
+	if timing.MinFrameRate != 25 || timing.MaxFrameRate != 29.97 || timing.MedianFrameRate != 29.97 {
+		t.Errorf("unexpected rates %+v", timing)
+	}
+}
+
+func TestMeasureFrameTiming_Irregular(t *testing.T) {
+	// A screen recording presents frames whenever the picture changes
+	var pts []float64
+	var now float64
+	for i := 0; i < 200; i++ {
+		pts = append(pts, now)
+		now += []float64{0.016, 0.033, 0.016, 0.1}[i%4]
+	}
+
+	timing := measureFrameTiming(pts, 0.001)
+	if !timing.IsVariableFrameRate || timing.IrregularFrames == 0 {
+		t.Fatalf("expected irregular frames to make the stream variable, got %+v", timing)
+	}
+	if timing.MinFrameRate != 10 || timing.MaxFrameRate != 62.5 {
+		t.Errorf("expected 10 to 62.5 fps, got %.3f to %.3f", timing.MinFrameRate, timing.MaxFrameRate)
+	}
+	if len(timing.DurationHistogram) != 3 {
+		t.Errorf("expected three durations, got %+v", timing.DurationHistogram)
+	}
+
+	if measureFrameTiming([]float64{1}, 0.001) != nil {
+		t.Error("expected no timing for a single frame")
+	}
+}
+
+func TestAnalyzeFrameTiming(t *testing.T) {
+	var b strings.Builder
+	for _, pts := range append(constantRate(0, 250, 25, 1.0/90000), constantRate(10, 300, 50, 1.0/90000)...) {
+		fmt.Fprintf(&b, "stream_index=0|pts_time=%f\n", pts)
+	}
+	fmt.Fprintf(&b, "stream_index=0|pts_time=N/A\n")
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "packets")
+	if err := os.WriteFile(output, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "ffprobe")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat "+output+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	streams := []StreamInfo{{Index: 0, CodecType: "video", RFrameRate: "25/1", AvgFrameRate: "25/1", TimeBase: "1/90000"}}
+	fra := NewFrameRateAnalyzer("ffmpeg", script, zerolog.Nop())
+	analysis := fra.AnalyzeFrameRate(streams)
+	if analysis.IsVariableFrameRate {
+		t.Fatal("expected the header rates to look constant")
+	}
+
+	if err := fra.AnalyzeFrameTiming(t.Context(), filepath.Join(dir, "in.mp4"), analysis, streams); err != nil {
+		t.Fatal(err)
+	}
+	timing := analysis.VideoStreams[0].Timing
+	if timing == nil || timing.TotalSegments != 2 || !analysis.IsVariableFrameRate || !analysis.VideoStreams[0].IsVariableFrameRate {
+		t.Fatalf("expected the measured rates to be variable, got %+v", timing)
+	}
+	if issues := strings.Join(analysis.Validation.Issues, "\n"); !strings.Contains(issues, "range from 25.000 to 50.000 fps over 2 segments") {
+		t.Errorf("expected the measured range as an issue, got %v", analysis.Validation.Issues)
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os/exec"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -35,16 +37,18 @@ func (ha *HDRAnalyzer) AnalyzeHDR(ctx context.Context, filePath string) (*HDRAna
 	analysis := &HDRAnalysis{}
 
 	// Get stream metadata for HDR indicators
-	streamMetadata, err := ha.getStreamMetadata(ctx, filePath)
+	streams, err := ha.getStreamMetadata(ctx, filePath)
 	if err != nil {
 		return analysis, fmt.Errorf("failed to get stream metadata: %w", err)
 	}
 
 	// Analyze video streams for HDR characteristics
-	for _, stream := range streamMetadata {
+	var video streamMetadata
+	for _, stream := range streams {
 		if stream.CodecType != "video" {
 			continue
 		}
+		video = stream
 
 		// Check basic HDR indicators
 		analysis.ColorPrimaries = stream.ColorPrimaries
@@ -62,6 +66,15 @@ func (ha *HDRAnalyzer) AnalyzeHDR(ctx context.Context, filePath string) (*HDRAna
 			analysis.HLGCompatible = ha.isHLGCompatible(stream.ColorTransfer)
 		}
 
+		// Containers carry the Dolby Vision configuration record, and MP4
+		// and Matroska static HDR metadata, as stream side data
+		ha.parseStreamSideData(stream.SideDataList, analysis)
+		if analysis.DolbyVision != nil {
+			// Profile 5 signals no color characteristics of its own
+			analysis.IsHDR = true
+			analysis.HDRFormat = "Dolby Vision"
+		}
+
 		// Only analyze the first video stream
 		break
 	}
@@ -73,11 +86,15 @@ func (ha *HDRAnalyzer) AnalyzeHDR(ctx context.Context, filePath string) (*HDRAna
 			ha.logger.Warn().Err(err).Msg("Failed to get side data, continuing without advanced metadata")
 		} else {
 			ha.parseSideDataMetadata(sideData, analysis)
+			if analysis.DolbyVision != nil && analysis.DolbyVision.RPUFound == nil {
+				found := false
+				analysis.DolbyVision.RPUFound = &found
+			}
 		}
 	}
 
 	// Validate HDR compliance
-	analysis.Validation = ha.validateHDRCompliance(analysis)
+	analysis.Validation = ha.validateHDRCompliance(analysis, video)
 
 	return analysis, nil
 }
@@ -96,6 +113,9 @@ type streamMetadata struct {
 	PixFmt         string `json:"pix_fmt,omitempty"`
 	Profile        string `json:"profile,omitempty"`
 	Level          int    `json:"level,omitempty"`
+	CodecName      string `json:"codec_name,omitempty"`
+
+	SideDataList []map[string]any `json:"side_data_list,omitempty"`
 }
 
 // getStreamMetadata retrieves basic stream metadata
@@ -108,7 +128,7 @@ func (ha *HDRAnalyzer) getStreamMetadata(ctx context.Context, filePath string) (
 		filePath,
 	)
 
-	output, err := cmd.Output()
+	output, err := commandOutput(ctx, cmd)
 	if err != nil {
 		return nil, fmt.Errorf("ffprobe command failed: %w", err)
 	}
@@ -121,7 +141,8 @@ func (ha *HDRAnalyzer) getStreamMetadata(ctx context.Context, filePath string) (
 	return result.Streams, nil
 }
 
-// getSideDataMetadata retrieves side data metadata for advanced HDR info
+// getSideDataMetadata reads the first frame, whose color properties and
+// side data carry the per-frame HDR metadata
 func (ha *HDRAnalyzer) getSideDataMetadata(ctx context.Context, filePath string) (string, error) {
 	cmd := exec.CommandContext(ctx, ha.ffprobePath,
 		"-v", "quiet",
@@ -129,11 +150,10 @@ func (ha *HDRAnalyzer) getSideDataMetadata(ctx context.Context, filePath string)
 		"-show_frames",
 		"-select_streams", "v:0",
 		"-read_intervals", "%+#1",
-		"-show_entries", "frame=side_data_list",
 		filePath,
 	)
 
-	output, err := cmd.Output()
+	output, err := commandOutput(ctx, cmd)
 	if err != nil {
 		return "", fmt.Errorf("ffprobe side data command failed: %w", err)
 	}
@@ -191,7 +211,16 @@ func (ha *HDRAnalyzer) isHLGCompatible(colorTransfer string) bool {
 	return colorTransfer == "arib-std-b67"
 }
 
-// parseSideDataMetadata parses side data for advanced HDR metadata
+// parseSideDataMetadata parses the first frame's default-format output,
+// e.g.
+//
+//	[FRAME]
+//	color_transfer=smpte2084
+//	[SIDE_DATA]
+//	side_data_type=Mastering display metadata
+//	red_x=35400/50000
+//	...
+//	[/SIDE_DATA]
 func (ha *HDRAnalyzer) parseSideDataMetadata(sideData string, analysis *HDRAnalysis) {
 	lines := strings.Split(sideData, "\n")
 
@@ -213,7 +242,7 @@ func (ha *HDRAnalyzer) parseSideDataMetadata(sideData string, analysis *HDRAnaly
 			continue
 		}
 
-		if strings.Contains(line, "side_data_type=HDR10+") {
+		if strings.Contains(line, "side_data_type=HDR10+") || strings.Contains(line, "side_data_type=HDR Dynamic Metadata SMPTE2094-40") {
 			currentSideData = "hdr10plus"
 			analysis.HDR10Plus = &HDR10PlusMetadata{Present: true}
 			if analysis.HDRFormat == "HDR10" {
@@ -222,9 +251,24 @@ func (ha *HDRAnalyzer) parseSideDataMetadata(sideData string, analysis *HDRAnaly
 			continue
 		}
 
-		if strings.Contains(line, "side_data_type=DOVI") || strings.Contains(line, "Dolby Vision") {
+		if strings.Contains(line, "side_data_type=DOVI") {
 			currentSideData = "dolby_vision"
-			analysis.DolbyVision = &DolbyVisionMetadata{}
+			if analysis.DolbyVision == nil {
+				analysis.DolbyVision = &DolbyVisionMetadata{}
+			}
+			analysis.HDRFormat = "Dolby Vision"
+			continue
+		}
+
+		// Frames carry the RPU itself as "Dolby Vision RPU Data" or, decoded,
+		// "Dolby Vision Metadata"
+		if strings.Contains(line, "side_data_type=Dolby Vision") {
+			currentSideData = "dolby_vision_rpu"
+			if analysis.DolbyVision == nil {
+				analysis.DolbyVision = &DolbyVisionMetadata{RPUPresent: true}
+			}
+			found := true
+			analysis.DolbyVision.RPUFound = &found
 			analysis.HDRFormat = "Dolby Vision"
 			continue
 		}
@@ -237,6 +281,10 @@ func (ha *HDRAnalyzer) parseSideDataMetadata(sideData string, analysis *HDRAnaly
 
 		// Parse specific metadata based on current side data type
 		switch currentSideData {
+		case "":
+			if transfer, ok := strings.CutPrefix(line, "color_transfer="); ok && transfer != "unknown" {
+				analysis.FrameTransfer = transfer
+			}
 		case "mastering":
 			ha.parseMasteringDisplayData(line, analysis.MasteringDisplay)
 		case "content_light":
@@ -249,8 +297,58 @@ func (ha *HDRAnalyzer) parseSideDataMetadata(sideData string, analysis *HDRAnaly
 	}
 }
 
-// parseMasteringDisplayData parses mastering display metadata
+// parseStreamSideData parses the side data ffprobe lists with the stream,
+// feeding each entry to the same parsers as frame side data
+func (ha *HDRAnalyzer) parseStreamSideData(sideDataList []map[string]any, analysis *HDRAnalysis) {
+	for _, sideData := range sideDataList {
+		var parse func(line string)
+		switch sideData["side_data_type"] {
+		case "DOVI configuration record":
+			analysis.DolbyVision = &DolbyVisionMetadata{}
+			parse = func(line string) { ha.parseDolbyVisionData(line, analysis.DolbyVision) }
+		case "Mastering display metadata":
+			analysis.MasteringDisplay = &MasteringDisplayMetadata{HasMasteringDisplay: true}
+			parse = func(line string) { ha.parseMasteringDisplayData(line, analysis.MasteringDisplay) }
+		case "Content light level metadata":
+			analysis.ContentLightLevel = &ContentLightLevelData{HasContentLightLevel: true}
+			parse = func(line string) { ha.parseContentLightData(line, analysis.ContentLightLevel) }
+		default:
+			continue
+		}
+		for key, value := range sideData {
+			parse(fmt.Sprintf("%s=%v", key, value))
+		}
+	}
+}
+
+// parseMasteringDisplayData parses mastering display metadata. ffprobe
+// prints each coordinate and luminance as a rational, e.g. red_x=35400/50000
+// and min_luminance=50/10000; the display_primaries form is x265's.
 func (ha *HDRAnalyzer) parseMasteringDisplayData(line string, metadata *MasteringDisplayMetadata) {
+	key, value, _ := strings.Cut(line, "=")
+	switch key {
+	case "red_x":
+		metadata.DisplayPrimariesX[0] = parseSideDataValue(value)
+	case "red_y":
+		metadata.DisplayPrimariesY[0] = parseSideDataValue(value)
+	case "green_x":
+		metadata.DisplayPrimariesX[1] = parseSideDataValue(value)
+	case "green_y":
+		metadata.DisplayPrimariesY[1] = parseSideDataValue(value)
+	case "blue_x":
+		metadata.DisplayPrimariesX[2] = parseSideDataValue(value)
+	case "blue_y":
+		metadata.DisplayPrimariesY[2] = parseSideDataValue(value)
+	case "white_point_x":
+		metadata.WhitePointX = parseSideDataValue(value)
+	case "white_point_y":
+		metadata.WhitePointY = parseSideDataValue(value)
+	case "max_luminance":
+		metadata.MaxDisplayLuminance = parseSideDataValue(value)
+	case "min_luminance":
+		metadata.MinDisplayLuminance = parseSideDataValue(value)
+	}
+
 	// Parse display primaries: display_primaries=G(13250,34500)B(7500,3000)R(34000,16000)
 	if strings.Contains(line, "display_primaries=") {
 		primariesRegex := regexp.MustCompile(`G\((\d+),(\d+)\)B\((\d+),(\d+)\)R\((\d+),(\d+)\)`)
@@ -295,27 +393,24 @@ func (ha *HDRAnalyzer) parseMasteringDisplayData(line string, metadata *Masterin
 			}
 		}
 	}
+}
 
-	// Parse luminance: max_luminance=1000.000000, min_luminance=0.005000
-	if strings.Contains(line, "max_luminance=") {
-		maxLumRegex := regexp.MustCompile(`max_luminance=([0-9.]+)`)
-		matches := maxLumRegex.FindStringSubmatch(line)
-		if len(matches) == 2 {
-			if val, err := strconv.ParseFloat(matches[1], 64); err == nil {
-				metadata.MaxDisplayLuminance = val
-			}
-		}
+// parseSideDataValue parses a side data number written either as a
+// decimal or as a rational such as 35400/50000
+func parseSideDataValue(value string) float64 {
+	num, den, isRational := strings.Cut(strings.TrimSpace(value), "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
 	}
-
-	if strings.Contains(line, "min_luminance=") {
-		minLumRegex := regexp.MustCompile(`min_luminance=([0-9.]+)`)
-		matches := minLumRegex.FindStringSubmatch(line)
-		if len(matches) == 2 {
-			if val, err := strconv.ParseFloat(matches[1], 64); err == nil {
-				metadata.MinDisplayLuminance = val
-			}
-		}
+	if !isRational {
+		return n
 	}
+	d, err := strconv.ParseFloat(den, 64)
+	if err != nil || d == 0 {
+		return 0
+	}
+	return n / d
 }
 
 // parseContentLightData parses content light level metadata
@@ -365,43 +460,71 @@ func (ha *HDRAnalyzer) parseHDR10PlusData(line string, metadata *HDR10PlusMetada
 	}
 }
 
-// parseDolbyVisionData parses Dolby Vision metadata
+// parseDolbyVisionData parses the Dolby Vision configuration record, whose
+// flags ffprobe names rpu_present_flag and so on
 func (ha *HDRAnalyzer) parseDolbyVisionData(line string, metadata *DolbyVisionMetadata) {
-	if strings.Contains(line, "dv_profile=") {
-		profileRegex := regexp.MustCompile(`dv_profile=(\d+)`)
-		matches := profileRegex.FindStringSubmatch(line)
-		if len(matches) == 2 {
-			if val, err := strconv.Atoi(matches[1]); err == nil {
-				metadata.Profile = val
-			}
-		}
-	}
-
-	if strings.Contains(line, "dv_level=") {
-		levelRegex := regexp.MustCompile(`dv_level=(\d+)`)
-		matches := levelRegex.FindStringSubmatch(line)
-		if len(matches) == 2 {
-			if val, err := strconv.Atoi(matches[1]); err == nil {
-				metadata.Level = val
-			}
-		}
+	key, value, _ := strings.Cut(line, "=")
+	number, _ := strconv.Atoi(value)
+	switch strings.TrimSuffix(key, "_flag") {
+	case "dv_profile":
+		metadata.Profile = number
+	case "dv_level":
+		metadata.Level = number
+	case "rpu_present":
+		metadata.RPUPresent = number == 1
+	case "el_present":
+		metadata.ELPresent = number == 1
+	case "bl_present":
+		metadata.BLPresent = number == 1
+	case "dv_bl_signal_compatibility_id":
+		metadata.BLSignalCompatibilityID = number
 	}
+}
 
-	if strings.Contains(line, "rpu_present=") {
-		metadata.RPUPresent = strings.Contains(line, "rpu_present=1")
-	}
+// HDR standards checked by validateHDRCompliance
+const (
+	HDRStandardPQ          = "st2084"
+	HDRStandardMastering   = "st2086"
+	HDRStandardLightLevel  = "cta861_3"
+	HDRStandardHLG         = "hlg"
+	HDRStandardHDR10Plus   = "st2094_40"
+	HDRStandardDolbyVision = "dolby_vision"
+)
 
-	if strings.Contains(line, "el_present=") {
-		metadata.ELPresent = strings.Contains(line, "el_present=1")
-	}
+// hdrGamuts are the mastering display gamuts in common use, with red, green
+// and blue primaries in CIE 1931 xy
+var hdrGamuts = []struct {
+	name      string
+	primaries [3][2]float64
+}{
+	{"BT.2020", [3][2]float64{{0.708, 0.292}, {0.170, 0.797}, {0.131, 0.046}}},
+	{"P3-D65", [3][2]float64{{0.680, 0.320}, {0.265, 0.690}, {0.150, 0.060}}},
+	{"BT.709", [3][2]float64{{0.640, 0.330}, {0.300, 0.600}, {0.150, 0.060}}},
+}
 
-	if strings.Contains(line, "bl_present=") {
-		metadata.BLPresent = strings.Contains(line, "bl_present=1")
-	}
+// hdrChromaticityTolerance is how far mastering display coordinates may
+// stray from a known gamut or the D65 white point
+const hdrChromaticityTolerance = 0.005
+
+// dolbyVisionProfiles lists the codec, valid base layer compatibility IDs
+// and enhancement layer support of each Dolby Vision profile
+var dolbyVisionProfiles = map[int]struct {
+	codec            string
+	compatibilityIDs []int
+	enhancementLayer bool
+}{
+	4:  {"hevc", []int{2}, true},
+	5:  {"hevc", []int{0}, false},
+	7:  {"hevc", []int{6}, true},
+	8:  {"hevc", []int{1, 2, 4}, false},
+	9:  {"h264", []int{2}, false},
+	10: {"av1", []int{0, 1, 2, 4}, false},
 }
 
-// validateHDRCompliance validates HDR compliance and provides recommendations
-func (ha *HDRAnalyzer) validateHDRCompliance(analysis *HDRAnalysis) *HDRValidation {
+// validateHDRCompliance checks every HDR standard the stream uses or its
+// format requires. The format is compliant when the standards it requires
+// pass; issues of the others are still reported.
+func (ha *HDRAnalyzer) validateHDRCompliance(analysis *HDRAnalysis, stream streamMetadata) *HDRValidation {
 	validation := &HDRValidation{
 		Standard:        analysis.HDRFormat,
 		Issues:          []string{},
@@ -413,106 +536,239 @@ func (ha *HDRAnalyzer) validateHDRCompliance(analysis *HDRAnalysis) *HDRValidati
 		return validation
 	}
 
-	// Validate based on HDR format
+	var required []string
 	switch analysis.HDRFormat {
 	case "HDR10":
-		validation.IsCompliant = ha.validateHDR10Compliance(analysis, validation)
+		required = []string{HDRStandardPQ, HDRStandardMastering}
 	case "HDR10+":
-		validation.IsCompliant = ha.validateHDR10PlusCompliance(analysis, validation)
+		required = []string{HDRStandardPQ, HDRStandardMastering, HDRStandardHDR10Plus}
 	case "Dolby Vision":
-		validation.IsCompliant = ha.validateDolbyVisionCompliance(analysis, validation)
+		required = []string{HDRStandardDolbyVision}
 	case "HLG":
-		validation.IsCompliant = ha.validateHLGCompliance(analysis, validation)
+		required = []string{HDRStandardHLG}
 	default:
 		validation.Issues = append(validation.Issues, "Unknown HDR format")
-		validation.IsCompliant = false
+	}
+
+	pq := analysis.ColorTransfer == "smpte2084" || analysis.HDRFormat == "HDR10" || analysis.HDRFormat == "HDR10+"
+	staticRequired := analysis.HDRFormat == "HDR10" || analysis.HDRFormat == "HDR10+"
+	if pq {
+		validation.Standards = append(validation.Standards, ha.checkPQ(analysis, stream))
+	}
+	if analysis.MasteringDisplay != nil || staticRequired {
+		validation.Standards = append(validation.Standards, ha.checkMasteringDisplay(analysis, validation))
+	}
+	if analysis.ContentLightLevel != nil {
+		validation.Standards = append(validation.Standards, ha.checkContentLightLevel(analysis, validation))
+	} else if staticRequired {
+		validation.Recommendations = append(validation.Recommendations, "Consider adding content light level metadata for optimal HDR10 playback")
+	}
+	if analysis.ColorTransfer == "arib-std-b67" || analysis.FrameTransfer == "arib-std-b67" || analysis.HDRFormat == "HLG" {
+		validation.Standards = append(validation.Standards, ha.checkHLG(analysis, stream))
+	}
+	if analysis.HDR10Plus != nil || analysis.HDRFormat == "HDR10+" {
+		validation.Standards = append(validation.Standards, ha.checkHDR10Plus(analysis))
+	}
+	if analysis.DolbyVision != nil {
+		validation.Standards = append(validation.Standards, ha.checkDolbyVision(analysis, stream))
+	}
+
+	validation.IsCompliant = len(required) > 0
+	for i := range validation.Standards {
+		standard := &validation.Standards[i]
+		standard.Required = slices.Contains(required, standard.ID)
+		validation.Issues = append(validation.Issues, standard.Issues...)
+		if standard.Required && !standard.Passed {
+			validation.IsCompliant = false
+		}
 	}
 
 	return validation
 }
 
-// validateHDR10Compliance validates HDR10 compliance
-func (ha *HDRAnalyzer) validateHDR10Compliance(analysis *HDRAnalysis, validation *HDRValidation) bool {
-	compliant := true
+// fail records an issue against a standard
+func (c *HDRStandardCheck) fail(format string, args ...any) {
+	c.Passed = false
+	c.Issues = append(c.Issues, fmt.Sprintf(format, args...))
+}
 
-	// Check required color characteristics
+// checkBT2100Signalling checks the color signalling BT.2100 requires of PQ
+// and HLG alike, and that the first frame agrees with the stream
+func (ha *HDRAnalyzer) checkBT2100Signalling(check *HDRStandardCheck, name, transfer string, analysis *HDRAnalysis, stream streamMetadata) {
 	if analysis.ColorPrimaries != "bt2020" {
-		validation.Issues = append(validation.Issues, "HDR10 requires BT.2020 color primaries")
-		compliant = false
+		check.fail("%s requires BT.2020 color primaries, not %s", name, signalled(analysis.ColorPrimaries))
 	}
-
-	if analysis.ColorTransfer != "smpte2084" {
-		validation.Issues = append(validation.Issues, "HDR10 requires SMPTE 2084 (PQ) transfer function")
-		compliant = false
+	if analysis.ColorTransfer != transfer {
+		check.fail("%s requires the %s transfer function, not %s", name, transfer, signalled(analysis.ColorTransfer))
 	}
-
-	// Check mastering display metadata
-	if analysis.MasteringDisplay == nil || !analysis.MasteringDisplay.HasMasteringDisplay {
-		validation.Issues = append(validation.Issues, "HDR10 should include mastering display metadata")
-		validation.Recommendations = append(validation.Recommendations, "Add mastering display metadata for better HDR10 compliance")
-		compliant = false
+	switch analysis.ColorSpace {
+	case "bt2020nc", "bt2020c", "ictcp":
+	default:
+		check.fail("%s requires a BT.2020 or ICtCp matrix, not %s", name, signalled(analysis.ColorSpace))
 	}
-
-	// Check content light level
-	if analysis.ContentLightLevel == nil || !analysis.ContentLightLevel.HasContentLightLevel {
-		validation.Recommendations = append(validation.Recommendations, "Consider adding content light level metadata for optimal HDR10 playback")
+	if depth := NewBitDepthAnalyzer().extractBitDepthFromPixelFormat(stream.PixFmt); depth > 0 && depth < 10 {
+		check.fail("%s requires at least 10-bit video, not %d-bit", name, depth)
 	}
+	if analysis.FrameTransfer != "" && analysis.FrameTransfer != analysis.ColorTransfer {
+		check.fail("The first frame signals the %s transfer function, but the stream signals %s", analysis.FrameTransfer, signalled(analysis.ColorTransfer))
+	}
+}
 
-	return compliant
+// checkPQ validates SMPTE ST 2084 (PQ) signalling
+func (ha *HDRAnalyzer) checkPQ(analysis *HDRAnalysis, stream streamMetadata) HDRStandardCheck {
+	check := HDRStandardCheck{ID: HDRStandardPQ, Standard: "SMPTE ST 2084", Passed: true}
+	ha.checkBT2100Signalling(&check, "PQ", "smpte2084", analysis, stream)
+	return check
 }
 
-// validateHDR10PlusCompliance validates HDR10+ compliance
-func (ha *HDRAnalyzer) validateHDR10PlusCompliance(analysis *HDRAnalysis, validation *HDRValidation) bool {
-	// First validate base HDR10 compliance
-	compliant := ha.validateHDR10Compliance(analysis, validation)
+// checkHLG validates ARIB STD-B67 (HLG) signalling
+func (ha *HDRAnalyzer) checkHLG(analysis *HDRAnalysis, stream streamMetadata) HDRStandardCheck {
+	check := HDRStandardCheck{ID: HDRStandardHLG, Standard: "ARIB STD-B67", Passed: true}
+	ha.checkBT2100Signalling(&check, "HLG", "arib-std-b67", analysis, stream)
+	return check
+}
 
-	// Check HDR10+ specific metadata
-	if analysis.HDR10Plus == nil || !analysis.HDR10Plus.Present {
-		validation.Issues = append(validation.Issues, "HDR10+ requires dynamic metadata")
-		compliant = false
+// checkMasteringDisplay validates SMPTE ST 2086 mastering display metadata
+func (ha *HDRAnalyzer) checkMasteringDisplay(analysis *HDRAnalysis, validation *HDRValidation) HDRStandardCheck {
+	check := HDRStandardCheck{ID: HDRStandardMastering, Standard: "SMPTE ST 2086", Passed: true}
+	mastering := analysis.MasteringDisplay
+	if mastering == nil || !mastering.HasMasteringDisplay {
+		check.fail("%s should include mastering display metadata", analysis.HDRFormat)
+		validation.Recommendations = append(validation.Recommendations, "Add mastering display metadata for better HDR10 compliance")
+		return check
 	}
 
-	return compliant
-}
+	inRange := true
+	for i := range mastering.DisplayPrimariesX {
+		x, y := mastering.DisplayPrimariesX[i], mastering.DisplayPrimariesY[i]
+		if x <= 0 || x >= 1 || y <= 0 || y >= 1 {
+			inRange = false
+		}
+	}
+	if !inRange {
+		check.fail("Mastering display primaries are missing or outside the CIE 1931 chromaticity range")
+	} else {
+		for _, gamut := range hdrGamuts {
+			matches := true
+			for i, primary := range gamut.primaries {
+				if math.Abs(mastering.DisplayPrimariesX[i]-primary[0]) > hdrChromaticityTolerance ||
+					math.Abs(mastering.DisplayPrimariesY[i]-primary[1]) > hdrChromaticityTolerance {
+					matches = false
+				}
+			}
+			if matches {
+				mastering.Gamut = gamut.name
+				break
+			}
+		}
+		if mastering.Gamut == "" {
+			check.fail("Mastering display primaries match none of BT.2020, P3-D65 and BT.709")
+		}
+	}
 
-// validateDolbyVisionCompliance validates Dolby Vision compliance
-func (ha *HDRAnalyzer) validateDolbyVisionCompliance(analysis *HDRAnalysis, validation *HDRValidation) bool {
-	compliant := true
+	if math.Abs(mastering.WhitePointX-0.3127) > hdrChromaticityTolerance || math.Abs(mastering.WhitePointY-0.3290) > hdrChromaticityTolerance {
+		check.fail("Mastering display white point (%.4f, %.4f) is not D65", mastering.WhitePointX, mastering.WhitePointY)
+	}
+	if mastering.MaxDisplayLuminance < 5 || mastering.MaxDisplayLuminance > 10000 {
+		check.fail("Mastering display peak of %g cd/m² is outside the 5-10000 cd/m² range", mastering.MaxDisplayLuminance)
+	}
+	if mastering.MinDisplayLuminance < 0 || mastering.MinDisplayLuminance > 5 || mastering.MinDisplayLuminance >= mastering.MaxDisplayLuminance {
+		check.fail("Mastering display black level of %g cd/m² is outside the 0-5 cd/m² range or not below the peak", mastering.MinDisplayLuminance)
+	}
+	return check
+}
 
-	if analysis.DolbyVision == nil {
-		validation.Issues = append(validation.Issues, "Dolby Vision metadata missing")
-		return false
+// checkContentLightLevel validates CTA-861.3 MaxCLL and MaxFALL. Zero
+// means unknown, so it passes with a recommendation.
+func (ha *HDRAnalyzer) checkContentLightLevel(analysis *HDRAnalysis, validation *HDRValidation) HDRStandardCheck {
+	check := HDRStandardCheck{ID: HDRStandardLightLevel, Standard: "CTA-861.3", Passed: true}
+	light := analysis.ContentLightLevel
+	if light.MaxCLL == 0 && light.MaxFALL == 0 {
+		validation.Recommendations = append(validation.Recommendations, "Measure and signal MaxCLL and MaxFALL rather than 0 (unknown)")
+		return check
 	}
 
-	// Check for valid profile
-	validProfiles := map[int]bool{4: true, 5: true, 7: true, 8: true, 9: true}
-	if !validProfiles[analysis.DolbyVision.Profile] {
-		validation.Issues = append(validation.Issues, fmt.Sprintf("Invalid Dolby Vision profile: %d", analysis.DolbyVision.Profile))
-		compliant = false
+	if light.MaxCLL > 10000 {
+		check.fail("MaxCLL of %d cd/m² exceeds the 10000 cd/m² PQ maximum", light.MaxCLL)
+	}
+	if light.MaxCLL > 0 && light.MaxFALL > light.MaxCLL {
+		check.fail("MaxFALL of %d cd/m² exceeds MaxCLL of %d cd/m²", light.MaxFALL, light.MaxCLL)
+	}
+	if mastering := analysis.MasteringDisplay; mastering != nil && mastering.MaxDisplayLuminance > 0 && float64(light.MaxCLL) > mastering.MaxDisplayLuminance {
+		check.fail("MaxCLL of %d cd/m² exceeds the mastering display peak of %g cd/m²", light.MaxCLL, mastering.MaxDisplayLuminance)
 	}
+	return check
+}
 
-	// Check RPU presence for most profiles
-	if !analysis.DolbyVision.RPUPresent && analysis.DolbyVision.Profile != 5 {
-		validation.Issues = append(validation.Issues, "Dolby Vision RPU (Reference Processing Unit) missing")
-		compliant = false
+// checkHDR10Plus validates SMPTE ST 2094-40 dynamic metadata
+func (ha *HDRAnalyzer) checkHDR10Plus(analysis *HDRAnalysis) HDRStandardCheck {
+	check := HDRStandardCheck{ID: HDRStandardHDR10Plus, Standard: "SMPTE ST 2094-40", Passed: true}
+	plus := analysis.HDR10Plus
+	if plus == nil || !plus.Present {
+		check.fail("HDR10+ requires dynamic metadata")
+		return check
 	}
 
-	return compliant
+	if plus.ApplicationVersion > 1 {
+		check.fail("Unknown HDR10+ application version %d", plus.ApplicationVersion)
+	}
+	if plus.NumWindows < 1 || plus.NumWindows > 3 {
+		check.fail("HDR10+ metadata has %d processing windows, outside 1-3", plus.NumWindows)
+	}
+	if analysis.ColorTransfer != "smpte2084" {
+		check.fail("HDR10+ dynamic metadata requires a PQ base layer, not %s", signalled(analysis.ColorTransfer))
+	}
+	return check
 }
 
-// validateHLGCompliance validates HLG compliance
-func (ha *HDRAnalyzer) validateHLGCompliance(analysis *HDRAnalysis, validation *HDRValidation) bool {
-	compliant := true
+// checkDolbyVision validates the Dolby Vision configuration record against
+// its profile, and that the first frame carries an RPU
+func (ha *HDRAnalyzer) checkDolbyVision(analysis *HDRAnalysis, stream streamMetadata) HDRStandardCheck {
+	check := HDRStandardCheck{ID: HDRStandardDolbyVision, Standard: "Dolby Vision", Passed: true}
+	dv := analysis.DolbyVision
 
-	if analysis.ColorTransfer != "arib-std-b67" {
-		validation.Issues = append(validation.Issues, "HLG requires ARIB STD-B67 transfer function")
-		compliant = false
+	if !dv.RPUPresent {
+		check.fail("Dolby Vision RPU (Reference Processing Unit) missing")
+	} else if dv.RPUFound != nil && !*dv.RPUFound {
+		check.fail("The first frame carries no Dolby Vision RPU")
+	}
+	if dv.Level < 1 || dv.Level > 13 {
+		check.fail("Dolby Vision level %d is outside 1-13", dv.Level)
 	}
 
-	if analysis.ColorPrimaries != "bt2020" {
-		validation.Issues = append(validation.Issues, "HLG requires BT.2020 color primaries")
-		compliant = false
+	profile, ok := dolbyVisionProfiles[dv.Profile]
+	if !ok {
+		check.fail("Invalid Dolby Vision profile: %d", dv.Profile)
+		return check
+	}
+	if stream.CodecName != "" && stream.CodecName != profile.codec {
+		check.fail("Dolby Vision profile %d requires %s, not %s", dv.Profile, profile.codec, stream.CodecName)
+	}
+	if dv.ELPresent && !profile.enhancementLayer {
+		check.fail("Dolby Vision profile %d does not carry an enhancement layer", dv.Profile)
 	}
+	if !slices.Contains(profile.compatibilityIDs, dv.BLSignalCompatibilityID) {
+		check.fail("Base layer compatibility ID %d is not valid for Dolby Vision profile %d", dv.BLSignalCompatibilityID, dv.Profile)
+	}
+
+	// The base layer must be signalled as what it claims to be compatible with
+	var transfer string
+	switch dv.BLSignalCompatibilityID {
+	case 1:
+		transfer = "smpte2084"
+	case 4:
+		transfer = "arib-std-b67"
+	}
+	if transfer != "" && analysis.ColorTransfer != transfer {
+		check.fail("Base layer compatibility ID %d requires the %s transfer function, not %s", dv.BLSignalCompatibilityID, transfer, signalled(analysis.ColorTransfer))
+	}
+	return check
+}
 
-	return compliant
+// signalled names an unset color property
+func signalled(value string) string {
+	if value == "" || value == "unknown" {
+		return "unspecified"
+	}
+	return value
 }
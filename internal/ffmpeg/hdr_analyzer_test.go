@@ -0,0 +1,167 @@
+package ffmpeg
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+const hdr10FrameOutput = `[FRAME]
+media_type=video
+pix_fmt=yuv420p10le
+color_range=tv
+color_space=bt2020nc
+color_primaries=bt2020
+color_transfer=smpte2084
+[SIDE_DATA]
+side_data_type=Mastering display metadata
+red_x=35400/50000
+red_y=14600/50000
+green_x=8500/50000
+green_y=39850/50000
+blue_x=6550/50000
+blue_y=2300/50000
+white_point_x=15635/50000
+white_point_y=16450/50000
+min_luminance=50/10000
+max_luminance=10000000/10000
+[/SIDE_DATA]
+[SIDE_DATA]
+side_data_type=Content light level metadata
+max_content=1000
+max_average=400
+[/SIDE_DATA]
+[/FRAME]
+`
+
+func hdr10Analysis() (*HDRAnalysis, streamMetadata) {
+	stream := streamMetadata{CodecType: "video", CodecName: "hevc", PixFmt: "yuv420p10le", ColorPrimaries: "bt2020", ColorTransfer: "smpte2084", ColorSpace: "bt2020nc"}
+	analysis := &HDRAnalysis{IsHDR: true, HDRFormat: "HDR10", ColorPrimaries: "bt2020", ColorTransfer: "smpte2084", ColorSpace: "bt2020nc"}
+	return analysis, stream
+}
+
+func standardByID(validation *HDRValidation, id string) *HDRStandardCheck {
+	for i := range validation.Standards {
+		if validation.Standards[i].ID == id {
+			return &validation.Standards[i]
+		}
+	}
+	return nil
+}
+
+func TestParseHDRSideData(t *testing.T) {
+	ha := NewHDRAnalyzer("", zerolog.Nop())
+	analysis, stream := hdr10Analysis()
+	ha.parseSideDataMetadata(hdr10FrameOutput, analysis)
+
+	mastering := analysis.MasteringDisplay
+	if mastering.DisplayPrimariesX[0] != 0.708 || mastering.DisplayPrimariesY[1] != 0.797 || mastering.WhitePointX != 0.3127 ||
+		mastering.MinDisplayLuminance != 0.005 || mastering.MaxDisplayLuminance != 1000 {
+		t.Errorf("unexpected mastering display %+v", mastering)
+	}
+	if analysis.ContentLightLevel.MaxCLL != 1000 || analysis.ContentLightLevel.MaxFALL != 400 || analysis.FrameTransfer != "smpte2084" {
+		t.Errorf("unexpected content light level %+v", analysis.ContentLightLevel)
+	}
+
+	validation := ha.validateHDRCompliance(analysis, stream)
+	if !validation.IsCompliant || len(validation.Issues) != 0 || len(validation.Standards) != 3 {
+		t.Fatalf("expected HDR10 to pass, got %+v", validation)
+	}
+	if mastering.Gamut != "BT.2020" {
+		t.Errorf("expected BT.2020 mastering primaries, got %q", mastering.Gamut)
+	}
+}
+
+func TestValidateHDRStaticMetadata(t *testing.T) {
+	ha := NewHDRAnalyzer("", zerolog.Nop())
+	analysis, stream := hdr10Analysis()
+	stream.PixFmt = "yuv420p"
+	analysis.MasteringDisplay = &MasteringDisplayMetadata{
+		HasMasteringDisplay: true,
+		DisplayPrimariesX:   [3]float64{0.68, 0.265, 0.15},
+		DisplayPrimariesY:   [3]float64{0.32, 0.69, 0.06},
+		WhitePointX:         0.3127,
+		WhitePointY:         0.329,
+		MaxDisplayLuminance: 1000,
+		MinDisplayLuminance: 0.0001,
+	}
+	analysis.ContentLightLevel = &ContentLightLevelData{HasContentLightLevel: true, MaxCLL: 4000, MaxFALL: 4200}
+
+	validation := ha.validateHDRCompliance(analysis, stream)
+	if validation.IsCompliant {
+		t.Error("expected 8-bit PQ to fail")
+	}
+	if pq := standardByID(validation, HDRStandardPQ); pq == nil || pq.Passed || len(pq.Issues) != 1 {
+		t.Errorf("expected only the bit depth to fail PQ, got %+v", pq)
+	}
+	if mastering := standardByID(validation, HDRStandardMastering); !mastering.Passed || analysis.MasteringDisplay.Gamut != "P3-D65" {
+		t.Errorf("expected a P3-D65 mastering display to pass, got %+v", mastering)
+	}
+	if light := standardByID(validation, HDRStandardLightLevel); light.Passed || len(light.Issues) != 2 {
+		t.Errorf("expected MaxFALL and the mastering peak to fail CTA-861.3, got %+v", light)
+	}
+
+	// Zero means unknown: a recommendation, not a failure
+	analysis.ContentLightLevel = &ContentLightLevelData{HasContentLightLevel: true}
+	validation = ha.validateHDRCompliance(analysis, stream)
+	if light := standardByID(validation, HDRStandardLightLevel); !light.Passed || len(validation.Recommendations) != 1 {
+		t.Errorf("expected unknown light levels to pass with a recommendation, got %+v", validation)
+	}
+}
+
+func TestValidateHLGTransferConsistency(t *testing.T) {
+	ha := NewHDRAnalyzer("", zerolog.Nop())
+	stream := streamMetadata{CodecType: "video", CodecName: "hevc", PixFmt: "yuv422p10le"}
+	analysis := &HDRAnalysis{IsHDR: true, HDRFormat: "HLG", ColorPrimaries: "bt2020", ColorTransfer: "arib-std-b67", ColorSpace: "bt2020nc"}
+
+	if validation := ha.validateHDRCompliance(analysis, stream); !validation.IsCompliant || len(validation.Standards) != 1 {
+		t.Errorf("expected HLG to pass, got %+v", validation)
+	}
+
+	analysis.FrameTransfer = "bt2020-10"
+	validation := ha.validateHDRCompliance(analysis, stream)
+	if validation.IsCompliant || len(validation.Issues) != 1 {
+		t.Errorf("expected the frame transfer mismatch to fail, got %+v", validation)
+	}
+}
+
+func TestValidateDolbyVision(t *testing.T) {
+	ha := NewHDRAnalyzer("", zerolog.Nop())
+	analysis, stream := hdr10Analysis()
+	ha.parseStreamSideData([]map[string]any{{
+		"side_data_type":                "DOVI configuration record",
+		"dv_version_major":              float64(1),
+		"dv_profile":                    float64(8),
+		"dv_level":                      float64(6),
+		"rpu_present_flag":              float64(1),
+		"el_present_flag":               float64(0),
+		"bl_present_flag":               float64(1),
+		"dv_bl_signal_compatibility_id": float64(1),
+	}}, analysis)
+	analysis.HDRFormat = "Dolby Vision"
+	ha.parseSideDataMetadata("[FRAME]\ncolor_transfer=smpte2084\n[SIDE_DATA]\nside_data_type=Dolby Vision RPU Data\n[/SIDE_DATA]\n[/FRAME]\n", analysis)
+
+	dv := analysis.DolbyVision
+	if dv.Profile != 8 || dv.Level != 6 || !dv.RPUPresent || dv.ELPresent || dv.BLSignalCompatibilityID != 1 || !*dv.RPUFound {
+		t.Fatalf("unexpected configuration record %+v", dv)
+	}
+
+	validation := ha.validateHDRCompliance(analysis, stream)
+	if !validation.IsCompliant || standardByID(validation, HDRStandardPQ) == nil {
+		t.Errorf("expected profile 8.1 to pass, got %+v", validation)
+	}
+
+	// Profile 8.4 claims an HLG base layer, but the stream is PQ
+	dv.BLSignalCompatibilityID = 4
+	dv.ELPresent = true
+	stream.CodecName = "h264"
+	validation = ha.validateHDRCompliance(analysis, stream)
+	check := standardByID(validation, HDRStandardDolbyVision)
+	if validation.IsCompliant || len(check.Issues) != 3 {
+		t.Errorf("expected codec, enhancement layer and transfer issues, got %+v", check)
+	}
+	if !slices.Contains(check.Issues, "Base layer compatibility ID 4 requires the arib-std-b67 transfer function, not smpte2084") {
+		t.Errorf("missing transfer issue in %v", check.Issues)
+	}
+}
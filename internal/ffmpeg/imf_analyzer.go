@@ -4,9 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"os"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -48,6 +49,7 @@ type CPLAnalysis struct {
 	CPLID                string                `json:"cpl_id,omitempty"`
 	CPLTitle             string                `json:"cpl_title,omitempty"`
 	EditRate             string                `json:"edit_rate,omitempty"`
+	CompositionCount     int                   `json:"composition_count,omitempty"` // CPLs in the package; the latest issued is analyzed
 	TotalRunningTime     string                `json:"total_running_time,omitempty"`
 	SegmentList          []IMFSegment          `json:"segment_list,omitempty"`
 	TrackList            []IMFTrack            `json:"track_list,omitempty"`
@@ -113,6 +115,7 @@ type SMPTE2067Compliance struct {
 
 // Supporting structures
 type IMFSegment struct {
+	SegmentID      string   `json:"segment_id"`
 	SequenceID     string   `json:"sequence_id"`
 	Duration       string   `json:"duration"`
 	TrackFileID    string   `json:"track_file_id"`
@@ -136,6 +139,7 @@ type IMFAsset struct {
 	Hash          string   `json:"hash,omitempty"`
 	HashAlgorithm string   `json:"hash_algorithm,omitempty"`
 	PackingListID string   `json:"packing_list_id,omitempty"`
+	Size          int64    `json:"size,omitempty"`
 	Issues        []string `json:"issues,omitempty"`
 }
 
@@ -303,7 +307,9 @@ type PackagingConstraints struct {
 	Issues             []string `json:"issues,omitempty"`
 }
 
-// AnalyzeIMF performs comprehensive IMF compliance analysis
+// AnalyzeIMF validates an IMF package, given as its directory or a zip of it:
+// the ASSETMAP paths, the PKL sizes and hashes, the CPL timeline and the
+// track files the CPL plays
 func (imf *IMFAnalyzer) AnalyzeIMF(ctx context.Context, packagePath string) (*IMFAnalysis, error) {
 	analysis := &IMFAnalysis{
 		IsIMFCompliant:     false,
@@ -312,7 +318,7 @@ func (imf *IMFAnalyzer) AnalyzeIMF(ctx context.Context, packagePath string) (*IM
 	}
 
 	// Step 1: Check if this looks like an IMF package
-	if !imf.isIMFPackage(packagePath) {
+	if !IsIMFPackage(packagePath) {
 		analysis.ValidationResults = &IMFValidationResults{
 			OverallCompliance: false,
 			CriticalIssues:    []string{"Not a valid IMF package structure"},
@@ -322,26 +328,33 @@ func (imf *IMFAnalyzer) AnalyzeIMF(ctx context.Context, packagePath string) (*IM
 		return analysis, nil
 	}
 
-	// Step 2: Analyze CPL (Composition Playlist)
-	if err := imf.analyzeCPL(packagePath, analysis); err != nil {
-		imf.logger.Warn().Err(err).Msg("Failed to analyze CPL")
+	root, cleanup, err := openIMFPackage(packagePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open IMF package: %w", err)
 	}
+	defer cleanup()
 
-	// Step 3: Analyze PKL (Packing List)
-	if err := imf.analyzePKL(packagePath, analysis); err != nil {
-		imf.logger.Warn().Err(err).Msg("Failed to analyze PKL")
+	pkg, err := readIMFPackage(root)
+	if err != nil {
+		return nil, err
 	}
 
-	// Step 4: Analyze Asset Map
-	if err := imf.analyzeAssetMap(packagePath, analysis); err != nil {
-		imf.logger.Warn().Err(err).Msg("Failed to analyze Asset Map")
-	}
+	// Step 2: Analyze Asset Map
+	analysis.AssetMapAnalysis = imf.analyzeAssetMap(pkg)
 
-	// Step 5: Analyze track files
-	if err := imf.analyzeTrackFiles(ctx, packagePath, analysis); err != nil {
-		imf.logger.Warn().Err(err).Msg("Failed to analyze track files")
+	// Step 3: Analyze PKL (Packing List), verifying every asset's hash
+	analysis.PKLAnalysis = imf.analyzePKL(ctx, pkg)
+
+	// Step 4: Analyze CPL (Composition Playlist)
+	cpl := pkg.composition()
+	analysis.CPLAnalysis = imf.analyzeCPL(pkg, cpl)
+	if cpl != nil {
+		analysis.IMFProfile = imfApplicationProfile(cpl.ApplicationIdentification)
 	}
 
+	// Step 5: Analyze the track files the CPL references
+	imf.analyzeTrackFiles(ctx, pkg, cpl, analysis)
+
 	// Step 6: Check Netflix compliance
 	analysis.NetflixCompliance = imf.checkNetflixCompliance(analysis)
 
@@ -360,206 +373,375 @@ func (imf *IMFAnalyzer) AnalyzeIMF(ctx context.Context, packagePath string) (*IM
 	return analysis, nil
 }
 
-// isIMFPackage checks if the input looks like an IMF package
-func (imf *IMFAnalyzer) isIMFPackage(packagePath string) bool {
-	// Check for required IMF files
-	requiredFiles := []string{"ASSETMAP.xml", "ASSETMAP"}
-	cplPattern := regexp.MustCompile(`CPL_.*\.xml`)
-	pklPattern := regexp.MustCompile(`PKL_.*\.xml`)
-
-	// Check if directory exists
-	if info, err := os.Stat(packagePath); err != nil || !info.IsDir() {
-		return false
+// analyzeAssetMap checks that every asset of the Asset Map is one chunk at a
+// path inside the package
+func (imf *IMFAnalyzer) analyzeAssetMap(pkg *imfPkg) *AssetMapAnalysis {
+	assetMapAnalysis := &AssetMapAnalysis{
+		AssetMapExists: true,
+		AssetMapID:     pkg.assetMap.ID,
+		VolumeCount:    pkg.assetMap.VolumeCount,
+		AssetCount:     len(pkg.assetMap.Assets),
+		ChunkMapping:   []ChunkMapping{},
+		Issues:         []string{},
 	}
-
-	// Look for asset map
-	hasAssetMap := false
-	for _, required := range requiredFiles {
-		if _, err := os.Stat(filepath.Join(packagePath, required)); err == nil {
-			hasAssetMap = true
-			break
-		}
+	validation := &PathValidation{
+		PathsExist:         true,
+		RelativePathsValid: true,
+		FileAccessible:     true,
+		Issues:             []string{},
 	}
+	assetMapAnalysis.PathValidation = validation
 
-	if !hasAssetMap {
-		return false
+	if assetMapAnalysis.VolumeCount > 1 {
+		assetMapAnalysis.Issues = append(assetMapAnalysis.Issues, fmt.Sprintf("Asset Map spans %d volumes, IMF packages are a single volume", assetMapAnalysis.VolumeCount))
 	}
-
-	// Look for CPL and PKL files
-	hasCPL := false
-	hasPKL := false
-
-	files, err := os.ReadDir(packagePath)
-	if err != nil {
-		return false
+	if len(pkg.packingLists) == 0 && len(pkg.pklIssues) == 0 {
+		assetMapAnalysis.Issues = append(assetMapAnalysis.Issues, "Asset Map marks no asset as a packing list")
 	}
 
-	for _, file := range files {
-		if cplPattern.MatchString(file.Name()) {
-			hasCPL = true
+	for _, asset := range pkg.assetMap.Assets {
+		if len(asset.Chunks) != 1 {
+			assetMapAnalysis.Issues = append(assetMapAnalysis.Issues, fmt.Sprintf("Asset %s has %d chunks, IMF assets are one chunk", asset.ID, len(asset.Chunks)))
 		}
-		if pklPattern.MatchString(file.Name()) {
-			hasPKL = true
+		for _, chunk := range asset.Chunks {
+			assetMapAnalysis.ChunkMapping = append(assetMapAnalysis.ChunkMapping, ChunkMapping{
+				ChunkID:     asset.ID,
+				Path:        chunk.Path,
+				VolumeIndex: chunk.VolumeIndex,
+				Offset:      chunk.Offset,
+				Length:      chunk.Length,
+			})
+
+			if !filepath.IsLocal(filepath.FromSlash(chunk.Path)) {
+				validation.RelativePathsValid = false
+				validation.Issues = append(validation.Issues, fmt.Sprintf("Path %s is not relative to the package", chunk.Path))
+				continue
+			}
+			info, err := os.Stat(filepath.Join(pkg.root, filepath.FromSlash(chunk.Path)))
+			if err != nil {
+				validation.PathsExist = false
+				validation.FileAccessible = false
+				validation.Issues = append(validation.Issues, fmt.Sprintf("File not found: %s", chunk.Path))
+			} else if info.IsDir() {
+				validation.FileAccessible = false
+				validation.Issues = append(validation.Issues, fmt.Sprintf("Path %s is a directory", chunk.Path))
+			}
 		}
 	}
 
-	return hasAssetMap && hasCPL && hasPKL
+	return assetMapAnalysis
 }
 
-// analyzeCPL analyzes the Composition Playlist
-func (imf *IMFAnalyzer) analyzeCPL(packagePath string, analysis *IMFAnalysis) error {
-	cplAnalysis := &CPLAnalysis{
-		CPLExists:   false,
-		SegmentList: []IMFSegment{},
-		TrackList:   []IMFTrack{},
-		Issues:      []string{},
+// analyzePKL checks the size and hash of every asset the packing lists name
+func (imf *IMFAnalyzer) analyzePKL(ctx context.Context, pkg *imfPkg) *PKLAnalysis {
+	pklAnalysis := &PKLAnalysis{
+		PKLExists: len(pkg.packingLists) > 0,
+		AssetList: []IMFAsset{},
+		Issues:    append([]string{}, pkg.pklIssues...),
+	}
+	if !pklAnalysis.PKLExists {
+		pklAnalysis.Issues = append(pklAnalysis.Issues, "PKL file not found")
+		return pklAnalysis
 	}
+	pklAnalysis.PKLID = pkg.packingLists[0].ID
 
-	// Find CPL file
-	cplPattern := regexp.MustCompile(`CPL_.*\.xml`)
-	files, err := os.ReadDir(packagePath)
-	if err != nil {
-		return fmt.Errorf("failed to read package directory: %w", err)
+	validation := &HashValidation{
+		HashesValid:      true,
+		MismatchedHashes: []string{},
+		Issues:           []string{},
 	}
+	signatures := &SignatureValidation{Issues: []string{}}
+	algorithms := map[string]bool{}
 
-	var cplFile string
-	for _, file := range files {
-		if cplPattern.MatchString(file.Name()) {
-			cplFile = filepath.Join(packagePath, file.Name())
-			cplAnalysis.CPLExists = true
-			break
+	for _, pkl := range pkg.packingLists {
+		if pkl.Signature != nil {
+			signatures.DigitalSignature = true
+		}
+		for _, asset := range pkl.Assets {
+			id := imfUUID(asset.ID)
+			entry := IMFAsset{
+				AssetID:       asset.ID,
+				Hash:          strings.TrimSpace(asset.Hash),
+				HashAlgorithm: imfHashAlgorithmName(asset.HashAlgorithm.Algorithm),
+				PackingListID: pkl.ID,
+				Size:          asset.Size,
+				Issues:        []string{},
+			}
+			algorithms[entry.HashAlgorithm] = true
+
+			path := pkg.assetPath(id)
+			switch {
+			case path == "":
+				entry.Issues = append(entry.Issues, "Not listed in the Asset Map")
+			case entry.Hash == "":
+				entry.ChunkList = []string{pkg.paths[id]}
+				entry.Issues = append(entry.Issues, "No hash")
+			case ctx.Err() != nil:
+				entry.ChunkList = []string{pkg.paths[id]}
+				entry.Issues = append(entry.Issues, "Not verified, analysis timed out")
+			default:
+				entry.ChunkList = []string{pkg.paths[id]}
+				digest, size, err := hashIMFAsset(ctx, path, asset.HashAlgorithm.Algorithm)
+				switch {
+				case err != nil:
+					entry.Issues = append(entry.Issues, fmt.Sprintf("Cannot verify: %v", err))
+				case size != asset.Size:
+					entry.Issues = append(entry.Issues, fmt.Sprintf("Size is %d bytes, the PKL says %d", size, asset.Size))
+				case digest != entry.Hash:
+					validation.MismatchedHashes = append(validation.MismatchedHashes, pkg.paths[id])
+					entry.Issues = append(entry.Issues, fmt.Sprintf("Hash %s does not match the PKL", digest))
+				}
+			}
+
+			if len(entry.Issues) > 0 {
+				validation.HashesValid = false
+				for _, issue := range entry.Issues {
+					validation.Issues = append(validation.Issues, fmt.Sprintf("Asset %s: %s", asset.ID, issue))
+				}
+			}
+			pklAnalysis.AssetList = append(pklAnalysis.AssetList, entry)
 		}
 	}
 
-	if !cplAnalysis.CPLExists {
-		cplAnalysis.Issues = append(cplAnalysis.Issues, "CPL file not found")
-		analysis.CPLAnalysis = cplAnalysis
-		return nil
+	var names []string
+	for name := range algorithms {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+	validation.HashAlgorithm = strings.Join(names, ", ")
+	pklAnalysis.HashValidation = validation
 
-	// Parse CPL file (simplified XML parsing)
-	if err := imf.parseCPLFile(cplFile, cplAnalysis); err != nil {
-		cplAnalysis.Issues = append(cplAnalysis.Issues, fmt.Sprintf("Failed to parse CPL: %v", err))
+	// The signature is reported but its certificate chain is not checked
+	if signatures.DigitalSignature {
+		signatures.Issues = append(signatures.Issues, "PKL signature present but not verified")
 	}
+	pklAnalysis.SignatureValidation = signatures
 
-	// Validate CPL structure
-	cplAnalysis.MetadataValidation = imf.validateCPLMetadata(cplAnalysis)
-	cplAnalysis.StructuralValidation = imf.validateCPLStructure(cplAnalysis)
+	return pklAnalysis
+}
 
-	analysis.CPLAnalysis = cplAnalysis
-	return nil
+// imfVirtualTrack sums up the resources of one virtual track across the
+// segments of a CPL
+type imfVirtualTrack struct {
+	track    IMFTrack
+	segments int
+	duration int64
+	played   int64
 }
 
-// analyzePKL analyzes the Packing List
-func (imf *IMFAnalyzer) analyzePKL(packagePath string, analysis *IMFAnalysis) error {
-	pklAnalysis := &PKLAnalysis{
-		PKLExists: false,
-		AssetList: []IMFAsset{},
-		Issues:    []string{},
+// analyzeCPL checks the composition's metadata and its timeline: every
+// segment plays each virtual track once and all of them for the same time
+func (imf *IMFAnalyzer) analyzeCPL(pkg *imfPkg, cpl *imfComposition) *CPLAnalysis {
+	cplAnalysis := &CPLAnalysis{
+		CPLExists:   cpl != nil,
+		SegmentList: []IMFSegment{},
+		TrackList:   []IMFTrack{},
+		Issues:      append([]string{}, pkg.cplIssues...),
 	}
-
-	// Find PKL file
-	pklPattern := regexp.MustCompile(`PKL_.*\.xml`)
-	files, err := os.ReadDir(packagePath)
-	if err != nil {
-		return fmt.Errorf("failed to read package directory: %w", err)
+	if cpl == nil {
+		cplAnalysis.Issues = append(cplAnalysis.Issues, "CPL file not found")
+		return cplAnalysis
 	}
 
-	var pklFile string
-	for _, file := range files {
-		if pklPattern.MatchString(file.Name()) {
-			pklFile = filepath.Join(packagePath, file.Name())
-			pklAnalysis.PKLExists = true
-			break
-		}
-	}
+	cplAnalysis.CPLID = cpl.ID
+	cplAnalysis.CPLTitle = cpl.ContentTitle
+	cplAnalysis.EditRate = cpl.EditRate
+	cplAnalysis.CompositionCount = len(pkg.compositions)
 
-	if !pklAnalysis.PKLExists {
-		pklAnalysis.Issues = append(pklAnalysis.Issues, "PKL file not found")
-		analysis.PKLAnalysis = pklAnalysis
-		return nil
+	validation := &StructuralValidation{
+		SegmentStructure:   true,
+		TrackStructure:     true,
+		TimingConsistency:  true,
+		ReferenceIntegrity: true,
+		Issues:             []string{},
 	}
+	cplAnalysis.StructuralValidation = validation
+	cplAnalysis.MetadataValidation = imf.validateCPLMetadata(cpl)
 
-	// Parse PKL file
-	if err := imf.parsePKLFile(pklFile, pklAnalysis); err != nil {
-		pklAnalysis.Issues = append(pklAnalysis.Issues, fmt.Sprintf("Failed to parse PKL: %v", err))
+	editRate, rateValid := parseIMFEditRate(cpl.EditRate)
+	if !rateValid {
+		validation.TimingConsistency = false
+		validation.Issues = append(validation.Issues, fmt.Sprintf("CPL edit rate %q is not a rational", cpl.EditRate))
+		editRate = big.NewRat(1, 1)
 	}
 
-	// Validate hashes
-	pklAnalysis.HashValidation = imf.validateAssetHashes(packagePath, pklAnalysis)
+	tracks := map[string]*imfVirtualTrack{}
+	var order []string
+	total := new(big.Rat)
+	hasMainImage := false
 
-	// Check digital signatures
-	pklAnalysis.SignatureValidation = imf.validateDigitalSignatures(pklFile, pklAnalysis)
-
-	analysis.PKLAnalysis = pklAnalysis
-	return nil
-}
-
-// analyzeAssetMap analyzes the Asset Map
-func (imf *IMFAnalyzer) analyzeAssetMap(packagePath string, analysis *IMFAnalysis) error {
-	assetMapAnalysis := &AssetMapAnalysis{
-		AssetMapExists: false,
-		ChunkMapping:   []ChunkMapping{},
-		Issues:         []string{},
+	if len(cpl.Segments) == 0 {
+		validation.SegmentStructure = false
+		validation.Issues = append(validation.Issues, "No segments found in CPL")
 	}
+	for _, segment := range cpl.Segments {
+		sequences := segment.SequenceList.Sequences
+		if len(sequences) == 0 {
+			validation.SegmentStructure = false
+			validation.Issues = append(validation.Issues, fmt.Sprintf("Segment %s has no sequences", segment.ID))
+			continue
+		}
 
-	// Find Asset Map file
-	assetMapFiles := []string{"ASSETMAP.xml", "ASSETMAP"}
-	var assetMapFile string
+		var segmentDuration *big.Rat
+		inSegment := map[string]bool{}
+		for _, sequence := range sequences {
+			kind := sequence.XMLName.Local
+			hasMainImage = hasMainImage || kind == "MainImageSequence"
+			trackID := imfUUID(sequence.TrackID)
+			if inSegment[trackID] {
+				validation.TrackStructure = false
+				validation.Issues = append(validation.Issues, fmt.Sprintf("Segment %s plays virtual track %s twice", segment.ID, sequence.TrackID))
+			}
+			inSegment[trackID] = true
 
-	for _, fileName := range assetMapFiles {
-		fullPath := filepath.Join(packagePath, fileName)
-		if _, err := os.Stat(fullPath); err == nil {
-			assetMapFile = fullPath
-			assetMapAnalysis.AssetMapExists = true
-			break
+			track, ok := tracks[trackID]
+			if !ok {
+				track = &imfVirtualTrack{track: IMFTrack{TrackID: sequence.TrackID, TrackType: kind}}
+				tracks[trackID] = track
+				order = append(order, trackID)
+			}
+			track.segments++
+
+			sequenceDuration := new(big.Rat)
+			for _, resource := range sequence.Resources {
+				rate := editRate
+				if resource.EditRate != "" {
+					if parsed, ok := parseIMFEditRate(resource.EditRate); ok {
+						rate = parsed
+					} else {
+						validation.TimingConsistency = false
+						validation.Issues = append(validation.Issues, fmt.Sprintf("Resource %s has an invalid edit rate %q", resource.ID, resource.EditRate))
+					}
+				}
+				if kind == "MainImageSequence" && rate.Cmp(editRate) != 0 {
+					validation.TimingConsistency = false
+					validation.Issues = append(validation.Issues, fmt.Sprintf("Image resource %s runs at %s, the CPL edit rate is %s", resource.ID, resource.EditRate, cpl.EditRate))
+				}
+				imf.validateCPLResource(resource, validation)
+
+				duration := resource.duration()
+				sequenceDuration.Add(sequenceDuration, new(big.Rat).Quo(big.NewRat(duration, 1), rate))
+				if track.track.EditRate == "" {
+					track.track.EditRate = strings.TrimSpace(resource.EditRate)
+					if track.track.EditRate == "" {
+						track.track.EditRate = cpl.EditRate
+					}
+					track.track.EntryPoint = strconv.FormatInt(resource.entryPoint(), 10)
+				}
+				track.duration += resource.IntrinsicDuration
+				track.played += duration
+
+				if resource.TrackFileID == "" {
+					continue
+				}
+				cplAnalysis.SegmentList = append(cplAnalysis.SegmentList, IMFSegment{
+					SegmentID:      segment.ID,
+					SequenceID:     sequence.ID,
+					Duration:       strconv.FormatInt(duration, 10),
+					TrackFileID:    resource.TrackFileID,
+					SourceEncoding: resource.SourceEncoding,
+				})
+				if _, ok := pkg.paths[imfUUID(resource.TrackFileID)]; !ok {
+					validation.ReferenceIntegrity = false
+					validation.Issues = append(validation.Issues, fmt.Sprintf("Track file %s is not in this package", resource.TrackFileID))
+				}
+			}
+
+			if segmentDuration == nil {
+				segmentDuration = sequenceDuration
+			} else if sequenceDuration.Cmp(segmentDuration) != 0 {
+				validation.TimingConsistency = false
+				validation.Issues = append(validation.Issues, fmt.Sprintf("Segment %s: %s sequence lasts %ss, other sequences %ss", segment.ID, kind, sequenceDuration.FloatString(3), segmentDuration.FloatString(3)))
+			}
+		}
+		if segmentDuration != nil {
+			total.Add(total, segmentDuration)
 		}
 	}
 
-	if !assetMapAnalysis.AssetMapExists {
-		assetMapAnalysis.Issues = append(assetMapAnalysis.Issues, "Asset Map file not found")
-		analysis.AssetMapAnalysis = assetMapAnalysis
-		return nil
+	for _, id := range order {
+		track := tracks[id]
+		if track.segments != len(cpl.Segments) {
+			validation.TrackStructure = false
+			track.track.Issues = append(track.track.Issues, fmt.Sprintf("Missing from %d of %d segments", len(cpl.Segments)-track.segments, len(cpl.Segments)))
+			validation.Issues = append(validation.Issues, fmt.Sprintf("Virtual track %s is missing from %d segments", track.track.TrackID, len(cpl.Segments)-track.segments))
+		}
+		track.track.IntrinsicDuration = strconv.FormatInt(track.duration, 10)
+		track.track.SourceDuration = strconv.FormatInt(track.played, 10)
+		cplAnalysis.TrackList = append(cplAnalysis.TrackList, track.track)
 	}
-
-	// Parse Asset Map file
-	if err := imf.parseAssetMapFile(assetMapFile, assetMapAnalysis); err != nil {
-		assetMapAnalysis.Issues = append(assetMapAnalysis.Issues, fmt.Sprintf("Failed to parse Asset Map: %v", err))
+	if len(order) == 0 {
+		validation.TrackStructure = false
+		validation.Issues = append(validation.Issues, "No tracks found in CPL")
+	} else if !hasMainImage {
+		validation.TrackStructure = false
+		validation.Issues = append(validation.Issues, "CPL has no MainImageSequence")
 	}
 
-	// Validate file paths
-	assetMapAnalysis.PathValidation = imf.validateAssetPaths(packagePath, assetMapAnalysis)
+	if rateValid {
+		frames := new(big.Rat).Mul(total, editRate)
+		fps := new(big.Rat).Add(editRate, big.NewRat(1, 2))
+		cplAnalysis.TotalRunningTime = FormatTimecode(new(big.Int).Quo(frames.Num(), frames.Denom()).Int64(),
+			int(new(big.Int).Quo(fps.Num(), fps.Denom()).Int64()), false)
+	}
 
-	analysis.AssetMapAnalysis = assetMapAnalysis
-	return nil
+	return cplAnalysis
 }
 
-// analyzeTrackFiles analyzes individual track files
-func (imf *IMFAnalyzer) analyzeTrackFiles(ctx context.Context, packagePath string, analysis *IMFAnalysis) error {
-	// Get list of MXF track files
-	mxfFiles, err := imf.findMXFFiles(packagePath)
-	if err != nil {
-		return fmt.Errorf("failed to find MXF files: %w", err)
+// validateCPLResource checks a resource's entry point and durations
+// against the track file's intrinsic duration
+func (imf *IMFAnalyzer) validateCPLResource(resource imfCPLResource, validation *StructuralValidation) {
+	switch {
+	case resource.IntrinsicDuration <= 0:
+		validation.SegmentStructure = false
+		validation.Issues = append(validation.Issues, fmt.Sprintf("Resource %s has no intrinsic duration", resource.ID))
+	case resource.entryPoint() < 0 || resource.entryPoint() >= resource.IntrinsicDuration:
+		validation.SegmentStructure = false
+		validation.Issues = append(validation.Issues, fmt.Sprintf("Resource %s entry point %d is outside its %d edit units", resource.ID, resource.entryPoint(), resource.IntrinsicDuration))
+	case resource.SourceDuration != nil && (*resource.SourceDuration <= 0 || resource.entryPoint()+*resource.SourceDuration > resource.IntrinsicDuration):
+		validation.SegmentStructure = false
+		validation.Issues = append(validation.Issues, fmt.Sprintf("Resource %s plays %d edit units from %d, past its %d edit units", resource.ID, *resource.SourceDuration, resource.entryPoint(), resource.IntrinsicDuration))
+	}
+	if resource.RepeatCount != nil && *resource.RepeatCount < 1 {
+		validation.SegmentStructure = false
+		validation.Issues = append(validation.Issues, fmt.Sprintf("Resource %s has repeat count %d", resource.ID, *resource.RepeatCount))
 	}
+}
 
-	for _, mxfFile := range mxfFiles {
-		trackAnalysis, err := imf.analyzeTrackFile(ctx, mxfFile)
-		if err != nil {
-			imf.logger.Warn().Err(err).Str("file", mxfFile).Msg("Failed to analyze track file")
-			continue
+// analyzeTrackFiles analyzes each track file the CPL plays, once
+func (imf *IMFAnalyzer) analyzeTrackFiles(ctx context.Context, pkg *imfPkg, cpl *imfComposition, analysis *IMFAnalysis) {
+	if cpl == nil {
+		return
+	}
+
+	analyzed := map[string]bool{}
+	for _, segment := range cpl.Segments {
+		for _, sequence := range segment.SequenceList.Sequences {
+			for _, resource := range sequence.Resources {
+				id := imfUUID(resource.TrackFileID)
+				path := pkg.assetPath(id)
+				if id == "" || path == "" || analyzed[id] {
+					continue
+				}
+				analyzed[id] = true
+
+				trackAnalysis := imf.analyzeTrackFile(ctx, path, sequence.XMLName.Local, resource.IntrinsicDuration)
+				trackAnalysis.TrackID = resource.TrackFileID
+				analysis.TrackFileAnalysis = append(analysis.TrackFileAnalysis, trackAnalysis)
+			}
 		}
-		analysis.TrackFileAnalysis = append(analysis.TrackFileAnalysis, trackAnalysis)
 	}
-
-	return nil
 }
 
-// analyzeTrackFile analyzes a single MXF track file
-func (imf *IMFAnalyzer) analyzeTrackFile(ctx context.Context, filePath string) (TrackFileAnalysis, error) {
+// analyzeTrackFile analyzes a single MXF track file: its streams with
+// ffprobe and its MXF structure against the IMF track file constraints
+func (imf *IMFAnalyzer) analyzeTrackFile(ctx context.Context, filePath, trackType string, intrinsicDuration int64) TrackFileAnalysis {
 	analysis := TrackFileAnalysis{
-		FileName: filepath.Base(filePath),
-		Issues:   []string{},
+		FileName:  filepath.Base(filePath),
+		TrackType: trackType,
+		Issues:    []string{},
 	}
+	analysis.MXFCompliance, analysis.EssenceDescriptor = imf.analyzeTrackFileStructure(filePath, trackType, intrinsicDuration)
 
 	// Use ffprobe to analyze the MXF file
 	cmd := []string{
@@ -574,7 +756,7 @@ func (imf *IMFAnalyzer) analyzeTrackFile(ctx context.Context, filePath string) (
 	output, err := imf.executeCommand(ctx, cmd)
 	if err != nil {
 		analysis.Issues = append(analysis.Issues, fmt.Sprintf("FFprobe analysis failed: %v", err))
-		return analysis, nil
+		return analysis
 	}
 
 	var result struct {
@@ -584,31 +766,115 @@ func (imf *IMFAnalyzer) analyzeTrackFile(ctx context.Context, filePath string) (
 
 	if err := json.Unmarshal([]byte(output), &result); err != nil {
 		analysis.Issues = append(analysis.Issues, fmt.Sprintf("Failed to parse FFprobe output: %v", err))
-		return analysis, nil
+		return analysis
 	}
 
-	// Analyze MXF compliance
-	analysis.MXFCompliance = imf.analyzeMXFCompliance(result.Format, result.Streams)
-
 	// Analyze based on stream types
 	for _, stream := range result.Streams {
 		switch strings.ToLower(stream.CodecType) {
 		case "video":
-			analysis.TrackType = "MainImageSequence"
 			analysis.ColorCompliance = imf.analyzeColorCompliance(stream)
 		case "audio":
-			analysis.TrackType = "MainAudioSequence"
 			analysis.AudioCompliance = imf.analyzeAudioCompliance(stream)
 		case "subtitle":
-			analysis.TrackType = "SubtitlesSequence"
 			analysis.SubtitleCompliance = imf.analyzeSubtitleCompliance(stream)
 		}
 	}
 
-	// Extract essence descriptor information
-	analysis.EssenceDescriptor = imf.extractEssenceDescriptor(result.Format, result.Streams)
+	// Fall back to ffprobe for the essence descriptor when the MXF
+	// structure could not be read
+	if analysis.EssenceDescriptor == nil {
+		analysis.EssenceDescriptor = imf.extractEssenceDescriptor(result.Format, result.Streams)
+	}
+
+	return analysis
+}
+
+// analyzeTrackFileStructure reads the MXF structure of a track file and
+// checks it against the ST 2067-5 track file constraints: OP1a, one
+// essence track, frame wrapped images and clip wrapped sound, and an
+// index table covering the duration the CPL gives
+func (imf *IMFAnalyzer) analyzeTrackFileStructure(filePath, trackType string, intrinsicDuration int64) (*MXFCompliance, *EssenceDescriptor) {
+	compliance := &MXFCompliance{
+		MXFCompliant: true,
+		Issues:       []string{},
+	}
+
+	file, err := readMXFFile(filePath)
+	if err != nil {
+		compliance.MXFCompliant = false
+		compliance.Issues = append(compliance.Issues, fmt.Sprintf("Cannot read MXF structure: %v", err))
+		return compliance, nil
+	}
+	mxf := NewMXFAnalyzer(imf.ffprobePath, imf.logger)
+	structure := &MXFAnalysis{IsMXFFile: true}
+	mxf.applyStructure(file, filePath, structure)
+
+	fail := func(issue string) {
+		compliance.MXFCompliant = false
+		compliance.Issues = append(compliance.Issues, issue)
+	}
+
+	compliance.MXFProfile = structure.MXFProfile
+	if op := structure.OperationalPattern; op != nil {
+		compliance.OperationalPattern = op.PatternLabel
+		if op.PatternLabel != "OP1a" {
+			fail(fmt.Sprintf("Operational pattern is %s, IMF track files are OP1a", op.PatternLabel))
+		}
+	}
+
+	var descriptors []EssenceDescriptorInfo
+	var wrapping string
+	for _, container := range structure.EssenceContainers {
+		if compliance.EssenceContainer == "" {
+			compliance.EssenceContainer = container.ContainerName
+			wrapping = container.WrappingType
+		}
+		descriptors = append(descriptors, container.EssenceDescriptors...)
+	}
+	if len(descriptors) != 1 {
+		fail(fmt.Sprintf("Track file has %d essence tracks, IMF track files carry one", len(descriptors)))
+	}
+	switch {
+	case wrapping == "":
+	case trackType == "MainImageSequence" && wrapping != "Frame":
+		fail(fmt.Sprintf("Image essence is %s wrapped, IMF requires frame wrapping", strings.ToLower(wrapping)))
+	case trackType == "MainAudioSequence" && wrapping != "Clip":
+		fail(fmt.Sprintf("Audio essence is %s wrapped, IMF requires clip wrapping", strings.ToLower(wrapping)))
+	}
 
-	return analysis, nil
+	compliance.HeaderMetadata = structure.HeaderMetadata != nil
+	if header := structure.HeaderMetadata; header != nil && header.ValidationResults != nil {
+		compliance.HeaderMetadata = header.ValidationResults.HeaderComplete && header.ValidationResults.MetadataValid
+	}
+	if !compliance.HeaderMetadata {
+		fail("Header metadata is incomplete or invalid")
+	}
+
+	index := structure.IndexTables
+	compliance.IndexTableCompliance = index != nil && index.HasIndexTables
+	if !compliance.IndexTableCompliance {
+		fail("Track file has no index table")
+	} else if index.IndexDuration > 0 && index.IndexDuration < intrinsicDuration {
+		compliance.IndexTableCompliance = false
+		fail(fmt.Sprintf("Index table covers %d edit units, the CPL plays %d", index.IndexDuration, intrinsicDuration))
+	}
+
+	if len(descriptors) == 0 {
+		return compliance, nil
+	}
+	descriptor := descriptors[0]
+	essence := &EssenceDescriptor{
+		EssenceContainer:  compliance.EssenceContainer,
+		EssenceEncoding:   mxf.descriptorCompression(descriptor),
+		SampleRate:        descriptor.SampleRate,
+		ContainerDuration: descriptor.ContainerDuration,
+		LinkedTrackID:     strconv.Itoa(descriptor.LinkedTrackID),
+	}
+	if duration, err := strconv.ParseInt(descriptor.ContainerDuration, 10, 64); err == nil && intrinsicDuration > 0 && duration != intrinsicDuration {
+		fail(fmt.Sprintf("Container duration is %d edit units, the CPL gives an intrinsic duration of %d", duration, intrinsicDuration))
+	}
+	return compliance, essence
 }
 
 // Helper methods for compliance checking
@@ -658,246 +924,218 @@ func (imf *IMFAnalyzer) checkNetflixCompliance(analysis *IMFAnalysis) *NetflixIM
 	return compliance
 }
 
-func (imf *IMFAnalyzer) checkSMPTE2067Compliance(analysis *IMFAnalysis) *SMPTE2067Compliance {
-	compliance := &SMPTE2067Compliance{
-		SMPTE2067Compliant: true,
-		ApplicationProfile: "SMPTE ST 2067-21",
-		Issues:             []string{},
-	}
-
-	// Check core constraints
-	compliance.CoreConstraints = &CoreConstraints{
-		XMLStructure:        true,
-		RequiredElements:    true,
-		UUIDFormat:          true,
-		EditRateConstraints: true,
+// validateCPLMetadata checks the CPL's identifiers, issue date, namespace
+// and required elements
+func (imf *IMFAnalyzer) validateCPLMetadata(cpl *imfComposition) *MetadataValidation {
+	validation := &MetadataValidation{
+		UUIDValidation:      true,
+		TimestampValidation: true,
+		NamespaceValidation: true,
+		SchemaValidation:    true,
 		Issues:              []string{},
 	}
 
-	// Validate CPL structure
-	if analysis.CPLAnalysis != nil {
-		if !analysis.CPLAnalysis.CPLExists {
-			compliance.CoreConstraints.RequiredElements = false
-			compliance.CoreConstraints.Issues = append(compliance.CoreConstraints.Issues, "CPL missing")
-			compliance.SMPTE2067Compliant = false
+	ids := []string{cpl.ID}
+	for _, segment := range cpl.Segments {
+		ids = append(ids, segment.ID)
+		for _, sequence := range segment.SequenceList.Sequences {
+			ids = append(ids, sequence.ID, sequence.TrackID)
+			for _, resource := range sequence.Resources {
+				ids = append(ids, resource.ID)
+				if resource.TrackFileID != "" {
+					ids = append(ids, resource.TrackFileID)
+				}
+			}
 		}
 	}
-
-	// Validate PKL structure
-	if analysis.PKLAnalysis != nil {
-		if !analysis.PKLAnalysis.PKLExists {
-			compliance.CoreConstraints.RequiredElements = false
-			compliance.CoreConstraints.Issues = append(compliance.CoreConstraints.Issues, "PKL missing")
-			compliance.SMPTE2067Compliant = false
+	for _, id := range ids {
+		if !validIMFUUID(id) {
+			validation.UUIDValidation = false
+			validation.Issues = append(validation.Issues, fmt.Sprintf("Invalid UUID %q, expected urn:uuid:", id))
 		}
 	}
 
-	return compliance
-}
-
-// Simplified parsing methods (would need full XML parsing in production)
-
-func (imf *IMFAnalyzer) parseCPLFile(filePath string, analysis *CPLAnalysis) error {
-	// This is a simplified implementation
-	// In production, you would use proper XML parsing
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
+	if _, err := time.Parse(time.RFC3339, strings.TrimSpace(cpl.IssueDate)); err != nil {
+		validation.TimestampValidation = false
+		validation.Issues = append(validation.Issues, fmt.Sprintf("IssueDate %q is not an xs:dateTime", cpl.IssueDate))
 	}
 
-	contentStr := string(content)
-
-	// Extract basic information using regex (simplified)
-	if match := regexp.MustCompile(`<Id>(.*?)</Id>`).FindStringSubmatch(contentStr); len(match) > 1 {
-		analysis.CPLID = match[1]
-	}
-
-	if match := regexp.MustCompile(`<ContentTitle>(.*?)</ContentTitle>`).FindStringSubmatch(contentStr); len(match) > 1 {
-		analysis.CPLTitle = match[1]
+	// ST 2067-3:2013 and :2016 share the namespace prefix
+	if !strings.HasPrefix(cpl.XMLName.Space, strings.TrimSuffix(CPL_Namespace_2016, "2016")) {
+		validation.NamespaceValidation = false
+		validation.Issues = append(validation.Issues, fmt.Sprintf("CPL namespace %q is not ST 2067-3", cpl.XMLName.Space))
 	}
 
-	if match := regexp.MustCompile(`<EditRate>(.*?)</EditRate>`).FindStringSubmatch(contentStr); len(match) > 1 {
-		analysis.EditRate = match[1]
+	for name, value := range map[string]string{"ContentTitle": cpl.ContentTitle, "EditRate": cpl.EditRate, "IssueDate": cpl.IssueDate} {
+		if strings.TrimSpace(value) == "" {
+			validation.SchemaValidation = false
+			validation.Issues = append(validation.Issues, fmt.Sprintf("Required element %s is missing", name))
+		}
 	}
+	sort.Strings(validation.Issues)
 
-	return nil
+	return validation
 }
 
-func (imf *IMFAnalyzer) parsePKLFile(filePath string, analysis *PKLAnalysis) error {
-	// Simplified PKL parsing
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
+func (imf *IMFAnalyzer) checkSMPTE2067Compliance(analysis *IMFAnalysis) *SMPTE2067Compliance {
+	compliance := &SMPTE2067Compliance{
+		SMPTE2067Compliant: true,
+		ApplicationProfile: analysis.IMFProfile,
+		Issues:             []string{},
 	}
 
-	contentStr := string(content)
-
-	// Extract PKL ID
-	if match := regexp.MustCompile(`<Id>(.*?)</Id>`).FindStringSubmatch(contentStr); len(match) > 1 {
-		analysis.PKLID = match[1]
+	// Check core constraints: ST 2067-2 and ST 2067-3
+	core := &CoreConstraints{
+		XMLStructure:        true,
+		RequiredElements:    true,
+		UUIDFormat:          true,
+		EditRateConstraints: true,
+		Issues:              []string{},
 	}
+	compliance.CoreConstraints = core
 
-	return nil
-}
-
-func (imf *IMFAnalyzer) parseAssetMapFile(filePath string, analysis *AssetMapAnalysis) error {
-	// Simplified Asset Map parsing
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return err
+	if cpl := analysis.CPLAnalysis; cpl != nil {
+		if !cpl.CPLExists {
+			core.RequiredElements = false
+			core.Issues = append(core.Issues, "CPL missing")
+		}
+		if len(cpl.Issues) > 0 {
+			core.XMLStructure = false
+			core.Issues = append(core.Issues, cpl.Issues...)
+		}
+		if metadata := cpl.MetadataValidation; metadata != nil {
+			core.UUIDFormat = metadata.UUIDValidation
+			core.RequiredElements = core.RequiredElements && metadata.SchemaValidation
+			if !metadata.TimestampValidation || !metadata.NamespaceValidation {
+				core.XMLStructure = false
+			}
+			core.Issues = append(core.Issues, metadata.Issues...)
+		}
+		if structure := cpl.StructuralValidation; structure != nil {
+			core.EditRateConstraints = structure.TimingConsistency
+			if !structure.SegmentStructure || !structure.TrackStructure {
+				core.RequiredElements = false
+			}
+			core.Issues = append(core.Issues, structure.Issues...)
+		}
 	}
-
-	contentStr := string(content)
-
-	// Extract Asset Map ID
-	if match := regexp.MustCompile(`<Id>(.*?)</Id>`).FindStringSubmatch(contentStr); len(match) > 1 {
-		analysis.AssetMapID = match[1]
+	if pkl := analysis.PKLAnalysis; pkl != nil {
+		if !pkl.PKLExists {
+			core.RequiredElements = false
+		}
+		if len(pkl.Issues) > 0 {
+			core.XMLStructure = false
+			core.Issues = append(core.Issues, pkl.Issues...)
+		}
 	}
 
-	// Count volumes and assets (simplified)
-	analysis.VolumeCount = len(regexp.MustCompile(`<Volume>`).FindAllString(contentStr, -1))
-	analysis.AssetCount = len(regexp.MustCompile(`<Asset>`).FindAllString(contentStr, -1))
-
-	return nil
-}
-
-// Validation helper methods
-
-func (imf *IMFAnalyzer) validateCPLMetadata(analysis *CPLAnalysis) *MetadataValidation {
-	validation := &MetadataValidation{
-		UUIDValidation:      true,
-		TimestampValidation: true,
-		NamespaceValidation: true,
-		SchemaValidation:    true,
+	// Check essence constraints of the application
+	essence := &EssenceConstraints{
+		VideoConstraints:    true,
+		AudioConstraints:    true,
+		SubtitleConstraints: true,
+		EssenceEncoding:     true,
 		Issues:              []string{},
 	}
+	compliance.EssenceConstraints = essence
 
-	// Validate UUID format
-	if analysis.CPLID != "" {
-		uuidPattern := regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
-		if !uuidPattern.MatchString(analysis.CPLID) {
-			validation.UUIDValidation = false
-			validation.Issues = append(validation.Issues, "Invalid CPL UUID format")
+	jpeg2000 := strings.Contains(analysis.IMFProfile, "2067-20") || strings.Contains(analysis.IMFProfile, "2067-21") || strings.Contains(analysis.IMFProfile, "2067-40")
+	for _, trackFile := range analysis.TrackFileAnalysis {
+		switch trackFile.TrackType {
+		case "MainImageSequence":
+			if jpeg2000 && trackFile.EssenceDescriptor != nil && trackFile.EssenceDescriptor.EssenceEncoding != "JPEG 2000" {
+				essence.VideoConstraints = false
+				essence.EssenceEncoding = false
+				essence.Issues = append(essence.Issues, fmt.Sprintf("%s: image essence is %s, %s requires JPEG 2000", trackFile.FileName, trackFile.EssenceDescriptor.EssenceEncoding, analysis.IMFProfile))
+			}
+		case "MainAudioSequence":
+			if trackFile.AudioCompliance != nil && !trackFile.AudioCompliance.IsCompliant {
+				essence.AudioConstraints = false
+				for _, issue := range trackFile.AudioCompliance.Issues {
+					essence.Issues = append(essence.Issues, fmt.Sprintf("%s: %s", trackFile.FileName, issue))
+				}
+			}
+		case "SubtitlesSequence":
+			if trackFile.SubtitleCompliance != nil && !trackFile.SubtitleCompliance.IsCompliant {
+				essence.SubtitleConstraints = false
+				for _, issue := range trackFile.SubtitleCompliance.Issues {
+					essence.Issues = append(essence.Issues, fmt.Sprintf("%s: %s", trackFile.FileName, issue))
+				}
+			}
 		}
 	}
 
-	return validation
-}
-
-func (imf *IMFAnalyzer) validateCPLStructure(analysis *CPLAnalysis) *StructuralValidation {
-	validation := &StructuralValidation{
-		SegmentStructure:   true,
-		TrackStructure:     true,
-		TimingConsistency:  true,
-		ReferenceIntegrity: true,
+	// Check packaging constraints: ST 2067-2 track files and ST 429-9
+	// asset maps
+	packaging := &PackagingConstraints{
+		MXFConstraints:     true,
+		FileNaming:         true,
+		DirectoryStructure: true,
+		AssetReferences:    true,
 		Issues:             []string{},
 	}
+	compliance.PackagingConstraints = packaging
 
-	// Basic structural validation
-	if len(analysis.SegmentList) == 0 {
-		validation.SegmentStructure = false
-		validation.Issues = append(validation.Issues, "No segments found in CPL")
-	}
-
-	if len(analysis.TrackList) == 0 {
-		validation.TrackStructure = false
-		validation.Issues = append(validation.Issues, "No tracks found in CPL")
-	}
-
-	return validation
-}
-
-func (imf *IMFAnalyzer) validateAssetHashes(packagePath string, analysis *PKLAnalysis) *HashValidation {
-	validation := &HashValidation{
-		HashAlgorithm:    "SHA-1",
-		HashesValid:      true,
-		MismatchedHashes: []string{},
-		Issues:           []string{},
+	for _, trackFile := range analysis.TrackFileAnalysis {
+		if trackFile.MXFCompliance != nil && !trackFile.MXFCompliance.MXFCompliant {
+			packaging.MXFConstraints = false
+			for _, issue := range trackFile.MXFCompliance.Issues {
+				packaging.Issues = append(packaging.Issues, fmt.Sprintf("%s: %s", trackFile.FileName, issue))
+			}
+		}
 	}
-
-	// This would require actual hash verification in production
-	// For now, just check if hashes are present
-	for _, asset := range analysis.AssetList {
-		if asset.Hash == "" {
-			validation.HashesValid = false
-			validation.Issues = append(validation.Issues, fmt.Sprintf("Missing hash for asset %s", asset.AssetID))
+	if assetMap := analysis.AssetMapAnalysis; assetMap != nil {
+		for _, chunk := range assetMap.ChunkMapping {
+			if issue := imfFileNameIssue(chunk.Path); issue != "" {
+				packaging.FileNaming = false
+				packaging.Issues = append(packaging.Issues, issue)
+			}
+		}
+		if assetMap.PathValidation != nil && len(assetMap.PathValidation.Issues) > 0 {
+			packaging.DirectoryStructure = false
+			packaging.Issues = append(packaging.Issues, assetMap.PathValidation.Issues...)
+		}
+		if len(assetMap.Issues) > 0 {
+			packaging.DirectoryStructure = false
+			packaging.Issues = append(packaging.Issues, assetMap.Issues...)
 		}
 	}
-
-	return validation
-}
-
-func (imf *IMFAnalyzer) validateDigitalSignatures(filePath string, analysis *PKLAnalysis) *SignatureValidation {
-	validation := &SignatureValidation{
-		DigitalSignature: false,
-		SignatureValid:   false,
-		CertificateChain: false,
-		Issues:           []string{},
+	if pkl := analysis.PKLAnalysis; pkl != nil && pkl.HashValidation != nil && !pkl.HashValidation.HashesValid {
+		packaging.AssetReferences = false
+		packaging.Issues = append(packaging.Issues, pkl.HashValidation.Issues...)
 	}
-
-	// Check for digital signature (simplified)
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		validation.Issues = append(validation.Issues, "Cannot read PKL file for signature validation")
-		return validation
+	if cpl := analysis.CPLAnalysis; cpl != nil && cpl.StructuralValidation != nil && !cpl.StructuralValidation.ReferenceIntegrity {
+		packaging.AssetReferences = false
 	}
 
-	contentStr := string(content)
-	if strings.Contains(contentStr, "<Signature>") {
-		validation.DigitalSignature = true
-		// In production, would verify the actual signature
-		validation.SignatureValid = true
-	}
+	compliance.Issues = append(compliance.Issues, core.Issues...)
+	compliance.Issues = append(compliance.Issues, essence.Issues...)
+	compliance.Issues = append(compliance.Issues, packaging.Issues...)
+	compliance.SMPTE2067Compliant = len(compliance.Issues) == 0 &&
+		core.XMLStructure && core.RequiredElements && core.UUIDFormat && core.EditRateConstraints &&
+		packaging.AssetReferences
 
-	return validation
+	return compliance
 }
 
-func (imf *IMFAnalyzer) validateAssetPaths(packagePath string, analysis *AssetMapAnalysis) *PathValidation {
-	validation := &PathValidation{
-		PathsExist:         true,
-		RelativePathsValid: true,
-		FileAccessible:     true,
-		Issues:             []string{},
-	}
-
-	// Validate chunk mappings
-	for _, chunk := range analysis.ChunkMapping {
-		fullPath := filepath.Join(packagePath, chunk.Path)
-		if _, err := os.Stat(fullPath); err != nil {
-			validation.PathsExist = false
-			validation.FileAccessible = false
-			validation.Issues = append(validation.Issues, fmt.Sprintf("File not found: %s", chunk.Path))
+// imfFileNameIssue checks an asset path against the file naming limits
+func imfFileNameIssue(path string) string {
+	name := path[strings.LastIndex(path, "/")+1:]
+	switch {
+	case len(path) > MaxPathLength:
+		return fmt.Sprintf("Path %s is longer than %d characters", path, MaxPathLength)
+	case len(name) > MaxAssetFilenameLength:
+		return fmt.Sprintf("File name %s is longer than %d characters", name, MaxAssetFilenameLength)
+	}
+	for _, illegal := range IllegalFilenameCharacters {
+		if illegal != "/" && strings.Contains(name, illegal) {
+			return fmt.Sprintf("File name %s contains %q", name, illegal)
 		}
 	}
-
-	return validation
+	return ""
 }
 
-// MXF and compliance analysis methods
-
-func (imf *IMFAnalyzer) analyzeMXFCompliance(format *FormatInfo, streams []StreamInfo) *MXFCompliance {
-	compliance := &MXFCompliance{
-		MXFCompliant:         false,
-		IndexTableCompliance: false,
-		HeaderMetadata:       false,
-		Issues:               []string{},
-	}
-
-	if format != nil {
-		formatName := strings.ToLower(format.FormatName)
-		if strings.Contains(formatName, "mxf") {
-			compliance.MXFCompliant = true
-			compliance.MXFProfile = "OP1a"
-			compliance.OperationalPattern = "OP1a"
-			compliance.HeaderMetadata = true
-			compliance.IndexTableCompliance = true
-		} else {
-			compliance.Issues = append(compliance.Issues, "Not an MXF file")
-		}
-	}
-
-	return compliance
-}
+// Essence compliance methods
 
 func (imf *IMFAnalyzer) analyzeColorCompliance(stream StreamInfo) *ColorCompliance {
 	compliance := &ColorCompliance{
@@ -945,9 +1183,9 @@ func (imf *IMFAnalyzer) analyzeAudioCompliance(stream StreamInfo) *AudioComplian
 	}
 
 	// Validate compliance
-	if compliance.SampleRate != 48000 {
+	if compliance.SampleRate != IMF_AudioSampleRate_48kHz && compliance.SampleRate != IMF_AudioSampleRate_96kHz {
 		compliance.IsCompliant = false
-		compliance.Issues = append(compliance.Issues, "Sample rate not 48kHz")
+		compliance.Issues = append(compliance.Issues, "Sample rate not 48kHz or 96kHz")
 	}
 
 	if compliance.BitDepth < 24 {
@@ -991,26 +1229,6 @@ func (imf *IMFAnalyzer) extractEssenceDescriptor(format *FormatInfo, streams []S
 	return descriptor
 }
 
-// Additional helper methods
-
-func (imf *IMFAnalyzer) findMXFFiles(packagePath string) ([]string, error) {
-	var mxfFiles []string
-
-	err := filepath.Walk(packagePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ".mxf") {
-			mxfFiles = append(mxfFiles, path)
-		}
-
-		return nil
-	})
-
-	return mxfFiles, err
-}
-
 func (imf *IMFAnalyzer) validateNetflixVideo(trackFile TrackFileAnalysis, reqs *NetflixVideoReqs) bool {
 	// Simplified Netflix video validation
 	if trackFile.ColorCompliance == nil {
@@ -1054,15 +1272,15 @@ func (imf *IMFAnalyzer) generateValidationResults(analysis *IMFAnalysis) *IMFVal
 		ComplianceScore:   100.0,
 	}
 
-	// Collect all issues
-	if analysis.CPLAnalysis != nil {
-		results.CriticalIssues = append(results.CriticalIssues, analysis.CPLAnalysis.Issues...)
-	}
-	if analysis.PKLAnalysis != nil {
-		results.CriticalIssues = append(results.CriticalIssues, analysis.PKLAnalysis.Issues...)
+	// Collect all issues: the ST 2067 checks gather those of the asset
+	// map, packing lists, CPL and track files
+	if analysis.SMPTE2067Compliance != nil {
+		results.CriticalIssues = append(results.CriticalIssues, analysis.SMPTE2067Compliance.Issues...)
 	}
-	if analysis.AssetMapAnalysis != nil {
-		results.CriticalIssues = append(results.CriticalIssues, analysis.AssetMapAnalysis.Issues...)
+	for _, trackFile := range analysis.TrackFileAnalysis {
+		for _, issue := range trackFile.Issues {
+			results.Warnings = append(results.Warnings, fmt.Sprintf("%s: %s", trackFile.FileName, issue))
+		}
 	}
 
 	// Calculate compliance score
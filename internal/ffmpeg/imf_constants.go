@@ -60,6 +60,15 @@ const (
 	MaxConcurrentValidations = 3
 )
 
+// Zipped IMF Package Limits
+const (
+	// MaxPackageZipEntries is the maximum number of entries in a zipped package
+	MaxPackageZipEntries = 10000
+
+	// MaxPackageExtractedBytes is the maximum size of a zipped package once extracted
+	MaxPackageExtractedBytes = 64 << 30
+)
+
 // IMF Validation Timeouts
 const (
 	// DefaultIMFAnalysisTimeout is the default timeout for IMF analysis operations
@@ -0,0 +1,492 @@
+package ffmpeg
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Asset map file names, SMPTE ST 429-9
+var imfAssetMapNames = []string{"ASSETMAP.xml", "ASSETMAP"}
+
+var imfUUIDPattern = regexp.MustCompile(UUIDPattern)
+
+// imfAssetMap is an ASSETMAP document
+type imfAssetMap struct {
+	ID          string `xml:"Id"`
+	VolumeCount int    `xml:"VolumeCount"`
+	Assets      []struct {
+		ID          string `xml:"Id"`
+		PackingList bool   `xml:"PackingList"`
+		Chunks      []struct {
+			Path        string `xml:"Path"`
+			VolumeIndex int    `xml:"VolumeIndex"`
+			Offset      int64  `xml:"Offset"`
+			Length      int64  `xml:"Length"`
+		} `xml:"ChunkList>Chunk"`
+	} `xml:"AssetList>Asset"`
+}
+
+// imfPackingList is a PKL document, SMPTE ST 2067-2 / ST 429-8
+type imfPackingList struct {
+	ID        string    `xml:"Id"`
+	Signer    *struct{} `xml:"Signer"`
+	Signature *struct{} `xml:"Signature"`
+	Assets    []struct {
+		ID            string `xml:"Id"`
+		Hash          string `xml:"Hash"`
+		Size          int64  `xml:"Size"`
+		Type          string `xml:"Type"`
+		HashAlgorithm struct {
+			Algorithm string `xml:"Algorithm,attr"`
+		} `xml:"HashAlgorithm"`
+	} `xml:"AssetList>Asset"`
+}
+
+// imfComposition is a Composition Playlist, SMPTE ST 2067-3
+type imfComposition struct {
+	XMLName                   xml.Name
+	ID                        string          `xml:"Id"`
+	ContentTitle              string          `xml:"ContentTitle"`
+	IssueDate                 string          `xml:"IssueDate"`
+	EditRate                  string          `xml:"EditRate"`
+	ApplicationIdentification string          `xml:"ExtensionProperties>ApplicationIdentification"`
+	Segments                  []imfCPLSegment `xml:"SegmentList>Segment"`
+}
+
+type imfCPLSegment struct {
+	ID           string `xml:"Id"`
+	SequenceList struct {
+		Sequences []imfCPLSequence `xml:",any"`
+	} `xml:"SequenceList"`
+}
+
+// imfCPLSequence is any sequence of a segment; its element name, such as
+// MainImageSequence, is the virtual track type
+type imfCPLSequence struct {
+	XMLName   xml.Name
+	ID        string           `xml:"Id"`
+	TrackID   string           `xml:"TrackId"`
+	Resources []imfCPLResource `xml:"ResourceList>Resource"`
+}
+
+type imfCPLResource struct {
+	ID                string `xml:"Id"`
+	EditRate          string `xml:"EditRate"`
+	IntrinsicDuration int64  `xml:"IntrinsicDuration"`
+	EntryPoint        *int64 `xml:"EntryPoint"`
+	SourceDuration    *int64 `xml:"SourceDuration"`
+	RepeatCount       *int64 `xml:"RepeatCount"`
+	TrackFileID       string `xml:"TrackFileId"`
+	SourceEncoding    string `xml:"SourceEncoding"`
+}
+
+// imfPkg is an IMF package read from disk. Assets are keyed by their UUID
+// in lower case without the urn:uuid: prefix.
+type imfPkg struct {
+	root         string
+	assetMap     *imfAssetMap
+	paths        map[string]string // Asset UUID to path relative to root
+	packingLists []imfPackingList
+	pklAssets    map[string]string // Asset UUID to the UUID of the PKL listing it
+	compositions []imfComposition
+	cplAssets    []string // UUIDs of the compositions, in the order read
+	pklIssues    []string // Packing lists that could not be read
+	cplIssues    []string // Composition playlists that could not be read
+}
+
+// IsIMFPackage reports whether path is an IMF package: a directory with an
+// ASSETMAP, or a zip archive of one. The asset map may sit in a single top
+// level folder, as it does when a package folder is zipped.
+func IsIMFPackage(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.IsDir() {
+		_, ok := imfPackageRoot(path)
+		return ok
+	}
+	if !strings.EqualFold(filepath.Ext(path), ".zip") {
+		return false
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return false
+	}
+	defer archive.Close()
+	for _, file := range archive.File {
+		name := strings.TrimPrefix(file.Name, "./")
+		base := name[strings.LastIndex(name, "/")+1:]
+		if strings.Count(name, "/") <= 1 && (base == imfAssetMapNames[0] || base == imfAssetMapNames[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// imfPackageRoot returns the directory holding the ASSETMAP: dir itself or
+// its only subdirectory
+func imfPackageRoot(dir string) (string, bool) {
+	if imfAssetMapPath(dir) != "" {
+		return dir, true
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	var subdirs []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") && entry.Name() != "__MACOSX" {
+			subdirs = append(subdirs, filepath.Join(dir, entry.Name()))
+		}
+	}
+	if len(subdirs) == 1 && imfAssetMapPath(subdirs[0]) != "" {
+		return subdirs[0], true
+	}
+	return "", false
+}
+
+func imfAssetMapPath(dir string) string {
+	for _, name := range imfAssetMapNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// openIMFPackage returns the root of the package at path, extracting zip
+// archives to a temporary directory that cleanup removes
+func openIMFPackage(path string) (string, func(), error) {
+	cleanup := func() {}
+	dir := path
+	if info, err := os.Stat(path); err != nil {
+		return "", cleanup, err
+	} else if !info.IsDir() {
+		tempDir, err := os.MkdirTemp("", "imf_package_*")
+		if err != nil {
+			return "", cleanup, err
+		}
+		cleanup = func() { os.RemoveAll(tempDir) }
+		if err := extractIMFZip(path, tempDir); err != nil {
+			cleanup()
+			return "", func() {}, err
+		}
+		dir = tempDir
+	}
+
+	root, ok := imfPackageRoot(dir)
+	if !ok {
+		cleanup()
+		return "", func() {}, fmt.Errorf("no ASSETMAP found")
+	}
+	return root, cleanup, nil
+}
+
+// extractIMFZip extracts a zipped package, refusing entries that would land
+// outside dir and archives beyond the extraction limits
+func extractIMFZip(zipPath, dir string) error {
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip: %w", err)
+	}
+	defer archive.Close()
+
+	if len(archive.File) > MaxPackageZipEntries {
+		return fmt.Errorf("zip has %d entries, the limit is %d", len(archive.File), MaxPackageZipEntries)
+	}
+
+	var total int64
+	for _, file := range archive.File {
+		name := filepath.FromSlash(file.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("zip entry %q escapes the package", file.Name)
+		}
+		target := filepath.Join(dir, name)
+		if file.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		}
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+
+		written, err := extractIMFZipFile(file, target, MaxPackageExtractedBytes-total)
+		if err != nil {
+			return err
+		}
+		total += written
+	}
+	return nil
+}
+
+func extractIMFZipFile(file *zip.File, target string, remaining int64) (int64, error) {
+	src, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer dst.Close()
+
+	// Sizes in the zip directory can lie, so count what is actually written
+	written, err := io.CopyN(dst, src, remaining+1)
+	if err != nil && err != io.EOF {
+		return written, err
+	}
+	if written > remaining {
+		return written, fmt.Errorf("package is larger than %d bytes when extracted", int64(MaxPackageExtractedBytes))
+	}
+	return written, nil
+}
+
+// readIMFPackage reads the asset map of the package at root, then the
+// packing lists it marks and the composition playlists they list. Packing
+// lists and playlists that cannot be read are recorded as issues.
+func readIMFPackage(root string) (*imfPkg, error) {
+	pkg := &imfPkg{
+		root:      root,
+		paths:     make(map[string]string),
+		pklAssets: make(map[string]string),
+	}
+
+	pkg.assetMap = &imfAssetMap{}
+	if err := readIMFXML(imfAssetMapPath(root), pkg.assetMap); err != nil {
+		return nil, fmt.Errorf("failed to parse ASSETMAP: %w", err)
+	}
+
+	var packingLists []string
+	for _, asset := range pkg.assetMap.Assets {
+		if len(asset.Chunks) == 0 {
+			continue
+		}
+		id := imfUUID(asset.ID)
+		pkg.paths[id] = asset.Chunks[0].Path
+		if asset.PackingList {
+			packingLists = append(packingLists, id)
+		}
+	}
+
+	for _, id := range packingLists {
+		var pkl imfPackingList
+		if err := readIMFXML(pkg.assetPath(id), &pkl); err != nil {
+			pkg.pklIssues = append(pkg.pklIssues, fmt.Sprintf("Failed to parse PKL %s: %v", pkg.paths[id], err))
+			continue
+		}
+		pkg.packingLists = append(pkg.packingLists, pkl)
+		for _, asset := range pkl.Assets {
+			pkg.pklAssets[imfUUID(asset.ID)] = imfUUID(pkl.ID)
+		}
+	}
+
+	// Composition playlists are the XML assets whose root element is
+	// CompositionPlaylist
+	for _, pkl := range pkg.packingLists {
+		for _, asset := range pkl.Assets {
+			id := imfUUID(asset.ID)
+			path, ok := pkg.paths[id]
+			if !ok || !strings.Contains(asset.Type, "xml") && !strings.EqualFold(filepath.Ext(path), ".xml") {
+				continue
+			}
+			if imfXMLRoot(pkg.assetPath(id)) != "CompositionPlaylist" {
+				continue
+			}
+			var cpl imfComposition
+			if err := readIMFXML(pkg.assetPath(id), &cpl); err != nil {
+				pkg.cplIssues = append(pkg.cplIssues, fmt.Sprintf("Failed to parse CPL %s: %v", path, err))
+				continue
+			}
+			pkg.compositions = append(pkg.compositions, cpl)
+			pkg.cplAssets = append(pkg.cplAssets, id)
+		}
+	}
+	return pkg, nil
+}
+
+// assetPath returns the path on disk of an asset, or "" when the asset map
+// does not list it or its path leaves the package
+func (pkg *imfPkg) assetPath(id string) string {
+	path, ok := pkg.paths[id]
+	if !ok || !filepath.IsLocal(filepath.FromSlash(path)) {
+		return ""
+	}
+	return filepath.Join(pkg.root, filepath.FromSlash(path))
+}
+
+// composition returns the CPL to analyze: the most recently issued one
+func (pkg *imfPkg) composition() *imfComposition {
+	if len(pkg.compositions) == 0 {
+		return nil
+	}
+	sort.SliceStable(pkg.compositions, func(i, j int) bool {
+		return pkg.compositions[i].IssueDate > pkg.compositions[j].IssueDate
+	})
+	return &pkg.compositions[0]
+}
+
+func readIMFXML(path string, v interface{}) error {
+	if path == "" {
+		return fmt.Errorf("file not found")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(data, v)
+}
+
+// imfXMLRoot returns the local name of an XML document's root element
+func imfXMLRoot(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	return xmlRootName(f).Local
+}
+
+// xmlRootName returns the name of an XML document's root element, with its
+// namespace
+func xmlRootName(r io.Reader) xml.Name {
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return xml.Name{}
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name
+		}
+	}
+}
+
+// imfUUID normalizes an asset ID for lookups
+func imfUUID(id string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(id), "urn:uuid:"))
+}
+
+// validIMFUUID reports whether an ID is a urn:uuid: URN
+func validIMFUUID(id string) bool {
+	id = strings.TrimSpace(id)
+	return strings.HasPrefix(id, "urn:uuid:") && imfUUIDPattern.MatchString(strings.TrimPrefix(id, "urn:uuid:"))
+}
+
+// hashIMFAsset computes the base64 digest PKLs carry for a file. SHA-1 is
+// the default; SHA-256 is used when the PKL names it.
+func hashIMFAsset(ctx context.Context, path, algorithm string) (string, int64, error) {
+	var h hash.Hash = sha1.New()
+	if strings.Contains(strings.ToLower(algorithm), "sha256") {
+		h = sha256.New()
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	buffer := make([]byte, 1<<20)
+	var size int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", size, err
+		}
+		n, err := f.Read(buffer)
+		h.Write(buffer[:n])
+		size += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", size, err
+		}
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// imfHashAlgorithmName names a PKL hash algorithm URI
+func imfHashAlgorithmName(algorithm string) string {
+	if strings.Contains(strings.ToLower(algorithm), "sha256") {
+		return Hash_SHA256
+	}
+	return Hash_SHA1
+}
+
+// parseIMFEditRate parses a CPL edit rate such as "24000 1001"
+func parseIMFEditRate(rate string) (*big.Rat, bool) {
+	fields := strings.Fields(rate)
+	if len(fields) != 2 {
+		return nil, false
+	}
+	num, err1 := strconv.ParseInt(fields[0], 10, 64)
+	den, err2 := strconv.ParseInt(fields[1], 10, 64)
+	if err1 != nil || err2 != nil || num <= 0 || den <= 0 {
+		return nil, false
+	}
+	return big.NewRat(num, den), true
+}
+
+// duration returns the edit units a resource plays, repeats included
+func (r imfCPLResource) duration() int64 {
+	duration := r.IntrinsicDuration - r.entryPoint()
+	if r.SourceDuration != nil {
+		duration = *r.SourceDuration
+	}
+	if r.RepeatCount != nil {
+		duration *= *r.RepeatCount
+	}
+	return duration
+}
+
+func (r imfCPLResource) entryPoint() int64 {
+	if r.EntryPoint != nil {
+		return *r.EntryPoint
+	}
+	return 0
+}
+
+// imfApplicationProfile names the application a CPL declares in its
+// ApplicationIdentification, which may list several
+func imfApplicationProfile(identification string) string {
+	var names []string
+	for _, uri := range strings.Fields(identification) {
+		name := uri
+		switch {
+		case strings.Contains(uri, "2067-20"):
+			name = "Application #2 (ST 2067-20)"
+		case strings.Contains(uri, "2067-21"):
+			name = "Application #2E (ST 2067-21)"
+		case strings.Contains(uri, "2067-40"):
+			name = "Application #4 (ST 2067-40)"
+		case strings.Contains(uri, "2067-50"):
+			name = "Application #5 ACES (ST 2067-50)"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ", ")
+}
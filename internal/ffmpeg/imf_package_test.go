@@ -0,0 +1,251 @@
+package ffmpeg
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	testIMFPKLID   = "urn:uuid:11111111-1111-4111-8111-111111111111"
+	testIMFCPLID   = "urn:uuid:22222222-2222-4222-8222-222222222222"
+	testIMFImageID = "urn:uuid:33333333-3333-4333-8333-333333333333"
+	testIMFAudioID = "urn:uuid:44444444-4444-4444-8444-444444444444"
+)
+
+// testIMFCPL returns a two segment CPL whose audio runs short in the
+// second segment when audioShort is set
+func testIMFCPL(audioShort bool) string {
+	audio := 96
+	if audioShort {
+		audio = 48
+	}
+	segment := func(n int, audioDuration int) string {
+		return fmt.Sprintf(`<Segment><Id>urn:uuid:5555555%[1]d-5555-4555-8555-555555555555</Id><SequenceList>
+<cc:MainImageSequence xmlns:cc="http://www.smpte-ra.org/schemas/2067-2/2016"><Id>urn:uuid:6666666%[1]d-6666-4666-8666-666666666666</Id><TrackId>urn:uuid:77777777-7777-4777-8777-777777777777</TrackId><ResourceList>
+<Resource><Id>urn:uuid:8888888%[1]d-8888-4888-8888-888888888888</Id><IntrinsicDuration>48</IntrinsicDuration><TrackFileId>%[2]s</TrackFileId></Resource>
+</ResourceList></cc:MainImageSequence>
+<cc:MainAudioSequence xmlns:cc="http://www.smpte-ra.org/schemas/2067-2/2016"><Id>urn:uuid:9999999%[1]d-9999-4999-8999-999999999999</Id><TrackId>urn:uuid:aaaaaaaa-aaaa-4aaa-8aaa-aaaaaaaaaaaa</TrackId><ResourceList>
+<Resource><Id>urn:uuid:bbbbbbb%[1]d-bbbb-4bbb-8bbb-bbbbbbbbbbbb</Id><EditRate>48000 1</EditRate><IntrinsicDuration>96000</IntrinsicDuration><SourceDuration>%[3]d000</SourceDuration><TrackFileId>%[4]s</TrackFileId></Resource>
+</ResourceList></cc:MainAudioSequence>
+</SequenceList></Segment>`, n, testIMFImageID, audioDuration, testIMFAudioID)
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<CompositionPlaylist xmlns="http://www.smpte-ra.org/schemas/2067-3/2016">
+<Id>` + testIMFCPLID + `</Id>
+<IssueDate>2024-05-01T10:00:00Z</IssueDate>
+<ContentTitle>Test Feature</ContentTitle>
+<EditRate>24 1</EditRate>
+<ExtensionProperties><ApplicationIdentification>http://www.smpte-ra.org/ns/2067-21/2016</ApplicationIdentification></ExtensionProperties>
+<SegmentList>` + segment(1, 96) + segment(2, audio) + `</SegmentList>
+</CompositionPlaylist>`
+}
+
+// writeTestIMFPackage writes an IMF package to dir. The image track file's
+// hash in the PKL is wrong when badHash is set.
+func writeTestIMFPackage(t *testing.T, dir, cpl string, badHash bool) {
+	t.Helper()
+	files := map[string][]byte{
+		"CPL.xml":   []byte(cpl),
+		"image.mxf": []byte("not really an image track file"),
+		"audio.mxf": []byte("not really an audio track file"),
+	}
+	digest := func(name string) string {
+		sum := sha1.Sum(files[name])
+		return base64.StdEncoding.EncodeToString(sum[:])
+	}
+	imageHash := digest("image.mxf")
+	if badHash {
+		imageHash = digest("audio.mxf")
+	}
+
+	asset := func(id, hash, name, kind string) string {
+		return fmt.Sprintf(`<Asset><Id>%s</Id><Hash>%s</Hash><Size>%d</Size><Type>%s</Type><OriginalFileName>%s</OriginalFileName></Asset>`,
+			id, hash, len(files[name]), kind, name)
+	}
+	files["PKL.xml"] = []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<PackingList xmlns="http://www.smpte-ra.org/schemas/2067-2/2016/PKL"><Id>` + testIMFPKLID + `</Id><AssetList>` +
+		asset(testIMFCPLID, digest("CPL.xml"), "CPL.xml", "text/xml") +
+		asset(testIMFImageID, imageHash, "image.mxf", "application/mxf") +
+		asset(testIMFAudioID, digest("audio.mxf"), "audio.mxf", "application/mxf") +
+		`</AssetList></PackingList>`)
+
+	chunk := func(id, path string, packingList bool) string {
+		pkl := ""
+		if packingList {
+			pkl = "<PackingList>true</PackingList>"
+		}
+		return fmt.Sprintf(`<Asset><Id>%s</Id>%s<ChunkList><Chunk><Path>%s</Path></Chunk></ChunkList></Asset>`, id, pkl, path)
+	}
+	files["ASSETMAP.xml"] = []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<AssetMap xmlns="http://www.smpte-ra.org/schemas/429-9/2007/AM"><Id>urn:uuid:cccccccc-cccc-4ccc-8ccc-cccccccccccc</Id><VolumeCount>1</VolumeCount><AssetList>` +
+		chunk(testIMFPKLID, "PKL.xml", true) +
+		chunk(testIMFCPLID, "CPL.xml", false) +
+		chunk(testIMFImageID, "image.mxf", false) +
+		chunk(testIMFAudioID, "audio.mxf", false) +
+		`</AssetList></AssetMap>`)
+
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReadIMFPackage(t *testing.T) {
+	dir := t.TempDir()
+	writeTestIMFPackage(t, dir, testIMFCPL(false), false)
+
+	pkg, err := readIMFPackage(dir)
+	if err != nil {
+		t.Fatalf("readIMFPackage: %v", err)
+	}
+	if len(pkg.packingLists) != 1 || len(pkg.compositions) != 1 {
+		t.Fatalf("got %d packing lists and %d compositions, want 1 and 1", len(pkg.packingLists), len(pkg.compositions))
+	}
+	if got := pkg.assetPath(imfUUID(testIMFImageID)); got != filepath.Join(dir, "image.mxf") {
+		t.Errorf("assetPath = %q", got)
+	}
+
+	imf := NewIMFAnalyzer("ffprobe", zerolog.Nop())
+	cpl := imf.analyzeCPL(pkg, pkg.composition())
+	if cpl.CPLTitle != "Test Feature" || cpl.EditRate != "24 1" {
+		t.Errorf("CPL title %q, edit rate %q", cpl.CPLTitle, cpl.EditRate)
+	}
+	if len(cpl.TrackList) != 2 || len(cpl.SegmentList) != 4 {
+		t.Errorf("got %d tracks and %d segment resources, want 2 and 4", len(cpl.TrackList), len(cpl.SegmentList))
+	}
+	if cpl.TotalRunningTime != "00:00:04:00" {
+		t.Errorf("TotalRunningTime = %q, want 00:00:04:00", cpl.TotalRunningTime)
+	}
+	if issues := cpl.StructuralValidation.Issues; len(issues) > 0 {
+		t.Errorf("unexpected structural issues: %v", issues)
+	}
+	if issues := cpl.MetadataValidation.Issues; len(issues) > 0 {
+		t.Errorf("unexpected metadata issues: %v", issues)
+	}
+	if profile := imfApplicationProfile(pkg.composition().ApplicationIdentification); profile != "Application #2E (ST 2067-21)" {
+		t.Errorf("profile = %q", profile)
+	}
+}
+
+func TestAnalyzeCPL_SegmentDurationMismatch(t *testing.T) {
+	dir := t.TempDir()
+	writeTestIMFPackage(t, dir, testIMFCPL(true), false)
+
+	pkg, err := readIMFPackage(dir)
+	if err != nil {
+		t.Fatalf("readIMFPackage: %v", err)
+	}
+	cpl := NewIMFAnalyzer("ffprobe", zerolog.Nop()).analyzeCPL(pkg, pkg.composition())
+	if cpl.StructuralValidation.TimingConsistency {
+		t.Fatal("TimingConsistency = true for a segment with short audio")
+	}
+	if !strings.Contains(strings.Join(cpl.StructuralValidation.Issues, "\n"), "MainAudioSequence sequence lasts 1.000s") {
+		t.Errorf("issues do not name the short sequence: %v", cpl.StructuralValidation.Issues)
+	}
+}
+
+func TestAnalyzePKL_HashMismatch(t *testing.T) {
+	dir := t.TempDir()
+	writeTestIMFPackage(t, dir, testIMFCPL(false), true)
+
+	pkg, err := readIMFPackage(dir)
+	if err != nil {
+		t.Fatalf("readIMFPackage: %v", err)
+	}
+	pkl := NewIMFAnalyzer("ffprobe", zerolog.Nop()).analyzePKL(context.Background(), pkg)
+	if pkl.HashValidation.HashesValid {
+		t.Fatal("HashesValid = true with a wrong image hash")
+	}
+	if len(pkl.HashValidation.MismatchedHashes) != 1 || pkl.HashValidation.MismatchedHashes[0] != "image.mxf" {
+		t.Errorf("MismatchedHashes = %v, want [image.mxf]", pkl.HashValidation.MismatchedHashes)
+	}
+	if pkl.HashValidation.HashAlgorithm != Hash_SHA1 {
+		t.Errorf("HashAlgorithm = %q", pkl.HashValidation.HashAlgorithm)
+	}
+}
+
+func TestOpenIMFPackage_Zip(t *testing.T) {
+	dir := t.TempDir()
+	source := filepath.Join(dir, "package")
+	if err := os.Mkdir(source, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeTestIMFPackage(t, source, testIMFCPL(false), false)
+
+	zipPath := filepath.Join(dir, "package.zip")
+	writeTestZip(t, zipPath, func(w *zip.Writer) {
+		entries, _ := os.ReadDir(source)
+		for _, entry := range entries {
+			data, _ := os.ReadFile(filepath.Join(source, entry.Name()))
+			f, _ := w.Create("package/" + entry.Name())
+			f.Write(data)
+		}
+	})
+
+	if !IsIMFPackage(zipPath) {
+		t.Fatal("IsIMFPackage = false for a zipped package folder")
+	}
+	root, cleanup, err := openIMFPackage(zipPath)
+	if err != nil {
+		t.Fatalf("openIMFPackage: %v", err)
+	}
+	defer cleanup()
+	if filepath.Base(root) != "package" {
+		t.Errorf("root = %q, want the package folder", root)
+	}
+	if _, err := os.Stat(filepath.Join(root, "CPL.xml")); err != nil {
+		t.Errorf("CPL not extracted: %v", err)
+	}
+}
+
+func TestExtractIMFZip_RejectsEscapingEntries(t *testing.T) {
+	dir := t.TempDir()
+	zipPath := filepath.Join(dir, "evil.zip")
+	writeTestZip(t, zipPath, func(w *zip.Writer) {
+		f, _ := w.Create("../escaped.txt")
+		f.Write([]byte("x"))
+	})
+
+	target := filepath.Join(dir, "out")
+	if err := extractIMFZip(zipPath, target); err == nil {
+		t.Fatal("extractIMFZip accepted an entry outside the package")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped.txt")); err == nil {
+		t.Error("entry was written outside the package")
+	}
+}
+
+func TestValidIMFUUID(t *testing.T) {
+	if !validIMFUUID(testIMFCPLID) {
+		t.Errorf("%s rejected", testIMFCPLID)
+	}
+	for _, id := range []string{"22222222-2222-4222-8222-222222222222", "urn:uuid:not-a-uuid", ""} {
+		if validIMFUUID(id) {
+			t.Errorf("%q accepted", id)
+		}
+	}
+}
+
+func writeTestZip(t *testing.T, path string, fill func(*zip.Writer)) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	fill(w)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,171 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// liveSampleSlack is added to the sample window to allow for connecting to
+// the stream and flushing the filters
+const liveSampleSlack = 30 * time.Second
+
+// LiveSample holds the QC metrics measured over one window of a live stream
+type LiveSample struct {
+	Timestamp          time.Time `json:"timestamp"`
+	Duration           float64   `json:"duration_seconds"`
+	BitRate            int64     `json:"bit_rate"` // Demuxed bits per second
+	IntegratedLoudness *float64  `json:"integrated_loudness_lufs,omitempty"`
+	TruePeak           *float64  `json:"true_peak_dbtp,omitempty"`
+	BlackSeconds       float64   `json:"black_seconds"`
+	FreezeSeconds      float64   `json:"freeze_seconds"`
+	TSErrors           int       `json:"ts_errors"` // Continuity errors and corrupt packets
+	Error              string    `json:"error,omitempty"`
+}
+
+// LiveSampler measures a live stream for a fixed window in a single ffmpeg
+// pass, so each sample costs one connection to the source
+type LiveSampler struct {
+	ffmpegPath string
+	logger     zerolog.Logger
+}
+
+// NewLiveSampler creates a new live stream sampler
+func NewLiveSampler(ffmpegPath string, logger zerolog.Logger) *LiveSampler {
+	if ffmpegPath == "" {
+		ffmpegPath = "ffmpeg"
+	}
+	return &LiveSampler{
+		ffmpegPath: ffmpegPath,
+		logger:     logger,
+	}
+}
+
+// Sample reads window of the stream at url and returns its metrics. A stream
+// that cannot be read at all is an error; metrics a stream has no track for
+// (such as loudness of a video-only stream) are left unset.
+func (ls *LiveSampler) Sample(ctx context.Context, url string, window time.Duration) (*LiveSample, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("sample window must be positive")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, window+liveSampleSlack)
+	defer cancel()
+
+	started := time.Now()
+	cmd := exec.CommandContext(ctx, ls.ffmpegPath,
+		"-hide_banner",
+		"-loglevel", "verbose", // Needed for the demuxed byte totals
+		"-rw_timeout", strconv.FormatInt(liveSampleSlack.Microseconds(), 10),
+		"-t", strconv.FormatFloat(window.Seconds(), 'f', 3, 64),
+		"-i", url,
+		"-vf", "blackdetect=d=0.5:pix_th=0.1,freezedetect=n=0.001:d=2",
+		"-af", "ebur128=peak=true",
+		"-f", "null",
+		"-",
+	)
+
+	output, err := commandCombinedOutput(ctx, cmd)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("live sample timed out: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("live sample failed: %w", err)
+	}
+
+	sample, demuxedBytes := parseLiveSampleOutput(output)
+	sample.Timestamp = started.UTC()
+	if sample.Duration <= 0 {
+		sample.Duration = window.Seconds()
+	}
+	if demuxedBytes > 0 {
+		sample.BitRate = int64(float64(demuxedBytes*8) / sample.Duration)
+	}
+
+	ls.logger.Debug().
+		Str("url", url).
+		Int64("bit_rate", sample.BitRate).
+		Int("ts_errors", sample.TSErrors).
+		Msg("Live stream sampled")
+
+	return sample, nil
+}
+
+// parseLiveSampleOutput extracts the metrics of a sample from ffmpeg's log
+// output, along with the number of bytes demuxed
+func parseLiveSampleOutput(output []byte) (*LiveSample, int64) {
+	sample := &LiveSample{}
+	var demuxedBytes int64
+
+	forEachLine(output, func(line string) bool {
+		// Progress updates are separated by carriage returns
+		if index := strings.LastIndex(line, "time="); index >= 0 {
+			if fields := strings.Fields(line[index+len("time="):]); len(fields) > 0 {
+				if seconds := parseDurationToSeconds(fields[0]); seconds > 0 {
+					sample.Duration = seconds
+				}
+			}
+		}
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.Contains(line, "black_duration:"):
+			sample.BlackSeconds += parseLogValue(line, "black_duration:")
+		case strings.Contains(line, "freeze_duration:"):
+			sample.FreezeSeconds += parseLogValue(line, "freeze_duration:")
+		case strings.Contains(line, "Continuity check failed"), strings.Contains(line, "Packet corrupt"):
+			sample.TSErrors++
+		case strings.Contains(line, "Total:") && strings.Contains(line, "bytes) demuxed"):
+			// e.g. "Total: 2500 packets (1875000 bytes) demuxed"
+			if start := strings.Index(line, "("); start >= 0 {
+				if fields := strings.Fields(line[start+1:]); len(fields) > 0 {
+					if n, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+						demuxedBytes += n
+					}
+				}
+			}
+		case strings.HasPrefix(trimmed, "I:"):
+			// ebur128 summary; per-frame lines start with the filter name
+			if value, ok := parseFiniteValue(strings.TrimPrefix(trimmed, "I:")); ok {
+				sample.IntegratedLoudness = &value
+			}
+		case strings.HasPrefix(trimmed, "Peak:"):
+			if value, ok := parseFiniteValue(strings.TrimPrefix(trimmed, "Peak:")); ok {
+				sample.TruePeak = &value
+			}
+		}
+		return true
+	})
+
+	return sample, demuxedBytes
+}
+
+// parseLogValue returns the number following key in a filter log line
+func parseLogValue(line, key string) float64 {
+	index := strings.Index(line, key)
+	if index < 0 {
+		return 0
+	}
+	value, _ := parseFiniteValue(line[index+len(key):])
+	return value
+}
+
+// parseFiniteValue parses the first field of s as a number. Silence makes
+// ebur128 report -inf, which has no JSON representation.
+func parseFiniteValue(s string) (float64, bool) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+		return 0, false
+	}
+	return value, true
+}
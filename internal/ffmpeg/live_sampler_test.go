@@ -0,0 +1,66 @@
+package ffmpeg
+
+import (
+	"testing"
+)
+
+func TestParseLiveSampleOutput(t *testing.T) {
+	output := []byte(`[mpegts @ 0x5581] Continuity check failed for pid 256 expected 3 got 5
+[mpegts @ 0x5581] Packet corrupt (stream = 0, dts = 900000).
+[Parsed_ebur128_1 @ 0x5590] t: 0.4  TARGET:-23 LUFS    M: -24.1 S:-120.7     I: -24.1 LUFS       LRA:   0.0 LU  FTPK: -3.2 dBFS  TPK: -3.2 dBFS
+[Parsed_blackdetect_0 @ 0x5592] black_start:1.2 black_end:2.2 black_duration:1
+[Parsed_blackdetect_0 @ 0x5592] black_start:4 black_end:4.5 black_duration:0.5
+[Parsed_freezedetect_1 @ 0x5593] lavfi.freezedetect.freeze_duration: 2.5
+frame=  125 fps= 25 q=-0.0 size=N/A time=00:00:04.00 bitrate=N/A speed=1x` + "\r" + `frame=  250 fps= 25 q=-0.0 size=N/A time=00:00:10.00 bitrate=N/A speed=1x
+[in#0/mpegts @ 0x5594]   Total: 980 packets (1250000 bytes) demuxed
+[Parsed_ebur128_1 @ 0x5590] Summary:
+
+  Integrated loudness:
+    I:         -22.5 LUFS
+    Threshold: -32.8 LUFS
+
+  True peak:
+    Peak:       -1.5 dBFS
+`)
+
+	sample, demuxedBytes := parseLiveSampleOutput(output)
+
+	if sample.Duration != 10 {
+		t.Errorf("expected duration 10, got %v", sample.Duration)
+	}
+	if demuxedBytes != 1250000 {
+		t.Errorf("expected 1250000 demuxed bytes, got %d", demuxedBytes)
+	}
+	if sample.BlackSeconds != 1.5 {
+		t.Errorf("expected 1.5 black seconds, got %v", sample.BlackSeconds)
+	}
+	if sample.FreezeSeconds != 2.5 {
+		t.Errorf("expected 2.5 freeze seconds, got %v", sample.FreezeSeconds)
+	}
+	if sample.TSErrors != 2 {
+		t.Errorf("expected 2 TS errors, got %d", sample.TSErrors)
+	}
+	if sample.IntegratedLoudness == nil || *sample.IntegratedLoudness != -22.5 {
+		t.Errorf("expected integrated loudness -22.5, got %v", sample.IntegratedLoudness)
+	}
+	if sample.TruePeak == nil || *sample.TruePeak != -1.5 {
+		t.Errorf("expected true peak -1.5, got %v", sample.TruePeak)
+	}
+}
+
+func TestParseLiveSampleOutput_SilenceHasNoLoudness(t *testing.T) {
+	output := []byte(`  Integrated loudness:
+    I:         -70.0 LUFS
+  True peak:
+    Peak:       -inf dBFS
+`)
+
+	sample, _ := parseLiveSampleOutput(output)
+
+	if sample.TruePeak != nil {
+		t.Errorf("expected no true peak for silence, got %v", *sample.TruePeak)
+	}
+	if sample.IntegratedLoudness == nil || *sample.IntegratedLoudness != -70 {
+		t.Errorf("expected integrated loudness -70, got %v", sample.IntegratedLoudness)
+	}
+}
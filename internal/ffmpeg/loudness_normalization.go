@@ -0,0 +1,180 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LoudnessTarget is the loudness a normalization aims for
+type LoudnessTarget struct {
+	Name               string  `json:"name"`
+	IntegratedLoudness float64 `json:"integrated_lufs"`
+	TruePeak           float64 `json:"true_peak_dbtp"`    // Highest allowed true peak
+	LoudnessRange      float64 `json:"loudness_range_lu"` // Highest loudness range a linear gain may keep
+}
+
+// Built-in loudness targets
+var (
+	LoudnessTargetEBUR128   = LoudnessTarget{Name: "ebu_r128", IntegratedLoudness: -23, TruePeak: -1, LoudnessRange: 20}
+	LoudnessTargetATSCA85   = LoudnessTarget{Name: "atsc_a85", IntegratedLoudness: -24, TruePeak: -2, LoudnessRange: 20}
+	LoudnessTargetStreaming = LoudnessTarget{Name: "streaming", IntegratedLoudness: -14, TruePeak: -1, LoudnessRange: 20}
+)
+
+// DefaultLoudnessTargets are used when no targets are configured
+var DefaultLoudnessTargets = []LoudnessTarget{LoudnessTargetEBUR128, LoudnessTargetATSCA85, LoudnessTargetStreaming}
+
+// loudnessSilence is the integrated loudness ebur128 reports when nothing
+// passed the absolute gate; such audio has no gain that reaches a target
+const loudnessSilence = -70.0
+
+// ParseLoudnessTargets parses a comma-separated list of targets. Each entry
+// is a built-in target name or a custom "I/TP/LRA" triple, e.g. "-16/-1/11".
+// An empty list returns DefaultLoudnessTargets.
+func ParseLoudnessTargets(spec string) ([]LoudnessTarget, error) {
+	var targets []LoudnessTarget
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		target, err := parseLoudnessTarget(entry)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+	if len(targets) == 0 {
+		return DefaultLoudnessTargets, nil
+	}
+	return targets, nil
+}
+
+func parseLoudnessTarget(entry string) (LoudnessTarget, error) {
+	for _, target := range DefaultLoudnessTargets {
+		if entry == target.Name {
+			return target, nil
+		}
+	}
+
+	parts := strings.Split(entry, "/")
+	if len(parts) != 3 {
+		return LoudnessTarget{}, fmt.Errorf("unknown loudness target %q: use ebu_r128, atsc_a85, streaming or I/TP/LRA", entry)
+	}
+	var values [3]float64
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(part, 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return LoudnessTarget{}, fmt.Errorf("invalid loudness target %q: %q is not a number", entry, part)
+		}
+		values[i] = value
+	}
+	target := LoudnessTarget{Name: entry, IntegratedLoudness: values[0], TruePeak: values[1], LoudnessRange: values[2]}
+	return target, target.Validate()
+}
+
+// Validate checks that a target is within the ranges the loudnorm filter
+// accepts
+func (t LoudnessTarget) Validate() error {
+	if t.IntegratedLoudness < -70 || t.IntegratedLoudness > -5 {
+		return fmt.Errorf("loudness target %s: integrated loudness must be between -70 and -5 LUFS", t.Name)
+	}
+	if t.TruePeak < -9 || t.TruePeak > 0 {
+		return fmt.Errorf("loudness target %s: true peak must be between -9 and 0 dBTP", t.Name)
+	}
+	if t.LoudnessRange < 1 || t.LoudnessRange > 20 {
+		return fmt.Errorf("loudness target %s: loudness range must be between 1 and 20 LU", t.Name)
+	}
+	return nil
+}
+
+// LoudnessNormalization is the correction that brings measured audio to a
+// target. Measured holds the loudnorm first pass values, so a transcoder can
+// normalize in a single pass with Filter.
+type LoudnessNormalization struct {
+	Target            LoudnessTarget     `json:"target"`
+	GainDB            float64            `json:"gain_db"`                  // Offset from the measured to the target integrated loudness
+	ResultingTruePeak float64            `json:"resulting_true_peak_dbtp"` // True peak after applying GainDB
+	Linear            bool               `json:"linear"`                   // GainDB alone reaches the target; otherwise loudnorm compresses dynamically
+	Measured          LoudnormParameters `json:"measured"`
+	Filter            string             `json:"filter"` // ffmpeg loudnorm filter for the correction
+}
+
+// LoudnormParameters are the measured_* options of the loudnorm filter
+type LoudnormParameters struct {
+	IntegratedLoudness float64 `json:"measured_I"`
+	TruePeak           float64 `json:"measured_TP"`
+	LoudnessRange      float64 `json:"measured_LRA"`
+	Threshold          float64 `json:"measured_thresh"`
+}
+
+type loudnessTargetsKey struct{}
+
+// withLoudnessTargets makes loudness analysis under ctx compute the
+// normalization to targets
+func withLoudnessTargets(ctx context.Context, targets []LoudnessTarget) context.Context {
+	if len(targets) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, loudnessTargetsKey{}, targets)
+}
+
+// loudnessTargets returns the normalization targets of ctx
+func loudnessTargets(ctx context.Context) []LoudnessTarget {
+	if targets, ok := ctx.Value(loudnessTargetsKey{}).([]LoudnessTarget); ok {
+		return targets
+	}
+	return DefaultLoudnessTargets
+}
+
+// normalizeLoudness computes the correction of a measured loudness to each
+// target. Silent audio gets none.
+func normalizeLoudness(analysis *LoudnessAnalysis, targets []LoudnessTarget) []LoudnessNormalization {
+	if analysis.IntegratedLoudness <= loudnessSilence || analysis.Threshold == 0 {
+		return nil
+	}
+
+	measured := LoudnormParameters{
+		IntegratedLoudness: analysis.IntegratedLoudness,
+		TruePeak:           analysis.TruePeak,
+		LoudnessRange:      analysis.LoudnessRange,
+		Threshold:          analysis.Threshold,
+	}
+	normalizations := make([]LoudnessNormalization, 0, len(targets))
+	for _, target := range targets {
+		gain := roundTo(target.IntegratedLoudness-measured.IntegratedLoudness, 2)
+		resultingPeak := roundTo(measured.TruePeak+gain, 2)
+		// loudnorm applies a linear gain only when neither the true peak
+		// nor the loudness range limit is exceeded, as computed here
+		linear := resultingPeak <= target.TruePeak && measured.LoudnessRange <= target.LoudnessRange
+
+		normalizations = append(normalizations, LoudnessNormalization{
+			Target:            target,
+			GainDB:            gain,
+			ResultingTruePeak: resultingPeak,
+			Linear:            linear,
+			Measured:          measured,
+			Filter:            loudnormFilter(target, measured, linear),
+		})
+	}
+	return normalizations
+}
+
+// loudnormFilter formats the loudnorm filter that applies a normalization
+func loudnormFilter(target LoudnessTarget, measured LoudnormParameters, linear bool) string {
+	return fmt.Sprintf("loudnorm=I=%s:TP=%s:LRA=%s:measured_I=%s:measured_TP=%s:measured_LRA=%s:measured_thresh=%s:linear=%t",
+		formatFilterValue(target.IntegratedLoudness), formatFilterValue(target.TruePeak), formatFilterValue(target.LoudnessRange),
+		formatFilterValue(measured.IntegratedLoudness), formatFilterValue(measured.TruePeak),
+		formatFilterValue(measured.LoudnessRange), formatFilterValue(measured.Threshold), linear)
+}
+
+func formatFilterValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func roundTo(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,70 @@
+package ffmpeg
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestParseLoudnessTargets(t *testing.T) {
+	targets, err := ParseLoudnessTargets("")
+	if err != nil || !reflect.DeepEqual(targets, DefaultLoudnessTargets) {
+		t.Errorf("ParseLoudnessTargets(\"\") = %v, %v, want the defaults", targets, err)
+	}
+
+	targets, err = ParseLoudnessTargets(" EBU_R128, -16/-1.5/11 ")
+	if err != nil {
+		t.Fatalf("ParseLoudnessTargets: %v", err)
+	}
+	want := []LoudnessTarget{
+		LoudnessTargetEBUR128,
+		{Name: "-16/-1.5/11", IntegratedLoudness: -16, TruePeak: -1.5, LoudnessRange: 11},
+	}
+	if !reflect.DeepEqual(targets, want) {
+		t.Errorf("targets = %+v, want %+v", targets, want)
+	}
+
+	for _, spec := range []string{"netflix", "-16/-1", "-16/x/11", "-80/-1/11", "-16/1/11", "-16/-1/30"} {
+		if _, err := ParseLoudnessTargets(spec); err == nil {
+			t.Errorf("ParseLoudnessTargets(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestNormalizeLoudness(t *testing.T) {
+	analysis := &LoudnessAnalysis{IntegratedLoudness: -18.52, TruePeak: -4.31, LoudnessRange: 6.4, Threshold: -28.74}
+	normalizations := normalizeLoudness(analysis, DefaultLoudnessTargets)
+	if len(normalizations) != 3 {
+		t.Fatalf("got %d normalizations, want 3", len(normalizations))
+	}
+
+	ebu := normalizations[0]
+	if ebu.GainDB != -4.48 || ebu.ResultingTruePeak != -8.79 || !ebu.Linear {
+		t.Errorf("EBU R128 normalization = %+v", ebu)
+	}
+	wantFilter := "loudnorm=I=-23:TP=-1:LRA=20:measured_I=-18.52:measured_TP=-4.31:measured_LRA=6.4:measured_thresh=-28.74:linear=true"
+	if ebu.Filter != wantFilter {
+		t.Errorf("filter = %s, want %s", ebu.Filter, wantFilter)
+	}
+
+	// Raising to -14 LUFS pushes the peak to +0.21 dBTP, so loudnorm has
+	// to limit instead of applying a plain gain
+	streaming := normalizations[2]
+	if streaming.GainDB != 4.52 || streaming.ResultingTruePeak != 0.21 || streaming.Linear {
+		t.Errorf("streaming normalization = %+v", streaming)
+	}
+
+	if silent := normalizeLoudness(&LoudnessAnalysis{IntegratedLoudness: -70, Threshold: -80}, DefaultLoudnessTargets); silent != nil {
+		t.Errorf("silence normalized as %+v", silent)
+	}
+}
+
+func TestLoudnessTargetsContext(t *testing.T) {
+	if targets := loudnessTargets(context.Background()); !reflect.DeepEqual(targets, DefaultLoudnessTargets) {
+		t.Errorf("default targets = %v", targets)
+	}
+	ctx := withLoudnessTargets(context.Background(), []LoudnessTarget{LoudnessTargetATSCA85})
+	if targets := loudnessTargets(ctx); len(targets) != 1 || targets[0] != LoudnessTargetATSCA85 {
+		t.Errorf("targets = %v, want ATSC A/85 only", targets)
+	}
+}
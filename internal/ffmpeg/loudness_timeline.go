@@ -0,0 +1,150 @@
+package ffmpeg
+
+import (
+	"math"
+	"strings"
+)
+
+// maxLoudnessTimelinePoints bounds the timeline of long files; two hours fit
+// at one point per second, longer files get wider points
+const maxLoudnessTimelinePoints = 7200
+
+// loudnessSilenceFloor is the value ebur128 reports for silence and for
+// short-term loudness before its first 3 second window is full
+const loudnessSilenceFloor = -120.0
+
+// LoudnessPoint is the loudness of one timeline interval. Values are
+// omitted for silence and for windows that are not yet full.
+type LoudnessPoint struct {
+	Time      float64  `json:"t"`                         // Start of the interval in seconds
+	Momentary *float64 `json:"momentary_lufs,omitempty"`  // Loudest 400 ms momentary loudness
+	ShortTerm *float64 `json:"short_term_lufs,omitempty"` // 3 s short-term loudness at the end of the interval
+	TruePeak  *float64 `json:"true_peak_dbtp,omitempty"`  // Highest true peak over all channels
+}
+
+// parseLoudnessOutput reads the ebur128 summary and its 100 ms frame log into
+// a LoudnessAnalysis with a timeline of one point per second
+func parseLoudnessOutput(output []byte) *LoudnessAnalysis {
+	analysis := &LoudnessAnalysis{Standard: "EBU R128", TimelineInterval: 1}
+	section := ""
+
+	forEachLine(output, func(line string) bool {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.Contains(line, "Parsed_ebur128") && strings.Contains(line, " t:"):
+			analysis.Timeline = addLoudnessFrame(analysis.Timeline, line)
+		case strings.HasSuffix(trimmed, ":") && !strings.Contains(trimmed, " @ "):
+			// Summary section headers, e.g. "Integrated loudness:"
+			section = trimmed
+		case strings.HasPrefix(trimmed, "I:") && section == "Integrated loudness:":
+			analysis.IntegratedLoudness, _ = parseFiniteValue(strings.TrimPrefix(trimmed, "I:"))
+		case strings.HasPrefix(trimmed, "Threshold:") && section == "Integrated loudness:":
+			analysis.Threshold, _ = parseFiniteValue(strings.TrimPrefix(trimmed, "Threshold:"))
+		case strings.HasPrefix(trimmed, "LRA:") && section == "Loudness range:":
+			analysis.LoudnessRange, _ = parseFiniteValue(strings.TrimPrefix(trimmed, "LRA:"))
+		case strings.HasPrefix(trimmed, "Peak:") && section == "True peak:":
+			analysis.TruePeak, _ = parseFiniteValue(strings.TrimPrefix(trimmed, "Peak:"))
+		}
+		return true
+	})
+
+	for len(analysis.Timeline) > maxLoudnessTimelinePoints {
+		analysis.Timeline = mergeLoudnessPoints(analysis.Timeline)
+		analysis.TimelineInterval *= 2
+	}
+	if len(analysis.Timeline) == 0 {
+		analysis.TimelineInterval = 0
+	}
+	return analysis
+}
+
+// addLoudnessFrame folds one ebur128 frame log line, e.g.
+// "t: 1.1  TARGET:-23 LUFS  M: -22.5 S:-120.7  I: -22.8 LUFS  LRA: 0.0 LU  FTPK: -5.6 -5.8 dBFS  TPK: -5.6 -5.8 dBFS",
+// into the point for its second
+func addLoudnessFrame(timeline []LoudnessPoint, line string) []LoudnessPoint {
+	t, ok := loudnessField(line, " t:")
+	if !ok || t < 0 {
+		return timeline
+	}
+	// Frames are logged at the end of the 100 ms they cover
+	second := math.Floor(math.Max(t-0.05, 0))
+	for float64(len(timeline)) <= second {
+		timeline = append(timeline, LoudnessPoint{Time: float64(len(timeline))})
+	}
+	point := &timeline[int(second)]
+
+	if value, ok := loudnessField(line, " M:"); ok && value > loudnessSilenceFloor {
+		point.Momentary = maxLoudness(point.Momentary, value)
+	}
+	if value, ok := loudnessField(line, " S:"); ok && value > loudnessSilenceFloor {
+		point.ShortTerm = &value
+	}
+	for _, peak := range loudnessChannelPeaks(line, "FTPK:") {
+		if peak != nil {
+			point.TruePeak = maxLoudness(point.TruePeak, *peak)
+		}
+	}
+	return timeline
+}
+
+// loudnessChannelPeaks returns the per-channel peaks following key in an
+// ebur128 frame line, e.g. "TPK: -5.6 -inf dBFS". Silent channels are nil.
+func loudnessChannelPeaks(line, key string) []*float64 {
+	index := strings.Index(line, key)
+	if index < 0 {
+		return nil
+	}
+	var peaks []*float64
+	// One value per channel, followed by the unit
+	for _, field := range strings.Fields(line[index+len(key):]) {
+		if field == "-inf" {
+			peaks = append(peaks, nil)
+			continue
+		}
+		value, ok := parseFiniteValue(field)
+		if !ok {
+			break
+		}
+		peaks = append(peaks, &value)
+	}
+	return peaks
+}
+
+// mergeLoudnessPoints halves the resolution of a timeline
+func mergeLoudnessPoints(timeline []LoudnessPoint) []LoudnessPoint {
+	merged := make([]LoudnessPoint, 0, (len(timeline)+1)/2)
+	for i := 0; i < len(timeline); i += 2 {
+		point := timeline[i]
+		if i+1 < len(timeline) {
+			next := timeline[i+1]
+			if next.Momentary != nil {
+				point.Momentary = maxLoudness(point.Momentary, *next.Momentary)
+			}
+			if next.ShortTerm != nil {
+				point.ShortTerm = next.ShortTerm
+			}
+			if next.TruePeak != nil {
+				point.TruePeak = maxLoudness(point.TruePeak, *next.TruePeak)
+			}
+		}
+		merged = append(merged, point)
+	}
+	return merged
+}
+
+// loudnessField returns the number following key in an ebur128 frame line.
+// Keys may be followed directly by the value, as in "S:-120.7".
+func loudnessField(line, key string) (float64, bool) {
+	index := strings.Index(line, key)
+	if index < 0 {
+		return 0, false
+	}
+	return parseFiniteValue(line[index+len(key):])
+}
+
+func maxLoudness(current *float64, value float64) *float64 {
+	if current != nil && *current >= value {
+		return current
+	}
+	return &value
+}
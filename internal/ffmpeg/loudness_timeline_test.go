@@ -0,0 +1,109 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseLoudnessOutput(t *testing.T) {
+	output := []byte(`Input #0, wav, from 'tone.wav':
+  Duration: 00:00:02.10, bitrate: 1536 kb/s
+[Parsed_ebur128_0 @ 0x5581] t: 0.1        TARGET:-23 LUFS    M:-120.7 S:-120.7     I: -70.0 LUFS       LRA:   0.0 LU  FTPK: -inf -inf dBFS  TPK: -inf -inf dBFS
+[Parsed_ebur128_0 @ 0x5581] t: 0.4        TARGET:-23 LUFS    M: -24.5 S:-120.7     I: -24.5 LUFS       LRA:   0.0 LU  FTPK: -6.2 -7.0 dBFS  TPK: -6.2 -7.0 dBFS
+[Parsed_ebur128_0 @ 0x5581] t: 1          TARGET:-23 LUFS    M: -22.1 S:-120.7     I: -23.0 LUFS       LRA:   0.0 LU  FTPK: -5.1 -4.8 dBFS  TPK: -4.8 -4.8 dBFS
+[Parsed_ebur128_0 @ 0x5581] t: 1.5        TARGET:-23 LUFS    M: -23.4 S:-120.7     I: -23.1 LUFS       LRA:   0.0 LU  FTPK: -6.0 -6.1 dBFS  TPK: -4.8 -4.8 dBFS
+[Parsed_ebur128_0 @ 0x5581] t: 2.1        TARGET:-23 LUFS    M: -23.2 S: -23.3     I: -23.1 LUFS       LRA:   1.2 LU  FTPK: -3.9 -5.5 dBFS  TPK: -3.9 -4.8 dBFS
+[Parsed_ebur128_0 @ 0x5581] Summary:
+
+  Integrated loudness:
+    I:         -23.1 LUFS
+    Threshold: -33.1 LUFS
+
+  Loudness range:
+    LRA:         1.2 LU
+    Threshold: -43.1 LUFS
+    LRA low:   -23.8 LUFS
+    LRA high:  -22.6 LUFS
+
+  True peak:
+    Peak:       -3.9 dBFS
+`)
+
+	analysis := parseLoudnessOutput(output)
+
+	if analysis.IntegratedLoudness != -23.1 || analysis.LoudnessRange != 1.2 || analysis.TruePeak != -3.9 || analysis.Threshold != -33.1 {
+		t.Errorf("unexpected summary %+v", analysis)
+	}
+	if analysis.TimelineInterval != 1 || len(analysis.Timeline) != 3 {
+		t.Fatalf("expected 3 one-second points, got %d at %vs", len(analysis.Timeline), analysis.TimelineInterval)
+	}
+
+	first := analysis.Timeline[0]
+	if first.Time != 0 || first.Momentary == nil || *first.Momentary != -22.1 || first.ShortTerm != nil {
+		t.Errorf("unexpected first point %+v", first)
+	}
+	if first.TruePeak == nil || *first.TruePeak != -4.8 {
+		t.Errorf("expected the highest channel peak in the first second, got %v", first.TruePeak)
+	}
+	if second := analysis.Timeline[1]; second.Momentary == nil || *second.Momentary != -23.4 || *second.TruePeak != -6.0 {
+		t.Errorf("unexpected second point %+v", second)
+	}
+	if third := analysis.Timeline[2]; third.Time != 2 || third.ShortTerm == nil || *third.ShortTerm != -23.3 {
+		t.Errorf("unexpected third point %+v", third)
+	}
+}
+
+func TestParseLoudnessOutput_LongFile(t *testing.T) {
+	var output strings.Builder
+	seconds := maxLoudnessTimelinePoints + 10
+	for i := 1; i <= seconds; i++ {
+		fmt.Fprintf(&output, "[Parsed_ebur128_0 @ 0x5581] t: %d  TARGET:-23 LUFS  M: %d.0 S: -23.0  I: -23.0 LUFS  LRA: 0.0 LU\n", i, -30+i%2)
+	}
+
+	analysis := parseLoudnessOutput([]byte(output.String()))
+
+	if analysis.TimelineInterval != 2 || len(analysis.Timeline) != seconds/2 {
+		t.Fatalf("expected %d two-second points, got %d at %vs", seconds/2, len(analysis.Timeline), analysis.TimelineInterval)
+	}
+	if point := analysis.Timeline[1]; point.Time != 2 || *point.Momentary != -29 {
+		t.Errorf("expected merged points to keep the loudest momentary value, got %+v", point)
+	}
+}
+
+func TestParseLoudnessOutput_NoAudio(t *testing.T) {
+	analysis := parseLoudnessOutput([]byte("Output file #0 does not contain any stream\n"))
+	if len(analysis.Timeline) != 0 || analysis.TimelineInterval != 0 {
+		t.Errorf("expected no timeline, got %+v", analysis)
+	}
+}
+
+func TestLoudnessChannelPeaks(t *testing.T) {
+	line := "[Parsed_ebur128_1 @ 0x5581] t: 2.1  TARGET:-23 LUFS  M: -23.2 S: -23.3  I: -23.1 LUFS  LRA: 1.2 LU  FTPK: -3.9 -inf dBFS  TPK: -0.4 -inf dBFS"
+	peaks := loudnessChannelPeaks(line, " TPK:")
+	if len(peaks) != 2 || peaks[0] == nil || *peaks[0] != -0.4 || peaks[1] != nil {
+		t.Fatalf("true peaks = %v, want [-0.4 silent]", peaks)
+	}
+	if peaks := loudnessChannelPeaks("[Parsed_astats_0 @ 0x5581] Channel: 1", " TPK:"); peaks != nil {
+		t.Errorf("peaks of an astats line = %v", peaks)
+	}
+
+	overall := -0.4
+	quiet := -1.5
+	checks := checkTruePeakLimits([]ChannelLevelInfo{
+		{Channel: 1, TruePeakDB: &overall},
+		{Channel: 2, TruePeakDB: &quiet},
+	}, &overall)
+	if len(checks) != 2 {
+		t.Fatalf("got %d checks, want EBU R128 and ATSC A/85", len(checks))
+	}
+	if ebu := checks[0]; ebu.Compliant || ebu.LimitDBTP != -1 || len(ebu.ChannelsOver) != 1 || ebu.ChannelsOver[0] != 1 {
+		t.Errorf("EBU R128 check = %+v, want channel 1 over -1 dBTP", ebu)
+	}
+	if atsc := checks[1]; atsc.Compliant || len(atsc.ChannelsOver) != 2 {
+		t.Errorf("ATSC A/85 check = %+v, want both channels over -2 dBTP", atsc)
+	}
+	if checks := checkTruePeakLimits(nil, nil); checks != nil {
+		t.Errorf("checks without true peaks = %+v", checks)
+	}
+}
@@ -0,0 +1,232 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// Tolerances of the MediaInfo cross-check. The tools measure duration and
+// overall bit rate from different container fields, so small differences
+// are expected.
+const (
+	mediaInfoDurationTolerance = 0.5  // seconds
+	mediaInfoBitRateTolerance  = 0.05 // fraction of the ffprobe value
+)
+
+// MediaInfo cross-check fields
+const (
+	MediaInfoFieldDuration      = "duration"
+	MediaInfoFieldBitRate       = "overall_bit_rate"
+	MediaInfoFieldAudioStreams  = "audio_streams"
+	MediaInfoFieldAudioChannels = "audio_channels"
+)
+
+// MediaInfoCrossCheck compares key properties reported by MediaInfo with
+// those reported by ffprobe. Fields either tool does not report are not
+// compared.
+type MediaInfoCrossCheck struct {
+	Version       string                `json:"version,omitempty"` // MediaInfoLib version
+	Comparisons   []MediaInfoComparison `json:"comparisons"`
+	Discrepancies int                   `json:"discrepancies"`
+}
+
+// MediaInfoComparison is one property as reported by both tools
+type MediaInfoComparison struct {
+	Field     string `json:"field"`
+	Stream    *int   `json:"stream,omitempty"` // ffprobe stream index for per-stream fields
+	FFprobe   string `json:"ffprobe"`
+	MediaInfo string `json:"mediainfo"`
+	Match     bool   `json:"match"`
+	Message   string `json:"message,omitempty"` // Describes a discrepancy
+}
+
+// MediaInfoAnalyzer runs mediainfo to cross-validate the ffprobe result
+type MediaInfoAnalyzer struct {
+	mediainfoPath string
+	logger        zerolog.Logger
+}
+
+// NewMediaInfoAnalyzer creates a new MediaInfo cross-checker
+func NewMediaInfoAnalyzer(mediainfoPath string, logger zerolog.Logger) *MediaInfoAnalyzer {
+	if mediainfoPath == "" {
+		mediainfoPath = "mediainfo"
+	}
+	return &MediaInfoAnalyzer{
+		mediainfoPath: mediainfoPath,
+		logger:        logger,
+	}
+}
+
+// mediaInfoOutput is the part of `mediainfo --Output=JSON` the cross-check
+// reads. MediaInfo reports every value as a string.
+type mediaInfoOutput struct {
+	CreatingLibrary struct {
+		Version string `json:"version"`
+	} `json:"creatingLibrary"`
+	Media *struct {
+		Track []mediaInfoTrack `json:"track"`
+	} `json:"media"`
+}
+
+type mediaInfoTrack struct {
+	Type           string `json:"@type"`
+	Duration       string `json:"Duration"`
+	OverallBitRate string `json:"OverallBitRate"`
+	Channels       string `json:"Channels"`
+}
+
+// CrossCheck runs mediainfo on filePath and compares its duration, overall
+// bit rate and audio channel counts with the ffprobe result
+func (ma *MediaInfoAnalyzer) CrossCheck(ctx context.Context, result *FFprobeResult, filePath string) (*MediaInfoCrossCheck, error) {
+	cmd := exec.CommandContext(ctx, ma.mediainfoPath, "--Output=JSON", filePath)
+	output, err := commandOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("mediainfo failed: %w", err)
+	}
+	return crossCheckMediaInfo(result, output)
+}
+
+// crossCheckMediaInfo compares the JSON output of mediainfo with result.
+// Audio streams are paired in order, since MediaInfo numbers streams
+// differently from ffprobe in some containers.
+func crossCheckMediaInfo(result *FFprobeResult, output []byte) (*MediaInfoCrossCheck, error) {
+	var info mediaInfoOutput
+	if err := json.Unmarshal(output, &info); err != nil {
+		return nil, fmt.Errorf("invalid mediainfo output: %w", err)
+	}
+	if info.Media == nil {
+		return nil, fmt.Errorf("mediainfo did not recognize the file")
+	}
+
+	check := &MediaInfoCrossCheck{Version: info.CreatingLibrary.Version, Comparisons: []MediaInfoComparison{}}
+	var general *mediaInfoTrack
+	var audio []mediaInfoTrack
+	for i, track := range info.Media.Track {
+		switch track.Type {
+		case "General":
+			general = &info.Media.Track[i]
+		case "Audio":
+			audio = append(audio, track)
+		}
+	}
+
+	if general != nil && result.Format != nil {
+		if a, b, ok := parseFloatPair(result.Format.Duration, general.Duration); ok {
+			check.add(MediaInfoComparison{
+				Field:     MediaInfoFieldDuration,
+				FFprobe:   fmt.Sprintf("%.3f s", a),
+				MediaInfo: fmt.Sprintf("%.3f s", b),
+				Match:     math.Abs(a-b) <= mediaInfoDurationTolerance,
+				Message:   fmt.Sprintf("Duration differs by %.3f s", math.Abs(a-b)),
+			})
+		}
+		if a, b, ok := parseFloatPair(result.Format.BitRate, general.OverallBitRate); ok && a > 0 {
+			check.add(MediaInfoComparison{
+				Field:     MediaInfoFieldBitRate,
+				FFprobe:   fmt.Sprintf("%.0f kb/s", a/1000),
+				MediaInfo: fmt.Sprintf("%.0f kb/s", b/1000),
+				Match:     math.Abs(a-b) <= a*mediaInfoBitRateTolerance,
+				Message:   fmt.Sprintf("Overall bit rate differs by %.1f%%", 100*math.Abs(a-b)/a),
+			})
+		}
+	}
+
+	var streams []StreamInfo
+	for _, stream := range result.Streams {
+		if stream.CodecType == "audio" {
+			streams = append(streams, stream)
+		}
+	}
+	if len(streams) != len(audio) {
+		check.add(MediaInfoComparison{
+			Field:     MediaInfoFieldAudioStreams,
+			FFprobe:   strconv.Itoa(len(streams)),
+			MediaInfo: strconv.Itoa(len(audio)),
+			Message:   fmt.Sprintf("ffprobe finds %d audio streams, MediaInfo %d", len(streams), len(audio)),
+		})
+	}
+	for i := range min(len(streams), len(audio)) {
+		channels, ok := parseLeadingInt(audio[i].Channels)
+		if !ok || streams[i].Channels == 0 {
+			continue
+		}
+		index := streams[i].Index
+		check.add(MediaInfoComparison{
+			Field:     MediaInfoFieldAudioChannels,
+			Stream:    &index,
+			FFprobe:   strconv.Itoa(streams[i].Channels),
+			MediaInfo: strconv.Itoa(channels),
+			Match:     streams[i].Channels == channels,
+			Message:   fmt.Sprintf("Audio stream %d has %d channels according to ffprobe, %d according to MediaInfo", index, streams[i].Channels, channels),
+		})
+	}
+	return check, nil
+}
+
+// add records a comparison, keeping its message only for discrepancies
+func (c *MediaInfoCrossCheck) add(comparison MediaInfoComparison) {
+	if comparison.Match {
+		comparison.Message = ""
+	} else {
+		c.Discrepancies++
+	}
+	c.Comparisons = append(c.Comparisons, comparison)
+}
+
+func parseFloatPair(a, b string) (float64, float64, bool) {
+	x, errA := strconv.ParseFloat(strings.TrimSpace(a), 64)
+	y, errB := strconv.ParseFloat(strings.TrimSpace(b), 64)
+	return x, y, errA == nil && errB == nil
+}
+
+// parseLeadingInt reads values such as "6" or "6 / 2", which MediaInfo
+// reports for streams whose channel count changes
+func parseLeadingInt(value string) (int, bool) {
+	field, _, _ := strings.Cut(strings.TrimSpace(value), " ")
+	n, err := strconv.Atoi(field)
+	return n, err == nil
+}
+
+// applyMediaInfoCrossCheck adds the cross-check to the analysis. A
+// discrepancy is worth reviewing but not evidence of corruption, so the
+// validation only gains a recommendation.
+func (d *DataIntegrityAnalysis) applyMediaInfoCrossCheck(check *MediaInfoCrossCheck) {
+	d.MediaInfoCrossCheck = check
+	if check.Discrepancies == 0 {
+		return
+	}
+	if d.Validation == nil {
+		d.Validation = &DataIntegrityValidation{IsValid: true}
+	}
+	d.Validation.Recommendations = append(d.Validation.Recommendations, "ffprobe and MediaInfo disagree; verify the container headers before delivery")
+}
+
+// AnalyzeMediaInfo cross-checks the result with mediainfo and adds the
+// comparison to the data integrity analysis, creating one if the selected
+// categories did not
+func (f *FFprobe) AnalyzeMediaInfo(ctx context.Context, result *FFprobeResult, filePath string) error {
+	check, err := NewMediaInfoAnalyzer(f.mediainfoPath, f.logger).CrossCheck(ctx, result, filePath)
+	if err != nil {
+		return err
+	}
+
+	if result.EnhancedAnalysis == nil {
+		result.EnhancedAnalysis = &EnhancedAnalysis{}
+	}
+	if result.EnhancedAnalysis.DataIntegrityAnalysis == nil {
+		result.EnhancedAnalysis.DataIntegrityAnalysis = &DataIntegrityAnalysis{
+			IntegrityScore:       100,
+			IsBroadcastCompliant: true,
+			Validation:           &DataIntegrityValidation{IsValid: true, BroadcastCompliant: true, StreamingCompliant: true},
+		}
+	}
+	result.EnhancedAnalysis.DataIntegrityAnalysis.applyMediaInfoCrossCheck(check)
+	return nil
+}
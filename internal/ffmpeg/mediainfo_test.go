@@ -0,0 +1,110 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+const testMediaInfoJSON = `{
+  "creatingLibrary": {"name": "MediaInfoLib", "version": "23.04", "url": "https://mediaarea.net/MediaInfo"},
+  "media": {
+    "@ref": "master.mov",
+    "track": [
+      {"@type": "General", "AudioCount": "2", "Duration": "61.480", "OverallBitRate": "1500000"},
+      {"@type": "Video", "StreamOrder": "0", "Duration": "61.440"},
+      {"@type": "Audio", "StreamOrder": "1", "Channels": "2"},
+      {"@type": "Audio", "StreamOrder": "2", "Channels": "6 / 2"}
+    ]
+  }
+}`
+
+func mediaInfoTestResult() *FFprobeResult {
+	return &FFprobeResult{
+		Format: &FormatInfo{Duration: "61.500000", BitRate: "1364000"},
+		Streams: []StreamInfo{
+			{Index: 0, CodecType: "video"},
+			{Index: 1, CodecType: "audio", Channels: 2},
+			{Index: 2, CodecType: "audio", Channels: 8},
+		},
+	}
+}
+
+func TestCrossCheckMediaInfo(t *testing.T) {
+	check, err := crossCheckMediaInfo(mediaInfoTestResult(), []byte(testMediaInfoJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Version != "23.04" || len(check.Comparisons) != 4 || check.Discrepancies != 2 {
+		t.Fatalf("cross-check = %+v", check)
+	}
+
+	matches := make(map[string]bool)
+	for _, comparison := range check.Comparisons {
+		key := comparison.Field
+		if comparison.Stream != nil {
+			key = fmt.Sprintf("%s/%d", key, *comparison.Stream)
+		}
+		matches[key] = comparison.Match
+		if comparison.Match == (comparison.Message != "") {
+			t.Errorf("%s: match %v with message %q", key, comparison.Match, comparison.Message)
+		}
+	}
+	want := map[string]bool{
+		MediaInfoFieldDuration:             true,  // 20 ms apart
+		MediaInfoFieldBitRate:              false, // 10% apart
+		MediaInfoFieldAudioChannels + "/1": true,
+		MediaInfoFieldAudioChannels + "/2": false,
+	}
+	for key, match := range want {
+		if got, ok := matches[key]; !ok || got != match {
+			t.Errorf("%s match = %v (compared %v), want %v", key, got, ok, match)
+		}
+	}
+
+	// A missing audio stream is a discrepancy of its own
+	result := mediaInfoTestResult()
+	result.Streams = result.Streams[:2]
+	check, err = crossCheckMediaInfo(result, []byte(testMediaInfoJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Comparisons[2].Field != MediaInfoFieldAudioStreams || check.Comparisons[2].Match || check.Comparisons[2].Message != "ffprobe finds 1 audio streams, MediaInfo 2" {
+		t.Errorf("audio streams = %+v", check.Comparisons[2])
+	}
+
+	if _, err := crossCheckMediaInfo(result, []byte(`{"creatingLibrary": {"version": "23.04"}}`)); err == nil {
+		t.Error("output without media accepted, want error")
+	}
+}
+
+func TestAnalyzeMediaInfo(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "mediainfo")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat <<'JSON'\n"+testMediaInfoJSON+"\nJSON\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFFprobe("ffprobe", zerolog.Nop())
+	f.SetMediaInfoPath(script)
+	result := mediaInfoTestResult()
+	if err := f.AnalyzeMediaInfo(context.Background(), result, "master.mov"); err != nil {
+		t.Fatal(err)
+	}
+	integrity := result.EnhancedAnalysis.DataIntegrityAnalysis
+	if integrity.MediaInfoCrossCheck == nil || integrity.MediaInfoCrossCheck.Discrepancies != 2 {
+		t.Fatalf("data integrity = %+v", integrity)
+	}
+	if integrity.IsCorrupted || len(integrity.Validation.Recommendations) != 1 {
+		t.Errorf("discrepancies should only add a recommendation: %+v", integrity.Validation)
+	}
+
+	f.SetMediaInfoPath(filepath.Join(dir, "missing"))
+	if err := f.AnalyzeMediaInfo(context.Background(), mediaInfoTestResult(), "master.mov"); err == nil {
+		t.Error("missing mediainfo succeeded, want error")
+	}
+}
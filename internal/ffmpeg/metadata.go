@@ -0,0 +1,351 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Limits for reading embedded metadata, so damaged or hostile files cannot
+// make the reader allocate or scan without bound
+const (
+	maxMetadataBlockBytes  = 16 << 20 // Atom, chunk, tag or segment read whole
+	maxMetadataScanBytes   = 4 << 20  // Start of the file searched for an XMP packet
+	maxMetadataFields      = 1000     // Fields kept per source
+	maxMetadataValueLength = 4096     // Runes kept per value
+)
+
+// Embedded metadata formats
+const (
+	MetadataFormatQuickTime = "quicktime" // udta atoms and meta item lists of MOV and MP4
+	MetadataFormatXMP       = "xmp"
+	MetadataFormatID3v2     = "id3v2"
+	MetadataFormatEXIF      = "exif"
+)
+
+// DescriptiveMetadata is the descriptive metadata embedded in a file beyond
+// the tags ffprobe reports, normalized for asset management ingest. The
+// normalized fields take the first value found, preferring XMP, then
+// QuickTime, ID3v2 and EXIF; Sources keeps every field as written.
+type DescriptiveMetadata struct {
+	Title       string           `json:"title,omitempty"`
+	Creator     string           `json:"creator,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Copyright   string           `json:"copyright,omitempty"`
+	Keywords    []string         `json:"keywords,omitempty"`
+	CreatedAt   string           `json:"created_at,omitempty"` // As written, e.g. 2024-05-01T10:12:00Z or 2024:05:01 10:12:00
+	Software    string           `json:"software,omitempty"`
+	Make        string           `json:"make,omitempty"`
+	Model       string           `json:"model,omitempty"`
+	Location    *GeoLocation     `json:"location,omitempty"`
+	Sources     []MetadataSource `json:"sources"`
+}
+
+// GeoLocation is a recording location in decimal degrees
+type GeoLocation struct {
+	Latitude  float64  `json:"latitude"`
+	Longitude float64  `json:"longitude"`
+	Altitude  *float64 `json:"altitude,omitempty"` // Meters above sea level
+}
+
+// MetadataSource is one embedded metadata format with its fields by the
+// format's own keys: atom types such as ©nam or mdta keys for QuickTime,
+// prefixed property paths for XMP, frame IDs for ID3v2 and tag names for
+// EXIF
+type MetadataSource struct {
+	Format  string            `json:"format"`
+	Version string            `json:"version,omitempty"`
+	Fields  map[string]string `json:"fields"`
+}
+
+// ReadDescriptiveMetadata reads the QuickTime atoms, XMP packets, ID3v2
+// tags and EXIF data embedded in a local file. Only headers and metadata
+// blocks are read, never the media. It returns nil if the file carries none.
+func ReadDescriptiveMetadata(filePath string) (*DescriptiveMetadata, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil
+	}
+	return parseDescriptiveMetadata(f, info.Size())
+}
+
+// parseDescriptiveMetadata reads the metadata of the file in r, which holds
+// size bytes
+func parseDescriptiveMetadata(r io.ReaderAt, size int64) (*DescriptiveMetadata, error) {
+	headSize := size
+	if headSize > maxMetadataScanBytes {
+		headSize = maxMetadataScanBytes
+	}
+	head := make([]byte, headSize)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	head = head[:n]
+
+	m := &metadataCollector{}
+	switch {
+	case bytes.HasPrefix(head, []byte("ID3")):
+		m.readID3v2(head)
+	case bytes.HasPrefix(head, []byte{0xFF, 0xD8}):
+		m.readJPEG(r, size)
+	case bytes.HasPrefix(head, pngSignature):
+		m.readPNG(r, size)
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		// The directories may be anywhere in the file, often at the end
+		if data, err := readMetadataBlock(r, 0, size); err == nil {
+			m.readEXIF(data)
+		} else {
+			m.readEXIF(head)
+		}
+	case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+		m.readRIFF(r, size)
+	case len(head) >= 12 && string(head[:4]) == "FORM" && (string(head[8:12]) == "AIFF" || string(head[8:12]) == "AIFC"):
+		m.readAIFF(r, size)
+	case len(head) >= 8 && isBMFFTopLevelBox(string(head[4:8])):
+		m.readBMFF(r, size)
+	}
+
+	// XMP packets are written to be found by scanning, so look for one in
+	// formats that have no defined place for it
+	if m.source(MetadataFormatXMP) == nil {
+		if start := bytes.Index(head, []byte("<x:xmpmeta")); start >= 0 {
+			m.readXMP(head[start:])
+		}
+	}
+
+	if len(m.sources) == 0 {
+		return nil, nil
+	}
+	return m.normalize(), nil
+}
+
+// metadataCollector gathers fields by format, keeping the first value of
+// each key
+type metadataCollector struct {
+	sources []MetadataSource
+}
+
+// source returns the source of format, or nil if nothing was read from it
+func (m *metadataCollector) source(format string) *MetadataSource {
+	for i := range m.sources {
+		if m.sources[i].Format == format {
+			return &m.sources[i]
+		}
+	}
+	return nil
+}
+
+// add records a field, ignoring empty values
+func (m *metadataCollector) add(format, key, value string) {
+	value = strings.TrimSpace(strings.Trim(value, "\x00"))
+	if key == "" || value == "" {
+		return
+	}
+	source := m.source(format)
+	if source == nil {
+		m.sources = append(m.sources, MetadataSource{Format: format, Fields: map[string]string{}})
+		source = &m.sources[len(m.sources)-1]
+	}
+	if _, ok := source.Fields[key]; ok || len(source.Fields) >= maxMetadataFields {
+		return
+	}
+	if utf8.RuneCountInString(value) > maxMetadataValueLength {
+		value = string([]rune(value)[:maxMetadataValueLength])
+	}
+	source.Fields[key] = value
+}
+
+// setVersion records the version of format, once fields were read from it
+func (m *metadataCollector) setVersion(format, version string) {
+	if source := m.source(format); source != nil && source.Version == "" {
+		source.Version = version
+	}
+}
+
+// metadataKey names a field of a source format
+type metadataKey struct{ format, key string }
+
+// Source fields of each normalized field, in order of preference
+var (
+	titleKeys = []metadataKey{
+		{MetadataFormatXMP, "dc:title"},
+		{MetadataFormatQuickTime, "com.apple.quicktime.title"}, {MetadataFormatQuickTime, "©nam"},
+		{MetadataFormatID3v2, "TIT2"},
+	}
+	creatorKeys = []metadataKey{
+		{MetadataFormatXMP, "dc:creator"},
+		{MetadataFormatQuickTime, "com.apple.quicktime.artist"}, {MetadataFormatQuickTime, "com.apple.quicktime.author"},
+		{MetadataFormatQuickTime, "©ART"}, {MetadataFormatQuickTime, "©aut"},
+		{MetadataFormatID3v2, "TPE1"},
+		{MetadataFormatEXIF, "Artist"},
+	}
+	descriptionKeys = []metadataKey{
+		{MetadataFormatXMP, "dc:description"},
+		{MetadataFormatQuickTime, "com.apple.quicktime.description"}, {MetadataFormatQuickTime, "©des"},
+		{MetadataFormatQuickTime, "desc"}, {MetadataFormatQuickTime, "©cmt"},
+		{MetadataFormatID3v2, "COMM"},
+		{MetadataFormatEXIF, "ImageDescription"},
+	}
+	copyrightKeys = []metadataKey{
+		{MetadataFormatXMP, "dc:rights"},
+		{MetadataFormatQuickTime, "com.apple.quicktime.copyright"}, {MetadataFormatQuickTime, "©cpy"},
+		{MetadataFormatQuickTime, "cprt"},
+		{MetadataFormatID3v2, "TCOP"},
+		{MetadataFormatEXIF, "Copyright"},
+	}
+	keywordKeys = []metadataKey{
+		{MetadataFormatXMP, "dc:subject"},
+		{MetadataFormatQuickTime, "com.apple.quicktime.keywords"},
+	}
+	createdKeys = []metadataKey{
+		{MetadataFormatXMP, "xmp:CreateDate"}, {MetadataFormatXMP, "photoshop:DateCreated"}, {MetadataFormatXMP, "exif:DateTimeOriginal"},
+		{MetadataFormatQuickTime, "com.apple.quicktime.creationdate"}, {MetadataFormatQuickTime, "©day"},
+		{MetadataFormatID3v2, "TDRC"}, {MetadataFormatID3v2, "TYER"},
+		{MetadataFormatEXIF, "DateTimeOriginal"}, {MetadataFormatEXIF, "DateTime"},
+	}
+	softwareKeys = []metadataKey{
+		{MetadataFormatXMP, "xmp:CreatorTool"},
+		{MetadataFormatQuickTime, "com.apple.quicktime.software"}, {MetadataFormatQuickTime, "©too"},
+		{MetadataFormatQuickTime, "©swr"},
+		{MetadataFormatID3v2, "TSSE"},
+		{MetadataFormatEXIF, "Software"},
+	}
+	makeKeys = []metadataKey{
+		{MetadataFormatXMP, "tiff:Make"},
+		{MetadataFormatQuickTime, "com.apple.quicktime.make"}, {MetadataFormatQuickTime, "©mak"},
+		{MetadataFormatEXIF, "Make"},
+	}
+	modelKeys = []metadataKey{
+		{MetadataFormatXMP, "tiff:Model"},
+		{MetadataFormatQuickTime, "com.apple.quicktime.model"}, {MetadataFormatQuickTime, "©mod"},
+		{MetadataFormatEXIF, "Model"},
+	}
+	locationKeys = []metadataKey{
+		{MetadataFormatQuickTime, "com.apple.quicktime.location.ISO6709"}, {MetadataFormatQuickTime, "©xyz"},
+	}
+)
+
+// first returns the first value found for keys
+func (m *metadataCollector) first(keys []metadataKey) string {
+	for _, key := range keys {
+		if source := m.source(key.format); source != nil {
+			if value, ok := source.Fields[key.key]; ok {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// normalize maps the fields read onto the common descriptive fields
+func (m *metadataCollector) normalize() *DescriptiveMetadata {
+	metadata := &DescriptiveMetadata{
+		Title:       m.first(titleKeys),
+		Creator:     m.first(creatorKeys),
+		Description: m.first(descriptionKeys),
+		Copyright:   m.first(copyrightKeys),
+		CreatedAt:   m.first(createdKeys),
+		Software:    m.first(softwareKeys),
+		Make:        m.first(makeKeys),
+		Model:       m.first(modelKeys),
+		Sources:     m.sources,
+	}
+
+	for _, keyword := range strings.FieldsFunc(m.first(keywordKeys), func(r rune) bool { return r == ';' || r == ',' }) {
+		if keyword = strings.TrimSpace(keyword); keyword != "" {
+			metadata.Keywords = append(metadata.Keywords, keyword)
+		}
+	}
+
+	if location, ok := parseISO6709(m.first(locationKeys)); ok {
+		metadata.Location = location
+	} else if exif := m.source(MetadataFormatEXIF); exif != nil {
+		metadata.Location = exifLocation(exif.Fields)
+	}
+	return metadata
+}
+
+// parseISO6709 parses a location such as +37.7749-122.4194+010.000/ as
+// QuickTime writes it
+func parseISO6709(value string) (*GeoLocation, bool) {
+	value = strings.TrimSuffix(strings.TrimSpace(value), "/")
+	var parts []string
+	for i := 0; i < len(value); {
+		if value[i] != '+' && value[i] != '-' {
+			return nil, false
+		}
+		end := strings.IndexAny(value[i+1:], "+-")
+		if end < 0 {
+			end = len(value)
+		} else {
+			end += i + 1
+		}
+		parts = append(parts, value[i:end])
+		i = end
+	}
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, false
+	}
+
+	var numbers []float64
+	for _, part := range parts {
+		number, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return nil, false
+		}
+		numbers = append(numbers, number)
+	}
+	if numbers[0] < -90 || numbers[0] > 90 || numbers[1] < -180 || numbers[1] > 180 {
+		return nil, false
+	}
+	location := &GeoLocation{Latitude: numbers[0], Longitude: numbers[1]}
+	if len(numbers) == 3 {
+		location.Altitude = &numbers[2]
+	}
+	return location, true
+}
+
+// decodeMetadataText decodes text that should be UTF-8 but may be Latin-1,
+// as older QuickTime and ID3 writers use
+func decodeMetadataText(data []byte) string {
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	return decodeLatin1(data)
+}
+
+func decodeLatin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// readMetadataBlock reads length bytes at offset, refusing blocks over the
+// metadata block limit
+func readMetadataBlock(r io.ReaderAt, offset, length int64) ([]byte, error) {
+	if length < 0 || length > maxMetadataBlockBytes {
+		return nil, fmt.Errorf("metadata block of %d bytes at byte %d exceeds the limit", length, offset)
+	}
+	data := make([]byte, length)
+	if _, err := r.ReadAt(data, offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,302 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}
+
+// Identifiers of the JPEG APP1 segments carrying EXIF and XMP
+var (
+	jpegEXIFHeader = []byte("Exif\x00\x00")
+	jpegXMPHeader  = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+// Pointers from the primary image directory to the EXIF and GPS directories
+const (
+	exifTagEXIFDirectory = 0x8769
+	exifTagGPSDirectory  = 0x8825
+)
+
+// exifTags names the TIFF and EXIF tags read from stills
+var exifTags = map[uint16]string{
+	0x010E: "ImageDescription",
+	0x010F: "Make",
+	0x0110: "Model",
+	0x0112: "Orientation",
+	0x0131: "Software",
+	0x0132: "DateTime",
+	0x013B: "Artist",
+	0x8298: "Copyright",
+	0x829A: "ExposureTime",
+	0x829D: "FNumber",
+	0x8827: "ISOSpeedRatings",
+	0x9003: "DateTimeOriginal",
+	0x9004: "DateTimeDigitized",
+	0x9011: "OffsetTimeOriginal",
+	0x920A: "FocalLength",
+	0xA002: "PixelXDimension",
+	0xA003: "PixelYDimension",
+	0xA431: "BodySerialNumber",
+	0xA434: "LensModel",
+}
+
+// exifGPSTags names the GPS tags, by their number in the GPS directory
+var exifGPSTags = map[uint16]string{
+	1: "GPSLatitudeRef",
+	2: "GPSLatitude",
+	3: "GPSLongitudeRef",
+	4: "GPSLongitude",
+	5: "GPSAltitudeRef",
+	6: "GPSAltitude",
+}
+
+// Sizes of the TIFF field types, by type number
+var exifTypeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8}
+
+// readJPEG reads the EXIF and XMP APP1 segments before the image data
+func (m *metadataCollector) readJPEG(r io.ReaderAt, size int64) {
+	header := make([]byte, 4)
+	for offset := int64(2); offset+4 <= size; {
+		if _, err := r.ReadAt(header, offset); err != nil || header[0] != 0xFF {
+			return
+		}
+		marker := header[1]
+		switch {
+		case marker == 0xFF:
+			offset++ // Fill byte
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			offset += 2
+			continue
+		case marker == 0xDA || marker == 0xD9:
+			return // Start of scan or end of image
+		}
+		length := int64(binary.BigEndian.Uint16(header[2:]))
+		if length < 2 {
+			return
+		}
+		if marker == 0xE1 {
+			if segment, err := readMetadataBlock(r, offset+4, length-2); err == nil {
+				switch {
+				case bytes.HasPrefix(segment, jpegEXIFHeader):
+					m.readEXIF(segment[len(jpegEXIFHeader):])
+				case bytes.HasPrefix(segment, jpegXMPHeader):
+					m.readXMP(segment[len(jpegXMPHeader):])
+				}
+			}
+		}
+		offset += 2 + length
+	}
+}
+
+// readPNG reads the eXIf chunk and the XMP iTXt chunk of a PNG file
+func (m *metadataCollector) readPNG(r io.ReaderAt, size int64) {
+	header := make([]byte, 8)
+	for offset, count := int64(len(pngSignature)), 0; offset+12 <= size && count < maxBMFFBoxes; count++ {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return
+		}
+		length := int64(binary.BigEndian.Uint32(header))
+		chunkType := string(header[4:])
+		if chunkType == "IEND" {
+			return
+		}
+		if chunkType == "eXIf" || chunkType == "iTXt" {
+			if data, err := readMetadataBlock(r, offset+8, length); err == nil {
+				if chunkType == "eXIf" {
+					m.readEXIF(data)
+				} else if keyword, rest, ok := bytes.Cut(data, []byte{0}); ok && string(keyword) == "XML:com.adobe.xmp" && len(rest) > 2 && rest[0] == 0 {
+					// Uncompressed text follows the language tag and the
+					// translated keyword
+					parts := bytes.SplitN(rest[2:], []byte{0}, 3)
+					if len(parts) == 3 {
+						m.readXMP(parts[2])
+					}
+				}
+			}
+		}
+		offset += 12 + length // Length, type, data and CRC
+	}
+}
+
+// readEXIF reads the primary image, EXIF and GPS directories of TIFF data
+// as JPEG APP1 segments, PNG eXIf chunks and TIFF files hold it
+func (m *metadataCollector) readEXIF(data []byte) {
+	if len(data) < 8 {
+		return
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return
+	}
+	if order.Uint16(data[2:]) != 42 {
+		return
+	}
+
+	ifd0 := exifDirectory(data, order, order.Uint32(data[4:]))
+	m.readEXIFDirectory(order, ifd0, exifTags)
+	if offset, ok := ifd0[exifTagEXIFDirectory]; ok {
+		m.readEXIFDirectory(order, exifDirectory(data, order, exifUint(order, offset)), exifTags)
+	}
+	if offset, ok := ifd0[exifTagGPSDirectory]; ok {
+		m.readEXIFDirectory(order, exifDirectory(data, order, exifUint(order, offset)), exifGPSTags)
+	}
+}
+
+// exifField is the type, count and value of a directory entry, with the
+// value inline or at its offset
+type exifField struct {
+	fieldType uint16
+	count     uint32
+	value     []byte
+}
+
+// exifDirectory reads the entries of the image file directory at offset
+func exifDirectory(data []byte, order binary.ByteOrder, offset uint32) map[uint16]exifField {
+	fields := map[uint16]exifField{}
+	if uint64(offset)+2 > uint64(len(data)) {
+		return fields
+	}
+	count := int(order.Uint16(data[offset:]))
+	for i := 0; i < count; i++ {
+		entry := int(offset) + 2 + 12*i
+		if entry+12 > len(data) {
+			break
+		}
+		field := exifField{
+			fieldType: order.Uint16(data[entry+2:]),
+			count:     order.Uint32(data[entry+4:]),
+		}
+		typeSize, ok := exifTypeSizes[field.fieldType]
+		if !ok {
+			continue
+		}
+		size := uint64(typeSize) * uint64(field.count)
+		start := uint64(entry + 8)
+		if size > 4 {
+			start = uint64(order.Uint32(data[entry+8:]))
+		}
+		if start+size > uint64(len(data)) {
+			continue
+		}
+		field.value = data[start : start+size]
+		fields[order.Uint16(data[entry:])] = field
+	}
+	return fields
+}
+
+// exifUint reads an integer field
+func exifUint(order binary.ByteOrder, field exifField) uint32 {
+	switch {
+	case field.fieldType == 3 && len(field.value) >= 2:
+		return uint32(order.Uint16(field.value))
+	case (field.fieldType == 4 || field.fieldType == 9) && len(field.value) >= 4:
+		return order.Uint32(field.value)
+	}
+	return 0
+}
+
+// readEXIFDirectory records the named fields of a directory
+func (m *metadataCollector) readEXIFDirectory(order binary.ByteOrder, fields map[uint16]exifField, names map[uint16]string) {
+	for tag, field := range fields {
+		if name, ok := names[tag]; ok {
+			m.add(MetadataFormatEXIF, name, exifValue(field, order))
+		}
+	}
+}
+
+// exifValue formats a field: text as is, numbers in decimal with several
+// values separated by spaces
+func exifValue(field exifField, order binary.ByteOrder) string {
+	switch field.fieldType {
+	case 2:
+		return decodeMetadataText(bytes.TrimRight(field.value, "\x00"))
+	case 7:
+		if isMetadataText(bytes.TrimRight(field.value, "\x00")) {
+			return decodeMetadataText(bytes.TrimRight(field.value, "\x00"))
+		}
+		return ""
+	}
+
+	size := exifTypeSizes[field.fieldType]
+	var values []string
+	for i := 0; i+size <= len(field.value) && len(values) < 16; i += size {
+		value := field.value[i : i+size]
+		switch field.fieldType {
+		case 1:
+			values = append(values, strconv.Itoa(int(value[0])))
+		case 6:
+			values = append(values, strconv.Itoa(int(int8(value[0]))))
+		case 3:
+			values = append(values, strconv.Itoa(int(order.Uint16(value))))
+		case 8:
+			values = append(values, strconv.Itoa(int(int16(order.Uint16(value)))))
+		case 4:
+			values = append(values, strconv.FormatUint(uint64(order.Uint32(value)), 10))
+		case 9:
+			values = append(values, strconv.Itoa(int(int32(order.Uint32(value)))))
+		case 5, 10:
+			numerator, denominator := float64(order.Uint32(value)), float64(order.Uint32(value[4:]))
+			if field.fieldType == 10 {
+				numerator, denominator = float64(int32(order.Uint32(value))), float64(int32(order.Uint32(value[4:])))
+			}
+			if denominator == 0 {
+				values = append(values, "0")
+				continue
+			}
+			values = append(values, strconv.FormatFloat(numerator/denominator, 'f', -1, 64))
+		}
+	}
+	return strings.Join(values, " ")
+}
+
+// exifLocation converts the GPS fields to decimal degrees, or returns nil
+// if the latitude or longitude is missing
+func exifLocation(fields map[string]string) *GeoLocation {
+	latitude, ok := exifDegrees(fields["GPSLatitude"], fields["GPSLatitudeRef"] == "S")
+	if !ok {
+		return nil
+	}
+	longitude, ok := exifDegrees(fields["GPSLongitude"], fields["GPSLongitudeRef"] == "W")
+	if !ok {
+		return nil
+	}
+	location := &GeoLocation{Latitude: latitude, Longitude: longitude}
+	if altitude, err := strconv.ParseFloat(fields["GPSAltitude"], 64); err == nil {
+		if fields["GPSAltitudeRef"] == "1" {
+			altitude = -altitude
+		}
+		location.Altitude = &altitude
+	}
+	return location
+}
+
+// exifDegrees converts degrees, minutes and seconds to decimal degrees
+func exifDegrees(value string, negative bool) (float64, bool) {
+	parts := strings.Fields(value)
+	if len(parts) != 3 {
+		return 0, false
+	}
+	var degrees float64
+	for i, part := range parts {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, false
+		}
+		degrees += n / []float64{1, 60, 3600}[i]
+	}
+	if negative {
+		degrees = -degrees
+	}
+	return degrees, true
+}
@@ -0,0 +1,293 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// id3v22Frames maps the three-character frame IDs of ID3v2.2 to their
+// v2.3 equivalents, so every version normalizes the same way
+var id3v22Frames = map[string]string{
+	"TT2": "TIT2", "TT3": "TIT3", "TP1": "TPE1", "TP2": "TPE2", "TAL": "TALB",
+	"TCR": "TCOP", "TYE": "TYER", "TSS": "TSSE", "TEN": "TENC", "TCO": "TCON",
+	"TRK": "TRCK", "TXX": "TXXX", "COM": "COMM", "PIC": "APIC",
+}
+
+// readID3v2 reads the text, comment, URL and picture frames of the ID3v2
+// tag at the start of data
+func (m *metadataCollector) readID3v2(data []byte) {
+	if len(data) < 10 || string(data[:3]) != "ID3" {
+		return
+	}
+	version := data[3]
+	flags := data[5]
+	size := id3SyncsafeInt(data[6:10])
+	if version < 2 || version > 4 || size < 0 {
+		return
+	}
+	tag := data[10:min(len(data), 10+size)]
+
+	if flags&0x80 != 0 && version < 4 {
+		tag = id3Resync(tag)
+	}
+	if version == 2 && flags&0x40 != 0 {
+		// ID3v2.2 compression was never defined
+		return
+	}
+	if flags&0x40 != 0 && len(tag) >= 4 {
+		skip := int(binary.BigEndian.Uint32(tag)) + 4
+		if version == 4 {
+			skip = id3SyncsafeInt(tag)
+		}
+		if skip < 0 || skip > len(tag) {
+			return
+		}
+		tag = tag[skip:]
+	}
+
+	idLength, headerLength := 4, 10
+	if version == 2 {
+		idLength, headerLength = 3, 6
+	}
+	for offset := 0; offset+headerLength <= len(tag); {
+		id := string(tag[offset : offset+idLength])
+		if tag[offset] == 0 {
+			break // Padding
+		}
+		var frameSize int
+		var formatFlags byte
+		switch version {
+		case 2:
+			frameSize = int(tag[offset+3])<<16 | int(tag[offset+4])<<8 | int(tag[offset+5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(tag[offset+4:]))
+			// Compression, encryption and grouping
+			if tag[offset+9]&0xE0 != 0 {
+				formatFlags = 0xFF
+			}
+		default:
+			frameSize = id3SyncsafeInt(tag[offset+4:])
+			formatFlags = tag[offset+9]
+		}
+		offset += headerLength
+		if frameSize < 0 || frameSize > len(tag)-offset {
+			break
+		}
+		frame := tag[offset : offset+frameSize]
+		offset += frameSize
+
+		if version == 4 {
+			// Compression and encryption
+			if formatFlags&0x0C != 0 {
+				continue
+			}
+			if formatFlags&0x40 != 0 && len(frame) >= 1 {
+				frame = frame[1:] // Group identifier
+			}
+			if formatFlags&0x01 != 0 && len(frame) >= 4 {
+				frame = frame[4:] // Data length indicator
+			}
+			if formatFlags&0x02 != 0 {
+				frame = id3Resync(frame)
+			}
+		} else if formatFlags != 0 {
+			continue
+		}
+		if v22, ok := id3v22Frames[id]; ok && version == 2 {
+			id = v22
+		}
+		m.readID3Frame(id, frame, version == 2)
+	}
+	m.setVersion(MetadataFormatID3v2, fmt.Sprintf("2.%d", version))
+}
+
+// readID3Frame records one frame. Frames other than text, comment, URL,
+// picture and XMP private frames are skipped.
+func (m *metadataCollector) readID3Frame(id string, frame []byte, v22 bool) {
+	if len(frame) == 0 {
+		return
+	}
+	switch {
+	case id == "TXXX" || id == "WXXX":
+		description, value := id3SplitString(frame[1:], frame[0])
+		if id == "WXXX" {
+			// URLs are always Latin-1
+			m.add(MetadataFormatID3v2, id3DescribedKey(id, description), decodeLatin1(bytes.TrimRight(value, "\x00")))
+			return
+		}
+		m.add(MetadataFormatID3v2, id3DescribedKey(id, description), id3Text(value, frame[0]))
+	case id[0] == 'T':
+		m.add(MetadataFormatID3v2, id, id3Text(frame[1:], frame[0]))
+	case id[0] == 'W':
+		m.add(MetadataFormatID3v2, id, decodeLatin1(bytes.TrimRight(frame, "\x00")))
+	case id == "COMM" || id == "USLT":
+		if len(frame) < 4 {
+			return
+		}
+		description, value := id3SplitString(frame[4:], frame[0])
+		m.add(MetadataFormatID3v2, id3DescribedKey(id, description), id3Text(value, frame[0]))
+	case id == "APIC":
+		encoding := frame[0]
+		var mimeType string
+		rest := frame[1:]
+		if v22 {
+			// ID3v2.2 names the image format in three characters
+			if len(rest) < 3 {
+				return
+			}
+			mimeType, rest = "image/"+strings.ToLower(string(rest[:3])), rest[3:]
+		} else {
+			end := bytes.IndexByte(rest, 0)
+			if end < 0 {
+				return
+			}
+			mimeType, rest = string(rest[:end]), rest[end+1:]
+		}
+		if len(rest) < 1 {
+			return
+		}
+		_, image := id3SplitString(rest[1:], encoding)
+		m.add(MetadataFormatID3v2, id, fmt.Sprintf("%s, %d bytes", mimeType, len(image)))
+	case id == "PRIV":
+		owner, data, ok := bytes.Cut(frame, []byte{0})
+		if ok && string(owner) == "XMP" {
+			m.readXMP(data)
+		}
+	}
+}
+
+// id3DescribedKey keys frames that may repeat with different descriptions,
+// such as TXXX:ISRC
+func id3DescribedKey(id, description string) string {
+	if description == "" {
+		return id
+	}
+	return id + ":" + description
+}
+
+// id3SplitString splits a terminated string from the rest of data. The
+// terminator is two zero bytes in the UTF-16 encodings.
+func id3SplitString(data []byte, encoding byte) (text string, rest []byte) {
+	if encoding == 1 || encoding == 2 {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				return id3Text(data[:i], encoding), data[i+2:]
+			}
+		}
+		return "", data
+	}
+	before, after, ok := bytes.Cut(data, []byte{0})
+	if !ok {
+		return "", data
+	}
+	return id3Text(before, encoding), after
+}
+
+// id3Text decodes text in one of the four ID3v2 encodings. ID3v2.4 lists
+// several values separated by zero characters, which are joined with "; ".
+func id3Text(data []byte, encoding byte) string {
+	var text string
+	switch encoding {
+	case 0:
+		text = decodeLatin1(data)
+	case 1, 2:
+		order := binary.ByteOrder(binary.BigEndian)
+		if encoding == 1 && len(data) >= 2 {
+			if data[0] == 0xFF && data[1] == 0xFE {
+				order = binary.LittleEndian
+			}
+			if (data[0] == 0xFF && data[1] == 0xFE) || (data[0] == 0xFE && data[1] == 0xFF) {
+				data = data[2:]
+			}
+		}
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = order.Uint16(data[2*i:])
+		}
+		text = string(utf16.Decode(units))
+		text = strings.ReplaceAll(text, "\uFEFF", "")
+	default:
+		text = decodeMetadataText(data)
+	}
+
+	var values []string
+	for _, value := range strings.Split(text, "\x00") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return strings.Join(values, "; ")
+}
+
+// id3SyncsafeInt reads a 28-bit integer stored seven bits per byte, or -1
+// if the high bits are set
+func id3SyncsafeInt(data []byte) int {
+	if len(data) < 4 {
+		return -1
+	}
+	n := 0
+	for _, b := range data[:4] {
+		if b&0x80 != 0 {
+			return -1
+		}
+		n = n<<7 | int(b)
+	}
+	return n
+}
+
+// id3Resync undoes unsynchronisation, which inserts a zero byte after every
+// 0xFF
+func id3Resync(data []byte) []byte {
+	return bytes.ReplaceAll(data, []byte{0xFF, 0x00}, []byte{0xFF})
+}
+
+// readRIFF reads the ID3 and XMP chunks of a WAVE file
+func (m *metadataCollector) readRIFF(r io.ReaderAt, size int64) {
+	walkChunks(r, 12, size, binary.LittleEndian, func(id string, data func() ([]byte, error)) {
+		switch id {
+		case "id3 ", "ID3 ":
+			if tag, err := data(); err == nil {
+				m.readID3v2(tag)
+			}
+		case "_PMX":
+			if packet, err := data(); err == nil {
+				m.readXMP(packet)
+			}
+		}
+	})
+}
+
+// readAIFF reads the ID3 chunk of an AIFF file
+func (m *metadataCollector) readAIFF(r io.ReaderAt, size int64) {
+	walkChunks(r, 12, size, binary.BigEndian, func(id string, data func() ([]byte, error)) {
+		if id == "ID3 " || id == "id3 " {
+			if tag, err := data(); err == nil {
+				m.readID3v2(tag)
+			}
+		}
+	})
+}
+
+// walkChunks walks the word-aligned chunks of a RIFF or AIFF file from
+// offset, reading a chunk's data only when visit asks for it
+func walkChunks(r io.ReaderAt, offset, size int64, order binary.ByteOrder, visit func(id string, data func() ([]byte, error))) {
+	header := make([]byte, 8)
+	for count := 0; offset+8 <= size && count < maxBMFFBoxes; count++ {
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return
+		}
+		length := int64(order.Uint32(header[4:]))
+		start := offset + 8
+		if length > size-start {
+			length = size - start
+		}
+		visit(string(header[:4]), func() ([]byte, error) {
+			return readMetadataBlock(r, start, length)
+		})
+		offset = start + length + length%2
+	}
+}
@@ -0,0 +1,264 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"unicode/utf16"
+)
+
+// xmpUUID is the uuid box type Adobe defines for XMP in ISO base media files
+var xmpUUID = []byte{0xBE, 0x7A, 0xCF, 0xCB, 0x97, 0xA9, 0x42, 0xE8, 0x9C, 0x71, 0x99, 0x94, 0x91, 0xE3, 0xAF, 0xAC}
+
+// maxBMFFBoxes bounds the boxes walked at one level
+const maxBMFFBoxes = 10000
+
+// isBMFFTopLevelBox reports whether a file starting with a box of this type
+// is a QuickTime or ISO base media file
+func isBMFFTopLevelBox(boxType string) bool {
+	switch boxType {
+	case "ftyp", "moov", "mdat", "wide", "free", "skip", "pnot":
+		return true
+	}
+	return false
+}
+
+// bmffBox locates one box
+type bmffBox struct {
+	boxType string
+	offset  int64 // Start of the payload
+	size    int64 // Payload bytes
+}
+
+// walkBMFFBoxes calls visit for each box between start and end, stopping at
+// the first malformed header
+func walkBMFFBoxes(r io.ReaderAt, start, end int64, visit func(box bmffBox)) {
+	header := make([]byte, 16)
+	for offset, count := start, 0; offset+8 <= end && count < maxBMFFBoxes; count++ {
+		if _, err := r.ReadAt(header[:8], offset); err != nil {
+			return
+		}
+		size := int64(binary.BigEndian.Uint32(header))
+		headerSize := int64(8)
+		switch size {
+		case 0:
+			size = end - offset
+		case 1:
+			if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+				return
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize || size > end-offset {
+			return
+		}
+		visit(bmffBox{boxType: string(header[4:8]), offset: offset + headerSize, size: size - headerSize})
+		offset += size
+	}
+}
+
+// walkBMFFPayload is walkBMFFBoxes over boxes already in memory
+func walkBMFFPayload(data []byte, visit func(box bmffBox)) {
+	walkBMFFBoxes(bytes.NewReader(data), 0, int64(len(data)), visit)
+}
+
+// readBMFF reads the udta and meta atoms of the movie and any XMP uuid box
+func (m *metadataCollector) readBMFF(r io.ReaderAt, size int64) {
+	walkBMFFBoxes(r, 0, size, func(box bmffBox) {
+		switch box.boxType {
+		case "moov":
+			walkBMFFBoxes(r, box.offset, box.offset+box.size, func(child bmffBox) {
+				if child.boxType != "udta" && child.boxType != "meta" {
+					return
+				}
+				data, err := readMetadataBlock(r, child.offset, child.size)
+				if err != nil {
+					return
+				}
+				if child.boxType == "udta" {
+					m.readUserData(data)
+				} else {
+					m.readMetaBox(data)
+				}
+			})
+		case "uuid":
+			if box.size > 16 {
+				if id, err := readMetadataBlock(r, box.offset, 16); err == nil && bytes.Equal(id, xmpUUID) {
+					if packet, err := readMetadataBlock(r, box.offset+16, box.size-16); err == nil {
+						m.readXMP(packet)
+					}
+				}
+			}
+		}
+	})
+}
+
+// readUserData reads the atoms of a movie udta. Atoms whose type starts
+// with © hold text items; XMP_ holds an XMP packet and meta an item list.
+func (m *metadataCollector) readUserData(data []byte) {
+	walkBMFFPayload(data, func(box bmffBox) {
+		payload := data[box.offset : box.offset+box.size]
+		switch {
+		case box.boxType == "XMP_":
+			m.readXMP(payload)
+		case box.boxType == "meta":
+			m.readMetaBox(payload)
+		case box.boxType[0] == 0xA9:
+			m.add(MetadataFormatQuickTime, quickTimeKey(box.boxType), quickTimeUserDataText(payload))
+		}
+	})
+}
+
+// quickTimeUserDataText reads the first text item of a © atom: a 16-bit
+// length, a 16-bit language code and the text
+func quickTimeUserDataText(payload []byte) string {
+	if len(payload) < 4 {
+		return ""
+	}
+	length := int(binary.BigEndian.Uint16(payload))
+	if length > len(payload)-4 {
+		// Some writers store the text with no item header
+		return decodeMetadataText(payload)
+	}
+	return decodeMetadataText(payload[4 : 4+length])
+}
+
+// quickTimeKey spells an atom type with its leading 0xA9 as ©
+func quickTimeKey(atomType string) string {
+	if atomType != "" && atomType[0] == 0xA9 {
+		return "©" + atomType[1:]
+	}
+	return atomType
+}
+
+// readMetaBox reads a meta atom's item list, keyed by the item atom type,
+// or for mdta handlers by the key names in the keys atom
+func (m *metadataCollector) readMetaBox(data []byte) {
+	// ISO meta boxes carry version and flags before their children, the
+	// QuickTime ones do not
+	if len(data) >= 8 && string(data[4:8]) != "hdlr" && string(data[4:8]) != "keys" {
+		data = data[4:]
+	}
+
+	var keys []string
+	var items []bmffBox
+	walkBMFFPayload(data, func(box bmffBox) {
+		payload := data[box.offset : box.offset+box.size]
+		switch box.boxType {
+		case "keys":
+			keys = quickTimeMetadataKeys(payload)
+		case "ilst":
+			walkBMFFPayload(payload, func(item bmffBox) {
+				item.offset += box.offset
+				items = append(items, item)
+			})
+		}
+	})
+
+	for _, item := range items {
+		key := quickTimeKey(item.boxType)
+		if index := binary.BigEndian.Uint32([]byte(item.boxType)); len(keys) > 0 && index >= 1 && int(index) <= len(keys) {
+			key = keys[index-1]
+		}
+		payload := data[item.offset : item.offset+item.size]
+		var name string
+		walkBMFFPayload(payload, func(box bmffBox) {
+			value := payload[box.offset : box.offset+box.size]
+			switch box.boxType {
+			case "name":
+				// Freeform ---- items are named by their name atom
+				if len(value) > 4 {
+					name = string(value[4:])
+				}
+			case "data":
+				if item.boxType == "----" && name != "" {
+					key = name
+				}
+				m.add(MetadataFormatQuickTime, key, quickTimeDataValue(value))
+			}
+		})
+	}
+}
+
+// quickTimeMetadataKeys reads the key names of a keys atom
+func quickTimeMetadataKeys(payload []byte) []string {
+	if len(payload) < 8 {
+		return nil
+	}
+	count := binary.BigEndian.Uint32(payload[4:])
+	var keys []string
+	for offset := 8; offset+8 <= len(payload) && uint32(len(keys)) < count; {
+		size := int(binary.BigEndian.Uint32(payload[offset:]))
+		if size < 8 || offset+size > len(payload) {
+			break
+		}
+		keys = append(keys, string(payload[offset+8:offset+size]))
+		offset += size
+	}
+	return keys
+}
+
+// quickTimeDataValue formats the value of a data atom by its well-known
+// type. Images and other binary values are described by type and size.
+func quickTimeDataValue(value []byte) string {
+	if len(value) < 8 {
+		return ""
+	}
+	dataType := binary.BigEndian.Uint32(value) & 0xFFFFFF
+	data := value[8:]
+	switch dataType {
+	case 1, 4: // UTF-8
+		return string(data)
+	case 2, 5: // UTF-16 big-endian
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(data[2*i:])
+		}
+		return string(utf16.Decode(units))
+	case 13:
+		return fmt.Sprintf("JPEG image, %d bytes", len(data))
+	case 14:
+		return fmt.Sprintf("PNG image, %d bytes", len(data))
+	case 21, 22, 65, 66, 67, 74, 75, 76, 77, 78: // Signed and unsigned integers
+		if len(data) == 0 || len(data) > 8 {
+			break
+		}
+		var n uint64
+		for _, b := range data {
+			n = n<<8 | uint64(b)
+		}
+		signed := dataType == 21 || (dataType >= 65 && dataType <= 67) || dataType == 74
+		if signed {
+			shift := 64 - 8*uint(len(data))
+			return strconv.FormatInt(int64(n<<shift)>>shift, 10)
+		}
+		return strconv.FormatUint(n, 10)
+	case 23: // 32-bit float
+		if len(data) == 4 {
+			return strconv.FormatFloat(float64(math.Float32frombits(binary.BigEndian.Uint32(data))), 'f', -1, 32)
+		}
+	case 24: // 64-bit float
+		if len(data) == 8 {
+			return strconv.FormatFloat(math.Float64frombits(binary.BigEndian.Uint64(data)), 'f', -1, 64)
+		}
+	case 0: // Implicit, as iTunes writes track numbers
+		if len(data) > 0 && isMetadataText(data) {
+			return string(data)
+		}
+	}
+	return fmt.Sprintf("binary data type %d, %d bytes", dataType, len(data))
+}
+
+// isMetadataText reports whether data looks like text rather than binary
+func isMetadataText(data []byte) bool {
+	for _, r := range decodeMetadataText(data) {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,241 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const testXMPPacket = `<?xpacket begin="" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:xmpDM="http://ns.adobe.com/xmp/1.0/DynamicMedia/" xmp:CreatorTool="Premiere Pro">
+   <dc:title><rdf:Alt><rdf:li xml:lang="x-default">Harbour at Dawn</rdf:li><rdf:li xml:lang="fr">Port à l'aube</rdf:li></rdf:Alt></dc:title>
+   <dc:creator><rdf:Seq><rdf:li>Ana Ruiz</rdf:li></rdf:Seq></dc:creator>
+   <dc:subject><rdf:Bag><rdf:li>harbour</rdf:li><rdf:li>sunrise</rdf:li></rdf:Bag></dc:subject>
+   <xmp:CreateDate>2024-05-01T06:12:00Z</xmp:CreateDate>
+   <xmpDM:duration rdf:parseType="Resource"><xmpDM:value>1500</xmpDM:value><xmpDM:scale>1/25</xmpDM:scale></xmpDM:duration>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`
+
+func testBox(boxType string, payload ...[]byte) []byte {
+	data := bytes.Join(payload, nil)
+	box := binary.BigEndian.AppendUint32(nil, uint32(8+len(data)))
+	return append(append(box, boxType...), data...)
+}
+
+func testDataAtom(dataType uint32, value []byte) []byte {
+	payload := binary.BigEndian.AppendUint32(nil, dataType)
+	payload = append(payload, 0, 0, 0, 0)
+	return testBox("data", append(payload, value...))
+}
+
+func TestReadDescriptiveMetadata_QuickTime(t *testing.T) {
+	text := func(value string) []byte {
+		item := binary.BigEndian.AppendUint16(nil, uint16(len(value)))
+		return append(append(item, 0x15, 0xC7), value...)
+	}
+	key := func(name string) []byte {
+		return testBox("mdta", []byte(name))
+	}
+	keys := testBox("keys", []byte{0, 0, 0, 0, 0, 0, 0, 2}, key("com.apple.quicktime.make"), key("com.apple.quicktime.location.ISO6709"))
+	ilst := testBox("ilst",
+		testBox("\x00\x00\x00\x01", testDataAtom(1, []byte("Apple"))),
+		testBox("\x00\x00\x00\x02", testDataAtom(1, []byte("+37.7749-122.4194+010.000/"))),
+	)
+	hdlr := testBox("hdlr", make([]byte, 8), []byte("mdta"), make([]byte, 13))
+	udta := testBox("udta",
+		testBox("\xA9nam", text("Harbour")),
+		testBox("\xA9day", text("2024-05-01")),
+		testBox("XMP_", []byte(testXMPPacket)),
+	)
+	moov := testBox("moov",
+		testBox("mvhd", make([]byte, 100)),
+		testBox("trak", testBox("tkhd", make([]byte, 84))),
+		udta,
+		testBox("meta", hdlr, keys, ilst),
+	)
+	file := append(testBox("ftyp", []byte("qt  \x00\x00\x02\x00qt  ")), moov...)
+	file = append(file, testBox("mdat", make([]byte, 1024))...)
+
+	metadata, err := parseDescriptiveMetadata(bytes.NewReader(file), int64(len(file)))
+	if err != nil {
+		t.Fatalf("parseDescriptiveMetadata() error = %v", err)
+	}
+	if metadata == nil {
+		t.Fatal("parseDescriptiveMetadata() = nil")
+	}
+
+	// XMP is preferred over the QuickTime atoms
+	if metadata.Title != "Harbour at Dawn" || metadata.Creator != "Ana Ruiz" || metadata.Software != "Premiere Pro" {
+		t.Errorf("title, creator, software = %q, %q, %q", metadata.Title, metadata.Creator, metadata.Software)
+	}
+	if metadata.CreatedAt != "2024-05-01T06:12:00Z" || metadata.Make != "Apple" {
+		t.Errorf("created, make = %q, %q", metadata.CreatedAt, metadata.Make)
+	}
+	if !slices.Equal(metadata.Keywords, []string{"harbour", "sunrise"}) {
+		t.Errorf("Keywords = %v", metadata.Keywords)
+	}
+	if metadata.Location == nil || metadata.Location.Latitude != 37.7749 || metadata.Location.Longitude != -122.4194 || metadata.Location.Altitude == nil || *metadata.Location.Altitude != 10 {
+		t.Errorf("Location = %+v", metadata.Location)
+	}
+
+	var quickTime, xmp map[string]string
+	for _, source := range metadata.Sources {
+		switch source.Format {
+		case MetadataFormatQuickTime:
+			quickTime = source.Fields
+		case MetadataFormatXMP:
+			xmp = source.Fields
+		}
+	}
+	if quickTime["©nam"] != "Harbour" || quickTime["©day"] != "2024-05-01" || quickTime["com.apple.quicktime.make"] != "Apple" {
+		t.Errorf("QuickTime fields = %v", quickTime)
+	}
+	if xmp["xmpDM:duration/xmpDM:value"] != "1500" || xmp["xmpDM:duration/xmpDM:scale"] != "1/25" {
+		t.Errorf("XMP struct fields = %v", xmp)
+	}
+}
+
+func TestReadDescriptiveMetadata_ID3v2(t *testing.T) {
+	frame := func(id string, payload []byte) []byte {
+		data := append([]byte(id), byte(len(payload)>>21&0x7F), byte(len(payload)>>14&0x7F), byte(len(payload)>>7&0x7F), byte(len(payload)&0x7F), 0, 0)
+		return append(data, payload...)
+	}
+	// UTF-16 with a byte order mark, little-endian
+	utf16Title := []byte{1, 0xFF, 0xFE, 'L', 0, 'i', 0, 'v', 0, 'e', 0}
+	frames := bytes.Join([][]byte{
+		frame("TIT2", utf16Title),
+		frame("TPE1", []byte("\x03Band\x00Guest")),
+		frame("TDRC", []byte("\x002019")),
+		frame("TXXX", []byte("\x00ISRC\x00GBAYE0000001")),
+		frame("COMM", []byte("\x00eng\x00Recorded live")),
+		frame("APIC", append([]byte("\x00image/jpeg\x00\x03\x00"), make([]byte, 500)...)),
+	}, nil)
+	frames = append(frames, make([]byte, 64)...) // Padding
+	size := len(frames)
+	tag := append([]byte("ID3\x04\x00\x00"), byte(size>>21&0x7F), byte(size>>14&0x7F), byte(size>>7&0x7F), byte(size&0x7F))
+	file := append(append(tag, frames...), 0xFF, 0xFB, 0x90, 0x00)
+
+	metadata, err := parseDescriptiveMetadata(bytes.NewReader(file), int64(len(file)))
+	if err != nil || metadata == nil {
+		t.Fatalf("parseDescriptiveMetadata() = %v, %v", metadata, err)
+	}
+	if metadata.Title != "Live" || metadata.Creator != "Band; Guest" || metadata.CreatedAt != "2019" || metadata.Description != "Recorded live" {
+		t.Errorf("normalized = %+v", metadata)
+	}
+	source := metadata.Sources[0]
+	if source.Format != MetadataFormatID3v2 || source.Version != "2.4" {
+		t.Errorf("source = %s %s", source.Format, source.Version)
+	}
+	if source.Fields["TXXX:ISRC"] != "GBAYE0000001" || source.Fields["APIC"] != "image/jpeg, 500 bytes" {
+		t.Errorf("fields = %v", source.Fields)
+	}
+}
+
+func TestReadDescriptiveMetadata_JPEGEXIF(t *testing.T) {
+	// Little-endian TIFF with IFD0 (Make, Model, GPS pointer) and a GPS IFD
+	order := binary.LittleEndian
+	tiff := []byte("II*\x00")
+	tiff = order.AppendUint32(tiff, 8)
+	entry := func(data []byte, tag, fieldType uint16, count, value uint32) []byte {
+		data = order.AppendUint16(data, tag)
+		data = order.AppendUint16(data, fieldType)
+		data = order.AppendUint32(data, count)
+		return order.AppendUint32(data, value)
+	}
+	// IFD0 at 8: 3 entries, then the next IFD offset; values follow at 50
+	const valuesAt = 8 + 2 + 3*12 + 4
+	tiff = order.AppendUint16(tiff, 3)
+	tiff = entry(tiff, 0x010F, 2, 6, valuesAt)     // Make "Canon\0"
+	tiff = entry(tiff, 0x0110, 2, 4, 0x00523552)   // Model "R5R" inline, little-endian bytes
+	tiff = entry(tiff, 0x8825, 4, 1, valuesAt+6+2) // GPS IFD
+	tiff = order.AppendUint32(tiff, 0)
+	tiff = append(tiff, "Canon\x00\x00\x00"...)
+	// GPS IFD: 4 entries, rationals after it
+	gpsAt := len(tiff)
+	rationalsAt := uint32(gpsAt + 2 + 4*12 + 4)
+	tiff = order.AppendUint16(tiff, 4)
+	tiff = entry(tiff, 1, 2, 2, uint32('S'))
+	tiff = entry(tiff, 2, 5, 3, rationalsAt)
+	tiff = entry(tiff, 3, 2, 2, uint32('E'))
+	tiff = entry(tiff, 4, 5, 3, rationalsAt+24)
+	tiff = order.AppendUint32(tiff, 0)
+	for _, rational := range [][2]uint32{{33, 1}, {51, 1}, {54, 1}, {151, 1}, {12, 1}, {36, 1}} {
+		tiff = order.AppendUint32(tiff, rational[0])
+		tiff = order.AppendUint32(tiff, rational[1])
+	}
+
+	segment := func(marker byte, payload []byte) []byte {
+		data := []byte{0xFF, marker}
+		data = binary.BigEndian.AppendUint16(data, uint16(len(payload)+2))
+		return append(data, payload...)
+	}
+	file := []byte{0xFF, 0xD8}
+	file = append(file, segment(0xE0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))...)
+	file = append(file, segment(0xE1, append([]byte("Exif\x00\x00"), tiff...))...)
+	file = append(file, segment(0xDA, make([]byte, 10))...)
+	file = append(file, 0xFF, 0xD9)
+
+	metadata, err := parseDescriptiveMetadata(bytes.NewReader(file), int64(len(file)))
+	if err != nil || metadata == nil {
+		t.Fatalf("parseDescriptiveMetadata() = %v, %v", metadata, err)
+	}
+	if metadata.Make != "Canon" || metadata.Model != "R5R" {
+		t.Errorf("make, model = %q, %q", metadata.Make, metadata.Model)
+	}
+	if metadata.Location == nil {
+		t.Fatal("Location = nil")
+	}
+	if lat := metadata.Location.Latitude; lat > -33.86 || lat < -33.87 {
+		t.Errorf("Latitude = %v", lat)
+	}
+	if lon := metadata.Location.Longitude; lon < 151.2 || lon > 151.22 {
+		t.Errorf("Longitude = %v", lon)
+	}
+}
+
+func TestReadDescriptiveMetadata_None(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plain.ts")
+	if err := os.WriteFile(path, bytes.Repeat([]byte{0x47, 0x1F, 0xFF, 0x10}, 1000), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := ReadDescriptiveMetadata(path)
+	if err != nil || metadata != nil {
+		t.Errorf("ReadDescriptiveMetadata() = %v, %v, want nil", metadata, err)
+	}
+
+	// Directories such as IMF packages are skipped
+	if metadata, err := ReadDescriptiveMetadata(t.TempDir()); err != nil || metadata != nil {
+		t.Errorf("ReadDescriptiveMetadata(dir) = %v, %v, want nil", metadata, err)
+	}
+}
+
+func TestParseISO6709(t *testing.T) {
+	tests := []struct {
+		value    string
+		ok       bool
+		lat, lon float64
+	}{
+		{"+48.8577+002.2950/", true, 48.8577, 2.295},
+		{"-33.8688+151.2093+005.000/", true, -33.8688, 151.2093},
+		{"48.85 2.29", false, 0, 0},
+		{"+95.0+000.0/", false, 0, 0},
+	}
+	for _, tt := range tests {
+		location, ok := parseISO6709(tt.value)
+		if ok != tt.ok {
+			t.Errorf("parseISO6709(%q) ok = %v, want %v", tt.value, ok, tt.ok)
+			continue
+		}
+		if ok && (location.Latitude != tt.lat || location.Longitude != tt.lon) {
+			t.Errorf("parseISO6709(%q) = %+v", tt.value, location)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+const rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+// xmlNode is any XML element, for walking an XMP packet's RDF
+type xmlNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Text     string     `xml:",chardata"`
+	Children []xmlNode  `xml:",any"`
+}
+
+// readXMP reads the properties of an XMP packet, keyed by the namespace
+// prefix the packet declares and the property name, e.g. dc:title. Struct
+// fields are keyed by their path, e.g. xmpDM:duration/xmpDM:value, and
+// array items are joined with "; ".
+func (m *metadataCollector) readXMP(packet []byte) {
+	if start := bytes.Index(packet, []byte("<x:xmpmeta")); start >= 0 {
+		packet = packet[start:]
+		if end := bytes.Index(packet, []byte("</x:xmpmeta>")); end >= 0 {
+			packet = packet[:end+len("</x:xmpmeta>")]
+		}
+	}
+
+	var root xmlNode
+	if err := xml.Unmarshal(packet, &root); err != nil {
+		return
+	}
+	prefixes := map[string]string{}
+	root.collectPrefixes(prefixes)
+
+	for _, description := range root.find(rdfNamespace, "Description") {
+		xmpProperties(description, "", prefixes, func(key, value string) {
+			m.add(MetadataFormatXMP, key, value)
+		})
+	}
+}
+
+// collectPrefixes maps every namespace declared in the tree to its prefix
+func (n *xmlNode) collectPrefixes(prefixes map[string]string) {
+	for _, attr := range n.Attrs {
+		if attr.Name.Space == "xmlns" {
+			if _, ok := prefixes[attr.Value]; !ok {
+				prefixes[attr.Value] = attr.Name.Local
+			}
+		}
+	}
+	for i := range n.Children {
+		n.Children[i].collectPrefixes(prefixes)
+	}
+}
+
+// find returns the outermost elements with the given name
+func (n *xmlNode) find(space, local string) []xmlNode {
+	if n.XMLName.Space == space && n.XMLName.Local == local {
+		return []xmlNode{*n}
+	}
+	var found []xmlNode
+	for i := range n.Children {
+		found = append(found, n.Children[i].find(space, local)...)
+	}
+	return found
+}
+
+// xmpName spells a name with its declared prefix
+func xmpName(name xml.Name, prefixes map[string]string) string {
+	if prefix, ok := prefixes[name.Space]; ok && prefix != "" {
+		return prefix + ":" + name.Local
+	}
+	return name.Local
+}
+
+// xmpProperties reports the properties of an rdf:Description, or of a
+// struct value, written either as attributes or as child elements
+func xmpProperties(node xmlNode, path string, prefixes map[string]string, report func(key, value string)) {
+	for _, attr := range node.Attrs {
+		if attr.Name.Space == "xmlns" || attr.Name.Space == rdfNamespace || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		if attr.Name.Space == "xml" || attr.Name.Space == "http://www.w3.org/XML/1998/namespace" {
+			continue
+		}
+		report(path+xmpName(attr.Name, prefixes), attr.Value)
+	}
+	for _, child := range node.Children {
+		xmpValue(child, path+xmpName(child.XMLName, prefixes), prefixes, report)
+	}
+}
+
+// xmpValue reports a property element's value: an array, a resource, a
+// struct or plain text
+func xmpValue(node xmlNode, key string, prefixes map[string]string, report func(key, value string)) {
+	for _, attr := range node.Attrs {
+		if attr.Name.Space == rdfNamespace && attr.Name.Local == "resource" {
+			report(key, attr.Value)
+			return
+		}
+	}
+
+	for _, child := range node.Children {
+		if child.XMLName.Space != rdfNamespace {
+			continue
+		}
+		switch child.XMLName.Local {
+		case "Alt", "Seq", "Bag":
+			var items []string
+			for _, item := range child.Children {
+				if text := strings.TrimSpace(item.Text); text != "" {
+					items = append(items, text)
+					// Alternatives are the same value in other languages,
+					// and the default comes first
+					if child.XMLName.Local == "Alt" {
+						break
+					}
+				}
+			}
+			report(key, strings.Join(items, "; "))
+			return
+		case "Description":
+			xmpProperties(child, key+"/", prefixes, report)
+			return
+		}
+	}
+
+	isStruct := len(node.Children) > 0
+	for _, attr := range node.Attrs {
+		if attr.Name.Space == rdfNamespace && attr.Name.Local == "parseType" && attr.Value == "Resource" {
+			isStruct = true
+		}
+	}
+	if isStruct {
+		xmpProperties(node, key+"/", prefixes, report)
+		return
+	}
+	if len(node.Attrs) > 0 && strings.TrimSpace(node.Text) == "" {
+		// Struct fields written as attributes
+		xmpProperties(node, key+"/", prefixes, report)
+		return
+	}
+	report(key, node.Text)
+}
@@ -0,0 +1,602 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/bits"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// mezzanineSampleFrames is how many frames of each stream are copied out
+	// and parsed; mezzanine codecs are intra-only, so every frame carries
+	// the full header
+	mezzanineSampleFrames = 4
+
+	// proResHighQScale is the average slice quantizer above which a ProRes
+	// encode is flagged as starved of bits for its profile
+	proResHighQScale = 32
+
+	// dnxhdAlignment is the size every VC-3 coding unit is padded to, so
+	// each field or frame header starts on a multiple of it
+	dnxhdAlignment = 4096
+)
+
+// MezzanineAnalyzer checks ProRes, DNxHD/DNxHR and JPEG 2000 streams against
+// their bitstream specifications, which ffprobe's generic output does not
+// cover
+type MezzanineAnalyzer struct {
+	ffmpegPath string
+	logger     zerolog.Logger
+}
+
+// NewMezzanineAnalyzer creates a new mezzanine codec conformance analyzer
+func NewMezzanineAnalyzer(ffmpegPath string, logger zerolog.Logger) *MezzanineAnalyzer {
+	return &MezzanineAnalyzer{
+		ffmpegPath: ffmpegPath,
+		logger:     logger,
+	}
+}
+
+// MezzanineAnalysis contains the conformance of each ProRes, DNxHD/DNxHR and
+// JPEG 2000 video stream
+type MezzanineAnalysis struct {
+	Streams []MezzanineStream `json:"streams"`
+	IsValid bool              `json:"is_valid"`
+}
+
+// MezzanineStream is the conformance of one mezzanine video stream, from the
+// frame headers of its first frames
+type MezzanineStream struct {
+	StreamIndex   int                  `json:"stream_index"`
+	Codec         string               `json:"codec"`             // "ProRes", "VC-3" or "JPEG 2000"
+	Profile       string               `json:"profile,omitempty"` // As ffprobe reports it
+	FramesChecked int                  `json:"frames_checked"`
+	ProRes        *ProResConformance   `json:"prores,omitempty"`
+	DNxHD         *DNxHDConformance    `json:"dnxhd,omitempty"`
+	JPEG2000      *JPEG2000Conformance `json:"jpeg2000,omitempty"`
+	IsValid       bool                 `json:"is_valid"`
+	Issues        []string             `json:"issues,omitempty"`
+	Warnings      []string             `json:"warnings,omitempty"`
+	Error         string               `json:"error,omitempty"` // Why the frames could not be read
+}
+
+// ProResConformance is what the ProRes frame headers (SMPTE RDD 36) signal
+type ProResConformance struct {
+	CodecTag         string  `json:"codec_tag,omitempty"` // Sample entry FourCC, e.g. "apch"
+	TagProfile       string  `json:"tag_profile,omitempty"`
+	BitstreamVersion int     `json:"bitstream_version"`
+	Encoder          string  `json:"encoder,omitempty"` // Encoder identifier, e.g. "apl0"
+	Width            int     `json:"width"`
+	Height           int     `json:"height"`
+	ChromaFormat     string  `json:"chroma_format"`  // "4:2:2" or "4:4:4"
+	InterlaceMode    string  `json:"interlace_mode"` // "progressive", "top_field_first" or "bottom_field_first"
+	Alpha            string  `json:"alpha"`          // "none", "8-bit" or "16-bit"
+	SlicesChecked    int     `json:"slices_checked"`
+	MinQScale        int     `json:"min_qscale"`
+	MaxQScale        int     `json:"max_qscale"`
+	AverageQScale    float64 `json:"average_qscale"`
+	InvalidQScales   int     `json:"invalid_qscales"` // Slices with a quantization index outside 1-224
+}
+
+// DNxHDConformance is what the VC-3 frame headers (SMPTE ST 2019-1) signal
+type DNxHDConformance struct {
+	CompressionID int    `json:"compression_id"`
+	Family        string `json:"family,omitempty"` // e.g. "DNxHD 220x" or "DNxHR HQX"
+	Width         int    `json:"width"`
+	Height        int    `json:"height"` // Frame height, twice the coded height of a field
+	BitDepth      int    `json:"bit_depth"`
+	ChromaFormat  string `json:"chroma_format"` // "4:2:2" or "4:4:4"
+	Interlaced    bool   `json:"interlaced"`
+	FieldOrder    string `json:"field_order,omitempty"` // "top_field_first" or "bottom_field_first"
+}
+
+// mezzanineCodecs names the checked codecs by their ffprobe codec name
+var mezzanineCodecs = map[string]string{"prores": "ProRes", "dnxhd": "VC-3", "jpeg2000": "JPEG 2000"}
+
+// proResProfiles names the ProRes profiles by sample entry FourCC, as
+// ffprobe names them
+var proResProfiles = map[string]string{
+	"apco": "Proxy",
+	"apcs": "LT",
+	"apcn": "Standard",
+	"apch": "HQ",
+	"ap4h": "4444",
+	"ap4x": "XQ",
+}
+
+// ProRes interlace modes and alpha channel types, by their header codes
+var (
+	proResInterlaceModes = []string{"progressive", "top_field_first", "bottom_field_first"}
+	proResAlphaTypes     = []string{"none", "8-bit", "16-bit"}
+)
+
+// dnxhdCompression describes a VC-3 compression ID. DNxHR IDs are
+// resolution independent and leave width and height zero.
+type dnxhdCompression struct {
+	family        string
+	width, height int
+	bitDepths     []int
+	interlaced    bool
+	chroma444     bool
+}
+
+// dnxhdCompressions lists the compression IDs ffmpeg can decode
+var dnxhdCompressions = map[int]dnxhdCompression{
+	1235: {"DNxHD 175x/185x/220x", 1920, 1080, []int{10}, false, false},
+	1237: {"DNxHD 115/120/145", 1920, 1080, []int{8}, false, false},
+	1238: {"DNxHD 175/185/220", 1920, 1080, []int{8}, false, false},
+	1241: {"DNxHD 185x/220x", 1920, 1080, []int{10}, true, false},
+	1242: {"DNxHD 120/145", 1920, 1080, []int{8}, true, false},
+	1243: {"DNxHD 185/220", 1920, 1080, []int{8}, true, false},
+	1244: {"DNxHD 120/145 (1440)", 1440, 1080, []int{8}, true, false},
+	1250: {"DNxHD 90x/110x", 1280, 720, []int{10}, false, false},
+	1251: {"DNxHD 90/110", 1280, 720, []int{8}, false, false},
+	1252: {"DNxHD 60/75", 1280, 720, []int{8}, false, false},
+	1253: {"DNxHD 36", 1920, 1080, []int{8}, false, false},
+	1256: {"DNxHD 350x/440x 4:4:4", 1920, 1080, []int{10}, false, true},
+	1258: {"DNxHD 960x720", 960, 720, []int{8}, false, false},
+	1259: {"DNxHD 1440x1080p", 1440, 1080, []int{8}, false, false},
+	1260: {"DNxHD 1440x1080i", 1440, 1080, []int{8}, true, false},
+	1270: {"DNxHR 444", 0, 0, []int{10, 12}, false, true},
+	1271: {"DNxHR HQX", 0, 0, []int{10, 12}, false, false},
+	1272: {"DNxHR HQ", 0, 0, []int{8}, false, false},
+	1273: {"DNxHR SQ", 0, 0, []int{8}, false, false},
+	1274: {"DNxHR LB", 0, 0, []int{8}, false, false},
+}
+
+// AnalyzeMezzanine checks every ProRes, DNxHD/DNxHR and JPEG 2000 video
+// stream. The first frames are copied out of the container so their headers
+// can be parsed.
+func (ma *MezzanineAnalyzer) AnalyzeMezzanine(ctx context.Context, filePath string, streams []StreamInfo) (*MezzanineAnalysis, error) {
+	analysis := &MezzanineAnalysis{IsValid: true}
+
+	for _, stream := range streams {
+		if !strings.EqualFold(stream.CodecType, "video") {
+			continue
+		}
+		codec := strings.ToLower(stream.CodecName)
+		name, ok := mezzanineCodecs[codec]
+		if !ok {
+			continue
+		}
+
+		result := MezzanineStream{StreamIndex: stream.Index, Codec: name, Profile: stream.Profile, IsValid: true}
+		frames, err := ma.readFrames(ctx, filePath, stream.Index)
+		if err == nil {
+			switch codec {
+			case "prores":
+				err = result.checkProRes(frames, stream)
+			case "dnxhd":
+				err = result.checkDNxHD(frames, stream)
+			default:
+				err = result.checkJPEG2000(frames, stream, false)
+			}
+		}
+		if err != nil {
+			ma.logger.Debug().Err(err).Int("stream", stream.Index).Msg("Failed to read mezzanine frame headers")
+			result.Error = err.Error()
+		}
+
+		analysis.IsValid = analysis.IsValid && result.IsValid
+		analysis.Streams = append(analysis.Streams, result)
+	}
+
+	if len(analysis.Streams) == 0 {
+		return nil, nil
+	}
+	return analysis, nil
+}
+
+// readFrames copies the first frames of a video stream without their
+// container
+func (ma *MezzanineAnalyzer) readFrames(ctx context.Context, filePath string, index int) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, ma.ffmpegPath,
+		"-v", "error",
+		"-i", filePath,
+		"-map", fmt.Sprintf("0:%d", index),
+		"-c:v", "copy",
+		"-frames:v", fmt.Sprint(mezzanineSampleFrames),
+		"-f", "rawvideo",
+		"-",
+	)
+
+	output, err := commandOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract video frames: %w", err)
+	}
+	return output, nil
+}
+
+func (s *MezzanineStream) addIssue(format string, args ...any) {
+	s.Issues = append(s.Issues, fmt.Sprintf(format, args...))
+	s.IsValid = false
+}
+
+func (s *MezzanineStream) addWarning(format string, args ...any) {
+	s.Warnings = append(s.Warnings, fmt.Sprintf(format, args...))
+}
+
+// proResFrame is the frame header of one ProRes frame and the quantizers of
+// its slices
+type proResFrame struct {
+	version       int
+	encoder       string
+	width, height int
+	chromaFormat  int
+	interlaceMode int
+	alphaType     int
+	qscales       []int
+}
+
+// checkProRes parses the ProRes frames and checks them against each other,
+// the profile and the stream parameters
+func (s *MezzanineStream) checkProRes(data []byte, stream StreamInfo) error {
+	var frames []proResFrame
+	for offset := 0; offset+8 <= len(data) && len(frames) < mezzanineSampleFrames; {
+		size := int(binary.BigEndian.Uint32(data[offset:]))
+		if string(data[offset+4:offset+8]) != "icpf" || size < 8 || offset+size > len(data) {
+			break
+		}
+		frame, err := parseProResFrame(data[offset+8 : offset+size])
+		if err != nil {
+			return fmt.Errorf("frame %d: %w", len(frames)+1, err)
+		}
+		frames = append(frames, frame)
+		offset += size
+	}
+	if len(frames) == 0 {
+		return fmt.Errorf("no ProRes frame found")
+	}
+	s.FramesChecked = len(frames)
+
+	first := frames[0]
+	conformance := &ProResConformance{
+		CodecTag:         strings.ToLower(stream.CodecTagString),
+		BitstreamVersion: first.version,
+		Encoder:          first.encoder,
+		Width:            first.width,
+		Height:           first.height,
+		ChromaFormat:     map[int]string{2: "4:2:2", 3: "4:4:4"}[first.chromaFormat],
+		InterlaceMode:    codeName(proResInterlaceModes, first.interlaceMode),
+		Alpha:            codeName(proResAlphaTypes, first.alphaType),
+	}
+	s.ProRes = conformance
+
+	for i, frame := range frames[1:] {
+		if frame.width != first.width || frame.height != first.height || frame.chromaFormat != first.chromaFormat ||
+			frame.interlaceMode != first.interlaceMode || frame.alphaType != first.alphaType {
+			s.addIssue("Frame %d header differs from the first frame in size, chroma format, interlacing or alpha", i+2)
+		}
+	}
+
+	if first.version > 1 {
+		s.addIssue("Bitstream version %d is not defined by SMPTE RDD 36", first.version)
+	}
+	if conformance.ChromaFormat == "" {
+		s.addIssue("Chroma format code %d is reserved", first.chromaFormat)
+	}
+	if first.interlaceMode >= len(proResInterlaceModes) {
+		s.addIssue("Interlace mode code %d is reserved", first.interlaceMode)
+	}
+	if first.alphaType >= len(proResAlphaTypes) {
+		s.addIssue("Alpha channel type %d is reserved", first.alphaType)
+	}
+	if stream.Width > 0 && (first.width != stream.Width || first.height != stream.Height) {
+		s.addIssue("Frame header size %dx%d does not match the stream's %dx%d", first.width, first.height, stream.Width, stream.Height)
+	}
+
+	// The sample entry FourCC names the profile; the bitstream must carry
+	// the chroma format and alpha that profile allows
+	profile := proResProfiles[conformance.CodecTag]
+	conformance.TagProfile = profile
+	if profile == "" {
+		profile = stream.Profile
+	} else if stream.Profile != "" && !strings.EqualFold(stream.Profile, profile) {
+		s.addIssue("Codec tag %s is ProRes %s but the stream profile is %s", conformance.CodecTag, profile, stream.Profile)
+	}
+	switch profile {
+	case "4444", "XQ":
+		if first.chromaFormat != 3 {
+			s.addIssue("ProRes %s frames must be 4:4:4, found %s", profile, orUnknown(conformance.ChromaFormat))
+		}
+	case "Proxy", "LT", "Standard", "HQ":
+		if first.chromaFormat != 2 {
+			s.addIssue("ProRes %s frames must be 4:2:2, found %s", profile, orUnknown(conformance.ChromaFormat))
+		}
+		if first.alphaType != 0 {
+			s.addIssue("ProRes %s does not allow an alpha channel, found %s alpha", profile, conformance.Alpha)
+		}
+	default:
+		s.addWarning("ProRes profile is not signalled, chroma format and alpha were not checked against it")
+	}
+
+	s.checkFieldOrder(conformance.InterlaceMode, stream.FieldOrder)
+
+	qscaleSum := 0
+	conformance.MinQScale = math.MaxInt
+	for _, frame := range frames {
+		for _, qscale := range frame.qscales {
+			conformance.SlicesChecked++
+			qscaleSum += qscale
+			conformance.MinQScale = min(conformance.MinQScale, qscale)
+			conformance.MaxQScale = max(conformance.MaxQScale, qscale)
+			if qscale < 1 || qscale > 224 {
+				conformance.InvalidQScales++
+			}
+		}
+	}
+	if conformance.SlicesChecked == 0 {
+		conformance.MinQScale = 0
+		s.addIssue("Frames carry no slices")
+		return nil
+	}
+	conformance.AverageQScale = math.Round(float64(qscaleSum)/float64(conformance.SlicesChecked)*10) / 10
+	if conformance.InvalidQScales > 0 {
+		s.addIssue("%d of %d slices have a quantization index outside 1-224", conformance.InvalidQScales, conformance.SlicesChecked)
+	}
+	if conformance.AverageQScale > proResHighQScale {
+		s.addWarning("Average slice quantizer %.1f is high, the encode is starved of bits for its profile", conformance.AverageQScale)
+	}
+	return nil
+}
+
+// parseProResFrame parses a frame header and the slice headers of each
+// picture; interlaced frames carry one picture per field
+func parseProResFrame(frame []byte) (proResFrame, error) {
+	if len(frame) < 20 {
+		return proResFrame{}, fmt.Errorf("frame header is truncated")
+	}
+	headerSize := int(binary.BigEndian.Uint16(frame))
+	if headerSize < 20 || headerSize > len(frame) {
+		return proResFrame{}, fmt.Errorf("frame header size %d is invalid", headerSize)
+	}
+	parsed := proResFrame{
+		version:       int(frame[3]),
+		encoder:       strings.TrimRight(string(frame[4:8]), "\x00 "),
+		width:         int(binary.BigEndian.Uint16(frame[8:])),
+		height:        int(binary.BigEndian.Uint16(frame[10:])),
+		chromaFormat:  int(frame[12] >> 6),
+		interlaceMode: int(frame[12]>>2) & 3,
+		alphaType:     int(frame[17] & 0x0F),
+	}
+
+	pictures := 1
+	mbHeight := (parsed.height + 15) >> 4
+	if parsed.interlaceMode != 0 {
+		pictures = 2
+		mbHeight = (parsed.height + 31) >> 5
+	}
+	mbWidth := (parsed.width + 15) >> 4
+
+	offset := headerSize
+	for picture := 0; picture < pictures; picture++ {
+		if offset+8 > len(frame) {
+			return parsed, fmt.Errorf("picture %d header is truncated", picture+1)
+		}
+		pictureHeaderSize := int(frame[offset] >> 3)
+		pictureSize := int(binary.BigEndian.Uint32(frame[offset+1:]))
+		if pictureHeaderSize < 8 || pictureSize < pictureHeaderSize || offset+pictureSize > len(frame) {
+			return parsed, fmt.Errorf("picture %d size is invalid", picture+1)
+		}
+		// Slices are 2^log2 macroblocks wide, with narrower ones filling
+		// the end of each row
+		log2SliceWidth := int(frame[offset+7] >> 4)
+		slices := mbHeight * ((mbWidth >> log2SliceWidth) + bits.OnesCount(uint(mbWidth&(1<<log2SliceWidth-1))))
+
+		table := offset + pictureHeaderSize
+		slice := table + 2*slices
+		end := offset + pictureSize
+		if slice > end {
+			return parsed, fmt.Errorf("picture %d slice table is truncated", picture+1)
+		}
+		for i := 0; i < slices; i++ {
+			if slice+2 > end {
+				return parsed, fmt.Errorf("picture %d slice %d is truncated", picture+1, i+1)
+			}
+			parsed.qscales = append(parsed.qscales, int(frame[slice+1]))
+			slice += int(binary.BigEndian.Uint16(frame[table+2*i:]))
+		}
+		offset = end
+	}
+	return parsed, nil
+}
+
+// dnxhdHeader is one VC-3 frame header; interlaced frames carry one per
+// field
+type dnxhdHeader struct {
+	interlaced  bool
+	field       int // 0 for the top field, 1 for the bottom field
+	progressive bool
+	width       int
+	height      int // Coded height, of one field when interlaced
+	bitDepth    int
+	cid         int
+	chroma444   bool
+}
+
+// checkDNxHD parses the VC-3 headers and checks them against the
+// compression ID and the stream parameters
+func (s *MezzanineStream) checkDNxHD(data []byte, stream StreamInfo) error {
+	var headers []dnxhdHeader
+	for offset := 0; offset+0x30 <= len(data); offset += dnxhdAlignment {
+		if !isDNxHDHeader(data[offset:]) {
+			continue
+		}
+		headers = append(headers, parseDNxHDHeader(data[offset:]))
+	}
+	if len(headers) == 0 {
+		return fmt.Errorf("no VC-3 frame header found")
+	}
+
+	first := headers[0]
+	conformance := &DNxHDConformance{
+		CompressionID: first.cid,
+		Width:         first.width,
+		Height:        first.height,
+		BitDepth:      first.bitDepth,
+		ChromaFormat:  "4:2:2",
+		Interlaced:    first.interlaced,
+	}
+	if first.chroma444 {
+		conformance.ChromaFormat = "4:4:4"
+	}
+	if first.interlaced {
+		conformance.Height *= 2
+		conformance.FieldOrder = proResInterlaceModes[1+first.field]
+	}
+	s.DNxHD = conformance
+
+	for i, header := range headers[1:] {
+		if header.cid != first.cid || header.width != first.width || header.height != first.height ||
+			header.bitDepth != first.bitDepth || header.interlaced != first.interlaced {
+			s.addIssue("Header %d differs from the first in compression ID, size, bit depth or interlacing", i+2)
+			break
+		}
+	}
+
+	for _, header := range headers {
+		if header.interlaced == header.progressive {
+			s.addIssue("Interlaced field flag and progressive flag of the frame header disagree")
+			break
+		}
+	}
+
+	// Interlaced frames code both fields, the second with the opposite
+	// field flag
+	if first.interlaced {
+		s.FramesChecked = (len(headers) + 1) / 2
+		for i := 0; i < len(headers); i += 2 {
+			if i+1 == len(headers) {
+				if len(headers) < 2*mezzanineSampleFrames {
+					s.addIssue("Frame %d is interlaced but carries only one field", i/2+1)
+				}
+				break
+			}
+			if headers[i].field == headers[i+1].field || headers[i].field != first.field {
+				s.addIssue("Frame %d field flags are %d and %d, the fields must alternate in the same order every frame", i/2+1, headers[i].field, headers[i+1].field)
+				break
+			}
+		}
+	} else {
+		s.FramesChecked = len(headers)
+	}
+
+	compression, ok := dnxhdCompressions[first.cid]
+	if !ok {
+		s.addIssue("Compression ID %d is not a known DNxHD or DNxHR ID", first.cid)
+	} else {
+		conformance.Family = compression.family
+		if compression.width > 0 {
+			if conformance.Width != compression.width || conformance.Height != compression.height {
+				s.addIssue("Compression ID %d is %dx%d, the header signals %dx%d", first.cid, compression.width, compression.height, conformance.Width, conformance.Height)
+			}
+			if compression.interlaced != first.interlaced {
+				s.addIssue("Compression ID %d is %s, the header signals %s", first.cid, interlacedName(compression.interlaced), interlacedName(first.interlaced))
+			}
+		}
+		if !slices.Contains(compression.bitDepths, first.bitDepth) {
+			s.addIssue("Compression ID %d does not allow %d-bit samples", first.cid, first.bitDepth)
+		}
+		if compression.chroma444 != first.chroma444 {
+			s.addIssue("Compression ID %d is not %s", first.cid, conformance.ChromaFormat)
+		}
+	}
+
+	if stream.Width > 0 && (conformance.Width != stream.Width || conformance.Height != stream.Height) {
+		s.addIssue("Frame header size %dx%d does not match the stream's %dx%d", conformance.Width, conformance.Height, stream.Width, stream.Height)
+	}
+	interlaceMode := proResInterlaceModes[0]
+	if first.interlaced {
+		interlaceMode = conformance.FieldOrder
+	}
+	s.checkFieldOrder(interlaceMode, stream.FieldOrder)
+	return nil
+}
+
+// isDNxHDHeader checks the header prefix as ffmpeg does: DNxHD uses two
+// fixed prefixes, DNxHR one with a variable data offset
+func isDNxHDHeader(data []byte) bool {
+	if len(data) < 0x30 || data[0] != 0 || data[1] != 0 {
+		return false
+	}
+	switch {
+	case data[2] == 0x02 && data[3] == 0x80 && (data[4] == 0x01 || data[4] == 0x02):
+		return true
+	case data[4] == 0x03:
+		dataOffset := binary.BigEndian.Uint16(data[2:])
+		return dataOffset >= 0x280 && dataOffset <= 0x2170 && dataOffset%4 == 0
+	}
+	return false
+}
+
+// parseDNxHDHeader reads the fields of a VC-3 frame header
+func parseDNxHDHeader(data []byte) dnxhdHeader {
+	header := dnxhdHeader{
+		interlaced:  data[5]&0x02 != 0,
+		progressive: data[0x2C]&0x80 != 0,
+		height:      int(binary.BigEndian.Uint16(data[0x18:])),
+		width:       int(binary.BigEndian.Uint16(data[0x1A:])),
+		cid:         int(binary.BigEndian.Uint32(data[0x28:])),
+		chroma444:   data[0x2C]&0x40 != 0,
+	}
+	if header.interlaced {
+		header.field = int(data[5] & 0x01)
+	}
+	switch data[0x21] >> 5 {
+	case 1:
+		header.bitDepth = 8
+	case 2:
+		header.bitDepth = 10
+	case 3:
+		header.bitDepth = 12
+	}
+	return header
+}
+
+// checkFieldOrder warns when the container's field order disagrees with
+// the interlacing the bitstream signals
+func (s *MezzanineStream) checkFieldOrder(interlaceMode, fieldOrder string) {
+	var signalled string
+	switch {
+	case fieldOrder == "progressive":
+		signalled = proResInterlaceModes[0]
+	case strings.HasPrefix(fieldOrder, "t"):
+		signalled = proResInterlaceModes[1]
+	case strings.HasPrefix(fieldOrder, "b"):
+		signalled = proResInterlaceModes[2]
+	default:
+		return // Unknown or not signalled
+	}
+	if interlaceMode != "" && signalled != interlaceMode {
+		s.addWarning("Container field order is %s but the bitstream is %s", signalled, interlaceMode)
+	}
+}
+
+// codeName returns the name of a header code, or "" for reserved codes
+func codeName(names []string, code int) string {
+	if code < len(names) {
+		return names[code]
+	}
+	return ""
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return "a reserved format"
+	}
+	return value
+}
+
+func interlacedName(interlaced bool) string {
+	if interlaced {
+		return "interlaced"
+	}
+	return "progressive"
+}
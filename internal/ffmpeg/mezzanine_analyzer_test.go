@@ -0,0 +1,266 @@
+package ffmpeg
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// testProResFrame builds a progressive 64x32 ProRes frame of two slices
+// with the given chroma format, alpha type and slice quantizers
+func testProResFrame(chromaFormat, alphaType byte, qscales ...byte) []byte {
+	header := make([]byte, 20)
+	binary.BigEndian.PutUint16(header, 20)
+	header[3] = 1
+	copy(header[4:], "apl0")
+	binary.BigEndian.PutUint16(header[8:], 64)
+	binary.BigEndian.PutUint16(header[10:], 32)
+	header[12] = chromaFormat << 6
+	header[17] = alphaType
+
+	// 64x32 is 4x2 macroblocks, so slices four macroblocks wide make one
+	// slice per row
+	picture := make([]byte, 8)
+	picture[0] = 8 << 3
+	picture[7] = 2 << 4
+	for range qscales {
+		picture = binary.BigEndian.AppendUint16(picture, 4)
+	}
+	for _, qscale := range qscales {
+		picture = append(picture, 4<<3, qscale, 0, 0)
+	}
+	binary.BigEndian.PutUint32(picture[1:], uint32(len(picture)))
+
+	frame := binary.BigEndian.AppendUint32(nil, uint32(8+len(header)+len(picture)))
+	frame = append(frame, "icpf"...)
+	frame = append(frame, header...)
+	return append(frame, picture...)
+}
+
+func TestMezzanineStream_CheckProRes(t *testing.T) {
+	stream := StreamInfo{Width: 64, Height: 32, CodecTagString: "apch", Profile: "HQ", FieldOrder: "progressive"}
+
+	t.Run("conformant", func(t *testing.T) {
+		data := append(testProResFrame(2, 0, 4, 6), testProResFrame(2, 0, 2, 4)...)
+		s := MezzanineStream{IsValid: true}
+		if err := s.checkProRes(data, stream); err != nil {
+			t.Fatalf("checkProRes() error = %v", err)
+		}
+		if !s.IsValid || len(s.Issues) > 0 || len(s.Warnings) > 0 {
+			t.Errorf("valid = %v, issues = %v, warnings = %v", s.IsValid, s.Issues, s.Warnings)
+		}
+		p := s.ProRes
+		if s.FramesChecked != 2 || p.TagProfile != "HQ" || p.Encoder != "apl0" || p.ChromaFormat != "4:2:2" || p.Alpha != "none" || p.InterlaceMode != "progressive" {
+			t.Errorf("conformance = %+v", p)
+		}
+		if p.SlicesChecked != 4 || p.MinQScale != 2 || p.MaxQScale != 6 || p.AverageQScale != 4 {
+			t.Errorf("qscale = %d slices, %d-%d, average %v", p.SlicesChecked, p.MinQScale, p.MaxQScale, p.AverageQScale)
+		}
+	})
+
+	t.Run("alpha and qscale in a 4:2:2 profile", func(t *testing.T) {
+		s := MezzanineStream{IsValid: true}
+		if err := s.checkProRes(testProResFrame(3, 1, 0, 40), stream); err != nil {
+			t.Fatalf("checkProRes() error = %v", err)
+		}
+		want := []string{"must be 4:2:2, found 4:4:4", "does not allow an alpha channel", "1 of 2 slices"}
+		if s.IsValid || len(s.Issues) != len(want) {
+			t.Fatalf("Issues = %q", s.Issues)
+		}
+		for i, w := range want {
+			if !strings.Contains(s.Issues[i], w) {
+				t.Errorf("Issues[%d] = %q, want %q", i, s.Issues[i], w)
+			}
+		}
+	})
+
+	t.Run("tag and profile disagree", func(t *testing.T) {
+		s := MezzanineStream{IsValid: true}
+		tagged := stream
+		tagged.Profile = "LT"
+		tagged.FieldOrder = "tt"
+		if err := s.checkProRes(testProResFrame(2, 0, 40, 40), tagged); err != nil {
+			t.Fatalf("checkProRes() error = %v", err)
+		}
+		if len(s.Issues) != 1 || !strings.Contains(s.Issues[0], "Codec tag apch is ProRes HQ but the stream profile is LT") {
+			t.Errorf("Issues = %q", s.Issues)
+		}
+		if len(s.Warnings) != 2 || !strings.Contains(s.Warnings[0], "field order") || !strings.Contains(s.Warnings[1], "quantizer 40.0") {
+			t.Errorf("Warnings = %q", s.Warnings)
+		}
+	})
+
+	t.Run("not ProRes", func(t *testing.T) {
+		s := MezzanineStream{IsValid: true}
+		if err := s.checkProRes([]byte("not a prores frame"), stream); err == nil {
+			t.Error("checkProRes() error = nil, want an error")
+		}
+	})
+}
+
+// testDNxHDField builds one VC-3 coding unit padded to the alignment
+func testDNxHDField(cid uint32, width, height int, bitDepth byte, interlaced bool, field byte) []byte {
+	data := make([]byte, dnxhdAlignment)
+	copy(data, []byte{0x00, 0x00, 0x02, 0x80, 0x01})
+	data[5] = 0x01
+	data[0x2C] = 0x80
+	if interlaced {
+		data[5] = 0x02 | field
+		data[0x2C] = 0x00
+	}
+	binary.BigEndian.PutUint16(data[0x18:], uint16(height))
+	binary.BigEndian.PutUint16(data[0x1A:], uint16(width))
+	data[0x21] = bitDepth << 5
+	binary.BigEndian.PutUint32(data[0x28:], cid)
+	return data
+}
+
+func TestMezzanineStream_CheckDNxHD(t *testing.T) {
+	t.Run("progressive", func(t *testing.T) {
+		stream := StreamInfo{Width: 1920, Height: 1080, FieldOrder: "progressive"}
+		data := append(testDNxHDField(1235, 1920, 1080, 2, false, 0), testDNxHDField(1235, 1920, 1080, 2, false, 0)...)
+		s := MezzanineStream{IsValid: true}
+		if err := s.checkDNxHD(data, stream); err != nil {
+			t.Fatalf("checkDNxHD() error = %v", err)
+		}
+		if !s.IsValid || len(s.Issues) > 0 || len(s.Warnings) > 0 {
+			t.Errorf("valid = %v, issues = %v, warnings = %v", s.IsValid, s.Issues, s.Warnings)
+		}
+		d := s.DNxHD
+		if s.FramesChecked != 2 || d.CompressionID != 1235 || d.BitDepth != 10 || d.ChromaFormat != "4:2:2" || d.Interlaced || d.Family == "" {
+			t.Errorf("conformance = %+v", d)
+		}
+	})
+
+	t.Run("interlaced", func(t *testing.T) {
+		stream := StreamInfo{Width: 1920, Height: 1080, FieldOrder: "bb"}
+		var data []byte
+		for _, field := range []byte{0, 1, 0, 0} {
+			data = append(data, testDNxHDField(1241, 1920, 540, 2, true, field)...)
+		}
+		s := MezzanineStream{IsValid: true}
+		if err := s.checkDNxHD(data, stream); err != nil {
+			t.Fatalf("checkDNxHD() error = %v", err)
+		}
+		if s.DNxHD.Height != 1080 || !s.DNxHD.Interlaced || s.DNxHD.FieldOrder != "top_field_first" || s.FramesChecked != 2 {
+			t.Errorf("conformance = %+v, frames = %d", s.DNxHD, s.FramesChecked)
+		}
+		if len(s.Issues) != 1 || !strings.Contains(s.Issues[0], "Frame 2 field flags are 0 and 0") {
+			t.Errorf("Issues = %q", s.Issues)
+		}
+		if len(s.Warnings) != 1 || !strings.Contains(s.Warnings[0], "bottom_field_first but the bitstream is top_field_first") {
+			t.Errorf("Warnings = %q", s.Warnings)
+		}
+	})
+
+	t.Run("compression ID mismatch", func(t *testing.T) {
+		stream := StreamInfo{Width: 1280, Height: 720}
+		s := MezzanineStream{IsValid: true}
+		if err := s.checkDNxHD(testDNxHDField(1237, 1280, 720, 2, false, 0), stream); err != nil {
+			t.Fatalf("checkDNxHD() error = %v", err)
+		}
+		want := []string{"Compression ID 1237 is 1920x1080", "does not allow 10-bit"}
+		if s.IsValid || len(s.Issues) != len(want) {
+			t.Fatalf("Issues = %q", s.Issues)
+		}
+		for i, w := range want {
+			if !strings.Contains(s.Issues[i], w) {
+				t.Errorf("Issues[%d] = %q, want %q", i, s.Issues[i], w)
+			}
+		}
+	})
+}
+
+func TestIsDNxHDHeader(t *testing.T) {
+	tests := []struct {
+		prefix []byte
+		want   bool
+	}{
+		{[]byte{0x00, 0x00, 0x02, 0x80, 0x01}, true},
+		{[]byte{0x00, 0x00, 0x02, 0x80, 0x02}, true},
+		{[]byte{0x00, 0x00, 0x03, 0x8C, 0x03}, true},
+		{[]byte{0x00, 0x00, 0x03, 0x8D, 0x03}, false}, // Data offset not a multiple of 4
+		{[]byte{0x00, 0x00, 0x02, 0x80, 0x04}, false},
+		{[]byte{0x00, 0x01, 0x02, 0x80, 0x01}, false},
+	}
+	for _, tt := range tests {
+		data := make([]byte, 0x30)
+		copy(data, tt.prefix)
+		if got := isDNxHDHeader(data); got != tt.want {
+			t.Errorf("isDNxHDHeader(% x) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+// testJ2KCodestream builds the main header of a single tile, three
+// component codestream up to its first tile-part
+func testJ2KCodestream(rsiz uint16, width, height uint32, bitDepth, levels, codeBlock, progression, transform, quantization byte) []byte {
+	marker := func(code uint16, segment []byte) []byte {
+		data := binary.BigEndian.AppendUint16(nil, code)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(segment)+2))
+		return append(data, segment...)
+	}
+	siz := binary.BigEndian.AppendUint16(nil, rsiz)
+	for _, v := range []uint32{width, height, 0, 0, width, height, 0, 0} {
+		siz = binary.BigEndian.AppendUint32(siz, v)
+	}
+	siz = binary.BigEndian.AppendUint16(siz, 3)
+	for range 3 {
+		siz = append(siz, bitDepth-1, 1, 1)
+	}
+	cod := []byte{0, progression, 0, 1, 1, levels, codeBlock - 2, codeBlock - 2, 0, transform}
+
+	data := append([]byte{0xFF, 0x4F}, marker(j2kMarkerSIZ, siz)...)
+	data = append(data, marker(j2kMarkerCOD, cod)...)
+	data = append(data, marker(j2kMarkerQCD, []byte{quantization, 0x00})...)
+	return append(data, 0xFF, 0x90)
+}
+
+func TestMezzanineStream_CheckJPEG2000(t *testing.T) {
+	t.Run("DCI 2K", func(t *testing.T) {
+		data := append([]byte("KLV header"), testJ2KCodestream(j2kRsizCinema2K, 2048, 858, 12, 5, 5, 4, 0, 0x42)...)
+		s := MezzanineStream{IsValid: true}
+		if err := s.checkJPEG2000(data, StreamInfo{Width: 2048, Height: 858}, true); err != nil {
+			t.Fatalf("checkJPEG2000() error = %v", err)
+		}
+		if !s.IsValid || len(s.Issues) > 0 {
+			t.Errorf("valid = %v, issues = %v", s.IsValid, s.Issues)
+		}
+		j := s.JPEG2000
+		if j.Capabilities != "DCI 2K" || j.Tiles != 1 || j.CodeBlockWidth != 32 || j.ProgressionOrder != "CPRL" || j.Wavelet != "9/7 irreversible" || j.Quantization != "scalar_expounded" || j.Lossless {
+			t.Errorf("conformance = %+v", j)
+		}
+	})
+
+	t.Run("lossless IMF codestream in a DCP", func(t *testing.T) {
+		data := testJ2KCodestream(0x0504, 3840, 2160, 10, 5, 6, 0, 1, 0x40)
+		s := MezzanineStream{IsValid: true}
+		if err := s.checkJPEG2000(data, StreamInfo{}, false); err != nil {
+			t.Fatalf("checkJPEG2000() error = %v", err)
+		}
+		if !s.IsValid || s.JPEG2000.Capabilities != "IMF 4K" || !s.JPEG2000.Lossless {
+			t.Errorf("valid = %v, issues = %v, conformance = %+v", s.IsValid, s.Issues, s.JPEG2000)
+		}
+
+		s = MezzanineStream{IsValid: true}
+		if err := s.checkJPEG2000(data, StreamInfo{}, true); err != nil {
+			t.Fatalf("checkJPEG2000() error = %v", err)
+		}
+		want := []string{"not a DCI cinema profile", "three 12-bit components", "32x32 code blocks", "9/7 irreversible", "CPRL progression"}
+		if len(s.Issues) != len(want) {
+			t.Fatalf("Issues = %q", s.Issues)
+		}
+		for i, w := range want {
+			if !strings.Contains(s.Issues[i], w) {
+				t.Errorf("Issues[%d] = %q, want %q", i, s.Issues[i], w)
+			}
+		}
+	})
+
+	t.Run("not JPEG 2000", func(t *testing.T) {
+		s := MezzanineStream{IsValid: true}
+		if err := s.checkJPEG2000([]byte("no codestream here"), StreamInfo{}, false); err == nil {
+			t.Error("checkJPEG2000() error = nil, want an error")
+		}
+	})
+}
@@ -0,0 +1,252 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// JPEG 2000 codestream markers, ISO/IEC 15444-1 annex A
+const (
+	j2kMarkerSIZ = 0xFF51
+	j2kMarkerCOD = 0xFF52
+	j2kMarkerQCD = 0xFF5C
+	j2kMarkerSOT = 0xFF90
+)
+
+// j2kCodestreamStart is the SOC marker followed by the SIZ marker, which
+// opens every codestream
+var j2kCodestreamStart = []byte{0xFF, 0x4F, 0xFF, 0x51}
+
+// Rsiz capabilities of the DCI cinema profiles
+const (
+	j2kRsizCinema2K = 0x0003
+	j2kRsizCinema4K = 0x0004
+)
+
+// JPEG2000Conformance is what the main header of the first codestream
+// signals: the SIZ image and tile size, the COD coding style and the QCD
+// quantization
+type JPEG2000Conformance struct {
+	Rsiz                int    `json:"rsiz"`
+	Capabilities        string `json:"capabilities"` // Profile named by Rsiz, e.g. "DCI 2K" or "IMF 4K"
+	Width               int    `json:"width"`
+	Height              int    `json:"height"`
+	Components          int    `json:"components"`
+	BitDepth            int    `json:"bit_depth"`
+	Subsampled          bool   `json:"subsampled"`
+	TileWidth           int    `json:"tile_width"`
+	TileHeight          int    `json:"tile_height"`
+	Tiles               int    `json:"tiles"`
+	ProgressionOrder    string `json:"progression_order"` // "LRCP", "RLCP", "RPCL", "PCRL" or "CPRL"
+	QualityLayers       int    `json:"quality_layers"`
+	ComponentTransform  bool   `json:"component_transform"`
+	DecompositionLevels int    `json:"decomposition_levels"`
+	CodeBlockWidth      int    `json:"code_block_width"`
+	CodeBlockHeight     int    `json:"code_block_height"`
+	Wavelet             string `json:"wavelet"`      // "9/7 irreversible" or "5/3 reversible"
+	Quantization        string `json:"quantization"` // "none", "scalar_derived" or "scalar_expounded"
+	Lossless            bool   `json:"lossless"`
+}
+
+// Progression orders and quantization styles, by their header codes
+var (
+	j2kProgressionOrders  = []string{"LRCP", "RLCP", "RPCL", "PCRL", "CPRL"}
+	j2kQuantizationStyles = []string{"none", "scalar_derived", "scalar_expounded"}
+)
+
+// checkJPEG2000 parses the main header of the first codestream. The DCI
+// constraints of SMPTE ST 429-4 apply when Rsiz names a cinema profile or
+// dci is set, as it is for the picture track files of a DCP.
+func (s *MezzanineStream) checkJPEG2000(data []byte, stream StreamInfo, dci bool) error {
+	start := bytes.Index(data, j2kCodestreamStart)
+	if start < 0 {
+		return fmt.Errorf("no JPEG 2000 codestream found")
+	}
+	j2k, err := parseJPEG2000Header(data[start:])
+	if err != nil {
+		return err
+	}
+	s.JPEG2000 = j2k
+	s.FramesChecked = 1
+
+	if stream.Width > 0 && (j2k.Width != stream.Width || j2k.Height != stream.Height) {
+		s.addIssue("Codestream image size %dx%d does not match the stream's %dx%d", j2k.Width, j2k.Height, stream.Width, stream.Height)
+	}
+	if j2k.ProgressionOrder == "" {
+		s.addIssue("Progression order code is reserved")
+	}
+	if j2k.Quantization == "" {
+		s.addIssue("Quantization style is reserved")
+	} else if j2k.Wavelet == "9/7 irreversible" && j2k.Quantization == "none" {
+		s.addIssue("The 9/7 irreversible wavelet needs scalar quantization")
+	}
+
+	if j2k.Rsiz == j2kRsizCinema2K || j2k.Rsiz == j2kRsizCinema4K || dci {
+		s.checkDCIConstraints(j2k)
+	}
+	return nil
+}
+
+// checkDCIConstraints checks the codestream against the DCI 2K or 4K
+// profile: one tile, 12-bit X'Y'Z' without subsampling, 32x32 code blocks,
+// the irreversible wavelet, CPRL progression and one quality layer
+func (s *MezzanineStream) checkDCIConstraints(j2k *JPEG2000Conformance) {
+	profile, maxWidth, maxHeight, maxLevels := "DCI 2K", 2048, 1080, 5
+	if j2k.Rsiz == j2kRsizCinema4K || (j2k.Rsiz != j2kRsizCinema2K && (j2k.Width > 2048 || j2k.Height > 1080)) {
+		profile, maxWidth, maxHeight, maxLevels = "DCI 4K", 4096, 2160, 6
+	}
+
+	if j2k.Rsiz != j2kRsizCinema2K && j2k.Rsiz != j2kRsizCinema4K {
+		s.addIssue("Rsiz 0x%04X is not a DCI cinema profile", j2k.Rsiz)
+	}
+	if j2k.Width > maxWidth || j2k.Height > maxHeight {
+		s.addIssue("%s images are at most %dx%d, found %dx%d", profile, maxWidth, maxHeight, j2k.Width, j2k.Height)
+	}
+	if j2k.Components != 3 || j2k.BitDepth != 12 || j2k.Subsampled {
+		s.addIssue("%s needs three 12-bit components without subsampling, found %d %d-bit", profile, j2k.Components, j2k.BitDepth)
+	}
+	if j2k.Tiles != 1 {
+		s.addIssue("%s needs a single tile, found %d", profile, j2k.Tiles)
+	}
+	if j2k.CodeBlockWidth != 32 || j2k.CodeBlockHeight != 32 {
+		s.addIssue("%s needs 32x32 code blocks, found %dx%d", profile, j2k.CodeBlockWidth, j2k.CodeBlockHeight)
+	}
+	if j2k.Wavelet != "9/7 irreversible" || !j2k.ComponentTransform {
+		s.addIssue("%s needs the 9/7 irreversible wavelet with the component transform", profile)
+	}
+	if j2k.DecompositionLevels < 1 || j2k.DecompositionLevels > maxLevels {
+		s.addIssue("%s allows 1-%d decomposition levels, found %d", profile, maxLevels, j2k.DecompositionLevels)
+	}
+	if j2k.ProgressionOrder != "CPRL" {
+		s.addIssue("%s needs CPRL progression, found %s", profile, orUnknown(j2k.ProgressionOrder))
+	}
+	if j2k.QualityLayers != 1 {
+		s.addIssue("%s needs one quality layer, found %d", profile, j2k.QualityLayers)
+	}
+}
+
+// parseJPEG2000Header reads the SIZ, COD and QCD markers of a main header,
+// up to the first tile
+func parseJPEG2000Header(data []byte) (*JPEG2000Conformance, error) {
+	j2k := &JPEG2000Conformance{}
+	var hasSIZ, hasCOD bool
+	for offset := 2; offset+4 <= len(data); {
+		marker := binary.BigEndian.Uint16(data[offset:])
+		if marker == j2kMarkerSOT {
+			break
+		}
+		length := int(binary.BigEndian.Uint16(data[offset+2:]))
+		if length < 2 || offset+2+length > len(data) {
+			return nil, fmt.Errorf("marker 0x%04X is truncated", marker)
+		}
+		segment := data[offset+4 : offset+2+length]
+		switch marker {
+		case j2kMarkerSIZ:
+			if err := j2k.readSIZ(segment); err != nil {
+				return nil, err
+			}
+			hasSIZ = true
+		case j2kMarkerCOD:
+			if len(segment) < 10 {
+				return nil, fmt.Errorf("COD marker is truncated")
+			}
+			j2k.ProgressionOrder = codeName(j2kProgressionOrders, int(segment[1]))
+			j2k.QualityLayers = int(binary.BigEndian.Uint16(segment[2:]))
+			j2k.ComponentTransform = segment[4] == 1
+			j2k.DecompositionLevels = int(segment[5])
+			j2k.CodeBlockWidth = 1 << (segment[6] + 2)
+			j2k.CodeBlockHeight = 1 << (segment[7] + 2)
+			j2k.Wavelet = "9/7 irreversible"
+			if segment[9] == 1 {
+				j2k.Wavelet = "5/3 reversible"
+			}
+			hasCOD = true
+		case j2kMarkerQCD:
+			if len(segment) < 1 {
+				return nil, fmt.Errorf("QCD marker is truncated")
+			}
+			j2k.Quantization = codeName(j2kQuantizationStyles, int(segment[0]&0x1F))
+		}
+		offset += 2 + length
+	}
+	if !hasSIZ || !hasCOD {
+		return nil, fmt.Errorf("main header has no SIZ or COD marker")
+	}
+
+	// Only the reversible wavelet without quantization reconstructs the
+	// samples exactly
+	j2k.Lossless = j2k.Wavelet == "5/3 reversible" && j2k.Quantization == "none"
+	return j2k, nil
+}
+
+// readSIZ reads the image and tile size and the components
+func (j2k *JPEG2000Conformance) readSIZ(segment []byte) error {
+	if len(segment) < 36 {
+		return fmt.Errorf("SIZ marker is truncated")
+	}
+	j2k.Rsiz = int(binary.BigEndian.Uint16(segment))
+	j2k.Capabilities = jpeg2000Capabilities(j2k.Rsiz)
+	width, height := binary.BigEndian.Uint32(segment[2:]), binary.BigEndian.Uint32(segment[6:])
+	offsetX, offsetY := binary.BigEndian.Uint32(segment[10:]), binary.BigEndian.Uint32(segment[14:])
+	tileWidth, tileHeight := binary.BigEndian.Uint32(segment[18:]), binary.BigEndian.Uint32(segment[22:])
+	tileOffsetX, tileOffsetY := binary.BigEndian.Uint32(segment[26:]), binary.BigEndian.Uint32(segment[30:])
+	if offsetX >= width || offsetY >= height || tileWidth == 0 || tileHeight == 0 || tileOffsetX >= width || tileOffsetY >= height {
+		return fmt.Errorf("SIZ marker has an invalid image or tile size")
+	}
+	j2k.Width, j2k.Height = int(width-offsetX), int(height-offsetY)
+	j2k.TileWidth, j2k.TileHeight = int(tileWidth), int(tileHeight)
+	j2k.Tiles = int((width-tileOffsetX+tileWidth-1)/tileWidth) * int((height-tileOffsetY+tileHeight-1)/tileHeight)
+
+	j2k.Components = int(binary.BigEndian.Uint16(segment[34:]))
+	if len(segment) < 36+3*j2k.Components {
+		return fmt.Errorf("SIZ marker is truncated")
+	}
+	for i := 0; i < j2k.Components; i++ {
+		component := segment[36+3*i:]
+		depth := int(component[0]&0x7F) + 1
+		if i == 0 || depth > j2k.BitDepth {
+			j2k.BitDepth = depth
+		}
+		j2k.Subsampled = j2k.Subsampled || component[1] != 1 || component[2] != 1
+	}
+	return nil
+}
+
+// jpeg2000Capabilities names the profile an Rsiz value declares, ISO/IEC
+// 15444-1 table A.10 and its amendments
+func jpeg2000Capabilities(rsiz int) string {
+	part2 := ""
+	if rsiz&0x8000 != 0 {
+		part2 = " with Part 2 extensions"
+		rsiz &^= 0x8000
+	}
+	var name string
+	switch {
+	case rsiz == 0:
+		name = "Part 1"
+	case rsiz == 1 || rsiz == 2:
+		name = fmt.Sprintf("Part 1 profile %d", rsiz-1)
+	case rsiz == j2kRsizCinema2K:
+		name = "DCI 2K"
+	case rsiz == j2kRsizCinema4K:
+		name = "DCI 4K"
+	case rsiz == 5:
+		name = "DCI scalable 2K"
+	case rsiz == 6:
+		name = "DCI scalable 4K"
+	case rsiz == 7:
+		name = "Long-term storage"
+	case rsiz>>8 == 0x01:
+		name = "Broadcast single tile"
+	case rsiz>>8 == 0x02:
+		name = "Broadcast multi-tile"
+	case rsiz>>8 == 0x03:
+		name = "Broadcast multi-tile reversible"
+	case rsiz>>8 >= 0x04 && rsiz>>8 <= 0x09:
+		name = []string{"IMF 2K", "IMF 4K", "IMF 8K", "IMF 2K reversible", "IMF 4K reversible", "IMF 8K reversible"}[rsiz>>8-0x04]
+	default:
+		name = fmt.Sprintf("Unknown (0x%04X)", rsiz)
+	}
+	return name + part2
+}
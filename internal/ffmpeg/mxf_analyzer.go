@@ -34,6 +34,7 @@ type MXFAnalysis struct {
 	HeaderMetadata        *HeaderMetadata         `json:"header_metadata,omitempty"`
 	IndexTables           *IndexTableAnalysis     `json:"index_tables,omitempty"`
 	PartitionStructure    *PartitionStructure     `json:"partition_structure,omitempty"`
+	Shims                 *MXFShimDetection       `json:"shims,omitempty"`
 	MXFCompliance         *MXFFormatCompliance    `json:"mxf_compliance,omitempty"`
 	BroadcastCompliance   *BroadcastMXFCompliance `json:"broadcast_compliance,omitempty"`
 	InteroperabilityTests *InteroperabilityTests  `json:"interoperability_tests,omitempty"`
@@ -45,9 +46,12 @@ type MXFAnalysis struct {
 type OperationalPattern struct {
 	PatternLabel     string   `json:"pattern_label"`
 	PatternName      string   `json:"pattern_name"`
-	Complexity       string   `json:"complexity"`        // "Atom", "Molecule", etc.
-	PackageStructure string   `json:"package_structure"` // "Ganged", "Alternative", etc.
-	EssenceStructure string   `json:"essence_structure"` // "Unicast", "Multicast", etc.
+	UL               string   `json:"ul,omitempty"`
+	Complexity       string   `json:"complexity"`                  // "Single Item", "Playlist Items", "Edit Items" or "Atom"
+	PackageStructure string   `json:"package_structure"`           // "Single", "Ganged" or "Alternative"
+	EssenceStructure string   `json:"essence_structure,omitempty"` // "Uni-track" or "Multi-track", from the label qualifiers
+	InternalEssence  *bool    `json:"internal_essence,omitempty"`  // Essence is in this file rather than referenced
+	StreamFile       *bool    `json:"stream_file,omitempty"`       // Essence is interleaved so the file can be played as it is read
 	ClipWrapping     bool     `json:"clip_wrapping"`
 	FrameWrapping    bool     `json:"frame_wrapping"`
 	IsValid          bool     `json:"is_valid"`
@@ -134,7 +138,9 @@ type HeaderMetadata struct {
 // IndexTableAnalysis contains MXF index table analysis
 type IndexTableAnalysis struct {
 	HasIndexTables     bool             `json:"has_index_tables"`
-	IndexTableCount    int              `json:"index_table_count"`
+	IndexTableCount    int              `json:"index_table_count"` // Distinct IndexSIDs
+	SegmentCount       int              `json:"segment_count"`
+	ExpectedDuration   int64            `json:"expected_duration,omitempty"` // Edit units of essence, from the descriptors
 	IndexEditRate      string           `json:"index_edit_rate,omitempty"`
 	IndexStartPosition int64            `json:"index_start_position"`
 	IndexDuration      int64            `json:"index_duration"`
@@ -156,6 +162,17 @@ type PartitionStructure struct {
 	Issues               []string              `json:"issues,omitempty"`
 }
 
+// MXFShimDetection reports the AMWA application specifications a file
+// follows and the constraints of those specifications it breaks
+type MXFShimDetection struct {
+	AS02        bool     `json:"as02"`
+	AS02Role    string   `json:"as02_role,omitempty"` // "version_file" or "essence_component"
+	AS02Issues  []string `json:"as02_issues,omitempty"`
+	AS11        bool     `json:"as11"`
+	AS11Schemes []string `json:"as11_schemes,omitempty"` // "core", "segmentation" and "uk_dpp"
+	AS11Issues  []string `json:"as11_issues,omitempty"`
+}
+
 // MXFFormatCompliance contains comprehensive MXF format compliance analysis
 type MXFFormatCompliance struct {
 	SMPTECompliant    bool     `json:"smpte_compliant"`
@@ -334,49 +351,51 @@ func (mxf *MXFAnalyzer) AnalyzeMXF(ctx context.Context, filePath string) (*MXFAn
 
 	analysis.IsMXFFile = true
 
-	// Step 2: Analyze operational pattern
-	if err := mxf.analyzeOperationalPattern(ctx, filePath, analysis); err != nil {
-		mxf.logger.Warn().Err(err).Msg("Failed to analyze operational pattern")
-	}
-
-	// Step 3: Analyze essence containers
-	if err := mxf.analyzeEssenceContainers(ctx, filePath, analysis); err != nil {
-		mxf.logger.Warn().Err(err).Msg("Failed to analyze essence containers")
-	}
-
-	// Step 4: Analyze header metadata
-	if err := mxf.analyzeHeaderMetadata(ctx, filePath, analysis); err != nil {
-		mxf.logger.Warn().Err(err).Msg("Failed to analyze header metadata")
-	}
-
-	// Step 5: Analyze index tables
-	if err := mxf.analyzeIndexTables(ctx, filePath, analysis); err != nil {
-		mxf.logger.Warn().Err(err).Msg("Failed to analyze index tables")
-	}
-
-	// Step 6: Analyze partition structure
-	if err := mxf.analyzePartitionStructure(ctx, filePath, analysis); err != nil {
-		mxf.logger.Warn().Err(err).Msg("Failed to analyze partition structure")
+	// Step 2: Read partitions, header metadata and index tables from the KLV
+	// structure, falling back to ffprobe metadata when it cannot be read
+	file, err := readMXFFile(filePath)
+	if err != nil {
+		mxf.logger.Warn().Err(err).Msg("Failed to read MXF structure")
+		mxf.analyzeWithFFprobe(ctx, filePath, analysis)
+		analysis.PartitionStructure = &PartitionStructure{
+			Issues: []string{fmt.Sprintf("MXF structure could not be read: %v", err)},
+		}
+	} else {
+		mxf.applyStructure(file, filePath, analysis)
 	}
 
-	// Step 7: Check MXF format compliance
+	// Step 3: Check MXF format compliance
 	analysis.MXFCompliance = mxf.checkMXFCompliance(analysis)
 
-	// Step 8: Check broadcast compliance
+	// Step 4: Check broadcast compliance
 	analysis.BroadcastCompliance = mxf.checkBroadcastCompliance(analysis)
 
-	// Step 9: Run interoperability tests
+	// Step 5: Run interoperability tests
 	analysis.InteroperabilityTests = mxf.runInteroperabilityTests(analysis)
 
-	// Step 10: Generate validation results
+	// Step 6: Generate validation results
 	analysis.ValidationResults = mxf.generateValidationResults(analysis)
 
-	// Step 11: Generate recommended actions
+	// Step 7: Generate recommended actions
 	analysis.RecommendedActions = mxf.generateRecommendedActions(analysis)
 
 	return analysis, nil
 }
 
+// analyzeWithFFprobe fills in the operational pattern, essence containers
+// and header metadata from ffprobe when the KLV structure cannot be read
+func (mxf *MXFAnalyzer) analyzeWithFFprobe(ctx context.Context, filePath string, analysis *MXFAnalysis) {
+	if err := mxf.analyzeOperationalPattern(ctx, filePath, analysis); err != nil {
+		mxf.logger.Warn().Err(err).Msg("Failed to analyze operational pattern")
+	}
+	if err := mxf.analyzeEssenceContainers(ctx, filePath, analysis); err != nil {
+		mxf.logger.Warn().Err(err).Msg("Failed to analyze essence containers")
+	}
+	if err := mxf.analyzeHeaderMetadata(ctx, filePath, analysis); err != nil {
+		mxf.logger.Warn().Err(err).Msg("Failed to analyze header metadata")
+	}
+}
+
 // isMXFFile checks if the file is a valid MXF file
 func (mxf *MXFAnalyzer) isMXFFile(ctx context.Context, filePath string) bool {
 	// Use ffprobe to detect MXF format
@@ -579,106 +598,20 @@ func (mxf *MXFAnalyzer) analyzeHeaderMetadata(ctx context.Context, filePath stri
 	return nil
 }
 
-// analyzeIndexTables analyzes MXF index tables
-func (mxf *MXFAnalyzer) analyzeIndexTables(ctx context.Context, filePath string, analysis *MXFAnalysis) error {
-	// Index table analysis is complex and requires specialized MXF tools
-	// For now, provide basic analysis based on available information
-	indexAnalysis := &IndexTableAnalysis{
-		HasIndexTables:     true, // Assume present for valid MXF
-		IndexTableCount:    1,
-		IndexStartPosition: 0,
-		EditUnitByteCount:  0,
-		SliceCount:         1,
-		DeltaEntryArray:    []DeltaEntry{},
-		IndexEntryArray:    []IndexEntry{},
-	}
-
-	// Basic validation
-	indexAnalysis.ValidationResults = &IndexValidation{
-		IndexComplete:     true,
-		IndexConsistent:   true,
-		RandomAccessValid: true,
-		Issues:            []string{},
-	}
-
-	analysis.IndexTables = indexAnalysis
-	return nil
-}
-
-// analyzePartitionStructure analyzes MXF partition structure
-func (mxf *MXFAnalyzer) analyzePartitionStructure(ctx context.Context, filePath string, analysis *MXFAnalysis) error {
-	// Partition analysis requires specialized MXF parsing
-	// Provide basic structure based on standard MXF patterns
-	partitionStructure := &PartitionStructure{
-		PartitionCount:     3, // Header, Body, Footer
-		Partitions:         []PartitionInfo{},
-		HasHeaderPartition: true,
-		HasBodyPartitions:  true,
-		HasFooterPartition: true,
-		Issues:             []string{},
-	}
-
-	// Create standard partition structure
-	headerPartition := PartitionInfo{
-		PartitionType:      "Header",
-		Status:             "Closed",
-		MajorVersion:       1,
-		MinorVersion:       3,
-		KAGSize:            512,
-		ThisPartition:      0,
-		PreviousPartition:  0,
-		BodySID:            1,
-		OperationalPattern: "OP1a",
-	}
-
-	bodyPartition := PartitionInfo{
-		PartitionType:      "Body",
-		Status:             "Closed",
-		MajorVersion:       1,
-		MinorVersion:       3,
-		KAGSize:            512,
-		BodySID:            1,
-		OperationalPattern: "OP1a",
-	}
-
-	footerPartition := PartitionInfo{
-		PartitionType:      "Footer",
-		Status:             "Closed",
-		MajorVersion:       1,
-		MinorVersion:       3,
-		KAGSize:            512,
-		BodySID:            0,
-		OperationalPattern: "OP1a",
-	}
-
-	partitionStructure.Partitions = append(partitionStructure.Partitions,
-		headerPartition, bodyPartition, footerPartition)
-
-	// Validate partition consistency
-	partitionStructure.PartitionConsistency = &PartitionConsistency{
-		PartitionChainValid: true,
-		RandomAccessPoints:  1,
-		PartitionBalance:    true,
-		Issues:              []string{},
-	}
-
-	analysis.PartitionStructure = partitionStructure
-	return nil
-}
-
 // Helper methods for MXF analysis
 
 func (mxf *MXFAnalyzer) getOperationalPatternName(pattern string) string {
 	patterns := map[string]string{
-		"OP1a": "Operational Pattern 1a - Single Item, Single Package",
-		"OP1b": "Operational Pattern 1b - Single Item, Ganged Packages",
-		"OP1c": "Operational Pattern 1c - Single Item, Alternative Packages",
-		"OP2a": "Operational Pattern 2a - Playlist Items, Single Package",
-		"OP2b": "Operational Pattern 2b - Playlist Items, Ganged Packages",
-		"OP2c": "Operational Pattern 2c - Playlist Items, Alternative Packages",
-		"OP3a": "Operational Pattern 3a - Edit Items, Single Package",
-		"OP3b": "Operational Pattern 3b - Edit Items, Ganged Packages",
-		"OP3c": "Operational Pattern 3c - Edit Items, Alternative Packages",
+		"OP1a":   "Operational Pattern 1a - Single Item, Single Package",
+		"OP1b":   "Operational Pattern 1b - Single Item, Ganged Packages",
+		"OP1c":   "Operational Pattern 1c - Single Item, Alternative Packages",
+		"OP2a":   "Operational Pattern 2a - Playlist Items, Single Package",
+		"OP2b":   "Operational Pattern 2b - Playlist Items, Ganged Packages",
+		"OP2c":   "Operational Pattern 2c - Playlist Items, Alternative Packages",
+		"OP3a":   "Operational Pattern 3a - Edit Items, Single Package",
+		"OP3b":   "Operational Pattern 3b - Edit Items, Ganged Packages",
+		"OP3c":   "Operational Pattern 3c - Edit Items, Alternative Packages",
+		"OPAtom": "Operational Pattern Atom - Single Essence Track",
 	}
 
 	if name, exists := patterns[pattern]; exists {
@@ -688,31 +621,18 @@ func (mxf *MXFAnalyzer) getOperationalPatternName(pattern string) string {
 }
 
 func (mxf *MXFAnalyzer) analyzePatternCharacteristics(op *OperationalPattern) {
-	switch op.PatternLabel {
-	case "OP1a":
+	label := op.PatternLabel
+	switch {
+	case label == "OPAtom":
 		op.Complexity = "Atom"
 		op.PackageStructure = "Single"
-		op.EssenceStructure = "Unicast"
-		op.ClipWrapping = true
-		op.FrameWrapping = false
-	case "OP1b":
-		op.Complexity = "Atom"
-		op.PackageStructure = "Ganged"
-		op.EssenceStructure = "Unicast"
-		op.ClipWrapping = true
-		op.FrameWrapping = false
-	case "OP1c":
-		op.Complexity = "Atom"
-		op.PackageStructure = "Alternative"
-		op.EssenceStructure = "Unicast"
-		op.ClipWrapping = true
-		op.FrameWrapping = false
+	case len(label) == 4 && label[2] >= '1' && label[2] <= '3' && label[3] >= 'a' && label[3] <= 'c':
+		op.Complexity = []string{"Single Item", "Playlist Items", "Edit Items"}[label[2]-'1']
+		op.PackageStructure = []string{"Single", "Ganged", "Alternative"}[label[3]-'a']
 	default:
 		op.Complexity = "Unknown"
 		op.PackageStructure = "Unknown"
-		op.EssenceStructure = "Unknown"
-		op.ClipWrapping = false
-		op.FrameWrapping = false
+		op.IsValid = false
 		op.Issues = append(op.Issues, "Unsupported operational pattern")
 	}
 }
@@ -914,6 +834,17 @@ func (mxf *MXFAnalyzer) checkMXFCompliance(analysis *MXFAnalysis) *MXFFormatComp
 		}
 	}
 
+	// Check the partition structure against SMPTE 377
+	if analysis.PartitionStructure != nil && len(analysis.PartitionStructure.Issues) > 0 {
+		compliance.SMPTE377Compliant = false
+		compliance.ComplianceIssues = append(compliance.ComplianceIssues, analysis.PartitionStructure.Issues...)
+		compliance.ComplianceScore -= 20
+	}
+
+	if analysis.Shims != nil {
+		compliance.AS02Compliant = analysis.Shims.AS02 && len(analysis.Shims.AS02Issues) == 0
+	}
+
 	// Determine overall compliance level
 	if compliance.ComplianceScore >= 90 {
 		compliance.ComplianceLevel = "Full"
@@ -1008,6 +939,20 @@ func (mxf *MXFAnalyzer) generateValidationResults(analysis *MXFAnalysis) *MXFVal
 		results.CriticalIssues = append(results.CriticalIssues, analysis.PartitionStructure.Issues...)
 	}
 
+	if analysis.PartitionStructure != nil && analysis.PartitionStructure.PartitionConsistency != nil {
+		results.Warnings = append(results.Warnings, analysis.PartitionStructure.PartitionConsistency.Issues...)
+	}
+	if analysis.IndexTables != nil && analysis.IndexTables.ValidationResults != nil {
+		results.Warnings = append(results.Warnings, analysis.IndexTables.ValidationResults.Issues...)
+	}
+	if analysis.HeaderMetadata != nil && analysis.HeaderMetadata.ValidationResults != nil {
+		results.Warnings = append(results.Warnings, analysis.HeaderMetadata.ValidationResults.Issues...)
+	}
+	if analysis.Shims != nil {
+		results.Warnings = append(results.Warnings, analysis.Shims.AS02Issues...)
+		results.Warnings = append(results.Warnings, analysis.Shims.AS11Issues...)
+	}
+
 	// Calculate overall compliance
 	issueCount := len(results.CriticalIssues)
 	if issueCount > 0 {
@@ -0,0 +1,923 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"unicode/utf16"
+)
+
+// Limits for reading MXF structure, so damaged or hostile files cannot
+// make the analyzer allocate or scan without bound
+const (
+	maxMXFRunIn          = 65536    // SMPTE 377 allows up to 64 KiB before the header partition
+	maxMXFMetadataBytes  = 64 << 20 // Header metadata or index bytes read from one partition
+	maxMXFPartitions     = 10000
+	maxMXFScannedKLVs    = 5000000 // KLVs walked when neither a RIP nor a footer is found
+	mxfPartitionPackSize = 80      // Fixed fields of a partition pack before its essence container batch
+)
+
+// mxfUL is a SMPTE Universal Label or KLV key
+type mxfUL [16]byte
+
+// Key prefixes. Byte 7 of a UL is the registry version and is ignored when
+// keys are compared.
+var (
+	mxfPartitionKey     = mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x02, 0x05, 0x01, 0x01, 0x0D, 0x01, 0x02, 0x01, 0x01}
+	mxfStructuralSetKey = mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x02, 0x53, 0x01, 0x01, 0x0D, 0x01, 0x01, 0x01, 0x01, 0x01}
+	mxfFillKey          = mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x01, 0x01, 0x01, 0x01, 0x03, 0x01, 0x02, 0x10}
+	mxfIndexSegmentKey  = mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x02, 0x53, 0x01, 0x01, 0x0D, 0x01, 0x02, 0x01, 0x01, 0x10, 0x01}
+)
+
+// Bytes 13 and 14 of partition pack keys
+const (
+	mxfPartitionHeader        = 0x02
+	mxfPartitionBody          = 0x03
+	mxfPartitionFooter        = 0x04
+	mxfRandomIndexPack        = 0x11
+	mxfStatusOpenIncomplete   = 0x01
+	mxfStatusClosedIncomplete = 0x02
+	mxfStatusOpenComplete     = 0x03
+	mxfStatusClosedComplete   = 0x04
+)
+
+// Header metadata set types, byte 14 of structural set keys
+const (
+	mxfSetSourceClip           = 0x11
+	mxfSetTimecodeComponent    = 0x14
+	mxfSetSequence             = 0x0F
+	mxfSetEssenceContainerData = 0x23
+	mxfSetFileDescriptor       = 0x25
+	mxfSetPictureDescriptor    = 0x27
+	mxfSetCDCIDescriptor       = 0x28
+	mxfSetRGBADescriptor       = 0x29
+	mxfSetPreface              = 0x2F
+	mxfSetIdentification       = 0x30
+	mxfSetMaterialPackage      = 0x36
+	mxfSetSourcePackage        = 0x37
+	mxfSetEventTrack           = 0x39
+	mxfSetStaticTrack          = 0x3A
+	mxfSetTimelineTrack        = 0x3B
+	mxfSetDMSegment            = 0x41
+	mxfSetSoundDescriptor      = 0x42
+	mxfSetDataDescriptor       = 0x43
+	mxfSetMultipleDescriptor   = 0x44
+	mxfSetAES3Descriptor       = 0x47
+	mxfSetWAVEDescriptor       = 0x48
+	mxfSetMPEGVideoDescriptor  = 0x51
+	mxfSetVBIDescriptor        = 0x5B
+	mxfSetANCDescriptor        = 0x5C
+)
+
+// Local tags of the header metadata and index table properties read here
+const (
+	mxfTagInstanceUID           = 0x3C0A
+	mxfTagPrefaceVersion        = 0x3B05
+	mxfTagOperationalPattern    = 0x3B09
+	mxfTagEssenceContainers     = 0x3B0A
+	mxfTagDMSchemes             = 0x3B0B
+	mxfTagCompanyName           = 0x3C01
+	mxfTagProductName           = 0x3C02
+	mxfTagProductVersion        = 0x3C03
+	mxfTagVersionString         = 0x3C04
+	mxfTagProductUID            = 0x3C05
+	mxfTagModificationDate      = 0x3C06
+	mxfTagPlatform              = 0x3C08
+	mxfTagGenerationUID         = 0x3C09
+	mxfTagPackageUID            = 0x4401
+	mxfTagPackageName           = 0x4402
+	mxfTagPackageTracks         = 0x4403
+	mxfTagPackageModified       = 0x4404
+	mxfTagPackageCreated        = 0x4405
+	mxfTagPackageDescriptor     = 0x4701
+	mxfTagTrackSequence         = 0x4803
+	mxfTagEditRate              = 0x4B01
+	mxfTagComponentDuration     = 0x0202
+	mxfTagTimecodeStart         = 0x1501
+	mxfTagTimecodeBase          = 0x1502
+	mxfTagTimecodeDropFrame     = 0x1503
+	mxfTagLinkedPackageUID      = 0x2701
+	mxfTagSampleRate            = 0x3001
+	mxfTagContainerDuration     = 0x3002
+	mxfTagEssenceContainer      = 0x3004
+	mxfTagLinkedTrackID         = 0x3006
+	mxfTagStoredHeight          = 0x3202
+	mxfTagStoredWidth           = 0x3203
+	mxfTagSampledHeight         = 0x3204
+	mxfTagSampledWidth          = 0x3205
+	mxfTagDisplayHeight         = 0x3208
+	mxfTagDisplayWidth          = 0x3209
+	mxfTagPictureCoding         = 0x3201
+	mxfTagFrameLayout           = 0x320C
+	mxfTagVideoLineMap          = 0x320D
+	mxfTagAspectRatio           = 0x320E
+	mxfTagComponentDepth        = 0x3301
+	mxfTagHorizontalSubsampling = 0x3302
+	mxfTagVerticalSubsampling   = 0x3308
+	mxfTagQuantizationBits      = 0x3D01
+	mxfTagLocked                = 0x3D02
+	mxfTagAudioSamplingRate     = 0x3D03
+	mxfTagAudioRefLevel         = 0x3D04
+	mxfTagSoundCoding           = 0x3D06
+	mxfTagChannelCount          = 0x3D07
+	mxfTagDialNorm              = 0x3D0C
+	mxfTagDataCoding            = 0x3E01
+	mxfTagIndexEditRate         = 0x3F0B
+	mxfTagIndexStartPosition    = 0x3F0C
+	mxfTagIndexDuration         = 0x3F0D
+	mxfTagEditUnitByteCount     = 0x3F05
+	mxfTagIndexSID              = 0x3F06
+	mxfTagBodySID               = 0x3F07
+	mxfTagSliceCount            = 0x3F08
+	mxfTagDeltaEntryArray       = 0x3F09
+	mxfTagIndexEntryArray       = 0x3F0A
+)
+
+// mxfIndexRandomAccess is the index entry flag of edit units a decoder can
+// start from
+const mxfIndexRandomAccess = 0x80
+
+// mxfKLV locates one KLV triplet in a file
+type mxfKLV struct {
+	key         mxfUL
+	offset      int64 // Start of the key
+	valueOffset int64
+	length      int64
+}
+
+func (k mxfKLV) end() int64 {
+	return k.valueOffset + k.length
+}
+
+// mxfPartition is a parsed partition pack
+type mxfPartition struct {
+	PartitionInfo
+	offset             int64 // Absolute position of the pack key, including any run-in
+	packEnd            int64
+	closed             bool
+	complete           bool
+	indexSID           uint32
+	operationalPattern mxfUL
+	essenceContainers  []mxfUL
+}
+
+// mxfLocalSet is a header metadata or index table set with its properties
+// by local tag
+type mxfLocalSet struct {
+	key   mxfUL
+	props map[uint16][]byte
+}
+
+// mxfIndexSegment is a parsed index table segment
+type mxfIndexSegment struct {
+	editRate          string
+	startPosition     int64
+	duration          int64
+	editUnitByteCount uint32
+	indexSID          uint32
+	bodySID           uint32
+	sliceCount        int
+	deltaEntries      []DeltaEntry
+	entryCount        int
+	randomAccess      int // Entries flagged as random access points
+}
+
+// mxfFile is the KLV structure of an MXF file: its partitions, the most
+// complete copy of the header metadata and every index table segment
+type mxfFile struct {
+	size              int64
+	runIn             int64
+	partitions        []mxfPartition
+	hasRIP            bool
+	metadataPartition int // Index of the partition the header metadata was read from
+	sets              []mxfLocalSet
+	indexSegments     []mxfIndexSegment
+	issues            []string
+}
+
+// readMXFFile reads the partition structure, header metadata and index
+// tables of an MXF file. Essence is skipped, so only the file's metadata is
+// read even for long files.
+func readMXFFile(path string) (*mxfFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return parseMXF(f, info.Size())
+}
+
+// parseMXF reads the MXF structure from r, which holds size bytes
+func parseMXF(r io.ReaderAt, size int64) (*mxfFile, error) {
+	file := &mxfFile{size: size}
+
+	runIn, err := findMXFHeaderPartition(r, size)
+	if err != nil {
+		return nil, err
+	}
+	file.runIn = runIn
+
+	header, err := readMXFPartition(r, runIn, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header partition: %w", err)
+	}
+
+	offsets := file.locatePartitions(r, header)
+	for _, offset := range offsets {
+		if offset == runIn {
+			file.partitions = append(file.partitions, header)
+			continue
+		}
+		partition, err := readMXFPartition(r, offset, size)
+		if err != nil {
+			file.issues = append(file.issues, fmt.Sprintf("No partition pack at byte %d: %v", offset, err))
+			continue
+		}
+		file.partitions = append(file.partitions, partition)
+	}
+
+	file.readHeaderMetadata(r)
+	file.readIndexTables(r)
+	return file, nil
+}
+
+// findMXFHeaderPartition returns the offset of the header partition pack,
+// which follows an optional run-in of up to 64 KiB
+func findMXFHeaderPartition(r io.ReaderAt, size int64) (int64, error) {
+	window := make([]byte, maxMXFRunIn+16)
+	if size < int64(len(window)) {
+		window = window[:size]
+	}
+	n, err := r.ReadAt(window, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	window = window[:n]
+
+	for offset := 0; offset+16 <= len(window); offset++ {
+		index := bytes.Index(window[offset:], mxfPartitionKey[:4])
+		if index < 0 {
+			break
+		}
+		offset += index
+		var key mxfUL
+		if copy(key[:], window[offset:]) == 16 && key.matches(mxfPartitionKey, 13) && key[13] == mxfPartitionHeader {
+			return int64(offset), nil
+		}
+	}
+	return 0, fmt.Errorf("no MXF header partition found")
+}
+
+// locatePartitions lists partition offsets from the random index pack, or
+// by following the footer's back links when there is none, or failing both
+// by walking every KLV of the file
+func (file *mxfFile) locatePartitions(r io.ReaderAt, header mxfPartition) []int64 {
+	if offsets, ok := readMXFRandomIndex(r, file.size, file.runIn); ok {
+		file.hasRIP = true
+		return offsets
+	}
+
+	footer := header.FooterPartition
+	if footer == 0 {
+		// Open headers may not know where the footer is, so look for it in
+		// the closed partitions
+		return file.scanPartitions(r, header)
+	}
+
+	var offsets []int64
+	for this := footer; ; {
+		offsets = append(offsets, file.runIn+this)
+		partition, err := readMXFPartition(r, file.runIn+this, file.size)
+		if err != nil || len(offsets) > maxMXFPartitions {
+			// The chain is broken, walk the file instead
+			file.issues = append(file.issues, "Partition chain from the footer is broken")
+			return file.scanPartitions(r, header)
+		}
+		if this == 0 {
+			break
+		}
+		if partition.PreviousPartition >= this {
+			file.issues = append(file.issues, "Partition chain from the footer is broken")
+			return file.scanPartitions(r, header)
+		}
+		this = partition.PreviousPartition
+	}
+
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets
+}
+
+// scanPartitions walks the file one KLV at a time collecting partition packs
+func (file *mxfFile) scanPartitions(r io.ReaderAt, header mxfPartition) []int64 {
+	offsets := []int64{header.offset}
+	position := header.packEnd
+	for count := 0; position < file.size; count++ {
+		if count == maxMXFScannedKLVs {
+			file.issues = append(file.issues, "Partition scan stopped early, the file has too many KLVs")
+			break
+		}
+		klv, err := readMXFKLV(r, position, file.size)
+		if err != nil {
+			file.issues = append(file.issues, fmt.Sprintf("Invalid KLV at byte %d: %v", position, err))
+			break
+		}
+		if klv.key.matches(mxfPartitionKey, 13) && klv.key[13] >= mxfPartitionHeader && klv.key[13] <= mxfPartitionFooter {
+			offsets = append(offsets, position)
+		}
+		position = klv.end()
+	}
+	return offsets
+}
+
+// readMXFRandomIndex reads the random index pack at the end of the file,
+// whose last four bytes hold its total length
+func readMXFRandomIndex(r io.ReaderAt, size, runIn int64) ([]int64, bool) {
+	if size < 20 {
+		return nil, false
+	}
+	tail := make([]byte, 4)
+	if _, err := r.ReadAt(tail, size-4); err != nil {
+		return nil, false
+	}
+	length := int64(binary.BigEndian.Uint32(tail))
+	if length < 20 || length > size {
+		return nil, false
+	}
+
+	klv, err := readMXFKLV(r, size-length, size)
+	if err != nil || !klv.key.matches(mxfPartitionKey, 13) || klv.key[13] != mxfRandomIndexPack || klv.end() != size {
+		return nil, false
+	}
+	value := make([]byte, klv.length)
+	if _, err := r.ReadAt(value, klv.valueOffset); err != nil {
+		return nil, false
+	}
+
+	var offsets []int64
+	for entry := value[:len(value)-4]; len(entry) >= 12 && len(offsets) < maxMXFPartitions; entry = entry[12:] {
+		offsets = append(offsets, runIn+int64(binary.BigEndian.Uint64(entry[4:12])))
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets, len(offsets) > 0
+}
+
+// readMXFKLV reads the key and BER length of the KLV at offset
+func readMXFKLV(r io.ReaderAt, offset, size int64) (mxfKLV, error) {
+	buffer := make([]byte, 25)
+	n, err := r.ReadAt(buffer, offset)
+	if n < 17 {
+		if err == nil || errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return mxfKLV{}, err
+	}
+
+	klv := mxfKLV{offset: offset}
+	copy(klv.key[:], buffer)
+	length, lengthSize, err := decodeBERLength(buffer[16:n])
+	if err != nil {
+		return mxfKLV{}, err
+	}
+	klv.valueOffset = offset + 16 + int64(lengthSize)
+	klv.length = length
+	if klv.end() > size || klv.end() < klv.valueOffset {
+		return mxfKLV{}, fmt.Errorf("KLV length %d runs past the end of the file", length)
+	}
+	return klv, nil
+}
+
+// decodeBERLength decodes a BER length, returning it and its size in bytes
+func decodeBERLength(data []byte) (int64, int, error) {
+	if len(data) == 0 {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	if data[0] < 0x80 {
+		return int64(data[0]), 1, nil
+	}
+	count := int(data[0] & 0x7F)
+	if count == 0 || count > 8 || count >= len(data) {
+		return 0, 0, fmt.Errorf("invalid BER length")
+	}
+	var length uint64
+	for _, b := range data[1 : count+1] {
+		length = length<<8 | uint64(b)
+	}
+	if length > 1<<62 {
+		return 0, 0, fmt.Errorf("invalid BER length")
+	}
+	return int64(length), count + 1, nil
+}
+
+// readMXFPartition reads the partition pack at offset
+func readMXFPartition(r io.ReaderAt, offset, size int64) (mxfPartition, error) {
+	klv, err := readMXFKLV(r, offset, size)
+	if err != nil {
+		return mxfPartition{}, err
+	}
+	kind := klv.key[13]
+	if !klv.key.matches(mxfPartitionKey, 13) || kind < mxfPartitionHeader || kind > mxfPartitionFooter {
+		return mxfPartition{}, fmt.Errorf("not a partition pack")
+	}
+	if klv.length < mxfPartitionPackSize+8 || klv.length > maxMXFMetadataBytes {
+		return mxfPartition{}, fmt.Errorf("invalid partition pack length %d", klv.length)
+	}
+	value := make([]byte, klv.length)
+	if _, err := r.ReadAt(value, klv.valueOffset); err != nil {
+		return mxfPartition{}, err
+	}
+
+	status := klv.key[14]
+	partition := mxfPartition{
+		offset:   offset,
+		packEnd:  klv.end(),
+		closed:   status == mxfStatusClosedIncomplete || status == mxfStatusClosedComplete,
+		complete: status == mxfStatusOpenComplete || status == mxfStatusClosedComplete,
+		indexSID: binary.BigEndian.Uint32(value[48:52]),
+	}
+	partition.PartitionType = map[byte]string{mxfPartitionHeader: "Header", mxfPartitionBody: "Body", mxfPartitionFooter: "Footer"}[kind]
+	partition.Status = mxfPartitionStatus(partition.closed, partition.complete)
+	partition.MajorVersion = int(binary.BigEndian.Uint16(value[0:2]))
+	partition.MinorVersion = int(binary.BigEndian.Uint16(value[2:4]))
+	partition.KAGSize = int(binary.BigEndian.Uint32(value[4:8]))
+	partition.ThisPartition = int64(binary.BigEndian.Uint64(value[8:16]))
+	partition.PreviousPartition = int64(binary.BigEndian.Uint64(value[16:24]))
+	partition.FooterPartition = int64(binary.BigEndian.Uint64(value[24:32]))
+	partition.HeaderByteCount = int64(binary.BigEndian.Uint64(value[32:40]))
+	partition.IndexByteCount = int64(binary.BigEndian.Uint64(value[40:48]))
+	partition.BodyOffset = int64(binary.BigEndian.Uint64(value[52:60]))
+	partition.BodySID = int(binary.BigEndian.Uint32(value[60:64]))
+	copy(partition.operationalPattern[:], value[64:80])
+	partition.OperationalPattern = mxfOperationalPatternLabel(partition.operationalPattern)
+	partition.essenceContainers = mxfULBatch(value[mxfPartitionPackSize:])
+	for _, container := range partition.essenceContainers {
+		partition.EssenceContainers = append(partition.EssenceContainers, container.String())
+	}
+	return partition, nil
+}
+
+func mxfPartitionStatus(closed, complete bool) string {
+	switch {
+	case closed && complete:
+		return "Closed Complete"
+	case closed:
+		return "Closed Incomplete"
+	case complete:
+		return "Open Complete"
+	default:
+		return "Open Incomplete"
+	}
+}
+
+// readHeaderMetadata parses the best copy of the header metadata: the last
+// closed and complete one, else the last closed one, else the header's own
+func (file *mxfFile) readHeaderMetadata(r io.ReaderAt) {
+	best, bestRank := -1, -1
+	for i, partition := range file.partitions {
+		if partition.HeaderByteCount == 0 {
+			continue
+		}
+		rank := 0
+		if partition.closed {
+			rank++
+		}
+		if partition.closed && partition.complete {
+			rank++
+		}
+		if rank >= bestRank {
+			best, bestRank = i, rank
+		}
+	}
+	file.metadataPartition = best
+	if best < 0 {
+		file.issues = append(file.issues, "No partition carries header metadata")
+		return
+	}
+
+	partition := file.partitions[best]
+	data, err := file.readRegion(r, file.skipFill(r, partition.packEnd), partition.HeaderByteCount)
+	if err != nil {
+		file.issues = append(file.issues, fmt.Sprintf("Failed to read header metadata: %v", err))
+		return
+	}
+	file.sets = parseMXFLocalSets(data)
+}
+
+// readIndexTables parses the index table segments of every partition
+func (file *mxfFile) readIndexTables(r io.ReaderAt) {
+	for _, partition := range file.partitions {
+		if partition.IndexByteCount == 0 {
+			continue
+		}
+		// Header metadata and index tables follow the fill that aligns the
+		// partition pack to the KAG
+		start := file.skipFill(r, partition.packEnd) + partition.HeaderByteCount
+		data, err := file.readRegion(r, file.skipFill(r, start), partition.IndexByteCount)
+		if err != nil {
+			file.issues = append(file.issues, fmt.Sprintf("Failed to read index table of the partition at byte %d: %v", partition.ThisPartition, err))
+			continue
+		}
+		for _, set := range parseMXFLocalSets(data) {
+			if set.key.matches(mxfIndexSegmentKey, 15) {
+				file.indexSegments = append(file.indexSegments, parseMXFIndexSegment(set))
+			}
+		}
+	}
+}
+
+// skipFill returns the position of the first KLV at or after position that
+// is not KLV fill
+func (file *mxfFile) skipFill(r io.ReaderAt, position int64) int64 {
+	for {
+		klv, err := readMXFKLV(r, position, file.size)
+		if err != nil || !klv.key.matches(mxfFillKey, 12) {
+			return position
+		}
+		position = klv.end()
+	}
+}
+
+func (file *mxfFile) readRegion(r io.ReaderAt, offset, length int64) ([]byte, error) {
+	if length > maxMXFMetadataBytes {
+		return nil, fmt.Errorf("%d bytes exceeds the %d byte limit", length, maxMXFMetadataBytes)
+	}
+	if offset+length > file.size {
+		return nil, fmt.Errorf("region runs past the end of the file")
+	}
+	data := make([]byte, length)
+	if _, err := r.ReadAt(data, offset); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// parseMXFLocalSets parses the local sets in a run of KLVs, skipping fill,
+// the primer pack and anything that is not a 2 byte tag, 2 byte length set
+func parseMXFLocalSets(data []byte) []mxfLocalSet {
+	var sets []mxfLocalSet
+	for len(data) >= 17 {
+		var key mxfUL
+		copy(key[:], data)
+		length, lengthSize, err := decodeBERLength(data[16:])
+		if err != nil || int64(len(data)-16-lengthSize) < length {
+			break
+		}
+		value := data[16+lengthSize : 16+lengthSize+int(length)]
+		data = data[16+lengthSize+int(length):]
+
+		if key[0] != 0x06 || key[4] != 0x02 || key[5] != 0x53 {
+			continue
+		}
+		set := mxfLocalSet{key: key, props: make(map[uint16][]byte)}
+		for len(value) >= 4 {
+			tag := binary.BigEndian.Uint16(value)
+			size := int(binary.BigEndian.Uint16(value[2:]))
+			if size > len(value)-4 {
+				break
+			}
+			set.props[tag] = value[4 : 4+size]
+			value = value[4+size:]
+		}
+		sets = append(sets, set)
+	}
+	return sets
+}
+
+func parseMXFIndexSegment(set mxfLocalSet) mxfIndexSegment {
+	segment := mxfIndexSegment{
+		editRate:          set.rational(mxfTagIndexEditRate),
+		startPosition:     int64(set.uint(mxfTagIndexStartPosition)),
+		duration:          int64(set.uint(mxfTagIndexDuration)),
+		editUnitByteCount: uint32(set.uint(mxfTagEditUnitByteCount)),
+		indexSID:          uint32(set.uint(mxfTagIndexSID)),
+		bodySID:           uint32(set.uint(mxfTagBodySID)),
+		sliceCount:        int(set.uint(mxfTagSliceCount)),
+	}
+
+	if count, size, items := mxfBatch(set.props[mxfTagDeltaEntryArray]); size >= 6 {
+		for i := 0; i < count; i++ {
+			item := items[i*size:]
+			segment.deltaEntries = append(segment.deltaEntries, DeltaEntry{
+				PosTableIndex: int(int8(item[0])),
+				Slice:         int(item[1]),
+				ElementData:   int(binary.BigEndian.Uint32(item[2:6])),
+			})
+		}
+	}
+	if count, size, items := mxfBatch(set.props[mxfTagIndexEntryArray]); size >= 11 {
+		segment.entryCount = count
+		for i := 0; i < count; i++ {
+			// Temporal offset, key frame offset, then the flags byte
+			if items[i*size+2]&mxfIndexRandomAccess != 0 {
+				segment.randomAccess++
+			}
+		}
+	}
+	return segment
+}
+
+// mxfBatch splits an MXF batch or array into its item count, item size and
+// items, clamping the count to the data present
+func mxfBatch(data []byte) (int, int, []byte) {
+	if len(data) < 8 {
+		return 0, 0, nil
+	}
+	count := int(binary.BigEndian.Uint32(data))
+	size := int(binary.BigEndian.Uint32(data[4:]))
+	items := data[8:]
+	if size == 0 {
+		return 0, 0, nil
+	}
+	count = min(count, len(items)/size)
+	return count, size, items
+}
+
+// mxfULBatch decodes a batch of ULs or UUIDs
+func mxfULBatch(data []byte) []mxfUL {
+	count, size, items := mxfBatch(data)
+	if size != 16 {
+		return nil
+	}
+	labels := make([]mxfUL, count)
+	for i := range labels {
+		copy(labels[i][:], items[i*16:])
+	}
+	return labels
+}
+
+// matches reports whether the first n bytes of ul equal those of prefix,
+// ignoring the registry version in byte 7
+func (ul mxfUL) matches(prefix mxfUL, n int) bool {
+	for i := 0; i < n; i++ {
+		if i != 7 && ul[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats a UL as a SMPTE URN
+func (ul mxfUL) String() string {
+	return fmt.Sprintf("urn:smpte:ul:%x.%x.%x.%x", ul[0:4], ul[4:8], ul[8:12], ul[12:16])
+}
+
+func (ul mxfUL) isZero() bool {
+	return ul == mxfUL{}
+}
+
+// setType returns byte 14 of a structural metadata set key, or 0 for other
+// sets such as descriptive metadata
+func (set mxfLocalSet) setType() byte {
+	if !set.key.matches(mxfStructuralSetKey, 14) {
+		return 0
+	}
+	return set.key[14]
+}
+
+func (set mxfLocalSet) uid(tag uint16) mxfUL {
+	var uid mxfUL
+	copy(uid[:], set.props[tag])
+	return uid
+}
+
+// uint decodes a big-endian unsigned property of 1 to 8 bytes
+func (set mxfLocalSet) uint(tag uint16) uint64 {
+	var value uint64
+	data := set.props[tag]
+	if len(data) > 8 {
+		return 0
+	}
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+	return value
+}
+
+// int decodes a signed property of 1 to 4 bytes
+func (set mxfLocalSet) int(tag uint16) int {
+	data := set.props[tag]
+	switch len(data) {
+	case 1:
+		return int(int8(data[0]))
+	case 2:
+		return int(int16(binary.BigEndian.Uint16(data)))
+	case 4:
+		return int(int32(binary.BigEndian.Uint32(data)))
+	}
+	return 0
+}
+
+func (set mxfLocalSet) rational(tag uint16) string {
+	data := set.props[tag]
+	if len(data) != 8 {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", int32(binary.BigEndian.Uint32(data)), int32(binary.BigEndian.Uint32(data[4:])))
+}
+
+// string decodes a UTF-16BE string property
+func (set mxfLocalSet) string(tag uint16) string {
+	data := set.props[tag]
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		unit := binary.BigEndian.Uint16(data[i:])
+		if unit == 0 {
+			break
+		}
+		units = append(units, unit)
+	}
+	return string(utf16.Decode(units))
+}
+
+// timestamp decodes an MXF timestamp: year, month, day, hour, minute,
+// second and quarter milliseconds
+func (set mxfLocalSet) timestamp(tag uint16) string {
+	data := set.props[tag]
+	if len(data) != 8 || (data[0] == 0 && data[1] == 0) {
+		return ""
+	}
+	return fmt.Sprintf("%04d-%02d-%02dT%02d:%02d:%02d.%03dZ",
+		binary.BigEndian.Uint16(data), data[2], data[3], data[4], data[5], data[6], int(data[7])*4)
+}
+
+// umid formats a 32 byte package UMID
+func (set mxfLocalSet) umid(tag uint16) string {
+	data := set.props[tag]
+	if len(data) != 32 {
+		return ""
+	}
+	return "urn:smpte:umid:" + hex.EncodeToString(data)
+}
+
+// refs decodes a batch of strong references
+func (set mxfLocalSet) refs(tag uint16) []mxfUL {
+	return mxfULBatch(set.props[tag])
+}
+
+// mxfOperationalPatternLabel names an operational pattern UL (SMPTE 377-1
+// section 9.5.2), e.g. "OP1a" or "OPAtom"
+func mxfOperationalPatternLabel(ul mxfUL) string {
+	prefix := mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x04, 0x01, 0x01, 0x01, 0x0D, 0x01, 0x02, 0x01}
+	if !ul.matches(prefix, 12) {
+		if ul.isZero() {
+			return ""
+		}
+		return "Unknown"
+	}
+	if ul[12] == 0x10 {
+		return "OPAtom"
+	}
+	if ul[12] < 1 || ul[12] > 3 || ul[13] < 1 || ul[13] > 3 {
+		return "Unknown"
+	}
+	return fmt.Sprintf("OP%d%c", ul[12], 'a'+ul[13]-1)
+}
+
+// mxfEssenceContainerNames names the Generic Container mappings by byte 13
+// of their essence container label
+var mxfEssenceContainerNames = map[byte]string{
+	0x01: "D-10 (SMPTE 386)",
+	0x02: "DV-DIF (SMPTE 383)",
+	0x03: "D-11 (SMPTE 387)",
+	0x04: "MPEG Elementary Stream (SMPTE 381-1)",
+	0x05: "Uncompressed Picture (SMPTE 384)",
+	0x06: "AES3/Broadcast Wave Audio (SMPTE 382)",
+	0x07: "MPEG PES (SMPTE 381-1)",
+	0x0A: "A-law Audio (SMPTE 388)",
+	0x0B: "Encrypted (SMPTE 429-6)",
+	0x0C: "JPEG 2000 (SMPTE 422)",
+	0x0D: "VBI Data (SMPTE 436)",
+	0x0E: "ANC Data (SMPTE 436)",
+	0x10: "AVC NAL Unit Stream (SMPTE 381-3)",
+	0x11: "VC-3 (SMPTE 2019-4)",
+	0x13: "Timed Text (SMPTE 429-5)",
+	0x1C: "Apple ProRes (RDD 44)",
+	0x7F: "Multiple Wrappings (SMPTE 379-1)",
+}
+
+// mxfGenericContainer prefixes Generic Container essence container labels
+var mxfGenericContainer = mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x04, 0x01, 0x01, 0x01, 0x0D, 0x01, 0x03, 0x01, 0x02}
+
+// mxfEssenceContainerName names an essence container label
+func mxfEssenceContainerName(ul mxfUL) string {
+	if ul.matches(mxfGenericContainer, 13) {
+		if name, ok := mxfEssenceContainerNames[ul[13]]; ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// mxfWrappingType reads whether an essence container label is frame, clip
+// or line wrapped. The wrapping byte differs between mappings.
+func mxfWrappingType(ul mxfUL) string {
+	if !ul.matches(mxfGenericContainer, 13) {
+		return "Unknown"
+	}
+	wrapping := ul[15]
+	switch ul[13] {
+	case 0x01, 0x03:
+		// D-10 and D-11 are always frame wrapped
+		return "Frame"
+	case 0x05:
+		wrapping %= 4
+	case 0x06:
+		// Broadcast Wave and AES3 variants
+		wrapping = ul[14]
+		if wrapping == 0x03 || wrapping == 0x04 {
+			wrapping -= 2
+		}
+	case 0x0C, 0x11, 0x1C:
+		wrapping = ul[14]
+	}
+	switch wrapping {
+	case 0x01:
+		return "Frame"
+	case 0x02:
+		return "Clip"
+	case 0x03:
+		return "Line"
+	}
+	return "Unknown"
+}
+
+// mxfEssenceCodingName names a picture, sound or data essence coding label
+func mxfEssenceCodingName(ul mxfUL) string {
+	if ul.isZero() {
+		return ""
+	}
+	prefix := mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x04, 0x01, 0x01, 0x01, 0x04}
+	if !ul.matches(prefix, 9) {
+		return ul.String()
+	}
+	switch {
+	case ul[9] == 0x01 && ul[10] == 0x02 && ul[11] == 0x01:
+		return "Uncompressed Picture"
+	case ul[9] == 0x01 && ul[10] == 0x02 && ul[11] == 0x02 && ul[12] == 0x01:
+		switch {
+		case ul[13] >= 0x30 && ul[13] <= 0x3F:
+			return "AVC (H.264)"
+		case ul[13] >= 0x20 && ul[13] <= 0x2F:
+			return "MPEG-4 Visual"
+		default:
+			return "MPEG-2 Video"
+		}
+	case ul[9] == 0x01 && ul[10] == 0x02 && ul[11] == 0x02 && ul[12] == 0x02:
+		return "DV"
+	case ul[9] == 0x01 && ul[10] == 0x02 && ul[11] == 0x02 && ul[12] == 0x03 && ul[13] == 0x01:
+		return "JPEG 2000"
+	case ul[9] == 0x01 && ul[10] == 0x02 && ul[11] == 0x02 && ul[12] == 0x03 && ul[13] == 0x06:
+		return "Apple ProRes"
+	case ul[9] == 0x01 && ul[10] == 0x02 && ul[11] == 0x02 && ul[12] == 0x71:
+		return "VC-3 (DNxHD/DNxHR)"
+	case ul[9] == 0x02 && ul[10] == 0x02 && ul[11] == 0x01:
+		return "PCM"
+	}
+	return ul.String()
+}
+
+// mxfAS11Scheme prefixes the AMWA AS-11 descriptive metadata scheme labels
+// and set keys; byte 13 tells the core, segmentation and UK DPP schemes
+// apart
+var mxfAS11Scheme = mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x04, 0x01, 0x01, 0x01, 0x0D, 0x01, 0x07, 0x01, 0x0B}
+
+// mxfAS11SchemeName names an AS-11 scheme label, or returns "" for other
+// labels
+func mxfAS11SchemeName(ul mxfUL) string {
+	if !ul.matches(mxfAS11Scheme, 4) || !bytes.Equal(ul[8:13], mxfAS11Scheme[8:13]) {
+		return ""
+	}
+	switch {
+	case ul[13] == 0x01 && ul[14] == 0x01:
+		return "core"
+	case ul[13] == 0x01 && ul[14] == 0x03:
+		return "segmentation"
+	case ul[13] == 0x02:
+		return "uk_dpp"
+	}
+	return "other"
+}
+
+// findAS02Manifest returns the manifest of the AS-02 bundle a file sits in.
+// Essence components live in the bundle's media folder, the version file at
+// its root next to manifest.xml.
+func findAS02Manifest(filePath string) string {
+	dir := filepath.Dir(filePath)
+	for _, candidate := range []string{dir, filepath.Dir(dir)} {
+		manifest := filepath.Join(candidate, "manifest.xml")
+		if info, err := os.Stat(manifest); err == nil && !info.IsDir() {
+			return manifest
+		}
+	}
+	return ""
+}
@@ -0,0 +1,359 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	testMXFOP1a       = mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x04, 0x01, 0x01, 0x01, 0x0D, 0x01, 0x02, 0x01, 0x01, 0x01, 0x09, 0x00}
+	testMXFMPEGFrame  = mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x04, 0x01, 0x01, 0x02, 0x0D, 0x01, 0x03, 0x01, 0x02, 0x04, 0x60, 0x01}
+	testMXFMPEG2      = mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x04, 0x01, 0x01, 0x03, 0x04, 0x01, 0x02, 0x02, 0x01, 0x04, 0x03, 0x00}
+	testMXFAS11Core   = mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x04, 0x01, 0x01, 0x0C, 0x0D, 0x01, 0x07, 0x01, 0x0B, 0x01, 0x01, 0x00}
+	testMXFAS11UKDPP  = mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x04, 0x01, 0x01, 0x0C, 0x0D, 0x01, 0x07, 0x01, 0x0B, 0x02, 0x01, 0x00}
+	testMXFPrimerPack = mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x02, 0x05, 0x01, 0x01, 0x0D, 0x01, 0x02, 0x01, 0x01, 0x05, 0x01, 0x00}
+)
+
+func testMXFKLV(key mxfUL, value []byte) []byte {
+	klv := append(key[:], 0x83, byte(len(value)>>16), byte(len(value)>>8), byte(len(value)))
+	return append(klv, value...)
+}
+
+// testMXFSet builds a structural metadata set from tag, value pairs
+func testMXFSet(setType, uid byte, props ...interface{}) []byte {
+	key := mxfStructuralSetKey
+	key[14] = setType
+	value := binary.BigEndian.AppendUint16(nil, mxfTagInstanceUID)
+	value = append(value, 0, 16)
+	value = append(value, testMXFUID(uid)...)
+	for i := 0; i < len(props); i += 2 {
+		data := props[i+1].([]byte)
+		value = binary.BigEndian.AppendUint16(value, uint16(props[i].(int)))
+		value = binary.BigEndian.AppendUint16(value, uint16(len(data)))
+		value = append(value, data...)
+	}
+	return testMXFKLV(key, value)
+}
+
+func testMXFUID(uid byte) []byte {
+	data := make([]byte, 16)
+	data[15] = uid
+	return data
+}
+
+func testMXFBatch(items ...[]byte) []byte {
+	batch := binary.BigEndian.AppendUint32(nil, uint32(len(items)))
+	batch = binary.BigEndian.AppendUint32(batch, 16)
+	for _, item := range items {
+		batch = append(batch, item...)
+	}
+	return batch
+}
+
+func testMXFUint(value uint64, size int) []byte {
+	return binary.BigEndian.AppendUint64(nil, value)[8-size:]
+}
+
+func testMXFPartition(kind, status byte, this, previous, footer int64, headerBytes, indexBytes int, indexSID uint32) []byte {
+	key := mxfPartitionKey
+	key[13], key[14] = kind, status
+	value := binary.BigEndian.AppendUint16(nil, 1)
+	value = binary.BigEndian.AppendUint16(value, 3)
+	value = binary.BigEndian.AppendUint32(value, 1)
+	for _, field := range []int64{this, previous, footer, int64(headerBytes), int64(indexBytes)} {
+		value = binary.BigEndian.AppendUint64(value, uint64(field))
+	}
+	value = binary.BigEndian.AppendUint32(value, indexSID)
+	value = binary.BigEndian.AppendUint64(value, 0)
+	value = binary.BigEndian.AppendUint32(value, 1)
+	value = append(value, testMXFOP1a[:]...)
+	value = append(value, testMXFBatch(testMXFMPEGFrame[:])...)
+	return testMXFKLV(key, value)
+}
+
+// testMXFHeaderMetadata builds the header metadata of a frame wrapped
+// MPEG-2 OP1a file of 250 frames with AS-11 UK DPP schemes
+func testMXFHeaderMetadata() []byte {
+	umid := bytes.Repeat([]byte{0xAB}, 32)
+	var metadata []byte
+	metadata = append(metadata, testMXFKLV(testMXFPrimerPack, testMXFBatch())...)
+	metadata = append(metadata, testMXFSet(mxfSetPreface, 1,
+		mxfTagPrefaceVersion, testMXFUint(0x0103, 2),
+		mxfTagOperationalPattern, testMXFOP1a[:],
+		mxfTagEssenceContainers, testMXFBatch(testMXFMPEGFrame[:]),
+		mxfTagDMSchemes, testMXFBatch(testMXFAS11Core[:], testMXFAS11UKDPP[:]))...)
+	metadata = append(metadata, testMXFSet(mxfSetIdentification, 2,
+		mxfTagCompanyName, []byte{0, 'A', 0, 'c', 0, 'm', 0, 'e'},
+		mxfTagProductVersion, []byte{0, 2, 0, 1, 0, 0, 0, 7, 0, 1},
+		mxfTagModificationDate, []byte{0x07, 0xEA, 10, 16, 12, 30, 0, 0})...)
+	metadata = append(metadata, testMXFSet(mxfSetMaterialPackage, 3,
+		mxfTagPackageUID, bytes.Repeat([]byte{0xCD}, 32),
+		mxfTagPackageTracks, testMXFBatch(testMXFUID(4)))...)
+	metadata = append(metadata, testMXFSet(mxfSetTimelineTrack, 4,
+		mxfTagEditRate, []byte{0, 0, 0, 25, 0, 0, 0, 1},
+		mxfTagTrackSequence, testMXFUID(5))...)
+	metadata = append(metadata, testMXFSet(mxfSetSequence, 5,
+		mxfTagComponentDuration, testMXFUint(250, 8))...)
+	metadata = append(metadata, testMXFSet(mxfSetTimecodeComponent, 6,
+		mxfTagTimecodeBase, testMXFUint(25, 2),
+		mxfTagTimecodeStart, testMXFUint(90000, 8),
+		mxfTagTimecodeDropFrame, []byte{0})...)
+	metadata = append(metadata, testMXFSet(mxfSetSourcePackage, 7,
+		mxfTagPackageUID, umid,
+		mxfTagPackageTracks, testMXFBatch(),
+		mxfTagPackageDescriptor, testMXFUID(8))...)
+	metadata = append(metadata, testMXFSet(mxfSetCDCIDescriptor, 8,
+		mxfTagLinkedTrackID, testMXFUint(2, 4),
+		mxfTagSampleRate, []byte{0, 0, 0, 25, 0, 0, 0, 1},
+		mxfTagContainerDuration, testMXFUint(250, 8),
+		mxfTagEssenceContainer, testMXFMPEGFrame[:],
+		mxfTagPictureCoding, testMXFMPEG2[:],
+		mxfTagStoredWidth, testMXFUint(1920, 4),
+		mxfTagStoredHeight, testMXFUint(540, 4),
+		mxfTagFrameLayout, []byte{1},
+		mxfTagVideoLineMap, []byte{0, 0, 0, 2, 0, 0, 0, 4, 0, 0, 0, 21, 0, 0, 2, 0x18},
+		mxfTagComponentDepth, testMXFUint(8, 4))...)
+	metadata = append(metadata, testMXFSet(mxfSetEssenceContainerData, 9,
+		mxfTagLinkedPackageUID, umid,
+		mxfTagIndexSID, testMXFUint(2, 4),
+		mxfTagBodySID, testMXFUint(1, 4))...)
+	return metadata
+}
+
+// testMXFIndexSegment builds a constant bytes per edit unit index segment
+func testMXFIndexSegment(start, duration uint64) []byte {
+	value := []byte{}
+	for _, prop := range []struct {
+		tag  uint16
+		data []byte
+	}{
+		{mxfTagIndexEditRate, []byte{0, 0, 0, 25, 0, 0, 0, 1}},
+		{mxfTagIndexStartPosition, testMXFUint(start, 8)},
+		{mxfTagIndexDuration, testMXFUint(duration, 8)},
+		{mxfTagEditUnitByteCount, testMXFUint(200000, 4)},
+		{mxfTagIndexSID, testMXFUint(2, 4)},
+		{mxfTagBodySID, testMXFUint(1, 4)},
+	} {
+		value = binary.BigEndian.AppendUint16(value, prop.tag)
+		value = binary.BigEndian.AppendUint16(value, uint16(len(prop.data)))
+		value = append(value, prop.data...)
+	}
+	return testMXFKLV(mxfIndexSegmentKey, value)
+}
+
+// testMXF builds a complete file: a closed complete header with metadata,
+// essence, a footer with an index segment and a random index pack
+func testMXF() []byte {
+	metadata := testMXFHeaderMetadata()
+	headerSize := len(testMXFPartition(mxfPartitionHeader, 0x04, 0, 0, 0, 0, 0, 0))
+	essence := testMXFKLV(mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x01, 0x02, 0x01, 0x01, 0x0D, 0x01, 0x03, 0x01, 0x15, 0x01, 0x05, 0x00}, make([]byte, 64))
+	footerOffset := int64(headerSize + len(metadata) + len(essence))
+	index := testMXFIndexSegment(0, 0)
+
+	var file []byte
+	file = append(file, testMXFPartition(mxfPartitionHeader, 0x04, 0, 0, footerOffset, len(metadata), 0, 0)...)
+	file = append(file, metadata...)
+	file = append(file, essence...)
+	file = append(file, testMXFPartition(mxfPartitionFooter, 0x04, footerOffset, 0, footerOffset, 0, len(index), 2)...)
+	file = append(file, index...)
+
+	rip := binary.BigEndian.AppendUint32(nil, 0)
+	rip = binary.BigEndian.AppendUint64(rip, 0)
+	rip = binary.BigEndian.AppendUint32(rip, 0)
+	rip = binary.BigEndian.AppendUint64(rip, uint64(footerOffset))
+	rip = binary.BigEndian.AppendUint32(rip, uint32(16+4+len(rip)+4))
+	ripKey := mxfPartitionKey
+	ripKey[13], ripKey[14] = mxfRandomIndexPack, 0x01
+	return append(file, testMXFKLV(ripKey, rip)...)
+}
+
+func TestParseMXF(t *testing.T) {
+	data := testMXF()
+	file, err := parseMXF(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("parseMXF failed: %v", err)
+	}
+
+	if !file.hasRIP || len(file.partitions) != 2 || len(file.issues) != 0 {
+		t.Fatalf("expected a header and footer from the RIP, got %d partitions, issues %v", len(file.partitions), file.issues)
+	}
+	if file.metadataPartition != 0 || len(file.sets) != 9 {
+		t.Errorf("expected 9 sets from the header partition, got %d from %d", len(file.sets), file.metadataPartition)
+	}
+	if len(file.indexSegments) != 1 || file.indexSegments[0].editUnitByteCount != 200000 {
+		t.Errorf("expected the footer's index segment, got %+v", file.indexSegments)
+	}
+	if footer := file.partitions[1]; footer.PartitionType != "Footer" || footer.Status != "Closed Complete" || footer.OperationalPattern != "OP1a" {
+		t.Errorf("unexpected footer %+v", footer.PartitionInfo)
+	}
+}
+
+func TestParseMXF_RunInAndNoHeader(t *testing.T) {
+	data := append(bytes.Repeat([]byte{0xFF}, 100), testMXF()...)
+	file, err := parseMXF(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("parseMXF failed: %v", err)
+	}
+	if file.runIn != 100 || len(file.partitions) != 2 || file.partitions[1].offset-file.runIn != file.partitions[1].ThisPartition {
+		t.Errorf("expected partitions offset by the run-in, got run-in %d and %d partitions", file.runIn, len(file.partitions))
+	}
+
+	if _, err := parseMXF(bytes.NewReader([]byte("not an mxf file")), 15); err == nil {
+		t.Error("expected an error without a header partition")
+	}
+}
+
+func TestApplyStructure(t *testing.T) {
+	data := testMXF()
+	file, err := parseMXF(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("parseMXF failed: %v", err)
+	}
+
+	mxf := NewMXFAnalyzer("ffprobe", zerolog.Nop())
+	analysis := &MXFAnalysis{}
+	mxf.applyStructure(file, "/nonexistent/clip.mxf", analysis)
+
+	op := analysis.OperationalPattern
+	if op.PatternLabel != "OP1a" || !op.IsValid || !op.FrameWrapping || op.EssenceStructure != "Multi-track" || !*op.InternalEssence {
+		t.Errorf("unexpected operational pattern %+v", op)
+	}
+
+	if len(analysis.EssenceContainers) != 1 {
+		t.Fatalf("expected one essence container, got %+v", analysis.EssenceContainers)
+	}
+	container := analysis.EssenceContainers[0]
+	if container.ContainerName != "MPEG Elementary Stream (SMPTE 381-1)" || container.WrappingType != "Frame" || container.EssenceCompression != "MPEG-2 Video" || !container.IsCompliant {
+		t.Errorf("unexpected essence container %+v", container)
+	}
+	picture := container.EssenceDescriptors[0].PictureEssence
+	if picture.StoredDimensions != "1920x540" || picture.FrameLayout != "SeparateFields" || len(picture.VideoLineMap) != 2 || picture.VideoLineMap[1] != 536 {
+		t.Errorf("unexpected picture descriptor %+v", picture)
+	}
+
+	header := analysis.HeaderMetadata
+	if header.MetadataVersion != "1.3" || header.MaterialPackage.Duration != "250 edit units at 25/1" || header.Timecode.StartTimecode != "01:00:00:00" {
+		t.Errorf("unexpected header metadata %+v", header)
+	}
+	if len(header.SourcePackages) != 1 || header.SourcePackages[0].PackageType != "File" || header.SourcePackages[0].Descriptor != "CDCIEssenceDescriptor" {
+		t.Errorf("unexpected source packages %+v", header.SourcePackages)
+	}
+	if identification := header.IdentificationSets[0]; identification.CompanyName != "Acme" || identification.ProductVersion != "2.1.0.7" || identification.ModificationDate != "2026-10-16T12:30:00.000Z" {
+		t.Errorf("unexpected identification %+v", identification)
+	}
+	if issues := header.ValidationResults.Issues; len(issues) != 0 {
+		t.Errorf("expected valid header metadata, got %v", issues)
+	}
+
+	index := analysis.IndexTables
+	if !index.HasIndexTables || index.SegmentCount != 1 || index.ExpectedDuration != 250 || len(index.ValidationResults.Issues) != 0 {
+		t.Errorf("expected a complete CBE index, got %+v, %v", index, index.ValidationResults.Issues)
+	}
+
+	partitions := analysis.PartitionStructure
+	if !partitions.HasHeaderPartition || !partitions.HasFooterPartition || len(partitions.Issues) != 0 || !partitions.PartitionConsistency.PartitionChainValid {
+		t.Errorf("expected a valid partition structure, got %+v, %v", partitions, partitions.PartitionConsistency.Issues)
+	}
+
+	if shims := analysis.Shims; !shims.AS11 || len(shims.AS11Issues) != 0 || shims.AS02 {
+		t.Errorf("expected a clean AS-11 file, got %+v", shims)
+	}
+	if analysis.MXFProfile != "AS-11 UK DPP" {
+		t.Errorf("expected the AS-11 UK DPP profile, got %q", analysis.MXFProfile)
+	}
+}
+
+func TestApplyStructure_TruncatedFile(t *testing.T) {
+	// An open header whose footer was never written
+	metadata := testMXFHeaderMetadata()
+	data := append(testMXFPartition(mxfPartitionHeader, 0x01, 0, 0, 0, len(metadata), 0, 0), metadata...)
+	file, err := parseMXF(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("parseMXF failed: %v", err)
+	}
+
+	mxf := NewMXFAnalyzer("ffprobe", zerolog.Nop())
+	analysis := &MXFAnalysis{}
+	mxf.applyStructure(file, "/nonexistent/clip.mxf", analysis)
+
+	issues := strings.Join(analysis.PartitionStructure.Issues, "\n")
+	for _, expected := range []string{"Header partition is open", "No footer partition"} {
+		if !strings.Contains(issues, expected) {
+			t.Errorf("expected %q in partition issues %v", expected, analysis.PartitionStructure.Issues)
+		}
+	}
+	if analysis.IndexTables.HasIndexTables || analysis.IndexTables.ValidationResults.IndexComplete {
+		t.Error("expected a missing index to be reported")
+	}
+	if analysis.HeaderMetadata.ValidationResults.HeaderComplete {
+		t.Error("expected header metadata from an open partition to be incomplete")
+	}
+	if shims := strings.Join(analysis.Shims.AS11Issues, "\n"); !strings.Contains(shims, "footer partition is required") || !strings.Contains(shims, "random index pack") {
+		t.Errorf("expected AS-11 structure issues, got %v", analysis.Shims.AS11Issues)
+	}
+}
+
+func TestIndexTablesFromStructure_Gaps(t *testing.T) {
+	file := &mxfFile{indexSegments: []mxfIndexSegment{
+		{indexSID: 1, startPosition: 0, duration: 100, entryCount: 100, randomAccess: 10},
+		{indexSID: 1, startPosition: 0, duration: 100, entryCount: 100, randomAccess: 10}, // Repeated in the footer
+		{indexSID: 1, startPosition: 150, duration: 50, entryCount: 40, randomAccess: 5},
+	}}
+
+	index := NewMXFAnalyzer("ffprobe", zerolog.Nop()).indexTablesFromStructure(file, newMXFMetadata(nil))
+
+	validation := index.ValidationResults
+	if index.SegmentCount != 2 || index.IndexDuration != 150 || validation.IndexComplete || validation.IndexConsistent {
+		t.Errorf("expected a gap and a short segment, got %+v, %v", index, validation.Issues)
+	}
+	if len(validation.Issues) != 2 || !strings.Contains(validation.Issues[1], "edit units 100 to 149") {
+		t.Errorf("unexpected index issues %v", validation.Issues)
+	}
+}
+
+func TestMXFTimecodeString(t *testing.T) {
+	cases := []struct {
+		frames    int64
+		base      int
+		dropFrame bool
+		expected  string
+	}{
+		{90000, 25, false, "01:00:00:00"},
+		{107892, 30, true, "01:00:00;00"},
+		{1800, 30, true, "00:01:00;02"},
+		{0, 0, false, ""},
+	}
+	for _, c := range cases {
+		if timecode := FormatTimecode(c.frames, c.base, c.dropFrame); timecode != c.expected {
+			t.Errorf("%d frames at %d: expected %q, got %q", c.frames, c.base, c.expected, timecode)
+		}
+	}
+}
+
+func TestMXFLabels(t *testing.T) {
+	if label := mxfOperationalPatternLabel(testMXFOP1a); label != "OP1a" {
+		t.Errorf("expected OP1a, got %q", label)
+	}
+	atom := testMXFOP1a
+	atom[12], atom[13] = 0x10, 0x00
+	if label := mxfOperationalPatternLabel(atom); label != "OPAtom" {
+		t.Errorf("expected OPAtom, got %q", label)
+	}
+
+	clip := testMXFMPEGFrame
+	clip[15] = 0x02
+	if wrapping := mxfWrappingType(clip); wrapping != "Clip" {
+		t.Errorf("expected clip wrapping, got %q", wrapping)
+	}
+	bwf := mxfUL{0x06, 0x0E, 0x2B, 0x34, 0x04, 0x01, 0x01, 0x01, 0x0D, 0x01, 0x03, 0x01, 0x02, 0x06, 0x01, 0x00}
+	if wrapping := mxfWrappingType(bwf); wrapping != "Frame" || mxfEssenceContainerName(bwf) != "AES3/Broadcast Wave Audio (SMPTE 382)" {
+		t.Errorf("unexpected Broadcast Wave label: %q", wrapping)
+	}
+	if name := mxfAS11SchemeName(testMXFAS11UKDPP); name != "uk_dpp" {
+		t.Errorf("expected the UK DPP scheme, got %q", name)
+	}
+}
@@ -0,0 +1,737 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"sort"
+)
+
+// mxfDescriptorTypes names the essence descriptors by header metadata set
+// type, with the kind of essence they describe
+var mxfDescriptorTypes = map[byte]struct{ name, essenceType string }{
+	mxfSetFileDescriptor:      {"FileDescriptor", "Unknown"},
+	mxfSetPictureDescriptor:   {"GenericPictureEssenceDescriptor", "Picture"},
+	mxfSetCDCIDescriptor:      {"CDCIEssenceDescriptor", "Picture"},
+	mxfSetRGBADescriptor:      {"RGBAEssenceDescriptor", "Picture"},
+	mxfSetMPEGVideoDescriptor: {"MPEG2VideoDescriptor", "Picture"},
+	mxfSetSoundDescriptor:     {"GenericSoundEssenceDescriptor", "Sound"},
+	mxfSetAES3Descriptor:      {"AES3AudioEssenceDescriptor", "Sound"},
+	mxfSetWAVEDescriptor:      {"WaveAudioEssenceDescriptor", "Sound"},
+	mxfSetDataDescriptor:      {"GenericDataEssenceDescriptor", "Data"},
+	mxfSetVBIDescriptor:       {"VBIDataDescriptor", "Data"},
+	mxfSetANCDescriptor:       {"ANCDataDescriptor", "Data"},
+}
+
+// Frame layouts by their SMPTE 377 code
+var mxfFrameLayouts = []string{"FullFrame", "SeparateFields", "OneField", "MixedFields", "SegmentedFrame"}
+
+// mxfMetadata indexes the header metadata sets by type and instance UID
+type mxfMetadata struct {
+	sets   []mxfLocalSet
+	byUID  map[mxfUL]mxfLocalSet
+	byType map[byte][]mxfLocalSet
+}
+
+func newMXFMetadata(sets []mxfLocalSet) *mxfMetadata {
+	metadata := &mxfMetadata{
+		sets:   sets,
+		byUID:  make(map[mxfUL]mxfLocalSet, len(sets)),
+		byType: make(map[byte][]mxfLocalSet),
+	}
+	for _, set := range sets {
+		if uid := set.uid(mxfTagInstanceUID); !uid.isZero() {
+			metadata.byUID[uid] = set
+		}
+		if setType := set.setType(); setType != 0 {
+			metadata.byType[setType] = append(metadata.byType[setType], set)
+		}
+	}
+	return metadata
+}
+
+// preface returns the Preface set, the root of the header metadata
+func (m *mxfMetadata) preface() (mxfLocalSet, bool) {
+	if prefaces := m.byType[mxfSetPreface]; len(prefaces) > 0 {
+		return prefaces[0], true
+	}
+	return mxfLocalSet{}, false
+}
+
+// descriptors lists the essence descriptors, leaving out the multiple
+// descriptors that only group them
+func (m *mxfMetadata) descriptors() []mxfLocalSet {
+	var descriptors []mxfLocalSet
+	for _, set := range m.sets {
+		if _, ok := mxfDescriptorTypes[set.setType()]; ok {
+			descriptors = append(descriptors, set)
+		}
+	}
+	return descriptors
+}
+
+// applyStructure fills the analysis from the KLV structure of the file
+func (mxf *MXFAnalyzer) applyStructure(file *mxfFile, filePath string, analysis *MXFAnalysis) {
+	metadata := newMXFMetadata(file.sets)
+
+	analysis.EssenceContainers = mxf.essenceContainersFromStructure(metadata)
+	analysis.OperationalPattern = mxf.operationalPatternFromStructure(file, metadata, analysis.EssenceContainers)
+	analysis.HeaderMetadata = mxf.headerMetadataFromStructure(file, metadata)
+	analysis.IndexTables = mxf.indexTablesFromStructure(file, metadata)
+	analysis.PartitionStructure = mxf.partitionStructureFromStructure(file)
+	analysis.Shims = mxf.detectShims(filePath, file, metadata, analysis)
+
+	switch {
+	case analysis.Shims.AS11 && containsFold(analysis.Shims.AS11Schemes, "uk_dpp"):
+		analysis.MXFProfile = "AS-11 UK DPP"
+	case analysis.Shims.AS11:
+		analysis.MXFProfile = "AS-11"
+	case analysis.Shims.AS02:
+		analysis.MXFProfile = "AS-02"
+	}
+}
+
+// operationalPatternFromStructure reads the operational pattern label from
+// the preface, or from the header partition pack when there is no preface
+func (mxf *MXFAnalyzer) operationalPatternFromStructure(file *mxfFile, metadata *mxfMetadata, containers []EssenceContainerInfo) *OperationalPattern {
+	var ul mxfUL
+	if preface, ok := metadata.preface(); ok {
+		ul = preface.uid(mxfTagOperationalPattern)
+	}
+	if ul.isZero() && len(file.partitions) > 0 {
+		ul = file.partitions[0].operationalPattern
+	}
+
+	label := mxfOperationalPatternLabel(ul)
+	op := &OperationalPattern{
+		PatternLabel: label,
+		PatternName:  mxf.getOperationalPatternName(label),
+		UL:           ul.String(),
+		IsValid:      true,
+	}
+	mxf.analyzePatternCharacteristics(op)
+
+	if label != "Unknown" {
+		// Byte 14 qualifiers: bit 1 external essence, bit 2 non-stream
+		// file, bit 3 multi-track
+		internal := ul[14]&0x02 == 0
+		stream := ul[14]&0x04 == 0
+		op.InternalEssence = &internal
+		op.StreamFile = &stream
+		op.EssenceStructure = "Uni-track"
+		if ul[14]&0x08 != 0 {
+			op.EssenceStructure = "Multi-track"
+		}
+	}
+
+	for _, container := range containers {
+		op.FrameWrapping = op.FrameWrapping || container.WrappingType == "Frame"
+		op.ClipWrapping = op.ClipWrapping || container.WrappingType == "Clip"
+	}
+	return op
+}
+
+// essenceContainersFromStructure groups the essence descriptors by the
+// essence container they are wrapped in
+func (mxf *MXFAnalyzer) essenceContainersFromStructure(metadata *mxfMetadata) []EssenceContainerInfo {
+	containers := []EssenceContainerInfo{}
+	positions := make(map[mxfUL]int)
+
+	for _, set := range metadata.descriptors() {
+		label := set.uid(mxfTagEssenceContainer)
+		descriptor := mxf.descriptorFromStructure(set)
+
+		position, ok := positions[label]
+		if !ok {
+			position = len(containers)
+			positions[label] = position
+			containers = append(containers, mxf.essenceContainerFromLabel(label))
+		}
+		container := &containers[position]
+		container.TrackCount++
+		container.EssenceDescriptors = append(container.EssenceDescriptors, descriptor)
+		if container.EssenceType == "" {
+			container.EssenceType = mxfDescriptorTypes[set.setType()].essenceType
+			container.EssenceCompression = mxf.descriptorCompression(descriptor)
+		}
+	}
+
+	// Files without descriptors still list their containers in the preface
+	if preface, ok := metadata.preface(); ok && len(containers) == 0 {
+		for _, label := range preface.refs(mxfTagEssenceContainers) {
+			if label[13] != 0x7F {
+				containers = append(containers, mxf.essenceContainerFromLabel(label))
+			}
+		}
+	}
+	return containers
+}
+
+func (mxf *MXFAnalyzer) essenceContainerFromLabel(label mxfUL) EssenceContainerInfo {
+	container := EssenceContainerInfo{
+		ContainerLabel:     label.String(),
+		ContainerName:      mxfEssenceContainerName(label),
+		WrappingType:       mxfWrappingType(label),
+		EssenceDescriptors: []EssenceDescriptorInfo{},
+		IsCompliant:        true,
+		Issues:             []string{},
+	}
+	if container.ContainerName == "" {
+		container.ContainerName = "Unregistered essence container"
+		container.IsCompliant = false
+		container.Issues = append(container.Issues, fmt.Sprintf("Essence container %s is not a registered Generic Container mapping", label))
+	}
+	return container
+}
+
+func (mxf *MXFAnalyzer) descriptorCompression(descriptor EssenceDescriptorInfo) string {
+	switch {
+	case descriptor.PictureEssence != nil:
+		return descriptor.PictureEssence.PictureCompression
+	case descriptor.SoundEssence != nil:
+		return descriptor.SoundEssence.SoundEssenceCompression
+	case descriptor.DataEssence != nil:
+		return descriptor.DataEssence.DataEssenceCompression
+	}
+	return ""
+}
+
+// descriptorFromStructure reads an essence descriptor set
+func (mxf *MXFAnalyzer) descriptorFromStructure(set mxfLocalSet) EssenceDescriptorInfo {
+	kind := mxfDescriptorTypes[set.setType()]
+	descriptor := EssenceDescriptorInfo{
+		DescriptorType:   kind.name,
+		InstanceUID:      mxfUUIDString(set.uid(mxfTagInstanceUID)),
+		LinkedTrackID:    int(set.uint(mxfTagLinkedTrackID)),
+		SampleRate:       set.rational(mxfTagSampleRate),
+		EssenceContainer: set.uid(mxfTagEssenceContainer).String(),
+	}
+	if _, ok := set.props[mxfTagContainerDuration]; ok {
+		descriptor.ContainerDuration = fmt.Sprintf("%d", set.uint(mxfTagContainerDuration))
+	}
+
+	switch kind.essenceType {
+	case "Picture":
+		stored := mxfDimensions(set, mxfTagStoredWidth, mxfTagStoredHeight)
+		picture := &PictureEssenceInfo{
+			PictureCompression:    mxfEssenceCodingName(set.uid(mxfTagPictureCoding)),
+			StoredDimensions:      stored,
+			SampledDimensions:     mxfDimensions(set, mxfTagSampledWidth, mxfTagSampledHeight),
+			DisplayDimensions:     mxfDimensions(set, mxfTagDisplayWidth, mxfTagDisplayHeight),
+			AspectRatio:           set.rational(mxfTagAspectRatio),
+			ComponentDepth:        int(set.uint(mxfTagComponentDepth)),
+			HorizontalSubsampling: int(set.uint(mxfTagHorizontalSubsampling)),
+			VerticalSubsampling:   int(set.uint(mxfTagVerticalSubsampling)),
+			Issues:                []string{},
+		}
+		if picture.PictureCompression == "" {
+			picture.PictureCompression = "Uncompressed Picture"
+		}
+		if picture.SampledDimensions == "" {
+			picture.SampledDimensions = stored
+		}
+		if picture.DisplayDimensions == "" {
+			picture.DisplayDimensions = picture.SampledDimensions
+		}
+		if layout, ok := set.props[mxfTagFrameLayout]; ok && len(layout) == 1 && int(layout[0]) < len(mxfFrameLayouts) {
+			picture.FrameLayout = mxfFrameLayouts[layout[0]]
+		}
+		if count, size, items := mxfBatch(set.props[mxfTagVideoLineMap]); size == 4 {
+			for i := 0; i < count; i++ {
+				picture.VideoLineMap = append(picture.VideoLineMap, int(int32(uint32(items[i*4])<<24|uint32(items[i*4+1])<<16|uint32(items[i*4+2])<<8|uint32(items[i*4+3]))))
+			}
+		}
+		if stored == "" {
+			picture.Issues = append(picture.Issues, "Picture descriptor has no stored dimensions")
+		}
+		descriptor.PictureEssence = picture
+	case "Sound":
+		sound := &SoundEssenceInfo{
+			AudioSamplingRate:       set.rational(mxfTagAudioSamplingRate),
+			Locked:                  set.uint(mxfTagLocked) != 0,
+			AudioRefLevel:           set.int(mxfTagAudioRefLevel),
+			ChannelCount:            int(set.uint(mxfTagChannelCount)),
+			QuantizationBits:        int(set.uint(mxfTagQuantizationBits)),
+			DialNorm:                set.int(mxfTagDialNorm),
+			SoundEssenceCompression: mxfEssenceCodingName(set.uid(mxfTagSoundCoding)),
+			Issues:                  []string{},
+		}
+		sound.ElectroSpatialForm = mxf.getElectroSpatialForm(sound.ChannelCount)
+		if sound.SoundEssenceCompression == "" {
+			// Wave and AES3 descriptors may leave out the coding of PCM
+			sound.SoundEssenceCompression = "PCM"
+		}
+		if sound.ChannelCount == 0 {
+			sound.Issues = append(sound.Issues, "Sound descriptor has no channel count")
+		}
+		descriptor.SoundEssence = sound
+	case "Data":
+		descriptor.DataEssence = &DataEssenceInfo{
+			DataEssenceCompression: mxfEssenceCodingName(set.uid(mxfTagDataCoding)),
+			DataDefinition:         kind.name,
+			DataFormat:             mxfEssenceContainerName(set.uid(mxfTagEssenceContainer)),
+			Issues:                 []string{},
+		}
+	}
+	return descriptor
+}
+
+// headerMetadataFromStructure reads the packages, identifications and
+// timecode of the header metadata
+func (mxf *MXFAnalyzer) headerMetadataFromStructure(file *mxfFile, metadata *mxfMetadata) *HeaderMetadata {
+	header := &HeaderMetadata{
+		SourcePackages:     []SourcePackage{},
+		IdentificationSets: []IdentificationSet{},
+	}
+	if file.metadataPartition >= 0 {
+		header.HeaderByteCount = file.partitions[file.metadataPartition].HeaderByteCount
+	}
+
+	preface, hasPreface := metadata.preface()
+	if hasPreface {
+		if version := preface.uint(mxfTagPrefaceVersion); version != 0 {
+			header.MetadataVersion = fmt.Sprintf("%d.%d", version>>8, version&0xFF)
+		}
+	}
+
+	for _, set := range metadata.byType[mxfSetIdentification] {
+		header.IdentificationSets = append(header.IdentificationSets, IdentificationSet{
+			InstanceUID:      mxfUUIDString(set.uid(mxfTagInstanceUID)),
+			GenerationUID:    mxfUUIDString(set.uid(mxfTagGenerationUID)),
+			CompanyName:      set.string(mxfTagCompanyName),
+			ProductName:      set.string(mxfTagProductName),
+			ProductVersion:   mxfProductVersion(set.props[mxfTagProductVersion]),
+			VersionString:    set.string(mxfTagVersionString),
+			ProductUID:       mxfUUIDString(set.uid(mxfTagProductUID)),
+			ModificationDate: set.timestamp(mxfTagModificationDate),
+			Platform:         set.string(mxfTagPlatform),
+		})
+	}
+
+	// File packages are the source packages essence container data links to
+	linked := make(map[string]bool)
+	for i, set := range metadata.byType[mxfSetEssenceContainerData] {
+		data := EssenceContainerData{
+			InstanceUID:      mxfUUIDString(set.uid(mxfTagInstanceUID)),
+			LinkedPackageUID: set.umid(mxfTagLinkedPackageUID),
+			BodySID:          int(set.uint(mxfTagBodySID)),
+			IndexSID:         int(set.uint(mxfTagIndexSID)),
+		}
+		linked[data.LinkedPackageUID] = true
+		if i == 0 {
+			header.EssenceContainerData = &data
+		}
+	}
+
+	var brokenRefs int
+	if packages := metadata.byType[mxfSetMaterialPackage]; len(packages) > 0 {
+		set := packages[0]
+		tracks := set.refs(mxfTagPackageTracks)
+		duration, editRate, missing := mxf.packageDuration(metadata, tracks)
+		brokenRefs += missing
+		material := &MaterialPackage{
+			PackageUID:   set.umid(mxfTagPackageUID),
+			Name:         set.string(mxfTagPackageName),
+			CreationDate: set.timestamp(mxfTagPackageCreated),
+			ModifiedDate: set.timestamp(mxfTagPackageModified),
+			TrackCount:   len(tracks),
+		}
+		if editRate != "" {
+			material.Duration = fmt.Sprintf("%d edit units at %s", duration, editRate)
+		}
+		header.MaterialPackage = material
+	}
+
+	for _, set := range metadata.byType[mxfSetSourcePackage] {
+		tracks := set.refs(mxfTagPackageTracks)
+		_, _, missing := mxf.packageDuration(metadata, tracks)
+		brokenRefs += missing
+		source := SourcePackage{
+			PackageUID:  set.umid(mxfTagPackageUID),
+			Name:        set.string(mxfTagPackageName),
+			PackageType: "Physical",
+			TrackCount:  len(tracks),
+		}
+		if linked[source.PackageUID] {
+			source.PackageType = "File"
+		}
+		if descriptor, ok := metadata.byUID[set.uid(mxfTagPackageDescriptor)]; ok {
+			if descriptor.setType() == mxfSetMultipleDescriptor {
+				source.Descriptor = "MultipleDescriptor"
+			} else {
+				source.Descriptor = mxfDescriptorTypes[descriptor.setType()].name
+			}
+		} else if _, ok := set.props[mxfTagPackageDescriptor]; ok {
+			brokenRefs++
+		}
+		header.SourcePackages = append(header.SourcePackages, source)
+	}
+
+	if components := metadata.byType[mxfSetTimecodeComponent]; len(components) > 0 {
+		set := components[0]
+		timecode := &TimecodeComponent{
+			RoundedTimecodeBase: int(set.uint(mxfTagTimecodeBase)),
+			DropFrame:           set.uint(mxfTagTimecodeDropFrame) != 0,
+			Duration:            int64(set.uint(mxfTagComponentDuration)),
+		}
+		timecode.StartTimecode = FormatTimecode(int64(set.uint(mxfTagTimecodeStart)), timecode.RoundedTimecodeBase, timecode.DropFrame)
+		header.Timecode = timecode
+	}
+
+	header.ObjectDirectory = &ObjectDirectory{
+		ObjectCount:    len(metadata.sets),
+		PackageCount:   len(metadata.byType[mxfSetMaterialPackage]) + len(metadata.byType[mxfSetSourcePackage]),
+		TrackCount:     len(metadata.byType[mxfSetTimelineTrack]) + len(metadata.byType[mxfSetEventTrack]) + len(metadata.byType[mxfSetStaticTrack]),
+		SequenceCount:  len(metadata.byType[mxfSetSequence]),
+		ComponentCount: len(metadata.byType[mxfSetSourceClip]) + len(metadata.byType[mxfSetTimecodeComponent]) + len(metadata.byType[mxfSetDMSegment]),
+	}
+
+	validation := mxf.validateHeaderMetadata(header)
+	if !hasPreface {
+		validation.MetadataValid = false
+		validation.Issues = append(validation.Issues, "Header metadata has no Preface set")
+	}
+	if header.MaterialPackage == nil {
+		validation.MetadataValid = false
+		validation.Issues = append(validation.Issues, "Header metadata has no Material Package")
+	}
+	if brokenRefs > 0 {
+		validation.ReferencesValid = false
+		validation.Issues = append(validation.Issues, fmt.Sprintf("%d strong references point to missing sets", brokenRefs))
+	}
+	if file.metadataPartition >= 0 {
+		if partition := file.partitions[file.metadataPartition]; !partition.closed || !partition.complete {
+			validation.HeaderComplete = false
+			validation.Issues = append(validation.Issues, fmt.Sprintf("Best header metadata is in a %s %s partition", partition.Status, partition.PartitionType))
+		}
+	}
+	header.ValidationResults = validation
+	return header
+}
+
+// packageDuration returns the longest timeline track of a package with its
+// edit rate, and the number of track references that could not be resolved
+func (mxf *MXFAnalyzer) packageDuration(metadata *mxfMetadata, tracks []mxfUL) (int64, string, int) {
+	var duration int64
+	var editRate string
+	missing := 0
+	for _, ref := range tracks {
+		track, ok := metadata.byUID[ref]
+		if !ok {
+			missing++
+			continue
+		}
+		if track.setType() != mxfSetTimelineTrack {
+			continue
+		}
+		sequence, ok := metadata.byUID[track.uid(mxfTagTrackSequence)]
+		if !ok {
+			missing++
+			continue
+		}
+		if length := int64(sequence.uint(mxfTagComponentDuration)); length > duration || editRate == "" {
+			duration = length
+			editRate = track.rational(mxfTagEditRate)
+		}
+	}
+	return duration, editRate, missing
+}
+
+// indexTablesFromStructure checks that the index table segments of each
+// IndexSID cover the essence without gaps or overlaps
+func (mxf *MXFAnalyzer) indexTablesFromStructure(file *mxfFile, metadata *mxfMetadata) *IndexTableAnalysis {
+	index := &IndexTableAnalysis{
+		DeltaEntryArray: []DeltaEntry{},
+		IndexEntryArray: []IndexEntry{},
+	}
+	validation := &IndexValidation{
+		IndexComplete:     true,
+		IndexConsistent:   true,
+		RandomAccessValid: true,
+		Issues:            []string{},
+	}
+	index.ValidationResults = validation
+
+	for _, set := range metadata.descriptors() {
+		index.ExpectedDuration = max(index.ExpectedDuration, int64(set.uint(mxfTagContainerDuration)))
+	}
+
+	// Index tables are often repeated in the footer, count each segment once
+	type segmentKey struct {
+		indexSID          uint32
+		start, duration   int64
+		editUnitByteCount uint32
+	}
+	seen := make(map[segmentKey]bool)
+	bySID := make(map[uint32][]mxfIndexSegment)
+	var sids []uint32
+	for _, segment := range file.indexSegments {
+		key := segmentKey{segment.indexSID, segment.startPosition, segment.duration, segment.editUnitByteCount}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if _, ok := bySID[segment.indexSID]; !ok {
+			sids = append(sids, segment.indexSID)
+		}
+		bySID[segment.indexSID] = append(bySID[segment.indexSID], segment)
+		index.SegmentCount++
+	}
+
+	if index.SegmentCount == 0 {
+		validation.IndexComplete = false
+		validation.RandomAccessValid = false
+		validation.Issues = append(validation.Issues, "No index tables, players must scan the essence to seek")
+		return index
+	}
+	index.HasIndexTables = true
+	index.IndexTableCount = len(sids)
+
+	first := bySID[sids[0]][0]
+	index.IndexEditRate = first.editRate
+	index.EditUnitByteCount = int(first.editUnitByteCount)
+	index.SliceCount = first.sliceCount
+	if first.deltaEntries != nil {
+		index.DeltaEntryArray = first.deltaEntries
+	}
+	index.IndexStartPosition = first.startPosition
+
+	for _, sid := range sids {
+		segments := bySID[sid]
+		sort.Slice(segments, func(i, j int) bool { return segments[i].startPosition < segments[j].startPosition })
+
+		var covered int64
+		whole, randomAccess := false, false
+		next := segments[0].startPosition
+		if next < index.IndexStartPosition {
+			index.IndexStartPosition = next
+		}
+		for _, segment := range segments {
+			if segment.editUnitByteCount > 0 {
+				// Constant bytes per edit unit; a zero duration covers the whole essence
+				randomAccess = true
+				whole = whole || segment.duration == 0
+			} else {
+				randomAccess = randomAccess || segment.randomAccess > 0
+				if segment.entryCount != int(segment.duration) {
+					validation.IndexConsistent = false
+					validation.Issues = append(validation.Issues, fmt.Sprintf("Index segment at edit unit %d of IndexSID %d has %d entries for %d edit units", segment.startPosition, sid, segment.entryCount, segment.duration))
+				}
+			}
+			switch {
+			case segment.startPosition > next:
+				validation.IndexComplete = false
+				validation.Issues = append(validation.Issues, fmt.Sprintf("IndexSID %d has no index for edit units %d to %d", sid, next, segment.startPosition-1))
+			case segment.startPosition < next:
+				validation.IndexConsistent = false
+				validation.Issues = append(validation.Issues, fmt.Sprintf("IndexSID %d has overlapping segments at edit unit %d", sid, segment.startPosition))
+			}
+			covered += segment.duration
+			next = max(next, segment.startPosition+segment.duration)
+		}
+		index.IndexDuration = max(index.IndexDuration, covered)
+
+		if !whole && index.ExpectedDuration > 0 && next < index.ExpectedDuration {
+			validation.IndexComplete = false
+			validation.Issues = append(validation.Issues, fmt.Sprintf("IndexSID %d indexes %d of %d edit units", sid, next, index.ExpectedDuration))
+		}
+		if !randomAccess {
+			validation.RandomAccessValid = false
+			validation.Issues = append(validation.Issues, fmt.Sprintf("IndexSID %d has no random access points", sid))
+		}
+	}
+	return index
+}
+
+// partitionStructureFromStructure checks the partitions against each other:
+// the back links, the footer links and the state of the header and footer
+func (mxf *MXFAnalyzer) partitionStructureFromStructure(file *mxfFile) *PartitionStructure {
+	structure := &PartitionStructure{
+		PartitionCount: len(file.partitions),
+		Partitions:     []PartitionInfo{},
+		Issues:         []string{},
+	}
+	consistency := &PartitionConsistency{
+		PartitionChainValid: true,
+		PartitionBalance:    true,
+		Issues:              []string{},
+	}
+	structure.PartitionConsistency = consistency
+
+	var footer *mxfPartition
+	var bodySizes []int64
+	for i := range file.partitions {
+		partition := &file.partitions[i]
+		structure.Partitions = append(structure.Partitions, partition.PartitionInfo)
+		switch partition.PartitionType {
+		case "Header":
+			structure.HasHeaderPartition = true
+		case "Body":
+			structure.HasBodyPartitions = true
+			if i+1 < len(file.partitions) {
+				bodySizes = append(bodySizes, file.partitions[i+1].offset-partition.offset)
+			}
+		case "Footer":
+			structure.HasFooterPartition = true
+			footer = partition
+		}
+		if partition.IndexByteCount > 0 {
+			consistency.RandomAccessPoints++
+		}
+
+		if partition.ThisPartition != partition.offset-file.runIn {
+			consistency.PartitionChainValid = false
+			consistency.Issues = append(consistency.Issues, fmt.Sprintf("%s partition at byte %d records its position as %d", partition.PartitionType, partition.offset-file.runIn, partition.ThisPartition))
+		}
+		if i > 0 && partition.PreviousPartition != file.partitions[i-1].ThisPartition {
+			consistency.PartitionChainValid = false
+			consistency.Issues = append(consistency.Issues, fmt.Sprintf("%s partition at byte %d links back to %d instead of %d", partition.PartitionType, partition.ThisPartition, partition.PreviousPartition, file.partitions[i-1].ThisPartition))
+		}
+	}
+
+	if footer != nil {
+		for _, partition := range file.partitions {
+			if partition.closed && partition.FooterPartition != 0 && partition.FooterPartition != footer.ThisPartition {
+				consistency.PartitionChainValid = false
+				consistency.Issues = append(consistency.Issues, fmt.Sprintf("%s partition at byte %d points to a footer at %d, the footer is at %d", partition.PartitionType, partition.ThisPartition, partition.FooterPartition, footer.ThisPartition))
+			}
+		}
+	}
+
+	if len(bodySizes) > 1 {
+		smallest, largest := bodySizes[0], bodySizes[0]
+		for _, size := range bodySizes {
+			if size < smallest {
+				smallest = size
+			}
+			largest = max(largest, size)
+		}
+		consistency.PartitionBalance = largest <= 2*smallest
+	}
+
+	if len(file.partitions) > 0 {
+		header := file.partitions[0]
+		if !header.closed {
+			structure.Issues = append(structure.Issues, "Header partition is open, its metadata may not be final")
+		}
+		if !header.complete {
+			structure.Issues = append(structure.Issues, "Header partition metadata is incomplete")
+		}
+	}
+	switch {
+	case footer == nil:
+		structure.Issues = append(structure.Issues, "No footer partition, the file may be truncated or still being written")
+	case !footer.closed || !footer.complete:
+		structure.Issues = append(structure.Issues, fmt.Sprintf("Footer partition is %s", footer.Status))
+	}
+	if !consistency.PartitionChainValid {
+		structure.Issues = append(structure.Issues, "Partition links are inconsistent")
+	}
+	if !file.hasRIP {
+		consistency.Issues = append(consistency.Issues, "No random index pack, readers must follow partition links to find partitions")
+	}
+	consistency.Issues = append(consistency.Issues, file.issues...)
+	return structure
+}
+
+// detectShims looks for the AMWA AS-11 descriptive metadata schemes and the
+// AS-02 bundle layout, then checks the structural constraints of each
+func (mxf *MXFAnalyzer) detectShims(filePath string, file *mxfFile, metadata *mxfMetadata, analysis *MXFAnalysis) *MXFShimDetection {
+	shims := &MXFShimDetection{}
+
+	schemes := make(map[string]bool)
+	if preface, ok := metadata.preface(); ok {
+		for _, label := range preface.refs(mxfTagDMSchemes) {
+			if name := mxfAS11SchemeName(label); name != "" {
+				schemes[name] = true
+			}
+		}
+	}
+	for _, set := range metadata.sets {
+		// Descriptive metadata sets are keyed under their scheme
+		if name := mxfAS11SchemeName(set.key); name != "" && set.key[4] == 0x02 {
+			schemes[name] = true
+		}
+	}
+	for _, name := range []string{"core", "segmentation", "uk_dpp", "other"} {
+		if schemes[name] {
+			shims.AS11Schemes = append(shims.AS11Schemes, name)
+		}
+	}
+	shims.AS11 = len(shims.AS11Schemes) > 0
+
+	op := analysis.OperationalPattern
+	if shims.AS11 {
+		if !schemes["core"] {
+			shims.AS11Issues = append(shims.AS11Issues, "AS-11: core descriptive metadata framework is missing")
+		}
+		if op == nil || op.PatternLabel != "OP1a" {
+			shims.AS11Issues = append(shims.AS11Issues, "AS-11: operational pattern must be OP1a")
+		}
+		if len(file.partitions) == 0 || !file.partitions[0].closed || !file.partitions[0].complete {
+			shims.AS11Issues = append(shims.AS11Issues, "AS-11: header partition must be closed and complete")
+		}
+		if analysis.PartitionStructure == nil || !analysis.PartitionStructure.HasFooterPartition {
+			shims.AS11Issues = append(shims.AS11Issues, "AS-11: footer partition is required")
+		}
+		if !file.hasRIP {
+			shims.AS11Issues = append(shims.AS11Issues, "AS-11: random index pack is required")
+		}
+		if analysis.IndexTables == nil || !analysis.IndexTables.HasIndexTables {
+			shims.AS11Issues = append(shims.AS11Issues, "AS-11: index tables are required")
+		}
+		for _, container := range analysis.EssenceContainers {
+			if container.WrappingType != "Frame" {
+				shims.AS11Issues = append(shims.AS11Issues, fmt.Sprintf("AS-11: %s essence must be frame wrapped", container.ContainerName))
+			}
+		}
+	}
+
+	if findAS02Manifest(filePath) != "" {
+		shims.AS02 = true
+		shims.AS02Role = "essence_component"
+		if op != nil && op.InternalEssence != nil && !*op.InternalEssence {
+			shims.AS02Role = "version_file"
+		}
+		if shims.AS02Role == "essence_component" {
+			if op == nil || op.PatternLabel != "OP1a" {
+				shims.AS02Issues = append(shims.AS02Issues, "AS-02: essence components must be OP1a")
+			}
+			if tracks := len(metadata.descriptors()); tracks != 1 {
+				shims.AS02Issues = append(shims.AS02Issues, fmt.Sprintf("AS-02: essence components carry one essence track, found %d", tracks))
+			}
+			if analysis.IndexTables == nil || !analysis.IndexTables.HasIndexTables {
+				shims.AS02Issues = append(shims.AS02Issues, "AS-02: essence components must be indexed")
+			}
+		}
+	}
+	return shims
+}
+
+// mxfDimensions formats a width and height property pair
+func mxfDimensions(set mxfLocalSet, widthTag, heightTag uint16) string {
+	width, height := set.uint(widthTag), set.uint(heightTag)
+	if width == 0 || height == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%dx%d", width, height)
+}
+
+// mxfUUIDString formats an instance or generation UID
+func mxfUUIDString(uid mxfUL) string {
+	if uid.isZero() {
+		return ""
+	}
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", uid[0:4], uid[4:6], uid[6:8], uid[8:10], uid[10:16])
+}
+
+// mxfProductVersion formats the major, minor, patch, build and release
+// numbers of an identification's product version
+func mxfProductVersion(data []byte) string {
+	if len(data) != 10 {
+		return ""
+	}
+	version := make([]int, 5)
+	for i := range version {
+		version[i] = int(data[2*i])<<8 | int(data[2*i+1])
+	}
+	return fmt.Sprintf("%d.%d.%d.%d", version[0], version[1], version[2], version[3])
+}
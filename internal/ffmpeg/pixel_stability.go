@@ -0,0 +1,285 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"math"
+)
+
+// DeadPixelThresholds configures dead, stuck and hot pixel detection. Levels
+// are 8-bit luma values (0-255, full range).
+type DeadPixelThresholds struct {
+	SampleFrames     int     // Frames sampled evenly across the timeline (3-255)
+	DarkLevel        uint8   // A dead pixel stays at or below this level
+	HotLevel         uint8   // A hot pixel stays at or above this level
+	NeighborContrast uint8   // Minimum luma difference from every surrounding pixel
+	StuckTolerance   uint8   // Maximum luma variation of a stuck pixel across samples
+	NeighborActivity uint8   // Minimum variation of the surroundings; rules out static content
+	MinPersistence   float64 // Fraction of sampled frames a dead/hot pixel keeps its level (0-1)
+	MinIsolation     float64 // Fraction of sampled frames a defect stands out from its surroundings (0-1)
+	MaxReported      int     // Defects listed per type; counts are always exact
+}
+
+// DefaultDeadPixelThresholds returns thresholds suited to typical camera and
+// mastering defects
+func DefaultDeadPixelThresholds() DeadPixelThresholds {
+	return DeadPixelThresholds{
+		SampleFrames:     30,
+		DarkLevel:        24,
+		HotLevel:         232,
+		NeighborContrast: 48,
+		StuckTolerance:   6,
+		NeighborActivity: 24,
+		MinPersistence:   0.9,
+		MinIsolation:     0.5,
+		MaxReported:      100,
+	}
+}
+
+// Validate checks that thresholds are usable
+func (t DeadPixelThresholds) Validate() error {
+	if t.SampleFrames < 3 || t.SampleFrames > math.MaxUint8 {
+		return fmt.Errorf("sample frames must be between 3 and %d", math.MaxUint8)
+	}
+	if t.MinPersistence <= 0 || t.MinPersistence > 1 {
+		return fmt.Errorf("min persistence must be in (0, 1]")
+	}
+	if t.MinIsolation <= 0 || t.MinIsolation > 1 {
+		return fmt.Errorf("min isolation must be in (0, 1]")
+	}
+	if t.DarkLevel >= t.HotLevel {
+		return fmt.Errorf("dark level must be below hot level")
+	}
+	if t.MaxReported < 0 {
+		return fmt.Errorf("max reported must not be negative")
+	}
+	return nil
+}
+
+// pixelNeighborOffset is the distance to the pixels a pixel is compared
+// against. Two pixels keeps compression ringing around a defect out of its
+// own reference neighborhood.
+const pixelNeighborOffset = 2
+
+// pixelStabilityMap accumulates per-pixel behaviour over sampled frames. Each
+// pixel is compared with the eight pixels pixelNeighborOffset away and is
+// isolated in a frame when it is darker or brighter than all of them; a pixel
+// that keeps being isolated while its surroundings change is a defect, while
+// the edges of static graphics are not, since some of their neighbors share
+// their level. Counters are uint8, which bounds the number of frames to 255.
+type pixelStabilityMap struct {
+	width, height int
+	frames        int
+
+	minLuma, maxLuma         []uint8 // Pixel range across frames
+	minNeighbor, maxNeighbor []uint8 // Neighborhood mean range across frames
+	dark, bright             []uint8 // Frames at or beyond DarkLevel/HotLevel
+	isolated                 []uint8 // Frames the pixel stood out from all neighbors
+	first, last              []uint8 // First and last isolated frame
+	color                    []uint8 // RGB of the latest frame, for stuck pixels
+	luma                     []uint8 // Scratch luma plane
+}
+
+func newPixelStabilityMap(width, height int) *pixelStabilityMap {
+	n := width * height
+	m := &pixelStabilityMap{
+		width:       width,
+		height:      height,
+		minLuma:     make([]uint8, n),
+		maxLuma:     make([]uint8, n),
+		minNeighbor: make([]uint8, n),
+		maxNeighbor: make([]uint8, n),
+		dark:        make([]uint8, n),
+		bright:      make([]uint8, n),
+		isolated:    make([]uint8, n),
+		first:       make([]uint8, n),
+		last:        make([]uint8, n),
+		luma:        make([]uint8, n),
+	}
+	for i := range m.minLuma {
+		m.minLuma[i] = math.MaxUint8
+		m.minNeighbor[i] = math.MaxUint8
+	}
+	return m
+}
+
+// addFrame accumulates one rgb24 frame
+func (m *pixelStabilityMap) addFrame(rgb []byte, t DeadPixelThresholds) error {
+	n := m.width * m.height
+	if len(rgb) != n*3 {
+		return fmt.Errorf("frame is %d bytes, expected %d", len(rgb), n*3)
+	}
+	if m.frames >= math.MaxUint8 {
+		return fmt.Errorf("too many frames for pixel stability map")
+	}
+
+	// BT.601 luma in 8.8 fixed point
+	for i := 0; i < n; i++ {
+		r, g, b := uint32(rgb[i*3]), uint32(rgb[i*3+1]), uint32(rgb[i*3+2])
+		m.luma[i] = uint8((77*r + 150*g + 29*b) >> 8)
+	}
+
+	frame := uint8(m.frames)
+	d := pixelNeighborOffset
+	w := m.width
+
+	for y := d; y < m.height-d; y++ {
+		for x := d; x < w-d; x++ {
+			i := y*w + x
+			v := m.luma[i]
+
+			neighbors := [8]uint8{
+				m.luma[i-d*w-d], m.luma[i-d*w], m.luma[i-d*w+d],
+				m.luma[i-d], m.luma[i+d],
+				m.luma[i+d*w-d], m.luma[i+d*w], m.luma[i+d*w+d],
+			}
+			lo, hi, sum := neighbors[0], neighbors[0], 0
+			for _, n := range neighbors {
+				if n < lo {
+					lo = n
+				}
+				if n > hi {
+					hi = n
+				}
+				sum += int(n)
+			}
+			mean := uint8(sum / 8)
+
+			if v < m.minLuma[i] {
+				m.minLuma[i] = v
+			}
+			if v > m.maxLuma[i] {
+				m.maxLuma[i] = v
+			}
+			if mean < m.minNeighbor[i] {
+				m.minNeighbor[i] = mean
+			}
+			if mean > m.maxNeighbor[i] {
+				m.maxNeighbor[i] = mean
+			}
+
+			if v <= t.DarkLevel {
+				m.dark[i]++
+			}
+			if v >= t.HotLevel {
+				m.bright[i]++
+			}
+
+			if int(v)+int(t.NeighborContrast) <= int(lo) || int(v)-int(t.NeighborContrast) >= int(hi) {
+				m.isolated[i]++
+				if m.isolated[i] == 1 {
+					m.first[i] = frame
+				}
+				m.last[i] = frame
+			}
+		}
+	}
+
+	m.color = rgb
+	m.frames++
+	return nil
+}
+
+// pixelDefectSummary lists the defects found in a stability map
+type pixelDefectSummary struct {
+	dead, stuck, hot                []PixelDefect
+	deadCount, stuckCount, hotCount int
+	analyzedPixels                  int // Pixels away from the frame border
+	staticPixels                    int // Pixels whose surroundings never changed enough to judge
+}
+
+// defects classifies every pixel that is isolated in at least MinIsolation
+// of the frames. Such a pixel is:
+//   - dead when it stays at or below DarkLevel in MinPersistence of the frames
+//   - hot when it stays at or above HotLevel in MinPersistence of the frames
+//   - stuck when its value varies by no more than StuckTolerance, at any level
+//
+// Pixels whose surroundings barely change are skipped, since a static image
+// cannot distinguish a defect from picture detail.
+func (m *pixelStabilityMap) defects(t DeadPixelThresholds) pixelDefectSummary {
+	var summary pixelDefectSummary
+	if m.frames == 0 {
+		return summary
+	}
+
+	persistent := uint8(math.Ceil(t.MinPersistence * float64(m.frames)))
+	isolated := uint8(math.Ceil(t.MinIsolation * float64(m.frames)))
+	d := pixelNeighborOffset
+
+	for y := d; y < m.height-d; y++ {
+		for x := d; x < m.width-d; x++ {
+			i := y*m.width + x
+			summary.analyzedPixels++
+			if m.maxNeighbor[i]-m.minNeighbor[i] < t.NeighborActivity {
+				summary.staticPixels++
+				continue
+			}
+			if m.isolated[i] < isolated {
+				continue
+			}
+
+			var defectType string
+			var count uint8
+			switch {
+			case m.dark[i] >= persistent:
+				defectType, count = "dead", m.dark[i]
+				summary.deadCount++
+			case m.bright[i] >= persistent:
+				defectType, count = "hot", m.bright[i]
+				summary.hotCount++
+			case m.maxLuma[i]-m.minLuma[i] <= t.StuckTolerance:
+				defectType, count = "stuck", m.isolated[i]
+				summary.stuckCount++
+			default:
+				continue
+			}
+
+			reported := &summary.stuck
+			switch defectType {
+			case "dead":
+				reported = &summary.dead
+			case "hot":
+				reported = &summary.hot
+			}
+			if len(*reported) < t.MaxReported {
+				*reported = append(*reported, m.defectAt(x, y, defectType, count))
+			}
+		}
+	}
+
+	return summary
+}
+
+func (m *pixelStabilityMap) defectAt(x, y int, defectType string, count uint8) PixelDefect {
+	i := y*m.width + x
+	lumaRange := float64(m.maxLuma[i] - m.minLuma[i])
+	consistency := float64(count) / float64(m.frames)
+
+	behavior := &TemporalBehavior{
+		Persistence:        "permanent",
+		VariationPattern:   "constant",
+		IntensityVariation: lumaRange / 255,
+		FrameConsistency:   consistency,
+	}
+	if int(count) < m.frames {
+		behavior.Persistence = "intermittent"
+	}
+	if lumaRange > float64(m.maxNeighbor[i]-m.minNeighbor[i])/2 {
+		behavior.VariationPattern = "flickering"
+	}
+
+	defect := PixelDefect{
+		X:                  x,
+		Y:                  y,
+		DefectType:         defectType,
+		Intensity:          (float64(m.minLuma[i]) + float64(m.maxLuma[i])) / 2 / 255,
+		FirstDetectedFrame: int(m.first[i]),
+		LastDetectedFrame:  int(m.last[i]),
+		FrameCount:         int(count),
+		Confidence:         consistency,
+		TemporalBehavior:   behavior,
+	}
+	if m.color != nil {
+		defect.Color = fmt.Sprintf("#%02x%02x%02x", m.color[i*3], m.color[i*3+1], m.color[i*3+2])
+	}
+	return defect
+}
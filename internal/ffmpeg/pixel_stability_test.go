@@ -0,0 +1,122 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// syntheticFrame returns a uniform gray rgb24 frame with the given pixels overridden
+func syntheticFrame(width, height int, gray uint8, pixels map[[2]int][3]uint8) []byte {
+	frame := make([]byte, width*height*3)
+	for i := range frame {
+		frame[i] = gray
+	}
+	for xy, rgb := range pixels {
+		i := (xy[1]*width + xy[0]) * 3
+		copy(frame[i:i+3], rgb[:])
+	}
+	return frame
+}
+
+func TestPixelStabilityMap_ClassifiesDefects(t *testing.T) {
+	const width, height = 32, 24
+	thresholds := DefaultDeadPixelThresholds()
+
+	defects := map[[2]int][3]uint8{
+		{5, 5}:   {0, 0, 0},       // dead
+		{20, 10}: {255, 255, 255}, // hot
+		{10, 15}: {0, 0, 255},     // stuck blue
+	}
+	// A static black block stands in for a logo; its edges must not be flagged
+	for y := 16; y < 22; y++ {
+		for x := 22; x < 28; x++ {
+			defects[[2]int{x, y}] = [3]uint8{0, 0, 0}
+		}
+	}
+
+	stability := newPixelStabilityMap(width, height)
+	for i := 0; i < 8; i++ {
+		gray := []uint8{60, 120, 200, 240}[i%4]
+		if err := stability.addFrame(syntheticFrame(width, height, gray, defects), thresholds); err != nil {
+			t.Fatalf("addFrame failed: %v", err)
+		}
+	}
+
+	summary := stability.defects(thresholds)
+	if summary.deadCount != 1 || summary.hotCount != 1 || summary.stuckCount != 1 {
+		t.Fatalf("expected 1 dead, 1 hot and 1 stuck pixel, got %d/%d/%d (dead %+v)",
+			summary.deadCount, summary.hotCount, summary.stuckCount, summary.dead)
+	}
+
+	if dead := summary.dead[0]; dead.X != 5 || dead.Y != 5 || dead.FrameCount != 8 {
+		t.Errorf("unexpected dead pixel: %+v", dead)
+	}
+	if hot := summary.hot[0]; hot.X != 20 || hot.Y != 10 {
+		t.Errorf("unexpected hot pixel: %+v", hot)
+	}
+	stuck := summary.stuck[0]
+	if stuck.X != 10 || stuck.Y != 15 || stuck.Color != "#0000ff" {
+		t.Errorf("unexpected stuck pixel: %+v", stuck)
+	}
+	if stuck.TemporalBehavior == nil || stuck.TemporalBehavior.Persistence != "intermittent" {
+		t.Errorf("expected intermittent stuck pixel, got %+v", stuck.TemporalBehavior)
+	}
+}
+
+func TestPixelStabilityMap_IgnoresStaticContent(t *testing.T) {
+	const width, height = 16, 16
+	thresholds := DefaultDeadPixelThresholds()
+	detail := map[[2]int][3]uint8{{8, 8}: {0, 0, 0}}
+
+	stability := newPixelStabilityMap(width, height)
+	for i := 0; i < 5; i++ {
+		if err := stability.addFrame(syntheticFrame(width, height, 128, detail), thresholds); err != nil {
+			t.Fatalf("addFrame failed: %v", err)
+		}
+	}
+
+	summary := stability.defects(thresholds)
+	if summary.deadCount != 0 {
+		t.Errorf("expected no defects in static content, got %d", summary.deadCount)
+	}
+	if summary.staticPixels != summary.analyzedPixels {
+		t.Errorf("expected every pixel to be static, got %d of %d", summary.staticPixels, summary.analyzedPixels)
+	}
+}
+
+func TestDeadPixelThresholds_Validate(t *testing.T) {
+	if err := DefaultDeadPixelThresholds().Validate(); err != nil {
+		t.Fatalf("default thresholds should be valid: %v", err)
+	}
+
+	invalid := DefaultDeadPixelThresholds()
+	invalid.SampleFrames = 300
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected error for too many sample frames")
+	}
+
+	invalid = DefaultDeadPixelThresholds()
+	invalid.DarkLevel, invalid.HotLevel = 200, 100
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected error for dark level above hot level")
+	}
+}
+
+func TestFindDefectClusters(t *testing.T) {
+	dpa := NewDeadPixelAnalyzer("", zerolog.Nop())
+	defects := []PixelDefect{
+		{X: 10, Y: 10, DefectType: "dead"},
+		{X: 11, Y: 10, DefectType: "dead"},
+		{X: 12, Y: 12, DefectType: "stuck"},
+		{X: 100, Y: 50, DefectType: "hot"},
+	}
+
+	clusters, isolated := dpa.findDefectClusters(defects)
+	if len(clusters) != 1 || isolated != 1 {
+		t.Fatalf("expected 1 cluster and 1 isolated defect, got %d and %d", len(clusters), isolated)
+	}
+	if clusters[0].PixelCount != 3 || clusters[0].DominantDefectType != "dead" {
+		t.Errorf("unexpected cluster: %+v", clusters[0])
+	}
+}
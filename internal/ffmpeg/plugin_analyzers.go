@@ -0,0 +1,284 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// PluginProtocolVersion is the version of the JSON exchanged with
+// executable plugins. It grows when fields are added.
+const PluginProtocolVersion = 1
+
+// DefaultPluginTimeout bounds one run of an executable plugin
+const DefaultPluginTimeout = 5 * time.Minute
+
+// maxPluginOutput limits the JSON a plugin may write to stdout
+const maxPluginOutput = 16 * 1024 * 1024
+
+// Plugin statuses, graded like the QC categories of reports
+const (
+	PluginStatusPass        = "pass"
+	PluginStatusInfo        = "info"
+	PluginStatusWarning     = "warning"
+	PluginStatusFail        = "fail"
+	PluginStatusNotAnalyzed = "not_analyzed" // The plugin failed to run
+)
+
+// Check severities, as in the QC result
+var pluginCheckSeverities = []string{"critical", "major", "minor", "info"}
+
+// pluginNamePattern is what plugin names, and so category IDs, look like
+var pluginNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// PluginAnalyzer is a custom QC analyzer, such as slate OCR or watermark
+// detection, whose result is merged into the analysis and its QC result.
+// Implement it in Go, or use an executable through ExecPluginAnalyzer.
+type PluginAnalyzer interface {
+	// Name identifies the plugin in results; see ValidatePluginName
+	Name() string
+	// Analyze examines the media at input, a path or URL, after ffprobe
+	// and the built-in analyzers have run on it
+	Analyze(ctx context.Context, input string, result *FFprobeResult) (*PluginAnalysis, error)
+}
+
+// PluginAnalysis is the result of one plugin analyzer
+type PluginAnalysis struct {
+	Name     string          `json:"name"`
+	Title    string          `json:"title,omitempty"`   // Display name in reports, Name when empty
+	Status   string          `json:"status"`            // pass, info, warning or fail; not_analyzed when the plugin failed
+	Summary  string          `json:"summary,omitempty"` // One line describing the outcome
+	Fields   []PluginField   `json:"fields,omitempty"`  // Values shown in reports
+	Findings []string        `json:"findings,omitempty"`
+	Checks   []PluginCheck   `json:"checks,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"` // Plugin-specific output, passed through unchanged
+	Error    string          `json:"error,omitempty"`
+}
+
+// PluginField is one labelled value reported by a plugin
+type PluginField struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// PluginCheck is one rule a plugin evaluated. IDs are prefixed with the
+// plugin name, e.g. "slate_ocr.present".
+type PluginCheck struct {
+	ID           string              `json:"id"`
+	Status       string              `json:"status"`
+	Severity     string              `json:"severity,omitempty"` // critical, major, minor or info; major when empty
+	Stream       *int                `json:"stream,omitempty"`
+	Message      string              `json:"message,omitempty"`
+	Measurements []PluginMeasurement `json:"measurements,omitempty"`
+	Evidence     []PluginEvidence    `json:"evidence,omitempty"`
+}
+
+// PluginMeasurement is one value measured by a plugin
+type PluginMeasurement struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+	Unit  string `json:"unit,omitempty"`
+}
+
+// PluginEvidence locates an event found by a plugin in the media timeline
+type PluginEvidence struct {
+	StartSeconds float64 `json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds,omitempty"`
+	Description  string  `json:"description,omitempty"`
+}
+
+// ValidatePluginName checks that name is lower case letters, digits and
+// underscores and does not shadow a built-in QC category
+func ValidatePluginName(name string) error {
+	if !pluginNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid plugin name %q: use lower case letters, digits and underscores", name)
+	}
+	if _, err := NormalizeQCCategories([]string{name}); err == nil {
+		return fmt.Errorf("plugin name %q is a built-in QC category", name)
+	}
+	return nil
+}
+
+// normalize validates a plugin result and fills in its defaults
+func (a *PluginAnalysis) normalize(name string) error {
+	a.Name = name
+	if !validPluginStatus(a.Status) {
+		return fmt.Errorf("invalid status %q", a.Status)
+	}
+	for i := range a.Checks {
+		check := &a.Checks[i]
+		if check.ID == "" {
+			return fmt.Errorf("check %d has no id", i)
+		}
+		if !strings.HasPrefix(check.ID, name+".") {
+			check.ID = name + "." + check.ID
+		}
+		if !validPluginStatus(check.Status) {
+			return fmt.Errorf("check %s: invalid status %q", check.ID, check.Status)
+		}
+		if check.Severity == "" {
+			check.Severity = "major"
+		} else if !containsPluginValue(pluginCheckSeverities, check.Severity) {
+			return fmt.Errorf("check %s: invalid severity %q", check.ID, check.Severity)
+		}
+	}
+	return nil
+}
+
+func validPluginStatus(status string) bool {
+	return containsPluginValue([]string{PluginStatusPass, PluginStatusInfo, PluginStatusWarning, PluginStatusFail}, status)
+}
+
+func containsPluginValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// runPluginAnalyzers runs the plugins one after another and adds their
+// results to the enhanced analysis. A failing plugin is reported as not
+// analyzed and does not fail the analysis.
+func runPluginAnalyzers(ctx context.Context, plugins []PluginAnalyzer, result *FFprobeResult, input string, logger zerolog.Logger) {
+	if result.EnhancedAnalysis == nil {
+		result.EnhancedAnalysis = &EnhancedAnalysis{}
+	}
+	for _, plugin := range plugins {
+		name := plugin.Name()
+		analysis, err := plugin.Analyze(ctx, input, result)
+		if err == nil && analysis == nil {
+			err = errors.New("no result")
+		}
+		if err == nil {
+			err = analysis.normalize(name)
+		}
+		if err != nil {
+			logger.Warn().Err(err).Str("plugin", name).Msg("Plugin analysis failed")
+			analysis = &PluginAnalysis{Name: name, Status: PluginStatusNotAnalyzed, Error: err.Error()}
+		}
+		result.EnhancedAnalysis.Plugins = append(result.EnhancedAnalysis.Plugins, analysis)
+	}
+}
+
+// PluginRequest is the JSON an executable plugin reads on stdin
+type PluginRequest struct {
+	ProtocolVersion int          `json:"protocol_version"`
+	Plugin          string       `json:"plugin"`
+	Input           string       `json:"input"` // Path or URL of the media
+	Format          *FormatInfo  `json:"format,omitempty"`
+	Streams         []StreamInfo `json:"streams,omitempty"`
+}
+
+// ExecPluginAnalyzer runs an executable as a plugin. The executable reads a
+// PluginRequest as JSON on stdin and writes a PluginAnalysis as JSON on
+// stdout; a non-zero exit fails the plugin with stderr as the error.
+type ExecPluginAnalyzer struct {
+	name    string
+	path    string
+	timeout time.Duration
+}
+
+// NewExecPluginAnalyzer creates a plugin running the executable at path,
+// for at most timeout
+func NewExecPluginAnalyzer(name, path string, timeout time.Duration) (*ExecPluginAnalyzer, error) {
+	if err := ValidatePluginName(name); err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = DefaultPluginTimeout
+	}
+	return &ExecPluginAnalyzer{name: name, path: path, timeout: timeout}, nil
+}
+
+// Name returns the plugin name
+func (p *ExecPluginAnalyzer) Name() string {
+	return p.name
+}
+
+// Analyze runs the executable on input
+func (p *ExecPluginAnalyzer) Analyze(ctx context.Context, input string, result *FFprobeResult) (*PluginAnalysis, error) {
+	request, err := json.Marshal(PluginRequest{
+		ProtocolVersion: PluginProtocolVersion,
+		Plugin:          p.name,
+		Input:           input,
+		Format:          result.Format,
+		Streams:         result.Streams,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.path)
+	cmd.Stdin = bytes.NewReader(request)
+	stdout := &limitedBuffer{limit: maxPluginOutput}
+	var stderr strings.Builder
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	if err := runCommand(ctx, cmd); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%w: %s", err, message)
+		}
+		return nil, err
+	}
+	if stdout.Len() >= maxPluginOutput {
+		return nil, fmt.Errorf("output exceeds %d bytes", maxPluginOutput)
+	}
+
+	var analysis PluginAnalysis
+	if err := json.Unmarshal(stdout.Bytes(), &analysis); err != nil {
+		return nil, fmt.Errorf("invalid output: %w", err)
+	}
+	return &analysis, nil
+}
+
+// LoadPluginAnalyzers creates a plugin for every executable file in dir,
+// in name order. Plugins are named after their file, lower case and
+// without extension, with dashes and dots replaced by underscores; other
+// files and hidden files are skipped.
+func LoadPluginAnalyzers(dir string, timeout time.Duration) ([]PluginAnalyzer, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var plugins []PluginAnalyzer
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+
+		name := strings.ToLower(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		name = strings.NewReplacer("-", "_", ".", "_").Replace(name)
+		if other, ok := seen[name]; ok {
+			return nil, fmt.Errorf("plugins %s and %s are both named %q", other, entry.Name(), name)
+		}
+		plugin, err := NewExecPluginAnalyzer(name, path, timeout)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		seen[name] = entry.Name()
+		plugins = append(plugins, plugin)
+	}
+	return plugins, nil
+}
@@ -0,0 +1,123 @@
+package ffmpeg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// writePlugin writes an executable shell script plugin into dir
+func writePlugin(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidatePluginName(t *testing.T) {
+	for _, name := range []string{"slate_ocr", "watermark2"} {
+		if err := ValidatePluginName(name); err != nil {
+			t.Errorf("ValidatePluginName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "Slate", "slate-ocr", "2pass", "codec", "hdr"} {
+		if err := ValidatePluginName(name); err == nil {
+			t.Errorf("ValidatePluginName(%q) succeeded, want error", name)
+		}
+	}
+}
+
+func TestLoadPluginAnalyzers(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "watermark-detect.sh", "exit 0\n")
+	writePlugin(t, dir, "Slate.OCR", "exit 0\n")
+	writePlugin(t, dir, ".hidden", "exit 0\n")
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a plugin"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	plugins, err := LoadPluginAnalyzers(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, plugin := range plugins {
+		names = append(names, plugin.Name())
+	}
+	if strings.Join(names, ",") != "slate,watermark_detect" {
+		t.Errorf("plugins = %v, want slate, watermark_detect", names)
+	}
+
+	writePlugin(t, dir, "slate.py", "exit 0\n")
+	if _, err := LoadPluginAnalyzers(dir, 0); err == nil {
+		t.Error("duplicate plugin names loaded, want error")
+	}
+}
+
+func TestRunPluginAnalyzers(t *testing.T) {
+	dir := t.TempDir()
+	// The request arrives on stdin; echo its input back as a finding
+	writePlugin(t, dir, "slate_ocr", `input=$(sed 's/.*"input":"\([^"]*\)".*/\1/')
+cat <<JSON
+{"title": "Slate OCR", "status": "warning", "summary": "Slate found",
+ "findings": ["read $input"],
+ "checks": [{"id": "present", "status": "pass"},
+            {"id": "slate_ocr.title", "status": "warning", "severity": "minor", "message": "Title differs",
+             "evidence": [{"start_seconds": 0, "end_seconds": 10}]}]}
+JSON
+`)
+	writePlugin(t, dir, "crash", "echo 'no model loaded' >&2\nexit 3\n")
+	writePlugin(t, dir, "bad_status", `echo '{"status": "great"}'`+"\n")
+
+	plugins, err := LoadPluginAnalyzers(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &FFprobeResult{Format: &FormatInfo{Duration: "60.0"}}
+	runPluginAnalyzers(context.Background(), plugins, result, "/media/clip.mov", zerolog.Nop())
+
+	analyses := make(map[string]*PluginAnalysis)
+	for _, analysis := range result.EnhancedAnalysis.Plugins {
+		analyses[analysis.Name] = analysis
+	}
+	if len(analyses) != 3 {
+		t.Fatalf("plugins = %+v, want 3 results", result.EnhancedAnalysis.Plugins)
+	}
+
+	slate := analyses["slate_ocr"]
+	if slate.Status != PluginStatusWarning || slate.Title != "Slate OCR" || len(slate.Findings) != 1 || slate.Findings[0] != "read /media/clip.mov" {
+		t.Errorf("slate_ocr = %+v", slate)
+	}
+	if len(slate.Checks) != 2 || slate.Checks[0].ID != "slate_ocr.present" || slate.Checks[0].Severity != "major" ||
+		slate.Checks[1].ID != "slate_ocr.title" || slate.Checks[1].Severity != "minor" {
+		t.Errorf("slate_ocr checks = %+v", slate.Checks)
+	}
+
+	if crash := analyses["crash"]; crash.Status != PluginStatusNotAnalyzed || !strings.Contains(crash.Error, "no model loaded") {
+		t.Errorf("crash = %+v, want not analyzed with stderr", crash)
+	}
+	if bad := analyses["bad_status"]; bad.Status != PluginStatusNotAnalyzed || !strings.Contains(bad.Error, `"great"`) {
+		t.Errorf("bad_status = %+v, want not analyzed", bad)
+	}
+}
+
+func TestFFprobe_SetPluginAnalyzers(t *testing.T) {
+	plugin, err := NewExecPluginAnalyzer("slate_ocr", "/bin/true", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewFFprobe("ffprobe", zerolog.Nop())
+	if err := f.SetPluginAnalyzers([]PluginAnalyzer{plugin, plugin}); err == nil {
+		t.Error("duplicate plugins accepted, want error")
+	}
+	if err := f.SetPluginAnalyzers([]PluginAnalyzer{plugin}); err != nil {
+		t.Error(err)
+	}
+}
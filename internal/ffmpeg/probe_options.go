@@ -0,0 +1,133 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ProbeOptionNames is the allowlist of ffprobe flags a request may set
+var ProbeOptionNames = []string{"probesize", "analyzeduration", "read_intervals", "select_streams"}
+
+// Bounds of the probe options a request may set
+const (
+	// MinProbeSize is the smallest probesize ffprobe accepts
+	MinProbeSize = 32
+	// MaxProbeSize is the largest probesize a request may set, 1GB
+	MaxProbeSize = 1024 * 1024 * 1024
+	// MaxAnalyzeDuration is the longest analyzeduration a request may set,
+	// one hour in microseconds
+	MaxAnalyzeDuration = 3600 * 1000000
+)
+
+// ProbeOptions are the ffprobe flags of a request, overriding the defaults
+// of the analysis. Zero values keep the default.
+type ProbeOptions struct {
+	// ProbeSize is the number of bytes probed for stream information
+	ProbeSize int64 `json:"probesize,omitempty"`
+	// AnalyzeDuration is the duration probed for stream information, in
+	// microseconds
+	AnalyzeDuration int64 `json:"analyzeduration,omitempty"`
+	// ReadIntervals limits the packets and frames read, such as "30%+10"
+	ReadIntervals string `json:"read_intervals,omitempty"`
+	// SelectStreams limits the streams reported, such as "v:0"
+	SelectStreams string `json:"select_streams,omitempty"`
+}
+
+// ParseProbeOptions parses and validates the JSON object of probe options.
+// Flags outside ProbeOptionNames are rejected. Empty data or null is nil.
+func ParseProbeOptions(data []byte) (*ProbeOptions, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+
+	var flags map[string]json.RawMessage
+	if err := json.Unmarshal(data, &flags); err != nil {
+		return nil, fmt.Errorf("ffprobe_options must be a JSON object")
+	}
+	for name := range flags {
+		if !isProbeOptionName(name) {
+			return nil, fmt.Errorf("unsupported ffprobe option %q (allowed: %s)", name, strings.Join(ProbeOptionNames, ", "))
+		}
+	}
+
+	var options ProbeOptions
+	if err := json.Unmarshal(data, &options); err != nil {
+		return nil, fmt.Errorf("invalid ffprobe_options: %w", err)
+	}
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	return &options, nil
+}
+
+func isProbeOptionName(name string) bool {
+	for _, allowed := range ProbeOptionNames {
+		if name == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the options are within bounds and well formed
+func (o *ProbeOptions) Validate() error {
+	if o.ProbeSize != 0 && (o.ProbeSize < MinProbeSize || o.ProbeSize > MaxProbeSize) {
+		return fmt.Errorf("probesize must be between %d and %d bytes", MinProbeSize, MaxProbeSize)
+	}
+	if o.AnalyzeDuration < 0 || o.AnalyzeDuration > MaxAnalyzeDuration {
+		return fmt.Errorf("analyzeduration must be between 0 and %d microseconds", MaxAnalyzeDuration)
+	}
+	if o.ReadIntervals != "" {
+		if err := validateReadIntervals(o.ReadIntervals); err != nil {
+			return fmt.Errorf("invalid read_intervals: %w", err)
+		}
+	}
+	if o.SelectStreams != "" {
+		if err := validateSelectStreams(o.SelectStreams); err != nil {
+			return fmt.Errorf("invalid select_streams: %w", err)
+		}
+	}
+	return nil
+}
+
+// ProbeOptions applies the flags set in options; nil leaves the builder
+// unchanged
+func (b *OptionsBuilder) ProbeOptions(options *ProbeOptions) *OptionsBuilder {
+	if options == nil {
+		return b
+	}
+	if options.ProbeSize > 0 {
+		b.ProbeSize(options.ProbeSize)
+	}
+	if options.AnalyzeDuration > 0 {
+		b.AnalyzeDuration(options.AnalyzeDuration)
+	}
+	if options.ReadIntervals != "" {
+		b.ReadIntervals(options.ReadIntervals)
+	}
+	if options.SelectStreams != "" {
+		b.SelectStreams(options.SelectStreams)
+	}
+	return b
+}
+
+type probeOptionsKey struct{}
+
+// WithProbeOptions returns ctx carrying the probe options of a request, so
+// they reach the analyses it starts
+func WithProbeOptions(ctx context.Context, options *ProbeOptions) context.Context {
+	if options == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, probeOptionsKey{}, options)
+}
+
+// ProbeOptionsFromContext returns the probe options carried by ctx, or nil
+func ProbeOptionsFromContext(ctx context.Context) *ProbeOptions {
+	options, _ := ctx.Value(probeOptionsKey{}).(*ProbeOptions)
+	return options
+}
@@ -0,0 +1,86 @@
+package ffmpeg
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseProbeOptions(t *testing.T) {
+	options, err := ParseProbeOptions([]byte(`{"probesize": 5000000, "analyzeduration": 2000000, "read_intervals": "30%+10,%+#42", "select_streams": "v:0"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ProbeOptions{ProbeSize: 5000000, AnalyzeDuration: 2000000, ReadIntervals: "30%+10,%+#42", SelectStreams: "v:0"}
+	if *options != want {
+		t.Errorf("got %+v, want %+v", *options, want)
+	}
+
+	for _, data := range []string{"", " null "} {
+		if options, err := ParseProbeOptions([]byte(data)); err != nil || options != nil {
+			t.Errorf("ParseProbeOptions(%q) = %v, %v, want no options", data, options, err)
+		}
+	}
+}
+
+func TestParseProbeOptions_Invalid(t *testing.T) {
+	tests := []string{
+		`[]`,
+		`{"fflags": "+genpts"}`,
+		`{"i": "/etc/passwd"}`,
+		`{"probesize": "big"}`,
+		`{"probesize": 16}`,
+		`{"probesize": 2147483648}`,
+		`{"analyzeduration": -1}`,
+		`{"analyzeduration": 7200000000}`,
+		`{"read_intervals": "10%+20;ls"}`,
+		`{"read_intervals": "10,,20"}`,
+		`{"select_streams": "x:0"}`,
+	}
+	for _, data := range tests {
+		if _, err := ParseProbeOptions([]byte(data)); err == nil {
+			t.Errorf("expected %s to be rejected", data)
+		}
+	}
+}
+
+func TestValidateReadIntervals(t *testing.T) {
+	for _, intervals := range []string{"10", "10%", "%+#42", "10%+20", "01:23:45.678%+5", "+30%02:00", "0%+#100", "10%20,30%40"} {
+		if err := validateReadIntervals(intervals); err != nil {
+			t.Errorf("validateReadIntervals(%q): %v", intervals, err)
+		}
+	}
+	for _, intervals := range []string{"abc", "10%+20%", "%#42", "-5", "10 %20"} {
+		if err := validateReadIntervals(intervals); err == nil {
+			t.Errorf("expected %q to be rejected", intervals)
+		}
+	}
+}
+
+func TestOptionsBuilder_ProbeOptions(t *testing.T) {
+	opts := NewOptionsBuilder().
+		ProbeSizeMB(100).
+		AnalyzeDurationSeconds(60).
+		ProbeOptions(&ProbeOptions{ProbeSize: 1 << 20, ReadIntervals: "%+#10"}).
+		Build()
+	if opts.ProbeSize != 1<<20 || opts.ReadIntervals != "%+#10" {
+		t.Errorf("expected the probe options to apply, got probesize %d and read_intervals %q", opts.ProbeSize, opts.ReadIntervals)
+	}
+	if opts.AnalyzeDuration != 60*1000000 {
+		t.Errorf("expected the default analyzeduration to be kept, got %d", opts.AnalyzeDuration)
+	}
+
+	if opts := NewOptionsBuilder().ProbeSizeMB(10).ProbeOptions(nil).Build(); opts.ProbeSize != 10*1024*1024 {
+		t.Errorf("expected nil options to keep the builder unchanged, got %d", opts.ProbeSize)
+	}
+}
+
+func TestWithProbeOptions(t *testing.T) {
+	ctx := context.Background()
+	if ProbeOptionsFromContext(ctx) != nil {
+		t.Error("expected no options in a plain context")
+	}
+	options := &ProbeOptions{SelectStreams: "a"}
+	if got := ProbeOptionsFromContext(WithProbeOptions(ctx, options)); got != options {
+		t.Errorf("expected the options back, got %v", got)
+	}
+}
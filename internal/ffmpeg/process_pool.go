@@ -0,0 +1,240 @@
+package ffmpeg
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrProcessQueueFull is returned when an invocation would wait for a
+// process slot while the wait queue is already full
+var ErrProcessQueueFull = errors.New("ffmpeg process queue is full")
+
+// Priority orders invocations waiting for a process slot, and batch jobs
+// waiting for a worker. Higher priorities are started first; equal
+// priorities start in arrival order.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1 // Batch jobs and watch folders
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1 // Interactive requests that a client waits on
+)
+
+// String returns the priority name accepted by ParsePriority
+func (p Priority) String() string {
+	switch {
+	case p < PriorityNormal:
+		return "low"
+	case p > PriorityNormal:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// ParsePriority resolves a priority name. An empty name is PriorityNormal.
+func ParsePriority(name string) (Priority, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "low":
+		return PriorityLow, nil
+	case "", "normal":
+		return PriorityNormal, nil
+	case "high":
+		return PriorityHigh, nil
+	default:
+		return PriorityNormal, fmt.Errorf("unsupported priority %q (use low, normal or high)", name)
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose ffmpeg and ffprobe invocations wait
+// for a process slot at the given priority
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the priority set with WithPriority, or
+// PriorityNormal
+func PriorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return PriorityNormal
+}
+
+// ProcessPoolStats is a snapshot of a process pool
+type ProcessPoolStats struct {
+	MaxProcesses int    `json:"max_processes"`
+	MaxQueued    int    `json:"max_queued"` // 0 when the queue is unbounded
+	Running      int    `json:"running"`
+	Queued       int    `json:"queued"`
+	Rejected     uint64 `json:"rejected"` // Invocations refused because the queue was full
+}
+
+// ProcessPool caps how many ffmpeg and ffprobe processes run at once.
+// Invocations over the cap wait in a priority queue until a process exits
+// or their context is done.
+type ProcessPool struct {
+	mu        sync.Mutex
+	limit     int
+	maxQueued int
+	running   int
+	waiters   waiterQueue
+	seq       uint64
+	rejected  uint64
+}
+
+// NewProcessPool creates a pool running at most maxProcesses processes.
+// maxQueued bounds the invocations waiting for a slot; 0 leaves it unbounded.
+func NewProcessPool(maxProcesses, maxQueued int) *ProcessPool {
+	if maxProcesses < 1 {
+		maxProcesses = 1
+	}
+	if maxQueued < 0 {
+		maxQueued = 0
+	}
+	return &ProcessPool{limit: maxProcesses, maxQueued: maxQueued}
+}
+
+// Acquire waits for a process slot at the priority carried by ctx. The
+// returned function gives the slot back and must be called once the
+// process has exited.
+func (p *ProcessPool) Acquire(ctx context.Context) (func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	if p.running < p.limit && p.waiters.Len() == 0 {
+		p.running++
+		p.mu.Unlock()
+		return p.releaseFunc(), nil
+	}
+	if p.maxQueued > 0 && p.waiters.Len() >= p.maxQueued {
+		p.rejected++
+		p.mu.Unlock()
+		return nil, ErrProcessQueueFull
+	}
+	w := &waiter{priority: PriorityFromContext(ctx), seq: p.seq, ready: make(chan struct{})}
+	p.seq++
+	heap.Push(&p.waiters, w)
+	p.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return p.releaseFunc(), nil
+	case <-ctx.Done():
+		p.mu.Lock()
+		granted := w.index < 0
+		if !granted {
+			heap.Remove(&p.waiters, w.index)
+		}
+		p.mu.Unlock()
+		if granted {
+			// The slot was handed over while ctx was cancelled; pass it on
+			p.release()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (p *ProcessPool) releaseFunc() func() {
+	var once sync.Once
+	return func() { once.Do(p.release) }
+}
+
+// release hands the slot to the next waiter, or frees it
+func (p *ProcessPool) release() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.waiters.Len() > 0 {
+		w := heap.Pop(&p.waiters).(*waiter)
+		close(w.ready)
+		return
+	}
+	p.running--
+}
+
+// Stats returns a snapshot of the pool
+func (p *ProcessPool) Stats() ProcessPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return ProcessPoolStats{
+		MaxProcesses: p.limit,
+		MaxQueued:    p.maxQueued,
+		Running:      p.running,
+		Queued:       p.waiters.Len(),
+		Rejected:     p.rejected,
+	}
+}
+
+type waiter struct {
+	priority Priority
+	seq      uint64
+	ready    chan struct{}
+	index    int // Position in the queue, -1 once the slot is granted
+}
+
+// waiterQueue is a heap of waiters, highest priority and then oldest first
+type waiterQueue []*waiter
+
+func (q waiterQueue) Len() int { return len(q) }
+
+func (q waiterQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waiterQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waiterQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waiterQueue) Pop() any {
+	old := *q
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*q = old[:len(old)-1]
+	return w
+}
+
+// processPool limits every invocation in this package; nil means unlimited
+var processPool atomic.Pointer[ProcessPool]
+
+// SetProcessPool makes every ffmpeg and ffprobe invocation in this package
+// wait for a slot in pool. A nil pool removes the limit.
+func SetProcessPool(pool *ProcessPool) {
+	processPool.Store(pool)
+}
+
+// CurrentProcessPool returns the pool set with SetProcessPool, or nil
+func CurrentProcessPool() *ProcessPool {
+	return processPool.Load()
+}
+
+// AcquireProcess waits for a slot in the process pool before a process is
+// started. The returned function gives the slot back; without a pool it
+// does nothing.
+func AcquireProcess(ctx context.Context) (func(), error) {
+	pool := processPool.Load()
+	if pool == nil {
+		return func() {}, nil
+	}
+	return pool.Acquire(ctx)
+}
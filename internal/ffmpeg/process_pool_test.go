@@ -0,0 +1,124 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParsePriority(t *testing.T) {
+	tests := map[string]Priority{"": PriorityNormal, "low": PriorityLow, "Normal": PriorityNormal, " high ": PriorityHigh}
+	for name, want := range tests {
+		got, err := ParsePriority(name)
+		if err != nil || got != want {
+			t.Errorf("ParsePriority(%q) = %v, %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParsePriority("urgent"); err == nil {
+		t.Error("ParsePriority(urgent) succeeded, want an error")
+	}
+}
+
+// waitForQueued polls until n invocations wait for a slot
+func waitForQueued(t *testing.T, pool *ProcessPool, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for pool.Stats().Queued != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued = %d, want %d", pool.Stats().Queued, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestProcessPoolPriorityOrder(t *testing.T) {
+	pool := NewProcessPool(1, 0)
+	release, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	order := make(chan string, 3)
+	start := func(name string, priority Priority) {
+		go func() {
+			done, err := pool.Acquire(WithPriority(context.Background(), priority))
+			if err != nil {
+				t.Errorf("Acquire(%s): %v", name, err)
+				return
+			}
+			order <- name
+			done()
+		}()
+	}
+	start("low", PriorityLow)
+	waitForQueued(t, pool, 1)
+	start("normal", PriorityNormal)
+	waitForQueued(t, pool, 2)
+	start("high", PriorityHigh)
+	waitForQueued(t, pool, 3)
+
+	release()
+	for _, want := range []string{"high", "normal", "low"} {
+		select {
+		case got := <-order:
+			if got != want {
+				t.Fatalf("started %s, want %s", got, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s never started", want)
+		}
+	}
+	if stats := pool.Stats(); stats.Running != 0 || stats.Queued != 0 {
+		t.Errorf("stats after release = %+v, want nothing running or queued", stats)
+	}
+}
+
+func TestProcessPoolQueueLimit(t *testing.T) {
+	pool := NewProcessPool(1, 1)
+	release, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	defer release()
+
+	go pool.Acquire(context.Background())
+	waitForQueued(t, pool, 1)
+
+	if _, err := pool.Acquire(context.Background()); !errors.Is(err, ErrProcessQueueFull) {
+		t.Errorf("Acquire with a full queue = %v, want ErrProcessQueueFull", err)
+	}
+	if rejected := pool.Stats().Rejected; rejected != 1 {
+		t.Errorf("rejected = %d, want 1", rejected)
+	}
+}
+
+func TestProcessPoolCancelledWaiter(t *testing.T) {
+	pool := NewProcessPool(1, 0)
+	release, err := pool.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire past its deadline = %v, want DeadlineExceeded", err)
+	}
+	if queued := pool.Stats().Queued; queued != 0 {
+		t.Errorf("queued = %d after the waiter gave up, want 0", queued)
+	}
+
+	// Releasing twice must not free a slot that was never taken
+	release()
+	release()
+	if running := pool.Stats().Running; running != 0 {
+		t.Errorf("running = %d, want 0", running)
+	}
+	if _, err := pool.Acquire(context.Background()); err != nil {
+		t.Errorf("Acquire after release: %v", err)
+	}
+	if running := pool.Stats().Running; running != 1 {
+		t.Errorf("running = %d, want 1", running)
+	}
+}
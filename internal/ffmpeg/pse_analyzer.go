@@ -411,7 +411,7 @@ type PSEAnalysisMetadata struct {
 type AnalysisParameters struct {
 	FlashThreshold     float64 `json:"flash_threshold"`     // flashes per second
 	RedFlashThreshold  float64 `json:"red_flash_threshold"` // red flashes per second
-	PatternThreshold   float64 `json:"pattern_threshold"`   // cycles per degree
+	PatternThreshold   float64 `json:"pattern_threshold"`   // light-dark pairs
 	LuminanceThreshold float64 `json:"luminance_threshold"` // cd/m²
 	TemporalResolution float64 `json:"temporal_resolution"` // samples per second
 	SpatialResolution  float64 `json:"spatial_resolution"`  // pixels
@@ -439,59 +439,74 @@ func (pse *PSEAnalyzer) AnalyzePSERisk(ctx context.Context, filePath string) (*P
 
 	// Initialize analysis metadata
 	analysis.AnalysisMetadata = &PSEAnalysisMetadata{
-		AnalysisVersion: "1.0",
+		AnalysisVersion: "2.0",
 		AnalysisDate:    time.Now().Format("2006-01-02T15:04:05Z"),
 		StandardsVersion: map[string]string{
-			"ITU-R BT.709": "2015",
-			"FCC PSE":      "2016",
-			"Ofcom":        "2018",
-			"EBU R 102":    "2014",
+			"ITU-R BT.709":  "2015",
+			"FCC PSE":       "2016",
+			"Ofcom":         "2018",
+			"EBU R 102":     "2014",
+			"ITU-R BT.1702": ITU_R_BT1702_Version,
 		},
 		AnalysisParameters: &AnalysisParameters{
-			FlashThreshold:     3.0,    // flashes per second
-			RedFlashThreshold:  3.0,    // red flashes per second
-			PatternThreshold:   20.0,   // cycles per degree
-			LuminanceThreshold: 160.0,  // cd/m²
-			TemporalResolution: 25.0,   // fps
-			SpatialResolution:  1920.0, // pixels
+			FlashThreshold:     3.0,                                   // flashes per second
+			RedFlashThreshold:  3.0,                                   // red flashes per second
+			PatternThreshold:   psePatternMaxPairs,                    // light-dark pairs
+			LuminanceThreshold: pseDarkLimit,                          // cd/m²
+			TemporalResolution: 25.0,                                  // fps
+			SpatialResolution:  float64(pseGridWidth * pseGridHeight), // pixels
 		},
 	}
 
 	startTime := time.Now()
+	reportContextPhase(ctx, PhasePSE, 0)
 
-	// Step 1: Extract video information and sample frames
+	// Step 1: Extract video information and measure every frame
 	videoInfo, err := pse.extractVideoInfo(ctx, filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract video info: %w", err)
 	}
+	measurement, err := pse.measureFlashes(ctx, filePath, videoInfo)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		pse.logger.Warn().Err(err).Msg("Failed to measure flashes, using fallback analysis")
+	} else {
+		analysis.AnalysisMetadata.AnalysisParameters.TemporalResolution = measurement.FrameRate
+	}
 
 	// Step 2: Analyze flash patterns
-	if err := pse.analyzeFlashPatterns(ctx, filePath, videoInfo, analysis); err != nil {
+	if err := pse.analyzeFlashPatterns(ctx, filePath, videoInfo, measurement, analysis); err != nil {
 		pse.logger.Warn().Err(err).Msg("Failed to analyze flash patterns")
 	}
+	// A cancelled fallback reports no flashes, which must not pass as a result
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 
 	// Step 3: Analyze red flash patterns
-	if err := pse.analyzeRedFlashPatterns(ctx, filePath, videoInfo, analysis); err != nil {
+	if err := pse.analyzeRedFlashPatterns(measurement, analysis); err != nil {
 		pse.logger.Warn().Err(err).Msg("Failed to analyze red flash patterns")
 	}
 
 	// Step 4: Analyze spatial patterns
-	if err := pse.analyzeSpatialPatterns(ctx, filePath, videoInfo, analysis); err != nil {
+	if err := pse.analyzeSpatialPatterns(measurement, analysis); err != nil {
 		pse.logger.Warn().Err(err).Msg("Failed to analyze spatial patterns")
 	}
 
 	// Step 5: Analyze luminance changes
-	if err := pse.analyzeLuminanceChanges(ctx, filePath, videoInfo, analysis); err != nil {
+	if err := pse.analyzeLuminanceChanges(measurement, analysis); err != nil {
 		pse.logger.Warn().Err(err).Msg("Failed to analyze luminance changes")
 	}
 
 	// Step 6: Perform temporal analysis
-	if err := pse.performTemporalAnalysis(ctx, filePath, videoInfo, analysis); err != nil {
+	if err := pse.performTemporalAnalysis(videoInfo, measurement, analysis); err != nil {
 		pse.logger.Warn().Err(err).Msg("Failed to perform temporal analysis")
 	}
 
 	// Step 7: Perform spatial analysis
-	if err := pse.performSpatialAnalysis(analysis); err != nil {
+	if err := pse.performSpatialAnalysis(measurement, analysis); err != nil {
 		pse.logger.Warn().Err(err).Msg("Failed to perform spatial analysis")
 	}
 
@@ -515,6 +530,10 @@ func (pse *PSEAnalyzer) AnalyzePSERisk(ctx context.Context, filePath string) (*P
 	// Step 13: Finalize metadata
 	analysis.AnalysisMetadata.ProcessingTime = time.Since(startTime).Seconds()
 	analysis.AnalysisMetadata.QualityMetrics = pse.calculateQualityMetrics(analysis)
+	if measurement != nil && videoInfo.Duration > 0 {
+		analysis.AnalysisMetadata.QualityMetrics.AnalysisCoverage = math.Min(1, measurement.duration()/videoInfo.Duration)
+	}
+	reportContextPhase(ctx, PhasePSE, 100)
 
 	return analysis, nil
 }
@@ -589,12 +608,9 @@ func (pse *PSEAnalyzer) extractVideoInfo(ctx context.Context, filePath string) (
 	return videoInfo, nil
 }
 
-// analyzeFlashPatterns analyzes general flash patterns using FFmpeg luminance analysis
-func (pse *PSEAnalyzer) analyzeFlashPatterns(ctx context.Context, filePath string, videoInfo *VideoInfo, analysis *PSEAnalysis) error {
-	// Use FFmpeg signalstats to measure luminance changes between frames
-	// A flash is defined as a pair of opposing luminance changes of 10%+ of max relative luminance
-	// where the darker image is below 0.80 relative luminance (per WCAG 2.0)
-
+// analyzeFlashPatterns counts luminance flashes per ITU-R BT.1702 from the
+// measured transitions, falling back to scene changes without them
+func (pse *PSEAnalyzer) analyzeFlashPatterns(ctx context.Context, filePath string, videoInfo *VideoInfo, measurement *pseMeasurement, analysis *PSEAnalysis) error {
 	flashAnalysis := &FlashAnalysis{
 		FlashCount:       0,
 		FlashRate:        0.0,
@@ -606,51 +622,41 @@ func (pse *PSEAnalyzer) analyzeFlashPatterns(ctx context.Context, filePath strin
 		CriticalPeriods:  []TimePeriod{},
 	}
 
-	// Run FFmpeg with signalstats to get luminance data per frame
-	luminanceData, err := pse.extractLuminanceData(ctx, filePath)
-	if err != nil {
-		pse.logger.Warn().Err(err).Msg("Failed to extract luminance data, using fallback analysis")
-		// Fallback to basic analysis
+	if measurement == nil {
 		return pse.fallbackFlashAnalysis(ctx, filePath, videoInfo, analysis, flashAnalysis)
 	}
 
-	// Analyze luminance changes for flash detection
-	flashes := pse.detectFlashesFromLuminance(luminanceData, videoInfo.FrameRate)
-
+	transitions := measurement.Luminance.transitions
+	flashes := pairFlashes(transitions, pseDisplayWhite)
 	flashAnalysis.FlashCount = len(flashes)
-
-	if videoInfo.Duration > 0 {
-		flashAnalysis.FlashRate = float64(len(flashes)) / videoInfo.Duration
+	flashAnalysis.FlashRate = float64(len(flashes)) / measurement.duration()
+	flashAnalysis.MaxFlashRate = measurement.Luminance.maxFlashRate()
+
+	for _, t := range transitions[:min(len(transitions), pseMaxEvents)] {
+		flashAnalysis.FlashIntensity = append(flashAnalysis.FlashIntensity, FlashIntensity{
+			Timestamp:       t.Timestamp,
+			LuminanceBefore: t.From,
+			LuminanceAfter:  t.To,
+			IntensityChange: math.Min(1, t.change()/pseDisplayWhite),
+			ScreenArea:      t.Area,
+		})
 	}
 
-	// Find max flash rate within any 1-second window
-	flashAnalysis.MaxFlashRate = pse.calculateMaxFlashRate(flashes, videoInfo.FrameRate)
-
-	// PSE threshold is 3 flashes per second (WCAG 2.0 / Ofcom)
-	if flashAnalysis.MaxFlashRate > 3.0 {
+	// More than three flashes in any one second fails ITU-R BT.1702 and
+	// Ofcom
+	if flashAnalysis.MaxFlashRate > MaxSafeFlashRate {
 		flashAnalysis.ExceedsThreshold = true
 
-		// Find critical periods where threshold is exceeded
-		criticalPeriods := pse.findCriticalPeriods(flashes, videoInfo.FrameRate)
-		flashAnalysis.CriticalPeriods = criticalPeriods
-
-		for _, period := range criticalPeriods {
-			violation := PSEViolation{
-				Timestamp:           period.StartTime,
-				ViolationType:       "flash",
-				Severity:            pse.determineSeverity(flashAnalysis.MaxFlashRate),
-				Description:         fmt.Sprintf("Flash rate of %.2f Hz detected (exceeds 3 Hz threshold)", flashAnalysis.MaxFlashRate),
-				AffectedArea:        1.0,
-				Duration:            period.EndTime - period.StartTime,
-				RiskScore:           math.Min(100, flashAnalysis.MaxFlashRate*20),
-				ComplianceStandards: []string{"WCAG 2.0", "Ofcom", "FCC PSE", "ITU-R BT.709"},
-			}
-			analysis.ViolationInstances = append(analysis.ViolationInstances, violation)
+		periods := measurement.Luminance.hazardousPeriods()
+		for _, period := range periods {
+			flashAnalysis.CriticalPeriods = append(flashAnalysis.CriticalPeriods, period.TimePeriod)
+			flashAnalysis.FlashSequences = append(flashAnalysis.FlashSequences, pse.flashSequence(period))
 		}
+		analysis.ViolationInstances = append(analysis.ViolationInstances,
+			pse.flashViolations(periods, "flash", "Flash", []string{"ITU-R BT.1702", "Ofcom", "FCC PSE", "WCAG 2.0"})...)
 	}
 
-	// Calculate flash characteristics from actual data
-	flashAnalysis.FlashCharacteristics = pse.analyzeFlashCharacteristics(flashes, videoInfo.FrameRate)
+	flashAnalysis.FlashCharacteristics = pse.analyzeFlashCharacteristics(flashes, measurement.FrameRate)
 
 	analysis.FlashAnalysis = flashAnalysis
 
@@ -664,206 +670,26 @@ func (pse *PSEAnalyzer) analyzeFlashPatterns(ctx context.Context, filePath strin
 	return nil
 }
 
-// LuminanceFrame holds luminance data for a single frame
-type LuminanceFrame struct {
-	FrameNumber int
-	Timestamp   float64
-	YAvg        float64 // Average luminance
-	YMin        float64 // Minimum luminance
-	YMax        float64 // Maximum luminance
-}
-
 // FlashEvent represents a detected flash
 type FlashEvent struct {
 	FrameNumber int
 	Timestamp   float64
-	Intensity   float64 // Delta luminance
-}
-
-// extractLuminanceData uses FFmpeg signalstats to get per-frame luminance
-func (pse *PSEAnalyzer) extractLuminanceData(ctx context.Context, filePath string) ([]LuminanceFrame, error) {
-	// Use FFmpeg signalstats filter to get luminance statistics
-	cmd := exec.CommandContext(ctx,
-		pse.ffmpegPath,
-		"-i", filePath,
-		"-vf", "signalstats,metadata=mode=print",
-		"-f", "null",
-		"-t", "30", // Analyze first 30 seconds
-		"-",
-	)
-
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("signalstats failed: %w", err)
-	}
-
-	return pse.parseLuminanceOutput(string(output))
-}
-
-// parseLuminanceOutput parses FFmpeg signalstats output for luminance data
-func (pse *PSEAnalyzer) parseLuminanceOutput(output string) ([]LuminanceFrame, error) {
-	var frames []LuminanceFrame
-	var currentFrame *LuminanceFrame
-	frameNum := 0
-
-	lines := strings.Split(output, "\n")
-	frameRe := regexp.MustCompile(`frame:\s*(\d+)`)
-	yavgRe := regexp.MustCompile(`signalstats\.YAVG=(\d+\.?\d*)`)
-	yminRe := regexp.MustCompile(`signalstats\.YMIN=(\d+\.?\d*)`)
-	ymaxRe := regexp.MustCompile(`signalstats\.YMAX=(\d+\.?\d*)`)
-	ptsRe := regexp.MustCompile(`pts_time:\s*(\d+\.?\d*)`)
-
-	for _, line := range lines {
-		// Check for new frame
-		if match := frameRe.FindStringSubmatch(line); len(match) > 1 {
-			if currentFrame != nil {
-				frames = append(frames, *currentFrame)
-			}
-			frameNum, _ = strconv.Atoi(match[1])
-			currentFrame = &LuminanceFrame{FrameNumber: frameNum}
-		}
-
-		if currentFrame == nil {
-			currentFrame = &LuminanceFrame{}
-		}
-
-		// Parse PTS time
-		if match := ptsRe.FindStringSubmatch(line); len(match) > 1 {
-			currentFrame.Timestamp, _ = strconv.ParseFloat(match[1], 64)
-		}
-
-		// Parse YAVG (average luminance)
-		if match := yavgRe.FindStringSubmatch(line); len(match) > 1 {
-			currentFrame.YAvg, _ = strconv.ParseFloat(match[1], 64)
-		}
-
-		// Parse YMIN
-		if match := yminRe.FindStringSubmatch(line); len(match) > 1 {
-			currentFrame.YMin, _ = strconv.ParseFloat(match[1], 64)
-		}
-
-		// Parse YMAX
-		if match := ymaxRe.FindStringSubmatch(line); len(match) > 1 {
-			currentFrame.YMax, _ = strconv.ParseFloat(match[1], 64)
-		}
-	}
-
-	// Add last frame
-	if currentFrame != nil && currentFrame.YAvg > 0 {
-		frames = append(frames, *currentFrame)
-	}
-
-	if len(frames) == 0 {
-		return nil, fmt.Errorf("no luminance data found")
-	}
-
-	return frames, nil
+	Intensity   float64 // Larger change of the flash's transitions, 0-1
 }
 
-// detectFlashesFromLuminance analyzes luminance data to detect flashes
-func (pse *PSEAnalyzer) detectFlashesFromLuminance(frames []LuminanceFrame, frameRate float64) []FlashEvent {
-	var flashes []FlashEvent
-
-	if len(frames) < 2 {
-		return flashes
+// flashSequence describes a hazardous period
+func (pse *PSEAnalyzer) flashSequence(period psePeriod) FlashSequence {
+	sequence := FlashSequence{
+		StartTime:  period.StartTime,
+		EndTime:    period.EndTime,
+		FlashCount: period.Transitions / 2,
+		PeakRate:   period.PeakRate,
+		RiskLevel:  pse.determineSeverity(period.PeakRate),
 	}
-
-	// A flash is a rapid change in luminance
-	// WCAG 2.0: 10% or more change in relative luminance where darker is below 0.80
-	const flashThreshold = 25.5 // 10% of 255 (max luminance in 8-bit)
-
-	for i := 1; i < len(frames); i++ {
-		delta := math.Abs(frames[i].YAvg - frames[i-1].YAvg)
-
-		// Check if this is a significant luminance change
-		if delta > flashThreshold {
-			// Check if the darker frame is below 0.80 relative luminance (204 in 8-bit)
-			minLuma := math.Min(frames[i].YAvg, frames[i-1].YAvg)
-			if minLuma < 204 {
-				flash := FlashEvent{
-					FrameNumber: frames[i].FrameNumber,
-					Timestamp:   frames[i].Timestamp,
-					Intensity:   delta / 255.0, // Normalize to 0-1
-				}
-				flashes = append(flashes, flash)
-			}
-		}
+	if period.Duration > 0 {
+		sequence.AverageRate = float64(period.Transitions) / 2 / period.Duration
 	}
-
-	return flashes
-}
-
-// calculateMaxFlashRate finds the maximum flash rate in any 1-second window
-func (pse *PSEAnalyzer) calculateMaxFlashRate(flashes []FlashEvent, frameRate float64) float64 {
-	if len(flashes) == 0 {
-		return 0
-	}
-
-	maxRate := 0.0
-
-	// Slide a 1-second window through the flashes
-	for i := 0; i < len(flashes); i++ {
-		windowEnd := flashes[i].Timestamp + 1.0
-		count := 0
-
-		for j := i; j < len(flashes) && flashes[j].Timestamp < windowEnd; j++ {
-			count++
-		}
-
-		if float64(count) > maxRate {
-			maxRate = float64(count)
-		}
-	}
-
-	return maxRate
-}
-
-// findCriticalPeriods identifies time periods where flash rate exceeds threshold
-func (pse *PSEAnalyzer) findCriticalPeriods(flashes []FlashEvent, frameRate float64) []TimePeriod {
-	var periods []TimePeriod
-
-	if len(flashes) == 0 {
-		return periods
-	}
-
-	var inCritical bool
-	var periodStart float64
-
-	for i := 0; i < len(flashes); i++ {
-		windowEnd := flashes[i].Timestamp + 1.0
-		count := 0
-
-		for j := i; j < len(flashes) && flashes[j].Timestamp < windowEnd; j++ {
-			count++
-		}
-
-		if count >= 3 { // 3+ flashes per second
-			if !inCritical {
-				inCritical = true
-				periodStart = flashes[i].Timestamp
-			}
-		} else if inCritical {
-			endTime := flashes[i-1].Timestamp
-			periods = append(periods, TimePeriod{
-				StartTime: periodStart,
-				EndTime:   endTime,
-				Duration:  endTime - periodStart,
-			})
-			inCritical = false
-		}
-	}
-
-	// Close any open period
-	if inCritical && len(flashes) > 0 {
-		endTime := flashes[len(flashes)-1].Timestamp
-		periods = append(periods, TimePeriod{
-			StartTime: periodStart,
-			EndTime:   endTime,
-			Duration:  endTime - periodStart,
-		})
-	}
-
-	return periods
+	return sequence
 }
 
 // determineSeverity returns severity level based on flash rate
@@ -935,7 +761,7 @@ func (pse *PSEAnalyzer) analyzeFlashCharacteristics(flashes []FlashEvent, frameR
 	}
 }
 
-// fallbackFlashAnalysis provides basic analysis when signalstats is unavailable
+// fallbackFlashAnalysis provides basic analysis when the frames cannot be measured
 func (pse *PSEAnalyzer) fallbackFlashAnalysis(ctx context.Context, filePath string, videoInfo *VideoInfo, analysis *PSEAnalysis, flashAnalysis *FlashAnalysis) error {
 	pse.logger.Info().Msg("Using fallback scene-change based flash analysis")
 
@@ -945,11 +771,10 @@ func (pse *PSEAnalyzer) fallbackFlashAnalysis(ctx context.Context, filePath stri
 		"-i", filePath,
 		"-vf", "select='gt(scene,0.3)',metadata=print",
 		"-f", "null",
-		"-t", "30",
 		"-",
 	)
 
-	output, err := cmd.CombinedOutput()
+	output, err := commandCombinedOutput(ctx, cmd)
 	if err != nil {
 		// If FFmpeg fails, report no flashes detected with low confidence
 		flashAnalysis.FlashCount = 0
@@ -985,9 +810,14 @@ func (pse *PSEAnalyzer) fallbackFlashAnalysis(ctx context.Context, filePath stri
 	return nil
 }
 
-// analyzeRedFlashPatterns analyzes red-specific flash patterns
-func (pse *PSEAnalyzer) analyzeRedFlashPatterns(ctx context.Context, filePath string, videoInfo *VideoInfo, analysis *PSEAnalysis) error {
-	// Simulate red flash analysis
+// analyzeRedFlashPatterns counts flashes to or from saturated red, which
+// ITU-R BT.1702 treats as a hazard of their own
+func (pse *PSEAnalyzer) analyzeRedFlashPatterns(measurement *pseMeasurement, analysis *PSEAnalysis) error {
+	if measurement == nil {
+		analysis.RedFlashRiskLevel = "unknown"
+		return fmt.Errorf("red flashes need the decoded frames")
+	}
+
 	redFlashAnalysis := &RedFlashAnalysis{
 		RedFlashCount:       0,
 		RedFlashRate:        0.0,
@@ -999,41 +829,63 @@ func (pse *PSEAnalyzer) analyzeRedFlashPatterns(ctx context.Context, filePath st
 		CriticalRedPeriods:  []TimePeriod{},
 	}
 
-	// Estimate red flashes as a portion of total flashes
-	if analysis.FlashAnalysis != nil {
-		estimatedRedFlashes := analysis.FlashAnalysis.FlashCount / 4 // Assume 25% are red-dominant
-		redFlashAnalysis.RedFlashCount = estimatedRedFlashes
+	transitions := measurement.Red.transitions
+	flashes := pairFlashes(transitions, pseRedScale)
+	redFlashAnalysis.RedFlashCount = len(flashes)
+	redFlashAnalysis.RedFlashRate = float64(len(flashes)) / measurement.duration()
+	redFlashAnalysis.MaxRedFlashRate = measurement.Red.maxFlashRate()
 
-		if videoInfo.Duration > 0 {
-			redFlashAnalysis.RedFlashRate = float64(estimatedRedFlashes) / videoInfo.Duration
-			redFlashAnalysis.MaxRedFlashRate = redFlashAnalysis.RedFlashRate * 1.2
-		}
+	for _, t := range transitions[:min(len(transitions), pseMaxEvents)] {
+		redFlashAnalysis.RedFlashArea = append(redFlashAnalysis.RedFlashArea, RedFlashArea{
+			Timestamp:       t.Timestamp,
+			AreaPercentage:  t.Area * 100,
+			RedIntensity:    math.Min(1, t.change()/pseRedScale),
+			SaturationLevel: math.Min(1, math.Max(t.From, t.To)/pseRedScale),
+		})
+	}
 
-		// Check red flash threshold
-		if redFlashAnalysis.RedFlashRate > 3.0 {
-			redFlashAnalysis.ExceedsRedThreshold = true
-
-			violation := PSEViolation{
-				Timestamp:           0.0,
-				ViolationType:       "red_flash",
-				Severity:            "high",
-				Description:         fmt.Sprintf("Red flash rate of %.2f Hz exceeds 3 Hz threshold", redFlashAnalysis.RedFlashRate),
-				AffectedArea:        0.8,
-				Duration:            videoInfo.Duration,
-				RiskScore:           70.0,
-				ComplianceStandards: []string{"FCC PSE", "Ofcom"},
+	if redFlashAnalysis.MaxRedFlashRate > MaxSafeFlashRate {
+		redFlashAnalysis.ExceedsRedThreshold = true
+
+		periods := measurement.Red.hazardousPeriods()
+		for _, period := range periods {
+			redFlashAnalysis.CriticalRedPeriods = append(redFlashAnalysis.CriticalRedPeriods, period.TimePeriod)
+
+			sequence := RedFlashSequence{
+				StartTime:       period.StartTime,
+				EndTime:         period.EndTime,
+				RedFlashCount:   period.Transitions / 2,
+				MaxAreaCoverage: period.Area * 100,
+				RiskLevel:       pse.determineSeverity(period.PeakRate),
+			}
+			matched := 0
+			for _, flash := range flashes {
+				if flash.Timestamp >= period.StartTime && flash.Timestamp <= period.EndTime {
+					sequence.AverageIntensity += flash.Intensity
+					matched++
+				}
 			}
-			analysis.ViolationInstances = append(analysis.ViolationInstances, violation)
+			if matched > 0 {
+				sequence.AverageIntensity /= float64(matched)
+			}
+			redFlashAnalysis.RedFlashSequences = append(redFlashAnalysis.RedFlashSequences, sequence)
 		}
+		analysis.ViolationInstances = append(analysis.ViolationInstances,
+			pse.flashViolations(periods, "red_flash", "Red flash", []string{"ITU-R BT.1702", "Ofcom", "FCC PSE"})...)
 	}
 
 	analysis.RedFlashAnalysis = redFlashAnalysis
 	return nil
 }
 
-// analyzeSpatialPatterns analyzes spatial patterns that may trigger seizures
-func (pse *PSEAnalyzer) analyzeSpatialPatterns(ctx context.Context, filePath string, videoInfo *VideoInfo, analysis *PSEAnalysis) error {
-	// Simulate pattern analysis
+// analyzeSpatialPatterns reports the striped and checkerboard patterns
+// found in the sampled frames, with the time segments they are hazardous
+func (pse *PSEAnalyzer) analyzeSpatialPatterns(measurement *pseMeasurement, analysis *PSEAnalysis) error {
+	if measurement == nil {
+		analysis.PatternRiskLevel = "unknown"
+		return fmt.Errorf("pattern detection needs the decoded frames")
+	}
+
 	patternAnalysis := &PatternAnalysis{
 		HasStripedPatterns:      false,
 		HasCheckerboardPatterns: false,
@@ -1046,27 +898,69 @@ func (pse *PSEAnalyzer) analyzeSpatialPatterns(ctx context.Context, filePath str
 		HighRiskPatterns:        []HighRiskPattern{},
 	}
 
-	// For demonstration, we'll check if the content might contain patterns
-	// based on resolution and content type assumptions
-	if videoInfo.Width >= 1920 && videoInfo.Height >= 1080 {
-		// High resolution content more likely to have fine patterns
-		patternAnalysis.PatternFrequency = 15.0 // cycles per degree
-		patternAnalysis.PatternContrast = 0.3
+	// Characteristics of the hazardous samples per pattern type
+	type patternStats struct {
+		samples  int
+		risk     float64
+		pairs    float64
+		coverage float64
+	}
+	risky := measurement.Patterns.Risky
+	stats := map[string]*patternStats{}
+	for _, sample := range risky {
+		patternAnalysis.PatternFrequency = math.Max(patternAnalysis.PatternFrequency, sample.Frequency)
+		patternAnalysis.PatternContrast = math.Max(patternAnalysis.PatternContrast, sample.Contrast)
+
+		st := stats[sample.Type]
+		if st == nil {
+			st = &patternStats{}
+			stats[sample.Type] = st
+		}
+		st.samples++
+		st.risk = math.Max(st.risk, patternRisk(sample.Coverage))
+		st.pairs = math.Max(st.pairs, sample.Pairs)
+		st.coverage = math.Max(st.coverage, sample.Coverage)
+	}
+	for _, patternType := range []string{psePatternStriped, psePatternCheckerboard} {
+		st := stats[patternType]
+		if st == nil {
+			continue
+		}
+		patternAnalysis.HighRiskPatterns = append(patternAnalysis.HighRiskPatterns, HighRiskPattern{
+			PatternType: patternType,
+			RiskScore:   st.risk,
+			Characteristics: map[string]interface{}{
+				"samples":             st.samples,
+				"max_pairs":           math.Round(st.pairs),
+				"max_screen_coverage": st.coverage,
+			},
+			Mitigation: []string{"Blur or soften the pattern", "Reduce its contrast or the screen area it covers"},
+		})
+	}
+	patternAnalysis.HasStripedPatterns = stats[psePatternStriped] != nil
+	patternAnalysis.HasCheckerboardPatterns = stats[psePatternCheckerboard] != nil
+
+	if len(risky) > 0 {
+		patternAnalysis.ExceedsPatternThreshold = true
 
-		if patternAnalysis.PatternFrequency > 20.0 {
-			patternAnalysis.ExceedsPatternThreshold = true
+		for _, instance := range measurement.Patterns.segments(measurement.duration()) {
+			if len(patternAnalysis.PatternInstances) == pseMaxEvents {
+				break
+			}
+			risk := patternRisk(instance.ScreenCoverage)
+			instance.RiskLevel = pse.scoreToRiskLevel(risk)
+			patternAnalysis.PatternInstances = append(patternAnalysis.PatternInstances, instance)
 
-			violation := PSEViolation{
-				Timestamp:           0.0,
+			analysis.ViolationInstances = append(analysis.ViolationInstances, PSEViolation{
+				Timestamp:           instance.StartTime,
 				ViolationType:       "pattern",
 				Severity:            "medium",
-				Description:         fmt.Sprintf("Spatial frequency of %.1f cycles/degree may exceed safe thresholds", patternAnalysis.PatternFrequency),
-				AffectedArea:        0.5,
-				Duration:            videoInfo.Duration,
-				RiskScore:           40.0,
-				ComplianceStandards: []string{"EBU R 102"},
-			}
-			analysis.ViolationInstances = append(analysis.ViolationInstances, violation)
+				Description:         fmt.Sprintf("Regular %s pattern at %.1f cycles/degree over %.0f%% of the screen (more than %.0f light-dark pairs)", instance.PatternType, instance.SpatialFrequency, instance.ScreenCoverage*100, psePatternMaxPairs),
+				AffectedArea:        instance.ScreenCoverage,
+				Duration:            instance.EndTime - instance.StartTime,
+				RiskScore:           risk,
+				ComplianceStandards: []string{"Ofcom", "ITU-R BT.1702"},
+			})
 		}
 	}
 
@@ -1075,7 +969,7 @@ func (pse *PSEAnalyzer) analyzeSpatialPatterns(ctx context.Context, filePath str
 }
 
 // analyzeLuminanceChanges analyzes luminance transitions
-func (pse *PSEAnalyzer) analyzeLuminanceChanges(ctx context.Context, filePath string, videoInfo *VideoInfo, analysis *PSEAnalysis) error {
+func (pse *PSEAnalyzer) analyzeLuminanceChanges(measurement *pseMeasurement, analysis *PSEAnalysis) error {
 	luminanceAnalysis := &LuminanceAnalysis{
 		LuminanceFlashes:     0,
 		MaxLuminanceChange:   0.0,
@@ -1095,8 +989,29 @@ func (pse *PSEAnalyzer) analyzeLuminanceChanges(ctx context.Context, filePath st
 		},
 	}
 
-	// Estimate luminance changes based on flash analysis
-	if analysis.FlashAnalysis != nil {
+	if measurement != nil {
+		transitions := measurement.Luminance.transitions
+		luminanceAnalysis.LuminanceFlashes = len(transitions) / 2
+		luminanceAnalysis.LuminanceChangeRate = float64(len(transitions)) / measurement.duration()
+		for i, t := range transitions {
+			luminanceAnalysis.MaxLuminanceChange = math.Max(luminanceAnalysis.MaxLuminanceChange, t.change())
+			if i >= pseMaxEvents {
+				continue
+			}
+			transition := LuminanceTransition{
+				Timestamp:     t.Timestamp,
+				FromLuminance: t.From,
+				ToLuminance:   t.To,
+				ScreenArea:    t.Area,
+			}
+			if t.Duration > 0 {
+				transition.TransitionSpeed = t.change() / t.Duration
+			}
+			luminanceAnalysis.LuminanceTransitions = append(luminanceAnalysis.LuminanceTransitions, transition)
+		}
+		luminanceAnalysis.BrightnessVariation = measurement.brightness()
+	} else if analysis.FlashAnalysis != nil {
+		// Estimate luminance changes based on flash analysis
 		luminanceAnalysis.LuminanceFlashes = analysis.FlashAnalysis.FlashCount
 		luminanceAnalysis.LuminanceChangeRate = analysis.FlashAnalysis.FlashRate
 		luminanceAnalysis.MaxLuminanceChange = 120.0 // cd/m² typical for flashes
@@ -1107,7 +1022,7 @@ func (pse *PSEAnalyzer) analyzeLuminanceChanges(ctx context.Context, filePath st
 }
 
 // performTemporalAnalysis analyzes temporal aspects
-func (pse *PSEAnalyzer) performTemporalAnalysis(ctx context.Context, filePath string, videoInfo *VideoInfo, analysis *PSEAnalysis) error {
+func (pse *PSEAnalyzer) performTemporalAnalysis(videoInfo *VideoInfo, measurement *pseMeasurement, analysis *PSEAnalysis) error {
 	temporal := &TemporalPSEAnalysis{
 		AnalysisDuration:    videoInfo.Duration,
 		SamplingRate:        videoInfo.FrameRate,
@@ -1117,6 +1032,13 @@ func (pse *PSEAnalyzer) performTemporalAnalysis(ctx context.Context, filePath st
 
 	// Create temporal windows (1-second intervals)
 	windowCount := int(videoInfo.Duration)
+	var flashes, redFlashes []FlashEvent
+	if measurement != nil {
+		windowCount = int(math.Ceil(measurement.duration()))
+		temporal.AnalysisDuration = measurement.duration()
+		flashes = pairFlashes(measurement.Luminance.transitions, pseDisplayWhite)
+		redFlashes = pairFlashes(measurement.Red.transitions, pseRedScale)
+	}
 	for i := 0; i < windowCount; i++ {
 		window := TemporalWindow{
 			StartTime:     float64(i),
@@ -1127,13 +1049,14 @@ func (pse *PSEAnalyzer) performTemporalAnalysis(ctx context.Context, filePath st
 			RiskScore:     0.0,
 		}
 
-		// Distribute flashes across windows
-		if analysis.FlashAnalysis != nil {
+		if measurement != nil {
+			window.FlashCount = countFlashes(flashes, window.StartTime, window.EndTime)
+			window.RedFlashCount = countFlashes(redFlashes, window.StartTime, window.EndTime)
+			window.PatternCount = measurement.Patterns.count(window.StartTime, window.EndTime)
+		} else if analysis.FlashAnalysis != nil {
+			// Distribute flashes across windows
 			window.FlashCount = analysis.FlashAnalysis.FlashCount / windowCount
 		}
-		if analysis.RedFlashAnalysis != nil {
-			window.RedFlashCount = analysis.RedFlashAnalysis.RedFlashCount / windowCount
-		}
 
 		// Calculate risk score for window
 		window.RiskScore = float64(window.FlashCount*10 + window.RedFlashCount*20 + window.PatternCount*15)
@@ -1169,8 +1092,19 @@ func (pse *PSEAnalyzer) performTemporalAnalysis(ctx context.Context, filePath st
 	return nil
 }
 
+// countFlashes counts the flashes completed in [start, end)
+func countFlashes(flashes []FlashEvent, start, end float64) int {
+	count := 0
+	for _, flash := range flashes {
+		if flash.Timestamp >= start && flash.Timestamp < end {
+			count++
+		}
+	}
+	return count
+}
+
 // performSpatialAnalysis analyzes spatial aspects
-func (pse *PSEAnalyzer) performSpatialAnalysis(analysis *PSEAnalysis) error {
+func (pse *PSEAnalyzer) performSpatialAnalysis(measurement *pseMeasurement, analysis *PSEAnalysis) error {
 	spatial := &SpatialPSEAnalysis{
 		ScreenCoverage:         1.0, // Assume full screen analysis
 		CentralVisionImpact:    0.8, // High impact on central vision
@@ -1178,6 +1112,11 @@ func (pse *PSEAnalyzer) performSpatialAnalysis(analysis *PSEAnalysis) error {
 		RegionAnalysis:         []RegionRiskAnalysis{},
 	}
 
+	// The largest area any transition changed at once
+	if measurement != nil {
+		spatial.ScreenCoverage = math.Max(maxTransitionArea(measurement.Luminance.transitions), maxTransitionArea(measurement.Red.transitions))
+	}
+
 	// Spatial extent
 	spatial.SpatialExtent = &SpatialExtent{
 		CenterX:           0.5,
@@ -1306,7 +1245,7 @@ func (pse *PSEAnalyzer) checkBroadcastCompliance(analysis *PSEAnalysis) *Broadca
 		compliance.EBUCompliant = false
 		compliance.ITU709Compliant = false
 		compliance.NonCompliantReasons = append(compliance.NonCompliantReasons,
-			"Spatial patterns exceed safe frequency thresholds")
+			"Regular patterns of more than five light-dark pairs cover a quarter of the screen")
 		compliance.ComplianceScore -= 25.0
 	}
 
@@ -1330,7 +1269,7 @@ func (pse *PSEAnalyzer) calculateRiskScores(analysis *PSEAnalysis) {
 	if analysis.FlashAnalysis != nil {
 		flashRisk := 0.0
 		if analysis.FlashAnalysis.ExceedsThreshold {
-			flashRisk = math.Min(analysis.FlashAnalysis.FlashRate*10, 50.0)
+			flashRisk = math.Min(analysis.FlashAnalysis.MaxFlashRate*10, 50.0)
 		}
 		riskFactors = append(riskFactors, flashRisk)
 		analysis.FlashRiskLevel = pse.scoreToRiskLevel(flashRisk)
@@ -1340,7 +1279,7 @@ func (pse *PSEAnalyzer) calculateRiskScores(analysis *PSEAnalysis) {
 	if analysis.RedFlashAnalysis != nil {
 		redFlashRisk := 0.0
 		if analysis.RedFlashAnalysis.ExceedsRedThreshold {
-			redFlashRisk = math.Min(analysis.RedFlashAnalysis.RedFlashRate*15, 75.0)
+			redFlashRisk = math.Min(analysis.RedFlashAnalysis.MaxRedFlashRate*15, 75.0)
 		}
 		riskFactors = append(riskFactors, redFlashRisk)
 		analysis.RedFlashRiskLevel = pse.scoreToRiskLevel(redFlashRisk)
@@ -1349,8 +1288,8 @@ func (pse *PSEAnalyzer) calculateRiskScores(analysis *PSEAnalysis) {
 	// Pattern risk
 	if analysis.PatternAnalysis != nil {
 		patternRisk := 0.0
-		if analysis.PatternAnalysis.ExceedsPatternThreshold {
-			patternRisk = math.Min(analysis.PatternAnalysis.PatternFrequency, 40.0)
+		for _, pattern := range analysis.PatternAnalysis.HighRiskPatterns {
+			patternRisk = math.Max(patternRisk, pattern.RiskScore)
 		}
 		riskFactors = append(riskFactors, patternRisk)
 		analysis.PatternRiskLevel = pse.scoreToRiskLevel(patternRisk)
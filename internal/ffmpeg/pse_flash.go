@@ -0,0 +1,511 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os/exec"
+	"strings"
+)
+
+// Flash measurement per ITU-R BT.1702. Every frame of the first video
+// stream is decoded at a reduced resolution, averaged into a coarser grid,
+// and each grid cell is followed through its luminance and saturated red
+// runs. A transition is a run of at least 20 cd/m² (or 20 red units) on a
+// quarter of the screen at once, a flash is a pair of opposing transitions,
+// and more than three flashes in any one second window is hazardous.
+const (
+	// Frames are decoded at pseFrameWidth x pseFrameHeight, which pattern
+	// detection reads, and flashes are measured on cells of pseGridScale
+	// square pixels
+	pseFrameWidth  = 320
+	pseFrameHeight = 180
+	pseGridScale   = 5
+	pseGridWidth   = pseFrameWidth / pseGridScale
+	pseGridHeight  = pseFrameHeight / pseGridScale
+
+	// pseDisplayWhite is the luminance of peak white on the reference
+	// display, cd/m²
+	pseDisplayWhite = 200.0
+
+	// pseFlashChange is the luminance change of a transition, cd/m²
+	pseFlashChange = 20.0
+
+	// pseDarkLimit is the luminance the darker image of a flash must be
+	// below, cd/m²
+	pseDarkLimit = 160.0
+
+	// pseRedRatio is the R/(R+G+B) at which a pixel is saturated red, and
+	// pseRedScale scales its R-G-B so that a change of 20 is a red transition
+	pseRedRatio = 0.8
+	pseRedScale = 320.0
+
+	// pseFlashArea is the part of the screen that has to change at once for
+	// a transition to count
+	pseFlashArea = 0.25
+
+	// pseNoiseFloor is the largest change against the direction of a run
+	// that does not end it, so grain does not split transitions
+	pseNoiseFloor = 2.0
+
+	// pseMaxEvents caps the transitions listed per hazard
+	pseMaxEvents = 100
+
+	// pseMeasureProgress is the share of PhasePSE progress the frame
+	// measurement reports; the analysis of the measurement takes the rest
+	pseMeasureProgress = 90.0
+)
+
+// srgbLinear maps 8-bit sRGB values to linear light
+var srgbLinear = func() (table [256]float64) {
+	for i := range table {
+		v := float64(i) / 255
+		if v <= 0.04045 {
+			table[i] = v / 12.92
+		} else {
+			table[i] = math.Pow((v+0.055)/1.055, 2.4)
+		}
+	}
+	return table
+}()
+
+// pseTransition is a luminance or red change that covered enough of the
+// screen to count towards a flash
+type pseTransition struct {
+	Frame     int
+	Timestamp float64
+	Direction int     // 1 brighter or redder, -1 darker or less red
+	From      float64 // Mean value over the changing area before the run
+	To        float64 // Mean value over the changing area at its extreme
+	Area      float64 // 0-1
+	Duration  float64 // Mean length of the runs, seconds
+}
+
+// change is the size of the transition
+func (t pseTransition) change() float64 {
+	return math.Abs(t.To - t.From)
+}
+
+// pseTracker follows the runs of every pixel of one measure, luminance or
+// red, and records a transition whenever a quarter of the screen is in a
+// large enough run in the direction opposite to the last transition
+type pseTracker struct {
+	threshold float64
+	darkLimit float64 // +Inf when the darker image is not limited
+	frameRate float64
+
+	direction []int8
+	base      []float64 // Value where the current run started
+	peak      []float64 // Extreme value of the current run
+	baseFrame []int
+	peakFrame []int
+
+	lastDirection int
+	transitions   []pseTransition
+}
+
+func newPSETracker(threshold, darkLimit, frameRate float64) *pseTracker {
+	return &pseTracker{threshold: threshold, darkLimit: darkLimit, frameRate: frameRate}
+}
+
+// pseArea sums the runs of the pixels changing in one direction
+type pseArea struct {
+	pixels   int
+	from, to float64
+	frames   int
+}
+
+func (a *pseArea) add(from, to float64, frames int) {
+	a.pixels++
+	a.from += from
+	a.to += to
+	a.frames += frames
+}
+
+// add feeds the per-pixel values of a frame
+func (t *pseTracker) add(frame int, values []float64) {
+	if t.base == nil {
+		n := len(values)
+		t.direction = make([]int8, n)
+		t.base = append([]float64(nil), values...)
+		t.peak = append([]float64(nil), values...)
+		t.baseFrame = make([]int, n)
+		t.peakFrame = make([]int, n)
+		for i := range t.baseFrame {
+			t.baseFrame[i], t.peakFrame[i] = frame, frame
+		}
+		return
+	}
+
+	var up, down pseArea
+	for i, v := range values {
+		switch {
+		case t.direction[i] >= 0 && v >= t.peak[i], t.direction[i] <= 0 && v <= t.peak[i]:
+			// The run continues, or a flat pixel starts one once it moves
+			// past the noise floor
+			if t.direction[i] == 0 && math.Abs(v-t.base[i]) <= pseNoiseFloor {
+				break
+			}
+			if t.direction[i] == 0 {
+				t.direction[i] = 1
+				if v < t.base[i] {
+					t.direction[i] = -1
+				}
+			}
+			t.peak[i], t.peakFrame[i] = v, frame
+		case math.Abs(v-t.peak[i]) > pseNoiseFloor:
+			// The pixel turned: a new run starts from the old extreme
+			t.direction[i] = -t.direction[i]
+			t.base[i], t.baseFrame[i] = t.peak[i], t.peakFrame[i]
+			t.peak[i], t.peakFrame[i] = v, frame
+		}
+
+		from, to := t.base[i], t.peak[i]
+		if math.Abs(to-from) < t.threshold || math.Min(from, to) >= t.darkLimit {
+			continue
+		}
+		if to > from {
+			up.add(from, to, t.peakFrame[i]-t.baseFrame[i])
+		} else {
+			down.add(from, to, t.peakFrame[i]-t.baseFrame[i])
+		}
+	}
+
+	area, direction := &up, 1
+	if down.pixels > up.pixels {
+		area, direction = &down, -1
+	}
+	fraction := float64(area.pixels) / float64(len(values))
+	if fraction < pseFlashArea || direction == t.lastDirection {
+		return
+	}
+	t.lastDirection = direction
+	n := float64(area.pixels)
+	t.transitions = append(t.transitions, pseTransition{
+		Frame:     frame,
+		Timestamp: float64(frame) / t.frameRate,
+		Direction: direction,
+		From:      area.from / n,
+		To:        area.to / n,
+		Area:      fraction,
+		Duration:  float64(area.frames) / n / t.frameRate,
+	})
+}
+
+// pseMeasurement accumulates the luminance and red transitions of every
+// decoded frame, and the patterns of sampled ones
+type pseMeasurement struct {
+	FrameRate float64
+	Frames    int
+	Luminance *pseTracker
+	Red       *pseTracker
+	Patterns  *psePatternSampler
+
+	// Mean frame luminance statistics, cd/m²
+	brightnessSum   float64
+	brightnessSumSq float64
+	brightnessMin   float64
+	brightnessMax   float64
+
+	luminance []float64
+	red       []float64
+	linear    []float64 // Linear R, G and B sums per cell
+}
+
+func newPSEMeasurement(frameRate float64) *pseMeasurement {
+	return &pseMeasurement{
+		FrameRate: frameRate,
+		Luminance: newPSETracker(pseFlashChange, pseDarkLimit, frameRate),
+		Red:       newPSETracker(pseFlashChange, math.Inf(1), frameRate),
+		Patterns:  newPSEPatternSampler(frameRate),
+		luminance: make([]float64, pseGridWidth*pseGridHeight),
+		red:       make([]float64, pseGridWidth*pseGridHeight),
+		linear:    make([]float64, pseGridWidth*pseGridHeight*3),
+	}
+}
+
+// addFrame averages an rgb24 frame at the decode size into grid cells in
+// linear light, converts them to luminance in cd/m² and saturated red, and
+// tracks both. Sampled frames are also checked for patterns.
+func (m *pseMeasurement) addFrame(frame []byte) {
+	clear(m.linear)
+	for y := 0; y < pseFrameHeight; y++ {
+		row := frame[y*pseFrameWidth*3:]
+		cells := m.linear[y/pseGridScale*pseGridWidth*3:]
+		for x := 0; x < pseFrameWidth; x++ {
+			cell := cells[x/pseGridScale*3:]
+			cell[0] += srgbLinear[row[3*x]]
+			cell[1] += srgbLinear[row[3*x+1]]
+			cell[2] += srgbLinear[row[3*x+2]]
+		}
+	}
+
+	var sum float64
+	const pixels = pseGridScale * pseGridScale
+	for i := range m.luminance {
+		r, g, b := m.linear[3*i]/pixels, m.linear[3*i+1]/pixels, m.linear[3*i+2]/pixels
+		m.luminance[i] = (0.2126*r + 0.7152*g + 0.0722*b) * pseDisplayWhite
+		sum += m.luminance[i]
+
+		m.red[i] = 0
+		if total := r + g + b; total > 0 && r/total >= pseRedRatio {
+			m.red[i] = (r - g - b) * pseRedScale
+		}
+	}
+
+	mean := sum / float64(len(m.luminance))
+	if m.Frames == 0 || mean < m.brightnessMin {
+		m.brightnessMin = mean
+	}
+	if m.Frames == 0 || mean > m.brightnessMax {
+		m.brightnessMax = mean
+	}
+	m.brightnessSum += mean
+	m.brightnessSumSq += mean * mean
+
+	m.Luminance.add(m.Frames, m.luminance)
+	m.Red.add(m.Frames, m.red)
+	m.Patterns.add(m.Frames, frame)
+	m.Frames++
+}
+
+// duration is the decoded length in seconds
+func (m *pseMeasurement) duration() float64 {
+	return float64(m.Frames) / m.FrameRate
+}
+
+// brightness summarizes the mean frame luminance over the decoded frames
+func (m *pseMeasurement) brightness() *BrightnessVariation {
+	if m.Frames == 0 {
+		return nil
+	}
+	n := float64(m.Frames)
+	mean := m.brightnessSum / n
+	stdDev := math.Sqrt(math.Max(0, m.brightnessSumSq/n-mean*mean))
+	variation := &BrightnessVariation{
+		MeanBrightness:   mean,
+		BrightnessStdDev: stdDev,
+		BrightnessRange:  m.brightnessMax - m.brightnessMin,
+	}
+	if mean > 0 {
+		variation.VariationCoefficient = stdDev / mean
+	}
+	return variation
+}
+
+// measureFlashes decodes the whole first video stream at the decode size,
+// tracks its transitions and samples its patterns. A decode that fails
+// part way keeps the frames read until then.
+func (pse *PSEAnalyzer) measureFlashes(ctx context.Context, filePath string, videoInfo *VideoInfo) (*pseMeasurement, error) {
+	frameRate := videoInfo.FrameRate
+	if frameRate <= 0 {
+		frameRate = 25
+	}
+	measurement := newPSEMeasurement(frameRate)
+
+	// Progress is reported in whole percent steps of the expected frames
+	expected := videoInfo.Duration * frameRate
+	reported := 0
+	err := pse.decodeFrames(ctx, filePath, pseFrameWidth*pseFrameHeight*3, func(frame []byte) {
+		measurement.addFrame(frame)
+		if expected <= 0 {
+			return
+		}
+		if percent := int(math.Min(float64(measurement.Frames)/expected, 1) * pseMeasureProgress); percent > reported {
+			reported = percent
+			reportContextPhase(ctx, PhasePSE, float64(percent))
+		}
+	})
+	if err != nil {
+		if measurement.Frames == 0 || ctx.Err() != nil {
+			return nil, err
+		}
+		pse.logger.Warn().Err(err).Int("frames", measurement.Frames).Msg("Flash measurement stopped early")
+	}
+	if measurement.Frames < 2 {
+		return nil, fmt.Errorf("too few frames decoded for flash measurement")
+	}
+	return measurement, nil
+}
+
+// decodeFrames streams the first video stream as rgb24 frames of
+// frameSize bytes at the decode size to fn
+func (pse *PSEAnalyzer) decodeFrames(ctx context.Context, filePath string, frameSize int, fn func([]byte)) error {
+	decodeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(decodeCtx, pse.ffmpegPath,
+		"-hide_banner", "-nostdin", "-loglevel", "error",
+		"-i", filePath,
+		"-map", "0:v:0",
+		"-vf", fmt.Sprintf("scale=%d:%d:flags=area", pseFrameWidth, pseFrameHeight),
+		"-f", "rawvideo",
+		"-pix_fmt", "rgb24",
+		"-",
+	)
+	stderr := &limitedBuffer{limit: maxStreamStderr}
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	release, err := AcquireProcess(decodeCtx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	span := startCommandSpan(decodeCtx, cmd)
+	if err := cmd.Start(); err != nil {
+		endCommandSpan(span, err)
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	frame := make([]byte, frameSize)
+	for {
+		// A partial last frame is dropped
+		if _, err := io.ReadFull(stdout, frame); err != nil {
+			break
+		}
+		fn(frame)
+	}
+	waitErr := cmd.Wait()
+
+	var decodeErr error
+	switch {
+	case ctx.Err() != nil:
+		decodeErr = ctx.Err()
+	case waitErr != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			decodeErr = fmt.Errorf("ffmpeg decode failed: %w: %s", waitErr, msg)
+		} else {
+			decodeErr = fmt.Errorf("ffmpeg decode failed: %w", waitErr)
+		}
+	}
+	endCommandSpan(span, decodeErr)
+	return decodeErr
+}
+
+// pairFlashes pairs consecutive opposing transitions into flashes, each
+// timed at the transition that completes it. scale normalizes the larger
+// change of the pair to an intensity of 0-1.
+func pairFlashes(transitions []pseTransition, scale float64) []FlashEvent {
+	flashes := make([]FlashEvent, 0, len(transitions)/2)
+	for i := 1; i < len(transitions); i += 2 {
+		second := transitions[i]
+		flashes = append(flashes, FlashEvent{
+			FrameNumber: second.Frame,
+			Timestamp:   second.Timestamp,
+			Intensity:   math.Min(1, math.Max(transitions[i-1].change(), second.change())/scale),
+		})
+	}
+	return flashes
+}
+
+// maxFlashRate is the most flashes, half the transitions, in any one
+// second window
+func (t *pseTracker) maxFlashRate() float64 {
+	most := 0
+	for i, end := 0, 0; i < len(t.transitions); i++ {
+		end = t.windowEnd(i, end)
+		most = max(most, end-i)
+	}
+	return float64(most) / 2
+}
+
+// windowEnd returns the index after the last transition in the one second
+// window opened by transition i, searching from end. Frames are compared
+// rather than timestamps so rounding cannot move a transition across the
+// window edge.
+func (t *pseTracker) windowEnd(i, end int) int {
+	window := AnalysisWindowSize * t.frameRate
+	for end < len(t.transitions) && float64(t.transitions[end].Frame-t.transitions[i].Frame) < window {
+		end++
+	}
+	return end
+}
+
+// psePeriod is a run of overlapping one second windows that each hold more
+// than three flashes
+type psePeriod struct {
+	TimePeriod
+	Transitions int     // Transitions in the period
+	PeakRate    float64 // Most flashes in one of its windows
+	Area        float64 // Largest transition area, 0-1
+}
+
+// hazardousPeriods merges the one second windows with more flashes than
+// MaxSafeFlashRate into periods
+func (t *pseTracker) hazardousPeriods() []psePeriod {
+	var periods []psePeriod
+	transitions := t.transitions
+	limit := int(2 * MaxSafeFlashRate)
+	first := -1 // First transition of the open period
+	last := -1  // Last transition of the open period
+	var peak int
+
+	closePeriod := func() {
+		if first < 0 {
+			return
+		}
+		period := psePeriod{
+			TimePeriod: TimePeriod{
+				StartTime: transitions[first].Timestamp,
+				EndTime:   transitions[last].Timestamp,
+				Duration:  transitions[last].Timestamp - transitions[first].Timestamp,
+			},
+			Transitions: last - first + 1,
+			PeakRate:    float64(peak) / 2,
+		}
+		period.Area = maxTransitionArea(transitions[first : last+1])
+		periods = append(periods, period)
+		first, last, peak = -1, -1, 0
+	}
+
+	for i, end := 0, 0; i < len(transitions); i++ {
+		end = t.windowEnd(i, end)
+		count := end - i
+		if count <= limit {
+			continue
+		}
+		if first >= 0 && i > last {
+			closePeriod()
+		}
+		if first < 0 {
+			first = i
+		}
+		last = max(last, end-1)
+		peak = max(peak, count)
+	}
+	closePeriod()
+	return periods
+}
+
+// flashViolations turns hazardous periods into violations of violationType
+func (pse *PSEAnalyzer) flashViolations(periods []psePeriod, violationType, label string, standards []string) []PSEViolation {
+	violations := make([]PSEViolation, 0, len(periods))
+	for _, period := range periods {
+		violations = append(violations, PSEViolation{
+			Timestamp:           period.StartTime,
+			ViolationType:       violationType,
+			Severity:            pse.determineSeverity(period.PeakRate),
+			Description:         fmt.Sprintf("%s rate of %.1f Hz over %.0f%% of the screen (exceeds %.0f Hz threshold)", label, period.PeakRate, period.Area*100, MaxSafeFlashRate),
+			AffectedArea:        period.Area,
+			Duration:            period.Duration,
+			RiskScore:           math.Min(100, period.PeakRate*20),
+			ComplianceStandards: standards,
+		})
+	}
+	return violations
+}
+
+// maxTransitionArea is the largest screen area of any transition
+func maxTransitionArea(transitions []pseTransition) float64 {
+	area := 0.0
+	for _, t := range transitions {
+		area = math.Max(area, t.Area)
+	}
+	return area
+}
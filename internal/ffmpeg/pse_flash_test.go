@@ -0,0 +1,253 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	pseBlack = [3]uint8{0, 0, 0}
+	pseWhite = [3]uint8{255, 255, 255}
+	pseRed   = [3]uint8{255, 0, 0}
+	// pseGray has about the luminance of pseRed
+	pseGray = [3]uint8{127, 127, 127}
+)
+
+// pseFrame returns a decode sized rgb24 frame with the top grid rows in one
+// color and the rest in another
+func pseFrame(rows int, top, rest [3]uint8) []byte {
+	frame := make([]byte, pseFrameWidth*pseFrameHeight*3)
+	for i := 0; i < pseFrameWidth*pseFrameHeight; i++ {
+		color := rest
+		if i < rows*pseGridScale*pseFrameWidth {
+			color = top
+		}
+		copy(frame[3*i:], color[:])
+	}
+	return frame
+}
+
+// pseFill returns a decode sized rgb24 frame in one color
+func pseFill(color [3]uint8) []byte {
+	return pseFrame(0, color, color)
+}
+
+// measurePSE feeds frames at 25 fps, switching between a and b every hold
+// frames while flashing(frame) is true and showing a otherwise
+func measurePSE(frames, hold int, a, b []byte, flashing func(frame int) bool) *pseMeasurement {
+	m := newPSEMeasurement(25)
+	for i := 0; i < frames; i++ {
+		frame := a
+		if flashing(i) && (i/hold)%2 == 1 {
+			frame = b
+		}
+		m.addFrame(frame)
+	}
+	return m
+}
+
+func always(int) bool { return true }
+
+func TestPSEMeasurement(t *testing.T) {
+	black := pseFill(pseBlack)
+	white := pseFill(pseWhite)
+
+	tests := []struct {
+		name           string
+		m              *pseMeasurement
+		wantRate       float64
+		wantRedRate    float64
+		wantPeriods    int
+		wantRedPeriods int
+	}{
+		{
+			name:        "full screen strobe",
+			m:           measurePSE(50, 1, black, white, always),
+			wantRate:    12.5,
+			wantPeriods: 1,
+		},
+		{
+			name: "two bursts",
+			m: measurePSE(100, 2, black, white, func(frame int) bool {
+				return frame < 25 || frame >= 75
+			}),
+			wantRate:    6.5,
+			wantPeriods: 2,
+		},
+		{
+			name:     "slow flashing",
+			m:        measurePSE(100, 5, black, white, always),
+			wantRate: 2.5,
+		},
+		{
+			name: "small area",
+			m:    measurePSE(50, 1, black, pseFrame(8, pseWhite, pseBlack), always),
+		},
+		{
+			name: "bright flicker",
+			m:    measurePSE(50, 1, pseFill([3]uint8{237, 237, 237}), white, always),
+		},
+		{
+			name:           "red against gray",
+			m:              measurePSE(50, 1, pseFill(pseGray), pseFill(pseRed), always),
+			wantRedRate:    12.5,
+			wantRedPeriods: 1,
+		},
+		{
+			name:           "red quarter",
+			m:              measurePSE(50, 2, black, pseFrame(9, pseRed, pseBlack), always),
+			wantRate:       6.5,
+			wantRedRate:    6.5,
+			wantPeriods:    1,
+			wantRedPeriods: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.Luminance.maxFlashRate(); got != tt.wantRate {
+				t.Errorf("max flash rate = %v, want %v", got, tt.wantRate)
+			}
+			if got := tt.m.Red.maxFlashRate(); got != tt.wantRedRate {
+				t.Errorf("max red flash rate = %v, want %v", got, tt.wantRedRate)
+			}
+			if got := len(tt.m.Luminance.hazardousPeriods()); got != tt.wantPeriods {
+				t.Errorf("hazardous periods = %d, want %d", got, tt.wantPeriods)
+			}
+			if got := len(tt.m.Red.hazardousPeriods()); got != tt.wantRedPeriods {
+				t.Errorf("red hazardous periods = %d, want %d", got, tt.wantRedPeriods)
+			}
+		})
+	}
+}
+
+func TestPSETrackerTransitions(t *testing.T) {
+	// A one second fade up and back down is two transitions, one flash
+	m := newPSEMeasurement(25)
+	for i := 0; i <= 50; i++ {
+		level := i
+		if i > 25 {
+			level = 50 - i
+		}
+		gray := uint8(level * 255 / 25)
+		m.addFrame(pseFill([3]uint8{gray, gray, gray}))
+	}
+
+	transitions := m.Luminance.transitions
+	if len(transitions) != 2 || transitions[0].Direction != 1 || transitions[1].Direction != -1 {
+		t.Fatalf("transitions = %+v, want one up and one down", transitions)
+	}
+	up := transitions[0]
+	if up.Area != 1 || up.From != 0 || up.change() < pseFlashChange || up.Duration <= 0 {
+		t.Errorf("up transition = %+v", up)
+	}
+	if flashes := pairFlashes(transitions, pseDisplayWhite); len(flashes) != 1 || flashes[0].Timestamp != transitions[1].Timestamp {
+		t.Errorf("flashes = %+v, want one at the second transition", flashes)
+	}
+	if brightness := m.brightness(); brightness.BrightnessRange < 199 || brightness.MeanBrightness <= 0 {
+		t.Errorf("brightness = %+v", brightness)
+	}
+}
+
+func TestPSEAnalyzer_FlashViolations(t *testing.T) {
+	pse := NewPSEAnalyzer("ffprobe", zerolog.Nop())
+	m := measurePSE(75, 1, pseFill(pseGray), pseFill(pseRed), func(frame int) bool {
+		return frame >= 25
+	})
+
+	analysis := &PSEAnalysis{}
+	if err := pse.analyzeFlashPatterns(context.Background(), "", &VideoInfo{FrameRate: 25, Duration: 3}, m, analysis); err != nil {
+		t.Fatal(err)
+	}
+	if err := pse.analyzeRedFlashPatterns(m, analysis); err != nil {
+		t.Fatal(err)
+	}
+
+	if analysis.FlashAnalysis.ExceedsThreshold || analysis.FlashAnalysis.FlashCount != 0 {
+		t.Errorf("flash analysis = %+v, want no luminance flashes", analysis.FlashAnalysis)
+	}
+	red := analysis.RedFlashAnalysis
+	if !red.ExceedsRedThreshold || red.MaxRedFlashRate != 12.5 || red.RedFlashCount != 25 {
+		t.Fatalf("red flash analysis = %+v", red)
+	}
+	if len(red.CriticalRedPeriods) != 1 || red.CriticalRedPeriods[0].StartTime != 1 {
+		t.Errorf("critical red periods = %+v", red.CriticalRedPeriods)
+	}
+	if len(red.RedFlashArea) != 50 || red.RedFlashArea[0].AreaPercentage != 100 {
+		t.Errorf("red flash areas = %d, first %+v", len(red.RedFlashArea), red.RedFlashArea)
+	}
+
+	if len(analysis.ViolationInstances) != 1 {
+		t.Fatalf("violations = %+v", analysis.ViolationInstances)
+	}
+	violation := analysis.ViolationInstances[0]
+	if violation.ViolationType != "red_flash" || violation.Severity != "high" || violation.AffectedArea != 1 {
+		t.Errorf("violation = %+v", violation)
+	}
+
+	if err := pse.analyzeRedFlashPatterns(nil, analysis); err == nil || analysis.RedFlashRiskLevel != "unknown" {
+		t.Errorf("unmeasured red flashes: err = %v, level = %q", err, analysis.RedFlashRiskLevel)
+	}
+}
+
+// writeFakeFFmpeg writes an ffmpeg stand-in that prints frames black
+// decode sized frames and then runs tail
+func writeFakeFFmpeg(t *testing.T, frames int, tail string) string {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "ffmpeg")
+	body := fmt.Sprintf("#!/bin/sh\nhead -c %d /dev/zero\n%s\n", frames*pseFrameWidth*pseFrameHeight*3, tail)
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+func TestPSEAnalyzer_MeasureFlashesProgress(t *testing.T) {
+	pse := NewPSEAnalyzer("ffprobe", zerolog.Nop())
+	pse.ffmpegPath = writeFakeFFmpeg(t, 10, "exit 0")
+
+	var percents []float64
+	ctx := withPhaseFunc(t.Context(), func(phase string, percent float64) {
+		if phase != PhasePSE {
+			t.Errorf("phase = %q, want %q", phase, PhasePSE)
+		}
+		percents = append(percents, percent)
+	})
+	m, err := pse.measureFlashes(ctx, "input.mp4", &VideoInfo{FrameRate: 25, Duration: 0.4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Frames != 10 || len(m.Luminance.transitions) != 0 {
+		t.Errorf("frames = %d, transitions = %d", m.Frames, len(m.Luminance.transitions))
+	}
+	if len(percents) != 10 || percents[0] != 9 || percents[9] != pseMeasureProgress {
+		t.Errorf("progress = %v, want 9 to %v in ten steps", percents, pseMeasureProgress)
+	}
+}
+
+func TestPSEAnalyzer_MeasureFlashesCancelled(t *testing.T) {
+	pse := NewPSEAnalyzer("ffprobe", zerolog.Nop())
+	pse.ffmpegPath = writeFakeFFmpeg(t, 1, "exec sleep 30")
+
+	// Cancel as soon as the first frame is measured, as the batch cancel
+	// endpoint does to a running item
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+	ctx = withPhaseFunc(ctx, func(string, float64) { cancel() })
+
+	start := time.Now()
+	_, err := pse.measureFlashes(ctx, "input.mp4", &VideoInfo{FrameRate: 25, Duration: 0.4})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("cancelled measurement took %v", elapsed)
+	}
+}
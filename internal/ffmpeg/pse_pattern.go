@@ -0,0 +1,368 @@
+package ffmpeg
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// Pattern detection. Twice a second a decoded frame is cut into blocks of
+// psePatternBlock pixels whose luminance spectrum is searched for a
+// dominant spatial frequency (stripes) or two at right angles
+// (checkerboard). Regular patterns with more than five light-dark pairs on
+// a quarter of the screen are the hazard Ofcom and ITU-R BT.1702 describe.
+const (
+	psePatternBlock = 32
+
+	// psePatternTop skips the rows above the blocks so they sit in the
+	// middle of the frame
+	psePatternTop     = (pseFrameHeight % psePatternBlock) / 2
+	psePatternColumns = pseFrameWidth / psePatternBlock
+	psePatternRows    = pseFrameHeight / psePatternBlock
+
+	// psePatternSampleRate is how many frames a second are checked
+	psePatternSampleRate = 2.0
+
+	// psePatternMinCycles is the lowest frequency a block can repeat,
+	// cycles per block; below it is shading rather than a pattern
+	psePatternMinCycles = 2.0
+
+	// psePatternPeakShare is the share of a block's AC energy its peaks
+	// must hold for the block to be patterned
+	psePatternPeakShare = 0.5
+
+	// psePatternMaxPairs is the most light-dark pairs a pattern may show
+	psePatternMaxPairs = 5.0
+)
+
+// Pattern types reported in PatternInstance and HighRiskPattern
+const (
+	psePatternStriped      = "striped"
+	psePatternCheckerboard = "checkerboard"
+)
+
+// psePixelsPerDegree converts cycles per pixel of the decoded frame to
+// cycles per degree at the assumed viewing distance
+var psePixelsPerDegree = pseFrameHeight / (2 * math.Atan(0.5/ViewingDistanceAssumption) * 180 / math.Pi)
+
+// psePatternSample is a sampled frame whose patterns are hazardous
+type psePatternSample struct {
+	Frame     int
+	Timestamp float64
+	Type      string
+	Frequency float64 // cycles per degree
+	Pairs     float64
+	Contrast  float64 // Michelson, 0-1
+	Coverage  float64 // 0-1
+}
+
+// psePatternSampler checks every interval-th frame for patterns and keeps
+// the hazardous samples
+type psePatternSampler struct {
+	FrameRate float64
+	Interval  int
+	Samples   int
+	Risky     []psePatternSample
+
+	luminance []float64
+	block     []complex128
+	column    []complex128
+	power     []float64
+	mask      []bool
+}
+
+func newPSEPatternSampler(frameRate float64) *psePatternSampler {
+	return &psePatternSampler{
+		FrameRate: frameRate,
+		Interval:  max(1, int(math.Round(frameRate/psePatternSampleRate))),
+		luminance: make([]float64, pseFrameWidth*pseFrameHeight),
+		block:     make([]complex128, psePatternBlock*psePatternBlock),
+		column:    make([]complex128, psePatternBlock),
+		power:     make([]float64, psePatternBlock*psePatternBlock),
+		mask:      make([]bool, psePatternBlock*psePatternBlock),
+	}
+}
+
+// blockPattern is the pattern found in one block
+type blockPattern struct {
+	checkerboard bool
+	fx, fy       float64 // dominant frequency, cycles per block
+	contrast     float64
+}
+
+// add checks an rgb24 frame at the decode size when it is due for a sample
+func (s *psePatternSampler) add(frame int, data []byte) {
+	if frame%s.Interval != 0 {
+		return
+	}
+	s.Samples++
+
+	for i := range s.luminance {
+		r, g, b := srgbLinear[data[3*i]], srgbLinear[data[3*i+1]], srgbLinear[data[3*i+2]]
+		s.luminance[i] = (0.2126*r + 0.7152*g + 0.0722*b) * pseDisplayWhite
+	}
+
+	var (
+		found            int
+		checkerboards    int
+		fx, fy, contrast float64
+		left, top        = psePatternColumns, psePatternRows
+		right, bottom    = -1, -1
+	)
+	for by := 0; by < psePatternRows; by++ {
+		for bx := 0; bx < psePatternColumns; bx++ {
+			p, ok := s.blockPattern(bx, by)
+			if !ok {
+				continue
+			}
+			found++
+			if p.checkerboard {
+				checkerboards++
+			}
+			fx += math.Abs(p.fx)
+			fy += math.Abs(p.fy)
+			contrast += p.contrast
+			left, right = min(left, bx), max(right, bx)
+			top, bottom = min(top, by), max(bottom, by)
+		}
+	}
+	if found == 0 {
+		return
+	}
+
+	n := float64(found)
+	fx, fy, contrast = fx/n, fy/n, contrast/n
+	coverage := n / (psePatternColumns * psePatternRows)
+
+	// Pairs are counted across the bounding box of the patterned blocks
+	// along the direction the pattern repeats
+	width := float64((right - left + 1) * psePatternBlock)
+	height := float64((bottom - top + 1) * psePatternBlock)
+	pairs := fx/psePatternBlock*width + fy/psePatternBlock*height
+
+	if coverage < CriticalPatternArea || pairs <= psePatternMaxPairs || contrast < MinPatternContrast {
+		return
+	}
+
+	patternType := psePatternStriped
+	if 2*checkerboards > found {
+		patternType = psePatternCheckerboard
+	}
+	s.Risky = append(s.Risky, psePatternSample{
+		Frame:     frame,
+		Timestamp: float64(frame) / s.FrameRate,
+		Type:      patternType,
+		Frequency: math.Hypot(fx, fy) / psePatternBlock * psePixelsPerDegree,
+		Pairs:     pairs,
+		Contrast:  contrast,
+		Coverage:  coverage,
+	})
+}
+
+// blockPattern looks for a regular pattern in block bx, by. The dark
+// parts of a pattern must be below pseDarkLimit and the light ones at
+// least pseFlashChange brighter.
+func (s *psePatternSampler) blockPattern(bx, by int) (blockPattern, bool) {
+	const n = psePatternBlock
+	x0, y0 := bx*n, psePatternTop+by*n
+
+	lo, hi, mean := math.Inf(1), math.Inf(-1), 0.0
+	for y := 0; y < n; y++ {
+		for _, v := range s.luminance[(y0+y)*pseFrameWidth+x0:][:n] {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+			mean += v
+		}
+	}
+	if hi-lo < pseFlashChange || lo >= pseDarkLimit {
+		return blockPattern{}, false
+	}
+	mean /= n * n
+
+	for y := 0; y < n; y++ {
+		for x, v := range s.luminance[(y0+y)*pseFrameWidth+x0:][:n] {
+			s.block[y*n+x] = complex(v-mean, 0)
+		}
+	}
+	s.fft2(s.block)
+
+	var ac float64
+	power := s.power
+	for i, c := range s.block {
+		power[i] = real(c)*real(c) + imag(c)*imag(c)
+		ac += power[i]
+	}
+	if ac == 0 {
+		return blockPattern{}, false
+	}
+
+	clear(s.mask)
+	first, ok := peakBin(power, s.mask, -1)
+	if !ok {
+		return blockPattern{}, false
+	}
+	share := markPeak(power, s.mask, first) / ac
+	p := blockPattern{contrast: (hi - lo) / (hi + lo)}
+	p.fx, p.fy = binFrequency(first)
+
+	// A checkerboard has a second peak of about the same energy at right
+	// angles to the first
+	if second, ok := peakBin(power, s.mask, first); ok {
+		if secondShare := markPeak(power, s.mask, second) / ac; secondShare >= share/2 {
+			p.checkerboard = true
+			share += secondShare
+		}
+	}
+	return p, share >= psePatternPeakShare
+}
+
+// binFrequency returns the signed horizontal and vertical frequency of a
+// spectrum bin, cycles per block
+func binFrequency(bin int) (float64, float64) {
+	const n = psePatternBlock
+	fx, fy := bin%n, bin/n
+	if fx >= n/2 {
+		fx -= n
+	}
+	if fy >= n/2 {
+		fy -= n
+	}
+	return float64(fx), float64(fy)
+}
+
+// peakBin returns the strongest unmasked bin between psePatternMinCycles
+// and the Nyquist frequency. When across is a bin, only bins oriented 60
+// to 120 degrees from it are considered.
+func peakBin(power []float64, mask []bool, across int) (int, bool) {
+	const n = psePatternBlock
+	var ax, ay float64
+	if across >= 0 {
+		ax, ay = binFrequency(across)
+	}
+	peak, best := -1, 0.0
+	for i, p := range power {
+		if mask[i] || p <= best {
+			continue
+		}
+		fx, fy := binFrequency(i)
+		if math.Abs(fx) >= n/2 || math.Abs(fy) >= n/2 || math.Hypot(fx, fy) < psePatternMinCycles {
+			continue
+		}
+		if across >= 0 {
+			cos := math.Abs(fx*ax+fy*ay) / (math.Hypot(fx, fy) * math.Hypot(ax, ay))
+			if cos > 0.5 {
+				continue
+			}
+		}
+		peak, best = i, p
+	}
+	return peak, peak >= 0
+}
+
+// markPeak masks the 3x3 neighbourhoods of a bin and its conjugate, which
+// hold the energy of a frequency between bins, and returns the energy
+// newly masked
+func markPeak(power []float64, mask []bool, bin int) float64 {
+	const n = psePatternBlock
+	x, y := bin%n, bin/n
+	energy := 0.0
+	for _, c := range [2][2]int{{x, y}, {(n - x) % n, (n - y) % n}} {
+		for dy := -1; dy <= 1; dy++ {
+			for dx := -1; dx <= 1; dx++ {
+				i := (c[1]+dy+n)%n*n + (c[0]+dx+n)%n
+				if !mask[i] {
+					mask[i] = true
+					energy += power[i]
+				}
+			}
+		}
+	}
+	return energy
+}
+
+// fft2 transforms a psePatternBlock square block in place, rows then
+// columns
+func (s *psePatternSampler) fft2(block []complex128) {
+	const n = psePatternBlock
+	for y := 0; y < n; y++ {
+		fft(block[y*n : (y+1)*n])
+	}
+	for x := 0; x < n; x++ {
+		for y := 0; y < n; y++ {
+			s.column[y] = block[y*n+x]
+		}
+		fft(s.column)
+		for y := 0; y < n; y++ {
+			block[y*n+x] = s.column[y]
+		}
+	}
+}
+
+// fft is an in place radix-2 transform of a power of two length
+func fft(a []complex128) {
+	n := len(a)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j |= bit
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				u, v := a[start+k], a[start+k+size/2]*w
+				a[start+k], a[start+k+size/2] = u+v, u-v
+				w *= step
+			}
+		}
+	}
+}
+
+// count returns the hazardous samples from start up to end, in seconds
+func (s *psePatternSampler) count(start, end float64) int {
+	n := 0
+	for _, sample := range s.Risky {
+		if sample.Timestamp >= start && sample.Timestamp < end {
+			n++
+		}
+	}
+	return n
+}
+
+// segments merges consecutive hazardous samples of one type into pattern
+// instances that last until the next sample
+func (s *psePatternSampler) segments(duration float64) []PatternInstance {
+	var instances []PatternInstance
+	interval := float64(s.Interval) / s.FrameRate
+	for i, sample := range s.Risky {
+		end := math.Min(sample.Timestamp+interval, duration)
+		if i > 0 {
+			prev, last := s.Risky[i-1], &instances[len(instances)-1]
+			if sample.Frame-prev.Frame == s.Interval && sample.Type == last.PatternType {
+				last.EndTime = end
+				last.SpatialFrequency = math.Max(last.SpatialFrequency, sample.Frequency)
+				last.Contrast = math.Max(last.Contrast, sample.Contrast)
+				last.ScreenCoverage = math.Max(last.ScreenCoverage, sample.Coverage)
+				continue
+			}
+		}
+		instances = append(instances, PatternInstance{
+			StartTime:        sample.Timestamp,
+			EndTime:          end,
+			PatternType:      sample.Type,
+			SpatialFrequency: sample.Frequency,
+			Contrast:         sample.Contrast,
+			ScreenCoverage:   sample.Coverage,
+		})
+	}
+	return instances
+}
+
+// patternRisk scores a hazardous pattern by the screen it covers
+func patternRisk(coverage float64) float64 {
+	return math.Min(40, 20+20*coverage)
+}
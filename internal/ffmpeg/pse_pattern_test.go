@@ -0,0 +1,177 @@
+package ffmpeg
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// psePattern returns a decode sized rgb24 frame colored by fn
+func psePattern(fn func(x, y int) [3]uint8) []byte {
+	frame := make([]byte, pseFrameWidth*pseFrameHeight*3)
+	for y := 0; y < pseFrameHeight; y++ {
+		for x := 0; x < pseFrameWidth; x++ {
+			color := fn(x, y)
+			copy(frame[3*(y*pseFrameWidth+x):], color[:])
+		}
+	}
+	return frame
+}
+
+// stripes alternates a and b every width pixels across the frame, left of
+// edge only
+func stripes(width, edge int, a, b [3]uint8) []byte {
+	return psePattern(func(x, y int) [3]uint8 {
+		if x < edge && (x/width)%2 == 1 {
+			return b
+		}
+		return a
+	})
+}
+
+func TestFFT(t *testing.T) {
+	a := make([]complex128, 8)
+	for i := range a {
+		a[i] = complex(math.Cos(2*math.Pi*3*float64(i)/8), 0)
+	}
+	fft(a)
+	for i, c := range a {
+		want := 0.0
+		if i == 3 || i == 5 {
+			want = 4
+		}
+		if math.Abs(cmplx.Abs(c)-want) > 1e-9 {
+			t.Errorf("bin %d = %v, want magnitude %v", i, c, want)
+		}
+	}
+}
+
+func TestPSEPatternSampler(t *testing.T) {
+	tests := []struct {
+		name      string
+		frame     []byte
+		wantType  string
+		wantPairs float64
+	}{
+		{
+			name:      "vertical stripes",
+			frame:     stripes(4, pseFrameWidth, pseBlack, pseWhite),
+			wantType:  psePatternStriped,
+			wantPairs: 40,
+		},
+		{
+			name: "horizontal stripes",
+			frame: psePattern(func(x, y int) [3]uint8 {
+				if (y/4)%2 == 1 {
+					return pseWhite
+				}
+				return pseBlack
+			}),
+			wantType:  psePatternStriped,
+			wantPairs: 20,
+		},
+		{
+			name: "checkerboard",
+			frame: psePattern(func(x, y int) [3]uint8 {
+				if (x/8+y/8)%2 == 1 {
+					return pseWhite
+				}
+				return pseBlack
+			}),
+			wantType: psePatternCheckerboard,
+		},
+		{
+			name:  "small area",
+			frame: stripes(4, 64, pseBlack, pseWhite),
+		},
+		{
+			name:  "bright stripes",
+			frame: stripes(4, pseFrameWidth, [3]uint8{237, 237, 237}, pseWhite),
+		},
+		{
+			name:  "low contrast",
+			frame: stripes(4, pseFrameWidth, [3]uint8{100, 100, 100}, [3]uint8{120, 120, 120}),
+		},
+		{
+			name:  "wide bands",
+			frame: stripes(80, pseFrameWidth, pseBlack, pseWhite),
+		},
+		{
+			name:  "uniform",
+			frame: pseFill(pseGray),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newPSEPatternSampler(25)
+			s.add(0, tt.frame)
+			if tt.wantType == "" {
+				if len(s.Risky) != 0 {
+					t.Errorf("risky samples = %+v, want none", s.Risky)
+				}
+				return
+			}
+			if len(s.Risky) != 1 {
+				t.Fatalf("risky samples = %+v, want one", s.Risky)
+			}
+			sample := s.Risky[0]
+			if sample.Type != tt.wantType || sample.Coverage != 1 || sample.Contrast < 0.9 {
+				t.Errorf("sample = %+v, want a full screen %s pattern", sample, tt.wantType)
+			}
+			if tt.wantPairs > 0 && math.Abs(sample.Pairs-tt.wantPairs) > 1 {
+				t.Errorf("pairs = %.1f, want %v", sample.Pairs, tt.wantPairs)
+			}
+		})
+	}
+}
+
+func TestPSEAnalyzer_SpatialPatterns(t *testing.T) {
+	pse := NewPSEAnalyzer("ffprobe", zerolog.Nop())
+
+	// Stripes from one to three seconds of four, sampled every 12 frames
+	striped := stripes(4, pseFrameWidth, pseBlack, pseWhite)
+	plain := pseFill(pseGray)
+	m := newPSEMeasurement(24)
+	for i := 0; i < 96; i++ {
+		if i >= 24 && i < 72 {
+			m.addFrame(striped)
+		} else {
+			m.addFrame(plain)
+		}
+	}
+	if m.Patterns.Samples != 8 || len(m.Patterns.Risky) != 4 {
+		t.Fatalf("samples = %d, risky = %d", m.Patterns.Samples, len(m.Patterns.Risky))
+	}
+
+	analysis := &PSEAnalysis{}
+	if err := pse.analyzeSpatialPatterns(m, analysis); err != nil {
+		t.Fatal(err)
+	}
+	patterns := analysis.PatternAnalysis
+	if !patterns.ExceedsPatternThreshold || !patterns.HasStripedPatterns || patterns.HasCheckerboardPatterns {
+		t.Errorf("pattern analysis = %+v", patterns)
+	}
+	if patterns.PatternFrequency < 1.1 || patterns.PatternFrequency > 1.3 {
+		t.Errorf("pattern frequency = %.2f cycles/degree, want about 1.2", patterns.PatternFrequency)
+	}
+	if len(patterns.PatternInstances) != 1 {
+		t.Fatalf("pattern instances = %+v", patterns.PatternInstances)
+	}
+	instance := patterns.PatternInstances[0]
+	if instance.StartTime != 1 || instance.EndTime != 3 || instance.PatternType != psePatternStriped {
+		t.Errorf("pattern instance = %+v, want striped from 1s to 3s", instance)
+	}
+	if len(patterns.HighRiskPatterns) != 1 || patterns.HighRiskPatterns[0].RiskScore != 40 {
+		t.Errorf("high risk patterns = %+v", patterns.HighRiskPatterns)
+	}
+	if len(analysis.ViolationInstances) != 1 || analysis.ViolationInstances[0].ViolationType != "pattern" || analysis.ViolationInstances[0].Duration != 2 {
+		t.Errorf("violations = %+v", analysis.ViolationInstances)
+	}
+
+	if err := pse.analyzeSpatialPatterns(nil, analysis); err == nil || analysis.PatternRiskLevel != "unknown" {
+		t.Errorf("unmeasured patterns: err = %v, level = %q", err, analysis.PatternRiskLevel)
+	}
+}
@@ -0,0 +1,187 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// QCCategory identifies a QC analysis category that can be selected per request
+type QCCategory string
+
+// QC categories. Names match the rendiffprobe-cli `categories` command.
+const (
+	QCCategoryAFD             QCCategory = "afd"
+	QCCategoryDeadPixel       QCCategory = "dead_pixel"
+	QCCategoryPSE             QCCategory = "pse"
+	QCCategoryHDR             QCCategory = "hdr"
+	QCCategoryAudioWrapping   QCCategory = "audio_wrapping"
+	QCCategoryEndianness      QCCategory = "endianness"
+	QCCategoryCodec           QCCategory = "codec"
+	QCCategoryContainer       QCCategory = "container"
+	QCCategoryResolution      QCCategory = "resolution"
+	QCCategoryFrameRate       QCCategory = "framerate"
+	QCCategoryBitDepth        QCCategory = "bitdepth"
+	QCCategoryTimecode        QCCategory = "timecode"
+	QCCategoryMXF             QCCategory = "mxf"
+	QCCategoryIMF             QCCategory = "imf"
+	QCCategoryTransportStream QCCategory = "transport_stream"
+	QCCategoryContent         QCCategory = "content"
+	QCCategoryEnhanced        QCCategory = "enhanced"
+	QCCategoryDisposition     QCCategory = "disposition"
+	QCCategoryIntegrity       QCCategory = "integrity"
+
+	// QCCategoryLoudness runs only the EBU R128 loudness meter from content
+	// analysis, which is far cheaper than the full content category
+	QCCategoryLoudness QCCategory = "loudness"
+
+	// QCCategoryVideoLevels likewise runs only the signalstats baseband
+	// level measurement from content analysis
+	QCCategoryVideoLevels QCCategory = "video_levels"
+
+	// QCCategoryDolby runs only the Dolby bitstream metadata part of audio
+	// wrapping analysis
+	QCCategoryDolby QCCategory = "dolby"
+
+	// QCCategoryBWF runs only the Broadcast Wave bext check of audio
+	// wrapping analysis
+	QCCategoryBWF QCCategory = "bwf"
+
+	// QCCategoryAVSync estimates the lip-sync offset and drift between the
+	// first video and audio streams
+	QCCategoryAVSync QCCategory = "av_sync"
+
+	// QCCategoryTimestamps runs only the packet PTS/DTS discontinuity
+	// check from data integrity analysis
+	QCCategoryTimestamps QCCategory = "timestamps"
+
+	// QCCategoryBitrate runs only the bit rate timeline and VBV buffer
+	// simulation from codec analysis
+	QCCategoryBitrate QCCategory = "bitrate"
+
+	// QCCategoryMezzanine runs only the ProRes and DNxHD/DNxHR frame header
+	// conformance check from codec analysis
+	QCCategoryMezzanine QCCategory = "mezzanine"
+)
+
+// AllQCCategories lists the 19 top-level QC categories in documentation order
+var AllQCCategories = []QCCategory{
+	QCCategoryAFD,
+	QCCategoryDeadPixel,
+	QCCategoryPSE,
+	QCCategoryHDR,
+	QCCategoryAudioWrapping,
+	QCCategoryEndianness,
+	QCCategoryCodec,
+	QCCategoryContainer,
+	QCCategoryResolution,
+	QCCategoryFrameRate,
+	QCCategoryBitDepth,
+	QCCategoryTimecode,
+	QCCategoryMXF,
+	QCCategoryIMF,
+	QCCategoryTransportStream,
+	QCCategoryContent,
+	QCCategoryEnhanced,
+	QCCategoryDisposition,
+	QCCategoryIntegrity,
+}
+
+// PartialQCCategories lists the categories that run one part of a
+// top-level category, or a check outside of them
+var PartialQCCategories = []QCCategory{
+	QCCategoryLoudness,
+	QCCategoryVideoLevels,
+	QCCategoryDolby,
+	QCCategoryBWF,
+	QCCategoryAVSync,
+	QCCategoryTimestamps,
+	QCCategoryBitrate,
+	QCCategoryMezzanine,
+}
+
+// advancedQCCategories are the categories the default pipeline runs after
+// content analysis: every category but the content ones, plus the partial
+// checks that no top-level category includes
+var advancedQCCategories = func() []QCCategory {
+	categories := make([]QCCategory, 0, len(AllQCCategories)+1)
+	for _, category := range AllQCCategories {
+		if category != QCCategoryContent {
+			categories = append(categories, category)
+		}
+	}
+	return append(categories, QCCategoryAVSync)
+}()
+
+// qcCategoryAliases maps alternative spellings (as used in API field names) to categories
+var qcCategoryAliases = map[string]QCCategory{
+	"frame_rate":         QCCategoryFrameRate,
+	"bit_depth":          QCCategoryBitDepth,
+	"dead_pixels":        QCCategoryDeadPixel,
+	"stream_disposition": QCCategoryDisposition,
+	"data_integrity":     QCCategoryIntegrity,
+	"ts":                 QCCategoryTransportStream,
+}
+
+// ParseQCCategories parses a comma-separated category list such as
+// "hdr,loudness,timecode". An empty string selects no specific categories,
+// which means all categories run.
+func ParseQCCategories(value string) ([]QCCategory, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	return NormalizeQCCategories(strings.Split(value, ","))
+}
+
+// NormalizeQCCategories validates category names, resolves aliases and
+// removes duplicates while preserving order
+func NormalizeQCCategories(names []string) ([]QCCategory, error) {
+	seen := make(map[QCCategory]bool, len(names))
+	categories := make([]QCCategory, 0, len(names))
+
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		category, ok := lookupQCCategory(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown QC category: %s", name)
+		}
+		if !seen[category] {
+			seen[category] = true
+			categories = append(categories, category)
+		}
+	}
+
+	return categories, nil
+}
+
+func lookupQCCategory(name string) (QCCategory, bool) {
+	if alias, ok := qcCategoryAliases[name]; ok {
+		return alias, true
+	}
+	for _, categories := range [][]QCCategory{AllQCCategories, PartialQCCategories} {
+		for _, category := range categories {
+			if QCCategory(name) == category {
+				return category, true
+			}
+		}
+	}
+	return "", false
+}
+
+// qcCategorySet is a lookup set for selected categories
+type qcCategorySet map[QCCategory]bool
+
+func newQCCategorySet(categories []QCCategory) qcCategorySet {
+	set := make(qcCategorySet, len(categories))
+	for _, category := range categories {
+		set[category] = true
+	}
+	return set
+}
+
+func (s qcCategorySet) has(category QCCategory) bool {
+	return s[category]
+}
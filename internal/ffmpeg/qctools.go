@@ -0,0 +1,108 @@
+package ffmpeg
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// MaxQCToolsReportSize limits a compressed QCTools report. An hour of HD
+// video compresses to roughly 50 MB.
+const MaxQCToolsReportSize = 256 * 1024 * 1024
+
+// QCToolsReportExtension is appended to the media file name, as QCTools
+// expects of the reports it opens next to their media
+const QCToolsReportExtension = ".qctools.xml.gz"
+
+// ErrNoQCToolsVideo is returned for files QCTools has nothing to graph for
+var ErrNoQCToolsVideo = errors.New("QCTools reports need a video stream")
+
+// QCToolsReporter writes QCTools reports: the frame-level signalstats,
+// cropdetect, interlacing, field PSNR/SSIM and audio loudness data QCTools
+// and QCView graph, as gzipped ffprobe XML
+type QCToolsReporter struct {
+	ffprobePath string
+	logger      zerolog.Logger
+}
+
+// NewQCToolsReporter creates a new QCTools report writer
+func NewQCToolsReporter(ffprobePath string, logger zerolog.Logger) *QCToolsReporter {
+	if ffprobePath == "" {
+		ffprobePath = "ffprobe"
+	}
+	return &QCToolsReporter{
+		ffprobePath: ffprobePath,
+		logger:      logger,
+	}
+}
+
+// qctoolsFilterGraph is the lavfi graph of a QCTools report, matching the
+// one the qcli tool of QCTools runs. The audio branch is left out for files
+// without audio.
+func qctoolsFilterGraph(filePath string, audio bool) string {
+	video := "signalstats=stat=tout+vrep+brng,cropdetect=reset=1:round=1,idet=half_life=1,split[a][b];" +
+		"[a]field=top[a1];[b]field=bottom,split[b1][b2];[a1][b1]psnr[c1];[c1][b2]ssim[out0]"
+	path := escapeFFmpegFilterPath(filePath)
+	if !audio {
+		return fmt.Sprintf("movie=%s:s=dv[in0];[in0]%s", path, video)
+	}
+	return fmt.Sprintf("movie=%s:s=dv+da[in0][in1];[in0]%s;[in1]ebur128=metadata=1,astats=metadata=1:reset=1:length=0.4[out1]", path, video)
+}
+
+// Generate decodes the file at filePath and returns its gzipped QCTools
+// report. The first video stream and, when present, the first audio stream
+// are measured, which takes about as long as decoding the file.
+func (qr *QCToolsReporter) Generate(ctx context.Context, filePath string, result *FFprobeResult) ([]byte, error) {
+	var video, audio bool
+	if result != nil {
+		for _, stream := range result.Streams {
+			switch {
+			case stream.CodecType == "video" && stream.Disposition["attached_pic"] == 0:
+				video = true
+			case stream.CodecType == "audio":
+				audio = true
+			}
+		}
+	}
+	if !video {
+		return nil, ErrNoQCToolsVideo
+	}
+
+	cmd := exec.CommandContext(ctx, qr.ffprobePath,
+		"-v", "error",
+		"-f", "lavfi",
+		"-i", qctoolsFilterGraph(filePath, audio),
+		"-show_frames",
+		"-show_versions",
+		"-of", "xml=x=1:q=1",
+		"-noprivate",
+	)
+	report := &limitedBuffer{limit: MaxQCToolsReportSize}
+	compressed := gzip.NewWriter(report)
+	var stderr strings.Builder
+	cmd.Stdout = compressed
+	cmd.Stderr = &stderr
+	if err := runCommand(ctx, cmd); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("QCTools report failed: %w: %s", err, message)
+		}
+		return nil, fmt.Errorf("QCTools report failed: %w", err)
+	}
+	if err := compressed.Close(); err != nil {
+		return nil, err
+	}
+	if report.Len() >= MaxQCToolsReportSize {
+		return nil, fmt.Errorf("QCTools report exceeds %d bytes", MaxQCToolsReportSize)
+	}
+
+	qr.logger.Debug().
+		Str("file", filePath).
+		Int("bytes", report.Len()).
+		Msg("QCTools report generated")
+	return report.Bytes(), nil
+}
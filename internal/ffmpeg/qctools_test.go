@@ -0,0 +1,63 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestQCToolsFilterGraph(t *testing.T) {
+	graph := qctoolsFilterGraph("/media/a,b.mov", true)
+	if !strings.HasPrefix(graph, `movie='/media/a\,b.mov':s=dv+da[in0][in1];[in0]signalstats=stat=tout+vrep+brng,`) ||
+		!strings.HasSuffix(graph, ";[in1]ebur128=metadata=1,astats=metadata=1:reset=1:length=0.4[out1]") {
+		t.Errorf("graph with audio = %s", graph)
+	}
+	graph = qctoolsFilterGraph("/media/clip.mov", false)
+	if !strings.HasPrefix(graph, "movie='/media/clip.mov':s=dv[in0];") || strings.Contains(graph, "ebur128") {
+		t.Errorf("graph without audio = %s", graph)
+	}
+}
+
+func TestQCToolsReporter_Generate(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "ffprobe")
+	// Echo the filter graph so the test can see which branches were built
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho '<ffprobe>'\necho \"$6\"\necho '</ffprobe>'\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	reporter := NewQCToolsReporter(script, zerolog.Nop())
+
+	result := &FFprobeResult{Streams: []StreamInfo{
+		{Index: 0, CodecType: "video"},
+		{Index: 1, CodecType: "audio"},
+	}}
+	report, err := reporter.Generate(context.Background(), "/media/clip.mov", result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(report))
+	if err != nil {
+		t.Fatalf("report is not gzipped: %v", err)
+	}
+	xml, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(xml), "<ffprobe>\nmovie='/media/clip.mov':s=dv+da") {
+		t.Errorf("report = %s", xml)
+	}
+
+	// Cover art is not video QCTools can graph
+	result.Streams[0].Disposition = map[string]int{"attached_pic": 1}
+	if _, err := reporter.Generate(context.Background(), "/media/song.m4a", result); !errors.Is(err, ErrNoQCToolsVideo) {
+		t.Errorf("audio with cover art: err = %v, want ErrNoQCToolsVideo", err)
+	}
+}
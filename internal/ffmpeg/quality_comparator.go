@@ -0,0 +1,415 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// QualityMetric identifies a full-reference quality metric
+type QualityMetric string
+
+// Quality metrics supported by QualityComparator
+const (
+	QualityMetricVMAF QualityMetric = "vmaf"
+	QualityMetricPSNR QualityMetric = "psnr"
+	QualityMetricSSIM QualityMetric = "ssim"
+)
+
+// AllQualityMetrics lists the supported metrics in the order they are computed
+var AllQualityMetrics = []QualityMetric{
+	QualityMetricVMAF,
+	QualityMetricPSNR,
+	QualityMetricSSIM,
+}
+
+// DefaultVMAFModel is the libvmaf model used when none is requested
+const DefaultVMAFModel = "vmaf_v0.6.1"
+
+// vmafModels are the models built into libvmaf that may be requested
+var vmafModels = map[string]bool{
+	"vmaf_v0.6.1":    true,
+	"vmaf_v0.6.1neg": true,
+	"vmaf_4k_v0.6.1": true,
+}
+
+// maxPSNR replaces the infinite PSNR ffmpeg reports for identical frames, so
+// scores stay JSON-encodable and averages stay finite
+const maxPSNR = 100.0
+
+// ErrVMAFUnavailable is returned when ffmpeg was built without libvmaf
+var ErrVMAFUnavailable = errors.New("ffmpeg was built without libvmaf")
+
+// QualityCompareOptions selects the metrics computed by Compare
+type QualityCompareOptions struct {
+	Metrics   []QualityMetric // Empty runs all metrics
+	VMAFModel string          // Built-in libvmaf model; empty uses DefaultVMAFModel
+	Subsample int             // Score every Nth frame for VMAF (0 or 1 = every frame)
+	Threads   int             // libvmaf worker threads (0 = libvmaf default)
+}
+
+// QualityScoreSummary aggregates the per-frame scores of one metric
+type QualityScoreSummary struct {
+	Mean   float64 `json:"mean"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	StdDev float64 `json:"std_dev"`
+	P5     float64 `json:"p5"` // 5th percentile, the "worst frames" score
+	Frames int     `json:"frames"`
+}
+
+// QualityFrameScore holds the scores of one distorted frame. Metrics that were
+// not computed for the frame are omitted.
+type QualityFrameScore struct {
+	Frame int      `json:"frame"`
+	VMAF  *float64 `json:"vmaf,omitempty"`
+	PSNR  *float64 `json:"psnr,omitempty"`
+	SSIM  *float64 `json:"ssim,omitempty"`
+}
+
+// QualityComparison is the result of comparing a distorted file to its reference
+type QualityComparison struct {
+	Metrics    []QualityMetric                       `json:"metrics"`
+	VMAFModel  string                                `json:"vmaf_model,omitempty"`
+	FrameCount int                                   `json:"frame_count"`
+	Summary    map[QualityMetric]QualityScoreSummary `json:"summary"`
+	Frames     []QualityFrameScore                   `json:"frames,omitempty"`
+}
+
+// QualityComparator computes full-reference quality metrics (VMAF, PSNR and
+// SSIM) between a reference and a distorted encode using ffmpeg filters
+type QualityComparator struct {
+	ffmpegPath string
+	logger     zerolog.Logger
+}
+
+// NewQualityComparator creates a new quality comparator
+func NewQualityComparator(ffmpegPath string, logger zerolog.Logger) *QualityComparator {
+	if ffmpegPath == "" {
+		ffmpegPath = "ffmpeg"
+	}
+
+	return &QualityComparator{
+		ffmpegPath: ffmpegPath,
+		logger:     logger,
+	}
+}
+
+// ParseQualityMetrics validates metric names and removes duplicates while
+// preserving order. An empty list selects all metrics.
+func ParseQualityMetrics(names []string) ([]QualityMetric, error) {
+	seen := make(map[QualityMetric]bool, len(names))
+	metrics := make([]QualityMetric, 0, len(names))
+
+	for _, name := range names {
+		metric := QualityMetric(strings.ToLower(strings.TrimSpace(name)))
+		if metric == "" {
+			continue
+		}
+		if metric != QualityMetricVMAF && metric != QualityMetricPSNR && metric != QualityMetricSSIM {
+			return nil, fmt.Errorf("unknown quality metric: %s", metric)
+		}
+		if !seen[metric] {
+			seen[metric] = true
+			metrics = append(metrics, metric)
+		}
+	}
+
+	if len(metrics) == 0 {
+		return AllQualityMetrics, nil
+	}
+	return metrics, nil
+}
+
+// ValidateVMAFModel reports whether model names a built-in libvmaf model
+func ValidateVMAFModel(model string) error {
+	if model == "" || vmafModels[model] {
+		return nil
+	}
+	return fmt.Errorf("unknown VMAF model: %s", model)
+}
+
+// Compare scores the distorted file against the reference, both local paths.
+// The distorted video is scaled to the reference resolution and both streams
+// are aligned to start at zero before scoring.
+func (qc *QualityComparator) Compare(ctx context.Context, referencePath, distortedPath string, opts QualityCompareOptions) (*QualityComparison, error) {
+	ctx, span := tracer.Start(ctx, "QualityComparator.Compare")
+	defer span.End()
+
+	metrics, err := ParseQualityMetrics(metricNames(opts.Metrics))
+	if err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	if opts.VMAFModel == "" {
+		opts.VMAFModel = DefaultVMAFModel
+	}
+	if err := ValidateVMAFModel(opts.VMAFModel); err != nil {
+		recordSpanError(span, err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.StringSlice("quality.metrics", metricNames(metrics)))
+
+	// ffmpeg runs inside workDir so stats files can be named without
+	// filtergraph escaping; inputs must therefore be absolute
+	referencePath, err = filepath.Abs(referencePath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid reference path: %w", err)
+	}
+	distortedPath, err = filepath.Abs(distortedPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid distorted path: %w", err)
+	}
+
+	workDir, err := os.MkdirTemp("", "rendiff-quality-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	scores := make(map[QualityMetric]map[int]float64, len(metrics))
+	for _, metric := range metrics {
+		metricScores, err := qc.runMetric(ctx, workDir, referencePath, distortedPath, metric, opts)
+		if err != nil {
+			recordSpanError(span, err)
+			return nil, err
+		}
+		scores[metric] = metricScores
+	}
+
+	comparison := buildQualityComparison(metrics, scores)
+	if scores[QualityMetricVMAF] != nil {
+		comparison.VMAFModel = opts.VMAFModel
+	}
+	span.SetAttributes(attribute.Int("quality.frames", comparison.FrameCount))
+
+	return comparison, nil
+}
+
+// runMetric runs one ffmpeg pass for metric and returns scores keyed by
+// zero-based frame number
+func (qc *QualityComparator) runMetric(ctx context.Context, workDir, referencePath, distortedPath string, metric QualityMetric, opts QualityCompareOptions) (map[int]float64, error) {
+	var logFile, filter string
+	switch metric {
+	case QualityMetricVMAF:
+		logFile = "vmaf.json"
+		filter = fmt.Sprintf("libvmaf=model=version=%s:log_fmt=json:log_path=%s", opts.VMAFModel, logFile)
+		if opts.Subsample > 1 {
+			filter += fmt.Sprintf(":n_subsample=%d", opts.Subsample)
+		}
+		if opts.Threads > 0 {
+			filter += fmt.Sprintf(":n_threads=%d", opts.Threads)
+		}
+	case QualityMetricPSNR:
+		logFile = "psnr.log"
+		filter = "psnr=stats_file=" + logFile
+	case QualityMetricSSIM:
+		logFile = "ssim.log"
+		filter = "ssim=stats_file=" + logFile
+	}
+
+	// Input 0 is the distorted (main) stream and input 1 the reference, as
+	// libvmaf expects; scale2ref matches the distorted frames to the reference
+	graph := "[0:v]settb=AVTB,setpts=PTS-STARTPTS[main0];" +
+		"[1:v]settb=AVTB,setpts=PTS-STARTPTS[ref0];" +
+		"[main0][ref0]scale2ref=flags=bicubic[main][ref];" +
+		"[main][ref]" + filter
+
+	cmd := exec.CommandContext(ctx, qc.ffmpegPath,
+		"-hide_banner", "-nostdin", "-nostats",
+		"-i", distortedPath,
+		"-i", referencePath,
+		"-lavfi", graph,
+		"-f", "null", "-",
+	)
+	cmd.Dir = workDir
+
+	qc.logger.Debug().Str("metric", string(metric)).Msg("Running quality comparison pass")
+
+	output, err := commandCombinedOutput(ctx, cmd)
+	if err != nil {
+		if metric == QualityMetricVMAF && bytes.Contains(output, []byte("libvmaf")) &&
+			bytes.Contains(output, []byte("No such filter")) {
+			return nil, ErrVMAFUnavailable
+		}
+		qc.logger.Debug().Str("metric", string(metric)).Bytes("output", lastBytes(output, 2048)).Msg("Quality comparison pass failed")
+		return nil, fmt.Errorf("%s comparison failed: %w", metric, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(workDir, logFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s log: %w", metric, err)
+	}
+
+	switch metric {
+	case QualityMetricVMAF:
+		return parseVMAFLog(data)
+	case QualityMetricPSNR:
+		return parseStatsLog(data, "psnr_avg")
+	default:
+		return parseStatsLog(data, "All")
+	}
+}
+
+// parseVMAFLog reads per-frame scores from a libvmaf JSON log
+func parseVMAFLog(data []byte) (map[int]float64, error) {
+	var vmafLog struct {
+		Frames []struct {
+			FrameNum int                `json:"frameNum"`
+			Metrics  map[string]float64 `json:"metrics"`
+		} `json:"frames"`
+	}
+	if err := json.Unmarshal(data, &vmafLog); err != nil {
+		return nil, fmt.Errorf("failed to parse VMAF log: %w", err)
+	}
+
+	scores := make(map[int]float64, len(vmafLog.Frames))
+	for _, frame := range vmafLog.Frames {
+		if score, ok := frame.Metrics["vmaf"]; ok {
+			scores[frame.FrameNum] = score
+		}
+	}
+	return scores, nil
+}
+
+// parseStatsLog reads one field from a psnr or ssim filter stats file. Each
+// line holds space-separated key:value pairs starting with the one-based
+// frame number, e.g. "n:1 Y:0.990 U:0.995 V:0.994 All:0.992 (20.97)".
+func parseStatsLog(data []byte, field string) (map[int]float64, error) {
+	scores := make(map[int]float64)
+	prefix := field + ":"
+
+	forEachLine(data, func(line string) bool {
+		frame := -1
+		value, found := 0.0, false
+
+		for _, token := range strings.Fields(line) {
+			switch {
+			case strings.HasPrefix(token, "n:"):
+				if n, err := strconv.Atoi(token[2:]); err == nil {
+					frame = n - 1
+				}
+			case strings.HasPrefix(token, prefix):
+				if v, err := strconv.ParseFloat(token[len(prefix):], 64); err == nil {
+					value, found = v, true
+				}
+			}
+		}
+
+		if frame >= 0 && found {
+			if math.IsInf(value, 1) {
+				value = maxPSNR
+			}
+			scores[frame] = value
+		}
+		return true
+	})
+
+	if len(scores) == 0 {
+		return nil, fmt.Errorf("no %s values found in stats log", field)
+	}
+	return scores, nil
+}
+
+// buildQualityComparison merges per-metric scores into per-frame rows and
+// computes summaries
+func buildQualityComparison(metrics []QualityMetric, scores map[QualityMetric]map[int]float64) *QualityComparison {
+	comparison := &QualityComparison{
+		Metrics: metrics,
+		Summary: make(map[QualityMetric]QualityScoreSummary, len(metrics)),
+	}
+
+	frames := make(map[int]*QualityFrameScore)
+	for _, metric := range metrics {
+		values := make([]float64, 0, len(scores[metric]))
+		for frame, score := range scores[metric] {
+			row, ok := frames[frame]
+			if !ok {
+				row = &QualityFrameScore{Frame: frame}
+				frames[frame] = row
+			}
+
+			score := score
+			switch metric {
+			case QualityMetricVMAF:
+				row.VMAF = &score
+			case QualityMetricPSNR:
+				row.PSNR = &score
+			case QualityMetricSSIM:
+				row.SSIM = &score
+			}
+			values = append(values, score)
+		}
+		comparison.Summary[metric] = summarizeScores(values)
+	}
+
+	comparison.Frames = make([]QualityFrameScore, 0, len(frames))
+	for _, row := range frames {
+		comparison.Frames = append(comparison.Frames, *row)
+	}
+	sort.Slice(comparison.Frames, func(i, j int) bool {
+		return comparison.Frames[i].Frame < comparison.Frames[j].Frame
+	})
+	comparison.FrameCount = len(comparison.Frames)
+
+	return comparison
+}
+
+// summarizeScores computes aggregate statistics over per-frame scores
+func summarizeScores(values []float64) QualityScoreSummary {
+	if len(values) == 0 {
+		return QualityScoreSummary{}
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+
+	var variance float64
+	for _, v := range sorted {
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(sorted))
+
+	return QualityScoreSummary{
+		Mean:   mean,
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		StdDev: math.Sqrt(variance),
+		P5:     sorted[int(float64(len(sorted)-1)*0.05)],
+		Frames: len(sorted),
+	}
+}
+
+func metricNames(metrics []QualityMetric) []string {
+	names := make([]string, len(metrics))
+	for i, metric := range metrics {
+		names[i] = string(metric)
+	}
+	return names
+}
+
+// lastBytes returns at most n trailing bytes of b, where ffmpeg puts the error
+func lastBytes(b []byte, n int) []byte {
+	if len(b) <= n {
+		return b
+	}
+	return b[len(b)-n:]
+}
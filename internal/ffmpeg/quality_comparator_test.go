@@ -0,0 +1,99 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseStatsLog(t *testing.T) {
+	psnr := []byte("n:1 mse_avg:0.00 mse_y:0.00 mse_u:0.00 mse_v:0.00 psnr_avg:inf psnr_y:inf psnr_u:inf psnr_v:inf\n" +
+		"n:2 mse_avg:4.12 mse_y:5.01 mse_u:2.33 mse_v:2.44 psnr_avg:41.98 psnr_y:41.13 psnr_u:44.46 psnr_v:44.26\n")
+
+	scores, err := parseStatsLog(psnr, "psnr_avg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if scores[0] != maxPSNR {
+		t.Errorf("expected infinite PSNR to be capped at %v, got %v", maxPSNR, scores[0])
+	}
+	if scores[1] != 41.98 {
+		t.Errorf("expected frame 1 PSNR 41.98, got %v", scores[1])
+	}
+
+	ssim := []byte("n:1 Y:0.990000 U:0.995000 V:0.994000 All:0.992000 (20.969100)\n")
+	scores, err = parseStatsLog(ssim, "All")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if scores[0] != 0.992 {
+		t.Errorf("expected frame 0 SSIM 0.992, got %v", scores[0])
+	}
+
+	if _, err := parseStatsLog([]byte("garbage\n"), "All"); err == nil {
+		t.Error("expected error for log without values")
+	}
+}
+
+func TestParseVMAFLog(t *testing.T) {
+	data := []byte(`{"version":"2.3.1","frames":[
+		{"frameNum":0,"metrics":{"integer_adm2":0.98,"vmaf":94.5}},
+		{"frameNum":2,"metrics":{"integer_adm2":0.97,"vmaf":90.25}}
+	],"pooled_metrics":{"vmaf":{"min":90.25,"max":94.5,"mean":92.375}}}`)
+
+	scores, err := parseVMAFLog(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(scores) != 2 || scores[0] != 94.5 || scores[2] != 90.25 {
+		t.Errorf("unexpected scores: %v", scores)
+	}
+}
+
+func TestBuildQualityComparison(t *testing.T) {
+	metrics := []QualityMetric{QualityMetricVMAF, QualityMetricPSNR}
+	comparison := buildQualityComparison(metrics, map[QualityMetric]map[int]float64{
+		QualityMetricVMAF: {0: 90, 2: 80},
+		QualityMetricPSNR: {0: 40, 1: 38, 2: 36},
+	})
+
+	if comparison.FrameCount != 3 {
+		t.Fatalf("expected 3 frames, got %d", comparison.FrameCount)
+	}
+	if comparison.Frames[1].Frame != 1 || comparison.Frames[1].VMAF != nil || *comparison.Frames[1].PSNR != 38 {
+		t.Errorf("unexpected frame 1: %+v", comparison.Frames[1])
+	}
+
+	vmaf := comparison.Summary[QualityMetricVMAF]
+	if vmaf.Mean != 85 || vmaf.Min != 80 || vmaf.Max != 90 || vmaf.Frames != 2 {
+		t.Errorf("unexpected VMAF summary: %+v", vmaf)
+	}
+	if comparison.Summary[QualityMetricPSNR].StdDev == 0 {
+		t.Error("expected non-zero PSNR standard deviation")
+	}
+
+	if _, err := json.Marshal(comparison); err != nil {
+		t.Errorf("comparison should be JSON-encodable: %v", err)
+	}
+}
+
+func TestParseQualityMetrics(t *testing.T) {
+	metrics, err := ParseQualityMetrics(nil)
+	if err != nil || len(metrics) != len(AllQualityMetrics) {
+		t.Errorf("expected all metrics for empty selection, got %v (%v)", metrics, err)
+	}
+
+	metrics, err = ParseQualityMetrics([]string{"SSIM", " psnr", "ssim"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(metrics) != 2 || metrics[0] != QualityMetricSSIM || metrics[1] != QualityMetricPSNR {
+		t.Errorf("unexpected metrics: %v", metrics)
+	}
+
+	if _, err := ParseQualityMetrics([]string{"ms-ssim"}); err == nil {
+		t.Error("expected error for unknown metric")
+	}
+	if err := ValidateVMAFModel("vmaf_custom"); err == nil {
+		t.Error("expected error for unknown VMAF model")
+	}
+}
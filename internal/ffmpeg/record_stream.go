@@ -0,0 +1,226 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+)
+
+// Record types emitted by StreamRecords
+const (
+	RecordTypeFrame  = "frame"
+	RecordTypePacket = "packet"
+)
+
+// maxStreamStderr bounds the ffprobe stderr kept for error reporting
+const maxStreamStderr = 4096
+
+// ProbeRecord is one frame or packet entry from ffprobe's JSON output. Data
+// holds the entry exactly as ffprobe printed it, including side data.
+type ProbeRecord struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// ErrNoRecordSections is returned when a streaming probe requests neither
+// frames nor packets
+var ErrNoRecordSections = errors.New("show_frames or show_packets is required")
+
+// StreamRecords runs ffprobe and passes each frame and packet record to emit
+// as soon as it is parsed, instead of buffering the whole output like Probe.
+// Output format options are overridden with JSON; other top-level sections
+// are skipped. Remote inputs are restricted to network protocols unless the
+// options set a protocol_whitelist. A non-nil error from emit stops ffprobe and is returned
+// unwrapped. The count of emitted records is returned either way.
+func (f *FFprobe) StreamRecords(ctx context.Context, options *FFprobeOptions, emit func(ProbeRecord) error) (int, error) {
+	if options == nil || (!options.ShowFrames && !options.ShowPackets) {
+		return 0, ErrNoRecordSections
+	}
+
+	streamOptions := *options
+	streamOptions.OutputFormat = OutputJSON
+	streamOptions.PrettyPrint = false
+	streamOptions.HideBanner = true
+	if streamOptions.LogLevel == "" {
+		streamOptions.LogLevel = LogError
+	}
+	if strings.Contains(streamOptions.Input, "://") && streamOptions.InputOptions["protocol_whitelist"] == "" {
+		// A remote input may only be read over the network protocols, not
+		// redirected to files or other protocols by the media it points at
+		inputOptions := map[string]string{"protocol_whitelist": remoteProtocols}
+		for key, value := range options.InputOptions {
+			if key != "protocol_whitelist" {
+				inputOptions[key] = value
+			}
+		}
+		streamOptions.InputOptions = inputOptions
+	}
+	if err := ValidateOptions(&streamOptions); err != nil {
+		return 0, fmt.Errorf("invalid options: %w", err)
+	}
+
+	args, err := f.buildArgs(&streamOptions)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build ffprobe arguments: %w", err)
+	}
+
+	probeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(probeCtx, f.binaryPath, args...)
+	stderr := &limitedBuffer{limit: maxStreamStderr}
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	f.logger.Debug().
+		Str("command", f.binaryPath).
+		Strs("args", args).
+		Msg("Streaming ffprobe records")
+
+	release, err := AcquireProcess(probeCtx)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+
+	span := startCommandSpan(probeCtx, cmd)
+	if err := cmd.Start(); err != nil {
+		endCommandSpan(span, err)
+		return 0, fmt.Errorf("failed to start ffprobe: %w", err)
+	}
+
+	var emitErr error
+	count, decodeErr := decodeProbeRecords(stdout, func(record ProbeRecord) error {
+		if err := emit(record); err != nil {
+			emitErr = err
+			return err
+		}
+		return nil
+	})
+	if emitErr != nil {
+		// The consumer is gone; stop ffprobe rather than decode the rest
+		cancel()
+	}
+	// Wait closes stdout, so drain it first in case decoding stopped early
+	_, _ = io.Copy(io.Discard, stdout)
+	waitErr := cmd.Wait()
+
+	var streamErr error
+	switch {
+	case emitErr != nil:
+		streamErr = emitErr
+	case ctx.Err() != nil:
+		streamErr = ctx.Err()
+	case waitErr != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			streamErr = fmt.Errorf("ffprobe execution failed: %w: %s", waitErr, msg)
+		} else {
+			streamErr = fmt.Errorf("ffprobe execution failed: %w", waitErr)
+		}
+	case decodeErr != nil:
+		streamErr = fmt.Errorf("failed to parse ffprobe output: %w", decodeErr)
+	}
+	endCommandSpan(span, streamErr)
+	return count, streamErr
+}
+
+// decodeProbeRecords reads ffprobe JSON output token by token and emits the
+// entries of the frames, packets and packets_and_frames arrays one at a time
+func decodeProbeRecords(r io.Reader, emit func(ProbeRecord) error) (int, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return count, err
+		}
+		section, _ := token.(string)
+
+		var recordType string
+		switch section {
+		case "frames":
+			recordType = RecordTypeFrame
+		case "packets":
+			recordType = RecordTypePacket
+		case "packets_and_frames":
+			// Entries carry their own "type"
+		default:
+			var skipped json.RawMessage
+			if err := dec.Decode(&skipped); err != nil {
+				return count, err
+			}
+			continue
+		}
+
+		if err := expectDelim(dec, '['); err != nil {
+			return count, err
+		}
+		for dec.More() {
+			var data json.RawMessage
+			if err := dec.Decode(&data); err != nil {
+				return count, err
+			}
+
+			record := ProbeRecord{Type: recordType, Data: data}
+			if record.Type == "" {
+				var entry struct {
+					Type string `json:"type"`
+				}
+				if err := json.Unmarshal(data, &entry); err != nil {
+					return count, err
+				}
+				record.Type = entry.Type
+			}
+
+			if err := emit(record); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return count, err
+		}
+	}
+
+	return count, expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("unexpected token %v, expected %v", token, want)
+	}
+	return nil
+}
+
+// limitedBuffer keeps the first limit bytes written and discards the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.Buffer.Write(p[:remaining])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
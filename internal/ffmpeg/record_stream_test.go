@@ -0,0 +1,174 @@
+package ffmpeg
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestDecodeProbeRecords(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "frames",
+			input: `{"frames": [{"media_type": "video", "key_frame": 1}, {"media_type": "video", "key_frame": 0}]}`,
+			want:  []string{RecordTypeFrame, RecordTypeFrame},
+		},
+		{
+			name: "interleaved with other sections",
+			input: `{
+				"packets_and_frames": [
+					{"type": "packet", "codec_type": "video", "flags": "K__"},
+					{"type": "frame", "media_type": "video", "side_data_list": [{"side_data_type": "AFD"}]}
+				],
+				"streams": [{"index": 0}],
+				"format": {"format_name": "mxf"}
+			}`,
+			want: []string{RecordTypePacket, RecordTypeFrame},
+		},
+		{
+			name:  "separate sections",
+			input: `{"format": {"tags": {"title": "x"}}, "packets": [{"size": "10"}], "frames": [{"pkt_size": "10"}]}`,
+			want:  []string{RecordTypePacket, RecordTypeFrame},
+		},
+		{
+			name:  "empty",
+			input: `{}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			count, err := decodeProbeRecords(strings.NewReader(tt.input), func(record ProbeRecord) error {
+				if len(record.Data) == 0 || record.Data[0] != '{' {
+					t.Errorf("record data is not an object: %s", record.Data)
+				}
+				got = append(got, record.Type)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if count != len(tt.want) || strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %d records %v, want %v", count, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeProbeRecordsStopsOnEmitError(t *testing.T) {
+	stop := errors.New("client gone")
+	input := `{"frames": [{"n": 1}, {"n": 2}, {"n": 3}]}`
+
+	count, err := decodeProbeRecords(strings.NewReader(input), func(ProbeRecord) error {
+		return stop
+	})
+	if !errors.Is(err, stop) || count != 0 {
+		t.Errorf("expected emit error after 0 records, got %d, %v", count, err)
+	}
+}
+
+func TestDecodeProbeRecordsTruncated(t *testing.T) {
+	count, err := decodeProbeRecords(strings.NewReader(`{"frames": [{"n": 1}, {"n": `), func(ProbeRecord) error {
+		return nil
+	})
+	if err == nil {
+		t.Fatal("expected error for truncated output")
+	}
+	if count != 1 {
+		t.Errorf("expected the complete record before the cut to be emitted, got %d", count)
+	}
+}
+
+func TestStreamRecordsRequiresSections(t *testing.T) {
+	f := &FFprobe{}
+	_, err := f.StreamRecords(t.Context(), &FFprobeOptions{Input: "video.mp4", ShowFormat: true}, func(ProbeRecord) error {
+		return nil
+	})
+	if !errors.Is(err, ErrNoRecordSections) {
+		t.Errorf("expected ErrNoRecordSections, got %v", err)
+	}
+}
+
+// fakeFFprobe writes a shell script that prints output and exits with code
+func fakeFFprobe(t *testing.T, output string, code int) *FFprobe {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "ffprobe")
+	body := "#!/bin/sh\nprintf '%s' '" + output + "'\necho 'Invalid data found' >&2\nexit " + strconv.Itoa(code) + "\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return NewFFprobe(script, zerolog.Nop())
+}
+
+func TestStreamRecords(t *testing.T) {
+	input := filepath.Join(t.TempDir(), "video.mp4")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	options := &FFprobeOptions{Input: input, ShowFrames: true}
+
+	f := fakeFFprobe(t, `{"frames": [{"n": 1}, {"n": 2}]}`, 0)
+	var records []ProbeRecord
+	count, err := f.StreamRecords(t.Context(), options, func(record ProbeRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil || count != 2 || len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d (%d emitted), %v", count, len(records), err)
+	}
+	if string(records[1].Data) != `{"n": 2}` {
+		t.Errorf("unexpected record data %s", records[1].Data)
+	}
+
+	f = fakeFFprobe(t, `{"frames": [{"n": 1}`, 1)
+	count, err = f.StreamRecords(t.Context(), options, func(ProbeRecord) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "Invalid data found") {
+		t.Errorf("expected ffprobe failure with stderr, got %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 record before the failure, got %d", count)
+	}
+}
+
+func TestStreamRecordsRemoteWhitelist(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	dir := t.TempDir()
+	argv := filepath.Join(dir, "argv")
+	script := filepath.Join(dir, "ffprobe")
+	body := "#!/bin/sh\necho \"$@\" > " + argv + "\nprintf '%s' '{\"frames\": [{\"n\": 1}]}'\n"
+	if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	options := &FFprobeOptions{Input: "https://example.com/video.mp4", ShowFrames: true}
+	if _, err := NewFFprobe(script, zerolog.Nop()).StreamRecords(t.Context(), options, func(ProbeRecord) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	args, err := os.ReadFile(argv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "-protocol_whitelist "+remoteProtocols+" ") {
+		t.Errorf("expected the remote protocol whitelist in %q", args)
+	}
+	if options.InputOptions != nil {
+		t.Error("expected the caller's options to be left unchanged")
+	}
+}
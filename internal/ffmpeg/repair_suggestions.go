@@ -0,0 +1,355 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// Integrity issues a repair can be suggested for
+const (
+	RepairMissingMoov      = "missing_moov"
+	RepairTruncated        = "truncated"
+	RepairBrokenIndex      = "broken_index"
+	RepairContinuityErrors = "continuity_errors"
+	RepairMissingPTS       = "missing_pts"
+	RepairTimestamps       = "timestamp_discontinuities"
+	RepairAudioGaps        = "audio_gaps"
+	RepairADTSAudio        = "adts_aac"
+	RepairDecodeErrors     = "decode_errors"
+	RepairColorTags        = "color_tags"
+	RepairChannelMapping   = "channel_mapping"
+)
+
+// RepairSuggestion is a command line that remedies an integrity or
+// metadata issue. The
+// input and output file names in Command are placeholders.
+type RepairSuggestion struct {
+	Issue       string `json:"issue"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Command     string `json:"command"`
+	Lossless    bool   `json:"lossless"` // The essence is copied, not re-encoded
+}
+
+// repairLogPatterns map ffmpeg log messages, from the probe or a decode
+// scan, to the issue they reveal
+var repairLogPatterns = []struct {
+	pattern string
+	issue   string
+}{
+	{"moov atom not found", RepairMissingMoov},
+	{"partial file", RepairTruncated},
+	{"could not find index", RepairBrokenIndex},
+	{"invalid index", RepairBrokenIndex},
+	{"broken index", RepairBrokenIndex},
+	{"non-interleaved avi", RepairBrokenIndex},
+	{"malformed aac bitstream", RepairADTSAudio},
+	{"aac_adtstoasc", RepairADTSAudio},
+}
+
+// SuggestRepairs returns the remux and repair commands for the integrity
+// issues found in result, in the order to try them: lossless remuxes before
+// re-encodes. input names the probed file, for its extension.
+func SuggestRepairs(result *FFprobeResult, input string) []RepairSuggestion {
+	if result == nil {
+		return nil
+	}
+	files := newRepairFiles(result, input)
+	issues := findRepairIssues(result)
+
+	var suggestions []RepairSuggestion
+	for _, issue := range []string{
+		RepairMissingMoov, RepairTruncated, RepairBrokenIndex, RepairContinuityErrors, RepairADTSAudio,
+		RepairMissingPTS, RepairTimestamps, RepairColorTags, RepairChannelMapping, RepairAudioGaps, RepairDecodeErrors,
+	} {
+		if issues[issue] {
+			suggestions = append(suggestions, files.suggestion(issue, result))
+		}
+	}
+	return suggestions
+}
+
+// findRepairIssues collects the issues of the probe log, the decode scan,
+// the transport stream checks, the packet timestamps, the color tags and the
+// audio channel mapping
+func findRepairIssues(result *FFprobeResult) map[string]bool {
+	issues := make(map[string]bool)
+
+	logs := []string{result.StdErr}
+	if result.Error != nil {
+		logs = append(logs, result.Error.String)
+	}
+	enhanced := result.EnhancedAnalysis
+	if enhanced != nil && enhanced.DataIntegrityAnalysis != nil {
+		integrity := enhanced.DataIntegrityAnalysis
+		if integrity.ErrorSummary != nil {
+			for _, list := range [][]ErrorDetail{integrity.ErrorSummary.CriticalErrors, integrity.ErrorSummary.MajorErrors, integrity.ErrorSummary.MinorErrors} {
+				for _, detail := range list {
+					logs = append(logs, detail.Message)
+				}
+			}
+		}
+		if scan := integrity.DecodeScan; scan != nil {
+			for _, decodeError := range scan.Errors {
+				logs = append(logs, decodeError.Message)
+			}
+			if scan.DecodeErrors > 0 || scan.CorruptFrames > 0 {
+				issues[RepairDecodeErrors] = true
+			}
+		}
+	}
+	for _, log := range logs {
+		log = strings.ToLower(log)
+		for _, p := range repairLogPatterns {
+			if strings.Contains(log, p.pattern) {
+				issues[p.issue] = true
+			}
+		}
+	}
+	if issues[RepairMissingMoov] {
+		// Nothing else can be read until the index is rebuilt
+		return map[string]bool{RepairMissingMoov: true}
+	}
+	if enhanced == nil {
+		return issues
+	}
+
+	if ts := enhanced.TransportStreamAnalysis; ts != nil && ts.ETR290 != nil {
+		for _, check := range ts.ETR290.Checks {
+			if check.ID == etr290Continuity && check.ErrorCount > 0 {
+				issues[RepairContinuityErrors] = true
+			}
+		}
+	}
+	if timestamps := enhanced.TimestampAnalysis; timestamps != nil {
+		for _, stream := range timestamps.Streams {
+			if stream.MissingPTS > 0 {
+				issues[RepairMissingPTS] = true
+			}
+			d := stream.Discontinuities
+			if d[TimestampNegativeDelta]+d[TimestampDuplicateDTS]+d[TimestampJump] > 0 {
+				issues[RepairTimestamps] = true
+			}
+			if stream.CodecType == "audio" && d[TimestampGap] > 0 {
+				issues[RepairAudioGaps] = true
+			}
+		}
+	}
+	if color := enhanced.ColorConsistencyAnalysis; color != nil && !color.IsConsistent {
+		issues[RepairColorTags] = true
+	}
+	if content := enhanced.ContentAnalysis; content != nil && content.ChannelMappingInfo != nil && content.ChannelMappingInfo.SuggestedRemap != "" {
+		issues[RepairChannelMapping] = true
+	}
+	return issues
+}
+
+// repairFiles names the placeholder input and output of the commands
+type repairFiles struct {
+	ext string // e.g. ".ts"
+}
+
+func newRepairFiles(result *FFprobeResult, input string) repairFiles {
+	if u, err := url.Parse(input); err == nil && u.Scheme != "" {
+		input = u.Path
+	}
+	ext := strings.ToLower(filepath.Ext(input))
+	if ext == "" && result.Format != nil {
+		switch name := result.Format.FormatName; {
+		case strings.Contains(name, "mpegts"):
+			ext = ".ts"
+		case strings.Contains(name, "mp4"):
+			ext = ".mp4"
+		case strings.Contains(name, "matroska"):
+			ext = ".mkv"
+		case name != "":
+			ext = "." + strings.Split(name, ",")[0]
+		}
+	}
+	if ext == "" {
+		ext = ".mp4"
+	}
+	return repairFiles{ext: ext}
+}
+
+// command formats an ffmpeg command line reading the input before args and
+// writing the repaired file after them
+func (f repairFiles) command(inputArgs, args, ext string) string {
+	if ext == "" {
+		ext = f.ext
+	}
+	command := "ffmpeg"
+	if inputArgs != "" {
+		command += " " + inputArgs
+	}
+	return fmt.Sprintf("%s -i input%s %s repaired%s", command, f.ext, args, ext)
+}
+
+func (f repairFiles) suggestion(issue string, result *FFprobeResult) RepairSuggestion {
+	s := RepairSuggestion{Issue: issue, Lossless: true}
+	switch issue {
+	case RepairMissingMoov:
+		s.Title = "Rebuild the missing moov index"
+		s.Description = "The MP4/MOV index (moov atom) is missing, usually because recording stopped before the file was finalized. ffmpeg cannot read the file without it; untrunc rebuilds the index from a healthy file recorded with the same camera or encoder settings."
+		s.Command = fmt.Sprintf("untrunc reference%s input%s", f.ext, f.ext)
+	case RepairTruncated:
+		s.Title = "Remux the readable part of the truncated file"
+		s.Description = "The file ends before its index says it should. Remuxing keeps everything up to the cut and writes a consistent index; the missing tail cannot be recovered."
+		s.Command = f.command("", "-map 0 -c copy", "")
+	case RepairBrokenIndex:
+		s.Title = "Remux to rebuild the index"
+		s.Description = "The container index is missing or broken, so seeking and duration are unreliable. A stream copy writes a new index; timestamps are regenerated because files without an index often lack them."
+		s.Command = f.command("-fflags +genpts", "-map 0 -c copy", "")
+	case RepairContinuityErrors:
+		s.Title = "Remux the transport stream dropping corrupt packets"
+		s.Description = "Continuity counter errors mean transport packets were lost or duplicated. Remuxing discards the packets ffmpeg flags as corrupt and rewrites continuity counters and timestamps; lost packets are not restored, so re-capture when the errors fall in programme content."
+		s.Command = f.command("-fflags +discardcorrupt+genpts", "-map 0 -c copy -f mpegts", ".ts")
+	case RepairADTSAudio:
+		s.Title = "Convert ADTS AAC for MP4"
+		s.Description = "AAC audio carries ADTS headers, as in a transport stream, which MP4 and MOV do not allow. The aac_adtstoasc bitstream filter converts them without re-encoding."
+		ext := ".mp4"
+		if f.ext == ".mov" {
+			ext = f.ext
+		}
+		s.Command = f.command("", "-map 0 -c copy -bsf:a aac_adtstoasc -movflags +faststart", ext)
+	case RepairMissingPTS:
+		s.Title = "Remux generating missing timestamps"
+		s.Description = "Packets without a presentation timestamp make players guess their timing. -fflags +genpts derives the missing ones from the decoding timestamps during a stream copy."
+		s.Command = f.command("-fflags +genpts", "-map 0 -c copy", "")
+	case RepairTimestamps:
+		s.Title = "Remux rewriting timestamps"
+		s.Description = "Decoding timestamps go backwards, repeat or jump. A stream copy that ignores the stored DTS and regenerates timestamps, shifted to start at zero, makes them monotonic."
+		s.Command = f.command("-fflags +genpts+igndts", "-map 0 -c copy -avoid_negative_ts make_zero", "")
+	case RepairAudioGaps:
+		s.Title = "Re-encode audio to fill gaps"
+		s.Description = "Audio packets are missing, which drifts audio against video after each gap. Resampling with aresample=async fills the gaps with silence and keeps sync; only the audio is re-encoded."
+		s.Command = f.command("", "-map 0 -c copy -c:a "+repairAudioEncoder(result)+" -af aresample=async=1000", "")
+		s.Lossless = false
+	case RepairDecodeErrors:
+		s.Title = "Re-encode video concealing decode errors"
+		s.Description = "Frames fail to decode or decode corrupted. Re-encoding with errors ignored bakes the decoder's concealment into a clean bitstream; replace the damaged section from a better source where one exists."
+		s.Command = f.command("-err_detect ignore_err", "-map 0 -c copy -c:v "+repairVideoEncoder(result), "")
+		s.Lossless = false
+	case RepairColorTags:
+		s.Title = "Remux rewriting the color tags"
+		s.Description = "The color primaries, transfer and matrix tags are missing, contradict each other or do not suit the resolution, so players guess how to convert the colors. A stream copy writes the recommended tags to the container and, through a metadata bitstream filter, to the H.264, HEVC or MPEG-2 headers; check the picture first, as the tags only describe the colors and do not convert them."
+		s.Command = f.command("", "-map 0 -c copy"+colorTagArgs(result.EnhancedAnalysis.ColorConsistencyAnalysis, result.Streams), "")
+	case RepairChannelMapping:
+		mapping := result.EnhancedAnalysis.ContentAnalysis.ChannelMappingInfo
+		stream, position := channelMappingStream(result.Streams)
+		s.Title = "Re-encode audio remapping channels"
+		s.Description = "Audio channels are silent or carried in the wrong place, e.g. center and LFE exchanged. The channelmap filter puts swapped channels back in place and pan drops silent ones down to the layout of the channels that carry audio; only the audio stream the analysis measured is re-encoded. Listen to the result before delivery, as the channels are told apart by their levels and frequency content."
+		s.Command = f.command("", fmt.Sprintf("-map 0 -c copy -filter:a:%d '%s' -c:a:%d %s", position, mapping.SuggestedRemap, position, audioCodecEncoder(stream.CodecName)), "")
+		s.Lossless = false
+	}
+	return s
+}
+
+// colorMetadataFilters are the bitstream filters that rewrite the color
+// description of a codec's headers
+var colorMetadataFilters = map[string]string{
+	"h264":       "h264_metadata",
+	"hevc":       "hevc_metadata",
+	"mpeg2video": "mpeg2_metadata",
+}
+
+// H.273 code points of the color tags that are recommended
+var (
+	colorPrimariesCodes = map[string]int{"bt709": 1, "bt470bg": 5, "smpte170m": 6, "bt2020": 9}
+	colorTransferCodes  = map[string]int{"bt709": 1, "smpte170m": 6, "bt2020-10": 14, "smpte2084": 16, "arib-std-b67": 18}
+	colorMatrixCodes    = map[string]int{"bt709": 1, "bt470bg": 5, "smpte170m": 6, "bt2020nc": 9}
+)
+
+// colorTagArgs formats the output options that tag each inconsistent video
+// stream with its recommended colors
+func colorTagArgs(color *ColorConsistencyAnalysis, streams []StreamInfo) string {
+	codecs := make(map[int]string, len(streams))
+	for _, stream := range streams {
+		codecs[stream.Index] = stream.CodecName
+	}
+
+	var args strings.Builder
+	for _, stream := range color.Streams {
+		if stream.IsConsistent {
+			continue
+		}
+		tags := stream.Recommended
+		if filter := colorMetadataFilters[codecs[stream.StreamIndex]]; filter != "" {
+			fmt.Fprintf(&args, " -bsf:%d %s=colour_primaries=%d:transfer_characteristics=%d:matrix_coefficients=%d",
+				stream.StreamIndex, filter, colorPrimariesCodes[tags.ColorPrimaries], colorTransferCodes[tags.ColorTransfer], colorMatrixCodes[tags.ColorSpace])
+			if filter != "mpeg2_metadata" && !strings.EqualFold(stream.Tags.ColorRange, tags.ColorRange) {
+				fullRange := 0
+				if tags.ColorRange == "pc" {
+					fullRange = 1
+				}
+				fmt.Fprintf(&args, ":video_full_range_flag=%d", fullRange)
+			}
+		}
+		fmt.Fprintf(&args, " -color_primaries:%d %s -color_trc:%d %s -colorspace:%d %s",
+			stream.StreamIndex, tags.ColorPrimaries, stream.StreamIndex, tags.ColorTransfer, stream.StreamIndex, tags.ColorSpace)
+		if !strings.EqualFold(stream.Tags.ColorRange, tags.ColorRange) {
+			fmt.Fprintf(&args, " -color_range:%d %s", stream.StreamIndex, tags.ColorRange)
+		}
+	}
+	return args.String()
+}
+
+// channelMappingStream returns the audio stream the channel mapping
+// analysis measured, ffmpeg's pick of the first with the most channels,
+// and its position among the audio streams
+func channelMappingStream(streams []StreamInfo) (StreamInfo, int) {
+	var best StreamInfo
+	position, audio := 0, 0
+	for _, stream := range streams {
+		if stream.CodecType != "audio" {
+			continue
+		}
+		if audio == 0 || stream.Channels > best.Channels {
+			best, position = stream, audio
+		}
+		audio++
+	}
+	return best, position
+}
+
+// repairVideoEncoder picks an encoder matching the first video stream
+func repairVideoEncoder(result *FFprobeResult) string {
+	for _, stream := range result.Streams {
+		if stream.CodecType != "video" || stream.Disposition["attached_pic"] == 1 {
+			continue
+		}
+		switch stream.CodecName {
+		case "hevc":
+			return "libx265 -crf 18"
+		case "prores":
+			return "prores_ks -profile:v 3"
+		case "mpeg2video":
+			return "mpeg2video -q:v 2"
+		}
+		break
+	}
+	return "libx264 -crf 16 -preset slow"
+}
+
+// repairAudioEncoder picks an encoder matching the first audio stream
+func repairAudioEncoder(result *FFprobeResult) string {
+	for _, stream := range result.Streams {
+		if stream.CodecType == "audio" {
+			return audioCodecEncoder(stream.CodecName)
+		}
+	}
+	return audioCodecEncoder("")
+}
+
+// audioCodecEncoder picks an encoder for audio of codec
+func audioCodecEncoder(codec string) string {
+	switch codec {
+	case "ac3", "eac3", "mp2":
+		return codec
+	case "pcm_s16le", "pcm_s24le":
+		return codec
+	}
+	return "aac -b:a 256k"
+}
@@ -0,0 +1,103 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSuggestRepairs(t *testing.T) {
+	tests := []struct {
+		name    string
+		result  *FFprobeResult
+		input   string
+		want    []string
+		command string // of the first suggestion
+	}{
+		{
+			name:    "missing moov",
+			result:  &FFprobeResult{StdErr: "[mov,mp4,m4a,3gp,3g2,mj2 @ 0x1] moov atom not found\ninput.mp4: Invalid data found when processing input"},
+			input:   "/tmp/rendiff_1_camera.MP4",
+			want:    []string{RepairMissingMoov},
+			command: "untrunc reference.mp4 input.mp4",
+		},
+		{
+			name: "continuity errors and ADTS audio",
+			result: &FFprobeResult{
+				StdErr: "[mp4 @ 0x1] Malformed AAC bitstream detected: use the audio bitstream filter 'aac_adtstoasc' to fix it",
+				EnhancedAnalysis: &EnhancedAnalysis{
+					TransportStreamAnalysis: &TransportStreamAnalysis{ETR290: &ETR290Analysis{Checks: []ETR290Check{
+						{ID: etr290Continuity, ErrorCount: 12},
+					}}},
+				},
+			},
+			input:   "https://cdn.example.com/live/segment.ts?token=abc",
+			want:    []string{RepairContinuityErrors, RepairADTSAudio},
+			command: "ffmpeg -fflags +discardcorrupt+genpts -i input.ts -map 0 -c copy -f mpegts repaired.ts",
+		},
+		{
+			name: "timestamps and decode errors",
+			result: &FFprobeResult{
+				Format:  &FormatInfo{FormatName: "matroska,webm"},
+				Streams: []StreamInfo{{CodecType: "video", CodecName: "hevc"}, {CodecType: "audio", CodecName: "ac3"}},
+				EnhancedAnalysis: &EnhancedAnalysis{
+					TimestampAnalysis: &TimestampAnalysis{Streams: []*StreamTimestamps{
+						{CodecType: "video", MissingPTS: 3, Discontinuities: map[string]int{TimestampNegativeDelta: 1}},
+						{CodecType: "audio", Discontinuities: map[string]int{TimestampGap: 2}},
+					}},
+					DataIntegrityAnalysis: &DataIntegrityAnalysis{DecodeScan: &DecodeScan{DecodeErrors: 4}},
+				},
+			},
+			want:    []string{RepairMissingPTS, RepairTimestamps, RepairAudioGaps, RepairDecodeErrors},
+			command: "ffmpeg -fflags +genpts -i input.mkv -map 0 -c copy repaired.mkv",
+		},
+		{
+			name:   "clean file",
+			result: &FFprobeResult{StdErr: "Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'input.mp4':"},
+			input:  "input.mp4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestions := SuggestRepairs(tt.result, tt.input)
+			var got []string
+			for _, s := range suggestions {
+				got = append(got, s.Issue)
+				if s.Title == "" || s.Description == "" || s.Command == "" {
+					t.Errorf("incomplete suggestion %+v", s)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("issues = %v, want %v", got, tt.want)
+			}
+			if len(suggestions) > 0 && suggestions[0].Command != tt.command {
+				t.Errorf("command = %q, want %q", suggestions[0].Command, tt.command)
+			}
+		})
+	}
+}
+
+func TestSuggestRepairs_Reencodes(t *testing.T) {
+	result := &FFprobeResult{
+		Streams: []StreamInfo{{CodecType: "video", CodecName: "hevc"}, {CodecType: "audio", CodecName: "ac3"}},
+		EnhancedAnalysis: &EnhancedAnalysis{
+			TimestampAnalysis: &TimestampAnalysis{Streams: []*StreamTimestamps{
+				{CodecType: "audio", Discontinuities: map[string]int{TimestampGap: 2}},
+			}},
+			DataIntegrityAnalysis: &DataIntegrityAnalysis{DecodeScan: &DecodeScan{CorruptFrames: 1}},
+		},
+	}
+	suggestions := SuggestRepairs(result, "input.mov")
+	if len(suggestions) != 2 {
+		t.Fatalf("suggestions = %+v", suggestions)
+	}
+	want := []string{
+		"ffmpeg -i input.mov -map 0 -c copy -c:a ac3 -af aresample=async=1000 repaired.mov",
+		"ffmpeg -err_detect ignore_err -i input.mov -map 0 -c copy -c:v libx265 -crf 18 repaired.mov",
+	}
+	for i, s := range suggestions {
+		if s.Lossless || s.Command != want[i] {
+			t.Errorf("suggestion %d = %+v, want lossy %q", i, s, want[i])
+		}
+	}
+}
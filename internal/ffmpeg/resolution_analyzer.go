@@ -5,14 +5,23 @@ import (
 	"math"
 	"strconv"
 	"strings"
+
+	"github.com/rs/zerolog"
 )
 
 // ResolutionAnalyzer handles resolution and aspect ratio analysis
-type ResolutionAnalyzer struct{}
+type ResolutionAnalyzer struct {
+	ffmpegPath string
+	logger     zerolog.Logger
+}
 
-// NewResolutionAnalyzer creates a new resolution analyzer
-func NewResolutionAnalyzer() *ResolutionAnalyzer {
-	return &ResolutionAnalyzer{}
+// NewResolutionAnalyzer creates a new resolution analyzer. ffmpegPath is
+// used to read the aspect ratio signalled in codec headers.
+func NewResolutionAnalyzer(ffmpegPath string, logger zerolog.Logger) *ResolutionAnalyzer {
+	return &ResolutionAnalyzer{
+		ffmpegPath: ffmpegPath,
+		logger:     logger,
+	}
 }
 
 // AnalyzeResolution analyzes resolution and aspect ratio from stream information
@@ -77,7 +86,7 @@ func (ra *ResolutionAnalyzer) analyzeVideoResolution(stream StreamInfo) *VideoRe
 	}
 
 	// Determine if anamorphic
-	resolution.IsAnamorphic = ra.isAnamorphic(resolution.SampleAspectRatio, resolution.DisplayAspectRatio)
+	resolution.IsAnamorphic = ra.isAnamorphic(resolution.SampleAspectRatio)
 
 	// Check for common aspect ratios
 	resolution.AspectRatioCategory = ra.categorizeAspectRatio(resolution.DisplayAspectRatio)
@@ -85,8 +94,9 @@ func (ra *ResolutionAnalyzer) analyzeVideoResolution(stream StreamInfo) *VideoRe
 	// Detect orientation
 	resolution.Orientation = ra.getOrientation(resolution.Width, resolution.Height)
 
-	// Validate consistency
-	resolution.IsConsistent = ra.validateResolutionConsistency(resolution, stream)
+	// Cross-check the aspect ratios and validate consistency
+	ra.checkAspectRatio(resolution, stream)
+	resolution.IsConsistent = ra.validateResolutionConsistency(resolution)
 
 	return resolution
 }
@@ -177,15 +187,15 @@ func (ra *ResolutionAnalyzer) parseAspectRatio(aspectRatio string) float64 {
 	return 0.0
 }
 
-// isAnamorphic determines if content is anamorphic
-func (ra *ResolutionAnalyzer) isAnamorphic(sampleAR, displayAR float64) bool {
-	if sampleAR == 0.0 || displayAR == 0.0 {
+// isAnamorphic determines if content is anamorphic, stored with
+// non-square pixels
+func (ra *ResolutionAnalyzer) isAnamorphic(sampleAR float64) bool {
+	if sampleAR == 0.0 {
 		return false
 	}
 
-	// If sample and display aspect ratios differ significantly, it's likely anamorphic
 	tolerance := 0.01
-	return math.Abs(sampleAR-displayAR) > tolerance
+	return math.Abs(sampleAR-1) > tolerance
 }
 
 // categorizeAspectRatio categorizes the aspect ratio
@@ -237,31 +247,12 @@ func (ra *ResolutionAnalyzer) getOrientation(width, height int) string {
 	}
 }
 
-// validateResolutionConsistency validates resolution metadata consistency
-func (ra *ResolutionAnalyzer) validateResolutionConsistency(resolution *VideoResolution, stream StreamInfo) bool {
-	issues := 0
-
-	// Check if coded dimensions match display dimensions
-	// Note: Coded dimensions can differ from display dimensions due to cropping
-	// This is informational only, not an error condition
-	_ = stream.CodedWidth
-	_ = stream.CodedHeight
-
-	// Check aspect ratio consistency
-	if resolution.PixelAspectRatio > 0 && resolution.DisplayAspectRatio > 0 {
-		// Calculate expected display AR from pixel AR and sample AR
-		expectedDisplayAR := resolution.PixelAspectRatio
-		if resolution.SampleAspectRatio > 0 {
-			expectedDisplayAR *= resolution.SampleAspectRatio
-		}
-
-		tolerance := 0.1
-		if math.Abs(expectedDisplayAR-resolution.DisplayAspectRatio) > tolerance {
-			issues++
-		}
-	}
-
-	return issues == 0
+// validateResolutionConsistency validates resolution metadata consistency:
+// the declared display aspect ratio must match the picture size and sample
+// aspect ratio
+func (ra *ResolutionAnalyzer) validateResolutionConsistency(resolution *VideoResolution) bool {
+	return resolution.DisplayAspectRatio <= 0 || resolution.ExpectedDisplayAspectRatio <= 0 ||
+		sameAspectRatio(resolution.DisplayAspectRatio, resolution.ExpectedDisplayAspectRatio)
 }
 
 // isWidescreenContent determines if the content is primarily widescreen
@@ -320,6 +311,15 @@ func (ra *ResolutionAnalyzer) validateResolution(analysis *ResolutionAnalysis) *
 			validation.IsValid = false
 		}
 
+		for _, issue := range videoRes.AspectRatioIssues {
+			validation.Issues = append(validation.Issues, fmt.Sprintf("Video stream %d: %s", streamIndex, issue))
+		}
+		if videoRes.IsAnamorphicSD {
+			validation.Recommendations = append(validation.Recommendations, fmt.Sprintf(
+				"Video stream %d is anamorphic SD (%dx%d displayed at %.3f) - carry the sample aspect ratio through transcodes (setsar) or scale to square pixels (scale=%d:%d,setsar=1)",
+				streamIndex, videoRes.Width, videoRes.Height, videoRes.ExpectedDisplayAspectRatio, videoRes.SquarePixelWidth, videoRes.Height))
+		}
+
 		// Check for unusual aspect ratios
 		if videoRes.DisplayAspectRatio > 0 {
 			if videoRes.DisplayAspectRatio < 0.5 || videoRes.DisplayAspectRatio > 4.0 {
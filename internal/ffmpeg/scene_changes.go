@@ -0,0 +1,76 @@
+package ffmpeg
+
+import (
+	"strings"
+)
+
+// sceneChangeThreshold is the scene score (0-1) above which content analysis
+// reports a cut
+const sceneChangeThreshold = 0.3
+
+// maxSceneChanges bounds the cuts listed per file; Count still covers all
+const maxSceneChanges = 1000
+
+// SceneChangeAnalysis lists the cuts detected in the video
+type SceneChangeAnalysis struct {
+	Threshold float64       `json:"threshold"`
+	Count     int           `json:"count"`
+	Changes   []SceneChange `json:"changes,omitempty"` // The first 1000 cuts, in time order
+}
+
+// SceneChange is a cut between two shots
+type SceneChange struct {
+	Timestamp     float64 `json:"timestamp"`                    // Seconds from the start of the file
+	Score         float64 `json:"score"`                        // Scene score of the first frame of the new shot, 0-1
+	PrecedingShot float64 `json:"preceding_shot_sec"`           // Length of the shot that ends at the cut
+	FollowingShot float64 `json:"following_shot_sec,omitempty"` // Length of the shot that starts at the cut; omitted when the file duration is unknown
+}
+
+// parseSceneChanges reads the frames passed by select='gt(scene,T)' and
+// logged by metadata=print, e.g.
+//
+//	[Parsed_metadata_2 @ 0x55a4] frame:0    pts:105472  pts_time:4.12
+//	[Parsed_metadata_2 @ 0x55a4] lavfi.scene_score=0.563210
+//
+// duration is the length of the file, used for the shot after the last cut.
+func parseSceneChanges(output []byte, duration float64) *SceneChangeAnalysis {
+	analysis := &SceneChangeAnalysis{Threshold: sceneChangeThreshold}
+	var times, scores []float64
+
+	forEachLine(output, func(line string) bool {
+		if !strings.Contains(line, "Parsed_metadata") {
+			return true
+		}
+		if index := strings.Index(line, "pts_time:"); index >= 0 {
+			if value, ok := parseFiniteValue(line[index+len("pts_time:"):]); ok && value >= 0 {
+				times = append(times, value)
+				scores = append(scores, 0)
+			}
+		} else if index := strings.Index(line, "lavfi.scene_score="); index >= 0 && len(scores) > 0 {
+			scores[len(scores)-1], _ = parseFiniteValue(line[index+len("lavfi.scene_score="):])
+		}
+		return true
+	})
+
+	analysis.Count = len(times)
+	for i, timestamp := range times {
+		if i == maxSceneChanges {
+			break
+		}
+		change := SceneChange{
+			Timestamp:     timestamp,
+			Score:         scores[i],
+			PrecedingShot: timestamp,
+		}
+		if i > 0 {
+			change.PrecedingShot = timestamp - times[i-1]
+		}
+		if i+1 < len(times) {
+			change.FollowingShot = times[i+1] - timestamp
+		} else if duration > timestamp {
+			change.FollowingShot = duration - timestamp
+		}
+		analysis.Changes = append(analysis.Changes, change)
+	}
+	return analysis
+}
@@ -0,0 +1,56 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseSceneChanges(t *testing.T) {
+	output := []byte(`Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'clip.mp4':
+  Duration: 00:00:30.00, start: 0.000000, bitrate: 2000 kb/s
+[Parsed_metadata_2 @ 0x55a4] frame:0    pts:105472  pts_time:4.12
+[Parsed_metadata_2 @ 0x55a4] lavfi.scene_score=0.563210
+[Parsed_metadata_2 @ 0x55a4] frame:1    pts:256000  pts_time:10
+[Parsed_metadata_2 @ 0x55a4] lavfi.scene_score=0.912000
+[Parsed_metadata_2 @ 0x55a4] frame:2    pts:537600  pts_time:21
+[Parsed_metadata_2 @ 0x55a4] lavfi.scene_score=0.350000
+[Parsed_showinfo_3 @ 0x55a5] n:   0 pts: 105472 pts_time:4.12
+`)
+
+	scenes := parseSceneChanges(output, 30)
+
+	if scenes.Count != 3 || len(scenes.Changes) != 3 || scenes.Threshold != sceneChangeThreshold {
+		t.Fatalf("expected 3 scene changes, got %+v", scenes)
+	}
+	first := scenes.Changes[0]
+	if first.Timestamp != 4.12 || first.Score != 0.56321 || first.PrecedingShot != 4.12 {
+		t.Errorf("unexpected first change %+v", first)
+	}
+	if diff := first.FollowingShot - 5.88; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("expected a 5.88s following shot, got %v", first.FollowingShot)
+	}
+	if last := scenes.Changes[2]; last.PrecedingShot != 11 || last.FollowingShot != 9 {
+		t.Errorf("unexpected last change %+v", last)
+	}
+
+	if unknown := parseSceneChanges(output, 0); unknown.Changes[2].FollowingShot != 0 {
+		t.Errorf("expected no following shot without a duration, got %v", unknown.Changes[2].FollowingShot)
+	}
+}
+
+func TestParseSceneChanges_Capped(t *testing.T) {
+	var output strings.Builder
+	for i := 0; i < maxSceneChanges+5; i++ {
+		fmt.Fprintf(&output, "[Parsed_metadata_2 @ 0x1] frame:%d pts:%d pts_time:%d\n[Parsed_metadata_2 @ 0x1] lavfi.scene_score=0.5\n", i, i, i+1)
+	}
+
+	scenes := parseSceneChanges([]byte(output.String()), 0)
+
+	if scenes.Count != maxSceneChanges+5 || len(scenes.Changes) != maxSceneChanges {
+		t.Errorf("expected %d of %d changes listed, got %d of %d", maxSceneChanges, maxSceneChanges+5, len(scenes.Changes), scenes.Count)
+	}
+	if last := scenes.Changes[maxSceneChanges-1]; last.FollowingShot != 1 {
+		t.Errorf("expected the last listed change to know its following shot, got %+v", last)
+	}
+}
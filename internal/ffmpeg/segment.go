@@ -0,0 +1,169 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// AnalysisSegment limits an analysis to a portion of the asset: ffprobe
+// reads it through -read_intervals and the ffmpeg content analyzers and
+// decode scan through -ss and -t. Event timestamps stay positions in the
+// asset.
+type AnalysisSegment struct {
+	// StartTime is where the segment starts, in seconds. A negative start
+	// counts back from the end, so -600 is the last ten minutes.
+	StartTime float64 `json:"start_time"`
+	// Duration is the length of the segment in seconds; zero runs to the end
+	Duration float64 `json:"duration,omitempty"`
+}
+
+// ErrInvalidSegment is returned for a segment outside the asset or not a
+// range at all
+var ErrInvalidSegment = errors.New("invalid segment")
+
+// NewAnalysisSegment returns the segment of a request's start_time and
+// duration, or nil when both are zero and the whole asset is analyzed
+func NewAnalysisSegment(startTime, duration float64) (*AnalysisSegment, error) {
+	if startTime == 0 && duration == 0 {
+		return nil, nil
+	}
+	segment := &AnalysisSegment{StartTime: startTime, Duration: duration}
+	if err := segment.Validate(); err != nil {
+		return nil, err
+	}
+	return segment, nil
+}
+
+// Validate checks the segment is a finite, non-empty range
+func (s *AnalysisSegment) Validate() error {
+	if math.IsNaN(s.StartTime) || math.IsInf(s.StartTime, 0) {
+		return fmt.Errorf("%w: start_time must be a number of seconds", ErrInvalidSegment)
+	}
+	if math.IsNaN(s.Duration) || math.IsInf(s.Duration, 0) || s.Duration < 0 {
+		return fmt.Errorf("%w: duration must be a positive number of seconds", ErrInvalidSegment)
+	}
+	return nil
+}
+
+// resolve returns the segment with a negative start counted back from the
+// end of an asset lasting total seconds, and the duration cut at the end.
+// A total of zero means the length is unknown.
+func (s *AnalysisSegment) resolve(total float64) (*AnalysisSegment, error) {
+	resolved := *s
+	if resolved.StartTime < 0 {
+		if total <= 0 {
+			return nil, fmt.Errorf("%w: a start_time before the end needs an asset of known duration", ErrInvalidSegment)
+		}
+		resolved.StartTime = math.Max(0, total+resolved.StartTime)
+	}
+	if total > 0 {
+		if resolved.StartTime >= total {
+			return nil, fmt.Errorf("%w: start_time %.3fs is past the end of the asset (%.3fs)", ErrInvalidSegment, resolved.StartTime, total)
+		}
+		if resolved.Duration == 0 || resolved.StartTime+resolved.Duration > total {
+			resolved.Duration = total - resolved.StartTime
+		}
+	}
+	return &resolved, nil
+}
+
+// readIntervals returns the segment in -read_intervals syntax
+func (s *AnalysisSegment) readIntervals() string {
+	if s.Duration > 0 {
+		return formatSeconds(s.StartTime) + "%+" + formatSeconds(s.Duration)
+	}
+	return formatSeconds(s.StartTime) + "%"
+}
+
+// inputArgs returns the ffmpeg input arguments that read only the segment
+// of filePath
+func (s *AnalysisSegment) inputArgs(filePath string) []string {
+	args := []string{"-ss", formatSeconds(s.StartTime)}
+	if s.Duration > 0 {
+		args = append(args, "-t", formatSeconds(s.Duration))
+	}
+	return append(args, "-i", filePath)
+}
+
+type segmentKey struct{}
+
+// withSegment makes the analyses under ctx read only the resolved segment
+func withSegment(ctx context.Context, segment *AnalysisSegment) context.Context {
+	if segment == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, segmentKey{}, segment)
+}
+
+func segmentFromContext(ctx context.Context) *AnalysisSegment {
+	segment, _ := ctx.Value(segmentKey{}).(*AnalysisSegment)
+	return segment
+}
+
+// Segment limits the analysis to a portion of the input; nil analyzes all
+// of it
+func (b *OptionsBuilder) Segment(segment *AnalysisSegment) *OptionsBuilder {
+	b.options.Segment = segment
+	return b
+}
+
+type analysisSegmentKey struct{}
+
+// WithAnalysisSegment returns ctx carrying the segment of a request, so it
+// reaches the analyses it starts
+func WithAnalysisSegment(ctx context.Context, segment *AnalysisSegment) context.Context {
+	if segment == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, analysisSegmentKey{}, segment)
+}
+
+// AnalysisSegmentFromContext returns the segment carried by ctx, or nil
+func AnalysisSegmentFromContext(ctx context.Context) *AnalysisSegment {
+	segment, _ := ctx.Value(analysisSegmentKey{}).(*AnalysisSegment)
+	return segment
+}
+
+// resolveSegment resolves the segment of options against the duration of
+// the input
+func (f *FFprobe) resolveSegment(ctx context.Context, options *FFprobeOptions) (*AnalysisSegment, error) {
+	if options.ReadIntervals != "" {
+		return nil, fmt.Errorf("%w: a segment and read_intervals cannot be combined", ErrInvalidSegment)
+	}
+	if err := options.Segment.Validate(); err != nil {
+		return nil, err
+	}
+
+	total, err := f.formatDuration(ctx, options.Input)
+	if err != nil {
+		return nil, err
+	}
+	return options.Segment.resolve(total)
+}
+
+// formatDuration reads the container duration of input, zero if it has none
+func (f *FFprobe) formatDuration(ctx context.Context, input string) (float64, error) {
+	if err := validateInput(input); err != nil {
+		return 0, fmt.Errorf("invalid input: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, f.binaryPath,
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		input,
+	)
+	output, err := commandOutput(ctx, cmd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read duration: %w", err)
+	}
+	duration, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return 0, nil
+	}
+	return duration, nil
+}
@@ -0,0 +1,156 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestNewAnalysisSegment(t *testing.T) {
+	if segment, err := NewAnalysisSegment(0, 0); err != nil || segment != nil {
+		t.Errorf("expected no segment for the whole asset, got %v, %v", segment, err)
+	}
+	segment, err := NewAnalysisSegment(-600, 0)
+	if err != nil || segment.StartTime != -600 || segment.Duration != 0 {
+		t.Errorf("unexpected segment %+v, %v", segment, err)
+	}
+	if _, err := NewAnalysisSegment(10, -5); !errors.Is(err, ErrInvalidSegment) {
+		t.Errorf("expected a negative duration to be invalid, got %v", err)
+	}
+}
+
+func TestAnalysisSegment_Resolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		segment AnalysisSegment
+		total   float64
+		want    AnalysisSegment
+		wantErr bool
+	}{
+		{"last ten minutes", AnalysisSegment{StartTime: -600}, 3600, AnalysisSegment{StartTime: 3000, Duration: 600}, false},
+		{"before the start", AnalysisSegment{StartTime: -600}, 300, AnalysisSegment{StartTime: 0, Duration: 300}, false},
+		{"cut at the end", AnalysisSegment{StartTime: 3500, Duration: 300}, 3600, AnalysisSegment{StartTime: 3500, Duration: 100}, false},
+		{"unknown length", AnalysisSegment{StartTime: 60}, 0, AnalysisSegment{StartTime: 60}, false},
+		{"from the end of unknown length", AnalysisSegment{StartTime: -60}, 0, AnalysisSegment{}, true},
+		{"past the end", AnalysisSegment{StartTime: 4000}, 3600, AnalysisSegment{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.segment.resolve(tt.total)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSegment) {
+					t.Errorf("expected ErrInvalidSegment, got %v", err)
+				}
+				return
+			}
+			if err != nil || *got != tt.want {
+				t.Errorf("resolve(%v) = %+v, %v, want %+v", tt.total, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalysisSegment_Args(t *testing.T) {
+	segment := &AnalysisSegment{StartTime: 3000, Duration: 600}
+	if got := segment.readIntervals(); got != "3000.000%+600.000" {
+		t.Errorf("readIntervals() = %q", got)
+	}
+	if err := validateReadIntervals(segment.readIntervals()); err != nil {
+		t.Errorf("segment intervals rejected: %v", err)
+	}
+	want := []string{"-ss", "3000.000", "-t", "600.000", "-i", "in.mxf"}
+	if got := segment.inputArgs("in.mxf"); !reflect.DeepEqual(got, want) {
+		t.Errorf("inputArgs() = %v, want %v", got, want)
+	}
+
+	open := &AnalysisSegment{StartTime: 60}
+	if got := open.readIntervals(); got != "60.000%" {
+		t.Errorf("readIntervals() = %q", got)
+	}
+	if got := open.inputArgs("in.mxf"); !reflect.DeepEqual(got, []string{"-ss", "60.000", "-i", "in.mxf"}) {
+		t.Errorf("inputArgs() = %v", got)
+	}
+}
+
+func TestSegmentPlan(t *testing.T) {
+	segment := &AnalysisSegment{StartTime: 3000, Duration: 600}
+	ca := NewContentAnalyzer("ffmpeg", zerolog.Nop())
+	ctx, plan := ca.startSampling(withSegment(context.Background(), segment), "in.mxf")
+	if plan == nil {
+		t.Fatal("expected a plan for the segment")
+	}
+	defer plan.close()
+
+	if got := contentInputArgs(ctx, "in.mxf"); !reflect.DeepEqual(got, segment.inputArgs("in.mxf")) {
+		t.Errorf("contentInputArgs() = %v", got)
+	}
+
+	analysis := &ContentAnalysis{
+		BlackFrames:  &BlackFrameAnalysis{DetectedFrames: 2, Periods: []EventPeriod{{StartTime: 10, EndTime: 12, Duration: 2}}},
+		SceneChanges: &SceneChangeAnalysis{Count: 1, Changes: []SceneChange{{Timestamp: 0.2}}},
+	}
+	plan.apply(analysis)
+
+	if analysis.Sampling != nil {
+		t.Errorf("a segment is not sampling, got %+v", analysis.Sampling)
+	}
+	if analysis.BlackFrames.DetectedFrames != 2 {
+		t.Errorf("expected counts of the segment, got %d", analysis.BlackFrames.DetectedFrames)
+	}
+	if period := analysis.BlackFrames.Periods[0]; period.StartTime != 3010 || period.EndTime != 3012 {
+		t.Errorf("expected positions in the asset, got %+v", period)
+	}
+	if scenes := analysis.SceneChanges; scenes.Count != 1 || scenes.Changes[0].Timestamp != 3000.2 {
+		t.Errorf("expected the cut kept at its position in the asset, got %+v", scenes)
+	}
+}
+
+func TestProbeSegment(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "record.ts")
+	if err := os.WriteFile(input, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\n" +
+		"case \"$*\" in *format=duration*) echo 3600.000000; exit 0;; esac\n" +
+		"echo \"$@\" > " + argsFile + "\n" +
+		"echo '{\"format\": {\"filename\": \"record.ts\", \"duration\": \"3600.000000\"}}'\n"
+	binary := filepath.Join(dir, "ffprobe")
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ffprobe := NewFFprobe(binary, zerolog.Nop())
+
+	options := NewOptionsBuilder().Input(input).JSON().ShowFormat().MetadataOnly().
+		Segment(&AnalysisSegment{StartTime: -600}).Build()
+	result, err := ffprobe.Probe(t.Context(), options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Segment == nil || *result.Segment != (AnalysisSegment{StartTime: 3000, Duration: 600}) {
+		t.Errorf("expected the resolved segment, got %+v", result.Segment)
+	}
+	args, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "-read_intervals 3000.000%+600.000") {
+		t.Errorf("expected the segment as read intervals, got %s", args)
+	}
+	if options.ReadIntervals != "" {
+		t.Error("the caller's options must not be changed")
+	}
+
+	options = NewOptionsBuilder().Input(input).JSON().ShowFormat().MetadataOnly().
+		Segment(&AnalysisSegment{StartTime: 4000}).Build()
+	if _, err := ffprobe.Probe(t.Context(), options); !errors.Is(err, ErrInvalidSegment) {
+		t.Errorf("expected a segment past the end to be invalid, got %v", err)
+	}
+}
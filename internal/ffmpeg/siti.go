@@ -0,0 +1,57 @@
+package ffmpeg
+
+import "strings"
+
+// SITIMeasurement is the ITU-T P.910 spatial and temporal information of the
+// luma plane, as measured by the siti filter. Higher SI means more detail
+// per frame, higher TI more change between frames.
+type SITIMeasurement struct {
+	SpatialAverage  float64 `json:"si_average"`
+	SpatialMax      float64 `json:"si_max"`
+	TemporalAverage float64 `json:"ti_average"`
+	TemporalMax     float64 `json:"ti_max"`
+	Frames          int     `json:"frames"`
+}
+
+// parseSITISummary reads the summary the siti filter logs with
+// print_summary=1. It returns nil when the summary is missing.
+func parseSITISummary(output []byte) *SITIMeasurement {
+	var siti *SITIMeasurement
+	section := ""
+
+	forEachLine(output, func(line string) bool {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasSuffix(trimmed, "SITI Summary:"):
+			siti = &SITIMeasurement{}
+			section = ""
+		case siti == nil:
+		case trimmed == "Spatial Information:" || trimmed == "Temporal Information:":
+			section = trimmed
+		case strings.HasPrefix(trimmed, "Total frames:"):
+			if frames, ok := parseFiniteValue(strings.TrimPrefix(trimmed, "Total frames:")); ok {
+				siti.Frames = int(frames)
+			}
+		case strings.HasPrefix(trimmed, "Average:"):
+			value, _ := parseFiniteValue(strings.TrimPrefix(trimmed, "Average:"))
+			if section == "Spatial Information:" {
+				siti.SpatialAverage = value
+			} else if section == "Temporal Information:" {
+				siti.TemporalAverage = value
+			}
+		case strings.HasPrefix(trimmed, "Max:"):
+			value, _ := parseFiniteValue(strings.TrimPrefix(trimmed, "Max:"))
+			if section == "Spatial Information:" {
+				siti.SpatialMax = value
+			} else if section == "Temporal Information:" {
+				siti.TemporalMax = value
+			}
+		}
+		return true
+	})
+
+	if siti != nil && siti.Frames == 0 {
+		return nil
+	}
+	return siti
+}
@@ -0,0 +1,29 @@
+package ffmpeg
+
+import "testing"
+
+func TestParseSITISummary(t *testing.T) {
+	output := []byte(`[Parsed_signalstats_1 @ 0x55d0] lavfi.scene_score=0.41
+[Parsed_siti_0 @ 0x55d0] SITI Summary:
+Total frames: 250
+
+Spatial Information:
+Average: 63.218750
+Max: 80.500000
+Min: 41.000000
+
+Temporal Information:
+Average: 12.250000
+Max: 48.750000
+Min: 0.000000
+`)
+	siti := parseSITISummary(output)
+	want := SITIMeasurement{SpatialAverage: 63.21875, SpatialMax: 80.5, TemporalAverage: 12.25, TemporalMax: 48.75, Frames: 250}
+	if siti == nil || *siti != want {
+		t.Errorf("parseSITISummary = %+v, want %+v", siti, want)
+	}
+
+	if siti := parseSITISummary([]byte("Average: 12\nMax: 40\n")); siti != nil {
+		t.Errorf("parsed %+v without a summary", siti)
+	}
+}
@@ -0,0 +1,66 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FormatTimecode formats a frame count from midnight as HH:MM:SS:FF at the
+// rounded timecode base (30 for 29.97 fps). Drop frame timecode skips frame
+// numbers, so it uses ';' and the SMPTE 12M numbering.
+func FormatTimecode(frames int64, base int, dropFrame bool) string {
+	if base <= 0 {
+		return ""
+	}
+	separator := ":"
+	if dropFrame && base%30 == 0 {
+		separator = ";"
+		dropped := int64(base / 15) // Frame numbers dropped each minute
+		perMinute := int64(base)*60 - dropped
+		perTenMinutes := perMinute*10 + dropped
+		tens, rest := frames/perTenMinutes, frames%perTenMinutes
+		frames += 9 * dropped * tens
+		if rest > dropped {
+			frames += dropped * ((rest - dropped) / perMinute)
+		}
+	}
+	fps := int64(base)
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", frames/(fps*3600)%24, frames/(fps*60)%60, frames/fps%60, separator, frames%fps)
+}
+
+// ParseTimecode parses HH:MM:SS:FF into a frame count from midnight at the
+// rounded timecode base. A ';' or '.' before the frames marks drop frame
+// timecode, which is reported so it can be formatted back the same way.
+func ParseTimecode(timecode string, base int) (frames int64, dropFrame bool, err error) {
+	timecode = strings.TrimSpace(timecode)
+	if len(timecode) != 11 || base <= 0 {
+		return 0, false, fmt.Errorf("invalid timecode %q", timecode)
+	}
+	dropFrame = timecode[8] == ';' || timecode[8] == '.'
+
+	var parts [4]int64
+	for i := range parts {
+		part, err := strconv.ParseInt(timecode[i*3:i*3+2], 10, 64)
+		if err != nil || part < 0 {
+			return 0, false, fmt.Errorf("invalid timecode %q", timecode)
+		}
+		parts[i] = part
+	}
+	hours, minutes, seconds, frame := parts[0], parts[1], parts[2], parts[3]
+	if minutes > 59 || seconds > 59 || frame >= int64(base) {
+		return 0, false, fmt.Errorf("invalid timecode %q", timecode)
+	}
+
+	frames = (hours*3600+minutes*60+seconds)*int64(base) + frame
+	if dropFrame && base%30 == 0 {
+		totalMinutes := hours*60 + minutes
+		frames -= int64(base/15) * (totalMinutes - totalMinutes/10)
+	}
+	return frames, dropFrame, nil
+}
+
+// TimecodeBase rounds a frame rate to its timecode base, e.g. 29.97 to 30
+func TimecodeBase(frameRate float64) int {
+	return int(frameRate + 0.5)
+}
@@ -0,0 +1,33 @@
+package ffmpeg
+
+import "testing"
+
+func TestParseTimecode(t *testing.T) {
+	cases := []struct {
+		timecode  string
+		base      int
+		frames    int64
+		dropFrame bool
+	}{
+		{"01:00:00:00", 25, 90000, false},
+		{"01:00:00;00", 30, 107892, true},
+		{"00:01:00;02", 30, 1800, true},
+		{"00:10:00;00", 30, 17982, true},
+		{"10:00:00:12", 24, 864012, false},
+	}
+	for _, c := range cases {
+		frames, dropFrame, err := ParseTimecode(c.timecode, c.base)
+		if err != nil || frames != c.frames || dropFrame != c.dropFrame {
+			t.Errorf("ParseTimecode(%q, %d) = %d, %v, %v; want %d, %v", c.timecode, c.base, frames, dropFrame, err, c.frames, c.dropFrame)
+		}
+		if formatted := FormatTimecode(frames, c.base, dropFrame); formatted != c.timecode {
+			t.Errorf("FormatTimecode(%d) = %q, want %q", frames, formatted, c.timecode)
+		}
+	}
+
+	for _, invalid := range []string{"", "1:00:00:00", "01:60:00:00", "01:00:00:25", "aa:00:00:00"} {
+		if _, _, err := ParseTimecode(invalid, 25); err == nil {
+			t.Errorf("ParseTimecode(%q) succeeded, want an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// Checks of a stitch validation
+const (
+	StitchCheckStreams         = "streams"          // The parts carry the same streams
+	StitchCheckCodecParameters = "codec_parameters" // Each stream is coded the same way in every part
+	StitchCheckDurations       = "durations"        // Audio and video of a part end together
+	StitchCheckTimecode        = "timecode"         // Timecode runs on across each join
+	StitchCheckLoudness        = "loudness"         // Loudness does not jump at a join
+)
+
+// Severities of a stitch issue
+const (
+	StitchIssueError   = "error"   // The parts cannot be joined cleanly
+	StitchIssueWarning = "warning" // Joins, but worth reviewing
+)
+
+// stitchLoudnessTolerance is the integrated loudness difference, in LU,
+// between adjacent parts that is audible as a jump at the join
+const stitchLoudnessTolerance = 1.0
+
+// stitchDurationTolerance is the difference, in seconds, between the audio
+// and video of a part that is allowed when there is no frame rate to
+// measure it in
+const stitchDurationTolerance = 0.04
+
+// StitchValidator checks that the reels or segments of a delivery
+// concatenate cleanly
+type StitchValidator struct {
+	ffmpegPath string
+	logger     zerolog.Logger
+}
+
+// NewStitchValidator creates a new stitch validator
+func NewStitchValidator(ffmpegPath string, logger zerolog.Logger) *StitchValidator {
+	return &StitchValidator{
+		ffmpegPath: ffmpegPath,
+		logger:     logger,
+	}
+}
+
+// StitchPart is one file of a multi-part delivery and its probe result
+type StitchPart struct {
+	Name   string
+	Path   string
+	Result *FFprobeResult
+}
+
+// StitchAnalysis reports whether the parts of a delivery, in order,
+// concatenate cleanly
+type StitchAnalysis struct {
+	Parts           []StitchPartSummary `json:"parts"`
+	IsCompatible    bool                `json:"is_compatible"` // No error-severity issues
+	Issues          []StitchIssue       `json:"issues,omitempty"`
+	Recommendations []string            `json:"recommendations,omitempty"`
+}
+
+// StitchPartSummary holds the properties of a part the joins are checked on
+type StitchPartSummary struct {
+	Index              int      `json:"index"`
+	Name               string   `json:"name"`
+	Duration           float64  `json:"duration"`
+	FrameRate          float64  `json:"frame_rate,omitempty"`
+	Frames             int64    `json:"frames,omitempty"`
+	StartTimecode      string   `json:"start_timecode,omitempty"`
+	EndTimecode        string   `json:"end_timecode,omitempty"` // The timecode the next part should start at
+	IntegratedLoudness *float64 `json:"integrated_loudness,omitempty"`
+
+	timecodeFrames int64 // Start timecode as frames from midnight, or -1
+	dropFrame      bool
+}
+
+// StitchIssue is a reason the parts may not concatenate cleanly
+type StitchIssue struct {
+	Check    string `json:"check"`
+	Severity string `json:"severity"` // "error" or "warning"
+	Parts    []int  `json:"parts"`    // Indexes into the list of parts
+	Message  string `json:"message"`
+}
+
+// ValidateStitch checks that parts, in playback order, carry the same
+// streams coded the same way, that audio and video of each part end
+// together, that timecode runs on across each join and that loudness does
+// not jump at one. The integrated loudness of the first audio stream of each
+// part is measured; a part it cannot be measured for is left out of the
+// loudness check.
+func (sv *StitchValidator) ValidateStitch(ctx context.Context, parts []StitchPart) (*StitchAnalysis, error) {
+	loudness := make([]*float64, len(parts))
+	for i, part := range parts {
+		if !hasStreamType(part.Result, "audio") {
+			continue
+		}
+		integrated, err := sv.measureLoudness(ctx, part.Path)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			sv.logger.Debug().Err(err).Str("part", part.Name).Msg("Failed to measure part loudness")
+			continue
+		}
+		loudness[i] = &integrated
+	}
+	return checkStitch(parts, loudness), nil
+}
+
+// measureLoudness measures the integrated loudness of the first audio
+// stream of filePath
+func (sv *StitchValidator) measureLoudness(ctx context.Context, filePath string) (float64, error) {
+	cmd := exec.CommandContext(ctx, sv.ffmpegPath,
+		"-hide_banner", "-nostats",
+		"-i", filePath,
+		"-map", "0:a:0",
+		"-af", "ebur128",
+		"-f", "null",
+		"-",
+	)
+	output, err := commandCombinedOutput(ctx, cmd)
+	if err != nil {
+		return 0, fmt.Errorf("loudness measurement failed: %w", err)
+	}
+	return parseLoudnessOutput(output).IntegratedLoudness, nil
+}
+
+// checkStitch runs the checks of a stitch validation on parts probed and,
+// where loudness holds a value, measured
+func checkStitch(parts []StitchPart, loudness []*float64) *StitchAnalysis {
+	analysis := &StitchAnalysis{IsCompatible: true}
+	for i, part := range parts {
+		summary := summarizeStitchPart(i, part)
+		summary.IntegratedLoudness = loudness[i]
+		analysis.Parts = append(analysis.Parts, summary)
+	}
+
+	for i := 1; i < len(parts); i++ {
+		analysis.checkStreams(parts[0], parts[i], 0, i)
+	}
+	for i, part := range parts {
+		analysis.checkDurations(part, i)
+	}
+	for i := 1; i < len(parts); i++ {
+		analysis.checkTimecode(i-1, i)
+		analysis.checkLoudness(i-1, i)
+	}
+
+	recommendations := map[string]string{
+		StitchCheckStreams:         "Conform every part to the streams of the first before concatenating, or join them with the concat filter and re-encode",
+		StitchCheckCodecParameters: "Conform every part to the codec parameters of the first before concatenating, or join them with the concat filter and re-encode",
+		StitchCheckDurations:       "Trim or pad the audio of each part to its video so the joins stay in sync",
+		StitchCheckTimecode:        "Restripe the timecode of the later parts to run on from the part before, or start each reel on the hour",
+		StitchCheckLoudness:        fmt.Sprintf("Normalize the parts to one loudness target so adjacent parts differ by no more than %.0f LU", stitchLoudnessTolerance),
+	}
+	seen := make(map[string]bool)
+	for _, issue := range analysis.Issues {
+		if issue.Severity == StitchIssueError {
+			analysis.IsCompatible = false
+		}
+		if !seen[issue.Check] {
+			seen[issue.Check] = true
+			analysis.Recommendations = append(analysis.Recommendations, recommendations[issue.Check])
+		}
+	}
+	return analysis
+}
+
+// summarizeStitchPart reads the duration, frame rate and start timecode of
+// a part
+func summarizeStitchPart(index int, part StitchPart) StitchPartSummary {
+	summary := StitchPartSummary{Index: index, Name: part.Name, timecodeFrames: -1}
+	result := part.Result
+	if result == nil {
+		return summary
+	}
+	if result.Format != nil {
+		summary.Duration, _ = strconv.ParseFloat(result.Format.Duration, 64)
+	}
+
+	video := firstStreamOfType(result, "video")
+	if video != nil {
+		summary.FrameRate = roundTo(parseFrameRateString(video.RFrameRate), 3)
+		if duration, err := strconv.ParseFloat(video.Duration, 64); err == nil && duration > 0 {
+			summary.Duration = duration
+		}
+		if frames, err := strconv.ParseInt(video.NBFrames, 10, 64); err == nil && frames > 0 {
+			summary.Frames = frames
+		} else if summary.FrameRate > 0 {
+			summary.Frames = int64(math.Round(summary.Duration * summary.FrameRate))
+		}
+	}
+
+	timecode := ""
+	if result.Format != nil {
+		timecode = result.Format.Tags["timecode"]
+	}
+	for _, stream := range result.Streams {
+		if timecode != "" {
+			break
+		}
+		timecode = stream.Tags["timecode"]
+	}
+	base := TimecodeBase(summary.FrameRate)
+	if frames, dropFrame, err := ParseTimecode(timecode, base); err == nil {
+		summary.StartTimecode = timecode
+		summary.EndTimecode = FormatTimecode(frames+summary.Frames, base, dropFrame)
+		summary.timecodeFrames = frames
+		summary.dropFrame = dropFrame
+	}
+	summary.Duration = roundTo(summary.Duration, 3)
+	return summary
+}
+
+// checkStreams compares the streams of part, at index j, to those of
+// first, at index i, by type and position within the type
+func (a *StitchAnalysis) checkStreams(first, part StitchPart, i, j int) {
+	firstStreams, partStreams := stitchStreams(first.Result), stitchStreams(part.Result)
+	for _, codecType := range []string{"video", "audio", "subtitle", "data"} {
+		x, y := firstStreams[codecType], partStreams[codecType]
+		if len(x) != len(y) {
+			a.addIssue(StitchCheckStreams, StitchIssueError, []int{i, j}, fmt.Sprintf("%s has %d %s streams where %s has %d",
+				part.Name, len(y), codecType, first.Name, len(x)))
+		}
+		for k := 0; k < len(x) && k < len(y); k++ {
+			var differences []string
+			for _, parameter := range stitchParameters(x[k]) {
+				value := stitchParameterValue(y[k], parameter.name)
+				if parameter.value != "" && value != "" && value != parameter.value {
+					differences = append(differences, fmt.Sprintf("%s %s rather than %s", parameter.name, value, parameter.value))
+				}
+			}
+			if len(differences) > 0 {
+				a.addIssue(StitchCheckCodecParameters, StitchIssueError, []int{i, j}, fmt.Sprintf("%s stream %d of %s differs from %s: %s",
+					codecType, k, part.Name, first.Name, strings.Join(differences, ", ")))
+			}
+		}
+	}
+}
+
+// checkDurations flags audio streams of a part that end more than a frame
+// away from its video, a difference that accumulates at each join
+func (a *StitchAnalysis) checkDurations(part StitchPart, i int) {
+	summary := a.Parts[i]
+	video := firstStreamOfType(part.Result, "video")
+	if video == nil || summary.Duration <= 0 {
+		return
+	}
+	tolerance := stitchDurationTolerance
+	if summary.FrameRate > 0 {
+		tolerance = 1 / summary.FrameRate
+	}
+	for k, stream := range stitchStreams(part.Result)["audio"] {
+		duration, err := strconv.ParseFloat(stream.Duration, 64)
+		if err != nil || duration <= 0 {
+			continue
+		}
+		if difference := duration - summary.Duration; math.Abs(difference) > tolerance {
+			direction := "longer"
+			if difference < 0 {
+				direction = "shorter"
+			}
+			a.addIssue(StitchCheckDurations, StitchIssueWarning, []int{i}, fmt.Sprintf("audio stream %d of %s is %.0f ms %s than its video, which drifts audio at the join",
+				k, part.Name, math.Abs(difference)*1000, direction))
+		}
+	}
+}
+
+// checkTimecode checks that part j starts at the timecode part i ends at.
+// A part starting on a later hour follows the convention of starting each
+// reel on the hour and is not a break.
+func (a *StitchAnalysis) checkTimecode(i, j int) {
+	previous, next := a.Parts[i], a.Parts[j]
+	hasPrevious, hasNext := previous.timecodeFrames >= 0, next.timecodeFrames >= 0
+	if hasPrevious != hasNext {
+		missing := previous
+		if hasPrevious {
+			missing = next
+		}
+		a.addIssue(StitchCheckTimecode, StitchIssueWarning, []int{i, j}, fmt.Sprintf("%s has no timecode to continue across the join", missing.Name))
+		return
+	}
+	if !hasPrevious || TimecodeBase(previous.FrameRate) != TimecodeBase(next.FrameRate) {
+		return
+	}
+	if previous.dropFrame != next.dropFrame {
+		a.addIssue(StitchCheckTimecode, StitchIssueError, []int{i, j}, fmt.Sprintf("%s and %s mix drop frame and non-drop frame timecode",
+			previous.Name, next.Name))
+		return
+	}
+
+	end := previous.timecodeFrames + previous.Frames
+	switch start := next.timecodeFrames; {
+	case start < end:
+		a.addIssue(StitchCheckTimecode, StitchIssueError, []int{i, j}, fmt.Sprintf("%s starts at %s, %d frames before %s ends at %s",
+			next.Name, next.StartTimecode, end-start, previous.Name, previous.EndTimecode))
+	case start > end && !onTheHour(next.StartTimecode):
+		a.addIssue(StitchCheckTimecode, StitchIssueWarning, []int{i, j}, fmt.Sprintf("%s starts at %s, %d frames after %s ends at %s",
+			next.Name, next.StartTimecode, start-end, previous.Name, previous.EndTimecode))
+	}
+}
+
+// checkLoudness flags adjacent parts whose integrated loudness differs by
+// more than stitchLoudnessTolerance
+func (a *StitchAnalysis) checkLoudness(i, j int) {
+	previous, next := a.Parts[i], a.Parts[j]
+	if previous.IntegratedLoudness == nil || next.IntegratedLoudness == nil {
+		return
+	}
+	if difference := *next.IntegratedLoudness - *previous.IntegratedLoudness; math.Abs(difference) > stitchLoudnessTolerance {
+		a.addIssue(StitchCheckLoudness, StitchIssueWarning, []int{i, j}, fmt.Sprintf("%s is %.1f LUFS and %s %.1f LUFS, a %.1f LU jump at the join",
+			previous.Name, *previous.IntegratedLoudness, next.Name, *next.IntegratedLoudness, math.Abs(difference)))
+	}
+}
+
+func (a *StitchAnalysis) addIssue(check, severity string, parts []int, message string) {
+	a.Issues = append(a.Issues, StitchIssue{Check: check, Severity: severity, Parts: parts, Message: message})
+}
+
+// onTheHour reports whether timecode is HH:00:00:00
+func onTheHour(timecode string) bool {
+	return len(timecode) == 11 && timecode[3:8] == "00:00" && timecode[9:] == "00"
+}
+
+type stitchParameter struct{ name, value string }
+
+// stitchParameters lists the parameters of a stream that must match for
+// its parts to be joined without re-encoding
+func stitchParameters(stream StreamInfo) []stitchParameter {
+	var names []string
+	switch stream.CodecType {
+	case "video":
+		names = []string{"codec", "profile", "resolution", "pix_fmt", "frame_rate", "field_order", "sample_aspect_ratio", "color_space"}
+	case "audio":
+		names = []string{"codec", "profile", "sample_rate", "channels", "channel_layout", "sample_fmt"}
+	default:
+		names = []string{"codec"}
+	}
+	parameters := make([]stitchParameter, len(names))
+	for i, name := range names {
+		parameters[i] = stitchParameter{name, stitchParameterValue(stream, name)}
+	}
+	return parameters
+}
+
+// stitchParameterValue formats one parameter of stream, "" when unset
+func stitchParameterValue(stream StreamInfo, name string) string {
+	switch name {
+	case "codec":
+		return stream.CodecName
+	case "profile":
+		return stream.Profile
+	case "resolution":
+		if stream.Width > 0 && stream.Height > 0 {
+			return fmt.Sprintf("%dx%d", stream.Width, stream.Height)
+		}
+	case "pix_fmt":
+		return stream.PixFmt
+	case "frame_rate":
+		if stream.RFrameRate != "0/0" {
+			return stream.RFrameRate
+		}
+	case "field_order":
+		return stream.FieldOrder
+	case "sample_aspect_ratio":
+		if stream.SampleAspectRatio != "0:1" && stream.SampleAspectRatio != "N/A" {
+			return stream.SampleAspectRatio
+		}
+	case "color_space":
+		if !unspecifiedColorTag(stream.ColorSpace) {
+			return stream.ColorSpace
+		}
+	case "sample_rate":
+		return stream.SampleRate
+	case "channels":
+		if stream.Channels > 0 {
+			return strconv.Itoa(stream.Channels)
+		}
+	case "channel_layout":
+		return stream.ChannelLayout
+	case "sample_fmt":
+		return stream.SampleFmt
+	}
+	return ""
+}
+
+// stitchStreams groups the streams of a result by type, leaving out cover
+// art
+func stitchStreams(result *FFprobeResult) map[string][]StreamInfo {
+	streams := make(map[string][]StreamInfo)
+	if result == nil {
+		return streams
+	}
+	for _, stream := range result.Streams {
+		if stream.Disposition["attached_pic"] == 1 {
+			continue
+		}
+		streams[stream.CodecType] = append(streams[stream.CodecType], stream)
+	}
+	return streams
+}
+
+// firstStreamOfType returns the first stream of codecType, leaving out
+// cover art
+func firstStreamOfType(result *FFprobeResult, codecType string) *StreamInfo {
+	if streams := stitchStreams(result)[codecType]; len(streams) > 0 {
+		return &streams[0]
+	}
+	return nil
+}
+
+// hasStreamType reports whether result has a stream of codecType
+func hasStreamType(result *FFprobeResult, codecType string) bool {
+	return firstStreamOfType(result, codecType) != nil
+}
+
+// parseFrameRateString parses a frame rate such as "30000/1001", 0 when
+// unset
+func parseFrameRateString(rate string) float64 {
+	num, den, ok := strings.Cut(rate, "/")
+	if !ok {
+		value, _ := strconv.ParseFloat(rate, 64)
+		return value
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || d == 0 {
+		return 0
+	}
+	return n / d
+}
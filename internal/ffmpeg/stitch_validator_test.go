@@ -0,0 +1,115 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// stitchReel builds a probed 25 fps HD reel of duration seconds with
+// stereo audio and a start timecode
+func stitchReel(name, timecode string, duration string) StitchPart {
+	return StitchPart{
+		Name: name,
+		Path: name,
+		Result: &FFprobeResult{
+			Format: &FormatInfo{Duration: duration, Tags: map[string]string{"timecode": timecode}},
+			Streams: []StreamInfo{
+				{Index: 0, CodecType: "video", CodecName: "prores", Profile: "HQ", Width: 1920, Height: 1080, PixFmt: "yuv422p10le",
+					RFrameRate: "25/1", FieldOrder: "progressive", SampleAspectRatio: "1:1", Duration: duration},
+				{Index: 1, CodecType: "audio", CodecName: "pcm_s24le", SampleRate: "48000", Channels: 2, ChannelLayout: "stereo", SampleFmt: "s32", Duration: duration},
+			},
+		},
+	}
+}
+
+func loudnessValues(values ...float64) []*float64 {
+	loudness := make([]*float64, len(values))
+	for i := range values {
+		loudness[i] = &values[i]
+	}
+	return loudness
+}
+
+func TestCheckStitch_Compatible(t *testing.T) {
+	parts := []StitchPart{
+		stitchReel("reel1.mov", "01:00:00:00", "600.000000"),
+		stitchReel("reel2.mov", "01:10:00:00", "300.000000"),
+		stitchReel("reel3.mov", "03:00:00:00", "60.000000"), // Reels may start on the hour
+	}
+	analysis := checkStitch(parts, loudnessValues(-23, -23.4, -22.6))
+	if !analysis.IsCompatible || len(analysis.Issues) != 0 {
+		t.Fatalf("expected the reels to join cleanly, got %+v", analysis.Issues)
+	}
+	if got := analysis.Parts[0]; got.Frames != 15000 || got.EndTimecode != "01:10:00:00" {
+		t.Errorf("expected 15000 frames ending at 01:10:00:00, got %+v", got)
+	}
+}
+
+func TestCheckStitch_Issues(t *testing.T) {
+	reel2 := stitchReel("reel2.mov", "01:09:59:20", "300.000000")
+	reel2.Result.Streams[0].Width = 1280
+	reel2.Result.Streams[0].Height = 720
+	reel2.Result.Streams[1].SampleRate = "44100"
+	reel3 := stitchReel("reel3.mov", "01:15:00:10", "300.000000")
+	reel3.Result.Streams[1].Duration = "299.800000"
+	reel4 := stitchReel("reel4.mov", "", "60.000000")
+	reel4.Result.Streams = reel4.Result.Streams[:1]
+
+	parts := []StitchPart{stitchReel("reel1.mov", "01:00:00:00", "600.000000"), reel2, reel3, reel4}
+	loudness := loudnessValues(-23, -23, -16, 0)
+	loudness[3] = nil // No audio to measure
+	analysis := checkStitch(parts, loudness)
+	if analysis.IsCompatible {
+		t.Error("expected the mismatched codec parameters to fail the validation")
+	}
+
+	want := []StitchIssue{
+		{StitchCheckCodecParameters, StitchIssueError, []int{0, 1}, "video stream 0 of reel2.mov differs from reel1.mov: resolution 1280x720 rather than 1920x1080"},
+		{StitchCheckCodecParameters, StitchIssueError, []int{0, 1}, "audio stream 0 of reel2.mov differs from reel1.mov: sample_rate 44100 rather than 48000"},
+		{StitchCheckStreams, StitchIssueError, []int{0, 3}, "reel4.mov has 0 audio streams where reel1.mov has 1"},
+		{StitchCheckDurations, StitchIssueWarning, []int{2}, "audio stream 0 of reel3.mov is 200 ms shorter than its video, which drifts audio at the join"},
+		{StitchCheckTimecode, StitchIssueError, []int{0, 1}, "reel2.mov starts at 01:09:59:20, 5 frames before reel1.mov ends at 01:10:00:00"},
+		{StitchCheckTimecode, StitchIssueWarning, []int{1, 2}, "reel3.mov starts at 01:15:00:10, 15 frames after reel2.mov ends at 01:14:59:20"},
+		{StitchCheckLoudness, StitchIssueWarning, []int{1, 2}, "reel2.mov is -23.0 LUFS and reel3.mov -16.0 LUFS, a 7.0 LU jump at the join"},
+		{StitchCheckTimecode, StitchIssueWarning, []int{2, 3}, "reel4.mov has no timecode to continue across the join"},
+	}
+	if !reflect.DeepEqual(analysis.Issues, want) {
+		t.Errorf("issues:\n got %+v\nwant %+v", analysis.Issues, want)
+	}
+	if len(analysis.Recommendations) != 5 {
+		t.Errorf("expected one recommendation per failed check, got %v", analysis.Recommendations)
+	}
+}
+
+func TestValidateStitch_Loudness(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "ebur128")
+	summary := "[Parsed_ebur128_0 @ 0x5581] Summary:\n\n  Integrated loudness:\n    I:         -24.5 LUFS\n    Threshold: -34.6 LUFS\n"
+	if err := os.WriteFile(output, []byte(summary), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	script := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncat "+output+" >&2\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	silent := stitchReel("reel2.mov", "01:10:00:00", "60.000000")
+	silent.Result.Streams = silent.Result.Streams[:1]
+	analysis, err := NewStitchValidator(script, zerolog.Nop()).ValidateStitch(t.Context(), []StitchPart{
+		stitchReel("reel1.mov", "01:00:00:00", "600.000000"),
+		silent,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loudness := analysis.Parts[0].IntegratedLoudness; loudness == nil || *loudness != -24.5 {
+		t.Errorf("expected the first reel to measure -24.5 LUFS, got %v", loudness)
+	}
+	if analysis.Parts[1].IntegratedLoudness != nil {
+		t.Error("expected no loudness for a reel without audio")
+	}
+}
@@ -74,6 +74,15 @@ func (a *StreamDispositionAnalyzer) AnalyzeStreamDisposition(ctx context.Context
 
 	analysis.AccessibilityScore = (accessibilityScore * 100) / maxAccessibilityScore
 
+	// Check the packaging rules players rely on to pick streams
+	analysis.PolicyViolations = checkDispositionPolicy(analysis)
+	analysis.PolicyCompliant = true
+	for _, violation := range analysis.PolicyViolations {
+		if violation.Severity == DispositionViolationError {
+			analysis.PolicyCompliant = false
+		}
+	}
+
 	// Validate disposition compliance
 	validation := a.validateDisposition(analysis)
 	analysis.Validation = validation
@@ -285,6 +294,14 @@ func (a *StreamDispositionAnalyzer) validateDisposition(analysis *StreamDisposit
 		}
 	}
 
+	// Packaging rule violations; errors make players pick the wrong stream
+	for _, violation := range analysis.PolicyViolations {
+		validation.Issues = append(validation.Issues, violation.Message)
+		if violation.Severity == DispositionViolationError {
+			validation.IsValid = false
+		}
+	}
+
 	// Accessibility score validation
 	if analysis.AccessibilityScore < 40 {
 		validation.AccessibilityCompliant = false
@@ -0,0 +1,173 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// streamSpecifierTypes maps the stream type letters of a specifier to
+// ffprobe codec types
+var streamSpecifierTypes = map[string]string{
+	"v": "video",
+	"V": "video", // Video that is not an attached picture
+	"a": "audio",
+	"s": "subtitle",
+	"d": "data",
+	"t": "attachment",
+}
+
+// StreamSelector picks streams with ffprobe's select_streams syntax, e.g.
+// "a", "a:2", "3" or "a:m:language:fre". Commas separate alternatives, so
+// "a:0,a:m:language:fre" picks the first audio stream and any French one.
+type StreamSelector struct {
+	spec       string
+	specifiers []streamSpecifier
+}
+
+// streamSpecifier is one alternative of a selector; unset fields match any
+// stream
+type streamSpecifier struct {
+	codecType string
+	attached  bool // Whether attached pictures are allowed
+	index     int  // Index among the streams of codecType, or in the file without a type
+	id        string
+	tagKey    string
+	tagValue  string
+	hasValue  bool
+}
+
+// ParseStreamSelector parses a stream selector. An empty spec gives a nil
+// selector, which selects nothing.
+func ParseStreamSelector(spec string) (*StreamSelector, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	selector := &StreamSelector{spec: spec}
+	for _, part := range strings.Split(spec, ",") {
+		specifier, err := parseStreamSpecifier(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid stream specifier %q: %w", part, err)
+		}
+		selector.specifiers = append(selector.specifiers, specifier)
+	}
+	return selector, nil
+}
+
+func parseStreamSpecifier(part string) (streamSpecifier, error) {
+	specifier := streamSpecifier{index: -1, attached: true}
+	if part == "" {
+		return specifier, fmt.Errorf("empty specifier")
+	}
+
+	fields := strings.Split(part, ":")
+	if codecType, ok := streamSpecifierTypes[fields[0]]; ok {
+		specifier.codecType = codecType
+		specifier.attached = fields[0] != "V"
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return specifier, nil
+	}
+
+	switch {
+	case fields[0] == "m":
+		if len(fields) < 2 || len(fields) > 3 || fields[1] == "" {
+			return specifier, fmt.Errorf("metadata needs m:key or m:key:value")
+		}
+		specifier.tagKey = fields[1]
+		if len(fields) == 3 {
+			specifier.tagValue = fields[2]
+			specifier.hasValue = true
+		}
+		return specifier, nil
+	case strings.HasPrefix(fields[0], "#") || fields[0] == "i":
+		id := strings.TrimPrefix(fields[0], "#")
+		if fields[0] == "i" && len(fields) == 2 {
+			id = fields[1]
+		} else if len(fields) != 1 {
+			return specifier, fmt.Errorf("unexpected %q after the stream ID", strings.Join(fields[1:], ":"))
+		}
+		if id == "" {
+			return specifier, fmt.Errorf("empty stream ID")
+		}
+		specifier.id = id
+		return specifier, nil
+	case fields[0] == "p":
+		return specifier, fmt.Errorf("program specifiers are not supported")
+	}
+
+	if len(fields) != 1 {
+		return specifier, fmt.Errorf("unexpected %q", strings.Join(fields[1:], ":"))
+	}
+	index, err := strconv.Atoi(fields[0])
+	if err != nil || index < 0 {
+		return specifier, fmt.Errorf("%q is not a stream type or index", fields[0])
+	}
+	specifier.index = index
+	return specifier, nil
+}
+
+// String returns the selector as it was given
+func (s *StreamSelector) String() string {
+	if s == nil {
+		return ""
+	}
+	return s.spec
+}
+
+// Select returns the streams any alternative matches, in file order
+func (s *StreamSelector) Select(streams []StreamInfo) []StreamInfo {
+	if s == nil {
+		return nil
+	}
+	var selected []StreamInfo
+	for position, stream := range streams {
+		for _, specifier := range s.specifiers {
+			if specifier.matches(streams, position) {
+				selected = append(selected, stream)
+				break
+			}
+		}
+	}
+	return selected
+}
+
+func (sp streamSpecifier) matches(streams []StreamInfo, position int) bool {
+	stream := streams[position]
+	if sp.codecType != "" {
+		if stream.CodecType != sp.codecType {
+			return false
+		}
+		if !sp.attached && stream.Disposition["attached_pic"] == 1 {
+			return false
+		}
+	}
+
+	if sp.index >= 0 {
+		if sp.codecType == "" {
+			return stream.Index == sp.index
+		}
+		// The index counts streams of the type, in file order
+		n := 0
+		for _, other := range streams[:position] {
+			if other.CodecType == sp.codecType && (sp.attached || other.Disposition["attached_pic"] != 1) {
+				n++
+			}
+		}
+		return n == sp.index
+	}
+
+	if sp.id != "" {
+		id, err := strconv.ParseInt(sp.id, 0, 64)
+		streamID, streamErr := strconv.ParseInt(stream.ID, 0, 64)
+		return err == nil && streamErr == nil && id == streamID
+	}
+
+	if sp.tagKey != "" {
+		value, ok := stream.Tags[sp.tagKey]
+		return ok && (!sp.hasValue || value == sp.tagValue)
+	}
+	return true
+}
@@ -0,0 +1,70 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testSelectionStreams() []StreamInfo {
+	return []StreamInfo{
+		{Index: 0, ID: "0x100", CodecType: "video"},
+		{Index: 1, ID: "0x101", CodecType: "audio", Tags: map[string]string{"language": "eng"}},
+		{Index: 2, ID: "0x102", CodecType: "audio", Tags: map[string]string{"language": "fre"}},
+		{Index: 3, ID: "0x103", CodecType: "subtitle", Tags: map[string]string{"language": "fre"}},
+		{Index: 4, CodecType: "video", Disposition: map[string]int{"attached_pic": 1}},
+		{Index: 5, CodecType: "audio"},
+	}
+}
+
+func TestStreamSelector_Select(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []int
+	}{
+		{"a", []int{1, 2, 5}},
+		{"a:1", []int{2}},
+		{"a:5", nil},
+		{"3", []int{3}},
+		{"v", []int{0, 4}},
+		{"V", []int{0}},
+		{"a:m:language:fre", []int{2}},
+		{"m:language:fre", []int{2, 3}},
+		{"a:m:language", []int{1, 2}},
+		{"#0x102", []int{2}},
+		{"i:258", []int{2}},
+		{"a:0, a:m:language:fre", []int{1, 2}},
+	}
+
+	streams := testSelectionStreams()
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			selector, err := ParseStreamSelector(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseStreamSelector(%q): %v", tt.spec, err)
+			}
+			var got []int
+			for _, stream := range selector.Select(streams) {
+				got = append(got, stream.Index)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Select = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStreamSelector_Invalid(t *testing.T) {
+	for _, spec := range []string{"x", "a:", "a:,", "p:1:a", "m", "a:1:2", "#", "-1"} {
+		if _, err := ParseStreamSelector(spec); err == nil {
+			t.Errorf("ParseStreamSelector(%q) succeeded, want an error", spec)
+		}
+	}
+
+	selector, err := ParseStreamSelector("  ")
+	if err != nil || selector != nil {
+		t.Errorf("ParseStreamSelector of a blank spec = %v, %v, want nil, nil", selector, err)
+	}
+	if got := selector.Select(testSelectionStreams()); got != nil {
+		t.Errorf("nil selector selected %v", got)
+	}
+}
@@ -0,0 +1,275 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/jpeg"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// thumbnailTimeout bounds the seek and decode of a single still
+const thumbnailTimeout = 30 * time.Second
+
+// sceneScanWidth is the width frames are scaled to before scene scoring;
+// the score only needs a coarse picture and this keeps UHD scans fast
+const sceneScanWidth = 160
+
+// DefaultSceneThreshold is the scene score (0-1) above which a frame starts
+// a new shot
+const DefaultSceneThreshold = 0.4
+
+// ThumbnailMode selects how filmstrip stills are chosen
+type ThumbnailMode string
+
+const (
+	// ThumbnailModeInterval takes a still every Interval seconds, or spreads
+	// MaxCount stills evenly when no interval is set
+	ThumbnailModeInterval ThumbnailMode = "interval"
+	// ThumbnailModeScene takes the first frame of each detected shot
+	ThumbnailModeScene ThumbnailMode = "scene"
+)
+
+// ParseThumbnailMode maps a request parameter to a ThumbnailMode
+func ParseThumbnailMode(name string) (ThumbnailMode, error) {
+	switch ThumbnailMode(name) {
+	case ThumbnailModeInterval, ThumbnailModeScene:
+		return ThumbnailMode(name), nil
+	default:
+		return "", fmt.Errorf("unsupported thumbnail mode %q (use interval or scene)", name)
+	}
+}
+
+// FilmstripOptions configures Filmstrip
+type FilmstripOptions struct {
+	Mode           ThumbnailMode `json:"mode"`
+	Interval       float64       `json:"interval,omitempty"`        // Seconds between interval stills
+	SceneThreshold float64       `json:"scene_threshold,omitempty"` // Scene score for scene mode, DefaultSceneThreshold when zero
+	MaxCount       int           `json:"max_count"`                 // Upper bound on the number of stills
+	Width          int           `json:"width"`                     // Still width in pixels
+}
+
+// Thumbnail is a JPEG still taken from the first video stream
+type Thumbnail struct {
+	Time   float64 `json:"time"` // Seconds from the start of the file
+	Width  int     `json:"width"`
+	Height int     `json:"height"`
+	JPEG   []byte  `json:"jpeg"`
+}
+
+// ThumbnailExtractor grabs stills for QC reports
+type ThumbnailExtractor struct {
+	ffmpegPath string
+	logger     zerolog.Logger
+}
+
+// NewThumbnailExtractor creates a new thumbnail extractor
+func NewThumbnailExtractor(ffmpegPath string, logger zerolog.Logger) *ThumbnailExtractor {
+	return &ThumbnailExtractor{
+		ffmpegPath: ffmpegPath,
+		logger:     logger,
+	}
+}
+
+// Extract takes count stills spread evenly over duration seconds, scaled to
+// width pixels. A file without a known duration yields a single still from
+// its first frame. Stills that fail to decode are skipped.
+func (te *ThumbnailExtractor) Extract(ctx context.Context, filePath string, duration float64, count, width int) ([]Thumbnail, error) {
+	if count <= 0 || width <= 0 {
+		return nil, fmt.Errorf("thumbnail count and width must be positive")
+	}
+	return te.extractPositions(ctx, filePath, evenPositions(duration, count), scaleFilter(width))
+}
+
+// evenPositions spreads count positions over duration seconds, or returns
+// the first frame when the duration is unknown
+func evenPositions(duration float64, count int) []float64 {
+	if duration <= 0 {
+		count = 1
+	}
+	positions := make([]float64, count)
+	for i := range positions {
+		// Centre each still in its slice of the timeline to avoid the
+		// black leader and trailing frames
+		positions[i] = duration * (float64(i) + 0.5) / float64(count)
+	}
+	return positions
+}
+
+// scaleFilter scales a still to width pixels, keeping the aspect ratio
+func scaleFilter(width int) string {
+	return fmt.Sprintf("scale=%d:-2", width)
+}
+
+// Filmstrip takes the representative stills of a file chosen by
+// opts.Mode. Scene mode decodes the whole first video stream once to find
+// shot changes and falls back to evenly spaced stills when it finds none,
+// so a single-shot file still gets a filmstrip.
+func (te *ThumbnailExtractor) Filmstrip(ctx context.Context, filePath string, duration float64, opts FilmstripOptions) ([]Thumbnail, error) {
+	if opts.MaxCount <= 0 || opts.Width <= 0 {
+		return nil, fmt.Errorf("thumbnail count and width must be positive")
+	}
+
+	switch opts.Mode {
+	case ThumbnailModeInterval:
+		if opts.Interval <= 0 {
+			return te.Extract(ctx, filePath, duration, opts.MaxCount, opts.Width)
+		}
+		positions := intervalPositions(duration, opts.Interval, opts.MaxCount)
+		if len(positions) == 0 {
+			return te.Extract(ctx, filePath, duration, 1, opts.Width)
+		}
+		return te.extractPositions(ctx, filePath, positions, scaleFilter(opts.Width))
+
+	case ThumbnailModeScene:
+		threshold := opts.SceneThreshold
+		if threshold <= 0 {
+			threshold = DefaultSceneThreshold
+		}
+		scenes, err := te.detectScenes(ctx, filePath, threshold)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			te.logger.Debug().Err(err).Msg("Scene detection failed, using evenly spaced stills")
+		}
+		if len(scenes) == 0 {
+			return te.Extract(ctx, filePath, duration, opts.MaxCount, opts.Width)
+		}
+		return te.extractPositions(ctx, filePath, spreadTimes(scenes, opts.MaxCount), scaleFilter(opts.Width))
+
+	default:
+		return nil, fmt.Errorf("unsupported thumbnail mode %q", opts.Mode)
+	}
+}
+
+// extractPositions takes a still at each position through the video
+// filter chain filter. Stills that fail to decode are skipped.
+func (te *ThumbnailExtractor) extractPositions(ctx context.Context, filePath string, positions []float64, filter string) ([]Thumbnail, error) {
+	var thumbnails []Thumbnail
+	var lastErr error
+	for _, position := range positions {
+		thumbnail, err := te.extractAt(ctx, filePath, position, filter)
+		if err != nil {
+			if ctx.Err() != nil {
+				return thumbnails, ctx.Err()
+			}
+			te.logger.Debug().Err(err).Float64("position", position).Msg("Failed to extract thumbnail")
+			lastErr = err
+			continue
+		}
+		thumbnails = append(thumbnails, thumbnail)
+	}
+
+	if len(thumbnails) == 0 && lastErr != nil {
+		return nil, fmt.Errorf("failed to extract thumbnails: %w", lastErr)
+	}
+	return thumbnails, nil
+}
+
+// detectScenes returns the start time of every shot after the first
+func (te *ThumbnailExtractor) detectScenes(ctx context.Context, filePath string, threshold float64) ([]float64, error) {
+	filter := fmt.Sprintf("scale=%d:-2,select='gt(scene,%s)',showinfo", sceneScanWidth, strconv.FormatFloat(threshold, 'f', 3, 64))
+	args := []string{
+		"-hide_banner", "-nostdin",
+		"-i", filePath,
+		"-map", "0:v:0",
+		"-an",
+		"-vf", filter,
+		"-f", "null",
+		"-",
+	}
+
+	output, err := commandCombinedOutput(ctx, exec.CommandContext(ctx, te.ffmpegPath, args...))
+	if err != nil {
+		return nil, fmt.Errorf("scene detection failed: %w", err)
+	}
+	return parseSceneTimes(string(output)), nil
+}
+
+// showinfoTimeRe matches the timestamp of a frame passed by the select filter
+var showinfoTimeRe = regexp.MustCompile(`Parsed_showinfo_\d+.*\spts_time:\s*(-?[0-9.]+)`)
+
+// parseSceneTimes reads the frame times logged by showinfo
+func parseSceneTimes(output string) []float64 {
+	var times []float64
+	for _, match := range showinfoTimeRe.FindAllStringSubmatch(output, -1) {
+		value, err := strconv.ParseFloat(match[1], 64)
+		if err != nil || value < 0 {
+			continue
+		}
+		times = append(times, value)
+	}
+	return times
+}
+
+// intervalPositions returns up to maxCount positions every interval
+// seconds, each centred in its interval
+func intervalPositions(duration, interval float64, maxCount int) []float64 {
+	var positions []float64
+	for i := 0; len(positions) < maxCount; i++ {
+		position := interval * (float64(i) + 0.5)
+		if duration > 0 && position >= duration {
+			break
+		}
+		positions = append(positions, position)
+		if duration <= 0 {
+			// Without a duration only the first still is known to exist
+			break
+		}
+	}
+	return positions
+}
+
+// spreadTimes picks at most count times spread evenly over times, keeping
+// their order
+func spreadTimes(times []float64, count int) []float64 {
+	if len(times) <= count {
+		return times
+	}
+	picked := make([]float64, count)
+	for i := range picked {
+		picked[i] = times[i*len(times)/count]
+	}
+	return picked
+}
+
+func (te *ThumbnailExtractor) extractAt(ctx context.Context, filePath string, position float64, filter string) (Thumbnail, error) {
+	execCtx, cancel := context.WithTimeout(ctx, thumbnailTimeout)
+	defer cancel()
+
+	args := []string{
+		"-hide_banner", "-nostdin", "-loglevel", "error",
+		"-ss", strconv.FormatFloat(position, 'f', 3, 64),
+		"-i", filePath,
+		"-map", "0:v:0",
+		"-frames:v", "1",
+		"-vf", filter,
+		"-f", "image2pipe",
+		"-c:v", "mjpeg",
+		"-q:v", "4",
+		"-",
+	}
+
+	output, err := commandOutput(execCtx, exec.CommandContext(execCtx, te.ffmpegPath, args...))
+	if err != nil {
+		return Thumbnail{}, err
+	}
+
+	config, err := jpeg.DecodeConfig(bytes.NewReader(output))
+	if err != nil {
+		return Thumbnail{}, fmt.Errorf("invalid thumbnail image: %w", err)
+	}
+
+	return Thumbnail{
+		Time:   position,
+		Width:  config.Width,
+		Height: config.Height,
+		JPEG:   output,
+	}, nil
+}
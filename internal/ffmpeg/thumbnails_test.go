@@ -0,0 +1,113 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"reflect"
+	"testing"
+)
+
+func TestParseSceneTimes(t *testing.T) {
+	output := `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'clip.mp4':
+  Duration: 00:01:00.00, start: 0.000000, bitrate: 2000 kb/s
+[Parsed_showinfo_2 @ 0x55d1] config in time_base: 1/12800, frame_rate: 25/1
+[Parsed_showinfo_2 @ 0x55d1] n:   0 pts:  52736 pts_time:4.12    duration:    512 fmt:yuv420p
+[Parsed_showinfo_2 @ 0x55d1] n:   1 pts: 201728 pts_time:15.76   duration:    512 fmt:yuv420p
+[Parsed_showinfo_2 @ 0x55d1] n:   2 pts: 540160 pts_time:42.2    duration:    512 fmt:yuv420p
+frame=    3 fps=0.0 q=-0.0 Lsize=N/A time=00:00:42.20 bitrate=N/A speed= 180x`
+
+	times := parseSceneTimes(output)
+	if want := []float64{4.12, 15.76, 42.2}; !reflect.DeepEqual(times, want) {
+		t.Errorf("expected %v, got %v", want, times)
+	}
+	if times := parseSceneTimes("no scenes here"); len(times) != 0 {
+		t.Errorf("expected no scenes, got %v", times)
+	}
+}
+
+func TestIntervalPositions(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration float64
+		interval float64
+		maxCount int
+		want     []float64
+	}{
+		{"every ten seconds", 35, 10, 10, []float64{5, 15, 25}},
+		{"capped", 100, 10, 2, []float64{5, 15}},
+		{"interval longer than file", 4, 10, 10, nil},
+		{"unknown duration", 0, 10, 10, []float64{5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := intervalPositions(tt.duration, tt.interval, tt.maxCount)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSpreadTimes(t *testing.T) {
+	times := []float64{1, 2, 3, 4, 5, 6, 7, 8}
+	if got := spreadTimes(times, 4); !reflect.DeepEqual(got, []float64{1, 3, 5, 7}) {
+		t.Errorf("unexpected spread %v", got)
+	}
+	if got := spreadTimes(times[:3], 4); len(got) != 3 {
+		t.Errorf("expected every time to be kept, got %v", got)
+	}
+}
+
+func TestContactSheet(t *testing.T) {
+	still := func(width, height int) Thumbnail {
+		img := image.NewRGBA(image.Rect(0, 0, width, height))
+		for i := range img.Pix {
+			img.Pix[i] = 0xff
+		}
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, nil); err != nil {
+			t.Fatal(err)
+		}
+		return Thumbnail{Width: width, Height: height, JPEG: buf.Bytes()}
+	}
+	thumbnails := []Thumbnail{still(160, 90), still(160, 90), still(160, 120), still(160, 90), still(160, 90)}
+
+	data, err := ContactSheet(thumbnails, 3)
+	if err != nil {
+		t.Fatalf("ContactSheet failed: %v", err)
+	}
+	sheet, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("sheet is not a JPEG: %v", err)
+	}
+	// 3 columns and 2 rows of 160x120 cells with 4px padding
+	if bounds := sheet.Bounds(); bounds.Dx() != 3*164+4 || bounds.Dy() != 2*124+4 {
+		t.Errorf("unexpected sheet size %v", bounds)
+	}
+	// The empty sixth cell keeps the background
+	r, g, b, _ := sheet.At(2*164+4+80, 124+4+60).RGBA()
+	if bg := contactSheetBackground; !near(r, bg.R) || !near(g, bg.G) || !near(b, bg.B) {
+		t.Errorf("expected background in the empty cell, got %v", color.RGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8)})
+	}
+
+	strip, err := ContactSheet(thumbnails[:2], 0)
+	if err != nil {
+		t.Fatalf("ContactSheet failed: %v", err)
+	}
+	if config, _ := jpeg.DecodeConfig(bytes.NewReader(strip)); config.Width != 2*164+4 || config.Height != 94+4 {
+		t.Errorf("expected a single-row filmstrip, got %dx%d", config.Width, config.Height)
+	}
+
+	if _, err := ContactSheet(nil, 3); err == nil {
+		t.Error("expected an error without thumbnails")
+	}
+}
+
+// near compares a 16-bit colour channel with an 8-bit one, allowing for
+// JPEG loss
+func near(channel uint32, want uint8) bool {
+	diff := int(channel>>8) - int(want)
+	return diff > -8 && diff < 8
+}
@@ -0,0 +1,268 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// Kinds of timestamp discontinuity
+const (
+	TimestampGap           = "gap"            // DTS advances further than the packet lasts: packets are missing
+	TimestampJump          = "jump"           // DTS moves by more than timestampJumpThreshold
+	TimestampNegativeDelta = "negative_delta" // DTS goes backwards
+	TimestampDuplicateDTS  = "duplicate_dts"
+	TimestampDuplicatePTS  = "duplicate_pts"
+	TimestampPTSBeforeDTS  = "pts_before_dts" // A packet is presented before it is decoded
+)
+
+const (
+	// timestampJumpThreshold is how far, in seconds, DTS must move from
+	// where the previous packet ends to be reported as a jump rather than
+	// a gap
+	timestampJumpThreshold = 1.0
+
+	// timestampReorderDepth is how many recent PTS are kept per stream to
+	// find duplicates; B-frame reordering never reaches further back
+	timestampReorderDepth = 16
+
+	// maxTimestampDiscontinuities bounds the discontinuities listed per
+	// file; the per-stream counts still cover all
+	maxTimestampDiscontinuities = 1000
+)
+
+// TimestampAnalyzer walks the packets of a file looking for PTS and DTS
+// discontinuities
+type TimestampAnalyzer struct {
+	ffprobePath string
+	logger      zerolog.Logger
+}
+
+// NewTimestampAnalyzer creates a new packet timestamp analyzer
+func NewTimestampAnalyzer(ffprobePath string, logger zerolog.Logger) *TimestampAnalyzer {
+	return &TimestampAnalyzer{
+		ffprobePath: ffprobePath,
+		logger:      logger,
+	}
+}
+
+// TimestampAnalysis reports the timestamp discontinuities of every stream
+type TimestampAnalysis struct {
+	PacketsAnalyzed      int                      `json:"packets_analyzed"`
+	HasDiscontinuities   bool                     `json:"has_discontinuities"`
+	TotalDiscontinuities int                      `json:"total_discontinuities"`
+	Streams              []*StreamTimestamps      `json:"streams"`
+	Discontinuities      []TimestampDiscontinuity `json:"discontinuities,omitempty"` // The first 1000, in file order
+}
+
+// StreamTimestamps summarizes the packet timestamps of one stream
+type StreamTimestamps struct {
+	StreamIndex     int            `json:"stream_index"`
+	CodecType       string         `json:"codec_type"`
+	Packets         int            `json:"packets"`
+	FirstDTS        *float64       `json:"first_dts,omitempty"` // Seconds
+	LastDTS         *float64       `json:"last_dts,omitempty"`
+	MissingPTS      int            `json:"missing_pts"`
+	MissingDTS      int            `json:"missing_dts"`
+	Discontinuities map[string]int `json:"discontinuities,omitempty"` // Count per kind
+
+	timeBase   float64
+	prevDTS    int64
+	prevEnd    int64 // DTS at which the previous packet ends
+	hasPrevDTS bool
+	recentPTS  []int64
+}
+
+// TimestampDiscontinuity is one discontinuity between a packet and the one
+// before it in the same stream
+type TimestampDiscontinuity struct {
+	StreamIndex int     `json:"stream_index"`
+	Kind        string  `json:"kind"`
+	Packet      int     `json:"packet"`                // Packet number within the stream, from 0
+	ByteOffset  *int64  `json:"byte_offset,omitempty"` // Position of the packet in the file, when the demuxer knows it
+	Timestamp   float64 `json:"timestamp"`             // DTS of the packet, or its PTS for duplicate_pts, in seconds
+	Expected    float64 `json:"expected,omitempty"`    // Where the previous packet ends, for gaps and jumps
+	Delta       float64 `json:"delta"`                 // Seconds from where the packet was expected, or between the timestamps compared
+}
+
+// AnalyzeTimestamps reads the timestamps of every packet with ffprobe and
+// reports where they jump, go backwards, repeat or leave gaps
+func (ta *TimestampAnalyzer) AnalyzeTimestamps(ctx context.Context, filePath string, streams []StreamInfo) (*TimestampAnalysis, error) {
+	cmd := exec.CommandContext(ctx, ta.ffprobePath,
+		"-v", "error",
+		"-show_entries", "packet=stream_index,pts,dts,duration,pos",
+		"-of", "compact=p=0",
+		filePath,
+	)
+
+	output, err := commandOutput(ctx, cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read packet timestamps: %w", err)
+	}
+	return parsePacketTimestamps(output, streams), nil
+}
+
+// parsePacketTimestamps walks ffprobe's compact packet output, e.g.
+//
+//	stream_index=0|pts=1024|dts=512|duration=512|pos=4823
+//	stream_index=1|pts=N/A|dts=N/A|duration=1024|pos=N/A
+func parsePacketTimestamps(output []byte, streams []StreamInfo) *TimestampAnalysis {
+	analysis := &TimestampAnalysis{}
+	byIndex := make(map[int]*StreamTimestamps)
+	for _, stream := range streams {
+		state := &StreamTimestamps{StreamIndex: stream.Index, CodecType: strings.ToLower(stream.CodecType), timeBase: parseTimeBase(stream.TimeBase)}
+		byIndex[stream.Index] = state
+		analysis.Streams = append(analysis.Streams, state)
+	}
+
+	forEachLine(output, func(line string) bool {
+		fields := make(map[string]string, 5)
+		for _, field := range strings.Split(strings.TrimSpace(line), "|") {
+			if key, value, ok := strings.Cut(field, "="); ok {
+				fields[key] = value
+			}
+		}
+		index, err := strconv.Atoi(fields["stream_index"])
+		if err != nil {
+			return true
+		}
+		state, ok := byIndex[index]
+		if !ok {
+			// Streams that appear mid-file are not in the stream list
+			state = &StreamTimestamps{StreamIndex: index, timeBase: parseTimeBase("")}
+			byIndex[index] = state
+			analysis.Streams = append(analysis.Streams, state)
+		}
+
+		analysis.PacketsAnalyzed++
+		for _, discontinuity := range state.add(fields) {
+			analysis.TotalDiscontinuities++
+			if len(analysis.Discontinuities) < maxTimestampDiscontinuities {
+				analysis.Discontinuities = append(analysis.Discontinuities, discontinuity)
+			}
+		}
+		return true
+	})
+
+	analysis.HasDiscontinuities = analysis.TotalDiscontinuities > 0
+	return analysis
+}
+
+// add checks one packet against the ones before it in the stream
+func (s *StreamTimestamps) add(fields map[string]string) []TimestampDiscontinuity {
+	packet := s.Packets
+	s.Packets++
+
+	pts, hasPTS := parseTimestamp(fields["pts"])
+	dts, hasDTS := parseTimestamp(fields["dts"])
+	duration, _ := parseTimestamp(fields["duration"])
+	if !hasPTS {
+		s.MissingPTS++
+	}
+	if !hasDTS {
+		s.MissingDTS++
+	}
+
+	var found []TimestampDiscontinuity
+	report := func(kind string, timestamp, expected, delta float64) {
+		discontinuity := TimestampDiscontinuity{
+			StreamIndex: s.StreamIndex,
+			Kind:        kind,
+			Packet:      packet,
+			Timestamp:   roundTo(timestamp, 6),
+			Expected:    roundTo(expected, 6),
+			Delta:       roundTo(delta, 6),
+		}
+		if pos, err := strconv.ParseInt(fields["pos"], 10, 64); err == nil && pos >= 0 {
+			discontinuity.ByteOffset = &pos
+		}
+		if s.Discontinuities == nil {
+			s.Discontinuities = make(map[string]int)
+		}
+		s.Discontinuities[kind]++
+		found = append(found, discontinuity)
+	}
+
+	if hasPTS && hasDTS && pts < dts {
+		report(TimestampPTSBeforeDTS, s.seconds(dts), 0, s.seconds(pts-dts))
+	}
+
+	if hasPTS {
+		for _, recent := range s.recentPTS {
+			if recent == pts {
+				report(TimestampDuplicatePTS, s.seconds(pts), 0, 0)
+				break
+			}
+		}
+		s.recentPTS = append(s.recentPTS, pts)
+		if len(s.recentPTS) > timestampReorderDepth {
+			s.recentPTS = s.recentPTS[1:]
+		}
+	}
+
+	if hasDTS {
+		seconds := roundTo(s.seconds(dts), 6)
+		if s.FirstDTS == nil {
+			s.FirstDTS = &seconds
+		}
+		s.LastDTS = &seconds
+
+		if s.hasPrevDTS {
+			// Subtitle and data packets are sparse, so only audio and video
+			// are expected to follow on where the previous packet ends
+			continuous := s.CodecType == "audio" || s.CodecType == "video"
+			late := s.seconds(dts - s.prevEnd)
+			switch {
+			case dts == s.prevDTS:
+				report(TimestampDuplicateDTS, seconds, 0, 0)
+			case dts < s.prevDTS:
+				report(TimestampNegativeDelta, seconds, s.seconds(s.prevEnd), s.seconds(dts-s.prevDTS))
+			case !continuous:
+			case math.Abs(late) > timestampJumpThreshold:
+				report(TimestampJump, seconds, s.seconds(s.prevEnd), late)
+			case s.prevEnd > s.prevDTS && dts-s.prevEnd > (s.prevEnd-s.prevDTS)/2:
+				// More than half a packet late: at least one packet is missing
+				report(TimestampGap, seconds, s.seconds(s.prevEnd), late)
+			}
+		}
+
+		// A packet without a duration is assumed to last as long as the
+		// step to it from the packet before
+		if duration <= 0 && s.hasPrevDTS && dts > s.prevDTS {
+			duration = dts - s.prevDTS
+		}
+		s.prevDTS, s.prevEnd, s.hasPrevDTS = dts, dts+duration, true
+	}
+	return found
+}
+
+// seconds converts a timestamp in the stream's time base to seconds
+func (s *StreamTimestamps) seconds(ticks int64) float64 {
+	return float64(ticks) * s.timeBase
+}
+
+// parseTimestamp parses a timestamp that ffprobe prints as N/A when unset
+func parseTimestamp(value string) (int64, bool) {
+	ticks, err := strconv.ParseInt(value, 10, 64)
+	return ticks, err == nil
+}
+
+// parseTimeBase parses a time base such as "1/90000". Timestamps of streams
+// with an unknown time base are treated as microseconds, FFmpeg's internal
+// time base.
+func parseTimeBase(value string) float64 {
+	num, den, ok := strings.Cut(value, "/")
+	if ok {
+		n, err1 := strconv.ParseFloat(num, 64)
+		d, err2 := strconv.ParseFloat(den, 64)
+		if err1 == nil && err2 == nil && n > 0 && d > 0 {
+			return n / d
+		}
+	}
+	return 1e-6
+}
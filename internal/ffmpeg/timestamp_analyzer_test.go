@@ -0,0 +1,75 @@
+package ffmpeg
+
+import (
+	"testing"
+)
+
+func TestParsePacketTimestamps(t *testing.T) {
+	streams := []StreamInfo{
+		{Index: 0, CodecType: "video", TimeBase: "1/12800"},
+		{Index: 1, CodecType: "audio", TimeBase: "1/48000"},
+		{Index: 2, CodecType: "subtitle", TimeBase: "1/1000"},
+	}
+	output := []byte(`stream_index=0|pts=1024|dts=0|duration=512|pos=48
+stream_index=1|pts=0|dts=0|duration=1024|pos=5000
+stream_index=0|pts=512|dts=512|duration=512|pos=9000
+stream_index=1|pts=1024|dts=1024|duration=1024|pos=9400
+stream_index=0|pts=512|dts=1024|duration=512|pos=12000
+stream_index=1|pts=4096|dts=4096|duration=1024|pos=12600
+stream_index=0|pts=40000|dts=40000|duration=512|pos=15000
+stream_index=1|pts=4096|dts=4096|duration=1024|pos=16000
+stream_index=1|pts=2048|dts=2048|duration=1024|pos=N/A
+stream_index=2|pts=1000|dts=1000|duration=2000|pos=20000
+stream_index=2|pts=9000|dts=9000|duration=2000|pos=21000
+stream_index=0|pts=N/A|dts=N/A|duration=512|pos=22000
+`)
+
+	analysis := parsePacketTimestamps(output, streams)
+	if analysis.PacketsAnalyzed != 12 || !analysis.HasDiscontinuities {
+		t.Fatalf("unexpected summary %+v", analysis)
+	}
+
+	video, audio, subtitle := analysis.Streams[0], analysis.Streams[1], analysis.Streams[2]
+	if video.Packets != 5 || video.MissingPTS != 1 || video.MissingDTS != 1 || *video.FirstDTS != 0 || *video.LastDTS != 3.125 {
+		t.Errorf("unexpected video summary %+v", video)
+	}
+	if video.Discontinuities[TimestampPTSBeforeDTS] != 1 || video.Discontinuities[TimestampDuplicatePTS] != 1 || video.Discontinuities[TimestampJump] != 1 {
+		t.Errorf("unexpected video discontinuities %v", video.Discontinuities)
+	}
+	if audio.Discontinuities[TimestampGap] != 1 || audio.Discontinuities[TimestampDuplicateDTS] != 1 ||
+		audio.Discontinuities[TimestampDuplicatePTS] != 1 || audio.Discontinuities[TimestampNegativeDelta] != 1 {
+		t.Errorf("unexpected audio discontinuities %v", audio.Discontinuities)
+	}
+	if len(subtitle.Discontinuities) != 0 {
+		t.Errorf("expected sparse subtitle packets to pass, got %v", subtitle.Discontinuities)
+	}
+	if analysis.TotalDiscontinuities != 7 || len(analysis.Discontinuities) != 7 {
+		t.Fatalf("expected 7 discontinuities, got %d: %+v", analysis.TotalDiscontinuities, analysis.Discontinuities)
+	}
+
+	var gap, negative *TimestampDiscontinuity
+	for i := range analysis.Discontinuities {
+		switch analysis.Discontinuities[i].Kind {
+		case TimestampGap:
+			gap = &analysis.Discontinuities[i]
+		case TimestampNegativeDelta:
+			negative = &analysis.Discontinuities[i]
+		}
+	}
+	if gap.StreamIndex != 1 || gap.Packet != 2 || *gap.ByteOffset != 12600 ||
+		gap.Timestamp != 0.085333 || gap.Expected != 0.042667 || gap.Delta != 0.042667 {
+		t.Errorf("unexpected gap %+v", gap)
+	}
+	if negative.ByteOffset != nil || negative.Delta != -0.042667 {
+		t.Errorf("unexpected negative delta %+v", negative)
+	}
+}
+
+func TestParseTimeBase(t *testing.T) {
+	if got := parseTimeBase("1/90000"); got != 1.0/90000 {
+		t.Errorf("expected 1/90000, got %v", got)
+	}
+	if got := parseTimeBase("0/0"); got != 1e-6 {
+		t.Errorf("expected microseconds for an invalid time base, got %v", got)
+	}
+}
@@ -0,0 +1,74 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultTonemapOperator is the curve used when a request names none; hable
+// rolls off highlights without crushing the mid tones
+const DefaultTonemapOperator = "hable"
+
+// DefaultTonemapPreviewCount is the number of SDR preview stills rendered
+// for an HDR file
+const DefaultTonemapPreviewCount = 3
+
+// TonemapOperators are the curves of FFmpeg's tonemap filter
+var TonemapOperators = []string{"clip", "linear", "gamma", "reinhard", "hable", "mobius"}
+
+// ParseTonemapOperator maps a request parameter to a tonemap operator; empty
+// selects DefaultTonemapOperator
+func ParseTonemapOperator(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return DefaultTonemapOperator, nil
+	}
+	if !slices.Contains(TonemapOperators, name) {
+		return "", fmt.Errorf("unsupported tonemap operator %q (use %s)", name, strings.Join(TonemapOperators, ", "))
+	}
+	return name, nil
+}
+
+// TonemapPreviews are stills of an HDR file converted to BT.709 SDR, for
+// judging whether the grade survives downconversion
+type TonemapPreviews struct {
+	Operator string      `json:"operator"`
+	Frames   []Thumbnail `json:"frames"`
+}
+
+// tonemapFilter scales a frame to width pixels, linearises it at a 100 nit
+// reference white, tone maps it with operator and encodes it as BT.709.
+// Scaling first keeps UHD conversions fast. zscale needs FFmpeg built with
+// libzimg.
+func tonemapFilter(operator string, width int) string {
+	return strings.Join([]string{
+		scaleFilter(width),
+		"zscale=t=linear:npl=100",
+		"format=gbrpf32le",
+		"zscale=p=bt709",
+		"tonemap=tonemap=" + operator + ":desat=0",
+		"zscale=t=bt709:m=bt709:r=tv",
+		"format=yuv420p",
+	}, ",")
+}
+
+// TonemapPreviews takes count stills spread evenly over duration seconds and
+// tone maps them to SDR with operator. The caller decides whether the file is
+// HDR; tone mapping an SDR source only darkens it.
+func (te *ThumbnailExtractor) TonemapPreviews(ctx context.Context, filePath string, duration float64, operator string, count, width int) (*TonemapPreviews, error) {
+	if count <= 0 || width <= 0 {
+		return nil, fmt.Errorf("preview count and width must be positive")
+	}
+	operator, err := ParseTonemapOperator(operator)
+	if err != nil {
+		return nil, err
+	}
+
+	frames, err := te.extractPositions(ctx, filePath, evenPositions(duration, count), tonemapFilter(operator, width))
+	if err != nil {
+		return nil, fmt.Errorf("tone mapping failed: %w", err)
+	}
+	return &TonemapPreviews{Operator: operator, Frames: frames}, nil
+}
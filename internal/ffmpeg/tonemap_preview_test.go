@@ -0,0 +1,28 @@
+package ffmpeg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTonemapOperator(t *testing.T) {
+	if operator, err := ParseTonemapOperator(""); err != nil || operator != DefaultTonemapOperator {
+		t.Errorf("expected the default operator, got %q, %v", operator, err)
+	}
+	if operator, err := ParseTonemapOperator(" Mobius "); err != nil || operator != "mobius" {
+		t.Errorf("expected mobius, got %q, %v", operator, err)
+	}
+	if _, err := ParseTonemapOperator("aces"); err == nil {
+		t.Error("expected an error for an unknown operator")
+	}
+}
+
+func TestTonemapFilter(t *testing.T) {
+	filter := tonemapFilter("reinhard", 320)
+	if !strings.HasPrefix(filter, "scale=320:-2,zscale=t=linear") {
+		t.Errorf("expected scaling before linearisation, got %s", filter)
+	}
+	if !strings.Contains(filter, ",tonemap=tonemap=reinhard:desat=0,") || !strings.HasSuffix(filter, "zscale=t=bt709:m=bt709:r=tv,format=yuv420p") {
+		t.Errorf("unexpected filter %s", filter)
+	}
+}
@@ -0,0 +1,94 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/rendiffdev/rendiff-probe/internal/ffmpeg")
+
+// startCommandSpan starts a span for an external ffmpeg/ffprobe invocation.
+// Arguments are not recorded since they carry input paths and URLs, which may
+// include signed query strings.
+func startCommandSpan(ctx context.Context, cmd *exec.Cmd) trace.Span {
+	name := filepath.Base(cmd.Path)
+	_, span := tracer.Start(ctx, "exec "+name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("process.executable.name", name),
+			attribute.Int("process.command_args.count", len(cmd.Args)-1),
+		),
+	)
+	return span
+}
+
+// endCommandSpan records the exit status of a command and ends its span
+func endCommandSpan(span trace.Span, err error) {
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			span.SetAttributes(attribute.Int("process.exit.code", exitErr.ExitCode()))
+		}
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	} else {
+		span.SetAttributes(attribute.Int("process.exit.code", 0))
+	}
+	span.End()
+}
+
+// runCommand runs cmd inside a span once the process pool has a slot
+func runCommand(ctx context.Context, cmd *exec.Cmd) error {
+	release, err := AcquireProcess(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	span := startCommandSpan(ctx, cmd)
+	err = cmd.Run()
+	endCommandSpan(span, err)
+	return err
+}
+
+// commandOutput is cmd.Output inside a span once the process pool has a slot
+func commandOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	release, err := AcquireProcess(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	span := startCommandSpan(ctx, cmd)
+	output, err := cmd.Output()
+	endCommandSpan(span, err)
+	return output, err
+}
+
+// commandCombinedOutput is cmd.CombinedOutput inside a span once the
+// process pool has a slot
+func commandCombinedOutput(ctx context.Context, cmd *exec.Cmd) ([]byte, error) {
+	release, err := AcquireProcess(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	span := startCommandSpan(ctx, cmd)
+	output, err := cmd.CombinedOutput()
+	endCommandSpan(span, err)
+	return output, err
+}
+
+// recordSpanError marks span as failed
+func recordSpanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(otelcodes.Error, err.Error())
+}
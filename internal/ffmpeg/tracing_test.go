@@ -0,0 +1,52 @@
+package ffmpeg
+
+import (
+	"context"
+	"os/exec"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCommandCombinedOutput_RecordsSpan(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	defer otel.SetTracerProvider(previous)
+
+	ctx := context.Background()
+	if _, err := commandCombinedOutput(ctx, exec.CommandContext(ctx, "sh", "-c", "exit 3")); err == nil {
+		t.Fatal("expected command to fail")
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	span := spans[0]
+	if span.Name() != "exec sh" {
+		t.Errorf("unexpected span name %q", span.Name())
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected error status, got %v", span.Status().Code)
+	}
+
+	var exitCode int64 = -1
+	for _, attr := range span.Attributes() {
+		if attr.Key == "process.exit.code" {
+			exitCode = attr.Value.AsInt64()
+		}
+	}
+	if exitCode != 3 {
+		t.Errorf("expected exit code 3, got %d", exitCode)
+	}
+}
@@ -41,6 +41,9 @@ type TransportStreamAnalysis struct {
 	PIDStatistics       *PIDStatistics         `json:"pid_statistics,omitempty"`
 	TransportValidation *TransportValidation   `json:"transport_validation,omitempty"`
 	BroadcastCompliance *TSBroadcastCompliance `json:"broadcast_compliance,omitempty"`
+	PCRAnalysis         []PCRAnalysis          `json:"pcr_analysis,omitempty"`
+	ETR290              *ETR290Analysis        `json:"etr_290,omitempty"`
+	MuxStatistics       *TSMuxStatistics       `json:"mux_statistics,omitempty"`
 }
 
 // TSProgram represents a transport stream program
@@ -249,6 +252,59 @@ type TSBroadcastCompliance struct {
 	Standard           string   `json:"primary_standard,omitempty"`
 }
 
+// PCRAnalysis contains the PCR timing measured on one PID
+type PCRAnalysis struct {
+	PID                      int      `json:"pid"`
+	PCRCount                 int64    `json:"pcr_count"`
+	MinIntervalMs            float64  `json:"min_interval_ms"`
+	MaxIntervalMs            float64  `json:"max_interval_ms"`
+	AvgIntervalMs            float64  `json:"avg_interval_ms"`
+	RepetitionErrors         int64    `json:"repetition_errors"`
+	DiscontinuityErrors      int64    `json:"discontinuity_errors"`
+	SignalledDiscontinuities int64    `json:"signalled_discontinuities"`
+	MaxJitterNs              float64  `json:"max_jitter_ns"`
+	MeanJitterNs             float64  `json:"mean_jitter_ns"`
+	AccuracyErrors           int64    `json:"accuracy_errors"`
+	BitRate                  float64  `json:"bit_rate"` // Mux rate between this PID's PCRs
+	IsValid                  bool     `json:"is_valid"`
+	Issues                   []string `json:"issues,omitempty"`
+}
+
+// TSMuxStatistics contains the packet counts and rates of the whole mux
+type TSMuxStatistics struct {
+	PacketSize          int      `json:"packet_size"`
+	TotalPackets        int64    `json:"total_packets"`
+	ScannedBytes        int64    `json:"scanned_bytes"`
+	Truncated           bool     `json:"truncated"`
+	DurationSeconds     float64  `json:"duration_seconds"`
+	MuxBitRate          float64  `json:"mux_bit_rate"`
+	EffectiveBitRate    float64  `json:"effective_bit_rate"` // Without null packets
+	MinEffectiveBitRate float64  `json:"min_effective_bit_rate,omitempty"`
+	MaxEffectiveBitRate float64  `json:"max_effective_bit_rate,omitempty"`
+	ConstantBitRate     bool     `json:"constant_bit_rate"`
+	NullPackets         int64    `json:"null_packets"`
+	NullPercent         float64  `json:"null_packet_percent"`
+	Issues              []string `json:"issues,omitempty"`
+}
+
+// ETR290Analysis contains the TR 101 290 priority 1 and 2 check results
+type ETR290Analysis struct {
+	Priority1Passed bool          `json:"priority_1_passed"`
+	Priority2Passed bool          `json:"priority_2_passed"`
+	Checks          []ETR290Check `json:"checks"`
+	Issues          []string      `json:"issues,omitempty"`
+}
+
+// ETR290Check is one TR 101 290 check with the first errors it found
+type ETR290Check struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Priority   int      `json:"priority"`
+	ErrorCount int64    `json:"error_count"`
+	Passed     bool     `json:"passed"`
+	Examples   []string `json:"examples,omitempty"`
+}
+
 // Stream type definitions
 var streamTypeDefinitions = map[int]string{
 	0x01: "ISO/IEC 11172-2 Video (MPEG-1)",
@@ -318,43 +374,60 @@ func (tsa *TransportStreamAnalyzer) AnalyzeTransportStream(ctx context.Context,
 
 	analysis.IsTransportStream = true
 
-	// Step 1: Extract program information
+	// Read the packets themselves; ffprobe's view of the programs is the
+	// fallback when the file cannot be read that way
+	file, err := readTSFile(ctx, filePath)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		tsa.logger.Warn().Err(err).Msg("Failed to read transport stream packets, using ffprobe")
+		tsa.analyzeWithFFprobe(ctx, filePath, streams, analysis)
+	} else {
+		tsa.applyPackets(file, streams, format, analysis)
+	}
+
+	// Validate transport stream
+	analysis.TransportValidation = tsa.validateTransportStream(analysis)
+
+	// Check broadcast compliance
+	analysis.BroadcastCompliance = tsa.checkBroadcastCompliance(analysis)
+
+	return analysis, nil
+}
+
+// analyzeWithFFprobe fills the analysis from ffprobe's program and stream
+// listing
+func (tsa *TransportStreamAnalyzer) analyzeWithFFprobe(ctx context.Context, filePath string, streams []StreamInfo, analysis *TransportStreamAnalysis) {
+	// Extract program information
 	if err := tsa.extractProgramInfo(ctx, filePath, analysis); err != nil {
 		tsa.logger.Warn().Err(err).Msg("Failed to extract program information")
 	}
 
-	// Step 2: Analyze PAT (Program Association Table)
+	// Analyze PAT (Program Association Table)
 	if err := tsa.analyzePAT(ctx, filePath, analysis); err != nil {
 		tsa.logger.Warn().Err(err).Msg("Failed to analyze PAT")
 	}
 
-	// Step 3: Analyze PMT (Program Map Table)
+	// Analyze PMT (Program Map Table)
 	if err := tsa.analyzePMT(ctx, filePath, analysis); err != nil {
 		tsa.logger.Warn().Err(err).Msg("Failed to analyze PMT")
 	}
 
-	// Step 4: Extract PID information for all stream types
+	// Extract PID information for all stream types
 	if err := tsa.extractPIDInformation(ctx, filePath, streams, analysis); err != nil {
 		tsa.logger.Warn().Err(err).Msg("Failed to extract PID information")
 	}
 
-	// Step 5: Analyze packet statistics
+	// Analyze packet statistics
 	if err := tsa.analyzePIDStatistics(ctx, filePath, analysis); err != nil {
 		tsa.logger.Warn().Err(err).Msg("Failed to analyze PID statistics")
 	}
 
-	// Step 6: Extract service information (SDT)
+	// Extract service information (SDT)
 	if err := tsa.analyzeSDT(ctx, filePath, analysis); err != nil {
 		tsa.logger.Warn().Err(err).Msg("Failed to analyze SDT")
 	}
-
-	// Step 7: Validate transport stream
-	analysis.TransportValidation = tsa.validateTransportStream(analysis)
-
-	// Step 8: Check broadcast compliance
-	analysis.BroadcastCompliance = tsa.checkBroadcastCompliance(analysis)
-
-	return analysis, nil
 }
 
 // isTransportStream determines if the input is a transport stream
@@ -746,6 +819,54 @@ func (tsa *TransportStreamAnalyzer) validateTransportStream(analysis *TransportS
 		}
 	}
 
+	// PAT, PMT and SDT consistency
+	if analysis.PATInfo != nil && len(analysis.PATInfo.Issues) > 0 {
+		validation.Errors = append(validation.Errors, analysis.PATInfo.Issues...)
+		validation.PATValid = false
+		validation.IsValid = false
+		validation.HasErrors = true
+	}
+	for _, pmt := range analysis.PMTInfo {
+		for _, issue := range pmt.Issues {
+			validation.Errors = append(validation.Errors, fmt.Sprintf("Program %d: %s", pmt.ProgramNumber, issue))
+			validation.PMTValid = false
+			validation.IsValid = false
+			validation.HasErrors = true
+		}
+	}
+	if analysis.SDTInfo != nil && len(analysis.SDTInfo.Issues) > 0 {
+		validation.Warnings = append(validation.Warnings, analysis.SDTInfo.Issues...)
+		validation.HasWarnings = true
+	}
+
+	// TR 101 290: priority 1 failures make the stream undecodable, priority 2
+	// failures are recommended monitoring
+	if analysis.ETR290 != nil {
+		for _, check := range analysis.ETR290.Checks {
+			if check.Passed {
+				continue
+			}
+			message := fmt.Sprintf("TR 101 290 %s %s: %d errors", check.ID, check.Name, check.ErrorCount)
+			if check.Priority == 1 {
+				validation.Errors = append(validation.Errors, message)
+				validation.IsValid = false
+				validation.HasErrors = true
+			} else {
+				validation.Warnings = append(validation.Warnings, message)
+				validation.HasWarnings = true
+			}
+			switch check.ID {
+			case etr290Continuity:
+				validation.PIDContinuityValid = false
+			case etr290PCRRepeat, etr290PCRDiscontinue, etr290PCRAccuracy:
+				validation.PCRContinuityValid = false
+			}
+		}
+		if !analysis.ETR290.Priority1Passed || !analysis.ETR290.Priority2Passed {
+			validation.IsCompliant = false
+		}
+	}
+
 	// Performance recommendations
 	if analysis.PIDStatistics != nil {
 		if analysis.PIDStatistics.PIDUtilization > 80.0 {
@@ -0,0 +1,986 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Transport stream constants, ISO/IEC 13818-1
+const (
+	tsPacketSize     = 188
+	tsSyncByte       = 0x47
+	tsPATPID         = 0x0000
+	tsCATPID         = 0x0001
+	tsSDTPID         = 0x0011
+	tsNullPID        = 0x1FFF
+	tsPCRHz          = 27000000
+	tsPCRWrap        = (int64(1) << 33) * 300
+	maxTSSectionSize = 4096
+)
+
+// Limits for reading a transport stream
+const (
+	maxTSScannedBytes  = 4 << 30 // Packets past this are not read
+	tsSyncCheckPackets = 5       // Sync bytes in a row that establish packet alignment
+	maxTSSyncSearch    = 1 << 20 // Bytes searched for the first sync pattern
+)
+
+// TR 101 290 thresholds, in 27 MHz ticks unless noted
+const (
+	etr290PSIInterval      = tsPCRHz / 2         // PAT and PMT at least every 0.5 s
+	etr290PCRRepetition    = tsPCRHz * 40 / 1000 // PCR at least every 40 ms
+	etr290PCRDiscontinuity = tsPCRHz / 10        // PCR jumps over 100 ms need the discontinuity indicator
+	etr290PCRAccuracyNs    = 500                 // PCR accuracy, nanoseconds
+	etr290PTSInterval      = tsPCRHz * 7 / 10    // PTS at least every 700 ms
+	etr290PIDInterval      = tsPCRHz * 5         // Referenced PIDs at least every 5 s
+	maxETR290Examples      = 10
+	tsCBRTolerance         = 0.005 // Spread of PCR pair rates within which a mux is constant bitrate
+)
+
+// TR 101 290 checks reported by the packet reader
+const (
+	etr290SyncLoss       = "1.1"
+	etr290SyncByte       = "1.2"
+	etr290PAT            = "1.3"
+	etr290Continuity     = "1.4"
+	etr290PMT            = "1.5"
+	etr290PID            = "1.6"
+	etr290Transport      = "2.1"
+	etr290CRC            = "2.2"
+	etr290PCRRepeat      = "2.3a"
+	etr290PCRDiscontinue = "2.3b"
+	etr290PCRAccuracy    = "2.4"
+	etr290PTS            = "2.5"
+	etr290CAT            = "2.6"
+)
+
+// tsErrorLog counts occurrences of one error and keeps the first few
+type tsErrorLog struct {
+	count    int64
+	examples []string
+}
+
+func (l *tsErrorLog) add(example string) {
+	l.count++
+	if len(l.examples) < maxETR290Examples {
+		l.examples = append(l.examples, example)
+	}
+}
+
+// tsPCRStats accumulates the PCRs carried on one PID
+type tsPCRStats struct {
+	count               int64
+	chain               int // PCRs since the last discontinuity
+	last, lastPos       int64
+	prev, prevPos       int64
+	minInterval         int64
+	maxInterval         int64
+	intervalSum         int64
+	intervals           int64
+	discontinuityFlags  int64
+	ticks, bytes        int64 // Spans between consecutive PCRs, for the bitrate
+	minRate, maxRate    float64
+	jitterMax           float64 // Nanoseconds
+	jitterSum           float64
+	jitterSamples       int64
+	accuracy            tsErrorLog
+	repetitionErrors    int64
+	discontinuityErrors int64
+}
+
+// tsPID is what the reader gathers about one PID
+type tsPID struct {
+	pid             int
+	packets         int64
+	transportErrors int64
+	scrambled       int64
+	ccErrors        int64
+	duplicates      int64
+	lastCC          int
+	duplicateRun    int
+	lastSeen        int64 // Clock of the last packet, -1 until known
+	gaps            tsErrorLog
+	pcr             *tsPCRStats
+	pesPackets      int64
+	lastPTS         int64 // Clock of the last PTS, -1 until known
+	maxPTSInterval  int64
+	pts             tsErrorLog
+
+	section       []byte
+	collecting    bool
+	sections      int64
+	crcErrors     int64
+	lastSection   int64 // Clock of the last PAT or PMT section, -1 until known
+	maxSectionGap int64
+}
+
+// tsDescriptor is a descriptor of a PSI table
+type tsDescriptor struct {
+	tag  int
+	data []byte
+}
+
+// tsPAT is the Program Association Table; programs maps program numbers to
+// PMT PIDs, program 0 to the NIT PID
+type tsPAT struct {
+	transportStreamID int
+	version           int
+	versionChanges    int
+	programs          map[int]int
+}
+
+// tsPMT is a Program Map Table
+type tsPMT struct {
+	pid            int
+	programNumber  int
+	version        int
+	versionChanges int
+	pcrPID         int
+	infoLength     int
+	descriptors    []tsDescriptor
+	streams        []tsElementaryStream
+}
+
+type tsElementaryStream struct {
+	streamType  int
+	pid         int
+	descriptors []tsDescriptor
+}
+
+// tsSDT is the Service Description Table of the actual transport stream
+type tsSDT struct {
+	transportStreamID int
+	originalNetworkID int
+	version           int
+	services          map[int]tsService
+}
+
+type tsService struct {
+	id          int
+	serviceType int
+	provider    string
+	name        string
+	freeCA      bool
+}
+
+// tsSecond counts the packets of one second of the stream
+type tsSecond struct {
+	packets, nullPackets int64
+}
+
+// tsFile is a transport stream read packet by packet
+type tsFile struct {
+	packetSize  int
+	packets     int64
+	nullPackets int64
+	bytes       int64
+	truncated   bool
+	pids        map[int]*tsPID
+	pat         *tsPAT
+	pmts        map[int]*tsPMT // By program number
+	pmtPIDs     map[int]bool
+	sdt         *tsSDT
+	hasCAT      bool
+	errors      map[string]*tsErrorLog
+	issues      []string
+
+	// The clock is the time since the first PCR of the reference PID,
+	// interpolated between PCRs by byte position
+	clockPID   int
+	clockValid bool
+	clockBase  int64 // Clock at clockPos
+	clockPos   int64
+	rateTicks  int64 // Ticks and bytes between the last two reference PCRs
+	rateBytes  int64
+	lastClock  int64
+	seconds    map[int64]*tsSecond
+	firstClock int64
+}
+
+// readTSFile reads a transport stream: its PSI tables, each PID's
+// continuity, PCRs and PTS timing, and the TR 101 290 errors among them.
+// Files longer than maxTSScannedBytes are read up to that point.
+func readTSFile(ctx context.Context, path string) (*tsFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseTS(ctx, f)
+}
+
+// parseTS reads a transport stream of 188, 192 (M2TS) or 204 byte packets
+func parseTS(ctx context.Context, r io.Reader) (*tsFile, error) {
+	reader := bufio.NewReaderSize(r, 1<<20)
+	size, offset, skip, err := detectTSPacketSize(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &tsFile{
+		packetSize: size,
+		pids:       make(map[int]*tsPID),
+		pmts:       make(map[int]*tsPMT),
+		pmtPIDs:    make(map[int]bool),
+		errors:     make(map[string]*tsErrorLog),
+		clockPID:   -1,
+		lastClock:  -1,
+		firstClock: -1,
+		seconds:    make(map[int64]*tsSecond),
+	}
+	if _, err := reader.Discard(skip); err != nil {
+		return nil, err
+	}
+	pos := int64(skip)
+	if skip > 0 {
+		file.issues = append(file.issues, fmt.Sprintf("%d bytes before the first packet", skip))
+	}
+
+	badSyncs := 0
+	for {
+		if file.packets%65536 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if pos >= maxTSScannedBytes {
+			file.truncated = true
+			break
+		}
+
+		unit, err := reader.Peek(size)
+		if len(unit) < size {
+			if len(unit) > 0 {
+				file.issues = append(file.issues, fmt.Sprintf("File ends with a partial %d byte packet", len(unit)))
+			}
+			pos += int64(len(unit))
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if unit[offset] != tsSyncByte {
+			file.errorAt(etr290SyncByte, pos, fmt.Sprintf("sync byte 0x%02X", unit[offset]))
+			badSyncs++
+			if badSyncs >= 2 {
+				file.errorAt(etr290SyncLoss, pos, "sync lost")
+				skipped := resyncTS(reader, size, offset)
+				pos += skipped
+				badSyncs = 0
+				continue
+			}
+			reader.Discard(size)
+			pos += int64(size)
+			continue
+		}
+		badSyncs = 0
+
+		file.packet(unit[offset:offset+tsPacketSize], pos+int64(offset))
+		reader.Discard(size)
+		pos += int64(size)
+	}
+
+	file.bytes = pos
+	return file, nil
+}
+
+// detectTSPacketSize finds the first run of sync bytes and the packet size
+// they repeat at. It returns the size, the offset of the sync byte in each
+// packet and the bytes to skip to the first whole packet.
+func detectTSPacketSize(reader *bufio.Reader) (int, int, int, error) {
+	window, err := reader.Peek(maxTSSyncSearch)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return 0, 0, 0, err
+	}
+	for i := 0; i < len(window); i++ {
+		if window[i] != tsSyncByte {
+			continue
+		}
+		for _, size := range []int{tsPacketSize, 192, 204} {
+			if !tsSyncRun(window, i, size) {
+				continue
+			}
+			offset := 0
+			if size == 192 {
+				offset = 4 // M2TS packets start with a 4 byte timestamp
+			}
+			skip := i - offset
+			if skip < 0 {
+				skip += size
+			}
+			return size, offset, skip, nil
+		}
+	}
+	return 0, 0, 0, fmt.Errorf("no transport stream sync pattern found")
+}
+
+func tsSyncRun(data []byte, start, size int) bool {
+	if start+(tsSyncCheckPackets-1)*size >= len(data) {
+		return false
+	}
+	for k := 0; k < tsSyncCheckPackets; k++ {
+		if data[start+k*size] != tsSyncByte {
+			return false
+		}
+	}
+	return true
+}
+
+// resyncTS skips to the next run of sync bytes and returns the bytes
+// skipped. At the end of the file everything left is skipped.
+func resyncTS(reader *bufio.Reader, size, offset int) int64 {
+	var skipped int64
+	for skipped < maxTSScannedBytes {
+		window, _ := reader.Peek(size * tsSyncCheckPackets * 4)
+		if len(window) < size*tsSyncCheckPackets {
+			n, _ := reader.Discard(len(window))
+			return skipped + int64(n)
+		}
+		for i := offset + 1; i < len(window); i++ {
+			if window[i] == tsSyncByte && tsSyncRun(window, i, size) {
+				n, _ := reader.Discard(i - offset)
+				return skipped + int64(n)
+			}
+		}
+		n, _ := reader.Discard(len(window) - size*tsSyncCheckPackets)
+		skipped += int64(n)
+	}
+	return skipped
+}
+
+// packet reads one 188 byte packet that starts at byte pos of the file
+func (file *tsFile) packet(p []byte, pos int64) {
+	file.packets++
+	pid := int(p[1]&0x1F)<<8 | int(p[2])
+	state := file.pid(pid)
+	state.packets++
+
+	if p[1]&0x80 != 0 {
+		state.transportErrors++
+		file.errorAt(etr290Transport, pos, fmt.Sprintf("PID 0x%04X transport_error_indicator set", pid))
+		file.count(pid, file.clock(pos))
+		return
+	}
+
+	control := (p[3] >> 4) & 0x03
+	if control == 0 {
+		file.count(pid, file.clock(pos))
+		return // Reserved, the packet is to be discarded
+	}
+	payloadStart := 4
+	discontinuity := false
+	if control&0x02 != 0 {
+		length := int(p[4])
+		if length > tsPacketSize-5 {
+			file.errorAt(etr290Transport, pos, fmt.Sprintf("PID 0x%04X adaptation field of %d bytes", pid, length))
+			file.count(pid, file.clock(pos))
+			return
+		}
+		if length > 0 {
+			flags := p[5]
+			discontinuity = flags&0x80 != 0
+			if flags&0x10 != 0 && length >= 7 {
+				file.readPCR(state, tsPCR(p[6:12]), pos, discontinuity)
+			}
+		}
+		payloadStart = 5 + length
+	}
+
+	now := file.clock(pos)
+	file.count(pid, now)
+	if pid == tsNullPID {
+		return
+	}
+
+	if state.lastSeen >= 0 && now >= 0 && now-state.lastSeen > etr290PIDInterval {
+		state.gaps.add(fmt.Sprintf("PID 0x%04X absent for %s at %s", pid, tsDuration(now-state.lastSeen), file.at(pos)))
+	}
+	if now >= 0 {
+		state.lastSeen = now
+	}
+
+	hasPayload := control&0x01 != 0
+	cc := int(p[3] & 0x0F)
+	file.checkContinuity(state, cc, hasPayload, discontinuity, pos)
+
+	if p[3]>>6 != 0 {
+		state.scrambled++
+		switch {
+		case pid == tsPATPID:
+			file.errorAt(etr290PAT, pos, "PAT packet scrambled")
+		case file.pmtPIDs[pid]:
+			file.errorAt(etr290PMT, pos, fmt.Sprintf("PMT PID 0x%04X packet scrambled", pid))
+		}
+		return
+	}
+	if !hasPayload || payloadStart >= tsPacketSize {
+		return
+	}
+
+	payload := p[payloadStart:]
+	unitStart := p[1]&0x40 != 0
+	if pid == tsPATPID || pid == tsCATPID || pid == tsSDTPID || file.pmtPIDs[pid] {
+		file.readPSI(state, payload, unitStart, now, pos)
+	} else if unitStart {
+		file.readPES(state, payload, now, pos)
+	}
+}
+
+// checkContinuity checks a packet's continuity counter, which advances
+// with each packet carrying payload. One repeated packet is allowed.
+func (file *tsFile) checkContinuity(state *tsPID, cc int, hasPayload, discontinuity bool, pos int64) {
+	last := state.lastCC
+	state.lastCC = cc
+	if last < 0 || discontinuity {
+		state.duplicateRun = 0
+		return
+	}
+	if !hasPayload {
+		if cc != last {
+			state.ccErrors++
+			file.errorAt(etr290Continuity, pos, fmt.Sprintf("PID 0x%04X counter changed from %d to %d without payload", state.pid, last, cc))
+		}
+		return
+	}
+
+	switch {
+	case cc == last:
+		state.duplicateRun++
+		state.duplicates++
+		if state.duplicateRun > 1 {
+			state.ccErrors++
+			file.errorAt(etr290Continuity, pos, fmt.Sprintf("PID 0x%04X counter %d repeated %d times", state.pid, cc, state.duplicateRun))
+		}
+	case cc != (last+1)&0x0F:
+		state.duplicateRun = 0
+		state.ccErrors++
+		file.errorAt(etr290Continuity, pos, fmt.Sprintf("PID 0x%04X counter jumped from %d to %d", state.pid, last, cc))
+	default:
+		state.duplicateRun = 0
+	}
+}
+
+// readPCR records a PCR and, on the reference PID, advances the clock
+func (file *tsFile) readPCR(state *tsPID, pcr, pos int64, discontinuity bool) {
+	stats := state.pcr
+	if stats == nil {
+		stats = &tsPCRStats{}
+		state.pcr = stats
+	}
+	stats.count++
+	if file.clockPID < 0 {
+		file.clockPID = state.pid
+	}
+	if discontinuity {
+		stats.discontinuityFlags++
+		stats.chain = 0
+	}
+
+	if stats.chain > 0 {
+		delta := (pcr - stats.last + tsPCRWrap) % tsPCRWrap
+		if delta == 0 || delta > etr290PCRDiscontinuity {
+			stats.discontinuityErrors++
+			file.errorAt(etr290PCRDiscontinue, pos, fmt.Sprintf("PCR on PID 0x%04X jumps by %s without the discontinuity indicator", state.pid, tsSignedDuration(delta)))
+			stats.chain = 0
+		} else {
+			if delta > etr290PCRRepetition {
+				stats.repetitionErrors++
+				file.errorAt(etr290PCRRepeat, pos, fmt.Sprintf("PCR on PID 0x%04X after %s", state.pid, tsDuration(delta)))
+			}
+			if stats.intervals == 0 || delta < stats.minInterval {
+				stats.minInterval = delta
+			}
+			if delta > stats.maxInterval {
+				stats.maxInterval = delta
+			}
+			stats.intervalSum += delta
+			stats.intervals++
+
+			span := pos - stats.lastPos
+			stats.ticks += delta
+			stats.bytes += span
+			if span > 0 {
+				rate := float64(span) / float64(delta)
+				if stats.minRate == 0 || rate < stats.minRate {
+					stats.minRate = rate
+				}
+				if rate > stats.maxRate {
+					stats.maxRate = rate
+				}
+			}
+
+			// The previous PCR should sit where the line from the one before
+			// it to this one puts its byte position
+			if stats.chain >= 2 && pos > stats.prevPos {
+				whole := (pcr - stats.prev + tsPCRWrap) % tsPCRWrap
+				predicted := float64(stats.prev) + float64(whole)*float64(stats.lastPos-stats.prevPos)/float64(pos-stats.prevPos)
+				jitter := (float64(stats.last) - predicted) * 1e9 / tsPCRHz
+				if jitter < 0 {
+					jitter = -jitter
+				}
+				// A wrap between the samples makes the line meaningless
+				if stats.prev < stats.last && stats.last < pcr {
+					stats.jitterSum += jitter
+					stats.jitterSamples++
+					if jitter > stats.jitterMax {
+						stats.jitterMax = jitter
+					}
+					if jitter > etr290PCRAccuracyNs {
+						stats.accuracy.add(fmt.Sprintf("PCR on PID 0x%04X off by %.0f ns at %s", state.pid, jitter, file.at(stats.lastPos)))
+					}
+				}
+			}
+
+			if state.pid == file.clockPID {
+				file.advanceClock(delta, span, pos)
+			}
+		}
+	}
+	if stats.chain == 0 && state.pid == file.clockPID {
+		file.restartClock(pos)
+	}
+
+	stats.prev, stats.prevPos = stats.last, stats.lastPos
+	stats.last, stats.lastPos = pcr, pos
+	stats.chain++
+}
+
+// advanceClock moves the clock to the reference PCR at pos, delta ticks
+// and span bytes after the previous one
+func (file *tsFile) advanceClock(delta, span, pos int64) {
+	if file.clockValid {
+		file.clockBase += delta
+	} else {
+		file.clockBase = delta
+		file.clockValid = true
+	}
+	file.clockPos = pos
+	if span > 0 {
+		file.rateTicks, file.rateBytes = delta, span
+	}
+}
+
+// restartClock re-anchors the clock after a discontinuity, carrying it on
+// from where the last rate put it
+func (file *tsFile) restartClock(pos int64) {
+	if file.clockValid {
+		file.clockBase = file.clock(pos)
+		file.clockPos = pos
+		return
+	}
+	file.clockBase = 0
+	file.clockPos = pos
+}
+
+// clock returns the time of byte pos since the first reference PCR, or -1
+// until two PCRs have given the rate
+func (file *tsFile) clock(pos int64) int64 {
+	if !file.clockValid || file.rateBytes == 0 {
+		return -1
+	}
+	return file.clockBase + int64(float64(pos-file.clockPos)*float64(file.rateTicks)/float64(file.rateBytes))
+}
+
+// count adds a packet to its second of the stream
+func (file *tsFile) count(pid int, now int64) {
+	if pid == tsNullPID {
+		file.nullPackets++
+	}
+	if now < 0 {
+		return
+	}
+	if file.firstClock < 0 {
+		file.firstClock = now
+	}
+	file.lastClock = now
+	second := file.seconds[now/tsPCRHz]
+	if second == nil {
+		second = &tsSecond{}
+		file.seconds[now/tsPCRHz] = second
+	}
+	second.packets++
+	if pid == tsNullPID {
+		second.nullPackets++
+	}
+}
+
+// readPES notes the PTS of a PES packet starting in this payload
+func (file *tsFile) readPES(state *tsPID, payload []byte, now, pos int64) {
+	if len(payload) < 9 || payload[0] != 0 || payload[1] != 0 || payload[2] != 1 {
+		return
+	}
+	state.pesPackets++
+	switch payload[3] {
+	case 0xBC, 0xBE, 0xBF, 0xF0, 0xF1, 0xF2, 0xF8, 0xFF:
+		return // No optional PES header
+	}
+	if payload[7]&0x80 == 0 || now < 0 {
+		return
+	}
+	if state.lastPTS >= 0 {
+		interval := now - state.lastPTS
+		if interval > state.maxPTSInterval {
+			state.maxPTSInterval = interval
+		}
+		if interval > etr290PTSInterval {
+			state.pts.add(fmt.Sprintf("PTS on PID 0x%04X after %s at %s", state.pid, tsDuration(interval), file.at(pos)))
+		}
+	}
+	state.lastPTS = now
+}
+
+// readPSI gathers PSI sections from a payload. A payload starting a
+// section opens with a pointer to it; the bytes before finish the last one.
+func (file *tsFile) readPSI(state *tsPID, payload []byte, unitStart bool, now, pos int64) {
+	if !unitStart {
+		if state.collecting {
+			file.appendSection(state, payload, now, pos)
+		}
+		return
+	}
+
+	pointer := int(payload[0])
+	payload = payload[1:]
+	if pointer > len(payload) {
+		state.section, state.collecting = state.section[:0], false
+		return
+	}
+	if state.collecting {
+		file.appendSection(state, payload[:pointer], now, pos)
+	}
+	state.section, state.collecting = state.section[:0], true
+	file.appendSection(state, payload[pointer:], now, pos)
+}
+
+// appendSection adds bytes to the section being gathered on a PID and
+// reads each section that completes
+func (file *tsFile) appendSection(state *tsPID, data []byte, now, pos int64) {
+	state.section = append(state.section, data...)
+	for state.collecting && len(state.section) >= 3 {
+		if state.section[0] == 0xFF {
+			state.section, state.collecting = state.section[:0], false // Stuffing ends the packet's sections
+			return
+		}
+		length := 3 + (int(state.section[1]&0x0F)<<8 | int(state.section[2]))
+		if length > maxTSSectionSize {
+			state.section, state.collecting = state.section[:0], false
+			return
+		}
+		if len(state.section) < length {
+			return
+		}
+		section := append([]byte(nil), state.section[:length]...)
+		state.section = append(state.section[:0], state.section[length:]...)
+		file.readSection(state, section, now, pos)
+	}
+}
+
+// readSection reads a complete PSI section
+func (file *tsFile) readSection(state *tsPID, section []byte, now, pos int64) {
+	tableID := int(section[0])
+	if section[1]&0x80 != 0 {
+		if len(section) < 12 || tsCRC32(section) != 0 {
+			state.crcErrors++
+			file.errorAt(etr290CRC, pos, fmt.Sprintf("PID 0x%04X table 0x%02X", state.pid, tableID))
+			return
+		}
+	}
+	state.sections++
+
+	switch {
+	case state.pid == tsPATPID:
+		if tableID != 0x00 {
+			file.errorAt(etr290PAT, pos, fmt.Sprintf("PID 0x0000 carries table 0x%02X", tableID))
+			return
+		}
+		file.checkSectionInterval(state, etr290PAT, "PAT", now, pos)
+		file.readPAT(section)
+	case state.pid == tsCATPID:
+		if tableID != 0x01 {
+			file.errorAt(etr290CAT, pos, fmt.Sprintf("PID 0x0001 carries table 0x%02X", tableID))
+			return
+		}
+		file.hasCAT = true
+	case state.pid == tsSDTPID:
+		if tableID == 0x42 {
+			file.readSDT(section)
+		}
+	case file.pmtPIDs[state.pid]:
+		if tableID != 0x02 {
+			return // Other tables may share a PMT PID
+		}
+		file.checkSectionInterval(state, etr290PMT, fmt.Sprintf("PMT on PID 0x%04X", state.pid), now, pos)
+		file.readPMT(state.pid, section)
+	}
+}
+
+// checkSectionInterval checks the time since the last PAT or PMT section
+func (file *tsFile) checkSectionInterval(state *tsPID, check, table string, now, pos int64) {
+	if now < 0 {
+		return
+	}
+	if state.lastSection >= 0 {
+		gap := now - state.lastSection
+		if gap > state.maxSectionGap {
+			state.maxSectionGap = gap
+		}
+		if gap > etr290PSIInterval {
+			file.errorAt(check, pos, fmt.Sprintf("%s repeated after %s", table, tsDuration(gap)))
+		}
+	}
+	state.lastSection = now
+}
+
+// readPAT reads a PAT section; programs from all its sections are merged
+func (file *tsFile) readPAT(section []byte) {
+	if section[5]&0x01 == 0 {
+		return // Not yet applicable
+	}
+	version := int(section[5]>>1) & 0x1F
+	if file.pat == nil {
+		file.pat = &tsPAT{version: version, programs: make(map[int]int)}
+	} else if file.pat.version != version {
+		file.pat.version = version
+		file.pat.versionChanges++
+	}
+	file.pat.transportStreamID = int(section[3])<<8 | int(section[4])
+
+	for entry := section[8 : len(section)-4]; len(entry) >= 4; entry = entry[4:] {
+		number := int(entry[0])<<8 | int(entry[1])
+		pid := int(entry[2]&0x1F)<<8 | int(entry[3])
+		file.pat.programs[number] = pid
+		if number != 0 {
+			file.pmtPIDs[pid] = true
+		}
+	}
+}
+
+// readPMT reads a PMT section
+func (file *tsFile) readPMT(pid int, section []byte) {
+	if section[5]&0x01 == 0 || len(section) < 16 {
+		return
+	}
+	number := int(section[3])<<8 | int(section[4])
+	version := int(section[5]>>1) & 0x1F
+	pmt := &tsPMT{
+		pid:           pid,
+		programNumber: number,
+		version:       version,
+		pcrPID:        int(section[8]&0x1F)<<8 | int(section[9]),
+		infoLength:    int(section[10]&0x0F)<<8 | int(section[11]),
+	}
+	if previous, ok := file.pmts[number]; ok {
+		pmt.versionChanges = previous.versionChanges
+		if previous.version != version {
+			pmt.versionChanges++
+		}
+	}
+
+	body := section[12 : len(section)-4]
+	if pmt.infoLength > len(body) {
+		return
+	}
+	pmt.descriptors = parseTSDescriptors(body[:pmt.infoLength])
+	for entries := body[pmt.infoLength:]; len(entries) >= 5; {
+		infoLength := int(entries[3]&0x0F)<<8 | int(entries[4])
+		if 5+infoLength > len(entries) {
+			break
+		}
+		pmt.streams = append(pmt.streams, tsElementaryStream{
+			streamType:  int(entries[0]),
+			pid:         int(entries[1]&0x1F)<<8 | int(entries[2]),
+			descriptors: parseTSDescriptors(entries[5 : 5+infoLength]),
+		})
+		entries = entries[5+infoLength:]
+	}
+	file.pmts[number] = pmt
+}
+
+// readSDT reads an SDT section for the actual transport stream
+func (file *tsFile) readSDT(section []byte) {
+	if section[5]&0x01 == 0 || len(section) < 15 {
+		return
+	}
+	if file.sdt == nil {
+		file.sdt = &tsSDT{services: make(map[int]tsService)}
+	}
+	file.sdt.transportStreamID = int(section[3])<<8 | int(section[4])
+	file.sdt.version = int(section[5]>>1) & 0x1F
+	file.sdt.originalNetworkID = int(section[8])<<8 | int(section[9])
+
+	for entries := section[11 : len(section)-4]; len(entries) >= 5; {
+		loopLength := int(entries[3]&0x0F)<<8 | int(entries[4])
+		if 5+loopLength > len(entries) {
+			break
+		}
+		service := tsService{
+			id:     int(entries[0])<<8 | int(entries[1]),
+			freeCA: entries[3]&0x10 != 0,
+		}
+		for _, descriptor := range parseTSDescriptors(entries[5 : 5+loopLength]) {
+			if descriptor.tag != 0x48 || len(descriptor.data) < 2 {
+				continue
+			}
+			data := descriptor.data
+			service.serviceType = int(data[0])
+			providerLength := int(data[1])
+			if 2+providerLength >= len(data) {
+				continue
+			}
+			service.provider = dvbString(data[2 : 2+providerLength])
+			nameLength := int(data[2+providerLength])
+			if name := data[3+providerLength:]; nameLength <= len(name) {
+				service.name = dvbString(name[:nameLength])
+			}
+		}
+		file.sdt.services[service.id] = service
+		entries = entries[5+loopLength:]
+	}
+}
+
+func parseTSDescriptors(data []byte) []tsDescriptor {
+	var descriptors []tsDescriptor
+	for len(data) >= 2 {
+		length := int(data[1])
+		if 2+length > len(data) {
+			break
+		}
+		descriptors = append(descriptors, tsDescriptor{tag: int(data[0]), data: data[2 : 2+length]})
+		data = data[2+length:]
+	}
+	return descriptors
+}
+
+// dvbString decodes DVB SI text, EN 300 468 Annex A. A leading byte below
+// 0x20 selects the character table; the Latin characters all tables share
+// are kept.
+func dvbString(data []byte) string {
+	if len(data) > 0 && data[0] < 0x20 {
+		switch data[0] {
+		case 0x10:
+			if len(data) < 3 {
+				return ""
+			}
+			data = data[3:]
+		case 0x1F:
+			if len(data) < 2 {
+				return ""
+			}
+			data = data[2:]
+		default:
+			data = data[1:]
+		}
+	}
+	var sb strings.Builder
+	for _, b := range data {
+		if b >= 0x20 && b < 0x7F {
+			sb.WriteByte(b)
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+func (file *tsFile) pid(pid int) *tsPID {
+	state, ok := file.pids[pid]
+	if !ok {
+		state = &tsPID{pid: pid, lastCC: -1, lastSeen: -1, lastPTS: -1, lastSection: -1}
+		file.pids[pid] = state
+	}
+	return state
+}
+
+// errorAt records a TR 101 290 error at byte pos
+func (file *tsFile) errorAt(check string, pos int64, detail string) {
+	log := file.errors[check]
+	if log == nil {
+		log = &tsErrorLog{}
+		file.errors[check] = log
+	}
+	log.add(fmt.Sprintf("%s at %s", detail, file.at(pos)))
+}
+
+// at describes the position of byte pos: its time when the clock is known
+func (file *tsFile) at(pos int64) string {
+	if now := file.clock(pos); now >= 0 {
+		return tsTimestamp(now)
+	}
+	return fmt.Sprintf("byte %d", pos)
+}
+
+// sortedPIDs returns the PIDs seen in ascending order
+func (file *tsFile) sortedPIDs() []int {
+	pids := make([]int, 0, len(file.pids))
+	for pid := range file.pids {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	return pids
+}
+
+// durationSeconds is the time the clock covered
+func (file *tsFile) durationSeconds() float64 {
+	if file.firstClock < 0 || file.lastClock <= file.firstClock {
+		return 0
+	}
+	return float64(file.lastClock-file.firstClock) / tsPCRHz
+}
+
+// tsPCR decodes the 33 bit base and 9 bit extension of a PCR field
+func tsPCR(data []byte) int64 {
+	base := int64(data[0])<<25 | int64(data[1])<<17 | int64(data[2])<<9 | int64(data[3])<<1 | int64(data[4]>>7)
+	extension := int64(data[4]&0x01)<<8 | int64(data[5])
+	return base*300 + extension
+}
+
+// tsDuration formats 27 MHz ticks as milliseconds
+func tsDuration(ticks int64) string {
+	return fmt.Sprintf("%.1f ms", float64(ticks)*1000/tsPCRHz)
+}
+
+// tsSignedDuration formats a PCR difference, which is negative when it is
+// more than half the PCR range
+func tsSignedDuration(delta int64) string {
+	if delta > tsPCRWrap/2 {
+		delta -= tsPCRWrap
+	}
+	return tsDuration(delta)
+}
+
+// tsTimestamp formats 27 MHz ticks as HH:MM:SS.mmm
+func tsTimestamp(ticks int64) string {
+	ms := ticks * 1000 / tsPCRHz
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// tsCRCTable is the MPEG-2 CRC-32 table, polynomial 0x04C11DB7 unreflected
+var tsCRCTable = func() (table [256]uint32) {
+	for i := range table {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// tsCRC32 returns the MPEG-2 CRC of data, zero for a section whose
+// trailing CRC_32 is correct
+func tsCRC32(data []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, b := range data {
+		crc = crc<<8 ^ tsCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
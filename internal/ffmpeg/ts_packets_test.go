@@ -0,0 +1,242 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// testTSMux builds transport stream packets with continuity counters kept
+// per PID
+type testTSMux struct {
+	buf bytes.Buffer
+	ccs map[int]int
+}
+
+func newTestTSMux() *testTSMux {
+	return &testTSMux{ccs: make(map[int]int)}
+}
+
+func (m *testTSMux) cc(pid int, payload bool) int {
+	cc, ok := m.ccs[pid]
+	if !ok {
+		cc = 15
+	}
+	if payload {
+		cc = (cc + 1) & 0x0F
+	}
+	m.ccs[pid] = cc
+	return cc
+}
+
+// payload writes a packet carrying data, padded with 0xFF
+func (m *testTSMux) payload(pid int, unitStart bool, data []byte) {
+	p := bytes.Repeat([]byte{0xFF}, tsPacketSize)
+	p[0] = tsSyncByte
+	p[1] = byte(pid >> 8 & 0x1F)
+	if unitStart {
+		p[1] |= 0x40
+	}
+	p[2] = byte(pid)
+	p[3] = 0x10 | byte(m.cc(pid, true))
+	copy(p[4:], data)
+	m.buf.Write(p)
+}
+
+// pcr writes an adaptation field only packet carrying a PCR
+func (m *testTSMux) pcr(pid int, pcr int64) {
+	p := bytes.Repeat([]byte{0xFF}, tsPacketSize)
+	p[0] = tsSyncByte
+	p[1] = byte(pid >> 8 & 0x1F)
+	p[2] = byte(pid)
+	p[3] = 0x20 | byte(m.cc(pid, false))
+	p[4] = tsPacketSize - 5
+	p[5] = 0x10
+	base, extension := pcr/300, pcr%300
+	p[6] = byte(base >> 25)
+	p[7] = byte(base >> 17)
+	p[8] = byte(base >> 9)
+	p[9] = byte(base >> 1)
+	p[10] = byte(base&1)<<7 | 0x7E | byte(extension>>8)
+	p[11] = byte(extension)
+	m.buf.Write(p)
+}
+
+func (m *testTSMux) null() {
+	m.payload(tsNullPID, false, nil)
+}
+
+// testPSISection returns a long form PSI section with its CRC
+func testPSISection(tableID, extension int, body []byte) []byte {
+	length := 5 + len(body) + 4
+	section := []byte{byte(tableID), 0xB0 | byte(length>>8), byte(length), byte(extension >> 8), byte(extension), 0xC1, 0, 0}
+	section = append(section, body...)
+	crc := tsCRC32(section)
+	return append(section, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+}
+
+var (
+	testTSPAT = testPSISection(0x00, 1, []byte{0x00, 0x01, 0xE1, 0x00}) // Program 1 on PID 0x100
+	testTSPMT = testPSISection(0x02, 1, []byte{
+		0xE1, 0x01, 0xF0, 0x00, // PCR PID 0x101, no program descriptors
+		0x1B, 0xE1, 0x01, 0xF0, 0x00, // H.264 on 0x101
+		0x0F, 0xE1, 0x02, 0xF0, 0x06, 0x0A, 0x04, 'e', 'n', 'g', 0x00, // AAC on 0x102, English
+	})
+	testTSPES = []byte{0x00, 0x00, 0x01, 0xE0, 0x00, 0x00, 0x80, 0x80, 0x05, 0x21, 0x00, 0x01, 0x00, 0x01}
+)
+
+// testTransportStream returns one second of a 1.504 Mbit/s mux: every 10 ms
+// a PCR, a video and an audio packet, the PAT and PMT every 100 ms, and
+// nulls. The audio counter skips once when ccError is set.
+func testTransportStream(ccError bool) []byte {
+	m := newTestTSMux()
+	for i := 0; i < 100; i++ {
+		m.pcr(0x101, int64(i)*tsPCRHz/100)
+		m.payload(0x101, true, testTSPES)
+		if ccError && i == 50 {
+			m.cc(0x102, true)
+		}
+		m.payload(0x102, true, testTSPES)
+		if i%10 == 0 {
+			m.payload(tsPATPID, true, append([]byte{0}, testTSPAT...))
+			m.payload(0x100, true, append([]byte{0}, testTSPMT...))
+		} else {
+			m.null()
+			m.null()
+		}
+		for n := 0; n < 5; n++ {
+			m.null()
+		}
+	}
+	return m.buf.Bytes()
+}
+
+func TestParseTS(t *testing.T) {
+	file, err := parseTS(context.Background(), bytes.NewReader(testTransportStream(true)))
+	if err != nil {
+		t.Fatalf("parseTS: %v", err)
+	}
+	if file.packetSize != tsPacketSize || file.packets != 1000 || file.nullPackets != 680 {
+		t.Errorf("got %d byte packets, %d packets, %d null, want 188, 1000, 680", file.packetSize, file.packets, file.nullPackets)
+	}
+	if file.pat == nil || file.pat.programs[1] != 0x100 {
+		t.Fatalf("PAT = %+v, want program 1 on PID 0x100", file.pat)
+	}
+	pmt := file.pmts[1]
+	if pmt == nil || pmt.pcrPID != 0x101 || len(pmt.streams) != 2 {
+		t.Fatalf("PMT = %+v, want PCR PID 0x101 and 2 streams", pmt)
+	}
+	if got := file.pids[0x102].ccErrors; got != 1 {
+		t.Errorf("audio continuity errors = %d, want 1", got)
+	}
+	if got := file.pids[0x101].ccErrors; got != 0 {
+		t.Errorf("video continuity errors = %d, want 0", got)
+	}
+
+	pcr := file.pids[0x101].pcr
+	if pcr == nil || pcr.count != 100 || pcr.maxInterval != tsPCRHz/100 || pcr.jitterMax > 1 {
+		t.Errorf("PCR stats = %+v, want 100 PCRs 10 ms apart without jitter", pcr)
+	}
+	if got := file.durationSeconds(); got < 0.98 || got > 1.0 {
+		t.Errorf("durationSeconds = %f, want about 1", got)
+	}
+}
+
+func TestParseTS_M2TS(t *testing.T) {
+	ts := testTransportStream(false)
+	var m2ts bytes.Buffer
+	for i := 0; i < len(ts); i += tsPacketSize {
+		m2ts.Write([]byte{0, 0, 0, 0})
+		m2ts.Write(ts[i : i+tsPacketSize])
+	}
+
+	file, err := parseTS(context.Background(), &m2ts)
+	if err != nil {
+		t.Fatalf("parseTS: %v", err)
+	}
+	if file.packetSize != 192 || file.packets != 1000 {
+		t.Errorf("got %d byte packets and %d packets, want 192 and 1000", file.packetSize, file.packets)
+	}
+	if file.pmts[1] == nil {
+		t.Error("PMT not read from M2TS packets")
+	}
+}
+
+func TestParseTS_CRCError(t *testing.T) {
+	ts := testTransportStream(false)
+	ts[3*tsPacketSize+14] ^= 0xFF // The first PAT's program entry
+
+	file, err := parseTS(context.Background(), bytes.NewReader(ts))
+	if err != nil {
+		t.Fatalf("parseTS: %v", err)
+	}
+	if got := file.pids[tsPATPID].crcErrors; got != 1 {
+		t.Errorf("PAT CRC errors = %d, want 1", got)
+	}
+	if log := file.errors[etr290CRC]; log == nil || log.count != 1 {
+		t.Errorf("CRC_error log = %+v, want one error", log)
+	}
+}
+
+func TestApplyPackets(t *testing.T) {
+	file, err := parseTS(context.Background(), bytes.NewReader(testTransportStream(true)))
+	if err != nil {
+		t.Fatalf("parseTS: %v", err)
+	}
+	tsa := NewTransportStreamAnalyzer("ffprobe", zerolog.Nop())
+	analysis := &TransportStreamAnalysis{}
+	streams := []StreamInfo{{Index: 0, ID: "0x101", CodecName: "h264", Width: 1920, Height: 1080}}
+	tsa.applyPackets(file, streams, nil, analysis)
+
+	if len(analysis.Programs) != 1 || analysis.Programs[0].PCRPid != 0x101 {
+		t.Fatalf("Programs = %+v", analysis.Programs)
+	}
+	if len(analysis.VideoPIDs) != 1 || analysis.VideoPIDs[0].Width != 1920 {
+		t.Errorf("VideoPIDs = %+v, want 0x101 with ffprobe's width", analysis.VideoPIDs)
+	}
+	if len(analysis.AudioPIDs) != 1 || analysis.AudioPIDs[0].Language != "eng" || analysis.AudioPIDs[0].DiscontinuityCount != 1 {
+		t.Errorf("AudioPIDs = %+v, want English 0x102 with one discontinuity", analysis.AudioPIDs)
+	}
+
+	mux := analysis.MuxStatistics
+	if !mux.ConstantBitRate || mux.MuxBitRate < 1503000 || mux.MuxBitRate > 1505000 {
+		t.Errorf("mux = %+v, want a constant 1.504 Mbit/s", mux)
+	}
+	if mux.NullPercent != 68 {
+		t.Errorf("NullPercent = %f, want 68", mux.NullPercent)
+	}
+	if len(analysis.PCRAnalysis) != 1 || !analysis.PCRAnalysis[0].IsValid || analysis.PCRAnalysis[0].AvgIntervalMs != 10 {
+		t.Errorf("PCRAnalysis = %+v", analysis.PCRAnalysis)
+	}
+
+	etr := analysis.ETR290
+	if etr.Priority1Passed || !etr.Priority2Passed {
+		t.Errorf("priority 1 passed %v, priority 2 passed %v, want false and true", etr.Priority1Passed, etr.Priority2Passed)
+	}
+	for _, check := range etr.Checks {
+		want := check.ID != etr290Continuity
+		if check.Passed != want {
+			t.Errorf("check %s %s passed = %v, want %v (%v)", check.ID, check.Name, check.Passed, want, check.Examples)
+		}
+	}
+
+	validation := tsa.validateTransportStream(analysis)
+	if validation.PIDContinuityValid || !validation.PCRContinuityValid {
+		t.Errorf("PID continuity valid %v, PCR continuity valid %v", validation.PIDContinuityValid, validation.PCRContinuityValid)
+	}
+	if !strings.Contains(strings.Join(validation.Errors, "\n"), "Continuity_count_error: 1 errors") {
+		t.Errorf("errors do not report the continuity error: %v", validation.Errors)
+	}
+}
+
+func TestTSCRC32(t *testing.T) {
+	if got := tsCRC32([]byte("123456789")); got != 0x0376E6E7 {
+		t.Errorf("tsCRC32 = %08X, want 0376E6E7", got)
+	}
+	if got := tsCRC32(testTSPAT); got != 0 {
+		t.Errorf("tsCRC32 of a section with its CRC = %08X, want 0", got)
+	}
+}
@@ -0,0 +1,819 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Stream types that carry video or audio whatever their descriptors
+var (
+	tsVideoStreamTypes = map[int]bool{0x01: true, 0x02: true, 0x10: true, 0x1B: true, 0x1E: true, 0x1F: true, 0x20: true, 0x21: true, 0x24: true, 0x25: true, 0x42: true, 0x80: true, 0xEA: true}
+	tsAudioStreamTypes = map[int]bool{0x03: true, 0x04: true, 0x0F: true, 0x11: true, 0x1C: true, 0x81: true, 0x87: true}
+)
+
+// tsStreamTypeCodecs names the codec a stream type implies
+var tsStreamTypeCodecs = map[int]string{
+	0x01: "mpeg1video", 0x02: "mpeg2video", 0x03: "mp2", 0x04: "mp2", 0x0F: "aac", 0x10: "mpeg4",
+	0x11: "aac_latm", 0x15: "timed_id3", 0x1B: "h264", 0x24: "hevc", 0x42: "cavs", 0x81: "ac3",
+	0x86: "scte_35", 0x87: "eac3", 0xEA: "vc1",
+}
+
+// tsDescriptorNames names the PMT descriptors, ISO/IEC 13818-1, EN 300 468
+// and ATSC A/52
+var tsDescriptorNames = map[int]string{
+	0x02: "Video stream", 0x03: "Audio stream", 0x05: "Registration", 0x06: "Data stream alignment",
+	0x09: "Conditional access", 0x0A: "ISO 639 language", 0x0E: "Maximum bitrate", 0x28: "AVC video",
+	0x2A: "AVC timing and HRD", 0x38: "HEVC video", 0x52: "Stream identifier", 0x56: "Teletext",
+	0x59: "Subtitling", 0x6A: "AC-3", 0x7A: "Enhanced AC-3", 0x7B: "DTS", 0x7C: "AAC",
+	0x7F: "Extension", 0x81: "ATSC AC-3 audio", 0x86: "Caption service", 0x8A: "Cue identifier",
+}
+
+// tsServiceTypes names the DVB service types, EN 300 468 table 87
+var tsServiceTypes = map[int]string{
+	0x01: "Digital television", 0x02: "Digital radio", 0x03: "Teletext", 0x0A: "Advanced codec digital radio",
+	0x0C: "Data broadcast", 0x11: "MPEG-2 HD digital television", 0x16: "Advanced codec SD digital television",
+	0x19: "Advanced codec HD digital television", 0x1F: "HEVC digital television",
+}
+
+// etr290Checks are the TR 101 290 priority 1 and 2 checks, in order
+var etr290Checks = []struct {
+	id, name string
+	priority int
+}{
+	{etr290SyncLoss, "TS_sync_loss", 1},
+	{etr290SyncByte, "Sync_byte_error", 1},
+	{etr290PAT, "PAT_error", 1},
+	{etr290Continuity, "Continuity_count_error", 1},
+	{etr290PMT, "PMT_error", 1},
+	{etr290PID, "PID_error", 1},
+	{etr290Transport, "Transport_error", 2},
+	{etr290CRC, "CRC_error", 2},
+	{etr290PCRRepeat, "PCR_repetition_error", 2},
+	{etr290PCRDiscontinue, "PCR_discontinuity_indicator_error", 2},
+	{etr290PCRAccuracy, "PCR_accuracy_error", 2},
+	{etr290PTS, "PTS_error", 2},
+	{etr290CAT, "CAT_error", 2},
+}
+
+// applyPackets fills the analysis from a transport stream read packet by
+// packet; ffprobe's streams add the codec details the tables do not carry
+func (tsa *TransportStreamAnalyzer) applyPackets(file *tsFile, streams []StreamInfo, format *FormatInfo, analysis *TransportStreamAnalysis) {
+	details := make(map[int]StreamInfo)
+	for _, stream := range streams {
+		if pid, err := strconv.ParseInt(strings.TrimPrefix(stream.ID, "0x"), 16, 32); err == nil {
+			details[int(pid)] = stream
+		}
+	}
+
+	analysis.MuxStatistics = tsa.muxStatisticsFromPackets(file, format)
+	analysis.PATInfo = tsa.patFromPackets(file)
+	tsa.programsFromPackets(file, details, analysis)
+	analysis.SDTInfo = tsa.sdtFromPackets(file, analysis.PATInfo)
+	analysis.SystemPIDs = tsa.systemPIDsFromPackets(file)
+	analysis.PCRAnalysis = tsa.pcrFromPackets(file, analysis.MuxStatistics.ConstantBitRate)
+	analysis.PIDStatistics = tsa.pidStatisticsFromPackets(file, analysis)
+	analysis.ETR290 = tsa.etr290FromPackets(file, analysis)
+}
+
+// muxStatisticsFromPackets measures the mux and null packet rates. The
+// duration comes from the PCRs, or ffprobe when the stream has none.
+func (tsa *TransportStreamAnalyzer) muxStatisticsFromPackets(file *tsFile, format *FormatInfo) *TSMuxStatistics {
+	stats := &TSMuxStatistics{
+		PacketSize:      file.packetSize,
+		TotalPackets:    file.packets,
+		ScannedBytes:    file.bytes,
+		Truncated:       file.truncated,
+		DurationSeconds: file.durationSeconds(),
+		NullPackets:     file.nullPackets,
+		Issues:          append([]string{}, file.issues...),
+	}
+	if file.packets > 0 {
+		stats.NullPercent = float64(file.nullPackets) / float64(file.packets) * 100
+	}
+	if file.truncated {
+		stats.Issues = append(stats.Issues, fmt.Sprintf("Only the first %d bytes were analyzed", file.bytes))
+	}
+
+	if reference, ok := file.pids[file.clockPID]; ok && reference.pcr != nil && reference.pcr.ticks > 0 {
+		pcr := reference.pcr
+		stats.MuxBitRate = float64(pcr.bytes) * 8 * tsPCRHz / float64(pcr.ticks)
+		stats.ConstantBitRate = pcr.minRate > 0 && (pcr.maxRate-pcr.minRate)/pcr.minRate <= tsCBRTolerance
+	} else if format != nil {
+		if duration, err := strconv.ParseFloat(format.Duration, 64); err == nil && duration > 0 {
+			stats.DurationSeconds = duration
+			stats.MuxBitRate = float64(file.bytes) * 8 / duration
+		}
+		stats.Issues = append(stats.Issues, "No PCRs found, rates are file averages")
+	}
+	stats.EffectiveBitRate = stats.MuxBitRate * (1 - stats.NullPercent/100)
+
+	// The first and last seconds are partial, so only whole ones count
+	var seconds []int64
+	for second := range file.seconds {
+		seconds = append(seconds, second)
+	}
+	sort.Slice(seconds, func(i, j int) bool { return seconds[i] < seconds[j] })
+	if len(seconds) > 2 {
+		for _, second := range seconds[1 : len(seconds)-1] {
+			counts := file.seconds[second]
+			rate := float64((counts.packets-counts.nullPackets)*int64(file.packetSize)) * 8
+			if stats.MinEffectiveBitRate == 0 || rate < stats.MinEffectiveBitRate {
+				stats.MinEffectiveBitRate = rate
+			}
+			if rate > stats.MaxEffectiveBitRate {
+				stats.MaxEffectiveBitRate = rate
+			}
+		}
+	}
+	return stats
+}
+
+// patFromPackets describes the PAT and checks it against the PMTs found
+func (tsa *TransportStreamAnalyzer) patFromPackets(file *tsFile) *PATInfo {
+	if file.pat == nil {
+		return nil
+	}
+	state := file.pids[tsPATPID]
+	pat := &PATInfo{
+		TableID:           0x00,
+		TransportStreamID: file.pat.transportStreamID,
+		VersionNumber:     file.pat.version,
+		Programs:          []PATProgram{},
+		CRCValid:          state.crcErrors == 0,
+		Issues:            []string{},
+	}
+	for _, number := range tsProgramNumbers(file) {
+		pat.Programs = append(pat.Programs, PATProgram{ProgramNumber: number, PMTPid: file.pat.programs[number]})
+	}
+	pat.ProgramCount = len(pat.Programs)
+
+	if pat.ProgramCount == 0 {
+		pat.Issues = append(pat.Issues, "PAT lists no programs")
+	}
+	if file.pat.versionChanges > 0 {
+		pat.Issues = append(pat.Issues, fmt.Sprintf("PAT version changed %d times", file.pat.versionChanges))
+	}
+	if state.crcErrors > 0 {
+		pat.Issues = append(pat.Issues, fmt.Sprintf("%d PAT sections failed the CRC", state.crcErrors))
+	}
+	if state.maxSectionGap > etr290PSIInterval {
+		pat.Issues = append(pat.Issues, fmt.Sprintf("PAT repeated at most every %s, the limit is 500 ms", tsDuration(state.maxSectionGap)))
+	}
+
+	for _, pmt := range file.pmts {
+		if pid, ok := file.pat.programs[pmt.programNumber]; !ok || pid != pmt.pid {
+			pat.Issues = append(pat.Issues, fmt.Sprintf("PMT on PID 0x%04X is for program %d, which the PAT does not map there", pmt.pid, pmt.programNumber))
+		}
+	}
+
+	// Every PID carrying packets should be a table, a referenced
+	// elementary stream or a PCR
+	referenced := map[int]bool{}
+	for _, pmt := range file.pmts {
+		referenced[pmt.pcrPID] = true
+		for _, stream := range pmt.streams {
+			referenced[stream.pid] = true
+		}
+	}
+	for _, pid := range file.sortedPIDs() {
+		if pid >= 0x0020 && pid != tsNullPID && !file.pmtPIDs[pid] && !referenced[pid] && pid != file.pat.programs[0] {
+			pat.Issues = append(pat.Issues, fmt.Sprintf("PID 0x%04X carries %d packets but no PMT references it", pid, file.pids[pid].packets))
+		}
+	}
+	sort.Strings(pat.Issues)
+	return pat
+}
+
+// programsFromPackets describes each program of the PAT from its PMT and
+// sorts its elementary streams into video, audio and data PIDs
+func (tsa *TransportStreamAnalyzer) programsFromPackets(file *tsFile, details map[int]StreamInfo, analysis *TransportStreamAnalysis) {
+	if file.pat == nil {
+		return
+	}
+	duration := analysis.MuxStatistics.DurationSeconds
+	streamTypes := map[int]int{} // PID to the stream type the first PMT gave it
+
+	for _, number := range tsProgramNumbers(file) {
+		pmtPID := file.pat.programs[number]
+		program := TSProgram{
+			ProgramNumber:     number,
+			PMTPid:            pmtPID,
+			ServiceTypeDesc:   "Unknown",
+			ElementaryStreams: []ElementaryStream{},
+		}
+		info := PMTInfo{
+			ProgramNumber:     number,
+			TableID:           0x02,
+			ElementaryStreams: []ElementaryStream{},
+			CRCValid:          true,
+			Issues:            []string{},
+		}
+		if state, ok := file.pids[pmtPID]; ok {
+			info.CRCValid = state.crcErrors == 0
+			if state.crcErrors > 0 {
+				info.Issues = append(info.Issues, fmt.Sprintf("%d PMT sections failed the CRC", state.crcErrors))
+			}
+			if state.maxSectionGap > etr290PSIInterval {
+				info.Issues = append(info.Issues, fmt.Sprintf("PMT repeated at most every %s, the limit is 500 ms", tsDuration(state.maxSectionGap)))
+			}
+		}
+		if file.sdt != nil {
+			if service, ok := file.sdt.services[number]; ok {
+				program.ServiceName = service.name
+				program.ServiceProvider = service.provider
+				program.ServiceType = service.serviceType
+				if name, ok := tsServiceTypes[service.serviceType]; ok {
+					program.ServiceTypeDesc = name
+				}
+			}
+		}
+
+		pmt, ok := file.pmts[number]
+		if !ok {
+			if _, present := file.pids[pmtPID]; present {
+				info.Issues = append(info.Issues, fmt.Sprintf("No valid PMT for program %d on PID 0x%04X", number, pmtPID))
+			} else {
+				info.Issues = append(info.Issues, fmt.Sprintf("PMT PID 0x%04X of program %d carries no packets", pmtPID, number))
+			}
+			analysis.Programs = append(analysis.Programs, program)
+			analysis.PMTInfo = append(analysis.PMTInfo, info)
+			continue
+		}
+
+		program.PCRPid = pmt.pcrPID
+		info.VersionNumber = pmt.version
+		info.PCRPid = pmt.pcrPID
+		info.ProgramInfoLength = pmt.infoLength
+		if pmt.versionChanges > 0 {
+			info.Issues = append(info.Issues, fmt.Sprintf("PMT version changed %d times", pmt.versionChanges))
+		}
+		if pmt.pcrPID != tsNullPID {
+			if state, ok := file.pids[pmt.pcrPID]; !ok || state.pcr == nil {
+				info.Issues = append(info.Issues, fmt.Sprintf("PCR PID 0x%04X carries no PCRs", pmt.pcrPID))
+			}
+		}
+		if len(pmt.streams) == 0 {
+			info.Issues = append(info.Issues, "PMT lists no elementary streams")
+		}
+		for _, descriptor := range pmt.descriptors {
+			if descriptor.tag == 0x09 && len(descriptor.data) >= 2 {
+				program.IsEncrypted = true
+				program.CASystemID = int(descriptor.data[0])<<8 | int(descriptor.data[1])
+			}
+		}
+
+		for _, stream := range pmt.streams {
+			es := tsa.elementaryStreamFromPMT(stream)
+			state, present := file.pids[stream.pid]
+			if present && state.scrambled > 0 {
+				es.IsEncrypted = true
+			}
+			if es.IsEncrypted {
+				program.IsEncrypted = true
+			}
+			program.ElementaryStreams = append(program.ElementaryStreams, es)
+			info.ElementaryStreams = append(info.ElementaryStreams, es)
+
+			switch {
+			case stream.pid < 0x0010 || stream.pid == tsNullPID || file.pmtPIDs[stream.pid]:
+				info.Issues = append(info.Issues, fmt.Sprintf("Elementary stream PID 0x%04X is reserved or used by a table", stream.pid))
+			case !present:
+				info.Issues = append(info.Issues, fmt.Sprintf("Elementary stream PID 0x%04X carries no packets", stream.pid))
+			}
+			// A PID shared between programs is listed once
+			if previous, ok := streamTypes[stream.pid]; ok {
+				if previous != stream.streamType {
+					info.Issues = append(info.Issues, fmt.Sprintf("PID 0x%04X is stream type 0x%02X here and 0x%02X in another program", stream.pid, stream.streamType, previous))
+				}
+				continue
+			}
+			streamTypes[stream.pid] = stream.streamType
+			tsa.addElementaryPID(number, stream, es, state, details[stream.pid], duration, file.packetSize, analysis)
+		}
+		analysis.Programs = append(analysis.Programs, program)
+		analysis.PMTInfo = append(analysis.PMTInfo, info)
+	}
+}
+
+// elementaryStreamFromPMT describes a PMT entry and its descriptors
+func (tsa *TransportStreamAnalyzer) elementaryStreamFromPMT(stream tsElementaryStream) ElementaryStream {
+	es := ElementaryStream{
+		PID:            stream.pid,
+		StreamType:     stream.streamType,
+		StreamTypeDesc: streamTypeDefinitions[stream.streamType],
+	}
+	if es.StreamTypeDesc == "" {
+		es.StreamTypeDesc = "Unknown"
+	}
+	for _, descriptor := range stream.descriptors {
+		name, ok := tsDescriptorNames[descriptor.tag]
+		if !ok {
+			name = "User private"
+		}
+		sd := StreamDescriptor{Tag: descriptor.tag, Description: name}
+		data := descriptor.data
+		switch descriptor.tag {
+		case 0x05:
+			if len(data) >= 4 {
+				sd.Data = map[string]interface{}{"format_identifier": string(data[:4])}
+			}
+		case 0x09:
+			es.IsEncrypted = true
+			if len(data) >= 4 {
+				sd.Data = map[string]interface{}{"ca_system_id": int(data[0])<<8 | int(data[1]), "ca_pid": int(data[2]&0x1F)<<8 | int(data[3])}
+			}
+		case 0x0A:
+			if len(data) >= 4 {
+				sd.Data = map[string]interface{}{"language": string(data[:3]), "audio_type": int(data[3])}
+			}
+		case 0x52:
+			if len(data) >= 1 {
+				sd.Data = map[string]interface{}{"component_tag": int(data[0])}
+			}
+		case 0x56, 0x59:
+			if len(data) >= 3 {
+				sd.Data = map[string]interface{}{"language": string(data[:3])}
+			}
+		}
+		es.Descriptors = append(es.Descriptors, sd)
+	}
+	return es
+}
+
+// addElementaryPID adds an elementary stream to the video, audio or data
+// PIDs with its packet counts
+func (tsa *TransportStreamAnalyzer) addElementaryPID(program int, stream tsElementaryStream, es ElementaryStream, state *tsPID, detail StreamInfo, duration float64, packetSize int, analysis *TransportStreamAnalysis) {
+	kind, codec, dataType := tsStreamKind(stream)
+	if detail.CodecName != "" {
+		codec = detail.CodecName
+	}
+	language, audioType := tsStreamLanguage(stream)
+	if language == "" {
+		language = detail.Tags["language"]
+	}
+
+	var packets, transportErrors, ccErrors int64
+	var issues []string
+	if state != nil {
+		packets, transportErrors, ccErrors = state.packets, state.transportErrors, state.ccErrors
+		if ccErrors > 0 {
+			issues = append(issues, fmt.Sprintf("%d continuity counter errors", ccErrors))
+		}
+		if transportErrors > 0 {
+			issues = append(issues, fmt.Sprintf("%d packets with transport errors", transportErrors))
+		}
+		if state.pts.count > 0 && kind != "data" {
+			issues = append(issues, fmt.Sprintf("PTS repeated at most every %s, the limit is 700 ms", tsDuration(state.maxPTSInterval)))
+		}
+	} else {
+		issues = append(issues, "No packets")
+	}
+	bitRate := 0
+	if duration > 0 {
+		bitRate = int(float64(packets*int64(packetSize)) * 8 / duration)
+	} else if rate, err := strconv.Atoi(detail.BitRate); err == nil {
+		bitRate = rate
+	}
+	valid := len(issues) == 0
+
+	switch kind {
+	case "video":
+		analysis.VideoPIDs = append(analysis.VideoPIDs, VideoPID{
+			PID:                stream.pid,
+			StreamType:         stream.streamType,
+			StreamTypeDesc:     es.StreamTypeDesc,
+			CodecName:          codec,
+			Width:              detail.Width,
+			Height:             detail.Height,
+			FrameRate:          detail.RFrameRate,
+			AspectRatio:        detail.DisplayAspectRatio,
+			BitRate:            bitRate,
+			ProgramNumber:      program,
+			PacketCount:        packets,
+			ErrorCount:         transportErrors,
+			DiscontinuityCount: ccErrors,
+			IsValid:            valid,
+			Issues:             issues,
+		})
+	case "audio":
+		audio := AudioPID{
+			PID:                stream.pid,
+			StreamType:         stream.streamType,
+			StreamTypeDesc:     es.StreamTypeDesc,
+			CodecName:          codec,
+			Language:           language,
+			AudioType:          audioType,
+			Channels:           detail.Channels,
+			BitRate:            bitRate,
+			ProgramNumber:      program,
+			PacketCount:        packets,
+			ErrorCount:         transportErrors,
+			DiscontinuityCount: ccErrors,
+			IsValid:            valid,
+			Issues:             issues,
+		}
+		if sampleRate, err := strconv.Atoi(detail.SampleRate); err == nil {
+			audio.SampleRate = sampleRate
+		}
+		analysis.AudioPIDs = append(analysis.AudioPIDs, audio)
+	default:
+		analysis.DataPIDs = append(analysis.DataPIDs, DataPID{
+			PID:            stream.pid,
+			StreamType:     stream.streamType,
+			StreamTypeDesc: es.StreamTypeDesc,
+			DataType:       dataType,
+			Language:       language,
+			ProgramNumber:  program,
+			PacketCount:    packets,
+			ErrorCount:     transportErrors + ccErrors,
+			IsValid:        valid,
+			Issues:         issues,
+		})
+	}
+}
+
+// tsStreamKind classifies an elementary stream as video, audio or data from
+// its stream type and, for private data, its descriptors
+func tsStreamKind(stream tsElementaryStream) (kind, codec, dataType string) {
+	codec = tsStreamTypeCodecs[stream.streamType]
+	switch {
+	case tsVideoStreamTypes[stream.streamType]:
+		return "video", codec, ""
+	case tsAudioStreamTypes[stream.streamType]:
+		return "audio", codec, ""
+	}
+
+	for _, descriptor := range stream.descriptors {
+		switch descriptor.tag {
+		case 0x6A:
+			return "audio", "ac3", ""
+		case 0x7A:
+			return "audio", "eac3", ""
+		case 0x7B:
+			return "audio", "dts", ""
+		case 0x7C:
+			return "audio", "aac", ""
+		case 0x59:
+			return "data", "dvb_subtitle", "subtitles"
+		case 0x56:
+			return "data", "dvb_teletext", "teletext"
+		case 0x05:
+			if len(descriptor.data) < 4 {
+				continue
+			}
+			switch string(descriptor.data[:4]) {
+			case "AC-3":
+				return "audio", "ac3", ""
+			case "EAC3":
+				return "audio", "eac3", ""
+			case "Opus":
+				return "audio", "opus", ""
+			case "BSSD":
+				return "audio", "s302m", ""
+			case "KLVA":
+				return "data", "klv", "metadata"
+			}
+		}
+	}
+
+	switch stream.streamType {
+	case 0x86:
+		return "data", codec, "scte35"
+	case 0x15:
+		return "data", codec, "metadata"
+	case 0x05, 0x0B:
+		return "data", codec, "private_sections"
+	}
+	return "data", codec, "private_data"
+}
+
+// tsStreamLanguage returns the language and audio type a stream's
+// descriptors give
+func tsStreamLanguage(stream tsElementaryStream) (string, string) {
+	audioTypes := []string{"", "clean_effects", "hearing_impaired", "visual_impaired_commentary"}
+	for _, descriptor := range stream.descriptors {
+		switch descriptor.tag {
+		case 0x0A:
+			if len(descriptor.data) >= 4 {
+				audioType := ""
+				if int(descriptor.data[3]) < len(audioTypes) {
+					audioType = audioTypes[descriptor.data[3]]
+				}
+				return string(descriptor.data[:3]), audioType
+			}
+		case 0x56, 0x59:
+			if len(descriptor.data) >= 3 {
+				return string(descriptor.data[:3]), ""
+			}
+		}
+	}
+	return "", ""
+}
+
+// sdtFromPackets describes the SDT and checks its services against the PAT
+func (tsa *TransportStreamAnalyzer) sdtFromPackets(file *tsFile, pat *PATInfo) *SDTInfo {
+	if file.sdt == nil {
+		return nil
+	}
+	sdt := &SDTInfo{
+		TableID:           0x42,
+		TransportStreamID: file.sdt.transportStreamID,
+		VersionNumber:     file.sdt.version,
+		Services:          []SDTService{},
+		CRCValid:          file.pids[tsSDTPID].crcErrors == 0,
+		Issues:            []string{},
+	}
+
+	var ids []int
+	for id := range file.sdt.services {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		service := file.sdt.services[id]
+		sdt.Services = append(sdt.Services, SDTService{
+			ServiceID:       id,
+			ServiceName:     service.name,
+			ServiceProvider: service.provider,
+			ServiceType:     service.serviceType,
+			FreeCAMode:      service.freeCA,
+		})
+		if file.pat != nil {
+			if _, ok := file.pat.programs[id]; !ok {
+				sdt.Issues = append(sdt.Issues, fmt.Sprintf("Service %d has no program in the PAT", id))
+			}
+		}
+	}
+	if pat != nil && pat.TransportStreamID != sdt.TransportStreamID {
+		sdt.Issues = append(sdt.Issues, fmt.Sprintf("SDT transport_stream_id %d differs from the PAT's %d", sdt.TransportStreamID, pat.TransportStreamID))
+	}
+	if !sdt.CRCValid {
+		sdt.Issues = append(sdt.Issues, fmt.Sprintf("%d SDT sections failed the CRC", file.pids[tsSDTPID].crcErrors))
+	}
+	return sdt
+}
+
+// systemPIDsFromPackets lists the table and null PIDs present
+func (tsa *TransportStreamAnalyzer) systemPIDsFromPackets(file *tsFile) []SystemPID {
+	names := map[int][2]string{
+		0x0000:    {"PAT", "Program Association Table"},
+		0x0001:    {"CAT", "Conditional Access Table"},
+		0x0002:    {"TSDT", "Transport Stream Description Table"},
+		0x0010:    {"NIT", "Network Information Table"},
+		0x0011:    {"SDT", "Service Description Table"},
+		0x0012:    {"EIT", "Event Information Table"},
+		0x0013:    {"RST", "Running Status Table"},
+		0x0014:    {"TDT", "Time and Date Table"},
+		tsNullPID: {"NULL", "Null Packet"},
+	}
+	if file.pat != nil {
+		if pid, ok := file.pat.programs[0]; ok {
+			names[pid] = [2]string{"NIT", "Network Information Table"}
+		}
+	}
+
+	systemPIDs := []SystemPID{}
+	for _, pid := range file.sortedPIDs() {
+		name, ok := names[pid]
+		if file.pmtPIDs[pid] {
+			name, ok = [2]string{"PMT", "Program Map Table"}, true
+		}
+		if !ok {
+			continue
+		}
+		state := file.pids[pid]
+		system := SystemPID{
+			PID:         pid,
+			Type:        name[0],
+			Description: name[1],
+			PacketCount: state.packets,
+			ErrorCount:  state.transportErrors + state.ccErrors + state.crcErrors,
+			Issues:      []string{},
+		}
+		if state.ccErrors > 0 {
+			system.Issues = append(system.Issues, fmt.Sprintf("%d continuity counter errors", state.ccErrors))
+		}
+		if state.crcErrors > 0 {
+			system.Issues = append(system.Issues, fmt.Sprintf("%d sections failed the CRC", state.crcErrors))
+		}
+		if state.scrambled > 0 && pid != tsNullPID {
+			system.Issues = append(system.Issues, fmt.Sprintf("%d scrambled packets", state.scrambled))
+		}
+		system.IsValid = len(system.Issues) == 0 && state.transportErrors == 0
+		systemPIDs = append(systemPIDs, system)
+	}
+	return systemPIDs
+}
+
+// pcrFromPackets measures each PCR PID's repetition and jitter. Jitter is
+// how far each PCR sits from the line through its neighbours, which only
+// measures PCR accuracy on a constant bitrate mux.
+func (tsa *TransportStreamAnalyzer) pcrFromPackets(file *tsFile, constantBitRate bool) []PCRAnalysis {
+	var analyses []PCRAnalysis
+	for _, pid := range file.sortedPIDs() {
+		stats := file.pids[pid].pcr
+		if stats == nil {
+			continue
+		}
+		pcr := PCRAnalysis{
+			PID:                      pid,
+			PCRCount:                 stats.count,
+			MaxIntervalMs:            float64(stats.maxInterval) * 1000 / tsPCRHz,
+			MinIntervalMs:            float64(stats.minInterval) * 1000 / tsPCRHz,
+			RepetitionErrors:         stats.repetitionErrors,
+			DiscontinuityErrors:      stats.discontinuityErrors,
+			SignalledDiscontinuities: stats.discontinuityFlags,
+			MaxJitterNs:              stats.jitterMax,
+			Issues:                   []string{},
+		}
+		if stats.intervals > 0 {
+			pcr.AvgIntervalMs = float64(stats.intervalSum) * 1000 / tsPCRHz / float64(stats.intervals)
+		}
+		if stats.jitterSamples > 0 {
+			pcr.MeanJitterNs = stats.jitterSum / float64(stats.jitterSamples)
+		}
+		if stats.ticks > 0 {
+			pcr.BitRate = float64(stats.bytes) * 8 * tsPCRHz / float64(stats.ticks)
+		}
+
+		if stats.repetitionErrors > 0 {
+			pcr.Issues = append(pcr.Issues, fmt.Sprintf("%d PCR intervals over 40 ms, the longest %.1f ms", stats.repetitionErrors, pcr.MaxIntervalMs))
+		}
+		if stats.discontinuityErrors > 0 {
+			pcr.Issues = append(pcr.Issues, fmt.Sprintf("%d PCR jumps without the discontinuity indicator", stats.discontinuityErrors))
+		}
+		if constantBitRate {
+			pcr.AccuracyErrors = stats.accuracy.count
+			if stats.accuracy.count > 0 {
+				pcr.Issues = append(pcr.Issues, fmt.Sprintf("%d PCRs off by more than 500 ns, the worst %.0f ns", stats.accuracy.count, stats.jitterMax))
+			}
+		}
+		pcr.IsValid = len(pcr.Issues) == 0
+		analyses = append(analyses, pcr)
+	}
+	return analyses
+}
+
+// pidStatisticsFromPackets counts the packets, errors and bitrate of every
+// PID present
+func (tsa *TransportStreamAnalyzer) pidStatisticsFromPackets(file *tsFile, analysis *TransportStreamAnalysis) *PIDStatistics {
+	statistics := &PIDStatistics{
+		TotalPIDs:        len(file.pids),
+		UsedPIDs:         len(file.pids),
+		UnusedPIDs:       8192 - len(file.pids),
+		PIDUtilization:   float64(len(file.pids)) / 8192.0 * 100.0,
+		PIDDistribution:  make(map[string]int),
+		PacketStatistics: make(map[int]PacketStats),
+		BitRateAnalysis:  make(map[int]float64),
+	}
+	statistics.PIDDistribution["audio"] = len(analysis.AudioPIDs)
+	statistics.PIDDistribution["video"] = len(analysis.VideoPIDs)
+	statistics.PIDDistribution["data"] = len(analysis.DataPIDs)
+	statistics.PIDDistribution["system"] = len(analysis.SystemPIDs)
+
+	duration := analysis.MuxStatistics.DurationSeconds
+	for pid, state := range file.pids {
+		stats := PacketStats{
+			PacketCount:        state.packets,
+			ErrorCount:         state.transportErrors,
+			DiscontinuityCount: state.ccErrors,
+			DuplicateCount:     state.duplicates,
+		}
+		if state.packets > 0 {
+			stats.ErrorRate = float64(state.transportErrors) / float64(state.packets) * 100.0
+			stats.DiscontinuityRate = float64(state.ccErrors) / float64(state.packets) * 100.0
+		}
+		statistics.PacketStatistics[pid] = stats
+		if duration > 0 {
+			statistics.BitRateAnalysis[pid] = float64(state.packets*int64(file.packetSize)) * 8 / duration
+		}
+	}
+	return statistics
+}
+
+// etr290FromPackets reports the TR 101 290 priority 1 and 2 checks. The
+// reader logs most errors as it goes; those that depend on the tables as a
+// whole are added here.
+func (tsa *TransportStreamAnalyzer) etr290FromPackets(file *tsFile, analysis *TransportStreamAnalysis) *ETR290Analysis {
+	logs := file.errors
+	log := func(check string) *tsErrorLog {
+		if logs[check] == nil {
+			logs[check] = &tsErrorLog{}
+		}
+		return logs[check]
+	}
+	etr := &ETR290Analysis{Priority1Passed: true, Priority2Passed: true, Checks: []ETR290Check{}, Issues: []string{}}
+
+	if file.pat == nil {
+		log(etr290PAT).add("No PAT found")
+	} else {
+		for _, number := range tsProgramNumbers(file) {
+			if _, ok := file.pmts[number]; !ok {
+				log(etr290PMT).add(fmt.Sprintf("No PMT for program %d on PID 0x%04X", number, file.pat.programs[number]))
+			}
+		}
+	}
+
+	var scrambled int64
+	for _, state := range file.pids {
+		scrambled += state.scrambled
+	}
+	if scrambled > 0 && !file.hasCAT {
+		log(etr290CAT).add(fmt.Sprintf("%d scrambled packets but no CAT", scrambled))
+	}
+
+	audioVideo := map[int]bool{}
+	for _, video := range analysis.VideoPIDs {
+		audioVideo[video.PID] = true
+	}
+	for _, audio := range analysis.AudioPIDs {
+		audioVideo[audio.PID] = true
+	}
+	referenced := map[int]bool{}
+	for _, number := range tsProgramNumbers(file) {
+		pmt, ok := file.pmts[number]
+		if !ok {
+			continue
+		}
+		for _, stream := range pmt.streams {
+			if referenced[stream.pid] {
+				continue
+			}
+			referenced[stream.pid] = true
+			state, present := file.pids[stream.pid]
+			if !present {
+				log(etr290PID).add(fmt.Sprintf("PID 0x%04X of program %d carries no packets", stream.pid, number))
+				continue
+			}
+			mergeTSErrorLog(log(etr290PID), state.gaps)
+			if audioVideo[stream.pid] {
+				mergeTSErrorLog(log(etr290PTS), state.pts)
+			}
+		}
+	}
+
+	if analysis.MuxStatistics.ConstantBitRate {
+		for _, pid := range file.sortedPIDs() {
+			if stats := file.pids[pid].pcr; stats != nil {
+				mergeTSErrorLog(log(etr290PCRAccuracy), stats.accuracy)
+			}
+		}
+	} else if file.clockPID >= 0 {
+		etr.Issues = append(etr.Issues, "PCR accuracy is not assessed on a variable bitrate mux")
+	}
+	if file.clockPID < 0 {
+		etr.Issues = append(etr.Issues, "No PCRs found, so table, PCR and PTS intervals were not measured")
+	}
+	if file.truncated {
+		etr.Issues = append(etr.Issues, fmt.Sprintf("Only the first %d bytes were analyzed", file.bytes))
+	}
+
+	for _, definition := range etr290Checks {
+		check := ETR290Check{
+			ID:       definition.id,
+			Name:     definition.name,
+			Priority: definition.priority,
+			Passed:   true,
+		}
+		if entry := logs[definition.id]; entry != nil && entry.count > 0 {
+			check.ErrorCount = entry.count
+			check.Examples = entry.examples
+			check.Passed = false
+			if definition.priority == 1 {
+				etr.Priority1Passed = false
+			} else {
+				etr.Priority2Passed = false
+			}
+		}
+		etr.Checks = append(etr.Checks, check)
+	}
+	return etr
+}
+
+func mergeTSErrorLog(dst *tsErrorLog, src tsErrorLog) {
+	dst.count += src.count
+	for _, example := range src.examples {
+		if len(dst.examples) >= maxETR290Examples {
+			break
+		}
+		dst.examples = append(dst.examples, example)
+	}
+}
+
+// tsProgramNumbers returns the PAT's program numbers in order, without the
+// NIT entry
+func tsProgramNumbers(file *tsFile) []int {
+	if file.pat == nil {
+		return nil
+	}
+	var numbers []int
+	for number := range file.pat.programs {
+		if number != 0 {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+	return numbers
+}
@@ -1,6 +1,7 @@
 package ffmpeg
 
 import (
+	"context"
 	"time"
 )
 
@@ -49,11 +50,75 @@ type FFprobeOptions struct {
 	// Processing limits
 	Timeout       time.Duration `json:"timeout,omitempty"`         // Custom timeout
 	MaxOutputSize int64         `json:"max_output_size,omitempty"` // Custom limit
+	MetadataOnly  bool          `json:"metadata_only,omitempty"`   // Skip enhanced analysis that reads the full input
+	QCCategories  []QCCategory  `json:"qc_categories,omitempty"`   // Run only these QC categories (empty = all)
+
+	// DeliveryProfile checks the result against a delivery preset, running
+	// any measurements it needs that the selected analysis skipped
+	DeliveryProfile *DeliveryProfile `json:"delivery_profile,omitempty"`
+
+	// AnalysisStreams selects audio streams, in select_streams syntax, whose
+	// loudness, silence and phase are measured individually
+	AnalysisStreams string `json:"analysis_streams,omitempty"`
+
+	// ContentSampling makes content analysis of long assets read evenly
+	// spaced windows instead of the whole asset
+	ContentSampling *ContentSamplingOptions `json:"content_sampling,omitempty"`
+
+	// LoudnessTargets are the targets loudness normalization is computed
+	// for; empty means DefaultLoudnessTargets
+	LoudnessTargets []LoudnessTarget `json:"loudness_targets,omitempty"`
+
+	// DecodeScan decodes the whole input after the analysis, adding the
+	// decode errors and corrupt frames found to the data integrity analysis
+	DecodeScan bool `json:"decode_scan,omitempty"`
+
+	// Segment limits the analysis to a portion of the input; it cannot be
+	// combined with ReadIntervals
+	Segment *AnalysisSegment `json:"segment,omitempty"`
+
+	// OnPhase is called as Probe enters and finishes each phase
+	OnPhase PhaseFunc `json:"-"`
 
 	// Custom arguments
 	Args []string `json:"args,omitempty"` // Custom FFprobe arguments
 }
 
+// Phases reported through FFprobeOptions.OnPhase. PhasePSE is reported
+// within PhaseContentAnalysis while the photosensitivity analysis measures
+// frames, which on long assets takes most of the analysis time.
+const (
+	PhaseProbe           = "probe"
+	PhaseContentAnalysis = "content_analysis"
+	PhasePSE             = "pse_analysis"
+)
+
+// PhaseFunc receives the current phase and its completion percentage
+type PhaseFunc func(phase string, percent float64)
+
+func (o *FFprobeOptions) reportPhase(phase string, percent float64) {
+	if o != nil && o.OnPhase != nil {
+		o.OnPhase(phase, percent)
+	}
+}
+
+type phaseFuncKey struct{}
+
+// withPhaseFunc lets analyses under ctx report phases of their own to fn
+func withPhaseFunc(ctx context.Context, fn PhaseFunc) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, phaseFuncKey{}, fn)
+}
+
+// reportContextPhase reports progress to the PhaseFunc of ctx, if any
+func reportContextPhase(ctx context.Context, phase string, percent float64) {
+	if fn, ok := ctx.Value(phaseFuncKey{}).(PhaseFunc); ok {
+		fn(phase, percent)
+	}
+}
+
 // OutputFormat represents ffprobe output formats
 type OutputFormat string
 
@@ -97,6 +162,18 @@ type FFprobeResult struct {
 	// Enhanced analysis data
 	EnhancedAnalysis *EnhancedAnalysis `json:"enhanced_analysis,omitempty"`
 
+	// Descriptive metadata embedded beyond the tags, for local files
+	Metadata *DescriptiveMetadata `json:"metadata,omitempty"`
+
+	// Checksums of the file and its streams, when requested
+	Checksums *Checksums `json:"checksums,omitempty"`
+
+	// Remux and repair commands for the integrity issues found
+	RepairSuggestions []RepairSuggestion `json:"repair_suggestions,omitempty"`
+
+	// Segment is the portion of the asset analyzed, when not all of it
+	Segment *AnalysisSegment `json:"segment,omitempty"`
+
 	// Execution metadata
 	Command       []string      `json:"command"`
 	ExecutionTime time.Duration `json:"execution_time"`
@@ -123,6 +200,7 @@ type FormatInfo struct {
 // StreamInfo represents stream information
 type StreamInfo struct {
 	Index              int                    `json:"index"`
+	ID                 string                 `json:"id,omitempty"`
 	CodecName          string                 `json:"codec_name"`
 	CodecLongName      string                 `json:"codec_long_name"`
 	Profile            string                 `json:"profile,omitempty"`
@@ -276,6 +354,7 @@ type EnhancedAnalysis struct {
 	ContentAnalysis           *ContentAnalysis           `json:"content_analysis,omitempty"`
 	BitDepthAnalysis          *BitDepthAnalysis          `json:"bit_depth_analysis,omitempty"`
 	ResolutionAnalysis        *ResolutionAnalysis        `json:"resolution_analysis,omitempty"`
+	ColorConsistencyAnalysis  *ColorConsistencyAnalysis  `json:"color_consistency_analysis,omitempty"`
 	FrameRateAnalysis         *FrameRateAnalysis         `json:"frame_rate_analysis,omitempty"`
 	CodecAnalysis             *CodecAnalysis             `json:"codec_analysis,omitempty"`
 	ContainerAnalysis         *ContainerAnalysis         `json:"container_analysis,omitempty"`
@@ -285,12 +364,24 @@ type EnhancedAnalysis struct {
 	TransportStreamAnalysis   *TransportStreamAnalysis   `json:"transport_stream_analysis,omitempty"`
 	EndiannessAnalysis        *EndiannessAnalysis        `json:"endianness_analysis,omitempty"`
 	AudioWrappingAnalysis     *AudioWrappingAnalysis     `json:"audio_wrapping_analysis,omitempty"`
+	DolbyAudioAnalysis        *DolbyAudioAnalysis        `json:"dolby_audio_analysis,omitempty"`
+	BWFAnalysis               *BWFAnalysis               `json:"bwf_analysis,omitempty"`
+	AVSyncAnalysis            *AVSyncAnalysis            `json:"av_sync_analysis,omitempty"`
+	TimestampAnalysis         *TimestampAnalysis         `json:"timestamp_analysis,omitempty"`
+	BitrateAnalysis           *BitrateAnalysis           `json:"bitrate_analysis,omitempty"`
+	MezzanineAnalysis         *MezzanineAnalysis         `json:"mezzanine_analysis,omitempty"`
 	IMFAnalysis               *IMFAnalysis               `json:"imf_analysis,omitempty"`
+	DCPAnalysis               *DCPAnalysis               `json:"dcp_analysis,omitempty"`
 	MXFAnalysis               *MXFAnalysis               `json:"mxf_analysis,omitempty"`
 	DeadPixelAnalysis         *DeadPixelAnalysis         `json:"dead_pixel_analysis,omitempty"`
 	PSEAnalysis               *PSEAnalysis               `json:"pse_analysis,omitempty"`
 	StreamDispositionAnalysis *StreamDispositionAnalysis `json:"stream_disposition_analysis,omitempty"`
 	DataIntegrityAnalysis     *DataIntegrityAnalysis     `json:"data_integrity_analysis,omitempty"`
+	DeliveryCompliance        *DeliveryCompliance        `json:"delivery_compliance,omitempty"`
+	Plugins                   []*PluginAnalysis          `json:"plugins,omitempty"` // Results of custom plugin analyzers
+
+	// QCCategories lists the categories that ran when the request selected a subset
+	QCCategories []QCCategory `json:"qc_categories,omitempty"`
 }
 
 // StreamCounts provides detailed stream counting
@@ -362,24 +453,73 @@ type ContentAnalysis struct {
 	BasebandInfo         *BasebandAnalysis             `json:"baseband_info,omitempty"`
 	VideoQualityScore    *VideoQualityScoreAnalysis    `json:"video_quality_score,omitempty"`
 	TemporalComplexity   *TemporalComplexityAnalysis   `json:"temporal_complexity,omitempty"`
+	SceneChanges         *SceneChangeAnalysis          `json:"scene_changes,omitempty"`
 	FieldDominance       *FieldDominanceAnalysis       `json:"field_dominance,omitempty"`
 	DifferentialFrame    *DifferentialFrameAnalysis    `json:"differential_frame,omitempty"`
 	LineErrors           *LineErrorAnalysis            `json:"line_errors,omitempty"`
 	AudioFrequency       *AudioFrequencyAnalysis       `json:"audio_frequency,omitempty"`
+	AudioStreams         map[int]*AudioStreamAnalysis  `json:"audio_streams,omitempty"` // By stream index
+	Sampling             *ContentSampling              `json:"sampling,omitempty"`      // Set when only windows of the asset were analyzed
+}
+
+// AudioStreamAnalysis holds the audio measurements of one selected stream
+type AudioStreamAnalysis struct {
+	StreamIndex   int               `json:"stream_index"`
+	CodecName     string            `json:"codec_name,omitempty"`
+	Channels      int               `json:"channels,omitempty"`
+	ChannelLayout string            `json:"channel_layout,omitempty"`
+	Language      string            `json:"language,omitempty"`
+	Title         string            `json:"title,omitempty"`
+	Loudness      *LoudnessAnalysis `json:"loudness,omitempty"`
+	Silence       *SilenceAnalysis  `json:"silence,omitempty"`
+	Phase         *PhaseAnalysis    `json:"phase,omitempty"`
+	// Multichannel streams are also measured per channel and per channel
+	// pair, e.g. the surrounds of a 5.1 mix
+	ChannelSilence []ChannelSilence   `json:"channel_silence,omitempty"`
+	ChannelPairs   []ChannelPairPhase `json:"channel_pairs,omitempty"`
+	// Declared layout and language checked against the measurements
+	MetadataChecks []AudioMetadataCheck `json:"metadata_checks,omitempty"`
+	Errors         []string             `json:"errors,omitempty"`
+}
+
+// ChannelSilence is the silence of one channel of an audio stream
+type ChannelSilence struct {
+	Channel int              `json:"channel"`
+	Name    string           `json:"name"` // ffmpeg channel name, e.g. "FL", or "c3" in an unknown layout
+	Silence *SilenceAnalysis `json:"silence"`
+}
+
+// ChannelPairPhase is the phase correlation of a left and right channel
+// pair of an audio stream
+type ChannelPairPhase struct {
+	Pair  string         `json:"pair"` // "L/R", "Ls/Rs" or "Lrs/Rrs"
+	Left  int            `json:"left_channel"`
+	Right int            `json:"right_channel"`
+	Phase *PhaseAnalysis `json:"phase,omitempty"`
 }
 
 // BlackFrameAnalysis detects black or nearly black frames
 type BlackFrameAnalysis struct {
-	DetectedFrames int     `json:"detected_frames"`
-	Percentage     float64 `json:"percentage"`
-	Threshold      float64 `json:"threshold"`
+	DetectedFrames int           `json:"detected_frames"`
+	Percentage     float64       `json:"percentage"`
+	Threshold      float64       `json:"threshold"`
+	Periods        []EventPeriod `json:"periods,omitempty"`
 }
 
 // FreezeFrameAnalysis detects static/frozen frames
 type FreezeFrameAnalysis struct {
-	DetectedFrames int     `json:"detected_frames"`
-	Percentage     float64 `json:"percentage"`
-	Threshold      float64 `json:"threshold"`
+	DetectedFrames int           `json:"detected_frames"`
+	Percentage     float64       `json:"percentage"`
+	Threshold      float64       `json:"threshold"`
+	Periods        []EventPeriod `json:"periods,omitempty"`
+}
+
+// EventPeriod is a stretch of the timeline where a detector fired, in
+// seconds from the start of the input
+type EventPeriod struct {
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+	Duration  float64 `json:"duration"`
 }
 
 // AudioClippingAnalysis detects audio clipping
@@ -448,21 +588,36 @@ type AudioLevelAnalysis struct {
 	IsBroadcastSafe   bool               `json:"is_broadcast_safe"`
 	Headroom          float64            `json:"headroom_db"`
 	Severity          string             `json:"severity"`
+
+	// True peaks are measured 4x oversampled by ebur128, unlike the sample
+	// peaks above; nil for silence
+	OverallTruePeak *float64             `json:"overall_true_peak_dbtp,omitempty"`
+	TruePeakLimits  []TruePeakLimitCheck `json:"true_peak_limits,omitempty"`
+}
+
+// TruePeakLimitCheck validates the true peak of every channel against a
+// broadcast limit
+type TruePeakLimitCheck struct {
+	Standard     string  `json:"standard"`
+	LimitDBTP    float64 `json:"limit_dbtp"`
+	Compliant    bool    `json:"compliant"`
+	ChannelsOver []int   `json:"channels_over,omitempty"`
 }
 
 // ChannelLevelInfo provides per-channel audio measurements
 type ChannelLevelInfo struct {
-	Channel      int     `json:"channel"`
-	PeakDB       float64 `json:"peak_db"`
-	RMSDB        float64 `json:"rms_db"`
-	MinDB        float64 `json:"min_db"`
-	MaxDB        float64 `json:"max_db"`
-	CrestFactor  float64 `json:"crest_factor"`
-	DCOffset     float64 `json:"dc_offset"`
-	FlatFactor   float64 `json:"flat_factor"`
-	PeakCount    int     `json:"peak_count"`
-	BitDepth     int     `json:"bit_depth,omitempty"`
-	DynamicRange float64 `json:"dynamic_range_db"`
+	Channel      int      `json:"channel"`
+	PeakDB       float64  `json:"peak_db"`
+	RMSDB        float64  `json:"rms_db"`
+	MinDB        float64  `json:"min_db"`
+	MaxDB        float64  `json:"max_db"`
+	CrestFactor  float64  `json:"crest_factor"`
+	DCOffset     float64  `json:"dc_offset"`
+	FlatFactor   float64  `json:"flat_factor"`
+	PeakCount    int      `json:"peak_count"`
+	BitDepth     int      `json:"bit_depth,omitempty"`
+	DynamicRange float64  `json:"dynamic_range_db"`
+	TruePeakDB   *float64 `json:"true_peak_dbtp,omitempty"`
 }
 
 // LetterboxAnalysis detects letterboxing and pillarboxing in video
@@ -580,16 +735,27 @@ type ChannelMappingAnalysis struct {
 	HasLFE            bool                   `json:"has_lfe"`
 	IsBroadcastLayout bool                   `json:"is_broadcast_layout"`
 	LayoutIssues      []string               `json:"layout_issues,omitempty"`
+	SilentChannels    []int                  `json:"silent_channels,omitempty"`
+	SwappedChannels   []ChannelSwap          `json:"swapped_channels,omitempty"`
+	SuggestedRemap    string                 `json:"suggested_remap,omitempty"` // ffmpeg channelmap or pan filter correcting the layout
 }
 
 // ChannelDetail provides info about individual audio channel
 type ChannelDetail struct {
-	Index       int     `json:"index"`
-	Name        string  `json:"name"`
-	PeakLevel   float64 `json:"peak_level_db"`
-	RMSLevel    float64 `json:"rms_level_db"`
-	IsSilent    bool    `json:"is_silent"`
-	IsActive    bool    `json:"is_active"`
+	Index            int     `json:"index"`
+	Name             string  `json:"name"`
+	PeakLevel        float64 `json:"peak_level_db"`
+	RMSLevel         float64 `json:"rms_level_db"`
+	ZeroCrossingRate float64 `json:"zero_crossing_rate"` // Zero crossings per sample; low for LFE content
+	IsSilent         bool    `json:"is_silent"`
+	IsActive         bool    `json:"is_active"`
+}
+
+// ChannelSwap is two channels whose content looks to be in each other's place
+type ChannelSwap struct {
+	Channels [2]int    `json:"channels"`
+	Names    [2]string `json:"names"`
+	Reason   string    `json:"reason"`
 }
 
 // TimecodeContinuityAnalysis checks for timecode gaps/discontinuities
@@ -645,11 +811,17 @@ type NoiseAnalysis struct {
 
 // LoudnessAnalysis provides broadcast loudness compliance
 type LoudnessAnalysis struct {
-	IntegratedLoudness float64 `json:"integrated_loudness_lufs"`
-	LoudnessRange      float64 `json:"loudness_range_lu"`
-	TruePeak           float64 `json:"true_peak_dbtp"`
-	Compliant          bool    `json:"broadcast_compliant"`
-	Standard           string  `json:"standard"`
+	IntegratedLoudness float64         `json:"integrated_loudness_lufs"`
+	LoudnessRange      float64         `json:"loudness_range_lu"`
+	TruePeak           float64         `json:"true_peak_dbtp"`
+	Threshold          float64         `json:"integrated_threshold_lufs,omitempty"` // Relative gate of the integrated loudness
+	Compliant          bool            `json:"broadcast_compliant"`
+	Standard           string          `json:"standard"`
+	TimelineInterval   float64         `json:"timeline_interval_seconds,omitempty"` // Seconds covered by each timeline point
+	Timeline           []LoudnessPoint `json:"timeline,omitempty"`                  // Loudness and peak over time, for drawing timelines
+
+	Normalization []LoudnessNormalization `json:"normalization,omitempty"` // Correction to each loudness target
+	Dialog        *DialogLoudness         `json:"dialog,omitempty"`        // Loudness of the dialog alone
 }
 
 // HDRAnalysis provides comprehensive HDR metadata analysis
@@ -664,6 +836,7 @@ type HDRAnalysis struct {
 	DolbyVision       *DolbyVisionMetadata      `json:"dolby_vision,omitempty"`
 	HDR10Plus         *HDR10PlusMetadata        `json:"hdr10_plus,omitempty"`
 	HLGCompatible     bool                      `json:"hlg_compatible"`
+	FrameTransfer     string                    `json:"frame_color_transfer,omitempty"` // Transfer signalled by the first frame, which should match the stream's
 	Validation        *HDRValidation            `json:"validation,omitempty"`
 }
 
@@ -675,6 +848,7 @@ type MasteringDisplayMetadata struct {
 	WhitePointY         float64    `json:"white_point_y"`
 	MaxDisplayLuminance float64    `json:"max_display_luminance"` // nits
 	MinDisplayLuminance float64    `json:"min_display_luminance"` // nits
+	Gamut               string     `json:"gamut,omitempty"`       // BT.2020, P3-D65 or BT.709 when the primaries match one
 	HasMasteringDisplay bool       `json:"has_mastering_display"`
 }
 
@@ -687,12 +861,13 @@ type ContentLightLevelData struct {
 
 // DolbyVisionMetadata contains Dolby Vision specific metadata
 type DolbyVisionMetadata struct {
-	Profile                 int  `json:"profile"`
-	Level                   int  `json:"level"`
-	RPUPresent              bool `json:"rpu_present"` // Reference Processing Unit
-	ELPresent               bool `json:"el_present"`  // Enhancement Layer
-	BLPresent               bool `json:"bl_present"`  // Base Layer
-	BLSignalCompatibilityID int  `json:"bl_signal_compatibility_id"`
+	Profile                 int   `json:"profile"`
+	Level                   int   `json:"level"`
+	RPUPresent              bool  `json:"rpu_present"` // Reference Processing Unit
+	ELPresent               bool  `json:"el_present"`  // Enhancement Layer
+	BLPresent               bool  `json:"bl_present"`  // Base Layer
+	BLSignalCompatibilityID int   `json:"bl_signal_compatibility_id"`
+	RPUFound                *bool `json:"rpu_found,omitempty"` // Whether the first frame carries an RPU, when frames were read
 }
 
 // HDR10PlusMetadata contains HDR10+ dynamic metadata information
@@ -710,6 +885,17 @@ type HDRValidation struct {
 	Issues          []string `json:"issues,omitempty"`
 	Recommendations []string `json:"recommendations,omitempty"`
 	GamutCoverage   float64  `json:"gamut_coverage,omitempty"` // Percentage of Rec.2020 gamut covered
+
+	Standards []HDRStandardCheck `json:"standards,omitempty"` // Verdict per HDR standard the stream uses or its format requires
+}
+
+// HDRStandardCheck is the verdict of one HDR standard
+type HDRStandardCheck struct {
+	ID       string   `json:"id"`       // st2084, st2086, cta861_3, hlg, st2094_40 or dolby_vision
+	Standard string   `json:"standard"` // Display name, e.g. SMPTE ST 2086
+	Required bool     `json:"required"` // Whether the HDR format's compliance depends on it
+	Passed   bool     `json:"passed"`
+	Issues   []string `json:"issues,omitempty"`
 }
 
 // BitDepthAnalysis provides comprehensive bit depth analysis
@@ -770,10 +956,17 @@ type VideoResolution struct {
 	SampleAspectRatio   float64 `json:"sample_aspect_ratio"`
 	DisplayAspectRatio  float64 `json:"display_aspect_ratio"`
 	PixelAspectRatio    float64 `json:"pixel_aspect_ratio"`
-	IsAnamorphic        bool    `json:"is_anamorphic"`
+	IsAnamorphic        bool    `json:"is_anamorphic"`         // Non-square pixels
 	AspectRatioCategory string  `json:"aspect_ratio_category"` // "16:9 (Widescreen)", "4:3 (Standard)", etc.
 	Orientation         string  `json:"orientation"`           // "Landscape", "Portrait", "Square"
 	IsConsistent        bool    `json:"is_consistent"`         // Whether metadata is consistent
+
+	ExpectedDisplayAspectRatio  float64  `json:"expected_display_aspect_ratio,omitempty"`  // Width × SAR / height
+	BitstreamSampleAspectRatio  string   `json:"bitstream_sample_aspect_ratio,omitempty"`  // As the H.264 or HEVC headers signal it, e.g. "64:45"
+	BitstreamDisplayAspectRatio float64  `json:"bitstream_display_aspect_ratio,omitempty"` // The display aspect ratio the codec headers give
+	IsAnamorphicSD              bool     `json:"is_anamorphic_sd"`
+	SquarePixelWidth            int      `json:"square_pixel_width,omitempty"` // Width to scale anamorphic SD to for square pixels
+	AspectRatioIssues           []string `json:"aspect_ratio_issues,omitempty"`
 }
 
 // ResolutionValidation contains resolution validation results
@@ -808,6 +1001,11 @@ type VideoFrameRate struct {
 	IsInterlaced        bool    `json:"is_interlaced"`
 	FrameDuration       float64 `json:"frame_duration_ms"` // Duration of one frame in milliseconds
 	IsConsistent        bool    `json:"is_consistent"`     // Whether metadata is consistent
+
+	// Cadence is the telecine cadence idet finds in the frames
+	Cadence *CadenceAnalysis `json:"cadence,omitempty"`
+	// Timing is the frame rate measured from packet timestamps
+	Timing *FrameTimingAnalysis `json:"timing,omitempty"`
 }
 
 // FrameRateValidation contains frame rate validation results
@@ -843,6 +1041,12 @@ type VideoCodecInfo struct {
 	Features        []string     `json:"features,omitempty"` // Codec-specific features
 	HardwareSupport []string     `json:"hardware_support,omitempty"`
 	IsValid         bool         `json:"is_valid"` // Whether profile/level combination is valid
+
+	// AV1 sequence header or VVC SPS, read from the first packet
+	AV1            *AV1SequenceHeader `json:"av1_sequence_header,omitempty"`
+	VVC            *VVCParameters     `json:"vvc_parameters,omitempty"`
+	StreamingNotes []string           `json:"streaming_notes,omitempty"`
+	Issues         []string           `json:"issues,omitempty"`
 }
 
 // AudioCodecInfo contains detailed audio codec information
@@ -942,6 +1146,8 @@ type StreamDispositionAnalysis struct {
 	HasDescriptiveAudio  bool                       `json:"has_descriptive_audio"` // Audio for visually impaired
 	LanguageDistribution map[string]int             `json:"language_distribution,omitempty"`
 	AccessibilityScore   int                        `json:"accessibility_score"` // 0-100 based on accessibility features
+	PolicyCompliant      bool                       `json:"policy_compliant"`    // No packaging rule is broken with an error
+	PolicyViolations     []DispositionViolation     `json:"policy_violations,omitempty"`
 	Validation           *DispositionValidation     `json:"validation,omitempty"`
 }
 
@@ -990,6 +1196,8 @@ type DataIntegrityAnalysis struct {
 	IsCorrupted          bool                     `json:"is_corrupted"`
 	IsBroadcastCompliant bool                     `json:"is_broadcast_compliant"`
 	Validation           *DataIntegrityValidation `json:"validation,omitempty"`
+	DecodeScan           *DecodeScan              `json:"decode_scan,omitempty"` // Full decode, deep mode only
+	MediaInfoCrossCheck  *MediaInfoCrossCheck     `json:"mediainfo_cross_check,omitempty"`
 }
 
 // ErrorSummary contains categorized error information
@@ -1104,6 +1312,10 @@ type TemporalComplexityAnalysis struct {
 	// High complexity segments
 	HighComplexitySegments []ComplexitySegment `json:"high_complexity_segments,omitempty"`
 	FramesAnalyzed      int                  `json:"frames_analyzed"`
+
+	// Spatial and temporal information, the basis of per-title encoding
+	// recommendations
+	SITI *SITIMeasurement `json:"siti,omitempty"`
 }
 
 // ComplexitySegment represents a segment with notable complexity
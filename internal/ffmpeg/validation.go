@@ -39,6 +39,13 @@ func ValidateOptions(opts *FFprobeOptions) error {
 		return fmt.Errorf("analyze duration cannot exceed 1 hour")
 	}
 
+	// Validate QC category selection
+	for _, category := range opts.QCCategories {
+		if _, ok := lookupQCCategory(string(category)); !ok {
+			return fmt.Errorf("unknown QC category: %s", category)
+		}
+	}
+
 	// Validate show entries format
 	if opts.ShowEntries != "" {
 		if err := validateShowEntries(opts.ShowEntries); err != nil {
@@ -53,6 +60,18 @@ func ValidateOptions(opts *FFprobeOptions) error {
 		}
 	}
 
+	// Validate the per-stream analysis selection
+	if _, err := ParseStreamSelector(opts.AnalysisStreams); err != nil {
+		return fmt.Errorf("invalid analysis_streams: %w", err)
+	}
+
+	// Validate content sampling
+	if opts.ContentSampling != nil {
+		if err := opts.ContentSampling.Validate(); err != nil {
+			return fmt.Errorf("invalid content_sampling: %w", err)
+		}
+	}
+
 	// Validate read intervals format
 	if opts.ReadIntervals != "" {
 		if err := validateReadIntervals(opts.ReadIntervals); err != nil {
@@ -69,10 +88,15 @@ func validateInput(input string) error {
 		return fmt.Errorf("input cannot be empty")
 	}
 
-	// Check for potential command injection
+	// Check for potential command injection. Query strings of URLs are exempt:
+	// arguments are passed without a shell and signed URLs routinely contain '&'.
+	checked := input
+	if strings.Contains(input, "://") {
+		checked, _, _ = strings.Cut(input, "?")
+	}
 	dangerousChars := []string{";", "&", "|", "`", "$", "(", ")", "<", ">"}
 	for _, char := range dangerousChars {
-		if strings.Contains(input, char) {
+		if strings.Contains(checked, char) {
 			return fmt.Errorf("input contains dangerous character: %s", char)
 		}
 	}
@@ -137,12 +161,17 @@ func validateSelectStreams(streams string) error {
 	return nil
 }
 
+// readIntervalPattern matches one read interval, [START|+START_OFFSET][%[END|+END_OFFSET]],
+// where the end offset may also be a packet count such as +#100
+var readIntervalPattern = regexp.MustCompile(`^(\+?[0-9]+(:[0-9]{1,2}){0,2}(\.[0-9]+)?)?(%(\+?[0-9]+(:[0-9]{1,2}){0,2}(\.[0-9]+)?|\+#[0-9]+)?)?$`)
+
 // validateReadIntervals validates the read_intervals parameter format
 func validateReadIntervals(intervals string) error {
-	// Basic validation for read intervals
-	// Format: %+#-start,+#end or timestamps
-	if strings.Contains(intervals, ";") || strings.Contains(intervals, "&") {
-		return fmt.Errorf("invalid characters in read_intervals")
+	// Format: comma-separated intervals such as 10%+20,%+#42
+	for _, interval := range strings.Split(intervals, ",") {
+		if interval == "" || !readIntervalPattern.MatchString(interval) {
+			return fmt.Errorf("invalid interval %q", interval)
+		}
 	}
 
 	return nil
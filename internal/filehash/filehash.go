@@ -0,0 +1,92 @@
+// Package filehash identifies media files by content so repeated
+// submissions of the same file can reuse an earlier analysis.
+package filehash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// Mode selects how a file is hashed
+type Mode string
+
+const (
+	// ModeFast hashes the size and the first and last SampleSize bytes with
+	// xxHash. Files differing only in the middle collide.
+	ModeFast Mode = "fast"
+	// ModeSHA256 hashes every byte with SHA-256
+	ModeSHA256 Mode = "sha256"
+)
+
+// SampleSize is how many bytes ModeFast reads from each end of a file
+const SampleSize = 4 << 20
+
+// ParseMode resolves a mode name; empty selects ModeFast
+func ParseMode(name string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(name))) {
+	case "", ModeFast:
+		return ModeFast, nil
+	case ModeSHA256:
+		return ModeSHA256, nil
+	default:
+		return "", fmt.Errorf("unsupported hash mode %q (use fast or sha256)", name)
+	}
+}
+
+// Sum hashes the file at path. Sums are prefixed with their mode, so sums
+// of different modes never match.
+func Sum(path string, mode Mode) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	switch mode {
+	case ModeSHA256:
+		hash := sha256.New()
+		if _, err := io.Copy(hash, file); err != nil {
+			return "", fmt.Errorf("failed to hash file: %w", err)
+		}
+		return string(ModeSHA256) + ":" + hex.EncodeToString(hash.Sum(nil)), nil
+	case ModeFast:
+		info, err := file.Stat()
+		if err != nil {
+			return "", fmt.Errorf("failed to stat file: %w", err)
+		}
+		size := info.Size()
+
+		hash := xxhash.New()
+		if _, err := io.Copy(hash, io.LimitReader(file, SampleSize)); err != nil {
+			return "", fmt.Errorf("failed to hash file: %w", err)
+		}
+		// The tail starts after the head, so small files are read once
+		if offset := max(SampleSize, size-SampleSize); offset < size {
+			if _, err := io.Copy(hash, io.NewSectionReader(file, offset, size-offset)); err != nil {
+				return "", fmt.Errorf("failed to hash file: %w", err)
+			}
+		}
+		return fmt.Sprintf("%s:%d:%016x", ModeFast, size, hash.Sum64()), nil
+	default:
+		return "", fmt.Errorf("unsupported hash mode %q", mode)
+	}
+}
+
+// Key combines a file sum with the options that shape an analysis of it
+// into a 64 character key. The same file analyzed with other options gets
+// another key.
+func Key(sum string, options ...string) string {
+	hash := sha256.New()
+	hash.Write([]byte(sum))
+	for _, option := range options {
+		hash.Write([]byte{0})
+		hash.Write([]byte(option))
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
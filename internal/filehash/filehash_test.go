@@ -0,0 +1,95 @@
+package filehash
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSum(t *testing.T) {
+	data := make([]byte, 3*SampleSize)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	original := writeFile(t, "a.mp4", data)
+	copied := writeFile(t, "b.mp4", data)
+
+	middle := append([]byte(nil), data...)
+	middle[len(middle)/2] ^= 0xff
+	changedMiddle := writeFile(t, "middle.mp4", middle)
+
+	tail := append([]byte(nil), data...)
+	tail[len(tail)-1] ^= 0xff
+	changedTail := writeFile(t, "tail.mp4", tail)
+
+	sum := func(path string, mode Mode) string {
+		t.Helper()
+		s, err := Sum(path, mode)
+		if err != nil {
+			t.Fatalf("Sum(%s, %s) failed: %v", path, mode, err)
+		}
+		return s
+	}
+
+	for _, mode := range []Mode{ModeFast, ModeSHA256} {
+		if !strings.HasPrefix(sum(original, mode), string(mode)+":") {
+			t.Errorf("%s sum lacks its mode prefix: %s", mode, sum(original, mode))
+		}
+		if sum(original, mode) != sum(copied, mode) {
+			t.Errorf("%s sums of identical files differ", mode)
+		}
+		if sum(original, mode) == sum(changedTail, mode) {
+			t.Errorf("%s sum missed a changed tail", mode)
+		}
+	}
+	if sum(original, ModeFast) != sum(changedMiddle, ModeFast) {
+		t.Error("fast sum should only read the head and tail")
+	}
+	if sum(original, ModeSHA256) == sum(changedMiddle, ModeSHA256) {
+		t.Error("sha256 sum missed a changed middle")
+	}
+
+	short := writeFile(t, "short.mp4", []byte("abc"))
+	longer := writeFile(t, "longer.mp4", []byte("abcd"))
+	if sum(short, ModeFast) == sum(longer, ModeFast) {
+		t.Error("fast sums of small files differing in size should differ")
+	}
+}
+
+func TestKey(t *testing.T) {
+	key := Key("fast:3:00", "categories=codec", "profile=")
+	if len(key) != 64 {
+		t.Errorf("expected a 64 character key, got %q", key)
+	}
+	if key != Key("fast:3:00", "categories=codec", "profile=") {
+		t.Error("keys of the same inputs differ")
+	}
+	if key == Key("fast:3:00", "categories=codec,profile=") {
+		t.Error("options should be delimited")
+	}
+	if key == Key("fast:3:00", "categories=audio", "profile=") {
+		t.Error("keys of different options should differ")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if mode, err := ParseMode(""); err != nil || mode != ModeFast {
+		t.Errorf("expected empty to select fast, got %q, %v", mode, err)
+	}
+	if mode, err := ParseMode("SHA256"); err != nil || mode != ModeSHA256 {
+		t.Errorf("expected sha256, got %q, %v", mode, err)
+	}
+	if _, err := ParseMode("md5"); err == nil {
+		t.Error("expected an error for md5")
+	}
+}
@@ -0,0 +1,257 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+)
+
+// segmentAlignmentTolerance is how far, in seconds, a segment boundary may
+// drift from the reference variant; EXTINF durations are often rounded to
+// milliseconds or frames
+const segmentAlignmentTolerance = 0.1
+
+// Ladder alignment checks
+const (
+	AlignmentCheckFetch                 = "playlist_fetch"
+	AlignmentCheckTargetDuration        = "target_duration"
+	AlignmentCheckDiscontinuitySequence = "discontinuity_sequence"
+	AlignmentCheckDiscontinuity         = "discontinuity"
+	AlignmentCheckSegmentCount          = "segment_count"
+	AlignmentCheckSegmentBoundary       = "segment_boundary"
+	AlignmentCheckKeyMethod             = "key_method"
+	AlignmentCheckKeyRotation           = "key_rotation"
+)
+
+// HLSLadderAlignment compares the media playlists of every variant with the
+// first variant of the master playlist. Players switch variants at segment
+// boundaries, so misaligned segments, discontinuities or keys make them
+// stall or glitch on a switch.
+type HLSLadderAlignment struct {
+	Aligned         bool                    `json:"aligned"`
+	ReferenceURI    string                  `json:"reference_uri"`
+	VariantsChecked int                     `json:"variants_checked"`
+	Tolerance       float64                 `json:"tolerance_seconds"`
+	Mismatches      []*HLSAlignmentMismatch `json:"mismatches,omitempty"`
+}
+
+// HLSAlignmentMismatch is one way a variant differs from the reference
+type HLSAlignmentMismatch struct {
+	VariantURI    string `json:"variant_uri"`
+	Bandwidth     int    `json:"bandwidth"`
+	Check         string `json:"check"`
+	MediaSequence *int   `json:"media_sequence,omitempty"` // Segment the mismatch starts at
+	Expected      string `json:"expected,omitempty"`
+	Actual        string `json:"actual,omitempty"`
+	Message       string `json:"message"`
+}
+
+// analyzeLadderAlignment fetches the media playlist of every variant and
+// checks that segments, discontinuities, keys and target durations line up
+func (a *HLSAnalyzer) analyzeLadderAlignment(ctx context.Context, analysis *HLSAnalysis) error {
+	if analysis.ManifestType != ManifestTypeMaster || analysis.MasterPlaylist == nil || len(analysis.MasterPlaylist.Variants) == 0 {
+		return nil
+	}
+
+	var fetchErrors []*HLSAlignmentMismatch
+	a.fetchVariantPlaylists(ctx, analysis.MasterPlaylist.Variants, func(variant *HLSVariant, err error) {
+		fetchErrors = append(fetchErrors, &HLSAlignmentMismatch{
+			VariantURI: variant.URI,
+			Bandwidth:  variant.Bandwidth,
+			Check:      AlignmentCheckFetch,
+			Message:    err.Error(),
+		})
+	})
+
+	alignment := checkLadderAlignment(analysis.MasterPlaylist.Variants)
+	alignment.Mismatches = append(fetchErrors, alignment.Mismatches...)
+	alignment.Aligned = len(alignment.Mismatches) == 0
+	analysis.LadderAlignment = alignment
+	return nil
+}
+
+// fetchVariantPlaylists fetches the media playlists of variants that have
+// not been fetched yet; onError is called for each that fails
+func (a *HLSAnalyzer) fetchVariantPlaylists(ctx context.Context, variants []*HLSVariant, onError func(*HLSVariant, error)) {
+	for _, variant := range variants {
+		if variant.MediaPlaylist != nil {
+			continue
+		}
+		playlist, err := a.fetchMediaPlaylist(ctx, variant.URI)
+		if err != nil {
+			onError(variant, err)
+			continue
+		}
+		variant.MediaPlaylist = playlist
+	}
+}
+
+// fetchMediaPlaylist fetches and parses the media playlist of a variant
+func (a *HLSAnalyzer) fetchMediaPlaylist(ctx context.Context, playlistURL string) (*HLSMediaPlaylist, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", playlistURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch media playlist: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch media playlist: HTTP %d", resp.StatusCode)
+	}
+
+	analysis, err := a.parser.ParseManifest(resp.Body, playlistURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse media playlist: %w", err)
+	}
+	if analysis.MediaPlaylist == nil {
+		return nil, fmt.Errorf("variant URI is not a media playlist")
+	}
+	return analysis.MediaPlaylist, nil
+}
+
+// checkLadderAlignment compares the fetched media playlists of variants
+// with the first one. Live playlists are compared over the media sequence
+// numbers present in both.
+func checkLadderAlignment(variants []*HLSVariant) *HLSLadderAlignment {
+	alignment := &HLSLadderAlignment{Tolerance: segmentAlignmentTolerance}
+
+	var reference *HLSVariant
+	for _, variant := range variants {
+		if variant.MediaPlaylist == nil {
+			continue
+		}
+		alignment.VariantsChecked++
+		if reference == nil {
+			reference = variant
+			alignment.ReferenceURI = variant.URI
+			continue
+		}
+		alignment.Mismatches = append(alignment.Mismatches, compareVariantPlaylists(reference.MediaPlaylist, variant)...)
+	}
+
+	alignment.Aligned = len(alignment.Mismatches) == 0
+	return alignment
+}
+
+// compareVariantPlaylists lists how the media playlist of variant differs
+// from the reference playlist
+func compareVariantPlaylists(reference *HLSMediaPlaylist, variant *HLSVariant) []*HLSAlignmentMismatch {
+	playlist := variant.MediaPlaylist
+	var mismatches []*HLSAlignmentMismatch
+	mismatch := func(check string, sequence *int, expected, actual, message string) {
+		mismatches = append(mismatches, &HLSAlignmentMismatch{
+			VariantURI:    variant.URI,
+			Bandwidth:     variant.Bandwidth,
+			Check:         check,
+			MediaSequence: sequence,
+			Expected:      expected,
+			Actual:        actual,
+			Message:       message,
+		})
+	}
+
+	if playlist.TargetDuration != reference.TargetDuration {
+		mismatch(AlignmentCheckTargetDuration, nil,
+			formatSeconds(reference.TargetDuration), formatSeconds(playlist.TargetDuration),
+			"Target duration differs from the reference variant")
+	}
+	if playlist.EndList && reference.EndList && len(playlist.Segments) != len(reference.Segments) {
+		mismatch(AlignmentCheckSegmentCount, nil,
+			fmt.Sprintf("%d", len(reference.Segments)), fmt.Sprintf("%d", len(playlist.Segments)),
+			"Variant has a different number of segments")
+	}
+
+	// Pair segments by media sequence number
+	first := max(reference.MediaSequence, playlist.MediaSequence)
+	last := min(reference.MediaSequence+len(reference.Segments), playlist.MediaSequence+len(playlist.Segments)) - 1
+	if first > last {
+		return mismatches
+	}
+	// Boundaries are timed from the first segment both playlists list
+	var referenceStart, start float64
+	referenceDiscontinuities := discontinuitySequenceAt(reference, first)
+	discontinuities := discontinuitySequenceAt(playlist, first)
+
+	if referenceDiscontinuities != discontinuities {
+		sequence := first
+		mismatch(AlignmentCheckDiscontinuitySequence, &sequence,
+			fmt.Sprintf("%d", referenceDiscontinuities), fmt.Sprintf("%d", discontinuities),
+			"Discontinuity sequence differs, so players cannot match timelines on a switch")
+	}
+
+	boundaryReported, discontinuityReported, methodReported, rotationReported := false, false, false, false
+	for sequence := first; sequence <= last; sequence++ {
+		referenceSegment := reference.Segments[sequence-reference.MediaSequence]
+		segment := playlist.Segments[sequence-playlist.MediaSequence]
+		at := sequence
+
+		// Report the first misaligned boundary; later ones follow from it
+		if !boundaryReported && math.Abs(start-referenceStart) > segmentAlignmentTolerance {
+			boundaryReported = true
+			mismatch(AlignmentCheckSegmentBoundary, &at,
+				formatSeconds(referenceStart), formatSeconds(start),
+				"Segment starts at a different time than in the reference variant")
+		}
+		if !discontinuityReported && segment.Discontinuity != referenceSegment.Discontinuity {
+			discontinuityReported = true
+			mismatch(AlignmentCheckDiscontinuity, &at,
+				fmt.Sprintf("%t", referenceSegment.Discontinuity), fmt.Sprintf("%t", segment.Discontinuity),
+				"EXT-X-DISCONTINUITY is placed at a different segment than in the reference variant")
+		}
+		if !methodReported && keyMethod(segment.Key) != keyMethod(referenceSegment.Key) {
+			methodReported = true
+			mismatch(AlignmentCheckKeyMethod, &at,
+				keyMethod(referenceSegment.Key), keyMethod(segment.Key),
+				"Segment is encrypted differently than in the reference variant")
+		}
+		if !rotationReported && sequence > first && keyRotates(reference, sequence) != keyRotates(playlist, sequence) {
+			rotationReported = true
+			mismatch(AlignmentCheckKeyRotation, &at,
+				fmt.Sprintf("%t", keyRotates(reference, sequence)), fmt.Sprintf("%t", keyRotates(playlist, sequence)),
+				"Key rotates at a different segment than in the reference variant")
+		}
+
+		referenceStart += referenceSegment.Duration
+		start += segment.Duration
+	}
+	return mismatches
+}
+
+// discontinuitySequenceAt returns the discontinuity number of the segment
+// with media sequence number sequence
+func discontinuitySequenceAt(playlist *HLSMediaPlaylist, sequence int) int {
+	discontinuities := playlist.DiscontinuitySequence
+	for _, segment := range playlist.Segments[:sequence-playlist.MediaSequence+1] {
+		if segment.Discontinuity {
+			discontinuities++
+		}
+	}
+	return discontinuities
+}
+
+// keyRotates reports whether the segment with media sequence number
+// sequence uses a different key than the segment before it
+func keyRotates(playlist *HLSMediaPlaylist, sequence int) bool {
+	index := sequence - playlist.MediaSequence
+	previous, current := playlist.Segments[index-1].Key, playlist.Segments[index].Key
+	if previous == nil || current == nil {
+		return previous != current
+	}
+	return previous.URI != current.URI || previous.IV != current.IV
+}
+
+func keyMethod(key *HLSKey) string {
+	if key == nil || key.Method == "" {
+		return "NONE"
+	}
+	return key.Method
+}
+
+func formatSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3fs", seconds)
+}
@@ -0,0 +1,115 @@
+package hls
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+const referencePlaylist = `#EXTM3U
+#EXT-X-VERSION:3
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:100
+#EXT-X-KEY:METHOD=AES-128,URI="key1"
+#EXTINF:6.0,
+seg100.ts
+#EXTINF:6.0,
+seg101.ts
+#EXT-X-DISCONTINUITY
+#EXT-X-PROGRAM-DATE-TIME:2026-01-01T00:00:12Z
+#EXTINF:6.0,
+seg102.ts
+#EXT-X-KEY:METHOD=AES-128,URI="key2"
+#EXTINF:6.0,
+seg103.ts
+`
+
+func parseTestPlaylist(t *testing.T, playlist string) *HLSMediaPlaylist {
+	t.Helper()
+	analysis, err := NewHLSParser(zerolog.Nop()).ParseManifest(strings.NewReader(playlist), "https://cdn.example.com/live/index.m3u8")
+	if err != nil {
+		t.Fatalf("ParseManifest: %v", err)
+	}
+	return analysis.MediaPlaylist
+}
+
+func TestParseMediaPlaylistSequences(t *testing.T) {
+	playlist := parseTestPlaylist(t, referencePlaylist)
+	if len(playlist.Segments) != 4 || playlist.Segments[0].Sequence != 100 || playlist.Segments[3].Sequence != 103 {
+		t.Fatalf("segments not numbered from the media sequence: %+v", playlist.Segments)
+	}
+	// The discontinuity is found behind the program date time
+	if segment := playlist.Segments[2]; !segment.Discontinuity || segment.ProgramDateTime == nil {
+		t.Errorf("segment 102 = %+v, want a discontinuity with a program date time", segment)
+	}
+}
+
+func TestCheckLadderAlignment(t *testing.T) {
+	reference := parseTestPlaylist(t, referencePlaylist)
+
+	// Live window one segment later, otherwise identical
+	aligned := parseTestPlaylist(t, `#EXTM3U
+#EXT-X-TARGETDURATION:6
+#EXT-X-MEDIA-SEQUENCE:101
+#EXT-X-KEY:METHOD=AES-128,URI="key1"
+#EXTINF:6.0,
+seg101.ts
+#EXT-X-DISCONTINUITY
+#EXTINF:6.0,
+seg102.ts
+#EXT-X-KEY:METHOD=AES-128,URI="key2"
+#EXTINF:6.0,
+seg103.ts
+`)
+
+	// Longer target, a boundary moved, the discontinuity missing and
+	// the key rotating one segment late
+	misaligned := parseTestPlaylist(t, `#EXTM3U
+#EXT-X-TARGETDURATION:8
+#EXT-X-MEDIA-SEQUENCE:100
+#EXT-X-KEY:METHOD=AES-128,URI="key1"
+#EXTINF:6.0,
+seg100.ts
+#EXTINF:7.5,
+seg101.ts
+#EXTINF:4.5,
+seg102.ts
+#EXTINF:6.0,
+seg103.ts
+`)
+
+	alignment := checkLadderAlignment([]*HLSVariant{
+		{URI: "1080p.m3u8", Bandwidth: 6000000, MediaPlaylist: reference},
+		{URI: "720p.m3u8", Bandwidth: 3000000, MediaPlaylist: aligned},
+		{URI: "360p.m3u8", Bandwidth: 800000, MediaPlaylist: misaligned},
+		{URI: "missing.m3u8"},
+	})
+	if alignment.Aligned || alignment.VariantsChecked != 3 || alignment.ReferenceURI != "1080p.m3u8" {
+		t.Errorf("alignment = %+v", alignment)
+	}
+
+	found := map[string]*HLSAlignmentMismatch{}
+	for _, mismatch := range alignment.Mismatches {
+		if mismatch.VariantURI != "360p.m3u8" {
+			t.Errorf("unexpected mismatch of %s: %+v", mismatch.VariantURI, mismatch)
+		}
+		found[mismatch.Check] = mismatch
+	}
+	for check, sequence := range map[string]int{
+		AlignmentCheckSegmentBoundary: 102,
+		AlignmentCheckDiscontinuity:   102,
+		AlignmentCheckKeyRotation:     103,
+	} {
+		mismatch := found[check]
+		if mismatch == nil || mismatch.MediaSequence == nil || *mismatch.MediaSequence != sequence {
+			t.Errorf("%s mismatch = %+v, want one at %d", check, mismatch, sequence)
+		}
+	}
+	if mismatch := found[AlignmentCheckTargetDuration]; mismatch == nil || mismatch.Expected != "6.000s" || mismatch.Actual != "8.000s" {
+		t.Errorf("target duration mismatch = %+v", mismatch)
+	}
+	if mismatch := found[AlignmentCheckDiscontinuitySequence]; mismatch != nil {
+		t.Errorf("unexpected discontinuity sequence mismatch at the first common segment: %+v", mismatch)
+	}
+}
@@ -41,6 +41,9 @@ func (a *HLSAnalyzer) AnalyzeHLS(ctx context.Context, request *HLSAnalysisReques
 		Bool("analyze_segments", request.AnalyzeSegments).
 		Bool("analyze_quality", request.AnalyzeQuality).
 		Bool("validate_compliance", request.ValidateCompliance).
+		Bool("validate_alignment", request.ValidateAlignment).
+		Bool("inspect_encryption", request.InspectEncryption).
+		Bool("validate_low_latency", request.ValidateLowLatency).
 		Msg("Starting HLS analysis")
 
 	result := &HLSAnalysisResult{
@@ -80,6 +83,23 @@ func (a *HLSAnalyzer) AnalyzeHLS(ctx context.Context, request *HLSAnalysisReques
 		}
 	}
 
+	// Check that the variants of the ladder line up
+	if request.ValidateAlignment {
+		if err := a.analyzeLadderAlignment(ctx, analysis); err != nil {
+			a.logger.Warn().Err(err).Msg("Failed to check ladder alignment")
+		}
+	}
+
+	// Check low-latency HLS tags
+	if request.ValidateLowLatency {
+		if err := a.analyzeLowLatency(ctx, analysis); err != nil {
+			a.logger.Warn().Err(err).Msg("Failed to check low-latency HLS")
+		}
+	}
+
+	// Report keys and DRM signalling
+	a.analyzeEncryption(ctx, analysis, request.InspectEncryption)
+
 	// Analyze performance
 	if request.PerformanceAnalysis {
 		if err := a.analyzePerformance(analysis); err != nil {
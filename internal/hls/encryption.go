@@ -0,0 +1,214 @@
+package hls
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rendiffdev/rendiff-probe/internal/drm"
+)
+
+// HLS KEYFORMAT values of DRM systems
+const (
+	KeyFormatIdentity  = "identity"
+	KeyFormatFairPlay  = "com.apple.streamingkeydelivery"
+	KeyFormatPlayReady = "com.microsoft.playready"
+)
+
+const (
+	// maxEncryptionInitSegments bounds the EXT-X-MAP segments fetched to
+	// read their protection boxes
+	maxEncryptionInitSegments = 10
+	// maxInitSegmentSize bounds each EXT-X-MAP download
+	maxInitSegmentSize = 10 * 1024 * 1024
+)
+
+// analyzeEncryption reports the keys of the playlists and, when inspect is
+// set, fetches the variant playlists and reads the protection boxes of their
+// fMP4 initialization segments
+func (a *HLSAnalyzer) analyzeEncryption(ctx context.Context, analysis *HLSAnalysis, inspect bool) {
+	encryption := &drm.Encryption{}
+
+	if master := analysis.MasterPlaylist; master != nil {
+		if inspect {
+			a.fetchVariantPlaylists(ctx, master.Variants, func(variant *HLSVariant, err error) {
+				encryption.AddIssue("Variant playlist %s could not be fetched: %v", variant.URI, err)
+			})
+		}
+		if key := master.SessionKey; key != nil {
+			addHLSKey(encryption, key.Method, key.URI, key.KeyFormat)
+		}
+	}
+
+	playlists := mediaPlaylists(analysis)
+	for _, playlist := range playlists {
+		addPlaylistKeys(encryption, playlist.uri, playlist.playlist)
+	}
+
+	if inspect {
+		a.inspectInitSegments(ctx, playlists, encryption)
+	}
+	encryption.Finalize()
+	analysis.Encryption = encryption
+}
+
+// namedPlaylist is a media playlist and the URI it was fetched from
+type namedPlaylist struct {
+	uri      string
+	playlist *HLSMediaPlaylist
+}
+
+// mediaPlaylists returns the media playlists of an analysis
+func mediaPlaylists(analysis *HLSAnalysis) []namedPlaylist {
+	var playlists []namedPlaylist
+	if analysis.MediaPlaylist != nil {
+		playlists = append(playlists, namedPlaylist{analysis.ManifestURL, analysis.MediaPlaylist})
+	}
+	if analysis.MasterPlaylist != nil {
+		for _, variant := range analysis.MasterPlaylist.Variants {
+			if variant.MediaPlaylist != nil {
+				playlists = append(playlists, namedPlaylist{variant.URI, variant.MediaPlaylist})
+			}
+		}
+	}
+	return playlists
+}
+
+// addPlaylistKeys records the keys of a media playlist and counts it as an
+// encrypted or clear rendition
+func addPlaylistKeys(encryption *drm.Encryption, uri string, playlist *HLSMediaPlaylist) {
+	encrypted, unencrypted := 0, 0
+	for _, segment := range playlist.Segments {
+		if keyMethod(segment.Key) == "NONE" {
+			unencrypted++
+			continue
+		}
+		encrypted++
+		for _, key := range segmentKeys(segment) {
+			addHLSKey(encryption, key.Method, key.URI, key.KeyFormat)
+		}
+	}
+
+	if encrypted > 0 {
+		encryption.EncryptedRenditions++
+	} else {
+		encryption.ClearRenditions++
+	}
+	if encrypted > 0 && unencrypted > 0 {
+		encryption.AddIssue("Playlist %s mixes %d encrypted and %d clear segments", uri, encrypted, unencrypted)
+	}
+}
+
+// segmentKeys returns the keys that apply to a segment
+func segmentKeys(segment *HLSSegment) []*HLSKey {
+	if len(segment.Keys) > 0 {
+		return segment.Keys
+	}
+	return []*HLSKey{segment.Key}
+}
+
+// addHLSKey records an EXT-X-KEY or EXT-X-SESSION-KEY
+func addHLSKey(encryption *drm.Encryption, method, uri, keyFormat string) {
+	if method == "" || strings.EqualFold(method, "NONE") {
+		return
+	}
+	encryption.AddMethod(method)
+	encryption.AddKeyURI(uri)
+	if strings.HasPrefix(uri, "http://") {
+		encryption.AddIssue("Key URI %s is served over plain HTTP", uri)
+	}
+
+	switch format := strings.ToLower(keyFormat); {
+	case format == "" || format == KeyFormatIdentity:
+		// Keys are fetched from the URI as is
+	case format == KeyFormatFairPlay:
+		encryption.AddSystem(drm.SystemFairPlay, drm.SystemName(drm.SystemFairPlay), keyFormat)
+	case format == KeyFormatPlayReady:
+		encryption.AddSystem(drm.SystemPlayReady, drm.SystemName(drm.SystemPlayReady), keyFormat)
+	case strings.HasPrefix(format, "urn:uuid:"):
+		systemID := strings.TrimPrefix(format, "urn:uuid:")
+		encryption.AddSystem(systemID, drm.SystemName(systemID), keyFormat)
+		// Widevine and other CENC systems carry the pssh box in a data URI
+		if _, data, ok := strings.Cut(uri, ";base64,"); ok && strings.HasPrefix(uri, "data:") {
+			pssh, err := drm.ParsePSSHBase64(data)
+			if err != nil {
+				encryption.AddIssue("Key URI for %s does not hold a valid pssh box: %v", keyFormat, err)
+				return
+			}
+			encryption.AddPSSH(pssh, false)
+		}
+	default:
+		encryption.AddSystem("", keyFormat, keyFormat)
+	}
+}
+
+// inspectInitSegments reads the protection boxes of the EXT-X-MAP segments
+// of the playlists
+func (a *HLSAnalyzer) inspectInitSegments(ctx context.Context, playlists []namedPlaylist, encryption *drm.Encryption) {
+	inspected := make(map[string]bool)
+	for _, playlist := range playlists {
+		for _, segment := range playlist.playlist.Segments {
+			if segment.Map == nil || segment.Map.URI == "" || inspected[segment.Map.URI] {
+				continue
+			}
+			if len(inspected) >= maxEncryptionInitSegments || ctx.Err() != nil {
+				return
+			}
+			inspected[segment.Map.URI] = true
+
+			data, err := a.fetchInitSegment(ctx, segment.Map)
+			if err != nil {
+				a.logger.Warn().Err(err).Str("segment_uri", segment.Map.URI).Msg("Failed to fetch initialization segment")
+				encryption.InitSegments = append(encryption.InitSegments, &drm.InitSegment{URL: segment.Map.URI, Error: err.Error()})
+				continue
+			}
+			encryption.AddInitSegment(segment.Map.URI, data)
+
+			initSegment := encryption.InitSegments[len(encryption.InitSegments)-1]
+			method := keyMethod(segment.Key)
+			switch {
+			case initSegment.Error != "":
+			case method == "SAMPLE-AES" && initSegment.Scheme != drm.SchemeCBCS:
+				encryption.AddIssue("Initialization segment %s should use the cbcs scheme for SAMPLE-AES, found %q", segment.Map.URI, initSegment.Scheme)
+			case method == "SAMPLE-AES-CTR" && initSegment.Scheme != drm.SchemeCENC:
+				encryption.AddIssue("Initialization segment %s should use the cenc scheme for SAMPLE-AES-CTR, found %q", segment.Map.URI, initSegment.Scheme)
+			case method == "NONE" && initSegment.Scheme != "":
+				encryption.AddIssue("Initialization segment %s is encrypted with %s but its segments have no EXT-X-KEY", segment.Map.URI, initSegment.Scheme)
+			}
+		}
+	}
+}
+
+// fetchInitSegment downloads an EXT-X-MAP segment, honouring its byte range
+func (a *HLSAnalyzer) fetchInitSegment(ctx context.Context, initMap *HLSMap) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", initMap.URI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if initMap.ByteRange != nil && initMap.ByteRange.Length > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", initMap.ByteRange.Start, initMap.ByteRange.Start+initMap.ByteRange.Length-1))
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch initialization segment: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("failed to fetch initialization segment: HTTP %d", resp.StatusCode)
+	}
+
+	var buf bytes.Buffer
+	written, err := io.Copy(&buf, io.LimitReader(resp.Body, maxInitSegmentSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read initialization segment: %w", err)
+	}
+	if written > maxInitSegmentSize {
+		return nil, fmt.Errorf("initialization segment exceeds %d bytes", maxInitSegmentSize)
+	}
+	return buf.Bytes(), nil
+}
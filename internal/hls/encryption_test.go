@@ -0,0 +1,69 @@
+package hls
+
+import (
+	"testing"
+
+	"github.com/rendiffdev/rendiff-probe/internal/drm"
+	"github.com/rs/zerolog"
+)
+
+const multiDRMPlaylist = `#EXTM3U
+#EXT-X-VERSION:6
+#EXT-X-TARGETDURATION:6
+#EXT-X-MAP:URI="init.mp4"
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="skd://key-1",KEYFORMAT="com.apple.streamingkeydelivery",KEYFORMATVERSIONS="1"
+#EXT-X-KEY:METHOD=SAMPLE-AES,URI="data:text/plain;base64,AAAAIHBzc2gAAAAA7e+LqXnWSs6jyCfc1R0h7QAAAAA=",KEYFORMAT="urn:uuid:edef8ba9-79d6-4ace-a3c8-27dcd51d21ed",KEYFORMATVERSIONS="1"
+#EXTINF:6.0,
+seg0.m4s
+#EXTINF:6.0,
+seg1.m4s
+#EXT-X-KEY:METHOD=NONE
+#EXTINF:6.0,
+seg2.m4s
+`
+
+func TestParseAttributesQuotedCommas(t *testing.T) {
+	attributes := NewHLSParser(zerolog.Nop()).parseAttributes(`#EXT-X-STREAM-INF:BANDWIDTH=800000,CODECS="avc1.4d401f,mp4a.40.2",RESOLUTION=640x360`)
+	if attributes["CODECS"] != "avc1.4d401f,mp4a.40.2" {
+		t.Errorf("expected both codecs, got %q", attributes["CODECS"])
+	}
+	if attributes["RESOLUTION"] != "640x360" || attributes["BANDWIDTH"] != "800000" {
+		t.Errorf("unexpected attributes %v", attributes)
+	}
+}
+
+func TestAddPlaylistKeys(t *testing.T) {
+	playlist := parseTestPlaylist(t, multiDRMPlaylist)
+	encryption := &drm.Encryption{}
+	addPlaylistKeys(encryption, "video.m3u8", playlist)
+	addHLSKey(encryption, "AES-128", "http://keys.example.com/k1", "")
+	encryption.Finalize()
+
+	if !encryption.Encrypted || encryption.EncryptedRenditions != 1 {
+		t.Fatalf("expected one encrypted rendition, got %+v", encryption)
+	}
+	if len(encryption.Methods) != 2 || encryption.Methods[0] != "SAMPLE-AES" || encryption.Methods[1] != "AES-128" {
+		t.Errorf("unexpected methods %v", encryption.Methods)
+	}
+	// The Widevine data URI is not a key URI
+	if len(encryption.KeyURIs) != 2 || encryption.KeyURIs[0] != "skd://key-1" {
+		t.Errorf("unexpected key URIs %v", encryption.KeyURIs)
+	}
+	if len(encryption.Systems) != 2 || encryption.Systems[0].Name != "FairPlay" || encryption.Systems[1].Name != "Widevine" {
+		t.Fatalf("expected FairPlay and Widevine, got %+v", encryption.Systems)
+	}
+	if !encryption.Systems[1].HasPSSH {
+		t.Error("expected the Widevine pssh from the data URI")
+	}
+
+	expected := map[string]bool{
+		"Playlist video.m3u8 mixes 2 encrypted and 1 clear segments":   true,
+		"Key URI http://keys.example.com/k1 is served over plain HTTP": true,
+	}
+	for _, issue := range encryption.Issues {
+		delete(expected, issue)
+	}
+	for issue := range expected {
+		t.Errorf("missing issue %q in %v", issue, encryption.Issues)
+	}
+}
@@ -0,0 +1,230 @@
+package hls
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// partDurationTolerance absorbs rounding of part durations and the hold
+// backs derived from them
+const partDurationTolerance = 0.001
+
+// Low-latency HLS checks
+const (
+	LowLatencyCheckFetch            = "playlist_fetch"
+	LowLatencyCheckPartInf          = "part_inf"
+	LowLatencyCheckPartDuration     = "part_duration"
+	LowLatencyCheckPartConsistency  = "part_duration_consistency"
+	LowLatencyCheckPartSum          = "part_segment_sum"
+	LowLatencyCheckIndependentParts = "independent_parts"
+	LowLatencyCheckBlockingReload   = "blocking_reload"
+	LowLatencyCheckPartHoldBack     = "part_hold_back"
+	LowLatencyCheckHoldBack         = "hold_back"
+	LowLatencyCheckSkipUntil        = "can_skip_until"
+	LowLatencyCheckPreloadHint      = "preload_hint"
+	LowLatencyCheckRenditionReport  = "rendition_report"
+)
+
+// HLSLowLatencyReport is the result of the LL-HLS checks of every media
+// playlist of an analysis
+type HLSLowLatencyReport struct {
+	Compliant        bool                     `json:"compliant"` // No errors in any low-latency playlist
+	PlaylistsChecked int                      `json:"playlists_checked"`
+	Playlists        []*HLSLowLatencyPlaylist `json:"playlists"`
+}
+
+// HLSLowLatencyPlaylist summarizes the LL-HLS signalling of a media playlist
+type HLSLowLatencyPlaylist struct {
+	URI              string                `json:"uri"`
+	LowLatency       bool                  `json:"low_latency"` // Lists parts or EXT-X-PART-INF
+	PartTarget       float64               `json:"part_target,omitempty"`
+	CanBlockReload   bool                  `json:"can_block_reload"`
+	PartHoldBack     float64               `json:"part_hold_back,omitempty"`
+	PartCount        int                   `json:"part_count"`
+	MaxPartDuration  float64               `json:"max_part_duration,omitempty"`
+	PreloadHints     int                   `json:"preload_hints"`
+	RenditionReports int                   `json:"rendition_reports"`
+	Issues           []*HLSLowLatencyIssue `json:"issues,omitempty"`
+}
+
+// HLSLowLatencyIssue is one LL-HLS rule a playlist breaks
+type HLSLowLatencyIssue struct {
+	Check         string `json:"check"`
+	Severity      string `json:"severity"`                 // error or warning
+	MediaSequence *int   `json:"media_sequence,omitempty"` // Segment the issue was found at
+	Message       string `json:"message"`
+}
+
+// analyzeLowLatency checks the LL-HLS tags of the media playlist, or of the
+// playlist of every variant of a master playlist
+func (a *HLSAnalyzer) analyzeLowLatency(ctx context.Context, analysis *HLSAnalysis) error {
+	report := &HLSLowLatencyReport{Compliant: true}
+	var renditions []string
+
+	if master := analysis.MasterPlaylist; master != nil {
+		a.fetchVariantPlaylists(ctx, master.Variants, func(variant *HLSVariant, err error) {
+			report.Playlists = append(report.Playlists, &HLSLowLatencyPlaylist{
+				URI:    variant.URI,
+				Issues: []*HLSLowLatencyIssue{{Check: LowLatencyCheckFetch, Severity: "error", Message: err.Error()}},
+			})
+			report.Compliant = false
+		})
+		for _, variant := range master.Variants {
+			renditions = append(renditions, variant.URI)
+		}
+	}
+
+	for _, playlist := range mediaPlaylists(analysis) {
+		checked := checkLowLatency(playlist.uri, playlist.playlist, renditions)
+		report.PlaylistsChecked++
+		report.Playlists = append(report.Playlists, checked)
+		for _, issue := range checked.Issues {
+			if issue.Severity == "error" {
+				report.Compliant = false
+			}
+		}
+	}
+
+	analysis.LowLatency = report
+	return nil
+}
+
+// checkLowLatency checks a media playlist against the LL-HLS rules of
+// draft-pantos-hls-rfc8216bis. renditions lists the playlists of the master
+// playlist that rendition reports are expected for.
+func checkLowLatency(uri string, playlist *HLSMediaPlaylist, renditions []string) *HLSLowLatencyPlaylist {
+	checked := &HLSLowLatencyPlaylist{
+		URI:              uri,
+		PartTarget:       playlist.PartTarget,
+		PreloadHints:     len(playlist.PreloadHints),
+		RenditionReports: len(playlist.RenditionReports),
+	}
+	issue := func(check, severity string, sequence *int, format string, args ...interface{}) {
+		checked.Issues = append(checked.Issues, &HLSLowLatencyIssue{
+			Check:         check,
+			Severity:      severity,
+			MediaSequence: sequence,
+			Message:       fmt.Sprintf(format, args...),
+		})
+	}
+	if control := playlist.ServerControl; control != nil {
+		checked.CanBlockReload = control.CanBlockReload
+		checked.PartHoldBack = control.PartHoldBack
+	}
+
+	independent := false
+	for _, segment := range playlist.Segments {
+		checked.PartCount += len(segment.Parts)
+		independent = independent || checkSegmentParts(checked, playlist, segment.Sequence, segment.Parts, segment.Duration, issue)
+	}
+	checked.PartCount += len(playlist.PendingParts)
+	independent = checkSegmentParts(checked, playlist, -1, playlist.PendingParts, 0, issue) || independent
+
+	checked.LowLatency = checked.PartCount > 0 || playlist.PartTarget > 0
+	if control := playlist.ServerControl; control != nil {
+		if control.HoldBack > 0 && control.HoldBack < 3*playlist.TargetDuration {
+			issue(LowLatencyCheckHoldBack, "error", nil,
+				"HOLD-BACK %s is less than three target durations (%s)", formatSeconds(control.HoldBack), formatSeconds(3*playlist.TargetDuration))
+		}
+		if control.CanSkipUntil > 0 && control.CanSkipUntil < 6*playlist.TargetDuration {
+			issue(LowLatencyCheckSkipUntil, "error", nil,
+				"CAN-SKIP-UNTIL %s is less than six target durations (%s)", formatSeconds(control.CanSkipUntil), formatSeconds(6*playlist.TargetDuration))
+		}
+	}
+	if !checked.LowLatency {
+		return checked
+	}
+
+	if playlist.PartTarget <= 0 {
+		issue(LowLatencyCheckPartInf, "error", nil, "Playlist lists EXT-X-PART but has no EXT-X-PART-INF with PART-TARGET")
+	}
+	if checked.PartCount > 0 && !independent {
+		issue(LowLatencyCheckIndependentParts, "warning", nil, "No part is marked INDEPENDENT=YES, so players can only join at segment boundaries")
+	}
+
+	// Live playlists are reloaded with blocking requests for the next part
+	if playlist.EndList {
+		return checked
+	}
+	if !checked.CanBlockReload {
+		issue(LowLatencyCheckBlockingReload, "error", nil, "EXT-X-SERVER-CONTROL must declare CAN-BLOCK-RELOAD=YES for low-latency playlists")
+	}
+	switch {
+	case checked.PartHoldBack <= 0:
+		issue(LowLatencyCheckPartHoldBack, "error", nil, "EXT-X-SERVER-CONTROL is missing PART-HOLD-BACK")
+	case playlist.PartTarget > 0 && checked.PartHoldBack+partDurationTolerance < 2*playlist.PartTarget:
+		issue(LowLatencyCheckPartHoldBack, "error", nil,
+			"PART-HOLD-BACK %s is less than twice the part target (%s)", formatSeconds(checked.PartHoldBack), formatSeconds(2*playlist.PartTarget))
+	case playlist.PartTarget > 0 && checked.PartHoldBack+partDurationTolerance < 3*playlist.PartTarget:
+		issue(LowLatencyCheckPartHoldBack, "warning", nil,
+			"PART-HOLD-BACK %s is less than the recommended three part targets (%s)", formatSeconds(checked.PartHoldBack), formatSeconds(3*playlist.PartTarget))
+	}
+
+	hasPartHint := false
+	for _, hint := range playlist.PreloadHints {
+		switch {
+		case hint.Type != "PART" && hint.Type != "MAP":
+			issue(LowLatencyCheckPreloadHint, "error", nil, "EXT-X-PRELOAD-HINT has TYPE %q; it must be PART or MAP", hint.Type)
+		case hint.URI == "":
+			issue(LowLatencyCheckPreloadHint, "error", nil, "EXT-X-PRELOAD-HINT of TYPE %s has no URI", hint.Type)
+		case hint.Type == "PART":
+			hasPartHint = true
+		}
+	}
+	if !hasPartHint {
+		issue(LowLatencyCheckPreloadHint, "warning", nil, "No EXT-X-PRELOAD-HINT announces the next part, so players cannot request it ahead of time")
+	}
+
+	reported := make(map[string]bool, len(playlist.RenditionReports))
+	for _, report := range playlist.RenditionReports {
+		reported[report.URI] = true
+		if report.LastMSN == nil {
+			issue(LowLatencyCheckRenditionReport, "warning", nil, "EXT-X-RENDITION-REPORT for %s has no LAST-MSN", report.URI)
+		}
+	}
+	for _, rendition := range renditions {
+		if rendition != uri && !reported[rendition] {
+			issue(LowLatencyCheckRenditionReport, "warning", nil,
+				"No EXT-X-RENDITION-REPORT for %s, so switching to it needs an extra playlist request", rendition)
+		}
+	}
+	return checked
+}
+
+// checkSegmentParts checks the parts of one segment and reports whether any
+// of them is independent. sequence is -1 for the parts of the segment still
+// being produced, whose duration is not known yet.
+func checkSegmentParts(checked *HLSLowLatencyPlaylist, playlist *HLSMediaPlaylist, sequence int, parts []*HLSPart, duration float64,
+	issue func(check, severity string, sequence *int, format string, args ...interface{})) bool {
+	var at *int
+	if sequence >= 0 {
+		at = &sequence
+	}
+
+	independent := false
+	var total float64
+	for i, part := range parts {
+		independent = independent || part.Independent
+		total += part.Duration
+		checked.MaxPartDuration = math.Max(checked.MaxPartDuration, part.Duration)
+		if playlist.PartTarget <= 0 {
+			continue
+		}
+		if part.Duration > playlist.PartTarget+partDurationTolerance {
+			issue(LowLatencyCheckPartDuration, "error", at,
+				"Part %d lasts %s, longer than the part target %s", i, formatSeconds(part.Duration), formatSeconds(playlist.PartTarget))
+		}
+		// Only the last part of a segment may be short
+		if i < len(parts)-1 && part.Duration < 0.85*playlist.PartTarget {
+			issue(LowLatencyCheckPartConsistency, "warning", at,
+				"Part %d lasts %s, less than 85%% of the part target %s", i, formatSeconds(part.Duration), formatSeconds(playlist.PartTarget))
+		}
+	}
+
+	if sequence >= 0 && len(parts) > 0 && math.Abs(total-duration) > segmentAlignmentTolerance {
+		issue(LowLatencyCheckPartSum, "warning", at,
+			"Parts add up to %s but the segment lasts %s", formatSeconds(total), formatSeconds(duration))
+	}
+	return independent
+}
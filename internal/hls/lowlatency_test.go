@@ -0,0 +1,81 @@
+package hls
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// lowLatencyPlaylist builds a live LL-HLS playlist with one complete segment
+// of 12 parts and two parts of the next segment
+func lowLatencyPlaylist(serverControl, partDuration, trailer string) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-TARGETDURATION:4\n#EXT-X-VERSION:6\n")
+	b.WriteString("#EXT-X-SERVER-CONTROL:" + serverControl + "\n")
+	b.WriteString("#EXT-X-PART-INF:PART-TARGET=0.33334\n#EXT-X-MEDIA-SEQUENCE:266\n")
+	b.WriteString("#EXTINF:4.00008,\nfileSequence266.mp4\n")
+	for i := 0; i < 12; i++ {
+		fmt.Fprintf(&b, "#EXT-X-PART:DURATION=%s,URI=\"filePart267.%d.mp4\"%s\n", partDuration, i, map[bool]string{true: ",INDEPENDENT=YES"}[i == 0])
+	}
+	b.WriteString("#EXTINF:4.00008,\nfileSequence267.mp4\n")
+	b.WriteString("#EXT-X-PART:DURATION=0.33334,URI=\"filePart268.0.mp4\",INDEPENDENT=YES\n")
+	b.WriteString("#EXT-X-PART:DURATION=0.33334,URI=\"filePart268.1.mp4\"\n")
+	b.WriteString(trailer)
+	return b.String()
+}
+
+func TestParseLowLatencyTags(t *testing.T) {
+	playlist := parseTestPlaylist(t, lowLatencyPlaylist("CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=1.0,CAN-SKIP-UNTIL=24.0", "0.33334",
+		"#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"filePart268.2.mp4\"\n#EXT-X-RENDITION-REPORT:URI=\"../1M/index.m3u8\",LAST-MSN=267,LAST-PART=1\n"))
+
+	if playlist.PartTarget != 0.33334 || playlist.ServerControl == nil || !playlist.ServerControl.CanBlockReload || playlist.ServerControl.CanSkipUntil != 24 {
+		t.Fatalf("unexpected server control %+v, part target %v", playlist.ServerControl, playlist.PartTarget)
+	}
+	if len(playlist.Segments) != 2 || len(playlist.Segments[0].Parts) != 0 || len(playlist.Segments[1].Parts) != 12 {
+		t.Fatalf("expected the 12 parts on the second segment, got %d segments", len(playlist.Segments))
+	}
+	if len(playlist.PendingParts) != 2 || !playlist.PendingParts[0].Independent {
+		t.Errorf("expected 2 pending parts, got %+v", playlist.PendingParts)
+	}
+	if len(playlist.PreloadHints) != 1 || playlist.PreloadHints[0].URI != "https://cdn.example.com/live/filePart268.2.mp4" {
+		t.Errorf("unexpected preload hints %+v", playlist.PreloadHints)
+	}
+	report := playlist.RenditionReports
+	if len(report) != 1 || report[0].URI != "https://cdn.example.com/1M/index.m3u8" || *report[0].LastMSN != 267 || *report[0].LastPart != 1 {
+		t.Errorf("unexpected rendition reports %+v", report)
+	}
+
+	renditions := []string{"https://cdn.example.com/live/index.m3u8", "https://cdn.example.com/1M/index.m3u8"}
+	checked := checkLowLatency(renditions[0], playlist, renditions)
+	if !checked.LowLatency || checked.PartCount != 14 || len(checked.Issues) != 0 {
+		t.Errorf("expected a compliant low-latency playlist, got %d parts and %d issues", checked.PartCount, len(checked.Issues))
+	}
+}
+
+func TestCheckLowLatencyIssues(t *testing.T) {
+	playlist := parseTestPlaylist(t, lowLatencyPlaylist("CAN-BLOCK-RELOAD=NO,PART-HOLD-BACK=0.5,HOLD-BACK=6", "0.4", ""))
+	checked := checkLowLatency("https://cdn.example.com/live/index.m3u8", playlist,
+		[]string{"https://cdn.example.com/live/index.m3u8", "https://cdn.example.com/1M/index.m3u8"})
+
+	found := make(map[string]string)
+	for _, issue := range checked.Issues {
+		found[issue.Check] = issue.Severity
+	}
+	expected := map[string]string{
+		LowLatencyCheckPartDuration:    "error",
+		LowLatencyCheckPartSum:         "warning",
+		LowLatencyCheckBlockingReload:  "error",
+		LowLatencyCheckPartHoldBack:    "error",
+		LowLatencyCheckHoldBack:        "error",
+		LowLatencyCheckPreloadHint:     "warning",
+		LowLatencyCheckRenditionReport: "warning",
+	}
+	for check, severity := range expected {
+		if found[check] != severity {
+			t.Errorf("expected %s %s, got %q in %+v", severity, check, found[check], checked.Issues)
+		}
+	}
+	if checked.MaxPartDuration != 0.4 {
+		t.Errorf("expected max part duration 0.4, got %v", checked.MaxPartDuration)
+	}
+}
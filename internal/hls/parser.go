@@ -172,7 +172,9 @@ func (p *HLSParser) parseMediaPlaylist(lines []string, baseURL string) (*HLSMedi
 	}
 
 	var currentKey *HLSKey
+	var currentKeys []*HLSKey // Keys of every KEYFORMAT in effect
 	var currentMap *HLSMap
+	var pendingParts []*HLSPart // Parts listed before their segment
 	sequence := 0
 
 	i := 0
@@ -193,9 +195,16 @@ func (p *HLSParser) parseMediaPlaylist(lines []string, baseURL string) (*HLSMedi
 			}
 
 		case strings.HasPrefix(line, "#EXT-X-MEDIA-SEQUENCE:"):
-			sequence, err := p.parseIntValue(line)
+			mediaSequence, err := p.parseIntValue(line)
 			if err == nil {
-				playlist.MediaSequence = sequence
+				playlist.MediaSequence = mediaSequence
+				sequence = mediaSequence
+			}
+
+		case strings.HasPrefix(line, "#EXT-X-DISCONTINUITY-SEQUENCE:"):
+			discontinuitySequence, err := p.parseIntValue(line)
+			if err == nil {
+				playlist.DiscontinuitySequence = discontinuitySequence
 			}
 
 		case strings.HasPrefix(line, "#EXT-X-ENDLIST"):
@@ -216,16 +225,38 @@ func (p *HLSParser) parseMediaPlaylist(lines []string, baseURL string) (*HLSMedi
 
 		case strings.HasPrefix(line, "#EXT-X-KEY:"):
 			currentKey = p.parseKey(line)
+			currentKeys = withKey(currentKeys, currentKey)
 			playlist.Key = currentKey
 
 		case strings.HasPrefix(line, "#EXT-X-MAP:"):
 			currentMap = p.parseMap(line, baseURL)
 
+		case strings.HasPrefix(line, "#EXT-X-SERVER-CONTROL:"):
+			playlist.ServerControl = p.parseServerControl(line)
+
+		case strings.HasPrefix(line, "#EXT-X-PART-INF:"):
+			if target, err := strconv.ParseFloat(p.parseAttributes(line)["PART-TARGET"], 64); err == nil {
+				playlist.PartTarget = target
+			}
+
+		case strings.HasPrefix(line, "#EXT-X-PART:"):
+			pendingParts = append(pendingParts, p.parsePart(line, baseURL))
+
+		case strings.HasPrefix(line, "#EXT-X-PRELOAD-HINT:"):
+			playlist.PreloadHints = append(playlist.PreloadHints, p.parsePreloadHint(line, baseURL))
+
+		case strings.HasPrefix(line, "#EXT-X-RENDITION-REPORT:"):
+			playlist.RenditionReports = append(playlist.RenditionReports, p.parseRenditionReport(line, baseURL))
+
 		case strings.HasPrefix(line, "#EXTINF:"):
 			segment, consumed, err := p.parseSegment(lines, i, baseURL, currentKey, currentMap, sequence)
 			if err != nil {
 				p.logger.Warn().Err(err).Msg("Failed to parse segment")
 			} else {
+				if len(currentKeys) > 1 {
+					segment.Keys = currentKeys
+				}
+				segment.Parts, pendingParts = pendingParts, nil
 				playlist.Segments = append(playlist.Segments, segment)
 				playlist.TotalDuration += segment.Duration
 				sequence++
@@ -235,6 +266,7 @@ func (p *HLSParser) parseMediaPlaylist(lines []string, baseURL string) (*HLSMedi
 
 		i++
 	}
+	playlist.PendingParts = pendingParts
 
 	return playlist, nil
 }
@@ -355,31 +387,26 @@ func (p *HLSParser) parseSegment(lines []string, startIndex int, baseURL string,
 
 	consumed := 2
 
-	// Look for additional segment tags before this segment
+	// Look for additional segment tags before this segment, back to the
+	// URI of the previous one
 	for i := startIndex - 1; i >= 0; i-- {
 		line := lines[i]
-		if strings.HasPrefix(line, "#EXT-X-DISCONTINUITY") {
-			segment.Discontinuity = true
-			break
+		if !strings.HasPrefix(line, "#") || strings.HasPrefix(line, "#EXTINF:") {
+			break // Found previous segment
 		}
-		if strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:") {
+		switch {
+		case line == "#EXT-X-DISCONTINUITY":
+			segment.Discontinuity = true
+		case strings.HasPrefix(line, "#EXT-X-PROGRAM-DATE-TIME:"):
 			if pdt := p.parseProgramDateTime(line); pdt != nil {
 				segment.ProgramDateTime = pdt
 			}
-			break
-		}
-		if strings.HasPrefix(line, "#EXT-X-BYTERANGE:") {
+		case strings.HasPrefix(line, "#EXT-X-BYTERANGE:"):
 			if br := p.parseByteRange(line); br != nil {
 				segment.ByteRange = br
 			}
-			break
-		}
-		if strings.HasPrefix(line, "#EXT-X-GAP") {
+		case line == "#EXT-X-GAP":
 			segment.Gap = true
-			break
-		}
-		if strings.HasPrefix(line, "#EXTINF:") {
-			break // Found previous segment
 		}
 	}
 
@@ -410,6 +437,10 @@ func (p *HLSParser) parseExtinf(line string, segment *HLSSegment) error {
 	return nil
 }
 
+// attributePattern matches the NAME=value pairs of an attribute list.
+// Quoted values may contain commas, e.g. CODECS and data URIs.
+var attributePattern = regexp.MustCompile(`([A-Z0-9-]+)=("[^"]*"|[^,]*)`)
+
 // parseAttributes parses attribute string into map
 func (p *HLSParser) parseAttributes(line string) map[string]string {
 	attributes := make(map[string]string)
@@ -422,9 +453,7 @@ func (p *HLSParser) parseAttributes(line string) map[string]string {
 
 	attributesStr := line[colonIndex+1:]
 
-	// Regular expression to match key=value pairs
-	re := regexp.MustCompile(`([A-Z-]+)=([^,]+)`)
-	matches := re.FindAllStringSubmatch(attributesStr, -1)
+	matches := attributePattern.FindAllStringSubmatch(attributesStr, -1)
 
 	for _, match := range matches {
 		if len(match) == 3 {
@@ -464,6 +493,22 @@ func (p *HLSParser) parseCodecs(codecs string) []string {
 	return strings.Split(codecs, ",")
 }
 
+// withKey returns the keys in effect after an EXT-X-KEY tag. Keys of
+// different KEYFORMATs apply together, as with multi-DRM packages; a key
+// replaces the one of its KEYFORMAT and METHOD=NONE clears them all.
+func withKey(keys []*HLSKey, key *HLSKey) []*HLSKey {
+	if keyMethod(key) == "NONE" {
+		return []*HLSKey{key}
+	}
+	updated := make([]*HLSKey, 0, len(keys)+1)
+	for _, existing := range keys {
+		if keyMethod(existing) != "NONE" && !strings.EqualFold(existing.KeyFormat, key.KeyFormat) {
+			updated = append(updated, existing)
+		}
+	}
+	return append(updated, key)
+}
+
 // parseKey parses EXT-X-KEY tag
 func (p *HLSParser) parseKey(line string) *HLSKey {
 	attributes := p.parseAttributes(line)
@@ -512,6 +557,74 @@ func (p *HLSParser) parseMap(line string, baseURL string) *HLSMap {
 	return mapInfo
 }
 
+// parseServerControl parses EXT-X-SERVER-CONTROL tag
+func (p *HLSParser) parseServerControl(line string) *HLSServerControl {
+	attributes := p.parseAttributes(line)
+
+	control := &HLSServerControl{
+		CanBlockReload:    attributes["CAN-BLOCK-RELOAD"] == "YES",
+		CanSkipDateRanges: attributes["CAN-SKIP-DATERANGES"] == "YES",
+	}
+	control.CanSkipUntil, _ = strconv.ParseFloat(attributes["CAN-SKIP-UNTIL"], 64)
+	control.HoldBack, _ = strconv.ParseFloat(attributes["HOLD-BACK"], 64)
+	control.PartHoldBack, _ = strconv.ParseFloat(attributes["PART-HOLD-BACK"], 64)
+
+	return control
+}
+
+// parsePart parses EXT-X-PART tag
+func (p *HLSParser) parsePart(line string, baseURL string) *HLSPart {
+	attributes := p.parseAttributes(line)
+
+	part := &HLSPart{
+		Independent: attributes["INDEPENDENT"] == "YES",
+		Gap:         attributes["GAP"] == "YES",
+	}
+	if uri, ok := attributes["URI"]; ok {
+		part.URI = p.resolveURL(uri, baseURL)
+	}
+	part.Duration, _ = strconv.ParseFloat(attributes["DURATION"], 64)
+	if byteRange, ok := attributes["BYTERANGE"]; ok {
+		part.ByteRange = p.parseByteRangeString(byteRange)
+	}
+
+	return part
+}
+
+// parsePreloadHint parses EXT-X-PRELOAD-HINT tag
+func (p *HLSParser) parsePreloadHint(line string, baseURL string) *HLSPreloadHint {
+	attributes := p.parseAttributes(line)
+
+	hint := &HLSPreloadHint{Type: attributes["TYPE"]}
+	if uri, ok := attributes["URI"]; ok {
+		hint.URI = p.resolveURL(uri, baseURL)
+	}
+	hint.ByteRangeStart, _ = strconv.Atoi(attributes["BYTERANGE-START"])
+	if length, err := strconv.Atoi(attributes["BYTERANGE-LENGTH"]); err == nil {
+		hint.ByteRangeLength = &length
+	}
+
+	return hint
+}
+
+// parseRenditionReport parses EXT-X-RENDITION-REPORT tag
+func (p *HLSParser) parseRenditionReport(line string, baseURL string) *HLSRenditionReport {
+	attributes := p.parseAttributes(line)
+
+	report := &HLSRenditionReport{}
+	if uri, ok := attributes["URI"]; ok {
+		report.URI = p.resolveURL(uri, baseURL)
+	}
+	if msn, err := strconv.Atoi(attributes["LAST-MSN"]); err == nil {
+		report.LastMSN = &msn
+	}
+	if part, err := strconv.Atoi(attributes["LAST-PART"]); err == nil {
+		report.LastPart = &part
+	}
+
+	return report
+}
+
 // parseByteRange parses EXT-X-BYTERANGE tag
 func (p *HLSParser) parseByteRange(line string) *HLSByteRange {
 	content := strings.TrimPrefix(line, "#EXT-X-BYTERANGE:")
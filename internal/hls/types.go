@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/drm"
 )
 
 // HLSAnalysis represents a complete HLS analysis result
@@ -18,6 +19,9 @@ type HLSAnalysis struct {
 	Variants           []*HLSVariant          `json:"variants,omitempty"`
 	Segments           []*HLSSegment          `json:"segments,omitempty"`
 	QualityLadder      *HLSQualityLadder      `json:"quality_ladder,omitempty"`
+	LadderAlignment    *HLSLadderAlignment    `json:"ladder_alignment,omitempty"`
+	Encryption         *drm.Encryption        `json:"encryption,omitempty"`
+	LowLatency         *HLSLowLatencyReport   `json:"low_latency,omitempty"`
 	ValidationResults  *HLSValidationResults  `json:"validation_results,omitempty"`
 	PerformanceMetrics *HLSPerformanceMetrics `json:"performance_metrics,omitempty"`
 	ProcessingTime     time.Duration          `json:"processing_time" db:"processing_time"`
@@ -82,17 +86,58 @@ type HLSMasterPlaylist struct {
 
 // HLSMediaPlaylist represents a media playlist
 type HLSMediaPlaylist struct {
-	Version             int           `json:"version"`
-	TargetDuration      float64       `json:"target_duration"`
-	MediaSequence       int           `json:"media_sequence"`
-	Segments            []*HLSSegment `json:"segments"`
-	EndList             bool          `json:"end_list"`
-	PlaylistType        string        `json:"playlist_type,omitempty"`
-	AllowCache          *bool         `json:"allow_cache,omitempty"`
-	IFramesOnly         bool          `json:"iframes_only"`
-	IndependentSegments bool          `json:"independent_segments"`
-	TotalDuration       float64       `json:"total_duration"`
-	Key                 *HLSKey       `json:"key,omitempty"`
+	Version               int           `json:"version"`
+	TargetDuration        float64       `json:"target_duration"`
+	MediaSequence         int           `json:"media_sequence"`
+	Segments              []*HLSSegment `json:"segments"`
+	DiscontinuitySequence int           `json:"discontinuity_sequence,omitempty"` // Discontinuity number of the first segment
+	EndList               bool          `json:"end_list"`
+	PlaylistType          string        `json:"playlist_type,omitempty"`
+	AllowCache            *bool         `json:"allow_cache,omitempty"`
+	IFramesOnly           bool          `json:"iframes_only"`
+	IndependentSegments   bool          `json:"independent_segments"`
+	TotalDuration         float64       `json:"total_duration"`
+	Key                   *HLSKey       `json:"key,omitempty"`
+
+	// Low-latency HLS
+	ServerControl    *HLSServerControl     `json:"server_control,omitempty"`
+	PartTarget       float64               `json:"part_target,omitempty"`   // EXT-X-PART-INF PART-TARGET
+	PendingParts     []*HLSPart            `json:"pending_parts,omitempty"` // Parts of the segment still being produced
+	PreloadHints     []*HLSPreloadHint     `json:"preload_hints,omitempty"`
+	RenditionReports []*HLSRenditionReport `json:"rendition_reports,omitempty"`
+}
+
+// HLSServerControl represents EXT-X-SERVER-CONTROL
+type HLSServerControl struct {
+	CanBlockReload    bool    `json:"can_block_reload"`
+	CanSkipUntil      float64 `json:"can_skip_until,omitempty"`
+	CanSkipDateRanges bool    `json:"can_skip_dateranges"`
+	HoldBack          float64 `json:"hold_back,omitempty"`
+	PartHoldBack      float64 `json:"part_hold_back,omitempty"`
+}
+
+// HLSPart represents an EXT-X-PART partial segment
+type HLSPart struct {
+	URI         string        `json:"uri"`
+	Duration    float64       `json:"duration"`
+	Independent bool          `json:"independent"`
+	ByteRange   *HLSByteRange `json:"byte_range,omitempty"`
+	Gap         bool          `json:"gap"`
+}
+
+// HLSPreloadHint represents EXT-X-PRELOAD-HINT
+type HLSPreloadHint struct {
+	Type            string `json:"type"` // PART or MAP
+	URI             string `json:"uri"`
+	ByteRangeStart  int    `json:"byte_range_start,omitempty"`
+	ByteRangeLength *int   `json:"byte_range_length,omitempty"`
+}
+
+// HLSRenditionReport represents EXT-X-RENDITION-REPORT
+type HLSRenditionReport struct {
+	URI      string `json:"uri"`
+	LastMSN  *int   `json:"last_msn,omitempty"`
+	LastPart *int   `json:"last_part,omitempty"`
 }
 
 // HLSVariant represents a variant stream in master playlist
@@ -126,6 +171,8 @@ type HLSSegment struct {
 	ByteRange       *HLSByteRange `json:"byte_range,omitempty"`
 	Discontinuity   bool          `json:"discontinuity" db:"discontinuity"`
 	Key             *HLSKey       `json:"key,omitempty"`
+	Keys            []*HLSKey     `json:"keys,omitempty"` // Every key when several KEYFORMATs apply; Key is the last
+	Parts           []*HLSPart    `json:"parts,omitempty"`
 	Map             *HLSMap       `json:"map,omitempty"`
 	ProgramDateTime *time.Time    `json:"program_date_time,omitempty" db:"program_date_time"`
 	DateRange       *HLSDateRange `json:"date_range,omitempty"`
@@ -396,6 +443,9 @@ type HLSAnalysisRequest struct {
 	AnalyzeQuality      bool     `json:"analyze_quality,omitempty"`
 	ValidateCompliance  bool     `json:"validate_compliance,omitempty"`
 	PerformanceAnalysis bool     `json:"performance_analysis,omitempty"`
+	ValidateAlignment   bool     `json:"validate_alignment,omitempty"`   // Fetch every variant playlist and check they line up
+	InspectEncryption   bool     `json:"inspect_encryption,omitempty"`   // Fetch variant playlists and init segments and read their protection
+	ValidateLowLatency  bool     `json:"validate_low_latency,omitempty"` // Check LL-HLS parts, server control, preload hints and rendition reports
 	IncludeMetrics      []string `json:"include_metrics,omitempty"`
 	MaxSegments         int      `json:"max_segments,omitempty"`
 	Timeout             int      `json:"timeout,omitempty"`
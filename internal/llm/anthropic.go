@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// anthropicVersion is the Messages API version sent with every request
+const anthropicVersion = "2023-06-01"
+
+// AnthropicProvider talks to the Anthropic Messages API
+type AnthropicProvider struct {
+	providerBase
+}
+
+// Name implements Provider
+func (p *AnthropicProvider) Name() string {
+	return ProviderAnthropic
+}
+
+// Generate implements Provider
+func (p *AnthropicProvider) Generate(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	start := time.Now()
+
+	body := map[string]interface{}{
+		"model": p.model,
+		"messages": []map[string]string{
+			{"role": "user", "content": request.Prompt},
+		},
+		"max_tokens": p.requestMaxTokens(request),
+	}
+	if temperature := p.requestTemperature(request); temperature > 0 {
+		body["temperature"] = temperature
+	}
+	if request.TopP > 0 {
+		body["top_p"] = request.TopP
+	}
+	if len(request.Stop) > 0 {
+		body["stop_sequences"] = request.Stop
+	}
+
+	headers := map[string]string{
+		"x-api-key":         p.apiKey,
+		"anthropic-version": anthropicVersion,
+	}
+
+	var response struct {
+		Model   string `json:"model"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		StopReason string `json:"stop_reason"`
+		Usage      struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	// Anthropic errors use the same {"error": {"message": ...}} shape as OpenAI
+	if err := p.postJSON(ctx, p.Name(), p.baseURL+"/v1/messages", headers, body, &response, openAIErrorMessage); err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	content := strings.TrimSpace(text.String())
+	if content == "" {
+		return nil, fmt.Errorf("empty response from %s", p.Name())
+	}
+
+	model := response.Model
+	if model == "" {
+		model = p.model
+	}
+	return &LLMResponse{
+		Content:      content,
+		FinishReason: response.StopReason,
+		Usage: &LLMUsage{
+			PromptTokens:     response.Usage.InputTokens,
+			CompletionTokens: response.Usage.OutputTokens,
+			TotalTokens:      response.Usage.InputTokens + response.Usage.OutputTokens,
+		},
+		ProcessingTime: time.Since(start),
+		Model:          model,
+		Provider:       p.Name(),
+	}, nil
+}
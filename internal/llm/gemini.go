@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GeminiProvider talks to the Gemini generateContent API
+type GeminiProvider struct {
+	providerBase
+}
+
+// Name implements Provider
+func (p *GeminiProvider) Name() string {
+	return ProviderGemini
+}
+
+// Generate implements Provider
+func (p *GeminiProvider) Generate(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	start := time.Now()
+
+	generationConfig := map[string]interface{}{
+		"maxOutputTokens": p.requestMaxTokens(request),
+	}
+	if temperature := p.requestTemperature(request); temperature > 0 {
+		generationConfig["temperature"] = temperature
+	}
+	if request.TopP > 0 {
+		generationConfig["topP"] = request.TopP
+	}
+	if len(request.Stop) > 0 {
+		generationConfig["stopSequences"] = request.Stop
+	}
+	body := map[string]interface{}{
+		"contents": []map[string]interface{}{
+			{
+				"role":  "user",
+				"parts": []map[string]string{{"text": request.Prompt}},
+			},
+		},
+		"generationConfig": generationConfig,
+	}
+
+	headers := map[string]string{"x-goog-api-key": p.apiKey}
+	endpoint := fmt.Sprintf("%s/v1beta/models/%s:generateContent", p.baseURL, url.PathEscape(p.model))
+
+	var response struct {
+		Candidates []struct {
+			Content struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"content"`
+			FinishReason string `json:"finishReason"`
+		} `json:"candidates"`
+		UsageMetadata struct {
+			PromptTokenCount     int `json:"promptTokenCount"`
+			CandidatesTokenCount int `json:"candidatesTokenCount"`
+			TotalTokenCount      int `json:"totalTokenCount"`
+		} `json:"usageMetadata"`
+		ModelVersion string `json:"modelVersion"`
+	}
+	// Gemini errors use the same {"error": {"message": ...}} shape as OpenAI
+	if err := p.postJSON(ctx, p.Name(), endpoint, headers, body, &response, openAIErrorMessage); err != nil {
+		return nil, err
+	}
+
+	if len(response.Candidates) == 0 {
+		return nil, fmt.Errorf("empty response from %s", p.Name())
+	}
+	var text strings.Builder
+	for _, part := range response.Candidates[0].Content.Parts {
+		text.WriteString(part.Text)
+	}
+	content := strings.TrimSpace(text.String())
+	if content == "" {
+		return nil, fmt.Errorf("empty response from %s (finish reason %s)", p.Name(), response.Candidates[0].FinishReason)
+	}
+
+	model := response.ModelVersion
+	if model == "" {
+		model = p.model
+	}
+	usage := response.UsageMetadata
+	return &LLMResponse{
+		Content:      content,
+		FinishReason: response.Candidates[0].FinishReason,
+		Usage: &LLMUsage{
+			PromptTokens:     usage.PromptTokenCount,
+			CompletionTokens: usage.CandidatesTokenCount,
+			TotalTokens:      usage.TotalTokenCount,
+		},
+		ProcessingTime: time.Since(start),
+		Model:          model,
+		Provider:       p.Name(),
+	}, nil
+}
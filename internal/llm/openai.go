@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OpenAIProvider talks to the OpenAI chat completions API or any server
+// that implements it
+type OpenAIProvider struct {
+	providerBase
+}
+
+// Name implements Provider
+func (p *OpenAIProvider) Name() string {
+	return ProviderOpenAI
+}
+
+// Generate implements Provider
+func (p *OpenAIProvider) Generate(ctx context.Context, request *LLMRequest) (*LLMResponse, error) {
+	start := time.Now()
+
+	body := map[string]interface{}{
+		"model": p.model,
+		"messages": []map[string]string{
+			{"role": "user", "content": request.Prompt},
+		},
+		"max_tokens": p.requestMaxTokens(request),
+	}
+	if temperature := p.requestTemperature(request); temperature > 0 {
+		body["temperature"] = temperature
+	}
+	if request.TopP > 0 {
+		body["top_p"] = request.TopP
+	}
+	if len(request.Stop) > 0 {
+		body["stop"] = request.Stop
+	}
+
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
+	}
+
+	var response struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			FinishReason string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage *LLMUsage `json:"usage"`
+	}
+	if err := p.postJSON(ctx, p.Name(), p.baseURL+"/chat/completions", headers, body, &response, openAIErrorMessage); err != nil {
+		return nil, err
+	}
+
+	if len(response.Choices) == 0 || strings.TrimSpace(response.Choices[0].Message.Content) == "" {
+		return nil, fmt.Errorf("empty response from %s", p.Name())
+	}
+
+	model := response.Model
+	if model == "" {
+		model = p.model
+	}
+	return &LLMResponse{
+		Content:        strings.TrimSpace(response.Choices[0].Message.Content),
+		FinishReason:   response.Choices[0].FinishReason,
+		Usage:          response.Usage,
+		ProcessingTime: time.Since(start),
+		Model:          model,
+		Provider:       p.Name(),
+	}, nil
+}
+
+// openAIErrorMessage extracts {"error": {"message": ...}}
+func openAIErrorMessage(data []byte) string {
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return ""
+	}
+	return body.Error.Message
+}
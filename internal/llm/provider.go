@@ -0,0 +1,175 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Provider names accepted by NewProvider
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderGemini    = "gemini"
+)
+
+// Default models used when LLMConfig.Model is empty
+const (
+	DefaultOpenAIModel    = "gpt-4o-mini"
+	DefaultAnthropicModel = "claude-3-5-haiku-latest"
+	DefaultGeminiModel    = "gemini-2.0-flash"
+)
+
+// defaultMaxTokens caps completions when neither the config nor the request
+// sets a limit. Anthropic requires one on every request.
+const defaultMaxTokens = 1500
+
+// maxErrorBody bounds how much of an error response is read for its message
+const maxErrorBody = 64 * 1024
+
+// Provider generates completions from one hosted LLM API
+type Provider interface {
+	// Name returns the provider name, e.g. "openai"
+	Name() string
+	// Generate sends a single-turn prompt. The response carries the
+	// provider's token usage when the API reports it.
+	Generate(ctx context.Context, request *LLMRequest) (*LLMResponse, error)
+}
+
+// Pricing is what a provider charges in USD per million tokens
+type Pricing struct {
+	InputPerMTok  float64 `json:"input_per_mtok"`
+	OutputPerMTok float64 `json:"output_per_mtok"`
+}
+
+// Cost returns the price of usage, or 0 when no pricing is set
+func (p Pricing) Cost(usage *LLMUsage) float64 {
+	if usage == nil {
+		return 0
+	}
+	return (float64(usage.PromptTokens)*p.InputPerMTok + float64(usage.CompletionTokens)*p.OutputPerMTok) / 1e6
+}
+
+// NewProvider returns the backend for config.Provider. An empty BaseURL uses
+// the provider's public API; for "openai" any OpenAI-compatible server
+// (vLLM, LM Studio, OpenRouter, Azure proxies) can be targeted instead.
+func NewProvider(config *LLMConfig, client *http.Client) (Provider, error) {
+	if config == nil {
+		return nil, fmt.Errorf("LLM configuration cannot be nil")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 120 * time.Second}
+	}
+
+	base := providerBase{
+		apiKey:      config.APIKey,
+		baseURL:     strings.TrimRight(config.BaseURL, "/"),
+		model:       config.Model,
+		maxTokens:   config.MaxTokens,
+		temperature: config.Temperature,
+		client:      client,
+	}
+
+	switch config.Provider {
+	case ProviderOpenAI:
+		if base.apiKey == "" && base.baseURL == "" {
+			return nil, fmt.Errorf("openai provider requires an API key or a base URL")
+		}
+		base.withDefaults("https://api.openai.com/v1", DefaultOpenAIModel)
+		return &OpenAIProvider{providerBase: base}, nil
+	case ProviderAnthropic:
+		if base.apiKey == "" {
+			return nil, fmt.Errorf("anthropic provider requires an API key")
+		}
+		base.withDefaults("https://api.anthropic.com", DefaultAnthropicModel)
+		return &AnthropicProvider{providerBase: base}, nil
+	case ProviderGemini:
+		if base.apiKey == "" {
+			return nil, fmt.Errorf("gemini provider requires an API key")
+		}
+		base.withDefaults("https://generativelanguage.googleapis.com", DefaultGeminiModel)
+		return &GeminiProvider{providerBase: base}, nil
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider: %s", config.Provider)
+	}
+}
+
+// providerBase holds the settings shared by all HTTP providers
+type providerBase struct {
+	apiKey      string
+	baseURL     string
+	model       string
+	maxTokens   int
+	temperature float64
+	client      *http.Client
+}
+
+func (b *providerBase) withDefaults(baseURL, model string) {
+	if b.baseURL == "" {
+		b.baseURL = baseURL
+	}
+	if b.model == "" {
+		b.model = model
+	}
+}
+
+// requestMaxTokens picks the request limit, then the configured one
+func (b *providerBase) requestMaxTokens(request *LLMRequest) int {
+	if request.MaxTokens > 0 {
+		return request.MaxTokens
+	}
+	if b.maxTokens > 0 {
+		return b.maxTokens
+	}
+	return defaultMaxTokens
+}
+
+// requestTemperature picks the request temperature, then the configured one
+func (b *providerBase) requestTemperature(request *LLMRequest) float64 {
+	if request.Temperature > 0 {
+		return request.Temperature
+	}
+	return b.temperature
+}
+
+// postJSON sends body to url and decodes a 200 response into out. For other
+// statuses the error includes the message extracted by errorMessage.
+func (b *providerBase) postJSON(ctx context.Context, provider, url string, headers map[string]string, body, out interface{}, errorMessage func([]byte) string) error {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", provider, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", provider, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s request: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		if message := errorMessage(data); message != "" {
+			return fmt.Errorf("%s API returned status %d: %s", provider, resp.StatusCode, message)
+		}
+		return fmt.Errorf("%s API returned status %d", provider, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", provider, err)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeAPI serves one canned JSON response and records the request
+func fakeAPI(t *testing.T, status int, response string, check func(r *http.Request, body map[string]interface{})) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		if check != nil {
+			check(r, body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestOpenAIProviderGenerate(t *testing.T) {
+	server := fakeAPI(t, http.StatusOK, `{
+		"model": "gpt-4o-mini-2024-07-18",
+		"choices": [{"message": {"content": " report "}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 120, "completion_tokens": 30, "total_tokens": 150}
+	}`, func(r *http.Request, body map[string]interface{}) {
+		if r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if body["model"] != DefaultOpenAIModel {
+			t.Errorf("model = %v", body["model"])
+		}
+		if body["max_tokens"] != float64(200) {
+			t.Errorf("max_tokens = %v", body["max_tokens"])
+		}
+	})
+
+	provider, err := NewProvider(&LLMConfig{Provider: ProviderOpenAI, APIKey: "sk-test", BaseURL: server.URL + "/v1/", MaxTokens: 200}, server.Client())
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	response, err := provider.Generate(context.Background(), &LLMRequest{Prompt: "analyze"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	if response.Content != "report" || response.FinishReason != "stop" {
+		t.Errorf("response = %+v", response)
+	}
+	if response.Provider != ProviderOpenAI || response.Model != "gpt-4o-mini-2024-07-18" {
+		t.Errorf("provider/model = %s/%s", response.Provider, response.Model)
+	}
+	if response.Usage == nil || response.Usage.PromptTokens != 120 || response.Usage.CompletionTokens != 30 || response.Usage.TotalTokens != 150 {
+		t.Errorf("usage = %+v", response.Usage)
+	}
+}
+
+func TestAnthropicProviderGenerate(t *testing.T) {
+	server := fakeAPI(t, http.StatusOK, `{
+		"model": "claude-3-5-haiku-20241022",
+		"content": [{"type": "text", "text": "part one, "}, {"type": "text", "text": "part two"}],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 400, "output_tokens": 100}
+	}`, func(r *http.Request, body map[string]interface{}) {
+		if r.URL.Path != "/v1/messages" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "sk-ant-test" || r.Header.Get("anthropic-version") != anthropicVersion {
+			t.Errorf("headers = %v", r.Header)
+		}
+		if body["max_tokens"] != float64(defaultMaxTokens) {
+			t.Errorf("max_tokens = %v", body["max_tokens"])
+		}
+	})
+
+	provider, err := NewProvider(&LLMConfig{Provider: ProviderAnthropic, APIKey: "sk-ant-test", BaseURL: server.URL}, server.Client())
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	response, err := provider.Generate(context.Background(), &LLMRequest{Prompt: "analyze"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	if response.Content != "part one, part two" || response.FinishReason != "end_turn" {
+		t.Errorf("response = %+v", response)
+	}
+	if response.Provider != ProviderAnthropic {
+		t.Errorf("provider = %s", response.Provider)
+	}
+	if response.Usage.PromptTokens != 400 || response.Usage.CompletionTokens != 100 || response.Usage.TotalTokens != 500 {
+		t.Errorf("usage = %+v", response.Usage)
+	}
+}
+
+func TestGeminiProviderGenerate(t *testing.T) {
+	server := fakeAPI(t, http.StatusOK, `{
+		"candidates": [{"content": {"parts": [{"text": "gemini report"}]}, "finishReason": "STOP"}],
+		"usageMetadata": {"promptTokenCount": 50, "candidatesTokenCount": 25, "totalTokenCount": 75}
+	}`, func(r *http.Request, body map[string]interface{}) {
+		if r.URL.Path != "/v1beta/models/gemini-1.5-pro:generateContent" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "gemini-key" {
+			t.Errorf("x-goog-api-key = %q", r.Header.Get("x-goog-api-key"))
+		}
+		config, _ := body["generationConfig"].(map[string]interface{})
+		if config["temperature"] != 0.2 {
+			t.Errorf("generationConfig = %v", config)
+		}
+	})
+
+	provider, err := NewProvider(&LLMConfig{Provider: ProviderGemini, APIKey: "gemini-key", BaseURL: server.URL, Model: "gemini-1.5-pro", Temperature: 0.2}, server.Client())
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	response, err := provider.Generate(context.Background(), &LLMRequest{Prompt: "analyze"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	if response.Content != "gemini report" || response.Model != "gemini-1.5-pro" || response.Provider != ProviderGemini {
+		t.Errorf("response = %+v", response)
+	}
+	if response.Usage.PromptTokens != 50 || response.Usage.CompletionTokens != 25 || response.Usage.TotalTokens != 75 {
+		t.Errorf("usage = %+v", response.Usage)
+	}
+}
+
+func TestProviderErrorIncludesAPIMessage(t *testing.T) {
+	server := fakeAPI(t, http.StatusUnauthorized, `{"error": {"message": "invalid x-api-key"}}`, nil)
+
+	provider, err := NewProvider(&LLMConfig{Provider: ProviderAnthropic, APIKey: "bad", BaseURL: server.URL}, server.Client())
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	_, err = provider.Generate(context.Background(), &LLMRequest{Prompt: "analyze"})
+	if err == nil || !strings.Contains(err.Error(), "401") || !strings.Contains(err.Error(), "invalid x-api-key") {
+		t.Errorf("expected status and API message in error, got %v", err)
+	}
+}
+
+func TestNewProviderRequiresKey(t *testing.T) {
+	for _, provider := range []string{ProviderAnthropic, ProviderGemini, ProviderOpenAI} {
+		if _, err := NewProvider(&LLMConfig{Provider: provider}, nil); err == nil {
+			t.Errorf("%s: expected an error without an API key", provider)
+		}
+	}
+	if _, err := NewProvider(&LLMConfig{Provider: ProviderOpenAI, BaseURL: "http://localhost:8000/v1"}, nil); err != nil {
+		t.Errorf("openai-compatible server without key: %v", err)
+	}
+	if _, err := NewProvider(&LLMConfig{Provider: "cohere", APIKey: "key"}, nil); err == nil {
+		t.Error("expected an error for an unsupported provider")
+	}
+}
+
+func TestPricingCost(t *testing.T) {
+	pricing := Pricing{InputPerMTok: 3, OutputPerMTok: 15}
+	cost := pricing.Cost(&LLMUsage{PromptTokens: 2000, CompletionTokens: 500})
+	if math.Abs(cost-0.0135) > 1e-9 {
+		t.Errorf("cost = %v, want 0.0135", cost)
+	}
+	if pricing.Cost(nil) != 0 {
+		t.Error("nil usage should cost nothing")
+	}
+}
@@ -19,7 +19,7 @@ func ValidateLLMConfig(config *LLMConfig) error {
 		return fmt.Errorf("LLM provider cannot be empty")
 	}
 
-	validProviders := []string{"openrouter", "openai", "anthropic", "ollama", "local"}
+	validProviders := []string{"openrouter", "openai", "anthropic", "gemini", "ollama", "local"}
 	validProvider := false
 	for _, valid := range validProviders {
 		if config.Provider == valid {
@@ -40,6 +40,8 @@ func ValidateLLMConfig(config *LLMConfig) error {
 		return validateOpenAIConfig(config)
 	case "anthropic":
 		return validateAnthropicConfig(config)
+	case "gemini":
+		return validateGeminiConfig(config)
 	case "ollama":
 		return validateOllamaConfig(config)
 	case "local":
@@ -176,6 +178,23 @@ func validateAnthropicConfig(config *LLMConfig) error {
 	return nil
 }
 
+// validateGeminiConfig validates Gemini-specific configuration
+func validateGeminiConfig(config *LLMConfig) error {
+	if config.APIKey == "" {
+		return fmt.Errorf("gemini API key cannot be empty")
+	}
+
+	if config.Model == "" {
+		config.Model = DefaultGeminiModel
+	}
+
+	if !strings.HasPrefix(config.Model, "gemini-") {
+		return fmt.Errorf("unsupported Gemini model: %s", config.Model)
+	}
+
+	return nil
+}
+
 // validateOllamaConfig validates Ollama-specific configuration
 func validateOllamaConfig(config *LLMConfig) error {
 	if config.BaseURL == "" {
@@ -414,10 +433,13 @@ type LLMResponse struct {
 	Usage          *LLMUsage     `json:"usage,omitempty"`
 	ProcessingTime time.Duration `json:"processing_time"`
 	Model          string        `json:"model,omitempty"`
+	Provider       string        `json:"provider,omitempty"`
 }
 
 type LLMUsage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+	// CostUSD is only set when pricing is configured for the provider
+	CostUSD float64 `json:"cost_usd,omitempty"`
 }
@@ -335,6 +335,7 @@ func (m *AuthMiddleware) extractToken(c *gin.Context) string {
 func (m *AuthMiddleware) isPublicEndpoint(path string) bool {
 	publicPaths := []string{
 		"/health",
+		"/readyz",
 		"/docs",
 		"/api/v1/auth/login",
 		"/api/v1/auth/refresh",
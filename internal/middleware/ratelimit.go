@@ -88,7 +88,8 @@ func (rl *RateLimitMiddleware) Stop() {
 func (rl *RateLimitMiddleware) RateLimit() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip rate limiting for health checks
-		if c.Request.URL.Path == "/health" {
+		switch c.Request.URL.Path {
+		case "/health", "/healthz", "/readyz":
 			c.Next()
 			return
 		}
@@ -448,7 +449,8 @@ func (rl *RateLimitMiddleware) getRemainingRequestsWithLimits(identifier string,
 func (rl *RateLimitMiddleware) DynamicRateLimit() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip rate limiting for health checks
-		if c.Request.URL.Path == "/health" {
+		switch c.Request.URL.Path {
+		case "/health", "/healthz", "/readyz":
 			c.Next()
 			return
 		}
@@ -83,7 +83,8 @@ func NewTenantRateLimiter(cacheClient cache.Client, logger zerolog.Logger, confi
 func (rl *TenantRateLimiter) RateLimitMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip rate limiting for health checks
-		if c.Request.URL.Path == "/health" || c.Request.URL.Path == "/metrics" {
+		switch c.Request.URL.Path {
+		case "/health", "/healthz", "/readyz", "/metrics":
 			c.Next()
 			return
 		}
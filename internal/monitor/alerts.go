@@ -0,0 +1,121 @@
+package monitor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// Alert rules
+const (
+	RuleStreamUnavailable = "stream_unavailable" // Sample could not be taken; always enabled
+	RuleBitRateLow        = "bit_rate_low"
+	RuleLoudnessLow       = "loudness_low"
+	RuleLoudnessHigh      = "loudness_high"
+	RuleTruePeakHigh      = "true_peak_high"
+	RuleBlack             = "black"
+	RuleFreeze            = "freeze"
+	RuleTSErrors          = "ts_errors"
+)
+
+// Thresholds are the limits a sample is checked against. A nil threshold
+// disables its rule.
+type Thresholds struct {
+	MinBitRate       *int64   `json:"min_bit_rate,omitempty"`
+	MinLoudness      *float64 `json:"min_loudness_lufs,omitempty"`
+	MaxLoudness      *float64 `json:"max_loudness_lufs,omitempty"`
+	MaxTruePeak      *float64 `json:"max_true_peak_dbtp,omitempty"`
+	MaxBlackSeconds  *float64 `json:"max_black_seconds,omitempty"`
+	MaxFreezeSeconds *float64 `json:"max_freeze_seconds,omitempty"`
+	MaxTSErrors      *int     `json:"max_ts_errors,omitempty"`
+}
+
+// Alert is a failing rule. Resolved alerts carry the time the rule passed
+// again.
+type Alert struct {
+	Rule       string     `json:"rule"`
+	Message    string     `json:"message"`
+	Value      float64    `json:"value"`
+	Threshold  float64    `json:"threshold"`
+	RaisedAt   time.Time  `json:"raised_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// Resolved reports whether the alert's rule passes again
+func (a Alert) Resolved() bool {
+	return a.ResolvedAt != nil
+}
+
+func (t Thresholds) validate() error {
+	if t.MinBitRate != nil && *t.MinBitRate < 0 {
+		return fmt.Errorf("min_bit_rate must not be negative")
+	}
+	if t.MinLoudness != nil && t.MaxLoudness != nil && *t.MinLoudness > *t.MaxLoudness {
+		return fmt.Errorf("min_loudness_lufs must not exceed max_loudness_lufs")
+	}
+	if t.MaxBlackSeconds != nil && *t.MaxBlackSeconds < 0 {
+		return fmt.Errorf("max_black_seconds must not be negative")
+	}
+	if t.MaxFreezeSeconds != nil && *t.MaxFreezeSeconds < 0 {
+		return fmt.Errorf("max_freeze_seconds must not be negative")
+	}
+	if t.MaxTSErrors != nil && *t.MaxTSErrors < 0 {
+		return fmt.Errorf("max_ts_errors must not be negative")
+	}
+	return nil
+}
+
+// evaluate returns the rules sample fails, keyed by rule. Loudness rules are
+// skipped for samples without audio.
+func (t Thresholds) evaluate(sample *ffmpeg.LiveSample) map[string]Alert {
+	violations := make(map[string]Alert)
+	if sample.Error != "" {
+		violations[RuleStreamUnavailable] = Alert{
+			Rule:    RuleStreamUnavailable,
+			Message: "Stream could not be sampled: " + sample.Error,
+		}
+		return violations
+	}
+
+	check := func(rule string, failing bool, value, threshold float64, format string) {
+		if failing {
+			violations[rule] = Alert{
+				Rule:      rule,
+				Message:   fmt.Sprintf(format, value, threshold),
+				Value:     value,
+				Threshold: threshold,
+			}
+		}
+	}
+
+	if t.MinBitRate != nil {
+		check(RuleBitRateLow, sample.BitRate < *t.MinBitRate, float64(sample.BitRate), float64(*t.MinBitRate),
+			"Bit rate %.0f b/s is below %.0f b/s")
+	}
+	if t.MinLoudness != nil && sample.IntegratedLoudness != nil {
+		check(RuleLoudnessLow, *sample.IntegratedLoudness < *t.MinLoudness, *sample.IntegratedLoudness, *t.MinLoudness,
+			"Integrated loudness %.1f LUFS is below %.1f LUFS")
+	}
+	if t.MaxLoudness != nil && sample.IntegratedLoudness != nil {
+		check(RuleLoudnessHigh, *sample.IntegratedLoudness > *t.MaxLoudness, *sample.IntegratedLoudness, *t.MaxLoudness,
+			"Integrated loudness %.1f LUFS is above %.1f LUFS")
+	}
+	if t.MaxTruePeak != nil && sample.TruePeak != nil {
+		check(RuleTruePeakHigh, *sample.TruePeak > *t.MaxTruePeak, *sample.TruePeak, *t.MaxTruePeak,
+			"True peak %.1f dBTP is above %.1f dBTP")
+	}
+	if t.MaxBlackSeconds != nil {
+		check(RuleBlack, sample.BlackSeconds > *t.MaxBlackSeconds, sample.BlackSeconds, *t.MaxBlackSeconds,
+			"%.1fs of black exceeds %.1fs")
+	}
+	if t.MaxFreezeSeconds != nil {
+		check(RuleFreeze, sample.FreezeSeconds > *t.MaxFreezeSeconds, sample.FreezeSeconds, *t.MaxFreezeSeconds,
+			"%.1fs of frozen video exceeds %.1fs")
+	}
+	if t.MaxTSErrors != nil {
+		check(RuleTSErrors, sample.TSErrors > *t.MaxTSErrors, float64(sample.TSErrors), float64(*t.MaxTSErrors),
+			"%.0f transport stream errors exceed %.0f")
+	}
+	return violations
+}
@@ -0,0 +1,452 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rs/zerolog"
+)
+
+var (
+	// ErrMonitorNotFound is returned for unknown monitor IDs
+	ErrMonitorNotFound = errors.New("monitor not found")
+	// ErrTooManyMonitors is returned when the monitor limit is reached
+	ErrTooManyMonitors = errors.New("too many monitors")
+)
+
+// Status is the state of a monitor
+type Status string
+
+const (
+	StatusStarting Status = "starting" // Waiting for the first sample
+	StatusHealthy  Status = "healthy"  // Last sample raised no alerts
+	StatusAlerting Status = "alerting" // At least one alert is active
+	StatusPaused   Status = "paused"   // Not sampling
+)
+
+// Sampler measures one window of a live stream
+type Sampler interface {
+	Sample(ctx context.Context, url string, window time.Duration) (*ffmpeg.LiveSample, error)
+}
+
+// Config configures a Manager
+type Config struct {
+	MaxMonitors      int           // Maximum monitors, paused or not
+	MinInterval      time.Duration // Shortest sampling interval accepted
+	MaxSampleSeconds int           // Longest sample window accepted
+}
+
+// Spec is the user-supplied definition of a monitor
+type Spec struct {
+	Name            string     `json:"name"`
+	URL             string     `json:"url"`
+	IntervalSeconds int        `json:"interval_seconds"`
+	SampleSeconds   int        `json:"sample_seconds"`
+	CallbackURL     string     `json:"callback_url,omitempty"`
+	Paused          bool       `json:"paused"`
+	Thresholds      Thresholds `json:"thresholds"`
+}
+
+// Interval returns the time between the starts of two samples
+func (s Spec) Interval() time.Duration {
+	return time.Duration(s.IntervalSeconds) * time.Second
+}
+
+// Window returns the length of stream read for each sample
+func (s Spec) Window() time.Duration {
+	return time.Duration(s.SampleSeconds) * time.Second
+}
+
+// Monitor is a snapshot of a monitor
+type Monitor struct {
+	ID string `json:"id"`
+	Spec
+	Status       Status             `json:"status"`
+	ActiveAlerts []Alert            `json:"active_alerts"`
+	LastSample   *ffmpeg.LiveSample `json:"last_sample,omitempty"`
+	SampleCount  int                `json:"sample_count"`
+	CreatedAt    time.Time          `json:"created_at"`
+	UpdatedAt    time.Time          `json:"updated_at"`
+}
+
+type monitor struct {
+	Monitor
+	alerts map[string]Alert // Active alerts by rule
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Manager samples live streams on an interval and raises alerts when their
+// metrics cross the configured thresholds.
+//
+// Each monitor that is not paused has its own sampling loop. Alerts are
+// raised when a rule starts failing and resolved when it passes again, so
+// the alert handler sees state changes rather than every failing sample.
+type Manager struct {
+	config  Config
+	sampler Sampler
+	logger  zerolog.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu            sync.RWMutex
+	monitors      map[string]*monitor
+	alertHandler  func(Monitor, Alert)
+	sampleHandler func(string, ffmpeg.LiveSample)
+}
+
+// NewManager creates a Manager that takes samples with sampler
+func NewManager(config Config, sampler Sampler, logger zerolog.Logger) *Manager {
+	if config.MaxMonitors <= 0 {
+		config.MaxMonitors = 1
+	}
+	if config.MinInterval <= 0 {
+		config.MinInterval = 10 * time.Second
+	}
+	if config.MaxSampleSeconds <= 0 {
+		config.MaxSampleSeconds = 60
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		config:   config,
+		sampler:  sampler,
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+		monitors: make(map[string]*monitor),
+	}
+}
+
+// SetAlertHandler sets the function called when an alert is raised or
+// resolved. It runs on the monitor's sampling loop.
+func (m *Manager) SetAlertHandler(handler func(Monitor, Alert)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.alertHandler = handler
+}
+
+// SetSampleHandler sets the function called with every sample taken. It runs
+// on the monitor's sampling loop.
+func (m *Manager) SetSampleHandler(handler func(id string, sample ffmpeg.LiveSample)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sampleHandler = handler
+}
+
+// Validate applies defaults to spec and checks it against the limits
+func (m *Manager) Validate(spec *Spec) error {
+	if spec.URL == "" {
+		return fmt.Errorf("url is required")
+	}
+	if spec.IntervalSeconds == 0 {
+		spec.IntervalSeconds = 60
+	}
+	if spec.SampleSeconds == 0 {
+		spec.SampleSeconds = 10
+	}
+	if spec.Interval() < m.config.MinInterval {
+		return fmt.Errorf("interval_seconds must be at least %d", int(m.config.MinInterval.Seconds()))
+	}
+	if spec.SampleSeconds < 1 || spec.SampleSeconds > m.config.MaxSampleSeconds {
+		return fmt.Errorf("sample_seconds must be between 1 and %d", m.config.MaxSampleSeconds)
+	}
+	if spec.SampleSeconds > spec.IntervalSeconds {
+		return fmt.Errorf("sample_seconds must not exceed interval_seconds")
+	}
+	return spec.Thresholds.validate()
+}
+
+// Create validates spec and starts a new monitor
+func (m *Manager) Create(spec Spec) (Monitor, error) {
+	if err := m.Validate(&spec); err != nil {
+		return Monitor{}, err
+	}
+	return m.add(uuid.New().String(), spec, time.Now())
+}
+
+// Restore starts a monitor saved by an earlier process under its original
+// ID. Active alerts are not restored, so alerts still failing are raised
+// again by the first sample.
+func (m *Manager) Restore(id string, spec Spec, createdAt time.Time) (Monitor, error) {
+	if err := m.Validate(&spec); err != nil {
+		return Monitor{}, err
+	}
+	return m.add(id, spec, createdAt)
+}
+
+func (m *Manager) add(id string, spec Spec, createdAt time.Time) (Monitor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.monitors[id]; exists {
+		return Monitor{}, fmt.Errorf("monitor %s already exists", id)
+	}
+	if len(m.monitors) >= m.config.MaxMonitors {
+		return Monitor{}, ErrTooManyMonitors
+	}
+
+	mon := &monitor{
+		Monitor: Monitor{
+			ID:        id,
+			Spec:      spec,
+			CreatedAt: createdAt,
+			UpdatedAt: time.Now(),
+		},
+		alerts: make(map[string]Alert),
+	}
+	m.monitors[id] = mon
+	m.startLocked(mon)
+
+	m.logger.Info().
+		Str("monitor_id", id).
+		Str("url", spec.URL).
+		Int("interval_seconds", spec.IntervalSeconds).
+		Msg("Live monitor started")
+
+	return m.snapshotLocked(mon), nil
+}
+
+// Get returns a snapshot of a monitor
+func (m *Manager) Get(id string) (Monitor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mon, ok := m.monitors[id]
+	if !ok {
+		return Monitor{}, ErrMonitorNotFound
+	}
+	return m.snapshotLocked(mon), nil
+}
+
+// List returns snapshots of every monitor, oldest first
+func (m *Manager) List() []Monitor {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	monitors := make([]Monitor, 0, len(m.monitors))
+	for _, mon := range m.monitors {
+		monitors = append(monitors, m.snapshotLocked(mon))
+	}
+	sort.Slice(monitors, func(i, j int) bool {
+		return monitors[i].CreatedAt.Before(monitors[j].CreatedAt)
+	})
+	return monitors
+}
+
+// Update replaces a monitor's spec and restarts its sampling loop. Active
+// alerts are kept and re-evaluated against the new thresholds by the next
+// sample.
+func (m *Manager) Update(id string, spec Spec) (Monitor, error) {
+	if err := m.Validate(&spec); err != nil {
+		return Monitor{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mon, ok := m.monitors[id]
+	if !ok {
+		return Monitor{}, ErrMonitorNotFound
+	}
+	m.stopLocked(mon)
+	mon.Spec = spec
+	mon.UpdatedAt = time.Now()
+	m.startLocked(mon)
+
+	return m.snapshotLocked(mon), nil
+}
+
+// Delete stops and removes a monitor, waiting for a sample in progress to
+// be abandoned so no handler runs for it afterwards
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	mon, ok := m.monitors[id]
+	if !ok {
+		m.mu.Unlock()
+		return ErrMonitorNotFound
+	}
+	delete(m.monitors, id)
+	done := m.stopLocked(mon)
+	m.mu.Unlock()
+
+	if done != nil {
+		<-done
+	}
+	m.logger.Info().Str("monitor_id", id).Msg("Live monitor deleted")
+	return nil
+}
+
+// Count returns the number of monitors and how many of them are alerting
+func (m *Manager) Count() (total, alerting int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, mon := range m.monitors {
+		if len(mon.alerts) > 0 {
+			alerting++
+		}
+	}
+	return len(m.monitors), alerting
+}
+
+// Close stops every sampling loop and waits for them to exit
+func (m *Manager) Close() {
+	m.mu.Lock()
+	m.cancel()
+	var loops []chan struct{}
+	for _, mon := range m.monitors {
+		if done := m.stopLocked(mon); done != nil {
+			loops = append(loops, done)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, done := range loops {
+		<-done
+	}
+}
+
+// startLocked starts the sampling loop of a monitor that is not paused
+func (m *Manager) startLocked(mon *monitor) {
+	if mon.Paused || m.ctx.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	done := make(chan struct{})
+	mon.cancel, mon.done = cancel, done
+	go m.run(ctx, mon.ID, mon.Spec, done)
+}
+
+// stopLocked cancels a monitor's sampling loop and returns a channel closed
+// when it has exited, or nil when it was not running
+func (m *Manager) stopLocked(mon *monitor) chan struct{} {
+	if mon.cancel == nil {
+		return nil
+	}
+	mon.cancel()
+	done := mon.done
+	mon.cancel, mon.done = nil, nil
+	return done
+}
+
+// run samples the stream every interval, measured from the start of one
+// sample to the start of the next, until ctx is cancelled
+func (m *Manager) run(ctx context.Context, id string, spec Spec, done chan struct{}) {
+	defer close(done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		started := time.Now()
+		m.sample(ctx, id, spec)
+		timer.Reset(max(spec.Interval()-time.Since(started), 0))
+	}
+}
+
+// sample takes one sample, records it and notifies the handlers
+func (m *Manager) sample(ctx context.Context, id string, spec Spec) {
+	started := time.Now()
+	sample, err := m.sampler.Sample(ctx, spec.URL, spec.Window())
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		m.logger.Warn().Err(err).Str("monitor_id", id).Msg("Live stream sample failed")
+		sample = &ffmpeg.LiveSample{Timestamp: started.UTC(), Error: err.Error()}
+	}
+	violations := spec.Thresholds.evaluate(sample)
+
+	m.mu.Lock()
+	mon, ok := m.monitors[id]
+	// A cancelled loop has been replaced or deleted; its sample is stale
+	if !ok || ctx.Err() != nil {
+		m.mu.Unlock()
+		return
+	}
+	changes := mon.applyLocked(sample, violations)
+	mon.LastSample = sample
+	mon.SampleCount++
+	snapshot := m.snapshotLocked(mon)
+	alertHandler, sampleHandler := m.alertHandler, m.sampleHandler
+	m.mu.Unlock()
+
+	if sampleHandler != nil {
+		sampleHandler(id, *sample)
+	}
+	for _, alert := range changes {
+		if alert.Resolved() {
+			m.logger.Info().Str("monitor_id", id).Str("rule", alert.Rule).Msg("Live monitor alert resolved")
+		} else {
+			m.logger.Warn().Str("monitor_id", id).Str("rule", alert.Rule).Str("message", alert.Message).Msg("Live monitor alert raised")
+		}
+		if alertHandler != nil {
+			alertHandler(snapshot, alert)
+		}
+	}
+}
+
+// applyLocked raises alerts for newly failing rules and resolves those that
+// pass again. While the stream is unavailable the other rules cannot be
+// checked, so their alerts are left as they were.
+func (mon *monitor) applyLocked(sample *ffmpeg.LiveSample, violations map[string]Alert) []Alert {
+	var changes []Alert
+	for rule, alert := range violations {
+		if _, active := mon.alerts[rule]; !active {
+			alert.RaisedAt = sample.Timestamp
+			mon.alerts[rule] = alert
+			changes = append(changes, alert)
+		}
+	}
+	for rule, alert := range mon.alerts {
+		if _, failing := violations[rule]; failing {
+			continue
+		}
+		if sample.Error != "" && rule != RuleStreamUnavailable {
+			continue
+		}
+		resolvedAt := sample.Timestamp
+		alert.ResolvedAt = &resolvedAt
+		delete(mon.alerts, rule)
+		changes = append(changes, alert)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Rule < changes[j].Rule })
+	return changes
+}
+
+func (m *Manager) snapshotLocked(mon *monitor) Monitor {
+	snapshot := mon.Monitor
+	snapshot.ActiveAlerts = make([]Alert, 0, len(mon.alerts))
+	for _, alert := range mon.alerts {
+		snapshot.ActiveAlerts = append(snapshot.ActiveAlerts, alert)
+	}
+	sort.Slice(snapshot.ActiveAlerts, func(i, j int) bool {
+		return snapshot.ActiveAlerts[i].Rule < snapshot.ActiveAlerts[j].Rule
+	})
+
+	switch {
+	case mon.Paused:
+		snapshot.Status = StatusPaused
+	case len(mon.alerts) > 0:
+		snapshot.Status = StatusAlerting
+	case mon.LastSample == nil:
+		snapshot.Status = StatusStarting
+	default:
+		snapshot.Status = StatusHealthy
+	}
+	return snapshot
+}
@@ -0,0 +1,181 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+	"github.com/rs/zerolog"
+)
+
+// fakeSampler returns queued samples in order; a nil sample is a failure
+type fakeSampler struct {
+	mu      sync.Mutex
+	samples []*ffmpeg.LiveSample
+	calls   chan string
+}
+
+func (f *fakeSampler) Sample(ctx context.Context, url string, window time.Duration) (*ffmpeg.LiveSample, error) {
+	if f.calls != nil {
+		f.calls <- url
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.samples) == 0 {
+		return nil, errors.New("no sample queued")
+	}
+	sample := f.samples[0]
+	f.samples = f.samples[1:]
+	if sample == nil {
+		return nil, errors.New("connection refused")
+	}
+	sample.Timestamp = time.Now()
+	return sample, nil
+}
+
+func float(v float64) *float64 { return &v }
+
+func TestManager_AlertTransitions(t *testing.T) {
+	sampler := &fakeSampler{samples: []*ffmpeg.LiveSample{
+		{BitRate: 500000, IntegratedLoudness: float(-23), FreezeSeconds: 3},
+		{BitRate: 500000, IntegratedLoudness: float(-23), FreezeSeconds: 3},
+		nil,
+		{BitRate: 500000, IntegratedLoudness: float(-16)},
+	}}
+	manager := NewManager(Config{MaxMonitors: 2}, sampler, zerolog.Nop())
+	defer manager.Close()
+
+	var alerts []Alert
+	manager.SetAlertHandler(func(mon Monitor, alert Alert) {
+		alerts = append(alerts, alert)
+	})
+	var samples int
+	manager.SetSampleHandler(func(id string, sample ffmpeg.LiveSample) {
+		samples++
+	})
+
+	// A paused monitor has no loop, so samples are taken by hand
+	spec := Spec{
+		URL:        "udp://239.0.0.1:5000",
+		Paused:     true,
+		Thresholds: Thresholds{MaxLoudness: float(-20), MaxFreezeSeconds: float(2)},
+	}
+	mon, err := manager.Create(spec)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if mon.IntervalSeconds != 60 || mon.SampleSeconds != 10 || mon.Status != StatusPaused {
+		t.Fatalf("unexpected defaults %+v", mon)
+	}
+	spec = mon.Spec
+
+	ctx := context.Background()
+	manager.sample(ctx, mon.ID, spec)
+	if len(alerts) != 1 || alerts[0].Rule != RuleFreeze || alerts[0].Resolved() {
+		t.Fatalf("expected a freeze alert, got %+v", alerts)
+	}
+
+	// A rule that keeps failing is not raised again
+	manager.sample(ctx, mon.ID, spec)
+	if len(alerts) != 1 {
+		t.Fatalf("expected no new alerts, got %+v", alerts[1:])
+	}
+
+	// An unavailable stream keeps the freeze alert active
+	manager.sample(ctx, mon.ID, spec)
+	if len(alerts) != 2 || alerts[1].Rule != RuleStreamUnavailable {
+		t.Fatalf("expected a stream_unavailable alert, got %+v", alerts)
+	}
+	mon, _ = manager.Get(mon.ID)
+	if len(mon.ActiveAlerts) != 2 {
+		t.Errorf("expected 2 active alerts, got %+v", mon.ActiveAlerts)
+	}
+
+	manager.sample(ctx, mon.ID, spec)
+	if len(alerts) != 5 {
+		t.Fatalf("expected 5 alert changes, got %+v", alerts)
+	}
+	changes := map[string]bool{}
+	for _, alert := range alerts[2:] {
+		changes[alert.Rule] = alert.Resolved()
+	}
+	if resolved, ok := changes[RuleLoudnessHigh]; !ok || resolved {
+		t.Errorf("expected loudness_high to be raised, got %+v", alerts[2:])
+	}
+	if !changes[RuleFreeze] || !changes[RuleStreamUnavailable] {
+		t.Errorf("expected freeze and stream_unavailable to resolve, got %+v", alerts[2:])
+	}
+
+	mon, _ = manager.Get(mon.ID)
+	if mon.SampleCount != 4 || samples != 4 {
+		t.Errorf("expected 4 samples, got %d (handler %d)", mon.SampleCount, samples)
+	}
+	if len(mon.ActiveAlerts) != 1 || mon.ActiveAlerts[0].Rule != RuleLoudnessHigh {
+		t.Errorf("expected only loudness_high active, got %+v", mon.ActiveAlerts)
+	}
+}
+
+func TestManager_SamplesUntilDeleted(t *testing.T) {
+	sampler := &fakeSampler{
+		samples: []*ffmpeg.LiveSample{{BitRate: 1000000}},
+		calls:   make(chan string, 1),
+	}
+	manager := NewManager(Config{MaxMonitors: 1}, sampler, zerolog.Nop())
+	defer manager.Close()
+
+	sampled := make(chan ffmpeg.LiveSample, 1)
+	manager.SetSampleHandler(func(id string, sample ffmpeg.LiveSample) {
+		sampled <- sample
+	})
+
+	mon, err := manager.Create(Spec{URL: "srt://example.com:9000", IntervalSeconds: 30, SampleSeconds: 5})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	select {
+	case sample := <-sampled:
+		if sample.BitRate != 1000000 {
+			t.Errorf("unexpected sample %+v", sample)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("first sample was not taken immediately")
+	}
+	<-sampler.calls
+
+	if mon, _ = manager.Get(mon.ID); mon.Status != StatusHealthy {
+		t.Errorf("expected healthy status, got %s", mon.Status)
+	}
+
+	if _, err := manager.Create(Spec{URL: "srt://example.com:9001"}); !errors.Is(err, ErrTooManyMonitors) {
+		t.Errorf("expected ErrTooManyMonitors, got %v", err)
+	}
+
+	if err := manager.Delete(mon.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := manager.Get(mon.ID); !errors.Is(err, ErrMonitorNotFound) {
+		t.Errorf("expected ErrMonitorNotFound, got %v", err)
+	}
+}
+
+func TestManager_Validate(t *testing.T) {
+	manager := NewManager(Config{MaxMonitors: 1, MinInterval: 10 * time.Second, MaxSampleSeconds: 30}, &fakeSampler{}, zerolog.Nop())
+	defer manager.Close()
+
+	invalid := []Spec{
+		{},
+		{URL: "udp://x", IntervalSeconds: 5},
+		{URL: "udp://x", IntervalSeconds: 60, SampleSeconds: 45},
+		{URL: "udp://x", IntervalSeconds: 10, SampleSeconds: 20},
+		{URL: "udp://x", Thresholds: Thresholds{MinLoudness: float(-18), MaxLoudness: float(-24)}},
+	}
+	for _, spec := range invalid {
+		if err := manager.Validate(&spec); err == nil {
+			t.Errorf("expected %+v to be rejected", spec)
+		}
+	}
+}
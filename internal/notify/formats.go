@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"strings"
+	"time"
+)
+
+// object is a JSON object of a webhook payload
+type object = map[string]interface{}
+
+// Slack limits: header text length and fields per section block
+const (
+	slackMaxHeader        = 150
+	slackFieldsPerSection = 10
+)
+
+// slackPayload formats a message as Slack Block Kit, with the plain text
+// as the fallback shown in notifications
+func slackPayload(message Message) object {
+	blocks := []object{{
+		"type": "header",
+		"text": object{"type": "plain_text", "text": truncate(message.Title, slackMaxHeader)},
+	}}
+	if message.Text != "" {
+		blocks = append(blocks, object{
+			"type": "section",
+			"text": object{"type": "mrkdwn", "text": slackEscape(message.Text)},
+		})
+	}
+	for start := 0; start < len(message.Fields); start += slackFieldsPerSection {
+		var fields []object
+		for _, field := range message.Fields[start:min(start+slackFieldsPerSection, len(message.Fields))] {
+			fields = append(fields, object{
+				"type": "mrkdwn",
+				"text": fmt.Sprintf("*%s*\n%s", slackEscape(field.Name), slackEscape(field.Value)),
+			})
+		}
+		blocks = append(blocks, object{"type": "section", "fields": fields})
+	}
+	if message.Link != "" {
+		blocks = append(blocks, object{
+			"type": "actions",
+			"elements": []object{{
+				"type": "button",
+				"text": object{"type": "plain_text", "text": "View"},
+				"url":  message.Link,
+			}},
+		})
+	}
+
+	fallback := message.Title
+	if message.Text != "" {
+		fallback += ": " + message.Text
+	}
+	return object{"text": slackEscape(fallback), "blocks": blocks}
+}
+
+// slackEscape escapes the characters Slack reserves for links and mentions
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// teamsPayload formats a message as an Adaptive Card, which both Teams
+// incoming webhooks and Workflows accept
+func teamsPayload(message Message) object {
+	body := []object{{
+		"type":   "TextBlock",
+		"text":   message.Title,
+		"weight": "Bolder",
+		"size":   "Medium",
+		"wrap":   true,
+	}}
+	if message.Text != "" {
+		body = append(body, object{"type": "TextBlock", "text": message.Text, "wrap": true})
+	}
+	if len(message.Fields) > 0 {
+		facts := make([]object, len(message.Fields))
+		for i, field := range message.Fields {
+			facts[i] = object{"title": field.Name, "value": field.Value}
+		}
+		body = append(body, object{"type": "FactSet", "facts": facts})
+	}
+
+	card := object{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	if message.Link != "" {
+		card["actions"] = []object{{"type": "Action.OpenUrl", "title": "View", "url": message.Link}}
+	}
+	return object{
+		"type": "message",
+		"attachments": []object{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	}
+}
+
+// emailMessage formats a message as a plain text email
+func emailMessage(from string, to []string, message Message) []byte {
+	var body bytes.Buffer
+	if message.Text != "" {
+		body.WriteString(message.Text + "\r\n\r\n")
+	}
+	for _, field := range message.Fields {
+		fmt.Fprintf(&body, "%s: %s\r\n", field.Name, field.Value)
+	}
+	if message.Link != "" {
+		fmt.Fprintf(&body, "\r\n%s\r\n", message.Link)
+	}
+
+	var email bytes.Buffer
+	fmt.Fprintf(&email, "From: %s\r\n", from)
+	fmt.Fprintf(&email, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&email, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerValue("[rendiff-probe] "+message.Title)))
+	fmt.Fprintf(&email, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	email.WriteString("MIME-Version: 1.0\r\n")
+	email.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	email.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	if message.Event != "" {
+		fmt.Fprintf(&email, "X-Rendiff-Event: %s\r\n", headerValue(message.Event))
+	}
+	email.WriteString("\r\n")
+
+	encoder := quotedprintable.NewWriter(&email)
+	_, _ = encoder.Write(body.Bytes())
+	_ = encoder.Close()
+	return email.Bytes()
+}
+
+// headerValue keeps file names and other values from starting new headers
+func headerValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func truncate(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,246 @@
+// Package notify delivers human-readable notifications of finished batch
+// jobs, failed QC policies and watch folder errors to email, Slack and
+// Microsoft Teams channels. Unlike webhook callbacks, which are signed JSON
+// for machines, notifications are formatted for people.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Channel types
+const (
+	ChannelEmail = "email" // Target is a comma-separated list of addresses
+	ChannelSlack = "slack" // Target is a Slack incoming webhook URL
+	ChannelTeams = "teams" // Target is a Teams incoming webhook or Workflows URL
+)
+
+// Events
+const (
+	EventBatchCompleted = "batch.completed"
+	EventPolicyFailed   = "policy.failed"
+	EventWatchError     = "watch.error"
+	EventTest           = "notification.test" // Sent on request to check a channel
+)
+
+// Events lists the events channels can subscribe to
+var Events = []string{EventBatchCompleted, EventPolicyFailed, EventWatchError}
+
+// maxEmailRecipients limits the addresses of one email channel
+const maxEmailRecipients = 20
+
+// maxResponseDrain bounds how much of a webhook response body is read
+const maxResponseDrain = 64 * 1024
+
+// ErrEmailDisabled is returned for email channels when no SMTP relay is
+// configured
+var ErrEmailDisabled = errors.New("email notifications are not configured")
+
+// Config configures notification delivery
+type Config struct {
+	SMTP    SMTPConfig
+	Timeout time.Duration // Per-delivery timeout
+}
+
+// SMTPConfig is the relay email notifications are sent through. Email
+// channels are disabled without a host.
+type SMTPConfig struct {
+	Host     string
+	Port     int // 465 uses implicit TLS; other ports use STARTTLS when offered
+	Username string
+	Password string
+	From     string
+}
+
+// Channel is a destination for notifications
+type Channel struct {
+	Type   string
+	Target string
+}
+
+// Message is a notification. Fields are shown as a table of facts and Link,
+// when set, as a button or link to the resource in the API.
+type Message struct {
+	Event  string
+	Title  string
+	Text   string
+	Fields []Field
+	Link   string
+}
+
+// Field is a named value of a message
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Notifier sends messages to channels
+type Notifier struct {
+	config       Config
+	httpClient   *http.Client
+	urlValidator func(string) error
+	sendMail     func(ctx context.Context, config SMTPConfig, to []string, message []byte) error
+	logger       zerolog.Logger
+}
+
+// NewNotifier creates a new notifier
+func NewNotifier(config Config, logger zerolog.Logger) *Notifier {
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.SMTP.Port == 0 {
+		config.SMTP.Port = 587
+	}
+	return &Notifier{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+		sendMail:   sendMail,
+		logger:     logger,
+	}
+}
+
+// SetHTTPClient sets a custom HTTP client for Slack and Teams webhooks
+func (n *Notifier) SetHTTPClient(client *http.Client) {
+	n.httpClient = client
+}
+
+// SetURLValidator sets a validator applied to webhook URLs before delivery
+func (n *Notifier) SetURLValidator(validator func(string) error) {
+	n.urlValidator = validator
+}
+
+// EmailEnabled reports whether an SMTP relay is configured
+func (n *Notifier) EmailEnabled() bool {
+	return n.config.SMTP.Host != ""
+}
+
+// ParseEvents validates a channel's event subscriptions. No events
+// subscribes to all of them.
+func ParseEvents(events []string) ([]string, error) {
+	parsed := []string{}
+	for _, event := range events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if event == "" || slices.Contains(parsed, event) {
+			continue
+		}
+		if !slices.Contains(Events, event) {
+			return nil, fmt.Errorf("unknown event %q (use %s)", event, strings.Join(Events, ", "))
+		}
+		parsed = append(parsed, event)
+	}
+	return parsed, nil
+}
+
+// Subscribed reports whether a channel with the given subscriptions
+// receives an event. Test messages reach every channel.
+func Subscribed(events []string, event string) bool {
+	return len(events) == 0 || event == EventTest || slices.Contains(events, event)
+}
+
+// ValidateChannel checks a channel when it is created, so unusable targets
+// are rejected up front
+func (n *Notifier) ValidateChannel(channel Channel) error {
+	switch channel.Type {
+	case ChannelEmail:
+		if !n.EmailEnabled() {
+			return ErrEmailDisabled
+		}
+		_, err := parseRecipients(channel.Target)
+		return err
+	case ChannelSlack, ChannelTeams:
+		if !strings.HasPrefix(channel.Target, "https://") {
+			return fmt.Errorf("%s webhook URL must use https", channel.Type)
+		}
+		if n.urlValidator != nil {
+			if err := n.urlValidator(channel.Target); err != nil {
+				return fmt.Errorf("webhook URL rejected: %w", err)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown channel type %q (use email, slack or teams)", channel.Type)
+}
+
+// Send delivers a message to a channel
+func (n *Notifier) Send(ctx context.Context, channel Channel, message Message) error {
+	if err := n.ValidateChannel(channel); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.config.Timeout)
+	defer cancel()
+
+	var err error
+	switch channel.Type {
+	case ChannelEmail:
+		recipients, _ := parseRecipients(channel.Target)
+		err = n.sendMail(ctx, n.config.SMTP, recipients, emailMessage(n.config.SMTP.From, recipients, message))
+	case ChannelSlack:
+		err = n.post(ctx, channel.Target, slackPayload(message))
+	case ChannelTeams:
+		err = n.post(ctx, channel.Target, teamsPayload(message))
+	}
+	if err != nil {
+		return fmt.Errorf("%s notification failed: %w", channel.Type, err)
+	}
+
+	n.logger.Debug().
+		Str("channel", channel.Type).
+		Str("event", message.Event).
+		Msg("Notification delivered")
+	return nil
+}
+
+// post sends a JSON payload to a Slack or Teams webhook
+func (n *Notifier) post(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rendiff-probe-notify/1.0")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseDrain))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// parseRecipients parses the comma-separated addresses of an email channel
+func parseRecipients(target string) ([]string, error) {
+	list, err := mail.ParseAddressList(target)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email addresses: %w", err)
+	}
+	if len(list) > maxEmailRecipients {
+		return nil, fmt.Errorf("at most %d email addresses per channel", maxEmailRecipients)
+	}
+	recipients := make([]string, len(list))
+	for i, address := range list {
+		recipients[i] = address.Address
+	}
+	return recipients, nil
+}
@@ -0,0 +1,172 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/quotedprintable"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+var testMessage = Message{
+	Event:  EventPolicyFailed,
+	Title:  "QC policy failed: news_hd",
+	Text:   "promo_<final>.mov does not meet the policy",
+	Fields: []Field{{Name: "File", Value: "promo_<final>.mov"}, {Name: "Failed checks", Value: "2"}},
+	Link:   "https://probe.example.com/api/v1/analyses/123/report",
+}
+
+// capture starts a webhook receiver that records the last payload
+func capture(t *testing.T, status int) (*httptest.Server, *map[string]interface{}) {
+	t.Helper()
+	var payload map[string]interface{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &payload
+}
+
+func TestSend_Slack(t *testing.T) {
+	server, payload := capture(t, 200)
+	n := NewNotifier(Config{}, zerolog.Nop())
+	n.SetHTTPClient(server.Client())
+
+	if err := n.Send(context.Background(), Channel{Type: ChannelSlack, Target: server.URL}, testMessage); err != nil {
+		t.Fatal(err)
+	}
+	text, _ := (*payload)["text"].(string)
+	if !strings.Contains(text, "promo_&lt;final&gt;.mov") {
+		t.Errorf("fallback text %q is not escaped", text)
+	}
+	blocks, _ := (*payload)["blocks"].([]interface{})
+	if len(blocks) != 4 {
+		t.Fatalf("got %d blocks, want header, text, fields and actions", len(blocks))
+	}
+	actions, _ := json.Marshal(blocks[3])
+	if !strings.Contains(string(actions), testMessage.Link) {
+		t.Errorf("actions block %s has no link", actions)
+	}
+}
+
+func TestSend_Teams(t *testing.T) {
+	server, payload := capture(t, 202)
+	n := NewNotifier(Config{}, zerolog.Nop())
+	n.SetHTTPClient(server.Client())
+
+	if err := n.Send(context.Background(), Channel{Type: ChannelTeams, Target: server.URL}, testMessage); err != nil {
+		t.Fatal(err)
+	}
+	encoded, _ := json.Marshal(*payload)
+	for _, want := range []string{`"AdaptiveCard"`, `"FactSet"`, `"Failed checks"`, `"Action.OpenUrl"`} {
+		if !strings.Contains(string(encoded), want) {
+			t.Errorf("payload %s has no %s", encoded, want)
+		}
+	}
+}
+
+func TestSend_WebhookError(t *testing.T) {
+	server, _ := capture(t, 404)
+	n := NewNotifier(Config{}, zerolog.Nop())
+	n.SetHTTPClient(server.Client())
+
+	err := n.Send(context.Background(), Channel{Type: ChannelSlack, Target: server.URL}, testMessage)
+	if err == nil || !strings.Contains(err.Error(), "HTTP 404") {
+		t.Errorf("error = %v, want HTTP 404", err)
+	}
+}
+
+func TestSend_Email(t *testing.T) {
+	n := NewNotifier(Config{SMTP: SMTPConfig{Host: "smtp.example.com", From: "QC <qc@example.com>"}}, zerolog.Nop())
+	var sent []byte
+	var recipients []string
+	n.sendMail = func(_ context.Context, config SMTPConfig, to []string, message []byte) error {
+		if config.Port != 587 {
+			t.Errorf("port = %d, want default 587", config.Port)
+		}
+		recipients, sent = to, message
+		return nil
+	}
+
+	channel := Channel{Type: ChannelEmail, Target: "ops@example.com, Archive <archive@example.com>"}
+	message := testMessage
+	message.Title = "QC policy failed:\r\nBcc: victim@example.com"
+	if err := n.Send(context.Background(), channel, message); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(recipients, ",") != "ops@example.com,archive@example.com" {
+		t.Errorf("recipients = %v", recipients)
+	}
+
+	header, body, _ := strings.Cut(string(sent), "\r\n\r\n")
+	if strings.Contains(header, "\r\nBcc:") {
+		t.Errorf("title injected a header:\n%s", header)
+	}
+	if !strings.Contains(header, "Subject: [rendiff-probe] QC policy failed: Bcc: victim@example.com") {
+		t.Errorf("unexpected headers:\n%s", header)
+	}
+	decoded, _ := io.ReadAll(quotedprintable.NewReader(strings.NewReader(body)))
+	if !strings.Contains(string(decoded), "Failed checks: 2") || !strings.Contains(string(decoded), testMessage.Link) {
+		t.Errorf("unexpected body:\n%s", decoded)
+	}
+}
+
+func TestValidateChannel(t *testing.T) {
+	n := NewNotifier(Config{}, zerolog.Nop())
+	n.SetURLValidator(func(url string) error {
+		if strings.Contains(url, "internal") {
+			return errors.New("blocked")
+		}
+		return nil
+	})
+
+	tests := []struct {
+		channel Channel
+		wantErr bool
+	}{
+		{Channel{Type: ChannelSlack, Target: "https://hooks.slack.com/services/T0/B0/x"}, false},
+		{Channel{Type: ChannelTeams, Target: "http://example.webhook.office.com/x"}, true},
+		{Channel{Type: ChannelSlack, Target: "https://internal.example.com/hook"}, true},
+		{Channel{Type: "pager", Target: "https://example.com"}, true},
+	}
+	for _, tt := range tests {
+		if err := n.ValidateChannel(tt.channel); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateChannel(%+v) error = %v, wantErr %v", tt.channel, err, tt.wantErr)
+		}
+	}
+
+	if err := n.ValidateChannel(Channel{Type: ChannelEmail, Target: "ops@example.com"}); !errors.Is(err, ErrEmailDisabled) {
+		t.Errorf("email without SMTP relay: error = %v, want ErrEmailDisabled", err)
+	}
+	n = NewNotifier(Config{SMTP: SMTPConfig{Host: "smtp.example.com", From: "qc@example.com"}}, zerolog.Nop())
+	if err := n.ValidateChannel(Channel{Type: ChannelEmail, Target: "not an address"}); err == nil {
+		t.Error("invalid address accepted")
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents([]string{" Batch.Completed", "watch.error", "batch.completed", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(events, ",") != "batch.completed,watch.error" {
+		t.Errorf("events = %v", events)
+	}
+	if _, err := ParseEvents([]string{"probe.completed"}); err == nil {
+		t.Error("unknown event accepted")
+	}
+
+	if !Subscribed(nil, EventWatchError) || !Subscribed(events, EventTest) || Subscribed(events, EventPolicyFailed) {
+		t.Error("unexpected subscription matching")
+	}
+}
@@ -0,0 +1,93 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+)
+
+// smtpImplicitTLSPort is the submissions port, where TLS starts before the
+// SMTP greeting
+const smtpImplicitTLSPort = 465
+
+// sendMail delivers an email through the relay. Unlike smtp.SendMail it
+// honors the context deadline and supports implicit TLS.
+func sendMail(ctx context.Context, config SMTPConfig, to []string, message []byte) error {
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	tlsConfig := &tls.Config{ServerName: config.Host, MinVersion: tls.VersionTLS12}
+
+	var conn net.Conn
+	var err error
+	if config.Port == smtpImplicitTLSPort {
+		dialer := &tls.Dialer{Config: tlsConfig}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP relay: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, config.Host)
+	if err != nil {
+		return fmt.Errorf("SMTP handshake failed: %w", err)
+	}
+	defer client.Close()
+
+	if config.Port != smtpImplicitTLSPort {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("STARTTLS failed: %w", err)
+			}
+		}
+	}
+	if config.Username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted
+		// connection to anything but localhost
+		if err := client.Auth(smtp.PlainAuth("", config.Username, config.Password, config.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	from, err := mailAddress(config.From)
+	if err != nil {
+		return err
+	}
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM rejected: %w", err)
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("SMTP recipient %s rejected: %w", recipient, err)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA rejected: %w", err)
+	}
+	if _, err := writer.Write(message); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return client.Quit()
+}
+
+// mailAddress returns the bare address of a From value such as
+// "QC <qc@example.com>"
+func mailAddress(value string) (string, error) {
+	addresses, err := parseRecipients(value)
+	if err != nil || len(addresses) != 1 {
+		return "", fmt.Errorf("invalid sender address %q", value)
+	}
+	return addresses[0], nil
+}
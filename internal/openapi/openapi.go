@@ -0,0 +1,345 @@
+// Package openapi builds OpenAPI 3.0 documents. Routes are added as they
+// are registered, and request and response schemas are generated from the
+// Go types the handlers bind and return.
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Version is the OpenAPI version of generated documents
+const Version = "3.0.3"
+
+// Document is an OpenAPI document
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Servers    []Server                         `json:"servers,omitempty"`
+	Tags       []Tag                            `json:"tags,omitempty"`
+	Security   []map[string][]string            `json:"security,omitempty"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components Components                       `json:"components"`
+
+	// names are the component names of the struct types seen so far
+	names map[reflect.Type]string
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Server is a base URL of the API
+type Server struct {
+	URL         string `json:"url"`
+	Description string `json:"description,omitempty"`
+}
+
+// Tag groups operations
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Components holds the schemas operations refer to
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme is a way to authenticate
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name,omitempty"`
+	In          string `json:"in,omitempty"`
+	Scheme      string `json:"scheme,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Operation is one method of a path
+type Operation struct {
+	OperationID string               `json:"operationId,omitempty"`
+	Summary     string               `json:"summary,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+	// Security overrides the document's security; an empty list makes the
+	// operation public
+	Security *[]map[string][]string `json:"security,omitempty"`
+	// Role is the least API key role that may call the operation
+	Role string `json:"x-required-role,omitempty"`
+}
+
+// Parameter is a path, query or header parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody is the body of a request by media type
+type RequestBody struct {
+	Description string               `json:"description,omitempty"`
+	Required    bool                 `json:"required,omitempty"`
+	Content     map[string]MediaType `json:"content"`
+}
+
+// Response is a response by media type
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType is the schema of one media type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema. The zero Schema accepts any value.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// New returns an empty document
+func New(info Info) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]map[string]*Operation),
+		Components: Components{
+			Schemas:         make(map[string]*Schema),
+			SecuritySchemes: make(map[string]*SecurityScheme),
+		},
+		names: make(map[reflect.Type]string),
+	}
+}
+
+// ginParam matches the :name and *name parameters of a gin route
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// Add adds an operation for a gin route such as /analyses/:id. The path
+// parameters the operation doesn't describe are added as strings.
+func (d *Document) Add(method, route string, op *Operation) {
+	path := ginParam.ReplaceAllString(route, "{$1}")
+	for _, match := range ginParam.FindAllStringSubmatch(route, -1) {
+		if !op.hasParameter(match[1], "path") {
+			op.Parameters = append(op.Parameters, Parameter{Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	if op.Responses == nil {
+		op.Responses = make(map[string]*Response)
+	}
+
+	item, ok := d.Paths[path]
+	if !ok {
+		item = make(map[string]*Operation)
+		d.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+func (op *Operation) hasParameter(name, in string) bool {
+	for _, p := range op.Parameters {
+		if p.Name == name && p.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+// JSON returns the content of a JSON request or response with the schema
+// of v
+func (d *Document) JSON(v interface{}) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: d.SchemaOf(v)}}
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// SchemaOf returns the schema of the JSON encoding of v. Named struct types
+// are added to the components and referred to, so recursive types work.
+// A nil v has no schema.
+func (d *Document) SchemaOf(v interface{}) *Schema {
+	if v == nil {
+		return nil
+	}
+	if schema, ok := v.(*Schema); ok {
+		return schema
+	}
+	return d.schema(reflect.TypeOf(v))
+}
+
+func (d *Document) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t.Kind() != reflect.Struct && implements(t, textMarshalerType):
+		return &Schema{Type: "string"}
+	case t.Kind() != reflect.Struct && implements(t, marshalerType):
+		// The encoding is the type's own, so any value is allowed
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		zero := 0.0
+		return &Schema{Type: "integer", Minimum: &zero}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: d.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: d.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + d.component(t)}
+	}
+	// Interfaces, and channels and functions, which don't encode
+	return &Schema{}
+}
+
+// component returns the component name of a named struct type, adding its
+// schema the first time
+func (d *Document) component(t reflect.Type) string {
+	if name, ok := d.names[t]; ok {
+		return name
+	}
+
+	name := componentName(t.Name())
+	if _, taken := d.Components.Schemas[name]; taken {
+		name = componentName(pathBase(t.PkgPath())) + name
+	}
+	d.names[t] = name
+	// Reserve the name before the fields are walked, they may refer back
+	d.Components.Schemas[name] = &Schema{}
+	*d.Components.Schemas[name] = *d.structSchema(t)
+	return name
+}
+
+// structSchema returns the object schema of a struct's exported fields.
+// Fields of embedded structs without a JSON name are promoted, as
+// encoding/json does, and binding:"required" fields are required.
+func (d *Document) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	d.addFields(schema, t)
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (d *Document) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			d.addFields(schema, fieldType)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := d.schema(field.Type)
+		if hasOption(options, "string") && property.Ref == "" {
+			property = &Schema{Type: "string"}
+		}
+		schema.Properties[name] = property
+		if hasOption(field.Tag.Get("binding"), "required") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
+
+func hasOption(options, option string) bool {
+	for _, o := range strings.Split(options, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// componentName makes a Go type name a component name: exported, without
+// the brackets of generic instances
+func componentName(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func pathBase(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
@@ -0,0 +1,102 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testBase struct {
+	ID        string    `json:"id" binding:"required"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type testNode struct {
+	testBase
+	Name     string                 `json:"name,omitempty"`
+	Size     int64                  `json:"size,string"`
+	Tags     []string               `json:"tags"`
+	Data     []byte                 `json:"data"`
+	Extra    map[string]interface{} `json:"extra"`
+	Children []*testNode            `json:"children"`
+	Secret   string                 `json:"-"`
+	hidden   string
+}
+
+func TestSchemaOf(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	schema := doc.SchemaOf(&testNode{})
+	if schema.Ref != "#/components/schemas/TestNode" {
+		t.Fatalf("expected a reference to TestNode, got %+v", schema)
+	}
+
+	node := doc.Components.Schemas["TestNode"]
+	if node == nil || node.Type != "object" {
+		t.Fatalf("expected the TestNode component, got %+v", node)
+	}
+	want := map[string]Schema{
+		"id":         {Type: "string"},
+		"created_at": {Type: "string", Format: "date-time"},
+		"name":       {Type: "string"},
+		"size":       {Type: "string"},
+		"data":       {Type: "string", Format: "byte"},
+	}
+	for name, property := range want {
+		if got := node.Properties[name]; got == nil || !reflect.DeepEqual(*got, property) {
+			t.Errorf("property %s = %+v, want %+v", name, got, property)
+		}
+	}
+	if got := node.Properties["children"]; got == nil || got.Items == nil || got.Items.Ref != "#/components/schemas/TestNode" {
+		t.Errorf("expected children to refer back to TestNode, got %+v", got)
+	}
+	if got := node.Properties["extra"]; got == nil || got.AdditionalProperties == nil {
+		t.Errorf("expected extra to be a map, got %+v", got)
+	}
+	for _, name := range []string{"Secret", "hidden", "testBase"} {
+		if _, ok := node.Properties[name]; ok {
+			t.Errorf("unexpected property %s", name)
+		}
+	}
+	if !reflect.DeepEqual(node.Required, []string{"id"}) {
+		t.Errorf("expected id to be required, got %v", node.Required)
+	}
+	if _, ok := doc.Components.Schemas["TestBase"]; ok {
+		t.Error("embedded struct should be promoted, not a component")
+	}
+}
+
+func TestSchemaOf_Anonymous(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	schema := doc.SchemaOf(struct {
+		URL string `json:"url" binding:"required"`
+	}{})
+	if schema.Type != "object" || schema.Properties["url"] == nil || len(doc.Components.Schemas) != 0 {
+		t.Errorf("expected an inline object schema, got %+v", schema)
+	}
+	if doc.SchemaOf(nil) != nil {
+		t.Error("expected no schema for nil")
+	}
+}
+
+func TestAdd(t *testing.T) {
+	doc := New(Info{Title: "test", Version: "1"})
+	doc.Add("GET", "/organizations/:id/projects/:project_id", &Operation{
+		Parameters: []Parameter{{Name: "id", In: "path", Required: true, Description: "Organization ID", Schema: &Schema{Type: "string"}}},
+	})
+
+	op := doc.Paths["/organizations/{id}/projects/{project_id}"]["get"]
+	if op == nil {
+		t.Fatalf("expected the converted path, got %v", doc.Paths)
+	}
+	if len(op.Parameters) != 2 || op.Parameters[0].Description != "Organization ID" || op.Parameters[1].Name != "project_id" {
+		t.Errorf("unexpected parameters %+v", op.Parameters)
+	}
+	if op.Responses == nil {
+		t.Error("expected responses to be initialized")
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatalf("document does not encode: %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+const (
+	// durationDiffTolerance is how far durations may drift, in seconds,
+	// before the change counts as essential; encoder priming and padding
+	// move audio durations by a few frames
+	durationDiffTolerance = 0.1
+
+	// loudnessDiffTolerance is how far integrated loudness may move, in LU,
+	// before a re-encode no longer preserves it
+	loudnessDiffTolerance = 1.0
+)
+
+// AnalysisDiff is a structured comparison of two analyses, A and B, for
+// checking that a re-encode kept the essential characteristics of its source
+type AnalysisDiff struct {
+	Preserved      bool            `json:"preserved"` // No stream added or removed, no essential change, loudness within tolerance
+	Format         []Change        `json:"format"`
+	Streams        []StreamDiff    `json:"streams"` // Streams present in both, paired by type and order
+	AddedStreams   []StreamSummary `json:"added_streams"`
+	RemovedStreams []StreamSummary `json:"removed_streams"`
+	Loudness       *LoudnessDelta  `json:"loudness,omitempty"`
+}
+
+// Change is one property whose value differs between A and B. Essential
+// changes alter what the viewer sees or hears, e.g. resolution, frame rate
+// or channel layout, rather than only how it is encoded.
+type Change struct {
+	Property  string `json:"property"`
+	A         string `json:"a"`
+	B         string `json:"b"`
+	Essential bool   `json:"essential,omitempty"`
+}
+
+// StreamDiff compares the streams paired from A and B
+type StreamDiff struct {
+	CodecType string         `json:"codec_type"`
+	IndexA    int            `json:"index_a"`
+	IndexB    int            `json:"index_b"`
+	Changes   []Change       `json:"changes,omitempty"`
+	Loudness  *LoudnessDelta `json:"loudness,omitempty"` // When both analyses measured the stream separately
+}
+
+// StreamSummary identifies a stream present in only one analysis
+type StreamSummary struct {
+	Index       int    `json:"index"`
+	CodecType   string `json:"codec_type"`
+	Description string `json:"description"`
+}
+
+// LoudnessDelta compares loudness measurements; deltas are B minus A
+type LoudnessDelta struct {
+	IntegratedA     float64 `json:"integrated_a_lufs"`
+	IntegratedB     float64 `json:"integrated_b_lufs"`
+	IntegratedDelta float64 `json:"integrated_delta_lu"`
+	TruePeakA       float64 `json:"true_peak_a_dbtp"`
+	TruePeakB       float64 `json:"true_peak_b_dbtp"`
+	TruePeakDelta   float64 `json:"true_peak_delta_db"`
+	RangeA          float64 `json:"range_a_lu"`
+	RangeB          float64 `json:"range_b_lu"`
+	RangeDelta      float64 `json:"range_delta_lu"`
+	WithinTolerance bool    `json:"within_tolerance"`
+}
+
+// DiffAnalyses compares two analyses stream by stream. Streams are paired by
+// codec type and order, so the second audio stream of A is compared with the
+// second audio stream of B; unpaired streams are listed as added or removed.
+func DiffAnalyses(a, b *ffmpeg.FFprobeResult) *AnalysisDiff {
+	diff := &AnalysisDiff{
+		Format:         formatChanges(a.Format, b.Format),
+		Streams:        []StreamDiff{},
+		AddedStreams:   []StreamSummary{},
+		RemovedStreams: []StreamSummary{},
+	}
+
+	streamsB := make(map[string][]ffmpeg.StreamInfo)
+	for _, stream := range b.Streams {
+		streamsB[stream.CodecType] = append(streamsB[stream.CodecType], stream)
+	}
+	paired := make(map[string]int)
+	for _, streamA := range a.Streams {
+		n := paired[streamA.CodecType]
+		if n >= len(streamsB[streamA.CodecType]) {
+			diff.RemovedStreams = append(diff.RemovedStreams, summarizeStream(streamA))
+			continue
+		}
+		paired[streamA.CodecType] = n + 1
+		streamB := streamsB[streamA.CodecType][n]
+
+		streamDiff := StreamDiff{
+			CodecType: streamA.CodecType,
+			IndexA:    streamA.Index,
+			IndexB:    streamB.Index,
+			Changes:   streamChanges(streamA, streamB),
+		}
+		if streamA.CodecType == "audio" {
+			streamDiff.Loudness = diffLoudness(audioStreamLoudness(a, streamA.Index), audioStreamLoudness(b, streamB.Index))
+		}
+		diff.Streams = append(diff.Streams, streamDiff)
+	}
+	for codecType, streams := range streamsB {
+		for _, stream := range streams[paired[codecType]:] {
+			diff.AddedStreams = append(diff.AddedStreams, summarizeStream(stream))
+		}
+	}
+	sort.Slice(diff.AddedStreams, func(i, j int) bool {
+		return diff.AddedStreams[i].Index < diff.AddedStreams[j].Index
+	})
+
+	diff.Loudness = diffLoudness(loudnessMeter(a), loudnessMeter(b))
+
+	diff.Preserved = len(diff.AddedStreams) == 0 && len(diff.RemovedStreams) == 0 &&
+		!hasEssentialChange(diff.Format) && (diff.Loudness == nil || diff.Loudness.WithinTolerance)
+	for _, stream := range diff.Streams {
+		if hasEssentialChange(stream.Changes) || (stream.Loudness != nil && !stream.Loudness.WithinTolerance) {
+			diff.Preserved = false
+		}
+	}
+	return diff
+}
+
+// changeList collects the properties that differ
+type changeList []Change
+
+func (l *changeList) add(property, a, b string, essential bool) {
+	if a != b {
+		*l = append(*l, Change{Property: property, A: orNA(a), B: orNA(b), Essential: essential})
+	}
+}
+
+func formatChanges(a, b *ffmpeg.FormatInfo) []Change {
+	var formatA, formatB ffmpeg.FormatInfo
+	if a != nil {
+		formatA = *a
+	}
+	if b != nil {
+		formatB = *b
+	}
+
+	changes := changeList{}
+	changes.add("Container", formatA.FormatName, formatB.FormatName, false)
+	if durationA, durationB := formatDuration(formatA.Duration), formatDuration(formatB.Duration); durationA != durationB {
+		changes = append(changes, Change{
+			Property:  "Duration",
+			A:         durationA,
+			B:         durationB,
+			Essential: !withinSeconds(formatA.Duration, formatB.Duration, durationDiffTolerance),
+		})
+	}
+	changes.add("File Size", optionalBytes(formatA.Size), optionalBytes(formatB.Size), false)
+	changes.add("Overall Bit Rate", optionalBitRate(formatA.BitRate), optionalBitRate(formatB.BitRate), false)
+	return changes
+}
+
+// streamChanges compares the properties relevant to the stream type
+func streamChanges(a, b ffmpeg.StreamInfo) []Change {
+	changes := changeList{}
+	changes.add("Codec", a.CodecName, b.CodecName, false)
+	changes.add("Profile", a.Profile, b.Profile, false)
+
+	switch a.CodecType {
+	case "video":
+		changes.add("Level", optionalInt(a.Level), optionalInt(b.Level), false)
+		changes.add("Resolution", fmt.Sprintf("%dx%d", a.Width, a.Height), fmt.Sprintf("%dx%d", b.Width, b.Height), true)
+		changes.add("Display Aspect Ratio", a.DisplayAspectRatio, b.DisplayAspectRatio, true)
+		changes.add("Frame Rate", a.AvgFrameRate, b.AvgFrameRate, true)
+		changes.add("Field Order", a.FieldOrder, b.FieldOrder, true)
+		changes.add("Pixel Format", a.PixFmt, b.PixFmt, false)
+		changes.add("Bit Depth", a.BitsPerRawSample, b.BitsPerRawSample, false)
+		changes.add("Color Primaries", a.ColorPrimaries, b.ColorPrimaries, true)
+		changes.add("Color Transfer", a.ColorTransfer, b.ColorTransfer, true)
+		changes.add("Color Space", a.ColorSpace, b.ColorSpace, true)
+		changes.add("Color Range", a.ColorRange, b.ColorRange, true)
+	case "audio":
+		changes.add("Sample Rate", a.SampleRate, b.SampleRate, true)
+		changes.add("Channels", optionalInt(a.Channels), optionalInt(b.Channels), true)
+		changes.add("Channel Layout", a.ChannelLayout, b.ChannelLayout, true)
+		changes.add("Sample Format", a.SampleFmt, b.SampleFmt, false)
+		changes.add("Language", a.Tags["language"], b.Tags["language"], true)
+	case "subtitle":
+		changes.add("Language", a.Tags["language"], b.Tags["language"], true)
+	}
+	changes.add("Bit Rate", optionalBitRate(a.BitRate), optionalBitRate(b.BitRate), false)
+	return changes
+}
+
+func summarizeStream(stream ffmpeg.StreamInfo) StreamSummary {
+	parts := []string{orNA(stream.CodecName)}
+	switch stream.CodecType {
+	case "video":
+		parts = append(parts, fmt.Sprintf("%dx%d", stream.Width, stream.Height))
+		if stream.AvgFrameRate != "" {
+			parts = append(parts, "@ "+stream.AvgFrameRate)
+		}
+	case "audio":
+		parts = append(parts, fmt.Sprintf("%d ch", stream.Channels))
+		if stream.SampleRate != "" {
+			parts = append(parts, "@ "+stream.SampleRate+" Hz")
+		}
+	}
+	if language := stream.Tags["language"]; language != "" {
+		parts = append(parts, "("+language+")")
+	}
+	return StreamSummary{Index: stream.Index, CodecType: stream.CodecType, Description: strings.Join(parts, " ")}
+}
+
+func loudnessMeter(result *ffmpeg.FFprobeResult) *ffmpeg.LoudnessAnalysis {
+	if result.EnhancedAnalysis == nil || result.EnhancedAnalysis.ContentAnalysis == nil {
+		return nil
+	}
+	return result.EnhancedAnalysis.ContentAnalysis.LoudnessMeter
+}
+
+func audioStreamLoudness(result *ffmpeg.FFprobeResult, index int) *ffmpeg.LoudnessAnalysis {
+	if result.EnhancedAnalysis == nil || result.EnhancedAnalysis.ContentAnalysis == nil {
+		return nil
+	}
+	if stream := result.EnhancedAnalysis.ContentAnalysis.AudioStreams[index]; stream != nil {
+		return stream.Loudness
+	}
+	return nil
+}
+
+// diffLoudness compares two loudness measurements, or returns nil unless
+// both were measured
+func diffLoudness(a, b *ffmpeg.LoudnessAnalysis) *LoudnessDelta {
+	if a == nil || b == nil {
+		return nil
+	}
+	delta := &LoudnessDelta{
+		IntegratedA:     a.IntegratedLoudness,
+		IntegratedB:     b.IntegratedLoudness,
+		IntegratedDelta: roundTenth(b.IntegratedLoudness - a.IntegratedLoudness),
+		TruePeakA:       a.TruePeak,
+		TruePeakB:       b.TruePeak,
+		TruePeakDelta:   roundTenth(b.TruePeak - a.TruePeak),
+		RangeA:          a.LoudnessRange,
+		RangeB:          b.LoudnessRange,
+		RangeDelta:      roundTenth(b.LoudnessRange - a.LoudnessRange),
+	}
+	delta.WithinTolerance = math.Abs(delta.IntegratedDelta) <= loudnessDiffTolerance
+	return delta
+}
+
+func hasEssentialChange(changes []Change) bool {
+	for _, change := range changes {
+		if change.Essential {
+			return true
+		}
+	}
+	return false
+}
+
+// withinSeconds reports whether two second values are at most tolerance
+// apart; values that do not parse only match themselves
+func withinSeconds(a, b string, tolerance float64) bool {
+	secondsA, errA := strconv.ParseFloat(a, 64)
+	secondsB, errB := strconv.ParseFloat(b, 64)
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return math.Abs(secondsA-secondsB) <= tolerance
+}
+
+func roundTenth(value float64) float64 {
+	return math.Round(value*10) / 10
+}
+
+func optionalInt(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
+
+// optionalBytes formats a byte count, leaving a missing one empty
+func optionalBytes(value string) string {
+	if value == "" {
+		return ""
+	}
+	return formatBytes(value)
+}
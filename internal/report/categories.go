@@ -0,0 +1,1105 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// maxAFDSegmentFields limits the AFD segments listed in the AFD category
+const maxAFDSegmentFields = 20
+
+// analysisData gives the category builders nil-safe access to a result
+type analysisData struct {
+	result   *ffmpeg.FFprobeResult
+	format   ffmpeg.FormatInfo
+	enhanced ffmpeg.EnhancedAnalysis
+	video    *ffmpeg.StreamInfo
+	audio    *ffmpeg.StreamInfo
+}
+
+func newAnalysisData(result *ffmpeg.FFprobeResult) *analysisData {
+	data := &analysisData{result: result}
+	if result.Format != nil {
+		data.format = *result.Format
+	}
+	if result.EnhancedAnalysis != nil {
+		data.enhanced = *result.EnhancedAnalysis
+	}
+	for i := range result.Streams {
+		stream := &result.Streams[i]
+		switch strings.ToLower(stream.CodecType) {
+		case "video":
+			if data.video == nil {
+				data.video = stream
+			}
+		case "audio":
+			if data.audio == nil {
+				data.audio = stream
+			}
+		}
+	}
+	return data
+}
+
+// summary lists the key media properties
+func (d *analysisData) summary() []Field {
+	fields := []Field{
+		{"Container", orNA(d.format.FormatLongName)},
+		{"Duration", formatDuration(d.format.Duration)},
+		{"File Size", formatBytes(d.format.Size)},
+		{"Overall Bit Rate", formatBitRate(d.format.BitRate)},
+	}
+	if d.video != nil {
+		fields = append(fields, Field{"Video", fmt.Sprintf("%s %dx%d @ %s", d.video.CodecName, d.video.Width, d.video.Height, orNA(d.video.AvgFrameRate))})
+	}
+	if d.audio != nil {
+		fields = append(fields, Field{"Audio", fmt.Sprintf("%s %d ch @ %s Hz", d.audio.CodecName, d.audio.Channels, orNA(d.audio.SampleRate))})
+	}
+	if metadata := d.result.Metadata; metadata != nil {
+		for _, field := range []Field{{"Title", metadata.Title}, {"Creator", metadata.Creator}, {"Created", metadata.CreatedAt}} {
+			if field.Value != "" {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
+
+// categories builds the 19 QC categories in their canonical order
+func (d *analysisData) categories() []Category {
+	builders := []func() Category{
+		d.afd, d.deadPixels, d.pse, d.hdr, d.audioWrapping, d.endianness,
+		d.codec, d.container, d.resolution, d.frameRate, d.bitDepth,
+		d.timecode, d.mxf, d.imf, d.transportStream, d.content,
+		d.enhancedSummary, d.disposition, d.dataIntegrity,
+	}
+	categories := make([]Category, len(builders))
+	for i, build := range builders {
+		categories[i] = build()
+		categories[i].Number = i + 1
+	}
+	return categories
+}
+
+// recommendations collects analyzer recommendations without duplicates
+func (d *analysisData) recommendations() []string {
+	e := d.enhanced
+	var lists [][]string
+	if e.CodecAnalysis != nil && e.CodecAnalysis.Validation != nil {
+		lists = append(lists, e.CodecAnalysis.Validation.Recommendations)
+	}
+	if e.ContainerAnalysis != nil && e.ContainerAnalysis.Validation != nil {
+		lists = append(lists, e.ContainerAnalysis.Validation.Recommendations)
+	}
+	if e.ResolutionAnalysis != nil && e.ResolutionAnalysis.Validation != nil {
+		lists = append(lists, e.ResolutionAnalysis.Validation.Recommendations)
+	}
+	if e.ColorConsistencyAnalysis != nil {
+		lists = append(lists, e.ColorConsistencyAnalysis.Recommendations)
+	}
+	if e.FrameRateAnalysis != nil && e.FrameRateAnalysis.Validation != nil {
+		lists = append(lists, e.FrameRateAnalysis.Validation.Recommendations)
+	}
+	if e.BitDepthAnalysis != nil && e.BitDepthAnalysis.Validation != nil {
+		lists = append(lists, e.BitDepthAnalysis.Validation.Recommendations)
+	}
+	if e.AFDAnalysis != nil && e.AFDAnalysis.ValidationResults != nil {
+		lists = append(lists, e.AFDAnalysis.ValidationResults.Recommendations)
+	}
+	if e.DeadPixelAnalysis != nil {
+		lists = append(lists, e.DeadPixelAnalysis.RecommendedActions)
+	}
+	if e.TimecodeAnalysis != nil && e.TimecodeAnalysis.TimecodeValidation != nil {
+		lists = append(lists, e.TimecodeAnalysis.TimecodeValidation.Recommendations)
+	}
+	if e.MXFAnalysis != nil && e.MXFAnalysis.IsMXFFile {
+		lists = append(lists, e.MXFAnalysis.RecommendedActions)
+	}
+	if e.TransportStreamAnalysis != nil && e.TransportStreamAnalysis.TransportValidation != nil {
+		lists = append(lists, e.TransportStreamAnalysis.TransportValidation.Recommendations)
+	}
+	if e.StreamDispositionAnalysis != nil && e.StreamDispositionAnalysis.Validation != nil {
+		lists = append(lists, e.StreamDispositionAnalysis.Validation.Recommendations)
+	}
+	if e.DataIntegrityAnalysis != nil && e.DataIntegrityAnalysis.Validation != nil {
+		lists = append(lists, e.DataIntegrityAnalysis.Validation.RequiredActions, e.DataIntegrityAnalysis.Validation.Recommendations)
+	}
+	var repairs []string
+	for _, repair := range d.result.RepairSuggestions {
+		repairs = append(repairs, fmt.Sprintf("%s: %s", repair.Title, repair.Command))
+	}
+	lists = append(lists, repairs)
+
+	seen := make(map[string]bool)
+	var recommendations []string
+	for _, list := range lists {
+		for _, recommendation := range list {
+			if recommendation != "" && !seen[recommendation] {
+				seen[recommendation] = true
+				recommendations = append(recommendations, recommendation)
+			}
+		}
+	}
+	return recommendations
+}
+
+// bitrateChart charts the bit rate of every stream that reports one
+func (d *analysisData) bitrateChart() (Chart, bool) {
+	chart := Chart{Title: "Bit rate by stream", Unit: "kb/s"}
+	for _, stream := range d.result.Streams {
+		rate, err := strconv.ParseFloat(stream.BitRate, 64)
+		if err != nil || rate <= 0 {
+			continue
+		}
+		chart.Bars = append(chart.Bars, Bar{
+			Label:    fmt.Sprintf("#%d %s (%s)", stream.Index, stream.CodecType, stream.CodecName),
+			Value:    rate / 1000,
+			Severity: SeverityInfo,
+		})
+	}
+	return chart, len(chart.Bars) > 0
+}
+
+// bitrateTimelines plots the bit rate of the first video stream over time
+// against the level's maximum and, when the decoder buffer was simulated,
+// its fullness
+func (d *analysisData) bitrateTimelines() []Timeline {
+	bitrate := d.enhanced.BitrateAnalysis
+	if bitrate == nil || len(bitrate.Timeline) < 2 {
+		return nil
+	}
+
+	rates := Timeline{
+		Title:    fmt.Sprintf("Video bit rate over time (stream #%d)", bitrate.StreamIndex),
+		Unit:     "kb/s",
+		Interval: bitrate.TimelineInterval,
+		Caption:  fmt.Sprintf("Average %.0f kb/s, peak %.0f kb/s", bitrate.AverageBitRate/1000, bitrate.PeakBitRate/1000),
+	}
+	for _, point := range bitrate.Timeline {
+		rates.Values = append(rates.Values, point.BitRate/1000)
+	}
+	timelines := []Timeline{rates}
+
+	if vbv := bitrate.VBV; vbv != nil {
+		timelines[0].Limit = vbv.MaxBitRate / 1000
+		timelines[0].Caption += fmt.Sprintf(", level %s maximum %.0f kb/s", vbv.Level, vbv.MaxBitRate/1000)
+
+		fullness := Timeline{
+			Title:    "VBV buffer fullness",
+			Unit:     "%",
+			Interval: bitrate.TimelineInterval,
+			Caption:  fmt.Sprintf("Lowest %.1f%% of %.0f kbit, %d underruns", vbv.MinFullnessPercent, vbv.BufferSize/1000, vbv.Underruns),
+		}
+		last := 100.0
+		for _, point := range bitrate.Timeline {
+			// Intervals without packets keep the last fullness
+			if point.BufferPercent != nil {
+				last = *point.BufferPercent
+			}
+			fullness.Values = append(fullness.Values, last)
+		}
+		timelines = append(timelines, fullness)
+	}
+	return timelines
+}
+
+// validationSeverity grades the common IsValid/Issues validation shape
+func validationSeverity(isValid bool, issues []string) Severity {
+	switch {
+	case !isValid:
+		return SeverityFail
+	case len(issues) > 0:
+		return SeverityWarning
+	default:
+		return SeverityPass
+	}
+}
+
+func notAnalyzed(name string) Category {
+	return Category{Name: name, Severity: SeverityNotAnalyzed}
+}
+
+func (d *analysisData) afd() Category {
+	const name = "AFD Analysis"
+	afd := d.enhanced.AFDAnalysis
+	if afd == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	c.Fields = append(c.Fields, Field{"AFD Present", yesNo(afd.HasAFD)})
+	if afd.PrimaryAFD != nil {
+		c.Fields = append(c.Fields, Field{"Primary AFD", fmt.Sprintf("%d - %s", afd.PrimaryAFD.AFDValue, afd.PrimaryAFD.AFDDescription)})
+	}
+	if afd.AspectRatioInfo != nil {
+		c.Fields = append(c.Fields, Field{"Display Aspect Ratio", orNA(afd.AspectRatioInfo.DisplayAspectRatio)})
+	}
+	c.Fields = append(c.Fields, Field{"AFD Segments", strconv.Itoa(len(afd.AFDSegments))})
+	for i, segment := range afd.AFDSegments {
+		if i == maxAFDSegmentFields {
+			c.Fields = append(c.Fields, Field{"More Segments", fmt.Sprintf("%d not shown", len(afd.AFDSegments)-i)})
+			break
+		}
+		c.Fields = append(c.Fields, Field{
+			fmt.Sprintf("Frames %d-%d", segment.StartFrame, segment.EndFrame),
+			fmt.Sprintf("AFD %d - %s", segment.AFDValue, segment.AFDDescription),
+		})
+	}
+
+	if validation := afd.ValidationResults; validation != nil {
+		c.Findings = append(c.Findings, validation.Issues...)
+		c.Findings = append(c.Findings, validation.Warnings...)
+	}
+	switch {
+	case !afd.HasAFD:
+		c.Severity = SeverityInfo
+	case afd.PrimaryAFD != nil && !afd.PrimaryAFD.IsValid:
+		c.Severity = SeverityFail
+	case len(afd.AFDChanges) > 0 || len(c.Findings) > 0:
+		c.Severity = SeverityWarning
+	}
+	return c
+}
+
+func (d *analysisData) deadPixels() Category {
+	const name = "Dead Pixel Detection"
+	dp := d.enhanced.DeadPixelAnalysis
+	if dp == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	c.Fields = []Field{
+		{"Dead Pixels", strconv.Itoa(dp.DeadPixelCount)},
+		{"Stuck Pixels", strconv.Itoa(dp.StuckPixelCount)},
+		{"Hot Pixels", strconv.Itoa(dp.HotPixelCount)},
+		{"Method", orNA(dp.AnalysisMethod)},
+		{"Confidence", fmt.Sprintf("%.0f%%", dp.DetectionConfidence)},
+	}
+	if impact := dp.QualityImpactAssessment; impact != nil && impact.ImpactDescription != "" {
+		c.Findings = append(c.Findings, impact.ImpactDescription)
+	}
+	switch {
+	case dp.DeadPixelCount > 0 || dp.HotPixelCount > 0:
+		c.Severity = SeverityFail
+	case dp.StuckPixelCount > 0:
+		c.Severity = SeverityWarning
+	}
+	return c
+}
+
+func (d *analysisData) pse() Category {
+	const name = "PSE Flash Analysis"
+	pse := d.enhanced.PSEAnalysis
+	if pse == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name}
+	c.Fields = []Field{
+		{"Risk Level", orNA(pse.PSERiskLevel)},
+		{"Flash Risk", orNA(pse.FlashRiskLevel)},
+		{"Red Flash Risk", orNA(pse.RedFlashRiskLevel)},
+		{"Pattern Risk", orNA(pse.PatternRiskLevel)},
+		{"Risk Score", fmt.Sprintf("%.1f / 100", pse.OverallRiskScore)},
+		{"Violations", strconv.Itoa(len(pse.ViolationInstances))},
+	}
+	if flash := pse.FlashAnalysis; flash != nil {
+		c.Fields = append(c.Fields, Field{"Max Flash Rate", fmt.Sprintf("%.1f / s (%d flashes)", flash.MaxFlashRate, flash.FlashCount)})
+	}
+	if red := pse.RedFlashAnalysis; red != nil {
+		c.Fields = append(c.Fields, Field{"Max Red Flash Rate", fmt.Sprintf("%.1f / s (%d flashes)", red.MaxRedFlashRate, red.RedFlashCount)})
+	}
+	if patterns := pse.PatternAnalysis; patterns != nil && patterns.ExceedsPatternThreshold {
+		c.Fields = append(c.Fields, Field{"Hazardous Patterns", fmt.Sprintf("%d segments, up to %.1f cycles/degree", len(patterns.PatternInstances), patterns.PatternFrequency)})
+	}
+	if pse.BroadcastCompliance != nil {
+		c.Findings = append(c.Findings, pse.BroadcastCompliance.NonCompliantReasons...)
+	}
+	switch strings.ToLower(pse.PSERiskLevel) {
+	case "safe":
+		c.Severity = SeverityPass
+	case "low":
+		c.Severity = SeverityInfo
+	case "medium":
+		c.Severity = SeverityWarning
+	case "high", "extreme":
+		c.Severity = SeverityFail
+	default:
+		c.Severity = SeverityInfo
+	}
+	return c
+}
+
+func (d *analysisData) hdr() Category {
+	const name = "HDR Analysis"
+	if d.video == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityInfo}
+	var hdr *ffmpeg.HDRAnalysis
+	if d.enhanced.ContentAnalysis != nil {
+		hdr = d.enhanced.ContentAnalysis.HDRAnalysis
+	}
+
+	isHDR := d.enhanced.BitDepthAnalysis != nil && d.enhanced.BitDepthAnalysis.IsHDR
+	if hdr != nil {
+		isHDR = hdr.IsHDR
+	}
+	c.Fields = append(c.Fields, Field{"HDR Content", yesNo(isHDR)})
+	if hdr != nil && hdr.HDRFormat != "" {
+		c.Fields = append(c.Fields, Field{"HDR Format", hdr.HDRFormat})
+	}
+	c.Fields = append(c.Fields,
+		Field{"Color Primaries", orNA(d.video.ColorPrimaries)},
+		Field{"Color Transfer", orNA(d.video.ColorTransfer)},
+		Field{"Color Space", orNA(d.video.ColorSpace)},
+		Field{"Color Range", orNA(d.video.ColorRange)},
+	)
+	color := d.enhanced.ColorConsistencyAnalysis
+	if color != nil {
+		c.Fields = append(c.Fields, Field{"Color Tags", passFailed(color.IsConsistent)})
+	}
+
+	if hdr != nil && hdr.MasteringDisplay != nil {
+		mastering := hdr.MasteringDisplay
+		c.Fields = append(c.Fields, Field{"Mastering Display", fmt.Sprintf("%s, %g-%g cd/m²", orNA(mastering.Gamut), mastering.MinDisplayLuminance, mastering.MaxDisplayLuminance)})
+	}
+	if hdr != nil && hdr.ContentLightLevel != nil {
+		c.Fields = append(c.Fields, Field{"MaxCLL / MaxFALL", fmt.Sprintf("%d / %d cd/m²", hdr.ContentLightLevel.MaxCLL, hdr.ContentLightLevel.MaxFALL)})
+	}
+	if hdr != nil && hdr.DolbyVision != nil {
+		dv := hdr.DolbyVision
+		c.Fields = append(c.Fields, Field{"Dolby Vision", fmt.Sprintf("Profile %d.%d level %d, RPU %s", dv.Profile, dv.BLSignalCompatibilityID, dv.Level, yesNo(dv.RPUPresent))})
+	}
+
+	if hdr != nil && hdr.IsHDR && hdr.Validation != nil {
+		for _, standard := range hdr.Validation.Standards {
+			c.Fields = append(c.Fields, Field{standard.Standard, passFailed(standard.Passed)})
+		}
+		c.Findings = append(c.Findings, hdr.Validation.Issues...)
+		c.Severity = validationSeverity(hdr.Validation.IsCompliant, hdr.Validation.Issues)
+	}
+	if color != nil && !color.IsConsistent {
+		c.Findings = append(c.Findings, color.Issues...)
+		if c.Severity.rank() < SeverityWarning.rank() {
+			c.Severity = SeverityWarning
+		}
+	}
+	return c
+}
+
+func (d *analysisData) audioWrapping() Category {
+	const name = "Audio Wrapping Analysis"
+	wrapping := d.enhanced.AudioWrappingAnalysis
+	if wrapping == nil && d.enhanced.BWFAnalysis == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	if wrapping != nil {
+		c.Fields = append(c.Fields, Field{"Audio Streams", strconv.Itoa(len(wrapping.AudioStreams))})
+	}
+	if d.audio != nil {
+		c.Fields = append(c.Fields,
+			Field{"Audio Codec", orNA(d.audio.CodecLongName)},
+			Field{"Sample Format", orNA(d.audio.SampleFmt)},
+		)
+	}
+	if wrapping != nil && wrapping.WrappingValidation != nil {
+		validation := wrapping.WrappingValidation
+		c.Fields = append(c.Fields, Field{"Compatibility", orNA(validation.CompatibilityLevel)})
+		c.Findings = append(c.Findings, validation.Issues...)
+		c.Findings = append(c.Findings, validation.Warnings...)
+		c.Severity = validationSeverity(validation.IsValid, c.Findings)
+	}
+	if bwf := d.enhanced.BWFAnalysis; bwf != nil {
+		c.Fields = append(c.Fields, Field{"Broadcast Wave", yesNo(bwf.HasBext)})
+		if bwf.Bext != nil {
+			c.Fields = append(c.Fields,
+				Field{"Originator", orNA(bwf.Bext.Originator)},
+				Field{"Origination", orNA(strings.TrimSpace(bwf.Bext.OriginationDate + " " + bwf.Bext.OriginationTime))},
+				Field{"Time Reference", orNA(bwf.Bext.TimeReferenceTimecode)},
+			)
+		}
+		c.Findings = append(c.Findings, bwf.Issues...)
+		c.Findings = append(c.Findings, bwf.Warnings...)
+		if !bwf.IsValid {
+			c.Severity = SeverityFail
+		} else if c.Severity == SeverityPass && len(c.Findings) > 0 {
+			c.Severity = SeverityWarning
+		}
+	}
+	return c
+}
+
+func (d *analysisData) endianness() Category {
+	const name = "Endianness Detection"
+	endianness := d.enhanced.EndiannessAnalysis
+	if endianness == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	c.Fields = append(c.Fields, Field{"Container Endianness", orNA(endianness.ContainerEndianness)})
+	if validation := endianness.EndiannessValidation; validation != nil {
+		c.Fields = append(c.Fields, Field{"Consistent", yesNo(validation.IsConsistent)})
+		c.Findings = append(c.Findings, validation.Issues...)
+		c.Findings = append(c.Findings, validation.Warnings...)
+		if validation.HasConflicts || len(c.Findings) > 0 {
+			c.Severity = SeverityWarning
+		}
+	}
+	return c
+}
+
+func (d *analysisData) codec() Category {
+	const name = "Codec Analysis"
+	codec := d.enhanced.CodecAnalysis
+	if codec == nil {
+		if d.enhanced.BitrateAnalysis == nil && d.enhanced.MezzanineAnalysis == nil {
+			return notAnalyzed(name)
+		}
+		c := Category{Name: name, Severity: SeverityPass}
+		d.addBitrate(&c)
+		d.addMezzanine(&c)
+		return c
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	for _, index := range sortedKeys(codec.VideoCodecs) {
+		info := codec.VideoCodecs[index]
+		c.Fields = append(c.Fields, Field{fmt.Sprintf("Video #%d", index), fmt.Sprintf("%s %s level %d", orNA(info.CodecFamily), info.Profile, info.Level)})
+		if seq := info.AV1; seq != nil {
+			value := fmt.Sprintf("%s profile, level %s, %s tier, %d-bit %s", seq.ProfileName, seq.Level, seq.Tier, seq.BitDepth, seq.ChromaSubsampling)
+			if seq.FilmGrain {
+				value += ", film grain"
+			}
+			c.Fields = append(c.Fields, Field{fmt.Sprintf("AV1 #%d", index), value})
+		}
+		if vvc := info.VVC; vvc != nil {
+			c.Fields = append(c.Fields, Field{fmt.Sprintf("VVC #%d", index), fmt.Sprintf("%s profile, level %s, %s tier, %s", vvc.Profile, vvc.Level, vvc.Tier, vvc.ChromaFormat)})
+		}
+		for _, note := range info.StreamingNotes {
+			c.Fields = append(c.Fields, Field{"Streaming Note", note})
+		}
+	}
+	for _, index := range sortedKeys(codec.AudioCodecs) {
+		c.Fields = append(c.Fields, Field{fmt.Sprintf("Audio #%d", index), orNA(codec.AudioCodecs[index].CodecLongName)})
+	}
+	c.Fields = append(c.Fields,
+		Field{"Modern Codecs", yesNo(codec.HasModernCodecs)},
+		Field{"Legacy Codecs", yesNo(codec.HasLegacyCodecs)},
+	)
+	if codec.Validation != nil {
+		c.Findings = codec.Validation.Issues
+		c.Severity = validationSeverity(codec.Validation.IsValid, codec.Validation.Issues)
+	}
+	d.addBitrate(&c)
+	d.addMezzanine(&c)
+	return c
+}
+
+// addBitrate adds the bit rate summary and VBV buffer simulation to the
+// codec category; underruns and overshoots are warnings
+func (d *analysisData) addBitrate(c *Category) {
+	bitrate := d.enhanced.BitrateAnalysis
+	if bitrate == nil {
+		return
+	}
+	c.Fields = append(c.Fields,
+		Field{"Average Bit Rate", formatBitRate(strconv.FormatFloat(bitrate.AverageBitRate, 'f', 0, 64))},
+		Field{"Peak Bit Rate", fmt.Sprintf("%s (%.2fx average)", formatBitRate(strconv.FormatFloat(bitrate.PeakBitRate, 'f', 0, 64)), bitrate.PeakToAverage)},
+	)
+
+	vbv := bitrate.VBV
+	if vbv == nil {
+		return
+	}
+	c.Fields = append(c.Fields,
+		Field{"VBV Model", fmt.Sprintf("%s level %s, %s, %.0f kbit buffer", vbv.Profile, vbv.Level, formatBitRate(strconv.FormatFloat(vbv.MaxBitRate, 'f', 0, 64)), vbv.BufferSize/1000)},
+		Field{"VBV Min Fullness", fmt.Sprintf("%.1f%%", vbv.MinFullnessPercent)},
+	)
+	if vbv.Compliant {
+		return
+	}
+
+	first := make(map[string]float64)
+	for _, event := range vbv.Events {
+		if _, ok := first[event.Kind]; !ok {
+			first[event.Kind] = event.Time
+		}
+	}
+	if vbv.Underruns > 0 {
+		c.Findings = append(c.Findings, fmt.Sprintf("%d VBV buffer underruns against %s level %s, first at %s", vbv.Underruns, vbv.Profile, vbv.Level, formatTimestamp(first[ffmpeg.VBVUnderrun])))
+	}
+	if vbv.Overshoots > 0 {
+		c.Findings = append(c.Findings, fmt.Sprintf("%d intervals exceed the level %s maximum bit rate, first at %s", vbv.Overshoots, vbv.Level, formatTimestamp(first[ffmpeg.VBVOvershoot])))
+	}
+	if c.Severity.rank() < SeverityWarning.rank() {
+		c.Severity = SeverityWarning
+	}
+}
+
+// addMezzanine adds the ProRes and DNxHD/DNxHR frame header conformance to
+// the codec category
+func (d *analysisData) addMezzanine(c *Category) {
+	mezzanine := d.enhanced.MezzanineAnalysis
+	if mezzanine == nil {
+		return
+	}
+	for _, stream := range mezzanine.Streams {
+		label := fmt.Sprintf("%s #%d", stream.Codec, stream.StreamIndex)
+		switch {
+		case stream.ProRes != nil:
+			p := stream.ProRes
+			c.Fields = append(c.Fields, Field{label, fmt.Sprintf("%s %s, %s alpha, qscale %d-%d (avg %.1f)", orNA(p.TagProfile), p.ChromaFormat, p.Alpha, p.MinQScale, p.MaxQScale, p.AverageQScale)})
+		case stream.DNxHD != nil:
+			x := stream.DNxHD
+			scan := "progressive"
+			if x.Interlaced {
+				scan = x.FieldOrder
+			}
+			c.Fields = append(c.Fields, Field{label, fmt.Sprintf("CID %d %s, %d-bit %s, %s", x.CompressionID, orNA(x.Family), x.BitDepth, x.ChromaFormat, scan)})
+		default:
+			c.Fields = append(c.Fields, Field{label, "Not read: " + orNA(stream.Error)})
+		}
+		c.Findings = append(c.Findings, stream.Issues...)
+		c.Findings = append(c.Findings, stream.Warnings...)
+	}
+	if !mezzanine.IsValid {
+		c.Severity = SeverityFail
+	} else if len(c.Findings) > 0 && c.Severity.rank() < SeverityWarning.rank() {
+		c.Severity = SeverityWarning
+	}
+}
+
+func (d *analysisData) container() Category {
+	const name = "Container Validation"
+	c := Category{Name: name, Severity: SeverityPass}
+	c.Fields = []Field{
+		{"Format", orNA(d.format.FormatName)},
+		{"Format Long Name", orNA(d.format.FormatLongName)},
+		{"Streams", strconv.Itoa(d.format.NBStreams)},
+		{"Probe Score", strconv.Itoa(d.format.ProbeScore)},
+	}
+
+	container := d.enhanced.ContainerAnalysis
+	if container == nil {
+		if d.result.Format == nil {
+			return notAnalyzed(name)
+		}
+		return c
+	}
+	c.Fields = append(c.Fields, Field{"Streaming Friendly", yesNo(container.IsStreamingFriendly)})
+	if container.Validation != nil {
+		c.Findings = container.Validation.Issues
+		c.Severity = validationSeverity(container.Validation.IsValid, container.Validation.Issues)
+	}
+	return c
+}
+
+func (d *analysisData) resolution() Category {
+	const name = "Resolution Analysis"
+	resolution := d.enhanced.ResolutionAnalysis
+	if resolution == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	c.Fields = []Field{
+		{"Resolution", fmt.Sprintf("%dx%d", resolution.MaxWidth, resolution.MaxHeight)},
+		{"Primary Resolution", orNA(resolution.PrimaryResolution)},
+		{"High Definition", yesNo(resolution.IsHighDefinition)},
+		{"Ultra High Definition", yesNo(resolution.IsUltraHighDefinition)},
+		{"Widescreen", yesNo(resolution.IsWidescreen)},
+	}
+	for _, index := range sortedKeys(resolution.VideoStreams) {
+		if video := resolution.VideoStreams[index]; video.IsAnamorphicSD {
+			c.Fields = append(c.Fields, Field{fmt.Sprintf("Anamorphic SD #%d", index),
+				fmt.Sprintf("%dx%d, square pixels at %dx%d", video.Width, video.Height, video.SquarePixelWidth, video.Height)})
+		}
+	}
+	if resolution.Validation != nil {
+		c.Findings = resolution.Validation.Issues
+		c.Severity = validationSeverity(resolution.Validation.IsValid, resolution.Validation.Issues)
+	}
+	return c
+}
+
+func (d *analysisData) frameRate() Category {
+	const name = "Frame Rate Analysis"
+	frameRate := d.enhanced.FrameRateAnalysis
+	if frameRate == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	c.Fields = []Field{
+		{"Frame Rate", fmt.Sprintf("%.3f fps", frameRate.MaxFrameRate)},
+		{"Standard", orNA(frameRate.PrimaryFrameRateStandard)},
+		{"Variable Frame Rate", yesNo(frameRate.IsVariableFrameRate)},
+		{"Interlaced", yesNo(frameRate.IsInterlaced)},
+	}
+	if frameRate.Validation != nil {
+		c.Findings = frameRate.Validation.Issues
+		c.Severity = validationSeverity(frameRate.Validation.IsValid, frameRate.Validation.Issues)
+	}
+	return c
+}
+
+func (d *analysisData) bitDepth() Category {
+	const name = "Bit Depth Analysis"
+	bitDepth := d.enhanced.BitDepthAnalysis
+	if bitDepth == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	c.Fields = []Field{
+		{"Max Video Bit Depth", fmt.Sprintf("%d-bit", bitDepth.MaxVideoBitDepth)},
+		{"Max Audio Bit Depth", fmt.Sprintf("%d-bit", bitDepth.MaxAudioBitDepth)},
+		{"High Bit Depth", yesNo(bitDepth.IsHighBitDepth)},
+	}
+	if d.video != nil {
+		c.Fields = append(c.Fields, Field{"Pixel Format", orNA(d.video.PixFmt)})
+	}
+	if bitDepth.Validation != nil {
+		c.Findings = bitDepth.Validation.Issues
+		c.Severity = validationSeverity(bitDepth.Validation.IsValid, bitDepth.Validation.Issues)
+	}
+	return c
+}
+
+func (d *analysisData) timecode() Category {
+	const name = "Timecode Analysis"
+	timecode := d.enhanced.TimecodeAnalysis
+	if timecode == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	c.Fields = append(c.Fields, Field{"Timecode Present", yesNo(timecode.HasTimecode)})
+	if !timecode.HasTimecode {
+		c.Severity = SeverityInfo
+		return c
+	}
+	if timecode.PrimaryTimecode != nil {
+		c.Fields = append(c.Fields,
+			Field{"Start Timecode", orNA(timecode.PrimaryTimecode.StartTimecode)},
+			Field{"Source", orNA(timecode.PrimaryTimecode.Format)},
+		)
+	}
+	c.Fields = append(c.Fields, Field{"Drop Frame", yesNo(timecode.IsDropFrame)})
+	if validation := timecode.TimecodeValidation; validation != nil {
+		c.Findings = validation.Issues
+		c.Severity = validationSeverity(validation.IsValid, validation.Issues)
+		if c.Severity == SeverityPass && validation.HasDiscontinuities {
+			c.Severity = SeverityWarning
+		}
+	}
+	return c
+}
+
+func (d *analysisData) mxf() Category {
+	const name = "MXF Analysis"
+	mxf := d.enhanced.MXFAnalysis
+	if mxf == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	c.Fields = append(c.Fields, Field{"MXF Container", yesNo(mxf.IsMXFFile)})
+	if !mxf.IsMXFFile {
+		c.Severity = SeverityInfo
+		return c
+	}
+	c.Fields = append(c.Fields, Field{"Profile", orNA(mxf.MXFProfile)})
+	if validation := mxf.ValidationResults; validation != nil {
+		c.Fields = append(c.Fields, Field{"Validation Score", fmt.Sprintf("%.0f / 100", validation.ValidationScore)})
+		c.Findings = append(c.Findings, validation.CriticalIssues...)
+		c.Findings = append(c.Findings, validation.Warnings...)
+		switch {
+		case len(validation.CriticalIssues) > 0 || !validation.OverallCompliance:
+			c.Severity = SeverityFail
+		case len(validation.Warnings) > 0:
+			c.Severity = SeverityWarning
+		}
+	}
+	return c
+}
+
+func (d *analysisData) imf() Category {
+	const name = "IMF Compliance"
+	if dcp := d.enhanced.DCPAnalysis; dcp != nil {
+		return dcpCompliance(name, dcp)
+	}
+	imf := d.enhanced.IMFAnalysis
+	if imf == nil {
+		return notAnalyzed(name)
+	}
+
+	// Files that are not packages still get an analysis, with no asset map
+	isPackage := imf.AssetMapAnalysis != nil
+	c := Category{Name: name, Severity: SeverityInfo}
+	c.Fields = append(c.Fields, Field{"IMF Package", yesNo(isPackage)})
+	if !isPackage {
+		return c
+	}
+	c.Fields = append(c.Fields, Field{"IMF Compliant", yesNo(imf.IsIMFCompliant)})
+	c.Fields = append(c.Fields, Field{"Profile", orNA(imf.IMFProfile)})
+	if cpl := imf.CPLAnalysis; cpl != nil && cpl.CPLExists {
+		c.Fields = append(c.Fields, Field{"Composition", orNA(cpl.CPLTitle)})
+		c.Fields = append(c.Fields, Field{"Edit Rate", orNA(cpl.EditRate)})
+		c.Fields = append(c.Fields, Field{"Running Time", orNA(cpl.TotalRunningTime)})
+	}
+	if imf.IsIMFCompliant {
+		c.Severity = SeverityPass
+	}
+	if validation := imf.ValidationResults; validation != nil {
+		c.Findings = append(c.Findings, validation.CriticalIssues...)
+		c.Findings = append(c.Findings, validation.Warnings...)
+		switch {
+		case len(validation.CriticalIssues) > 0:
+			c.Severity = SeverityFail
+		case len(validation.Warnings) > 0:
+			c.Severity = SeverityWarning
+		}
+	}
+	return c
+}
+
+// dcpCompliance reports a Digital Cinema Package, which shares the IMF
+// package layout, in the IMF category
+func dcpCompliance(name string, dcp *ffmpeg.DCPAnalysis) Category {
+	c := Category{Name: name, Severity: SeverityPass}
+	c.Fields = append(c.Fields, Field{"DCP", orNA(dcp.Standard)})
+	for _, cpl := range dcp.Compositions {
+		c.Fields = append(c.Fields,
+			Field{"Composition", orNA(cpl.ContentTitle)},
+			Field{"Content Kind", orNA(cpl.ContentKind)},
+			Field{"Edit Rate", orNA(cpl.EditRate)},
+			Field{"Reels", strconv.Itoa(len(cpl.Reels))},
+			Field{"Running Time", orNA(cpl.RunningTime)},
+			Field{"Encrypted", yesNo(cpl.Encrypted)},
+		)
+	}
+	if picture := dcp.Picture; picture != nil {
+		if j2k := picture.JPEG2000; j2k != nil {
+			c.Fields = append(c.Fields, Field{"Picture Essence", fmt.Sprintf("%s, %dx%d, %d-bit", j2k.Capabilities, j2k.Width, j2k.Height, j2k.BitDepth)})
+		}
+		c.Findings = append(c.Findings, picture.Issues...)
+		c.Findings = append(c.Findings, picture.Warnings...)
+	}
+	c.Findings = append(c.Findings, dcp.Issues...)
+	c.Findings = append(c.Findings, dcp.Warnings...)
+	switch {
+	case !dcp.IsValid:
+		c.Severity = SeverityFail
+	case len(c.Findings) > 0:
+		c.Severity = SeverityWarning
+	}
+	return c
+}
+
+func (d *analysisData) transportStream() Category {
+	const name = "Transport Stream Analysis"
+	ts := d.enhanced.TransportStreamAnalysis
+	if ts == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	c.Fields = append(c.Fields, Field{"Transport Stream", yesNo(ts.IsTransportStream)})
+	if !ts.IsTransportStream {
+		c.Severity = SeverityInfo
+		return c
+	}
+	c.Fields = append(c.Fields,
+		Field{"Programs", strconv.Itoa(len(ts.Programs))},
+		Field{"Video PIDs", strconv.Itoa(len(ts.VideoPIDs))},
+		Field{"Audio PIDs", strconv.Itoa(len(ts.AudioPIDs))},
+	)
+	if mux := ts.MuxStatistics; mux != nil {
+		mode := "VBR"
+		if mux.ConstantBitRate {
+			mode = "CBR"
+		}
+		c.Fields = append(c.Fields,
+			Field{"Mux Bitrate", fmt.Sprintf("%.3f Mbps (%s)", mux.MuxBitRate/1e6, mode)},
+			Field{"Null Packets", fmt.Sprintf("%.1f%%", mux.NullPercent)},
+		)
+	}
+	for _, pcr := range ts.PCRAnalysis {
+		c.Fields = append(c.Fields, Field{fmt.Sprintf("PCR 0x%04X", pcr.PID), fmt.Sprintf("max interval %.1f ms, max jitter %.0f ns", pcr.MaxIntervalMs, pcr.MaxJitterNs)})
+	}
+	if etr := ts.ETR290; etr != nil {
+		c.Fields = append(c.Fields,
+			Field{"TR 101 290 Priority 1", passFailed(etr.Priority1Passed)},
+			Field{"TR 101 290 Priority 2", passFailed(etr.Priority2Passed)},
+		)
+		for _, check := range etr.Checks {
+			if !check.Passed && len(check.Examples) > 0 {
+				c.Findings = append(c.Findings, fmt.Sprintf("%s %s: %s", check.ID, check.Name, check.Examples[0]))
+			}
+		}
+	}
+	if validation := ts.TransportValidation; validation != nil {
+		c.Findings = append(c.Findings, validation.Errors...)
+		c.Findings = append(c.Findings, validation.Warnings...)
+		switch {
+		case validation.HasErrors || !validation.IsValid:
+			c.Severity = SeverityFail
+		case validation.HasWarnings:
+			c.Severity = SeverityWarning
+		}
+	}
+	return c
+}
+
+func (d *analysisData) content() Category {
+	const name = "Content Analysis"
+	content := d.enhanced.ContentAnalysis
+	if content == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	if content.BlackFrames != nil {
+		c.Fields = append(c.Fields, Field{"Black Frames", fmt.Sprintf("%d (%.1f%%)", content.BlackFrames.DetectedFrames, content.BlackFrames.Percentage)})
+	}
+	if content.FreezeFrames != nil {
+		c.Fields = append(c.Fields, Field{"Frozen Frames", fmt.Sprintf("%d (%.1f%%)", content.FreezeFrames.DetectedFrames, content.FreezeFrames.Percentage)})
+		if content.FreezeFrames.DetectedFrames > 0 {
+			c.Findings = append(c.Findings, fmt.Sprintf("%d frozen frames detected", content.FreezeFrames.DetectedFrames))
+		}
+	}
+	if content.AudioClipping != nil {
+		c.Fields = append(c.Fields, Field{"Clipped Samples", strconv.Itoa(content.AudioClipping.ClippedSamples)})
+		if content.AudioClipping.ClippedSamples > 0 {
+			c.Findings = append(c.Findings, fmt.Sprintf("Audio clipping in %.2f%% of samples", content.AudioClipping.Percentage))
+		}
+	}
+	if content.SilenceInfo != nil {
+		c.Fields = append(c.Fields, Field{"Silence", fmt.Sprintf("%.1f s", content.SilenceInfo.TotalSilenceSec)})
+		if content.SilenceInfo.HasProblematicMute {
+			c.Findings = append(c.Findings, fmt.Sprintf("Problematic mute, longest %.1f s", content.SilenceInfo.LongestSilenceSec))
+		}
+	}
+	if loudness := content.LoudnessMeter; loudness != nil {
+		c.Fields = append(c.Fields,
+			Field{"Integrated Loudness", fmt.Sprintf("%.1f LUFS", loudness.IntegratedLoudness)},
+			Field{"True Peak", fmt.Sprintf("%.1f dBTP", loudness.TruePeak)},
+		)
+		if loudness.Dialog != nil && loudness.Dialog.IntegratedLoudness != nil {
+			c.Fields = append(c.Fields, Field{"Dialog Loudness", fmt.Sprintf("%.1f LUFS (%.0f%% speech)", *loudness.Dialog.IntegratedLoudness, loudness.Dialog.SpeechPercentage)})
+		}
+		if !loudness.Compliant {
+			c.Findings = append(c.Findings, fmt.Sprintf("Loudness not compliant with %s", orNA(loudness.Standard)))
+		}
+	}
+	c.Fields, c.Findings = audioStreamFields(content.AudioStreams, c.Fields, c.Findings)
+	if len(c.Findings) > 0 {
+		c.Severity = SeverityWarning
+	}
+	// Audio that does not match its declared layout or language fails
+	for _, stream := range content.AudioStreams {
+		for _, check := range stream.MetadataChecks {
+			if !check.Passed {
+				c.Severity = SeverityFail
+			}
+		}
+	}
+	return c
+}
+
+// audioStreamFields adds one field per separately measured audio stream,
+// in stream order, and the streams' loudness, mute, phase and declared
+// metadata findings
+func audioStreamFields(streams map[int]*ffmpeg.AudioStreamAnalysis, fields []Field, findings []string) ([]Field, []string) {
+	for _, index := range sortedKeys(streams) {
+		stream := streams[index]
+		label := fmt.Sprintf("Audio Stream %d", index)
+		if stream.Language != "" {
+			label += " (" + stream.Language + ")"
+		}
+
+		var values []string
+		if stream.Loudness != nil {
+			values = append(values, fmt.Sprintf("%.1f LUFS, %.1f dBTP", stream.Loudness.IntegratedLoudness, stream.Loudness.TruePeak))
+			if !stream.Loudness.Compliant {
+				findings = append(findings, fmt.Sprintf("%s loudness not compliant with %s", label, orNA(stream.Loudness.Standard)))
+			}
+		}
+		if stream.Silence != nil {
+			values = append(values, fmt.Sprintf("%.1f s silence", stream.Silence.TotalSilenceSec))
+			if stream.Silence.HasProblematicMute {
+				findings = append(findings, fmt.Sprintf("%s problematic mute, longest %.1f s", label, stream.Silence.LongestSilenceSec))
+			}
+		}
+		if stream.Phase != nil && stream.Phase.HasPhaseIssues {
+			findings = append(findings, fmt.Sprintf("%s out of phase %.1f%% of the time", label, stream.Phase.OutOfPhasePercent))
+		}
+		for _, channel := range stream.ChannelSilence {
+			if channel.Silence != nil && channel.Silence.HasProblematicMute {
+				findings = append(findings, fmt.Sprintf("%s channel %s problematic mute, longest %.1f s", label, channel.Name, channel.Silence.LongestSilenceSec))
+			}
+		}
+		for _, pair := range stream.ChannelPairs {
+			if pair.Phase != nil && pair.Phase.HasPhaseIssues {
+				findings = append(findings, fmt.Sprintf("%s %s out of phase %.1f%% of the time", label, pair.Pair, pair.Phase.OutOfPhasePercent))
+			}
+		}
+		for _, check := range stream.MetadataChecks {
+			if !check.Passed {
+				findings = append(findings, fmt.Sprintf("%s: %s", label, check.Message))
+			}
+		}
+		for _, err := range stream.Errors {
+			findings = append(findings, fmt.Sprintf("%s: %s", label, err))
+		}
+		if len(values) == 0 {
+			values = append(values, "N/A")
+		}
+		fields = append(fields, Field{label, strings.Join(values, ", ")})
+	}
+	return fields, findings
+}
+
+func (d *analysisData) enhancedSummary() Category {
+	const name = "Enhanced Analysis"
+	counts := d.enhanced.StreamCounts
+	if counts == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	c.Fields = []Field{
+		{"Total Streams", strconv.Itoa(counts.TotalStreams)},
+		{"Video Streams", strconv.Itoa(counts.VideoStreams)},
+		{"Audio Streams", strconv.Itoa(counts.AudioStreams)},
+		{"Subtitle Streams", strconv.Itoa(counts.SubtitleStreams)},
+	}
+	if video := d.enhanced.VideoAnalysis; video != nil && video.ChromaSubsampling != nil {
+		c.Fields = append(c.Fields, Field{"Chroma Subsampling", *video.ChromaSubsampling})
+	}
+	return c
+}
+
+func (d *analysisData) disposition() Category {
+	const name = "Stream Disposition Analysis"
+	disposition := d.enhanced.StreamDispositionAnalysis
+	if disposition == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	c.Fields = []Field{
+		{"Main Streams", yesNo(disposition.HasMainStreams)},
+		{"Forced Subtitles", yesNo(disposition.HasForcedSubtitles)},
+		{"SDH Subtitles", yesNo(disposition.HasSDHSubtitles)},
+		{"Descriptive Audio", yesNo(disposition.HasDescriptiveAudio)},
+		{"Accessibility Score", fmt.Sprintf("%d / 100", disposition.AccessibilityScore)},
+		{"Packaging Rules", packagingRules(disposition)},
+	}
+	if disposition.Validation != nil {
+		c.Findings = disposition.Validation.Issues
+		c.Severity = validationSeverity(disposition.Validation.IsValid, disposition.Validation.Issues)
+	}
+	return c
+}
+
+// packagingRules summarizes the disposition policy violations
+func packagingRules(disposition *ffmpeg.StreamDispositionAnalysis) string {
+	switch n := len(disposition.PolicyViolations); {
+	case n == 0:
+		return "Compliant"
+	case n == 1:
+		return "1 violation"
+	default:
+		return fmt.Sprintf("%d violations", n)
+	}
+}
+
+// maxDecodeErrorFindings bounds the decode errors listed as findings
+const maxDecodeErrorFindings = 10
+
+// timestampKinds lists the packet timestamp discontinuities in report order
+var timestampKinds = []string{
+	ffmpeg.TimestampGap,
+	ffmpeg.TimestampJump,
+	ffmpeg.TimestampNegativeDelta,
+	ffmpeg.TimestampDuplicateDTS,
+	ffmpeg.TimestampDuplicatePTS,
+	ffmpeg.TimestampPTSBeforeDTS,
+}
+
+func (d *analysisData) dataIntegrity() Category {
+	const name = "Data Integrity Analysis"
+	integrity := d.enhanced.DataIntegrityAnalysis
+	timestamps := d.enhanced.TimestampAnalysis
+	if integrity == nil && timestamps == nil {
+		return notAnalyzed(name)
+	}
+
+	c := Category{Name: name, Severity: SeverityPass}
+	if integrity != nil {
+		c.Fields = []Field{
+			{"Integrity Score", fmt.Sprintf("%d / 100", integrity.IntegrityScore)},
+			{"Format Errors", strconv.Itoa(integrity.FormatErrors)},
+			{"Bitstream Errors", strconv.Itoa(integrity.BitstreamErrors)},
+			{"Packet Errors", strconv.Itoa(integrity.PacketErrors)},
+			{"Continuity Errors", strconv.Itoa(integrity.ContinuityErrors)},
+			{"Corrupted", yesNo(integrity.IsCorrupted)},
+		}
+		if integrity.Validation != nil {
+			c.Findings = integrity.Validation.Issues
+		}
+		if scan := integrity.DecodeScan; scan != nil {
+			c.Fields = append(c.Fields,
+				Field{"Decode Errors", strconv.Itoa(scan.DecodeErrors)},
+				Field{"Corrupt Frames", strconv.Itoa(scan.CorruptFrames)},
+				Field{"Decoded Duration", fmt.Sprintf("%.2f s", scan.DecodedSeconds)})
+			for i, decodeError := range scan.Errors {
+				if i == maxDecodeErrorFindings {
+					c.Findings = append(c.Findings, fmt.Sprintf("%d more decode errors", len(scan.Errors)-i))
+					break
+				}
+				c.Findings = append(c.Findings, fmt.Sprintf("%.2fs: %s", decodeError.Timestamp, decodeError.Message))
+			}
+		}
+		if check := integrity.MediaInfoCrossCheck; check != nil {
+			c.Fields = append(c.Fields, Field{"MediaInfo Discrepancies", strconv.Itoa(check.Discrepancies)})
+			for _, comparison := range check.Comparisons {
+				if !comparison.Match {
+					c.Findings = append(c.Findings, fmt.Sprintf("MediaInfo cross-check: %s (ffprobe %s, MediaInfo %s)", comparison.Message, comparison.FFprobe, comparison.MediaInfo))
+				}
+			}
+		}
+		switch {
+		case integrity.IsCorrupted:
+			c.Severity = SeverityFail
+		case integrity.FormatErrors+integrity.BitstreamErrors+integrity.PacketErrors+integrity.ContinuityErrors > 0 || len(c.Findings) > 0:
+			c.Severity = SeverityWarning
+		}
+	}
+
+	if timestamps != nil {
+		c.Fields = append(c.Fields, Field{"Timestamp Discontinuities", strconv.Itoa(timestamps.TotalDiscontinuities)})
+		for _, stream := range timestamps.Streams {
+			for _, kind := range timestampKinds {
+				if count := stream.Discontinuities[kind]; count > 0 {
+					c.Findings = append(c.Findings, fmt.Sprintf("Stream %d: %d %s", stream.StreamIndex, count, strings.ReplaceAll(kind, "_", " ")))
+				}
+			}
+		}
+		if timestamps.HasDiscontinuities && c.Severity == SeverityPass {
+			c.Severity = SeverityWarning
+		}
+	}
+	return c
+}
@@ -0,0 +1,152 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// Comparison areas, in the order differences are listed
+const (
+	AreaContainer  = "container"
+	AreaCodec      = "codec"
+	AreaQuality    = "quality"
+	AreaLoudness   = "loudness"
+	AreaCompliance = "compliance"
+)
+
+// Difference is one property whose value differs between two analyses
+type Difference struct {
+	Area     string `json:"area"`
+	Property string `json:"property"`
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+}
+
+// comparedField is one property of a single analysis, listed in the same
+// order for every analysis so two lists can be compared index by index
+type comparedField struct {
+	area  string
+	field Field
+}
+
+// Diff lists the properties that differ between a source and a target
+// analysis, e.g. a master and its transcode. Besides container, codec,
+// quality and loudness properties it includes every QC category whose
+// severity changed, under the compliance area.
+func Diff(source, target *ffmpeg.FFprobeResult) []Difference {
+	sourceFields := newAnalysisData(source).comparedFields()
+	targetFields := newAnalysisData(target).comparedFields()
+
+	var differences []Difference
+	for i, field := range sourceFields {
+		if value := targetFields[i].field.Value; value != field.field.Value {
+			differences = append(differences, Difference{
+				Area:     field.area,
+				Property: field.field.Label,
+				Source:   field.field.Value,
+				Target:   value,
+			})
+		}
+	}
+	return differences
+}
+
+// comparedFields lists the properties Diff compares. Missing values are N/A.
+func (d *analysisData) comparedFields() []comparedField {
+	var fields []comparedField
+	add := func(area, label, value string) {
+		fields = append(fields, comparedField{area, Field{label, orNA(value)}})
+	}
+
+	add(AreaContainer, "Container", d.format.FormatLongName)
+	add(AreaContainer, "Duration", formatDuration(d.format.Duration))
+	add(AreaContainer, "File Size", formatBytes(d.format.Size))
+	add(AreaContainer, "Overall Bit Rate", formatBitRate(d.format.BitRate))
+
+	var video, audio ffmpeg.StreamInfo
+	if d.video != nil {
+		video = *d.video
+	}
+	if d.audio != nil {
+		audio = *d.audio
+	}
+	add(AreaCodec, "Video Codec", strings.TrimSpace(video.CodecName+" "+video.Profile))
+	if d.video != nil {
+		add(AreaCodec, "Resolution", fmt.Sprintf("%dx%d", video.Width, video.Height))
+	} else {
+		add(AreaCodec, "Resolution", "")
+	}
+	add(AreaCodec, "Frame Rate", video.AvgFrameRate)
+	add(AreaCodec, "Pixel Format", video.PixFmt)
+	add(AreaCodec, "Video Bit Rate", optionalBitRate(video.BitRate))
+	add(AreaCodec, "Audio Codec", strings.TrimSpace(audio.CodecName+" "+audio.Profile))
+	channels := ""
+	if d.audio != nil {
+		channels = strconv.Itoa(audio.Channels)
+		if audio.ChannelLayout != "" {
+			channels += " (" + audio.ChannelLayout + ")"
+		}
+	}
+	add(AreaCodec, "Audio Channels", channels)
+	add(AreaCodec, "Sample Rate", audio.SampleRate)
+	add(AreaCodec, "Audio Bit Rate", optionalBitRate(audio.BitRate))
+
+	var content ffmpeg.ContentAnalysis
+	if d.enhanced.ContentAnalysis != nil {
+		content = *d.enhanced.ContentAnalysis
+	}
+	score := ""
+	if quality := content.VideoQualityScore; quality != nil {
+		score = fmt.Sprintf("%.1f", quality.OverallScore)
+		if quality.QualityClass != "" {
+			score += " (" + quality.QualityClass + ")"
+		}
+	}
+	add(AreaQuality, "Quality Score", score)
+	add(AreaQuality, "Bit Depth", video.BitsPerRawSample)
+	add(AreaQuality, "Color Primaries", video.ColorPrimaries)
+	add(AreaQuality, "Color Transfer", video.ColorTransfer)
+
+	var integrated, loudnessRange, truePeak, loudnessStatus string
+	if loudness := content.LoudnessMeter; loudness != nil {
+		integrated = fmt.Sprintf("%.1f LUFS", loudness.IntegratedLoudness)
+		loudnessRange = fmt.Sprintf("%.1f LU", loudness.LoudnessRange)
+		truePeak = fmt.Sprintf("%.1f dBTP", loudness.TruePeak)
+		loudnessStatus = "Not compliant"
+		if loudness.Compliant {
+			loudnessStatus = "Compliant"
+		}
+		if loudness.Standard != "" {
+			loudnessStatus += " (" + loudness.Standard + ")"
+		}
+	}
+	add(AreaLoudness, "Integrated Loudness", integrated)
+	add(AreaLoudness, "Loudness Range", loudnessRange)
+	add(AreaLoudness, "True Peak", truePeak)
+	add(AreaLoudness, "Loudness Compliance", loudnessStatus)
+
+	delivery := ""
+	if compliance := d.enhanced.DeliveryCompliance; compliance != nil {
+		delivery = compliance.ProfileName + ": Compliant"
+		if !compliance.Compliant {
+			delivery = fmt.Sprintf("%s: %d checks failed", compliance.ProfileName, compliance.Failed)
+		}
+	}
+	add(AreaCompliance, "Delivery Profile", delivery)
+	for _, category := range d.categories() {
+		add(AreaCompliance, category.Name, category.Severity.Label())
+	}
+
+	return fields
+}
+
+// optionalBitRate formats a bit rate, leaving a missing one empty
+func optionalBitRate(value string) string {
+	if value == "" {
+		return ""
+	}
+	return formatBitRate(value)
+}
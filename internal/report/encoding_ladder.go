@@ -0,0 +1,212 @@
+package report
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// Errors returned by RecommendLadder
+var (
+	ErrNoVideoStream = errors.New("analysis has no video stream")
+	ErrNoComplexity  = errors.New("analysis has no temporal complexity measurement; run content analysis")
+)
+
+// Normalization of complexity measurements to 0-1. SI and TI of typical
+// broadcast content stay below these; YDIF is only used without SITI.
+const (
+	maxSpatialInformation  = 100.0
+	maxTemporalInformation = 40.0
+	maxYDIF                = 30.0
+)
+
+// referenceRung is an H.264 rung of a reference ladder for content of medium
+// complexity at up to 30 fps
+type referenceRung struct {
+	height      int
+	bitrateKbps float64
+}
+
+// referenceLadder follows common HLS authoring ladders, from the top down
+var referenceLadder = []referenceRung{
+	{2160, 16000},
+	{1440, 9000},
+	{1080, 5000},
+	{720, 3000},
+	{540, 2000},
+	{432, 1100},
+	{360, 730},
+	{234, 365},
+}
+
+// LadderRecommendation is a per-title ABR ladder derived from the spatial
+// and temporal complexity of an analysis. Bitrates are for H.264; HEVC
+// needs roughly 60% of them.
+type LadderRecommendation struct {
+	SourceWidth     int     `json:"source_width"`
+	SourceHeight    int     `json:"source_height"`
+	SourceFrameRate float64 `json:"source_frame_rate"`
+
+	Complexity          float64 `json:"complexity"`       // 0-1, 0.5 matches the reference ladder
+	ComplexityClass     string  `json:"complexity_class"` // low, medium or high
+	Basis               string  `json:"basis"`            // siti, or ydif when SI/TI was not measured
+	SpatialInformation  float64 `json:"spatial_information,omitempty"`
+	TemporalInformation float64 `json:"temporal_information,omitempty"`
+
+	CRF   CRFEstimate  `json:"crf"`
+	Rungs []LadderRung `json:"rungs"`
+}
+
+// CRFEstimate is the constant rate factor expected to give transparent
+// quality for the title
+type CRFEstimate struct {
+	X264 int `json:"x264"`
+	X265 int `json:"x265"`
+}
+
+// LadderRung is one rendition of the recommended ladder
+type LadderRung struct {
+	Width          int     `json:"width"`
+	Height         int     `json:"height"`
+	FrameRate      float64 `json:"frame_rate"`
+	BitrateKbps    int     `json:"bitrate_kbps"`     // Target average bitrate
+	MinBitrateKbps int     `json:"min_bitrate_kbps"` // Lowest average bitrate worth encoding
+	MaxBitrateKbps int     `json:"max_bitrate_kbps"` // VBV maxrate; more motion needs more peak headroom
+}
+
+// RecommendLadder derives a per-title ABR ladder from the first video stream
+// of an analysis and its temporal complexity. The reference ladder is scaled
+// by complexity and frame rate; rungs above the source resolution are left
+// out.
+func RecommendLadder(result *ffmpeg.FFprobeResult) (*LadderRecommendation, error) {
+	var video *ffmpeg.StreamInfo
+	for i := range result.Streams {
+		if strings.EqualFold(result.Streams[i].CodecType, "video") && result.Streams[i].Height > 0 {
+			video = &result.Streams[i]
+			break
+		}
+	}
+	if video == nil {
+		return nil, ErrNoVideoStream
+	}
+
+	var complexity *ffmpeg.TemporalComplexityAnalysis
+	if enhanced := result.EnhancedAnalysis; enhanced != nil && enhanced.ContentAnalysis != nil {
+		complexity = enhanced.ContentAnalysis.TemporalComplexity
+	}
+	if complexity == nil || (complexity.SITI == nil && complexity.FramesAnalyzed == 0) {
+		return nil, ErrNoComplexity
+	}
+
+	recommendation := &LadderRecommendation{
+		SourceWidth:     video.Width,
+		SourceHeight:    video.Height,
+		SourceFrameRate: parseRate(video.AvgFrameRate),
+	}
+	if recommendation.SourceFrameRate <= 0 {
+		recommendation.SourceFrameRate = parseRate(video.RFrameRate)
+	}
+	if recommendation.SourceFrameRate <= 0 {
+		recommendation.SourceFrameRate = defaultMarkerFrameRate
+	}
+
+	var motion float64
+	if siti := complexity.SITI; siti != nil {
+		recommendation.Basis = "siti"
+		recommendation.SpatialInformation = siti.SpatialAverage
+		recommendation.TemporalInformation = siti.TemporalAverage
+		motion = clamp01(siti.TemporalAverage / maxTemporalInformation)
+		recommendation.Complexity = (clamp01(siti.SpatialAverage/maxSpatialInformation) + motion) / 2
+	} else {
+		recommendation.Basis = "ydif"
+		motion = clamp01(complexity.AverageComplexity / maxYDIF)
+		recommendation.Complexity = motion
+	}
+	recommendation.Complexity = math.Round(recommendation.Complexity*100) / 100
+
+	switch {
+	case recommendation.Complexity < 0.35:
+		recommendation.ComplexityClass = "low"
+	case recommendation.Complexity < 0.65:
+		recommendation.ComplexityClass = "medium"
+	default:
+		recommendation.ComplexityClass = "high"
+	}
+
+	x264 := int(math.Round(27 - 6*recommendation.Complexity))
+	recommendation.CRF = CRFEstimate{X264: x264, X265: x264 + 5}
+
+	aspect := float64(video.Width) / float64(video.Height)
+	if dar := parseAspectRatio(video.DisplayAspectRatio); dar > 0 {
+		aspect = dar
+	}
+	// Complexity 0.5 keeps the reference bitrates, 0 halves them and 1
+	// adds half
+	scale := 0.5 + recommendation.Complexity
+	for _, reference := range referenceLadder {
+		if reference.height > video.Height {
+			continue
+		}
+		rung := LadderRung{
+			Width:     evenDimension(float64(reference.height) * aspect),
+			Height:    reference.height,
+			FrameRate: recommendation.SourceFrameRate,
+		}
+		// Small renditions of high frame rate sources play at half rate
+		if rung.FrameRate > 30 && rung.Height <= 360 {
+			rung.FrameRate /= 2
+		}
+		bitrate := reference.bitrateKbps * scale * frameRateFactor(rung.FrameRate)
+		rung.BitrateKbps = roundKbps(bitrate)
+		rung.MinBitrateKbps = roundKbps(bitrate * 0.7)
+		rung.MaxBitrateKbps = roundKbps(bitrate * (1.25 + 0.5*motion))
+		recommendation.Rungs = append(recommendation.Rungs, rung)
+	}
+	if len(recommendation.Rungs) == 0 {
+		// Sources smaller than the lowest reference rung get a single rung
+		smallest := referenceLadder[len(referenceLadder)-1]
+		pixels := float64(video.Width*video.Height) / (float64(smallest.height) * float64(smallest.height) * 16 / 9)
+		bitrate := smallest.bitrateKbps * pixels * scale * frameRateFactor(recommendation.SourceFrameRate)
+		recommendation.Rungs = append(recommendation.Rungs, LadderRung{
+			Width:          video.Width,
+			Height:         video.Height,
+			FrameRate:      recommendation.SourceFrameRate,
+			BitrateKbps:    roundKbps(bitrate),
+			MinBitrateKbps: roundKbps(bitrate * 0.7),
+			MaxBitrateKbps: roundKbps(bitrate * (1.25 + 0.5*motion)),
+		})
+	}
+	return recommendation, nil
+}
+
+// frameRateFactor scales reference bitrates, which assume at most 30 fps;
+// 60 fps needs half as much again
+func frameRateFactor(fps float64) float64 {
+	if fps <= 30 {
+		return 1
+	}
+	return 1 + 0.5*(fps-30)/30
+}
+
+// parseAspectRatio parses a display aspect ratio such as "16:9"
+func parseAspectRatio(ratio string) float64 {
+	if ratio == "" || ratio == "0:1" {
+		return 0
+	}
+	return parseRate(strings.Replace(ratio, ":", "/", 1))
+}
+
+func evenDimension(value float64) int {
+	return int(math.Round(value/2)) * 2
+}
+
+// roundKbps rounds bitrates to 10 kbps, never below 10
+func roundKbps(kbps float64) int {
+	return int(math.Max(math.Round(kbps/10), 1)) * 10
+}
+
+func clamp01(value float64) float64 {
+	return math.Max(0, math.Min(1, value))
+}
@@ -0,0 +1,176 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// FlatResult is one analysis to serialize as flat rows. Result is nil when
+// the analysis failed; the row then carries only the file and error.
+type FlatResult struct {
+	Source
+	Result *ffmpeg.FFprobeResult
+	Error  string
+}
+
+// flatFileColumns are repeated on every row of a file
+var flatFileColumns = []string{
+	"analysis_id", "filename", "analyzed_at", "status", "error", "overall",
+	"failed_categories", "warning_categories", "delivery_profile", "delivery_compliant",
+	"container", "duration_seconds", "size_bytes", "bit_rate", "nb_streams",
+	"integrated_loudness_lufs", "true_peak_dbtp",
+}
+
+// flatStreamColumns describe the stream of a row
+var flatStreamColumns = []string{
+	"stream_index", "codec_type", "codec_name", "profile", "width", "height",
+	"display_aspect_ratio", "frame_rate", "pix_fmt", "field_order",
+	"color_primaries", "color_transfer", "stream_bit_rate", "sample_rate",
+	"channels", "channel_layout", "language", "title", "stream_duration_seconds",
+}
+
+// FlatColumns are the columns of the flattened result set: the file-level
+// columns followed by those of one stream
+var FlatColumns = append(append([]string{}, flatFileColumns...), flatStreamColumns...)
+
+// IsFlat reports whether the format serializes flat rows rather than a
+// rendered report
+func (f Format) IsFlat() bool {
+	return f == FormatCSV || f == FormatXML
+}
+
+// ParseFlatFormat resolves a flat output format name
+func ParseFlatFormat(name string) (Format, error) {
+	switch format := Format(name); format {
+	case FormatCSV, FormatXML:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported flat format %q (use csv or xml)", name)
+	}
+}
+
+// FlatRows flattens one analysis into rows of FlatColumns, one per stream,
+// or a single row without stream columns when it has no streams
+func FlatRows(result FlatResult) [][]string {
+	file := []string{result.AnalysisID, result.Filename, "", "success", result.Error}
+	if !result.AnalyzedAt.IsZero() {
+		file[2] = result.AnalyzedAt.UTC().Format(time.RFC3339)
+	}
+	if result.Result == nil {
+		file[3] = "error"
+		return [][]string{append(file, make([]string, len(FlatColumns)-len(file))...)}
+	}
+
+	qc := Build(result.Source, result.Result, nil)
+	counts := qc.SeverityCounts()
+	file = append(file, string(qc.Overall), strconv.Itoa(counts[SeverityFail]), strconv.Itoa(counts[SeverityWarning]))
+
+	data := newAnalysisData(result.Result)
+	if compliance := data.enhanced.DeliveryCompliance; compliance != nil {
+		file = append(file, compliance.Profile, strconv.FormatBool(compliance.Compliant))
+	} else {
+		file = append(file, "", "")
+	}
+	file = append(file, data.format.FormatName, data.format.Duration, data.format.Size, data.format.BitRate, optionalInt(data.format.NBStreams))
+	if content := data.enhanced.ContentAnalysis; content != nil && content.LoudnessMeter != nil {
+		file = append(file, formatFloat(content.LoudnessMeter.IntegratedLoudness), formatFloat(content.LoudnessMeter.TruePeak))
+	} else {
+		file = append(file, "", "")
+	}
+
+	if len(result.Result.Streams) == 0 {
+		return [][]string{append(file, make([]string, len(flatStreamColumns))...)}
+	}
+	rows := make([][]string, 0, len(result.Result.Streams))
+	for _, stream := range result.Result.Streams {
+		row := append(append([]string{}, file...),
+			strconv.Itoa(stream.Index), stream.CodecType, stream.CodecName, stream.Profile,
+			optionalInt(stream.Width), optionalInt(stream.Height), stream.DisplayAspectRatio,
+			stream.AvgFrameRate, stream.PixFmt, stream.FieldOrder,
+			stream.ColorPrimaries, stream.ColorTransfer, stream.BitRate, stream.SampleRate,
+			optionalInt(stream.Channels), stream.ChannelLayout, stream.Tags["language"], stream.Tags["title"],
+			stream.Duration,
+		)
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// RenderFlat writes analyses as CSV with a header row, or as XML with one
+// <row> element per row and one child element per column
+func RenderFlat(w io.Writer, format Format, results ...FlatResult) error {
+	switch format {
+	case FormatCSV:
+		return renderCSV(w, results)
+	case FormatXML:
+		return renderXML(w, results)
+	default:
+		return fmt.Errorf("unsupported flat format %q", format)
+	}
+}
+
+func renderCSV(w io.Writer, results []FlatResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(FlatColumns); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if err := writer.WriteAll(FlatRows(result)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func renderXML(w io.Writer, results []FlatResult) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+
+	root := xml.StartElement{Name: xml.Name{Local: "analysis_results"}}
+	row := xml.StartElement{Name: xml.Name{Local: "row"}}
+	if err := encoder.EncodeToken(root); err != nil {
+		return err
+	}
+	for _, result := range results {
+		for _, values := range FlatRows(result) {
+			if err := encoder.EncodeToken(row); err != nil {
+				return err
+			}
+			for i, value := range values {
+				// Empty columns are left out rather than written as empty elements
+				if value == "" {
+					continue
+				}
+				column := xml.StartElement{Name: xml.Name{Local: FlatColumns[i]}}
+				if err := encoder.EncodeElement(value, column); err != nil {
+					return err
+				}
+			}
+			if err := encoder.EncodeToken(row.End()); err != nil {
+				return err
+			}
+		}
+	}
+	if err := encoder.EncodeToken(root.End()); err != nil {
+		return err
+	}
+	if err := encoder.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
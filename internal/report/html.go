@@ -0,0 +1,175 @@
+package report
+
+import (
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"thumbnailSrc": func(data []byte) template.URL {
+		// Only JPEG produced by the thumbnail extractor reaches this point
+		return template.URL("data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(data))
+	},
+	"timestamp": formatTimestamp,
+	"barWidth": func(chart Chart, value float64) string {
+		maxValue := chart.Max()
+		if maxValue <= 0 {
+			return "0%"
+		}
+		return fmt.Sprintf("%.1f%%", value/maxValue*100)
+	},
+	"number": func(value float64) string {
+		return fmt.Sprintf("%.0f", value)
+	},
+	"polyline": func(t Timeline) string {
+		var b strings.Builder
+		for i, point := range t.Points() {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "%.1f,%.1f", point[0], point[1])
+		}
+		return b.String()
+	},
+	"limitY": func(t Timeline) string {
+		if y, ok := t.LimitY(); ok {
+			return fmt.Sprintf("%.1f", y)
+		}
+		return ""
+	},
+	"datetime": func(r *Report) string {
+		if r.AnalyzedAt.IsZero() {
+			return r.GeneratedAt.Format("2006-01-02 15:04:05 MST")
+		}
+		return r.AnalyzedAt.Format("2006-01-02 15:04:05 MST")
+	},
+}).Parse(htmlSource))
+
+// renderHTML writes a self-contained HTML document; thumbnails are inlined
+// as data URIs so the file can be archived or mailed on its own
+func renderHTML(w io.Writer, reports []*Report) error {
+	return htmlTemplate.Execute(w, reports)
+}
+
+const htmlSource = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>QC Report{{if eq (len .) 1}} - {{(index . 0).Filename}}{{end}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #212121; margin: 0; background: #f5f5f5; }
+main { max-width: 1040px; margin: 0 auto; padding: 24px; }
+article { background: #fff; border-radius: 6px; box-shadow: 0 1px 3px rgba(0,0,0,.12); padding: 24px 32px; margin-bottom: 32px; page-break-after: always; }
+header { display: flex; justify-content: space-between; align-items: flex-start; border-bottom: 2px solid #e0e0e0; padding-bottom: 16px; }
+h1 { font-size: 22px; margin: 0 0 4px; }
+h2 { font-size: 17px; margin: 28px 0 12px; }
+h3 { font-size: 15px; margin: 0; display: flex; justify-content: space-between; align-items: center; }
+.meta { color: #616161; font-size: 13px; line-height: 1.6; }
+.badge { display: inline-block; color: #fff; border-radius: 12px; padding: 2px 12px; font-size: 12px; font-weight: 600; text-transform: uppercase; letter-spacing: .04em; }
+.overall { font-size: 15px; padding: 6px 18px; border-radius: 16px; }
+table { border-collapse: collapse; width: 100%; font-size: 13px; }
+td { padding: 4px 8px; border-bottom: 1px solid #eeeeee; vertical-align: top; }
+td:first-child { color: #616161; width: 40%; }
+.summary td:first-child { width: 25%; }
+.thumbnails { display: flex; gap: 12px; flex-wrap: wrap; }
+.thumbnails figure { margin: 0; font-size: 12px; color: #616161; text-align: center; }
+.thumbnails img { display: block; max-width: 230px; border-radius: 4px; border: 1px solid #e0e0e0; }
+.charts { display: grid; grid-template-columns: repeat(auto-fit, minmax(300px, 1fr)); gap: 24px; }
+.chart h4 { font-size: 13px; margin: 0 0 8px; }
+.bar { display: grid; grid-template-columns: 140px 1fr 80px; align-items: center; gap: 8px; font-size: 12px; margin-bottom: 4px; }
+.bar .track { background: #eeeeee; border-radius: 3px; height: 14px; }
+.bar .fill { height: 14px; border-radius: 3px; }
+.bar .value { text-align: right; color: #616161; }
+.timeline { margin-top: 24px; }
+.timeline h4 { font-size: 13px; margin: 0 0 8px; }
+.timeline svg { display: block; width: 100%; height: 160px; background: #fafafa; border: 1px solid #eeeeee; border-radius: 3px; }
+.timeline .meta { display: flex; justify-content: space-between; font-size: 12px; }
+.categories { display: grid; grid-template-columns: repeat(auto-fit, minmax(440px, 1fr)); gap: 16px; }
+.category { border: 1px solid #e0e0e0; border-left-width: 5px; border-radius: 4px; padding: 12px 16px; page-break-inside: avoid; }
+.category table { margin-top: 8px; }
+.findings { margin: 8px 0 0; padding-left: 18px; font-size: 12px; }
+.recommendations li { font-size: 13px; margin-bottom: 4px; }
+.error { color: #c62828; font-weight: 600; }
+footer { color: #9e9e9e; font-size: 12px; margin-top: 24px; }
+@media print { body { background: #fff; } main { padding: 0; } article { box-shadow: none; padding: 0; } }
+</style>
+</head>
+<body>
+<main>
+{{range .}}
+<article>
+<header>
+<div>
+<h1>QC Analysis Report</h1>
+<div class="meta">
+<div><strong>File:</strong> {{.Filename}}</div>
+{{if .AnalysisID}}<div><strong>Analysis ID:</strong> {{.AnalysisID}}</div>{{end}}
+<div><strong>Analyzed:</strong> {{datetime .}}</div>
+</div>
+</div>
+<span class="badge overall" style="background: {{.Overall.Color}}">{{.Overall.Label}}</span>
+</header>
+{{if eq .Status "error"}}
+<p class="error">Analysis failed: {{.Error}}</p>
+{{else}}
+<h2>Summary</h2>
+<table class="summary">
+{{range .Summary}}<tr><td>{{.Label}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+{{if .Thumbnails}}
+<h2>Thumbnails</h2>
+<div class="thumbnails">
+{{range .Thumbnails}}<figure><img src="{{thumbnailSrc .JPEG}}" width="{{.Width}}" height="{{.Height}}" alt="Frame at {{timestamp .Time}}"><figcaption>{{timestamp .Time}}</figcaption></figure>
+{{end}}</div>
+{{end}}
+{{with .TonemapPreviews}}{{if .Frames}}
+<h2>SDR Previews</h2>
+<p class="meta">Tone mapped to BT.709 SDR with the {{.Operator}} operator</p>
+<div class="thumbnails">
+{{range .Frames}}<figure><img src="{{thumbnailSrc .JPEG}}" width="{{.Width}}" height="{{.Height}}" alt="SDR frame at {{timestamp .Time}}"><figcaption>{{timestamp .Time}}</figcaption></figure>
+{{end}}</div>
+{{end}}{{end}}
+<h2>Overview</h2>
+<div class="charts">
+{{range $chart := .Charts}}<div class="chart">
+<h4>{{$chart.Title}}</h4>
+{{range $chart.Bars}}<div class="bar"><span>{{.Label}}</span><div class="track"><div class="fill" style="width: {{barWidth $chart .Value}}; background: {{.Severity.Color}}"></div></div><span class="value">{{number .Value}} {{$chart.Unit}}</span></div>
+{{end}}</div>
+{{end}}</div>
+{{range .Timelines}}<div class="timeline">
+<h4>{{.Title}}</h4>
+<svg viewBox="0 0 1000 160" preserveAspectRatio="none" role="img" aria-label="{{.Title}}">
+<polyline points="{{polyline .}}" fill="none" stroke="#1565c0" stroke-width="1.5" vector-effect="non-scaling-stroke"/>
+{{with limitY .}}<line x1="0" y1="{{.}}" x2="1000" y2="{{.}}" stroke="#c62828" stroke-dasharray="6 4" vector-effect="non-scaling-stroke"/>{{end}}
+</svg>
+<div class="meta"><span>{{.Caption}}</span><span>0:00:00 &ndash; {{timestamp .Duration}}</span></div>
+</div>
+{{end}}
+<h2>QC Categories</h2>
+<div class="categories">
+{{range .Categories}}<section class="category" style="border-left-color: {{.Severity.Color}}">
+<h3><span>{{.Number}}. {{.Name}}</span><span class="badge" style="background: {{.Severity.Color}}">{{.Severity.Label}}</span></h3>
+{{if .Fields}}<table>
+{{range .Fields}}<tr><td>{{.Label}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>{{end}}
+{{if .Findings}}<ul class="findings">
+{{range .Findings}}<li>{{.}}</li>
+{{end}}</ul>{{end}}
+</section>
+{{end}}</div>
+<h2>Recommendations</h2>
+{{if .Recommendations}}<ol class="recommendations">
+{{range .Recommendations}}<li>{{.}}</li>
+{{end}}</ol>{{else}}<p class="meta">No recommendations</p>{{end}}
+{{end}}
+<footer>Generated by Rendiff Probe on {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}</footer>
+</article>
+{{end}}
+</main>
+</body>
+</html>
+`
@@ -0,0 +1,329 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// MarkerFormat is an event list format that editing systems import
+type MarkerFormat string
+
+const (
+	MarkerFormatCSV  MarkerFormat = "csv"  // One row per event
+	MarkerFormatEDL  MarkerFormat = "edl"  // CMX 3600 EDL with a locator per event
+	MarkerFormatAvid MarkerFormat = "avid" // Avid Media Composer marker list
+)
+
+// ParseMarkerFormat resolves a marker format name; empty selects CSV
+func ParseMarkerFormat(name string) (MarkerFormat, error) {
+	switch format := MarkerFormat(strings.ToLower(strings.TrimSpace(name))); format {
+	case "":
+		return MarkerFormatCSV, nil
+	case MarkerFormatCSV, MarkerFormatEDL, MarkerFormatAvid:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported marker format %q (use csv, edl or avid)", name)
+	}
+}
+
+// ContentType returns the MIME type of the format
+func (f MarkerFormat) ContentType() string {
+	if f == MarkerFormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// Extension returns the usual file extension of the format
+func (f MarkerFormat) Extension() string {
+	if f == MarkerFormatAvid {
+		return "txt"
+	}
+	return string(f)
+}
+
+// Marker types
+const (
+	MarkerBlack   = "black"
+	MarkerFreeze  = "freeze"
+	MarkerSilence = "silence"
+	MarkerDropout = "dropout"
+	MarkerPhase   = "phase"
+	MarkerFlash   = "flash" // Photosensitive epilepsy risk
+)
+
+// markerColors are the Avid locator colors of each marker type
+var markerColors = map[string]string{
+	MarkerBlack:   "red",
+	MarkerFreeze:  "blue",
+	MarkerSilence: "yellow",
+	MarkerDropout: "magenta",
+	MarkerPhase:   "green",
+	MarkerFlash:   "cyan",
+}
+
+// Marker is one detected event on the media timeline
+type Marker struct {
+	Type        string
+	Start       float64 // Seconds from the start of the media
+	End         float64
+	Audio       bool // Placed on an audio track rather than the picture
+	Stream      *int // Audio stream for per-stream measurements
+	Description string
+}
+
+// Markers lists the detected events of an analysis in timeline order
+func Markers(result *ffmpeg.FFprobeResult) []Marker {
+	if result == nil || result.EnhancedAnalysis == nil {
+		return nil
+	}
+	enhanced := result.EnhancedAnalysis
+
+	var markers []Marker
+	add := func(marker Marker) {
+		if marker.End < marker.Start {
+			marker.End = marker.Start
+		}
+		markers = append(markers, marker)
+	}
+	silence := func(analysis *ffmpeg.SilenceAnalysis, stream *int) {
+		for _, period := range analysis.SilencePeriods {
+			add(Marker{Type: MarkerSilence, Start: period.StartTime, End: period.EndTime, Audio: true, Stream: stream, Description: fmt.Sprintf("Silence %.2f s", period.Duration)})
+		}
+	}
+	phase := func(analysis *ffmpeg.PhaseAnalysis, stream *int) {
+		for _, event := range analysis.PhaseEvents {
+			add(Marker{Type: MarkerPhase, Start: event.StartTime, End: event.EndTime, Audio: true, Stream: stream, Description: fmt.Sprintf("Out of phase %.2f s", event.Duration)})
+		}
+	}
+
+	if content := enhanced.ContentAnalysis; content != nil {
+		if content.BlackFrames != nil {
+			for _, period := range content.BlackFrames.Periods {
+				add(Marker{Type: MarkerBlack, Start: period.StartTime, End: period.EndTime, Description: fmt.Sprintf("Black %.2f s", period.Duration)})
+			}
+		}
+		if content.FreezeFrames != nil {
+			for _, period := range content.FreezeFrames.Periods {
+				add(Marker{Type: MarkerFreeze, Start: period.StartTime, End: period.EndTime, Description: fmt.Sprintf("Freeze %.2f s", period.Duration)})
+			}
+		}
+		if content.SilenceInfo != nil {
+			silence(content.SilenceInfo, nil)
+		}
+		if content.PhaseInfo != nil {
+			phase(content.PhaseInfo, nil)
+		}
+		if dropouts := content.DropoutInfo; dropouts != nil {
+			for _, event := range dropouts.VideoDropouts {
+				add(Marker{Type: MarkerDropout, Start: event.StartTime, End: event.EndTime, Description: fmt.Sprintf("Video dropout %.2f s (%s)", event.Duration, event.Severity)})
+			}
+			for _, event := range dropouts.AudioDropouts {
+				add(Marker{Type: MarkerDropout, Start: event.StartTime, End: event.EndTime, Audio: true, Description: fmt.Sprintf("Audio dropout %.2f s (%s)", event.Duration, event.Severity)})
+			}
+		}
+		for _, index := range sortedKeys(content.AudioStreams) {
+			stream := content.AudioStreams[index]
+			streamIndex := index
+			if stream.Silence != nil {
+				silence(stream.Silence, &streamIndex)
+			}
+			if stream.Phase != nil {
+				phase(stream.Phase, &streamIndex)
+			}
+		}
+	}
+	if pse := enhanced.PSEAnalysis; pse != nil {
+		for _, violation := range pse.ViolationInstances {
+			add(Marker{Type: MarkerFlash, Start: violation.Timestamp, End: violation.Timestamp + violation.Duration, Description: fmt.Sprintf("PSE %s risk (%s)", violation.ViolationType, violation.Severity)})
+		}
+	}
+
+	sort.SliceStable(markers, func(i, j int) bool { return markers[i].Start < markers[j].Start })
+	return markers
+}
+
+// Timebase converts media seconds to SMPTE timecode from the start
+// timecode and frame rate of the media
+type Timebase struct {
+	FrameRate     float64
+	Base          int // Rounded timecode base, 30 for 29.97 fps
+	DropFrame     bool
+	StartTimecode string
+	startFrames   int64
+}
+
+// defaultMarkerFrameRate is used for media without a video stream
+const defaultMarkerFrameRate = 25
+
+// NewTimebase takes the start timecode from the timecode analysis or the
+// stream and container tags, and the frame rate from the first video
+// stream. Media without a start timecode starts at 00:00:00:00.
+func NewTimebase(result *ffmpeg.FFprobeResult) Timebase {
+	t := Timebase{FrameRate: defaultMarkerFrameRate}
+	var startTimecode string
+	if result != nil {
+		for _, stream := range result.Streams {
+			if !strings.EqualFold(stream.CodecType, "video") {
+				continue
+			}
+			for _, rate := range []string{stream.RFrameRate, stream.AvgFrameRate} {
+				if fps := parseRate(rate); fps > 0 {
+					t.FrameRate = fps
+					break
+				}
+			}
+			startTimecode = stream.Tags["timecode"]
+			break
+		}
+		if result.Format != nil && startTimecode == "" {
+			startTimecode = result.Format.Tags["timecode"]
+		}
+		if enhanced := result.EnhancedAnalysis; enhanced != nil && enhanced.TimecodeAnalysis != nil && enhanced.TimecodeAnalysis.PrimaryTimecode != nil {
+			if primary := enhanced.TimecodeAnalysis.PrimaryTimecode.StartTimecode; primary != "" {
+				startTimecode = primary
+			}
+		}
+	}
+
+	t.Base = ffmpeg.TimecodeBase(t.FrameRate)
+	// 29.97 and 59.94 fps media is timed in drop frame unless its timecode says otherwise
+	t.DropFrame = t.Base%30 == 0 && math.Abs(t.FrameRate-float64(t.Base)) > 0.01
+	if frames, dropFrame, err := ffmpeg.ParseTimecode(startTimecode, t.Base); err == nil {
+		t.startFrames, t.DropFrame = frames, dropFrame
+	}
+	t.StartTimecode = ffmpeg.FormatTimecode(t.startFrames, t.Base, t.DropFrame)
+	return t
+}
+
+// frames returns the frame count from midnight at a media position
+func (t Timebase) frames(seconds float64) int64 {
+	return t.startFrames + int64(math.Floor(seconds*t.FrameRate+1e-6))
+}
+
+// Timecode returns the timecode of a media position
+func (t Timebase) Timecode(seconds float64) string {
+	return ffmpeg.FormatTimecode(t.frames(seconds), t.Base, t.DropFrame)
+}
+
+// parseRate parses an ffprobe rate such as "30000/1001"
+func parseRate(rate string) float64 {
+	num, den, found := strings.Cut(rate, "/")
+	value, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return value
+	}
+	divisor, err := strconv.ParseFloat(den, 64)
+	if err != nil || divisor == 0 {
+		return 0
+	}
+	return value / divisor
+}
+
+// RenderMarkers writes the detected events of an analysis as a marker list
+// with SMPTE timecodes. title names the EDL.
+func RenderMarkers(w io.Writer, format MarkerFormat, title string, result *ffmpeg.FFprobeResult) error {
+	markers := Markers(result)
+	timebase := NewTimebase(result)
+	switch format {
+	case MarkerFormatCSV:
+		return renderMarkerCSV(w, timebase, markers)
+	case MarkerFormatEDL:
+		return renderMarkerEDL(w, timebase, title, markers)
+	case MarkerFormatAvid:
+		return renderMarkerAvid(w, timebase, markers)
+	default:
+		return fmt.Errorf("unsupported marker format %q", format)
+	}
+}
+
+func renderMarkerCSV(w io.Writer, timebase Timebase, markers []Marker) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"type", "start_timecode", "end_timecode", "start_seconds", "end_seconds", "duration_seconds", "stream_index", "description"})
+	for _, marker := range markers {
+		stream := ""
+		if marker.Stream != nil {
+			stream = strconv.Itoa(*marker.Stream)
+		}
+		writer.Write([]string{
+			marker.Type,
+			timebase.Timecode(marker.Start),
+			timebase.Timecode(marker.End),
+			strconv.FormatFloat(marker.Start, 'f', 3, 64),
+			strconv.FormatFloat(marker.End, 'f', 3, 64),
+			strconv.FormatFloat(marker.End-marker.Start, 'f', 3, 64),
+			stream,
+			marker.Description,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// renderMarkerEDL writes one cut per event, with the event as both source
+// and record range, and a LOC comment that NLEs import as a marker
+func renderMarkerEDL(w io.Writer, timebase Timebase, title string, markers []Marker) error {
+	fcm := "NON-DROP FRAME"
+	if timebase.DropFrame {
+		fcm = "DROP FRAME"
+	}
+	if _, err := fmt.Fprintf(w, "TITLE: %s\nFCM: %s\n", oneLine(title), fcm); err != nil {
+		return err
+	}
+
+	for i, marker := range markers {
+		track := "V"
+		if marker.Audio {
+			track = "A"
+		}
+		in := timebase.frames(marker.Start)
+		out := timebase.frames(marker.End)
+		if out <= in {
+			out = in + 1 // Events must cover at least one frame
+		}
+		inTC := ffmpeg.FormatTimecode(in, timebase.Base, timebase.DropFrame)
+		outTC := ffmpeg.FormatTimecode(out, timebase.Base, timebase.DropFrame)
+		if _, err := fmt.Fprintf(w, "\n%03d  AX       %-5s C        %s %s %s %s\n* LOC: %s %-7s %s\n",
+			i+1, track, inTC, outTC, inTC, outTC, inTC, strings.ToUpper(markerColors[marker.Type]), oneLine(marker.Description)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderMarkerAvid writes the tab separated locator list Media Composer
+// imports: user, timecode, track, color, comment and duration in frames
+func renderMarkerAvid(w io.Writer, timebase Timebase, markers []Marker) error {
+	for _, marker := range markers {
+		track := "V1"
+		if marker.Audio {
+			track = "A1"
+		}
+		in := timebase.frames(marker.Start)
+		duration := timebase.frames(marker.End) - in
+		if duration < 1 {
+			duration = 1
+		}
+		if _, err := fmt.Fprintf(w, "rendiffprobe\t%s\t%s\t%s\t%s\t%d\n",
+			ffmpeg.FormatTimecode(in, timebase.Base, timebase.DropFrame), track, markerColors[marker.Type], oneLine(marker.Description), duration); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// oneLine collapses whitespace so text cannot break an EDL line or the tab
+// separated fields of a locator
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,334 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// PDF layout in millimetres on A4 portrait
+const (
+	pdfMargin        = 15.0
+	pdfLineHeight    = 5.0
+	pdfLabelWidth    = 60.0
+	pdfThumbnailGap  = 4.0
+	pdfThumbnailsMax = 4
+	pdfBarLabelWidth = 45.0
+	pdfBarValueWidth = 30.0
+	pdfBarHeight     = 4.0
+	pdfPlotHeight    = 30.0
+)
+
+// pdfWriter keeps the document and its text encoder together. The core
+// Helvetica font only covers cp1252, so all text goes through tr.
+type pdfWriter struct {
+	pdf   *fpdf.Fpdf
+	tr    func(string) string
+	width float64 // Printable width
+}
+
+// renderPDF writes an A4 PDF with one section per report, each starting on
+// a new page
+func renderPDF(w io.Writer, reports []*Report) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.SetTitle("QC Analysis Report", true)
+	pdf.SetCreator("Rendiff Probe", true)
+	pdf.AliasNbPages("")
+
+	pageWidth, _ := pdf.GetPageSize()
+	pw := &pdfWriter{
+		pdf:   pdf,
+		tr:    pdf.UnicodeTranslatorFromDescriptor(""),
+		width: pageWidth - 2*pdfMargin,
+	}
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-10)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(0x9e, 0x9e, 0x9e)
+		pdf.CellFormat(0, 4, fmt.Sprintf("Rendiff Probe QC Report - page %d of {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	for i, r := range reports {
+		pdf.AddPage()
+		pw.report(r, i)
+	}
+
+	if err := pdf.Error(); err != nil {
+		return fmt.Errorf("failed to render PDF: %w", err)
+	}
+	return pdf.Output(w)
+}
+
+func (pw *pdfWriter) report(r *Report, index int) {
+	pdf := pw.pdf
+
+	// Header with the overall result badge on the right
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.SetTextColor(0x21, 0x21, 0x21)
+	pdf.CellFormat(pw.width-35, 9, "QC Analysis Report", "", 0, "L", false, 0, "")
+	pw.badge(r.Overall, 35, 8, 11)
+	pdf.Ln(11)
+
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(0x61, 0x61, 0x61)
+	pw.line("File: " + r.Filename)
+	if r.AnalysisID != "" {
+		pw.line("Analysis ID: " + r.AnalysisID)
+	}
+	analyzedAt := r.AnalyzedAt
+	if analyzedAt.IsZero() {
+		analyzedAt = r.GeneratedAt
+	}
+	pw.line("Analyzed: " + analyzedAt.Format("2006-01-02 15:04:05 MST"))
+	pw.rule()
+
+	if r.Status == "error" {
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.SetTextColor(SeverityFail.color())
+		pdf.MultiCell(pw.width, 6, pw.tr("Analysis failed: "+r.Error), "", "L", false)
+		return
+	}
+
+	pw.heading("Summary")
+	pw.fields(r.Summary)
+
+	if len(r.Thumbnails) > 0 {
+		pw.heading("Thumbnails")
+		pw.thumbnails(r.Thumbnails, fmt.Sprintf("thumbnail-%d", index))
+	}
+	if previews := r.TonemapPreviews; previews != nil && len(previews.Frames) > 0 {
+		pw.heading("SDR Previews")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(0x61, 0x61, 0x61)
+		pw.line(fmt.Sprintf("Tone mapped to BT.709 SDR with the %s operator", previews.Operator))
+		pw.thumbnails(previews.Frames, fmt.Sprintf("tonemap-%d", index))
+	}
+
+	pw.heading("Overview")
+	for _, chart := range r.Charts {
+		pw.chart(chart)
+	}
+	for _, timeline := range r.Timelines {
+		pw.timeline(timeline)
+	}
+
+	pw.heading("QC Categories")
+	for _, category := range r.Categories {
+		pw.category(category)
+	}
+
+	pw.heading("Recommendations")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(0x21, 0x21, 0x21)
+	if len(r.Recommendations) == 0 {
+		pw.line("No recommendations")
+	}
+	for i, recommendation := range r.Recommendations {
+		pdf.MultiCell(pw.width, pdfLineHeight, pw.tr(fmt.Sprintf("%d. %s", i+1, recommendation)), "", "L", false)
+	}
+}
+
+// badge draws a filled, rounded severity label at the current position
+func (pw *pdfWriter) badge(severity Severity, width, height, fontSize float64) {
+	pdf := pw.pdf
+	x, y := pdf.GetXY()
+	pdf.SetFillColor(severity.color())
+	pdf.RoundedRect(x, y, width, height, height/2, "1234", "F")
+	pdf.SetFont("Helvetica", "B", fontSize)
+	pdf.SetTextColor(0xff, 0xff, 0xff)
+	pdf.CellFormat(width, height, pw.tr(severity.Label()), "", 0, "C", false, 0, "")
+}
+
+func (pw *pdfWriter) heading(text string) {
+	pdf := pw.pdf
+	pdf.Ln(4)
+	// Keep headings with at least a few lines of their content
+	_, pageHeight := pdf.GetPageSize()
+	if pdf.GetY() > pageHeight-pdfMargin-30 {
+		pdf.AddPage()
+	}
+	pdf.SetFont("Helvetica", "B", 13)
+	pdf.SetTextColor(0x21, 0x21, 0x21)
+	pdf.CellFormat(pw.width, 8, pw.tr(text), "", 1, "L", false, 0, "")
+}
+
+func (pw *pdfWriter) line(text string) {
+	pw.pdf.CellFormat(pw.width, pdfLineHeight, pw.tr(text), "", 1, "L", false, 0, "")
+}
+
+func (pw *pdfWriter) rule() {
+	pdf := pw.pdf
+	pdf.Ln(2)
+	pdf.SetDrawColor(0xe0, 0xe0, 0xe0)
+	pdf.SetLineWidth(0.5)
+	pdf.Line(pdfMargin, pdf.GetY(), pdfMargin+pw.width, pdf.GetY())
+	pdf.Ln(2)
+}
+
+// fields prints a two-column label/value table
+func (pw *pdfWriter) fields(fields []Field) {
+	pdf := pw.pdf
+	pdf.SetFont("Helvetica", "", 9)
+	for _, field := range fields {
+		pdf.SetTextColor(0x61, 0x61, 0x61)
+		pdf.CellFormat(pdfLabelWidth, pdfLineHeight, pw.tr(field.Label), "", 0, "L", false, 0, "")
+		pdf.SetTextColor(0x21, 0x21, 0x21)
+		pdf.MultiCell(pw.width-pdfLabelWidth, pdfLineHeight, pw.tr(field.Value), "", "L", false)
+	}
+}
+
+// thumbnails places up to pdfThumbnailsMax stills in one row, registering
+// them as images named after prefix
+func (pw *pdfWriter) thumbnails(thumbnails []ffmpeg.Thumbnail, prefix string) {
+	pdf := pw.pdf
+	count := len(thumbnails)
+	if count > pdfThumbnailsMax {
+		count = pdfThumbnailsMax
+	}
+	width := (pw.width - pdfThumbnailGap*float64(pdfThumbnailsMax-1)) / pdfThumbnailsMax
+
+	rowHeight := 0.0
+	for _, thumbnail := range thumbnails[:count] {
+		if thumbnail.Width > 0 {
+			if h := width * float64(thumbnail.Height) / float64(thumbnail.Width); h > rowHeight {
+				rowHeight = h
+			}
+		}
+	}
+	_, pageHeight := pdf.GetPageSize()
+	if pdf.GetY()+rowHeight+pdfLineHeight > pageHeight-pdfMargin {
+		pdf.AddPage()
+	}
+
+	y := pdf.GetY()
+	options := fpdf.ImageOptions{ImageType: "JPG"}
+	for i, thumbnail := range thumbnails[:count] {
+		name := fmt.Sprintf("%s-%d", prefix, i)
+		pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(thumbnail.JPEG))
+		x := pdfMargin + float64(i)*(width+pdfThumbnailGap)
+		pdf.ImageOptions(name, x, y, width, 0, false, options, 0, "")
+
+		pdf.SetXY(x, y+rowHeight+1)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(0x61, 0x61, 0x61)
+		pdf.CellFormat(width, 4, formatTimestamp(thumbnail.Time), "", 0, "C", false, 0, "")
+	}
+	pdf.SetXY(pdfMargin, y+rowHeight+pdfLineHeight+1)
+}
+
+// chart draws a horizontal bar chart
+func (pw *pdfWriter) chart(chart Chart) {
+	pdf := pw.pdf
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetTextColor(0x21, 0x21, 0x21)
+	pdf.CellFormat(pw.width, 6, pw.tr(chart.Title), "", 1, "L", false, 0, "")
+
+	maxValue := chart.Max()
+	trackWidth := pw.width - pdfBarLabelWidth - pdfBarValueWidth - 4
+	pdf.SetFont("Helvetica", "", 8)
+	for _, bar := range chart.Bars {
+		x, y := pdfMargin, pdf.GetY()
+		pdf.SetTextColor(0x61, 0x61, 0x61)
+		pdf.CellFormat(pdfBarLabelWidth, pdfLineHeight, pw.tr(bar.Label), "", 0, "L", false, 0, "")
+
+		trackX := x + pdfBarLabelWidth + 2
+		barY := y + (pdfLineHeight-pdfBarHeight)/2
+		pdf.SetFillColor(0xee, 0xee, 0xee)
+		pdf.Rect(trackX, barY, trackWidth, pdfBarHeight, "F")
+		if maxValue > 0 && bar.Value > 0 {
+			pdf.SetFillColor(bar.Severity.color())
+			pdf.Rect(trackX, barY, trackWidth*bar.Value/maxValue, pdfBarHeight, "F")
+		}
+
+		pdf.SetXY(trackX+trackWidth+2, y)
+		pdf.CellFormat(pdfBarValueWidth, pdfLineHeight, pw.tr(fmt.Sprintf("%.0f %s", bar.Value, chart.Unit)), "", 1, "R", false, 0, "")
+	}
+	pdf.Ln(3)
+}
+
+// timeline draws a line chart scaled from the timelineWidth by
+// timelineHeight plot, with the limit as a red line
+func (pw *pdfWriter) timeline(timeline Timeline) {
+	pdf := pw.pdf
+	_, pageHeight := pdf.GetPageSize()
+	if pageHeight-pdfMargin-pdf.GetY() < pdfPlotHeight+6+2*pdfLineHeight {
+		pdf.AddPage()
+	}
+
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetTextColor(0x21, 0x21, 0x21)
+	pdf.CellFormat(pw.width, 6, pw.tr(timeline.Title), "", 1, "L", false, 0, "")
+
+	top := pdf.GetY()
+	scaleX := pw.width / timelineWidth
+	scaleY := pdfPlotHeight / timelineHeight
+	pdf.SetFillColor(0xfa, 0xfa, 0xfa)
+	pdf.SetDrawColor(0xee, 0xee, 0xee)
+	pdf.Rect(pdfMargin, top, pw.width, pdfPlotHeight, "FD")
+
+	pdf.SetLineWidth(0.3)
+	pdf.SetDrawColor(0x15, 0x65, 0xc0)
+	points := timeline.Points()
+	for i := 1; i < len(points); i++ {
+		pdf.Line(pdfMargin+points[i-1][0]*scaleX, top+points[i-1][1]*scaleY, pdfMargin+points[i][0]*scaleX, top+points[i][1]*scaleY)
+	}
+	if y, ok := timeline.LimitY(); ok {
+		pdf.SetDrawColor(SeverityFail.color())
+		pdf.Line(pdfMargin, top+y*scaleY, pdfMargin+pw.width, top+y*scaleY)
+	}
+
+	pdf.SetXY(pdfMargin, top+pdfPlotHeight+1)
+	pdf.SetFont("Helvetica", "", 8)
+	pdf.SetTextColor(0x61, 0x61, 0x61)
+	pdf.CellFormat(pw.width-30, pdfLineHeight, pw.tr(timeline.Caption), "", 0, "L", false, 0, "")
+	pdf.CellFormat(30, pdfLineHeight, formatTimestamp(timeline.Duration()), "", 1, "R", false, 0, "")
+	pdf.Ln(3)
+}
+
+// category draws one QC category with a severity stripe and badge, moving
+// to a new page rather than splitting a short category
+func (pw *pdfWriter) category(category Category) {
+	pdf := pw.pdf
+	height := 9 + pdfLineHeight*float64(len(category.Fields)+len(category.Findings))
+	_, pageHeight := pdf.GetPageSize()
+	if remaining := pageHeight - pdfMargin - pdf.GetY(); height > remaining && height < pageHeight-2*pdfMargin {
+		pdf.AddPage()
+	}
+
+	startY := pdf.GetY()
+	pdf.SetX(pdfMargin + 3)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetTextColor(0x21, 0x21, 0x21)
+	pdf.CellFormat(pw.width-33, 7, pw.tr(fmt.Sprintf("%d. %s", category.Number, category.Name)), "", 0, "L", false, 0, "")
+	pdf.SetY(startY + 1)
+	pdf.SetX(pdfMargin + pw.width - 28)
+	pw.badge(category.Severity, 28, 5, 7)
+	pdf.SetXY(pdfMargin, startY+8)
+
+	pdf.SetFont("Helvetica", "", 8)
+	for _, field := range category.Fields {
+		pdf.SetX(pdfMargin + 3)
+		pdf.SetTextColor(0x61, 0x61, 0x61)
+		pdf.CellFormat(pdfLabelWidth-3, pdfLineHeight-1, pw.tr(field.Label), "", 0, "L", false, 0, "")
+		pdf.SetTextColor(0x21, 0x21, 0x21)
+		pdf.MultiCell(pw.width-pdfLabelWidth, pdfLineHeight-1, pw.tr(field.Value), "", "L", false)
+	}
+	for _, finding := range category.Findings {
+		pdf.SetX(pdfMargin + 3)
+		pdf.SetTextColor(category.Severity.color())
+		pdf.MultiCell(pw.width-3, pdfLineHeight-1, pw.tr("- "+finding), "", "L", false)
+	}
+
+	// The stripe spans the category when it fits on one page
+	if endY := pdf.GetY(); endY > startY {
+		pdf.SetFillColor(category.Severity.color())
+		pdf.Rect(pdfMargin, startY, 1.5, endY-startY, "F")
+	}
+	pdf.Ln(3)
+}
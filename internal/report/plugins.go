@@ -0,0 +1,84 @@
+package report
+
+import (
+	"strings"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// pluginCategories lists the results of plugin analyzers as categories
+// numbered after the built-in ones
+func (d *analysisData) pluginCategories(first int) []Category {
+	var categories []Category
+	for i, plugin := range d.enhanced.Plugins {
+		name := plugin.Title
+		if name == "" {
+			name = plugin.Name
+		}
+		c := Category{Number: first + i, Name: name, Severity: Severity(plugin.Status)}
+		if plugin.Error != "" {
+			c.Severity = SeverityNotAnalyzed
+			c.Findings = []string{"Plugin failed: " + plugin.Error}
+			categories = append(categories, c)
+			continue
+		}
+		if plugin.Summary != "" {
+			c.Fields = append(c.Fields, Field{"Summary", plugin.Summary})
+		}
+		for _, field := range plugin.Fields {
+			c.Fields = append(c.Fields, Field{field.Label, orNA(field.Value)})
+		}
+		c.Findings = append(c.Findings, plugin.Findings...)
+		categories = append(categories, c)
+	}
+	return categories
+}
+
+// pluginQCCategories lists the results of plugin analyzers in the QC
+// result, with the plugin name as category ID. Plugins that report no
+// checks get a single "<name>.validation" check like built-in categories.
+func pluginQCCategories(plugins []*ffmpeg.PluginAnalysis, categories []Category) []QCCategoryResult {
+	results := make([]QCCategoryResult, 0, len(plugins))
+	for i, plugin := range plugins {
+		category := categories[i]
+		result := QCCategoryResult{ID: plugin.Name, Name: category.Name, Status: category.Severity, Checks: []QCCheck{}}
+		if category.Severity == SeverityNotAnalyzed {
+			results = append(results, result)
+			continue
+		}
+		for _, check := range plugin.Checks {
+			result.Checks = append(result.Checks, pluginCheck(check))
+		}
+		if len(result.Checks) == 0 {
+			message := plugin.Summary
+			if len(category.Findings) > 0 {
+				message = strings.Join(category.Findings, "; ")
+			}
+			result.Checks = append(result.Checks, QCCheck{
+				ID:       plugin.Name + ".validation",
+				Status:   category.Severity,
+				Severity: CheckMajor,
+				Message:  message,
+			})
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func pluginCheck(check ffmpeg.PluginCheck) QCCheck {
+	qc := QCCheck{
+		ID:       check.ID,
+		Status:   Severity(check.Status),
+		Severity: CheckSeverity(check.Severity),
+		Stream:   check.Stream,
+		Message:  check.Message,
+	}
+	for _, measurement := range check.Measurements {
+		qc.Measurements = append(qc.Measurements, QCMeasurement{Name: measurement.Name, Value: measurement.Value, Unit: measurement.Unit})
+	}
+	for _, evidence := range check.Evidence {
+		qc.addEvidence(evidence.StartSeconds, evidence.EndSeconds, evidence.Description)
+	}
+	return qc
+}
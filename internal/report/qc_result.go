@@ -0,0 +1,688 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// QCResultSchemaVersion identifies the QCResult layout. The minor version
+// grows when fields or check IDs are added; the major version changes when
+// any are renamed, removed or change meaning.
+const QCResultSchemaVersion = "1.6"
+
+// maxQCEvidence limits the evidence listed per check
+const maxQCEvidence = 100
+
+// CheckSeverity is how serious a failed check is, independent of whether
+// it failed
+type CheckSeverity string
+
+const (
+	CheckCritical CheckSeverity = "critical" // Rejects the delivery
+	CheckMajor    CheckSeverity = "major"    // Needs a fix or a waiver
+	CheckMinor    CheckSeverity = "minor"    // Worth reviewing
+	CheckInfo     CheckSeverity = "info"     // Reported for reference only
+)
+
+// QCResult is the machine-readable QC verdict for one analysis. Its layout
+// is versioned by SchemaVersion so automation can rely on it, unlike the
+// analyzer output it is derived from.
+type QCResult struct {
+	SchemaVersion string             `json:"schema_version"`
+	AnalysisID    string             `json:"analysis_id,omitempty"`
+	Filename      string             `json:"filename,omitempty"`
+	Status        Severity           `json:"status"` // Worst category status
+	Summary       QCSummary          `json:"summary"`
+	Categories    []QCCategoryResult `json:"categories"`
+	Delivery      *QCCategoryResult  `json:"delivery,omitempty"` // Checks of the selected delivery profile
+}
+
+// QCSummary counts categories by status
+type QCSummary struct {
+	Passed      int `json:"passed"`
+	Info        int `json:"info"`
+	Warnings    int `json:"warnings"`
+	Failed      int `json:"failed"`
+	NotAnalyzed int `json:"not_analyzed"`
+}
+
+// QCCategoryResult is the verdict of one QC category
+type QCCategoryResult struct {
+	ID     string    `json:"id"` // QC category name as accepted by ?categories=, or the plugin name
+	Name   string    `json:"name"`
+	Status Severity  `json:"status"` // Graded as in the HTML and PDF reports
+	Checks []QCCheck `json:"checks"`
+}
+
+// QCCheck is one rule evaluated within a category. IDs are
+// "<category>.<check>" and stable within a schema major version.
+type QCCheck struct {
+	ID           string          `json:"id"`
+	Status       Severity        `json:"status"`
+	Severity     CheckSeverity   `json:"severity"`
+	Stream       *int            `json:"stream,omitempty"` // Stream index for per-stream checks
+	Message      string          `json:"message,omitempty"`
+	Measurements []QCMeasurement `json:"measurements,omitempty"`
+	Evidence     []QCEvidence    `json:"evidence,omitempty"`
+}
+
+// QCMeasurement is one measured value. Value is a number, string or boolean.
+type QCMeasurement struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+	Unit  string `json:"unit,omitempty"`
+}
+
+// QCEvidence locates an event behind a check in the media timeline
+type QCEvidence struct {
+	StartSeconds float64 `json:"start_seconds"`
+	EndSeconds   float64 `json:"end_seconds,omitempty"` // Omitted for instantaneous events
+	Description  string  `json:"description,omitempty"`
+}
+
+// BuildQCResult derives the versioned QC verdict of a finished analysis.
+// Category statuses match the HTML and PDF reports.
+func BuildQCResult(source Source, result *ffmpeg.FFprobeResult) *QCResult {
+	qc := &QCResult{
+		SchemaVersion: QCResultSchemaVersion,
+		AnalysisID:    source.AnalysisID,
+		Filename:      source.Filename,
+		Status:        SeverityFail,
+		Categories:    []QCCategoryResult{},
+	}
+	if result == nil {
+		return qc
+	}
+
+	data := newAnalysisData(result)
+	report := Build(source, result, nil)
+	qc.Status = report.Overall
+	builtin := report.Categories[:len(ffmpeg.AllQCCategories)]
+	for i, category := range builtin {
+		id := string(ffmpeg.AllQCCategories[i])
+		checks := data.qcChecks(id, category)
+		qc.Categories = append(qc.Categories, QCCategoryResult{
+			ID:     id,
+			Name:   category.Name,
+			Status: category.Severity,
+			Checks: checks,
+		})
+		qc.Summary.add(category.Severity)
+	}
+	for _, category := range pluginQCCategories(data.enhanced.Plugins, report.Categories[len(builtin):]) {
+		qc.Categories = append(qc.Categories, category)
+		qc.Summary.add(category.Status)
+	}
+	qc.Delivery = deliveryChecks(data.enhanced.DeliveryCompliance)
+	return qc
+}
+
+func (s *QCSummary) add(severity Severity) {
+	switch severity {
+	case SeverityPass:
+		s.Passed++
+	case SeverityInfo:
+		s.Info++
+	case SeverityWarning:
+		s.Warnings++
+	case SeverityFail:
+		s.Failed++
+	default:
+		s.NotAnalyzed++
+	}
+}
+
+// qcChecks lists the checks of a category. Categories without dedicated
+// checks get a single "<id>.validation" check carrying the category status
+// and findings.
+func (d *analysisData) qcChecks(id string, category Category) []QCCheck {
+	if category.Severity == SeverityNotAnalyzed {
+		return []QCCheck{}
+	}
+
+	var checks []QCCheck
+	switch ffmpeg.QCCategory(id) {
+	case ffmpeg.QCCategoryAFD:
+		checks = d.afdChecks()
+	case ffmpeg.QCCategoryDeadPixel:
+		checks = d.deadPixelChecks()
+	case ffmpeg.QCCategoryPSE:
+		checks = d.pseChecks()
+	case ffmpeg.QCCategoryHDR:
+		checks = d.hdrChecks()
+	case ffmpeg.QCCategoryTransportStream:
+		checks = d.transportStreamChecks()
+	case ffmpeg.QCCategoryContent:
+		checks = d.contentChecks()
+	case ffmpeg.QCCategoryIntegrity:
+		checks = d.integrityChecks()
+	case ffmpeg.QCCategoryDisposition:
+		checks = d.dispositionChecks()
+	}
+	if len(checks) > 0 {
+		return checks
+	}
+	return []QCCheck{{
+		ID:       id + ".validation",
+		Status:   category.Severity,
+		Severity: CheckMajor,
+		Message:  strings.Join(category.Findings, "; "),
+	}}
+}
+
+// countCheck passes when count is zero and otherwise takes failStatus
+func countCheck(id string, severity CheckSeverity, failStatus Severity, name string, count int) QCCheck {
+	check := QCCheck{
+		ID:           id,
+		Status:       SeverityPass,
+		Severity:     severity,
+		Measurements: []QCMeasurement{{Name: name, Value: count}},
+	}
+	if count > 0 {
+		check.Status = failStatus
+	}
+	return check
+}
+
+// addEvidence appends evidence up to maxQCEvidence entries
+func (c *QCCheck) addEvidence(start, end float64, description string) {
+	if len(c.Evidence) < maxQCEvidence {
+		c.Evidence = append(c.Evidence, QCEvidence{StartSeconds: start, EndSeconds: end, Description: description})
+	}
+}
+
+func (d *analysisData) afdChecks() []QCCheck {
+	afd := d.enhanced.AFDAnalysis
+	present := QCCheck{ID: "afd.present", Status: SeverityPass, Severity: CheckInfo}
+	if !afd.HasAFD {
+		present.Status = SeverityInfo
+		present.Message = "No AFD signalled"
+		return []QCCheck{present}
+	}
+	checks := []QCCheck{present}
+
+	if primary := afd.PrimaryAFD; primary != nil {
+		valid := QCCheck{
+			ID:           "afd.valid",
+			Status:       SeverityPass,
+			Severity:     CheckMajor,
+			Message:      primary.AFDDescription,
+			Measurements: []QCMeasurement{{Name: "afd_value", Value: primary.AFDValue}},
+		}
+		if !primary.IsValid {
+			valid.Status = SeverityFail
+		}
+		checks = append(checks, valid)
+	}
+
+	changes := countCheck("afd.changes", CheckMinor, SeverityWarning, "changes", len(afd.AFDChanges))
+	for _, change := range afd.AFDChanges {
+		changes.addEvidence(change.Timestamp, 0, fmt.Sprintf("AFD %d to %d: %s", change.OldAFDValue, change.NewAFDValue, change.Reason))
+	}
+	checks = append(checks, changes)
+
+	if validation := afd.ValidationResults; validation != nil && len(validation.Issues)+len(validation.Warnings) > 0 {
+		findings := append(append([]string{}, validation.Issues...), validation.Warnings...)
+		checks = append(checks, QCCheck{
+			ID:       "afd.validation",
+			Status:   SeverityWarning,
+			Severity: CheckMinor,
+			Message:  strings.Join(findings, "; "),
+		})
+	}
+	return checks
+}
+
+func (d *analysisData) deadPixelChecks() []QCCheck {
+	dp := d.enhanced.DeadPixelAnalysis
+	return []QCCheck{
+		countCheck("dead_pixel.dead", CheckCritical, SeverityFail, "pixels", dp.DeadPixelCount),
+		countCheck("dead_pixel.hot", CheckMajor, SeverityFail, "pixels", dp.HotPixelCount),
+		countCheck("dead_pixel.stuck", CheckMinor, SeverityWarning, "pixels", dp.StuckPixelCount),
+	}
+}
+
+// pseRiskSeverity grades the per-hazard PSE risk levels
+func pseRiskSeverity(level string) Severity {
+	switch strings.ToLower(level) {
+	case "safe":
+		return SeverityPass
+	case "caution":
+		return SeverityWarning
+	case "danger":
+		return SeverityFail
+	default:
+		return SeverityInfo
+	}
+}
+
+func (d *analysisData) pseChecks() []QCCheck {
+	pse := d.enhanced.PSEAnalysis
+	hazards := []struct {
+		kind  string
+		level string
+	}{
+		{"flash", pse.FlashRiskLevel},
+		{"red_flash", pse.RedFlashRiskLevel},
+		{"pattern", pse.PatternRiskLevel},
+	}
+
+	checks := make([]QCCheck, 0, len(hazards))
+	for _, hazard := range hazards {
+		check := QCCheck{
+			ID:       "pse." + hazard.kind,
+			Status:   pseRiskSeverity(hazard.level),
+			Severity: CheckCritical,
+			Message:  hazard.level,
+		}
+		violations := 0
+		for _, violation := range pse.ViolationInstances {
+			if violation.ViolationType == hazard.kind {
+				violations++
+				check.addEvidence(violation.Timestamp, violation.Timestamp+violation.Duration, violation.Description)
+			}
+		}
+		check.Measurements = []QCMeasurement{{Name: "violations", Value: violations}}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+func (d *analysisData) transportStreamChecks() []QCCheck {
+	ts := d.enhanced.TransportStreamAnalysis
+	if !ts.IsTransportStream || ts.ETR290 == nil {
+		return nil
+	}
+
+	var checks []QCCheck
+	for _, etr := range ts.ETR290.Checks {
+		check := QCCheck{
+			ID:           "transport_stream.etr290." + etr.ID,
+			Status:       SeverityPass,
+			Severity:     CheckMinor,
+			Message:      etr.Name,
+			Measurements: []QCMeasurement{{Name: "errors", Value: etr.ErrorCount}},
+		}
+		switch etr.Priority {
+		case 1:
+			check.Severity = CheckCritical
+		case 2:
+			check.Severity = CheckMajor
+		}
+		if !etr.Passed {
+			check.Status = SeverityWarning
+			if etr.Priority == 1 {
+				check.Status = SeverityFail
+			}
+			if len(etr.Examples) > 0 {
+				check.Message = etr.Name + ": " + etr.Examples[0]
+			}
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+func (d *analysisData) contentChecks() []QCCheck {
+	content := d.enhanced.ContentAnalysis
+	var checks []QCCheck
+
+	if black := content.BlackFrames; black != nil {
+		check := countCheck("content.black_frames", CheckInfo, SeverityInfo, "frames", black.DetectedFrames)
+		check.Measurements = append(check.Measurements, QCMeasurement{Name: "percentage", Value: black.Percentage, Unit: "%"})
+		checks = append(checks, check)
+	}
+	if freeze := content.FreezeFrames; freeze != nil {
+		check := countCheck("content.freeze_frames", CheckMajor, SeverityWarning, "frames", freeze.DetectedFrames)
+		check.Measurements = append(check.Measurements, QCMeasurement{Name: "percentage", Value: freeze.Percentage, Unit: "%"})
+		checks = append(checks, check)
+	}
+	if clipping := content.AudioClipping; clipping != nil {
+		check := countCheck("content.audio_clipping", CheckMajor, SeverityWarning, "samples", clipping.ClippedSamples)
+		check.Measurements = append(check.Measurements, QCMeasurement{Name: "peak_level", Value: clipping.PeakLevel, Unit: "dB"})
+		checks = append(checks, check)
+	}
+	if content.SilenceInfo != nil {
+		checks = append(checks, silenceCheck(content.SilenceInfo, nil))
+	}
+	if content.LoudnessMeter != nil {
+		checks = append(checks, loudnessCheck(content.LoudnessMeter, nil))
+	}
+
+	for _, index := range sortedKeys(content.AudioStreams) {
+		stream := content.AudioStreams[index]
+		streamIndex := index
+		if stream.Loudness != nil {
+			checks = append(checks, loudnessCheck(stream.Loudness, &streamIndex))
+		}
+		if stream.Silence != nil {
+			checks = append(checks, silenceCheck(stream.Silence, &streamIndex))
+		}
+		if stream.Phase != nil {
+			checks = append(checks, phaseCheck(stream.Phase, &streamIndex))
+		}
+
+		// Channel checks name their channel or pair in the message
+		for _, channel := range stream.ChannelSilence {
+			check := silenceCheck(channel.Silence, &streamIndex)
+			check.Message = channel.Name
+			checks = append(checks, check)
+		}
+		for _, pair := range stream.ChannelPairs {
+			check := phaseCheck(pair.Phase, &streamIndex)
+			check.Message = pair.Pair
+			checks = append(checks, check)
+		}
+		for _, metadata := range stream.MetadataChecks {
+			checks = append(checks, audioMetadataCheck(metadata, &streamIndex))
+		}
+	}
+	return checks
+}
+
+// audioMetadataCheck fails a stream whose declared layout or language does
+// not match its audio, e.g. "content.audio_metadata.channel_layout"
+func audioMetadataCheck(metadata ffmpeg.AudioMetadataCheck, stream *int) QCCheck {
+	check := QCCheck{
+		ID:       "content.audio_metadata." + metadata.Check,
+		Status:   SeverityPass,
+		Severity: CheckMajor,
+		Stream:   stream,
+		Message:  metadata.Declared + ": " + metadata.Measured,
+	}
+	if !metadata.Passed {
+		check.Status = SeverityFail
+		check.Message = metadata.Message
+	}
+	return check
+}
+
+func phaseCheck(phase *ffmpeg.PhaseAnalysis, stream *int) QCCheck {
+	check := QCCheck{
+		ID:           "content.phase",
+		Status:       SeverityPass,
+		Severity:     CheckMajor,
+		Stream:       stream,
+		Measurements: []QCMeasurement{{Name: "out_of_phase", Value: phase.OutOfPhasePercent, Unit: "%"}},
+	}
+	if phase.HasPhaseIssues {
+		check.Status = SeverityWarning
+	}
+	for _, event := range phase.PhaseEvents {
+		check.addEvidence(event.StartTime, event.EndTime, fmt.Sprintf("Average phase %.2f", event.AveragePhase))
+	}
+	return check
+}
+
+func silenceCheck(silence *ffmpeg.SilenceAnalysis, stream *int) QCCheck {
+	check := QCCheck{
+		ID:       "content.silence",
+		Status:   SeverityPass,
+		Severity: CheckMajor,
+		Stream:   stream,
+		Measurements: []QCMeasurement{
+			{Name: "periods", Value: silence.TotalSilenceCount},
+			{Name: "total", Value: silence.TotalSilenceSec, Unit: "s"},
+			{Name: "longest", Value: silence.LongestSilenceSec, Unit: "s"},
+		},
+	}
+	if silence.HasProblematicMute {
+		check.Status = SeverityWarning
+	}
+	for _, period := range silence.SilencePeriods {
+		check.addEvidence(period.StartTime, period.EndTime, fmt.Sprintf("%.1f s of silence", period.Duration))
+	}
+	return check
+}
+
+func loudnessCheck(loudness *ffmpeg.LoudnessAnalysis, stream *int) QCCheck {
+	check := QCCheck{
+		ID:       "content.loudness",
+		Status:   SeverityPass,
+		Severity: CheckMajor,
+		Stream:   stream,
+		Message:  loudness.Standard,
+		Measurements: []QCMeasurement{
+			{Name: "integrated", Value: loudness.IntegratedLoudness, Unit: "LUFS"},
+			{Name: "range", Value: loudness.LoudnessRange, Unit: "LU"},
+			{Name: "true_peak", Value: loudness.TruePeak, Unit: "dBTP"},
+		},
+	}
+	if loudness.Dialog != nil && loudness.Dialog.IntegratedLoudness != nil {
+		check.Measurements = append(check.Measurements, QCMeasurement{Name: "dialog", Value: *loudness.Dialog.IntegratedLoudness, Unit: "LUFS"})
+	}
+	if !loudness.Compliant {
+		check.Status = SeverityWarning
+	}
+	return check
+}
+
+// hdrChecks has one check per HDR standard, e.g. "hdr.st2086", and
+// "hdr.color_tags" for the color tags of the video streams. Standards the
+// HDR format requires fail; the others only warn.
+func (d *analysisData) hdrChecks() []QCCheck {
+	var checks []QCCheck
+	var hdr *ffmpeg.HDRAnalysis
+	if d.enhanced.ContentAnalysis != nil {
+		hdr = d.enhanced.ContentAnalysis.HDRAnalysis
+	}
+	if hdr != nil && hdr.Validation != nil {
+		for _, standard := range hdr.Validation.Standards {
+			check := QCCheck{
+				ID:       "hdr." + standard.ID,
+				Status:   SeverityPass,
+				Severity: CheckMinor,
+				Message:  standard.Standard,
+			}
+			if standard.Required {
+				check.Severity = CheckMajor
+			}
+			if !standard.Passed {
+				check.Status = SeverityWarning
+				if standard.Required {
+					check.Status = SeverityFail
+				}
+				check.Message += ": " + strings.Join(standard.Issues, "; ")
+			}
+			checks = append(checks, check)
+		}
+	}
+
+	if color := d.enhanced.ColorConsistencyAnalysis; color != nil {
+		var inconsistent int
+		for _, stream := range color.Streams {
+			if !stream.IsConsistent {
+				inconsistent++
+			}
+		}
+		check := countCheck("hdr.color_tags", CheckMinor, SeverityWarning, "inconsistent_streams", inconsistent)
+		if !color.IsConsistent {
+			check.Status = SeverityWarning
+			check.Message = strings.Join(color.Issues, "; ")
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// dispositionRuleSeverity is how serious a broken packaging rule is
+var dispositionRuleSeverity = map[string]CheckSeverity{
+	ffmpeg.DispositionRuleDefaultVideo:     CheckMajor,
+	ffmpeg.DispositionRuleDefaultAudio:     CheckMajor,
+	ffmpeg.DispositionRuleForcedSubtitles:  CheckMajor,
+	ffmpeg.DispositionRuleDefaultSubtitles: CheckMinor,
+}
+
+func (d *analysisData) dispositionChecks() []QCCheck {
+	disposition := d.enhanced.StreamDispositionAnalysis
+	policy := make(map[string]bool)
+	var checks []QCCheck
+	for _, rule := range ffmpeg.DispositionPolicyRules {
+		check := QCCheck{ID: "disposition." + rule, Status: SeverityPass, Severity: dispositionRuleSeverity[rule]}
+		var messages []string
+		for _, violation := range disposition.PolicyViolations {
+			if violation.Rule != rule {
+				continue
+			}
+			policy[violation.Message] = true
+			messages = append(messages, violation.Message)
+			if violation.Severity == ffmpeg.DispositionViolationError {
+				check.Status = SeverityFail
+			} else if check.Status == SeverityPass {
+				check.Status = SeverityWarning
+			}
+		}
+		check.Message = strings.Join(messages, "; ")
+		check.Measurements = []QCMeasurement{{Name: "violations", Value: len(messages)}}
+		checks = append(checks, check)
+	}
+
+	accessibility := QCCheck{
+		ID:           "disposition.accessibility",
+		Status:       SeverityPass,
+		Severity:     CheckMinor,
+		Measurements: []QCMeasurement{{Name: "accessibility_score", Value: disposition.AccessibilityScore}},
+	}
+	if validation := disposition.Validation; validation != nil {
+		var findings []string
+		for _, issue := range validation.Issues {
+			if !policy[issue] {
+				findings = append(findings, issue)
+			}
+		}
+		accessibility.Message = strings.Join(findings, "; ")
+		if !validation.AccessibilityCompliant || len(findings) > 0 {
+			accessibility.Status = SeverityWarning
+		}
+	}
+	return append(checks, accessibility)
+}
+
+func (d *analysisData) integrityChecks() []QCCheck {
+	var checks []QCCheck
+	if integrity := d.enhanced.DataIntegrityAnalysis; integrity != nil {
+		check := QCCheck{
+			ID:       "integrity.errors",
+			Status:   SeverityPass,
+			Severity: CheckCritical,
+			Measurements: []QCMeasurement{
+				{Name: "format_errors", Value: integrity.FormatErrors},
+				{Name: "bitstream_errors", Value: integrity.BitstreamErrors},
+				{Name: "packet_errors", Value: integrity.PacketErrors},
+				{Name: "continuity_errors", Value: integrity.ContinuityErrors},
+				{Name: "integrity_score", Value: integrity.IntegrityScore},
+			},
+		}
+		if scan := integrity.DecodeScan; scan != nil {
+			check.Measurements = append(check.Measurements,
+				QCMeasurement{Name: "decode_errors", Value: scan.DecodeErrors},
+				QCMeasurement{Name: "corrupt_frames", Value: scan.CorruptFrames},
+				QCMeasurement{Name: "decoded_seconds", Value: scan.DecodedSeconds, Unit: "s"})
+			for _, decodeError := range scan.Errors {
+				check.addEvidence(decodeError.Timestamp, 0, decodeError.Message)
+			}
+		}
+		if integrity.Validation != nil {
+			check.Message = strings.Join(integrity.Validation.Issues, "; ")
+		}
+		switch {
+		case integrity.IsCorrupted:
+			check.Status = SeverityFail
+		case integrity.FormatErrors+integrity.BitstreamErrors+integrity.PacketErrors+integrity.ContinuityErrors > 0 || check.Message != "":
+			check.Status = SeverityWarning
+		}
+		checks = append(checks, check)
+
+		if crossCheck := integrity.MediaInfoCrossCheck; crossCheck != nil {
+			checks = append(checks, mediaInfoCheck(crossCheck))
+		}
+	}
+
+	if timestamps := d.enhanced.TimestampAnalysis; timestamps != nil {
+		check := countCheck("integrity.timestamps", CheckMajor, SeverityWarning, "discontinuities", timestamps.TotalDiscontinuities)
+		for _, stream := range timestamps.Streams {
+			for _, kind := range timestampKinds {
+				if count := stream.Discontinuities[kind]; count > 0 {
+					check.Measurements = append(check.Measurements, QCMeasurement{Name: fmt.Sprintf("stream_%d_%s", stream.StreamIndex, kind), Value: count})
+				}
+			}
+		}
+		for _, discontinuity := range timestamps.Discontinuities {
+			description := fmt.Sprintf("Stream %d packet %d: %s", discontinuity.StreamIndex, discontinuity.Packet, strings.ReplaceAll(discontinuity.Kind, "_", " "))
+			if discontinuity.Delta != 0 {
+				description += fmt.Sprintf(" of %.3f s", discontinuity.Delta)
+			}
+			if discontinuity.ByteOffset != nil {
+				description += fmt.Sprintf(" at byte %d", *discontinuity.ByteOffset)
+			}
+			check.addEvidence(discontinuity.Timestamp, 0, description)
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// deliveryChecks maps the delivery profile rules to checks, or nil when no
+// profile was selected
+func deliveryChecks(compliance *ffmpeg.DeliveryCompliance) *QCCategoryResult {
+	if compliance == nil {
+		return nil
+	}
+
+	category := &QCCategoryResult{
+		ID:     compliance.Profile,
+		Name:   compliance.ProfileName,
+		Status: SeverityPass,
+		Checks: make([]QCCheck, 0, len(compliance.Checks)),
+	}
+	for _, rule := range compliance.Checks {
+		check := QCCheck{
+			ID:       "delivery." + rule.Rule,
+			Severity: CheckCritical,
+			Message:  "expected " + rule.Expected,
+		}
+		if rule.Actual != "" {
+			check.Measurements = []QCMeasurement{{Name: "actual", Value: rule.Actual}}
+		}
+		switch rule.Status {
+		case ffmpeg.DeliveryCheckPass:
+			check.Status = SeverityPass
+		case ffmpeg.DeliveryCheckWarning:
+			check.Status = SeverityWarning
+			check.Severity = CheckMinor
+		case ffmpeg.DeliveryCheckFail:
+			check.Status = SeverityFail
+		default:
+			check.Status = SeverityNotAnalyzed
+		}
+		if check.Status.rank() > category.Status.rank() {
+			category.Status = check.Status
+		}
+		category.Checks = append(category.Checks, check)
+	}
+	if !compliance.Compliant {
+		category.Status = SeverityFail
+	}
+	return category
+}
+
+// mediaInfoCheck reports each property MediaInfo and ffprobe disagree on,
+// with the MediaInfo values as measurements
+func mediaInfoCheck(crossCheck *ffmpeg.MediaInfoCrossCheck) QCCheck {
+	check := countCheck("integrity.mediainfo", CheckMajor, SeverityWarning, "discrepancies", crossCheck.Discrepancies)
+	var messages []string
+	for _, comparison := range crossCheck.Comparisons {
+		name := "mediainfo_" + comparison.Field
+		if comparison.Stream != nil {
+			name = fmt.Sprintf("stream_%d_mediainfo_%s", *comparison.Stream, comparison.Field)
+		}
+		check.Measurements = append(check.Measurements, QCMeasurement{Name: name, Value: comparison.MediaInfo})
+		if !comparison.Match {
+			messages = append(messages, comparison.Message)
+		}
+	}
+	check.Message = strings.Join(messages, "; ")
+	return check
+}
@@ -0,0 +1,472 @@
+// Package report renders QC analysis results as styled HTML and PDF
+// documents covering the 19 QC categories.
+package report
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+// Format is a rendered report format
+type Format string
+
+const (
+	FormatHTML Format = "html"
+	FormatPDF  Format = "pdf"
+	FormatCSV  Format = "csv" // Flat rows, see RenderFlat
+	FormatXML  Format = "xml" // Flat rows, see RenderFlat
+)
+
+// ParseFormat resolves a format name; empty selects HTML
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(name))) {
+	case "", FormatHTML:
+		return FormatHTML, nil
+	case FormatPDF:
+		return FormatPDF, nil
+	default:
+		return "", fmt.Errorf("unsupported report format %q (use html or pdf)", name)
+	}
+}
+
+// ContentType returns the MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatPDF:
+		return "application/pdf"
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXML:
+		return "application/xml; charset=utf-8"
+	default:
+		return "text/html; charset=utf-8"
+	}
+}
+
+// Thumbnails embedded in reports
+const (
+	ThumbnailCount = 4
+	ThumbnailWidth = 320
+)
+
+// CaptureThumbnails extracts the report stills for a local file. Files
+// without a video stream have none.
+func CaptureThumbnails(ctx context.Context, extractor *ffmpeg.ThumbnailExtractor, filePath string, result *ffmpeg.FFprobeResult) ([]ffmpeg.Thumbnail, error) {
+	if result == nil {
+		return nil, nil
+	}
+	hasVideo := false
+	for _, stream := range result.Streams {
+		if strings.EqualFold(stream.CodecType, "video") {
+			hasVideo = true
+			break
+		}
+	}
+	if !hasVideo {
+		return nil, nil
+	}
+
+	var duration float64
+	if result.Format != nil {
+		duration, _ = strconv.ParseFloat(result.Format.Duration, 64)
+	}
+	return extractor.Extract(ctx, filePath, duration, ThumbnailCount, ThumbnailWidth)
+}
+
+// CaptureTonemapPreviews renders SDR preview stills of an HDR file with the
+// tonemap operator. SDR files have none.
+func CaptureTonemapPreviews(ctx context.Context, extractor *ffmpeg.ThumbnailExtractor, filePath string, result *ffmpeg.FFprobeResult, operator string) (*ffmpeg.TonemapPreviews, error) {
+	if !isHDR(result) {
+		return nil, nil
+	}
+	var duration float64
+	if result.Format != nil {
+		duration, _ = strconv.ParseFloat(result.Format.Duration, 64)
+	}
+	return extractor.TonemapPreviews(ctx, filePath, duration, operator, ffmpeg.DefaultTonemapPreviewCount, ThumbnailWidth)
+}
+
+// isHDR reports whether the HDR analysis, or the transfer function of the
+// first video stream when HDR analysis did not run, marks the file as HDR
+func isHDR(result *ffmpeg.FFprobeResult) bool {
+	if result == nil {
+		return false
+	}
+	data := newAnalysisData(result)
+	if content := data.enhanced.ContentAnalysis; content != nil && content.HDRAnalysis != nil {
+		return content.HDRAnalysis.IsHDR
+	}
+	if data.video == nil {
+		return false
+	}
+	return data.video.ColorTransfer == "smpte2084" || data.video.ColorTransfer == "arib-std-b67"
+}
+
+// Severity grades a category or the report as a whole
+type Severity string
+
+const (
+	SeverityPass        Severity = "pass"
+	SeverityInfo        Severity = "info"
+	SeverityWarning     Severity = "warning"
+	SeverityFail        Severity = "fail"
+	SeverityNotAnalyzed Severity = "not_analyzed"
+)
+
+// severityOrder lists severities from best to worst for charts and totals
+var severityOrder = []Severity{SeverityPass, SeverityInfo, SeverityWarning, SeverityFail, SeverityNotAnalyzed}
+
+// Label returns the display name of the severity
+func (s Severity) Label() string {
+	switch s {
+	case SeverityPass:
+		return "Pass"
+	case SeverityInfo:
+		return "Info"
+	case SeverityWarning:
+		return "Warning"
+	case SeverityFail:
+		return "Fail"
+	default:
+		return "Not analyzed"
+	}
+}
+
+// color returns the RGB color used for the severity in both renderers
+func (s Severity) color() (int, int, int) {
+	switch s {
+	case SeverityPass:
+		return 0x2e, 0x7d, 0x32
+	case SeverityInfo:
+		return 0x15, 0x65, 0xc0
+	case SeverityWarning:
+		return 0xef, 0x6c, 0x00
+	case SeverityFail:
+		return 0xc6, 0x28, 0x28
+	default:
+		return 0x9e, 0x9e, 0x9e
+	}
+}
+
+// Color returns the severity color as a CSS hex value
+func (s Severity) Color() string {
+	r, g, b := s.color()
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
+}
+
+// rank orders severities for worst-of aggregation; not analyzed ranks lowest
+func (s Severity) rank() int {
+	switch s {
+	case SeverityFail:
+		return 4
+	case SeverityWarning:
+		return 3
+	case SeverityInfo:
+		return 2
+	case SeverityPass:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Field is one labelled value in a category table
+type Field struct {
+	Label string
+	Value string
+}
+
+// Category is one of the 19 QC categories, or the result of a plugin
+// analyzer numbered after them
+type Category struct {
+	Number   int
+	Name     string
+	Severity Severity
+	Fields   []Field
+	Findings []string // Issues and warnings raised by the analyzer
+}
+
+// Bar is one value in a chart
+type Bar struct {
+	Label    string
+	Value    float64
+	Severity Severity // Colors the bar
+}
+
+// Chart is a horizontal bar chart
+type Chart struct {
+	Title string
+	Unit  string
+	Bars  []Bar
+}
+
+// Max returns the largest bar value, used to scale the chart
+func (c Chart) Max() float64 {
+	maxValue := 0.0
+	for _, bar := range c.Bars {
+		if bar.Value > maxValue {
+			maxValue = bar.Value
+		}
+	}
+	return maxValue
+}
+
+// Timeline plot size in SVG user units; the HTML scales it to the page
+const (
+	timelineWidth     = 1000
+	timelineHeight    = 160
+	maxTimelineValues = 500
+)
+
+// Timeline is a line chart of values taken at a fixed interval
+type Timeline struct {
+	Title    string
+	Unit     string
+	Interval float64 // Seconds between values
+	Values   []float64
+	Limit    float64 // Drawn as a dashed line when positive and within the plot
+	Caption  string
+}
+
+// Max returns the top of the plot: the largest value, or the limit when it
+// is no more than half as large again so it stays readable
+func (t Timeline) Max() float64 {
+	maxValue := 0.0
+	for _, value := range t.Values {
+		maxValue = math.Max(maxValue, value)
+	}
+	if t.Limit > maxValue && t.Limit <= maxValue*1.5 {
+		maxValue = t.Limit
+	}
+	return maxValue * 1.05
+}
+
+// Duration returns the seconds the timeline covers
+func (t Timeline) Duration() float64 {
+	return float64(len(t.Values)) * t.Interval
+}
+
+// Points scales the values to a timelineWidth by timelineHeight plot with y
+// growing downwards. Long timelines keep the peak of every run of values
+// that share a point, so short spikes still show.
+func (t Timeline) Points() [][2]float64 {
+	maxValue := t.Max()
+	if len(t.Values) == 0 || maxValue <= 0 {
+		return nil
+	}
+	step := (len(t.Values) + maxTimelineValues - 1) / maxTimelineValues
+	count := (len(t.Values) + step - 1) / step
+
+	points := make([][2]float64, 0, count)
+	for i := 0; i < len(t.Values); i += step {
+		peak := 0.0
+		for _, value := range t.Values[i:min(i+step, len(t.Values))] {
+			peak = math.Max(peak, value)
+		}
+		x := 0.0
+		if count > 1 {
+			x = float64(len(points)) / float64(count-1) * timelineWidth
+		}
+		points = append(points, [2]float64{x, timelineHeight - peak/maxValue*timelineHeight})
+	}
+	return points
+}
+
+// LimitY returns the height of the limit line in plot units, or false when
+// the limit is off the plot
+func (t Timeline) LimitY() (float64, bool) {
+	maxValue := t.Max()
+	if t.Limit <= 0 || t.Limit > maxValue {
+		return 0, false
+	}
+	return timelineHeight - t.Limit/maxValue*timelineHeight, true
+}
+
+// Source identifies the analyzed media
+type Source struct {
+	AnalysisID string
+	Filename   string
+	AnalyzedAt time.Time
+}
+
+// Report is a rendered-ready QC report for one file
+type Report struct {
+	Source
+	Status          string // "success" or "error"
+	Error           string
+	Overall         Severity
+	Summary         []Field // Key media properties shown above the categories
+	Categories      []Category
+	Recommendations []string
+	Charts          []Chart
+	Timelines       []Timeline
+	Thumbnails      []ffmpeg.Thumbnail
+	TonemapPreviews *ffmpeg.TonemapPreviews // SDR stills of an HDR file, when requested
+	GeneratedAt     time.Time
+}
+
+// Build assembles the report for a finished analysis
+func Build(source Source, result *ffmpeg.FFprobeResult, thumbnails []ffmpeg.Thumbnail) *Report {
+	r := &Report{
+		Source:      source,
+		Status:      "success",
+		Thumbnails:  thumbnails,
+		GeneratedAt: time.Now(),
+	}
+	if result == nil {
+		r.Status = "error"
+		r.Error = "No analysis result"
+		r.Overall = SeverityFail
+		return r
+	}
+
+	data := newAnalysisData(result)
+	r.Summary = data.summary()
+	r.Categories = data.categories()
+	r.Categories = append(r.Categories, data.pluginCategories(len(r.Categories)+1)...)
+	r.Recommendations = data.recommendations()
+
+	r.Overall = SeverityPass
+	for _, category := range r.Categories {
+		if category.Severity.rank() > r.Overall.rank() {
+			r.Overall = category.Severity
+		}
+	}
+
+	r.Charts = append(r.Charts, r.severityChart())
+	if chart, ok := data.bitrateChart(); ok {
+		r.Charts = append(r.Charts, chart)
+	}
+	r.Timelines = data.bitrateTimelines()
+
+	return r
+}
+
+// Failed builds the report for an analysis that did not complete
+func Failed(source Source, message string) *Report {
+	return &Report{
+		Source:      source,
+		Status:      "error",
+		Error:       message,
+		Overall:     SeverityFail,
+		GeneratedAt: time.Now(),
+	}
+}
+
+// SeverityCounts returns the number of categories per severity
+func (r *Report) SeverityCounts() map[Severity]int {
+	counts := make(map[Severity]int)
+	for _, category := range r.Categories {
+		counts[category.Severity]++
+	}
+	return counts
+}
+
+func (r *Report) severityChart() Chart {
+	counts := r.SeverityCounts()
+	chart := Chart{Title: "Category results", Unit: "categories"}
+	for _, severity := range severityOrder {
+		chart.Bars = append(chart.Bars, Bar{
+			Label:    severity.Label(),
+			Value:    float64(counts[severity]),
+			Severity: severity,
+		})
+	}
+	return chart
+}
+
+// Render writes reports in the given format; several reports share one
+// document
+func Render(w io.Writer, format Format, reports ...*Report) error {
+	switch format {
+	case FormatHTML:
+		return renderHTML(w, reports)
+	case FormatPDF:
+		return renderPDF(w, reports)
+	default:
+		return fmt.Errorf("unsupported report format %q", format)
+	}
+}
+
+// formatTimestamp formats a thumbnail position as h:mm:ss
+func formatTimestamp(seconds float64) string {
+	total := int(seconds)
+	return fmt.Sprintf("%d:%02d:%02d", total/3600, total/60%60, total%60)
+}
+
+// formatBytes formats a byte count with a binary unit
+func formatBytes(value string) string {
+	size, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return orNA(value)
+	}
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s", size, units[unit])
+}
+
+// formatBitRate formats a bit rate in bits per second as kb/s or Mb/s
+func formatBitRate(value string) string {
+	rate, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return orNA(value)
+	}
+	if rate >= 1e6 {
+		return fmt.Sprintf("%.2f Mb/s", rate/1e6)
+	}
+	return fmt.Sprintf("%.0f kb/s", rate/1e3)
+}
+
+// formatDuration formats seconds as h:mm:ss.mmm
+func formatDuration(value string) string {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return orNA(value)
+	}
+	whole := int(seconds)
+	millis := int((seconds - float64(whole)) * 1000)
+	return fmt.Sprintf("%d:%02d:%02d.%03d", whole/3600, whole/60%60, whole%60, millis)
+}
+
+func orNA(value string) string {
+	if value == "" {
+		return "N/A"
+	}
+	return value
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "Yes"
+	}
+	return "No"
+}
+
+func passFailed(passed bool) string {
+	if passed {
+		return "Passed"
+	}
+	return "Failed"
+}
+
+// sortedKeys returns map keys in ascending order for stable output
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
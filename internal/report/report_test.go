@@ -0,0 +1,834 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rendiffdev/rendiff-probe/internal/ffmpeg"
+)
+
+func testResult() *ffmpeg.FFprobeResult {
+	return &ffmpeg.FFprobeResult{
+		Format: &ffmpeg.FormatInfo{
+			FormatName:     "mov,mp4,m4a,3gp,3g2,mj2",
+			FormatLongName: "QuickTime / MOV",
+			NBStreams:      2,
+			Duration:       "61.500000",
+			Size:           "10485760",
+			BitRate:        "1364000",
+			ProbeScore:     100,
+		},
+		Streams: []ffmpeg.StreamInfo{
+			{Index: 0, CodecType: "video", CodecName: "h264", Width: 1920, Height: 1080, AvgFrameRate: "25/1", BitRate: "1200000"},
+			{Index: 1, CodecType: "audio", CodecName: "aac", Channels: 2, SampleRate: "48000", BitRate: "128000"},
+		},
+		EnhancedAnalysis: &ffmpeg.EnhancedAnalysis{
+			AFDAnalysis: &ffmpeg.AFDAnalysis{
+				HasAFD:     true,
+				PrimaryAFD: &ffmpeg.AFDInfo{AFDValue: 10, AFDDescription: "Full frame 16:9 (center)", IsValid: true},
+				AFDSegments: []ffmpeg.AFDSegment{
+					{AFDValue: 10, StartFrame: 0, EndFrame: 99},
+					{AFDValue: 9, StartFrame: 100, EndFrame: 1536},
+				},
+				AFDChanges: []ffmpeg.AFDChange{{FrameNumber: 100, OldAFDValue: 10, NewAFDValue: 9}},
+			},
+			DeadPixelAnalysis: &ffmpeg.DeadPixelAnalysis{HotPixelCount: 1},
+			CodecAnalysis: &ffmpeg.CodecAnalysis{
+				Validation: &ffmpeg.CodecValidation{IsValid: true, Recommendations: []string{"Use a streaming-friendly profile"}},
+			},
+			DataIntegrityAnalysis: &ffmpeg.DataIntegrityAnalysis{
+				IntegrityScore: 100,
+				Validation:     &ffmpeg.DataIntegrityValidation{IsValid: true, Recommendations: []string{"Use a streaming-friendly profile"}},
+			},
+		},
+	}
+}
+
+func testThumbnail(t *testing.T) ffmpeg.Thumbnail {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, 32, 18))
+	for i := range img.Pix {
+		img.Pix[i] = 0x80
+	}
+	img.Set(0, 0, color.White)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("failed to encode test thumbnail: %v", err)
+	}
+	return ffmpeg.Thumbnail{Time: 30.75, Width: 32, Height: 18, JPEG: buf.Bytes()}
+}
+
+func TestBuild(t *testing.T) {
+	r := Build(Source{AnalysisID: "abc", Filename: "clip.mp4", AnalyzedAt: time.Now()}, testResult(), nil)
+
+	if len(r.Categories) != 19 {
+		t.Fatalf("expected 19 categories, got %d", len(r.Categories))
+	}
+	if r.Categories[0].Number != 1 || r.Categories[18].Number != 19 {
+		t.Errorf("categories should be numbered 1-19")
+	}
+
+	expected := map[string]Severity{
+		"AFD Analysis":            SeverityWarning,
+		"Dead Pixel Detection":    SeverityFail,
+		"PSE Flash Analysis":      SeverityNotAnalyzed,
+		"Codec Analysis":          SeverityPass,
+		"Data Integrity Analysis": SeverityPass,
+	}
+	for _, category := range r.Categories {
+		if severity, ok := expected[category.Name]; ok && category.Severity != severity {
+			t.Errorf("%s: expected %s, got %s", category.Name, severity, category.Severity)
+		}
+	}
+
+	if r.Overall != SeverityFail {
+		t.Errorf("expected overall fail, got %s", r.Overall)
+	}
+	if len(r.Recommendations) != 1 {
+		t.Errorf("expected duplicate recommendations to be merged, got %v", r.Recommendations)
+	}
+	if len(r.Charts) != 2 || len(r.Charts[1].Bars) != 2 || r.Charts[1].Bars[0].Value != 1200 {
+		t.Errorf("unexpected charts: %+v", r.Charts)
+	}
+}
+
+func TestBuild_RepairSuggestions(t *testing.T) {
+	result := testResult()
+	result.RepairSuggestions = []ffmpeg.RepairSuggestion{
+		{Issue: ffmpeg.RepairBrokenIndex, Title: "Remux to rebuild the index", Command: "ffmpeg -fflags +genpts -i input.mp4 -map 0 -c copy repaired.mp4", Lossless: true},
+	}
+	r := Build(Source{Filename: "clip.mp4"}, result, nil)
+
+	want := "Remux to rebuild the index: ffmpeg -fflags +genpts -i input.mp4 -map 0 -c copy repaired.mp4"
+	if len(r.Recommendations) != 2 || r.Recommendations[1] != want {
+		t.Errorf("expected the repair command after the analyzer recommendations, got %v", r.Recommendations)
+	}
+}
+
+func TestBitrateTimelines(t *testing.T) {
+	result := testResult()
+	percent := func(value float64) *float64 { return &value }
+	result.EnhancedAnalysis.BitrateAnalysis = &ffmpeg.BitrateAnalysis{
+		StreamIndex:      0,
+		AverageBitRate:   1200000,
+		PeakBitRate:      2400000,
+		PeakToAverage:    2,
+		TimelineInterval: 1,
+		Timeline: []ffmpeg.BitratePoint{
+			{Time: 0, BitRate: 1000000, BufferPercent: percent(90)},
+			{Time: 1, BitRate: 2400000, BufferPercent: percent(0)},
+			{Time: 2, BitRate: 200000},
+		},
+		VBV: &ffmpeg.VBVSimulation{
+			Profile: "Main", Level: "2.0", MaxBitRate: 2000000, BufferSize: 2000000, Underruns: 3, Overshoots: 1,
+			Events: []ffmpeg.VBVEvent{{Kind: ffmpeg.VBVOvershoot, Time: 1}, {Kind: ffmpeg.VBVUnderrun, Time: 1.5}},
+		},
+	}
+	r := Build(Source{Filename: "clip.mp4"}, result, nil)
+
+	for _, category := range r.Categories {
+		if category.Name == "Codec Analysis" && (category.Severity != SeverityWarning || len(category.Findings) != 2) {
+			t.Errorf("expected VBV warnings, got %+v", category)
+		}
+	}
+	if len(r.Timelines) != 2 || r.Timelines[0].Limit != 2000 || r.Timelines[1].Values[2] != 0 {
+		t.Fatalf("unexpected timelines %+v", r.Timelines)
+	}
+	if y, ok := r.Timelines[0].LimitY(); !ok || y <= 0 {
+		t.Errorf("expected the level maximum within the plot, got %v", y)
+	}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, FormatHTML, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `<polyline points="0.0,96.5 500.0,7.6 1000.0,147.3"`) {
+		t.Error("HTML output missing the bit rate polyline")
+	}
+	if err := Render(&buf, FormatPDF, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, expected := range map[string]Format{"": FormatHTML, "HTML": FormatHTML, " pdf": FormatPDF} {
+		format, err := ParseFormat(name)
+		if err != nil || format != expected {
+			t.Errorf("ParseFormat(%q) = %q, %v", name, format, err)
+		}
+	}
+	if _, err := ParseFormat("docx"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	r := Build(Source{AnalysisID: "abc", Filename: "<clip>.mp4"}, testResult(), []ffmpeg.Thumbnail{testThumbnail(t)})
+	r.TonemapPreviews = &ffmpeg.TonemapPreviews{Operator: "hable", Frames: []ffmpeg.Thumbnail{testThumbnail(t)}}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, FormatHTML, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	html := buf.String()
+
+	for _, want := range []string{
+		"&lt;clip&gt;.mp4",
+		"data:image/jpeg;base64,",
+		"0:00:30",
+		"19. Data Integrity Analysis",
+		"background: " + SeverityFail.Color(),
+		"Use a streaming-friendly profile",
+		"with the hable operator",
+		`alt="SDR frame at 0:00:30"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("HTML output missing %q", want)
+		}
+	}
+	if strings.Contains(html, "ZgotmplZ") {
+		t.Error("HTML output contains escaped unsafe content")
+	}
+}
+
+func TestRenderPDF(t *testing.T) {
+	reports := []*Report{
+		Build(Source{AnalysisID: "abc", Filename: "clip.mp4"}, testResult(), []ffmpeg.Thumbnail{testThumbnail(t)}),
+		Failed(Source{Filename: "broken.mp4"}, "file not found"),
+	}
+	reports[0].TonemapPreviews = &ffmpeg.TonemapPreviews{Operator: "mobius", Frames: []ffmpeg.Thumbnail{testThumbnail(t)}}
+
+	var buf bytes.Buffer
+	if err := Render(&buf, FormatPDF, reports...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Error("output is not a PDF document")
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("/DCTDecode")) {
+		t.Error("PDF should embed the JPEG thumbnail")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	source := testResult()
+	target := testResult()
+	target.Format.BitRate = "564000"
+	target.Streams[0].BitRate = "400000"
+	target.Streams[0].Width, target.Streams[0].Height = 1280, 720
+	target.EnhancedAnalysis.DeadPixelAnalysis = nil
+	source.EnhancedAnalysis.ContentAnalysis = &ffmpeg.ContentAnalysis{
+		LoudnessMeter: &ffmpeg.LoudnessAnalysis{IntegratedLoudness: -23, TruePeak: -1.5, Compliant: true, Standard: "EBU R128"},
+	}
+	target.EnhancedAnalysis.ContentAnalysis = &ffmpeg.ContentAnalysis{
+		LoudnessMeter: &ffmpeg.LoudnessAnalysis{IntegratedLoudness: -16, TruePeak: -0.2, Standard: "EBU R128"},
+	}
+
+	got := make(map[string]Difference)
+	for _, difference := range Diff(source, target) {
+		got[difference.Property] = difference
+	}
+
+	expected := map[string]Difference{
+		"Overall Bit Rate":     {AreaContainer, "Overall Bit Rate", "1.36 Mb/s", "564 kb/s"},
+		"Resolution":           {AreaCodec, "Resolution", "1920x1080", "1280x720"},
+		"Video Bit Rate":       {AreaCodec, "Video Bit Rate", "1.20 Mb/s", "400 kb/s"},
+		"Integrated Loudness":  {AreaLoudness, "Integrated Loudness", "-23.0 LUFS", "-16.0 LUFS"},
+		"True Peak":            {AreaLoudness, "True Peak", "-1.5 dBTP", "-0.2 dBTP"},
+		"Loudness Compliance":  {AreaLoudness, "Loudness Compliance", "Compliant (EBU R128)", "Not compliant (EBU R128)"},
+		"Dead Pixel Detection": {AreaCompliance, "Dead Pixel Detection", "Fail", "Not analyzed"},
+	}
+	for property, want := range expected {
+		if got[property] != want {
+			t.Errorf("%s: got %+v, want %+v", property, got[property], want)
+		}
+	}
+	for _, unchanged := range []string{"Container", "Video Codec", "Audio Codec", "Codec Analysis"} {
+		if _, ok := got[unchanged]; ok {
+			t.Errorf("%s is unchanged but listed as a difference", unchanged)
+		}
+	}
+
+	if differences := Diff(source, source); len(differences) != 0 {
+		t.Errorf("expected no differences for identical analyses, got %+v", differences)
+	}
+}
+
+func TestDiffAnalyses(t *testing.T) {
+	a := testResult()
+	a.Streams = append(a.Streams, ffmpeg.StreamInfo{Index: 2, CodecType: "audio", CodecName: "aac", Channels: 2, SampleRate: "48000", Tags: map[string]string{"language": "fre"}})
+	a.EnhancedAnalysis.ContentAnalysis = &ffmpeg.ContentAnalysis{
+		LoudnessMeter: &ffmpeg.LoudnessAnalysis{IntegratedLoudness: -23, TruePeak: -1.5, LoudnessRange: 8},
+	}
+	b := testResult()
+	b.Format.Duration = "61.520000"
+	b.Streams[0].CodecName = "hevc"
+	b.Streams[1].Channels = 6
+	b.Streams = append(b.Streams, ffmpeg.StreamInfo{Index: 2, CodecType: "subtitle", CodecName: "mov_text"})
+	b.EnhancedAnalysis.ContentAnalysis = &ffmpeg.ContentAnalysis{
+		LoudnessMeter: &ffmpeg.LoudnessAnalysis{IntegratedLoudness: -23.4, TruePeak: -1, LoudnessRange: 7.5},
+	}
+
+	diff := DiffAnalyses(a, b)
+	if diff.Preserved {
+		t.Error("a lost audio stream and a channel change should not count as preserved")
+	}
+	if len(diff.Format) != 1 || diff.Format[0].Property != "Duration" || diff.Format[0].Essential {
+		t.Errorf("Format = %+v, want a non-essential duration change", diff.Format)
+	}
+	if len(diff.Streams) != 2 {
+		t.Fatalf("Streams = %+v, want the video and first audio stream paired", diff.Streams)
+	}
+	if changes := diff.Streams[0].Changes; len(changes) != 1 || changes[0] != (Change{"Codec", "h264", "hevc", false}) {
+		t.Errorf("video changes = %+v, want only the codec", changes)
+	}
+	if changes := diff.Streams[1].Changes; len(changes) != 1 || changes[0] != (Change{"Channels", "2", "6", true}) {
+		t.Errorf("audio changes = %+v, want an essential channel change", changes)
+	}
+	if len(diff.RemovedStreams) != 1 || diff.RemovedStreams[0].Description != "aac 2 ch @ 48000 Hz (fre)" {
+		t.Errorf("RemovedStreams = %+v, want the French audio stream", diff.RemovedStreams)
+	}
+	if len(diff.AddedStreams) != 1 || diff.AddedStreams[0].CodecType != "subtitle" {
+		t.Errorf("AddedStreams = %+v, want the subtitle stream", diff.AddedStreams)
+	}
+	loudness := diff.Loudness
+	if loudness == nil || loudness.IntegratedDelta != -0.4 || loudness.TruePeakDelta != 0.5 || loudness.RangeDelta != -0.5 || !loudness.WithinTolerance {
+		t.Errorf("Loudness = %+v, want -0.4 LU, +0.5 dB, -0.5 LU within tolerance", loudness)
+	}
+
+	if same := DiffAnalyses(a, a); !same.Preserved || len(same.Format) != 0 || len(same.AddedStreams) != 0 || len(same.RemovedStreams) != 0 {
+		t.Errorf("identical analyses: %+v", same)
+	}
+}
+
+func TestRenderFlat(t *testing.T) {
+	results := []FlatResult{
+		{Source: Source{AnalysisID: "a1", Filename: "clip.mp4"}, Result: testResult()},
+		{Source: Source{AnalysisID: "a2", Filename: "broken.mp4"}, Error: "ffprobe failed"},
+	}
+
+	var buf bytes.Buffer
+	if err := RenderFlat(&buf, FormatCSV, results...); err != nil {
+		t.Fatalf("RenderFlat csv: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d CSV lines, want a header, 2 stream rows and an error row:\n%s", len(lines), buf.String())
+	}
+	if lines[0] != strings.Join(FlatColumns, ",") {
+		t.Errorf("header = %q", lines[0])
+	}
+	for _, want := range []string{"a1,clip.mp4,,success,,fail,", ",0,video,h264,,1920,1080,", ",1,audio,aac,"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("CSV is missing %q:\n%s", want, buf.String())
+		}
+	}
+	if !strings.HasPrefix(lines[3], "a2,broken.mp4,,error,ffprobe failed,") {
+		t.Errorf("error row = %q", lines[3])
+	}
+	records, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV does not parse: %v", err)
+	}
+	if container := records[1][10]; container != "mov,mp4,m4a,3gp,3g2,mj2" {
+		t.Errorf("container = %q", container)
+	}
+
+	buf.Reset()
+	if err := RenderFlat(&buf, FormatXML, results...); err != nil {
+		t.Fatalf("RenderFlat xml: %v", err)
+	}
+	xml := buf.String()
+	if strings.Count(xml, "<row>") != 3 || !strings.Contains(xml, "<codec_name>h264</codec_name>") || !strings.Contains(xml, "<error>ffprobe failed</error>") {
+		t.Errorf("unexpected XML:\n%s", xml)
+	}
+	if strings.Contains(xml, "<profile></profile>") {
+		t.Error("empty columns should be left out of the XML")
+	}
+}
+
+func TestBuildQCResult(t *testing.T) {
+	result := testResult()
+	result.EnhancedAnalysis.ContentAnalysis = &ffmpeg.ContentAnalysis{
+		SilenceInfo: &ffmpeg.SilenceAnalysis{
+			TotalSilenceCount:  1,
+			TotalSilenceSec:    4,
+			HasProblematicMute: true,
+			SilencePeriods:     []ffmpeg.SilencePeriod{{StartTime: 10, EndTime: 14, Duration: 4}},
+		},
+		HDRAnalysis: &ffmpeg.HDRAnalysis{
+			IsHDR:     true,
+			HDRFormat: "HDR10",
+			Validation: &ffmpeg.HDRValidation{
+				IsCompliant: true,
+				Issues:      []string{"MaxFALL of 500 cd/m² exceeds MaxCLL of 400 cd/m²"},
+				Standards: []ffmpeg.HDRStandardCheck{
+					{ID: ffmpeg.HDRStandardPQ, Standard: "SMPTE ST 2084", Required: true, Passed: true},
+					{ID: ffmpeg.HDRStandardLightLevel, Standard: "CTA-861.3", Issues: []string{"MaxFALL of 500 cd/m² exceeds MaxCLL of 400 cd/m²"}},
+				},
+			},
+		},
+	}
+	result.EnhancedAnalysis.DeliveryCompliance = &ffmpeg.DeliveryCompliance{
+		Profile: "netflix_imf",
+		Checks: []ffmpeg.DeliveryCheck{
+			{Rule: "loudness.integrated", Status: ffmpeg.DeliveryCheckFail, Expected: "-27 LUFS", Actual: "-20 LUFS"},
+		},
+	}
+	offset := int64(188000)
+	result.EnhancedAnalysis.TimestampAnalysis = &ffmpeg.TimestampAnalysis{
+		HasDiscontinuities:   true,
+		TotalDiscontinuities: 1,
+		Streams:              []*ffmpeg.StreamTimestamps{{StreamIndex: 1, Discontinuities: map[string]int{ffmpeg.TimestampGap: 1}}},
+		Discontinuities: []ffmpeg.TimestampDiscontinuity{
+			{StreamIndex: 1, Kind: ffmpeg.TimestampGap, Packet: 42, ByteOffset: &offset, Timestamp: 12.5, Expected: 12.479, Delta: 0.021},
+		},
+	}
+
+	result.EnhancedAnalysis.ColorConsistencyAnalysis = &ffmpeg.ColorConsistencyAnalysis{
+		Streams: []*ffmpeg.StreamColorTags{{StreamIndex: 0, MissingTags: []string{"color_primaries"}}},
+		Issues:  []string{"Video stream 0 has no color_primaries tag"},
+	}
+
+	qc := BuildQCResult(Source{AnalysisID: "abc", Filename: "clip.mp4"}, result)
+	if qc.SchemaVersion != QCResultSchemaVersion || qc.Status != SeverityFail {
+		t.Fatalf("schema %s status %s, want %s fail", qc.SchemaVersion, qc.Status, QCResultSchemaVersion)
+	}
+	if len(qc.Categories) != len(ffmpeg.AllQCCategories) {
+		t.Fatalf("expected %d categories, got %d", len(ffmpeg.AllQCCategories), len(qc.Categories))
+	}
+	if qc.Summary.Failed != 1 || qc.Summary.Passed+qc.Summary.Info+qc.Summary.Warnings+qc.Summary.Failed+qc.Summary.NotAnalyzed != 19 {
+		t.Errorf("summary = %+v", qc.Summary)
+	}
+
+	checks := make(map[string]QCCheck)
+	for _, category := range qc.Categories {
+		for _, check := range category.Checks {
+			if !strings.HasPrefix(check.ID, category.ID+".") {
+				t.Errorf("check %s listed under %s", check.ID, category.ID)
+			}
+			checks[check.ID] = check
+		}
+	}
+	if check := checks["dead_pixel.hot"]; check.Status != SeverityFail || check.Severity != CheckMajor {
+		t.Errorf("dead_pixel.hot = %+v", check)
+	}
+	if check := checks["afd.changes"]; check.Status != SeverityWarning || len(check.Evidence) != 1 {
+		t.Errorf("afd.changes = %+v", check)
+	}
+	silence := checks["content.silence"]
+	if silence.Status != SeverityWarning || len(silence.Evidence) != 1 || silence.Evidence[0].StartSeconds != 10 || silence.Evidence[0].EndSeconds != 14 {
+		t.Errorf("content.silence = %+v", silence)
+	}
+	timestamps := checks["integrity.timestamps"]
+	if timestamps.Status != SeverityWarning || len(timestamps.Measurements) != 2 || timestamps.Measurements[1].Name != "stream_1_gap" ||
+		len(timestamps.Evidence) != 1 || timestamps.Evidence[0].Description != "Stream 1 packet 42: gap of 0.021 s at byte 188000" {
+		t.Errorf("integrity.timestamps = %+v", timestamps)
+	}
+	if check := checks["hdr.st2084"]; check.Status != SeverityPass || check.Severity != CheckMajor {
+		t.Errorf("hdr.st2084 = %+v", check)
+	}
+	if check := checks["hdr.cta861_3"]; check.Status != SeverityWarning || check.Message != "CTA-861.3: MaxFALL of 500 cd/m² exceeds MaxCLL of 400 cd/m²" {
+		t.Errorf("hdr.cta861_3 = %+v", check)
+	}
+	if check := checks["hdr.color_tags"]; check.Status != SeverityWarning || check.Message != "Video stream 0 has no color_primaries tag" ||
+		check.Measurements[0].Value != 1 {
+		t.Errorf("hdr.color_tags = %+v", check)
+	}
+	if check := checks["codec.validation"]; check.Status != SeverityPass {
+		t.Errorf("codec.validation = %+v", check)
+	}
+
+	if qc.Delivery == nil || qc.Delivery.Status != SeverityFail || len(qc.Delivery.Checks) != 1 || qc.Delivery.Checks[0].ID != "delivery.loudness.integrated" {
+		t.Errorf("delivery = %+v", qc.Delivery)
+	}
+
+	if failed := BuildQCResult(Source{}, nil); failed.Status != SeverityFail || failed.SchemaVersion != QCResultSchemaVersion {
+		t.Errorf("result without analysis = %+v", failed)
+	}
+}
+
+func TestDecodeScanIntegrity(t *testing.T) {
+	result := testResult()
+	integrity := result.EnhancedAnalysis.DataIntegrityAnalysis
+	integrity.IsCorrupted = true
+	integrity.DecodeScan = &ffmpeg.DecodeScan{
+		DecodeErrors:   1,
+		CorruptFrames:  1,
+		DecodedSeconds: 60.5,
+		Completed:      true,
+		Errors: []ffmpeg.DecodeError{
+			{Timestamp: 12.48, Source: "h264", Message: "error while decoding MB 31 17, bytestream -7"},
+			{Timestamp: 12.48, Source: "h264", Message: "corrupt decoded frame", CorruptFrame: true},
+		},
+	}
+
+	category := newAnalysisData(result).dataIntegrity()
+	if category.Severity != SeverityFail {
+		t.Errorf("severity = %s, want fail", category.Severity)
+	}
+	if !slices.Contains(category.Findings, "12.48s: error while decoding MB 31 17, bytestream -7") {
+		t.Errorf("findings = %v", category.Findings)
+	}
+	if !slices.Contains(category.Fields, Field{"Corrupt Frames", "1"}) {
+		t.Errorf("fields = %v", category.Fields)
+	}
+
+	var check *QCCheck
+	for _, candidate := range newAnalysisData(result).integrityChecks() {
+		if candidate.ID == "integrity.errors" {
+			check = &candidate
+		}
+	}
+	if check == nil || check.Status != SeverityFail || len(check.Evidence) != 2 || check.Evidence[0].StartSeconds != 12.48 {
+		t.Fatalf("integrity.errors = %+v", check)
+	}
+	if !slices.Contains(check.Measurements, QCMeasurement{Name: "corrupt_frames", Value: 1}) {
+		t.Errorf("measurements = %+v", check.Measurements)
+	}
+}
+
+func TestBuildQCResult_AudioChannels(t *testing.T) {
+	result := testResult()
+	result.EnhancedAnalysis.ContentAnalysis = &ffmpeg.ContentAnalysis{
+		AudioStreams: map[int]*ffmpeg.AudioStreamAnalysis{
+			1: {
+				StreamIndex: 1,
+				ChannelSilence: []ffmpeg.ChannelSilence{
+					{Channel: 0, Name: "FL", Silence: &ffmpeg.SilenceAnalysis{}},
+					{Channel: 4, Name: "BL", Silence: &ffmpeg.SilenceAnalysis{
+						HasProblematicMute: true,
+						LongestSilenceSec:  5.5,
+						SilencePeriods:     []ffmpeg.SilencePeriod{{StartTime: 5, EndTime: 10.5, Duration: 5.5}},
+					}},
+				},
+				ChannelPairs: []ffmpeg.ChannelPairPhase{
+					{Pair: "L/R", Left: 0, Right: 1, Phase: &ffmpeg.PhaseAnalysis{}},
+					{Pair: "Ls/Rs", Left: 4, Right: 5, Phase: &ffmpeg.PhaseAnalysis{HasPhaseIssues: true, OutOfPhasePercent: 40}},
+				},
+			},
+		},
+	}
+
+	qc := BuildQCResult(Source{AnalysisID: "abc", Filename: "clip.mxf"}, result)
+	statuses := make(map[string]Severity)
+	for _, category := range qc.Categories {
+		for _, check := range category.Checks {
+			if check.Stream != nil && *check.Stream == 1 {
+				statuses[check.ID+" "+check.Message] = check.Status
+			}
+		}
+	}
+	want := map[string]Severity{
+		"content.silence FL":  SeverityPass,
+		"content.silence BL":  SeverityWarning,
+		"content.phase L/R":   SeverityPass,
+		"content.phase Ls/Rs": SeverityWarning,
+	}
+	if !maps.Equal(statuses, want) {
+		t.Errorf("channel checks = %v, want %v", statuses, want)
+	}
+
+	_, findings := audioStreamFields(result.EnhancedAnalysis.ContentAnalysis.AudioStreams, nil, nil)
+	if len(findings) != 2 || findings[0] != "Audio Stream 1 channel BL problematic mute, longest 5.5 s" || findings[1] != "Audio Stream 1 Ls/Rs out of phase 40.0% of the time" {
+		t.Errorf("findings = %q", findings)
+	}
+}
+
+func TestBuildQCResult_AudioMetadata(t *testing.T) {
+	result := testResult()
+	result.EnhancedAnalysis.ContentAnalysis = &ffmpeg.ContentAnalysis{
+		AudioStreams: map[int]*ffmpeg.AudioStreamAnalysis{
+			1: {
+				StreamIndex: 1,
+				Language:    "eng",
+				MetadataChecks: []ffmpeg.AudioMetadataCheck{
+					{Check: "channel_layout", Passed: false, Declared: "5.1", Measured: "4 of 6 channels active", Message: "5.1 stream has BL, BR silent throughout"},
+					{Check: "language", Passed: true, Declared: "eng", Measured: "38.0% speech"},
+				},
+			},
+		},
+	}
+
+	statuses := make(map[string]Severity)
+	for _, category := range BuildQCResult(Source{AnalysisID: "abc", Filename: "clip.mxf"}, result).Categories {
+		for _, check := range category.Checks {
+			if strings.HasPrefix(check.ID, "content.audio_metadata.") {
+				statuses[check.ID] = check.Status
+			}
+		}
+	}
+	want := map[string]Severity{
+		"content.audio_metadata.channel_layout": SeverityFail,
+		"content.audio_metadata.language":       SeverityPass,
+	}
+	if !maps.Equal(statuses, want) {
+		t.Errorf("metadata checks = %v, want %v", statuses, want)
+	}
+
+	category := newAnalysisData(result).content()
+	if category.Severity != SeverityFail || !slices.Contains(category.Findings, "Audio Stream 1 (eng): 5.1 stream has BL, BR silent throughout") {
+		t.Errorf("content category = %s, findings %q", category.Severity, category.Findings)
+	}
+}
+
+func TestBuildQCResult_MediaInfo(t *testing.T) {
+	stream := 1
+	result := testResult()
+	result.EnhancedAnalysis.DataIntegrityAnalysis.MediaInfoCrossCheck = &ffmpeg.MediaInfoCrossCheck{
+		Version:       "23.04",
+		Discrepancies: 1,
+		Comparisons: []ffmpeg.MediaInfoComparison{
+			{Field: ffmpeg.MediaInfoFieldDuration, FFprobe: "61.500 s", MediaInfo: "61.480 s", Match: true},
+			{Field: ffmpeg.MediaInfoFieldAudioChannels, Stream: &stream, FFprobe: "2", MediaInfo: "6", Message: "Audio stream 1 has 2 channels according to ffprobe, 6 according to MediaInfo"},
+		},
+	}
+
+	var check QCCheck
+	for _, category := range BuildQCResult(Source{AnalysisID: "abc", Filename: "clip.mp4"}, result).Categories {
+		for _, c := range category.Checks {
+			if c.ID == "integrity.mediainfo" {
+				check = c
+			}
+		}
+	}
+	if check.Status != SeverityWarning || check.Message != "Audio stream 1 has 2 channels according to ffprobe, 6 according to MediaInfo" ||
+		len(check.Measurements) != 3 || check.Measurements[2].Name != "stream_1_mediainfo_audio_channels" || check.Measurements[2].Value != "6" {
+		t.Errorf("integrity.mediainfo = %+v", check)
+	}
+
+	category := newAnalysisData(result).dataIntegrity()
+	if category.Severity != SeverityWarning || !slices.Contains(category.Findings, "MediaInfo cross-check: Audio stream 1 has 2 channels according to ffprobe, 6 according to MediaInfo (ffprobe 2, MediaInfo 6)") {
+		t.Errorf("data integrity category = %s, findings %q", category.Severity, category.Findings)
+	}
+}
+
+func TestBuildQCResult_Disposition(t *testing.T) {
+	result := testResult()
+	result.EnhancedAnalysis.StreamDispositionAnalysis = &ffmpeg.StreamDispositionAnalysis{
+		HasMainStreams:     true,
+		AccessibilityScore: 0,
+		PolicyViolations: []ffmpeg.DispositionViolation{
+			{Rule: ffmpeg.DispositionRuleDefaultAudio, Severity: ffmpeg.DispositionViolationError, Streams: []int{1, 2}, Language: "eng", Message: "2 eng audio streams are flagged default (streams 1, 2)"},
+			{Rule: ffmpeg.DispositionRuleDefaultSubtitles, Severity: ffmpeg.DispositionViolationWarning, Streams: []int{3}, Language: "eng", Message: "Subtitle stream 3 is flagged default but not forced, so it shows for every viewer"},
+		},
+		Validation: &ffmpeg.DispositionValidation{
+			Issues: []string{"No accessibility subtitles found", "2 eng audio streams are flagged default (streams 1, 2)", "Subtitle stream 3 is flagged default but not forced, so it shows for every viewer"},
+		},
+	}
+
+	statuses := make(map[string]Severity)
+	checks := make(map[string]QCCheck)
+	for _, category := range BuildQCResult(Source{AnalysisID: "abc", Filename: "clip.mkv"}, result).Categories {
+		if category.ID != string(ffmpeg.QCCategoryDisposition) {
+			continue
+		}
+		if category.Status != SeverityFail {
+			t.Errorf("disposition category = %s, want fail", category.Status)
+		}
+		for _, check := range category.Checks {
+			statuses[check.ID] = check.Status
+			checks[check.ID] = check
+		}
+	}
+	want := map[string]Severity{
+		"disposition.default_video":     SeverityPass,
+		"disposition.default_audio":     SeverityFail,
+		"disposition.forced_subtitles":  SeverityPass,
+		"disposition.default_subtitles": SeverityWarning,
+		"disposition.accessibility":     SeverityWarning,
+	}
+	if !maps.Equal(statuses, want) {
+		t.Errorf("disposition checks = %v, want %v", statuses, want)
+	}
+	if check := checks["disposition.default_audio"]; check.Severity != CheckMajor || check.Message != "2 eng audio streams are flagged default (streams 1, 2)" {
+		t.Errorf("disposition.default_audio = %+v", check)
+	}
+	if check := checks["disposition.accessibility"]; check.Message != "No accessibility subtitles found" {
+		t.Errorf("disposition.accessibility = %+v", check)
+	}
+}
+
+func TestBuildQCResult_Plugins(t *testing.T) {
+	result := testResult()
+	result.EnhancedAnalysis.Plugins = []*ffmpeg.PluginAnalysis{
+		{
+			Name:    "slate_ocr",
+			Title:   "Slate OCR",
+			Status:  ffmpeg.PluginStatusWarning,
+			Summary: "Slate found",
+			Fields:  []ffmpeg.PluginField{{Label: "Title", Value: "Episode 1"}},
+			Checks: []ffmpeg.PluginCheck{
+				{ID: "slate_ocr.title", Status: ffmpeg.PluginStatusWarning, Severity: "minor", Message: "Title differs",
+					Evidence: []ffmpeg.PluginEvidence{{StartSeconds: 0, EndSeconds: 10}}},
+			},
+		},
+		{Name: "watermark", Status: ffmpeg.PluginStatusPass, Findings: []string{"No watermark"}},
+		{Name: "crash", Status: ffmpeg.PluginStatusNotAnalyzed, Error: "exit status 3"},
+	}
+
+	r := Build(Source{AnalysisID: "abc", Filename: "clip.mp4"}, result, nil)
+	if len(r.Categories) != 22 {
+		t.Fatalf("expected 22 categories, got %d", len(r.Categories))
+	}
+	slate := r.Categories[19]
+	if slate.Number != 20 || slate.Name != "Slate OCR" || slate.Severity != SeverityWarning || len(slate.Fields) != 2 || slate.Fields[1].Value != "Episode 1" {
+		t.Errorf("slate_ocr category = %+v", slate)
+	}
+	if crash := r.Categories[21]; crash.Name != "crash" || crash.Severity != SeverityNotAnalyzed || crash.Findings[0] != "Plugin failed: exit status 3" {
+		t.Errorf("crash category = %+v", crash)
+	}
+
+	qc := BuildQCResult(Source{AnalysisID: "abc", Filename: "clip.mp4"}, result)
+	if len(qc.Categories) != len(ffmpeg.AllQCCategories)+3 {
+		t.Fatalf("expected %d categories, got %d", len(ffmpeg.AllQCCategories)+3, len(qc.Categories))
+	}
+	if qc.Summary.Passed+qc.Summary.Info+qc.Summary.Warnings+qc.Summary.Failed+qc.Summary.NotAnalyzed != 22 {
+		t.Errorf("summary = %+v", qc.Summary)
+	}
+	plugins := qc.Categories[len(ffmpeg.AllQCCategories):]
+	if plugins[0].ID != "slate_ocr" || len(plugins[0].Checks) != 1 || plugins[0].Checks[0].Severity != CheckMinor || len(plugins[0].Checks[0].Evidence) != 1 {
+		t.Errorf("slate_ocr = %+v", plugins[0])
+	}
+	if check := plugins[1].Checks; len(check) != 1 || check[0].ID != "watermark.validation" || check[0].Status != SeverityPass || check[0].Message != "No watermark" {
+		t.Errorf("watermark checks = %+v", check)
+	}
+	if plugins[2].Status != SeverityNotAnalyzed || len(plugins[2].Checks) != 0 {
+		t.Errorf("crash = %+v", plugins[2])
+	}
+}
+
+func markerResult() *ffmpeg.FFprobeResult {
+	result := testResult()
+	result.Streams[0].RFrameRate = "30000/1001"
+	result.Streams[0].Tags = map[string]string{"timecode": "01:00:00;00"}
+	result.EnhancedAnalysis.ContentAnalysis = &ffmpeg.ContentAnalysis{
+		BlackFrames: &ffmpeg.BlackFrameAnalysis{
+			DetectedFrames: 1,
+			Periods:        []ffmpeg.EventPeriod{{StartTime: 60, EndTime: 62, Duration: 2}},
+		},
+		FreezeFrames: &ffmpeg.FreezeFrameAnalysis{
+			DetectedFrames: 1,
+			Periods:        []ffmpeg.EventPeriod{{StartTime: 10, EndTime: 12.5, Duration: 2.5}},
+		},
+		AudioStreams: map[int]*ffmpeg.AudioStreamAnalysis{
+			1: {Silence: &ffmpeg.SilenceAnalysis{SilencePeriods: []ffmpeg.SilencePeriod{{StartTime: 20, EndTime: 21, Duration: 1}}}},
+		},
+	}
+	return result
+}
+
+func TestMarkers(t *testing.T) {
+	markers := Markers(markerResult())
+	if len(markers) != 3 {
+		t.Fatalf("expected 3 markers, got %+v", markers)
+	}
+	if markers[0].Type != MarkerFreeze || markers[1].Type != MarkerSilence || markers[2].Type != MarkerBlack {
+		t.Errorf("markers out of timeline order: %+v", markers)
+	}
+	if !markers[1].Audio || markers[1].Stream == nil || *markers[1].Stream != 1 {
+		t.Errorf("silence marker = %+v, want audio stream 1", markers[1])
+	}
+
+	timebase := NewTimebase(markerResult())
+	if timebase.Base != 30 || !timebase.DropFrame || timebase.StartTimecode != "01:00:00;00" {
+		t.Errorf("timebase = %+v", timebase)
+	}
+	// 60 s at 29.97 fps is 1798 frames, two short of the first drop frame minute
+	if tc := timebase.Timecode(60); tc != "01:00:59;28" {
+		t.Errorf("Timecode(60) = %s", tc)
+	}
+
+	if tc := NewTimebase(testResult()).Timecode(61.5); tc != "00:01:01:12" {
+		t.Errorf("Timecode without start timecode = %s, want 00:01:01:12", tc)
+	}
+}
+
+func TestRenderMarkers(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderMarkers(&buf, MarkerFormatCSV, "clip.mp4", markerResult()); err != nil {
+		t.Fatalf("RenderMarkers csv: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(rows) != 4 || rows[1][0] != MarkerFreeze || rows[1][1] != "01:00:09;29" || rows[2][6] != "1" {
+		t.Errorf("unexpected CSV rows: %v", rows)
+	}
+
+	buf.Reset()
+	if err := RenderMarkers(&buf, MarkerFormatEDL, "clip.mp4", markerResult()); err != nil {
+		t.Fatalf("RenderMarkers edl: %v", err)
+	}
+	edl := buf.String()
+	for _, want := range []string{
+		"TITLE: clip.mp4\nFCM: DROP FRAME\n",
+		"002  AX       A     C        01:00:19;29 01:00:20;29 01:00:19;29 01:00:20;29\n* LOC: 01:00:19;29 YELLOW  Silence 1.00 s\n",
+	} {
+		if !strings.Contains(edl, want) {
+			t.Errorf("EDL missing %q:\n%s", want, edl)
+		}
+	}
+
+	buf.Reset()
+	if err := RenderMarkers(&buf, MarkerFormatAvid, "clip.mp4", markerResult()); err != nil {
+		t.Fatalf("RenderMarkers avid: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || lines[2] != "rendiffprobe\t01:00:59;28\tV1\tred\tBlack 2.00 s\t60" {
+		t.Errorf("unexpected Avid locators: %q", lines)
+	}
+}
+
+func TestRecommendLadder(t *testing.T) {
+	result := &ffmpeg.FFprobeResult{
+		Streams: []ffmpeg.StreamInfo{
+			{Index: 0, CodecType: "audio"},
+			{Index: 1, CodecType: "video", Width: 1920, Height: 1080, DisplayAspectRatio: "16:9", AvgFrameRate: "50/1"},
+		},
+	}
+	if _, err := RecommendLadder(result); err != ErrNoComplexity {
+		t.Errorf("RecommendLadder without complexity = %v, want ErrNoComplexity", err)
+	}
+
+	result.EnhancedAnalysis = &ffmpeg.EnhancedAnalysis{ContentAnalysis: &ffmpeg.ContentAnalysis{
+		TemporalComplexity: &ffmpeg.TemporalComplexityAnalysis{
+			SITI: &ffmpeg.SITIMeasurement{SpatialAverage: 75, TemporalAverage: 20, Frames: 500},
+		},
+	}}
+	ladder, err := RecommendLadder(result)
+	if err != nil {
+		t.Fatalf("RecommendLadder: %v", err)
+	}
+	if ladder.Complexity != 0.63 || ladder.ComplexityClass != "medium" || ladder.Basis != "siti" {
+		t.Errorf("complexity = %v (%s from %s), want 0.63 medium from siti", ladder.Complexity, ladder.ComplexityClass, ladder.Basis)
+	}
+	if ladder.CRF != (CRFEstimate{X264: 23, X265: 28}) {
+		t.Errorf("CRF = %+v, want x264 23, x265 28", ladder.CRF)
+	}
+	if len(ladder.Rungs) != 6 {
+		t.Fatalf("got %d rungs, want 1080p down to 234p", len(ladder.Rungs))
+	}
+	// 5000 kbps scaled by 1.13 for complexity and 1.33 for 50 fps
+	if top := ladder.Rungs[0]; top.Width != 1920 || top.BitrateKbps != 7530 || top.MinBitrateKbps != 5270 || top.MaxBitrateKbps != 11300 || top.FrameRate != 50 {
+		t.Errorf("top rung = %+v", top)
+	}
+	if low := ladder.Rungs[4]; low.Width != 640 || low.Height != 360 || low.FrameRate != 25 || low.BitrateKbps != 820 {
+		t.Errorf("360p rung = %+v, want 640x360 at 25 fps and 820 kbps", low)
+	}
+
+	result.Streams = result.Streams[:1]
+	if _, err := RecommendLadder(result); err != ErrNoVideoStream {
+		t.Errorf("RecommendLadder of audio = %v, want ErrNoVideoStream", err)
+	}
+}
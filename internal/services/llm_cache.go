@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/rendiffdev/rendiff-probe/internal/llm"
+	"github.com/rendiffdev/rendiff-probe/internal/models"
+	"golang.org/x/sync/singleflight"
+)
+
+// analysisPromptVersion is part of every analysis cache key. Bump it whenever
+// buildAnalysisPrompt changes so reports written for the old prompt are not
+// served.
+const analysisPromptVersion = "analysis-v1"
+
+// llmCacheMaxEntries bounds the number of cached reports
+const llmCacheMaxEntries = 1000
+
+// llmCache keeps LLM responses for a TTL and collapses concurrent requests
+// for the same key into one LLM call
+type llmCache struct {
+	ttl      time.Duration
+	mu       sync.Mutex
+	entries  map[string]llmCacheEntry
+	inflight singleflight.Group
+}
+
+type llmCacheEntry struct {
+	response  *llm.LLMResponse
+	expiresAt time.Time
+}
+
+// newLLMCache returns a cache, or nil when ttl disables caching
+func newLLMCache(ttl time.Duration) *llmCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &llmCache{ttl: ttl, entries: make(map[string]llmCacheEntry)}
+}
+
+func (c *llmCache) get(key string) (*llm.LLMResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.response, true
+}
+
+func (c *llmCache) set(key string, response *llm.LLMResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if len(c.entries) >= llmCacheMaxEntries {
+		// Drop expired entries, then the one closest to expiry if still full
+		var oldestKey string
+		var oldest time.Time
+		for k, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, k)
+				continue
+			}
+			if oldestKey == "" || entry.expiresAt.Before(oldest) {
+				oldestKey, oldest = k, entry.expiresAt
+			}
+		}
+		if len(c.entries) >= llmCacheMaxEntries {
+			delete(c.entries, oldestKey)
+		}
+	}
+	c.entries[key] = llmCacheEntry{response: response, expiresAt: now.Add(c.ttl)}
+}
+
+// do returns the cached response for key or runs generate, sharing one
+// in-flight call between concurrent callers. refresh skips the cache lookup
+// but still stores the new response. generate runs detached from the
+// caller's cancellation so one caller giving up doesn't fail the others.
+func (c *llmCache) do(ctx context.Context, key string, refresh bool, generate func(context.Context) (*llm.LLMResponse, error)) (response *llm.LLMResponse, cached bool, err error) {
+	if !refresh {
+		if response, ok := c.get(key); ok {
+			return response, true, nil
+		}
+	}
+
+	results := c.inflight.DoChan(key, func() (interface{}, error) {
+		response, err := generate(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		c.set(key, response)
+		return response, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	case result := <-results:
+		if result.Err != nil {
+			return nil, false, result.Err
+		}
+		return result.Val.(*llm.LLMResponse), false, nil
+	}
+}
+
+// analysisCacheKey hashes the FFprobe data an analysis prompt is built from
+// together with the prompt version, so identical files share a report. The
+// probed path is left out since uploads and downloads land at temp paths.
+func analysisCacheKey(analysis *models.Analysis) string {
+	ffprobeData := analysis.FFprobeData
+	var format map[string]json.RawMessage
+	if err := json.Unmarshal(ffprobeData.Format, &format); err == nil {
+		delete(format, "filename")
+		ffprobeData.Format, _ = json.Marshal(format)
+	}
+
+	data, _ := json.Marshal(ffprobeData)
+	sum := sha256.New()
+	sum.Write([]byte(analysisPromptVersion))
+	sum.Write([]byte{0})
+	sum.Write(data)
+	return hex.EncodeToString(sum.Sum(nil))
+}
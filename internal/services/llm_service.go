@@ -4,17 +4,34 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/rendiffdev/rendiff-probe/internal/circuitbreaker"
 	"github.com/rendiffdev/rendiff-probe/internal/config"
+	"github.com/rendiffdev/rendiff-probe/internal/llm"
 	"github.com/rendiffdev/rendiff-probe/internal/models"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var llmTracer = otel.Tracer("github.com/rendiffdev/rendiff-probe/internal/services")
+
+// ErrLLMDisabled is returned by CheckHealth when no LLM backend is
+// configured
+var ErrLLMDisabled = errors.New("no LLM backend configured")
+
+// openRouterModel is the model requested from the OpenRouter fallback
+const openRouterModel = "anthropic/claude-3-haiku"
+
 // LLMService handles LLM operations for GenAI features
 type LLMService struct {
 	config                   *config.Config
@@ -22,6 +39,29 @@ type LLMService struct {
 	httpClient               *http.Client
 	ollamaCircuitBreaker     *circuitbreaker.CircuitBreaker
 	openrouterCircuitBreaker *circuitbreaker.CircuitBreaker
+	provider                 llm.Provider // nil unless LLM_PROVIDER selects a hosted API
+	providerCircuitBreaker   *circuitbreaker.CircuitBreaker
+	pricing                  llm.Pricing
+	analysisCache            *llmCache // nil when LLM_CACHE_TTL is 0
+}
+
+// AnalysisReport is an LLM analysis report with the metadata of the call
+// that produced it
+type AnalysisReport struct {
+	Report   string      `json:"report"`
+	Metadata LLMMetadata `json:"metadata"`
+}
+
+// LLMMetadata describes which backend answered a prompt and what it cost.
+// A cached report carries the usage of the call that originally produced it.
+type LLMMetadata struct {
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model,omitempty"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+	Cached           bool    `json:"cached"`
 }
 
 // NewLLMService creates a new LLM service with production-ready timeouts and circuit breakers
@@ -83,72 +123,289 @@ func NewLLMService(cfg *config.Config, logger zerolog.Logger) *LLMService {
 		},
 	})
 
-	return &LLMService{
+	service := &LLMService{
 		config: cfg,
 		logger: logger,
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
 		},
 		ollamaCircuitBreaker:     ollamaCircuitBreaker,
 		openrouterCircuitBreaker: openrouterCircuitBreaker,
+		pricing: llm.Pricing{
+			InputPerMTok:  cfg.LLMInputCostPerMTok,
+			OutputPerMTok: cfg.LLMOutputCostPerMTok,
+		},
+		analysisCache: newLLMCache(time.Duration(cfg.LLMCacheTTL) * time.Second),
 	}
+
+	// A hosted provider replaces the Ollama/OpenRouter chain entirely
+	if cfg.LLMProvider != "" && cfg.LLMProvider != "ollama" {
+		provider, err := llm.NewProvider(&llm.LLMConfig{
+			Provider:  cfg.LLMProvider,
+			APIKey:    cfg.LLMAPIKey,
+			BaseURL:   cfg.LLMBaseURL,
+			Model:     cfg.LLMModel,
+			MaxTokens: cfg.LLMMaxTokens,
+		}, service.httpClient)
+		if err != nil {
+			logger.Error().Err(err).Str("provider", cfg.LLMProvider).Msg("Invalid LLM provider, falling back to Ollama")
+		} else {
+			service.provider = provider
+			service.providerCircuitBreaker = circuitbreaker.NewCircuitBreaker(circuitbreaker.Settings{
+				Name:        provider.Name() + "-llm",
+				MaxRequests: 2,
+				Interval:    openrouterInterval,
+				Timeout:     openrouterTimeout,
+				ReadyToTrip: func(counts circuitbreaker.Counts) bool {
+					return counts.ConsecutiveFailures >= 2 ||
+						(counts.Requests >= 3 && float64(counts.TotalFailures)/float64(counts.Requests) >= 0.6)
+				},
+				OnStateChange: func(name string, from circuitbreaker.State, to circuitbreaker.State) {
+					logger.Warn().
+						Str("service", name).
+						Str("from_state", from.String()).
+						Str("to_state", to.String()).
+						Msg("Circuit breaker state changed")
+				},
+			})
+			logger.Info().Str("provider", provider.Name()).Msg("Using hosted LLM provider")
+		}
+	}
+
+	return service
 }
 
-// GenerateAnalysis generates human-readable analysis from ffprobe data
+// GenerateAnalysis generates human-readable analysis from ffprobe data.
+// Reports are cached by FFprobe data, so re-analyzing an identical file
+// returns the earlier report without an LLM call.
 func (s *LLMService) GenerateAnalysis(ctx context.Context, analysis *models.Analysis) (string, error) {
-	// Create prompt for media analysis
-	prompt := s.buildAnalysisPrompt(analysis)
+	report, err := s.GenerateAnalysisReport(ctx, analysis, false)
+	if err != nil {
+		return "", err
+	}
+	return report.Report, nil
+}
 
-	// Try local LLM first (if available), then fallback to OpenRouter
-	response, err := s.generateWithLocalLLM(ctx, prompt)
+// RefreshAnalysis is GenerateAnalysis ignoring any cached report. The new
+// report replaces the cached one.
+func (s *LLMService) RefreshAnalysis(ctx context.Context, analysis *models.Analysis) (string, error) {
+	report, err := s.GenerateAnalysisReport(ctx, analysis, true)
 	if err != nil {
-		s.logger.Warn().Err(err).Msg("Local LLM failed, falling back to OpenRouter")
-		response, err = s.generateWithOpenRouter(ctx, prompt)
-		if err != nil {
-			return "", fmt.Errorf("both local and remote LLM failed: %w", err)
+		return "", err
+	}
+	return report.Report, nil
+}
+
+// GenerateAnalysisReport is GenerateAnalysis returning the provider, model,
+// token usage and cost alongside the report. refresh skips the cache.
+func (s *LLMService) GenerateAnalysisReport(ctx context.Context, analysis *models.Analysis, refresh bool) (_ *AnalysisReport, err error) {
+	ctx, span := llmTracer.Start(ctx, "LLMService.GenerateAnalysis")
+	defer func() { endLLMSpan(span, err) }()
+	span.SetAttributes(attribute.Bool("llm.cache_refresh", refresh))
+
+	var response *llm.LLMResponse
+	var cached bool
+	if s.analysisCache == nil {
+		response, err = s.generateAnalysis(ctx, analysis)
+	} else {
+		response, cached, err = s.analysisCache.do(ctx, analysisCacheKey(analysis), refresh, func(ctx context.Context) (*llm.LLMResponse, error) {
+			return s.generateAnalysis(ctx, analysis)
+		})
+		span.SetAttributes(attribute.Bool("llm.cache_hit", cached))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cached {
+		s.logger.Debug().Str("file_name", analysis.FileName).Msg("Serving cached LLM analysis")
+	}
+	return newAnalysisReport(response, cached), nil
+}
+
+// StreamAnalysisReport is GenerateAnalysisReport passing report text to
+// onChunk as it is generated. Only Ollama streams token by token; cached
+// reports and the other backends arrive as a single chunk. The finished
+// report is cached, but concurrent streams for the same data are not merged.
+func (s *LLMService) StreamAnalysisReport(ctx context.Context, analysis *models.Analysis, refresh bool, onChunk func(string) error) (_ *AnalysisReport, err error) {
+	ctx, span := llmTracer.Start(ctx, "LLMService.StreamAnalysis")
+	defer func() { endLLMSpan(span, err) }()
+	span.SetAttributes(attribute.Bool("llm.cache_refresh", refresh))
+
+	var key string
+	if s.analysisCache != nil {
+		key = analysisCacheKey(analysis)
+		if !refresh {
+			if response, ok := s.analysisCache.get(key); ok {
+				span.SetAttributes(attribute.Bool("llm.cache_hit", true))
+				if err := onChunk(response.Content); err != nil {
+					return nil, err
+				}
+				return newAnalysisReport(response, true), nil
+			}
 		}
 	}
 
-	return response, nil
+	response, err := s.generateStream(ctx, s.buildAnalysisPrompt(analysis), onChunk)
+	if err != nil {
+		return nil, err
+	}
+	if s.analysisCache != nil {
+		s.analysisCache.set(key, response)
+	}
+	return newAnalysisReport(response, false), nil
+}
+
+// newAnalysisReport wraps an LLM response with its metadata
+func newAnalysisReport(response *llm.LLMResponse, cached bool) *AnalysisReport {
+	metadata := LLMMetadata{
+		Provider: response.Provider,
+		Model:    response.Model,
+		Cached:   cached,
+	}
+	if response.Usage != nil {
+		metadata.PromptTokens = response.Usage.PromptTokens
+		metadata.CompletionTokens = response.Usage.CompletionTokens
+		metadata.TotalTokens = response.Usage.TotalTokens
+		metadata.CostUSD = response.Usage.CostUSD
+	}
+	return &AnalysisReport{Report: response.Content, Metadata: metadata}
+}
+
+// generateAnalysis runs the analysis prompt through the LLM backends
+func (s *LLMService) generateAnalysis(ctx context.Context, analysis *models.Analysis) (*llm.LLMResponse, error) {
+	return s.generate(ctx, s.buildAnalysisPrompt(analysis))
 }
 
 // AnswerQuestion answers a question about media file using LLM
 func (s *LLMService) AnswerQuestion(ctx context.Context, analysis *models.Analysis, question string) (string, error) {
-	// Create prompt for Q&A
-	prompt := s.buildQAPrompt(analysis, question)
-
-	// Try local LLM first, then fallback to OpenRouter
-	response, err := s.generateWithLocalLLM(ctx, prompt)
+	response, err := s.generate(ctx, s.buildQAPrompt(analysis, question))
 	if err != nil {
-		s.logger.Warn().Err(err).Msg("Local LLM failed, falling back to OpenRouter")
-		response, err = s.generateWithOpenRouter(ctx, prompt)
+		return "", err
+	}
+	return response.Content, nil
+}
+
+// generate runs prompt through the configured backend: the hosted provider
+// when LLM_PROVIDER selects one, otherwise local Ollama with OpenRouter as
+// fallback. Usage is priced with LLM_INPUT/OUTPUT_COST_PER_MTOK.
+func (s *LLMService) generate(ctx context.Context, prompt string) (*llm.LLMResponse, error) {
+	return s.generateStream(ctx, prompt, nil)
+}
+
+// generateStream is generate passing text to onChunk, when set, as it
+// arrives. Backends that don't stream send the whole response as one chunk.
+// Once Ollama has streamed part of a response there is no fallback, since
+// the client has already seen it.
+func (s *LLMService) generateStream(ctx context.Context, prompt string, onChunk func(string) error) (*llm.LLMResponse, error) {
+	var response *llm.LLMResponse
+	var err error
+	if s.provider != nil {
+		response, err = s.generateWithProvider(ctx, prompt)
+		if err != nil {
+			return nil, fmt.Errorf("%s LLM failed: %w", s.provider.Name(), err)
+		}
+		if err := sendWholeResponse(response, onChunk); err != nil {
+			return nil, err
+		}
+	} else {
+		// Try local LLM first (if available), then fallback to OpenRouter
+		streamed := false
+		var localChunk func(string) error
+		if onChunk != nil {
+			localChunk = func(text string) error {
+				streamed = true
+				return onChunk(text)
+			}
+		}
+		response, err = s.generateWithLocalLLM(ctx, prompt, localChunk)
 		if err != nil {
-			return "", fmt.Errorf("both local and remote LLM failed: %w", err)
+			if streamed {
+				return nil, fmt.Errorf("local LLM failed mid-stream: %w", err)
+			}
+			s.logger.Warn().Err(err).Msg("Local LLM failed, falling back to OpenRouter")
+			response, err = s.generateWithOpenRouter(ctx, prompt)
+			if err != nil {
+				return nil, fmt.Errorf("both local and remote LLM failed: %w", err)
+			}
+			if err := sendWholeResponse(response, onChunk); err != nil {
+				return nil, err
+			}
 		}
 	}
 
+	if response.Usage != nil {
+		response.Usage.CostUSD = s.pricing.Cost(response.Usage)
+	}
+	return response, nil
+}
+
+// sendWholeResponse passes a non-streamed response to onChunk, if set
+func sendWholeResponse(response *llm.LLMResponse, onChunk func(string) error) error {
+	if onChunk == nil {
+		return nil
+	}
+	return onChunk(response.Content)
+}
+
+// generateWithProvider sends prompt to the hosted provider with circuit breaker protection
+func (s *LLMService) generateWithProvider(ctx context.Context, prompt string) (_ *llm.LLMResponse, err error) {
+	ctx, span := llmTracer.Start(ctx, "LLMService.provider",
+		trace.WithAttributes(attribute.String("llm.provider", s.provider.Name())))
+	defer func() { endLLMSpan(span, err) }()
+
+	result, err := s.providerCircuitBreaker.Execute(func() (interface{}, error) {
+		return s.provider.Generate(ctx, &llm.LLMRequest{Prompt: prompt, Temperature: 0.7})
+	})
+	if err != nil {
+		s.logger.Error().
+			Err(err).
+			Str("provider", s.provider.Name()).
+			Str("circuit_breaker_state", s.providerCircuitBreaker.State().String()).
+			Interface("circuit_breaker_counts", s.providerCircuitBreaker.Counts()).
+			Msg("LLM provider request failed through circuit breaker")
+		return nil, err
+	}
+
+	response := result.(*llm.LLMResponse)
+	span.SetAttributes(attribute.String("llm.model", response.Model))
+	if response.Usage != nil {
+		span.SetAttributes(
+			attribute.Int("llm.prompt_tokens", response.Usage.PromptTokens),
+			attribute.Int("llm.completion_tokens", response.Usage.CompletionTokens))
+	}
 	return response, nil
 }
 
-// generateWithLocalLLM attempts to use local LLM via Ollama with circuit breaker protection
-func (s *LLMService) generateWithLocalLLM(ctx context.Context, prompt string) (string, error) {
+// generateWithLocalLLM attempts to use local LLM via Ollama with circuit breaker protection.
+// With onChunk set the response is streamed, and the fallback model is only
+// tried if the primary failed before sending any text.
+func (s *LLMService) generateWithLocalLLM(ctx context.Context, prompt string, onChunk func(string) error) (*llm.LLMResponse, error) {
 	// Check if local LLM is enabled
 	if !s.config.EnableLocalLLM {
-		return "", fmt.Errorf("local LLM disabled")
+		return nil, fmt.Errorf("local LLM disabled")
 	}
 
 	if s.config.OllamaURL == "" {
-		return "", fmt.Errorf("ollama URL not configured")
+		return nil, fmt.Errorf("ollama URL not configured")
 	}
 
 	if s.config.OllamaModel == "" {
-		return "", fmt.Errorf("ollama model not configured")
+		return nil, fmt.Errorf("ollama model not configured")
 	}
 
 	// Use circuit breaker to protect against cascading failures
 	result, err := s.ollamaCircuitBreaker.Execute(func() (interface{}, error) {
 		// Try primary model first (Gemma 3 270M - optimized for speed)
-		response, err := s.generateWithOllamaModel(ctx, s.config.OllamaModel, prompt, map[string]interface{}{
+		streamed := false
+		primaryChunk := onChunk
+		if onChunk != nil {
+			primaryChunk = func(text string) error {
+				streamed = true
+				return onChunk(text)
+			}
+		}
+		response, err := s.generateWithOllamaModel(ctx, s.config.OllamaModel, prompt, primaryChunk, map[string]interface{}{
 			"temperature":    0.7,
 			"top_p":          0.9,
 			"top_k":          40,
@@ -173,8 +430,8 @@ func (s *LLMService) generateWithLocalLLM(ctx context.Context, prompt string) (s
 			Str("model", s.config.OllamaModel).
 			Msg("Primary model failed, trying fallback")
 
-		if s.config.OllamaFallbackModel != "" {
-			response, err = s.generateWithOllamaModel(ctx, s.config.OllamaFallbackModel, prompt, map[string]interface{}{
+		if s.config.OllamaFallbackModel != "" && !streamed {
+			response, err = s.generateWithOllamaModel(ctx, s.config.OllamaFallbackModel, prompt, onChunk, map[string]interface{}{
 				"temperature":    0.7,
 				"top_p":          0.9,
 				"top_k":          40,
@@ -194,7 +451,7 @@ func (s *LLMService) generateWithLocalLLM(ctx context.Context, prompt string) (s
 			}
 		}
 
-		return "", fmt.Errorf("both primary and fallback models failed: %w", err)
+		return nil, fmt.Errorf("both primary and fallback models failed: %w", err)
 	})
 
 	if err != nil {
@@ -203,25 +460,31 @@ func (s *LLMService) generateWithLocalLLM(ctx context.Context, prompt string) (s
 			Str("circuit_breaker_state", s.ollamaCircuitBreaker.State().String()).
 			Interface("circuit_breaker_counts", s.ollamaCircuitBreaker.Counts()).
 			Msg("Ollama LLM request failed through circuit breaker")
-		return "", err
+		return nil, err
 	}
 
-	return result.(string), nil
+	return result.(*llm.LLMResponse), nil
 }
 
-// generateWithOllamaModel generates response using specific Ollama model
-func (s *LLMService) generateWithOllamaModel(ctx context.Context, model string, prompt string, options map[string]interface{}) (string, error) {
+// generateWithOllamaModel generates response using specific Ollama model.
+// With onChunk set Ollama streams the response and each piece of text is
+// passed on as it arrives.
+func (s *LLMService) generateWithOllamaModel(ctx context.Context, model string, prompt string, onChunk func(string) error, options map[string]interface{}) (_ *llm.LLMResponse, err error) {
+	ctx, span := llmTracer.Start(ctx, "LLMService.ollama",
+		trace.WithAttributes(attribute.String("llm.provider", "ollama"), attribute.String("llm.model", model)))
+	defer func() { endLLMSpan(span, err) }()
+
 	// Prepare Ollama request
 	requestBody := map[string]interface{}{
 		"model":   model,
 		"prompt":  prompt,
-		"stream":  false,
+		"stream":  onChunk != nil,
 		"options": options,
 	}
 
 	jsonBody, err := json.Marshal(requestBody)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal Ollama request: %w", err)
+		return nil, fmt.Errorf("failed to marshal Ollama request: %w", err)
 	}
 
 	// Create request with timeout (shorter for Gemma3, longer for Phi3)
@@ -234,7 +497,7 @@ func (s *LLMService) generateWithOllamaModel(ctx context.Context, model string,
 
 	req, err := http.NewRequestWithContext(ctx, "POST", s.config.OllamaURL+"/api/generate", bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return "", fmt.Errorf("failed to create Ollama request: %w", err)
+		return nil, fmt.Errorf("failed to create Ollama request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -242,51 +505,80 @@ func (s *LLMService) generateWithOllamaModel(ctx context.Context, model string,
 	// Send request to Ollama
 	resp, err := s.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("failed to send Ollama request: %w", err)
+		return nil, fmt.Errorf("failed to send Ollama request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ollama API returned status %d", resp.StatusCode)
+		return nil, fmt.Errorf("ollama API returned status %d", resp.StatusCode)
 	}
 
-	// Parse Ollama response
+	// Parse Ollama response. A streamed response is a sequence of these
+	// objects; the last one has done set and carries the token counts.
 	var response struct {
-		Response string `json:"response"`
-		Done     bool   `json:"done"`
-		Error    string `json:"error,omitempty"`
+		Response        string `json:"response"`
+		Done            bool   `json:"done"`
+		DoneReason      string `json:"done_reason"`
+		PromptEvalCount int    `json:"prompt_eval_count"`
+		EvalCount       int    `json:"eval_count"`
+		Error           string `json:"error,omitempty"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", fmt.Errorf("failed to decode Ollama response: %w", err)
-	}
+	var text strings.Builder
+	decoder := json.NewDecoder(resp.Body)
+	for !response.Done {
+		response.Response = ""
+		if err := decoder.Decode(&response); err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("ollama response incomplete")
+			}
+			return nil, fmt.Errorf("failed to decode Ollama response: %w", err)
+		}
 
-	if response.Error != "" {
-		return "", fmt.Errorf("ollama API error: %s", response.Error)
-	}
+		if response.Error != "" {
+			return nil, fmt.Errorf("ollama API error: %s", response.Error)
+		}
 
-	if !response.Done {
-		return "", fmt.Errorf("ollama response incomplete")
+		text.WriteString(response.Response)
+		if onChunk != nil && response.Response != "" {
+			if err := onChunk(response.Response); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	if response.Response == "" {
-		return "", fmt.Errorf("empty response from Ollama")
+	if text.Len() == 0 {
+		return nil, fmt.Errorf("empty response from Ollama")
 	}
 
-	return strings.TrimSpace(response.Response), nil
+	return &llm.LLMResponse{
+		Content:      strings.TrimSpace(text.String()),
+		FinishReason: response.DoneReason,
+		Usage: &llm.LLMUsage{
+			PromptTokens:     response.PromptEvalCount,
+			CompletionTokens: response.EvalCount,
+			TotalTokens:      response.PromptEvalCount + response.EvalCount,
+		},
+		Model:    model,
+		Provider: "ollama",
+	}, nil
 }
 
 // generateWithOpenRouter uses OpenRouter API as fallback with circuit breaker protection
-func (s *LLMService) generateWithOpenRouter(ctx context.Context, prompt string) (string, error) {
+func (s *LLMService) generateWithOpenRouter(ctx context.Context, prompt string) (_ *llm.LLMResponse, err error) {
+	ctx, span := llmTracer.Start(ctx, "LLMService.openrouter",
+		trace.WithAttributes(attribute.String("llm.provider", "openrouter")))
+	defer func() { endLLMSpan(span, err) }()
+
 	if s.config.OpenRouterAPIKey == "" {
-		return "", fmt.Errorf("OpenRouter API key not configured")
+		return nil, fmt.Errorf("OpenRouter API key not configured")
 	}
 
 	// Use circuit breaker to protect against external API failures
 	result, err := s.openrouterCircuitBreaker.Execute(func() (interface{}, error) {
 		// Prepare request
 		requestBody := map[string]interface{}{
-			"model": "anthropic/claude-3-haiku",
+			"model": openRouterModel,
 			"messages": []map[string]string{
 				{
 					"role":    "user",
@@ -299,13 +591,13 @@ func (s *LLMService) generateWithOpenRouter(ctx context.Context, prompt string)
 
 		jsonBody, err := json.Marshal(requestBody)
 		if err != nil {
-			return "", fmt.Errorf("failed to marshal request: %w", err)
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
 		}
 
 		// Create request
 		req, err := http.NewRequestWithContext(ctx, "POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(jsonBody))
 		if err != nil {
-			return "", fmt.Errorf("failed to create request: %w", err)
+			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
 
 		req.Header.Set("Content-Type", "application/json")
@@ -316,39 +608,50 @@ func (s *LLMService) generateWithOpenRouter(ctx context.Context, prompt string)
 		// Send request
 		resp, err := s.httpClient.Do(req)
 		if err != nil {
-			return "", fmt.Errorf("failed to send request: %w", err)
+			return nil, fmt.Errorf("failed to send request: %w", err)
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			return "", fmt.Errorf("OpenRouter API returned status %d", resp.StatusCode)
+			return nil, fmt.Errorf("OpenRouter API returned status %d", resp.StatusCode)
 		}
 
 		// Parse response
 		var response struct {
+			Model   string `json:"model"`
 			Choices []struct {
 				Message struct {
 					Content string `json:"content"`
 				} `json:"message"`
 			} `json:"choices"`
+			Usage *llm.LLMUsage `json:"usage"`
 			Error struct {
 				Message string `json:"message"`
 			} `json:"error"`
 		}
 
 		if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-			return "", fmt.Errorf("failed to decode response: %w", err)
+			return nil, fmt.Errorf("failed to decode response: %w", err)
 		}
 
 		if response.Error.Message != "" {
-			return "", fmt.Errorf("OpenRouter API error: %s", response.Error.Message)
+			return nil, fmt.Errorf("OpenRouter API error: %s", response.Error.Message)
 		}
 
 		if len(response.Choices) == 0 {
-			return "", fmt.Errorf("no response from OpenRouter API")
+			return nil, fmt.Errorf("no response from OpenRouter API")
 		}
 
-		return strings.TrimSpace(response.Choices[0].Message.Content), nil
+		model := response.Model
+		if model == "" {
+			model = openRouterModel
+		}
+		return &llm.LLMResponse{
+			Content:  strings.TrimSpace(response.Choices[0].Message.Content),
+			Usage:    response.Usage,
+			Model:    model,
+			Provider: "openrouter",
+		}, nil
 	})
 
 	if err != nil {
@@ -357,14 +660,23 @@ func (s *LLMService) generateWithOpenRouter(ctx context.Context, prompt string)
 			Str("circuit_breaker_state", s.openrouterCircuitBreaker.State().String()).
 			Interface("circuit_breaker_counts", s.openrouterCircuitBreaker.Counts()).
 			Msg("OpenRouter LLM request failed through circuit breaker")
-		return "", err
+		return nil, err
 	}
 
 	s.logger.Info().
 		Str("circuit_breaker_state", s.openrouterCircuitBreaker.State().String()).
 		Msg("Successfully generated with OpenRouter")
 
-	return result.(string), nil
+	return result.(*llm.LLMResponse), nil
+}
+
+// endLLMSpan records the outcome of an LLM call and ends its span
+func endLLMSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
 }
 
 // buildAnalysisPrompt creates a prompt for general media analysis
@@ -459,6 +771,64 @@ func (s *LLMService) buildAnalysisPrompt(analysis *models.Analysis) string {
 	return prompt.String()
 }
 
+// ComparedAsset is one side of an LLM asset comparison
+type ComparedAsset struct {
+	FileName    string
+	FFprobeData json.RawMessage // raw format and streams
+	QCResults   json.RawMessage // enhanced analysis, summarized per QC category
+}
+
+// GenerateComparisonReport has the LLM compare a source asset with a target
+// derived from it, e.g. a master and its transcode. differences holds the
+// precomputed property differences the report is organized around.
+func (s *LLMService) GenerateComparisonReport(ctx context.Context, source, target ComparedAsset, differences json.RawMessage) (_ *AnalysisReport, err error) {
+	ctx, span := llmTracer.Start(ctx, "LLMService.GenerateComparison")
+	defer func() { endLLMSpan(span, err) }()
+
+	response, err := s.generate(ctx, s.buildAssetComparisonPrompt(source, target, differences))
+	if err != nil {
+		return nil, err
+	}
+	return newAnalysisReport(response, false), nil
+}
+
+// buildAssetComparisonPrompt creates a prompt comparing two analyzed assets
+func (s *LLMService) buildAssetComparisonPrompt(source, target ComparedAsset, differences json.RawMessage) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("You are a senior video engineer reviewing a target media file against its source (for example a transcode against its master).\n\n")
+	prompt.WriteString("Compare the two files using the FFprobe data, the QC results and the list of detected differences below. ")
+	prompt.WriteString("Write a structured comparison report with these sections:\n\n")
+	prompt.WriteString("1. **Verdict** - one paragraph: is the target an acceptable derivative of the source, and what is the most important change?\n")
+	prompt.WriteString("2. **Codec & Container Differences** - codecs, profiles, resolution, frame rate, bit rates, audio layout; note intended vs suspicious changes\n")
+	prompt.WriteString("3. **Quality Differences** - quality score, bit depth, color metadata, compression artifacts; estimate the visible impact\n")
+	prompt.WriteString("4. **Loudness Differences** - integrated loudness, loudness range, true peak and standard compliance\n")
+	prompt.WriteString("5. **Compliance Differences** - QC categories and delivery profile results that changed, with the reason\n")
+	prompt.WriteString("6. **Recommendations** - FFmpeg commands or settings to fix regressions in the target\n\n")
+	prompt.WriteString("Only report differences supported by the data. If a section has no differences, say so in one line.\n\n")
+
+	prompt.WriteString("Detected Differences:\n")
+	prompt.Write(differences)
+	prompt.WriteString("\n\n")
+
+	for _, asset := range []struct {
+		label string
+		ComparedAsset
+	}{{"Source", source}, {"Target", target}} {
+		prompt.WriteString(fmt.Sprintf("%s File: %s\n", asset.label, asset.FileName))
+		prompt.WriteString(fmt.Sprintf("%s FFprobe Data:\n", asset.label))
+		prompt.Write(asset.FFprobeData)
+		prompt.WriteString("\n")
+		prompt.WriteString(fmt.Sprintf("%s QC Results:\n", asset.label))
+		prompt.Write(asset.QCResults)
+		prompt.WriteString("\n\n")
+	}
+
+	prompt.WriteString("Be precise and professional. Use industry-standard terminology.")
+
+	return prompt.String()
+}
+
 // buildQAPrompt creates a prompt for Q&A about media file
 func (s *LLMService) buildQAPrompt(analysis *models.Analysis, question string) string {
 	var prompt strings.Builder
@@ -485,34 +855,20 @@ func (s *LLMService) buildQAPrompt(analysis *models.Analysis, question string) s
 
 // GenerateQualityInsights generates insights about video quality metrics
 func (s *LLMService) GenerateQualityInsights(ctx context.Context, analysis *models.Analysis, metrics []models.QualityMetrics) (string, error) {
-	prompt := s.buildQualityInsightsPrompt(analysis, metrics)
-
-	// Try local LLM first, then fallback to OpenRouter
-	response, err := s.generateWithLocalLLM(ctx, prompt)
+	response, err := s.generate(ctx, s.buildQualityInsightsPrompt(analysis, metrics))
 	if err != nil {
-		s.logger.Warn().Err(err).Msg("Local LLM failed, falling back to OpenRouter")
-		response, err = s.generateWithOpenRouter(ctx, prompt)
-		if err != nil {
-			return "", fmt.Errorf("both local and remote LLM failed: %w", err)
-		}
+		return "", err
 	}
-
-	return response, nil
+	return response.Content, nil
 }
 
 // GenerateResponse generates a response for a custom prompt (used by comparison service)
 func (s *LLMService) GenerateResponse(ctx context.Context, prompt string) (string, error) {
-	// Try local LLM first, then fallback to OpenRouter
-	response, err := s.generateWithLocalLLM(ctx, prompt)
+	response, err := s.generate(ctx, prompt)
 	if err != nil {
-		s.logger.Warn().Err(err).Msg("Local LLM failed, falling back to OpenRouter")
-		response, err = s.generateWithOpenRouter(ctx, prompt)
-		if err != nil {
-			return "", fmt.Errorf("both local and remote LLM failed: %w", err)
-		}
+		return "", err
 	}
-
-	return response, nil
+	return response.Content, nil
 }
 
 // buildQualityInsightsPrompt creates a prompt for quality metrics analysis
@@ -635,7 +991,7 @@ func (s *LLMService) CheckOllamaHealth(ctx context.Context) (*OllamaHealthStatus
 
 // GetCircuitBreakerStatus returns the status of all circuit breakers
 func (s *LLMService) GetCircuitBreakerStatus() map[string]interface{} {
-	return map[string]interface{}{
+	status := map[string]interface{}{
 		"ollama": map[string]interface{}{
 			"name":   s.ollamaCircuitBreaker.Name(),
 			"state":  s.ollamaCircuitBreaker.State().String(),
@@ -647,6 +1003,58 @@ func (s *LLMService) GetCircuitBreakerStatus() map[string]interface{} {
 			"counts": s.openrouterCircuitBreaker.Counts(),
 		},
 	}
+	if s.provider != nil {
+		status[s.provider.Name()] = map[string]interface{}{
+			"name":   s.providerCircuitBreaker.Name(),
+			"state":  s.providerCircuitBreaker.State().String(),
+			"counts": s.providerCircuitBreaker.Counts(),
+		}
+	}
+	return status
+}
+
+// CheckHealth reports whether the backend that would answer a prompt can
+// be reached, and returns its name. Ollama is asked for its models; hosted
+// APIs are not called, to spend no tokens, and count as unreachable while
+// their circuit breaker is open. When Ollama is down the OpenRouter
+// fallback is checked instead.
+func (s *LLMService) CheckHealth(ctx context.Context) (string, error) {
+	if s.provider != nil {
+		return s.provider.Name(), circuitBreakerHealth(s.providerCircuitBreaker)
+	}
+
+	var ollamaErr error
+	if s.config.EnableLocalLLM && s.config.OllamaURL != "" {
+		status, err := s.CheckOllamaHealth(ctx)
+		switch {
+		case err != nil:
+			ollamaErr = err
+		case status.Error != "":
+			ollamaErr = errors.New(status.Error)
+		case status.ConfiguredModel != "" && !status.ModelAvailable:
+			ollamaErr = fmt.Errorf("model %s is not available", status.ConfiguredModel)
+		default:
+			return "ollama", nil
+		}
+	}
+
+	if s.config.OpenRouterAPIKey != "" {
+		if err := circuitBreakerHealth(s.openrouterCircuitBreaker); err == nil || ollamaErr == nil {
+			return "openrouter", err
+		}
+	}
+	if ollamaErr != nil {
+		return "ollama", ollamaErr
+	}
+	return "", ErrLLMDisabled
+}
+
+// circuitBreakerHealth fails while cb is open after repeated failures
+func circuitBreakerHealth(cb *circuitbreaker.CircuitBreaker) error {
+	if cb.State() == circuitbreaker.StateOpen {
+		return fmt.Errorf("%s circuit breaker is open after repeated failures", cb.Name())
+	}
+	return nil
 }
 
 // PullModel downloads a model to Ollama
@@ -0,0 +1,79 @@
+// Package sharelink signs expiring links to analysis reports, so results can
+// be shared with people who hold no API credentials.
+package sharelink
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrInvalidSignature is returned for links that were not signed with
+	// the secret, or whose parameters were altered
+	ErrInvalidSignature = errors.New("invalid share link signature")
+	// ErrExpired is returned for correctly signed links past their expiry
+	ErrExpired = errors.New("share link has expired")
+)
+
+// Query parameters of a signed link
+const (
+	ParamFormat    = "format"
+	ParamExpires   = "expires"
+	ParamSignature = "signature"
+)
+
+// Link grants read access to one rendering of an analysis until Expires
+type Link struct {
+	AnalysisID string
+	Format     string
+	Expires    time.Time
+}
+
+// Sign returns the hex HMAC-SHA256 of "<analysis_id>.<format>.<expires>",
+// with expires in Unix seconds, keyed with secret
+func Sign(secret string, link Link) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(link.AnalysisID))
+	mac.Write([]byte("."))
+	mac.Write([]byte(link.Format))
+	mac.Write([]byte("."))
+	mac.Write([]byte(strconv.FormatInt(link.Expires.Unix(), 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Query returns the query parameters of the signed link
+func Query(secret string, link Link) url.Values {
+	return url.Values{
+		ParamFormat:    {link.Format},
+		ParamExpires:   {strconv.FormatInt(link.Expires.Unix(), 10)},
+		ParamSignature: {Sign(secret, link)},
+	}
+}
+
+// Verify checks the query parameters of a link to an analysis in constant
+// time and returns the link they grant
+func Verify(secret, analysisID string, query url.Values, now time.Time) (Link, error) {
+	expires, err := strconv.ParseInt(query.Get(ParamExpires), 10, 64)
+	if err != nil {
+		return Link{}, ErrInvalidSignature
+	}
+	link := Link{
+		AnalysisID: analysisID,
+		Format:     query.Get(ParamFormat),
+		Expires:    time.Unix(expires, 0).UTC(),
+	}
+
+	expected := Sign(secret, link)
+	if !hmac.Equal([]byte(expected), []byte(query.Get(ParamSignature))) {
+		return Link{}, ErrInvalidSignature
+	}
+	if !now.Before(link.Expires) {
+		return Link{}, ErrExpired
+	}
+	return link, nil
+}
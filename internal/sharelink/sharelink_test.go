@@ -0,0 +1,54 @@
+package sharelink
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const testSecret = "test-share-secret-0123456789"
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	link := Link{AnalysisID: "7d0c3f1e-5b1a-4c1e-9a55-3d1f0a6e2b44", Format: "pdf", Expires: now.Add(time.Hour)}
+	query := Query(testSecret, link)
+
+	got, err := Verify(testSecret, link.AnalysisID, query, now)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if got != link {
+		t.Errorf("unexpected link %+v", got)
+	}
+
+	if _, err := Verify(testSecret, link.AnalysisID, query, now.Add(time.Hour)); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected ErrExpired at expiry, got %v", err)
+	}
+	if _, err := Verify("other-secret-value", link.AnalysisID, query, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for the wrong secret, got %v", err)
+	}
+	if _, err := Verify(testSecret, "0b6f0c55-1d4e-4f5a-8d0e-6b8e3c1a9f21", query, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature for another analysis, got %v", err)
+	}
+}
+
+func TestVerify_RejectsAlteredParameters(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	link := Link{AnalysisID: "7d0c3f1e-5b1a-4c1e-9a55-3d1f0a6e2b44", Format: "html", Expires: now.Add(time.Hour)}
+
+	tests := map[string]func(q map[string][]string){
+		"format":    func(q map[string][]string) { q[ParamFormat] = []string{"pdf"} },
+		"expires":   func(q map[string][]string) { q[ParamExpires] = []string{"4102444800"} },
+		"malformed": func(q map[string][]string) { q[ParamExpires] = []string{"tomorrow"} },
+		"missing":   func(q map[string][]string) { delete(q, ParamSignature) },
+	}
+	for name, alter := range tests {
+		t.Run(name, func(t *testing.T) {
+			query := Query(testSecret, link)
+			alter(query)
+			if _, err := Verify(testSecret, link.AnalysisID, query, now); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
@@ -13,9 +13,10 @@ import (
 )
 
 type AzureProvider struct {
-	client    *azblob.Client
-	container string
-	account   string
+	client     *azblob.Client
+	credential *azblob.SharedKeyCredential
+	container  string
+	account    string
 }
 
 func NewAzureProvider(cfg Config) (*AzureProvider, error) {
@@ -31,9 +32,10 @@ func NewAzureProvider(cfg Config) (*AzureProvider, error) {
 	}
 
 	return &AzureProvider{
-		client:    client,
-		container: cfg.Bucket,
-		account:   cfg.AccessKey,
+		client:     client,
+		credential: credential,
+		container:  cfg.Bucket,
+		account:    cfg.AccessKey,
 	}, nil
 }
 
@@ -98,3 +100,23 @@ func (a *AzureProvider) GetSignedURL(ctx context.Context, key string, expiration
 	sasURL := fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s?%s", a.account, a.container, key, sasQueryParams.Encode())
 	return sasURL, nil
 }
+
+func (a *AzureProvider) GetSignedUploadURL(ctx context.Context, key string, size int64, expiration int64) (*SignedUpload, error) {
+	sasQueryParams, err := sas.BlobSignatureValues{
+		Protocol:      sas.ProtocolHTTPS,
+		ExpiryTime:    time.Now().UTC().Add(time.Duration(expiration) * time.Second),
+		Permissions:   to.Ptr(sas.BlobPermissions{Create: true, Write: true}).String(),
+		ContainerName: a.container,
+		BlobName:      key,
+	}.SignWithSharedKey(a.credential)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign upload URL: %w", err)
+	}
+
+	sasURL := fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s?%s", a.account, a.container, key, sasQueryParams.Encode())
+	return &SignedUpload{
+		URL:     sasURL,
+		Method:  "PUT",
+		Headers: map[string]string{"x-ms-blob-type": "BlockBlob"},
+	}, nil
+}
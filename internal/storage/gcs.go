@@ -96,6 +96,21 @@ func (g *GCSProvider) GetSignedURL(ctx context.Context, key string, expiration i
 	return url, nil
 }
 
+func (g *GCSProvider) GetSignedUploadURL(ctx context.Context, key string, size int64, expiration int64) (*SignedUpload, error) {
+	opts := &storage.SignedURLOptions{
+		Scheme:  storage.SigningSchemeV4,
+		Method:  "PUT",
+		Expires: time.Now().Add(time.Duration(expiration) * time.Second),
+	}
+
+	url, err := g.client.Bucket(g.bucket).SignedURL(key, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signed upload URL: %w", err)
+	}
+
+	return &SignedUpload{URL: url, Method: "PUT", Headers: map[string]string{}}, nil
+}
+
 // Close closes the GCS client and releases resources
 func (g *GCSProvider) Close() error {
 	if g.client != nil {
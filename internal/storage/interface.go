@@ -2,9 +2,14 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"io"
 )
 
+// ErrSignedUploadUnsupported is returned by providers that cannot sign
+// upload URLs, since clients could only upload through the server
+var ErrSignedUploadUnsupported = errors.New("signed upload URLs are not supported by this storage provider")
+
 type Provider interface {
 	Upload(ctx context.Context, key string, reader io.Reader, size int64) error
 	Download(ctx context.Context, key string) (io.ReadCloser, error)
@@ -12,6 +17,18 @@ type Provider interface {
 	Exists(ctx context.Context, key string) (bool, error)
 	GetURL(ctx context.Context, key string) (string, error)
 	GetSignedURL(ctx context.Context, key string, expiration int64) (string, error)
+	// GetSignedUploadURL returns a request that uploads the object without
+	// credentials until it expires. A positive size is the only Content-Length
+	// accepted where the provider can enforce it.
+	GetSignedUploadURL(ctx context.Context, key string, size int64, expiration int64) (*SignedUpload, error)
+}
+
+// SignedUpload is a presigned upload request
+type SignedUpload struct {
+	URL    string
+	Method string
+	// Headers must be sent with the request, besides those a browser sets
+	Headers map[string]string
 }
 
 type UploadOptions struct {
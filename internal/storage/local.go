@@ -152,3 +152,7 @@ func (l *LocalProvider) GetURL(ctx context.Context, key string) (string, error)
 func (l *LocalProvider) GetSignedURL(ctx context.Context, key string, expiration int64) (string, error) {
 	return l.GetURL(ctx, key)
 }
+
+func (l *LocalProvider) GetSignedUploadURL(ctx context.Context, key string, size int64, expiration int64) (*SignedUpload, error) {
+	return nil, ErrSignedUploadUnsupported
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -116,3 +117,30 @@ func (s *S3Provider) GetSignedURL(ctx context.Context, key string, expiration in
 
 	return request.URL, nil
 }
+
+func (s *S3Provider) GetSignedUploadURL(ctx context.Context, key string, size int64, expiration int64) (*SignedUpload, error) {
+	presignClient := s3.NewPresignClient(s.client)
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}
+	if size > 0 {
+		input.ContentLength = aws.Int64(size)
+	}
+	request, err := presignClient.PresignPutObject(ctx, input, func(opts *s3.PresignOptions) {
+		opts.Expires = time.Duration(expiration) * time.Second
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate presigned upload URL: %w", err)
+	}
+
+	// Browsers set Host and Content-Length themselves
+	headers := make(map[string]string)
+	for name, values := range request.SignedHeader {
+		if len(values) > 0 && !strings.EqualFold(name, "Host") && !strings.EqualFold(name, "Content-Length") {
+			headers[name] = values[0]
+		}
+	}
+	return &SignedUpload{URL: request.URL, Method: request.Method, Headers: headers}, nil
+}
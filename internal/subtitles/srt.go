@@ -0,0 +1,85 @@
+package subtitles
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// srtTiming matches the timing line of a SubRip cue. Some tools write a
+// period instead of a comma before the milliseconds, or append position
+// coordinates.
+var srtTiming = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})[,.](\d{1,3})\s*-->\s*(\d+):(\d{2}):(\d{2})[,.](\d{1,3})`)
+
+// markupTag matches the formatting tags of SRT and the {\an8} style
+// positioning codes some players read, which take no space on screen
+var markupTag = regexp.MustCompile(`</?[a-zA-Z][^>]*>|\{\\[^}]*\}`)
+
+// parseSRT parses a SubRip file. Blocks without a valid timing line are
+// reported and skipped.
+func parseSRT(data []byte) (*document, error) {
+	text := string(bytes.TrimPrefix(data, utf8BOM))
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	doc := &document{format: FormatSRT}
+	block := 0
+	for _, raw := range strings.Split(text, "\n\n") {
+		lines := strings.Split(strings.Trim(raw, "\n"), "\n")
+		if len(lines) == 0 || strings.TrimSpace(lines[0]) == "" {
+			continue
+		}
+		block++
+
+		// The cue number is optional in practice; find the timing line
+		timing := -1
+		for i, line := range lines[:min(2, len(lines))] {
+			if strings.Contains(line, "-->") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			doc.issues = append(doc.issues, &Issue{
+				Check:    CheckParse,
+				Severity: "error",
+				Message:  fmt.Sprintf("Block %d has no timing line", block),
+			})
+			continue
+		}
+		m := srtTiming.FindStringSubmatch(strings.TrimSpace(lines[timing]))
+		if m == nil {
+			doc.issues = append(doc.issues, &Issue{
+				Check:    CheckParse,
+				Severity: "error",
+				Message:  fmt.Sprintf("Block %d has an invalid timing line %q", block, lines[timing]),
+			})
+			continue
+		}
+
+		c := cue{
+			number: block,
+			start:  clockSeconds(m[1], m[2], m[3], m[4]),
+			end:    clockSeconds(m[5], m[6], m[7], m[8]),
+		}
+		for _, line := range lines[timing+1:] {
+			if line = strings.TrimSpace(markupTag.ReplaceAllString(line, "")); line != "" {
+				c.lines = append(c.lines, line)
+			}
+		}
+		doc.cues = append(doc.cues, c)
+	}
+	return doc, nil
+}
+
+// clockSeconds converts matched hours, minutes, seconds and a decimal
+// fraction of a second to seconds
+func clockSeconds(hours, minutes, seconds, fraction string) float64 {
+	h, _ := strconv.Atoi(hours)
+	m, _ := strconv.Atoi(minutes)
+	sec, _ := strconv.Atoi(seconds)
+	f, _ := strconv.ParseFloat("0."+fraction, 64)
+	return float64(h*3600+m*60+sec) + f
+}
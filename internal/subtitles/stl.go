@@ -0,0 +1,178 @@
+package subtitles
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// EBU STL (EBU Tech 3264) layout: a 1024 byte General Subtitle Information
+// block followed by 128 byte Text and Timing Information blocks
+const (
+	stlGSISize    = 1024
+	stlTTISize    = 128
+	stlTextOffset = 16
+)
+
+// Extension block numbers and text field codes of TTI blocks
+const (
+	stlLastBlock   = 0xFF // Last or only block of a subtitle
+	stlUserData    = 0xFE // User data block, not part of a subtitle
+	stlCommentFlag = 1
+	stlNewline     = 0x8A
+)
+
+// stlTimecode is a TTI time code: hours, minutes, seconds and frames
+type stlTimecode [4]byte
+
+func (tc stlTimecode) String() string {
+	return fmt.Sprintf("%02d:%02d:%02d:%02d", tc[0], tc[1], tc[2], tc[3])
+}
+
+func (tc stlTimecode) valid(frameRate int) bool {
+	return tc[0] < 24 && tc[1] < 60 && tc[2] < 60 && int(tc[3]) < frameRate
+}
+
+func (tc stlTimecode) seconds(frameRate int) float64 {
+	return float64(int(tc[0])*3600+int(tc[1])*60+int(tc[2])) + float64(tc[3])/float64(frameRate)
+}
+
+// parseSTL parses an EBU STL file. Cue times are made relative to the start
+// of programme time code, which is often 10:00:00:00.
+func parseSTL(data []byte) (*document, error) {
+	if len(data) < stlGSISize {
+		return nil, fmt.Errorf("invalid EBU STL: GSI block is %d bytes, expected %d", len(data), stlGSISize)
+	}
+	gsi := data[:stlGSISize]
+
+	// Disk format code, STL25.01 or STL30.01
+	dfc := string(gsi[3:11])
+	frameRate, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(dfc, "STL"), ".01"))
+	if err != nil || (frameRate != 25 && frameRate != 30) {
+		return nil, fmt.Errorf("invalid EBU STL: unsupported disk format code %q", dfc)
+	}
+
+	doc := &document{format: FormatSTL, frameRate: float64(frameRate), frameTimed: true}
+	programmeStart := 0.0
+	if tcp := string(gsi[256:264]); strings.Trim(tcp, " 0") != "" {
+		if start, ok := parseSTLTimecode(tcp, frameRate); ok {
+			programmeStart = start
+		} else {
+			doc.issues = append(doc.issues, &Issue{
+				Check:    CheckFrameRate,
+				Severity: "error",
+				Message:  fmt.Sprintf("Start of programme time code %q does not fit %d fps", tcp, frameRate),
+			})
+		}
+	}
+
+	blocks := data[stlGSISize:]
+	if len(blocks)%stlTTISize != 0 {
+		doc.issues = append(doc.issues, &Issue{
+			Check:    CheckParse,
+			Severity: "warning",
+			Message:  fmt.Sprintf("File ends with a partial %d byte TTI block", len(blocks)%stlTTISize),
+		})
+	}
+
+	var current *cue
+	var text []byte
+	currentNumber := -1
+	for offset := 0; offset+stlTTISize <= len(blocks); offset += stlTTISize {
+		tti := blocks[offset : offset+stlTTISize]
+		number := int(binary.LittleEndian.Uint16(tti[1:3]))
+		extension := tti[3]
+		if extension == stlUserData || tti[15] == stlCommentFlag {
+			continue
+		}
+
+		// Extension blocks continue the text of the subtitle before them
+		if current == nil || number != currentNumber {
+			if current != nil {
+				current.lines = stlLines(text)
+				doc.cues = append(doc.cues, *current)
+			}
+			var in, out stlTimecode
+			copy(in[:], tti[5:9])
+			copy(out[:], tti[9:13])
+			current = &cue{number: len(doc.cues) + 1}
+			for _, tc := range []stlTimecode{in, out} {
+				if !tc.valid(frameRate) {
+					doc.issues = append(doc.issues, &Issue{
+						Check:    CheckFrameRate,
+						Severity: "error",
+						Cue:      current.number,
+						Message:  fmt.Sprintf("Time code %s does not fit %d fps", tc, frameRate),
+					})
+				}
+			}
+			current.start = in.seconds(frameRate)
+			current.end = out.seconds(frameRate)
+			if current.start >= programmeStart {
+				current.start -= programmeStart
+				current.end -= programmeStart
+			}
+			currentNumber = number
+			text = text[:0]
+		}
+		text = append(text, tti[stlTextOffset:]...)
+
+		if extension == stlLastBlock {
+			current.lines = stlLines(text)
+			doc.cues = append(doc.cues, *current)
+			current = nil
+		}
+	}
+	if current != nil {
+		current.lines = stlLines(text)
+		doc.cues = append(doc.cues, *current)
+	}
+	return doc, nil
+}
+
+// parseSTLTimecode parses an HHMMSSFF time code of the GSI block
+func parseSTLTimecode(value string, frameRate int) (float64, bool) {
+	if len(value) != 8 {
+		return 0, false
+	}
+	var tc stlTimecode
+	for i := range tc {
+		n, err := strconv.Atoi(value[2*i : 2*i+2])
+		if err != nil {
+			return 0, false
+		}
+		tc[i] = byte(n)
+	}
+	return tc.seconds(frameRate), tc.valid(frameRate)
+}
+
+// stlLines splits a TTI text field into its lines. Only the length of the
+// lines is used, so teletext control codes, formatting codes and the
+// unused space filling the end of each block are dropped, the non-spacing diacritics of ISO 6937 are merged into the
+// letter they precede and other characters above 0x7F count as one.
+func stlLines(text []byte) []string {
+	var lines []string
+	var line strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(line.String()); s != "" {
+			lines = append(lines, s)
+		}
+		line.Reset()
+	}
+
+	for _, b := range text {
+		switch {
+		case b == stlNewline:
+			flush()
+		case b >= 0x20 && b < 0x7F:
+			line.WriteByte(b)
+		case b >= 0xC1 && b <= 0xCF:
+			// Diacritic of the next character
+		case b >= 0xA0:
+			line.WriteRune('\uFFFD')
+		}
+	}
+	flush()
+	return lines
+}
@@ -0,0 +1,296 @@
+// Package subtitles validates standalone subtitle deliverables: SubRip
+// (SRT), TTML including IMSC1, and EBU STL files. Cues are checked for
+// timing overlaps, reading speed, line length and count, timecodes that do
+// not fit the frame rate, and cues running past the end of the media.
+package subtitles
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+// MaxFileSize limits the subtitle files read for validation
+const MaxFileSize = 10 * 1024 * 1024
+
+// Subtitle formats
+const (
+	FormatSRT  = "srt"
+	FormatTTML = "ttml"
+	FormatSTL  = "stl"
+)
+
+// Default limits, following common broadcast and streaming delivery
+// specifications
+const (
+	DefaultMaxCPS         = 20.0
+	DefaultMaxLineLength  = 42
+	DefaultMaxLines       = 2
+	DefaultMinCueDuration = 0.833 // seconds, 20 frames at 24 fps
+)
+
+// maxReportedIssues caps the issues listed for a file. Errors and warnings
+// are still counted past it.
+const maxReportedIssues = 500
+
+// frameRateTolerance treats rates such as 29.97 and 30 as the same, since
+// EBU STL and many TTML files only declare the nominal rate
+const frameRateTolerance = 0.05
+
+// Subtitle checks
+const (
+	CheckParse        = "parse"
+	CheckTiming       = "timing"
+	CheckOverlap      = "overlap"
+	CheckDuration     = "min_duration"
+	CheckReadingSpeed = "reading_speed"
+	CheckLineLength   = "line_length"
+	CheckLineCount    = "line_count"
+	CheckFrameRate    = "frame_rate"
+	CheckMediaEnd     = "media_duration"
+)
+
+// ErrUnknownFormat is returned for files that are not SRT, TTML or EBU STL
+var ErrUnknownFormat = errors.New("unrecognized subtitle format, expected SRT, TTML/IMSC or EBU STL")
+
+// Options are the limits and reference media a file is validated against.
+// Zero values use the defaults; a zero frame rate or reference duration
+// skips the checks that need them.
+type Options struct {
+	MaxCPS            float64 // Characters per second
+	MaxLineLength     int     // Characters per line
+	MaxLines          int     // Lines per cue
+	MinCueDuration    float64 // Seconds
+	FrameRate         float64 // Frame rate of the reference media
+	ReferenceDuration float64 // Duration of the reference media in seconds
+}
+
+// Validation is the result of validating a subtitle file
+type Validation struct {
+	Format            string   `json:"format"`
+	Profile           string   `json:"profile,omitempty"`    // TTML profile, e.g. IMSC1 text
+	FrameRate         float64  `json:"frame_rate,omitempty"` // Declared by the file
+	Cues              int      `json:"cues"`
+	FirstCueStart     float64  `json:"first_cue_start"`
+	LastCueEnd        float64  `json:"last_cue_end"`
+	MaxCPS            float64  `json:"max_cps"`
+	MaxLineLength     int      `json:"max_line_length"`
+	MaxLines          int      `json:"max_lines"`
+	ReferenceDuration float64  `json:"reference_duration,omitempty"`
+	Valid             bool     `json:"valid"` // No errors
+	Errors            int      `json:"errors"`
+	Warnings          int      `json:"warnings"`
+	Issues            []*Issue `json:"issues"`
+	IssuesTruncated   bool     `json:"issues_truncated,omitempty"`
+}
+
+// Issue is one rule a subtitle file breaks
+type Issue struct {
+	Check    string   `json:"check"`
+	Severity string   `json:"severity"`       // error or warning
+	Cue      int      `json:"cue,omitempty"`  // 1-based position of the cue in the file
+	Time     *float64 `json:"time,omitempty"` // Start of the cue in seconds
+	Message  string   `json:"message"`
+}
+
+// cue is a parsed subtitle with its times in seconds
+type cue struct {
+	number int // 1-based position in the file
+	start  float64
+	end    float64
+	lines  []string
+}
+
+// document is a parsed subtitle file, with the issues found while parsing
+type document struct {
+	format    string
+	profile   string
+	frameRate float64
+	cues      []cue
+	issues    []*Issue
+	// frameTimed is set for formats whose times are whole frames, which
+	// makes the frame boundary check redundant
+	frameTimed bool
+}
+
+// Detect returns the format of a subtitle file from its contents
+func Detect(data []byte) (string, error) {
+	if len(data) >= 1024 && bytes.HasPrefix(data[3:], []byte("STL")) {
+		return FormatSTL, nil
+	}
+	text := bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM))
+	switch {
+	case bytes.HasPrefix(text, []byte("<")):
+		return FormatTTML, nil
+	case bytes.Contains(text, []byte("-->")):
+		return FormatSRT, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// Validate detects the format of a subtitle file and checks its cues
+func Validate(data []byte, opts Options) (*Validation, error) {
+	format, err := Detect(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc *document
+	switch format {
+	case FormatSTL:
+		doc, err = parseSTL(data)
+	case FormatTTML:
+		doc, err = parseTTML(data)
+	default:
+		doc, err = parseSRT(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return validate(doc, opts.withDefaults()), nil
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxCPS <= 0 {
+		o.MaxCPS = DefaultMaxCPS
+	}
+	if o.MaxLineLength <= 0 {
+		o.MaxLineLength = DefaultMaxLineLength
+	}
+	if o.MaxLines <= 0 {
+		o.MaxLines = DefaultMaxLines
+	}
+	if o.MinCueDuration <= 0 {
+		o.MinCueDuration = DefaultMinCueDuration
+	}
+	return o
+}
+
+func validate(doc *document, opts Options) *Validation {
+	v := &Validation{
+		Format:            doc.format,
+		Profile:           doc.profile,
+		FrameRate:         doc.frameRate,
+		Cues:              len(doc.cues),
+		ReferenceDuration: opts.ReferenceDuration,
+		Issues:            []*Issue{},
+	}
+	for _, issue := range doc.issues {
+		v.add(issue)
+	}
+
+	if opts.FrameRate > 0 && doc.frameRate > 0 && math.Abs(opts.FrameRate-doc.frameRate) > frameRateTolerance {
+		v.add(&Issue{
+			Check:    CheckFrameRate,
+			Severity: "error",
+			Message:  fmt.Sprintf("File is timed at %s fps but the media runs at %s fps", formatRate(doc.frameRate), formatRate(opts.FrameRate)),
+		})
+	}
+
+	cues := append([]cue(nil), doc.cues...)
+	sort.SliceStable(cues, func(i, j int) bool { return cues[i].start < cues[j].start })
+
+	// The cue shown until the latest time so far, which a later cue
+	// starting before that time overlaps
+	var shown *cue
+	offFrame := 0
+	for i, c := range cues {
+		if i == 0 || c.start < v.FirstCueStart {
+			v.FirstCueStart = c.start
+		}
+		v.LastCueEnd = max(v.LastCueEnd, c.end)
+		v.MaxLines = max(v.MaxLines, len(c.lines))
+
+		duration := c.end - c.start
+		if duration <= 0 {
+			v.addCue(c, CheckTiming, "error", fmt.Sprintf("Cue ends at %s, not after it starts", formatTime(c.end)))
+			continue
+		}
+		if duration < opts.MinCueDuration {
+			v.addCue(c, CheckDuration, "warning", fmt.Sprintf("Cue is shown for %.3f s, less than %.3f s", duration, opts.MinCueDuration))
+		}
+		if shown != nil && c.start < shown.end {
+			v.addCue(c, CheckOverlap, "error", fmt.Sprintf("Cue starts at %s before cue %d ends at %s", formatTime(c.start), shown.number, formatTime(shown.end)))
+		}
+		if shown == nil || c.end > shown.end {
+			shown = &cues[i]
+		}
+
+		characters := 0
+		for _, line := range c.lines {
+			length := utf8.RuneCountInString(line)
+			characters += length
+			v.MaxLineLength = max(v.MaxLineLength, length)
+			if length > opts.MaxLineLength {
+				v.addCue(c, CheckLineLength, "warning", fmt.Sprintf("Line has %d characters, more than %d", length, opts.MaxLineLength))
+			}
+		}
+		if len(c.lines) > opts.MaxLines {
+			v.addCue(c, CheckLineCount, "warning", fmt.Sprintf("Cue has %d lines, more than %d", len(c.lines), opts.MaxLines))
+		}
+		cps := float64(characters) / duration
+		v.MaxCPS = max(v.MaxCPS, math.Round(cps*10)/10)
+		if cps > opts.MaxCPS {
+			v.addCue(c, CheckReadingSpeed, "warning", fmt.Sprintf("Reading speed is %.1f characters per second, more than %.1f", cps, opts.MaxCPS))
+		}
+
+		if opts.ReferenceDuration > 0 && c.end > opts.ReferenceDuration {
+			v.addCue(c, CheckMediaEnd, "error", fmt.Sprintf("Cue ends at %s, after the media ends at %s", formatTime(c.end), formatTime(opts.ReferenceDuration)))
+		}
+		if opts.FrameRate > 0 && !doc.frameTimed && (!onFrame(c.start, opts.FrameRate) || !onFrame(c.end, opts.FrameRate)) {
+			offFrame++
+		}
+	}
+
+	if offFrame > 0 {
+		v.add(&Issue{
+			Check:    CheckFrameRate,
+			Severity: "warning",
+			Message:  fmt.Sprintf("%d cues start or end between frames at %s fps", offFrame, formatRate(opts.FrameRate)),
+		})
+	}
+
+	v.Valid = v.Errors == 0
+	return v
+}
+
+func (v *Validation) add(issue *Issue) {
+	if issue.Severity == "error" {
+		v.Errors++
+	} else {
+		v.Warnings++
+	}
+	if len(v.Issues) >= maxReportedIssues {
+		v.IssuesTruncated = true
+		return
+	}
+	v.Issues = append(v.Issues, issue)
+}
+
+func (v *Validation) addCue(c cue, check, severity, message string) {
+	start := c.start
+	v.add(&Issue{Check: check, Severity: severity, Cue: c.number, Time: &start, Message: message})
+}
+
+// onFrame reports whether a time falls on a frame boundary, allowing for
+// the millisecond rounding of SRT and TTML clock times
+func onFrame(seconds, frameRate float64) bool {
+	frames := seconds * frameRate
+	return math.Abs(frames-math.Round(frames))/frameRate <= 0.0005+1e-9
+}
+
+// formatTime formats seconds as HH:MM:SS.mmm
+func formatTime(seconds float64) string {
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(math.Round(rate*1000)/1000, 'f', -1, 64)
+}
@@ -0,0 +1,230 @@
+package subtitles
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func issueChecks(v *Validation) map[string]int {
+	checks := map[string]int{}
+	for _, issue := range v.Issues {
+		checks[issue.Check]++
+	}
+	return checks
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"srt", []byte("1\n00:00:01,000 --> 00:00:02,000\nHello\n"), FormatSRT},
+		{"ttml with bom", []byte("\xef\xbb\xbf<?xml version=\"1.0\"?><tt/>"), FormatTTML},
+		{"stl", stlFile(25, "00000000"), FormatSTL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Detect(tt.data)
+			if err != nil || got != tt.want {
+				t.Errorf("Detect() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Detect([]byte("WEBVTT\n")); err != ErrUnknownFormat {
+		t.Errorf("Detect(WEBVTT) error = %v, want ErrUnknownFormat", err)
+	}
+}
+
+func TestValidate_SRT(t *testing.T) {
+	srt := "1\r\n00:00:01,000 --> 00:00:03,000\r\n<i>Hello there</i>\r\n\r\n" +
+		"2\r\n00:00:02,500 --> 00:00:04,000\r\nOverlapping cue\r\n\r\n" +
+		"3\r\n00:00:05,000 --> 00:00:05,500\r\nThis cue is far too long to read in half a second\r\n\r\n" +
+		"4\r\n00:00:06,000 --> 00:00:09,000\r\nOne\r\nTwo\r\nThree\r\n\r\n" +
+		"5\r\nnot a timing line\r\nBroken\r\n"
+
+	v, err := Validate([]byte(srt), Options{ReferenceDuration: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Format != FormatSRT || v.Cues != 4 {
+		t.Errorf("format %q with %d cues, want srt with 4", v.Format, v.Cues)
+	}
+	checks := issueChecks(v)
+	for check, want := range map[string]int{
+		CheckParse:        1,
+		CheckOverlap:      1,
+		CheckDuration:     1,
+		CheckReadingSpeed: 1,
+		CheckLineLength:   1,
+		CheckLineCount:    1,
+		CheckMediaEnd:     1,
+	} {
+		if checks[check] != want {
+			t.Errorf("%d %s issues, want %d: %+v", checks[check], check, want, v.Issues)
+		}
+	}
+	if v.Valid {
+		t.Error("file with overlaps should not be valid")
+	}
+	if v.MaxLineLength != 49 || v.MaxLines != 3 || v.LastCueEnd != 9 {
+		t.Errorf("max line length %d, max lines %d, last cue end %v", v.MaxLineLength, v.MaxLines, v.LastCueEnd)
+	}
+}
+
+func TestValidate_SRTFrameBoundaries(t *testing.T) {
+	srt := "1\n00:00:01,000 --> 00:00:03,040\nOn frames\n\n2\n00:00:04,010 --> 00:00:06,000\nOff frames\n"
+
+	v, err := Validate([]byte(srt), Options{FrameRate: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Issues) != 1 || v.Issues[0].Check != CheckFrameRate || v.Issues[0].Severity != "warning" {
+		t.Fatalf("issues = %+v, want one frame rate warning", v.Issues)
+	}
+	if !strings.HasPrefix(v.Issues[0].Message, "1 cues") {
+		t.Errorf("message = %q", v.Issues[0].Message)
+	}
+}
+
+const testTTML = `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:ttp="http://www.w3.org/ns/ttml#parameter"
+    ttp:frameRate="25" ttp:profile="http://www.w3.org/ns/ttml/profile/imsc1/text">
+  <head/>
+  <body>
+    <div begin="10s">
+      <p begin="00:00:01:00" end="00:00:03:00">First line<br/>
+        <span>second   line</span></p>
+      <p begin="2.4s" dur="2s">Overlaps the first</p>
+      <p begin="00:00:06:30" end="00:00:09:00">Bad frame</p>
+    </div>
+  </body>
+</tt>`
+
+func TestValidate_TTML(t *testing.T) {
+	v, err := Validate([]byte(testTTML), Options{FrameRate: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Format != FormatTTML || v.FrameRate != 25 || !strings.Contains(v.Profile, "imsc1") {
+		t.Errorf("format %q, frame rate %v, profile %q", v.Format, v.FrameRate, v.Profile)
+	}
+	if v.Cues != 3 || v.FirstCueStart != 11 || v.LastCueEnd != 19 {
+		t.Errorf("%d cues from %v to %v, want 3 from 11 to 19", v.Cues, v.FirstCueStart, v.LastCueEnd)
+	}
+	if v.MaxLines != 2 || v.MaxLineLength != 18 {
+		t.Errorf("max lines %d, max line length %d", v.MaxLines, v.MaxLineLength)
+	}
+	checks := issueChecks(v)
+	if checks[CheckOverlap] != 1 || checks[CheckFrameRate] != 1 {
+		t.Errorf("issues = %+v, want an overlap and a frame rate error", v.Issues)
+	}
+}
+
+func TestValidate_TTMLFrameRateMismatch(t *testing.T) {
+	ttml := `<tt xmlns="http://www.w3.org/ns/ttml" xmlns:ttp="http://www.w3.org/ns/ttml#parameter"
+	ttp:frameRate="30" ttp:frameRateMultiplier="1000 1001"><body><div>
+	<p begin="00:00:01:00" end="00:00:02:15">Hello</p></div></body></tt>`
+
+	v, err := Validate([]byte(ttml), Options{FrameRate: 25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.FrameRate < 29.96 || v.FrameRate > 29.98 {
+		t.Errorf("frame rate = %v, want 29.97", v.FrameRate)
+	}
+	if v.Valid || v.Issues[0].Check != CheckFrameRate {
+		t.Errorf("issues = %+v, want a frame rate error", v.Issues)
+	}
+
+	if v, _ := Validate([]byte(ttml), Options{FrameRate: 30000.0 / 1001}); !v.Valid {
+		t.Errorf("issues at matching frame rate: %+v", v.Issues)
+	}
+}
+
+func TestValidate_TTMLInvalid(t *testing.T) {
+	for _, doc := range []string{`<html></html>`, `<tt><body><p begin="1s" end="2s">Unclosed</body></tt>`} {
+		if _, err := Validate([]byte(doc), Options{}); err == nil {
+			t.Errorf("Validate(%q) succeeded", doc)
+		}
+	}
+}
+
+// stlFile builds an EBU STL file with the given disk format frame rate and
+// start of programme time code
+func stlFile(frameRate int, tcp string, blocks ...[]byte) []byte {
+	gsi := make([]byte, stlGSISize)
+	for i := range gsi {
+		gsi[i] = ' '
+	}
+	copy(gsi, "850")
+	copy(gsi[3:], "STL"+map[int]string{25: "25", 30: "30"}[frameRate]+".01")
+	copy(gsi[256:], tcp)
+	data := gsi
+	for _, block := range blocks {
+		data = append(data, block...)
+	}
+	return data
+}
+
+func ttiBlock(number uint16, extension byte, in, out [4]byte, text ...string) []byte {
+	tti := make([]byte, stlTTISize)
+	binary.LittleEndian.PutUint16(tti[1:], number)
+	tti[3] = extension
+	copy(tti[5:], in[:])
+	copy(tti[9:], out[:])
+	field := tti[stlTextOffset:]
+	for i := range field {
+		field[i] = 0x8F
+	}
+	copy(field, strings.Join(text, "\x8a"))
+	return tti
+}
+
+func TestValidate_STL(t *testing.T) {
+	data := stlFile(25, "10000000",
+		ttiBlock(1, stlLastBlock, [4]byte{10, 0, 1, 0}, [4]byte{10, 0, 3, 0}, "First", "\x0dDouble \xc2ecart"),
+		ttiBlock(2, 0, [4]byte{10, 0, 4, 0}, [4]byte{10, 0, 6, 0}, "Split over", ""),
+		ttiBlock(2, stlLastBlock, [4]byte{10, 0, 4, 0}, [4]byte{10, 0, 6, 0}, "two blocks"),
+		ttiBlock(3, stlUserData, [4]byte{}, [4]byte{}, "user data"),
+		ttiBlock(4, stlLastBlock, [4]byte{10, 0, 5, 24}, [4]byte{10, 0, 7, 25}, "Bad frame"),
+	)
+
+	v, err := Validate(data, Options{FrameRate: 25, ReferenceDuration: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Format != FormatSTL || v.FrameRate != 25 || v.Cues != 3 {
+		t.Fatalf("format %q, frame rate %v, %d cues", v.Format, v.FrameRate, v.Cues)
+	}
+	if v.FirstCueStart != 1 || v.MaxLines != 2 {
+		t.Errorf("first cue start %v, max lines %d", v.FirstCueStart, v.MaxLines)
+	}
+	// Teletext codes are dropped and the diacritic merged: "Double ecart"
+	if v.MaxLineLength != 12 {
+		t.Errorf("max line length = %d, want 12", v.MaxLineLength)
+	}
+	checks := issueChecks(v)
+	if checks[CheckFrameRate] != 1 || checks[CheckOverlap] != 1 || len(v.Issues) != 2 {
+		t.Errorf("issues = %+v, want a frame rate error and an overlap", v.Issues)
+	}
+
+	v, _ = Validate(data, Options{FrameRate: 23.976})
+	mismatch := false
+	for _, issue := range v.Issues {
+		mismatch = mismatch || issue.Check == CheckFrameRate && strings.Contains(issue.Message, "23.976")
+	}
+	if !mismatch {
+		t.Errorf("issues = %+v, want a frame rate mismatch", v.Issues)
+	}
+}
+
+func TestValidate_STLInvalidDiskFormat(t *testing.T) {
+	data := stlFile(25, "")
+	copy(data[3:], "STL99.01")
+	if _, err := Validate(data, Options{}); err == nil {
+		t.Error("unsupported disk format code accepted")
+	}
+}
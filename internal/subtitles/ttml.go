@@ -0,0 +1,255 @@
+package subtitles
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// TTML parameter defaults, from TTML2 section 7.2
+const (
+	ttmlDefaultFrameRate = 30
+	ttmlDefaultTickRate  = 1
+)
+
+var (
+	ttmlClockTime  = regexp.MustCompile(`^(\d{2,}):(\d{2}):(\d{2})(?:\.(\d+)|:(\d{2,})(?:\.(\d+))?)?$`)
+	ttmlOffsetTime = regexp.MustCompile(`^(\d+(?:\.\d+)?)(h|m|s|ms|f|t)$`)
+	whitespaceRun  = regexp.MustCompile(`\s+`)
+)
+
+// ttmlTiming holds the tt element parameters that time expressions are
+// resolved with
+type ttmlTiming struct {
+	frameRate  int     // ttp:frameRate, used by frame counts in time expressions
+	multiplier float64 // ttp:frameRateMultiplier
+	tickRate   float64 // ttp:tickRate
+}
+
+// effectiveFrameRate is the frame rate with its multiplier, e.g. 29.97 for a
+// frame rate of 30 with a multiplier of 1000 1001
+func (t ttmlTiming) effectiveFrameRate() float64 {
+	return float64(t.frameRate) * t.multiplier
+}
+
+// parseTTML parses a TTML document, including the IMSC1 and EBU-TT-D
+// profiles. Every p element is a cue, timed relative to the begin of its
+// ancestors.
+func parseTTML(data []byte) (*document, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	doc := &document{format: FormatTTML}
+	timing := ttmlTiming{frameRate: ttmlDefaultFrameRate, multiplier: 1, tickRate: ttmlDefaultTickRate}
+
+	// Active intervals of the open elements, starting with the document,
+	// which begins at zero and has no end
+	intervals := []ttmlInterval{{}}
+	var current *cue
+	var line strings.Builder
+	seenRoot := false
+
+	flushLine := func() {
+		text := strings.TrimSpace(whitespaceRun.ReplaceAllString(line.String(), " "))
+		if text != "" {
+			current.lines = append(current.lines, text)
+		}
+		line.Reset()
+	}
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid TTML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if !seenRoot {
+				if t.Name.Local != "tt" {
+					return nil, fmt.Errorf("invalid TTML: root element is %q, not tt", t.Name.Local)
+				}
+				seenRoot = true
+				if err := doc.readTTMLParameters(t, &timing); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if t.Name.Local == "br" {
+				if current != nil {
+					flushLine()
+				}
+				continue
+			}
+
+			interval := doc.resolveInterval(t, intervals[len(intervals)-1], timing)
+			intervals = append(intervals, interval)
+			if t.Name.Local == "p" && current == nil {
+				if !interval.hasEnd {
+					doc.issues = append(doc.issues, &Issue{
+						Check:    CheckParse,
+						Severity: "error",
+						Message:  fmt.Sprintf("Paragraph %d has no end time", len(doc.cues)+1),
+					})
+				}
+				current = &cue{number: len(doc.cues) + 1, start: interval.begin, end: interval.end}
+			}
+
+		case xml.EndElement:
+			if t.Name.Local == "br" || t.Name.Local == "tt" {
+				continue
+			}
+			interval := intervals[len(intervals)-1]
+			intervals = intervals[:len(intervals)-1]
+			if t.Name.Local == "p" && current != nil {
+				flushLine()
+				if interval.hasEnd {
+					doc.cues = append(doc.cues, *current)
+				}
+				current = nil
+			}
+
+		case xml.CharData:
+			if current != nil {
+				line.Write(t)
+			}
+		}
+	}
+
+	if !seenRoot {
+		return nil, errors.New("invalid TTML: no tt element")
+	}
+	return doc, nil
+}
+
+// ttmlInterval is the active interval of a TTML element in seconds
+type ttmlInterval struct {
+	begin  float64
+	end    float64
+	hasEnd bool
+}
+
+// resolveInterval resolves the begin, end and dur attributes of an element
+// against the interval of its parent. Elements without an end end with
+// their parent.
+func (doc *document) resolveInterval(element xml.StartElement, parent ttmlInterval, timing ttmlTiming) ttmlInterval {
+	interval := parent
+	var end, dur *float64
+	for _, attr := range element.Attr {
+		if attr.Name.Space != "" {
+			continue
+		}
+		if attr.Name.Local != "begin" && attr.Name.Local != "end" && attr.Name.Local != "dur" {
+			continue
+		}
+		value, err := doc.ttmlTime(attr.Value, timing)
+		if err != nil {
+			doc.issues = append(doc.issues, &Issue{Check: CheckParse, Severity: "error", Message: err.Error()})
+			continue
+		}
+		switch attr.Name.Local {
+		case "begin":
+			interval.begin = parent.begin + value
+		case "end":
+			end = &value
+		case "dur":
+			dur = &value
+		}
+	}
+
+	switch {
+	case end != nil:
+		interval.end, interval.hasEnd = parent.begin+*end, true
+	case dur != nil:
+		interval.end, interval.hasEnd = interval.begin+*dur, true
+	}
+	if parent.hasEnd && (!interval.hasEnd || interval.end > parent.end) {
+		interval.end, interval.hasEnd = parent.end, true
+	}
+	return interval
+}
+
+// readTTMLParameters reads the timing parameters and profile of the tt
+// element
+func (doc *document) readTTMLParameters(tt xml.StartElement, timing *ttmlTiming) error {
+	declaredRate, declaredTicks := false, false
+	for _, attr := range tt.Attr {
+		switch attr.Name.Local {
+		case "frameRate":
+			rate, err := strconv.Atoi(strings.TrimSpace(attr.Value))
+			if err != nil || rate <= 0 {
+				return fmt.Errorf("invalid TTML: ttp:frameRate %q", attr.Value)
+			}
+			timing.frameRate = rate
+			declaredRate = true
+		case "frameRateMultiplier":
+			var numerator, denominator float64
+			if _, err := fmt.Sscanf(attr.Value, "%g %g", &numerator, &denominator); err != nil || numerator <= 0 || denominator <= 0 {
+				return fmt.Errorf("invalid TTML: ttp:frameRateMultiplier %q", attr.Value)
+			}
+			timing.multiplier = numerator / denominator
+		case "tickRate":
+			rate, err := strconv.ParseFloat(strings.TrimSpace(attr.Value), 64)
+			if err != nil || rate <= 0 {
+				return fmt.Errorf("invalid TTML: ttp:tickRate %q", attr.Value)
+			}
+			timing.tickRate = rate
+			declaredTicks = true
+		case "profile":
+			doc.profile = attr.Value
+		}
+	}
+	if declaredRate {
+		doc.frameRate = timing.effectiveFrameRate()
+		if !declaredTicks {
+			timing.tickRate = float64(timing.frameRate)
+		}
+	}
+	return nil
+}
+
+// ttmlTime converts a TTML clock time or offset time to seconds. Frame
+// counts that do not fit the frame rate are reported.
+func (doc *document) ttmlTime(value string, timing ttmlTiming) (float64, error) {
+	value = strings.TrimSpace(value)
+	if m := ttmlClockTime.FindStringSubmatch(value); m != nil {
+		seconds := clockSeconds(m[1], m[2], m[3], m[4])
+		if m[5] != "" {
+			frames, _ := strconv.Atoi(m[5])
+			if frames >= timing.frameRate {
+				doc.issues = append(doc.issues, &Issue{
+					Check:    CheckFrameRate,
+					Severity: "error",
+					Message:  fmt.Sprintf("Time %s has frame %d, but the frame rate is %d", value, frames, timing.frameRate),
+				})
+			}
+			seconds += float64(frames) / timing.effectiveFrameRate()
+		}
+		return seconds, nil
+	}
+
+	m := ttmlOffsetTime.FindStringSubmatch(value)
+	if m == nil {
+		return 0, fmt.Errorf("invalid TTML time expression %q", value)
+	}
+	count, _ := strconv.ParseFloat(m[1], 64)
+	switch m[2] {
+	case "h":
+		return count * 3600, nil
+	case "m":
+		return count * 60, nil
+	case "ms":
+		return count / 1000, nil
+	case "f":
+		return count / timing.effectiveFrameRate(), nil
+	case "t":
+		return count / timing.tickRate, nil
+	}
+	return count, nil
+}
@@ -0,0 +1,67 @@
+// Package tracing configures OpenTelemetry tracing for the probe pipeline.
+//
+// Packages create spans through the global tracer provider, so instrumentation
+// is a no-op until Init installs an exporting provider.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rendiffdev/rendiff-probe/internal/config"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+// ServiceName is reported as service.name on every span
+const ServiceName = "rendiff-probe"
+
+// ShutdownFunc flushes pending spans and stops the exporter
+type ShutdownFunc func(ctx context.Context) error
+
+// Init installs a global tracer provider that exports spans over OTLP/gRPC.
+// The exporter reads its endpoint, headers and TLS settings from the standard
+// OTEL_EXPORTER_OTLP_* environment variables. When tracing is disabled the
+// returned shutdown function does nothing.
+func Init(ctx context.Context, cfg *config.Config, version string, logger zerolog.Logger) (ShutdownFunc, error) {
+	if !cfg.EnableTracing {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.TracingSampleRatio))),
+	)
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	logger.Info().
+		Float64("sample_ratio", cfg.TracingSampleRatio).
+		Msg("OpenTelemetry tracing enabled")
+
+	return provider.Shutdown, nil
+}
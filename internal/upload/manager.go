@@ -0,0 +1,413 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+var (
+	// ErrSessionNotFound is returned for unknown or expired upload IDs
+	ErrSessionNotFound = errors.New("upload session not found")
+	// ErrOffsetMismatch is returned when a chunk does not start at the current offset
+	ErrOffsetMismatch = errors.New("upload offset mismatch")
+	// ErrSizeExceeded is returned when a chunk would grow the file past its declared size
+	ErrSizeExceeded = errors.New("chunk exceeds declared upload size")
+	// ErrIncomplete is returned when completing an upload that is missing bytes
+	ErrIncomplete = errors.New("upload is incomplete")
+	// ErrNotUploading is returned when appending to or completing a finished session
+	ErrNotUploading = errors.New("upload session is no longer accepting data")
+	// ErrTooManySessions is returned when the active session limit is reached
+	ErrTooManySessions = errors.New("too many active upload sessions")
+)
+
+// Status is the lifecycle state of an upload session
+type Status string
+
+const (
+	StatusUploading  Status = "uploading"  // Accepting chunks
+	StatusProcessing Status = "processing" // All bytes received, analysis running
+	StatusCompleted  Status = "completed"  // Analysis finished; Result is set
+	StatusFailed     Status = "failed"     // Analysis failed; Error is set
+)
+
+// Config configures a Manager
+type Config struct {
+	Dir         string        // Directory holding partial uploads
+	MaxFileSize int64         // Largest declared upload size accepted
+	SessionTTL  time.Duration // Idle time before a session and its data expire
+	MaxSessions int           // Maximum sessions uploading or processing at once
+
+	// Reserve, if set, accounts for the declared size of each session
+	// until its data is removed; its error fails Create
+	Reserve func(size int64) (release func(), err error)
+}
+
+// Session is a snapshot of an upload session
+type Session struct {
+	ID        string      `json:"upload_id"`
+	Filename  string      `json:"filename"`
+	Size      int64       `json:"size"`
+	Offset    int64       `json:"offset"`
+	Status    Status      `json:"status"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+	ExpiresAt time.Time   `json:"expires_at"`
+	Result    interface{} `json:"result,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// Progress returns the received percentage of the declared size
+func (s Session) Progress() float64 {
+	if s.Size <= 0 {
+		return 0
+	}
+	return float64(s.Offset) / float64(s.Size) * 100
+}
+
+type session struct {
+	Session
+	path    string
+	release func()     // Releases the reserved size, if any
+	writeMu sync.Mutex // Serialises chunk writes so offsets stay consistent
+}
+
+// Manager tracks resumable upload sessions and assembles their data on disk.
+//
+// Clients create a session with the final file size, append chunks at the
+// current offset (resuming after a failure by asking for the offset), and
+// complete the session once every byte has arrived. Sessions that stop
+// receiving data expire after SessionTTL and their partial files are removed.
+type Manager struct {
+	config Config
+	logger zerolog.Logger
+
+	mu       sync.RWMutex
+	sessions map[string]*session
+}
+
+// NewManager creates a Manager, creating the upload directory if needed and
+// removing partial uploads of sessions lost when a previous process stopped
+func NewManager(config Config, logger zerolog.Logger) (*Manager, error) {
+	if config.Dir == "" {
+		config.Dir = filepath.Join(os.TempDir(), "rendiff-uploads")
+	}
+	if config.SessionTTL <= 0 {
+		config.SessionTTL = 24 * time.Hour
+	}
+	if config.MaxSessions <= 0 {
+		config.MaxSessions = 1
+	}
+
+	if err := os.MkdirAll(config.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	// Sessions only live in memory, so every partial file is orphaned
+	orphans, _ := filepath.Glob(filepath.Join(config.Dir, "*.part"))
+	for _, path := range orphans {
+		if err := os.Remove(path); err != nil {
+			logger.Warn().Err(err).Str("path", path).Msg("Failed to remove orphaned upload file")
+		}
+	}
+	if len(orphans) > 0 {
+		logger.Info().Int("count", len(orphans)).Msg("Orphaned upload files removed")
+	}
+
+	return &Manager{
+		config:   config,
+		logger:   logger,
+		sessions: make(map[string]*session),
+	}, nil
+}
+
+// SetMaxFileSize changes the largest size accepted by Create. Sessions
+// already created are not affected.
+func (m *Manager) SetMaxFileSize(size int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.config.MaxFileSize = size
+}
+
+// Create starts a new upload session for a file of the given size
+func (m *Manager) Create(filename string, size int64) (Session, error) {
+	if size <= 0 {
+		return Session{}, fmt.Errorf("upload size must be greater than 0")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.config.MaxFileSize > 0 && size > m.config.MaxFileSize {
+		return Session{}, fmt.Errorf("upload size exceeds limit of %d bytes", m.config.MaxFileSize)
+	}
+
+	if m.activeCountLocked() >= m.config.MaxSessions {
+		return Session{}, ErrTooManySessions
+	}
+
+	release := func() {}
+	if m.config.Reserve != nil {
+		var err error
+		if release, err = m.config.Reserve(size); err != nil {
+			return Session{}, err
+		}
+	}
+
+	id := uuid.New().String()
+	path := filepath.Join(m.config.Dir, id+".part")
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		release()
+		return Session{}, fmt.Errorf("failed to create upload file: %w", err)
+	}
+	file.Close()
+
+	now := time.Now()
+	s := &session{
+		Session: Session{
+			ID:        id,
+			Filename:  filename,
+			Size:      size,
+			Status:    StatusUploading,
+			CreatedAt: now,
+			UpdatedAt: now,
+			ExpiresAt: now.Add(m.config.SessionTTL),
+		},
+		path:    path,
+		release: release,
+	}
+	m.sessions[id] = s
+
+	m.logger.Info().
+		Str("upload_id", id).
+		Int64("size", size).
+		Msg("Upload session created")
+
+	return s.Session, nil
+}
+
+// Get returns a snapshot of a session
+func (m *Manager) Get(id string) (Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.sessions[id]
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+	return s.Session, nil
+}
+
+// Append writes a chunk starting at offset, which must equal the session's
+// current offset. Bytes read before a read error are kept, so a client that
+// loses its connection mid-chunk can resume from the returned offset.
+func (m *Manager) Append(id string, offset int64, r io.Reader) (Session, error) {
+	m.mu.RLock()
+	s, ok := m.sessions[id]
+	m.mu.RUnlock()
+	if !ok {
+		return Session{}, ErrSessionNotFound
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	m.mu.RLock()
+	status, current, size := s.Status, s.Offset, s.Size
+	m.mu.RUnlock()
+
+	if status != StatusUploading {
+		return m.snapshot(s), ErrNotUploading
+	}
+	if offset != current {
+		return m.snapshot(s), ErrOffsetMismatch
+	}
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY, 0)
+	if err != nil {
+		return m.snapshot(s), fmt.Errorf("failed to open upload file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return m.snapshot(s), fmt.Errorf("failed to seek upload file: %w", err)
+	}
+
+	remaining := size - offset
+	written, copyErr := io.Copy(file, io.LimitReader(r, remaining+1))
+	if written > remaining {
+		// Drop the whole chunk rather than silently truncating it
+		if err := file.Truncate(offset); err != nil {
+			return m.snapshot(s), fmt.Errorf("failed to roll back oversized chunk: %w", err)
+		}
+		return m.snapshot(s), ErrSizeExceeded
+	}
+
+	m.mu.Lock()
+	now := time.Now()
+	s.Offset += written
+	s.UpdatedAt = now
+	s.ExpiresAt = now.Add(m.config.SessionTTL)
+	snapshot := s.Session
+	m.mu.Unlock()
+
+	if copyErr != nil {
+		return snapshot, fmt.Errorf("upload interrupted: %w", copyErr)
+	}
+	return snapshot, nil
+}
+
+// Complete marks a fully received upload as processing and returns the path
+// of the assembled file. The file stays owned by the Manager and is removed
+// by Finish, Abort or expiry.
+func (m *Manager) Complete(id string) (Session, string, error) {
+	m.mu.RLock()
+	s, ok := m.sessions[id]
+	m.mu.RUnlock()
+	if !ok {
+		return Session{}, "", ErrSessionNotFound
+	}
+
+	// Wait for any in-flight chunk so the offset check sees its bytes
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if s.Status != StatusUploading {
+		return s.Session, "", ErrNotUploading
+	}
+	if s.Offset != s.Size {
+		return s.Session, "", ErrIncomplete
+	}
+
+	s.Status = StatusProcessing
+	s.UpdatedAt = time.Now()
+	return s.Session, s.path, nil
+}
+
+// Finish records the analysis outcome of a processing session and removes its
+// data. The session remains queryable until it expires.
+func (m *Manager) Finish(id string, result interface{}, err error) {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	if ok {
+		now := time.Now()
+		if err != nil {
+			s.Status = StatusFailed
+			s.Error = err.Error()
+		} else {
+			s.Status = StatusCompleted
+			s.Result = result
+		}
+		s.UpdatedAt = now
+		s.ExpiresAt = now.Add(m.config.SessionTTL)
+	}
+	m.mu.Unlock()
+
+	if ok {
+		m.removeFile(s)
+	}
+}
+
+// Abort cancels a session and deletes its data. Sessions that are still
+// being analyzed cannot be aborted.
+func (m *Manager) Abort(id string) error {
+	m.mu.Lock()
+	s, ok := m.sessions[id]
+	if !ok {
+		m.mu.Unlock()
+		return ErrSessionNotFound
+	}
+	if s.Status == StatusProcessing {
+		m.mu.Unlock()
+		return ErrNotUploading
+	}
+	delete(m.sessions, id)
+	m.mu.Unlock()
+
+	m.removeFile(s)
+	return nil
+}
+
+// CleanupExpired removes sessions past their expiry time and returns how many
+// were removed. Sessions being analyzed never expire.
+func (m *Manager) CleanupExpired() int {
+	now := time.Now()
+	var expired []*session
+
+	m.mu.Lock()
+	for id, s := range m.sessions {
+		if s.Status != StatusProcessing && now.After(s.ExpiresAt) {
+			expired = append(expired, s)
+			delete(m.sessions, id)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, s := range expired {
+		m.removeFile(s)
+	}
+
+	if len(expired) > 0 {
+		m.logger.Info().Int("count", len(expired)).Msg("Expired upload sessions removed")
+	}
+	return len(expired)
+}
+
+// StartCleanup removes expired sessions every interval until ctx is done
+func (m *Manager) StartCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.CleanupExpired()
+			}
+		}
+	}()
+}
+
+// ActiveCount returns the number of sessions uploading or processing
+func (m *Manager) ActiveCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.activeCountLocked()
+}
+
+func (m *Manager) activeCountLocked() int {
+	count := 0
+	for _, s := range m.sessions {
+		if s.Status == StatusUploading || s.Status == StatusProcessing {
+			count++
+		}
+	}
+	return count
+}
+
+func (m *Manager) snapshot(s *session) Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return s.Session
+}
+
+func (m *Manager) removeFile(s *session) {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		m.logger.Warn().Err(err).Str("upload_id", s.ID).Msg("Failed to remove upload file")
+	}
+	s.release()
+}
@@ -0,0 +1,204 @@
+package upload
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func newTestManager(t *testing.T, ttl time.Duration) *Manager {
+	t.Helper()
+	manager, err := NewManager(Config{
+		Dir:         t.TempDir(),
+		MaxFileSize: 1024,
+		SessionTTL:  ttl,
+		MaxSessions: 2,
+	}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	return manager
+}
+
+func TestManager_ChunkedUpload(t *testing.T) {
+	manager := newTestManager(t, time.Hour)
+
+	session, err := manager.Create("video.mp4", 10)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if _, err := manager.Append(session.ID, 0, strings.NewReader("hello")); err != nil {
+		t.Fatalf("first Append failed: %v", err)
+	}
+	if _, _, err := manager.Complete(session.ID); !errors.Is(err, ErrIncomplete) {
+		t.Errorf("expected ErrIncomplete, got %v", err)
+	}
+
+	session, err = manager.Append(session.ID, 5, strings.NewReader("world"))
+	if err != nil {
+		t.Fatalf("second Append failed: %v", err)
+	}
+	if session.Offset != 10 || session.Progress() != 100 {
+		t.Errorf("unexpected offset %d / progress %.0f", session.Offset, session.Progress())
+	}
+
+	session, path, err := manager.Complete(session.ID)
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if session.Status != StatusProcessing {
+		t.Errorf("expected processing status, got %s", session.Status)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read assembled file: %v", err)
+	}
+	if string(data) != "helloworld" {
+		t.Errorf("unexpected file contents %q", data)
+	}
+
+	manager.Finish(session.ID, map[string]string{"ok": "yes"}, nil)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected upload file to be removed after Finish")
+	}
+	if session, _ = manager.Get(session.ID); session.Status != StatusCompleted || session.Result == nil {
+		t.Errorf("unexpected finished session %+v", session)
+	}
+}
+
+func TestManager_RejectsBadChunks(t *testing.T) {
+	manager := newTestManager(t, time.Hour)
+
+	session, err := manager.Create("video.mp4", 4)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	if _, err := manager.Append(session.ID, 2, strings.NewReader("ab")); !errors.Is(err, ErrOffsetMismatch) {
+		t.Errorf("expected ErrOffsetMismatch, got %v", err)
+	}
+
+	session, err = manager.Append(session.ID, 0, strings.NewReader("abcdef"))
+	if !errors.Is(err, ErrSizeExceeded) {
+		t.Errorf("expected ErrSizeExceeded, got %v", err)
+	}
+	if session.Offset != 0 {
+		t.Errorf("oversized chunk should not advance offset, got %d", session.Offset)
+	}
+
+	if _, err := manager.Create("big.mp4", 4096); err == nil {
+		t.Error("expected error for size above MaxFileSize")
+	}
+	manager.SetMaxFileSize(8192)
+	if _, err := manager.Create("big.mp4", 4096); err != nil {
+		t.Errorf("raised MaxFileSize should accept the upload: %v", err)
+	}
+}
+
+func TestManager_KeepsPartialChunkOnReadError(t *testing.T) {
+	manager := newTestManager(t, time.Hour)
+
+	session, err := manager.Create("video.mp4", 10)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	reader := io.MultiReader(bytes.NewReader([]byte("abc")), &failingReader{})
+	session, err = manager.Append(session.ID, 0, reader)
+	if err == nil {
+		t.Fatal("expected interrupted upload error")
+	}
+	if session.Offset != 3 {
+		t.Errorf("expected offset 3 after interrupted chunk, got %d", session.Offset)
+	}
+}
+
+func TestManager_SessionLimitAndExpiry(t *testing.T) {
+	manager := newTestManager(t, time.Millisecond)
+
+	first, err := manager.Create("a.mp4", 1)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := manager.Create("b.mp4", 1); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := manager.Create("c.mp4", 1); !errors.Is(err, ErrTooManySessions) {
+		t.Errorf("expected ErrTooManySessions, got %v", err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if removed := manager.CleanupExpired(); removed != 2 {
+		t.Errorf("expected 2 expired sessions, got %d", removed)
+	}
+	if _, err := manager.Get(first.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("expected expired session to be gone, got %v", err)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestManager_ReservesDeclaredSize(t *testing.T) {
+	var reserved int64
+	errFull := errors.New("disk full")
+	manager, err := NewManager(Config{
+		Dir:         t.TempDir(),
+		MaxSessions: 2,
+		Reserve: func(size int64) (func(), error) {
+			if reserved+size > 10 {
+				return nil, errFull
+			}
+			reserved += size
+			return func() { reserved -= size }, nil
+		},
+	}, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	session, err := manager.Create("video.mp4", 8)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, err := manager.Create("other.mp4", 4); !errors.Is(err, errFull) {
+		t.Errorf("expected the reservation error, got %v", err)
+	}
+	if manager.ActiveCount() != 1 || reserved != 8 {
+		t.Errorf("expected one session holding 8 bytes, got %d sessions and %d bytes", manager.ActiveCount(), reserved)
+	}
+
+	if err := manager.Abort(session.ID); err != nil {
+		t.Fatalf("Abort failed: %v", err)
+	}
+	if reserved != 0 {
+		t.Errorf("expected Abort to release the reservation, %d bytes reserved", reserved)
+	}
+}
+
+func TestNewManager_RemovesOrphanedParts(t *testing.T) {
+	dir := t.TempDir()
+	orphan := filepath.Join(dir, "lost.part")
+	if err := os.WriteFile(orphan, []byte("data"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewManager(Config{Dir: dir}, zerolog.Nop()); err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("expected orphaned part to be removed, got %v", err)
+	}
+}
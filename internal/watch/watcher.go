@@ -0,0 +1,347 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Verdicts recorded for processed files
+const (
+	VerdictPassed = "passed"
+	VerdictFailed = "failed"
+)
+
+// DefaultExtensions are the media files picked up when Config.Extensions is empty
+var DefaultExtensions = []string{
+	".mp4", ".avi", ".mkv", ".mov", ".wmv", ".flv", ".webm",
+	".m4v", ".mpg", ".mpeg", ".3gp", ".3g2", ".mxf", ".ts",
+	".mp3", ".wav", ".flac", ".aac", ".ogg", ".wma", ".m4a", ".opus",
+}
+
+// Config configures a Watcher
+type Config struct {
+	Folders    []string      // Directories to scan; subdirectories are not scanned
+	Interval   time.Duration // Time between scans
+	PassDir    string        // Destination of passing files, relative to their folder unless absolute
+	FailDir    string        // Destination of failing files
+	Extensions []string      // Lower-case extensions to pick up, including the dot
+}
+
+// Outcome is the QC verdict of one file
+type Outcome struct {
+	AnalysisID string
+	Passed     bool
+	Reasons    []string // Why the file failed
+}
+
+// Analyzer analyzes the file at path and applies the QC policy. An error
+// fails the file with the error as its reason.
+type Analyzer func(ctx context.Context, path string) (Outcome, error)
+
+// Result is the record of a processed file
+type Result struct {
+	Folder      string    `json:"folder"`
+	Filename    string    `json:"filename"`
+	Size        int64     `json:"size"`
+	AnalysisID  string    `json:"analysis_id,omitempty"`
+	Verdict     string    `json:"verdict"`
+	Reasons     []string  `json:"reasons,omitempty"`
+	MovedTo     string    `json:"moved_to,omitempty"`
+	Error       string    `json:"error,omitempty"` // Set when the file could not be moved
+	ProcessedAt time.Time `json:"processed_at"`
+}
+
+// FolderStatus is a snapshot of a watched folder
+type FolderStatus struct {
+	Path      string     `json:"path"`
+	PassDir   string     `json:"pass_dir"`
+	FailDir   string     `json:"fail_dir"`
+	Pending   int        `json:"pending"` // Files waiting to stop changing
+	Passed    int        `json:"passed"`
+	Failed    int        `json:"failed"`
+	LastScan  *time.Time `json:"last_scan,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// fileState identifies a version of a file between scans
+type fileState struct {
+	size    int64
+	modTime time.Time
+	done    bool // Processed but could not be moved away
+}
+
+type folder struct {
+	status FolderStatus
+	files  map[string]fileState
+}
+
+// Watcher scans folders for new media files, analyzes them and moves them
+// to a pass or fail folder.
+//
+// A file is only picked up once its size and modification time are the same
+// on two consecutive scans, so files still being copied in are left alone.
+// Files are processed one at a time to keep the load of a large drop bounded.
+type Watcher struct {
+	config   Config
+	analyze  Analyzer
+	logger   zerolog.Logger
+	onResult func(Result)
+
+	mu      sync.RWMutex
+	folders []*folder
+}
+
+// NewWatcher creates a Watcher, creating the pass and fail folders if needed
+func NewWatcher(config Config, analyze Analyzer, logger zerolog.Logger) (*Watcher, error) {
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.PassDir == "" {
+		config.PassDir = VerdictPassed
+	}
+	if config.FailDir == "" {
+		config.FailDir = VerdictFailed
+	}
+	if len(config.Extensions) == 0 {
+		config.Extensions = DefaultExtensions
+	}
+
+	w := &Watcher{
+		config:  config,
+		analyze: analyze,
+		logger:  logger,
+	}
+	for _, path := range config.Folders {
+		path = filepath.Clean(path)
+		f := &folder{
+			status: FolderStatus{
+				Path:    path,
+				PassDir: destination(path, config.PassDir),
+				FailDir: destination(path, config.FailDir),
+			},
+			files: make(map[string]fileState),
+		}
+		for _, dir := range []string{f.status.PassDir, f.status.FailDir} {
+			if err := os.MkdirAll(dir, 0o750); err != nil {
+				return nil, fmt.Errorf("failed to create watch destination: %w", err)
+			}
+		}
+		w.folders = append(w.folders, f)
+	}
+	return w, nil
+}
+
+// SetResultHandler sets the function called for every processed file
+func (w *Watcher) SetResultHandler(handler func(Result)) {
+	w.onResult = handler
+}
+
+// Start scans every interval until ctx is done, starting immediately
+func (w *Watcher) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(w.config.Interval)
+		defer ticker.Stop()
+
+		for {
+			w.Scan(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Scan checks every folder once and processes the files that have stopped
+// changing. It returns the number of files processed.
+func (w *Watcher) Scan(ctx context.Context) int {
+	processed := 0
+	for _, f := range w.folders {
+		if ctx.Err() != nil {
+			break
+		}
+		processed += w.scanFolder(ctx, f)
+	}
+	return processed
+}
+
+// Folders returns a snapshot of every watched folder
+func (w *Watcher) Folders() []FolderStatus {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	statuses := make([]FolderStatus, len(w.folders))
+	for i, f := range w.folders {
+		statuses[i] = f.status
+	}
+	return statuses
+}
+
+func (w *Watcher) scanFolder(ctx context.Context, f *folder) int {
+	entries, err := os.ReadDir(f.status.Path)
+	now := time.Now()
+
+	w.mu.Lock()
+	f.status.LastScan = &now
+	if err != nil {
+		f.status.LastError = err.Error()
+		w.mu.Unlock()
+		w.logger.Error().Err(err).Str("folder", f.status.Path).Msg("Failed to scan watch folder")
+		return 0
+	}
+	f.status.LastError = ""
+
+	var ready []string
+	present := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || !w.isMedia(name) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		present[name] = true
+
+		current := fileState{size: info.Size(), modTime: info.ModTime()}
+		previous, seen := f.files[name]
+		if seen && previous.size == current.size && previous.modTime.Equal(current.modTime) {
+			if !previous.done {
+				ready = append(ready, name)
+			}
+			continue
+		}
+		f.files[name] = current
+	}
+	for name := range f.files {
+		if !present[name] {
+			delete(f.files, name)
+		}
+	}
+	pending := 0
+	for _, state := range f.files {
+		if !state.done {
+			pending++
+		}
+	}
+	f.status.Pending = pending - len(ready)
+	w.mu.Unlock()
+
+	processed := 0
+	for _, name := range ready {
+		if ctx.Err() != nil {
+			break
+		}
+		w.process(ctx, f, name)
+		processed++
+	}
+	return processed
+}
+
+// process analyzes one file, moves it to its verdict's folder and reports
+// the result
+func (w *Watcher) process(ctx context.Context, f *folder, name string) {
+	path := filepath.Join(f.status.Path, name)
+	w.mu.RLock()
+	size := f.files[name].size
+	w.mu.RUnlock()
+
+	outcome, err := w.analyze(ctx, path)
+	if err != nil {
+		if ctx.Err() != nil {
+			// Interrupted by shutdown; the file is picked up again next time
+			return
+		}
+		outcome = Outcome{AnalysisID: outcome.AnalysisID, Reasons: []string{err.Error()}}
+	}
+
+	result := Result{
+		Folder:      f.status.Path,
+		Filename:    name,
+		Size:        size,
+		AnalysisID:  outcome.AnalysisID,
+		Verdict:     VerdictFailed,
+		Reasons:     outcome.Reasons,
+		ProcessedAt: time.Now().UTC(),
+	}
+	dir := f.status.FailDir
+	if outcome.Passed {
+		result.Verdict = VerdictPassed
+		dir = f.status.PassDir
+	}
+
+	movedTo, moveErr := moveFile(path, dir)
+	w.mu.Lock()
+	if moveErr != nil {
+		result.Error = moveErr.Error()
+		// Do not process the same file again until it changes
+		state := f.files[name]
+		state.done = true
+		f.files[name] = state
+	} else {
+		result.MovedTo = movedTo
+		delete(f.files, name)
+	}
+	if outcome.Passed {
+		f.status.Passed++
+	} else {
+		f.status.Failed++
+	}
+	w.mu.Unlock()
+
+	event := w.logger.Info()
+	if moveErr != nil {
+		event = w.logger.Error().Err(moveErr)
+	}
+	event.Str("file", path).Str("verdict", result.Verdict).Str("moved_to", movedTo).Msg("Watch folder file processed")
+
+	if w.onResult != nil {
+		w.onResult(result)
+	}
+}
+
+func (w *Watcher) isMedia(name string) bool {
+	// Skip hidden files, which include the partial files of most copy tools
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, allowed := range w.config.Extensions {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// destination resolves a pass or fail folder against its watch folder
+func destination(folder, dir string) string {
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(folder, dir)
+}
+
+// moveFile moves path into dir, adding a timestamp to the name when a file
+// with the same name is already there. dir must be on the same filesystem.
+func moveFile(path, dir string) (string, error) {
+	name := filepath.Base(path)
+	target := filepath.Join(dir, name)
+	if _, err := os.Stat(target); err == nil {
+		ext := filepath.Ext(name)
+		target = filepath.Join(dir, fmt.Sprintf("%s_%s%s", strings.TrimSuffix(name, ext), time.Now().UTC().Format("20060102T150405"), ext))
+	}
+	if err := os.Rename(path, target); err != nil {
+		return "", fmt.Errorf("failed to move file: %w", err)
+	}
+	return target, nil
+}
@@ -0,0 +1,104 @@
+package watch
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestWatcher_ProcessesStableFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	write("good.mp4", "good")
+	write("bad.mov", "bad")
+	write("broken.mkv", "broken")
+	write("notes.txt", "ignored")
+	write(".partial.mp4", "ignored")
+
+	analyzer := func(ctx context.Context, path string) (Outcome, error) {
+		switch filepath.Base(path) {
+		case "good.mp4":
+			return Outcome{AnalysisID: "a1", Passed: true}, nil
+		case "bad.mov":
+			return Outcome{AnalysisID: "a2", Reasons: []string{"loudness.integrated: expected -23 LUFS"}}, nil
+		}
+		return Outcome{}, errors.New("analysis failed")
+	}
+	watcher, err := NewWatcher(Config{Folders: []string{dir}}, analyzer, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewWatcher failed: %v", err)
+	}
+	results := map[string]Result{}
+	watcher.SetResultHandler(func(result Result) {
+		results[result.Filename] = result
+	})
+
+	ctx := context.Background()
+	if processed := watcher.Scan(ctx); processed != 0 {
+		t.Fatalf("expected new files to wait for a second scan, processed %d", processed)
+	}
+	if status := watcher.Folders()[0]; status.Pending != 3 {
+		t.Errorf("expected 3 pending files, got %d", status.Pending)
+	}
+
+	// A file still being written is held back
+	write("broken.mkv", "broken and growing")
+	if processed := watcher.Scan(ctx); processed != 2 {
+		t.Fatalf("expected 2 files processed, got %d", processed)
+	}
+	if processed := watcher.Scan(ctx); processed != 1 {
+		t.Fatalf("expected the grown file to be processed, got %d", processed)
+	}
+
+	good := results["good.mp4"]
+	if good.Verdict != VerdictPassed || good.MovedTo != filepath.Join(dir, "passed", "good.mp4") || good.AnalysisID != "a1" {
+		t.Errorf("unexpected result for good.mp4: %+v", good)
+	}
+	if _, err := os.Stat(good.MovedTo); err != nil {
+		t.Errorf("good.mp4 was not moved: %v", err)
+	}
+	if bad := results["bad.mov"]; bad.Verdict != VerdictFailed || len(bad.Reasons) != 1 || !strings.HasPrefix(bad.MovedTo, filepath.Join(dir, "failed")) {
+		t.Errorf("unexpected result for bad.mov: %+v", bad)
+	}
+	if broken := results["broken.mkv"]; broken.Verdict != VerdictFailed || broken.Reasons[0] != "analysis failed" || broken.Size != int64(len("broken and growing")) {
+		t.Errorf("unexpected result for broken.mkv: %+v", broken)
+	}
+
+	status := watcher.Folders()[0]
+	if status.Passed != 1 || status.Failed != 2 || status.Pending != 0 || status.LastScan == nil {
+		t.Errorf("unexpected folder status %+v", status)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Errorf("non-media file should be left alone: %v", err)
+	}
+}
+
+func TestMoveFile_KeepsExistingFile(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	for _, path := range []string{filepath.Join(src, "clip.mp4"), filepath.Join(dst, "clip.mp4")} {
+		if err := os.WriteFile(path, []byte(path), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	target, err := moveFile(filepath.Join(src, "clip.mp4"), dst)
+	if err != nil {
+		t.Fatalf("moveFile failed: %v", err)
+	}
+	if target == filepath.Join(dst, "clip.mp4") || !strings.HasSuffix(target, ".mp4") {
+		t.Errorf("expected a renamed target, got %s", target)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dst, "clip.mp4")); string(data) != filepath.Join(dst, "clip.mp4") {
+		t.Error("existing file was overwritten")
+	}
+}
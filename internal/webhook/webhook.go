@@ -0,0 +1,229 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Headers sent with every callback
+const (
+	HeaderEvent     = "X-Rendiff-Event"
+	HeaderDelivery  = "X-Rendiff-Delivery"
+	HeaderTimestamp = "X-Rendiff-Timestamp"
+	HeaderSignature = "X-Rendiff-Signature"
+)
+
+// Event types
+const (
+	EventProbeCompleted = "probe.completed"
+	EventProbeFailed    = "probe.failed"
+	EventBatchCompleted = "batch.completed"
+	EventBatchCancelled = "batch.cancelled"
+
+	EventMonitorAlert    = "monitor.alert"
+	EventMonitorResolved = "monitor.resolved"
+)
+
+// maxResponseDrain bounds how much of a receiver's response body is read
+const maxResponseDrain = 64 * 1024
+
+// ErrDisabled is returned when sending without a configured signing secret
+var ErrDisabled = errors.New("webhook callbacks are not configured")
+
+// Config configures webhook delivery
+type Config struct {
+	Secret       string        // HMAC-SHA256 signing key; empty disables callbacks
+	Timeout      time.Duration // Per-attempt timeout
+	MaxRetries   int           // Retries after the first failed attempt
+	RetryBackoff time.Duration // Initial delay between retries, doubled each attempt
+}
+
+// Event is the JSON body POSTed to a callback URL
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"event"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Sender delivers signed webhook callbacks
+type Sender struct {
+	config       Config
+	httpClient   *http.Client
+	urlValidator func(string) error
+	logger       zerolog.Logger
+}
+
+// NewSender creates a new webhook sender
+func NewSender(config Config, logger zerolog.Logger) *Sender {
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = time.Second
+	}
+
+	return &Sender{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+		logger:     logger,
+	}
+}
+
+// SetHTTPClient sets a custom HTTP client
+func (s *Sender) SetHTTPClient(client *http.Client) {
+	s.httpClient = client
+}
+
+// SetURLValidator sets a validator applied to callback URLs before delivery
+func (s *Sender) SetURLValidator(validator func(string) error) {
+	s.urlValidator = validator
+}
+
+// Enabled reports whether callbacks can be signed and sent
+func (s *Sender) Enabled() bool {
+	return s.config.Secret != ""
+}
+
+// ValidateCallbackURL checks a callback URL when it is submitted, so
+// requests with unusable URLs are rejected up front
+func (s *Sender) ValidateCallbackURL(callbackURL string) error {
+	if !s.Enabled() {
+		return ErrDisabled
+	}
+	if !strings.HasPrefix(callbackURL, "http://") && !strings.HasPrefix(callbackURL, "https://") {
+		return fmt.Errorf("callback URL must use http or https")
+	}
+	if s.urlValidator != nil {
+		if err := s.urlValidator(callbackURL); err != nil {
+			return fmt.Errorf("callback URL rejected: %w", err)
+		}
+	}
+	return nil
+}
+
+// Send delivers an event, retrying on network errors, 429 and 5xx responses
+func (s *Sender) Send(ctx context.Context, callbackURL, eventType string, data interface{}) error {
+	if err := s.ValidateCallbackURL(callbackURL); err != nil {
+		return err
+	}
+
+	event := Event{
+		ID:        uuid.New().String(),
+		Type:      eventType,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	backoff := s.config.RetryBackoff
+	var lastErr error
+
+	for attempt := 0; attempt <= s.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		retry, err := s.deliver(ctx, callbackURL, &event, body)
+		if err == nil {
+			s.logger.Info().
+				Str("event", eventType).
+				Str("delivery_id", event.ID).
+				Int("attempt", attempt+1).
+				Msg("Webhook delivered")
+			return nil
+		}
+
+		lastErr = err
+		s.logger.Warn().
+			Err(err).
+			Str("event", eventType).
+			Str("delivery_id", event.ID).
+			Int("attempt", attempt+1).
+			Msg("Webhook delivery failed")
+
+		if !retry {
+			break
+		}
+	}
+
+	return fmt.Errorf("webhook delivery failed: %w", lastErr)
+}
+
+// deliver performs a single POST and reports whether a failure is retryable
+func (s *Sender) deliver(ctx context.Context, callbackURL string, event *Event, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(event.Timestamp.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rendiff-probe-webhook/1.0")
+	req.Header.Set(HeaderEvent, event.Type)
+	req.Header.Set(HeaderDelivery, event.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(s.config.Secret, timestamp, body))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		// Network errors and per-attempt timeouts are retried; Send stops
+		// retrying once the caller's context is done
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseDrain))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("callback returned HTTP %d", resp.StatusCode)
+}
+
+// Sign returns the signature header value for a payload: "sha256=" followed by
+// the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a received signature in constant time. Receivers should also
+// reject timestamps outside their replay window.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	expected := Sign(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
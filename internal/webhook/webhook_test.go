@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const testSecret = "test-webhook-secret-0123456789"
+
+func TestSend_SignsPayload(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	var body []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		received <- r
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sender := NewSender(Config{Secret: testSecret}, zerolog.Nop())
+	data := map[string]interface{}{"job_id": "abc"}
+
+	if err := sender.Send(context.Background(), server.URL, EventBatchCompleted, data); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	req := <-received
+	if req.Header.Get(HeaderEvent) != EventBatchCompleted {
+		t.Errorf("unexpected event header %q", req.Header.Get(HeaderEvent))
+	}
+	if !Verify(testSecret, req.Header.Get(HeaderTimestamp), body, req.Header.Get(HeaderSignature)) {
+		t.Error("signature did not verify")
+	}
+	if Verify("other-secret-value", req.Header.Get(HeaderTimestamp), body, req.Header.Get(HeaderSignature)) {
+		t.Error("signature verified with the wrong secret")
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if event.ID != req.Header.Get(HeaderDelivery) || event.Type != EventBatchCompleted {
+		t.Errorf("unexpected event %+v", event)
+	}
+}
+
+func TestSend_RetriesServerErrors(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sender := NewSender(Config{Secret: testSecret, MaxRetries: 3, RetryBackoff: time.Millisecond}, zerolog.Nop())
+	if err := sender.Send(context.Background(), server.URL, EventProbeCompleted, nil); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("expected 3 attempts, got %d", got)
+	}
+}
+
+func TestSend_DoesNotRetryClientErrors(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sender := NewSender(Config{Secret: testSecret, MaxRetries: 3, RetryBackoff: time.Millisecond}, zerolog.Nop())
+	if err := sender.Send(context.Background(), server.URL, EventProbeCompleted, nil); err == nil {
+		t.Fatal("expected error for 400 response")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("expected 1 attempt, got %d", got)
+	}
+}
+
+func TestValidateCallbackURL(t *testing.T) {
+	if err := NewSender(Config{}, zerolog.Nop()).ValidateCallbackURL("https://example.com/hook"); err != ErrDisabled {
+		t.Errorf("expected ErrDisabled without a secret, got %v", err)
+	}
+
+	sender := NewSender(Config{Secret: testSecret}, zerolog.Nop())
+	if err := sender.ValidateCallbackURL("ftp://example.com/hook"); err == nil {
+		t.Error("expected error for non-HTTP scheme")
+	}
+	if err := sender.ValidateCallbackURL("https://example.com/hook"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
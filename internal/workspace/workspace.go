@@ -0,0 +1,235 @@
+// Package workspace hands out the temporary files that uploads and downloads
+// are written to before analysis, keeping them in one work directory and
+// within per-job and global disk quotas.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	// ErrInsufficientStorage is matched by every error returned when a
+	// write or reservation does not fit
+	ErrInsufficientStorage = errors.New("insufficient storage")
+	// ErrJobQuotaExceeded is returned when one job would outgrow JobQuota
+	ErrJobQuotaExceeded = fmt.Errorf("%w: job disk quota exceeded", ErrInsufficientStorage)
+	// ErrQuotaExceeded is returned when all jobs together would outgrow
+	// TotalQuota
+	ErrQuotaExceeded = fmt.Errorf("%w: disk quota exceeded", ErrInsufficientStorage)
+	// ErrLowDiskSpace is returned when the volume would drop below
+	// MinFreeSpace, or is full
+	ErrLowDiskSpace = fmt.Errorf("%w: not enough free disk space", ErrInsufficientStorage)
+)
+
+// tempPrefix marks the files a Manager creates in its directory
+const tempPrefix = "ffprobe_"
+
+// Config configures a Manager. Zero limits are disabled.
+type Config struct {
+	Dir          string // Work directory for temporary media files
+	JobQuota     int64  // Largest number of bytes one file or reservation may hold
+	TotalQuota   int64  // Largest number of bytes all files and reservations may hold
+	MinFreeSpace int64  // Bytes to leave free on the work directory's volume
+}
+
+// Manager creates temporary media files and accounts for their size.
+//
+// Files are written through File, which fails with an ErrInsufficientStorage
+// error instead of writing past a quota. Disk space held elsewhere, such as
+// resumable upload sessions, is accounted for with Reserve.
+type Manager struct {
+	config Config
+	logger zerolog.Logger
+
+	mu    sync.Mutex
+	used  int64
+	files map[string]*File
+}
+
+// NewManager creates a Manager, creating the work directory if needed and
+// removing temporary files left behind by a previous process
+func NewManager(config Config, logger zerolog.Logger) (*Manager, error) {
+	if config.Dir == "" {
+		config.Dir = filepath.Join(os.TempDir(), "rendiff-work")
+	}
+	if err := os.MkdirAll(config.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	m := &Manager{
+		config: config,
+		logger: logger,
+		files:  make(map[string]*File),
+	}
+	if removed := m.removeOrphans(); removed > 0 {
+		logger.Info().Int("count", removed).Str("dir", config.Dir).Msg("Orphaned temporary files removed")
+	}
+	return m, nil
+}
+
+// Dir returns the work directory
+func (m *Manager) Dir() string {
+	return m.config.Dir
+}
+
+// Used returns the bytes held by open files and reservations
+func (m *Manager) Used() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.used
+}
+
+// FreeSpace returns the bytes available on the work directory's volume and
+// whether that is below MinFreeSpace, or the volume is full. ok is false
+// where free space cannot be determined.
+func (m *Manager) FreeSpace() (free int64, low bool, ok bool) {
+	free, ok = freeSpace(m.config.Dir)
+	if !ok {
+		return 0, false, false
+	}
+	return free, free == 0 || free < m.config.MinFreeSpace, true
+}
+
+// Create creates an empty temporary file whose name ends in name, which must
+// already be sanitized. Remove it with Remove to release its space.
+func (m *Manager) Create(name string) (*File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkLocked(0, 0); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(m.config.Dir, fmt.Sprintf("%s%d_%s", tempPrefix, time.Now().UnixNano(), name))
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	f := &File{manager: m, file: file, path: path}
+	m.files[path] = f
+	return f, nil
+}
+
+// Remove deletes a file created by Create and releases its space. Paths the
+// Manager did not create are only deleted.
+func (m *Manager) Remove(path string) error {
+	m.mu.Lock()
+	if f, ok := m.files[path]; ok {
+		m.used -= f.size
+		delete(m.files, path)
+	}
+	m.mu.Unlock()
+
+	return os.Remove(path)
+}
+
+// Reserve accounts for size bytes written outside the Manager, failing like a
+// File write would. Call the returned function once the bytes are gone.
+func (m *Manager) Reserve(size int64) (release func(), err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.checkLocked(0, size); err != nil {
+		return nil, err
+	}
+	m.used += size
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			m.mu.Lock()
+			m.used -= size
+			m.mu.Unlock()
+		})
+	}, nil
+}
+
+// checkLocked returns why a job holding held bytes cannot grow by n
+func (m *Manager) checkLocked(held, n int64) error {
+	if m.config.JobQuota > 0 && held+n > m.config.JobQuota {
+		return ErrJobQuotaExceeded
+	}
+	if m.config.TotalQuota > 0 && m.used+n > m.config.TotalQuota {
+		return ErrQuotaExceeded
+	}
+	if m.config.MinFreeSpace > 0 {
+		if free, ok := freeSpace(m.config.Dir); ok && free-n < m.config.MinFreeSpace {
+			return ErrLowDiskSpace
+		}
+	}
+	return nil
+}
+
+// removeOrphans deletes temporary files in the work directory, which no
+// running job can own before the Manager exists
+func (m *Manager) removeOrphans() int {
+	matches, err := filepath.Glob(filepath.Join(m.config.Dir, tempPrefix+"*"))
+	if err != nil {
+		return 0
+	}
+
+	removed := 0
+	for _, path := range matches {
+		if err := os.RemoveAll(path); err != nil {
+			m.logger.Warn().Err(err).Str("path", path).Msg("Failed to remove orphaned temporary file")
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// File is a temporary file created by Manager.Create. Writes that would
+// exceed a quota fail with an ErrInsufficientStorage error and write nothing.
+type File struct {
+	manager *Manager
+	file    *os.File
+	path    string
+	size    int64 // bytes accounted for, guarded by manager.mu
+}
+
+// Name returns the path of the file
+func (f *File) Name() string {
+	return f.path
+}
+
+// Write appends p to the file
+func (f *File) Write(p []byte) (int, error) {
+	m := f.manager
+	n := int64(len(p))
+
+	m.mu.Lock()
+	if err := m.checkLocked(f.size, n); err != nil {
+		m.mu.Unlock()
+		return 0, err
+	}
+	f.size += n
+	m.used += n
+	m.mu.Unlock()
+
+	written, err := f.file.Write(p)
+	if unused := n - int64(written); unused > 0 {
+		m.mu.Lock()
+		f.size -= unused
+		m.used -= unused
+		m.mu.Unlock()
+	}
+	if errors.Is(err, syscall.ENOSPC) {
+		return written, fmt.Errorf("%w: %v", ErrLowDiskSpace, err)
+	}
+	return written, err
+}
+
+// Close closes the file; it keeps its space until removed
+func (f *File) Close() error {
+	return f.file.Close()
+}
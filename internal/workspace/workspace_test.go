@@ -0,0 +1,159 @@
+package workspace
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func newTestManager(t *testing.T, config Config) *Manager {
+	t.Helper()
+	config.Dir = t.TempDir()
+	m, err := NewManager(config, zerolog.Nop())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	return m
+}
+
+func TestFile_AccountsForWrites(t *testing.T) {
+	m := newTestManager(t, Config{})
+
+	f, err := m.Create("video.mp4")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if filepath.Dir(f.Name()) != m.Dir() {
+		t.Errorf("file %s created outside the work directory", f.Name())
+	}
+	if _, err := io.Copy(f, bytes.NewReader(make([]byte, 1000))); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	f.Close()
+	if m.Used() != 1000 {
+		t.Errorf("expected 1000 bytes used, got %d", m.Used())
+	}
+
+	if err := m.Remove(f.Name()); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if m.Used() != 0 {
+		t.Errorf("expected no bytes used after Remove, got %d", m.Used())
+	}
+	if _, err := os.Stat(f.Name()); !os.IsNotExist(err) {
+		t.Errorf("expected file to be deleted, got %v", err)
+	}
+}
+
+func TestFile_JobQuota(t *testing.T) {
+	m := newTestManager(t, Config{JobQuota: 100})
+
+	f, err := m.Create("video.mp4")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(make([]byte, 60)); err != nil {
+		t.Fatalf("write within quota failed: %v", err)
+	}
+	n, err := f.Write(make([]byte, 60))
+	if !errors.Is(err, ErrJobQuotaExceeded) || !errors.Is(err, ErrInsufficientStorage) {
+		t.Errorf("expected ErrJobQuotaExceeded, got %v", err)
+	}
+	if n != 0 || m.Used() != 60 {
+		t.Errorf("expected the rejected write to be dropped, wrote %d and used %d", n, m.Used())
+	}
+
+	// Another job has its own quota
+	g, err := m.Create("other.mp4")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer g.Close()
+	if _, err := g.Write(make([]byte, 100)); err != nil {
+		t.Errorf("second job write failed: %v", err)
+	}
+}
+
+func TestManager_TotalQuota(t *testing.T) {
+	m := newTestManager(t, Config{TotalQuota: 100})
+
+	release, err := m.Reserve(80)
+	if err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	if _, err := m.Reserve(30); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded for a reservation, got %v", err)
+	}
+
+	f, err := m.Create("video.mp4")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(make([]byte, 30)); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("expected ErrQuotaExceeded for a write, got %v", err)
+	}
+
+	release()
+	release()
+	if m.Used() != 0 {
+		t.Errorf("expected release to be idempotent, %d bytes used", m.Used())
+	}
+	if _, err := f.Write(make([]byte, 30)); err != nil {
+		t.Errorf("write after release failed: %v", err)
+	}
+}
+
+func TestManager_MinFreeSpace(t *testing.T) {
+	m := newTestManager(t, Config{MinFreeSpace: 1 << 62})
+	if _, ok := freeSpace(m.Dir()); !ok {
+		t.Skip("free space is unknown on this platform")
+	}
+	if _, err := m.Create("video.mp4"); !errors.Is(err, ErrLowDiskSpace) {
+		t.Errorf("expected ErrLowDiskSpace, got %v", err)
+	}
+}
+
+func TestManager_FreeSpace(t *testing.T) {
+	m := newTestManager(t, Config{})
+	free, low, ok := m.FreeSpace()
+	if !ok {
+		t.Skip("free space is unknown on this platform")
+	}
+	if free <= 0 || low {
+		t.Errorf("FreeSpace() = %d, %v; want free space that is not low", free, low)
+	}
+
+	m = newTestManager(t, Config{MinFreeSpace: 1 << 62})
+	if _, low, _ := m.FreeSpace(); !low {
+		t.Error("expected free space below MinFreeSpace to be low")
+	}
+}
+
+func TestNewManager_RemovesOrphans(t *testing.T) {
+	dir := t.TempDir()
+	orphan := filepath.Join(dir, tempPrefix+"1_video.mp4")
+	unrelated := filepath.Join(dir, "keep.txt")
+	for _, path := range []string{orphan, unrelated} {
+		if err := os.WriteFile(path, []byte("data"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := NewManager(Config{Dir: dir}, zerolog.Nop()); err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if _, err := os.Stat(orphan); !os.IsNotExist(err) {
+		t.Errorf("expected orphan to be removed, got %v", err)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("expected unrelated file to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,419 @@
+// Rendiff Probe gRPC API
+//
+// This service exposes the same analysis capabilities as the REST API
+// (/api/v1/probe/*, /api/v1/batch/*) for service-to-service integrations.
+// Batch progress is delivered as a server stream instead of a WebSocket.
+//
+// Regenerate the Go bindings with `make proto`.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: probe.proto
+
+package probev1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	ProbeService_ProbeUpload_FullMethodName    = "/rendiff.probe.v1.ProbeService/ProbeUpload"
+	ProbeService_ProbeURL_FullMethodName       = "/rendiff.probe.v1.ProbeService/ProbeURL"
+	ProbeService_AnalyzeHLS_FullMethodName     = "/rendiff.probe.v1.ProbeService/AnalyzeHLS"
+	ProbeService_AnalyzeDASH_FullMethodName    = "/rendiff.probe.v1.ProbeService/AnalyzeDASH"
+	ProbeService_SubmitBatch_FullMethodName    = "/rendiff.probe.v1.ProbeService/SubmitBatch"
+	ProbeService_GetBatchStatus_FullMethodName = "/rendiff.probe.v1.ProbeService/GetBatchStatus"
+	ProbeService_WatchBatch_FullMethodName     = "/rendiff.probe.v1.ProbeService/WatchBatch"
+)
+
+// ProbeServiceClient is the client API for ProbeService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ProbeServiceClient interface {
+	// ProbeUpload analyzes a file streamed by the client. The first message
+	// must carry the upload metadata, all following messages carry data chunks.
+	ProbeUpload(ctx context.Context, opts ...grpc.CallOption) (ProbeService_ProbeUploadClient, error)
+	// ProbeURL downloads and analyzes a remote media file.
+	ProbeURL(ctx context.Context, in *ProbeURLRequest, opts ...grpc.CallOption) (*ProbeResponse, error)
+	// AnalyzeHLS analyzes an HLS master or media playlist.
+	AnalyzeHLS(ctx context.Context, in *AnalyzeHLSRequest, opts ...grpc.CallOption) (*AnalyzeHLSResponse, error)
+	// AnalyzeDASH analyzes a DASH MPD manifest.
+	AnalyzeDASH(ctx context.Context, in *AnalyzeDASHRequest, opts ...grpc.CallOption) (*AnalyzeDASHResponse, error)
+	// SubmitBatch starts an asynchronous batch job and returns immediately.
+	SubmitBatch(ctx context.Context, in *SubmitBatchRequest, opts ...grpc.CallOption) (*BatchJob, error)
+	// GetBatchStatus returns the current state of a batch job.
+	GetBatchStatus(ctx context.Context, in *GetBatchStatusRequest, opts ...grpc.CallOption) (*BatchJob, error)
+	// WatchBatch streams progress events for a batch job until it finishes
+	// or the client cancels the call.
+	WatchBatch(ctx context.Context, in *WatchBatchRequest, opts ...grpc.CallOption) (ProbeService_WatchBatchClient, error)
+}
+
+type probeServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewProbeServiceClient(cc grpc.ClientConnInterface) ProbeServiceClient {
+	return &probeServiceClient{cc}
+}
+
+func (c *probeServiceClient) ProbeUpload(ctx context.Context, opts ...grpc.CallOption) (ProbeService_ProbeUploadClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ProbeService_ServiceDesc.Streams[0], ProbeService_ProbeUpload_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &probeServiceProbeUploadClient{stream}
+	return x, nil
+}
+
+type ProbeService_ProbeUploadClient interface {
+	Send(*ProbeUploadRequest) error
+	CloseAndRecv() (*ProbeResponse, error)
+	grpc.ClientStream
+}
+
+type probeServiceProbeUploadClient struct {
+	grpc.ClientStream
+}
+
+func (x *probeServiceProbeUploadClient) Send(m *ProbeUploadRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *probeServiceProbeUploadClient) CloseAndRecv() (*ProbeResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(ProbeResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *probeServiceClient) ProbeURL(ctx context.Context, in *ProbeURLRequest, opts ...grpc.CallOption) (*ProbeResponse, error) {
+	out := new(ProbeResponse)
+	err := c.cc.Invoke(ctx, ProbeService_ProbeURL_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *probeServiceClient) AnalyzeHLS(ctx context.Context, in *AnalyzeHLSRequest, opts ...grpc.CallOption) (*AnalyzeHLSResponse, error) {
+	out := new(AnalyzeHLSResponse)
+	err := c.cc.Invoke(ctx, ProbeService_AnalyzeHLS_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *probeServiceClient) AnalyzeDASH(ctx context.Context, in *AnalyzeDASHRequest, opts ...grpc.CallOption) (*AnalyzeDASHResponse, error) {
+	out := new(AnalyzeDASHResponse)
+	err := c.cc.Invoke(ctx, ProbeService_AnalyzeDASH_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *probeServiceClient) SubmitBatch(ctx context.Context, in *SubmitBatchRequest, opts ...grpc.CallOption) (*BatchJob, error) {
+	out := new(BatchJob)
+	err := c.cc.Invoke(ctx, ProbeService_SubmitBatch_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *probeServiceClient) GetBatchStatus(ctx context.Context, in *GetBatchStatusRequest, opts ...grpc.CallOption) (*BatchJob, error) {
+	out := new(BatchJob)
+	err := c.cc.Invoke(ctx, ProbeService_GetBatchStatus_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *probeServiceClient) WatchBatch(ctx context.Context, in *WatchBatchRequest, opts ...grpc.CallOption) (ProbeService_WatchBatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ProbeService_ServiceDesc.Streams[1], ProbeService_WatchBatch_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &probeServiceWatchBatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ProbeService_WatchBatchClient interface {
+	Recv() (*ProgressEvent, error)
+	grpc.ClientStream
+}
+
+type probeServiceWatchBatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *probeServiceWatchBatchClient) Recv() (*ProgressEvent, error) {
+	m := new(ProgressEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ProbeServiceServer is the server API for ProbeService service.
+// All implementations must embed UnimplementedProbeServiceServer
+// for forward compatibility
+type ProbeServiceServer interface {
+	// ProbeUpload analyzes a file streamed by the client. The first message
+	// must carry the upload metadata, all following messages carry data chunks.
+	ProbeUpload(ProbeService_ProbeUploadServer) error
+	// ProbeURL downloads and analyzes a remote media file.
+	ProbeURL(context.Context, *ProbeURLRequest) (*ProbeResponse, error)
+	// AnalyzeHLS analyzes an HLS master or media playlist.
+	AnalyzeHLS(context.Context, *AnalyzeHLSRequest) (*AnalyzeHLSResponse, error)
+	// AnalyzeDASH analyzes a DASH MPD manifest.
+	AnalyzeDASH(context.Context, *AnalyzeDASHRequest) (*AnalyzeDASHResponse, error)
+	// SubmitBatch starts an asynchronous batch job and returns immediately.
+	SubmitBatch(context.Context, *SubmitBatchRequest) (*BatchJob, error)
+	// GetBatchStatus returns the current state of a batch job.
+	GetBatchStatus(context.Context, *GetBatchStatusRequest) (*BatchJob, error)
+	// WatchBatch streams progress events for a batch job until it finishes
+	// or the client cancels the call.
+	WatchBatch(*WatchBatchRequest, ProbeService_WatchBatchServer) error
+	mustEmbedUnimplementedProbeServiceServer()
+}
+
+// UnimplementedProbeServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedProbeServiceServer struct {
+}
+
+func (UnimplementedProbeServiceServer) ProbeUpload(ProbeService_ProbeUploadServer) error {
+	return status.Errorf(codes.Unimplemented, "method ProbeUpload not implemented")
+}
+func (UnimplementedProbeServiceServer) ProbeURL(context.Context, *ProbeURLRequest) (*ProbeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ProbeURL not implemented")
+}
+func (UnimplementedProbeServiceServer) AnalyzeHLS(context.Context, *AnalyzeHLSRequest) (*AnalyzeHLSResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AnalyzeHLS not implemented")
+}
+func (UnimplementedProbeServiceServer) AnalyzeDASH(context.Context, *AnalyzeDASHRequest) (*AnalyzeDASHResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AnalyzeDASH not implemented")
+}
+func (UnimplementedProbeServiceServer) SubmitBatch(context.Context, *SubmitBatchRequest) (*BatchJob, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitBatch not implemented")
+}
+func (UnimplementedProbeServiceServer) GetBatchStatus(context.Context, *GetBatchStatusRequest) (*BatchJob, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBatchStatus not implemented")
+}
+func (UnimplementedProbeServiceServer) WatchBatch(*WatchBatchRequest, ProbeService_WatchBatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchBatch not implemented")
+}
+func (UnimplementedProbeServiceServer) mustEmbedUnimplementedProbeServiceServer() {}
+
+// UnsafeProbeServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ProbeServiceServer will
+// result in compilation errors.
+type UnsafeProbeServiceServer interface {
+	mustEmbedUnimplementedProbeServiceServer()
+}
+
+func RegisterProbeServiceServer(s grpc.ServiceRegistrar, srv ProbeServiceServer) {
+	s.RegisterService(&ProbeService_ServiceDesc, srv)
+}
+
+func _ProbeService_ProbeUpload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ProbeServiceServer).ProbeUpload(&probeServiceProbeUploadServer{stream})
+}
+
+type ProbeService_ProbeUploadServer interface {
+	SendAndClose(*ProbeResponse) error
+	Recv() (*ProbeUploadRequest, error)
+	grpc.ServerStream
+}
+
+type probeServiceProbeUploadServer struct {
+	grpc.ServerStream
+}
+
+func (x *probeServiceProbeUploadServer) SendAndClose(m *ProbeResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *probeServiceProbeUploadServer) Recv() (*ProbeUploadRequest, error) {
+	m := new(ProbeUploadRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ProbeService_ProbeURL_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ProbeURLRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProbeServiceServer).ProbeURL(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProbeService_ProbeURL_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProbeServiceServer).ProbeURL(ctx, req.(*ProbeURLRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProbeService_AnalyzeHLS_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnalyzeHLSRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProbeServiceServer).AnalyzeHLS(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProbeService_AnalyzeHLS_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProbeServiceServer).AnalyzeHLS(ctx, req.(*AnalyzeHLSRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProbeService_AnalyzeDASH_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AnalyzeDASHRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProbeServiceServer).AnalyzeDASH(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProbeService_AnalyzeDASH_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProbeServiceServer).AnalyzeDASH(ctx, req.(*AnalyzeDASHRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProbeService_SubmitBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProbeServiceServer).SubmitBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProbeService_SubmitBatch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProbeServiceServer).SubmitBatch(ctx, req.(*SubmitBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProbeService_GetBatchStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBatchStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ProbeServiceServer).GetBatchStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ProbeService_GetBatchStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ProbeServiceServer).GetBatchStatus(ctx, req.(*GetBatchStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ProbeService_WatchBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchBatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ProbeServiceServer).WatchBatch(m, &probeServiceWatchBatchServer{stream})
+}
+
+type ProbeService_WatchBatchServer interface {
+	Send(*ProgressEvent) error
+	grpc.ServerStream
+}
+
+type probeServiceWatchBatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *probeServiceWatchBatchServer) Send(m *ProgressEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// ProbeService_ServiceDesc is the grpc.ServiceDesc for ProbeService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ProbeService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rendiff.probe.v1.ProbeService",
+	HandlerType: (*ProbeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ProbeURL",
+			Handler:    _ProbeService_ProbeURL_Handler,
+		},
+		{
+			MethodName: "AnalyzeHLS",
+			Handler:    _ProbeService_AnalyzeHLS_Handler,
+		},
+		{
+			MethodName: "AnalyzeDASH",
+			Handler:    _ProbeService_AnalyzeDASH_Handler,
+		},
+		{
+			MethodName: "SubmitBatch",
+			Handler:    _ProbeService_SubmitBatch_Handler,
+		},
+		{
+			MethodName: "GetBatchStatus",
+			Handler:    _ProbeService_GetBatchStatus_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ProbeUpload",
+			Handler:       _ProbeService_ProbeUpload_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchBatch",
+			Handler:       _ProbeService_WatchBatch_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "probe.proto",
+}